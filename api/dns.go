@@ -0,0 +1,340 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+)
+
+//RecordType type of DNS record
+type RecordType string
+
+const (
+	//RecordA IPv4 address record
+	RecordA RecordType = "A"
+	//RecordAAAA IPv6 address record
+	RecordAAAA RecordType = "AAAA"
+	//RecordCNAME canonical name record
+	RecordCNAME RecordType = "CNAME"
+	//RecordTXT text record
+	RecordTXT RecordType = "TXT"
+)
+
+//ZoneApex relative name of the records of the zone apex
+const ZoneApex = "@"
+
+//Zone defines DNS zone properties
+type Zone struct {
+	ID string
+	//Name domain name of the zone without trailing dot (e.g. example.com)
+	Name string
+	//NameServers name servers the zone is delegated to
+	NameServers []string
+}
+
+//Record defines a DNS record set, i.e. the records of a zone sharing the same name and type
+type Record struct {
+	ZoneID string
+	//Name name of the record relative to the zone (e.g. www), ZoneApex for the zone apex
+	Name string
+	Type RecordType
+	//TTL time to live in seconds
+	TTL int
+	//Values record values, TXT values are unquoted
+	Values []string
+}
+
+//CreateZoneOptions defines options to use when creating a DNS zone
+type CreateZoneOptions struct {
+	Name string
+}
+
+//UpsertRecordOptions defines a record set to create or to replace
+type UpsertRecordOptions struct {
+	ZoneID string
+	Name   string
+	Type   RecordType
+	//TTL time to live in seconds, 300 is used if TTL is 0
+	TTL    int
+	Values []string
+	//PublicIPID public IP the A or AAAA record points at, the address of the public IP is used instead of Values
+	PublicIPID string
+}
+
+//DeleteRecordOptions identifies a record set to delete
+type DeleteRecordOptions struct {
+	ZoneID string
+	Name   string
+	Type   RecordType
+}
+
+//DNSManagerWithContext defines the context aware version of DNSManager functions
+type DNSManagerWithContext interface {
+	CreateZoneWithContext(ctx context.Context, options CreateZoneOptions) (*Zone, CreateZoneError)
+	DeleteZoneWithContext(ctx context.Context, id string) DeleteZoneError
+	ListZonesWithContext(ctx context.Context) ([]Zone, ListZonesError)
+	GetZoneWithContext(ctx context.Context, id string) (*Zone, GetZoneError)
+	UpsertRecordWithContext(ctx context.Context, options UpsertRecordOptions) (*Record, UpsertRecordError)
+	DeleteRecordWithContext(ctx context.Context, options DeleteRecordOptions) DeleteRecordError
+	ListRecordsWithContext(ctx context.Context, zoneID string) ([]Record, ListRecordsError)
+}
+
+//DNSManager defines DNS management functions an anyclouds provider must provide
+//Only A, AAAA, CNAME and TXT records are managed, the records of other types are neither listed nor deleted
+type DNSManager interface {
+	DNSManagerWithContext
+	CreateZone(options CreateZoneOptions) (*Zone, CreateZoneError)
+	//DeleteZone deletes the zone and its records
+	DeleteZone(id string) DeleteZoneError
+	ListZones() ([]Zone, ListZonesError)
+	GetZone(id string) (*Zone, GetZoneError)
+	//UpsertRecord creates the record set or replaces it if it already exists
+	UpsertRecord(options UpsertRecordOptions) (*Record, UpsertRecordError)
+	DeleteRecord(options DeleteRecordOptions) DeleteRecordError
+	//ListRecords lists the records of the zone sorted by name and type
+	ListRecords(zoneID string) ([]Record, ListRecordsError)
+}
+
+//CreateZoneError create zone error type
+type CreateZoneError interface {
+	Error() string
+}
+
+//NewCreateZoneError creates a new CreateZoneError
+func NewCreateZoneError(cause error, options CreateZoneOptions) CreateZoneError {
+	if cause == nil {
+		return nil
+	}
+	return NewErrorStack(cause, "error creating DNS zone", options)
+}
+
+//DeleteZoneError delete zone error type
+type DeleteZoneError interface {
+	Error() string
+}
+
+//NewDeleteZoneError creates a new DeleteZoneError
+func NewDeleteZoneError(cause error, id string) DeleteZoneError {
+	if cause == nil {
+		return nil
+	}
+	return NewErrorStack(cause, "error deleting DNS zone", id)
+}
+
+//ListZonesError list zones error type
+type ListZonesError interface {
+	Error() string
+}
+
+//NewListZonesError creates a new ListZonesError
+func NewListZonesError(cause error) ListZonesError {
+	if cause == nil {
+		return nil
+	}
+	return NewErrorStack(cause, "error listing DNS zones")
+}
+
+//GetZoneError get zone error type
+type GetZoneError interface {
+	Error() string
+}
+
+//NewGetZoneError creates a new GetZoneError
+func NewGetZoneError(cause error, id string) GetZoneError {
+	if cause == nil {
+		return nil
+	}
+	return NewErrorStack(cause, "error getting DNS zone", id)
+}
+
+//UpsertRecordError upsert record error type
+type UpsertRecordError interface {
+	Error() string
+}
+
+//NewUpsertRecordError creates a new UpsertRecordError
+func NewUpsertRecordError(cause error, options UpsertRecordOptions) UpsertRecordError {
+	if cause == nil {
+		return nil
+	}
+	return NewErrorStack(cause, "error upserting DNS record", options)
+}
+
+//DeleteRecordError delete record error type
+type DeleteRecordError interface {
+	Error() string
+}
+
+//NewDeleteRecordError creates a new DeleteRecordError
+func NewDeleteRecordError(cause error, options DeleteRecordOptions) DeleteRecordError {
+	if cause == nil {
+		return nil
+	}
+	return NewErrorStack(cause, "error deleting DNS record", options)
+}
+
+//ListRecordsError list records error type
+type ListRecordsError interface {
+	Error() string
+}
+
+//NewListRecordsError creates a new ListRecordsError
+func NewListRecordsError(cause error, zoneID string) ListRecordsError {
+	if cause == nil {
+		return nil
+	}
+	return NewErrorStack(cause, "error listing DNS records", zoneID)
+}
+
+//ZoneName returns the normalized name of a zone, i.e. lower case without trailing dot
+func ZoneName(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, "."))
+}
+
+//RecordFQDN returns the fully qualified domain name, with trailing dot, of the record named name of the zone zoneName
+func RecordFQDN(name string, zoneName string) string {
+	if name == ZoneApex || name == "" {
+		return ZoneName(zoneName) + "."
+	}
+	return strings.ToLower(name) + "." + ZoneName(zoneName) + "."
+}
+
+//RecordName returns the name relative to the zone zoneName of the record of fully qualified domain name fqdn
+func RecordName(fqdn string, zoneName string) string {
+	name := ZoneName(fqdn)
+	zone := ZoneName(zoneName)
+	if name == zone {
+		return ZoneApex
+	}
+	return strings.TrimSuffix(name, "."+zone)
+}
+
+//SortRecords sorts records by name and type
+func SortRecords(records []Record) {
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Name != records[j].Name {
+			return records[i].Name < records[j].Name
+		}
+		return records[i].Type < records[j].Type
+	})
+}
+
+//CheckZoneOptions checks that the name of the zone is a valid domain name
+func CheckZoneOptions(options *CreateZoneOptions) error {
+	name := ZoneName(options.Name)
+	if !strings.Contains(name, ".") || !validDomainName(name) {
+		return WithKind(fmt.Errorf("invalid zone name %q", options.Name), ErrInvalidArgument)
+	}
+	return nil
+}
+
+func validDomainName(name string) bool {
+	for _, label := range strings.Split(name, ".") {
+		if len(label) == 0 || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+//ResolveRecordOptions checks options and resolves the address of the public IP referenced by options using ips
+//The returned options hold the lower case name of the record, its values and its TTL
+func ResolveRecordOptions(ctx context.Context, ips PublicIPManager, options UpsertRecordOptions) (*UpsertRecordOptions, error) {
+	if options.Name != ZoneApex && !validDomainName(strings.ToLower(options.Name)) {
+		return nil, WithKind(fmt.Errorf("invalid record name %q", options.Name), ErrInvalidArgument)
+	}
+	options.Name = strings.ToLower(options.Name)
+	if options.TTL == 0 {
+		options.TTL = 300
+	}
+	if options.PublicIPID != "" {
+		if len(options.Values) > 0 || (options.Type != RecordA && options.Type != RecordAAAA) {
+			return nil, WithKind(fmt.Errorf("only A and AAAA records without values can point at a public IP"), ErrInvalidArgument)
+		}
+		ip, err := ips.GetWithContext(ctx, options.PublicIPID)
+		if err != nil {
+			return nil, err
+		}
+		options.Values = []string{ip.Address}
+	}
+	if len(options.Values) == 0 {
+		return nil, WithKind(fmt.Errorf("a record must have at least one value"), ErrInvalidArgument)
+	}
+	for _, v := range options.Values {
+		ip := net.ParseIP(v)
+		switch options.Type {
+		case RecordA:
+			if ip == nil || ip.To4() == nil {
+				return nil, WithKind(fmt.Errorf("invalid IPv4 address %q", v), ErrInvalidArgument)
+			}
+		case RecordAAAA:
+			if ip == nil || ip.To4() != nil {
+				return nil, WithKind(fmt.Errorf("invalid IPv6 address %q", v), ErrInvalidArgument)
+			}
+		case RecordCNAME:
+			if len(options.Values) > 1 || !validDomainName(ZoneName(v)) || options.Name == ZoneApex {
+				return nil, WithKind(fmt.Errorf("a CNAME record must have a single domain name value and cannot be defined at the zone apex"), ErrInvalidArgument)
+			}
+		case RecordTXT:
+		default:
+			return nil, WithKind(fmt.Errorf("unsupported record type %q", options.Type), ErrInvalidArgument)
+		}
+	}
+	return &options, nil
+}
+
+//IsRecordType reports whether t is the type of records managed by a DNSManager
+func IsRecordType(t string) bool {
+	switch RecordType(t) {
+	case RecordA, RecordAAAA, RecordCNAME, RecordTXT:
+		return true
+	}
+	return false
+}
+
+//QuoteTXT returns the zone file representation of a TXT value, i.e. quoted strings of at most 255 characters
+func QuoteTXT(value string) string {
+	var chunks []string
+	for {
+		n := len(value)
+		if n > 255 {
+			n = 255
+		}
+		chunk := strings.Replace(value[:n], `\`, `\\`, -1)
+		chunks = append(chunks, `"`+strings.Replace(chunk, `"`, `\"`, -1)+`"`)
+		value = value[n:]
+		if len(value) == 0 {
+			return strings.Join(chunks, " ")
+		}
+	}
+}
+
+//UnquoteTXT returns the TXT value represented by the quoted strings of value, unquoted values are returned unchanged
+func UnquoteTXT(value string) string {
+	if !strings.HasPrefix(value, `"`) {
+		return value
+	}
+	var b strings.Builder
+	quoted, escaped := false, false
+	for _, c := range value {
+		switch {
+		case escaped:
+			b.WriteRune(c)
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
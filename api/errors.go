@@ -2,10 +2,54 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 )
 
-//stringify print the contents of the obj
+//Error kinds, providers map their native errors to these kinds
+//The kind of an error returned by a manager can be checked with errors.Is, for instance errors.Is(err, api.ErrNotFound)
+var (
+	//ErrNotFound the resource does not exist
+	ErrNotFound = errors.New("resource not found")
+	//ErrAlreadyExists a resource with the same identifier already exists
+	ErrAlreadyExists = errors.New("resource already exists")
+	//ErrQuotaExceeded the request would exceed a quota or a limit of the account
+	ErrQuotaExceeded = errors.New("quota exceeded")
+	//ErrThrottled the request was rejected by the provider rate limiter
+	ErrThrottled = errors.New("request throttled")
+	//ErrInvalidArgument the request contains an invalid argument
+	ErrInvalidArgument = errors.New("invalid argument")
+	//ErrUnauthorized the credentials are invalid or do not grant access to the resource
+	ErrUnauthorized = errors.New("unauthorized")
+)
+
+var errorKinds = []error{ErrNotFound, ErrAlreadyExists, ErrQuotaExceeded, ErrThrottled, ErrInvalidArgument, ErrUnauthorized}
+
+//ErrorKind returns the kind of err or nil if err has no kind
+//The chain of causes is followed through Unwrap and github.com/pkg/errors Cause methods
+func ErrorKind(err error) error {
+	for err != nil {
+		for _, kind := range errorKinds {
+			if err == kind {
+				return kind
+			}
+			if e, ok := err.(interface{ Is(error) bool }); ok && e.Is(kind) {
+				return kind
+			}
+		}
+		switch e := err.(type) {
+		case interface{ Unwrap() error }:
+			err = e.Unwrap()
+		case interface{ Cause() error }:
+			err = e.Cause()
+		default:
+			err = nil
+		}
+	}
+	return nil
+}
+
+//stringify print the contents of the obj, sensitive data are masked using Redact
 func stringify(data interface{}) string {
 	if data == nil {
 		return "null"
@@ -14,7 +58,7 @@ func stringify(data interface{}) string {
 	type d struct {
 		Arguments interface{}
 	}
-	p, err := json.MarshalIndent(d{Arguments: data}, "", "\t")
+	p, err := json.MarshalIndent(d{Arguments: Redact(data)}, "", "\t")
 	if err != nil {
 		return err.Error()
 	}
@@ -25,6 +69,18 @@ func stringify(data interface{}) string {
 type ErrorStack struct {
 	Cause   error
 	Message string
+	//Kind of the error, one of ErrNotFound, ErrAlreadyExists, ErrQuotaExceeded, ErrThrottled, ErrInvalidArgument, ErrUnauthorized or nil
+	Kind error
+}
+
+//Unwrap returns the cause of the error
+func (e *ErrorStack) Unwrap() error {
+	return e.Cause
+}
+
+//Is reports whether target is the kind of the error
+func (e *ErrorStack) Is(target error) bool {
+	return e.Kind != nil && e.Kind == target
 }
 
 //Error format error message
@@ -38,7 +94,7 @@ func (e *ErrorStack) Error() string {
 	return e.Message
 }
 
-//NewErrorStack create a new provider error
+//NewErrorStack create a new provider error, the kind of the error is the kind of its cause
 func NewErrorStack(cause error, message string, args ...interface{}) *ErrorStack {
 	msg := message
 	if args != nil {
@@ -47,6 +103,18 @@ func NewErrorStack(cause error, message string, args ...interface{}) *ErrorStack
 	return &ErrorStack{
 		Cause:   cause,
 		Message: msg,
+		Kind:    ErrorKind(cause),
+	}
+}
+
+//WithKind annotates cause with kind, the message of the returned error is the message of cause
+func WithKind(cause error, kind error) error {
+	if cause == nil {
+		return nil
+	}
+	return &ErrorStack{
+		Cause: cause,
+		Kind:  kind,
 	}
 }
 
@@ -70,5 +138,6 @@ func NewErrorStackFromError(cause error, err error) *ErrorStack {
 	return &ErrorStack{
 		Cause:   cause,
 		Message: msg,
+		Kind:    ErrorKind(cause),
 	}
 }
@@ -1,10 +1,15 @@
 package api_test
 
 import (
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/SebastienDorgan/anyclouds/sshutils"
+	pkgerrors "github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"runtime"
+	"strings"
 	"testing"
 )
 
@@ -57,3 +62,62 @@ func TestFuncForPC(t *testing.T) {
 	r := runtime.FuncForPC(pc)
 	fmt.Println(r.Name(), r.Entry())
 }
+
+func TestErrorKinds(t *testing.T) {
+	cause := api.WithKind(fmt.Errorf("no such image"), api.ErrNotFound)
+	assert.True(t, errors.Is(cause, api.ErrNotFound))
+	assert.Equal(t, "no such image", cause.Error())
+
+	err := api.NewGetImageError(pkgerrors.Wrap(cause, "error reading image"), "i-4545644")
+	assert.True(t, errors.Is(err, api.ErrNotFound))
+	assert.False(t, errors.Is(err, api.ErrAlreadyExists))
+	assert.Equal(t, api.ErrNotFound, api.ErrorKind(err))
+
+	var stack *api.ErrorStack
+	assert.True(t, errors.As(err, &stack))
+	assert.Equal(t, api.ErrNotFound, stack.Kind)
+
+	err = api.NewGetImageError(fmt.Errorf("terrible error"), "i-4545644")
+	assert.Nil(t, api.ErrorKind(err))
+	assert.Nil(t, api.WithKind(nil, api.ErrNotFound))
+	assert.Nil(t, api.NewCreateServerError(nil, api.CreateServerOptions{}))
+}
+
+type credentials struct {
+	Login    string
+	Password string `redact:"true"`
+}
+
+type token string
+
+func (t token) Redact() interface{} {
+	return api.RedactedValue
+}
+
+func TestRedactedErrors(t *testing.T) {
+	kp, err := sshutils.CreateKeyPair(1024)
+	assert.NoError(t, err)
+	script := "#!/bin/sh\nexport DB_PASSWORD=secret_password\n"
+	err = api.NewCreateServerError(fmt.Errorf("terrible error"), api.CreateServerOptions{
+		Name:            "server",
+		BootstrapScript: strings.NewReader(script),
+		KeyPair:         *kp,
+	})
+	msg := err.Error()
+	assert.Contains(t, msg, "server")
+	assert.Contains(t, msg, api.RedactedValue)
+	assert.NotContains(t, msg, base64.StdEncoding.EncodeToString(kp.PrivateKey))
+	assert.NotContains(t, msg, "PRIVATE KEY")
+	assert.NotContains(t, msg, "secret_password")
+	assert.Contains(t, msg, base64.StdEncoding.EncodeToString(kp.PublicKey))
+
+	err = api.NewErrorStack(fmt.Errorf("terrible error"), "error authenticating",
+		credentials{Login: "user", Password: "secret_password"},
+		&credentials{Login: "user"},
+		map[string]interface{}{"token": token("secret_token")},
+	)
+	msg = err.Error()
+	assert.Contains(t, msg, "user")
+	assert.NotContains(t, msg, "secret_password")
+	assert.NotContains(t, msg, "secret_token")
+}
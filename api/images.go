@@ -1,9 +1,27 @@
 package api
 
 import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
+//OSFamily enum defining operating system families
+type OSFamily string
+
+const (
+	//OSLinux Linux operating systems
+	OSLinux OSFamily = "linux"
+	//OSWindows Windows operating systems
+	OSWindows OSFamily = "windows"
+	//OSUnknown unknown operating system
+	OSUnknown OSFamily = "unknown"
+)
+
 //Image defines Image type
 type Image struct {
 	//unique identifier of the image
@@ -18,12 +36,52 @@ type Image struct {
 	CreatedAt time.Time
 	//last update time
 	UpdatedAt time.Time
+	//OSFamily family of the operating system of the image
+	OSFamily OSFamily
+	//Distribution lower case name of the distribution (ubuntu, debian, centos, rhel, windows, ...), empty if unknown
+	Distribution string
+	//Version version of the distribution (18.04, 7.6, 2019, ...), empty if unknown
+	Version string
+	//Arch CPU architecture of the image
+	Arch CPUArch
+	//Owner publisher or owner of the image (canonical, the account or the project owning the image, ...)
+	Owner string
+	//DefaultUser user to use to connect to the servers created from the image, empty if unknown
+	DefaultUser string
+}
+
+//ImageQuery defines the criteria used to find an image, empty fields are not checked
+//String fields are compared case insensitively
+type ImageQuery struct {
+	OSFamily     OSFamily
+	Distribution string
+	//Version matches the images of version Version or of a version starting with Version followed by a dot, e.g. 7 matches 7.6
+	Version string
+	Arch    CPUArch
+	Owner   string
+	//Latest selects the most recent image if several images match, the query must match a single image otherwise
+	Latest bool
+}
+
+//ImageManagerWithContext defines the context aware version of ImageManager functions
+type ImageManagerWithContext interface {
+	ListWithContext(ctx context.Context) ([]Image, ListImageError)
+	GetWithContext(ctx context.Context, id string) (*Image, GetImageError)
+	CreateFromServerWithContext(ctx context.Context, serverID string, name string) (*Image, CreateImageError)
+	DeleteWithContext(ctx context.Context, id string) DeleteImageError
+	FindWithContext(ctx context.Context, query ImageQuery) (*Image, FindImageError)
 }
 
 //ImageManager defines image management functions a anyclouds provider must provide
 type ImageManager interface {
+	ImageManagerWithContext
 	List() ([]Image, ListImageError)
 	Get(id string) (*Image, GetImageError)
+	//CreateFromServer captures the system disk of a server and returns once the image can be used to create servers
+	CreateFromServer(serverID string, name string) (*Image, CreateImageError)
+	Delete(id string) DeleteImageError
+	//Find returns the image matching query
+	Find(query ImageQuery) (*Image, FindImageError)
 }
 
 //ListImageError list image error type
@@ -51,3 +109,208 @@ func NewGetImageError(cause error, imageID string) GetImageError {
 	}
 	return NewErrorStack(cause, "error getting image", imageID)
 }
+
+//CreateImageError create image error type
+type CreateImageError interface {
+	Error() string
+}
+
+//NewCreateImageError create a new CreateImageError
+func NewCreateImageError(cause error, serverID string, name string) CreateImageError {
+	if cause == nil {
+		return nil
+	}
+	return NewErrorStack(cause, "error creating image", serverID, name)
+}
+
+//DeleteImageError delete image error type
+type DeleteImageError interface {
+	Error() string
+}
+
+//NewDeleteImageError create a new DeleteImageError
+func NewDeleteImageError(cause error, imageID string) DeleteImageError {
+	if cause == nil {
+		return nil
+	}
+	return NewErrorStack(cause, "error deleting image", imageID)
+}
+
+//FindImageError find image error type
+type FindImageError interface {
+	Error() string
+}
+
+//NewFindImageError create a new FindImageError
+func NewFindImageError(cause error, query ImageQuery) FindImageError {
+	if cause == nil {
+		return nil
+	}
+	return NewErrorStack(cause, "error finding image", query)
+}
+
+//Match returns true if img matches q
+func (q *ImageQuery) Match(img *Image) bool {
+	if q.OSFamily != "" && img.OSFamily != q.OSFamily {
+		return false
+	}
+	if q.Distribution != "" && !strings.EqualFold(img.Distribution, q.Distribution) {
+		return false
+	}
+	if q.Version != "" && img.Version != q.Version && !strings.HasPrefix(img.Version, q.Version+".") {
+		return false
+	}
+	if q.Arch != "" && img.Arch != q.Arch {
+		return false
+	}
+	return q.Owner == "" || strings.EqualFold(img.Owner, q.Owner)
+}
+
+//compareVersions compares the dot separated versions v1 and v2 numerically
+func compareVersions(v1, v2 string) int {
+	t1, t2 := strings.Split(v1, "."), strings.Split(v2, ".")
+	for i := 0; i < len(t1) && i < len(t2); i++ {
+		n1, err1 := strconv.Atoi(t1[i])
+		n2, err2 := strconv.Atoi(t2[i])
+		if err1 != nil || err2 != nil {
+			if c := strings.Compare(t1[i], t2[i]); c != 0 {
+				return c
+			}
+			continue
+		}
+		if n1 != n2 {
+			if n1 < n2 {
+				return -1
+			}
+			return 1
+		}
+	}
+	return len(t1) - len(t2)
+}
+
+//FindImage returns the image of images matching query
+//The error is of kind ErrNotFound if no image matches query and of kind ErrInvalidArgument if several images match and query.Latest is false
+func FindImage(images []Image, query ImageQuery) (*Image, error) {
+	var found []Image
+	for i := range images {
+		if query.Match(&images[i]) {
+			found = append(found, images[i])
+		}
+	}
+	if len(found) == 0 {
+		return nil, WithKind(fmt.Errorf("no image matches %+v", query), ErrNotFound)
+	}
+	if len(found) > 1 && !query.Latest {
+		return nil, WithKind(fmt.Errorf("%d images match %+v", len(found), query), ErrInvalidArgument)
+	}
+	sort.SliceStable(found, func(i, j int) bool {
+		if !found[i].CreatedAt.Equal(found[j].CreatedAt) {
+			return found[i].CreatedAt.After(found[j].CreatedAt)
+		}
+		if c := compareVersions(found[i].Version, found[j].Version); c != 0 {
+			return c > 0
+		}
+		return found[i].Name > found[j].Name
+	})
+	return &found[0], nil
+}
+
+//distributions known distributions indexed by the words identifying them
+var distributions = map[string]string{
+	"ubuntu":    "ubuntu",
+	"debian":    "debian",
+	"centos":    "centos",
+	"rhel":      "rhel",
+	"redhat":    "rhel",
+	"red hat":   "rhel",
+	"fedora":    "fedora",
+	"amzn":      "amazon",
+	"amazon":    "amazon",
+	"coreos":    "coreos",
+	"opensuse":  "opensuse",
+	"sles":      "sles",
+	"cirros":    "cirros",
+	"windows":   "windows",
+	"freebsd":   "freebsd",
+	"oracle":    "oracle",
+	"almalinux": "almalinux",
+	"rocky":     "rocky",
+}
+
+//codenames versions of the distributions identified by their code names
+var codenames = map[string]string{
+	"trusty":   "14.04",
+	"xenial":   "16.04",
+	"bionic":   "18.04",
+	"cosmic":   "18.10",
+	"disco":    "19.04",
+	"eoan":     "19.10",
+	"focal":    "20.04",
+	"jessie":   "8",
+	"stretch":  "9",
+	"buster":   "10",
+	"bullseye": "11",
+}
+
+//defaultUsers users created by the cloud images of the distributions
+var defaultUsers = map[string]string{
+	"ubuntu":  "ubuntu",
+	"debian":  "debian",
+	"centos":  "centos",
+	"rhel":    "cloud-user",
+	"fedora":  "fedora",
+	"amazon":  "ec2-user",
+	"coreos":  "core",
+	"cirros":  "cirros",
+	"windows": "Administrator",
+}
+
+var versionExp = regexp.MustCompile(`\d+(\.\d+)*`)
+
+//archExp matches the architectures names appearing in image names, they must not be taken for versions
+var archExp = regexp.MustCompile(`x86_64|amd64|i386|i686|aarch64|arm64|armhf`)
+
+//ParseOS extracts the operating system family, the distribution and the version from a free text description of an image such as its name
+func ParseOS(text string) (OSFamily, string, string) {
+	lower := strings.ToLower(text)
+	distribution, pos := "", len(lower)
+	for word, d := range distributions {
+		if i := strings.Index(lower, word); i >= 0 && (i < pos || i == pos && d < distribution) {
+			distribution, pos = d, i
+		}
+	}
+	if distribution == "" {
+		return OSUnknown, "", ""
+	}
+	family := OSLinux
+	if distribution == "windows" {
+		family = OSWindows
+	}
+	tail := lower[pos:]
+	for name, version := range codenames {
+		if strings.Contains(tail, name) {
+			return family, distribution, version
+		}
+	}
+	return family, distribution, versionExp.FindString(archExp.ReplaceAllString(tail, " "))
+}
+
+//ParseArch returns the CPU architecture named arch (x86_64, amd64, aarch64, ...)
+func ParseArch(arch string) CPUArch {
+	switch strings.ToLower(arch) {
+	case "x86_64", "amd64", "x64":
+		return ArchAmd64
+	case "i386", "i686", "x86":
+		return Arch386
+	case "aarch64", "arm64":
+		return ArchARM64
+	case "arm", "armhf", "armv7l":
+		return ArchARM
+	}
+	return ArchUnknown
+}
+
+//DefaultUser returns the user created by the cloud images of distribution, empty if it is unknown
+func DefaultUser(distribution string) string {
+	return defaultUsers[distribution]
+}
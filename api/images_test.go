@@ -0,0 +1,62 @@
+package api_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseOS(t *testing.T) {
+	cases := []struct {
+		text         string
+		family       api.OSFamily
+		distribution string
+		version      string
+	}{
+		{"ubuntu/images/hvm-ssd/ubuntu-bionic-18.04-amd64-server-20190627", api.OSLinux, "ubuntu", "18.04"},
+		{"RHEL-7.6_HVM_GA-20190128-x86_64-0-Hourly2-GP2", api.OSLinux, "rhel", "7.6"},
+		{"debian-stretch-hvm-x86_64-gp2-2019-05-14-84483", api.OSLinux, "debian", "9"},
+		{"CentOS Linux 7 x86_64 HVM EBS ENA 1901_01", api.OSLinux, "centos", "7"},
+		{"UbuntuServer 16.04-LTS", api.OSLinux, "ubuntu", "16.04"},
+		{"WindowsServer 2019-Datacenter", api.OSWindows, "windows", "2019"},
+		{"cirros-0.4.0-x86_64-disk", api.OSLinux, "cirros", "0.4.0"},
+		{"my image", api.OSUnknown, "", ""},
+	}
+	for _, c := range cases {
+		family, distribution, version := api.ParseOS(c.text)
+		assert.Equal(t, c.family, family, c.text)
+		assert.Equal(t, c.distribution, distribution, c.text)
+		assert.Equal(t, c.version, version, c.text)
+	}
+	assert.Equal(t, api.ArchAmd64, api.ParseArch("x86_64"))
+	assert.Equal(t, api.ArchUnknown, api.ParseArch(""))
+	assert.Equal(t, "ubuntu", api.DefaultUser("ubuntu"))
+	assert.Equal(t, "", api.DefaultUser(""))
+}
+
+func TestFindImage(t *testing.T) {
+	date := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	images := []api.Image{
+		{ID: "a", Distribution: "ubuntu", Version: "18.04", Arch: api.ArchAmd64, CreatedAt: date},
+		{ID: "b", Distribution: "ubuntu", Version: "18.04", Arch: api.ArchAmd64, CreatedAt: date.AddDate(0, 1, 0)},
+		{ID: "c", Distribution: "ubuntu", Version: "16.04", Arch: api.ArchAmd64, CreatedAt: date.AddDate(0, 2, 0)},
+		{ID: "d", Distribution: "centos", Version: "7.6", Arch: api.ArchAmd64, CreatedAt: date},
+		{ID: "e", Distribution: "centos", Version: "7.10", Arch: api.ArchAmd64, CreatedAt: date},
+	}
+	img, err := api.FindImage(images, api.ImageQuery{Distribution: "Ubuntu", Version: "18.04", Latest: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "b", img.ID)
+	img, err = api.FindImage(images, api.ImageQuery{Distribution: "centos", Version: "7", Latest: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "e", img.ID)
+	img, err = api.FindImage(images, api.ImageQuery{Version: "16"})
+	assert.NoError(t, err)
+	assert.Equal(t, "c", img.ID)
+	_, err = api.FindImage(images, api.ImageQuery{Distribution: "ubuntu"})
+	assert.True(t, errors.Is(err, api.ErrInvalidArgument))
+	_, err = api.FindImage(images, api.ImageQuery{Version: "7.1"})
+	assert.True(t, errors.Is(err, api.ErrNotFound))
+}
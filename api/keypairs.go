@@ -0,0 +1,83 @@
+package api
+
+import (
+	"context"
+)
+
+//KeyPair defines a named SSH public key registered on a provider
+type KeyPair struct {
+	Name string
+	//Fingerprint MD5 fingerprint of the public key, colon separated hexadecimal digits
+	Fingerprint string
+}
+
+//KeyPairManagerWithContext defines the context aware version of KeyPairManager functions
+type KeyPairManagerWithContext interface {
+	ImportWithContext(ctx context.Context, name string, publicKey []byte) (*KeyPair, ImportKeyPairError)
+	DeleteWithContext(ctx context.Context, name string) DeleteKeyPairError
+	ListWithContext(ctx context.Context) ([]KeyPair, ListKeyPairsError)
+	GetWithContext(ctx context.Context, name string) (*KeyPair, GetKeyPairError)
+}
+
+//KeyPairManager defines key pair management functions an anyclouds provider must provide
+//Imported key pairs can be referenced by name when creating servers
+type KeyPairManager interface {
+	KeyPairManagerWithContext
+	//Import registers publicKey, in authorized_keys format, under name
+	Import(name string, publicKey []byte) (*KeyPair, ImportKeyPairError)
+	Delete(name string) DeleteKeyPairError
+	List() ([]KeyPair, ListKeyPairsError)
+	Get(name string) (*KeyPair, GetKeyPairError)
+}
+
+//ImportKeyPairError import key pair error type
+type ImportKeyPairError interface {
+	Error() string
+}
+
+//NewImportKeyPairError creates a new ImportKeyPairError
+func NewImportKeyPairError(cause error, name string) ImportKeyPairError {
+	if cause == nil {
+		return nil
+	}
+	return NewErrorStack(cause, "error importing key pair", name)
+}
+
+//DeleteKeyPairError delete key pair error type
+type DeleteKeyPairError interface {
+	Error() string
+}
+
+//NewDeleteKeyPairError creates a new DeleteKeyPairError
+func NewDeleteKeyPairError(cause error, name string) DeleteKeyPairError {
+	if cause == nil {
+		return nil
+	}
+	return NewErrorStack(cause, "error deleting key pair", name)
+}
+
+//ListKeyPairsError list key pairs error type
+type ListKeyPairsError interface {
+	Error() string
+}
+
+//NewListKeyPairsError creates a new ListKeyPairsError
+func NewListKeyPairsError(cause error) ListKeyPairsError {
+	if cause == nil {
+		return nil
+	}
+	return NewErrorStack(cause, "error listing key pairs")
+}
+
+//GetKeyPairError get key pair error type
+type GetKeyPairError interface {
+	Error() string
+}
+
+//NewGetKeyPairError creates a new GetKeyPairError
+func NewGetKeyPairError(cause error, name string) GetKeyPairError {
+	if cause == nil {
+		return nil
+	}
+	return NewErrorStack(cause, "error getting key pair", name)
+}
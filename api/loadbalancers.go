@@ -0,0 +1,209 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+//LoadBalancerType type of load balancer
+type LoadBalancerType string
+
+const (
+	//LoadBalancerL4 load balancer forwarding TCP connections or UDP datagrams
+	LoadBalancerL4 LoadBalancerType = "L4"
+	//LoadBalancerL7 load balancer forwarding HTTP requests
+	LoadBalancerL7 LoadBalancerType = "L7"
+)
+
+//LoadBalancerProtocol protocol of a listener, a backend pool or a health check
+//L4 load balancers accept TCP and UDP listeners, L7 load balancers accept HTTP listeners
+type LoadBalancerProtocol string
+
+const (
+	//LoadBalancerTCP TCP protocol
+	LoadBalancerTCP LoadBalancerProtocol = "TCP"
+	//LoadBalancerUDP UDP protocol
+	LoadBalancerUDP LoadBalancerProtocol = "UDP"
+	//LoadBalancerHTTP HTTP protocol
+	LoadBalancerHTTP LoadBalancerProtocol = "HTTP"
+)
+
+//HealthCheck defines how the servers of a backend pool are checked
+type HealthCheck struct {
+	//Protocol TCP to check that connections are accepted or HTTP to check that Path answers 2XX or 3XX
+	Protocol LoadBalancerProtocol
+	//Port checked port, the port of the backend pool is used if Port is 0
+	Port int
+	//Path checked path of HTTP health checks
+	Path     string
+	Interval time.Duration
+	//UnhealthyThreshold number of consecutive failed checks before a server stops receiving traffic
+	UnhealthyThreshold int
+}
+
+//BackendPool defines the servers traffic is forwarded to
+type BackendPool struct {
+	Protocol LoadBalancerProtocol
+	//Port port of the servers traffic is forwarded to
+	Port      int
+	ServerIDs []string
+	//HealthCheck a TCP check of the pool port is used if HealthCheck is nil
+	HealthCheck *HealthCheck
+}
+
+//Listener defines a port of the load balancer and the backend pool its traffic is forwarded to
+type Listener struct {
+	ID       string
+	Protocol LoadBalancerProtocol
+	Port     int
+	Pool     BackendPool
+}
+
+//LoadBalancer defines load balancer properties
+type LoadBalancer struct {
+	ID   string
+	Name string
+	Type LoadBalancerType
+	//Public true if the load balancer is reachable from internet
+	Public    bool
+	SubnetIDs []string
+	//Address DNS name or IP address of the load balancer
+	Address string
+	//Listeners listeners sorted by port
+	Listeners []Listener
+	Tags      map[string]string
+}
+
+//ListenerOptions defines a listener of a load balancer to create
+type ListenerOptions struct {
+	Protocol LoadBalancerProtocol
+	Port     int
+	Pool     BackendPool
+}
+
+//CreateLoadBalancerOptions defines options to use when creating a load balancer
+type CreateLoadBalancerOptions struct {
+	Name   string
+	Type   LoadBalancerType
+	Public bool
+	//SubnetIDs subnets the load balancer is attached to, all the subnets must belong to the same network
+	SubnetIDs []string
+	Listeners []ListenerOptions
+	Tags      map[string]string
+}
+
+//LoadBalancerManagerWithContext defines the context aware version of LoadBalancerManager functions
+type LoadBalancerManagerWithContext interface {
+	CreateWithContext(ctx context.Context, options CreateLoadBalancerOptions) (*LoadBalancer, CreateLoadBalancerError)
+	DeleteWithContext(ctx context.Context, id string) DeleteLoadBalancerError
+	ListWithContext(ctx context.Context) ([]LoadBalancer, ListLoadBalancersError)
+	GetWithContext(ctx context.Context, id string) (*LoadBalancer, GetLoadBalancerError)
+}
+
+//LoadBalancerManager defines load balancer management functions an anyclouds provider must provide
+//Listeners, backend pools and health checks are created and deleted with their load balancer
+type LoadBalancerManager interface {
+	LoadBalancerManagerWithContext
+	//Create returns once the load balancer is ready to forward traffic
+	Create(options CreateLoadBalancerOptions) (*LoadBalancer, CreateLoadBalancerError)
+	Delete(id string) DeleteLoadBalancerError
+	List() ([]LoadBalancer, ListLoadBalancersError)
+	Get(id string) (*LoadBalancer, GetLoadBalancerError)
+}
+
+//CreateLoadBalancerError create load balancer error type
+type CreateLoadBalancerError interface {
+	Error() string
+}
+
+//NewCreateLoadBalancerError creates a new CreateLoadBalancerError
+func NewCreateLoadBalancerError(cause error, options CreateLoadBalancerOptions) CreateLoadBalancerError {
+	if cause == nil {
+		return nil
+	}
+	return NewErrorStack(cause, "error creating load balancer", options)
+}
+
+//DeleteLoadBalancerError delete load balancer error type
+type DeleteLoadBalancerError interface {
+	Error() string
+}
+
+//NewDeleteLoadBalancerError creates a new DeleteLoadBalancerError
+func NewDeleteLoadBalancerError(cause error, id string) DeleteLoadBalancerError {
+	if cause == nil {
+		return nil
+	}
+	return NewErrorStack(cause, "error deleting load balancer", id)
+}
+
+//ListLoadBalancersError list load balancers error type
+type ListLoadBalancersError interface {
+	Error() string
+}
+
+//NewListLoadBalancersError creates a new ListLoadBalancersError
+func NewListLoadBalancersError(cause error) ListLoadBalancersError {
+	if cause == nil {
+		return nil
+	}
+	return NewErrorStack(cause, "error listing load balancers")
+}
+
+//GetLoadBalancerError get load balancer error type
+type GetLoadBalancerError interface {
+	Error() string
+}
+
+//NewGetLoadBalancerError creates a new GetLoadBalancerError
+func NewGetLoadBalancerError(cause error, id string) GetLoadBalancerError {
+	if cause == nil {
+		return nil
+	}
+	return NewErrorStack(cause, "error getting load balancer", id)
+}
+
+//DefaultHealthCheck returns the health check of backend pools using protocol created without health check
+//HTTP pools are checked with HTTP requests on /, the other pools with TCP connections
+func DefaultHealthCheck(protocol LoadBalancerProtocol) *HealthCheck {
+	hc := &HealthCheck{
+		Protocol:           LoadBalancerTCP,
+		Interval:           30 * time.Second,
+		UnhealthyThreshold: 3,
+	}
+	if protocol == LoadBalancerHTTP {
+		hc.Protocol = LoadBalancerHTTP
+		hc.Path = "/"
+	}
+	return hc
+}
+
+//CheckLoadBalancerOptions checks the consistency of options, the protocols of the listeners must match the load balancer type
+func CheckLoadBalancerOptions(options *CreateLoadBalancerOptions) error {
+	if options.Type != LoadBalancerL4 && options.Type != LoadBalancerL7 {
+		return WithKind(fmt.Errorf("invalid load balancer type %q", options.Type), ErrInvalidArgument)
+	}
+	if len(options.SubnetIDs) == 0 {
+		return WithKind(fmt.Errorf("a load balancer must be attached to at least one subnet"), ErrInvalidArgument)
+	}
+	for _, l := range options.Listeners {
+		if l.Port < 1 || l.Port > 65535 || l.Pool.Port < 1 || l.Pool.Port > 65535 {
+			return WithKind(fmt.Errorf("invalid listener port %d or backend port %d", l.Port, l.Pool.Port), ErrInvalidArgument)
+		}
+		l7 := l.Protocol == LoadBalancerHTTP
+		if l7 != (options.Type == LoadBalancerL7) || (!l7 && l.Protocol != LoadBalancerTCP && l.Protocol != LoadBalancerUDP) {
+			return WithKind(fmt.Errorf("protocol %q cannot be used by %s load balancers", l.Protocol, options.Type), ErrInvalidArgument)
+		}
+		if l.Pool.Protocol != l.Protocol {
+			return WithKind(fmt.Errorf("backend pool protocol %q does not match listener protocol %q", l.Pool.Protocol, l.Protocol), ErrInvalidArgument)
+		}
+		if hc := l.Pool.HealthCheck; hc != nil && hc.Protocol != LoadBalancerTCP && hc.Protocol != LoadBalancerHTTP {
+			return WithKind(fmt.Errorf("invalid health check protocol %q", hc.Protocol), ErrInvalidArgument)
+		}
+		if hc := l.Pool.HealthCheck; hc != nil && l7 && hc.Protocol != LoadBalancerHTTP {
+			return WithKind(fmt.Errorf("health check protocol %q cannot be used by %s backend pools", hc.Protocol, l.Pool.Protocol), ErrInvalidArgument)
+		}
+	}
+	return nil
+}
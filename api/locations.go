@@ -0,0 +1,73 @@
+package api
+
+import (
+	"context"
+	"fmt"
+)
+
+//Region defines a region of a provider, i.e. a set of data centers sharing the same API endpoints
+type Region struct {
+	//ID identifier of the region, used as region in provider configurations and by Provider.WithRegion
+	ID string
+	//Name human readable name of the region, ID if the provider does not define one
+	Name string
+}
+
+//AvailabilityZone defines an availability zone of a region
+type AvailabilityZone struct {
+	ID     string
+	Region string
+	//Available false if the zone does not accept new resources
+	Available bool
+}
+
+//LocationManagerWithContext defines the context aware version of LocationManager functions
+type LocationManagerWithContext interface {
+	ListRegionsWithContext(ctx context.Context) ([]Region, ListRegionsError)
+	ListZonesWithContext(ctx context.Context, region string) ([]AvailabilityZone, ListAvailabilityZonesError)
+}
+
+//LocationManager defines region and availability zone discovery functions an anyclouds provider must provide
+type LocationManager interface {
+	LocationManagerWithContext
+	//ListRegions lists the regions available to the account of the provider
+	ListRegions() ([]Region, ListRegionsError)
+	//ListZones lists the availability zones of region, the list is empty if the region has no availability zones
+	ListZones(region string) ([]AvailabilityZone, ListAvailabilityZonesError)
+}
+
+//ListRegionsError list regions error type
+type ListRegionsError interface {
+	Error() string
+}
+
+//NewListRegionsError creates a new ListRegionsError
+func NewListRegionsError(cause error) ListRegionsError {
+	if cause == nil {
+		return nil
+	}
+	return NewErrorStack(cause, "error listing regions")
+}
+
+//ListAvailabilityZonesError list availability zones error type
+type ListAvailabilityZonesError interface {
+	Error() string
+}
+
+//NewListAvailabilityZonesError creates a new ListAvailabilityZonesError
+func NewListAvailabilityZonesError(cause error, region string) ListAvailabilityZonesError {
+	if cause == nil {
+		return nil
+	}
+	return NewErrorStack(cause, "error listing availability zones", region)
+}
+
+//FindRegion returns the region identified by id, the error is of kind ErrNotFound if regions does not contain it
+func FindRegion(regions []Region, id string) (*Region, error) {
+	for i := range regions {
+		if regions[i].ID == id {
+			return &regions[i], nil
+		}
+	}
+	return nil, WithKind(fmt.Errorf("region %s not found", id), ErrNotFound)
+}
@@ -1,5 +1,7 @@
 package api
 
+import "context"
+
 //NetworkInterface represents an network interface card
 type NetworkInterface struct {
 	ID               string
@@ -11,6 +13,7 @@ type NetworkInterface struct {
 	PublicIPAddress  string
 	PrivateIPAddress string
 	SecurityGroupID  string
+	Tags             map[string]string
 }
 
 //CreateNetworkInterfaceOptions options that can be used to create a network interface card
@@ -22,6 +25,7 @@ type CreateNetworkInterfaceOptions struct {
 	SecurityGroupID  string
 	Primary          bool
 	PrivateIPAddress *string
+	Tags             map[string]string
 }
 
 //UpdateNetworkInterfaceOptions options can be used to update a network interface card
@@ -40,8 +44,18 @@ type ListNetworkInterfacesOptions struct {
 	PrivateIPAddress *string
 }
 
+//NetworkInterfaceManagerWithContext defines the context aware version of NetworkInterfaceManager functions
+type NetworkInterfaceManagerWithContext interface {
+	CreateWithContext(ctx context.Context, options CreateNetworkInterfaceOptions) (*NetworkInterface, CreateNetworkInterfaceError)
+	DeleteWithContext(ctx context.Context, id string) DeleteNetworkInterfaceError
+	GetWithContext(ctx context.Context, id string) (*NetworkInterface, GetNetworkInterfaceError)
+	ListWithContext(ctx context.Context, options *ListNetworkInterfacesOptions) ([]NetworkInterface, ListNetworkInterfacesError)
+	UpdateWithContext(ctx context.Context, options UpdateNetworkInterfaceOptions) (*NetworkInterface, UpdateNetworkInterfaceError)
+}
+
 //NetworkInterfaceManager an interface providing an abstraction to manipulate network interface cards
 type NetworkInterfaceManager interface {
+	NetworkInterfaceManagerWithContext
 	Create(options CreateNetworkInterfaceOptions) (*NetworkInterface, CreateNetworkInterfaceError)
 	Delete(id string) DeleteNetworkInterfaceError
 	Get(id string) (*NetworkInterface, GetNetworkInterfaceError)
@@ -1,5 +1,7 @@
 package api
 
+import "context"
+
 //Network defines network properties
 type Network struct {
 	//unique identifier of the network
@@ -8,6 +10,8 @@ type Network struct {
 	Name string
 	//Cidr of the network
 	CIDR string
+	//Tags of the network
+	Tags map[string]string
 }
 
 //CreateNetworkOptions defines options to use when creating a network
@@ -16,6 +20,8 @@ type CreateNetworkOptions struct {
 	CIDR string
 	//DeviceName of the network
 	Name string
+	//Tags of the network
+	Tags map[string]string
 }
 
 //IPVersion ip version enum
@@ -41,6 +47,11 @@ type CreateSubnetOptions struct {
 	CIDR string
 	//IP Version
 	IPVersion IPVersion
+	//AvailabilityZone availability zone of the sub network, the zone of the provider configuration if empty
+	//Ignored by the providers whose sub networks span all the zones of a region
+	AvailabilityZone string
+	//Tags of the sub network
+	Tags map[string]string
 }
 
 //Subnet defines sub network properties
@@ -55,10 +66,26 @@ type Subnet struct {
 	CIDR string
 	//IP Version
 	IPVersion IPVersion
+	//Tags of the sub network
+	Tags map[string]string
+}
+
+//NetworkManagerWithContext defines the context aware version of NetworkManager functions
+type NetworkManagerWithContext interface {
+	CreateNetworkWithContext(ctx context.Context, options CreateNetworkOptions) (*Network, CreateNetworkError)
+	DeleteNetworkWithContext(ctx context.Context, id string) DeleteNetworkError
+	ListNetworksWithContext(ctx context.Context) ([]Network, ListNetworksError)
+	GetNetworkWithContext(ctx context.Context, id string) (*Network, GetNetworkError)
+
+	CreateSubnetWithContext(ctx context.Context, options CreateSubnetOptions) (*Subnet, CreateSubnetError)
+	DeleteSubnetWithContext(ctx context.Context, networkID string, subnetID string) DeleteSubnetError
+	ListSubnetsWithContext(ctx context.Context, networkID string) ([]Subnet, ListSubnetsError)
+	GetSubnetWithContext(ctx context.Context, networkID, subnetID string) (*Subnet, GetSubnetError)
 }
 
 //NetworkManager defines networking functions a anyclouds provider must provide
 type NetworkManager interface {
+	NetworkManagerWithContext
 	CreateNetwork(options CreateNetworkOptions) (*Network, CreateNetworkError)
 	DeleteNetwork(id string) DeleteNetworkError
 	ListNetworks() ([]Network, ListNetworksError)
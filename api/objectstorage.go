@@ -0,0 +1,259 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+//Bucket defines object storage bucket properties
+type Bucket struct {
+	//ID identifier of the bucket, all the providers identify buckets by their name
+	ID   string
+	Name string
+}
+
+//Object defines the properties of an object stored in a bucket
+type Object struct {
+	BucketID    string
+	Key         string
+	Size        int64
+	ContentType string
+	//ETag opaque identifier of the content of the object, it changes when the object is replaced
+	ETag         string
+	LastModified time.Time
+}
+
+//ObjectReader streams the content of an object, it must be closed by the caller
+type ObjectReader struct {
+	Object
+	io.ReadCloser
+}
+
+//CreateBucketOptions defines options to use when creating a bucket
+type CreateBucketOptions struct {
+	//Name of the bucket, it must be 3 to 63 lower case letters, digits and hyphens
+	Name string
+}
+
+//PutObjectOptions defines options to use when storing an object
+type PutObjectOptions struct {
+	BucketID string
+	Key      string
+	//Content of the object, it is read until EOF
+	Content io.Reader
+	//ContentType MIME type of the object, application/octet-stream is used if ContentType is empty
+	ContentType string
+}
+
+//ListObjectsOptions defines options to use when listing objects
+type ListObjectsOptions struct {
+	BucketID string
+	//Prefix lists only the objects whose key starts with Prefix
+	Prefix string
+}
+
+//PresignURLOptions defines the operation a pre-signed URL grants
+type PresignURLOptions struct {
+	BucketID string
+	Key      string
+	//Method http.MethodGet to download the object or http.MethodPut to upload it
+	//Uploads to Azure Blob Storage must carry the x-ms-blob-type: BlockBlob header, the other providers ignore it
+	Method string
+	//Expiry validity duration of the URL
+	Expiry time.Duration
+}
+
+//ObjectStorageManagerWithContext defines the context aware version of ObjectStorageManager functions
+type ObjectStorageManagerWithContext interface {
+	CreateBucketWithContext(ctx context.Context, options CreateBucketOptions) (*Bucket, CreateBucketError)
+	DeleteBucketWithContext(ctx context.Context, id string) DeleteBucketError
+	ListBucketsWithContext(ctx context.Context) ([]Bucket, ListBucketsError)
+	GetBucketWithContext(ctx context.Context, id string) (*Bucket, GetBucketError)
+	PutObjectWithContext(ctx context.Context, options PutObjectOptions) (*Object, PutObjectError)
+	GetObjectWithContext(ctx context.Context, bucketID string, key string) (*ObjectReader, GetObjectError)
+	ListObjectsWithContext(ctx context.Context, options ListObjectsOptions) ([]Object, ListObjectsError)
+	DeleteObjectWithContext(ctx context.Context, bucketID string, key string) DeleteObjectError
+	PresignURLWithContext(ctx context.Context, options PresignURLOptions) (string, PresignURLError)
+}
+
+//ObjectStorageManager defines object storage management functions an anyclouds provider must provide
+type ObjectStorageManager interface {
+	ObjectStorageManagerWithContext
+	CreateBucket(options CreateBucketOptions) (*Bucket, CreateBucketError)
+	//DeleteBucket deletes the bucket and its objects
+	DeleteBucket(id string) DeleteBucketError
+	ListBuckets() ([]Bucket, ListBucketsError)
+	GetBucket(id string) (*Bucket, GetBucketError)
+	//PutObject creates the object or replaces it if it already exists
+	PutObject(options PutObjectOptions) (*Object, PutObjectError)
+	//GetObject returns a reader streaming the content of the object
+	GetObject(bucketID string, key string) (*ObjectReader, GetObjectError)
+	//ListObjects lists the objects of the bucket sorted by key
+	ListObjects(options ListObjectsOptions) ([]Object, ListObjectsError)
+	DeleteObject(bucketID string, key string) DeleteObjectError
+	//PresignURL returns a URL granting the operation defined by options without credentials until it expires
+	PresignURL(options PresignURLOptions) (string, PresignURLError)
+}
+
+//CreateBucketError create bucket error type
+type CreateBucketError interface {
+	Error() string
+}
+
+//NewCreateBucketError creates a new CreateBucketError
+func NewCreateBucketError(cause error, options CreateBucketOptions) CreateBucketError {
+	if cause == nil {
+		return nil
+	}
+	return NewErrorStack(cause, "error creating bucket", options)
+}
+
+//DeleteBucketError delete bucket error type
+type DeleteBucketError interface {
+	Error() string
+}
+
+//NewDeleteBucketError creates a new DeleteBucketError
+func NewDeleteBucketError(cause error, id string) DeleteBucketError {
+	if cause == nil {
+		return nil
+	}
+	return NewErrorStack(cause, "error deleting bucket", id)
+}
+
+//ListBucketsError list buckets error type
+type ListBucketsError interface {
+	Error() string
+}
+
+//NewListBucketsError creates a new ListBucketsError
+func NewListBucketsError(cause error) ListBucketsError {
+	if cause == nil {
+		return nil
+	}
+	return NewErrorStack(cause, "error listing buckets")
+}
+
+//GetBucketError get bucket error type
+type GetBucketError interface {
+	Error() string
+}
+
+//NewGetBucketError creates a new GetBucketError
+func NewGetBucketError(cause error, id string) GetBucketError {
+	if cause == nil {
+		return nil
+	}
+	return NewErrorStack(cause, "error getting bucket", id)
+}
+
+//PutObjectError put object error type
+type PutObjectError interface {
+	Error() string
+}
+
+//NewPutObjectError creates a new PutObjectError
+func NewPutObjectError(cause error, options PutObjectOptions) PutObjectError {
+	if cause == nil {
+		return nil
+	}
+	options.Content = nil
+	return NewErrorStack(cause, "error putting object", options)
+}
+
+//GetObjectError get object error type
+type GetObjectError interface {
+	Error() string
+}
+
+//NewGetObjectError creates a new GetObjectError
+func NewGetObjectError(cause error, bucketID string, key string) GetObjectError {
+	if cause == nil {
+		return nil
+	}
+	return NewErrorStack(cause, "error getting object", bucketID, key)
+}
+
+//ListObjectsError list objects error type
+type ListObjectsError interface {
+	Error() string
+}
+
+//NewListObjectsError creates a new ListObjectsError
+func NewListObjectsError(cause error, options ListObjectsOptions) ListObjectsError {
+	if cause == nil {
+		return nil
+	}
+	return NewErrorStack(cause, "error listing objects", options)
+}
+
+//DeleteObjectError delete object error type
+type DeleteObjectError interface {
+	Error() string
+}
+
+//NewDeleteObjectError creates a new DeleteObjectError
+func NewDeleteObjectError(cause error, bucketID string, key string) DeleteObjectError {
+	if cause == nil {
+		return nil
+	}
+	return NewErrorStack(cause, "error deleting object", bucketID, key)
+}
+
+//PresignURLError presign URL error type
+type PresignURLError interface {
+	Error() string
+}
+
+//NewPresignURLError creates a new PresignURLError
+func NewPresignURLError(cause error, options PresignURLOptions) PresignURLError {
+	if cause == nil {
+		return nil
+	}
+	return NewErrorStack(cause, "error pre-signing URL", options)
+}
+
+//DefaultContentType content type of the objects stored without content type
+const DefaultContentType = "application/octet-stream"
+
+//CheckBucketOptions checks that the name of the bucket is valid for all the providers
+func CheckBucketOptions(options *CreateBucketOptions) error {
+	name := options.Name
+	valid := len(name) >= 3 && len(name) <= 63 && name[0] != '-' && name[len(name)-1] != '-' && !strings.Contains(name, "--")
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-') {
+			valid = false
+		}
+	}
+	if !valid {
+		return WithKind(fmt.Errorf("invalid bucket name %q", name), ErrInvalidArgument)
+	}
+	return nil
+}
+
+//CheckObjectKey checks that key can be used as object key by all the providers
+func CheckObjectKey(key string) error {
+	if key == "" || len(key) > 1024 || strings.HasPrefix(key, "/") {
+		return WithKind(fmt.Errorf("invalid object key %q", key), ErrInvalidArgument)
+	}
+	return nil
+}
+
+//CheckPresignURLOptions checks the key, the method and the expiry of options
+func CheckPresignURLOptions(options *PresignURLOptions) error {
+	err := CheckObjectKey(options.Key)
+	if err != nil {
+		return err
+	}
+	if options.Method != http.MethodGet && options.Method != http.MethodPut {
+		return WithKind(fmt.Errorf("unsupported pre-signed URL method %q", options.Method), ErrInvalidArgument)
+	}
+	if options.Expiry <= 0 || options.Expiry > 7*24*time.Hour {
+		return WithKind(fmt.Errorf("pre-signed URL expiry must be between 1s and 7 days"), ErrInvalidArgument)
+	}
+	return nil
+}
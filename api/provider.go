@@ -3,6 +3,7 @@ package api
 import "io"
 
 //Provider implement api.Provider for Provider
+//Each manager exposes context aware XxxWithContext methods, methods without context use context.Background()
 type Provider interface {
 	Init(config io.Reader, format string) error
 	GetNetworkManager() NetworkManager
@@ -13,4 +14,16 @@ type Provider interface {
 	GetVolumeManager() VolumeManager
 	GetPublicIPAddressManager() PublicIPManager
 	GetNetworkInterfaceManager() NetworkInterfaceManager
+	GetTagManager() TagManager
+	GetSnapshotManager() SnapshotManager
+	GetKeyPairManager() KeyPairManager
+	GetLoadBalancerManager() LoadBalancerManager
+	GetDNSManager() DNSManager
+	GetObjectStorageManager() ObjectStorageManager
+	GetServerGroupManager() ServerGroupManager
+	GetLocationManager() LocationManager
+	GetQuotaManager() QuotaManager
+	//WithRegion returns a new provider initialized with the configuration of the provider but bound to region
+	//The error is of kind ErrNotFound if the region does not exist
+	WithRegion(region string) (Provider, error)
 }
@@ -1,5 +1,7 @@
 package api
 
+import "context"
+
 //PublicIP represent a public ip address
 type PublicIP struct {
 	ID                 string
@@ -7,6 +9,7 @@ type PublicIP struct {
 	Address            string
 	NetworkInterfaceID string
 	PrivateAddress     string
+	Tags               map[string]string
 }
 
 //CreatePublicIPOptions options that can be used to allocate a public ip address
@@ -14,6 +17,7 @@ type CreatePublicIPOptions struct {
 	Name            string
 	IPAddress       *string
 	IPAddressPoolID *string
+	Tags            map[string]string
 }
 
 //AssociatePublicIPOptions options that can be used to associate a public ip address to a server
@@ -44,8 +48,20 @@ type ListPublicIPsOptions struct {
 	ServerID *string
 }
 
+//PublicIPManagerWithContext defines the context aware version of PublicIPManager functions
+type PublicIPManagerWithContext interface {
+	ListAvailablePoolsWithContext(ctx context.Context) ([]PublicIPPool, ListAvailablePublicIPPoolsError)
+	ListWithContext(ctx context.Context, options *ListPublicIPsOptions) ([]PublicIP, ListPublicIPsError)
+	CreateWithContext(ctx context.Context, options CreatePublicIPOptions) (*PublicIP, CreatePublicIPError)
+	AssociateWithContext(ctx context.Context, options AssociatePublicIPOptions) AssociatePublicIPError
+	DissociateWithContext(ctx context.Context, id string) DissociatePublicIPError
+	DeleteWithContext(ctx context.Context, id string) DeletePublicIPError
+	GetWithContext(ctx context.Context, id string) (*PublicIP, GetPublicIPError)
+}
+
 //PublicIPManager an interface providing an abstraction to manipulate public ip addresses
 type PublicIPManager interface {
+	PublicIPManagerWithContext
 	ListAvailablePools() ([]PublicIPPool, ListAvailablePublicIPPoolsError)
 	List(options *ListPublicIPsOptions) ([]PublicIP, ListPublicIPsError)
 	Create(options CreatePublicIPOptions) (*PublicIP, CreatePublicIPError)
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"context"
+	"fmt"
+)
+
+//UnknownLimit value of the fields of a Limit the provider does not enforce or does not expose
+const UnknownLimit = -1
+
+//Limit defines the maximum and the current usage of a resource
+type Limit struct {
+	//Max maximum usage allowed, UnknownLimit if the resource is not limited or if its limit is not exposed
+	Max int
+	//Used current usage, UnknownLimit if it is not exposed
+	Used int
+}
+
+//Remaining returns the usage still allowed, UnknownLimit if Max or Used is unknown
+func (l Limit) Remaining() int {
+	if l.Max == UnknownLimit || l.Used == UnknownLimit {
+		return UnknownLimit
+	}
+	if l.Used > l.Max {
+		return 0
+	}
+	return l.Max - l.Used
+}
+
+//Limits defines the limits and the current usage of the account of a provider in its region
+type Limits struct {
+	Instances Limit
+	//Cores number of virtual CPUs
+	Cores Limit
+	//RAMSize in MB
+	RAMSize Limit
+	Volumes Limit
+	//StorageSize size of the volumes in GB
+	StorageSize Limit
+	PublicIPs   Limit
+	//SecurityGroups number of security groups
+	SecurityGroups Limit
+}
+
+//QuotaManagerWithContext defines the context aware version of QuotaManager functions
+type QuotaManagerWithContext interface {
+	GetLimitsWithContext(ctx context.Context) (*Limits, GetLimitsError)
+}
+
+//QuotaManager defines quota inspection functions an anyclouds provider must provide
+type QuotaManager interface {
+	QuotaManagerWithContext
+	//GetLimits returns the limits and the current usage of the account
+	GetLimits() (*Limits, GetLimitsError)
+}
+
+//GetLimitsError get limits error type
+type GetLimitsError interface {
+	Error() string
+}
+
+//NewGetLimitsError creates a new GetLimitsError
+func NewGetLimitsError(cause error) GetLimitsError {
+	if cause == nil {
+		return nil
+	}
+	return NewErrorStack(cause, "error getting limits")
+}
+
+//CheckServerLimits checks that limits allow the creation of count servers of template tpl
+//The error is of kind ErrQuotaExceeded if a known limit would be exceeded
+func CheckServerLimits(limits *Limits, tpl *ServerTemplate, count int) error {
+	check := func(name string, l Limit, needed int) error {
+		if r := l.Remaining(); r != UnknownLimit && r < needed {
+			return WithKind(fmt.Errorf("%s limit exceeded: %d needed, %d remaining", name, needed, r), ErrQuotaExceeded)
+		}
+		return nil
+	}
+	if err := check("instances", limits.Instances, count); err != nil {
+		return err
+	}
+	if err := check("cores", limits.Cores, count*tpl.NumberOfCPUCore); err != nil {
+		return err
+	}
+	return check("RAM", limits.RAMSize, count*tpl.RAMSize)
+}
@@ -0,0 +1,172 @@
+package api
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+//RedactedValue value used in place of sensitive data in error messages
+const RedactedValue = "[REDACTED]"
+
+//Redactor is implemented by types holding sensitive data
+//Redact returns a copy of the value where sensitive data are masked, it is used when the value is formatted in an error message
+type Redactor interface {
+	Redact() interface{}
+}
+
+//Redact returns a copy of data where sensitive data are masked
+//Struct fields tagged with `redact:"true"` are replaced by RedactedValue unless they are empty and values implementing Redactor are replaced by the result of Redact
+func Redact(data interface{}) interface{} {
+	return redactValue(reflect.ValueOf(data))
+}
+
+var (
+	redactorType      = reflect.TypeOf((*Redactor)(nil)).Elem()
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+//redactedField field of a redacted struct
+type redactedField struct {
+	name  string
+	value interface{}
+}
+
+//redactedStruct redacted struct, fields are marshalled in declaration order
+type redactedStruct []redactedField
+
+//MarshalJSON marshals the redacted struct as a JSON object
+func (s redactedStruct) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range s {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(f.name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func isRedacted(f reflect.StructField) bool {
+	return f.Tag.Get("redact") == "true"
+}
+
+//fieldName returns the JSON name of the field and false if the field is not marshalled
+func fieldName(f reflect.StructField) (string, bool) {
+	if f.PkgPath != "" && !f.Anonymous {
+		return "", false
+	}
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	if name := strings.Split(tag, ",")[0]; name != "" {
+		return name, true
+	}
+	return f.Name, true
+}
+
+func redactStruct(v reflect.Value) redactedStruct {
+	var res redactedStruct
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, ok := fieldName(f)
+		if !ok {
+			continue
+		}
+		fv := v.Field(i)
+		if isRedacted(f) {
+			if fv.IsZero() {
+				res = append(res, redactedField{name: name, value: nil})
+			} else {
+				res = append(res, redactedField{name: name, value: RedactedValue})
+			}
+			continue
+		}
+		if f.Anonymous && f.Tag.Get("json") == "" {
+			//fields of embedded structs are promoted as JSON does
+			ft := fv
+			if ft.Kind() == reflect.Ptr {
+				if ft.IsNil() {
+					continue
+				}
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct && !ft.Type().Implements(redactorType) {
+				res = append(res, redactStruct(ft)...)
+				continue
+			}
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		res = append(res, redactedField{name: name, value: redactValue(fv)})
+	}
+	return res
+}
+
+func redactValue(v reflect.Value) interface{} {
+	if !v.IsValid() || !v.CanInterface() {
+		return nil
+	}
+	if v.Type().Implements(redactorType) {
+		if v.Kind() == reflect.Ptr && v.IsNil() {
+			return nil
+		}
+		return v.Interface().(Redactor).Redact()
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return redactValue(v.Elem())
+	case reflect.Struct:
+		if v.Type().Implements(jsonMarshalerType) || v.Type().Implements(textMarshalerType) {
+			return v.Interface()
+		}
+		return redactStruct(v)
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil
+		}
+		fallthrough
+	case reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Interface()
+		}
+		res := make([]interface{}, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			res[i] = redactValue(v.Index(i))
+		}
+		return res
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		res := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			res[fmt.Sprint(iter.Key().Interface())] = redactValue(iter.Value())
+		}
+		return res
+	}
+	return v.Interface()
+}
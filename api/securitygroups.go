@@ -1,5 +1,7 @@
 package api
 
+import "context"
+
 //Protocol valid options are empty string (any protocol), tcp or upd
 type Protocol string
 
@@ -66,6 +68,7 @@ type SecurityGroup struct {
 	Name      string
 	NetworkID string
 	Rules     []SecurityRule
+	Tags      map[string]string
 }
 
 //SecurityGroupOptions defines security groups properties
@@ -73,6 +76,7 @@ type SecurityGroupOptions struct {
 	Name        string
 	Description string
 	NetworkID   string
+	Tags        map[string]string
 }
 
 //AttachSecurityGroupOptions options that can be used to attach a security group to a network interface
@@ -84,8 +88,27 @@ type AttachSecurityGroupOptions struct {
 	IPAddress       *string
 }
 
+//SecurityGroupManagerWithContext defines the context aware version of SecurityGroupManager functions
+type SecurityGroupManagerWithContext interface {
+	//Create a security group
+	CreateWithContext(ctx context.Context, options SecurityGroupOptions) (*SecurityGroup, CreateSecurityGroupError)
+	//Delete a security group
+	DeleteWithContext(ctx context.Context, id string) DeleteSecurityGroupError
+	//List security groups
+	ListWithContext(ctx context.Context) ([]SecurityGroup, ListSecurityGroupsError)
+	//Get security group
+	GetWithContext(ctx context.Context, id string) (*SecurityGroup, GetSecurityGroupError)
+	//Attach a security group to a server
+	AttachWithContext(ctx context.Context, options AttachSecurityGroupOptions) AttachSecurityGroupError
+	//Add a rule to a security group
+	AddSecurityRuleWithContext(ctx context.Context, options AddSecurityRuleOptions) (*SecurityRule, AddSecurityRuleError)
+	//Delete a rule
+	RemoveSecurityRuleWithContext(ctx context.Context, groupID, ruleID string) RemoveSecurityRuleError
+}
+
 //SecurityGroupManager defines security group management functions a anyclouds provider must provide
 type SecurityGroupManager interface {
+	SecurityGroupManagerWithContext
 	//Create a security group
 	Create(options SecurityGroupOptions) (*SecurityGroup, CreateSecurityGroupError)
 	//Delete a security group
@@ -109,6 +132,9 @@ type CreateSecurityGroupError interface {
 
 //NewCreateSecurityGroupError creates a new CreateSecurityGroupError
 func NewCreateSecurityGroupError(cause error, options SecurityGroupOptions) CreateSecurityGroupError {
+	if cause == nil {
+		return nil
+	}
 	return NewErrorStack(cause, "error creating security group", options)
 }
 
@@ -119,6 +145,9 @@ type DeleteSecurityGroupError interface {
 
 //NewDeleteSecurityGroupError creates a new DeleteSecurityGroupError
 func NewDeleteSecurityGroupError(cause error, id string) DeleteSecurityGroupError {
+	if cause == nil {
+		return nil
+	}
 	return NewErrorStack(cause, "error deleting security group", id)
 }
 
@@ -129,6 +158,9 @@ type ListSecurityGroupsError interface {
 
 //NewListSecurityGroupsError creates a new ListSecurityGroupsError
 func NewListSecurityGroupsError(cause error) ListSecurityGroupsError {
+	if cause == nil {
+		return nil
+	}
 	return NewErrorStack(cause, "error listing security groups")
 }
 
@@ -139,6 +171,9 @@ type GetSecurityGroupError interface {
 
 //NewGetSecurityGroupError creates a new GetSecurityGroupError
 func NewGetSecurityGroupError(cause error, id string) GetSecurityGroupError {
+	if cause == nil {
+		return nil
+	}
 	return NewErrorStack(cause, "error getting security group", id)
 }
 
@@ -149,6 +184,9 @@ type AttachSecurityGroupError interface {
 
 //NewAttachSecurityGroupError creates a new AttachSecurityGroupError
 func NewAttachSecurityGroupError(cause error, options AttachSecurityGroupOptions) AttachSecurityGroupError {
+	if cause == nil {
+		return nil
+	}
 	return NewErrorStack(cause, "error attaching security group", options)
 }
 
@@ -169,6 +207,9 @@ type AddSecurityRuleError interface {
 
 //NewAddSecurityRuleError creates a new AddSecurityRuleError
 func NewAddSecurityRuleError(cause error, options AddSecurityRuleOptions) AddSecurityRuleError {
+	if cause == nil {
+		return nil
+	}
 	return NewErrorStack(cause, "error adding security rule", options)
 }
 
@@ -179,5 +220,8 @@ type RemoveSecurityRuleError interface {
 
 //NewRemoveSecurityRuleError creates a new RemoveSecurityRuleError
 func NewRemoveSecurityRuleError(cause error, id string, ruleID string) RemoveSecurityRuleError {
+	if cause == nil {
+		return nil
+	}
 	return NewErrorStack(cause, "error removing security rule", id, ruleID)
 }
@@ -0,0 +1,171 @@
+package api
+
+import (
+	"context"
+	"fmt"
+)
+
+//ServerGroup defines the properties of a group of servers created from the same launch specification
+type ServerGroup struct {
+	ID   string
+	Name string
+	//MinSize and MaxSize bounds of DesiredSize
+	MinSize int
+	MaxSize int
+	//DesiredSize number of servers the provider keeps alive
+	DesiredSize int
+	//ServerIDs identifiers of the servers of the group sorted in ascending order
+	ServerIDs []string
+	Tags      map[string]string
+}
+
+//ServerGroupSize defines the number of servers of a group and its bounds
+type ServerGroupSize struct {
+	MinSize     int
+	MaxSize     int
+	DesiredSize int
+}
+
+//CreateServerGroupOptions defines options to use when creating a server group
+type CreateServerGroupOptions struct {
+	//Name of the group, it must be 1 to 64 letters, digits and hyphens, the servers of the group are named after it
+	Name string
+	//Spec launch specification of the servers of the group
+	//Spec.Name is ignored, Spec.Subnets must contain a single subnet and leasing options are not supported
+	Spec CreateServerOptions
+	Size ServerGroupSize
+	Tags map[string]string
+}
+
+//ServerGroupManagerWithContext defines the context aware version of ServerGroupManager functions
+type ServerGroupManagerWithContext interface {
+	CreateWithContext(ctx context.Context, options CreateServerGroupOptions) (*ServerGroup, CreateServerGroupError)
+	DeleteWithContext(ctx context.Context, id string) DeleteServerGroupError
+	ListWithContext(ctx context.Context) ([]ServerGroup, ListServerGroupsError)
+	GetWithContext(ctx context.Context, id string) (*ServerGroup, GetServerGroupError)
+	ScaleWithContext(ctx context.Context, id string, size ServerGroupSize) (*ServerGroup, ScaleServerGroupError)
+	ReplaceServerWithContext(ctx context.Context, id string, serverID string) (*ServerGroup, ReplaceServerGroupServerError)
+}
+
+//ServerGroupManager defines server group management functions an anyclouds provider must provide
+//The provider keeps DesiredSize servers alive in a group: servers that fail or are deleted are replaced
+type ServerGroupManager interface {
+	ServerGroupManagerWithContext
+	//Create returns once the group has DesiredSize servers
+	Create(options CreateServerGroupOptions) (*ServerGroup, CreateServerGroupError)
+	//Delete deletes the group and its servers
+	Delete(id string) DeleteServerGroupError
+	List() ([]ServerGroup, ListServerGroupsError)
+	Get(id string) (*ServerGroup, GetServerGroupError)
+	//Scale changes the size of the group and returns once the group has the new DesiredSize servers
+	Scale(id string, size ServerGroupSize) (*ServerGroup, ScaleServerGroupError)
+	//ReplaceServer deletes the server identified by serverID and returns once the group has created its replacement
+	ReplaceServer(id string, serverID string) (*ServerGroup, ReplaceServerGroupServerError)
+}
+
+//CreateServerGroupError create server group error type
+type CreateServerGroupError interface {
+	Error() string
+}
+
+//NewCreateServerGroupError creates a new CreateServerGroupError
+func NewCreateServerGroupError(cause error, options CreateServerGroupOptions) CreateServerGroupError {
+	if cause == nil {
+		return nil
+	}
+	return NewErrorStack(cause, "error creating server group", options)
+}
+
+//DeleteServerGroupError delete server group error type
+type DeleteServerGroupError interface {
+	Error() string
+}
+
+//NewDeleteServerGroupError creates a new DeleteServerGroupError
+func NewDeleteServerGroupError(cause error, id string) DeleteServerGroupError {
+	if cause == nil {
+		return nil
+	}
+	return NewErrorStack(cause, "error deleting server group", id)
+}
+
+//ListServerGroupsError list server groups error type
+type ListServerGroupsError interface {
+	Error() string
+}
+
+//NewListServerGroupsError creates a new ListServerGroupsError
+func NewListServerGroupsError(cause error) ListServerGroupsError {
+	if cause == nil {
+		return nil
+	}
+	return NewErrorStack(cause, "error listing server groups")
+}
+
+//GetServerGroupError get server group error type
+type GetServerGroupError interface {
+	Error() string
+}
+
+//NewGetServerGroupError creates a new GetServerGroupError
+func NewGetServerGroupError(cause error, id string) GetServerGroupError {
+	if cause == nil {
+		return nil
+	}
+	return NewErrorStack(cause, "error getting server group", id)
+}
+
+//ScaleServerGroupError scale server group error type
+type ScaleServerGroupError interface {
+	Error() string
+}
+
+//NewScaleServerGroupError creates a new ScaleServerGroupError
+func NewScaleServerGroupError(cause error, id string, size ServerGroupSize) ScaleServerGroupError {
+	if cause == nil {
+		return nil
+	}
+	return NewErrorStack(cause, "error scaling server group", id, size)
+}
+
+//ReplaceServerGroupServerError replace server group server error type
+type ReplaceServerGroupServerError interface {
+	Error() string
+}
+
+//NewReplaceServerGroupServerError creates a new ReplaceServerGroupServerError
+func NewReplaceServerGroupServerError(cause error, id string, serverID string) ReplaceServerGroupServerError {
+	if cause == nil {
+		return nil
+	}
+	return NewErrorStack(cause, "error replacing server of server group", id, serverID)
+}
+
+//CheckServerGroupSize checks that 0 <= MinSize <= DesiredSize <= MaxSize and that MaxSize is not 0
+func CheckServerGroupSize(size ServerGroupSize) error {
+	if size.MinSize < 0 || size.MinSize > size.DesiredSize || size.DesiredSize > size.MaxSize || size.MaxSize == 0 {
+		return WithKind(fmt.Errorf("invalid server group size %d <= %d <= %d", size.MinSize, size.DesiredSize, size.MaxSize), ErrInvalidArgument)
+	}
+	return nil
+}
+
+//CheckServerGroupOptions checks the name, the launch specification and the size of a server group
+func CheckServerGroupOptions(options *CreateServerGroupOptions) error {
+	name := options.Name
+	valid := len(name) >= 1 && len(name) <= 64
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-') {
+			valid = false
+		}
+	}
+	if !valid {
+		return WithKind(fmt.Errorf("invalid server group name %q", name), ErrInvalidArgument)
+	}
+	if len(options.Spec.Subnets) != 1 {
+		return WithKind(fmt.Errorf("the servers of a group must be attached to a single subnet"), ErrInvalidArgument)
+	}
+	if options.Spec.LowPriorityServerOptions != nil || options.Spec.ReservedServerOptions != nil {
+		return WithKind(fmt.Errorf("leasing options are not supported by server groups"), ErrInvalidArgument)
+	}
+	return CheckServerGroupSize(options.Size)
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"github.com/SebastienDorgan/anyclouds/sshutils"
 	"io"
 	"time"
@@ -47,6 +48,7 @@ type Server struct {
 	CreatedAt     time.Time
 	LeasingType   LeasingType
 	LeaseDuration time.Duration
+	Tags          map[string]string
 }
 
 //LeasingType type of leasing
@@ -63,19 +65,34 @@ const (
 
 //CreateServerOptions defines options to use when creating an Server
 type CreateServerOptions struct {
-	Name                     string
-	TemplateID               string
-	ImageID                  string
-	DefaultSecurityGroup     string
-	Subnets                  []Subnet
-	BootstrapScript          io.Reader
-	KeyPair                  sshutils.KeyPair
+	Name                 string
+	TemplateID           string
+	ImageID              string
+	DefaultSecurityGroup string
+	Subnets              []Subnet
+	BootstrapScript      io.Reader `redact:"true"`
+	KeyPair              sshutils.KeyPair
+	//KeyPairName name of a key pair imported with the KeyPairManager, KeyPair is ignored if KeyPairName is not empty
+	KeyPairName              string
 	LowPriorityServerOptions *LowPriorityServerOptions
 	ReservedServerOptions    *ReservedServerOptions
+	Tags                     map[string]string
+}
+
+//ServerManagerWithContext defines the context aware version of ServerManager functions
+type ServerManagerWithContext interface {
+	CreateWithContext(ctx context.Context, options CreateServerOptions) (*Server, CreateServerError)
+	DeleteWithContext(ctx context.Context, id string) DeleteServerError
+	ListWithContext(ctx context.Context) ([]Server, ListServersError)
+	GetWithContext(ctx context.Context, id string) (*Server, GetServerError)
+	StartWithContext(ctx context.Context, id string) StartServerError
+	StopWithContext(ctx context.Context, id string) StopServerError
+	ResizeWithContext(ctx context.Context, id string, templateID string) ResizeServerError
 }
 
 //ServerManager defines Server management functions an anyclouds provider must provide
 type ServerManager interface {
+	ServerManagerWithContext
 	Create(options CreateServerOptions) (*Server, CreateServerError)
 	Delete(id string) DeleteServerError
 	List() ([]Server, ListServersError)
@@ -92,6 +109,9 @@ type CreateServerError interface {
 
 //NewCreateServerError creates a new CreateServerError
 func NewCreateServerError(cause error, options CreateServerOptions) CreateServerError {
+	if cause == nil {
+		return nil
+	}
 	return NewErrorStack(cause, "error creating server", options)
 }
 
@@ -102,6 +122,9 @@ type DeleteServerError interface {
 
 //NewDeleteServerError creates a new DeleteServerError
 func NewDeleteServerError(cause error, id string) DeleteServerError {
+	if cause == nil {
+		return nil
+	}
 	return NewErrorStack(cause, "error deleting server", id)
 }
 
@@ -112,6 +135,9 @@ type ListServersError interface {
 
 //NewListServersError creates a new ListServersError
 func NewListServersError(cause error) ListServersError {
+	if cause == nil {
+		return nil
+	}
 	return NewErrorStack(cause, "error listing servers")
 }
 
@@ -122,6 +148,9 @@ type GetServerError interface {
 
 //NewGetServerError creates a new GetServerError
 func NewGetServerError(cause error, id string) GetServerError {
+	if cause == nil {
+		return nil
+	}
 	return NewErrorStack(cause, "error getting server", id)
 }
 
@@ -132,6 +161,9 @@ type StartServerError interface {
 
 //NewStartServerError creates a new StartServerError
 func NewStartServerError(cause error, id string) StartServerError {
+	if cause == nil {
+		return nil
+	}
 	return NewErrorStack(cause, "error starting server", id)
 }
 
@@ -142,6 +174,9 @@ type StopServerError interface {
 
 //NewStopServerError creates a new StopServerError
 func NewStopServerError(cause error, id string) StopServerError {
+	if cause == nil {
+		return nil
+	}
 	return NewErrorStack(cause, "error stopping server", id)
 }
 
@@ -152,5 +187,8 @@ type ResizeServerError interface {
 
 //NewResizeServerError creates a new ResizeServerError
 func NewResizeServerError(cause error, id string, templateID string) ResizeServerError {
+	if cause == nil {
+		return nil
+	}
 	return NewErrorStack(cause, "error resizing server", id, templateID)
 }
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"context"
+	"time"
+)
+
+//SnapshotState state of a snapshot
+type SnapshotState string
+
+const (
+	//SnapshotPending the snapshot is being created
+	SnapshotPending SnapshotState = "pending"
+	//SnapshotReady the snapshot can be used to create volumes
+	SnapshotReady SnapshotState = "ready"
+	//SnapshotInError the snapshot creation failed
+	SnapshotInError SnapshotState = "error"
+	//SnapshotUnknownState the state of the snapshot is unknown
+	SnapshotUnknownState SnapshotState = "unknown"
+)
+
+//Snapshot defines point in time copy of a volume
+type Snapshot struct {
+	ID        string
+	Name      string
+	VolumeID  string
+	Size      int64
+	State     SnapshotState
+	CreatedAt time.Time
+}
+
+//CreateSnapshotOptions defines options to use when creating a snapshot
+type CreateSnapshotOptions struct {
+	Name     string
+	VolumeID string
+}
+
+//CreateVolumeFromSnapshotOptions defines options to use when creating a volume from a snapshot
+type CreateVolumeFromSnapshotOptions struct {
+	SnapshotID string
+	Name       string
+	//Size of the volume, the size of the snapshot is used if Size is 0
+	Size        int64
+	MinIOPS     int64
+	MinDataRate int64
+	Tags        map[string]string
+}
+
+//SnapshotManagerWithContext defines the context aware version of SnapshotManager functions
+type SnapshotManagerWithContext interface {
+	CreateWithContext(ctx context.Context, options CreateSnapshotOptions) (*Snapshot, CreateSnapshotError)
+	DeleteWithContext(ctx context.Context, id string) DeleteSnapshotError
+	ListWithContext(ctx context.Context) ([]Snapshot, ListSnapshotsError)
+	GetWithContext(ctx context.Context, id string) (*Snapshot, GetSnapshotError)
+	CreateVolumeFromSnapshotWithContext(ctx context.Context, options CreateVolumeFromSnapshotOptions) (*Volume, CreateVolumeFromSnapshotError)
+}
+
+//SnapshotManager defines volume snapshot management functions an anyclouds provider must provide
+//Create returns once the snapshot is ready
+type SnapshotManager interface {
+	SnapshotManagerWithContext
+	Create(options CreateSnapshotOptions) (*Snapshot, CreateSnapshotError)
+	Delete(id string) DeleteSnapshotError
+	List() ([]Snapshot, ListSnapshotsError)
+	Get(id string) (*Snapshot, GetSnapshotError)
+	CreateVolumeFromSnapshot(options CreateVolumeFromSnapshotOptions) (*Volume, CreateVolumeFromSnapshotError)
+}
+
+//CreateSnapshotError create snapshot error type
+type CreateSnapshotError interface {
+	Error() string
+}
+
+//NewCreateSnapshotError creates a new CreateSnapshotError
+func NewCreateSnapshotError(cause error, options CreateSnapshotOptions) CreateSnapshotError {
+	if cause == nil {
+		return nil
+	}
+	return NewErrorStack(cause, "error creating snapshot", options)
+}
+
+//DeleteSnapshotError delete snapshot error type
+type DeleteSnapshotError interface {
+	Error() string
+}
+
+//NewDeleteSnapshotError creates a new DeleteSnapshotError
+func NewDeleteSnapshotError(cause error, id string) DeleteSnapshotError {
+	if cause == nil {
+		return nil
+	}
+	return NewErrorStack(cause, "error deleting snapshot", id)
+}
+
+//ListSnapshotsError list snapshots error type
+type ListSnapshotsError interface {
+	Error() string
+}
+
+//NewListSnapshotsError creates a new ListSnapshotsError
+func NewListSnapshotsError(cause error) ListSnapshotsError {
+	if cause == nil {
+		return nil
+	}
+	return NewErrorStack(cause, "error listing snapshots")
+}
+
+//GetSnapshotError get snapshot error type
+type GetSnapshotError interface {
+	Error() string
+}
+
+//NewGetSnapshotError creates a new GetSnapshotError
+func NewGetSnapshotError(cause error, id string) GetSnapshotError {
+	if cause == nil {
+		return nil
+	}
+	return NewErrorStack(cause, "error getting snapshot", id)
+}
+
+//CreateVolumeFromSnapshotError create volume from snapshot error type
+type CreateVolumeFromSnapshotError interface {
+	Error() string
+}
+
+//NewCreateVolumeFromSnapshotError creates a new CreateVolumeFromSnapshotError
+func NewCreateVolumeFromSnapshotError(cause error, options CreateVolumeFromSnapshotOptions) CreateVolumeFromSnapshotError {
+	if cause == nil {
+		return nil
+	}
+	return NewErrorStack(cause, "error creating volume from snapshot", options)
+}
@@ -0,0 +1,97 @@
+package api
+
+import "context"
+
+//ResourceType type of a resource that can be tagged
+type ResourceType string
+
+//noinspection ALL
+const (
+	//ResourceServer a server
+	ResourceServer ResourceType = "server"
+	//ResourceVolume a volume
+	ResourceVolume ResourceType = "volume"
+	//ResourceNetwork a network
+	ResourceNetwork ResourceType = "network"
+	//ResourceSubnet a sub network
+	ResourceSubnet ResourceType = "subnet"
+	//ResourceSecurityGroup a security group
+	ResourceSecurityGroup ResourceType = "security-group"
+	//ResourcePublicIP a public ip address
+	ResourcePublicIP ResourceType = "public-ip"
+	//ResourceNetworkInterface a network interface
+	ResourceNetworkInterface ResourceType = "network-interface"
+)
+
+//TaggedResource identifies a resource by its type and its identifier
+type TaggedResource struct {
+	Type ResourceType
+	ID   string
+}
+
+//AddTagsOptions options used to add tags to a resource, existing tags with the same keys are overwritten
+type AddTagsOptions struct {
+	Resource TaggedResource
+	Tags     map[string]string
+}
+
+//RemoveTagsOptions options used to remove tags from a resource, missing keys are ignored
+type RemoveTagsOptions struct {
+	Resource TaggedResource
+	Keys     []string
+}
+
+//TagManagerWithContext defines the context aware version of TagManager functions
+type TagManagerWithContext interface {
+	AddWithContext(ctx context.Context, options AddTagsOptions) AddTagsError
+	RemoveWithContext(ctx context.Context, options RemoveTagsOptions) RemoveTagsError
+	ListWithContext(ctx context.Context, resource TaggedResource) (map[string]string, ListTagsError)
+}
+
+//TagManager defines tag management functions an anyclouds provider must provide
+//Tags are key value pairs attached to resources, they can be used for ownership, cost allocation or cleanup
+type TagManager interface {
+	TagManagerWithContext
+	Add(options AddTagsOptions) AddTagsError
+	Remove(options RemoveTagsOptions) RemoveTagsError
+	List(resource TaggedResource) (map[string]string, ListTagsError)
+}
+
+//AddTagsError add tags error type
+type AddTagsError interface {
+	Error() string
+}
+
+//NewAddTagsError creates a new AddTagsError
+func NewAddTagsError(cause error, options AddTagsOptions) AddTagsError {
+	if cause == nil {
+		return nil
+	}
+	return NewErrorStack(cause, "error adding tags", options)
+}
+
+//RemoveTagsError remove tags error type
+type RemoveTagsError interface {
+	Error() string
+}
+
+//NewRemoveTagsError creates a new RemoveTagsError
+func NewRemoveTagsError(cause error, options RemoveTagsOptions) RemoveTagsError {
+	if cause == nil {
+		return nil
+	}
+	return NewErrorStack(cause, "error removing tags", options)
+}
+
+//ListTagsError list tags error type
+type ListTagsError interface {
+	Error() string
+}
+
+//NewListTagsError creates a new ListTagsError
+func NewListTagsError(cause error, resource TaggedResource) ListTagsError {
+	if cause == nil {
+		return nil
+	}
+	return NewErrorStack(cause, "error listing tags", resource)
+}
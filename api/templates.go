@@ -1,6 +1,9 @@
 package api
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 //CPUArch enum defining CPU Architectures
 type CPUArch string
@@ -46,8 +49,15 @@ type ServerTemplate struct {
 	OneDemandPrice    float32
 }
 
+//ServerTemplateManagerWithContext defines the context aware version of ServerTemplateManager functions
+type ServerTemplateManagerWithContext interface {
+	ListWithContext(ctx context.Context) ([]ServerTemplate, ListServerTemplatesError)
+	GetWithContext(ctx context.Context, id string) (*ServerTemplate, GetServerTemplateError)
+}
+
 //ServerTemplateManager defines Server template management functions a anyclouds provider must provide
 type ServerTemplateManager interface {
+	ServerTemplateManagerWithContext
 	List() ([]ServerTemplate, ListServerTemplatesError)
 	Get(id string) (*ServerTemplate, GetServerTemplateError)
 }
@@ -59,6 +69,9 @@ type ListServerTemplatesError interface {
 
 //NewListServerTemplatesError  creates a new ListServerTemplatesError
 func NewListServerTemplatesError(cause error) ListServerTemplatesError {
+	if cause == nil {
+		return nil
+	}
 	return NewErrorStack(cause, "error listing server templates")
 }
 
@@ -69,5 +82,8 @@ type GetServerTemplateError interface {
 
 //NewGetServerTemplateError  creates a new GetServerTemplateError
 func NewGetServerTemplateError(cause error, id string) GetServerTemplateError {
+	if cause == nil {
+		return nil
+	}
 	return NewErrorStack(cause, "error get server templates", id)
 }
@@ -1,5 +1,7 @@
 package api
 
+import "context"
+
 //Volume defines volume properties
 type Volume struct {
 	ID       string
@@ -7,6 +9,7 @@ type Volume struct {
 	Size     int64
 	IOPS     int64
 	DataRate int64
+	Tags     map[string]string
 }
 
 //CreateVolumeOptions defines options to use when creating a volume
@@ -15,6 +18,7 @@ type CreateVolumeOptions struct {
 	Size        int64
 	MinIOPS     int64
 	MinDataRate int64
+	Tags        map[string]string
 }
 
 //ResizeVolumeOptions options that can be used to modify a volume
@@ -53,8 +57,21 @@ type ListAttachmentsOptions struct {
 	ServerID *string
 }
 
+//VolumeManagerWithContext defines the context aware version of VolumeManager functions
+type VolumeManagerWithContext interface {
+	CreateWithContext(ctx context.Context, options CreateVolumeOptions) (*Volume, CreateVolumeError)
+	DeleteWithContext(ctx context.Context, id string) DeleteVolumeError
+	ListWithContext(ctx context.Context) ([]Volume, ListVolumesError)
+	GetWithContext(ctx context.Context, id string) (*Volume, GetVolumeError)
+	ResizeWithContext(ctx context.Context, options ResizeVolumeOptions) (*Volume, ResizeVolumeError)
+	AttachWithContext(ctx context.Context, options AttachVolumeOptions) (*VolumeAttachment, AttachVolumeError)
+	DetachWithContext(ctx context.Context, options DetachVolumeOptions) DetachVolumeError
+	ListAttachmentsWithContext(ctx context.Context, options *ListAttachmentsOptions) ([]VolumeAttachment, ListVolumeAttachmentsError)
+}
+
 //VolumeManager defines volume management functions an anyclouds provider must provide
 type VolumeManager interface {
+	VolumeManagerWithContext
 	Create(options CreateVolumeOptions) (*Volume, CreateVolumeError)
 	Delete(id string) DeleteVolumeError
 	List() ([]Volume, ListVolumesError)
@@ -72,6 +89,9 @@ type CreateVolumeError interface {
 
 //NewCreateVolumeError creates a new CreateVolumeError
 func NewCreateVolumeError(cause error, options CreateVolumeOptions) CreateVolumeError {
+	if cause == nil {
+		return nil
+	}
 	return NewErrorStack(cause, "error creating volume", options)
 }
 
@@ -82,6 +102,9 @@ type DeleteVolumeError interface {
 
 //NewDeleteVolumeError creates a new DeleteVolumeError
 func NewDeleteVolumeError(cause error, id string) DeleteVolumeError {
+	if cause == nil {
+		return nil
+	}
 	return NewErrorStack(cause, "error deleting volume", id)
 }
 
@@ -92,6 +115,9 @@ type ListVolumesError interface {
 
 //NewListVolumesError creates a new ListVolumesError
 func NewListVolumesError(cause error) ListVolumesError {
+	if cause == nil {
+		return nil
+	}
 	return NewErrorStack(cause, "error listing volume")
 }
 
@@ -102,6 +128,9 @@ type GetVolumeError interface {
 
 //NewGetVolumeError creates a new GetVolumeError
 func NewGetVolumeError(cause error, id string) GetVolumeError {
+	if cause == nil {
+		return nil
+	}
 	return NewErrorStack(cause, "error getting volume", id)
 }
 
@@ -112,6 +141,9 @@ type ResizeVolumeError interface {
 
 //NewResizeVolumeError creates a new ResizeVolumeError
 func NewResizeVolumeError(cause error, options ResizeVolumeOptions) ResizeVolumeError {
+	if cause == nil {
+		return nil
+	}
 	return NewErrorStack(cause, "error resizing volume", options)
 }
 
@@ -122,6 +154,9 @@ type AttachVolumeError interface {
 
 //NewAttachVolumeError creates a new AttachVolumeError
 func NewAttachVolumeError(cause error, options AttachVolumeOptions) AttachVolumeError {
+	if cause == nil {
+		return nil
+	}
 	return NewErrorStack(cause, "error attaching volume", options)
 }
 
@@ -132,6 +167,9 @@ type DetachVolumeError interface {
 
 //NewDetachVolumeError creates a new DetachVolumeError
 func NewDetachVolumeError(cause error, options DetachVolumeOptions) DetachVolumeError {
+	if cause == nil {
+		return nil
+	}
 	return NewErrorStack(cause, "error detaching volume", options)
 }
 
@@ -142,5 +180,8 @@ type ListVolumeAttachmentsError interface {
 
 //NewListVolumeAttachmentsError creates a new ListVolumeAttachmentsError
 func NewListVolumeAttachmentsError(cause error, options *ListAttachmentsOptions) ListVolumeAttachmentsError {
+	if cause == nil {
+		return nil
+	}
 	return NewErrorStack(cause, "error listing attachments volume", options)
 }
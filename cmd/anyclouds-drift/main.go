@@ -0,0 +1,81 @@
+//Command anyclouds-drift reports the resources of a stack changed out of band since its state was recorded
+//
+//	anyclouds-drift -provider aws -config ~/.anyclouds/aws.json -state web.state.json
+//
+//The state file is the one written with stack.FileStore after the stack has been applied, it is locked while the resources are read
+//The exit status is 0 if no drift is detected, 2 if drifts are detected and 1 if an error occurs
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/SebastienDorgan/anyclouds/providers"
+	_ "github.com/SebastienDorgan/anyclouds/providers/all"
+	"github.com/SebastienDorgan/anyclouds/stack"
+)
+
+//run detects the drifts of the stack recorded in the state file and prints them, it returns the number of drifts detected
+func run(provider, config, format, state string, forceUnlock bool) (int, error) {
+	f, err := os.Open(config)
+	if err != nil {
+		return 0, err
+	}
+	prov, err := providers.New(provider, f, format)
+	_ = f.Close()
+	if err != nil {
+		return 0, err
+	}
+
+	store := stack.NewFileStore(state)
+	if forceUnlock {
+		err = store.ForceUnlock()
+		if err != nil {
+			return 0, err
+		}
+	}
+	err = store.Lock()
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = store.Unlock() }()
+	st, err := store.Read()
+	if err != nil {
+		return 0, err
+	}
+	drifts, err := stack.DetectDrift(prov, st)
+	if err != nil {
+		return 0, err
+	}
+	for _, d := range drifts {
+		fmt.Println(d.String())
+	}
+	return len(drifts), nil
+}
+
+func main() {
+	provider := flag.String("provider", "", "name of the provider: "+strings.Join(providers.Registered(), ", "))
+	config := flag.String("config", "", "configuration file of the provider")
+	format := flag.String("format", "", "format of the configuration file (json, yaml or toml), deduced from its extension by default")
+	state := flag.String("state", "", "state file of the stack")
+	forceUnlock := flag.Bool("force-unlock", false, "remove the lock left on the state file by a process that crashed")
+	flag.Parse()
+	if *provider == "" || *config == "" || *state == "" {
+		flag.Usage()
+		os.Exit(1)
+	}
+	if *format == "" {
+		*format = strings.TrimPrefix(filepath.Ext(*config), ".")
+	}
+	n, err := run(*provider, *config, *format, *state, *forceUnlock)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if n > 0 {
+		os.Exit(2)
+	}
+}
@@ -8,7 +8,6 @@ require (
 	github.com/Azure/go-autorest/autorest/to v0.3.0
 	github.com/Azure/go-autorest/autorest/validation v0.2.0 // indirect
 	github.com/BurntSushi/toml v0.3.1 // indirect
-	github.com/SebastienDorgan/retry v0.0.0-20190902110557-59863ce7f436
 	github.com/SebastienDorgan/talgo v0.0.0-20190901103057-247d33849fe0
 	github.com/aws/aws-sdk-go v1.17.14
 	github.com/google/uuid v1.1.1
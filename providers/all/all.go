@@ -0,0 +1,14 @@
+//Package all registers all the built-in providers
+//	import _ "github.com/SebastienDorgan/anyclouds/providers/all"
+package all
+
+import (
+	//register aws provider
+	_ "github.com/SebastienDorgan/anyclouds/providers/aws"
+	//register azure provider
+	_ "github.com/SebastienDorgan/anyclouds/providers/azure"
+	//register memory provider
+	_ "github.com/SebastienDorgan/anyclouds/providers/memory"
+	//register openstack provider
+	_ "github.com/SebastienDorgan/anyclouds/providers/openstack"
+)
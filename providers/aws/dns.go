@@ -0,0 +1,343 @@
+package aws
+
+import (
+	"context"
+	"strings"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/google/uuid"
+)
+
+//DNSManager aws implementation of api.DNSManager using Route53 public hosted zones
+type DNSManager struct {
+	Provider *Provider
+}
+
+//hostedZoneID returns the identifier of a hosted zone without its /hostedzone/ prefix
+func hostedZoneID(id *string) string {
+	return strings.TrimPrefix(aws.StringValue(id), "/hostedzone/")
+}
+
+func zone(hz *route53.HostedZone, ds *route53.DelegationSet) *api.Zone {
+	z := &api.Zone{
+		ID:   hostedZoneID(hz.Id),
+		Name: api.ZoneName(aws.StringValue(hz.Name)),
+	}
+	if ds != nil {
+		for _, ns := range ds.NameServers {
+			z.NameServers = append(z.NameServers, api.ZoneName(aws.StringValue(ns)))
+		}
+	}
+	return z
+}
+
+//record converts a record set of the zone z, it returns nil if the type of the record set is not managed
+func record(z *api.Zone, rs *route53.ResourceRecordSet) *api.Record {
+	if !api.IsRecordType(aws.StringValue(rs.Type)) || rs.AliasTarget != nil {
+		return nil
+	}
+	r := &api.Record{
+		ZoneID: z.ID,
+		Name:   api.RecordName(aws.StringValue(rs.Name), z.Name),
+		Type:   api.RecordType(aws.StringValue(rs.Type)),
+		TTL:    int(aws.Int64Value(rs.TTL)),
+	}
+	for _, rr := range rs.ResourceRecords {
+		v := aws.StringValue(rr.Value)
+		switch r.Type {
+		case api.RecordTXT:
+			v = api.UnquoteTXT(v)
+		case api.RecordCNAME:
+			v = api.ZoneName(v)
+		}
+		r.Values = append(r.Values, v)
+	}
+	return r
+}
+
+func (mgr *DNSManager) createZone(ctx context.Context, options api.CreateZoneOptions) (*api.Zone, error) {
+	err := api.CheckZoneOptions(&options)
+	if err != nil {
+		return nil, err
+	}
+	client := mgr.Provider.AWSServices.Route53Client
+	name := api.ZoneName(options.Name)
+	//Route53 allows several hosted zones with the same name
+	zones, err := client.ListHostedZonesByNameWithContext(ctx, &route53.ListHostedZonesByNameInput{
+		DNSName:  aws.String(name),
+		MaxItems: aws.String("1"),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(zones.HostedZones) > 0 && api.ZoneName(aws.StringValue(zones.HostedZones[0].Name)) == name {
+		return nil, alreadyExistsError("hosted zone %s already exists", name)
+	}
+	out, err := client.CreateHostedZoneWithContext(ctx, &route53.CreateHostedZoneInput{
+		Name:            aws.String(name),
+		CallerReference: aws.String(uuid.New().String()),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return zone(out.HostedZone, out.DelegationSet), nil
+}
+
+//CreateZoneWithContext creates a public hosted zone
+func (mgr *DNSManager) CreateZoneWithContext(ctx context.Context, options api.CreateZoneOptions) (*api.Zone, api.CreateZoneError) {
+	z, err := mgr.createZone(ctx, options)
+	if err != nil {
+		return nil, api.NewCreateZoneError(err, options)
+	}
+	return z, nil
+}
+
+//CreateZone creates a public hosted zone
+func (mgr *DNSManager) CreateZone(options api.CreateZoneOptions) (*api.Zone, api.CreateZoneError) {
+	return mgr.CreateZoneWithContext(context.Background(), options)
+}
+
+//recordSets returns the record sets of a hosted zone
+func (mgr *DNSManager) recordSets(ctx context.Context, id string) ([]*route53.ResourceRecordSet, error) {
+	var res []*route53.ResourceRecordSet
+	err := mgr.Provider.AWSServices.Route53Client.ListResourceRecordSetsPagesWithContext(ctx, &route53.ListResourceRecordSetsInput{
+		HostedZoneId: aws.String(id),
+	}, func(out *route53.ListResourceRecordSetsOutput, last bool) bool {
+		res = append(res, out.ResourceRecordSets...)
+		return true
+	})
+	return res, err
+}
+
+//changeRecordSets applies the changes to the record sets of a hosted zone
+func (mgr *DNSManager) changeRecordSets(ctx context.Context, id string, action string, sets ...*route53.ResourceRecordSet) error {
+	var changes []*route53.Change
+	for _, rs := range sets {
+		changes = append(changes, &route53.Change{
+			Action:            aws.String(action),
+			ResourceRecordSet: rs,
+		})
+	}
+	_, err := mgr.Provider.AWSServices.Route53Client.ChangeResourceRecordSetsWithContext(ctx, &route53.ChangeResourceRecordSetsInput{
+		HostedZoneId: aws.String(id),
+		ChangeBatch: &route53.ChangeBatch{
+			Changes: changes,
+		},
+	})
+	//the SDK unmarshals the errors of ChangeResourceRecordSets after the handlers of the client
+	return UnwrapAWSError(err)
+}
+
+func (mgr *DNSManager) deleteZone(ctx context.Context, id string) error {
+	z, err := mgr.getZone(ctx, id)
+	if err != nil {
+		return err
+	}
+	sets, err := mgr.recordSets(ctx, id)
+	if err != nil {
+		return err
+	}
+	//the SOA and NS records of the zone apex are deleted with the hosted zone
+	var records []*route53.ResourceRecordSet
+	for _, rs := range sets {
+		t := aws.StringValue(rs.Type)
+		if api.ZoneName(aws.StringValue(rs.Name)) == z.Name && (t == route53.RRTypeSoa || t == route53.RRTypeNs) {
+			continue
+		}
+		records = append(records, rs)
+	}
+	if len(records) > 0 {
+		err = mgr.changeRecordSets(ctx, id, route53.ChangeActionDelete, records...)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = mgr.Provider.AWSServices.Route53Client.DeleteHostedZoneWithContext(ctx, &route53.DeleteHostedZoneInput{
+		Id: aws.String(id),
+	})
+	return err
+}
+
+//DeleteZoneWithContext deletes the hosted zone identified by id and its records
+func (mgr *DNSManager) DeleteZoneWithContext(ctx context.Context, id string) api.DeleteZoneError {
+	return api.NewDeleteZoneError(mgr.deleteZone(ctx, id), id)
+}
+
+//DeleteZone deletes the hosted zone identified by id and its records
+func (mgr *DNSManager) DeleteZone(id string) api.DeleteZoneError {
+	return mgr.DeleteZoneWithContext(context.Background(), id)
+}
+
+func (mgr *DNSManager) listZones(ctx context.Context) ([]api.Zone, error) {
+	var ids []*string
+	err := mgr.Provider.AWSServices.Route53Client.ListHostedZonesPagesWithContext(ctx, &route53.ListHostedZonesInput{}, func(out *route53.ListHostedZonesOutput, last bool) bool {
+		for _, hz := range out.HostedZones {
+			if hz.Config == nil || !aws.BoolValue(hz.Config.PrivateZone) {
+				ids = append(ids, hz.Id)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	//name servers are only returned by GetHostedZone
+	zones := []api.Zone{}
+	for _, id := range ids {
+		z, err := mgr.getZone(ctx, hostedZoneID(id))
+		if err != nil {
+			return nil, err
+		}
+		zones = append(zones, *z)
+	}
+	return zones, nil
+}
+
+//ListZonesWithContext lists public hosted zones
+func (mgr *DNSManager) ListZonesWithContext(ctx context.Context) ([]api.Zone, api.ListZonesError) {
+	zones, err := mgr.listZones(ctx)
+	if err != nil {
+		return nil, api.NewListZonesError(err)
+	}
+	return zones, nil
+}
+
+//ListZones lists public hosted zones
+func (mgr *DNSManager) ListZones() ([]api.Zone, api.ListZonesError) {
+	return mgr.ListZonesWithContext(context.Background())
+}
+
+func (mgr *DNSManager) getZone(ctx context.Context, id string) (*api.Zone, error) {
+	out, err := mgr.Provider.AWSServices.Route53Client.GetHostedZoneWithContext(ctx, &route53.GetHostedZoneInput{
+		Id: aws.String(id),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return zone(out.HostedZone, out.DelegationSet), nil
+}
+
+//GetZoneWithContext returns the hosted zone identified by id
+func (mgr *DNSManager) GetZoneWithContext(ctx context.Context, id string) (*api.Zone, api.GetZoneError) {
+	z, err := mgr.getZone(ctx, id)
+	if err != nil {
+		return nil, api.NewGetZoneError(err, id)
+	}
+	return z, nil
+}
+
+//GetZone returns the hosted zone identified by id
+func (mgr *DNSManager) GetZone(id string) (*api.Zone, api.GetZoneError) {
+	return mgr.GetZoneWithContext(context.Background(), id)
+}
+
+func (mgr *DNSManager) upsertRecord(ctx context.Context, options api.UpsertRecordOptions) (*api.Record, error) {
+	resolved, err := api.ResolveRecordOptions(ctx, &mgr.Provider.PublicIPAddressManager, options)
+	if err != nil {
+		return nil, err
+	}
+	z, err := mgr.getZone(ctx, options.ZoneID)
+	if err != nil {
+		return nil, err
+	}
+	rs := &route53.ResourceRecordSet{
+		Name: aws.String(api.RecordFQDN(resolved.Name, z.Name)),
+		Type: aws.String(string(resolved.Type)),
+		TTL:  aws.Int64(int64(resolved.TTL)),
+	}
+	for _, v := range resolved.Values {
+		switch resolved.Type {
+		case api.RecordTXT:
+			v = api.QuoteTXT(v)
+		case api.RecordCNAME:
+			v = api.ZoneName(v) + "."
+		}
+		rs.ResourceRecords = append(rs.ResourceRecords, &route53.ResourceRecord{Value: aws.String(v)})
+	}
+	err = mgr.changeRecordSets(ctx, z.ID, route53.ChangeActionUpsert, rs)
+	if err != nil {
+		return nil, err
+	}
+	return record(z, rs), nil
+}
+
+//UpsertRecordWithContext creates or replaces a record set
+func (mgr *DNSManager) UpsertRecordWithContext(ctx context.Context, options api.UpsertRecordOptions) (*api.Record, api.UpsertRecordError) {
+	r, err := mgr.upsertRecord(ctx, options)
+	if err != nil {
+		return nil, api.NewUpsertRecordError(err, options)
+	}
+	return r, nil
+}
+
+//UpsertRecord creates or replaces a record set
+func (mgr *DNSManager) UpsertRecord(options api.UpsertRecordOptions) (*api.Record, api.UpsertRecordError) {
+	return mgr.UpsertRecordWithContext(context.Background(), options)
+}
+
+func (mgr *DNSManager) deleteRecord(ctx context.Context, options api.DeleteRecordOptions) error {
+	z, err := mgr.getZone(ctx, options.ZoneID)
+	if err != nil {
+		return err
+	}
+	//the record set must be deleted with its current values
+	name := api.RecordFQDN(options.Name, z.Name)
+	out, err := mgr.Provider.AWSServices.Route53Client.ListResourceRecordSetsWithContext(ctx, &route53.ListResourceRecordSetsInput{
+		HostedZoneId:    aws.String(z.ID),
+		StartRecordName: aws.String(name),
+		StartRecordType: aws.String(string(options.Type)),
+		MaxItems:        aws.String("1"),
+	})
+	if err != nil {
+		return err
+	}
+	if len(out.ResourceRecordSets) == 0 || aws.StringValue(out.ResourceRecordSets[0].Name) != name || aws.StringValue(out.ResourceRecordSets[0].Type) != string(options.Type) {
+		return notFoundError("record %s %s not found in zone %s", options.Type, options.Name, z.Name)
+	}
+	return mgr.changeRecordSets(ctx, z.ID, route53.ChangeActionDelete, out.ResourceRecordSets[0])
+}
+
+//DeleteRecordWithContext deletes a record set
+func (mgr *DNSManager) DeleteRecordWithContext(ctx context.Context, options api.DeleteRecordOptions) api.DeleteRecordError {
+	return api.NewDeleteRecordError(mgr.deleteRecord(ctx, options), options)
+}
+
+//DeleteRecord deletes a record set
+func (mgr *DNSManager) DeleteRecord(options api.DeleteRecordOptions) api.DeleteRecordError {
+	return mgr.DeleteRecordWithContext(context.Background(), options)
+}
+
+func (mgr *DNSManager) listRecords(ctx context.Context, zoneID string) ([]api.Record, error) {
+	z, err := mgr.getZone(ctx, zoneID)
+	if err != nil {
+		return nil, err
+	}
+	sets, err := mgr.recordSets(ctx, zoneID)
+	if err != nil {
+		return nil, err
+	}
+	records := []api.Record{}
+	for _, rs := range sets {
+		if r := record(z, rs); r != nil {
+			records = append(records, *r)
+		}
+	}
+	api.SortRecords(records)
+	return records, nil
+}
+
+//ListRecordsWithContext lists the A, AAAA, CNAME and TXT record sets of the hosted zone identified by zoneID
+func (mgr *DNSManager) ListRecordsWithContext(ctx context.Context, zoneID string) ([]api.Record, api.ListRecordsError) {
+	records, err := mgr.listRecords(ctx, zoneID)
+	if err != nil {
+		return nil, api.NewListRecordsError(err, zoneID)
+	}
+	return records, nil
+}
+
+//ListRecords lists the A, AAAA, CNAME and TXT record sets of the hosted zone identified by zoneID
+func (mgr *DNSManager) ListRecords(zoneID string) ([]api.Record, api.ListRecordsError) {
+	return mgr.ListRecordsWithContext(context.Background(), zoneID)
+}
@@ -0,0 +1,21 @@
+package aws_test
+
+import (
+	"testing"
+
+	"github.com/SebastienDorgan/anyclouds/tests"
+	"github.com/stretchr/testify/suite"
+)
+
+type AWSDNSManagerTestSuite struct {
+	tests.DNSManagerTestSuite
+}
+
+//SetupSuite set up DNS manager
+func (suite *AWSDNSManagerTestSuite) SetupSuite() {
+	suite.Prov = GetProvider()
+}
+
+func TestAWSDNSManagerTestSuite(t *testing.T) {
+	suite.Run(t, new(AWSDNSManagerTestSuite))
+}
@@ -0,0 +1,146 @@
+package aws
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/pkg/errors"
+)
+
+//awsError an awserr.Error annotated with its kind
+//It still implements awserr.Error so that SDK waiters and error code checks keep working
+type awsError struct {
+	cause awserr.Error
+	kind  error
+}
+
+//Error returns the message of the native AWS error
+func (e *awsError) Error() string {
+	return e.cause.Error()
+}
+
+//Code returns the code of the native AWS error
+func (e *awsError) Code() string {
+	return e.cause.Code()
+}
+
+//Message returns the message of the native AWS error
+func (e *awsError) Message() string {
+	return e.cause.Message()
+}
+
+//OrigErr returns the original error of the native AWS error
+func (e *awsError) OrigErr() error {
+	return e.cause.OrigErr()
+}
+
+//Unwrap returns the native AWS error
+func (e *awsError) Unwrap() error {
+	return e.cause
+}
+
+//Is reports whether target is the kind of the error
+func (e *awsError) Is(target error) bool {
+	return e.kind != nil && e.kind == target
+}
+
+var unauthorizedCodes = map[string]bool{
+	"AuthFailure":           true,
+	"UnauthorizedOperation": true,
+	"AccessDenied":          true,
+	"AccessDeniedException": true,
+	"InvalidClientTokenId":  true,
+	"SignatureDoesNotMatch": true,
+	"ExpiredToken":          true,
+	"OptInRequired":         true,
+}
+
+var invalidArgumentCodes = map[string]bool{
+	"MissingParameter":            true,
+	"InvalidParameter":            true,
+	"InvalidParameterValue":       true,
+	"InvalidParameterCombination": true,
+	"ValidationError":             true,
+	"ValidationException":         true,
+	"InvalidInput":                true,
+}
+
+func kindOf(code string, status int) error {
+	switch {
+	case code == "RequestLimitExceeded" || strings.HasPrefix(code, "Throttling") || code == "TooManyRequestsException":
+		return api.ErrThrottled
+	case unauthorizedCodes[code]:
+		return api.ErrUnauthorized
+	case strings.HasSuffix(code, "NotFound") || strings.HasSuffix(code, "NotFoundException") || strings.HasPrefix(code, "NoSuch"):
+		return api.ErrNotFound
+	case strings.HasSuffix(code, ".Duplicate") || strings.HasSuffix(code, "AlreadyExists") || strings.HasSuffix(code, "AlreadyExistsException"):
+		return api.ErrAlreadyExists
+	case strings.HasSuffix(code, "LimitExceeded") || strings.HasPrefix(code, "InsufficientInstanceCapacity") || code == "MaxSpotInstanceCountExceeded":
+		return api.ErrQuotaExceeded
+	case invalidArgumentCodes[code] || strings.HasSuffix(code, ".Malformed") || strings.HasPrefix(code, "InvalidParameter"):
+		return api.ErrInvalidArgument
+	}
+	switch status {
+	case http.StatusNotFound:
+		return api.ErrNotFound
+	case http.StatusConflict:
+		return api.ErrAlreadyExists
+	case http.StatusTooManyRequests:
+		return api.ErrThrottled
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return api.ErrUnauthorized
+	case http.StatusBadRequest:
+		if strings.HasPrefix(code, "Invalid") {
+			return api.ErrInvalidArgument
+		}
+	}
+	return nil
+}
+
+//UnwrapAWSError annotates err with its api error kind
+//The returned error still implements awserr.Error, errors without kind are returned unchanged
+func UnwrapAWSError(err error) error {
+	aerr, ok := err.(awserr.Error)
+	if !ok {
+		return err
+	}
+	if _, ok := err.(*awsError); ok {
+		return err
+	}
+	status := 0
+	if rf, ok := err.(awserr.RequestFailure); ok {
+		status = rf.StatusCode()
+	}
+	kind := kindOf(aerr.Code(), status)
+	if kind == nil {
+		return err
+	}
+	return &awsError{cause: aerr, kind: kind}
+}
+
+//unwrapErrorHandler request handler annotating the errors returned by AWS services with their api error kind
+//It must run after the protocol error unmarshaler of the service client
+var unwrapErrorHandler = request.NamedHandler{
+	Name: "anyclouds.UnwrapAWSError",
+	Fn: func(r *request.Request) {
+		r.Error = UnwrapAWSError(r.Error)
+	},
+}
+
+//notFoundError returns an error of kind api.ErrNotFound, used when AWS returns an empty result instead of an error
+func notFoundError(format string, args ...interface{}) error {
+	return api.WithKind(errors.Errorf(format, args...), api.ErrNotFound)
+}
+
+//invalidArgumentError returns an error of kind api.ErrInvalidArgument, used when a request is rejected before calling AWS
+func invalidArgumentError(format string, args ...interface{}) error {
+	return api.WithKind(errors.Errorf(format, args...), api.ErrInvalidArgument)
+}
+
+//alreadyExistsError returns an error of kind api.ErrAlreadyExists, used when a conflict is detected before calling AWS
+func alreadyExistsError(format string, args ...interface{}) error {
+	return api.WithKind(errors.Errorf(format, args...), api.ErrAlreadyExists)
+}
@@ -0,0 +1,40 @@
+package aws_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/SebastienDorgan/anyclouds/providers/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnwrapAWSError(t *testing.T) {
+	kinds := map[string]error{
+		"InvalidInstanceID.NotFound": api.ErrNotFound,
+		"InvalidGroup.Duplicate":     api.ErrAlreadyExists,
+		"VpcLimitExceeded":           api.ErrQuotaExceeded,
+		"RequestLimitExceeded":       api.ErrThrottled,
+		"InvalidParameterValue":      api.ErrInvalidArgument,
+		"UnauthorizedOperation":      api.ErrUnauthorized,
+		"InvalidAMIID.Malformed":     api.ErrInvalidArgument,
+		"IncorrectInstanceState":     nil,
+	}
+	for code, kind := range kinds {
+		err := aws.UnwrapAWSError(awserr.New(code, "message", nil))
+		assert.Equal(t, kind, api.ErrorKind(err), code)
+		if kind != nil {
+			assert.True(t, errors.Is(err, kind), code)
+		}
+		aerr, ok := err.(awserr.Error)
+		assert.True(t, ok)
+		assert.Equal(t, code, aerr.Code())
+	}
+	err := aws.UnwrapAWSError(awserr.NewRequestFailure(awserr.New("Unknown", "message", nil), 404, "id"))
+	assert.True(t, errors.Is(err, api.ErrNotFound))
+	var rf awserr.RequestFailure
+	assert.True(t, errors.As(err, &rf))
+	assert.Equal(t, 404, rf.StatusCode())
+	assert.Nil(t, aws.UnwrapAWSError(nil))
+}
@@ -0,0 +1,200 @@
+package fake
+
+import (
+	"net"
+
+	"github.com/SebastienDorgan/anyclouds/iputils"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+//publicPool range of the public addresses allocated by the fake (TEST-NET-2)
+const publicPool = "198.51.100.0/24"
+
+func (api *ec2API) address(allocationID *string) (*ec2.Address, error) {
+	if err := checkID("InvalidAllocationID.Malformed", "eipalloc", allocationID); err != nil {
+		return nil, err
+	}
+	addr, ok := api.addresses[aws.StringValue(allocationID)]
+	if !ok {
+		return nil, errorf("InvalidAllocationID.NotFound", "The allocation ID '%s' does not exist", aws.StringValue(allocationID))
+	}
+	return addr, nil
+}
+
+//disassociateAddress removes the association between addr and its network interface
+func (api *ec2API) disassociateAddress(addr *ec2.Address) {
+	if ni, ok := api.interfaces[aws.StringValue(addr.NetworkInterfaceId)]; ok {
+		ni.Association = nil
+	}
+	addr.AssociationId = nil
+	addr.InstanceId = nil
+	addr.NetworkInterfaceId = nil
+	addr.NetworkInterfaceOwnerId = nil
+	addr.PrivateIpAddress = nil
+}
+
+//AllocateAddress allocates an elastic IP
+func (api *ec2API) AllocateAddress(in *ec2.AllocateAddressInput) (*ec2.AllocateAddressOutput, error) {
+	_, n, _ := net.ParseCIDR(publicPool)
+	used := map[string]bool{}
+	for _, addr := range api.addresses {
+		used[*addr.PublicIp] = true
+	}
+	first, last := bounds(n)
+	var ip *string
+	for u := first + 1; u < last; u++ {
+		s := iputils.Utoi(u).String()
+		if !used[s] {
+			ip = aws.String(s)
+			break
+		}
+	}
+	if ip == nil {
+		return nil, errorf("AddressLimitExceeded", "The maximum number of addresses has been reached.")
+	}
+	addr := &ec2.Address{
+		AllocationId:   aws.String(api.newID("eipalloc")),
+		Domain:         aws.String(ec2.DomainTypeVpc),
+		PublicIp:       ip,
+		PublicIpv4Pool: aws.String("amazon"),
+	}
+	api.addresses[*addr.AllocationId] = addr
+	return &ec2.AllocateAddressOutput{
+		AllocationId:   addr.AllocationId,
+		Domain:         addr.Domain,
+		PublicIp:       addr.PublicIp,
+		PublicIpv4Pool: addr.PublicIpv4Pool,
+	}, nil
+}
+
+//AssociateAddress associates an elastic IP with a network interface, the primary network interface is used if an instance is given
+func (api *ec2API) AssociateAddress(in *ec2.AssociateAddressInput) (*ec2.AssociateAddressOutput, error) {
+	addr, err := api.address(in.AllocationId)
+	if err != nil {
+		return nil, err
+	}
+	var ni *ec2.NetworkInterface
+	switch {
+	case in.NetworkInterfaceId != nil:
+		ni, err = api.networkInterface(in.NetworkInterfaceId)
+		if err != nil {
+			return nil, err
+		}
+	case in.InstanceId != nil:
+		inst, err := api.instance(in.InstanceId)
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range api.interfaces {
+			if n.Attachment != nil && *n.Attachment.InstanceId == *inst.InstanceId && aws.Int64Value(n.Attachment.DeviceIndex) == 0 {
+				ni = n
+			}
+		}
+		if ni == nil {
+			return nil, errorf("InvalidInstanceID", "The instance '%s' has no network interface", *inst.InstanceId)
+		}
+	default:
+		return nil, errorf("MissingParameter", "Either instance ID or network interface id must be specified")
+	}
+	if addr.AssociationId != nil {
+		if !aws.BoolValue(in.AllowReassociation) {
+			return nil, errorf("Resource.AlreadyAssociated", "resource %s is already associated with associate-id %s", *addr.AllocationId, *addr.AssociationId)
+		}
+		api.disassociateAddress(addr)
+	}
+	if ni.Association != nil {
+		if other, ok := api.addresses[aws.StringValue(ni.Association.AllocationId)]; ok {
+			api.disassociateAddress(other)
+		}
+	}
+	privateIP := ni.PrivateIpAddress
+	if in.PrivateIpAddress != nil {
+		privateIP = in.PrivateIpAddress
+	}
+	addr.AssociationId = aws.String(api.newID("eipassoc"))
+	addr.NetworkInterfaceId = ni.NetworkInterfaceId
+	addr.NetworkInterfaceOwnerId = aws.String(AccountID)
+	addr.PrivateIpAddress = privateIP
+	if ni.Attachment != nil {
+		addr.InstanceId = ni.Attachment.InstanceId
+	}
+	ni.Association = &ec2.NetworkInterfaceAssociation{
+		AllocationId:  addr.AllocationId,
+		AssociationId: addr.AssociationId,
+		IpOwnerId:     aws.String(AccountID),
+		PublicIp:      addr.PublicIp,
+	}
+	return &ec2.AssociateAddressOutput{AssociationId: addr.AssociationId}, nil
+}
+
+//DisassociateAddress disassociates an elastic IP from its network interface
+func (api *ec2API) DisassociateAddress(in *ec2.DisassociateAddressInput) (*ec2.DisassociateAddressOutput, error) {
+	for _, addr := range api.addresses {
+		if addr.AssociationId != nil && *addr.AssociationId == aws.StringValue(in.AssociationId) {
+			api.disassociateAddress(addr)
+			return &ec2.DisassociateAddressOutput{}, nil
+		}
+	}
+	return nil, errorf("InvalidAssociationID.NotFound", "The association ID '%s' does not exist", aws.StringValue(in.AssociationId))
+}
+
+//ReleaseAddress releases an elastic IP
+func (api *ec2API) ReleaseAddress(in *ec2.ReleaseAddressInput) (*ec2.ReleaseAddressOutput, error) {
+	addr, err := api.address(in.AllocationId)
+	if err != nil {
+		return nil, err
+	}
+	if addr.AssociationId != nil {
+		return nil, errorf("InvalidIPAddress.InUse", "Address %s is in use.", *addr.PublicIp)
+	}
+	delete(api.addresses, *addr.AllocationId)
+	return &ec2.ReleaseAddressOutput{}, nil
+}
+
+//DescribeAddresses describes elastic IPs
+func (api *ec2API) DescribeAddresses(in *ec2.DescribeAddressesInput) (*ec2.DescribeAddressesOutput, error) {
+	for _, id := range in.AllocationIds {
+		if _, err := api.address(id); err != nil {
+			return nil, err
+		}
+	}
+	out := &ec2.DescribeAddressesOutput{}
+	for _, id := range sortedKeys(api.addresses) {
+		addr := api.addresses[id]
+		if !contains(in.AllocationIds, id) || !contains(in.PublicIps, *addr.PublicIp) {
+			continue
+		}
+		ok, err := match(in.Filters, func(name string) ([]string, bool) {
+			switch name {
+			case "allocation-id":
+				return values(addr.AllocationId), true
+			case "association-id":
+				return values(addr.AssociationId), true
+			case "domain":
+				return values(addr.Domain), true
+			case "instance-id":
+				return values(addr.InstanceId), true
+			case "network-interface-id":
+				return values(addr.NetworkInterfaceId), true
+			case "private-ip-address":
+				return values(addr.PrivateIpAddress), true
+			case "public-ip":
+				return values(addr.PublicIp), true
+			}
+			return tagValues(addr.Tags, name)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out.Addresses = append(out.Addresses, addr)
+		}
+	}
+	return out, nil
+}
+
+//DescribePublicIpv4Pools describes the public IP v4 pools, the fake has no BYOIP pool
+func (api *ec2API) DescribePublicIpv4Pools(in *ec2.DescribePublicIpv4PoolsInput) (*ec2.DescribePublicIpv4PoolsOutput, error) {
+	return &ec2.DescribePublicIpv4PoolsOutput{}, nil
+}
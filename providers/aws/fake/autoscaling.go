@@ -0,0 +1,281 @@
+package fake
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+const (
+	autoScalingNamespace = "http://autoscaling.amazonaws.com/doc/2011-01-01/"
+	//autoScalingVersion version parameter of the Auto Scaling queries, used to tell them apart from EC2 queries
+	autoScalingVersion = "2011-01-01"
+)
+
+//autoScalingAPI fake Auto Scaling API
+//Each exported method implements the Auto Scaling action of the same name
+//Groups are reconciled before each action: terminated or stopped instances are replaced and instances are launched or terminated until groups reach their desired capacity
+type autoScalingAPI struct {
+	ec2    *ec2API
+	groups map[string]*autoscaling.Group
+}
+
+func newAutoScalingAPI(ec2 *ec2API) *autoScalingAPI {
+	return &autoScalingAPI{
+		ec2:    ec2,
+		groups: map[string]*autoscaling.Group{},
+	}
+}
+
+func (api *autoScalingAPI) group(name *string) (*autoscaling.Group, error) {
+	g, ok := api.groups[aws.StringValue(name)]
+	if !ok {
+		return nil, errorf("ValidationError", "AutoScalingGroup name not found - AutoScalingGroup %s not found", aws.StringValue(name))
+	}
+	return g, nil
+}
+
+//launch launches an instance of a group from its launch template
+func (api *autoScalingAPI) launch(g *autoscaling.Group) error {
+	t, err := api.ec2.launchTemplate(g.LaunchTemplate.LaunchTemplateId, g.LaunchTemplate.LaunchTemplateName)
+	if err != nil {
+		return err
+	}
+	var tags []*ec2.Tag
+	for _, t := range g.Tags {
+		if aws.BoolValue(t.PropagateAtLaunch) {
+			tags = append(tags, &ec2.Tag{Key: t.Key, Value: t.Value})
+		}
+	}
+	spec := t.spec(g.VPCZoneIdentifier, tags)
+	err = api.ec2.checkLaunchSpecification(spec)
+	if err != nil {
+		return err
+	}
+	res, err := api.ec2.reserve(spec, 1)
+	if err != nil {
+		return err
+	}
+	inst := res.Instances[0]
+	g.Instances = append(g.Instances, &autoscaling.Instance{
+		AvailabilityZone:     inst.Placement.AvailabilityZone,
+		HealthStatus:         aws.String("Healthy"),
+		InstanceId:           inst.InstanceId,
+		LaunchTemplate:       g.LaunchTemplate,
+		LifecycleState:       aws.String(autoscaling.LifecycleStateInService),
+		ProtectedFromScaleIn: aws.Bool(false),
+	})
+	return nil
+}
+
+//terminate terminates the i-th instance of a group
+func (api *autoScalingAPI) terminate(g *autoscaling.Group, i int) {
+	if inst, ok := api.ec2.instances[*g.Instances[i].InstanceId]; ok && *inst.State.Code != terminated {
+		api.ec2.terminate(inst)
+	}
+	g.Instances = append(g.Instances[:i], g.Instances[i+1:]...)
+}
+
+//reconcile replaces the unhealthy instances of a group and launches or terminates instances until the group reaches its desired capacity
+//The most recent instances are terminated first
+func (api *autoScalingAPI) reconcile(g *autoscaling.Group) error {
+	for i := len(g.Instances) - 1; i >= 0; i-- {
+		inst, ok := api.ec2.instances[*g.Instances[i].InstanceId]
+		if !ok || *inst.State.Code != running {
+			api.terminate(g, i)
+		}
+	}
+	for int64(len(g.Instances)) > *g.DesiredCapacity {
+		api.terminate(g, len(g.Instances)-1)
+	}
+	for int64(len(g.Instances)) < *g.DesiredCapacity {
+		err := api.launch(g)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//reconcileAll reconciles all the groups, launch errors are ignored until the group is described
+func (api *autoScalingAPI) reconcileAll() {
+	for _, g := range api.groups {
+		_ = api.reconcile(g)
+	}
+}
+
+func checkCapacity(min, max, desired *int64) error {
+	if *min < 0 || *min > *max {
+		return errorf("ValidationError", "Max bound, %d, must be greater than or equal to min bound, %d", *max, *min)
+	}
+	if *desired < *min || *desired > *max {
+		return errorf("ValidationError", "Desired capacity:%d must be between the specified min size:%d and max size:%d", *desired, *min, *max)
+	}
+	return nil
+}
+
+//CreateAutoScalingGroup creates a group launching the instances of a launch template in the subnet of VPCZoneIdentifier
+func (api *autoScalingAPI) CreateAutoScalingGroup(in *autoscaling.CreateAutoScalingGroupInput) (*autoscaling.CreateAutoScalingGroupOutput, error) {
+	name := aws.StringValue(in.AutoScalingGroupName)
+	if name == "" || len(name) > 255 {
+		return nil, errorf("ValidationError", "The AutoScalingGroup name %s is not valid", name)
+	}
+	if _, ok := api.groups[name]; ok {
+		return nil, errorf("AlreadyExists", "AutoScalingGroup by this name already exists - A group with the name %s already exists", name)
+	}
+	if in.LaunchTemplate == nil {
+		return nil, errorf("ValidationError", "Valid requests must contain either LaunchTemplate, LaunchConfigurationName, InstanceId or MixedInstancesPolicy parameter.")
+	}
+	t, err := api.ec2.launchTemplate(in.LaunchTemplate.LaunchTemplateId, in.LaunchTemplate.LaunchTemplateName)
+	if err != nil {
+		return nil, errorf("ValidationError", "%s", toAPIError(err).message)
+	}
+	if strings.Contains(aws.StringValue(in.VPCZoneIdentifier), ",") {
+		return nil, errorf("ValidationError", "The fake only supports groups in a single subnet")
+	}
+	sn, err := api.ec2.subnet(in.VPCZoneIdentifier)
+	if err != nil {
+		return nil, errorf("ValidationError", "%s", toAPIError(err).message)
+	}
+	desired := in.DesiredCapacity
+	if desired == nil {
+		desired = in.MinSize
+	}
+	err = checkCapacity(in.MinSize, in.MaxSize, desired)
+	if err != nil {
+		return nil, err
+	}
+	g := &autoscaling.Group{
+		AutoScalingGroupARN:  aws.String(fmt.Sprintf("arn:aws:autoscaling:%s:%s:autoScalingGroup:%s:autoScalingGroupName/%s", Region, AccountID, api.ec2.newID("")[1:], name)),
+		AutoScalingGroupName: aws.String(name),
+		AvailabilityZones:    []*string{sn.AvailabilityZone},
+		CreatedTime:          aws.Time(time.Now().UTC().Truncate(time.Second)),
+		DefaultCooldown:      aws.Int64(300),
+		DesiredCapacity:      desired,
+		HealthCheckType:      aws.String("EC2"),
+		LaunchTemplate: &autoscaling.LaunchTemplateSpecification{
+			LaunchTemplateId:   t.LaunchTemplateId,
+			LaunchTemplateName: t.LaunchTemplateName,
+			Version:            in.LaunchTemplate.Version,
+		},
+		MaxSize:                          in.MaxSize,
+		MinSize:                          in.MinSize,
+		NewInstancesProtectedFromScaleIn: aws.Bool(false),
+		VPCZoneIdentifier:                in.VPCZoneIdentifier,
+	}
+	for _, t := range in.Tags {
+		g.Tags = append(g.Tags, &autoscaling.TagDescription{
+			Key:               t.Key,
+			PropagateAtLaunch: t.PropagateAtLaunch,
+			ResourceId:        g.AutoScalingGroupName,
+			ResourceType:      aws.String("auto-scaling-group"),
+			Value:             t.Value,
+		})
+	}
+	sort.Slice(g.Tags, func(i, j int) bool {
+		return *g.Tags[i].Key < *g.Tags[j].Key
+	})
+	err = api.reconcile(g)
+	if err != nil {
+		for len(g.Instances) > 0 {
+			api.terminate(g, 0)
+		}
+		return nil, err
+	}
+	api.groups[name] = g
+	return &autoscaling.CreateAutoScalingGroupOutput{}, nil
+}
+
+//DescribeAutoScalingGroups describes groups, all the groups are returned in a single page
+func (api *autoScalingAPI) DescribeAutoScalingGroups(in *autoscaling.DescribeAutoScalingGroupsInput) (*autoscaling.DescribeAutoScalingGroupsOutput, error) {
+	api.reconcileAll()
+	out := &autoscaling.DescribeAutoScalingGroupsOutput{AutoScalingGroups: []*autoscaling.Group{}}
+	for _, name := range sortedKeys(api.groups) {
+		if len(in.AutoScalingGroupNames) > 0 && !contains(in.AutoScalingGroupNames, name) {
+			continue
+		}
+		out.AutoScalingGroups = append(out.AutoScalingGroups, api.groups[name])
+	}
+	return out, nil
+}
+
+//UpdateAutoScalingGroup updates the capacity of a group
+func (api *autoScalingAPI) UpdateAutoScalingGroup(in *autoscaling.UpdateAutoScalingGroupInput) (*autoscaling.UpdateAutoScalingGroupOutput, error) {
+	api.reconcileAll()
+	g, err := api.group(in.AutoScalingGroupName)
+	if err != nil {
+		return nil, err
+	}
+	min, max, desired := g.MinSize, g.MaxSize, g.DesiredCapacity
+	if in.MinSize != nil {
+		min = in.MinSize
+	}
+	if in.MaxSize != nil {
+		max = in.MaxSize
+	}
+	if in.DesiredCapacity != nil {
+		desired = in.DesiredCapacity
+	}
+	err = checkCapacity(min, max, desired)
+	if err != nil {
+		return nil, err
+	}
+	g.MinSize, g.MaxSize, g.DesiredCapacity = min, max, desired
+	return &autoscaling.UpdateAutoScalingGroupOutput{}, api.reconcile(g)
+}
+
+//TerminateInstanceInAutoScalingGroup terminates an instance of a group, it is replaced unless ShouldDecrementDesiredCapacity is true
+func (api *autoScalingAPI) TerminateInstanceInAutoScalingGroup(in *autoscaling.TerminateInstanceInAutoScalingGroupInput) (*autoscaling.TerminateInstanceInAutoScalingGroupOutput, error) {
+	api.reconcileAll()
+	for _, name := range sortedKeys(api.groups) {
+		g := api.groups[name]
+		for i, inst := range g.Instances {
+			if *inst.InstanceId != aws.StringValue(in.InstanceId) {
+				continue
+			}
+			if aws.BoolValue(in.ShouldDecrementDesiredCapacity) {
+				if *g.DesiredCapacity == *g.MinSize {
+					return nil, errorf("ValidationError", "Currently, desiredSize equals minSize (%d). Terminating instance without replacement will violate group's min size constraint.", *g.MinSize)
+				}
+				*g.DesiredCapacity--
+			}
+			api.terminate(g, i)
+			now := aws.Time(time.Now().UTC().Truncate(time.Second))
+			return &autoscaling.TerminateInstanceInAutoScalingGroupOutput{
+				Activity: &autoscaling.Activity{
+					ActivityId:           aws.String(api.ec2.newID("")[1:]),
+					AutoScalingGroupName: g.AutoScalingGroupName,
+					Cause:                aws.String(fmt.Sprintf("At %s instance %s was taken out of service in response to a user request.", now.Format(time.RFC3339), *inst.InstanceId)),
+					Description:          aws.String("Terminating EC2 instance: " + *inst.InstanceId),
+					Progress:             aws.Int64(100),
+					StartTime:            now,
+					EndTime:              now,
+					StatusCode:           aws.String(autoscaling.ScalingActivityStatusCodeSuccessful),
+				},
+			}, api.reconcile(g)
+		}
+	}
+	return nil, errorf("ValidationError", "Instance Id not found - No managed instance found for instance ID: %s", aws.StringValue(in.InstanceId))
+}
+
+//DeleteAutoScalingGroup deletes a group, its instances are terminated if ForceDelete is true
+func (api *autoScalingAPI) DeleteAutoScalingGroup(in *autoscaling.DeleteAutoScalingGroupInput) (*autoscaling.DeleteAutoScalingGroupOutput, error) {
+	api.reconcileAll()
+	g, err := api.group(in.AutoScalingGroupName)
+	if err != nil {
+		return nil, err
+	}
+	if len(g.Instances) > 0 && !aws.BoolValue(in.ForceDelete) {
+		return nil, errorf("ResourceInUse", "You cannot delete an AutoScalingGroup while there are instances still in the group.")
+	}
+	for len(g.Instances) > 0 {
+		api.terminate(g, 0)
+	}
+	delete(api.groups, *g.AutoScalingGroupName)
+	return &autoscaling.DeleteAutoScalingGroupOutput{}, nil
+}
@@ -0,0 +1,377 @@
+package fake
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+//ec2API fake EC2 API
+//Each exported method implements the EC2 action of the same name
+type ec2API struct {
+	counter          uint64
+	vpcs             map[string]*ec2.Vpc
+	subnets          map[string]*ec2.Subnet
+	internetGateways map[string]*ec2.InternetGateway
+	routeTables      map[string]*ec2.RouteTable
+	securityGroups   map[string]*ec2.SecurityGroup
+	keyPairs         map[string]*ec2.KeyPairInfo
+	images           map[string]*ec2.Image
+	reservations     map[string]*ec2.Reservation
+	instances        map[string]*ec2.Instance
+	spotRequests     map[string]*ec2.SpotInstanceRequest
+	volumes          map[string]*ec2.Volume
+	snapshots        map[string]*ec2.Snapshot
+	interfaces       map[string]*ec2.NetworkInterface
+	addresses        map[string]*ec2.Address
+	launchTemplates  map[string]*launchTemplate
+}
+
+func newEC2API() *ec2API {
+	api := &ec2API{
+		vpcs:             map[string]*ec2.Vpc{},
+		subnets:          map[string]*ec2.Subnet{},
+		internetGateways: map[string]*ec2.InternetGateway{},
+		routeTables:      map[string]*ec2.RouteTable{},
+		securityGroups:   map[string]*ec2.SecurityGroup{},
+		keyPairs:         map[string]*ec2.KeyPairInfo{},
+		images:           map[string]*ec2.Image{},
+		reservations:     map[string]*ec2.Reservation{},
+		instances:        map[string]*ec2.Instance{},
+		spotRequests:     map[string]*ec2.SpotInstanceRequest{},
+		volumes:          map[string]*ec2.Volume{},
+		snapshots:        map[string]*ec2.Snapshot{},
+		interfaces:       map[string]*ec2.NetworkInterface{},
+		addresses:        map[string]*ec2.Address{},
+		launchTemplates:  map[string]*launchTemplate{},
+	}
+	for _, img := range defaultImages() {
+		img.ImageId = aws.String(api.newID("ami"))
+		api.images[*img.ImageId] = img
+	}
+	vpc := api.createVpc("172.31.0.0/16", false)
+	vpc.IsDefault = aws.Bool(true)
+	return api
+}
+
+//newID generates a new resource identifier
+func (api *ec2API) newID(prefix string) string {
+	api.counter++
+	return fmt.Sprintf("%s-%017x", prefix, api.counter)
+}
+
+//checkID returns the error returned by EC2 when id does not have the prefix of the identifiers of its kind
+func checkID(code string, prefix string, id *string) error {
+	if strings.HasPrefix(aws.StringValue(id), prefix+"-") {
+		return nil
+	}
+	return errorf(code, "Invalid id: \"%s\" (expecting \"%s-...\")", aws.StringValue(id), prefix)
+}
+
+//sortedKeys returns the keys of a resource map in ascending order
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	for _, k := range reflect.ValueOf(m).MapKeys() {
+		keys = append(keys, k.String())
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+//fieldValues returns the values of a resource matched by a filter and false if the filter is not supported
+type fieldValues func(name string) ([]string, bool)
+
+//glob returns a regular expression matching the EC2 filter pattern p, * matches any string and ? any character
+func glob(p string) *regexp.Regexp {
+	var expr strings.Builder
+	expr.WriteString("^")
+	for _, c := range p {
+		switch c {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteString(".")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	expr.WriteString("$")
+	return regexp.MustCompile(expr.String())
+}
+
+//match checks that a resource matches all the filters
+func match(filters []*ec2.Filter, values fieldValues) (bool, error) {
+	for _, f := range filters {
+		if f.Name == nil {
+			continue
+		}
+		vs, ok := values(*f.Name)
+		if !ok {
+			return false, errorf("InvalidParameterValue", "The filter '%s' is invalid", *f.Name)
+		}
+		if !matchAny(f.Values, vs) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func matchAny(patterns []*string, values []string) bool {
+	for _, p := range patterns {
+		re := glob(aws.StringValue(p))
+		for _, v := range values {
+			if re.MatchString(v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+//tagValues returns the values of the tag filter name
+func tagValues(tags []*ec2.Tag, name string) ([]string, bool) {
+	var res []string
+	switch {
+	case strings.HasPrefix(name, "tag:"):
+		key := strings.TrimPrefix(name, "tag:")
+		for _, t := range tags {
+			if aws.StringValue(t.Key) == key {
+				res = append(res, aws.StringValue(t.Value))
+			}
+		}
+	case name == "tag-key":
+		for _, t := range tags {
+			res = append(res, aws.StringValue(t.Key))
+		}
+	case name == "tag-value":
+		for _, t := range tags {
+			res = append(res, aws.StringValue(t.Value))
+		}
+	default:
+		return nil, false
+	}
+	return res, true
+}
+
+func values(vs ...*string) []string {
+	var res []string
+	for _, v := range vs {
+		if v != nil {
+			res = append(res, *v)
+		}
+	}
+	return res
+}
+
+func bools(b *bool) []string {
+	if b == nil {
+		return nil
+	}
+	return []string{fmt.Sprintf("%t", *b)}
+}
+
+func contains(ids []*string, id string) bool {
+	if len(ids) == 0 {
+		return true
+	}
+	for _, i := range ids {
+		if aws.StringValue(i) == id {
+			return true
+		}
+	}
+	return false
+}
+
+//owners returns the owner identifiers ids in which the alias self is replaced by the fake account identifier
+func owners(ids []*string) []*string {
+	res := make([]*string, len(ids))
+	for i, id := range ids {
+		res[i] = id
+		if aws.StringValue(id) == "self" {
+			res[i] = aws.String(AccountID)
+		}
+	}
+	return res
+}
+
+//setTags adds or overwrites tags in a tag list
+func setTags(tags []*ec2.Tag, newTags []*ec2.Tag) []*ec2.Tag {
+	for _, nt := range newTags {
+		found := false
+		for _, t := range tags {
+			if aws.StringValue(t.Key) == aws.StringValue(nt.Key) {
+				t.Value = aws.String(aws.StringValue(nt.Value))
+				found = true
+				break
+			}
+		}
+		if !found {
+			tags = append(tags, &ec2.Tag{
+				Key:   aws.String(aws.StringValue(nt.Key)),
+				Value: aws.String(aws.StringValue(nt.Value)),
+			})
+		}
+	}
+	return tags
+}
+
+//tags returns the tag list of the resource identified by id
+func (api *ec2API) tags(id string) (*[]*ec2.Tag, bool) {
+	if r, ok := api.vpcs[id]; ok {
+		return &r.Tags, true
+	}
+	if r, ok := api.subnets[id]; ok {
+		return &r.Tags, true
+	}
+	if r, ok := api.internetGateways[id]; ok {
+		return &r.Tags, true
+	}
+	if r, ok := api.routeTables[id]; ok {
+		return &r.Tags, true
+	}
+	if r, ok := api.securityGroups[id]; ok {
+		return &r.Tags, true
+	}
+	if r, ok := api.images[id]; ok {
+		return &r.Tags, true
+	}
+	if r, ok := api.instances[id]; ok {
+		return &r.Tags, true
+	}
+	if r, ok := api.spotRequests[id]; ok {
+		return &r.Tags, true
+	}
+	if r, ok := api.volumes[id]; ok {
+		return &r.Tags, true
+	}
+	if r, ok := api.snapshots[id]; ok {
+		return &r.Tags, true
+	}
+	if r, ok := api.interfaces[id]; ok {
+		return &r.TagSet, true
+	}
+	if r, ok := api.addresses[id]; ok {
+		return &r.Tags, true
+	}
+	return nil, false
+}
+
+//tagSpecifications returns the tags of specs applying to resources of type resourceType
+func tagSpecifications(specs []*ec2.TagSpecification, resourceType string) []*ec2.Tag {
+	var tags []*ec2.Tag
+	for _, s := range specs {
+		if aws.StringValue(s.ResourceType) == resourceType {
+			tags = setTags(tags, s.Tags)
+		}
+	}
+	return tags
+}
+
+//CreateTags adds or overwrites tags of resources
+func (api *ec2API) CreateTags(in *ec2.CreateTagsInput) (*ec2.CreateTagsOutput, error) {
+	for _, id := range in.Resources {
+		if _, ok := api.tags(aws.StringValue(id)); !ok {
+			return nil, errorf("InvalidID", "The ID '%s' is not valid", aws.StringValue(id))
+		}
+	}
+	for _, id := range in.Resources {
+		tags, _ := api.tags(aws.StringValue(id))
+		*tags = setTags(*tags, in.Tags)
+	}
+	return &ec2.CreateTagsOutput{}, nil
+}
+
+//DeleteTags deletes tags of resources
+func (api *ec2API) DeleteTags(in *ec2.DeleteTagsInput) (*ec2.DeleteTagsOutput, error) {
+	for _, id := range in.Resources {
+		tags, ok := api.tags(aws.StringValue(id))
+		if !ok {
+			return nil, errorf("InvalidID", "The ID '%s' is not valid", aws.StringValue(id))
+		}
+		var res []*ec2.Tag
+		for _, t := range *tags {
+			deleted := false
+			for _, dt := range in.Tags {
+				if aws.StringValue(dt.Key) == aws.StringValue(t.Key) && (dt.Value == nil || *dt.Value == aws.StringValue(t.Value)) {
+					deleted = true
+					break
+				}
+			}
+			if !deleted {
+				res = append(res, t)
+			}
+		}
+		*tags = res
+	}
+	return &ec2.DeleteTagsOutput{}, nil
+}
+
+//taggedResource a resource listed by DescribeTags
+type taggedResource struct {
+	id           string
+	resourceType string
+	tags         []*ec2.Tag
+}
+
+//taggedResources returns all the resources that can be tagged
+func (api *ec2API) taggedResources() []taggedResource {
+	var res []taggedResource
+	add := func(resourceType string, m interface{}) {
+		for _, id := range sortedKeys(m) {
+			tags, _ := api.tags(id)
+			res = append(res, taggedResource{id: id, resourceType: resourceType, tags: *tags})
+		}
+	}
+	add("vpc", api.vpcs)
+	add("subnet", api.subnets)
+	add("internet-gateway", api.internetGateways)
+	add("route-table", api.routeTables)
+	add("security-group", api.securityGroups)
+	add("image", api.images)
+	add("instance", api.instances)
+	add("spot-instances-request", api.spotRequests)
+	add("volume", api.volumes)
+	add("snapshot", api.snapshots)
+	add("network-interface", api.interfaces)
+	add("elastic-ip", api.addresses)
+	return res
+}
+
+//DescribeTags describes the tags of resources
+func (api *ec2API) DescribeTags(in *ec2.DescribeTagsInput) (*ec2.DescribeTagsOutput, error) {
+	out := &ec2.DescribeTagsOutput{}
+	for _, r := range api.taggedResources() {
+		for _, t := range r.tags {
+			ok, err := match(in.Filters, func(name string) ([]string, bool) {
+				switch name {
+				case "resource-id":
+					return []string{r.id}, true
+				case "resource-type":
+					return []string{r.resourceType}, true
+				case "key":
+					return values(t.Key), true
+				case "value":
+					return values(t.Value), true
+				}
+				return nil, false
+			})
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				out.Tags = append(out.Tags, &ec2.TagDescription{
+					ResourceId:   aws.String(r.id),
+					ResourceType: aws.String(r.resourceType),
+					Key:          aws.String(aws.StringValue(t.Key)),
+					Value:        aws.String(aws.StringValue(t.Value)),
+				})
+			}
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,483 @@
+package fake
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+)
+
+const (
+	elbv2Namespace = "http://elasticloadbalancing.amazonaws.com/doc/2015-12-01/"
+	//elbv2Version version parameter of the ELBv2 queries, used to tell them apart from EC2 queries
+	elbv2Version = "2015-12-01"
+)
+
+//elbv2Name valid load balancer and target group names
+var elbv2Name = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]{0,30}[a-zA-Z0-9])?$`)
+
+//elbv2API fake Elastic Load Balancing v2 API
+//Each exported method implements the ELBv2 action of the same name, load balancers are active as soon as they are created
+type elbv2API struct {
+	ec2           *ec2API
+	loadBalancers map[string]*elbv2.LoadBalancer
+	listeners     map[string]*elbv2.Listener
+	targetGroups  map[string]*elbv2.TargetGroup
+	targets       map[string][]*elbv2.TargetDescription
+	tags          map[string][]*elbv2.Tag
+}
+
+func newELBv2API(ec2 *ec2API) *elbv2API {
+	return &elbv2API{
+		ec2:           ec2,
+		loadBalancers: map[string]*elbv2.LoadBalancer{},
+		listeners:     map[string]*elbv2.Listener{},
+		targetGroups:  map[string]*elbv2.TargetGroup{},
+		targets:       map[string][]*elbv2.TargetDescription{},
+		tags:          map[string][]*elbv2.Tag{},
+	}
+}
+
+//arn returns the ARN of an ELBv2 resource
+func (api *elbv2API) arn(resource string) string {
+	return fmt.Sprintf("arn:aws:elasticloadbalancing:%s:%s:%s/%s", Region, AccountID, resource, api.ec2.newID("")[1:])
+}
+
+func (api *elbv2API) loadBalancer(arn *string) (*elbv2.LoadBalancer, error) {
+	lb, ok := api.loadBalancers[aws.StringValue(arn)]
+	if !ok {
+		return nil, errorf("LoadBalancerNotFound", "Load balancer '%s' not found", aws.StringValue(arn))
+	}
+	return lb, nil
+}
+
+func (api *elbv2API) targetGroup(arn *string) (*elbv2.TargetGroup, error) {
+	tg, ok := api.targetGroups[aws.StringValue(arn)]
+	if !ok {
+		return nil, errorf("TargetGroupNotFound", "Target groups '%s' not found", aws.StringValue(arn))
+	}
+	return tg, nil
+}
+
+func (api *elbv2API) listener(arn *string) (*elbv2.Listener, error) {
+	l, ok := api.listeners[aws.StringValue(arn)]
+	if !ok {
+		return nil, errorf("ListenerNotFound", "Listener '%s' not found", aws.StringValue(arn))
+	}
+	return l, nil
+}
+
+//subnets checks that the subnets belong to the same VPC and to distinct availability zones
+func (api *elbv2API) subnets(ids []*string) ([]*ec2.Subnet, error) {
+	var subnets []*ec2.Subnet
+	zones := map[string]bool{}
+	for _, id := range ids {
+		sn, ok := api.ec2.subnets[aws.StringValue(id)]
+		if !ok {
+			return nil, errorf("SubnetNotFound", "The subnet ID '%s' is not valid", aws.StringValue(id))
+		}
+		if len(subnets) > 0 && *sn.VpcId != *subnets[0].VpcId {
+			return nil, errorf("InvalidConfigurationRequest", "Subnets must belong to the same VPC")
+		}
+		if zones[*sn.AvailabilityZone] {
+			return nil, errorf("InvalidConfigurationRequest", "A load balancer cannot be attached to multiple subnets in the same Availability Zone")
+		}
+		zones[*sn.AvailabilityZone] = true
+		subnets = append(subnets, sn)
+	}
+	return subnets, nil
+}
+
+//CreateLoadBalancer creates a load balancer, application load balancers must be attached to subnets of at least two availability zones
+func (api *elbv2API) CreateLoadBalancer(in *elbv2.CreateLoadBalancerInput) (*elbv2.CreateLoadBalancerOutput, error) {
+	name := aws.StringValue(in.Name)
+	if !elbv2Name.MatchString(name) || strings.HasPrefix(name, "internal-") {
+		return nil, errorf("ValidationError", "The load balancer name '%s' is not valid", name)
+	}
+	for _, lb := range api.loadBalancers {
+		if *lb.LoadBalancerName == name {
+			return nil, errorf("DuplicateLoadBalancerName", "A load balancer with the same name '%s' exists", name)
+		}
+	}
+	lbType := aws.StringValue(in.Type)
+	if lbType == "" {
+		lbType = elbv2.LoadBalancerTypeEnumApplication
+	}
+	if lbType != elbv2.LoadBalancerTypeEnumApplication && lbType != elbv2.LoadBalancerTypeEnumNetwork {
+		return nil, errorf("ValidationError", "The load balancer type '%s' is not valid", lbType)
+	}
+	scheme := aws.StringValue(in.Scheme)
+	if scheme == "" {
+		scheme = elbv2.LoadBalancerSchemeEnumInternetFacing
+	}
+	subnets, err := api.subnets(in.Subnets)
+	if err != nil {
+		return nil, err
+	}
+	if len(subnets) == 0 || (lbType == elbv2.LoadBalancerTypeEnumApplication && len(subnets) < 2) {
+		return nil, errorf("ValidationError", "At least two subnets in two different Availability Zones must be specified")
+	}
+	short := "app"
+	if lbType == elbv2.LoadBalancerTypeEnumNetwork {
+		short = "net"
+	}
+	arn := api.arn("loadbalancer/" + short + "/" + name)
+	lb := &elbv2.LoadBalancer{
+		CreatedTime:      aws.Time(time.Now().UTC().Truncate(time.Second)),
+		DNSName:          aws.String(fmt.Sprintf("%s-%s.elb.%s.amazonaws.com", name, arn[strings.LastIndex(arn, "/")+1:], Region)),
+		IpAddressType:    aws.String(elbv2.IpAddressTypeIpv4),
+		LoadBalancerArn:  aws.String(arn),
+		LoadBalancerName: aws.String(name),
+		Scheme:           aws.String(scheme),
+		State:            &elbv2.LoadBalancerState{Code: aws.String(elbv2.LoadBalancerStateEnumActive)},
+		Type:             aws.String(lbType),
+		VpcId:            subnets[0].VpcId,
+	}
+	if scheme == elbv2.LoadBalancerSchemeEnumInternal {
+		lb.DNSName = aws.String("internal-" + *lb.DNSName)
+	}
+	for _, sn := range subnets {
+		lb.AvailabilityZones = append(lb.AvailabilityZones, &elbv2.AvailabilityZone{
+			SubnetId: sn.SubnetId,
+			ZoneName: sn.AvailabilityZone,
+		})
+	}
+	api.loadBalancers[arn] = lb
+	if len(in.Tags) > 0 {
+		api.tags[arn] = in.Tags
+	}
+	return &elbv2.CreateLoadBalancerOutput{LoadBalancers: []*elbv2.LoadBalancer{lb}}, nil
+}
+
+//DeleteLoadBalancer deletes a load balancer and its listeners
+func (api *elbv2API) DeleteLoadBalancer(in *elbv2.DeleteLoadBalancerInput) (*elbv2.DeleteLoadBalancerOutput, error) {
+	lb, err := api.loadBalancer(in.LoadBalancerArn)
+	if err != nil {
+		return nil, err
+	}
+	for arn, l := range api.listeners {
+		if *l.LoadBalancerArn == *lb.LoadBalancerArn {
+			delete(api.listeners, arn)
+		}
+	}
+	for _, tg := range api.targetGroups {
+		var arns []*string
+		for _, arn := range tg.LoadBalancerArns {
+			if *arn != *lb.LoadBalancerArn {
+				arns = append(arns, arn)
+			}
+		}
+		tg.LoadBalancerArns = arns
+	}
+	delete(api.loadBalancers, *lb.LoadBalancerArn)
+	delete(api.tags, *lb.LoadBalancerArn)
+	return &elbv2.DeleteLoadBalancerOutput{}, nil
+}
+
+//DescribeLoadBalancers describes load balancers
+func (api *elbv2API) DescribeLoadBalancers(in *elbv2.DescribeLoadBalancersInput) (*elbv2.DescribeLoadBalancersOutput, error) {
+	out := &elbv2.DescribeLoadBalancersOutput{}
+	for _, arn := range in.LoadBalancerArns {
+		lb, err := api.loadBalancer(arn)
+		if err != nil {
+			return nil, err
+		}
+		out.LoadBalancers = append(out.LoadBalancers, lb)
+	}
+	if len(in.LoadBalancerArns) > 0 {
+		return out, nil
+	}
+	names := map[string]bool{}
+	for _, n := range in.Names {
+		names[aws.StringValue(n)] = true
+	}
+	all := len(names) == 0
+	for _, arn := range sortedKeys(api.loadBalancers) {
+		lb := api.loadBalancers[arn]
+		if all || names[*lb.LoadBalancerName] {
+			out.LoadBalancers = append(out.LoadBalancers, lb)
+			delete(names, *lb.LoadBalancerName)
+		}
+	}
+	for n := range names {
+		return nil, errorf("LoadBalancerNotFound", "Load balancers '[%s]' not found", n)
+	}
+	return out, nil
+}
+
+//CreateTargetGroup creates a target group
+func (api *elbv2API) CreateTargetGroup(in *elbv2.CreateTargetGroupInput) (*elbv2.CreateTargetGroupOutput, error) {
+	name := aws.StringValue(in.Name)
+	if !elbv2Name.MatchString(name) {
+		return nil, errorf("ValidationError", "The target group name '%s' is not valid", name)
+	}
+	for _, tg := range api.targetGroups {
+		if *tg.TargetGroupName == name {
+			return nil, errorf("DuplicateTargetGroupName", "A target group with the same name '%s' exists", name)
+		}
+	}
+	if _, err := api.ec2.vpc(in.VpcId); err != nil {
+		return nil, err
+	}
+	if in.Protocol == nil || in.Port == nil {
+		return nil, errorf("ValidationError", "A protocol and a port must be specified")
+	}
+	tg := &elbv2.TargetGroup{
+		HealthCheckEnabled:         aws.Bool(true),
+		HealthCheckIntervalSeconds: aws.Int64(30),
+		HealthCheckPort:            aws.String("traffic-port"),
+		HealthCheckProtocol:        in.Protocol,
+		HealthCheckTimeoutSeconds:  aws.Int64(10),
+		HealthyThresholdCount:      aws.Int64(3),
+		Port:                       in.Port,
+		Protocol:                   in.Protocol,
+		TargetGroupArn:             aws.String(api.arn("targetgroup/" + name)),
+		TargetGroupName:            aws.String(name),
+		TargetType:                 aws.String(elbv2.TargetTypeEnumInstance),
+		UnhealthyThresholdCount:    aws.Int64(3),
+		VpcId:                      in.VpcId,
+	}
+	if *tg.HealthCheckProtocol == "UDP" {
+		tg.HealthCheckProtocol = aws.String(elbv2.ProtocolEnumTcp)
+	}
+	if in.HealthCheckIntervalSeconds != nil {
+		tg.HealthCheckIntervalSeconds = in.HealthCheckIntervalSeconds
+	}
+	if in.HealthCheckPort != nil {
+		tg.HealthCheckPort = in.HealthCheckPort
+	}
+	if in.HealthCheckProtocol != nil {
+		tg.HealthCheckProtocol = in.HealthCheckProtocol
+	}
+	if in.HealthCheckTimeoutSeconds != nil {
+		tg.HealthCheckTimeoutSeconds = in.HealthCheckTimeoutSeconds
+	}
+	if in.HealthyThresholdCount != nil {
+		tg.HealthyThresholdCount = in.HealthyThresholdCount
+	}
+	if in.UnhealthyThresholdCount != nil {
+		tg.UnhealthyThresholdCount = in.UnhealthyThresholdCount
+	}
+	if in.TargetType != nil {
+		tg.TargetType = in.TargetType
+	}
+	switch *tg.HealthCheckProtocol {
+	case elbv2.ProtocolEnumHttp, elbv2.ProtocolEnumHttps:
+		tg.HealthCheckPath = aws.String("/")
+		if in.HealthCheckPath != nil {
+			tg.HealthCheckPath = in.HealthCheckPath
+		}
+		tg.Matcher = &elbv2.Matcher{HttpCode: aws.String("200")}
+	case elbv2.ProtocolEnumTcp:
+		if *tg.Protocol == elbv2.ProtocolEnumHttp || *tg.Protocol == elbv2.ProtocolEnumHttps {
+			return nil, errorf("ValidationError", "Health check protocol 'TCP' is not supported for target groups with the '%s' protocol", *tg.Protocol)
+		}
+		if in.HealthCheckPath != nil {
+			return nil, errorf("ValidationError", "A health check path cannot be specified for TCP health checks")
+		}
+	default:
+		return nil, errorf("ValidationError", "The health check protocol '%s' is not valid", *tg.HealthCheckProtocol)
+	}
+	api.targetGroups[*tg.TargetGroupArn] = tg
+	return &elbv2.CreateTargetGroupOutput{TargetGroups: []*elbv2.TargetGroup{tg}}, nil
+}
+
+//DeleteTargetGroup deletes a target group that is not used by a listener
+func (api *elbv2API) DeleteTargetGroup(in *elbv2.DeleteTargetGroupInput) (*elbv2.DeleteTargetGroupOutput, error) {
+	tg, err := api.targetGroup(in.TargetGroupArn)
+	if err != nil {
+		return nil, err
+	}
+	if len(tg.LoadBalancerArns) > 0 {
+		return nil, errorf("ResourceInUse", "Target group '%s' is currently in use by a listener or a rule", *tg.TargetGroupArn)
+	}
+	delete(api.targetGroups, *tg.TargetGroupArn)
+	delete(api.targets, *tg.TargetGroupArn)
+	return &elbv2.DeleteTargetGroupOutput{}, nil
+}
+
+//DescribeTargetGroups describes target groups
+func (api *elbv2API) DescribeTargetGroups(in *elbv2.DescribeTargetGroupsInput) (*elbv2.DescribeTargetGroupsOutput, error) {
+	out := &elbv2.DescribeTargetGroupsOutput{}
+	for _, arn := range in.TargetGroupArns {
+		tg, err := api.targetGroup(arn)
+		if err != nil {
+			return nil, err
+		}
+		out.TargetGroups = append(out.TargetGroups, tg)
+	}
+	if len(in.TargetGroupArns) > 0 {
+		return out, nil
+	}
+	if in.LoadBalancerArn != nil {
+		if _, err := api.loadBalancer(in.LoadBalancerArn); err != nil {
+			return nil, err
+		}
+	}
+	for _, arn := range sortedKeys(api.targetGroups) {
+		tg := api.targetGroups[arn]
+		if in.LoadBalancerArn == nil || hasValue(tg.LoadBalancerArns, *in.LoadBalancerArn) {
+			out.TargetGroups = append(out.TargetGroups, tg)
+		}
+	}
+	return out, nil
+}
+
+//hasValue returns true if values contains value
+func hasValue(values []*string, value string) bool {
+	for _, v := range values {
+		if aws.StringValue(v) == value {
+			return true
+		}
+	}
+	return false
+}
+
+//RegisterTargets registers instances in a target group
+func (api *elbv2API) RegisterTargets(in *elbv2.RegisterTargetsInput) (*elbv2.RegisterTargetsOutput, error) {
+	tg, err := api.targetGroup(in.TargetGroupArn)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range in.Targets {
+		inst, ok := api.ec2.instances[aws.StringValue(t.Id)]
+		if !ok || *inst.State.Code == terminated {
+			return nil, errorf("InvalidTarget", "The following targets are not valid instances: '%s'", aws.StringValue(t.Id))
+		}
+		if *inst.VpcId != *tg.VpcId {
+			return nil, errorf("InvalidTarget", "The following targets are not in the target group VPC '%s': '%s'", *tg.VpcId, *inst.InstanceId)
+		}
+	}
+	for _, t := range in.Targets {
+		registered := false
+		for _, r := range api.targets[*tg.TargetGroupArn] {
+			registered = registered || *r.Id == *t.Id
+		}
+		if !registered {
+			api.targets[*tg.TargetGroupArn] = append(api.targets[*tg.TargetGroupArn], &elbv2.TargetDescription{
+				Id:   t.Id,
+				Port: tg.Port,
+			})
+		}
+	}
+	return &elbv2.RegisterTargetsOutput{}, nil
+}
+
+//DescribeTargetHealth describes the targets of a target group, registered instances are healthy unless they are terminated
+func (api *elbv2API) DescribeTargetHealth(in *elbv2.DescribeTargetHealthInput) (*elbv2.DescribeTargetHealthOutput, error) {
+	tg, err := api.targetGroup(in.TargetGroupArn)
+	if err != nil {
+		return nil, err
+	}
+	out := &elbv2.DescribeTargetHealthOutput{}
+	var targets []*elbv2.TargetDescription
+	for _, t := range api.targets[*tg.TargetGroupArn] {
+		inst, ok := api.ec2.instances[*t.Id]
+		if !ok || *inst.State.Code == terminated {
+			continue
+		}
+		targets = append(targets, t)
+		port := *tg.HealthCheckPort
+		if port == "traffic-port" {
+			port = fmt.Sprint(*t.Port)
+		}
+		out.TargetHealthDescriptions = append(out.TargetHealthDescriptions, &elbv2.TargetHealthDescription{
+			HealthCheckPort: aws.String(port),
+			Target:          t,
+			TargetHealth:    &elbv2.TargetHealth{State: aws.String(elbv2.TargetHealthStateEnumHealthy)},
+		})
+	}
+	api.targets[*tg.TargetGroupArn] = targets
+	return out, nil
+}
+
+//listenerProtocols protocols of the listeners of each type of load balancer
+var listenerProtocols = map[string][]string{
+	elbv2.LoadBalancerTypeEnumApplication: {elbv2.ProtocolEnumHttp, elbv2.ProtocolEnumHttps},
+	elbv2.LoadBalancerTypeEnumNetwork:     {elbv2.ProtocolEnumTcp, elbv2.ProtocolEnumTls, "UDP", "TCP_UDP"},
+}
+
+//CreateListener creates a listener forwarding traffic to a target group
+func (api *elbv2API) CreateListener(in *elbv2.CreateListenerInput) (*elbv2.CreateListenerOutput, error) {
+	lb, err := api.loadBalancer(in.LoadBalancerArn)
+	if err != nil {
+		return nil, err
+	}
+	if !hasValue(aws.StringSlice(listenerProtocols[*lb.Type]), aws.StringValue(in.Protocol)) {
+		return nil, errorf("ValidationError", "The protocol '%s' is not supported by %s load balancers", aws.StringValue(in.Protocol), *lb.Type)
+	}
+	for _, l := range api.listeners {
+		if *l.LoadBalancerArn == *lb.LoadBalancerArn && *l.Port == aws.Int64Value(in.Port) {
+			return nil, errorf("DuplicateListener", "A listener already exists on port %d", *l.Port)
+		}
+	}
+	if len(in.DefaultActions) != 1 || aws.StringValue(in.DefaultActions[0].Type) != elbv2.ActionTypeEnumForward {
+		return nil, errorf("ValidationError", "A single forward default action must be specified")
+	}
+	tg, err := api.targetGroup(in.DefaultActions[0].TargetGroupArn)
+	if err != nil {
+		return nil, err
+	}
+	if *tg.Protocol != *in.Protocol || *tg.VpcId != *lb.VpcId {
+		return nil, errorf("ValidationError", "The target group '%s' cannot be used by the listener", *tg.TargetGroupArn)
+	}
+	lbID := strings.TrimPrefix(*lb.LoadBalancerArn, fmt.Sprintf("arn:aws:elasticloadbalancing:%s:%s:loadbalancer/", Region, AccountID))
+	l := &elbv2.Listener{
+		DefaultActions:  in.DefaultActions,
+		ListenerArn:     aws.String(api.arn("listener/" + lbID)),
+		LoadBalancerArn: lb.LoadBalancerArn,
+		Port:            in.Port,
+		Protocol:        in.Protocol,
+	}
+	api.listeners[*l.ListenerArn] = l
+	if !hasValue(tg.LoadBalancerArns, *lb.LoadBalancerArn) {
+		tg.LoadBalancerArns = append(tg.LoadBalancerArns, lb.LoadBalancerArn)
+	}
+	return &elbv2.CreateListenerOutput{Listeners: []*elbv2.Listener{l}}, nil
+}
+
+//DescribeListeners describes the listeners of a load balancer
+func (api *elbv2API) DescribeListeners(in *elbv2.DescribeListenersInput) (*elbv2.DescribeListenersOutput, error) {
+	out := &elbv2.DescribeListenersOutput{}
+	for _, arn := range in.ListenerArns {
+		l, err := api.listener(arn)
+		if err != nil {
+			return nil, err
+		}
+		out.Listeners = append(out.Listeners, l)
+	}
+	if len(in.ListenerArns) > 0 {
+		return out, nil
+	}
+	lb, err := api.loadBalancer(in.LoadBalancerArn)
+	if err != nil {
+		return nil, err
+	}
+	for _, arn := range sortedKeys(api.listeners) {
+		if l := api.listeners[arn]; *l.LoadBalancerArn == *lb.LoadBalancerArn {
+			out.Listeners = append(out.Listeners, l)
+		}
+	}
+	return out, nil
+}
+
+//DescribeTags describes the tags of load balancers and target groups
+func (api *elbv2API) DescribeTags(in *elbv2.DescribeTagsInput) (*elbv2.DescribeTagsOutput, error) {
+	out := &elbv2.DescribeTagsOutput{}
+	for _, arn := range in.ResourceArns {
+		_, isLB := api.loadBalancers[aws.StringValue(arn)]
+		_, isTG := api.targetGroups[aws.StringValue(arn)]
+		if !isLB && !isTG {
+			return nil, errorf("LoadBalancerNotFound", "Load balancer '%s' not found", aws.StringValue(arn))
+		}
+		out.TagDescriptions = append(out.TagDescriptions, &elbv2.TagDescription{
+			ResourceArn: arn,
+			Tags:        api.tags[*arn],
+		})
+	}
+	return out, nil
+}
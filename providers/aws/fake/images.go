@@ -0,0 +1,246 @@
+package fake
+
+import (
+	"crypto/md5"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"golang.org/x/crypto/ssh"
+)
+
+func newImage(owner, name, description, creationDate string) *ec2.Image {
+	return &ec2.Image{
+		Architecture: aws.String("x86_64"),
+		BlockDeviceMappings: []*ec2.BlockDeviceMapping{
+			{
+				DeviceName: aws.String("/dev/sda1"),
+				Ebs: &ec2.EbsBlockDevice{
+					DeleteOnTermination: aws.Bool(true),
+					Encrypted:           aws.Bool(false),
+					VolumeSize:          aws.Int64(8),
+					VolumeType:          aws.String("gp2"),
+				},
+			},
+		},
+		CreationDate:       aws.String(creationDate),
+		Description:        aws.String(description),
+		EnaSupport:         aws.Bool(true),
+		Hypervisor:         aws.String("xen"),
+		ImageLocation:      aws.String(fmt.Sprintf("%s/%s", owner, name)),
+		ImageType:          aws.String("machine"),
+		Name:               aws.String(name),
+		OwnerId:            aws.String(owner),
+		Public:             aws.Bool(true),
+		RootDeviceName:     aws.String("/dev/sda1"),
+		RootDeviceType:     aws.String("ebs"),
+		SriovNetSupport:    aws.String("simple"),
+		State:              aws.String("available"),
+		VirtualizationType: aws.String("hvm"),
+	}
+}
+
+//defaultImages returns the public images available in the fake
+func defaultImages() []*ec2.Image {
+	return []*ec2.Image{
+		newImage("099720109477", "ubuntu/images/hvm-ssd/ubuntu-bionic-18.04-amd64-server-20190627", "Canonical, Ubuntu, 18.04 LTS, amd64 bionic image build on 2019-06-27", "2019-06-27T16:21:47.000Z"),
+		newImage("099720109477", "ubuntu/images/hvm-ssd/ubuntu-bionic-18.04-amd64-server-20190212", "Canonical, Ubuntu, 18.04 LTS, amd64 bionic image build on 2019-02-12", "2019-02-12T18:52:31.000Z"),
+		newImage("099720109477", "ubuntu/images/hvm-ssd/ubuntu-xenial-16.04-amd64-server-20190628", "Canonical, Ubuntu, 16.04 LTS, amd64 xenial image build on 2019-06-28", "2019-06-28T14:11:23.000Z"),
+		newImage("309956199498", "RHEL-7.6_HVM_GA-20190128-x86_64-0-Hourly2-GP2", "Provided by Red Hat, Inc.", "2019-02-05T22:47:22.000Z"),
+		newImage("379101102735", "debian-stretch-hvm-x86_64-gp2-2019-05-14-84483", "Debian stretch amd64", "2019-05-14T11:56:45.000Z"),
+		newImage("410186602215", "CentOS Linux 7 x86_64 HVM EBS ENA 1901_01-b7ee8a69-ee97-4a49-9e68-afaee216db2e-ami-05713873c6794f575.4", "CentOS Linux 7 x86_64 HVM EBS ENA 1901_01", "2019-01-30T21:03:50.000Z"),
+	}
+}
+
+func (api *ec2API) image(id *string) (*ec2.Image, error) {
+	img, ok := api.images[aws.StringValue(id)]
+	if !ok {
+		return nil, errorf("InvalidAMIID.NotFound", "The image id '[%s]' does not exist", aws.StringValue(id))
+	}
+	return img, nil
+}
+
+//DescribeImages describes images
+func (api *ec2API) DescribeImages(in *ec2.DescribeImagesInput) (*ec2.DescribeImagesOutput, error) {
+	for _, id := range in.ImageIds {
+		if _, err := api.image(id); err != nil {
+			return nil, err
+		}
+	}
+	owners := owners(in.Owners)
+	out := &ec2.DescribeImagesOutput{}
+	for _, id := range sortedKeys(api.images) {
+		img := api.images[id]
+		if !contains(in.ImageIds, id) || !contains(owners, *img.OwnerId) {
+			continue
+		}
+		ok, err := match(in.Filters, func(name string) ([]string, bool) {
+			switch name {
+			case "image-id":
+				return values(img.ImageId), true
+			case "name":
+				return values(img.Name), true
+			case "owner-id":
+				return values(img.OwnerId), true
+			case "architecture":
+				return values(img.Architecture), true
+			case "virtualization-type":
+				return values(img.VirtualizationType), true
+			case "root-device-type":
+				return values(img.RootDeviceType), true
+			case "state":
+				return values(img.State), true
+			case "is-public":
+				return bools(img.Public), true
+			case "block-device-mapping.volume-type":
+				var types []string
+				for _, bdm := range img.BlockDeviceMappings {
+					if bdm.Ebs != nil {
+						types = append(types, values(bdm.Ebs.VolumeType)...)
+					}
+				}
+				return types, true
+			}
+			return tagValues(img.Tags, name)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out.Images = append(out.Images, img)
+		}
+	}
+	return out, nil
+}
+
+//fingerprint returns the MD5 fingerprint of a public key as computed by EC2 for imported keys
+func fingerprint(key ssh.PublicKey) string {
+	sum := md5.Sum(key.Marshal())
+	hex := make([]string, len(sum))
+	for i, b := range sum {
+		hex[i] = fmt.Sprintf("%02x", b)
+	}
+	return strings.Join(hex, ":")
+}
+
+//ImportKeyPair imports a public key
+func (api *ec2API) ImportKeyPair(in *ec2.ImportKeyPairInput) (*ec2.ImportKeyPairOutput, error) {
+	name := aws.StringValue(in.KeyName)
+	if name == "" {
+		return nil, errorf("MissingParameter", "The request must contain the parameter KeyName")
+	}
+	if _, ok := api.keyPairs[name]; ok {
+		return nil, errorf("InvalidKeyPair.Duplicate", "The keypair '%s' already exists.", name)
+	}
+	key, _, _, _, err := ssh.ParseAuthorizedKey(in.PublicKeyMaterial)
+	if err != nil {
+		return nil, errorf("InvalidKey.Format", "Key is not in valid OpenSSH public key format")
+	}
+	kp := &ec2.KeyPairInfo{
+		KeyFingerprint: aws.String(fingerprint(key)),
+		KeyName:        aws.String(name),
+	}
+	api.keyPairs[name] = kp
+	return &ec2.ImportKeyPairOutput{
+		KeyFingerprint: kp.KeyFingerprint,
+		KeyName:        kp.KeyName,
+	}, nil
+}
+
+//DeleteKeyPair deletes a key pair, deleting an unknown key pair is not an error
+func (api *ec2API) DeleteKeyPair(in *ec2.DeleteKeyPairInput) (*ec2.DeleteKeyPairOutput, error) {
+	delete(api.keyPairs, aws.StringValue(in.KeyName))
+	return &ec2.DeleteKeyPairOutput{}, nil
+}
+
+//DescribeKeyPairs describes key pairs
+func (api *ec2API) DescribeKeyPairs(in *ec2.DescribeKeyPairsInput) (*ec2.DescribeKeyPairsOutput, error) {
+	for _, name := range in.KeyNames {
+		if _, ok := api.keyPairs[aws.StringValue(name)]; !ok {
+			return nil, errorf("InvalidKeyPair.NotFound", "The key pair '%s' does not exist", aws.StringValue(name))
+		}
+	}
+	out := &ec2.DescribeKeyPairsOutput{}
+	for _, name := range sortedKeys(api.keyPairs) {
+		kp := api.keyPairs[name]
+		if !contains(in.KeyNames, name) {
+			continue
+		}
+		ok, err := match(in.Filters, func(name string) ([]string, bool) {
+			switch name {
+			case "key-name":
+				return values(kp.KeyName), true
+			case "fingerprint":
+				return values(kp.KeyFingerprint), true
+			}
+			return nil, false
+		})
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out.KeyPairs = append(out.KeyPairs, kp)
+		}
+	}
+	return out, nil
+}
+
+//CreateImage creates an image from the volumes attached to an instance, the image is available immediately
+func (api *ec2API) CreateImage(in *ec2.CreateImageInput) (*ec2.CreateImageOutput, error) {
+	inst, err := api.instance(in.InstanceId)
+	if err != nil {
+		return nil, err
+	}
+	if *inst.State.Code != running && *inst.State.Code != stopped {
+		return nil, errorf("IncorrectInstanceState", "The instance '%s' is not in a state from which it can be imaged.", *inst.InstanceId)
+	}
+	name := aws.StringValue(in.Name)
+	if name == "" {
+		return nil, errorf("MissingParameter", "The request must contain the parameter name")
+	}
+	for _, img := range api.images {
+		if *img.OwnerId == AccountID && *img.Name == name {
+			return nil, errorf("InvalidAMIName.Duplicate", "AMI name %s is already in use by AMI %s", name, *img.ImageId)
+		}
+	}
+	img := newImage(AccountID, name, aws.StringValue(in.Description), time.Now().UTC().Format("2006-01-02T15:04:05.000Z"))
+	img.ImageId = aws.String(api.newID("ami"))
+	img.Public = aws.Bool(false)
+	img.RootDeviceName = inst.RootDeviceName
+	img.BlockDeviceMappings = nil
+	for _, id := range sortedKeys(api.volumes) {
+		v := api.volumes[id]
+		if len(v.Attachments) == 0 || *v.Attachments[0].InstanceId != *inst.InstanceId {
+			continue
+		}
+		att := v.Attachments[0]
+		s := api.createSnapshot(v, fmt.Sprintf("Created by CreateImage(%s) for %s", *inst.InstanceId, *img.ImageId), nil)
+		img.BlockDeviceMappings = append(img.BlockDeviceMappings, &ec2.BlockDeviceMapping{
+			DeviceName: att.Device,
+			Ebs: &ec2.EbsBlockDevice{
+				DeleteOnTermination: aws.Bool(aws.BoolValue(att.DeleteOnTermination)),
+				Encrypted:           aws.Bool(aws.BoolValue(v.Encrypted)),
+				SnapshotId:          s.SnapshotId,
+				VolumeSize:          aws.Int64(*v.Size),
+				VolumeType:          aws.String(*v.VolumeType),
+			},
+		})
+	}
+	api.images[*img.ImageId] = img
+	return &ec2.CreateImageOutput{ImageId: img.ImageId}, nil
+}
+
+//DeregisterImage deregisters an image, the snapshots of the image are kept
+func (api *ec2API) DeregisterImage(in *ec2.DeregisterImageInput) (*ec2.DeregisterImageOutput, error) {
+	img, err := api.image(in.ImageId)
+	if err != nil {
+		return nil, err
+	}
+	if *img.OwnerId != AccountID {
+		return nil, errorf("AuthFailure", "Not authorized for image:%s", *img.ImageId)
+	}
+	delete(api.images, *img.ImageId)
+	return &ec2.DeregisterImageOutput{}, nil
+}
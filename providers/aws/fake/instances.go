@@ -0,0 +1,711 @@
+package fake
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+//Instance state codes
+const (
+	pending    = 0
+	running    = 16
+	terminated = 48
+	stopped    = 80
+)
+
+var stateNames = map[int64]string{
+	pending:    "pending",
+	running:    "running",
+	terminated: "terminated",
+	stopped:    "stopped",
+}
+
+func instanceState(code int64) *ec2.InstanceState {
+	return &ec2.InstanceState{
+		Code: aws.Int64(code),
+		Name: aws.String(stateNames[code]),
+	}
+}
+
+func (api *ec2API) instance(id *string) (*ec2.Instance, error) {
+	if err := checkID("InvalidInstanceID.Malformed", "i", id); err != nil {
+		return nil, err
+	}
+	inst, ok := api.instances[aws.StringValue(id)]
+	if !ok {
+		return nil, errorf("InvalidInstanceID.NotFound", "The instance ID '%s' does not exist", aws.StringValue(id))
+	}
+	return inst, nil
+}
+
+//refreshInstance updates the network and storage description of an instance from the state of its network interfaces and volumes
+func (api *ec2API) refreshInstance(inst *ec2.Instance) {
+	if *inst.State.Code == terminated {
+		return
+	}
+	inst.NetworkInterfaces = nil
+	inst.SecurityGroups = nil
+	inst.PublicIpAddress = nil
+	for _, id := range sortedKeys(api.interfaces) {
+		ni := api.interfaces[id]
+		if ni.Attachment == nil || aws.StringValue(ni.Attachment.InstanceId) != *inst.InstanceId {
+			continue
+		}
+		ini := &ec2.InstanceNetworkInterface{
+			Attachment: &ec2.InstanceNetworkInterfaceAttachment{
+				AttachTime:          ni.Attachment.AttachTime,
+				AttachmentId:        ni.Attachment.AttachmentId,
+				DeleteOnTermination: ni.Attachment.DeleteOnTermination,
+				DeviceIndex:         ni.Attachment.DeviceIndex,
+				Status:              ni.Attachment.Status,
+			},
+			Description:        ni.Description,
+			Groups:             ni.Groups,
+			MacAddress:         ni.MacAddress,
+			NetworkInterfaceId: ni.NetworkInterfaceId,
+			OwnerId:            ni.OwnerId,
+			PrivateIpAddress:   ni.PrivateIpAddress,
+			SourceDestCheck:    ni.SourceDestCheck,
+			Status:             ni.Status,
+			SubnetId:           ni.SubnetId,
+			VpcId:              ni.VpcId,
+		}
+		if ni.Association != nil {
+			ini.Association = &ec2.InstanceNetworkInterfaceAssociation{
+				IpOwnerId: ni.Association.IpOwnerId,
+				PublicIp:  ni.Association.PublicIp,
+			}
+		}
+		inst.NetworkInterfaces = append(inst.NetworkInterfaces, ini)
+		if aws.Int64Value(ni.Attachment.DeviceIndex) == 0 {
+			inst.PrivateIpAddress = ni.PrivateIpAddress
+			inst.SubnetId = ni.SubnetId
+			inst.VpcId = ni.VpcId
+			inst.SecurityGroups = ni.Groups
+			if ni.Association != nil {
+				inst.PublicIpAddress = ni.Association.PublicIp
+			}
+		}
+	}
+	inst.BlockDeviceMappings = nil
+	for _, id := range sortedKeys(api.volumes) {
+		for _, att := range api.volumes[id].Attachments {
+			if *att.InstanceId != *inst.InstanceId {
+				continue
+			}
+			inst.BlockDeviceMappings = append(inst.BlockDeviceMappings, &ec2.InstanceBlockDeviceMapping{
+				DeviceName: att.Device,
+				Ebs: &ec2.EbsInstanceBlockDevice{
+					AttachTime:          att.AttachTime,
+					DeleteOnTermination: att.DeleteOnTermination,
+					Status:              att.State,
+					VolumeId:            att.VolumeId,
+				},
+			})
+		}
+	}
+}
+
+//launchSpecification parameters of the instances to launch, common to RunInstances and RequestSpotInstances
+type launchSpecification struct {
+	imageID           *string
+	instanceType      *string
+	keyName           *string
+	availabilityZone  *string
+	subnetID          *string
+	securityGroupIDs  []*string
+	networkInterfaces []*ec2.InstanceNetworkInterfaceSpecification
+	tagSpecifications []*ec2.TagSpecification
+	spotRequestID     *string
+}
+
+func (api *ec2API) checkLaunchSpecification(spec *launchSpecification) error {
+	if _, err := api.image(spec.imageID); err != nil {
+		return err
+	}
+	if findInstanceType(aws.StringValue(spec.instanceType)) == nil {
+		return errorf("InvalidParameterValue", "Invalid value '%s' for InstanceType.", aws.StringValue(spec.instanceType))
+	}
+	if spec.keyName != nil {
+		if _, ok := api.keyPairs[*spec.keyName]; !ok {
+			return errorf("InvalidKeyPair.NotFound", "The key pair '%s' does not exist", *spec.keyName)
+		}
+	}
+	if len(spec.networkInterfaces) == 0 {
+		if spec.subnetID == nil {
+			return errorf("MissingInput", "No subnet specified and no default subnet is available")
+		}
+		if _, err := api.subnet(spec.subnetID); err != nil {
+			return err
+		}
+		for _, g := range spec.securityGroupIDs {
+			if _, err := api.securityGroup(g); err != nil {
+				return err
+			}
+		}
+	}
+	for _, ni := range spec.networkInterfaces {
+		if ni.NetworkInterfaceId != nil {
+			eni, err := api.networkInterface(ni.NetworkInterfaceId)
+			if err != nil {
+				return err
+			}
+			if eni.Attachment != nil {
+				return errorf("InvalidNetworkInterface.InUse", "Interface: [%s] in use.", *eni.NetworkInterfaceId)
+			}
+			continue
+		}
+		if _, err := api.subnet(ni.SubnetId); err != nil {
+			return err
+		}
+		for _, g := range ni.Groups {
+			if _, err := api.securityGroup(g); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+//cpuOptions returns the CPU options of the instances of type it, instances with an even number of vCPUs have 2 threads per core
+func (it *instanceType) cpuOptions() *ec2.CpuOptions {
+	if it.vcpu%2 == 0 {
+		return &ec2.CpuOptions{CoreCount: aws.Int64(int64(it.vcpu / 2)), ThreadsPerCore: aws.Int64(2)}
+	}
+	return &ec2.CpuOptions{CoreCount: aws.Int64(int64(it.vcpu)), ThreadsPerCore: aws.Int64(1)}
+}
+
+//launch creates an instance, its network interfaces and its root volume
+func (api *ec2API) launch(spec *launchSpecification, index int64) (*ec2.Instance, error) {
+	img := api.images[*spec.imageID]
+	now := time.Now().UTC().Truncate(time.Second)
+	inst := &ec2.Instance{
+		AmiLaunchIndex:        aws.Int64(index),
+		Architecture:          img.Architecture,
+		EbsOptimized:          aws.Bool(false),
+		EnaSupport:            img.EnaSupport,
+		Hypervisor:            img.Hypervisor,
+		ImageId:               img.ImageId,
+		InstanceId:            aws.String(api.newID("i")),
+		InstanceType:          spec.instanceType,
+		KeyName:               spec.keyName,
+		LaunchTime:            aws.Time(now),
+		Placement:             &ec2.Placement{AvailabilityZone: spec.availabilityZone, Tenancy: aws.String("default")},
+		RootDeviceName:        img.RootDeviceName,
+		RootDeviceType:        img.RootDeviceType,
+		SpotInstanceRequestId: spec.spotRequestID,
+		State:                 instanceState(running),
+		Tags:                  tagSpecifications(spec.tagSpecifications, "instance"),
+		VirtualizationType:    img.VirtualizationType,
+	}
+	if it := findInstanceType(aws.StringValue(spec.instanceType)); it != nil {
+		inst.CpuOptions = it.cpuOptions()
+	}
+	if spec.spotRequestID != nil {
+		inst.InstanceLifecycle = aws.String("spot")
+	}
+	if inst.Placement.AvailabilityZone == nil {
+		inst.Placement.AvailabilityZone = aws.String(AvailabilityZone)
+	}
+	api.instances[*inst.InstanceId] = inst
+
+	nis := spec.networkInterfaces
+	if len(nis) == 0 {
+		nis = []*ec2.InstanceNetworkInterfaceSpecification{
+			{
+				DeviceIndex: aws.Int64(0),
+				Groups:      spec.securityGroupIDs,
+				SubnetId:    spec.subnetID,
+			},
+		}
+	}
+	niTags := tagSpecifications(spec.tagSpecifications, "network-interface")
+	for i, s := range nis {
+		var ni *ec2.NetworkInterface
+		if s.NetworkInterfaceId != nil {
+			ni = api.interfaces[*s.NetworkInterfaceId]
+		} else {
+			var err error
+			ni, err = api.createNetworkInterface(api.subnets[*s.SubnetId], s.Groups, s.PrivateIpAddress, s.Description)
+			if err != nil {
+				api.terminate(inst)
+				delete(api.instances, *inst.InstanceId)
+				return nil, err
+			}
+			ni.TagSet = setTags(ni.TagSet, niTags)
+		}
+		deviceIndex := s.DeviceIndex
+		if deviceIndex == nil {
+			deviceIndex = aws.Int64(int64(i))
+		}
+		deleteOnTermination := s.DeleteOnTermination
+		if deleteOnTermination == nil {
+			deleteOnTermination = aws.Bool(s.NetworkInterfaceId == nil)
+		}
+		api.attachNetworkInterface(ni, inst, deviceIndex, deleteOnTermination)
+	}
+	for _, bdm := range img.BlockDeviceMappings {
+		if bdm.Ebs == nil {
+			continue
+		}
+		v := api.createVolume(*inst.Placement.AvailabilityZone, *bdm.Ebs.VolumeSize, *bdm.Ebs.VolumeType, nil, tagSpecifications(spec.tagSpecifications, "volume"))
+		api.attachVolume(v, inst, *bdm.DeviceName, aws.BoolValue(bdm.Ebs.DeleteOnTermination))
+	}
+	api.refreshInstance(inst)
+	return inst, nil
+}
+
+//terminate terminates an instance, its network interfaces and volumes are deleted or detached
+func (api *ec2API) terminate(inst *ec2.Instance) {
+	for _, id := range sortedKeys(api.interfaces) {
+		ni := api.interfaces[id]
+		if ni.Attachment == nil || *ni.Attachment.InstanceId != *inst.InstanceId {
+			continue
+		}
+		deleteOnTermination := aws.BoolValue(ni.Attachment.DeleteOnTermination)
+		api.detachNetworkInterface(ni)
+		if deleteOnTermination {
+			for _, addr := range api.addresses {
+				if aws.StringValue(addr.NetworkInterfaceId) == id {
+					api.disassociateAddress(addr)
+				}
+			}
+			delete(api.interfaces, id)
+		}
+	}
+	for _, id := range sortedKeys(api.volumes) {
+		v := api.volumes[id]
+		for _, att := range v.Attachments {
+			if *att.InstanceId != *inst.InstanceId {
+				continue
+			}
+			deleteOnTermination := aws.BoolValue(att.DeleteOnTermination)
+			api.detachVolume(v)
+			if deleteOnTermination {
+				delete(api.volumes, id)
+			}
+		}
+	}
+	if inst.SpotInstanceRequestId != nil {
+		if req, ok := api.spotRequests[*inst.SpotInstanceRequestId]; ok {
+			req.State = aws.String("closed")
+			req.Status = spotStatus("instance-terminated-by-user")
+		}
+	}
+	inst.State = instanceState(terminated)
+	inst.StateTransitionReason = aws.String("User initiated")
+	inst.NetworkInterfaces = nil
+	inst.SecurityGroups = nil
+	inst.PrivateIpAddress = nil
+	inst.PublicIpAddress = nil
+	inst.BlockDeviceMappings = nil
+}
+
+//RunInstances launches instances
+func (api *ec2API) RunInstances(in *ec2.RunInstancesInput) (*ec2.Reservation, error) {
+	spec := &launchSpecification{
+		imageID:           in.ImageId,
+		instanceType:      in.InstanceType,
+		keyName:           in.KeyName,
+		subnetID:          in.SubnetId,
+		securityGroupIDs:  in.SecurityGroupIds,
+		networkInterfaces: in.NetworkInterfaces,
+		tagSpecifications: in.TagSpecifications,
+	}
+	if in.Placement != nil {
+		spec.availabilityZone = in.Placement.AvailabilityZone
+	}
+	err := api.checkLaunchSpecification(spec)
+	if err != nil {
+		return nil, err
+	}
+	count := aws.Int64Value(in.MaxCount)
+	if count < 1 {
+		count = 1
+	}
+	return api.reserve(spec, count)
+}
+
+//reserve launches count instances in a new reservation
+func (api *ec2API) reserve(spec *launchSpecification, count int64) (*ec2.Reservation, error) {
+	res := &ec2.Reservation{
+		OwnerId:       aws.String(AccountID),
+		ReservationId: aws.String(api.newID("r")),
+	}
+	for i := int64(0); i < count; i++ {
+		inst, err := api.launch(spec, i)
+		if err != nil {
+			for _, inst := range res.Instances {
+				api.terminate(inst)
+				delete(api.instances, *inst.InstanceId)
+			}
+			return nil, err
+		}
+		res.Instances = append(res.Instances, inst)
+	}
+	api.reservations[*res.ReservationId] = res
+	return res, nil
+}
+
+func instanceFilter(inst *ec2.Instance) fieldValues {
+	return func(name string) ([]string, bool) {
+		switch name {
+		case "instance-id":
+			return values(inst.InstanceId), true
+		case "instance-type":
+			return values(inst.InstanceType), true
+		case "image-id":
+			return values(inst.ImageId), true
+		case "instance-state-name":
+			return values(inst.State.Name), true
+		case "instance-state-code":
+			return []string{fmt.Sprint(*inst.State.Code)}, true
+		case "key-name":
+			return values(inst.KeyName), true
+		case "vpc-id":
+			return values(inst.VpcId), true
+		case "subnet-id":
+			return values(inst.SubnetId), true
+		case "availability-zone":
+			return values(inst.Placement.AvailabilityZone), true
+		case "spot-instance-request-id":
+			return values(inst.SpotInstanceRequestId), true
+		case "instance-lifecycle":
+			return values(inst.InstanceLifecycle), true
+		}
+		return tagValues(inst.Tags, name)
+	}
+}
+
+//DescribeInstances describes instances, terminated instances are kept in the results
+func (api *ec2API) DescribeInstances(in *ec2.DescribeInstancesInput) (*ec2.DescribeInstancesOutput, error) {
+	for _, id := range in.InstanceIds {
+		if _, err := api.instance(id); err != nil {
+			return nil, err
+		}
+	}
+	out := &ec2.DescribeInstancesOutput{}
+	for _, id := range sortedKeys(api.reservations) {
+		res := api.reservations[id]
+		var instances []*ec2.Instance
+		for _, inst := range res.Instances {
+			if !contains(in.InstanceIds, *inst.InstanceId) {
+				continue
+			}
+			api.refreshInstance(inst)
+			ok, err := match(in.Filters, instanceFilter(inst))
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				instances = append(instances, inst)
+			}
+		}
+		if len(instances) > 0 {
+			out.Reservations = append(out.Reservations, &ec2.Reservation{
+				Instances:     instances,
+				OwnerId:       res.OwnerId,
+				ReservationId: res.ReservationId,
+			})
+		}
+	}
+	return out, nil
+}
+
+//DescribeInstanceStatus describes the status of instances, all the running instances are healthy
+func (api *ec2API) DescribeInstanceStatus(in *ec2.DescribeInstanceStatusInput) (*ec2.DescribeInstanceStatusOutput, error) {
+	for _, id := range in.InstanceIds {
+		if _, err := api.instance(id); err != nil {
+			return nil, err
+		}
+	}
+	out := &ec2.DescribeInstanceStatusOutput{}
+	for _, id := range sortedKeys(api.instances) {
+		inst := api.instances[id]
+		if !contains(in.InstanceIds, id) {
+			continue
+		}
+		if *inst.State.Code != running && !aws.BoolValue(in.IncludeAllInstances) {
+			continue
+		}
+		ok, err := match(in.Filters, instanceFilter(inst))
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		status := "not-applicable"
+		if *inst.State.Code == running {
+			status = "ok"
+		}
+		summary := func() *ec2.InstanceStatusSummary {
+			return &ec2.InstanceStatusSummary{
+				Details: []*ec2.InstanceStatusDetails{
+					{
+						Name:   aws.String("reachability"),
+						Status: aws.String("passed"),
+					},
+				},
+				Status: aws.String(status),
+			}
+		}
+		out.InstanceStatuses = append(out.InstanceStatuses, &ec2.InstanceStatus{
+			AvailabilityZone: inst.Placement.AvailabilityZone,
+			InstanceId:       inst.InstanceId,
+			InstanceState:    inst.State,
+			InstanceStatus:   summary(),
+			SystemStatus:     summary(),
+		})
+	}
+	return out, nil
+}
+
+//ModifyInstanceAttribute modifies the security groups of the primary network interface or the type of a stopped instance
+func (api *ec2API) ModifyInstanceAttribute(in *ec2.ModifyInstanceAttributeInput) (*ec2.ModifyInstanceAttributeOutput, error) {
+	inst, err := api.instance(in.InstanceId)
+	if err != nil {
+		return nil, err
+	}
+	if *inst.State.Code == terminated {
+		return nil, errorf("IncorrectInstanceState", "The instance '%s' is not in a state from which it can be modified.", *inst.InstanceId)
+	}
+	instanceType := in.InstanceType
+	if aws.StringValue(in.Attribute) == "instanceType" {
+		instanceType = &ec2.AttributeValue{Value: in.Value}
+	}
+	if instanceType != nil {
+		if findInstanceType(aws.StringValue(instanceType.Value)) == nil {
+			return nil, errorf("InvalidParameterValue", "Invalid value '%s' for InstanceType.", aws.StringValue(instanceType.Value))
+		}
+		if *inst.State.Code != stopped {
+			return nil, errorf("IncorrectInstanceState", "The instance '%s' is not in the 'stopped' state.", *inst.InstanceId)
+		}
+		if arch := findInstanceType(*instanceType.Value).architecture(); arch != aws.StringValue(inst.Architecture) {
+			return nil, errorf("InvalidParameterValue", "Instance type '%s' does not support the %s architecture.", *instanceType.Value, aws.StringValue(inst.Architecture))
+		}
+		inst.InstanceType = instanceType.Value
+	}
+	if len(in.Groups) > 0 {
+		for _, g := range in.Groups {
+			if _, err := api.securityGroup(g); err != nil {
+				return nil, err
+			}
+		}
+		for _, ni := range api.interfaces {
+			if ni.Attachment != nil && *ni.Attachment.InstanceId == *inst.InstanceId && aws.Int64Value(ni.Attachment.DeviceIndex) == 0 {
+				ni.Groups = api.groupIdentifiers(in.Groups)
+			}
+		}
+		api.refreshInstance(inst)
+	}
+	return &ec2.ModifyInstanceAttributeOutput{}, nil
+}
+
+//changeState changes the state of instances, states lists the states from which the transition is allowed
+func (api *ec2API) changeState(ids []*string, code int64, states ...int64) ([]*ec2.InstanceStateChange, error) {
+	var instances []*ec2.Instance
+	for _, id := range ids {
+		inst, err := api.instance(id)
+		if err != nil {
+			return nil, err
+		}
+		allowed := *inst.State.Code == code
+		for _, s := range states {
+			allowed = allowed || *inst.State.Code == s
+		}
+		if !allowed {
+			return nil, errorf("IncorrectInstanceState", "The instance '%s' is not in a state from which it can be %s.", *inst.InstanceId, stateNames[code])
+		}
+		instances = append(instances, inst)
+	}
+	var changes []*ec2.InstanceStateChange
+	for _, inst := range instances {
+		change := &ec2.InstanceStateChange{
+			InstanceId:    inst.InstanceId,
+			PreviousState: inst.State,
+		}
+		if code == terminated {
+			if *inst.State.Code != terminated {
+				api.terminate(inst)
+			}
+		} else {
+			inst.State = instanceState(code)
+		}
+		change.CurrentState = inst.State
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+//StartInstances starts stopped instances
+func (api *ec2API) StartInstances(in *ec2.StartInstancesInput) (*ec2.StartInstancesOutput, error) {
+	changes, err := api.changeState(in.InstanceIds, running, stopped)
+	if err != nil {
+		return nil, err
+	}
+	return &ec2.StartInstancesOutput{StartingInstances: changes}, nil
+}
+
+//StopInstances stops running instances
+func (api *ec2API) StopInstances(in *ec2.StopInstancesInput) (*ec2.StopInstancesOutput, error) {
+	changes, err := api.changeState(in.InstanceIds, stopped, running)
+	if err != nil {
+		return nil, err
+	}
+	return &ec2.StopInstancesOutput{StoppingInstances: changes}, nil
+}
+
+//TerminateInstances terminates instances
+func (api *ec2API) TerminateInstances(in *ec2.TerminateInstancesInput) (*ec2.TerminateInstancesOutput, error) {
+	changes, err := api.changeState(in.InstanceIds, terminated, pending, running, stopped)
+	if err != nil {
+		return nil, err
+	}
+	return &ec2.TerminateInstancesOutput{TerminatingInstances: changes}, nil
+}
+
+func spotStatus(code string) *ec2.SpotInstanceStatus {
+	return &ec2.SpotInstanceStatus{
+		Code:       aws.String(code),
+		UpdateTime: aws.Time(time.Now().UTC().Truncate(time.Second)),
+	}
+}
+
+func (api *ec2API) spotRequest(id *string) (*ec2.SpotInstanceRequest, error) {
+	req, ok := api.spotRequests[aws.StringValue(id)]
+	if !ok {
+		return nil, errorf("InvalidSpotInstanceRequestID.NotFound", "The spot instance request ID '%s' does not exist", aws.StringValue(id))
+	}
+	return req, nil
+}
+
+//RequestSpotInstances creates spot instance requests, the requests are fulfilled immediately
+func (api *ec2API) RequestSpotInstances(in *ec2.RequestSpotInstancesInput) (*ec2.RequestSpotInstancesOutput, error) {
+	ls := in.LaunchSpecification
+	if ls == nil {
+		return nil, errorf("MissingParameter", "The request must contain the parameter LaunchSpecification")
+	}
+	spec := &launchSpecification{
+		imageID:           ls.ImageId,
+		instanceType:      ls.InstanceType,
+		keyName:           ls.KeyName,
+		subnetID:          ls.SubnetId,
+		networkInterfaces: ls.NetworkInterfaces,
+	}
+	spec.securityGroupIDs = append(spec.securityGroupIDs, ls.SecurityGroupIds...)
+	for _, name := range ls.SecurityGroups {
+		for _, sg := range api.securityGroups {
+			if *sg.GroupName == aws.StringValue(name) {
+				spec.securityGroupIDs = append(spec.securityGroupIDs, sg.GroupId)
+			}
+		}
+	}
+	if ls.Placement != nil {
+		spec.availabilityZone = ls.Placement.AvailabilityZone
+	}
+	err := api.checkLaunchSpecification(spec)
+	if err != nil {
+		return nil, err
+	}
+	count := aws.Int64Value(in.InstanceCount)
+	if count < 1 {
+		count = 1
+	}
+	out := &ec2.RequestSpotInstancesOutput{}
+	for i := int64(0); i < count; i++ {
+		req := &ec2.SpotInstanceRequest{
+			BlockDurationMinutes:     in.BlockDurationMinutes,
+			CreateTime:               aws.Time(time.Now().UTC().Truncate(time.Second)),
+			LaunchSpecification:      &ec2.LaunchSpecification{ImageId: ls.ImageId, InstanceType: ls.InstanceType, KeyName: ls.KeyName},
+			LaunchedAvailabilityZone: spec.availabilityZone,
+			ProductDescription:       aws.String("Linux/UNIX"),
+			SpotInstanceRequestId:    aws.String(api.newID("sir")),
+			SpotPrice:                in.SpotPrice,
+			State:                    aws.String("active"),
+			Status:                   spotStatus("fulfilled"),
+			Type:                     in.Type,
+		}
+		spec.spotRequestID = req.SpotInstanceRequestId
+		res, err := api.reserve(spec, 1)
+		if err != nil {
+			return nil, err
+		}
+		req.InstanceId = res.Instances[0].InstanceId
+		api.spotRequests[*req.SpotInstanceRequestId] = req
+		out.SpotInstanceRequests = append(out.SpotInstanceRequests, req)
+	}
+	return out, nil
+}
+
+//DescribeSpotInstanceRequests describes spot instance requests
+func (api *ec2API) DescribeSpotInstanceRequests(in *ec2.DescribeSpotInstanceRequestsInput) (*ec2.DescribeSpotInstanceRequestsOutput, error) {
+	for _, id := range in.SpotInstanceRequestIds {
+		if _, err := api.spotRequest(id); err != nil {
+			return nil, err
+		}
+	}
+	out := &ec2.DescribeSpotInstanceRequestsOutput{}
+	for _, id := range sortedKeys(api.spotRequests) {
+		req := api.spotRequests[id]
+		if !contains(in.SpotInstanceRequestIds, id) {
+			continue
+		}
+		ok, err := match(in.Filters, func(name string) ([]string, bool) {
+			switch name {
+			case "spot-instance-request-id":
+				return values(req.SpotInstanceRequestId), true
+			case "instance-id":
+				return values(req.InstanceId), true
+			case "state":
+				return values(req.State), true
+			case "status-code":
+				return values(req.Status.Code), true
+			}
+			return tagValues(req.Tags, name)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out.SpotInstanceRequests = append(out.SpotInstanceRequests, req)
+		}
+	}
+	return out, nil
+}
+
+//CancelSpotInstanceRequests cancels spot instance requests, the instances are not terminated
+func (api *ec2API) CancelSpotInstanceRequests(in *ec2.CancelSpotInstanceRequestsInput) (*ec2.CancelSpotInstanceRequestsOutput, error) {
+	out := &ec2.CancelSpotInstanceRequestsOutput{}
+	for _, id := range in.SpotInstanceRequestIds {
+		req, err := api.spotRequest(id)
+		if err != nil {
+			return nil, err
+		}
+		req.State = aws.String("cancelled")
+		req.Status = spotStatus("request-canceled-and-instance-running")
+		out.CancelledSpotInstanceRequests = append(out.CancelledSpotInstanceRequests, &ec2.CancelledSpotInstanceRequest{
+			SpotInstanceRequestId: req.SpotInstanceRequestId,
+			State:                 req.State,
+		})
+	}
+	return out, nil
+}
+
+//DescribeReservedInstances describes reserved instances, the fake does not sell reserved instances
+func (api *ec2API) DescribeReservedInstances(in *ec2.DescribeReservedInstancesInput) (*ec2.DescribeReservedInstancesOutput, error) {
+	return &ec2.DescribeReservedInstancesOutput{}, nil
+}
+
+//DescribeReservedInstancesOfferings describes reserved instance offerings, the fake does not sell reserved instances
+func (api *ec2API) DescribeReservedInstancesOfferings(in *ec2.DescribeReservedInstancesOfferingsInput) (*ec2.DescribeReservedInstancesOfferingsOutput, error) {
+	return &ec2.DescribeReservedInstancesOfferingsOutput{}, nil
+}
+
+//PurchaseReservedInstancesOffering purchases a reserved instance offering, the fake does not sell reserved instances
+func (api *ec2API) PurchaseReservedInstancesOffering(in *ec2.PurchaseReservedInstancesOfferingInput) (*ec2.PurchaseReservedInstancesOfferingOutput, error) {
+	return nil, errorf("InvalidReservedInstancesOfferingId.NotFound", "The reserved instances offering ID '%s' does not exist", aws.StringValue(in.ReservedInstancesOfferingId))
+}
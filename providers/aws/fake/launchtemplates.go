@@ -0,0 +1,98 @@
+package fake
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+//launchTemplateName valid launch template names
+var launchTemplateName = regexp.MustCompile(`^[a-zA-Z0-9().\-/_]{3,128}$`)
+
+//launchTemplate a launch template and the data of its single version
+type launchTemplate struct {
+	ec2.LaunchTemplate
+	data *ec2.RequestLaunchTemplateData
+}
+
+//launchTemplate returns the launch template identified by id or named name
+func (api *ec2API) launchTemplate(id, name *string) (*launchTemplate, error) {
+	if id != nil {
+		t, ok := api.launchTemplates[*id]
+		if !ok {
+			return nil, errorf("InvalidLaunchTemplateId.NotFound", "The specified launch template, with template ID %s, does not exist.", *id)
+		}
+		return t, nil
+	}
+	for _, t := range api.launchTemplates {
+		if *t.LaunchTemplateName == aws.StringValue(name) {
+			return t, nil
+		}
+	}
+	return nil, errorf("InvalidLaunchTemplateName.NotFoundException", "The specified launch template, with template name %s, does not exist.", aws.StringValue(name))
+}
+
+//spec returns the launch specification of an instance launched from the template in a subnet with additional instance tags
+func (t *launchTemplate) spec(subnetID *string, tags []*ec2.Tag) *launchSpecification {
+	spec := &launchSpecification{
+		imageID:          t.data.ImageId,
+		instanceType:     t.data.InstanceType,
+		keyName:          t.data.KeyName,
+		subnetID:         subnetID,
+		securityGroupIDs: t.data.SecurityGroupIds,
+	}
+	for _, ts := range t.data.TagSpecifications {
+		spec.tagSpecifications = append(spec.tagSpecifications, &ec2.TagSpecification{
+			ResourceType: ts.ResourceType,
+			Tags:         ts.Tags,
+		})
+	}
+	if len(tags) > 0 {
+		spec.tagSpecifications = append(spec.tagSpecifications, &ec2.TagSpecification{
+			ResourceType: aws.String("instance"),
+			Tags:         tags,
+		})
+	}
+	return spec
+}
+
+//CreateLaunchTemplate creates a launch template, its data are checked when instances are launched
+func (api *ec2API) CreateLaunchTemplate(in *ec2.CreateLaunchTemplateInput) (*ec2.CreateLaunchTemplateOutput, error) {
+	name := aws.StringValue(in.LaunchTemplateName)
+	if !launchTemplateName.MatchString(name) {
+		return nil, errorf("InvalidLaunchTemplateName.MalformedException", "The launch template name %s is not valid", name)
+	}
+	if _, err := api.launchTemplate(nil, in.LaunchTemplateName); err == nil {
+		return nil, errorf("InvalidLaunchTemplateName.AlreadyExistsException", "Launch template name already in use.")
+	}
+	if in.LaunchTemplateData == nil {
+		return nil, errorf("MissingParameter", "The request must contain the parameter LaunchTemplateData")
+	}
+	t := &launchTemplate{
+		LaunchTemplate: ec2.LaunchTemplate{
+			CreateTime:           aws.Time(time.Now().UTC().Truncate(time.Second)),
+			CreatedBy:            aws.String("arn:aws:iam::" + AccountID + ":root"),
+			DefaultVersionNumber: aws.Int64(1),
+			LatestVersionNumber:  aws.Int64(1),
+			LaunchTemplateId:     aws.String(api.newID("lt")),
+			LaunchTemplateName:   in.LaunchTemplateName,
+		},
+		data: in.LaunchTemplateData,
+	}
+	api.launchTemplates[*t.LaunchTemplateId] = t
+	res := t.LaunchTemplate
+	return &ec2.CreateLaunchTemplateOutput{LaunchTemplate: &res}, nil
+}
+
+//DeleteLaunchTemplate deletes a launch template, the instances launched from it are not affected
+func (api *ec2API) DeleteLaunchTemplate(in *ec2.DeleteLaunchTemplateInput) (*ec2.DeleteLaunchTemplateOutput, error) {
+	t, err := api.launchTemplate(in.LaunchTemplateId, in.LaunchTemplateName)
+	if err != nil {
+		return nil, err
+	}
+	delete(api.launchTemplates, *t.LaunchTemplateId)
+	res := t.LaunchTemplate
+	return &ec2.DeleteLaunchTemplateOutput{LaunchTemplate: &res}, nil
+}
@@ -0,0 +1,308 @@
+package fake
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/SebastienDorgan/anyclouds/iputils"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func (api *ec2API) networkInterface(id *string) (*ec2.NetworkInterface, error) {
+	if err := checkID("InvalidNetworkInterfaceID.Malformed", "eni", id); err != nil {
+		return nil, err
+	}
+	ni, ok := api.interfaces[aws.StringValue(id)]
+	if !ok {
+		return nil, errorf("InvalidNetworkInterfaceID.NotFound", "The networkInterface ID '%s' does not exist", aws.StringValue(id))
+	}
+	return ni, nil
+}
+
+//groupIdentifiers returns the identifiers of the security groups ids
+func (api *ec2API) groupIdentifiers(ids []*string) []*ec2.GroupIdentifier {
+	var groups []*ec2.GroupIdentifier
+	for _, id := range ids {
+		sg := api.securityGroups[*id]
+		groups = append(groups, &ec2.GroupIdentifier{
+			GroupId:   sg.GroupId,
+			GroupName: sg.GroupName,
+		})
+	}
+	return groups
+}
+
+//privateIPAddress allocates a private ip address in a subnet, the first four addresses and the last one are reserved
+func (api *ec2API) privateIPAddress(sn *ec2.Subnet, requested *string) (*string, error) {
+	_, n, _ := net.ParseCIDR(*sn.CidrBlock)
+	used := map[string]bool{}
+	for _, ni := range api.interfaces {
+		if *ni.SubnetId == *sn.SubnetId {
+			used[*ni.PrivateIpAddress] = true
+		}
+	}
+	if requested != nil {
+		ip := net.ParseIP(*requested)
+		if ip == nil || !n.Contains(ip) {
+			return nil, errorf("InvalidParameterValue", "Address %s does not fall within the subnet's address range", *requested)
+		}
+		if used[ip.String()] {
+			return nil, errorf("InvalidIPAddress.InUse", "Address %s is in use.", *requested)
+		}
+		return aws.String(ip.String()), nil
+	}
+	first, last := bounds(n)
+	for u := first + 4; u < last; u++ {
+		ip := iputils.Utoi(u)
+		if !used[ip.String()] {
+			return aws.String(ip.String()), nil
+		}
+	}
+	return nil, errorf("InsufficientFreeAddressesInSubnet", "Insufficient free IP addresses in subnet %s", *sn.SubnetId)
+}
+
+//createNetworkInterface creates a network interface in a subnet, the default security group of the VPC is used if groups is empty
+func (api *ec2API) createNetworkInterface(sn *ec2.Subnet, groups []*string, privateIP *string, description *string) (*ec2.NetworkInterface, error) {
+	ip, err := api.privateIPAddress(sn, privateIP)
+	if err != nil {
+		return nil, err
+	}
+	if len(groups) == 0 {
+		for _, sg := range api.securityGroups {
+			if *sg.VpcId == *sn.VpcId && isDefaultGroup(sg) {
+				groups = []*string{sg.GroupId}
+			}
+		}
+	}
+	for _, g := range groups {
+		sg, err := api.securityGroup(g)
+		if err != nil {
+			return nil, err
+		}
+		if *sg.VpcId != *sn.VpcId {
+			return nil, errorf("InvalidParameter", "Security group %s and subnet %s belong to different networks.", *sg.GroupId, *sn.SubnetId)
+		}
+	}
+	if description == nil {
+		description = aws.String("")
+	}
+	id := api.newID("eni")
+	c := api.counter
+	ni := &ec2.NetworkInterface{
+		AvailabilityZone:   sn.AvailabilityZone,
+		Description:        description,
+		Groups:             api.groupIdentifiers(groups),
+		InterfaceType:      aws.String("interface"),
+		MacAddress:         aws.String(fmt.Sprintf("0e:%02x:%02x:%02x:%02x:%02x", byte(c>>32), byte(c>>24), byte(c>>16), byte(c>>8), byte(c))),
+		NetworkInterfaceId: aws.String(id),
+		OwnerId:            aws.String(AccountID),
+		PrivateIpAddress:   ip,
+		PrivateIpAddresses: []*ec2.NetworkInterfacePrivateIpAddress{
+			{
+				Primary:          aws.Bool(true),
+				PrivateIpAddress: ip,
+			},
+		},
+		RequesterManaged: aws.Bool(false),
+		SourceDestCheck:  aws.Bool(true),
+		Status:           aws.String("available"),
+		SubnetId:         sn.SubnetId,
+		VpcId:            sn.VpcId,
+	}
+	api.interfaces[id] = ni
+	return ni, nil
+}
+
+func (api *ec2API) attachNetworkInterface(ni *ec2.NetworkInterface, inst *ec2.Instance, deviceIndex *int64, deleteOnTermination *bool) {
+	ni.Attachment = &ec2.NetworkInterfaceAttachment{
+		AttachTime:          aws.Time(time.Now().UTC().Truncate(time.Second)),
+		AttachmentId:        aws.String(api.newID("eni-attach")),
+		DeleteOnTermination: deleteOnTermination,
+		DeviceIndex:         deviceIndex,
+		InstanceId:          inst.InstanceId,
+		InstanceOwnerId:     aws.String(AccountID),
+		Status:              aws.String("attached"),
+	}
+	ni.Status = aws.String("in-use")
+}
+
+func (api *ec2API) detachNetworkInterface(ni *ec2.NetworkInterface) {
+	ni.Attachment = nil
+	ni.Status = aws.String("available")
+}
+
+//CreateNetworkInterface creates a network interface
+func (api *ec2API) CreateNetworkInterface(in *ec2.CreateNetworkInterfaceInput) (*ec2.CreateNetworkInterfaceOutput, error) {
+	sn, err := api.subnet(in.SubnetId)
+	if err != nil {
+		return nil, err
+	}
+	ni, err := api.createNetworkInterface(sn, in.Groups, in.PrivateIpAddress, in.Description)
+	if err != nil {
+		return nil, err
+	}
+	return &ec2.CreateNetworkInterfaceOutput{NetworkInterface: ni}, nil
+}
+
+//DeleteNetworkInterface deletes a detached network interface
+func (api *ec2API) DeleteNetworkInterface(in *ec2.DeleteNetworkInterfaceInput) (*ec2.DeleteNetworkInterfaceOutput, error) {
+	ni, err := api.networkInterface(in.NetworkInterfaceId)
+	if err != nil {
+		return nil, err
+	}
+	if ni.Attachment != nil {
+		return nil, errorf("InvalidNetworkInterface.InUse", "Interface: [%s] in use.", *ni.NetworkInterfaceId)
+	}
+	for _, addr := range api.addresses {
+		if aws.StringValue(addr.NetworkInterfaceId) == *ni.NetworkInterfaceId {
+			api.disassociateAddress(addr)
+		}
+	}
+	delete(api.interfaces, *ni.NetworkInterfaceId)
+	return &ec2.DeleteNetworkInterfaceOutput{}, nil
+}
+
+//DescribeNetworkInterfaces describes network interfaces
+func (api *ec2API) DescribeNetworkInterfaces(in *ec2.DescribeNetworkInterfacesInput) (*ec2.DescribeNetworkInterfacesOutput, error) {
+	for _, id := range in.NetworkInterfaceIds {
+		if _, err := api.networkInterface(id); err != nil {
+			return nil, err
+		}
+	}
+	out := &ec2.DescribeNetworkInterfacesOutput{}
+	for _, id := range sortedKeys(api.interfaces) {
+		ni := api.interfaces[id]
+		if !contains(in.NetworkInterfaceIds, id) {
+			continue
+		}
+		ok, err := match(in.Filters, func(name string) ([]string, bool) {
+			switch name {
+			case "network-interface-id":
+				return values(ni.NetworkInterfaceId), true
+			case "vpc-id":
+				return values(ni.VpcId), true
+			case "subnet-id":
+				return values(ni.SubnetId), true
+			case "private-ip-address", "addresses.private-ip-address":
+				return values(ni.PrivateIpAddress), true
+			case "mac-address":
+				return values(ni.MacAddress), true
+			case "status":
+				return values(ni.Status), true
+			case "description":
+				return values(ni.Description), true
+			case "availability-zone":
+				return values(ni.AvailabilityZone), true
+			case "group-id":
+				var groups []string
+				for _, g := range ni.Groups {
+					groups = append(groups, *g.GroupId)
+				}
+				return groups, true
+			case "attachment.instance-id":
+				if ni.Attachment == nil {
+					return nil, true
+				}
+				return values(ni.Attachment.InstanceId), true
+			case "attachment.attachment-id":
+				if ni.Attachment == nil {
+					return nil, true
+				}
+				return values(ni.Attachment.AttachmentId), true
+			case "association.public-ip":
+				if ni.Association == nil {
+					return nil, true
+				}
+				return values(ni.Association.PublicIp), true
+			}
+			return tagValues(ni.TagSet, name)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out.NetworkInterfaces = append(out.NetworkInterfaces, ni)
+		}
+	}
+	return out, nil
+}
+
+//AttachNetworkInterface attaches a network interface to an instance
+func (api *ec2API) AttachNetworkInterface(in *ec2.AttachNetworkInterfaceInput) (*ec2.AttachNetworkInterfaceOutput, error) {
+	ni, err := api.networkInterface(in.NetworkInterfaceId)
+	if err != nil {
+		return nil, err
+	}
+	inst, err := api.instance(in.InstanceId)
+	if err != nil {
+		return nil, err
+	}
+	if *inst.State.Code == terminated {
+		return nil, errorf("IncorrectInstanceState", "The instance '%s' is not in a valid state for this operation.", *inst.InstanceId)
+	}
+	if ni.Attachment != nil {
+		return nil, errorf("InvalidNetworkInterface.InUse", "Interface: [%s] in use.", *ni.NetworkInterfaceId)
+	}
+	api.refreshInstance(inst)
+	if *ni.AvailabilityZone != *inst.Placement.AvailabilityZone || (inst.VpcId != nil && *ni.VpcId != *inst.VpcId) {
+		return nil, errorf("InvalidParameterCombination", "The network interface and the instance must be in the same network and availability zone")
+	}
+	deviceIndex := in.DeviceIndex
+	if deviceIndex == nil {
+		deviceIndex = aws.Int64(int64(len(inst.NetworkInterfaces)))
+	}
+	for _, n := range inst.NetworkInterfaces {
+		if aws.Int64Value(n.Attachment.DeviceIndex) == *deviceIndex {
+			return nil, errorf("InvalidParameterValue", "Instance '%s' already has an interface attached at device index '%d'.", *inst.InstanceId, *deviceIndex)
+		}
+	}
+	api.attachNetworkInterface(ni, inst, deviceIndex, aws.Bool(false))
+	return &ec2.AttachNetworkInterfaceOutput{AttachmentId: ni.Attachment.AttachmentId}, nil
+}
+
+//DetachNetworkInterface detaches a network interface from an instance, the primary network interface cannot be detached
+func (api *ec2API) DetachNetworkInterface(in *ec2.DetachNetworkInterfaceInput) (*ec2.DetachNetworkInterfaceOutput, error) {
+	for _, ni := range api.interfaces {
+		if ni.Attachment == nil || *ni.Attachment.AttachmentId != aws.StringValue(in.AttachmentId) {
+			continue
+		}
+		if aws.Int64Value(ni.Attachment.DeviceIndex) == 0 {
+			return nil, errorf("OperationNotPermitted", "The network interface at device index 0 cannot be detached.")
+		}
+		api.detachNetworkInterface(ni)
+		return &ec2.DetachNetworkInterfaceOutput{}, nil
+	}
+	return nil, errorf("InvalidAttachmentID.NotFound", "Interface attachment '%s' does not exist", aws.StringValue(in.AttachmentId))
+}
+
+//ModifyNetworkInterfaceAttribute modifies the security groups, the description or the source/destination check of a network interface
+func (api *ec2API) ModifyNetworkInterfaceAttribute(in *ec2.ModifyNetworkInterfaceAttributeInput) (*ec2.ModifyNetworkInterfaceAttributeOutput, error) {
+	ni, err := api.networkInterface(in.NetworkInterfaceId)
+	if err != nil {
+		return nil, err
+	}
+	if len(in.Groups) > 0 {
+		for _, g := range in.Groups {
+			sg, err := api.securityGroup(g)
+			if err != nil {
+				return nil, err
+			}
+			if *sg.VpcId != *ni.VpcId {
+				return nil, errorf("InvalidParameter", "Security group %s and interface %s belong to different networks.", *sg.GroupId, *ni.NetworkInterfaceId)
+			}
+		}
+		ni.Groups = api.groupIdentifiers(in.Groups)
+	}
+	if in.Description != nil {
+		ni.Description = in.Description.Value
+	}
+	if in.SourceDestCheck != nil {
+		ni.SourceDestCheck = in.SourceDestCheck.Value
+	}
+	if in.Attachment != nil && ni.Attachment != nil && in.Attachment.DeleteOnTermination != nil {
+		ni.Attachment.DeleteOnTermination = in.Attachment.DeleteOnTermination
+	}
+	return &ec2.ModifyNetworkInterfaceAttributeOutput{}, nil
+}
@@ -0,0 +1,609 @@
+package fake
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/SebastienDorgan/anyclouds/iputils"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+//bounds returns the first and last addresses of n
+func bounds(n *net.IPNet) (uint32, uint32) {
+	ip := n.IP.To4()
+	first := iputils.Itou(&ip)
+	ones, bits := n.Mask.Size()
+	return first, first + uint32(uint64(1)<<uint(bits-ones)-1)
+}
+
+func overlaps(n1, n2 *net.IPNet) bool {
+	f1, l1 := bounds(n1)
+	f2, l2 := bounds(n2)
+	return f1 <= l2 && f2 <= l1
+}
+
+func includes(outer, inner *net.IPNet) bool {
+	fo, lo := bounds(outer)
+	fi, li := bounds(inner)
+	return fo <= fi && li <= lo
+}
+
+func parseCIDR(cidr *string, minSize, maxSize int) (*net.IPNet, error) {
+	if cidr == nil {
+		return nil, errorf("MissingParameter", "The request must contain the parameter CidrBlock")
+	}
+	_, n, err := net.ParseCIDR(*cidr)
+	if err != nil || n.IP.To4() == nil {
+		return nil, errorf("InvalidParameterValue", "Value (%s) for parameter cidrBlock is invalid. This is not a valid CIDR block.", *cidr)
+	}
+	if ones, _ := n.Mask.Size(); ones < minSize || ones > maxSize {
+		return nil, errorf("InvalidParameterValue", "Value (%s) for parameter cidrBlock is invalid. The size must be between /%d and /%d.", *cidr, minSize, maxSize)
+	}
+	return n, nil
+}
+
+//createVpc creates a VPC with its default security group and its main route table
+func (api *ec2API) createVpc(cidr string, ipv6 bool) *ec2.Vpc {
+	vpc := &ec2.Vpc{
+		CidrBlock: aws.String(cidr),
+		CidrBlockAssociationSet: []*ec2.VpcCidrBlockAssociation{
+			{
+				AssociationId:  aws.String(api.newID("vpc-cidr-assoc")),
+				CidrBlock:      aws.String(cidr),
+				CidrBlockState: &ec2.VpcCidrBlockState{State: aws.String("associated")},
+			},
+		},
+		DhcpOptionsId:   aws.String("dopt-00000000000000001"),
+		InstanceTenancy: aws.String("default"),
+		IsDefault:       aws.Bool(false),
+		OwnerId:         aws.String(AccountID),
+		State:           aws.String("available"),
+		VpcId:           aws.String(api.newID("vpc")),
+	}
+	if ipv6 {
+		vpc.Ipv6CidrBlockAssociationSet = []*ec2.VpcIpv6CidrBlockAssociation{
+			{
+				AssociationId:      aws.String(api.newID("vpc-cidr-assoc")),
+				Ipv6CidrBlock:      aws.String(fmt.Sprintf("2600:1f18:%x::/56", api.counter&0xffff)),
+				Ipv6CidrBlockState: &ec2.VpcCidrBlockState{State: aws.String("associated")},
+			},
+		}
+	}
+	api.vpcs[*vpc.VpcId] = vpc
+	sg := &ec2.SecurityGroup{
+		Description:         aws.String("default VPC security group"),
+		GroupId:             aws.String(api.newID("sg")),
+		GroupName:           aws.String("default"),
+		OwnerId:             aws.String(AccountID),
+		VpcId:               vpc.VpcId,
+		IpPermissionsEgress: []*ec2.IpPermission{allTraffic()},
+	}
+	api.securityGroups[*sg.GroupId] = sg
+	rt := &ec2.RouteTable{
+		OwnerId:      aws.String(AccountID),
+		RouteTableId: aws.String(api.newID("rtb")),
+		Routes: []*ec2.Route{
+			{
+				DestinationCidrBlock: aws.String(cidr),
+				GatewayId:            aws.String("local"),
+				Origin:               aws.String("CreateRouteTable"),
+				State:                aws.String("active"),
+			},
+		},
+		VpcId: vpc.VpcId,
+	}
+	rt.Associations = []*ec2.RouteTableAssociation{
+		{
+			Main:                    aws.Bool(true),
+			RouteTableAssociationId: aws.String(api.newID("rtbassoc")),
+			RouteTableId:            rt.RouteTableId,
+		},
+	}
+	api.routeTables[*rt.RouteTableId] = rt
+	return vpc
+}
+
+func isMain(rt *ec2.RouteTable) bool {
+	for _, a := range rt.Associations {
+		if aws.BoolValue(a.Main) {
+			return true
+		}
+	}
+	return false
+}
+
+//isDefaultGroup returns true if sg is the default security group of its VPC
+func isDefaultGroup(sg *ec2.SecurityGroup) bool {
+	return aws.StringValue(sg.GroupName) == "default"
+}
+
+//CreateVpc creates a VPC
+func (api *ec2API) CreateVpc(in *ec2.CreateVpcInput) (*ec2.CreateVpcOutput, error) {
+	n, err := parseCIDR(in.CidrBlock, 16, 28)
+	if err != nil {
+		return nil, err
+	}
+	vpc := api.createVpc(n.String(), aws.BoolValue(in.AmazonProvidedIpv6CidrBlock))
+	return &ec2.CreateVpcOutput{Vpc: vpc}, nil
+}
+
+func (api *ec2API) vpc(id *string) (*ec2.Vpc, error) {
+	if err := checkID("InvalidVpcID.Malformed", "vpc", id); err != nil {
+		return nil, err
+	}
+	vpc, ok := api.vpcs[aws.StringValue(id)]
+	if !ok {
+		return nil, errorf("InvalidVpcID.NotFound", "The vpc ID '%s' does not exist", aws.StringValue(id))
+	}
+	return vpc, nil
+}
+
+//defaultVpc returns the default VPC
+func (api *ec2API) defaultVpc() (*ec2.Vpc, error) {
+	for _, vpc := range api.vpcs {
+		if aws.BoolValue(vpc.IsDefault) {
+			return vpc, nil
+		}
+	}
+	return nil, errorf("VPCIdNotSpecified", "No default VPC for this user")
+}
+
+//DeleteVpc deletes a VPC
+func (api *ec2API) DeleteVpc(in *ec2.DeleteVpcInput) (*ec2.DeleteVpcOutput, error) {
+	vpc, err := api.vpc(in.VpcId)
+	if err != nil {
+		return nil, err
+	}
+	id := *vpc.VpcId
+	dependencyViolation := errorf("DependencyViolation", "The vpc '%s' has dependencies and cannot be deleted.", id)
+	for _, sn := range api.subnets {
+		if *sn.VpcId == id {
+			return nil, dependencyViolation
+		}
+	}
+	for _, gw := range api.internetGateways {
+		for _, att := range gw.Attachments {
+			if *att.VpcId == id {
+				return nil, dependencyViolation
+			}
+		}
+	}
+	for _, sg := range api.securityGroups {
+		if *sg.VpcId == id && !isDefaultGroup(sg) {
+			return nil, dependencyViolation
+		}
+	}
+	for _, rt := range api.routeTables {
+		if *rt.VpcId == id && !isMain(rt) {
+			return nil, dependencyViolation
+		}
+	}
+	for _, sg := range api.securityGroups {
+		if *sg.VpcId == id {
+			delete(api.securityGroups, *sg.GroupId)
+		}
+	}
+	for _, rt := range api.routeTables {
+		if *rt.VpcId == id {
+			delete(api.routeTables, *rt.RouteTableId)
+		}
+	}
+	delete(api.vpcs, id)
+	return &ec2.DeleteVpcOutput{}, nil
+}
+
+//DescribeVpcs describes VPCs
+func (api *ec2API) DescribeVpcs(in *ec2.DescribeVpcsInput) (*ec2.DescribeVpcsOutput, error) {
+	for _, id := range in.VpcIds {
+		if _, err := api.vpc(id); err != nil {
+			return nil, err
+		}
+	}
+	out := &ec2.DescribeVpcsOutput{}
+	for _, id := range sortedKeys(api.vpcs) {
+		vpc := api.vpcs[id]
+		if !contains(in.VpcIds, id) {
+			continue
+		}
+		ok, err := match(in.Filters, func(name string) ([]string, bool) {
+			switch name {
+			case "vpc-id":
+				return values(vpc.VpcId), true
+			case "cidr", "cidr-block-association.cidr-block":
+				return values(vpc.CidrBlock), true
+			case "is-default":
+				return bools(vpc.IsDefault), true
+			case "state":
+				return values(vpc.State), true
+			}
+			return tagValues(vpc.Tags, name)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out.Vpcs = append(out.Vpcs, vpc)
+		}
+	}
+	return out, nil
+}
+
+func (api *ec2API) subnet(id *string) (*ec2.Subnet, error) {
+	if err := checkID("InvalidSubnetID.Malformed", "subnet", id); err != nil {
+		return nil, err
+	}
+	sn, ok := api.subnets[aws.StringValue(id)]
+	if !ok {
+		return nil, errorf("InvalidSubnetID.NotFound", "The subnet ID '%s' does not exist", aws.StringValue(id))
+	}
+	return sn, nil
+}
+
+//CreateSubnet creates a subnet
+func (api *ec2API) CreateSubnet(in *ec2.CreateSubnetInput) (*ec2.CreateSubnetOutput, error) {
+	vpc, err := api.vpc(in.VpcId)
+	if err != nil {
+		return nil, err
+	}
+	n, err := parseCIDR(in.CidrBlock, 16, 28)
+	if err != nil {
+		return nil, err
+	}
+	_, vpcNet, _ := net.ParseCIDR(*vpc.CidrBlock)
+	if !includes(vpcNet, n) {
+		return nil, errorf("InvalidSubnet.Range", "The CIDR '%s' is invalid.", *in.CidrBlock)
+	}
+	for _, sn := range api.subnets {
+		if *sn.VpcId != *vpc.VpcId {
+			continue
+		}
+		_, snNet, _ := net.ParseCIDR(*sn.CidrBlock)
+		if overlaps(snNet, n) {
+			return nil, errorf("InvalidSubnet.Conflict", "The CIDR '%s' conflicts with another subnet", *in.CidrBlock)
+		}
+	}
+	az := in.AvailabilityZone
+	if az == nil {
+		az = aws.String(AvailabilityZone)
+	}
+	ones, bits := n.Mask.Size()
+	sn := &ec2.Subnet{
+		AssignIpv6AddressOnCreation: aws.Bool(false),
+		AvailabilityZone:            az,
+		AvailableIpAddressCount:     aws.Int64(int64(1)<<uint(bits-ones) - 5),
+		CidrBlock:                   aws.String(n.String()),
+		DefaultForAz:                aws.Bool(false),
+		MapPublicIpOnLaunch:         aws.Bool(false),
+		OwnerId:                     aws.String(AccountID),
+		State:                       aws.String("available"),
+		SubnetId:                    aws.String(api.newID("subnet")),
+		VpcId:                       vpc.VpcId,
+	}
+	if in.Ipv6CidrBlock != nil {
+		sn.Ipv6CidrBlockAssociationSet = []*ec2.SubnetIpv6CidrBlockAssociation{
+			{
+				AssociationId:      aws.String(api.newID("subnet-cidr-assoc")),
+				Ipv6CidrBlock:      in.Ipv6CidrBlock,
+				Ipv6CidrBlockState: &ec2.SubnetCidrBlockState{State: aws.String("associated")},
+			},
+		}
+	}
+	api.subnets[*sn.SubnetId] = sn
+	return &ec2.CreateSubnetOutput{Subnet: sn}, nil
+}
+
+//DeleteSubnet deletes a subnet
+func (api *ec2API) DeleteSubnet(in *ec2.DeleteSubnetInput) (*ec2.DeleteSubnetOutput, error) {
+	sn, err := api.subnet(in.SubnetId)
+	if err != nil {
+		return nil, err
+	}
+	for _, ni := range api.interfaces {
+		if *ni.SubnetId == *sn.SubnetId {
+			return nil, errorf("DependencyViolation", "The subnet '%s' has dependencies and cannot be deleted.", *sn.SubnetId)
+		}
+	}
+	for _, rt := range api.routeTables {
+		var associations []*ec2.RouteTableAssociation
+		for _, a := range rt.Associations {
+			if aws.StringValue(a.SubnetId) != *sn.SubnetId {
+				associations = append(associations, a)
+			}
+		}
+		rt.Associations = associations
+	}
+	delete(api.subnets, *sn.SubnetId)
+	return &ec2.DeleteSubnetOutput{}, nil
+}
+
+//DescribeSubnets describes subnets
+func (api *ec2API) DescribeSubnets(in *ec2.DescribeSubnetsInput) (*ec2.DescribeSubnetsOutput, error) {
+	for _, id := range in.SubnetIds {
+		if _, err := api.subnet(id); err != nil {
+			return nil, err
+		}
+	}
+	out := &ec2.DescribeSubnetsOutput{}
+	for _, id := range sortedKeys(api.subnets) {
+		sn := api.subnets[id]
+		if !contains(in.SubnetIds, id) {
+			continue
+		}
+		ok, err := match(in.Filters, func(name string) ([]string, bool) {
+			switch name {
+			case "subnet-id":
+				return values(sn.SubnetId), true
+			case "vpc-id":
+				return values(sn.VpcId), true
+			case "cidr-block", "cidr", "cidrBlock":
+				return values(sn.CidrBlock), true
+			case "availability-zone", "availabilityZone":
+				return values(sn.AvailabilityZone), true
+			case "state":
+				return values(sn.State), true
+			}
+			return tagValues(sn.Tags, name)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out.Subnets = append(out.Subnets, sn)
+		}
+	}
+	return out, nil
+}
+
+func (api *ec2API) internetGateway(id *string) (*ec2.InternetGateway, error) {
+	gw, ok := api.internetGateways[aws.StringValue(id)]
+	if !ok {
+		return nil, errorf("InvalidInternetGatewayID.NotFound", "The internetGateway ID '%s' does not exist", aws.StringValue(id))
+	}
+	return gw, nil
+}
+
+//CreateInternetGateway creates an internet gateway
+func (api *ec2API) CreateInternetGateway(in *ec2.CreateInternetGatewayInput) (*ec2.CreateInternetGatewayOutput, error) {
+	gw := &ec2.InternetGateway{
+		InternetGatewayId: aws.String(api.newID("igw")),
+		OwnerId:           aws.String(AccountID),
+	}
+	api.internetGateways[*gw.InternetGatewayId] = gw
+	return &ec2.CreateInternetGatewayOutput{InternetGateway: gw}, nil
+}
+
+//AttachInternetGateway attaches an internet gateway to a VPC
+func (api *ec2API) AttachInternetGateway(in *ec2.AttachInternetGatewayInput) (*ec2.AttachInternetGatewayOutput, error) {
+	gw, err := api.internetGateway(in.InternetGatewayId)
+	if err != nil {
+		return nil, err
+	}
+	vpc, err := api.vpc(in.VpcId)
+	if err != nil {
+		return nil, err
+	}
+	if len(gw.Attachments) > 0 {
+		return nil, errorf("Resource.AlreadyAssociated", "resource %s is already attached to network %s", *gw.InternetGatewayId, *gw.Attachments[0].VpcId)
+	}
+	gw.Attachments = []*ec2.InternetGatewayAttachment{
+		{
+			State: aws.String("available"),
+			VpcId: vpc.VpcId,
+		},
+	}
+	return &ec2.AttachInternetGatewayOutput{}, nil
+}
+
+//DetachInternetGateway detaches an internet gateway from a VPC
+func (api *ec2API) DetachInternetGateway(in *ec2.DetachInternetGatewayInput) (*ec2.DetachInternetGatewayOutput, error) {
+	gw, err := api.internetGateway(in.InternetGatewayId)
+	if err != nil {
+		return nil, err
+	}
+	if len(gw.Attachments) == 0 || *gw.Attachments[0].VpcId != aws.StringValue(in.VpcId) {
+		return nil, errorf("Gateway.NotAttached", "resource %s is not attached to network %s", *gw.InternetGatewayId, aws.StringValue(in.VpcId))
+	}
+	gw.Attachments = nil
+	return &ec2.DetachInternetGatewayOutput{}, nil
+}
+
+//DeleteInternetGateway deletes an internet gateway
+func (api *ec2API) DeleteInternetGateway(in *ec2.DeleteInternetGatewayInput) (*ec2.DeleteInternetGatewayOutput, error) {
+	gw, err := api.internetGateway(in.InternetGatewayId)
+	if err != nil {
+		return nil, err
+	}
+	if len(gw.Attachments) > 0 {
+		return nil, errorf("DependencyViolation", "The internetGateway '%s' has dependencies and cannot be deleted.", *gw.InternetGatewayId)
+	}
+	delete(api.internetGateways, *gw.InternetGatewayId)
+	return &ec2.DeleteInternetGatewayOutput{}, nil
+}
+
+//DescribeInternetGateways describes internet gateways
+func (api *ec2API) DescribeInternetGateways(in *ec2.DescribeInternetGatewaysInput) (*ec2.DescribeInternetGatewaysOutput, error) {
+	for _, id := range in.InternetGatewayIds {
+		if _, err := api.internetGateway(id); err != nil {
+			return nil, err
+		}
+	}
+	out := &ec2.DescribeInternetGatewaysOutput{}
+	for _, id := range sortedKeys(api.internetGateways) {
+		gw := api.internetGateways[id]
+		if !contains(in.InternetGatewayIds, id) {
+			continue
+		}
+		ok, err := match(in.Filters, func(name string) ([]string, bool) {
+			switch name {
+			case "internet-gateway-id":
+				return values(gw.InternetGatewayId), true
+			case "attachment.vpc-id":
+				var vpcs []string
+				for _, att := range gw.Attachments {
+					vpcs = append(vpcs, *att.VpcId)
+				}
+				return vpcs, true
+			}
+			return tagValues(gw.Tags, name)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out.InternetGateways = append(out.InternetGateways, gw)
+		}
+	}
+	return out, nil
+}
+
+func (api *ec2API) routeTable(id *string) (*ec2.RouteTable, error) {
+	rt, ok := api.routeTables[aws.StringValue(id)]
+	if !ok {
+		return nil, errorf("InvalidRouteTableID.NotFound", "The routeTable ID '%s' does not exist", aws.StringValue(id))
+	}
+	return rt, nil
+}
+
+//destination returns the destination of a route
+func destination(cidr, ipv6CIDR *string) string {
+	if cidr != nil {
+		return *cidr
+	}
+	return aws.StringValue(ipv6CIDR)
+}
+
+//CreateRoute adds a route to a route table
+func (api *ec2API) CreateRoute(in *ec2.CreateRouteInput) (*ec2.CreateRouteOutput, error) {
+	rt, err := api.routeTable(in.RouteTableId)
+	if err != nil {
+		return nil, err
+	}
+	dest := destination(in.DestinationCidrBlock, in.DestinationIpv6CidrBlock)
+	if dest == "" {
+		return nil, errorf("MissingParameter", "The request must contain the parameter destinationCidrBlock or destinationIpv6CidrBlock")
+	}
+	if in.GatewayId != nil {
+		gw, err := api.internetGateway(in.GatewayId)
+		if err != nil {
+			return nil, err
+		}
+		if len(gw.Attachments) == 0 || *gw.Attachments[0].VpcId != *rt.VpcId {
+			return nil, errorf("InvalidParameterValue", "route table %s and network gateway %s belong to different networks", *rt.RouteTableId, *gw.InternetGatewayId)
+		}
+	}
+	for _, r := range rt.Routes {
+		if destination(r.DestinationCidrBlock, r.DestinationIpv6CidrBlock) == dest {
+			return nil, errorf("RouteAlreadyExists", "The route identified by %s already exists.", dest)
+		}
+	}
+	rt.Routes = append(rt.Routes, &ec2.Route{
+		DestinationCidrBlock:     in.DestinationCidrBlock,
+		DestinationIpv6CidrBlock: in.DestinationIpv6CidrBlock,
+		GatewayId:                in.GatewayId,
+		InstanceId:               in.InstanceId,
+		NetworkInterfaceId:       in.NetworkInterfaceId,
+		Origin:                   aws.String("CreateRoute"),
+		State:                    aws.String("active"),
+	})
+	return &ec2.CreateRouteOutput{Return: aws.Bool(true)}, nil
+}
+
+//DeleteRoute deletes a route from a route table
+func (api *ec2API) DeleteRoute(in *ec2.DeleteRouteInput) (*ec2.DeleteRouteOutput, error) {
+	rt, err := api.routeTable(in.RouteTableId)
+	if err != nil {
+		return nil, err
+	}
+	dest := destination(in.DestinationCidrBlock, in.DestinationIpv6CidrBlock)
+	for i, r := range rt.Routes {
+		if destination(r.DestinationCidrBlock, r.DestinationIpv6CidrBlock) == dest {
+			rt.Routes = append(rt.Routes[:i], rt.Routes[i+1:]...)
+			return &ec2.DeleteRouteOutput{}, nil
+		}
+	}
+	return nil, errorf("InvalidRoute.NotFound", "no route with destination-cidr-block %s in route table %s", dest, *rt.RouteTableId)
+}
+
+//AssociateRouteTable associates a route table with a subnet
+func (api *ec2API) AssociateRouteTable(in *ec2.AssociateRouteTableInput) (*ec2.AssociateRouteTableOutput, error) {
+	rt, err := api.routeTable(in.RouteTableId)
+	if err != nil {
+		return nil, err
+	}
+	sn, err := api.subnet(in.SubnetId)
+	if err != nil {
+		return nil, err
+	}
+	if *sn.VpcId != *rt.VpcId {
+		return nil, errorf("InvalidParameterValue", "route table %s and subnet %s belong to different networks", *rt.RouteTableId, *sn.SubnetId)
+	}
+	for _, t := range api.routeTables {
+		for _, a := range t.Associations {
+			if aws.StringValue(a.SubnetId) == *sn.SubnetId {
+				return nil, errorf("Resource.AlreadyAssociated", "the specified association for route table %s conflicts with an existing association", *rt.RouteTableId)
+			}
+		}
+	}
+	a := &ec2.RouteTableAssociation{
+		Main:                    aws.Bool(false),
+		RouteTableAssociationId: aws.String(api.newID("rtbassoc")),
+		RouteTableId:            rt.RouteTableId,
+		SubnetId:                sn.SubnetId,
+	}
+	rt.Associations = append(rt.Associations, a)
+	return &ec2.AssociateRouteTableOutput{AssociationId: a.RouteTableAssociationId}, nil
+}
+
+//DeleteRouteTable deletes a route table
+func (api *ec2API) DeleteRouteTable(in *ec2.DeleteRouteTableInput) (*ec2.DeleteRouteTableOutput, error) {
+	rt, err := api.routeTable(in.RouteTableId)
+	if err != nil {
+		return nil, err
+	}
+	if len(rt.Associations) > 0 {
+		return nil, errorf("DependencyViolation", "The routeTable '%s' has dependencies and cannot be deleted.", *rt.RouteTableId)
+	}
+	delete(api.routeTables, *rt.RouteTableId)
+	return &ec2.DeleteRouteTableOutput{}, nil
+}
+
+//DescribeRouteTables describes route tables
+func (api *ec2API) DescribeRouteTables(in *ec2.DescribeRouteTablesInput) (*ec2.DescribeRouteTablesOutput, error) {
+	for _, id := range in.RouteTableIds {
+		if _, err := api.routeTable(id); err != nil {
+			return nil, err
+		}
+	}
+	out := &ec2.DescribeRouteTablesOutput{}
+	for _, id := range sortedKeys(api.routeTables) {
+		rt := api.routeTables[id]
+		if !contains(in.RouteTableIds, id) {
+			continue
+		}
+		ok, err := match(in.Filters, func(name string) ([]string, bool) {
+			switch name {
+			case "route-table-id":
+				return values(rt.RouteTableId), true
+			case "vpc-id":
+				return values(rt.VpcId), true
+			case "association.main":
+				return []string{fmt.Sprintf("%t", isMain(rt))}, true
+			case "association.subnet-id":
+				var subnets []string
+				for _, a := range rt.Associations {
+					subnets = append(subnets, values(a.SubnetId)...)
+				}
+				return subnets, true
+			}
+			return tagValues(rt.Tags, name)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out.RouteTables = append(out.RouteTables, rt)
+		}
+	}
+	return out, nil
+}
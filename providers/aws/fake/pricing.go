@@ -0,0 +1,210 @@
+package fake
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/private/protocol/json/jsonutil"
+	"github.com/aws/aws-sdk-go/service/pricing"
+)
+
+//publicationDate publication date of the fake price list
+const publicationDate = "2019-07-01T00:00:00Z"
+
+//instanceType describes an EC2 instance type as published in the price list
+type instanceType struct {
+	name                  string
+	family                string
+	vcpu                  int
+	memory                string
+	storage               string
+	clockSpeed            string
+	physicalProcessor     string
+	processorArchitecture string
+	networkPerformance    string
+	gpu                   int
+	price                 string
+}
+
+var instanceTypes = []instanceType{
+	{"t3.micro", "General purpose", 2, "1 GiB", "EBS only", "2.5 GHz", "Intel Skylake E5 2686 v5", "64-bit", "Up to 5 Gigabit", 0, "0.0104000000"},
+	{"t3.medium", "General purpose", 2, "4 GiB", "EBS only", "2.5 GHz", "Intel Skylake E5 2686 v5", "64-bit", "Up to 5 Gigabit", 0, "0.0416000000"},
+	{"t3a.medium", "General purpose", 2, "4 GiB", "EBS only", "2.5 GHz", "AMD EPYC 7571", "64-bit", "Up to 5 Gigabit", 0, "0.0376000000"},
+	{"m5.large", "General purpose", 2, "8 GiB", "EBS only", "3.1 GHz", "Intel Xeon Platinum 8175", "64-bit", "Up to 10 Gigabit", 0, "0.0960000000"},
+	{"m5.xlarge", "General purpose", 4, "16 GiB", "EBS only", "3.1 GHz", "Intel Xeon Platinum 8175", "64-bit", "Up to 10 Gigabit", 0, "0.1920000000"},
+	{"m5d.xlarge", "General purpose", 4, "16 GiB", "1 x 150 NVMe SSD", "3.1 GHz", "Intel Xeon Platinum 8175", "64-bit", "Up to 10 Gigabit", 0, "0.2260000000"},
+	{"a1.xlarge", "General purpose", 4, "8 GiB", "EBS only", "2.3 GHz", "AWS Graviton Processor", "64-bit", "Up to 10 Gigabit", 0, "0.1020000000"},
+	{"c5.2xlarge", "Compute optimized", 8, "16 GiB", "EBS only", "3.4 GHz", "Intel Xeon Platinum 8124M", "64-bit", "Up to 10 Gigabit", 0, "0.3400000000"},
+	{"r5.large", "Memory optimized", 2, "16 GiB", "EBS only", "3.1 GHz", "Intel Xeon Platinum 8175", "64-bit", "Up to 10 Gigabit", 0, "0.1260000000"},
+	{"p3.2xlarge", "GPU instance", 8, "61 GiB", "EBS only", "2.3 GHz", "Intel Xeon E5-2686 v4 (Broadwell)", "64-bit", "Up to 10 Gigabit", 1, "3.0600000000"},
+}
+
+//findInstanceType returns the instance type named name or nil if it does not exist
+func findInstanceType(name string) *instanceType {
+	for i := range instanceTypes {
+		if instanceTypes[i].name == name {
+			return &instanceTypes[i]
+		}
+	}
+	return nil
+}
+
+//architecture returns the EC2 architecture of the instances of type it
+func (it *instanceType) architecture() string {
+	if strings.Contains(it.physicalProcessor, "Graviton") {
+		return "arm64"
+	}
+	return "x86_64"
+}
+
+//attributes returns the product attributes of the price list entry of the instance type it
+func (it *instanceType) attributes() map[string]string {
+	return map[string]string{
+		"capacitystatus":        "Used",
+		"clockSpeed":            it.clockSpeed,
+		"gpu":                   strconv.Itoa(it.gpu),
+		"instanceFamily":        it.family,
+		"instanceType":          it.name,
+		"location":              RegionName,
+		"locationType":          "AWS Region",
+		"memory":                it.memory,
+		"networkPerformance":    it.networkPerformance,
+		"operatingSystem":       "Linux",
+		"physicalProcessor":     it.physicalProcessor,
+		"preInstalledSw":        "NA",
+		"processorArchitecture": it.processorArchitecture,
+		"servicecode":           "AmazonEC2",
+		"storage":               it.storage,
+		"tenancy":               "Shared",
+		"vcpu":                  strconv.Itoa(it.vcpu),
+	}
+}
+
+//product returns the price list entry of the instance type it
+func (it *instanceType) product() map[string]interface{} {
+	sku := strings.ToUpper(strings.Replace(it.name, ".", "", -1))
+	offer := sku + ".JRTCKXETXF"
+	return map[string]interface{}{
+		"product": map[string]interface{}{
+			"productFamily": "Compute Instance",
+			"attributes":    it.attributes(),
+			"sku":           sku,
+		},
+		"serviceCode":     "AmazonEC2",
+		"version":         "20190701000000",
+		"publicationDate": publicationDate,
+		"terms": map[string]interface{}{
+			"OnDemand": map[string]interface{}{
+				offer: map[string]interface{}{
+					"offerTermCode": "JRTCKXETXF",
+					"sku":           sku,
+					"effectiveDate": publicationDate,
+					"priceDimensions": map[string]interface{}{
+						offer + ".6YS6EN2CT7": map[string]interface{}{
+							"unit":         "Hrs",
+							"description":  fmt.Sprintf("$%s per On Demand Linux %s Instance Hour", it.price, it.name),
+							"pricePerUnit": map[string]string{"USD": it.price},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+type pricingAPI struct {
+	products []map[string]interface{}
+}
+
+func newPricingAPI() *pricingAPI {
+	p := &pricingAPI{}
+	for i := range instanceTypes {
+		p.products = append(p.products, instanceTypes[i].product())
+	}
+	return p
+}
+
+//field returns the value of the field name of product
+func field(product map[string]interface{}, name string) (string, bool) {
+	p := product["product"].(map[string]interface{})
+	switch strings.ToLower(name) {
+	case "servicecode":
+		return product["serviceCode"].(string), true
+	case "productfamily":
+		return p["productFamily"].(string), true
+	case "sku":
+		return p["sku"].(string), true
+	}
+	for k, v := range p["attributes"].(map[string]string) {
+		if strings.EqualFold(k, name) {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+//getProducts handles the GetProducts action
+func (api *pricingAPI) getProducts(body []byte) ([]byte, error) {
+	in := &pricing.GetProductsInput{}
+	if len(body) > 0 {
+		err := jsonutil.UnmarshalJSON(in, bytes.NewReader(body))
+		if err != nil {
+			return nil, errorf("InvalidParameterException", "%s", err.Error())
+		}
+	}
+	if aws.StringValue(in.ServiceCode) != "AmazonEC2" {
+		return nil, errorf("InvalidParameterException", "Input Parameters are invalid. Invalid Service Code %s", aws.StringValue(in.ServiceCode))
+	}
+	var selected []map[string]interface{}
+	for _, p := range api.products {
+		ok := true
+		for _, f := range in.Filters {
+			if aws.StringValue(f.Type) != pricing.FilterTypeTermMatch {
+				return nil, errorf("InvalidParameterException", "Input Parameters are invalid. Invalid filter type %s", aws.StringValue(f.Type))
+			}
+			v, found := field(p, aws.StringValue(f.Field))
+			if !found || !strings.EqualFold(v, aws.StringValue(f.Value)) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			selected = append(selected, p)
+		}
+	}
+	start := 0
+	if in.NextToken != nil {
+		var err error
+		start, err = strconv.Atoi(*in.NextToken)
+		if err != nil || start < 0 || start > len(selected) {
+			return nil, errorf("InvalidNextTokenException", "Invalid NextToken %s", *in.NextToken)
+		}
+	}
+	end := len(selected)
+	if max := int(aws.Int64Value(in.MaxResults)); max > 0 && start+max < end {
+		end = start + max
+	}
+	out := struct {
+		FormatVersion string
+		NextToken     *string `json:",omitempty"`
+		PriceList     []string
+	}{
+		FormatVersion: "aws_v1",
+		PriceList:     []string{},
+	}
+	for _, p := range selected[start:end] {
+		b, err := json.Marshal(p)
+		if err != nil {
+			return nil, err
+		}
+		out.PriceList = append(out.PriceList, string(b))
+	}
+	if end < len(selected) {
+		out.NextToken = aws.String(strconv.Itoa(end))
+	}
+	return json.Marshal(out)
+}
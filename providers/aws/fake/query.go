@@ -0,0 +1,150 @@
+package fake
+
+import (
+	"encoding/base64"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+//queryDecoder decodes the parameters of an EC2 query or of an AWS query protocol request, it mirrors the SDK query serializer
+//Unlike EC2 queries, query protocol requests keep field names unchanged and prefix list items with member
+type queryDecoder struct {
+	ec2 bool
+}
+
+var (
+	ec2Decoder      = queryDecoder{ec2: true}
+	protocolDecoder = queryDecoder{}
+)
+
+//name returns the name of a field in a query
+func (d queryDecoder) name(f reflect.StructField) string {
+	if name := f.Tag.Get("queryName"); name != "" && d.ec2 {
+		return name
+	}
+	if name := f.Tag.Get("locationName"); name != "" {
+		if d.ec2 {
+			return strings.ToUpper(name[0:1]) + name[1:]
+		}
+		return name
+	}
+	return f.Name
+}
+
+//hasPrefix returns true if values contains prefix or a key starting with prefix followed by a dot
+func hasPrefix(values url.Values, prefix string) bool {
+	if _, ok := values[prefix]; ok {
+		return true
+	}
+	for k := range values {
+		if strings.HasPrefix(k, prefix+".") {
+			return true
+		}
+	}
+	return false
+}
+
+//decode fills v with the parameters of a query
+func (d queryDecoder) decode(values url.Values, v reflect.Value, prefix string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" || f.Tag.Get("ignore") != "" {
+			continue
+		}
+		name := d.name(f)
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+		if !hasPrefix(values, name) {
+			continue
+		}
+		err := d.decodeValue(values, v.Field(i), name)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d queryDecoder) decodeValue(values url.Values, v reflect.Value, name string) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.Type().Elem().Kind() == reflect.Struct && v.Type().Elem() != reflect.TypeOf(time.Time{}) {
+			e := reflect.New(v.Type().Elem())
+			v.Set(e)
+			return d.decode(values, e.Elem(), name)
+		}
+		e := reflect.New(v.Type().Elem())
+		err := decodeScalar(values.Get(name), e.Elem(), name)
+		if err != nil {
+			return err
+		}
+		v.Set(e)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return decodeScalar(values.Get(name), v, name)
+		}
+		s := reflect.MakeSlice(v.Type(), 0, 0)
+		if !d.ec2 {
+			name += ".member"
+		}
+		for i := 1; hasPrefix(values, name+"."+strconv.Itoa(i)); i++ {
+			e := reflect.New(v.Type().Elem()).Elem()
+			err := d.decodeValue(values, e, name+"."+strconv.Itoa(i))
+			if err != nil {
+				return err
+			}
+			s = reflect.Append(s, e)
+		}
+		v.Set(s)
+	default:
+		return decodeScalar(values.Get(name), v, name)
+	}
+	return nil
+}
+
+func decodeScalar(s string, v reflect.Value, name string) error {
+	switch v.Interface().(type) {
+	case string:
+		v.SetString(s)
+	case []byte:
+		b, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return errors.Wrapf(err, "invalid value for parameter %s", name)
+		}
+		v.SetBytes(b)
+	case bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return errors.Wrapf(err, "invalid value for parameter %s", name)
+		}
+		v.SetBool(b)
+	case int64:
+		i, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return errors.Wrapf(err, "invalid value for parameter %s", name)
+		}
+		v.SetInt(i)
+	case float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return errors.Wrapf(err, "invalid value for parameter %s", name)
+		}
+		v.SetFloat(f)
+	case time.Time:
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return errors.Wrapf(err, "invalid value for parameter %s", name)
+		}
+		v.Set(reflect.ValueOf(t))
+	default:
+		return errors.Errorf("unsupported type %s for parameter %s", v.Type(), name)
+	}
+	return nil
+}
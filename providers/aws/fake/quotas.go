@@ -0,0 +1,92 @@
+package fake
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+//serviceQuotasPrefix prefix of the X-Amz-Target header of the Service Quotas requests
+const serviceQuotasPrefix = "ServiceQuotasV20190624."
+
+//MaxInstances value of the max-instances account attribute
+const MaxInstances = 20
+
+//accountAttributes account attributes returned by DescribeAccountAttributes
+var accountAttributes = map[string]string{
+	"supported-platforms": "VPC",
+	"default-vpc":         "none",
+	"max-instances":       strconv.Itoa(MaxInstances),
+	"vpc-max-elastic-ips": "5",
+	"max-elastic-ips":     "5",
+}
+
+//serviceQuotas values of the quotas returned by GetServiceQuota indexed by service code and quota code
+var serviceQuotas = map[string]map[string]float64{
+	"ec2": {
+		"L-1216C47A": 32,
+		"L-0263D0A3": 5,
+	},
+	"vpc": {
+		"L-E79EC296": 2500,
+	},
+}
+
+//DescribeAccountAttributes describes the attributes of the account
+func (api *ec2API) DescribeAccountAttributes(in *ec2.DescribeAccountAttributesInput) (*ec2.DescribeAccountAttributesOutput, error) {
+	out := &ec2.DescribeAccountAttributesOutput{}
+	for _, name := range []string{"supported-platforms", "default-vpc", "max-instances", "vpc-max-elastic-ips", "max-elastic-ips"} {
+		if len(in.AttributeNames) > 0 && !matchAny(in.AttributeNames, []string{name}) {
+			continue
+		}
+		out.AccountAttributes = append(out.AccountAttributes, &ec2.AccountAttribute{
+			AttributeName: aws.String(name),
+			AttributeValues: []*ec2.AccountAttributeValue{
+				{AttributeValue: aws.String(accountAttributes[name])},
+			},
+		})
+	}
+	return out, nil
+}
+
+//serveServiceQuotas serves the GetServiceQuota operation of the Service Quotas API
+func (s *Server) serveServiceQuotas(w http.ResponseWriter, r *http.Request, target string) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writePricingError(w, errorf("IllegalArgumentException", "%s", err.Error()))
+		return
+	}
+	if strings.TrimPrefix(target, serviceQuotasPrefix) != "GetServiceQuota" {
+		writePricingError(w, errorf("UnknownOperationException", "unknown operation %s", target))
+		return
+	}
+	in := struct {
+		ServiceCode string
+		QuotaCode   string
+	}{}
+	err = json.Unmarshal(body, &in)
+	if err != nil {
+		writePricingError(w, errorf("IllegalArgumentException", "%s", err.Error()))
+		return
+	}
+	value, ok := serviceQuotas[in.ServiceCode][in.QuotaCode]
+	if !ok {
+		writePricingError(w, errorf("NoSuchResourceException", "quota %s of service %s not found", in.QuotaCode, in.ServiceCode))
+		return
+	}
+	b, _ := json.Marshal(map[string]interface{}{
+		"Quota": map[string]interface{}{
+			"ServiceCode": in.ServiceCode,
+			"QuotaCode":   in.QuotaCode,
+			"Value":       value,
+			"Adjustable":  true,
+		},
+	})
+	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+	_, _ = w.Write(b)
+}
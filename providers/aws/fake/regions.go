@@ -0,0 +1,80 @@
+package fake
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+//regions regions listed by the fake, all of them are served by the resources of Region
+var regions = []string{Region, "eu-west-1", "ap-northeast-1"}
+
+//zoneSuffixes suffixes of the availability zones of each region
+var zoneSuffixes = []string{"a", "b", "c"}
+
+//DescribeRegions describes the regions enabled for the account
+func (api *ec2API) DescribeRegions(in *ec2.DescribeRegionsInput) (*ec2.DescribeRegionsOutput, error) {
+	out := &ec2.DescribeRegionsOutput{}
+	for _, r := range regions {
+		ok, err := match(in.Filters, func(name string) ([]string, bool) {
+			switch name {
+			case "region-name":
+				return []string{r}, true
+			}
+			return nil, false
+		})
+		if err != nil {
+			return nil, err
+		}
+		if !ok || len(in.RegionNames) > 0 && !matchAny(in.RegionNames, []string{r}) {
+			continue
+		}
+		out.Regions = append(out.Regions, &ec2.Region{
+			RegionName: aws.String(r),
+			Endpoint:   aws.String("ec2." + r + ".amazonaws.com"),
+		})
+	}
+	return out, nil
+}
+
+//DescribeAvailabilityZones describes the availability zones of the regions selected by the region-name filter, of Region if there is none
+func (api *ec2API) DescribeAvailabilityZones(in *ec2.DescribeAvailabilityZonesInput) (*ec2.DescribeAvailabilityZonesOutput, error) {
+	selected := []string{Region}
+	for _, f := range in.Filters {
+		if aws.StringValue(f.Name) == "region-name" {
+			selected = aws.StringValueSlice(f.Values)
+		}
+	}
+	out := &ec2.DescribeAvailabilityZonesOutput{}
+	for _, r := range regions {
+		if !matchAny(aws.StringSlice(selected), []string{r}) {
+			continue
+		}
+		for _, suffix := range zoneSuffixes {
+			zone := r + suffix
+			ok, err := match(in.Filters, func(name string) ([]string, bool) {
+				switch name {
+				case "region-name":
+					return []string{r}, true
+				case "zone-name":
+					return []string{zone}, true
+				case "state":
+					return []string{ec2.AvailabilityZoneStateAvailable}, true
+				}
+				return nil, false
+			})
+			if err != nil {
+				return nil, err
+			}
+			if !ok || len(in.ZoneNames) > 0 && !matchAny(in.ZoneNames, []string{zone}) {
+				continue
+			}
+			out.AvailabilityZones = append(out.AvailabilityZones, &ec2.AvailabilityZone{
+				RegionName: aws.String(r),
+				ZoneName:   aws.String(zone),
+				State:      aws.String(ec2.AvailabilityZoneStateAvailable),
+				Messages:   []*ec2.AvailabilityZoneMessage{},
+			})
+		}
+	}
+	return out, nil
+}
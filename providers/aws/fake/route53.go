@@ -0,0 +1,540 @@
+package fake
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/private/protocol/xml/xmlutil"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/google/uuid"
+)
+
+const (
+	route53Namespace = "https://route53.amazonaws.com/doc/2013-04-01/"
+	//route53Prefix path prefix of the Route53 requests, used to tell them apart from query requests
+	route53Prefix = "/2013-04-01/"
+)
+
+//route53Route maps the method and the path, relative to route53Prefix, of a Route53 request to its action
+//The named groups of the path are the values of the uri members of the action input
+type route53Route struct {
+	method string
+	path   *regexp.Regexp
+	action string
+	status int
+}
+
+var route53Routes = []route53Route{
+	{http.MethodPost, regexp.MustCompile(`^hostedzone$`), "CreateHostedZone", http.StatusCreated},
+	{http.MethodGet, regexp.MustCompile(`^hostedzone$`), "ListHostedZones", http.StatusOK},
+	{http.MethodGet, regexp.MustCompile(`^hostedzonesbyname$`), "ListHostedZonesByName", http.StatusOK},
+	{http.MethodGet, regexp.MustCompile(`^hostedzone/(?P<Id>[^/]+)$`), "GetHostedZone", http.StatusOK},
+	{http.MethodDelete, regexp.MustCompile(`^hostedzone/(?P<Id>[^/]+)$`), "DeleteHostedZone", http.StatusOK},
+	{http.MethodPost, regexp.MustCompile(`^hostedzone/(?P<Id>[^/]+)/rrset/?$`), "ChangeResourceRecordSets", http.StatusOK},
+	{http.MethodGet, regexp.MustCompile(`^hostedzone/(?P<Id>[^/]+)/rrset$`), "ListResourceRecordSets", http.StatusOK},
+	{http.MethodGet, regexp.MustCompile(`^change/(?P<Id>[^/]+)$`), "GetChange", http.StatusOK},
+}
+
+//hostedZone hosted zone and its record sets
+type hostedZone struct {
+	zone          *route53.HostedZone
+	delegationSet *route53.DelegationSet
+	records       []*route53.ResourceRecordSet
+}
+
+//route53API fake Route53 API
+//Each exported method implements the Route53 action of the same name, changes are in sync as soon as they are submitted
+type route53API struct {
+	zones map[string]*hostedZone
+}
+
+func newRoute53API() *route53API {
+	return &route53API{
+		zones: map[string]*hostedZone{},
+	}
+}
+
+func route53ID(prefix string) string {
+	return prefix + strings.ToUpper(strings.Replace(uuid.New().String(), "-", "", -1)[:13])
+}
+
+func changeInfo() *route53.ChangeInfo {
+	return &route53.ChangeInfo{
+		Id:          aws.String("/change/" + route53ID("C")),
+		Status:      aws.String(route53.ChangeStatusInsync),
+		SubmittedAt: aws.Time(time.Now().UTC()),
+	}
+}
+
+//fqdn returns the normalized fully qualified form of a domain name
+func fqdn(name *string) string {
+	return strings.ToLower(strings.TrimSuffix(aws.StringValue(name), ".")) + "."
+}
+
+//reversedName returns the labels of a domain name in reverse order, Route53 sorts record sets using this form
+func reversedName(name string) string {
+	labels := strings.Split(strings.TrimSuffix(name, "."), ".")
+	for i, j := 0, len(labels)-1; i < j; i, j = i+1, j-1 {
+		labels[i], labels[j] = labels[j], labels[i]
+	}
+	return strings.Join(labels, ".")
+}
+
+func lessRecordSet(name1, type1, name2, type2 string) bool {
+	if name1 != name2 {
+		return reversedName(name1) < reversedName(name2)
+	}
+	return type1 < type2
+}
+
+func (api *route53API) hostedZone(id *string) (*hostedZone, error) {
+	z, ok := api.zones[strings.TrimPrefix(aws.StringValue(id), "/hostedzone/")]
+	if !ok {
+		return nil, &apiError{
+			status:  http.StatusNotFound,
+			code:    "NoSuchHostedZone",
+			message: fmt.Sprintf("No hosted zone found with ID: %s", aws.StringValue(id)),
+		}
+	}
+	return z, nil
+}
+
+func (api *route53API) CreateHostedZone(input *route53.CreateHostedZoneInput) (*route53.CreateHostedZoneOutput, error) {
+	name := fqdn(input.Name)
+	if input.Name == nil || strings.ContainsAny(name, " /") || strings.Contains(name, "..") {
+		return nil, errorf("InvalidDomainName", "%s is not a valid domain name", aws.StringValue(input.Name))
+	}
+	if aws.StringValue(input.CallerReference) == "" {
+		return nil, errorf("InvalidInput", "CallerReference is required")
+	}
+	for _, z := range api.zones {
+		if aws.StringValue(z.zone.CallerReference) == aws.StringValue(input.CallerReference) {
+			return nil, &apiError{
+				status:  http.StatusConflict,
+				code:    "HostedZoneAlreadyExists",
+				message: fmt.Sprintf("A hosted zone has already been created with the specified caller reference %s", aws.StringValue(input.CallerReference)),
+			}
+		}
+	}
+	id := route53ID("Z")
+	config := input.HostedZoneConfig
+	if config == nil {
+		config = &route53.HostedZoneConfig{}
+	}
+	config.PrivateZone = aws.Bool(false)
+	z := &hostedZone{
+		zone: &route53.HostedZone{
+			Id:                     aws.String("/hostedzone/" + id),
+			Name:                   aws.String(name),
+			CallerReference:        input.CallerReference,
+			Config:                 config,
+			ResourceRecordSetCount: aws.Int64(2),
+		},
+		delegationSet: &route53.DelegationSet{},
+	}
+	for i := 1; i <= 4; i++ {
+		z.delegationSet.NameServers = append(z.delegationSet.NameServers, aws.String(fmt.Sprintf("ns-%d.awsdns-fake.net", i)))
+	}
+	var ns []*route53.ResourceRecord
+	for _, n := range z.delegationSet.NameServers {
+		ns = append(ns, &route53.ResourceRecord{Value: aws.String(*n + ".")})
+	}
+	z.records = []*route53.ResourceRecordSet{
+		{
+			Name:            aws.String(name),
+			Type:            aws.String(route53.RRTypeNs),
+			TTL:             aws.Int64(172800),
+			ResourceRecords: ns,
+		},
+		{
+			Name: aws.String(name),
+			Type: aws.String(route53.RRTypeSoa),
+			TTL:  aws.Int64(900),
+			ResourceRecords: []*route53.ResourceRecord{
+				{Value: aws.String(*z.delegationSet.NameServers[0] + ". awsdns-hostmaster.amazon.com. 1 7200 900 1209600 86400")},
+			},
+		},
+	}
+	api.zones[id] = z
+	return &route53.CreateHostedZoneOutput{
+		HostedZone:    z.zone,
+		DelegationSet: z.delegationSet,
+		ChangeInfo:    changeInfo(),
+		Location:      aws.String(route53Namespace + "hostedzone/" + id),
+	}, nil
+}
+
+//sortedZones returns the hosted zones sorted by name
+func (api *route53API) sortedZones() []*hostedZone {
+	var zones []*hostedZone
+	for _, z := range api.zones {
+		zones = append(zones, z)
+	}
+	sort.Slice(zones, func(i, j int) bool {
+		n1, n2 := reversedName(*zones[i].zone.Name), reversedName(*zones[j].zone.Name)
+		if n1 != n2 {
+			return n1 < n2
+		}
+		return *zones[i].zone.Id < *zones[j].zone.Id
+	})
+	return zones
+}
+
+func (api *route53API) ListHostedZones(input *route53.ListHostedZonesInput) (*route53.ListHostedZonesOutput, error) {
+	out := &route53.ListHostedZonesOutput{
+		HostedZones: []*route53.HostedZone{},
+		IsTruncated: aws.Bool(false),
+		Marker:      aws.String(aws.StringValue(input.Marker)),
+		MaxItems:    aws.String("100"),
+	}
+	started := input.Marker == nil
+	for _, z := range api.sortedZones() {
+		id := strings.TrimPrefix(*z.zone.Id, "/hostedzone/")
+		if !started && id != *input.Marker {
+			continue
+		}
+		started = true
+		if len(out.HostedZones) == 100 {
+			out.IsTruncated = aws.Bool(true)
+			out.NextMarker = aws.String(id)
+			break
+		}
+		out.HostedZones = append(out.HostedZones, z.zone)
+	}
+	return out, nil
+}
+
+func (api *route53API) ListHostedZonesByName(input *route53.ListHostedZonesByNameInput) (*route53.ListHostedZonesByNameOutput, error) {
+	out := &route53.ListHostedZonesByNameOutput{
+		DNSName:     input.DNSName,
+		HostedZones: []*route53.HostedZone{},
+		IsTruncated: aws.Bool(false),
+		MaxItems:    aws.String("100"),
+	}
+	max := 100
+	if input.MaxItems != nil {
+		n, err := strconv.Atoi(*input.MaxItems)
+		if err != nil || n < 1 {
+			return nil, errorf("InvalidInput", "invalid maxitems %s", *input.MaxItems)
+		}
+		max = n
+		out.MaxItems = input.MaxItems
+	}
+	for _, z := range api.sortedZones() {
+		if input.DNSName != nil && reversedName(*z.zone.Name) < reversedName(fqdn(input.DNSName)) {
+			continue
+		}
+		if len(out.HostedZones) == max {
+			out.IsTruncated = aws.Bool(true)
+			out.NextDNSName = z.zone.Name
+			out.NextHostedZoneId = aws.String(strings.TrimPrefix(*z.zone.Id, "/hostedzone/"))
+			break
+		}
+		out.HostedZones = append(out.HostedZones, z.zone)
+	}
+	return out, nil
+}
+
+func (api *route53API) GetHostedZone(input *route53.GetHostedZoneInput) (*route53.GetHostedZoneOutput, error) {
+	z, err := api.hostedZone(input.Id)
+	if err != nil {
+		return nil, err
+	}
+	return &route53.GetHostedZoneOutput{
+		HostedZone:    z.zone,
+		DelegationSet: z.delegationSet,
+	}, nil
+}
+
+func (api *route53API) DeleteHostedZone(input *route53.DeleteHostedZoneInput) (*route53.DeleteHostedZoneOutput, error) {
+	z, err := api.hostedZone(input.Id)
+	if err != nil {
+		return nil, err
+	}
+	if len(z.records) > 2 {
+		return nil, errorf("HostedZoneNotEmpty", "The specified hosted zone contains non-required resource record sets and so cannot be deleted.")
+	}
+	delete(api.zones, strings.TrimPrefix(*z.zone.Id, "/hostedzone/"))
+	return &route53.DeleteHostedZoneOutput{
+		ChangeInfo: changeInfo(),
+	}, nil
+}
+
+//checkRecordSet checks that the values of a record set are consistent with its type
+func checkRecordSet(zone string, rs *route53.ResourceRecordSet) error {
+	name := fqdn(rs.Name)
+	if name != zone && !strings.HasSuffix(name, "."+zone) {
+		return fmt.Errorf("RRSet with DNS name %s is not permitted in zone %s", name, zone)
+	}
+	if rs.TTL == nil || len(rs.ResourceRecords) == 0 {
+		return fmt.Errorf("Invalid request: Missing field 'TTL' or 'ResourceRecords' in RRSet %s", name)
+	}
+	for _, r := range rs.ResourceRecords {
+		v := aws.StringValue(r.Value)
+		ip := net.ParseIP(v)
+		switch aws.StringValue(rs.Type) {
+		case route53.RRTypeA:
+			if ip == nil || ip.To4() == nil {
+				return fmt.Errorf("ARRDATAIllegalIPv4Address (Value is not a valid IPv4 address) encountered with '%s'", v)
+			}
+		case route53.RRTypeAaaa:
+			if ip == nil || ip.To4() != nil {
+				return fmt.Errorf("AAAARRDATAIllegalIPv6Address (Value is not a valid IPv6 address) encountered with '%s'", v)
+			}
+		case route53.RRTypeCname:
+			if len(rs.ResourceRecords) > 1 || name == zone {
+				return fmt.Errorf("RRSet of type CNAME with DNS name %s is not permitted at apex in zone %s or with several values", name, zone)
+			}
+		case route53.RRTypeTxt:
+			if !strings.HasPrefix(v, `"`) || !strings.HasSuffix(v, `"`) {
+				return fmt.Errorf("Invalid Resource Record: FATAL problem: InvalidCharacterString (Value should be enclosed in quotation marks) encountered with '%s'", v)
+			}
+		}
+	}
+	return nil
+}
+
+//invalidChangeBatch InvalidChangeBatch error, it is written using a specific XML document
+func invalidChangeBatch(err error) error {
+	return errorf("InvalidChangeBatch", "%s", err.Error())
+}
+
+//change applies a change to the record sets of a zone, the resulting record sets are returned
+func (z *hostedZone) change(records []*route53.ResourceRecordSet, c *route53.Change) ([]*route53.ResourceRecordSet, error) {
+	rs := c.ResourceRecordSet
+	if rs == nil || rs.Name == nil || rs.Type == nil {
+		return nil, invalidChangeBatch(fmt.Errorf("Invalid request: Missing field 'Name' or 'Type'"))
+	}
+	name, t := fqdn(rs.Name), aws.StringValue(rs.Type)
+	index := -1
+	for i, r := range records {
+		if aws.StringValue(r.Name) == name && aws.StringValue(r.Type) == t {
+			index = i
+		}
+	}
+	switch aws.StringValue(c.Action) {
+	case route53.ChangeActionDelete:
+		if index < 0 || !reflect.DeepEqual(records[index].ResourceRecords, rs.ResourceRecords) || aws.Int64Value(records[index].TTL) != aws.Int64Value(rs.TTL) {
+			return nil, invalidChangeBatch(fmt.Errorf("Tried to delete resource record set [name='%s', type='%s'] but it was not found", name, t))
+		}
+		if name == *z.zone.Name && (t == route53.RRTypeNs || t == route53.RRTypeSoa) {
+			return nil, invalidChangeBatch(fmt.Errorf("A HostedZone must contain exactly one SOA record and at least one NS record at its apex"))
+		}
+		return append(records[:index:index], records[index+1:]...), nil
+	case route53.ChangeActionCreate, route53.ChangeActionUpsert:
+		if index >= 0 && aws.StringValue(c.Action) == route53.ChangeActionCreate {
+			return nil, invalidChangeBatch(fmt.Errorf("Tried to create resource record set [name='%s', type='%s'] but it already exists", name, t))
+		}
+		err := checkRecordSet(*z.zone.Name, rs)
+		if err != nil {
+			return nil, invalidChangeBatch(err)
+		}
+		for _, r := range records {
+			if aws.StringValue(r.Name) == name && (aws.StringValue(r.Type) == route53.RRTypeCname) != (t == route53.RRTypeCname) {
+				return nil, invalidChangeBatch(fmt.Errorf("RRSet of type %s with DNS name %s is not permitted as it conflicts with other records with the same DNS name in zone %s", t, name, *z.zone.Name))
+			}
+		}
+		created := &route53.ResourceRecordSet{
+			Name:            aws.String(name),
+			Type:            aws.String(t),
+			TTL:             rs.TTL,
+			ResourceRecords: rs.ResourceRecords,
+		}
+		if index >= 0 {
+			res := append([]*route53.ResourceRecordSet{}, records...)
+			res[index] = created
+			return res, nil
+		}
+		return append(records[:len(records):len(records)], created), nil
+	default:
+		return nil, errorf("InvalidInput", "Invalid action %s", aws.StringValue(c.Action))
+	}
+}
+
+func (api *route53API) ChangeResourceRecordSets(input *route53.ChangeResourceRecordSetsInput) (*route53.ChangeResourceRecordSetsOutput, error) {
+	z, err := api.hostedZone(input.HostedZoneId)
+	if err != nil {
+		return nil, err
+	}
+	if input.ChangeBatch == nil || len(input.ChangeBatch.Changes) == 0 {
+		return nil, errorf("InvalidInput", "ChangeBatch must contain at least one change")
+	}
+	//changes are applied atomically
+	records := z.records
+	for _, c := range input.ChangeBatch.Changes {
+		records, err = z.change(records, c)
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return lessRecordSet(*records[i].Name, *records[i].Type, *records[j].Name, *records[j].Type)
+	})
+	z.records = records
+	z.zone.ResourceRecordSetCount = aws.Int64(int64(len(records)))
+	return &route53.ChangeResourceRecordSetsOutput{
+		ChangeInfo: changeInfo(),
+	}, nil
+}
+
+func (api *route53API) ListResourceRecordSets(input *route53.ListResourceRecordSetsInput) (*route53.ListResourceRecordSetsOutput, error) {
+	z, err := api.hostedZone(input.HostedZoneId)
+	if err != nil {
+		return nil, err
+	}
+	max := 300
+	if input.MaxItems != nil {
+		max, err = strconv.Atoi(*input.MaxItems)
+		if err != nil || max < 1 {
+			return nil, errorf("InvalidInput", "invalid maxitems %s", *input.MaxItems)
+		}
+	}
+	if input.StartRecordType != nil && input.StartRecordName == nil {
+		return nil, errorf("InvalidInput", "The input is not valid: type requires name")
+	}
+	out := &route53.ListResourceRecordSetsOutput{
+		ResourceRecordSets: []*route53.ResourceRecordSet{},
+		IsTruncated:        aws.Bool(false),
+		MaxItems:           aws.String(strconv.Itoa(max)),
+	}
+	for _, r := range z.records {
+		if input.StartRecordName != nil && lessRecordSet(*r.Name, *r.Type, fqdn(input.StartRecordName), aws.StringValue(input.StartRecordType)) {
+			continue
+		}
+		if len(out.ResourceRecordSets) == max {
+			out.IsTruncated = aws.Bool(true)
+			out.NextRecordName = r.Name
+			out.NextRecordType = r.Type
+			break
+		}
+		out.ResourceRecordSets = append(out.ResourceRecordSets, r)
+	}
+	return out, nil
+}
+
+func (api *route53API) GetChange(input *route53.GetChangeInput) (*route53.GetChangeOutput, error) {
+	info := changeInfo()
+	info.Id = aws.String("/change/" + strings.TrimPrefix(aws.StringValue(input.Id), "/change/"))
+	return &route53.GetChangeOutput{
+		ChangeInfo: info,
+	}, nil
+}
+
+//decodeRoute53Input decodes the body, the uri and the querystring members of the input of a Route53 action
+func decodeRoute53Input(r *http.Request, uri map[string]string, input reflect.Value) error {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	if len(bytes.TrimSpace(body)) > 0 {
+		err = xmlutil.UnmarshalXML(input.Interface(), xml.NewDecoder(bytes.NewReader(body)), "")
+		if err != nil {
+			return err
+		}
+	}
+	query := r.URL.Query()
+	t := input.Elem().Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		var value string
+		switch f.Tag.Get("location") {
+		case "uri":
+			value = uri[f.Tag.Get("locationName")]
+		case "querystring":
+			value = query.Get(f.Tag.Get("locationName"))
+		}
+		if value != "" && f.Type == reflect.TypeOf((*string)(nil)) {
+			input.Elem().Field(i).Set(reflect.ValueOf(aws.String(value)))
+		}
+	}
+	return nil
+}
+
+func (s *Server) serveRoute53(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, route53Prefix)
+	for _, route := range route53Routes {
+		match := route.path.FindStringSubmatch(path)
+		if route.method != r.Method || match == nil {
+			continue
+		}
+		uri := map[string]string{}
+		for i, name := range route.path.SubexpNames() {
+			if name != "" {
+				uri[name] = match[i]
+			}
+		}
+		method := reflect.ValueOf(s.route53).MethodByName(route.action)
+		input := reflect.New(method.Type().In(0).Elem())
+		err := decodeRoute53Input(r, uri, input)
+		if err != nil {
+			writeRoute53Error(w, errorf("InvalidInput", "%s", err.Error()))
+			return
+		}
+		res := method.Call([]reflect.Value{input})
+		if !res[1].IsNil() {
+			writeRoute53Error(w, res[1].Interface().(error))
+			return
+		}
+		writeRoute53Response(w, route, res[0].Interface())
+		return
+	}
+	writeRoute53Error(w, &apiError{
+		status:  http.StatusNotFound,
+		code:    "UnknownOperationException",
+		message: fmt.Sprintf("unknown operation %s %s", r.Method, r.URL.Path),
+	})
+}
+
+func writeRoute53Response(w http.ResponseWriter, route route53Route, output interface{}) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	fmt.Fprintf(&buf, `<%sResponse xmlns="%s">`, route.action, route53Namespace)
+	err := xmlutil.BuildXML(output, xml.NewEncoder(&buf))
+	if err != nil {
+		writeRoute53Error(w, err)
+		return
+	}
+	fmt.Fprintf(&buf, "</%sResponse>", route.action)
+	if out, ok := output.(*route53.CreateHostedZoneOutput); ok {
+		w.Header().Set("Location", aws.StringValue(out.Location))
+	}
+	w.Header().Set("Content-Type", "text/xml")
+	w.WriteHeader(route.status)
+	_, _ = w.Write(buf.Bytes())
+}
+
+type invalidChangeBatchResponse struct {
+	XMLName  xml.Name `xml:"InvalidChangeBatch"`
+	Messages []string `xml:"Messages>Message"`
+}
+
+func writeRoute53Error(w http.ResponseWriter, err error) {
+	e := toAPIError(err)
+	var b []byte
+	if e.code == "InvalidChangeBatch" {
+		b, _ = xml.Marshal(&invalidChangeBatchResponse{
+			Messages: []string{e.message},
+		})
+	} else {
+		b, _ = xml.Marshal(&errorResponse{
+			Type:      "Sender",
+			Code:      e.code,
+			Message:   e.message,
+			RequestID: uuid.New().String(),
+		})
+	}
+	w.Header().Set("Content-Type", "text/xml")
+	w.WriteHeader(e.status)
+	_, _ = w.Write(append([]byte(xml.Header), b...))
+}
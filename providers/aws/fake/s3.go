@@ -0,0 +1,574 @@
+package fake
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/google/uuid"
+)
+
+const s3Namespace = "http://s3.amazonaws.com/doc/2006-03-01/"
+
+//s3BucketName valid bucket names
+var s3BucketName = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`)
+
+type s3Object struct {
+	content      []byte
+	contentType  string
+	etag         string
+	lastModified time.Time
+}
+
+type s3Bucket struct {
+	name    string
+	created time.Time
+	objects map[string]*s3Object
+}
+
+//s3Upload multipart upload in progress
+type s3Upload struct {
+	bucket      string
+	key         string
+	contentType string
+	parts       map[int][]byte
+}
+
+//s3API fake S3 API, buckets are addressed in path style (http://endpoint/bucket/key)
+type s3API struct {
+	buckets map[string]*s3Bucket
+	uploads map[string]*s3Upload
+}
+
+func newS3API() *s3API {
+	return &s3API{
+		buckets: map[string]*s3Bucket{},
+		uploads: map[string]*s3Upload{},
+	}
+}
+
+//isS3Request reports whether the request is signed for the S3 service, in its Authorization header or in its pre-signed URL
+func isS3Request(r *http.Request) bool {
+	scope := "/" + Region + "/s3/aws4_request"
+	return strings.Contains(r.Header.Get("Authorization"), scope) || strings.HasSuffix(r.URL.Query().Get("X-Amz-Credential"), scope)
+}
+
+func s3Error(status int, code string, format string, args ...interface{}) error {
+	return &apiError{
+		status:  status,
+		code:    code,
+		message: fmt.Sprintf(format, args...),
+	}
+}
+
+func noSuchBucket() error {
+	return s3Error(http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist")
+}
+
+func (api *s3API) bucket(name string) (*s3Bucket, error) {
+	b, ok := api.buckets[name]
+	if !ok {
+		return nil, noSuchBucket()
+	}
+	return b, nil
+}
+
+func (api *s3API) object(bucket, key string) (*s3Object, error) {
+	b, err := api.bucket(bucket)
+	if err != nil {
+		return nil, err
+	}
+	o, ok := b.objects[key]
+	if !ok {
+		return nil, s3Error(http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+	}
+	return o, nil
+}
+
+func newS3Object(content []byte, contentType string) *s3Object {
+	if contentType == "" {
+		contentType = "binary/octet-stream"
+	}
+	sum := md5.Sum(content)
+	return &s3Object{
+		content:      content,
+		contentType:  contentType,
+		etag:         `"` + hex.EncodeToString(sum[:]) + `"`,
+		lastModified: time.Now().UTC().Truncate(time.Second),
+	}
+}
+
+//checkPresignedURL checks the signature and the expiry of a pre-signed URL
+//The signature is computed again with the credentials of the configuration returned by Server.Config
+func checkPresignedURL(r *http.Request) error {
+	query := r.URL.Query()
+	signTime, err := time.Parse("20060102T150405Z", query.Get("X-Amz-Date"))
+	if err != nil {
+		return s3Error(http.StatusForbidden, "AccessDenied", "X-Amz-Date must be in the ISO8601 Long Format")
+	}
+	expires, err := strconv.Atoi(query.Get("X-Amz-Expires"))
+	if err != nil {
+		return s3Error(http.StatusForbidden, "AccessDenied", "X-Amz-Expires must be a number")
+	}
+	if time.Now().After(signTime.Add(time.Duration(expires) * time.Second)) {
+		return s3Error(http.StatusForbidden, "AccessDenied", "Request has expired")
+	}
+	u := *r.URL
+	u.Scheme, u.Host = "http", r.Host
+	unsigned := url.Values{}
+	for k, v := range query {
+		if !strings.HasPrefix(k, "X-Amz-") {
+			unsigned[k] = v
+		}
+	}
+	u.RawQuery = unsigned.Encode()
+	req, err := http.NewRequest(r.Method, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	signer := v4.NewSigner(credentials.NewStaticCredentials("fake", "fake", ""), func(s *v4.Signer) {
+		s.DisableURIPathEscaping = true
+	})
+	_, err = signer.Presign(req, nil, "s3", Region, time.Duration(expires)*time.Second, signTime)
+	if err != nil {
+		return err
+	}
+	if req.URL.Query().Get("X-Amz-Signature") != query.Get("X-Amz-Signature") {
+		return s3Error(http.StatusForbidden, "SignatureDoesNotMatch", "The request signature we calculated does not match the signature you provided. Check your key and signing method.")
+	}
+	return nil
+}
+
+//serveS3 dispatches S3 requests using their method, their path and their sub-resource parameters
+func (s *Server) serveS3(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("X-Amz-Signature") != "" {
+		err := checkPresignedURL(r)
+		if err != nil {
+			writeS3Error(w, r, err)
+			return
+		}
+	}
+	path := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)
+	bucket, key := path[0], ""
+	if len(path) == 2 {
+		key = path[1]
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeS3Error(w, r, err)
+		return
+	}
+	query := r.URL.Query()
+	api := s.s3
+	var output interface{}
+	switch {
+	case bucket == "" && r.Method == http.MethodGet:
+		output = api.listBuckets()
+	case key == "" && r.Method == http.MethodPut:
+		err = api.createBucket(w, bucket)
+	case key == "" && r.Method == http.MethodHead:
+		_, err = api.bucket(bucket)
+	case key == "" && r.Method == http.MethodDelete:
+		err = api.deleteBucket(w, bucket)
+	case key == "" && r.Method == http.MethodGet:
+		output, err = api.listObjects(bucket, query)
+	case key == "" && r.Method == http.MethodPost && hasParam(query, "delete"):
+		output, err = api.deleteObjects(bucket, body)
+	case r.Method == http.MethodPost && hasParam(query, "uploads"):
+		output, err = api.createMultipartUpload(bucket, key, r.Header.Get("Content-Type"))
+	case r.Method == http.MethodPost && query.Get("uploadId") != "":
+		output, err = api.completeMultipartUpload(bucket, key, query.Get("uploadId"))
+	case r.Method == http.MethodPut && query.Get("uploadId") != "":
+		err = api.uploadPart(w, bucket, key, query, body)
+	case r.Method == http.MethodPut:
+		err = api.putObject(w, bucket, key, r.Header.Get("Content-Type"), body)
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		err = api.getObject(w, r, bucket, key)
+		if err == nil {
+			return
+		}
+	case r.Method == http.MethodDelete && query.Get("uploadId") != "":
+		err = api.abortMultipartUpload(w, query.Get("uploadId"))
+	case r.Method == http.MethodDelete:
+		err = api.deleteObject(w, bucket, key)
+	default:
+		err = s3Error(http.StatusNotImplemented, "NotImplemented", "A header you provided implies functionality that is not implemented")
+	}
+	if err != nil {
+		writeS3Error(w, r, err)
+		return
+	}
+	writeS3Response(w, output)
+}
+
+func hasParam(query url.Values, name string) bool {
+	_, ok := query[name]
+	return ok
+}
+
+func writeS3Response(w http.ResponseWriter, output interface{}) {
+	w.Header().Set("x-amz-request-id", strings.ToUpper(uuid.New().String()[:16]))
+	if output == nil {
+		return
+	}
+	b, err := xml.Marshal(output)
+	if err != nil {
+		writeS3Error(w, nil, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	_, _ = w.Write(append([]byte(xml.Header), b...))
+}
+
+type s3ErrorResponse struct {
+	XMLName   xml.Name `xml:"Error"`
+	Code      string   `xml:"Code"`
+	Message   string   `xml:"Message"`
+	Resource  string   `xml:"Resource,omitempty"`
+	RequestID string   `xml:"RequestId"`
+}
+
+//writeS3Error writes the error document of the S3 API, responses to HEAD requests have no body
+func writeS3Error(w http.ResponseWriter, r *http.Request, err error) {
+	e := toAPIError(err)
+	w.Header().Set("x-amz-request-id", strings.ToUpper(uuid.New().String()[:16]))
+	if r != nil && r.Method == http.MethodHead {
+		w.WriteHeader(e.status)
+		return
+	}
+	res := &s3ErrorResponse{
+		Code:      e.code,
+		Message:   e.message,
+		RequestID: uuid.New().String(),
+	}
+	if r != nil {
+		res.Resource = r.URL.Path
+	}
+	b, _ := xml.Marshal(res)
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(e.status)
+	_, _ = w.Write(append([]byte(xml.Header), b...))
+}
+
+type s3Owner struct {
+	ID          string `xml:"ID"`
+	DisplayName string `xml:"DisplayName"`
+}
+
+var fakeOwner = s3Owner{ID: "75aa57f09aa0c8caeab4f8c24e99d10f8e7faeebf76c078efc7c6caea54ba06a", DisplayName: "anyclouds"}
+
+type listBucketsResult struct {
+	XMLName xml.Name `xml:"ListAllMyBucketsResult"`
+	Xmlns   string   `xml:"xmlns,attr"`
+	Owner   s3Owner  `xml:"Owner"`
+	Buckets []struct {
+		Name         string `xml:"Name"`
+		CreationDate string `xml:"CreationDate"`
+	} `xml:"Buckets>Bucket"`
+}
+
+func s3Time(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000Z")
+}
+
+func (api *s3API) listBuckets() interface{} {
+	res := &listBucketsResult{Xmlns: s3Namespace, Owner: fakeOwner}
+	var names []string
+	for name := range api.buckets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		res.Buckets = append(res.Buckets, struct {
+			Name         string `xml:"Name"`
+			CreationDate string `xml:"CreationDate"`
+		}{name, s3Time(api.buckets[name].created)})
+	}
+	return res
+}
+
+func (api *s3API) createBucket(w http.ResponseWriter, name string) error {
+	if !s3BucketName.MatchString(name) {
+		return s3Error(http.StatusBadRequest, "InvalidBucketName", "The specified bucket is not valid.")
+	}
+	if _, ok := api.buckets[name]; ok {
+		return s3Error(http.StatusConflict, "BucketAlreadyOwnedByYou", "Your previous request to create the named bucket succeeded and you already own it.")
+	}
+	api.buckets[name] = &s3Bucket{
+		name:    name,
+		created: time.Now(),
+		objects: map[string]*s3Object{},
+	}
+	w.Header().Set("Location", "/"+name)
+	return nil
+}
+
+func (api *s3API) deleteBucket(w http.ResponseWriter, name string) error {
+	b, err := api.bucket(name)
+	if err != nil {
+		return err
+	}
+	if len(b.objects) > 0 {
+		return s3Error(http.StatusConflict, "BucketNotEmpty", "The bucket you tried to delete is not empty")
+	}
+	delete(api.buckets, name)
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+type s3Content struct {
+	Key          string `xml:"Key"`
+	LastModified string `xml:"LastModified"`
+	ETag         string `xml:"ETag"`
+	Size         int64  `xml:"Size"`
+	StorageClass string `xml:"StorageClass"`
+}
+
+type listObjectsV2Result struct {
+	XMLName               xml.Name    `xml:"ListBucketResult"`
+	Xmlns                 string      `xml:"xmlns,attr"`
+	Name                  string      `xml:"Name"`
+	Prefix                string      `xml:"Prefix"`
+	KeyCount              int         `xml:"KeyCount"`
+	MaxKeys               int         `xml:"MaxKeys"`
+	IsTruncated           bool        `xml:"IsTruncated"`
+	Contents              []s3Content `xml:"Contents"`
+	ContinuationToken     string      `xml:"ContinuationToken,omitempty"`
+	NextContinuationToken string      `xml:"NextContinuationToken,omitempty"`
+}
+
+//listObjects implements ListObjectsV2, the continuation token is the last key of the previous page
+func (api *s3API) listObjects(name string, query url.Values) (interface{}, error) {
+	b, err := api.bucket(name)
+	if err != nil {
+		return nil, err
+	}
+	if query.Get("list-type") != "2" {
+		return nil, s3Error(http.StatusNotImplemented, "NotImplemented", "only ListObjectsV2 is implemented")
+	}
+	maxKeys := 1000
+	if v := query.Get("max-keys"); v != "" {
+		maxKeys, err = strconv.Atoi(v)
+		if err != nil || maxKeys < 0 {
+			return nil, s3Error(http.StatusBadRequest, "InvalidArgument", "Provided max-keys not an integer or within integer range")
+		}
+	}
+	prefix, token := query.Get("prefix"), query.Get("continuation-token")
+	var keys []string
+	for key := range b.objects {
+		if strings.HasPrefix(key, prefix) && key > token {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	res := &listObjectsV2Result{
+		Xmlns:             s3Namespace,
+		Name:              name,
+		Prefix:            prefix,
+		MaxKeys:           maxKeys,
+		ContinuationToken: token,
+	}
+	if len(keys) > maxKeys {
+		keys = keys[:maxKeys]
+		res.IsTruncated = true
+		res.NextContinuationToken = keys[len(keys)-1]
+	}
+	for _, key := range keys {
+		o := b.objects[key]
+		res.Contents = append(res.Contents, s3Content{
+			Key:          key,
+			LastModified: s3Time(o.lastModified),
+			ETag:         o.etag,
+			Size:         int64(len(o.content)),
+			StorageClass: "STANDARD",
+		})
+	}
+	res.KeyCount = len(res.Contents)
+	return res, nil
+}
+
+type deleteObjectsRequest struct {
+	Objects []struct {
+		Key string `xml:"Key"`
+	} `xml:"Object"`
+	Quiet bool `xml:"Quiet"`
+}
+
+type deleteObjectsResult struct {
+	XMLName xml.Name `xml:"DeleteResult"`
+	Xmlns   string   `xml:"xmlns,attr"`
+	Deleted []struct {
+		Key string `xml:"Key"`
+	} `xml:"Deleted"`
+}
+
+func (api *s3API) deleteObjects(name string, body []byte) (interface{}, error) {
+	b, err := api.bucket(name)
+	if err != nil {
+		return nil, err
+	}
+	in := &deleteObjectsRequest{}
+	err = xml.Unmarshal(body, in)
+	if err != nil {
+		return nil, s3Error(http.StatusBadRequest, "MalformedXML", "The XML you provided was not well-formed or did not validate against our published schema")
+	}
+	res := &deleteObjectsResult{Xmlns: s3Namespace}
+	for _, o := range in.Objects {
+		delete(b.objects, o.Key)
+		if !in.Quiet {
+			res.Deleted = append(res.Deleted, struct {
+				Key string `xml:"Key"`
+			}{o.Key})
+		}
+	}
+	return res, nil
+}
+
+func (api *s3API) putObject(w http.ResponseWriter, bucket, key, contentType string, body []byte) error {
+	b, err := api.bucket(bucket)
+	if err != nil {
+		return err
+	}
+	o := newS3Object(body, contentType)
+	b.objects[key] = o
+	w.Header().Set("ETag", o.etag)
+	return nil
+}
+
+//getObject implements GetObject and HeadObject
+func (api *s3API) getObject(w http.ResponseWriter, r *http.Request, bucket, key string) error {
+	o, err := api.object(bucket, key)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("x-amz-request-id", strings.ToUpper(uuid.New().String()[:16]))
+	w.Header().Set("Content-Type", o.contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(o.content)))
+	w.Header().Set("ETag", o.etag)
+	w.Header().Set("Last-Modified", o.lastModified.Format(http.TimeFormat))
+	w.Header().Set("Accept-Ranges", "bytes")
+	if r.Method == http.MethodGet {
+		_, _ = w.Write(o.content)
+	}
+	return nil
+}
+
+func (api *s3API) deleteObject(w http.ResponseWriter, bucket, key string) error {
+	b, err := api.bucket(bucket)
+	if err != nil {
+		return err
+	}
+	//deleting a missing object succeeds
+	delete(b.objects, key)
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+type initiateMultipartUploadResult struct {
+	XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+	Xmlns    string   `xml:"xmlns,attr"`
+	Bucket   string   `xml:"Bucket"`
+	Key      string   `xml:"Key"`
+	UploadID string   `xml:"UploadId"`
+}
+
+func (api *s3API) createMultipartUpload(bucket, key, contentType string) (interface{}, error) {
+	_, err := api.bucket(bucket)
+	if err != nil {
+		return nil, err
+	}
+	id := strings.Replace(uuid.New().String(), "-", "", -1)
+	api.uploads[id] = &s3Upload{
+		bucket:      bucket,
+		key:         key,
+		contentType: contentType,
+		parts:       map[int][]byte{},
+	}
+	return &initiateMultipartUploadResult{Xmlns: s3Namespace, Bucket: bucket, Key: key, UploadID: id}, nil
+}
+
+func (api *s3API) upload(id string) (*s3Upload, error) {
+	u, ok := api.uploads[id]
+	if !ok {
+		return nil, s3Error(http.StatusNotFound, "NoSuchUpload", "The specified upload does not exist. The upload ID may be invalid, or the upload may have been aborted or completed.")
+	}
+	return u, nil
+}
+
+func (api *s3API) uploadPart(w http.ResponseWriter, bucket, key string, query url.Values, body []byte) error {
+	u, err := api.upload(query.Get("uploadId"))
+	if err != nil {
+		return err
+	}
+	n, err := strconv.Atoi(query.Get("partNumber"))
+	if err != nil || n < 1 || n > 10000 {
+		return s3Error(http.StatusBadRequest, "InvalidArgument", "Part number must be an integer between 1 and 10000, inclusive")
+	}
+	u.parts[n] = body
+	sum := md5.Sum(body)
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:])+`"`)
+	return nil
+}
+
+type completeMultipartUploadResult struct {
+	XMLName  xml.Name `xml:"CompleteMultipartUploadResult"`
+	Xmlns    string   `xml:"xmlns,attr"`
+	Location string   `xml:"Location"`
+	Bucket   string   `xml:"Bucket"`
+	Key      string   `xml:"Key"`
+	ETag     string   `xml:"ETag"`
+}
+
+//completeMultipartUpload assembles the parts in the order of their number, the parts listed in the request are not checked
+func (api *s3API) completeMultipartUpload(bucket, key, id string) (interface{}, error) {
+	u, err := api.upload(id)
+	if err != nil {
+		return nil, err
+	}
+	b, err := api.bucket(u.bucket)
+	if err != nil {
+		return nil, err
+	}
+	var numbers []int
+	for n := range u.parts {
+		numbers = append(numbers, n)
+	}
+	sort.Ints(numbers)
+	var content bytes.Buffer
+	for _, n := range numbers {
+		content.Write(u.parts[n])
+	}
+	o := newS3Object(content.Bytes(), u.contentType)
+	o.etag = fmt.Sprintf(`"%s-%d"`, strings.Trim(o.etag, `"`), len(numbers))
+	b.objects[u.key] = o
+	delete(api.uploads, id)
+	return &completeMultipartUploadResult{
+		Xmlns:    s3Namespace,
+		Location: "/" + bucket + "/" + key,
+		Bucket:   bucket,
+		Key:      key,
+		ETag:     o.etag,
+	}, nil
+}
+
+func (api *s3API) abortMultipartUpload(w http.ResponseWriter, id string) error {
+	_, err := api.upload(id)
+	if err != nil {
+		return err
+	}
+	delete(api.uploads, id)
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
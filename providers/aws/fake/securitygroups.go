@@ -0,0 +1,286 @@
+package fake
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+//allTraffic returns the permission granting all the traffic
+func allTraffic() *ec2.IpPermission {
+	return &ec2.IpPermission{
+		IpProtocol: aws.String("-1"),
+		IpRanges:   []*ec2.IpRange{{CidrIp: aws.String("0.0.0.0/0")}},
+	}
+}
+
+func (api *ec2API) securityGroup(id *string) (*ec2.SecurityGroup, error) {
+	if err := checkID("InvalidGroupId.Malformed", "sg", id); err != nil {
+		return nil, err
+	}
+	sg, ok := api.securityGroups[aws.StringValue(id)]
+	if !ok {
+		return nil, errorf("InvalidGroup.NotFound", "The security group '%s' does not exist", aws.StringValue(id))
+	}
+	return sg, nil
+}
+
+//CreateSecurityGroup creates a security group
+func (api *ec2API) CreateSecurityGroup(in *ec2.CreateSecurityGroupInput) (*ec2.CreateSecurityGroupOutput, error) {
+	var vpc *ec2.Vpc
+	var err error
+	if in.VpcId == nil {
+		vpc, err = api.defaultVpc()
+	} else {
+		vpc, err = api.vpc(in.VpcId)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if in.GroupName == nil || in.Description == nil {
+		return nil, errorf("MissingParameter", "The request must contain the parameters GroupName and GroupDescription")
+	}
+	if *in.GroupName == "default" {
+		return nil, errorf("InvalidParameterValue", "Cannot use reserved security group name: default")
+	}
+	for _, sg := range api.securityGroups {
+		if *sg.VpcId == *vpc.VpcId && *sg.GroupName == *in.GroupName {
+			return nil, errorf("InvalidGroup.Duplicate", "The security group '%s' already exists for VPC '%s'", *in.GroupName, *vpc.VpcId)
+		}
+	}
+	sg := &ec2.SecurityGroup{
+		Description:         in.Description,
+		GroupId:             aws.String(api.newID("sg")),
+		GroupName:           in.GroupName,
+		OwnerId:             aws.String(AccountID),
+		VpcId:               vpc.VpcId,
+		IpPermissionsEgress: []*ec2.IpPermission{allTraffic()},
+	}
+	api.securityGroups[*sg.GroupId] = sg
+	return &ec2.CreateSecurityGroupOutput{GroupId: sg.GroupId}, nil
+}
+
+//DeleteSecurityGroup deletes a security group
+func (api *ec2API) DeleteSecurityGroup(in *ec2.DeleteSecurityGroupInput) (*ec2.DeleteSecurityGroupOutput, error) {
+	sg, err := api.securityGroup(in.GroupId)
+	if err != nil {
+		return nil, err
+	}
+	if isDefaultGroup(sg) {
+		return nil, errorf("CannotDelete", "the specified group: \"%s\" name: \"default\" cannot be deleted by a user", *sg.GroupId)
+	}
+	for _, ni := range api.interfaces {
+		for _, g := range ni.Groups {
+			if *g.GroupId == *sg.GroupId {
+				return nil, errorf("DependencyViolation", "resource %s has a dependent object", *sg.GroupId)
+			}
+		}
+	}
+	delete(api.securityGroups, *sg.GroupId)
+	return &ec2.DeleteSecurityGroupOutput{}, nil
+}
+
+//DescribeSecurityGroups describes security groups
+func (api *ec2API) DescribeSecurityGroups(in *ec2.DescribeSecurityGroupsInput) (*ec2.DescribeSecurityGroupsOutput, error) {
+	for _, id := range in.GroupIds {
+		if _, err := api.securityGroup(id); err != nil {
+			return nil, err
+		}
+	}
+	out := &ec2.DescribeSecurityGroupsOutput{}
+	for _, id := range sortedKeys(api.securityGroups) {
+		sg := api.securityGroups[id]
+		if !contains(in.GroupIds, id) || !contains(in.GroupNames, *sg.GroupName) {
+			continue
+		}
+		ok, err := match(in.Filters, func(name string) ([]string, bool) {
+			switch name {
+			case "group-id":
+				return values(sg.GroupId), true
+			case "group-name":
+				return values(sg.GroupName), true
+			case "vpc-id":
+				return values(sg.VpcId), true
+			case "description":
+				return values(sg.Description), true
+			}
+			return tagValues(sg.Tags, name)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out.SecurityGroups = append(out.SecurityGroups, sg)
+		}
+	}
+	return out, nil
+}
+
+//samePorts returns true if p1 and p2 apply to the same protocol and ports
+func samePorts(p1, p2 *ec2.IpPermission) bool {
+	return aws.StringValue(p1.IpProtocol) == aws.StringValue(p2.IpProtocol) &&
+		aws.Int64Value(p1.FromPort) == aws.Int64Value(p2.FromPort) &&
+		aws.Int64Value(p1.ToPort) == aws.Int64Value(p2.ToPort)
+}
+
+func checkPermission(p *ec2.IpPermission) error {
+	switch aws.StringValue(p.IpProtocol) {
+	case "tcp", "udp", "6", "17":
+		from, to := aws.Int64Value(p.FromPort), aws.Int64Value(p.ToPort)
+		if from < 0 || to > 65535 || from > to {
+			return errorf("InvalidParameterValue", "Invalid port range %d-%d", from, to)
+		}
+	case "icmp", "1", "-1", "icmpv6", "58":
+	default:
+		return errorf("InvalidParameterValue", "Invalid value '%s' for IP protocol.", aws.StringValue(p.IpProtocol))
+	}
+	return nil
+}
+
+//authorize adds the permissions perms to the permission list
+func authorize(list []*ec2.IpPermission, perms []*ec2.IpPermission) ([]*ec2.IpPermission, error) {
+	for _, p := range perms {
+		err := checkPermission(p)
+		if err != nil {
+			return nil, err
+		}
+		var current *ec2.IpPermission
+		for _, l := range list {
+			if samePorts(l, p) {
+				current = l
+				break
+			}
+		}
+		if current == nil {
+			current = &ec2.IpPermission{
+				FromPort:   p.FromPort,
+				IpProtocol: p.IpProtocol,
+				ToPort:     p.ToPort,
+			}
+			list = append(list, current)
+		}
+		for _, r := range p.IpRanges {
+			for _, cr := range current.IpRanges {
+				if aws.StringValue(cr.CidrIp) == aws.StringValue(r.CidrIp) {
+					return nil, errorf("InvalidPermission.Duplicate", "the specified rule \"peer: %s, %s\" already exists", aws.StringValue(r.CidrIp), aws.StringValue(p.IpProtocol))
+				}
+			}
+			current.IpRanges = append(current.IpRanges, r)
+		}
+		for _, r := range p.Ipv6Ranges {
+			for _, cr := range current.Ipv6Ranges {
+				if aws.StringValue(cr.CidrIpv6) == aws.StringValue(r.CidrIpv6) {
+					return nil, errorf("InvalidPermission.Duplicate", "the specified rule \"peer: %s, %s\" already exists", aws.StringValue(r.CidrIpv6), aws.StringValue(p.IpProtocol))
+				}
+			}
+			current.Ipv6Ranges = append(current.Ipv6Ranges, r)
+		}
+		current.UserIdGroupPairs = append(current.UserIdGroupPairs, p.UserIdGroupPairs...)
+	}
+	return list, nil
+}
+
+//revoke removes the permissions perms from the permission list
+func revoke(list []*ec2.IpPermission, perms []*ec2.IpPermission) ([]*ec2.IpPermission, error) {
+	notFound := errorf("InvalidPermission.NotFound", "The specified rule does not exist in this security group.")
+	for _, p := range perms {
+		var current *ec2.IpPermission
+		for _, l := range list {
+			if samePorts(l, p) {
+				current = l
+				break
+			}
+		}
+		if current == nil {
+			return nil, notFound
+		}
+		for _, r := range p.IpRanges {
+			found := false
+			for i, cr := range current.IpRanges {
+				if aws.StringValue(cr.CidrIp) == aws.StringValue(r.CidrIp) {
+					current.IpRanges = append(current.IpRanges[:i], current.IpRanges[i+1:]...)
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, notFound
+			}
+		}
+		for _, r := range p.Ipv6Ranges {
+			found := false
+			for i, cr := range current.Ipv6Ranges {
+				if aws.StringValue(cr.CidrIpv6) == aws.StringValue(r.CidrIpv6) {
+					current.Ipv6Ranges = append(current.Ipv6Ranges[:i], current.Ipv6Ranges[i+1:]...)
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, notFound
+			}
+		}
+	}
+	var res []*ec2.IpPermission
+	for _, l := range list {
+		if len(l.IpRanges) > 0 || len(l.Ipv6Ranges) > 0 || len(l.UserIdGroupPairs) > 0 {
+			res = append(res, l)
+		}
+	}
+	return res, nil
+}
+
+//AuthorizeSecurityGroupIngress adds ingress rules to a security group
+func (api *ec2API) AuthorizeSecurityGroupIngress(in *ec2.AuthorizeSecurityGroupIngressInput) (*ec2.AuthorizeSecurityGroupIngressOutput, error) {
+	sg, err := api.securityGroup(in.GroupId)
+	if err != nil {
+		return nil, err
+	}
+	perms, err := authorize(sg.IpPermissions, in.IpPermissions)
+	if err != nil {
+		return nil, err
+	}
+	sg.IpPermissions = perms
+	return &ec2.AuthorizeSecurityGroupIngressOutput{}, nil
+}
+
+//AuthorizeSecurityGroupEgress adds egress rules to a security group
+func (api *ec2API) AuthorizeSecurityGroupEgress(in *ec2.AuthorizeSecurityGroupEgressInput) (*ec2.AuthorizeSecurityGroupEgressOutput, error) {
+	sg, err := api.securityGroup(in.GroupId)
+	if err != nil {
+		return nil, err
+	}
+	perms, err := authorize(sg.IpPermissionsEgress, in.IpPermissions)
+	if err != nil {
+		return nil, err
+	}
+	sg.IpPermissionsEgress = perms
+	return &ec2.AuthorizeSecurityGroupEgressOutput{}, nil
+}
+
+//RevokeSecurityGroupIngress removes ingress rules from a security group
+func (api *ec2API) RevokeSecurityGroupIngress(in *ec2.RevokeSecurityGroupIngressInput) (*ec2.RevokeSecurityGroupIngressOutput, error) {
+	sg, err := api.securityGroup(in.GroupId)
+	if err != nil {
+		return nil, err
+	}
+	perms, err := revoke(sg.IpPermissions, in.IpPermissions)
+	if err != nil {
+		return nil, err
+	}
+	sg.IpPermissions = perms
+	return &ec2.RevokeSecurityGroupIngressOutput{}, nil
+}
+
+//RevokeSecurityGroupEgress removes egress rules from a security group
+func (api *ec2API) RevokeSecurityGroupEgress(in *ec2.RevokeSecurityGroupEgressInput) (*ec2.RevokeSecurityGroupEgressOutput, error) {
+	sg, err := api.securityGroup(in.GroupId)
+	if err != nil {
+		return nil, err
+	}
+	perms, err := revoke(sg.IpPermissionsEgress, in.IpPermissions)
+	if err != nil {
+		return nil, err
+	}
+	sg.IpPermissionsEgress = perms
+	return &ec2.RevokeSecurityGroupEgressOutput{}, nil
+}
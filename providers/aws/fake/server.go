@@ -0,0 +1,284 @@
+//Package fake implements an in process fake of the EC2, ELBv2, Auto Scaling, Route53, S3, Pricing and Service Quotas APIs used by the aws provider
+//It allows the aws provider to be tested without an AWS account
+package fake
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/private/protocol/xml/xmlutil"
+	"github.com/google/uuid"
+)
+
+const (
+	//Region region simulated by the fake
+	Region = "us-east-1"
+	//RegionName name of the region used by the Pricing API
+	RegionName = "US East (N. Virginia)"
+	//AvailabilityZone availability zone simulated by the fake
+	AvailabilityZone = "us-east-1a"
+	//AccountID identifier of the account owning the resources
+	AccountID = "123456789012"
+)
+
+const ec2Namespace = "http://ec2.amazonaws.com/doc/2016-11-15/"
+
+//Server fake EC2, ELBv2, Auto Scaling, Route53, S3, Pricing and Service Quotas API server
+//All the resources are created in their final state (running instances, available volumes, ...) so that the SDK waiters succeed at their first attempt
+type Server struct {
+	//URL base URL of the server, to be used as the aws provider Endpoint and PricingEndpoint
+	URL string
+
+	server      *httptest.Server
+	lock        sync.Mutex
+	ec2         *ec2API
+	elbv2       *elbv2API
+	autoScaling *autoScalingAPI
+	route53     *route53API
+	s3          *s3API
+	pricing     *pricingAPI
+}
+
+//NewServer starts a fake EC2, ELBv2, Auto Scaling, Route53, S3, Pricing and Service Quotas API server
+func NewServer() *Server {
+	ec2 := newEC2API()
+	s := &Server{
+		ec2:         ec2,
+		elbv2:       newELBv2API(ec2),
+		autoScaling: newAutoScalingAPI(ec2),
+		route53:     newRoute53API(),
+		s3:          newS3API(),
+		pricing:     newPricingAPI(),
+	}
+	s.server = httptest.NewServer(s)
+	s.URL = s.server.URL
+	return s
+}
+
+//Close shuts down the server
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+//Config returns a JSON configuration of the aws provider targeting the server
+func (s *Server) Config() string {
+	cfg, _ := json.Marshal(map[string]string{
+		"Region":           Region,
+		"RegionName":       RegionName,
+		"AvailabilityZone": AvailabilityZone,
+		"AccessKeyID":      "fake",
+		"SecretAccessKey":  "fake",
+		"Endpoint":         s.URL,
+		"PricingEndpoint":  s.URL,
+	})
+	return string(cfg)
+}
+
+//ServeHTTP dispatches Pricing and Service Quotas requests using the X-Amz-Target header, S3 requests using the scope of their signature, Route53 requests using their path, ELBv2 and Auto Scaling requests using the Version parameter and EC2 requests using the Action parameter
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if target := r.Header.Get("X-Amz-Target"); strings.HasPrefix(target, serviceQuotasPrefix) {
+		s.serveServiceQuotas(w, r, target)
+		return
+	}
+	if target := r.Header.Get("X-Amz-Target"); target != "" {
+		s.servePricing(w, r, target)
+		return
+	}
+	if isS3Request(r) {
+		s.serveS3(w, r)
+		return
+	}
+	if strings.HasPrefix(r.URL.Path, route53Prefix) {
+		s.serveRoute53(w, r)
+		return
+	}
+	err := r.ParseForm()
+	if err != nil {
+		writeEC2Error(w, errorf("MalformedQueryString", "%s", err.Error()))
+		return
+	}
+	switch r.Form.Get("Version") {
+	case elbv2Version:
+		s.serveQuery(w, s.elbv2, elbv2Namespace, r.Form)
+	case autoScalingVersion:
+		s.serveQuery(w, s.autoScaling, autoScalingNamespace, r.Form)
+	default:
+		s.serveEC2(w, r.Form)
+	}
+}
+
+//apiError error returned by the fake APIs
+type apiError struct {
+	status  int
+	code    string
+	message string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("%s: %s", e.code, e.message)
+}
+
+func errorf(code string, format string, args ...interface{}) error {
+	return &apiError{
+		status:  http.StatusBadRequest,
+		code:    code,
+		message: fmt.Sprintf(format, args...),
+	}
+}
+
+func toAPIError(err error) *apiError {
+	if e, ok := err.(*apiError); ok {
+		return e
+	}
+	return &apiError{
+		status:  http.StatusInternalServerError,
+		code:    "InternalError",
+		message: err.Error(),
+	}
+}
+
+//call invokes the method of api implementing the action of a query with the parameters of the query
+func call(api interface{}, d queryDecoder, form url.Values) (interface{}, error) {
+	action := form.Get("Action")
+	method := reflect.ValueOf(api).MethodByName(action)
+	if !method.IsValid() {
+		return nil, errorf("InvalidAction", "The action %s is not valid for this web service.", action)
+	}
+	input := reflect.New(method.Type().In(0).Elem())
+	err := d.decode(form, input.Elem(), "")
+	if err != nil {
+		return nil, errorf("InvalidParameterValue", "%s", err.Error())
+	}
+	res := method.Call([]reflect.Value{input})
+	if !res[1].IsNil() {
+		return nil, res[1].Interface().(error)
+	}
+	return res[0].Interface(), nil
+}
+
+func (s *Server) serveEC2(w http.ResponseWriter, form url.Values) {
+	output, err := call(s.ec2, ec2Decoder, form)
+	if err != nil {
+		writeEC2Error(w, err)
+		return
+	}
+	writeEC2Response(w, form.Get("Action"), output)
+}
+
+func writeEC2Response(w http.ResponseWriter, action string, output interface{}) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	fmt.Fprintf(&buf, `<%sResponse xmlns="%s"><requestId>%s</requestId>`, action, ec2Namespace, uuid.New().String())
+	err := xmlutil.BuildXML(output, xml.NewEncoder(&buf))
+	if err != nil {
+		writeEC2Error(w, err)
+		return
+	}
+	fmt.Fprintf(&buf, "</%sResponse>", action)
+	w.Header().Set("Content-Type", "text/xml;charset=UTF-8")
+	_, _ = w.Write(buf.Bytes())
+}
+
+type ec2ErrorResponse struct {
+	XMLName   xml.Name `xml:"Response"`
+	Code      string   `xml:"Errors>Error>Code"`
+	Message   string   `xml:"Errors>Error>Message"`
+	RequestID string   `xml:"RequestID"`
+}
+
+func writeEC2Error(w http.ResponseWriter, err error) {
+	e := toAPIError(err)
+	b, _ := xml.Marshal(&ec2ErrorResponse{
+		Code:      e.code,
+		Message:   e.message,
+		RequestID: uuid.New().String(),
+	})
+	w.Header().Set("Content-Type", "text/xml;charset=UTF-8")
+	w.WriteHeader(e.status)
+	_, _ = w.Write(append([]byte(xml.Header), b...))
+}
+
+//serveQuery serves the query protocol requests of the ELBv2 and Auto Scaling APIs
+func (s *Server) serveQuery(w http.ResponseWriter, api interface{}, namespace string, form url.Values) {
+	output, err := call(api, protocolDecoder, form)
+	if err != nil {
+		writeQueryError(w, err)
+		return
+	}
+	action := form.Get("Action")
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	fmt.Fprintf(&buf, `<%sResponse xmlns="%s"><%sResult>`, action, namespace, action)
+	err = xmlutil.BuildXML(output, xml.NewEncoder(&buf))
+	if err != nil {
+		writeQueryError(w, err)
+		return
+	}
+	fmt.Fprintf(&buf, "</%sResult><ResponseMetadata><RequestId>%s</RequestId></ResponseMetadata></%sResponse>", action, uuid.New().String(), action)
+	w.Header().Set("Content-Type", "text/xml;charset=UTF-8")
+	_, _ = w.Write(buf.Bytes())
+}
+
+//errorResponse error document of the ELBv2, Auto Scaling and Route53 APIs
+type errorResponse struct {
+	XMLName   xml.Name `xml:"ErrorResponse"`
+	Type      string   `xml:"Error>Type"`
+	Code      string   `xml:"Error>Code"`
+	Message   string   `xml:"Error>Message"`
+	RequestID string   `xml:"RequestId"`
+}
+
+func writeQueryError(w http.ResponseWriter, err error) {
+	e := toAPIError(err)
+	b, _ := xml.Marshal(&errorResponse{
+		Type:      "Sender",
+		Code:      e.code,
+		Message:   e.message,
+		RequestID: uuid.New().String(),
+	})
+	w.Header().Set("Content-Type", "text/xml;charset=UTF-8")
+	w.WriteHeader(e.status)
+	_, _ = w.Write(append([]byte(xml.Header), b...))
+}
+
+func (s *Server) servePricing(w http.ResponseWriter, r *http.Request, target string) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writePricingError(w, errorf("InvalidParameterException", "%s", err.Error()))
+		return
+	}
+	switch strings.TrimPrefix(target, "AWSPriceListService.") {
+	case "GetProducts":
+		out, err := s.pricing.getProducts(body)
+		if err != nil {
+			writePricingError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		_, _ = w.Write(out)
+	default:
+		writePricingError(w, errorf("UnknownOperationException", "unknown operation %s", target))
+	}
+}
+
+func writePricingError(w http.ResponseWriter, err error) {
+	e := toAPIError(err)
+	b, _ := json.Marshal(map[string]string{
+		"__type":  e.code,
+		"message": e.message,
+	})
+	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+	w.WriteHeader(e.status)
+	_, _ = w.Write(b)
+}
@@ -0,0 +1,103 @@
+package fake
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func (api *ec2API) snapshot(id *string) (*ec2.Snapshot, error) {
+	if err := checkID("InvalidSnapshotID.Malformed", "snap", id); err != nil {
+		return nil, err
+	}
+	s, ok := api.snapshots[aws.StringValue(id)]
+	if !ok {
+		return nil, errorf("InvalidSnapshot.NotFound", "The snapshot '%s' does not exist.", aws.StringValue(id))
+	}
+	return s, nil
+}
+
+//createSnapshot creates a snapshot of the volume v, the snapshot is completed immediately
+func (api *ec2API) createSnapshot(v *ec2.Volume, description string, tags []*ec2.Tag) *ec2.Snapshot {
+	s := &ec2.Snapshot{
+		Description: aws.String(description),
+		Encrypted:   aws.Bool(aws.BoolValue(v.Encrypted)),
+		OwnerId:     aws.String(AccountID),
+		Progress:    aws.String("100%"),
+		SnapshotId:  aws.String(api.newID("snap")),
+		StartTime:   aws.Time(time.Now().UTC().Truncate(time.Second)),
+		State:       aws.String("completed"),
+		Tags:        tags,
+		VolumeId:    v.VolumeId,
+		VolumeSize:  aws.Int64(*v.Size),
+	}
+	api.snapshots[*s.SnapshotId] = s
+	return s
+}
+
+//CreateSnapshot creates a snapshot of a volume
+func (api *ec2API) CreateSnapshot(in *ec2.CreateSnapshotInput) (*ec2.Snapshot, error) {
+	v, err := api.volume(in.VolumeId)
+	if err != nil {
+		return nil, err
+	}
+	return api.createSnapshot(v, aws.StringValue(in.Description), tagSpecifications(in.TagSpecifications, "snapshot")), nil
+}
+
+//DeleteSnapshot deletes a snapshot
+func (api *ec2API) DeleteSnapshot(in *ec2.DeleteSnapshotInput) (*ec2.DeleteSnapshotOutput, error) {
+	s, err := api.snapshot(in.SnapshotId)
+	if err != nil {
+		return nil, err
+	}
+	for _, img := range api.images {
+		for _, bdm := range img.BlockDeviceMappings {
+			if bdm.Ebs != nil && aws.StringValue(bdm.Ebs.SnapshotId) == *s.SnapshotId {
+				return nil, errorf("InvalidSnapshot.InUse", "The snapshot %s is currently in use by %s", *s.SnapshotId, *img.ImageId)
+			}
+		}
+	}
+	delete(api.snapshots, *s.SnapshotId)
+	return &ec2.DeleteSnapshotOutput{}, nil
+}
+
+//DescribeSnapshots describes snapshots
+func (api *ec2API) DescribeSnapshots(in *ec2.DescribeSnapshotsInput) (*ec2.DescribeSnapshotsOutput, error) {
+	for _, id := range in.SnapshotIds {
+		if _, err := api.snapshot(id); err != nil {
+			return nil, err
+		}
+	}
+	owners := owners(in.OwnerIds)
+	out := &ec2.DescribeSnapshotsOutput{}
+	for _, id := range sortedKeys(api.snapshots) {
+		s := api.snapshots[id]
+		if !contains(in.SnapshotIds, id) || !contains(owners, *s.OwnerId) {
+			continue
+		}
+		ok, err := match(in.Filters, func(name string) ([]string, bool) {
+			switch name {
+			case "snapshot-id":
+				return values(s.SnapshotId), true
+			case "volume-id":
+				return values(s.VolumeId), true
+			case "owner-id":
+				return values(s.OwnerId), true
+			case "status":
+				return values(s.State), true
+			case "volume-size":
+				return []string{strconv.FormatInt(*s.VolumeSize, 10)}, true
+			}
+			return tagValues(s.Tags, name)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out.Snapshots = append(out.Snapshots, s)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,309 @@
+package fake
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+//volumeSizes minimum and maximum sizes in GiB of each volume type
+var volumeSizes = map[string][2]int64{
+	"standard": {1, 1024},
+	"gp2":      {1, 16384},
+	"io1":      {4, 16384},
+	"st1":      {500, 16384},
+	"sc1":      {500, 16384},
+}
+
+//iops returns the IOPS of a volume, the IOPS of io1 volumes are provisioned
+func iops(volumeType string, size int64, provisioned *int64) int64 {
+	switch volumeType {
+	case "io1":
+		return aws.Int64Value(provisioned)
+	case "gp2":
+		iops := 3 * size
+		if iops < 100 {
+			iops = 100
+		}
+		if iops > 16000 {
+			iops = 16000
+		}
+		return iops
+	}
+	return 0
+}
+
+func checkVolume(volumeType string, size int64, provisioned *int64) error {
+	sizes, ok := volumeSizes[volumeType]
+	if !ok {
+		return errorf("InvalidParameterValue", "Value (%s) for parameter volumeType is invalid.", volumeType)
+	}
+	if size < sizes[0] || size > sizes[1] {
+		return errorf("InvalidParameterValue", "Volume of %dGiB is too small or too large; minimum is %dGiB, maximum is %dGiB", size, sizes[0], sizes[1])
+	}
+	if volumeType == "io1" {
+		iops := aws.Int64Value(provisioned)
+		if iops < 100 || iops > 64000 {
+			return errorf("InvalidParameterValue", "Volume iops of %d is too low or too high; minimum is 100, maximum is 64000", iops)
+		}
+		if iops > 50*size {
+			return errorf("InvalidParameterValue", "Iops to volume size ratio of %d is too high; maximum is 50", iops/size)
+		}
+	}
+	return nil
+}
+
+func (api *ec2API) volume(id *string) (*ec2.Volume, error) {
+	if err := checkID("InvalidVolumeID.Malformed", "vol", id); err != nil {
+		return nil, err
+	}
+	v, ok := api.volumes[aws.StringValue(id)]
+	if !ok {
+		return nil, errorf("InvalidVolume.NotFound", "The volume '%s' does not exist.", aws.StringValue(id))
+	}
+	return v, nil
+}
+
+func (api *ec2API) createVolume(az string, size int64, volumeType string, provisioned *int64, tags []*ec2.Tag) *ec2.Volume {
+	v := &ec2.Volume{
+		AvailabilityZone: aws.String(az),
+		CreateTime:       aws.Time(time.Now().UTC().Truncate(time.Second)),
+		Encrypted:        aws.Bool(false),
+		Iops:             aws.Int64(iops(volumeType, size, provisioned)),
+		Size:             aws.Int64(size),
+		SnapshotId:       aws.String(""),
+		State:            aws.String("available"),
+		Tags:             tags,
+		VolumeId:         aws.String(api.newID("vol")),
+		VolumeType:       aws.String(volumeType),
+	}
+	api.volumes[*v.VolumeId] = v
+	return v
+}
+
+func (api *ec2API) attachVolume(v *ec2.Volume, inst *ec2.Instance, device string, deleteOnTermination bool) *ec2.VolumeAttachment {
+	att := &ec2.VolumeAttachment{
+		AttachTime:          aws.Time(time.Now().UTC().Truncate(time.Second)),
+		DeleteOnTermination: aws.Bool(deleteOnTermination),
+		Device:              aws.String(device),
+		InstanceId:          inst.InstanceId,
+		State:               aws.String("attached"),
+		VolumeId:            v.VolumeId,
+	}
+	v.Attachments = []*ec2.VolumeAttachment{att}
+	v.State = aws.String("in-use")
+	return att
+}
+
+func (api *ec2API) detachVolume(v *ec2.Volume) *ec2.VolumeAttachment {
+	att := v.Attachments[0]
+	v.Attachments = nil
+	v.State = aws.String("available")
+	return &ec2.VolumeAttachment{
+		AttachTime: att.AttachTime,
+		Device:     att.Device,
+		InstanceId: att.InstanceId,
+		State:      aws.String("detached"),
+		VolumeId:   att.VolumeId,
+	}
+}
+
+//CreateVolume creates a volume
+func (api *ec2API) CreateVolume(in *ec2.CreateVolumeInput) (*ec2.Volume, error) {
+	if in.AvailabilityZone == nil {
+		return nil, errorf("MissingParameter", "The request must contain the parameter AvailabilityZone")
+	}
+	volumeType := aws.StringValue(in.VolumeType)
+	if volumeType == "" {
+		volumeType = "gp2"
+	}
+	var snapshot *ec2.Snapshot
+	if in.SnapshotId != nil {
+		s, err := api.snapshot(in.SnapshotId)
+		if err != nil {
+			return nil, err
+		}
+		snapshot = s
+	}
+	if in.Size == nil && snapshot == nil {
+		return nil, errorf("MissingParameter", "The request must contain the parameter size or snapshotId")
+	}
+	size := aws.Int64Value(in.Size)
+	if snapshot != nil {
+		if in.Size == nil {
+			size = *snapshot.VolumeSize
+		}
+		if size < *snapshot.VolumeSize {
+			return nil, errorf("InvalidParameterValue", "Volume of %dGiB is smaller than snapshot '%s', expect size >= %dGiB", size, *snapshot.SnapshotId, *snapshot.VolumeSize)
+		}
+	}
+	err := checkVolume(volumeType, size, in.Iops)
+	if err != nil {
+		return nil, err
+	}
+	v := api.createVolume(*in.AvailabilityZone, size, volumeType, in.Iops, tagSpecifications(in.TagSpecifications, "volume"))
+	v.Encrypted = aws.Bool(aws.BoolValue(in.Encrypted))
+	if snapshot != nil {
+		v.SnapshotId = snapshot.SnapshotId
+	}
+	return v, nil
+}
+
+//DeleteVolume deletes a volume
+func (api *ec2API) DeleteVolume(in *ec2.DeleteVolumeInput) (*ec2.DeleteVolumeOutput, error) {
+	v, err := api.volume(in.VolumeId)
+	if err != nil {
+		return nil, err
+	}
+	if len(v.Attachments) > 0 {
+		return nil, errorf("VolumeInUse", "Volume %s is currently attached to %s", *v.VolumeId, *v.Attachments[0].InstanceId)
+	}
+	delete(api.volumes, *v.VolumeId)
+	return &ec2.DeleteVolumeOutput{}, nil
+}
+
+//DescribeVolumes describes volumes
+func (api *ec2API) DescribeVolumes(in *ec2.DescribeVolumesInput) (*ec2.DescribeVolumesOutput, error) {
+	for _, id := range in.VolumeIds {
+		if _, err := api.volume(id); err != nil {
+			return nil, err
+		}
+	}
+	out := &ec2.DescribeVolumesOutput{}
+	for _, id := range sortedKeys(api.volumes) {
+		v := api.volumes[id]
+		if !contains(in.VolumeIds, id) {
+			continue
+		}
+		ok, err := match(in.Filters, func(name string) ([]string, bool) {
+			switch name {
+			case "volume-id":
+				return values(v.VolumeId), true
+			case "availability-zone":
+				return values(v.AvailabilityZone), true
+			case "status":
+				return values(v.State), true
+			case "volume-type":
+				return values(v.VolumeType), true
+			case "size":
+				return []string{strconv.FormatInt(*v.Size, 10)}, true
+			case "attachment.instance-id", "attachment.device", "attachment.status":
+				var res []string
+				for _, att := range v.Attachments {
+					switch name {
+					case "attachment.instance-id":
+						res = append(res, values(att.InstanceId)...)
+					case "attachment.device":
+						res = append(res, values(att.Device)...)
+					default:
+						res = append(res, values(att.State)...)
+					}
+				}
+				return res, true
+			}
+			return tagValues(v.Tags, name)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out.Volumes = append(out.Volumes, v)
+		}
+	}
+	return out, nil
+}
+
+//AttachVolume attaches a volume to an instance
+func (api *ec2API) AttachVolume(in *ec2.AttachVolumeInput) (*ec2.VolumeAttachment, error) {
+	v, err := api.volume(in.VolumeId)
+	if err != nil {
+		return nil, err
+	}
+	inst, err := api.instance(in.InstanceId)
+	if err != nil {
+		return nil, err
+	}
+	if *inst.State.Code != running && *inst.State.Code != stopped {
+		return nil, errorf("IncorrectState", "Instance '%s' is not 'running' or 'stopped'.", *inst.InstanceId)
+	}
+	if len(v.Attachments) > 0 {
+		return nil, errorf("VolumeInUse", "%s is already attached to an instance", *v.VolumeId)
+	}
+	if *v.AvailabilityZone != *inst.Placement.AvailabilityZone {
+		return nil, errorf("InvalidVolume.ZoneMismatch", "The volume '%s' is not in the same availability zone as instance '%s'", *v.VolumeId, *inst.InstanceId)
+	}
+	for _, other := range api.volumes {
+		for _, att := range other.Attachments {
+			if *att.InstanceId == *inst.InstanceId && *att.Device == aws.StringValue(in.Device) {
+				return nil, errorf("InvalidParameterValue", "Invalid value '%s' for unixDevice. Attachment point %s is already in use", *att.Device, *att.Device)
+			}
+		}
+	}
+	return api.attachVolume(v, inst, aws.StringValue(in.Device), false), nil
+}
+
+//DetachVolume detaches a volume from an instance
+func (api *ec2API) DetachVolume(in *ec2.DetachVolumeInput) (*ec2.VolumeAttachment, error) {
+	v, err := api.volume(in.VolumeId)
+	if err != nil {
+		return nil, err
+	}
+	if len(v.Attachments) == 0 {
+		return nil, errorf("IncorrectState", "Volume '%s' is in the 'available' state.", *v.VolumeId)
+	}
+	att := v.Attachments[0]
+	if in.InstanceId != nil && *in.InstanceId != *att.InstanceId {
+		return nil, errorf("InvalidAttachment.NotFound", "Volume '%s' is not attached to instance '%s'", *v.VolumeId, *in.InstanceId)
+	}
+	if att.Device != nil && in.Device != nil && *in.Device != *att.Device {
+		return nil, errorf("InvalidAttachment.NotFound", "Volume '%s' is not attached to device '%s'", *v.VolumeId, *in.Device)
+	}
+	return api.detachVolume(v), nil
+}
+
+//ModifyVolume modifies the size, the type or the IOPS of a volume, the modification is completed immediately
+func (api *ec2API) ModifyVolume(in *ec2.ModifyVolumeInput) (*ec2.ModifyVolumeOutput, error) {
+	v, err := api.volume(in.VolumeId)
+	if err != nil {
+		return nil, err
+	}
+	volumeType := aws.StringValue(v.VolumeType)
+	if in.VolumeType != nil {
+		volumeType = *in.VolumeType
+	}
+	size := *v.Size
+	if in.Size != nil {
+		size = *in.Size
+	}
+	if size < *v.Size {
+		return nil, errorf("InvalidParameterValue", "New size cannot be smaller than existing size")
+	}
+	provisioned := in.Iops
+	if provisioned == nil {
+		provisioned = v.Iops
+	}
+	err = checkVolume(volumeType, size, provisioned)
+	if err != nil {
+		return nil, err
+	}
+	now := aws.Time(time.Now().UTC().Truncate(time.Second))
+	modification := &ec2.VolumeModification{
+		EndTime:            now,
+		ModificationState:  aws.String("completed"),
+		OriginalIops:       v.Iops,
+		OriginalSize:       v.Size,
+		OriginalVolumeType: v.VolumeType,
+		Progress:           aws.Int64(100),
+		StartTime:          now,
+		VolumeId:           v.VolumeId,
+	}
+	v.VolumeType = aws.String(volumeType)
+	v.Size = aws.Int64(size)
+	v.Iops = aws.Int64(iops(volumeType, size, provisioned))
+	modification.TargetIops = v.Iops
+	modification.TargetSize = v.Size
+	modification.TargetVolumeType = v.VolumeType
+	return &ec2.ModifyVolumeOutput{VolumeModification: modification}, nil
+}
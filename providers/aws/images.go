@@ -1,6 +1,7 @@
 package aws
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -23,8 +24,8 @@ func values(values ...string) []*string {
 	return result
 }
 
-func (mgr *ImageManager) search(owner string, name string) ([]api.Image, error) {
-	out, err := mgr.Provider.AWSServices.EC2Client.DescribeImages(&ec2.DescribeImagesInput{
+func (mgr *ImageManager) search(ctx context.Context, owner string, name string) ([]api.Image, error) {
+	out, err := mgr.Provider.AWSServices.EC2Client.DescribeImagesWithContext(ctx, &ec2.DescribeImagesInput{
 		DryRun: aws.Bool(false),
 		Owners: values(owner),
 		Filters: []*ec2.Filter{
@@ -60,20 +61,38 @@ func (mgr *ImageManager) search(owner string, name string) ([]api.Image, error)
 	return result, nil
 }
 
-func (mgr *ImageManager) list() ([]api.Image, error) {
-	ubuntuImages, err := mgr.search("099720109477", "ubuntu/images/hvm-ssd/ubuntu-*-*-*-*-????????")
+//owners names of the owners of the public images indexed by AWS account id
+var owners = map[string]string{
+	"099720109477": "canonical",
+	"309956199498": "redhat",
+	"379101102735": "debian",
+	"410186602215": "centos",
+}
+
+//defaultUsers users of the AMIs whose user differs from the default user of their distribution
+var defaultUsers = map[string]string{
+	"debian": "admin",
+	"rhel":   "ec2-user",
+}
+
+func (mgr *ImageManager) list(ctx context.Context) ([]api.Image, error) {
+	ubuntuImages, err := mgr.search(ctx, "099720109477", "ubuntu/images/hvm-ssd/ubuntu-*-*-*-*-????????")
+	if err != nil {
+		return nil, err
+	}
+	RHELSImages, err := mgr.search(ctx, "309956199498", "RHEL-?.?_HVM_GA*")
 	if err != nil {
 		return nil, err
 	}
-	RHELSImages, err := mgr.search("309956199498", "RHEL-?.?_HVM_GA*")
+	debianImages, err := mgr.search(ctx, "379101102735", "debian-*")
 	if err != nil {
 		return nil, err
 	}
-	debianImages, err := mgr.search("379101102735", "debian-*")
+	centosImages, err := mgr.search(ctx, "410186602215", "CentOS*")
 	if err != nil {
 		return nil, err
 	}
-	centosImages, err := mgr.search("410186602215", "CentOS*")
+	ownImages, err := mgr.search(ctx, "self", "*")
 	if err != nil {
 		return nil, err
 	}
@@ -83,29 +102,56 @@ func (mgr *ImageManager) list() ([]api.Image, error) {
 	result = append(result, RHELSImages...)
 	result = append(result, debianImages...)
 	result = append(result, centosImages...)
+	result = append(result, ownImages...)
 	return result, nil
 }
 
+//ListWithContext returns available image list
+func (mgr *ImageManager) ListWithContext(ctx context.Context) ([]api.Image, api.ListImageError) {
+	l, err := mgr.list(ctx)
+	return l, api.NewListImageError(err)
+}
+
 //List returns available image list
 func (mgr *ImageManager) List() ([]api.Image, api.ListImageError) {
-	l, err := mgr.list()
-	return l, api.NewListImageError(err)
+	return mgr.ListWithContext(context.Background())
 }
 
 func image(img *ec2.Image) *api.Image {
 	creationDate, _ := time.Parse(time.RFC3339, *img.CreationDate)
+	family, distribution, version := api.ParseOS(*img.Name)
+	if aws.StringValue(img.Platform) == ec2.PlatformValuesWindows {
+		family, distribution = api.OSWindows, "windows"
+	}
+	owner, ok := owners[aws.StringValue(img.OwnerId)]
+	if !ok {
+		owner = aws.StringValue(img.ImageOwnerAlias)
+	}
+	if owner == "" {
+		owner = aws.StringValue(img.OwnerId)
+	}
+	user, ok := defaultUsers[distribution]
+	if !ok {
+		user = api.DefaultUser(distribution)
+	}
 	return &api.Image{
-		CreatedAt: creationDate,
-		ID:        *img.ImageId,
-		Name:      *img.Name,
-		MinDisk:   0,
-		MinRAM:    0,
-		UpdatedAt: creationDate,
+		CreatedAt:    creationDate,
+		ID:           *img.ImageId,
+		Name:         *img.Name,
+		MinDisk:      0,
+		MinRAM:       0,
+		UpdatedAt:    creationDate,
+		OSFamily:     family,
+		Distribution: distribution,
+		Version:      version,
+		Arch:         api.ParseArch(aws.StringValue(img.Architecture)),
+		Owner:        owner,
+		DefaultUser:  user,
 	}
 }
 
-func (mgr *ImageManager) get(id string) (*api.Image, error) {
-	out, err := mgr.Provider.AWSServices.EC2Client.DescribeImages(&ec2.DescribeImagesInput{
+func (mgr *ImageManager) get(ctx context.Context, id string) (*api.Image, error) {
+	out, err := mgr.Provider.AWSServices.EC2Client.DescribeImagesWithContext(ctx, &ec2.DescribeImagesInput{
 		DryRun: aws.Bool(false),
 		ImageIds: []*string{
 			aws.String(id),
@@ -115,7 +161,7 @@ func (mgr *ImageManager) get(id string) (*api.Image, error) {
 		return nil, err
 	}
 	if len(out.Images) == 0 {
-		return nil, nil
+		return nil, notFoundError("image %s not found", id)
 	}
 	if len(out.Images) > 1 {
 		return nil, fmt.Errorf("at least to images have the same id")
@@ -123,8 +169,101 @@ func (mgr *ImageManager) get(id string) (*api.Image, error) {
 	return image(out.Images[0]), nil
 }
 
+//GetWithContext returns the image identified by id
+func (mgr *ImageManager) GetWithContext(ctx context.Context, id string) (*api.Image, api.GetImageError) {
+	i, err := mgr.get(ctx, id)
+	return i, api.NewGetImageError(err, id)
+}
+
 //Get returns the image identified by id
 func (mgr *ImageManager) Get(id string) (*api.Image, api.GetImageError) {
-	i, err := mgr.get(id)
-	return i, api.NewGetImageError(err, id)
+	return mgr.GetWithContext(context.Background(), id)
+}
+
+func (mgr *ImageManager) createFromServer(ctx context.Context, serverID string, name string) (*api.Image, error) {
+	out, err := mgr.Provider.AWSServices.EC2Client.CreateImageWithContext(ctx, &ec2.CreateImageInput{
+		DryRun:     aws.Bool(false),
+		InstanceId: aws.String(serverID),
+		Name:       aws.String(name),
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = mgr.Provider.AWSServices.EC2Client.WaitUntilImageAvailableWithContext(ctx, &ec2.DescribeImagesInput{
+		ImageIds: []*string{out.ImageId},
+	})
+	if err != nil {
+		return nil, api.NewErrorStackFromError(err, mgr.delete(ctx, *out.ImageId))
+	}
+	return mgr.get(ctx, *out.ImageId)
+}
+
+//CreateFromServerWithContext creates an image from the server identified by serverID and waits until the image is available
+func (mgr *ImageManager) CreateFromServerWithContext(ctx context.Context, serverID string, name string) (*api.Image, api.CreateImageError) {
+	img, err := mgr.createFromServer(ctx, serverID, name)
+	return img, api.NewCreateImageError(err, serverID, name)
+}
+
+//CreateFromServer creates an image from the server identified by serverID and waits until the image is available
+func (mgr *ImageManager) CreateFromServer(serverID string, name string) (*api.Image, api.CreateImageError) {
+	return mgr.CreateFromServerWithContext(context.Background(), serverID, name)
+}
+
+//delete deregisters the image identified by id and deletes its snapshots
+func (mgr *ImageManager) delete(ctx context.Context, id string) error {
+	out, err := mgr.Provider.AWSServices.EC2Client.DescribeImagesWithContext(ctx, &ec2.DescribeImagesInput{
+		DryRun:   aws.Bool(false),
+		ImageIds: []*string{aws.String(id)},
+	})
+	if err != nil {
+		return err
+	}
+	if len(out.Images) == 0 {
+		return notFoundError("image %s not found", id)
+	}
+	_, err = mgr.Provider.AWSServices.EC2Client.DeregisterImageWithContext(ctx, &ec2.DeregisterImageInput{
+		DryRun:  aws.Bool(false),
+		ImageId: aws.String(id),
+	})
+	if err != nil {
+		return err
+	}
+	for _, bdm := range out.Images[0].BlockDeviceMappings {
+		if bdm.Ebs == nil || bdm.Ebs.SnapshotId == nil {
+			continue
+		}
+		_, err = mgr.Provider.AWSServices.EC2Client.DeleteSnapshotWithContext(ctx, &ec2.DeleteSnapshotInput{
+			DryRun:     aws.Bool(false),
+			SnapshotId: bdm.Ebs.SnapshotId,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//DeleteWithContext deregisters the image identified by id and deletes its snapshots
+func (mgr *ImageManager) DeleteWithContext(ctx context.Context, id string) api.DeleteImageError {
+	return api.NewDeleteImageError(mgr.delete(ctx, id), id)
+}
+
+//Delete deregisters the image identified by id and deletes its snapshots
+func (mgr *ImageManager) Delete(id string) api.DeleteImageError {
+	return mgr.DeleteWithContext(context.Background(), id)
+}
+
+//FindWithContext returns the image matching query among the images listed by List
+func (mgr *ImageManager) FindWithContext(ctx context.Context, query api.ImageQuery) (*api.Image, api.FindImageError) {
+	images, err := mgr.list(ctx)
+	if err != nil {
+		return nil, api.NewFindImageError(err, query)
+	}
+	img, err := api.FindImage(images, query)
+	return img, api.NewFindImageError(err, query)
+}
+
+//Find returns the image matching query among the images listed by List
+func (mgr *ImageManager) Find(query api.ImageQuery) (*api.Image, api.FindImageError) {
+	return mgr.FindWithContext(context.Background(), query)
 }
@@ -1,31 +1,96 @@
 package aws
 
 import (
+	"context"
+
+	"github.com/SebastienDorgan/anyclouds/api"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
-	"github.com/pkg/errors"
 )
 
-//KeyPairManager openstack implementation of api.KeyPairManager
+//KeyPairManager aws implementation of api.KeyPairManager
 type KeyPairManager struct {
 	Provider *Provider
 }
 
-//Import load a public key
-func (mgr *KeyPairManager) Import(name string, publicKey []byte) error {
-	_, err := mgr.Provider.AWSServices.EC2Client.ImportKeyPair(&ec2.ImportKeyPairInput{
+func keyPair(kp *ec2.KeyPairInfo) *api.KeyPair {
+	return &api.KeyPair{
+		Name:        aws.StringValue(kp.KeyName),
+		Fingerprint: aws.StringValue(kp.KeyFingerprint),
+	}
+}
+
+//ImportWithContext registers publicKey under name
+func (mgr *KeyPairManager) ImportWithContext(ctx context.Context, name string, publicKey []byte) (*api.KeyPair, api.ImportKeyPairError) {
+	out, err := mgr.Provider.AWSServices.EC2Client.ImportKeyPairWithContext(ctx, &ec2.ImportKeyPairInput{
 		DryRun:            aws.Bool(false),
 		KeyName:           aws.String(name),
 		PublicKeyMaterial: publicKey,
 	})
-	return errors.Wrap(err, "Error loading key pair")
+	if err != nil {
+		return nil, api.NewImportKeyPairError(err, name)
+	}
+	return &api.KeyPair{
+		Name:        aws.StringValue(out.KeyName),
+		Fingerprint: aws.StringValue(out.KeyFingerprint),
+	}, nil
+}
+
+//Import registers publicKey under name
+func (mgr *KeyPairManager) Import(name string, publicKey []byte) (*api.KeyPair, api.ImportKeyPairError) {
+	return mgr.ImportWithContext(context.Background(), name, publicKey)
 }
 
-//Delete a key pair
-func (mgr *KeyPairManager) Delete(name string) error {
-	_, err := mgr.Provider.AWSServices.EC2Client.DeleteKeyPair(&ec2.DeleteKeyPairInput{
+//DeleteWithContext deletes the key pair identified by name
+func (mgr *KeyPairManager) DeleteWithContext(ctx context.Context, name string) api.DeleteKeyPairError {
+	_, err := mgr.Provider.AWSServices.EC2Client.DeleteKeyPairWithContext(ctx, &ec2.DeleteKeyPairInput{
 		DryRun:  aws.Bool(false),
 		KeyName: aws.String(name),
 	})
-	return errors.Wrap(err, "Error deleting key pair")
+	return api.NewDeleteKeyPairError(err, name)
+}
+
+//Delete deletes the key pair identified by name
+func (mgr *KeyPairManager) Delete(name string) api.DeleteKeyPairError {
+	return mgr.DeleteWithContext(context.Background(), name)
+}
+
+//ListWithContext lists key pairs
+func (mgr *KeyPairManager) ListWithContext(ctx context.Context) ([]api.KeyPair, api.ListKeyPairsError) {
+	out, err := mgr.Provider.AWSServices.EC2Client.DescribeKeyPairsWithContext(ctx, &ec2.DescribeKeyPairsInput{
+		DryRun: aws.Bool(false),
+	})
+	if err != nil {
+		return nil, api.NewListKeyPairsError(err)
+	}
+	var res []api.KeyPair
+	for _, kp := range out.KeyPairs {
+		res = append(res, *keyPair(kp))
+	}
+	return res, nil
+}
+
+//List lists key pairs
+func (mgr *KeyPairManager) List() ([]api.KeyPair, api.ListKeyPairsError) {
+	return mgr.ListWithContext(context.Background())
+}
+
+//GetWithContext returns the key pair identified by name
+func (mgr *KeyPairManager) GetWithContext(ctx context.Context, name string) (*api.KeyPair, api.GetKeyPairError) {
+	out, err := mgr.Provider.AWSServices.EC2Client.DescribeKeyPairsWithContext(ctx, &ec2.DescribeKeyPairsInput{
+		DryRun:   aws.Bool(false),
+		KeyNames: []*string{aws.String(name)},
+	})
+	if err != nil {
+		return nil, api.NewGetKeyPairError(err, name)
+	}
+	if len(out.KeyPairs) == 0 {
+		return nil, api.NewGetKeyPairError(notFoundError("key pair %s not found", name), name)
+	}
+	return keyPair(out.KeyPairs[0]), nil
+}
+
+//Get returns the key pair identified by name
+func (mgr *KeyPairManager) Get(name string) (*api.KeyPair, api.GetKeyPairError) {
+	return mgr.GetWithContext(context.Background(), name)
 }
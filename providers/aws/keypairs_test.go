@@ -0,0 +1,21 @@
+package aws_test
+
+import (
+	"testing"
+
+	"github.com/SebastienDorgan/anyclouds/tests"
+	"github.com/stretchr/testify/suite"
+)
+
+type AWSKeyPairManagerTestSuite struct {
+	tests.KeyPairManagerTestSuite
+}
+
+//SetupSuite set up key pair manager
+func (suite *AWSKeyPairManagerTestSuite) SetupSuite() {
+	suite.Prov = GetProvider()
+}
+
+func TestAWSKeyPairManagerTestSuite(t *testing.T) {
+	suite.Run(t, new(AWSKeyPairManagerTestSuite))
+}
@@ -0,0 +1,415 @@
+package aws
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/SebastienDorgan/anyclouds/providers"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/google/uuid"
+)
+
+//LoadBalancerManager aws implementation of api.LoadBalancerManager based on Elastic Load Balancing v2
+//L4 load balancers are network load balancers, L7 load balancers are application load balancers and each listener forwards traffic to its own target group
+type LoadBalancerManager struct {
+	Provider *Provider
+}
+
+//healthCheckTrafficPort health check port of target groups checking the port receiving traffic
+const healthCheckTrafficPort = "traffic-port"
+
+//elbName returns a random name of load balancer or target group, names are limited to 32 characters
+func elbName() string {
+	return "anyclouds-" + strings.Replace(uuid.New().String(), "-", "", -1)[:16]
+}
+
+func createELBTags(tags map[string]string) []*elbv2.Tag {
+	var elbTags []*elbv2.Tag
+	for k, v := range tags {
+		elbTags = append(elbTags, &elbv2.Tag{
+			Key:   aws.String(k),
+			Value: aws.String(v),
+		})
+	}
+	return elbTags
+}
+
+//ec2Tags converts ELBv2 tags to EC2 tags so that they can be read with userTags and tagValue
+func ec2Tags(tags []*elbv2.Tag) []*ec2.Tag {
+	var res []*ec2.Tag
+	for _, t := range tags {
+		res = append(res, &ec2.Tag{Key: t.Key, Value: t.Value})
+	}
+	return res
+}
+
+func loadBalancerType(t api.LoadBalancerType) string {
+	if t == api.LoadBalancerL7 {
+		return elbv2.LoadBalancerTypeEnumApplication
+	}
+	return elbv2.LoadBalancerTypeEnumNetwork
+}
+
+func createTargetGroupInput(vpcID string, pool api.BackendPool) *elbv2.CreateTargetGroupInput {
+	hc := pool.HealthCheck
+	if hc == nil {
+		hc = api.DefaultHealthCheck(pool.Protocol)
+	}
+	in := &elbv2.CreateTargetGroupInput{
+		HealthCheckPort:     aws.String(healthCheckTrafficPort),
+		HealthCheckProtocol: aws.String(string(hc.Protocol)),
+		Name:                aws.String(elbName()),
+		Port:                aws.Int64(int64(pool.Port)),
+		Protocol:            aws.String(string(pool.Protocol)),
+		TargetType:          aws.String(elbv2.TargetTypeEnumInstance),
+		VpcId:               aws.String(vpcID),
+	}
+	if hc.Port != 0 {
+		in.HealthCheckPort = aws.String(strconv.Itoa(hc.Port))
+	}
+	if hc.Protocol == api.LoadBalancerHTTP && hc.Path != "" {
+		in.HealthCheckPath = aws.String(hc.Path)
+	}
+	if hc.Interval > 0 {
+		in.HealthCheckIntervalSeconds = aws.Int64(int64(hc.Interval / time.Second))
+	}
+	if hc.UnhealthyThreshold > 0 {
+		//network load balancers require the healthy and unhealthy thresholds to be equal
+		in.HealthyThresholdCount = aws.Int64(int64(hc.UnhealthyThreshold))
+		in.UnhealthyThresholdCount = aws.Int64(int64(hc.UnhealthyThreshold))
+	}
+	return in
+}
+
+func healthCheck(tg *elbv2.TargetGroup) *api.HealthCheck {
+	hc := &api.HealthCheck{
+		Protocol:           api.LoadBalancerProtocol(aws.StringValue(tg.HealthCheckProtocol)),
+		Interval:           time.Duration(aws.Int64Value(tg.HealthCheckIntervalSeconds)) * time.Second,
+		UnhealthyThreshold: int(aws.Int64Value(tg.UnhealthyThresholdCount)),
+	}
+	if port, err := strconv.Atoi(aws.StringValue(tg.HealthCheckPort)); err == nil && int64(port) != aws.Int64Value(tg.Port) {
+		hc.Port = port
+	}
+	if hc.Protocol == api.LoadBalancerHTTP {
+		hc.Path = aws.StringValue(tg.HealthCheckPath)
+	}
+	return hc
+}
+
+func (mgr *LoadBalancerManager) vpcID(ctx context.Context, subnetIDs []string) (string, error) {
+	out, err := mgr.Provider.AWSServices.EC2Client.DescribeSubnetsWithContext(ctx, &ec2.DescribeSubnetsInput{
+		DryRun:    aws.Bool(false),
+		SubnetIds: aws.StringSlice(subnetIDs),
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(out.Subnets) == 0 {
+		return "", notFoundError("subnet %s not found", subnetIDs[0])
+	}
+	return aws.StringValue(out.Subnets[0].VpcId), nil
+}
+
+//createListener creates the target group of the listener, registers the servers of the pool and creates the listener
+//It returns the ARN of the target group if it has been created
+func (mgr *LoadBalancerManager) createListener(ctx context.Context, arn string, vpcID string, options api.ListenerOptions) (string, error) {
+	client := mgr.Provider.AWSServices.ELBClient
+	out, err := client.CreateTargetGroupWithContext(ctx, createTargetGroupInput(vpcID, options.Pool))
+	if err != nil {
+		return "", err
+	}
+	tgArn := aws.StringValue(out.TargetGroups[0].TargetGroupArn)
+	if len(options.Pool.ServerIDs) > 0 {
+		var targets []*elbv2.TargetDescription
+		for _, id := range options.Pool.ServerIDs {
+			targets = append(targets, &elbv2.TargetDescription{Id: aws.String(id)})
+		}
+		_, err = client.RegisterTargetsWithContext(ctx, &elbv2.RegisterTargetsInput{
+			TargetGroupArn: aws.String(tgArn),
+			Targets:        targets,
+		})
+		if err != nil {
+			return tgArn, err
+		}
+	}
+	_, err = client.CreateListenerWithContext(ctx, &elbv2.CreateListenerInput{
+		DefaultActions: []*elbv2.Action{
+			{
+				TargetGroupArn: aws.String(tgArn),
+				Type:           aws.String(elbv2.ActionTypeEnumForward),
+			},
+		},
+		LoadBalancerArn: aws.String(arn),
+		Port:            aws.Int64(int64(options.Port)),
+		Protocol:        aws.String(string(options.Protocol)),
+	})
+	return tgArn, err
+}
+
+func (mgr *LoadBalancerManager) create(ctx context.Context, options api.CreateLoadBalancerOptions) (*api.LoadBalancer, error) {
+	err := api.CheckLoadBalancerOptions(&options)
+	if err != nil {
+		return nil, err
+	}
+	vpcID, err := mgr.vpcID(ctx, options.SubnetIDs)
+	if err != nil {
+		return nil, err
+	}
+	scheme := elbv2.LoadBalancerSchemeEnumInternal
+	if options.Public {
+		scheme = elbv2.LoadBalancerSchemeEnumInternetFacing
+	}
+	client := mgr.Provider.AWSServices.ELBClient
+	out, err := client.CreateLoadBalancerWithContext(ctx, &elbv2.CreateLoadBalancerInput{
+		Name:    aws.String(elbName()),
+		Scheme:  aws.String(scheme),
+		Subnets: aws.StringSlice(options.SubnetIDs),
+		Tags:    createELBTags(resourceTags(options.Name, options.Tags)),
+		Type:    aws.String(loadBalancerType(options.Type)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	arn := aws.StringValue(out.LoadBalancers[0].LoadBalancerArn)
+	var targetGroups []string
+	err = client.WaitUntilLoadBalancerAvailableWithContext(ctx, &elbv2.DescribeLoadBalancersInput{
+		LoadBalancerArns: []*string{aws.String(arn)},
+	})
+	for _, l := range options.Listeners {
+		if err != nil {
+			break
+		}
+		var tgArn string
+		tgArn, err = mgr.createListener(ctx, arn, vpcID, l)
+		if tgArn != "" {
+			targetGroups = append(targetGroups, tgArn)
+		}
+	}
+	if err != nil {
+		err2 := providers.Cleanup(func(ctx context.Context) error {
+			err := mgr.deleteLoadBalancer(ctx, arn)
+			if err != nil {
+				return err
+			}
+			return mgr.deleteTargetGroups(ctx, targetGroups)
+		})
+		return nil, api.NewErrorStackFromError(err, err2)
+	}
+	return mgr.get(ctx, arn)
+}
+
+//CreateWithContext creates a load balancer and waits until it is active
+func (mgr *LoadBalancerManager) CreateWithContext(ctx context.Context, options api.CreateLoadBalancerOptions) (*api.LoadBalancer, api.CreateLoadBalancerError) {
+	lb, err := mgr.create(ctx, options)
+	if err != nil {
+		return nil, api.NewCreateLoadBalancerError(err, options)
+	}
+	return lb, nil
+}
+
+//Create creates a load balancer and waits until it is active
+func (mgr *LoadBalancerManager) Create(options api.CreateLoadBalancerOptions) (*api.LoadBalancer, api.CreateLoadBalancerError) {
+	return mgr.CreateWithContext(context.Background(), options)
+}
+
+//deleteLoadBalancer deletes the load balancer identified by arn and waits until it is deleted
+func (mgr *LoadBalancerManager) deleteLoadBalancer(ctx context.Context, arn string) error {
+	client := mgr.Provider.AWSServices.ELBClient
+	_, err := client.DeleteLoadBalancerWithContext(ctx, &elbv2.DeleteLoadBalancerInput{
+		LoadBalancerArn: aws.String(arn),
+	})
+	if err != nil {
+		return err
+	}
+	return client.WaitUntilLoadBalancersDeletedWithContext(ctx, &elbv2.DescribeLoadBalancersInput{
+		LoadBalancerArns: []*string{aws.String(arn)},
+	})
+}
+
+func (mgr *LoadBalancerManager) deleteTargetGroups(ctx context.Context, arns []string) error {
+	for _, arn := range arns {
+		_, err := mgr.Provider.AWSServices.ELBClient.DeleteTargetGroupWithContext(ctx, &elbv2.DeleteTargetGroupInput{
+			TargetGroupArn: aws.String(arn),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (mgr *LoadBalancerManager) delete(ctx context.Context, id string) error {
+	out, err := mgr.Provider.AWSServices.ELBClient.DescribeTargetGroupsWithContext(ctx, &elbv2.DescribeTargetGroupsInput{
+		LoadBalancerArn: aws.String(id),
+	})
+	if err != nil {
+		return err
+	}
+	err = mgr.deleteLoadBalancer(ctx, id)
+	if err != nil {
+		return err
+	}
+	var arns []string
+	for _, tg := range out.TargetGroups {
+		arns = append(arns, aws.StringValue(tg.TargetGroupArn))
+	}
+	return mgr.deleteTargetGroups(ctx, arns)
+}
+
+//DeleteWithContext deletes the load balancer identified by id with its listeners and target groups
+func (mgr *LoadBalancerManager) DeleteWithContext(ctx context.Context, id string) api.DeleteLoadBalancerError {
+	return api.NewDeleteLoadBalancerError(mgr.delete(ctx, id), id)
+}
+
+//Delete deletes the load balancer identified by id with its listeners and target groups
+func (mgr *LoadBalancerManager) Delete(id string) api.DeleteLoadBalancerError {
+	return mgr.DeleteWithContext(context.Background(), id)
+}
+
+//ListWithContext lists load balancers
+func (mgr *LoadBalancerManager) ListWithContext(ctx context.Context) ([]api.LoadBalancer, api.ListLoadBalancersError) {
+	var arns []string
+	err := mgr.Provider.AWSServices.ELBClient.DescribeLoadBalancersPagesWithContext(ctx, &elbv2.DescribeLoadBalancersInput{},
+		func(out *elbv2.DescribeLoadBalancersOutput, last bool) bool {
+			for _, lb := range out.LoadBalancers {
+				arns = append(arns, aws.StringValue(lb.LoadBalancerArn))
+			}
+			return true
+		})
+	if err != nil {
+		return nil, api.NewListLoadBalancersError(err)
+	}
+	lbs := []api.LoadBalancer{}
+	for _, arn := range arns {
+		lb, err := mgr.get(ctx, arn)
+		if err != nil {
+			return nil, api.NewListLoadBalancersError(err)
+		}
+		lbs = append(lbs, *lb)
+	}
+	return lbs, nil
+}
+
+//List lists load balancers
+func (mgr *LoadBalancerManager) List() ([]api.LoadBalancer, api.ListLoadBalancersError) {
+	return mgr.ListWithContext(context.Background())
+}
+
+//listener returns the listener l with the target group its traffic is forwarded to
+func (mgr *LoadBalancerManager) listener(ctx context.Context, l *elbv2.Listener) (*api.Listener, error) {
+	client := mgr.Provider.AWSServices.ELBClient
+	res := &api.Listener{
+		ID:       aws.StringValue(l.ListenerArn),
+		Protocol: api.LoadBalancerProtocol(aws.StringValue(l.Protocol)),
+		Port:     int(aws.Int64Value(l.Port)),
+	}
+	var tgArn *string
+	for _, a := range l.DefaultActions {
+		if aws.StringValue(a.Type) == elbv2.ActionTypeEnumForward {
+			tgArn = a.TargetGroupArn
+		}
+	}
+	if tgArn == nil {
+		return res, nil
+	}
+	out, err := client.DescribeTargetGroupsWithContext(ctx, &elbv2.DescribeTargetGroupsInput{
+		TargetGroupArns: []*string{tgArn},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(out.TargetGroups) == 0 {
+		return nil, notFoundError("target group %s not found", *tgArn)
+	}
+	tg := out.TargetGroups[0]
+	health, err := client.DescribeTargetHealthWithContext(ctx, &elbv2.DescribeTargetHealthInput{
+		TargetGroupArn: tgArn,
+	})
+	if err != nil {
+		return nil, err
+	}
+	res.Pool = api.BackendPool{
+		Protocol:    api.LoadBalancerProtocol(aws.StringValue(tg.Protocol)),
+		Port:        int(aws.Int64Value(tg.Port)),
+		HealthCheck: healthCheck(tg),
+	}
+	for _, h := range health.TargetHealthDescriptions {
+		res.Pool.ServerIDs = append(res.Pool.ServerIDs, aws.StringValue(h.Target.Id))
+	}
+	return res, nil
+}
+
+func (mgr *LoadBalancerManager) get(ctx context.Context, id string) (*api.LoadBalancer, error) {
+	client := mgr.Provider.AWSServices.ELBClient
+	out, err := client.DescribeLoadBalancersWithContext(ctx, &elbv2.DescribeLoadBalancersInput{
+		LoadBalancerArns: []*string{aws.String(id)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(out.LoadBalancers) == 0 {
+		return nil, notFoundError("load balancer %s not found", id)
+	}
+	lb := out.LoadBalancers[0]
+	tags, err := client.DescribeTagsWithContext(ctx, &elbv2.DescribeTagsInput{
+		ResourceArns: []*string{aws.String(id)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var lbTags []*ec2.Tag
+	for _, d := range tags.TagDescriptions {
+		lbTags = append(lbTags, ec2Tags(d.Tags)...)
+	}
+	res := &api.LoadBalancer{
+		ID:      id,
+		Name:    tagValue(lbTags, "name"),
+		Type:    api.LoadBalancerL4,
+		Public:  aws.StringValue(lb.Scheme) == elbv2.LoadBalancerSchemeEnumInternetFacing,
+		Address: aws.StringValue(lb.DNSName),
+		Tags:    userTags(lbTags),
+	}
+	if aws.StringValue(lb.Type) == elbv2.LoadBalancerTypeEnumApplication {
+		res.Type = api.LoadBalancerL7
+	}
+	for _, az := range lb.AvailabilityZones {
+		res.SubnetIDs = append(res.SubnetIDs, aws.StringValue(az.SubnetId))
+	}
+	listeners, err := client.DescribeListenersWithContext(ctx, &elbv2.DescribeListenersInput{
+		LoadBalancerArn: aws.String(id),
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range listeners.Listeners {
+		listener, err := mgr.listener(ctx, l)
+		if err != nil {
+			return nil, err
+		}
+		res.Listeners = append(res.Listeners, *listener)
+	}
+	sort.Slice(res.Listeners, func(i, j int) bool {
+		return res.Listeners[i].Port < res.Listeners[j].Port
+	})
+	return res, nil
+}
+
+//GetWithContext returns the load balancer identified by id
+func (mgr *LoadBalancerManager) GetWithContext(ctx context.Context, id string) (*api.LoadBalancer, api.GetLoadBalancerError) {
+	lb, err := mgr.get(ctx, id)
+	if err != nil {
+		return nil, api.NewGetLoadBalancerError(err, id)
+	}
+	return lb, nil
+}
+
+//Get returns the load balancer identified by id
+func (mgr *LoadBalancerManager) Get(id string) (*api.LoadBalancer, api.GetLoadBalancerError) {
+	return mgr.GetWithContext(context.Background(), id)
+}
@@ -0,0 +1,32 @@
+package aws_test
+
+import (
+	"testing"
+
+	"github.com/SebastienDorgan/anyclouds/tests"
+	"github.com/stretchr/testify/suite"
+)
+
+type AWSLoadBalancerManagerTestSuite struct {
+	tests.LoadBalancerManagerTestSuite
+}
+
+//SetupSuite set up load balancer manager
+func (suite *AWSLoadBalancerManagerTestSuite) SetupSuite() {
+	prov := GetProvider()
+	suite.Prov = prov
+	//application load balancers must be attached to subnets of two availability zones
+	zones, err := prov.GetLocationManager().ListZones(prov.Configuration.Region)
+	suite.Require().NoError(err)
+	for _, z := range zones {
+		if z.Available && z.ID != prov.Configuration.AvailabilityZone {
+			suite.SecondZone = z.ID
+			break
+		}
+	}
+	suite.Require().NotEmpty(suite.SecondZone)
+}
+
+func TestAWSLoadBalancerManagerTestSuite(t *testing.T) {
+	suite.Run(t, new(AWSLoadBalancerManagerTestSuite))
+}
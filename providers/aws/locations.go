@@ -0,0 +1,167 @@
+package aws
+
+import (
+	"context"
+	"sort"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+//LocationManager aws implementation of api.LocationManager
+type LocationManager struct {
+	Provider *Provider
+}
+
+//regionName returns the description of the region identified by id, it is the location name used by the Pricing API
+func regionName(id string) string {
+	for _, p := range endpoints.DefaultPartitions() {
+		if r, ok := p.Regions()[id]; ok && r.Description() != "" {
+			return r.Description()
+		}
+	}
+	return id
+}
+
+//ec2Client returns an EC2 client of region
+func (mgr *LocationManager) ec2Client(region string) (*ec2.EC2, error) {
+	if region == mgr.Provider.Configuration.Region {
+		return mgr.Provider.AWSServices.EC2Client, nil
+	}
+	cfg := mgr.Provider.config
+	cfg.Region = region
+	sess, err := session.NewSession(getEC2Config(&cfg))
+	if err != nil {
+		return nil, err
+	}
+	client := ec2.New(sess)
+	client.Handlers.UnmarshalError.PushBackNamed(unwrapErrorHandler)
+	return client, nil
+}
+
+func (mgr *LocationManager) listRegions(ctx context.Context) ([]api.Region, error) {
+	out, err := mgr.Provider.AWSServices.EC2Client.DescribeRegionsWithContext(ctx, &ec2.DescribeRegionsInput{})
+	if err != nil {
+		return nil, err
+	}
+	regions := []api.Region{}
+	for _, r := range out.Regions {
+		id := aws.StringValue(r.RegionName)
+		regions = append(regions, api.Region{ID: id, Name: regionName(id)})
+	}
+	sort.Slice(regions, func(i, j int) bool {
+		return regions[i].ID < regions[j].ID
+	})
+	return regions, nil
+}
+
+//ListRegionsWithContext lists the regions enabled for the account
+func (mgr *LocationManager) ListRegionsWithContext(ctx context.Context) ([]api.Region, api.ListRegionsError) {
+	regions, err := mgr.listRegions(ctx)
+	if err != nil {
+		return nil, api.NewListRegionsError(err)
+	}
+	return regions, nil
+}
+
+//ListRegions lists the regions enabled for the account
+func (mgr *LocationManager) ListRegions() ([]api.Region, api.ListRegionsError) {
+	return mgr.ListRegionsWithContext(context.Background())
+}
+
+func (mgr *LocationManager) listZones(ctx context.Context, region string) ([]api.AvailabilityZone, error) {
+	regions, err := mgr.listRegions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	_, err = api.FindRegion(regions, region)
+	if err != nil {
+		return nil, err
+	}
+	client, err := mgr.ec2Client(region)
+	if err != nil {
+		return nil, err
+	}
+	out, err := client.DescribeAvailabilityZonesWithContext(ctx, &ec2.DescribeAvailabilityZonesInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("region-name"),
+				Values: []*string{aws.String(region)},
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	zones := []api.AvailabilityZone{}
+	for _, z := range out.AvailabilityZones {
+		zones = append(zones, api.AvailabilityZone{
+			ID:        aws.StringValue(z.ZoneName),
+			Region:    aws.StringValue(z.RegionName),
+			Available: aws.StringValue(z.State) == ec2.AvailabilityZoneStateAvailable,
+		})
+	}
+	sort.Slice(zones, func(i, j int) bool {
+		return zones[i].ID < zones[j].ID
+	})
+	return zones, nil
+}
+
+//ListZonesWithContext lists the availability zones of region
+func (mgr *LocationManager) ListZonesWithContext(ctx context.Context, region string) ([]api.AvailabilityZone, api.ListAvailabilityZonesError) {
+	zones, err := mgr.listZones(ctx, region)
+	if err != nil {
+		return nil, api.NewListAvailabilityZonesError(err, region)
+	}
+	return zones, nil
+}
+
+//ListZones lists the availability zones of region
+func (mgr *LocationManager) ListZones(region string) ([]api.AvailabilityZone, api.ListAvailabilityZonesError) {
+	return mgr.ListZonesWithContext(context.Background(), region)
+}
+
+//withRegion returns a provider initialized with the configuration of p bound to region
+//The availability zone of the provider is the first available zone of region if the configured one is not in region
+func (p *Provider) withRegion(region string) (*Provider, error) {
+	zones, err := p.LocationManager.listZones(context.Background(), region)
+	if err != nil {
+		return nil, err
+	}
+	configuration := Configuration{
+		Region:     region,
+		RegionName: regionName(region),
+	}
+	if region == p.Configuration.Region {
+		configuration = p.Configuration
+	}
+	for _, z := range zones {
+		if z.ID == p.Configuration.AvailabilityZone {
+			configuration.AvailabilityZone = z.ID
+			break
+		}
+		if z.Available && configuration.AvailabilityZone == "" {
+			configuration.AvailabilityZone = z.ID
+		}
+	}
+	cfg := p.config
+	cfg.Region = region
+	res := &Provider{}
+	err = res.init(&cfg, configuration)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+//WithRegion returns a new aws provider with the configuration of p bound to region
+func (p *Provider) WithRegion(region string) (api.Provider, error) {
+	res, err := p.withRegion(region)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
@@ -0,0 +1,21 @@
+package aws_test
+
+import (
+	"testing"
+
+	"github.com/SebastienDorgan/anyclouds/tests"
+	"github.com/stretchr/testify/suite"
+)
+
+type AWSLocationManagerTestSuite struct {
+	tests.LocationManagerTestSuite
+}
+
+//SetupSuite set up location manager
+func (suite *AWSLocationManagerTestSuite) SetupSuite() {
+	suite.Prov = GetProvider()
+}
+
+func TestAWSLocationManagerTestSuite(t *testing.T) {
+	suite.Run(t, new(AWSLocationManagerTestSuite))
+}
@@ -1,8 +1,9 @@
 package aws
 
 import (
-	"fmt"
+	"context"
 	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/SebastienDorgan/anyclouds/providers"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
 )
@@ -24,21 +25,26 @@ func (mgr *NetworkInterfaceManager) convert(ni *ec2.NetworkInterface) *api.Netwo
 	if ni.Association != nil {
 		publicIP = *ni.Association.PublicIp
 	}
+	sgID := ""
+	if len(ni.Groups) > 0 {
+		sgID = *ni.Groups[0].GroupId
+	}
 	return &api.NetworkInterface{
 		ID:               *ni.NetworkInterfaceId,
-		Name:             *ni.Description,
+		Name:             aws.StringValue(ni.Description),
 		MacAddress:       *ni.MacAddress,
 		NetworkID:        *ni.VpcId,
 		SubnetID:         *ni.SubnetId,
 		ServerID:         srvID,
 		PrivateIPAddress: ipAddr,
 		PublicIPAddress:  publicIP,
-		SecurityGroupID:  *ni.Groups[0].GroupId,
+		SecurityGroupID:  sgID,
+		Tags:             userTags(ni.TagSet),
 	}
 }
 
-func (mgr *NetworkInterfaceManager) create(options api.CreateNetworkInterfaceOptions) (*api.NetworkInterface, error) {
-	out, err := mgr.Provider.AWSServices.EC2Client.CreateNetworkInterface(&ec2.CreateNetworkInterfaceInput{
+func (mgr *NetworkInterfaceManager) create(ctx context.Context, options api.CreateNetworkInterfaceOptions) (*api.NetworkInterface, error) {
+	out, err := mgr.Provider.AWSServices.EC2Client.CreateNetworkInterfaceWithContext(ctx, &ec2.CreateNetworkInterfaceInput{
 		Description:      &options.Name,
 		Groups:           []*string{&options.SecurityGroupID},
 		PrivateIpAddress: options.PrivateIPAddress,
@@ -47,99 +53,136 @@ func (mgr *NetworkInterfaceManager) create(options api.CreateNetworkInterfaceOpt
 	if err != nil {
 		return nil, err
 	}
+	if len(options.Tags) > 0 {
+		err = mgr.Provider.AddTags(ctx, *out.NetworkInterface.NetworkInterfaceId, resourceTags(options.Name, options.Tags))
+		if err != nil {
+			err2 := providers.Cleanup(func(ctx context.Context) error {
+				return mgr.DeleteWithContext(ctx, *out.NetworkInterface.NetworkInterfaceId)
+			})
+			return nil, api.NewErrorStackFromError(err, err2)
+		}
+	}
 	if options.ServerID == nil {
-		err = mgr.Provider.AWSServices.EC2Client.WaitUntilNetworkInterfaceAvailable(&ec2.DescribeNetworkInterfacesInput{
+		err = mgr.Provider.AWSServices.EC2Client.WaitUntilNetworkInterfaceAvailableWithContext(ctx, &ec2.DescribeNetworkInterfacesInput{
 			NetworkInterfaceIds: []*string{out.NetworkInterface.NetworkInterfaceId},
 		})
 		if err != nil {
 			if out.NetworkInterface != nil {
-				err2 := mgr.Delete(*out.NetworkInterface.NetworkInterfaceId)
+				err2 := providers.Cleanup(func(ctx context.Context) error {
+					return mgr.DeleteWithContext(ctx, *out.NetworkInterface.NetworkInterfaceId)
+				})
 				err = api.NewErrorStackFromError(err, err2)
 			}
 			return nil, err
 		}
-		return mgr.Get(*out.NetworkInterface.NetworkInterfaceId)
+		return mgr.GetWithContext(ctx, *out.NetworkInterface.NetworkInterfaceId)
 	}
-	att, err := mgr.Provider.AWSServices.EC2Client.AttachNetworkInterface(&ec2.AttachNetworkInterfaceInput{
+	att, err := mgr.Provider.AWSServices.EC2Client.AttachNetworkInterfaceWithContext(ctx, &ec2.AttachNetworkInterfaceInput{
 		InstanceId:         options.ServerID,
 		NetworkInterfaceId: out.NetworkInterface.NetworkInterfaceId,
 	})
 	if err != nil {
-		_, err2 := mgr.Provider.AWSServices.EC2Client.DeleteNetworkInterface(&ec2.DeleteNetworkInterfaceInput{
-			NetworkInterfaceId: out.NetworkInterface.NetworkInterfaceId,
+		err2 := providers.Cleanup(func(ctx context.Context) error {
+			_, err := mgr.Provider.AWSServices.EC2Client.DeleteNetworkInterfaceWithContext(ctx, &ec2.DeleteNetworkInterfaceInput{
+				NetworkInterfaceId: out.NetworkInterface.NetworkInterfaceId,
+			})
+			return err
 		})
 		return nil, api.NewErrorStackFromError(err, err2)
 	}
-	err = mgr.Provider.AWSServices.EC2Client.WaitUntilNetworkInterfaceAvailable(&ec2.DescribeNetworkInterfacesInput{
+	err = mgr.Provider.AWSServices.EC2Client.WaitUntilNetworkInterfaceAvailableWithContext(ctx, &ec2.DescribeNetworkInterfacesInput{
 		NetworkInterfaceIds: []*string{out.NetworkInterface.NetworkInterfaceId},
 	})
 	if err != nil {
-		_, err2 := mgr.Provider.AWSServices.EC2Client.DetachNetworkInterface(&ec2.DetachNetworkInterfaceInput{
-			AttachmentId: att.AttachmentId,
-			Force:        aws.Bool(true),
-		})
-		err = api.NewErrorStackFromError(err, err2)
-		_, err2 = mgr.Provider.AWSServices.EC2Client.DeleteNetworkInterface(&ec2.DeleteNetworkInterfaceInput{
-			NetworkInterfaceId: out.NetworkInterface.NetworkInterfaceId,
+		err2 := providers.Cleanup(func(ctx context.Context) error {
+			_, err := mgr.Provider.AWSServices.EC2Client.DetachNetworkInterfaceWithContext(ctx, &ec2.DetachNetworkInterfaceInput{
+				AttachmentId: att.AttachmentId,
+				Force:        aws.Bool(true),
+			})
+			_, err2 := mgr.Provider.AWSServices.EC2Client.DeleteNetworkInterfaceWithContext(ctx, &ec2.DeleteNetworkInterfaceInput{
+				NetworkInterfaceId: out.NetworkInterface.NetworkInterfaceId,
+			})
+			if err != nil {
+				return api.NewErrorStackFromError(err, err2)
+			}
+			return err2
 		})
 		return nil, api.NewErrorStackFromError(err, err2)
 	}
-	return mgr.Get(*out.NetworkInterface.NetworkInterfaceId)
+	return mgr.GetWithContext(ctx, *out.NetworkInterface.NetworkInterfaceId)
 }
 
-func (mgr *NetworkInterfaceManager) Create(options api.CreateNetworkInterfaceOptions) (*api.NetworkInterface, api.CreateNetworkInterfaceError) {
-	ni, err := mgr.create(options)
+//CreateWithContext context aware version of Create
+func (mgr *NetworkInterfaceManager) CreateWithContext(ctx context.Context, options api.CreateNetworkInterfaceOptions) (*api.NetworkInterface, api.CreateNetworkInterfaceError) {
+	ni, err := mgr.create(ctx, options)
 	return ni, api.NewCreateNetworkInterfaceError(err, options)
 }
 
-func (mgr *NetworkInterfaceManager) delete(id string) error {
-	out, err := mgr.Provider.AWSServices.EC2Client.DescribeNetworkInterfaces(&ec2.DescribeNetworkInterfacesInput{
+func (mgr *NetworkInterfaceManager) Create(options api.CreateNetworkInterfaceOptions) (*api.NetworkInterface, api.CreateNetworkInterfaceError) {
+	return mgr.CreateWithContext(context.Background(), options)
+}
+
+func (mgr *NetworkInterfaceManager) delete(ctx context.Context, id string) error {
+	out, err := mgr.Provider.AWSServices.EC2Client.DescribeNetworkInterfacesWithContext(ctx, &ec2.DescribeNetworkInterfacesInput{
 		NetworkInterfaceIds: []*string{&id},
 	})
 	if err != nil {
 		return err
 	}
-	if out.NetworkInterfaces == nil {
-		return fmt.Errorf("network interface %s not found", id)
+	if len(out.NetworkInterfaces) == 0 {
+		return notFoundError("network interface %s not found", id)
 	}
 	ni := out.NetworkInterfaces[0]
 	var err2 error
 	if ni.Attachment != nil && ni.Attachment.AttachmentId != nil {
-		_, err2 = mgr.Provider.AWSServices.EC2Client.DetachNetworkInterface(&ec2.DetachNetworkInterfaceInput{
+		_, err2 = mgr.Provider.AWSServices.EC2Client.DetachNetworkInterfaceWithContext(ctx, &ec2.DetachNetworkInterfaceInput{
 			AttachmentId: ni.Attachment.AttachmentId,
 			Force:        aws.Bool(true),
 		})
 	}
 
-	_, err = mgr.Provider.AWSServices.EC2Client.DeleteNetworkInterface(&ec2.DeleteNetworkInterfaceInput{
+	_, err = mgr.Provider.AWSServices.EC2Client.DeleteNetworkInterfaceWithContext(ctx, &ec2.DeleteNetworkInterfaceInput{
 		NetworkInterfaceId: ni.NetworkInterfaceId,
 	})
 	//if detach fails but delete succeeds then no error is raised
 	if err != nil {
-		err = api.NewErrorStackFromError(err, err2)
+		return api.NewErrorStackFromError(err, err2)
 	}
 	return nil
 }
 
+//DeleteWithContext context aware version of Delete
+func (mgr *NetworkInterfaceManager) DeleteWithContext(ctx context.Context, id string) api.DeleteNetworkInterfaceError {
+	return api.NewDeleteNetworkInterfaceError(mgr.delete(ctx, id), id)
+}
+
 func (mgr *NetworkInterfaceManager) Delete(id string) api.DeleteNetworkInterfaceError {
-	return api.NewDeleteNetworkInterfaceError(mgr.delete(id), id)
+	return mgr.DeleteWithContext(context.Background(), id)
 }
 
-func (mgr *NetworkInterfaceManager) get(id string) (*api.NetworkInterface, error) {
-	out, err := mgr.Provider.AWSServices.EC2Client.DescribeNetworkInterfaces(&ec2.DescribeNetworkInterfacesInput{
+func (mgr *NetworkInterfaceManager) get(ctx context.Context, id string) (*api.NetworkInterface, error) {
+	out, err := mgr.Provider.AWSServices.EC2Client.DescribeNetworkInterfacesWithContext(ctx, &ec2.DescribeNetworkInterfacesInput{
 		NetworkInterfaceIds: []*string{&id},
 	})
 	if err != nil {
 		return nil, err
 	}
+	if len(out.NetworkInterfaces) == 0 {
+		return nil, notFoundError("network interface %s not found", id)
+	}
 	return mgr.convert(out.NetworkInterfaces[0]), nil
 }
 
-func (mgr *NetworkInterfaceManager) Get(id string) (*api.NetworkInterface, api.GetNetworkInterfaceError) {
-	ni, err := mgr.get(id)
+//GetWithContext context aware version of Get
+func (mgr *NetworkInterfaceManager) GetWithContext(ctx context.Context, id string) (*api.NetworkInterface, api.GetNetworkInterfaceError) {
+	ni, err := mgr.get(ctx, id)
 	return ni, api.NewGetNetworkInterfaceError(err, id)
 }
 
+func (mgr *NetworkInterfaceManager) Get(id string) (*api.NetworkInterface, api.GetNetworkInterfaceError) {
+	return mgr.GetWithContext(context.Background(), id)
+}
+
 func createFilters(options *api.ListNetworkInterfacesOptions) []*ec2.Filter {
 	if options == nil {
 		return nil
@@ -179,8 +222,8 @@ func createFilters(options *api.ListNetworkInterfacesOptions) []*ec2.Filter {
 	return filters
 }
 
-func (mgr *NetworkInterfaceManager) list(options *api.ListNetworkInterfacesOptions) ([]api.NetworkInterface, error) {
-	out, err := mgr.Provider.AWSServices.EC2Client.DescribeNetworkInterfaces(&ec2.DescribeNetworkInterfacesInput{
+func (mgr *NetworkInterfaceManager) list(ctx context.Context, options *api.ListNetworkInterfacesOptions) ([]api.NetworkInterface, error) {
+	out, err := mgr.Provider.AWSServices.EC2Client.DescribeNetworkInterfacesWithContext(ctx, &ec2.DescribeNetworkInterfacesInput{
 		Filters: createFilters(options),
 	})
 	if err != nil {
@@ -193,25 +236,30 @@ func (mgr *NetworkInterfaceManager) list(options *api.ListNetworkInterfacesOptio
 	return list, nil
 }
 
-func (mgr *NetworkInterfaceManager) List(options *api.ListNetworkInterfacesOptions) ([]api.NetworkInterface, api.ListNetworkInterfacesError) {
-	nis, err := mgr.list(options)
+//ListWithContext context aware version of List
+func (mgr *NetworkInterfaceManager) ListWithContext(ctx context.Context, options *api.ListNetworkInterfacesOptions) ([]api.NetworkInterface, api.ListNetworkInterfacesError) {
+	nis, err := mgr.list(ctx, options)
 	return nis, api.NewListNetworkInterfacesError(err, options)
 }
 
-func (mgr *NetworkInterfaceManager) update(options api.UpdateNetworkInterfaceOptions) (*api.NetworkInterface, error) {
+func (mgr *NetworkInterfaceManager) List(options *api.ListNetworkInterfacesOptions) ([]api.NetworkInterface, api.ListNetworkInterfacesError) {
+	return mgr.ListWithContext(context.Background(), options)
+}
+
+func (mgr *NetworkInterfaceManager) update(ctx context.Context, options api.UpdateNetworkInterfaceOptions) (*api.NetworkInterface, error) {
 	if options.ServerID != nil {
-		out, err := mgr.Provider.AWSServices.EC2Client.DescribeNetworkInterfaces(&ec2.DescribeNetworkInterfacesInput{
+		out, err := mgr.Provider.AWSServices.EC2Client.DescribeNetworkInterfacesWithContext(ctx, &ec2.DescribeNetworkInterfacesInput{
 			NetworkInterfaceIds: []*string{&options.ID},
 		})
 		if err != nil {
 			return nil, err
 		}
-		if out.NetworkInterfaces != nil {
-			return nil, fmt.Errorf("network interface %s not found", options.ID)
+		if len(out.NetworkInterfaces) == 0 {
+			return nil, notFoundError("network interface %s not found", options.ID)
 		}
 		ni := out.NetworkInterfaces[0]
 		if ni.Attachment != nil && ni.Attachment.AttachmentId != nil {
-			_, err = mgr.Provider.AWSServices.EC2Client.DetachNetworkInterface(&ec2.DetachNetworkInterfaceInput{
+			_, err = mgr.Provider.AWSServices.EC2Client.DetachNetworkInterfaceWithContext(ctx, &ec2.DetachNetworkInterfaceInput{
 				AttachmentId: ni.Attachment.AttachmentId,
 				Force:        aws.Bool(true),
 			})
@@ -219,35 +267,40 @@ func (mgr *NetworkInterfaceManager) update(options api.UpdateNetworkInterfaceOpt
 				return nil, err
 			}
 		}
-		_, err = mgr.Provider.AWSServices.EC2Client.AttachNetworkInterface(&ec2.AttachNetworkInterfaceInput{
+		_, err = mgr.Provider.AWSServices.EC2Client.AttachNetworkInterfaceWithContext(ctx, &ec2.AttachNetworkInterfaceInput{
 			InstanceId:         options.ServerID,
 			NetworkInterfaceId: ni.NetworkInterfaceId,
 		})
 		if err != nil {
 			return nil, err
 		}
-		err = mgr.Provider.AWSServices.EC2Client.WaitUntilNetworkInterfaceAvailable(&ec2.DescribeNetworkInterfacesInput{
+		err = mgr.Provider.AWSServices.EC2Client.WaitUntilNetworkInterfaceAvailableWithContext(ctx, &ec2.DescribeNetworkInterfacesInput{
 			NetworkInterfaceIds: []*string{&options.ID},
 		})
 		return nil, err
 	}
 	if options.SecurityGroupID != nil {
-		_, err := mgr.Provider.AWSServices.EC2Client.ModifyNetworkInterfaceAttribute(&ec2.ModifyNetworkInterfaceAttributeInput{
+		_, err := mgr.Provider.AWSServices.EC2Client.ModifyNetworkInterfaceAttributeWithContext(ctx, &ec2.ModifyNetworkInterfaceAttributeInput{
 			Groups:             []*string{options.SecurityGroupID},
 			NetworkInterfaceId: aws.String(options.ID),
 		})
 		if err != nil {
 			return nil, err
 		}
-		err = mgr.Provider.AWSServices.EC2Client.WaitUntilNetworkInterfaceAvailable(&ec2.DescribeNetworkInterfacesInput{
+		err = mgr.Provider.AWSServices.EC2Client.WaitUntilNetworkInterfaceAvailableWithContext(ctx, &ec2.DescribeNetworkInterfacesInput{
 			NetworkInterfaceIds: []*string{&options.ID},
 		})
 		return nil, err
 	}
-	return mgr.Get(options.ID)
+	return mgr.GetWithContext(ctx, options.ID)
 }
 
-func (mgr *NetworkInterfaceManager) Update(options api.UpdateNetworkInterfaceOptions) (*api.NetworkInterface, api.UpdateNetworkInterfaceError) {
-	ni, err := mgr.update(options)
+//UpdateWithContext context aware version of Update
+func (mgr *NetworkInterfaceManager) UpdateWithContext(ctx context.Context, options api.UpdateNetworkInterfaceOptions) (*api.NetworkInterface, api.UpdateNetworkInterfaceError) {
+	ni, err := mgr.update(ctx, options)
 	return ni, api.NewUpdateNetworkInterfaceError(err, options)
 }
+
+func (mgr *NetworkInterfaceManager) Update(options api.UpdateNetworkInterfaceOptions) (*api.NetworkInterface, api.UpdateNetworkInterfaceError) {
+	return mgr.UpdateWithContext(context.Background(), options)
+}
@@ -1,10 +1,11 @@
 package aws
 
 import (
+	"context"
 	"fmt"
-	"sort"
 
 	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/SebastienDorgan/anyclouds/providers"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
 )
@@ -14,14 +15,19 @@ type NetworkManager struct {
 	Provider *Provider
 }
 
+//CreateNetworkWithContext creates a network
+func (mgr *NetworkManager) CreateNetworkWithContext(ctx context.Context, options api.CreateNetworkOptions) (*api.Network, api.CreateNetworkError) {
+	n, err := mgr.createNetwork(ctx, options)
+	return n, api.NewCreateNetworkError(err, options)
+}
+
 //CreateNetwork creates a network
 func (mgr *NetworkManager) CreateNetwork(options api.CreateNetworkOptions) (*api.Network, api.CreateNetworkError) {
-	n, err := mgr.createNetwork(options)
-	return n, api.NewCreateNetworkError(err, options)
+	return mgr.CreateNetworkWithContext(context.Background(), options)
 }
 
-func (mgr *NetworkManager) createNetwork(options api.CreateNetworkOptions) (*api.Network, error) {
-	out, err := mgr.Provider.AWSServices.EC2Client.CreateVpc(&ec2.CreateVpcInput{
+func (mgr *NetworkManager) createNetwork(ctx context.Context, options api.CreateNetworkOptions) (*api.Network, error) {
+	out, err := mgr.Provider.AWSServices.EC2Client.CreateVpcWithContext(ctx, &ec2.CreateVpcInput{
 		AmazonProvidedIpv6CidrBlock: aws.Bool(true),
 		CidrBlock:                   &options.CIDR,
 	})
@@ -29,31 +35,37 @@ func (mgr *NetworkManager) createNetwork(options api.CreateNetworkOptions) (*api
 		return nil, err
 	}
 
-	err = mgr.Provider.AddTags(*out.Vpc.VpcId, map[string]string{"name": options.Name})
+	err = mgr.Provider.AddTags(ctx, *out.Vpc.VpcId, resourceTags(options.Name, options.Tags))
 	if err != nil {
-		err2 := mgr.DeleteNetwork(*out.Vpc.VpcId)
+		err2 := providers.Cleanup(func(ctx context.Context) error {
+			return mgr.DeleteNetworkWithContext(ctx, *out.Vpc.VpcId)
+		})
 		return nil, api.NewErrorStackFromError(err, err2)
 	}
 
-	gw, err := mgr.addInternetGateway(out)
+	gw, err := mgr.addInternetGateway(ctx, out)
 	if err != nil {
-		err2 := mgr.DeleteNetwork(*out.Vpc.VpcId)
+		err2 := providers.Cleanup(func(ctx context.Context) error {
+			return mgr.DeleteNetworkWithContext(ctx, *out.Vpc.VpcId)
+		})
 		return nil, api.NewErrorStackFromError(err, err2)
 	}
-	_, err = mgr.populateRouteTable(*out.Vpc.VpcId, gw)
+	_, err = mgr.populateRouteTable(ctx, *out.Vpc.VpcId, gw)
 	if err != nil {
-		err2 := mgr.DeleteNetwork(*out.Vpc.VpcId)
+		err2 := providers.Cleanup(func(ctx context.Context) error {
+			return mgr.DeleteNetworkWithContext(ctx, *out.Vpc.VpcId)
+		})
 		return nil, api.NewErrorStackFromError(err, err2)
 	}
-	return mgr.GetNetwork(*out.Vpc.VpcId)
+	return mgr.GetNetworkWithContext(ctx, *out.Vpc.VpcId)
 }
 
-func (mgr *NetworkManager) addInternetGateway(out *ec2.CreateVpcOutput) (*ec2.InternetGateway, error) {
-	outGW, err := mgr.Provider.AWSServices.EC2Client.CreateInternetGateway(&ec2.CreateInternetGatewayInput{})
+func (mgr *NetworkManager) addInternetGateway(ctx context.Context, out *ec2.CreateVpcOutput) (*ec2.InternetGateway, error) {
+	outGW, err := mgr.Provider.AWSServices.EC2Client.CreateInternetGatewayWithContext(ctx, &ec2.CreateInternetGatewayInput{})
 	if err != nil {
 		return nil, err
 	}
-	_, err = mgr.Provider.AWSServices.EC2Client.AttachInternetGateway(&ec2.AttachInternetGatewayInput{
+	_, err = mgr.Provider.AWSServices.EC2Client.AttachInternetGatewayWithContext(ctx, &ec2.AttachInternetGatewayInput{
 		DryRun:            nil,
 		InternetGatewayId: outGW.InternetGateway.InternetGatewayId,
 		VpcId:             out.Vpc.VpcId,
@@ -61,20 +73,20 @@ func (mgr *NetworkManager) addInternetGateway(out *ec2.CreateVpcOutput) (*ec2.In
 	return outGW.InternetGateway, err
 }
 
-func (mgr *NetworkManager) removeInternetGateway(id string) error {
-	out, err := mgr.getInternetGateway(id)
+func (mgr *NetworkManager) removeInternetGateway(ctx context.Context, id string) error {
+	out, err := mgr.getInternetGateway(ctx, id)
 	if err != nil {
 		return err
 	}
 	for _, gw := range out.InternetGateways {
-		_, err = mgr.Provider.AWSServices.EC2Client.DetachInternetGateway(&ec2.DetachInternetGatewayInput{
+		_, err = mgr.Provider.AWSServices.EC2Client.DetachInternetGatewayWithContext(ctx, &ec2.DetachInternetGatewayInput{
 			InternetGatewayId: gw.InternetGatewayId,
 			VpcId:             aws.String(id),
 		})
 		if err != nil {
 			return err
 		}
-		_, err = mgr.Provider.AWSServices.EC2Client.DeleteInternetGateway(&ec2.DeleteInternetGatewayInput{
+		_, err = mgr.Provider.AWSServices.EC2Client.DeleteInternetGatewayWithContext(ctx, &ec2.DeleteInternetGatewayInput{
 			InternetGatewayId: gw.InternetGatewayId,
 		})
 		if err != nil {
@@ -85,8 +97,8 @@ func (mgr *NetworkManager) removeInternetGateway(id string) error {
 
 }
 
-func (mgr *NetworkManager) getInternetGateway(vpcID string) (*ec2.DescribeInternetGatewaysOutput, error) {
-	out, err := mgr.Provider.AWSServices.EC2Client.DescribeInternetGateways(&ec2.DescribeInternetGatewaysInput{
+func (mgr *NetworkManager) getInternetGateway(ctx context.Context, vpcID string) (*ec2.DescribeInternetGatewaysOutput, error) {
+	out, err := mgr.Provider.AWSServices.EC2Client.DescribeInternetGatewaysWithContext(ctx, &ec2.DescribeInternetGatewaysInput{
 		Filters: []*ec2.Filter{
 			{
 				Name:   aws.String("attachment.vpc-id"),
@@ -97,25 +109,30 @@ func (mgr *NetworkManager) getInternetGateway(vpcID string) (*ec2.DescribeIntern
 	return out, err
 }
 
+//DeleteNetworkWithContext deletes the network identified by id
+func (mgr *NetworkManager) DeleteNetworkWithContext(ctx context.Context, id string) api.DeleteNetworkError {
+	return api.NewDeleteNetworkError(mgr.deleteNetwork(ctx, id), id)
+}
+
 //DeleteNetwork deletes the network identified by id
 func (mgr *NetworkManager) DeleteNetwork(id string) api.DeleteNetworkError {
-	return api.NewDeleteNetworkError(mgr.deleteNetwork(id), id)
+	return mgr.DeleteNetworkWithContext(context.Background(), id)
 }
 
-func (mgr *NetworkManager) deleteNetwork(id string) error {
-	err := mgr.removeInternetGateway(id)
+func (mgr *NetworkManager) deleteNetwork(ctx context.Context, id string) error {
+	err := mgr.removeInternetGateway(ctx, id)
 	if err != nil {
 		return err
 	}
 
-	_, err = mgr.Provider.AWSServices.EC2Client.DeleteVpc(&ec2.DeleteVpcInput{
+	_, err = mgr.Provider.AWSServices.EC2Client.DeleteVpcWithContext(ctx, &ec2.DeleteVpcInput{
 		VpcId: &id,
 	})
 	return err
 }
 
-func (mgr *NetworkManager) getRouteTable(networkID string) (*ec2.RouteTable, error) {
-	tables, err := mgr.Provider.AWSServices.EC2Client.DescribeRouteTables(&ec2.DescribeRouteTablesInput{
+func (mgr *NetworkManager) getRouteTable(ctx context.Context, networkID string) (*ec2.RouteTable, error) {
+	tables, err := mgr.Provider.AWSServices.EC2Client.DescribeRouteTablesWithContext(ctx, &ec2.DescribeRouteTablesInput{
 		DryRun: nil,
 		Filters: []*ec2.Filter{
 			{
@@ -134,27 +151,27 @@ func (mgr *NetworkManager) getRouteTable(networkID string) (*ec2.RouteTable, err
 }
 
 func network(v *ec2.Vpc) *api.Network {
-	net := &api.Network{
+	return &api.Network{
 		ID:   *v.VpcId,
+		Name: tagValue(v.Tags, "name"),
 		CIDR: *v.CidrBlock,
+		Tags: userTags(v.Tags),
 	}
-	n := sort.Search(len(v.Tags), func(i int) bool {
-		return *v.Tags[i].Key == "name"
-	})
-	if n < len(v.Tags) {
-		net.Name = *v.Tags[n].Value
-	}
-	return net
+}
+
+//ListNetworksWithContext lists networks
+func (mgr *NetworkManager) ListNetworksWithContext(ctx context.Context) ([]api.Network, api.ListNetworksError) {
+	ns, err := mgr.listNetworks(ctx)
+	return ns, api.NewListNetworksError(err)
 }
 
 //ListNetworks lists networks
 func (mgr *NetworkManager) ListNetworks() ([]api.Network, api.ListNetworksError) {
-	ns, err := mgr.ListNetworks()
-	return ns, api.NewListNetworksError(err)
+	return mgr.ListNetworksWithContext(context.Background())
 }
 
-func (mgr *NetworkManager) listNetworks() ([]api.Network, error) {
-	out, err := mgr.Provider.AWSServices.EC2Client.DescribeVpcs(&ec2.DescribeVpcsInput{})
+func (mgr *NetworkManager) listNetworks(ctx context.Context) ([]api.Network, error) {
+	out, err := mgr.Provider.AWSServices.EC2Client.DescribeVpcsWithContext(ctx, &ec2.DescribeVpcsInput{})
 	if err != nil {
 		return nil, err
 	}
@@ -165,24 +182,28 @@ func (mgr *NetworkManager) listNetworks() ([]api.Network, error) {
 	return result, nil
 }
 
+//GetNetworkWithContext returns the configuration of the network identified by id
+func (mgr *NetworkManager) GetNetworkWithContext(ctx context.Context, id string) (*api.Network, api.GetNetworkError) {
+	n, err := mgr.getNetwork(ctx, id)
+	return n, api.NewGetNetworkError(err, id)
+}
+
 //GetNetwork returns the configuration of the network identified by id
 func (mgr *NetworkManager) GetNetwork(id string) (*api.Network, api.GetNetworkError) {
-	n, err := mgr.getNetwork(id)
-	return n, api.NewGetNetworkError(err, id)
+	return mgr.GetNetworkWithContext(context.Background(), id)
 }
 
-func (mgr *NetworkManager) getNetwork(id string) (*api.Network, error) {
-	out, err := mgr.Provider.AWSServices.EC2Client.DescribeVpcs(&ec2.DescribeVpcsInput{
+func (mgr *NetworkManager) getNetwork(ctx context.Context, id string) (*api.Network, error) {
+	out, err := mgr.Provider.AWSServices.EC2Client.DescribeVpcsWithContext(ctx, &ec2.DescribeVpcsInput{
 		VpcIds: []*string{&id},
 	})
 	if err != nil {
 		return nil, err
 	}
-	if len(out.Vpcs) > 0 {
-		return network(out.Vpcs[0]), nil
+	if len(out.Vpcs) == 0 {
+		return nil, notFoundError("network %s not found", id)
 	}
-	return nil, err
-
+	return network(out.Vpcs[0]), nil
 }
 
 func subnet(s *ec2.Subnet) *api.Subnet {
@@ -197,88 +218,96 @@ func subnet(s *ec2.Subnet) *api.Subnet {
 		sn.IPVersion = api.IPVersion4
 		sn.CIDR = *s.CidrBlock
 	}
-	n := sort.Search(len(s.Tags), func(i int) bool {
-		return *s.Tags[i].Key == "name"
-	})
-	if n < len(s.Tags) {
-		sn.Name = *s.Tags[n].Value
-	}
+	sn.Name = tagValue(s.Tags, "name")
+	sn.Tags = userTags(s.Tags)
 	return &sn
 }
 
+//CreateSubnetWithContext creates a subnet
+func (mgr *NetworkManager) CreateSubnetWithContext(ctx context.Context, options api.CreateSubnetOptions) (*api.Subnet, api.CreateSubnetError) {
+	sn, err := mgr.createSubnet(ctx, options)
+	return sn, api.NewCreateSubnetError(err, options)
+}
+
 //CreateSubnet creates a subnet
 func (mgr *NetworkManager) CreateSubnet(options api.CreateSubnetOptions) (*api.Subnet, api.CreateSubnetError) {
-	sn, err := mgr.createSubnet(options)
-	return sn, api.NewCreateSubnetError(err, options)
+	return mgr.CreateSubnetWithContext(context.Background(), options)
 }
 
-func (mgr *NetworkManager) createSubnet(options api.CreateSubnetOptions) (*api.Subnet, error) {
+func (mgr *NetworkManager) createSubnet(ctx context.Context, options api.CreateSubnetOptions) (*api.Subnet, error) {
 	input := ec2.CreateSubnetInput{
 		AvailabilityZone: aws.String(mgr.Provider.Configuration.AvailabilityZone),
 		VpcId:            &options.NetworkID,
 	}
+	if options.AvailabilityZone != "" {
+		input.AvailabilityZone = aws.String(options.AvailabilityZone)
+	}
 	if options.IPVersion == api.IPVersion4 {
 		input.CidrBlock = &options.CIDR
 	} else if options.IPVersion == api.IPVersion6 {
 		input.Ipv6CidrBlock = &options.CIDR
 	}
-	out, err := mgr.Provider.AWSServices.EC2Client.CreateSubnet(&input)
+	out, err := mgr.Provider.AWSServices.EC2Client.CreateSubnetWithContext(ctx, &input)
 	if err != nil {
 		return nil, err
 	}
 
-	err = mgr.Provider.AddTags(*out.Subnet.SubnetId, map[string]string{"name": options.Name})
+	err = mgr.Provider.AddTags(ctx, *out.Subnet.SubnetId, resourceTags(options.Name, options.Tags))
 	if err != nil {
-		err2 := mgr.DeleteSubnet(options.NetworkID, *out.Subnet.SubnetId)
+		err2 := providers.Cleanup(func(ctx context.Context) error {
+			return mgr.DeleteSubnetWithContext(ctx, options.NetworkID, *out.Subnet.SubnetId)
+		})
 		return nil, api.NewErrorStackFromError(err, err2)
 	}
-	err = mgr.associateRouteTable(&options, out)
+	err = mgr.associateRouteTable(ctx, &options, out)
 	if err != nil {
-		err2 := mgr.DeleteSubnet(options.NetworkID, *out.Subnet.SubnetId)
+		err2 := providers.Cleanup(func(ctx context.Context) error {
+			return mgr.DeleteSubnetWithContext(ctx, options.NetworkID, *out.Subnet.SubnetId)
+		})
 		return nil, api.NewErrorStackFromError(err, err2)
 	}
-	return mgr.GetSubnet(options.NetworkID, *out.Subnet.SubnetId)
+	return mgr.GetSubnetWithContext(ctx, options.NetworkID, *out.Subnet.SubnetId)
 }
 
-func (mgr *NetworkManager) associateRouteTable(options *api.CreateSubnetOptions, out *ec2.CreateSubnetOutput) error {
-	rt, err := mgr.getRouteTable(options.NetworkID)
+func (mgr *NetworkManager) associateRouteTable(ctx context.Context, options *api.CreateSubnetOptions, out *ec2.CreateSubnetOutput) error {
+	rt, err := mgr.getRouteTable(ctx, options.NetworkID)
 	if err != nil {
 		return err
 	}
-	_, err = mgr.Provider.AWSServices.EC2Client.AssociateRouteTable(&ec2.AssociateRouteTableInput{
+	_, err = mgr.Provider.AWSServices.EC2Client.AssociateRouteTableWithContext(ctx, &ec2.AssociateRouteTableInput{
 		RouteTableId: rt.RouteTableId,
 		SubnetId:     out.Subnet.SubnetId,
 	})
 	return err
 }
 
-func (mgr *NetworkManager) populateRouteTable(networkID string, gw *ec2.InternetGateway) (*ec2.RouteTable, error) {
-	rt, err := mgr.getRouteTable(networkID)
+func (mgr *NetworkManager) populateRouteTable(ctx context.Context, networkID string, gw *ec2.InternetGateway) (*ec2.RouteTable, error) {
+	rt, err := mgr.getRouteTable(ctx, networkID)
 	if err != nil {
 		return nil, err
 	}
-	_, err = mgr.Provider.AWSServices.EC2Client.CreateRoute(&ec2.CreateRouteInput{
+	_, err = mgr.Provider.AWSServices.EC2Client.CreateRouteWithContext(ctx, &ec2.CreateRouteInput{
 		DestinationCidrBlock: aws.String("0.0.0.0/0"),
 		GatewayId:            gw.InternetGatewayId,
 		RouteTableId:         rt.RouteTableId,
 	})
 	if err != nil {
-		_, _ = mgr.Provider.AWSServices.EC2Client.DeleteRouteTable(&ec2.DeleteRouteTableInput{
+		_, _ = mgr.Provider.AWSServices.EC2Client.DeleteRouteTableWithContext(ctx, &ec2.DeleteRouteTableInput{
 			RouteTableId: rt.RouteTableId,
 		})
 		return nil, err
 	}
-	_, err = mgr.Provider.AWSServices.EC2Client.CreateRoute(&ec2.CreateRouteInput{
+	_, err = mgr.Provider.AWSServices.EC2Client.CreateRouteWithContext(ctx, &ec2.CreateRouteInput{
 		DestinationIpv6CidrBlock: aws.String("::/0"),
 		GatewayId:                gw.InternetGatewayId,
 		RouteTableId:             rt.RouteTableId,
 	})
 	if err != nil {
-		_, _ = mgr.Provider.AWSServices.EC2Client.DeleteRoute(&ec2.DeleteRouteInput{
+		_, _ = mgr.Provider.AWSServices.EC2Client.DeleteRouteWithContext(ctx, &ec2.DeleteRouteInput{
 			DestinationCidrBlock: aws.String("0.0.0.0/0"),
 			RouteTableId:         rt.RouteTableId,
 		})
-		_, _ = mgr.Provider.AWSServices.EC2Client.DeleteRouteTable(&ec2.DeleteRouteTableInput{
+		_, _ = mgr.Provider.AWSServices.EC2Client.DeleteRouteTableWithContext(ctx, &ec2.DeleteRouteTableInput{
 			RouteTableId: rt.RouteTableId,
 		})
 		return nil, err
@@ -286,17 +315,22 @@ func (mgr *NetworkManager) populateRouteTable(networkID string, gw *ec2.Internet
 	return rt, err
 }
 
-//DeleteSubnet deletes the subnet identified by id
-func (mgr *NetworkManager) DeleteSubnet(networkID, subnetID string) api.DeleteSubnetError {
-	_, err := mgr.Provider.AWSServices.EC2Client.DeleteSubnet(&ec2.DeleteSubnetInput{
+//DeleteSubnetWithContext deletes the subnet identified by id
+func (mgr *NetworkManager) DeleteSubnetWithContext(ctx context.Context, networkID, subnetID string) api.DeleteSubnetError {
+	_, err := mgr.Provider.AWSServices.EC2Client.DeleteSubnetWithContext(ctx, &ec2.DeleteSubnetInput{
 		SubnetId: &subnetID,
 	})
 	return api.NewDeleteSubnetError(err, networkID, subnetID)
 }
 
-//ListSubnets lists the subnet
-func (mgr *NetworkManager) ListSubnets(networkID string) ([]api.Subnet, api.ListSubnetsError) {
-	out, err := mgr.Provider.AWSServices.EC2Client.DescribeSubnets(&ec2.DescribeSubnetsInput{
+//DeleteSubnet deletes the subnet identified by id
+func (mgr *NetworkManager) DeleteSubnet(networkID, subnetID string) api.DeleteSubnetError {
+	return mgr.DeleteSubnetWithContext(context.Background(), networkID, subnetID)
+}
+
+//ListSubnetsWithContext lists the subnet
+func (mgr *NetworkManager) ListSubnetsWithContext(ctx context.Context, networkID string) ([]api.Subnet, api.ListSubnetsError) {
+	out, err := mgr.Provider.AWSServices.EC2Client.DescribeSubnetsWithContext(ctx, &ec2.DescribeSubnetsInput{
 		Filters: []*ec2.Filter{
 			{
 				Name: aws.String("vpc-id"),
@@ -316,9 +350,14 @@ func (mgr *NetworkManager) ListSubnets(networkID string) ([]api.Subnet, api.List
 	return result, nil
 }
 
-//GetSubnet returns the configuration of the subnet identified by id
-func (mgr *NetworkManager) GetSubnet(networkID, subnetID string) (*api.Subnet, api.GetSubnetError) {
-	out, err := mgr.Provider.AWSServices.EC2Client.DescribeSubnets(&ec2.DescribeSubnetsInput{
+//ListSubnets lists the subnet
+func (mgr *NetworkManager) ListSubnets(networkID string) ([]api.Subnet, api.ListSubnetsError) {
+	return mgr.ListSubnetsWithContext(context.Background(), networkID)
+}
+
+//GetSubnetWithContext returns the configuration of the subnet identified by id
+func (mgr *NetworkManager) GetSubnetWithContext(ctx context.Context, networkID, subnetID string) (*api.Subnet, api.GetSubnetError) {
+	out, err := mgr.Provider.AWSServices.EC2Client.DescribeSubnetsWithContext(ctx, &ec2.DescribeSubnetsInput{
 		SubnetIds: []*string{&subnetID},
 	})
 	if err != nil {
@@ -329,5 +368,10 @@ func (mgr *NetworkManager) GetSubnet(networkID, subnetID string) (*api.Subnet, a
 			return subnet(sn), nil
 		}
 	}
-	return nil, api.NewGetSubnetError(err, networkID, subnetID)
+	return nil, api.NewGetSubnetError(notFoundError("subnet %s not found", subnetID), networkID, subnetID)
+}
+
+//GetSubnet returns the configuration of the subnet identified by id
+func (mgr *NetworkManager) GetSubnet(networkID, subnetID string) (*api.Subnet, api.GetSubnetError) {
+	return mgr.GetSubnetWithContext(context.Background(), networkID, subnetID)
 }
@@ -0,0 +1,330 @@
+package aws
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+//ObjectStorageManager aws implementation of api.ObjectStorageManager using S3
+//Buckets are identified by their name
+type ObjectStorageManager struct {
+	Provider *Provider
+}
+
+func (mgr *ObjectStorageManager) client() *s3.S3 {
+	return mgr.Provider.AWSServices.S3Client
+}
+
+//etag returns the ETag of an object without its quotes
+func etag(tag *string) string {
+	return strings.Trim(aws.StringValue(tag), `"`)
+}
+
+func (mgr *ObjectStorageManager) createBucket(ctx context.Context, options api.CreateBucketOptions) (*api.Bucket, error) {
+	err := api.CheckBucketOptions(&options)
+	if err != nil {
+		return nil, err
+	}
+	_, err = mgr.client().CreateBucketWithContext(ctx, &s3.CreateBucketInput{
+		Bucket: aws.String(options.Name),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &api.Bucket{
+		ID:   options.Name,
+		Name: options.Name,
+	}, nil
+}
+
+//CreateBucketWithContext creates a bucket in the region of the provider
+func (mgr *ObjectStorageManager) CreateBucketWithContext(ctx context.Context, options api.CreateBucketOptions) (*api.Bucket, api.CreateBucketError) {
+	b, err := mgr.createBucket(ctx, options)
+	if err != nil {
+		return nil, api.NewCreateBucketError(err, options)
+	}
+	return b, nil
+}
+
+//CreateBucket creates a bucket in the region of the provider
+func (mgr *ObjectStorageManager) CreateBucket(options api.CreateBucketOptions) (*api.Bucket, api.CreateBucketError) {
+	return mgr.CreateBucketWithContext(context.Background(), options)
+}
+
+func (mgr *ObjectStorageManager) deleteBucket(ctx context.Context, id string) error {
+	//S3 only deletes empty buckets
+	var deleteErr error
+	err := mgr.client().ListObjectsV2PagesWithContext(ctx, &s3.ListObjectsV2Input{
+		Bucket: aws.String(id),
+	}, func(out *s3.ListObjectsV2Output, last bool) bool {
+		if len(out.Contents) == 0 {
+			return true
+		}
+		var objects []*s3.ObjectIdentifier
+		for _, o := range out.Contents {
+			objects = append(objects, &s3.ObjectIdentifier{Key: o.Key})
+		}
+		_, deleteErr = mgr.client().DeleteObjectsWithContext(ctx, &s3.DeleteObjectsInput{
+			Bucket: aws.String(id),
+			Delete: &s3.Delete{Objects: objects, Quiet: aws.Bool(true)},
+		})
+		return deleteErr == nil
+	})
+	if err != nil {
+		return err
+	}
+	if deleteErr != nil {
+		return deleteErr
+	}
+	_, err = mgr.client().DeleteBucketWithContext(ctx, &s3.DeleteBucketInput{
+		Bucket: aws.String(id),
+	})
+	return err
+}
+
+//DeleteBucketWithContext deletes the bucket identified by id and its objects
+func (mgr *ObjectStorageManager) DeleteBucketWithContext(ctx context.Context, id string) api.DeleteBucketError {
+	return api.NewDeleteBucketError(mgr.deleteBucket(ctx, id), id)
+}
+
+//DeleteBucket deletes the bucket identified by id and its objects
+func (mgr *ObjectStorageManager) DeleteBucket(id string) api.DeleteBucketError {
+	return mgr.DeleteBucketWithContext(context.Background(), id)
+}
+
+//ListBucketsWithContext lists the buckets of the account
+func (mgr *ObjectStorageManager) ListBucketsWithContext(ctx context.Context) ([]api.Bucket, api.ListBucketsError) {
+	out, err := mgr.client().ListBucketsWithContext(ctx, &s3.ListBucketsInput{})
+	if err != nil {
+		return nil, api.NewListBucketsError(err)
+	}
+	buckets := []api.Bucket{}
+	for _, b := range out.Buckets {
+		buckets = append(buckets, api.Bucket{
+			ID:   aws.StringValue(b.Name),
+			Name: aws.StringValue(b.Name),
+		})
+	}
+	return buckets, nil
+}
+
+//ListBuckets lists the buckets of the account
+func (mgr *ObjectStorageManager) ListBuckets() ([]api.Bucket, api.ListBucketsError) {
+	return mgr.ListBucketsWithContext(context.Background())
+}
+
+//GetBucketWithContext returns the bucket identified by id
+func (mgr *ObjectStorageManager) GetBucketWithContext(ctx context.Context, id string) (*api.Bucket, api.GetBucketError) {
+	_, err := mgr.client().HeadBucketWithContext(ctx, &s3.HeadBucketInput{
+		Bucket: aws.String(id),
+	})
+	if err != nil {
+		return nil, api.NewGetBucketError(err, id)
+	}
+	return &api.Bucket{
+		ID:   id,
+		Name: id,
+	}, nil
+}
+
+//GetBucket returns the bucket identified by id
+func (mgr *ObjectStorageManager) GetBucket(id string) (*api.Bucket, api.GetBucketError) {
+	return mgr.GetBucketWithContext(context.Background(), id)
+}
+
+func (mgr *ObjectStorageManager) headObject(ctx context.Context, bucketID, key string) (*api.Object, error) {
+	out, err := mgr.client().HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucketID),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &api.Object{
+		BucketID:     bucketID,
+		Key:          key,
+		Size:         aws.Int64Value(out.ContentLength),
+		ContentType:  aws.StringValue(out.ContentType),
+		ETag:         etag(out.ETag),
+		LastModified: aws.TimeValue(out.LastModified),
+	}, nil
+}
+
+func (mgr *ObjectStorageManager) putObject(ctx context.Context, options api.PutObjectOptions) (*api.Object, error) {
+	err := api.CheckObjectKey(options.Key)
+	if err != nil {
+		return nil, err
+	}
+	contentType := options.ContentType
+	if contentType == "" {
+		contentType = api.DefaultContentType
+	}
+	//the uploader streams the content, using a multipart upload if it is larger than a part
+	uploader := s3manager.NewUploaderWithClient(mgr.client())
+	_, err = uploader.UploadWithContext(ctx, &s3manager.UploadInput{
+		Bucket:      aws.String(options.BucketID),
+		Key:         aws.String(options.Key),
+		Body:        options.Content,
+		ContentType: aws.String(contentType),
+	})
+	if err != nil {
+		return nil, unwrapUploadError(err)
+	}
+	return mgr.headObject(ctx, options.BucketID, options.Key)
+}
+
+//unwrapUploadError returns the error of the S3 request which made an upload fail
+func unwrapUploadError(err error) error {
+	if mErr, ok := err.(s3manager.MultiUploadFailure); ok && mErr.OrigErr() != nil {
+		return UnwrapAWSError(mErr.OrigErr())
+	}
+	return UnwrapAWSError(err)
+}
+
+//PutObjectWithContext creates or replaces an object
+func (mgr *ObjectStorageManager) PutObjectWithContext(ctx context.Context, options api.PutObjectOptions) (*api.Object, api.PutObjectError) {
+	o, err := mgr.putObject(ctx, options)
+	if err != nil {
+		return nil, api.NewPutObjectError(err, options)
+	}
+	return o, nil
+}
+
+//PutObject creates or replaces an object
+func (mgr *ObjectStorageManager) PutObject(options api.PutObjectOptions) (*api.Object, api.PutObjectError) {
+	return mgr.PutObjectWithContext(context.Background(), options)
+}
+
+//GetObjectWithContext returns a reader streaming the content of an object
+func (mgr *ObjectStorageManager) GetObjectWithContext(ctx context.Context, bucketID string, key string) (*api.ObjectReader, api.GetObjectError) {
+	out, err := mgr.client().GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucketID),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, api.NewGetObjectError(err, bucketID, key)
+	}
+	return &api.ObjectReader{
+		Object: api.Object{
+			BucketID:     bucketID,
+			Key:          key,
+			Size:         aws.Int64Value(out.ContentLength),
+			ContentType:  aws.StringValue(out.ContentType),
+			ETag:         etag(out.ETag),
+			LastModified: aws.TimeValue(out.LastModified),
+		},
+		ReadCloser: out.Body,
+	}, nil
+}
+
+//GetObject returns a reader streaming the content of an object
+func (mgr *ObjectStorageManager) GetObject(bucketID string, key string) (*api.ObjectReader, api.GetObjectError) {
+	return mgr.GetObjectWithContext(context.Background(), bucketID, key)
+}
+
+func (mgr *ObjectStorageManager) listObjects(ctx context.Context, options api.ListObjectsOptions) ([]api.Object, error) {
+	objects := []api.Object{}
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(options.BucketID),
+	}
+	if options.Prefix != "" {
+		input.Prefix = aws.String(options.Prefix)
+	}
+	err := mgr.client().ListObjectsV2PagesWithContext(ctx, input, func(out *s3.ListObjectsV2Output, last bool) bool {
+		for _, o := range out.Contents {
+			objects = append(objects, api.Object{
+				BucketID:     options.BucketID,
+				Key:          aws.StringValue(o.Key),
+				Size:         aws.Int64Value(o.Size),
+				ETag:         etag(o.ETag),
+				LastModified: aws.TimeValue(o.LastModified),
+			})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return objects, nil
+}
+
+//ListObjectsWithContext lists the objects of a bucket sorted by key
+//S3 does not list the content type of the objects
+func (mgr *ObjectStorageManager) ListObjectsWithContext(ctx context.Context, options api.ListObjectsOptions) ([]api.Object, api.ListObjectsError) {
+	objects, err := mgr.listObjects(ctx, options)
+	if err != nil {
+		return nil, api.NewListObjectsError(err, options)
+	}
+	return objects, nil
+}
+
+//ListObjects lists the objects of a bucket sorted by key
+//S3 does not list the content type of the objects
+func (mgr *ObjectStorageManager) ListObjects(options api.ListObjectsOptions) ([]api.Object, api.ListObjectsError) {
+	return mgr.ListObjectsWithContext(context.Background(), options)
+}
+
+func (mgr *ObjectStorageManager) deleteObject(ctx context.Context, bucketID, key string) error {
+	//S3 does not report the deletion of missing objects
+	_, err := mgr.headObject(ctx, bucketID, key)
+	if err != nil {
+		return err
+	}
+	_, err = mgr.client().DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(bucketID),
+		Key:    aws.String(key),
+	})
+	return err
+}
+
+//DeleteObjectWithContext deletes an object
+func (mgr *ObjectStorageManager) DeleteObjectWithContext(ctx context.Context, bucketID string, key string) api.DeleteObjectError {
+	return api.NewDeleteObjectError(mgr.deleteObject(ctx, bucketID, key), bucketID, key)
+}
+
+//DeleteObject deletes an object
+func (mgr *ObjectStorageManager) DeleteObject(bucketID string, key string) api.DeleteObjectError {
+	return mgr.DeleteObjectWithContext(context.Background(), bucketID, key)
+}
+
+func (mgr *ObjectStorageManager) presignURL(options api.PresignURLOptions) (string, error) {
+	err := api.CheckPresignURLOptions(&options)
+	if err != nil {
+		return "", err
+	}
+	var req *request.Request
+	if options.Method == http.MethodPut {
+		req, _ = mgr.client().PutObjectRequest(&s3.PutObjectInput{
+			Bucket: aws.String(options.BucketID),
+			Key:    aws.String(options.Key),
+		})
+	} else {
+		req, _ = mgr.client().GetObjectRequest(&s3.GetObjectInput{
+			Bucket: aws.String(options.BucketID),
+			Key:    aws.String(options.Key),
+		})
+	}
+	return req.Presign(options.Expiry)
+}
+
+//PresignURLWithContext returns a URL granting the operation defined by options until it expires
+//The URL is signed locally, ctx is not used
+func (mgr *ObjectStorageManager) PresignURLWithContext(ctx context.Context, options api.PresignURLOptions) (string, api.PresignURLError) {
+	u, err := mgr.presignURL(options)
+	if err != nil {
+		return "", api.NewPresignURLError(err, options)
+	}
+	return u, nil
+}
+
+//PresignURL returns a URL granting the operation defined by options until it expires
+func (mgr *ObjectStorageManager) PresignURL(options api.PresignURLOptions) (string, api.PresignURLError) {
+	return mgr.PresignURLWithContext(context.Background(), options)
+}
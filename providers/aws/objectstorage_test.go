@@ -0,0 +1,21 @@
+package aws_test
+
+import (
+	"testing"
+
+	"github.com/SebastienDorgan/anyclouds/tests"
+	"github.com/stretchr/testify/suite"
+)
+
+type AWSObjectStorageManagerTestSuite struct {
+	tests.ObjectStorageManagerTestSuite
+}
+
+//SetupSuite set up object storage manager
+func (suite *AWSObjectStorageManagerTestSuite) SetupSuite() {
+	suite.Prov = GetProvider()
+}
+
+func TestAWSObjectStorageManagerTestSuite(t *testing.T) {
+	suite.Run(t, new(AWSObjectStorageManagerTestSuite))
+}
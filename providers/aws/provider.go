@@ -4,17 +4,29 @@ import (
 	"io"
 
 	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/SebastienDorgan/anyclouds/providers"
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/elbv2"
 	"github.com/aws/aws-sdk-go/service/opsworks"
 	"github.com/aws/aws-sdk-go/service/pricing"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/aws/aws-sdk-go/service/s3"
 
 	"github.com/pkg/errors"
 	"github.com/spf13/viper"
 )
 
+func init() {
+	providers.Register("aws", func() api.Provider {
+		return &Provider{}
+	})
+}
+
 //Config Provider session configuration
 type Config struct {
 	// Provider Region
@@ -23,13 +35,20 @@ type Config struct {
 	AccessKeyID string
 
 	// Provider Secret Access Key
-	SecretAccessKey string
+	SecretAccessKey string `redact:"true"`
 
 	// Provider Session Token
-	SessionToken string
+	SessionToken string `redact:"true"`
 
 	// Provider used to get credentials
 	ProviderName string
+
+	// EC2, ELBv2, Auto Scaling, Route53, S3 and Service Quotas endpoint, overrides the endpoint resolved from the region
+	// S3 buckets are addressed in path style when Endpoint is set
+	Endpoint string
+
+	// Pricing endpoint, overrides the endpoint of the us-east-1 pricing service
+	PricingEndpoint string
 }
 
 //Retrieve adapts Config to Provider Provider interface
@@ -55,9 +74,14 @@ type Configuration struct {
 
 //BaseServices aws raw services
 type BaseServices struct {
-	EC2Client      *ec2.EC2
-	OpsWorksClient *opsworks.OpsWorks
-	PricingClient  *pricing.Pricing
+	EC2Client           *ec2.EC2
+	OpsWorksClient      *opsworks.OpsWorks
+	PricingClient       *pricing.Pricing
+	ELBClient           *elbv2.ELBV2
+	Route53Client       *route53.Route53
+	S3Client            *s3.S3
+	AutoScalingClient   *autoscaling.AutoScaling
+	ServiceQuotasClient *client.Client
 }
 
 //Provider Provider provider
@@ -73,20 +97,38 @@ type Provider struct {
 	SecurityGroupManager    SecurityGroupManager
 	VolumeManager           VolumeManager
 	PublicIPAddressManager  PublicIPManager
+	TagManager              TagManager
+	SnapshotManager         SnapshotManager
+	LoadBalancerManager     LoadBalancerManager
+	DNSManager              DNSManager
+	ObjectStorageManager    ObjectStorageManager
+	ServerGroupManager      ServerGroupManager
+	LocationManager         LocationManager
+	QuotaManager            QuotaManager
+
+	config Config
 }
 
 func getEC2Config(cfg *Config) *aws.Config {
-	return &aws.Config{
+	awsCfg := &aws.Config{
 		Region:      aws.String(cfg.Region),
 		Credentials: credentials.NewCredentials(cfg),
 	}
+	if cfg.Endpoint != "" {
+		awsCfg.Endpoint = aws.String(cfg.Endpoint)
+	}
+	return awsCfg
 }
 
 func getPricingConfig(cfg *Config) *aws.Config {
-	return &aws.Config{
+	awsCfg := &aws.Config{
 		Region:      aws.String("us-east-1"),
 		Credentials: credentials.NewCredentials(cfg),
 	}
+	if cfg.PricingEndpoint != "" {
+		awsCfg.Endpoint = aws.String(cfg.PricingEndpoint)
+	}
+	return awsCfg
 }
 
 //Init initialize Provider Provider
@@ -101,19 +143,42 @@ func (p *Provider) Init(config io.Reader, format string) error {
 		AccessKeyID:     v.GetString("AccessKeyID"),
 		Region:          v.GetString("Region"),
 		SecretAccessKey: v.GetString("SecretAccessKey"),
+		Endpoint:        v.GetString("Endpoint"),
+		PricingEndpoint: v.GetString("PricingEndpoint"),
 	}
-	ec2session, err := session.NewSession(getEC2Config(&cfg))
+	return p.init(&cfg, Configuration{
+		Region:           cfg.Region,
+		RegionName:       v.GetString("RegionName"),
+		AvailabilityZone: v.GetString("AvailabilityZone"),
+	})
+}
+
+//init creates the clients of the services and the managers of the provider
+func (p *Provider) init(cfg *Config, configuration Configuration) error {
+	ec2session, err := session.NewSession(getEC2Config(cfg))
 	if err != nil {
 		return errors.Wrap(err, "Error creation provider session")
 	}
 	p.AWSServices.EC2Client = ec2.New(ec2session)
+	p.AWSServices.EC2Client.Handlers.UnmarshalError.PushBackNamed(unwrapErrorHandler)
 	p.AWSServices.OpsWorksClient = opsworks.New(ec2session)
+	p.AWSServices.OpsWorksClient.Handlers.UnmarshalError.PushBackNamed(unwrapErrorHandler)
+	p.AWSServices.ELBClient = elbv2.New(ec2session)
+	p.AWSServices.ELBClient.Handlers.UnmarshalError.PushBackNamed(unwrapErrorHandler)
+	p.AWSServices.Route53Client = route53.New(ec2session)
+	p.AWSServices.Route53Client.Handlers.UnmarshalError.PushBackNamed(unwrapErrorHandler)
+	p.AWSServices.S3Client = s3.New(ec2session, &aws.Config{S3ForcePathStyle: aws.Bool(cfg.Endpoint != "")})
+	p.AWSServices.S3Client.Handlers.UnmarshalError.PushBackNamed(unwrapErrorHandler)
+	p.AWSServices.AutoScalingClient = autoscaling.New(ec2session)
+	p.AWSServices.AutoScalingClient.Handlers.UnmarshalError.PushBackNamed(unwrapErrorHandler)
+	p.AWSServices.ServiceQuotasClient = newServiceQuotasClient(ec2session)
 
-	pricingSession, err := session.NewSession(getPricingConfig(&cfg))
+	pricingSession, err := session.NewSession(getPricingConfig(cfg))
 	if err != nil {
 		return errors.Wrap(err, "Error creation provider session")
 	}
 	p.AWSServices.PricingClient = pricing.New(pricingSession)
+	p.AWSServices.PricingClient.Handlers.UnmarshalError.PushBackNamed(unwrapErrorHandler)
 	p.ImagesManager.Provider = p
 	p.NetworkManager.Provider = p
 	p.NetworkInterfaceManager.Provider = p
@@ -123,12 +188,18 @@ func (p *Provider) Init(config io.Reader, format string) error {
 	p.SecurityGroupManager.Provider = p
 	p.KeyPairManager.Provider = p
 	p.PublicIPAddressManager.Provider = p
-	p.Configuration.Region = cfg.Region
-	p.Configuration.RegionName = v.GetString("RegionName")
-	p.Configuration.AvailabilityZone = v.GetString("AvailabilityZone")
+	p.TagManager.Provider = p
+	p.SnapshotManager.Provider = p
+	p.LoadBalancerManager.Provider = p
+	p.DNSManager.Provider = p
+	p.ObjectStorageManager.Provider = p
+	p.ServerGroupManager.Provider = p
+	p.LocationManager.Provider = p
+	p.QuotaManager.Provider = p
+	p.config = *cfg
+	p.Configuration = configuration
 
 	return nil
-
 }
 
 //Name name of the provider
@@ -174,3 +245,48 @@ func (p *Provider) GetVolumeManager() api.VolumeManager {
 func (p *Provider) GetPublicIPAddressManager() api.PublicIPManager {
 	return &p.PublicIPAddressManager
 }
+
+//GetTagManager returns aws TagManager
+func (p *Provider) GetTagManager() api.TagManager {
+	return &p.TagManager
+}
+
+//GetSnapshotManager returns aws SnapshotManager
+func (p *Provider) GetSnapshotManager() api.SnapshotManager {
+	return &p.SnapshotManager
+}
+
+//GetKeyPairManager returns aws KeyPairManager
+func (p *Provider) GetKeyPairManager() api.KeyPairManager {
+	return &p.KeyPairManager
+}
+
+//GetLoadBalancerManager returns aws LoadBalancerManager
+func (p *Provider) GetLoadBalancerManager() api.LoadBalancerManager {
+	return &p.LoadBalancerManager
+}
+
+//GetDNSManager returns aws DNSManager
+func (p *Provider) GetDNSManager() api.DNSManager {
+	return &p.DNSManager
+}
+
+//GetObjectStorageManager returns aws ObjectStorageManager
+func (p *Provider) GetObjectStorageManager() api.ObjectStorageManager {
+	return &p.ObjectStorageManager
+}
+
+//GetServerGroupManager returns aws ServerGroupManager
+func (p *Provider) GetServerGroupManager() api.ServerGroupManager {
+	return &p.ServerGroupManager
+}
+
+//GetLocationManager returns aws LocationManager
+func (p *Provider) GetLocationManager() api.LocationManager {
+	return &p.LocationManager
+}
+
+//GetQuotaManager returns aws QuotaManager
+func (p *Provider) GetQuotaManager() api.QuotaManager {
+	return &p.QuotaManager
+}
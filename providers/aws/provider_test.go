@@ -1,21 +1,45 @@
 package aws_test
 
 import (
+	"fmt"
+	"io"
 	"os"
 	"os/user"
 	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/SebastienDorgan/anyclouds/api"
 	"github.com/SebastienDorgan/anyclouds/providers/aws"
+	"github.com/SebastienDorgan/anyclouds/providers/aws/fake"
 	"github.com/stretchr/testify/assert"
 )
 
+//IsFake returns true if the tests run against the EC2 fake, i.e. if ~/.anyclouds/aws_test.json does not exist
+func IsFake() bool {
+	usr, _ := user.Current()
+	_, err := os.Stat(filepath.Join(usr.HomeDir, ".anyclouds/aws_test.json"))
+	return err != nil
+}
+
+//GetConfig returns the content of ~/.anyclouds/aws_test.json if it exists, otherwise a configuration targeting a new EC2 fake
+//The fake is left running until the end of the tests
+func GetConfig() (io.Reader, error) {
+	if IsFake() {
+		srv := fake.NewServer()
+		return strings.NewReader(srv.Config()), nil
+	}
+	usr, _ := user.Current()
+	return os.Open(filepath.Join(usr.HomeDir, ".anyclouds/aws_test.json"))
+}
+
 func GetProvider() *aws.Provider {
 	var provider aws.Provider
-	usr, _ := user.Current()
-	path := filepath.Join(usr.HomeDir, ".anyclouds/aws_test.json")
-	file, err := os.Open(path)
-	err = provider.Init(file, "json")
+	cfg, err := GetConfig()
+	if err != nil {
+		return nil
+	}
+	err = provider.Init(cfg, "json")
 	if err != nil {
 		return nil
 	}
@@ -25,13 +49,25 @@ func GetProvider() *aws.Provider {
 //TestCreate create Provider provider
 func TestCreate(t *testing.T) {
 	var provider aws.Provider
-	usr, _ := user.Current()
-	path := filepath.Join(usr.HomeDir, ".anyclouds/aws_test.json")
-	file, err := os.Open(path)
+	cfg, err := GetConfig()
 	assert.NoError(t, err)
-	err = provider.Init(file, "json")
+	err = provider.Init(cfg, "json")
 	assert.NoError(t, err)
 	images, err := provider.GetImageManager().List()
 	assert.NoError(t, err)
 	assert.True(t, len(images) > 0)
 }
+
+//TestRedactedConfig checks that the credentials of the configuration are redacted in error stacks
+func TestRedactedConfig(t *testing.T) {
+	err := api.NewErrorStack(fmt.Errorf("terrible error"), "error initializing provider", aws.Config{
+		Region:          "us-east-1",
+		AccessKeyID:     "access_key_id",
+		SecretAccessKey: "secret_access_key",
+		SessionToken:    "secret_session_token",
+	})
+	msg := err.Error()
+	assert.Contains(t, msg, "access_key_id")
+	assert.NotContains(t, msg, "secret_access_key")
+	assert.NotContains(t, msg, "secret_session_token")
+}
@@ -1,7 +1,9 @@
 package aws
 
 import (
+	"context"
 	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/SebastienDorgan/anyclouds/providers"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
 )
@@ -21,9 +23,10 @@ func addressRanges(pool *ec2.PublicIpv4Pool) []api.AddressRange {
 	return ranges
 }
 
-func (mgr *PublicIPManager) ListAvailablePools() ([]api.PublicIPPool, api.ListAvailablePublicIPPoolsError) {
+//ListAvailablePoolsWithContext context aware version of ListAvailablePools
+func (mgr *PublicIPManager) ListAvailablePoolsWithContext(ctx context.Context) ([]api.PublicIPPool, api.ListAvailablePublicIPPoolsError) {
 	var pools []api.PublicIPPool
-	out, err := mgr.Provider.AWSServices.EC2Client.DescribePublicIpv4Pools(&ec2.DescribePublicIpv4PoolsInput{})
+	out, err := mgr.Provider.AWSServices.EC2Client.DescribePublicIpv4PoolsWithContext(ctx, &ec2.DescribePublicIpv4PoolsInput{})
 	if err != nil {
 		return nil, api.NewListAvailablePublicIPPoolsError(err)
 	}
@@ -34,7 +37,7 @@ func (mgr *PublicIPManager) ListAvailablePools() ([]api.PublicIPPool, api.ListAv
 		})
 	}
 	for out.NextToken != nil {
-		out, err = mgr.Provider.AWSServices.EC2Client.DescribePublicIpv4Pools(&ec2.DescribePublicIpv4PoolsInput{
+		out, err = mgr.Provider.AWSServices.EC2Client.DescribePublicIpv4PoolsWithContext(ctx, &ec2.DescribePublicIpv4PoolsInput{
 			NextToken: out.NextToken,
 		})
 		if err != nil {
@@ -49,6 +52,11 @@ func (mgr *PublicIPManager) ListAvailablePools() ([]api.PublicIPPool, api.ListAv
 	}
 	return pools, nil
 }
+
+func (mgr *PublicIPManager) ListAvailablePools() ([]api.PublicIPPool, api.ListAvailablePublicIPPoolsError) {
+	return mgr.ListAvailablePoolsWithContext(context.Background())
+}
+
 func createListPublicIPAddressFilters(options *api.ListPublicIPsOptions) []*ec2.Filter {
 	if options == nil {
 		return nil
@@ -64,8 +72,9 @@ func createListPublicIPAddressFilters(options *api.ListPublicIPsOptions) []*ec2.
 	}
 }
 
-func (mgr *PublicIPManager) List(options *api.ListPublicIPsOptions) ([]api.PublicIP, api.ListPublicIPsError) {
-	out, err := mgr.Provider.AWSServices.EC2Client.DescribeAddresses(&ec2.DescribeAddressesInput{
+//ListWithContext context aware version of List
+func (mgr *PublicIPManager) ListWithContext(ctx context.Context, options *api.ListPublicIPsOptions) ([]api.PublicIP, api.ListPublicIPsError) {
+	out, err := mgr.Provider.AWSServices.EC2Client.DescribeAddressesWithContext(ctx, &ec2.DescribeAddressesInput{
 		Filters: createListPublicIPAddressFilters(options),
 	})
 	if err != nil {
@@ -82,6 +91,10 @@ func (mgr *PublicIPManager) List(options *api.ListPublicIPsOptions) ([]api.Publi
 	return addresses, nil
 }
 
+func (mgr *PublicIPManager) List(options *api.ListPublicIPsOptions) ([]api.PublicIP, api.ListPublicIPsError) {
+	return mgr.ListWithContext(context.Background(), options)
+}
+
 func toAllocateAddressInput(options *api.CreatePublicIPOptions) *ec2.AllocateAddressInput {
 	return &ec2.AllocateAddressInput{
 		Address:        options.IPAddress,
@@ -90,14 +103,18 @@ func toAllocateAddressInput(options *api.CreatePublicIPOptions) *ec2.AllocateAdd
 	}
 
 }
-func (mgr *PublicIPManager) Create(options api.CreatePublicIPOptions) (*api.PublicIP, api.CreatePublicIPError) {
-	out, err := mgr.Provider.AWSServices.EC2Client.AllocateAddress(toAllocateAddressInput(&options))
+
+//CreateWithContext context aware version of Create
+func (mgr *PublicIPManager) CreateWithContext(ctx context.Context, options api.CreatePublicIPOptions) (*api.PublicIP, api.CreatePublicIPError) {
+	out, err := mgr.Provider.AWSServices.EC2Client.AllocateAddressWithContext(ctx, toAllocateAddressInput(&options))
 	if err != nil {
 		return nil, api.NewCreatePublicIPError(err, options)
 	}
-	err = mgr.Provider.AddTags(*out.AllocationId, map[string]string{"name": options.Name})
+	err = mgr.Provider.AddTags(ctx, *out.AllocationId, resourceTags(options.Name, options.Tags))
 	if err != nil {
-		err2 := mgr.Delete(*out.AllocationId)
+		err2 := providers.Cleanup(func(ctx context.Context) error {
+			return mgr.DeleteWithContext(ctx, *out.AllocationId)
+		})
 		err = api.NewErrorStackFromError(err, err2)
 		return nil, api.NewCreatePublicIPError(err, options)
 	}
@@ -105,25 +122,36 @@ func (mgr *PublicIPManager) Create(options api.CreatePublicIPOptions) (*api.Publ
 		Name:    options.Name,
 		ID:      *out.AllocationId,
 		Address: *out.PublicIp,
+		Tags:    userTags(createAWSTags(options.Tags)),
 	}, nil
 }
-func (mgr *PublicIPManager) Associate(options api.AssociatePublicIPOptions) api.AssociatePublicIPError {
-	input, err := mgr.toAssociatedAddressInput(&options)
+
+func (mgr *PublicIPManager) Create(options api.CreatePublicIPOptions) (*api.PublicIP, api.CreatePublicIPError) {
+	return mgr.CreateWithContext(context.Background(), options)
+}
+
+//AssociateWithContext context aware version of Associate
+func (mgr *PublicIPManager) AssociateWithContext(ctx context.Context, options api.AssociatePublicIPOptions) api.AssociatePublicIPError {
+	input, err := mgr.toAssociatedAddressInput(ctx, &options)
 	if err != nil {
 		return api.NewAssociatePublicIPError(err, options)
 	}
-	_, err = mgr.Provider.AWSServices.EC2Client.AssociateAddress(input)
+	_, err = mgr.Provider.AWSServices.EC2Client.AssociateAddressWithContext(ctx, input)
 	return api.NewAssociatePublicIPError(err, options)
 }
 
-func (mgr *PublicIPManager) toAssociatedAddressInput(options *api.AssociatePublicIPOptions) (*ec2.AssociateAddressInput, error) {
+func (mgr *PublicIPManager) Associate(options api.AssociatePublicIPOptions) api.AssociatePublicIPError {
+	return mgr.AssociateWithContext(context.Background(), options)
+}
+
+func (mgr *PublicIPManager) toAssociatedAddressInput(ctx context.Context, options *api.AssociatePublicIPOptions) (*ec2.AssociateAddressInput, error) {
 	var privateIP *string
 	if len(options.PrivateIP) > 0 {
 		privateIP = aws.String(options.PrivateIP)
 	}
 	var networkInterface *string
 	if len(options.SubnetID) > 0 {
-		ni, err := mgr.getNetworkInterface(options, privateIP)
+		ni, err := mgr.getNetworkInterface(ctx, options, privateIP)
 		if err != nil {
 			return nil, err
 		}
@@ -137,8 +165,8 @@ func (mgr *PublicIPManager) toAssociatedAddressInput(options *api.AssociatePubli
 	}, nil
 }
 
-func (mgr *PublicIPManager) getNetworkInterface(options *api.AssociatePublicIPOptions, privateIP *string) (*ec2.NetworkInterface, error) {
-	out, err := mgr.Provider.AWSServices.EC2Client.DescribeNetworkInterfaces(&ec2.DescribeNetworkInterfacesInput{
+func (mgr *PublicIPManager) getNetworkInterface(ctx context.Context, options *api.AssociatePublicIPOptions, privateIP *string) (*ec2.NetworkInterface, error) {
+	out, err := mgr.Provider.AWSServices.EC2Client.DescribeNetworkInterfacesWithContext(ctx, &ec2.DescribeNetworkInterfacesInput{
 		DryRun: nil,
 		Filters: []*ec2.Filter{
 			{
@@ -154,6 +182,9 @@ func (mgr *PublicIPManager) getNetworkInterface(options *api.AssociatePublicIPOp
 	if err != nil {
 		return nil, err
 	}
+	if len(out.NetworkInterfaces) == 0 {
+		return nil, notFoundError("no network interface of server %s in subnet %s", options.ServerID, options.SubnetID)
+	}
 	networkInterface := out.NetworkInterfaces[0]
 	if privateIP != nil {
 		for _, ni := range out.NetworkInterfaces {
@@ -165,25 +196,38 @@ func (mgr *PublicIPManager) getNetworkInterface(options *api.AssociatePublicIPOp
 	}
 	return networkInterface, nil
 }
-func (mgr *PublicIPManager) Dissociate(publicIPID string) api.DissociatePublicIPError {
-	addr, err := mgr.getAddress(publicIPID)
+
+//DissociateWithContext context aware version of Dissociate
+func (mgr *PublicIPManager) DissociateWithContext(ctx context.Context, publicIPID string) api.DissociatePublicIPError {
+	addr, err := mgr.getAddress(ctx, publicIPID)
 	if err != nil {
 		return api.NewDissociatePublicIPError(err, publicIPID)
 	}
-	_, err = mgr.Provider.AWSServices.EC2Client.DisassociateAddress(&ec2.DisassociateAddressInput{
+	_, err = mgr.Provider.AWSServices.EC2Client.DisassociateAddressWithContext(ctx, &ec2.DisassociateAddressInput{
 		AssociationId: addr.AssociationId,
 	})
 	return api.NewDissociatePublicIPError(err, publicIPID)
 }
-func (mgr *PublicIPManager) Delete(publicIPId string) api.DeletePublicIPError {
-	_, err := mgr.Provider.AWSServices.EC2Client.ReleaseAddress(&ec2.ReleaseAddressInput{
+
+func (mgr *PublicIPManager) Dissociate(publicIPID string) api.DissociatePublicIPError {
+	return mgr.DissociateWithContext(context.Background(), publicIPID)
+}
+
+//DeleteWithContext context aware version of Delete
+func (mgr *PublicIPManager) DeleteWithContext(ctx context.Context, publicIPId string) api.DeletePublicIPError {
+	_, err := mgr.Provider.AWSServices.EC2Client.ReleaseAddressWithContext(ctx, &ec2.ReleaseAddressInput{
 		AllocationId: aws.String(publicIPId),
 	})
 	return api.NewDeletePublicIPError(err, publicIPId)
 }
 
-func (mgr *PublicIPManager) Get(publicIPId string) (*api.PublicIP, api.GetPublicIPError) {
-	addr, err := mgr.getAddress(publicIPId)
+func (mgr *PublicIPManager) Delete(publicIPId string) api.DeletePublicIPError {
+	return mgr.DeleteWithContext(context.Background(), publicIPId)
+}
+
+//GetWithContext context aware version of Get
+func (mgr *PublicIPManager) GetWithContext(ctx context.Context, publicIPId string) (*api.PublicIP, api.GetPublicIPError) {
+	addr, err := mgr.getAddress(ctx, publicIPId)
 	if err != nil {
 		return nil, api.NewGetPublicIPError(err, publicIPId)
 	}
@@ -192,34 +236,30 @@ func (mgr *PublicIPManager) Get(publicIPId string) (*api.PublicIP, api.GetPublic
 
 }
 
-func toPublicIP(addr *ec2.Address) (*api.PublicIP, error) {
-	var name string
-	for _, t := range addr.Tags {
-		if *t.Key == "name" {
-			name = *t.Value
-			break
-		}
-	}
-	var privateAddress string
-	if addr.PrivateIpAddress != nil {
-		privateAddress = *addr.PrivateIpAddress
-	}
+func (mgr *PublicIPManager) Get(publicIPId string) (*api.PublicIP, api.GetPublicIPError) {
+	return mgr.GetWithContext(context.Background(), publicIPId)
+}
 
+func toPublicIP(addr *ec2.Address) (*api.PublicIP, error) {
 	return &api.PublicIP{
 		ID:                 *addr.AllocationId,
-		Name:               name,
+		Name:               tagValue(addr.Tags, "name"),
 		Address:            *addr.PublicIp,
-		NetworkInterfaceID: *addr.NetworkInterfaceId,
-		PrivateAddress:     privateAddress,
+		NetworkInterfaceID: aws.StringValue(addr.NetworkInterfaceId),
+		PrivateAddress:     aws.StringValue(addr.PrivateIpAddress),
+		Tags:               userTags(addr.Tags),
 	}, nil
 }
 
-func (mgr *PublicIPManager) getAddress(publicIPId string) (*ec2.Address, error) {
-	out, err := mgr.Provider.AWSServices.EC2Client.DescribeAddresses(&ec2.DescribeAddressesInput{
+func (mgr *PublicIPManager) getAddress(ctx context.Context, publicIPId string) (*ec2.Address, error) {
+	out, err := mgr.Provider.AWSServices.EC2Client.DescribeAddressesWithContext(ctx, &ec2.DescribeAddressesInput{
 		AllocationIds: []*string{aws.String(publicIPId)},
 	})
 	if err != nil {
 		return nil, err
 	}
+	if len(out.Addresses) == 0 {
+		return nil, notFoundError("public ip %s not found", publicIPId)
+	}
 	return out.Addresses[0], nil
 }
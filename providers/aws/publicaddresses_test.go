@@ -5,10 +5,8 @@ import (
 	"github.com/SebastienDorgan/anyclouds/api"
 	"github.com/SebastienDorgan/anyclouds/providers/aws"
 	"github.com/SebastienDorgan/anyclouds/sshutils"
-	"github.com/SebastienDorgan/talgo"
 	"github.com/stretchr/testify/assert"
 	"sort"
-	"strings"
 	"testing"
 )
 
@@ -76,24 +74,13 @@ func TestPublicAddresses(t *testing.T) {
 	sort.Slice(templates, func(i, j int) bool {
 		return templates[i].OneDemandPrice < templates[j].OneDemandPrice
 	})
-	images, err := prov.GetImageManager().List()
+	img, err := prov.GetImageManager().Find(api.ImageQuery{Distribution: "ubuntu", Version: "18.04", Latest: true})
 	assert.NoError(t, err)
-	selection := talgo.FindAll(len(images), func(i int) bool {
-		name := strings.ToUpper(images[i].Name)
-		return strings.Contains(name, "UBUNTU") && strings.Contains(name, "18.04")
-	})
-	n := talgo.Select(len(selection), func(i, j int) int {
-		if images[selection[i]].UpdatedAt.After(images[selection[j]].UpdatedAt) {
-			return i
-		}
-		return j
-	})
-	assert.True(t, n < len(selection))
 
 	srv, err := prov.GetServerManager().Create(api.CreateServerOptions{
 		Name:                 "test_server",
 		TemplateID:           templates[0].ID,
-		ImageID:              images[selection[n]].ID,
+		ImageID:              img.ID,
 		DefaultSecurityGroup: sg.ID,
 		Subnets:              []api.Subnet{*subnet},
 		BootstrapScript:      nil,
@@ -0,0 +1,261 @@
+package aws
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/client/metadata"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/aws/aws-sdk-go/private/protocol/jsonrpc"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+//Service Quotas codes of the quotas used by the QuotaManager
+const (
+	ec2ServiceCode = "ec2"
+	vpcServiceCode = "vpc"
+	//standardVCPUQuotaCode running On-Demand standard instances vCPUs
+	standardVCPUQuotaCode = "L-1216C47A"
+	//elasticIPQuotaCode EC2-VPC Elastic IPs
+	elasticIPQuotaCode = "L-0263D0A3"
+	//securityGroupQuotaCode VPC security groups per region
+	securityGroupQuotaCode = "L-E79EC296"
+)
+
+//newServiceQuotasClient creates a client of the Service Quotas JSON API, the SDK does not provide one
+func newServiceQuotasClient(sess *session.Session) *client.Client {
+	c := sess.ClientConfig("servicequotas")
+	if c.SigningNameDerived || len(c.SigningName) == 0 {
+		c.SigningName = "servicequotas"
+	}
+	svc := client.New(
+		*c.Config,
+		metadata.ClientInfo{
+			ServiceName:   "servicequotas",
+			ServiceID:     "Service Quotas",
+			SigningName:   c.SigningName,
+			SigningRegion: c.SigningRegion,
+			Endpoint:      c.Endpoint,
+			APIVersion:    "2019-06-24",
+			JSONVersion:   "1.1",
+			TargetPrefix:  "ServiceQuotasV20190624",
+		},
+		c.Handlers,
+	)
+	svc.Handlers.Sign.PushBackNamed(v4.SignRequestHandler)
+	svc.Handlers.Build.PushBackNamed(jsonrpc.BuildHandler)
+	svc.Handlers.Unmarshal.PushBackNamed(jsonrpc.UnmarshalHandler)
+	svc.Handlers.UnmarshalMeta.PushBackNamed(jsonrpc.UnmarshalMetaHandler)
+	svc.Handlers.UnmarshalError.PushBackNamed(jsonrpc.UnmarshalErrorHandler)
+	svc.Handlers.UnmarshalError.PushBackNamed(unwrapErrorHandler)
+	return svc
+}
+
+type getServiceQuotaInput struct {
+	_ struct{} `type:"structure"`
+
+	ServiceCode *string `type:"string" required:"true"`
+	QuotaCode   *string `type:"string" required:"true"`
+}
+
+type serviceQuota struct {
+	_ struct{} `type:"structure"`
+
+	QuotaCode *string  `type:"string"`
+	Value     *float64 `type:"double"`
+}
+
+type getServiceQuotaOutput struct {
+	_ struct{} `type:"structure"`
+
+	Quota *serviceQuota `type:"structure"`
+}
+
+//QuotaManager aws implementation of api.QuotaManager
+//Maximums come from the EC2 account attributes and from Service Quotas, RAM is not limited by AWS
+type QuotaManager struct {
+	Provider *Provider
+}
+
+//getServiceQuota returns the value of the quota identified by quotaCode of the service identified by serviceCode
+func (mgr *QuotaManager) getServiceQuota(ctx context.Context, serviceCode, quotaCode string) (int, error) {
+	out := &getServiceQuotaOutput{}
+	req := mgr.Provider.AWSServices.ServiceQuotasClient.NewRequest(&request.Operation{
+		Name:       "GetServiceQuota",
+		HTTPMethod: "POST",
+		HTTPPath:   "/",
+	}, &getServiceQuotaInput{
+		ServiceCode: aws.String(serviceCode),
+		QuotaCode:   aws.String(quotaCode),
+	}, out)
+	req.SetContext(ctx)
+	err := req.Send()
+	if err != nil {
+		return api.UnknownLimit, err
+	}
+	if out.Quota == nil || out.Quota.Value == nil {
+		return api.UnknownLimit, nil
+	}
+	return int(*out.Quota.Value), nil
+}
+
+//accountAttributes returns the values of the EC2 account attributes
+func (mgr *QuotaManager) accountAttributes(ctx context.Context) (map[string]int, error) {
+	out, err := mgr.Provider.AWSServices.EC2Client.DescribeAccountAttributesWithContext(ctx, &ec2.DescribeAccountAttributesInput{
+		AttributeNames: aws.StringSlice([]string{"max-instances", "vpc-max-elastic-ips"}),
+	})
+	if err != nil {
+		return nil, err
+	}
+	res := map[string]int{}
+	for _, a := range out.AccountAttributes {
+		if len(a.AttributeValues) == 0 {
+			continue
+		}
+		v, err := strconv.Atoi(aws.StringValue(a.AttributeValues[0].AttributeValue))
+		if err == nil {
+			res[aws.StringValue(a.AttributeName)] = v
+		}
+	}
+	return res, nil
+}
+
+//quotasUnavailable returns true if err means that Service Quotas cannot be used by the account or in the region
+func quotasUnavailable(err error) bool {
+	kind := api.ErrorKind(err)
+	return kind == api.ErrUnauthorized || kind == api.ErrNotFound
+}
+
+//maxLimits sets the maximums of limits
+//The maximums read from Service Quotas are left unknown if it is not available, except the number of public ip addresses which falls back to the vpc-max-elastic-ips account attribute
+//The other errors returned by Service Quotas are returned
+func (mgr *QuotaManager) maxLimits(ctx context.Context, limits *api.Limits) error {
+	attributes, err := mgr.accountAttributes(ctx)
+	if err != nil {
+		return err
+	}
+	if v, ok := attributes["max-instances"]; ok {
+		limits.Instances.Max = v
+	}
+	limits.Cores.Max, err = mgr.getServiceQuota(ctx, ec2ServiceCode, standardVCPUQuotaCode)
+	if quotasUnavailable(err) {
+		limits.Cores.Max = api.UnknownLimit
+		if v, ok := attributes["vpc-max-elastic-ips"]; ok {
+			limits.PublicIPs.Max = v
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	limits.PublicIPs.Max, err = mgr.getServiceQuota(ctx, ec2ServiceCode, elasticIPQuotaCode)
+	if err != nil {
+		return err
+	}
+	limits.SecurityGroups.Max, err = mgr.getServiceQuota(ctx, vpcServiceCode, securityGroupQuotaCode)
+	return err
+}
+
+//standardFamily returns true if instanceType belongs to the standard instance families (A, C, D, H, I, M, R, T and Z) whose vCPUs
+//are limited by the standardVCPUQuotaCode quota, the other families have their own quotas
+func standardFamily(instanceType string) bool {
+	for _, prefix := range []string{"dl", "hpc", "inf", "mac", "trn", "u-", "vt"} {
+		if strings.HasPrefix(instanceType, prefix) {
+			return false
+		}
+	}
+	return instanceType != "" && strings.ContainsRune("acdhimrtz", rune(instanceType[0]))
+}
+
+//usedLimits sets the current usage of limits
+//The cores used are the vCPUs of the On-Demand instances of the standard families, the instances limited by the Cores maximum
+func (mgr *QuotaManager) usedLimits(ctx context.Context, limits *api.Limits) error {
+	client := mgr.Provider.AWSServices.EC2Client
+	limits.Instances.Used, limits.Cores.Used = 0, 0
+	err := client.DescribeInstancesPagesWithContext(ctx, &ec2.DescribeInstancesInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("instance-state-name"),
+				Values: aws.StringSlice([]string{ec2.InstanceStateNamePending, ec2.InstanceStateNameRunning}),
+			},
+		},
+	}, func(out *ec2.DescribeInstancesOutput, last bool) bool {
+		for _, r := range out.Reservations {
+			for _, inst := range r.Instances {
+				limits.Instances.Used++
+				spot := aws.StringValue(inst.InstanceLifecycle) == ec2.InstanceLifecycleTypeSpot
+				if inst.CpuOptions != nil && !spot && standardFamily(aws.StringValue(inst.InstanceType)) {
+					limits.Cores.Used += int(aws.Int64Value(inst.CpuOptions.CoreCount) * aws.Int64Value(inst.CpuOptions.ThreadsPerCore))
+				}
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	limits.Volumes.Used, limits.StorageSize.Used = 0, 0
+	err = client.DescribeVolumesPagesWithContext(ctx, &ec2.DescribeVolumesInput{}, func(out *ec2.DescribeVolumesOutput, last bool) bool {
+		for _, v := range out.Volumes {
+			limits.Volumes.Used++
+			limits.StorageSize.Used += int(aws.Int64Value(v.Size))
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	addresses, err := client.DescribeAddressesWithContext(ctx, &ec2.DescribeAddressesInput{})
+	if err != nil {
+		return err
+	}
+	limits.PublicIPs.Used = len(addresses.Addresses)
+	groups, err := client.DescribeSecurityGroupsWithContext(ctx, &ec2.DescribeSecurityGroupsInput{})
+	if err != nil {
+		return err
+	}
+	limits.SecurityGroups.Used = len(groups.SecurityGroups)
+	return nil
+}
+
+func (mgr *QuotaManager) getLimits(ctx context.Context) (*api.Limits, error) {
+	unknown := api.Limit{Max: api.UnknownLimit, Used: api.UnknownLimit}
+	limits := &api.Limits{
+		Instances:      unknown,
+		Cores:          unknown,
+		RAMSize:        unknown,
+		Volumes:        unknown,
+		StorageSize:    unknown,
+		PublicIPs:      unknown,
+		SecurityGroups: unknown,
+	}
+	err := mgr.maxLimits(ctx, limits)
+	if err != nil {
+		return nil, err
+	}
+	err = mgr.usedLimits(ctx, limits)
+	if err != nil {
+		return nil, err
+	}
+	return limits, nil
+}
+
+//GetLimitsWithContext returns the limits and the current usage of the account in the region of the provider
+func (mgr *QuotaManager) GetLimitsWithContext(ctx context.Context) (*api.Limits, api.GetLimitsError) {
+	limits, err := mgr.getLimits(ctx)
+	if err != nil {
+		return nil, api.NewGetLimitsError(err)
+	}
+	return limits, nil
+}
+
+//GetLimits returns the limits and the current usage of the account in the region of the provider
+func (mgr *QuotaManager) GetLimits() (*api.Limits, api.GetLimitsError) {
+	return mgr.GetLimitsWithContext(context.Background())
+}
@@ -0,0 +1,30 @@
+package aws_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/SebastienDorgan/anyclouds/tests"
+	"github.com/stretchr/testify/suite"
+)
+
+type AWSQuotaManagerTestSuite struct {
+	tests.QuotaManagerTestSuite
+}
+
+//SetupSuite set up quota manager
+func (suite *AWSQuotaManagerTestSuite) SetupSuite() {
+	suite.Prov = GetProvider()
+}
+
+func TestAWSQuotaManagerTestSuite(t *testing.T) {
+	suite.Run(t, new(AWSQuotaManagerTestSuite))
+}
+
+//TestCancelled checks that limits are not reported as unknown when the context is cancelled
+func (suite *AWSQuotaManagerTestSuite) TestCancelled() {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := suite.Prov.GetQuotaManager().GetLimitsWithContext(ctx)
+	suite.Error(err)
+}
@@ -2,14 +2,15 @@ package aws
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/gob"
 	"fmt"
-	"github.com/SebastienDorgan/retry"
 	"net"
 	"time"
 
 	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/SebastienDorgan/anyclouds/providers"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/pkg/errors"
@@ -58,7 +59,7 @@ func group(g *ec2.SecurityGroup) *api.SecurityGroup {
 			},
 			Protocol:    api.Protocol(*pi.IpProtocol),
 			CIDR:        *pi.IpRanges[0].CidrIp,
-			Description: *pi.IpRanges[0].Description,
+			Description: aws.StringValue(pi.IpRanges[0].Description),
 		}
 		r.ID = rid(r)
 		rules = append(rules, r)
@@ -68,24 +69,28 @@ func group(g *ec2.SecurityGroup) *api.SecurityGroup {
 		Name:      *g.GroupName,
 		NetworkID: *g.VpcId,
 		Rules:     rules,
+		Tags:      userTags(g.Tags),
 	}
 }
 
-func (mgr *SecurityGroupManager) getGroup(id string) retry.Action {
-	return func() (v interface{}, e error) {
-		return mgr.Get(id)
-	}
-}
-
-func noError() retry.Condition {
-	return func(v interface{}, e error) bool {
-		return e == nil
-	}
+//waitVisible waits until the security group identified by id can be read or ctx is done, the group is not immediately visible
+//after its creation, errors other than api.ErrNotFound are returned immediately
+func (mgr *SecurityGroupManager) waitVisible(ctx context.Context, id string) (*api.SecurityGroup, error) {
+	var sg *api.SecurityGroup
+	err := providers.Poll(ctx, time.Minute, func(ctx context.Context) (bool, error) {
+		var err error
+		sg, err = mgr.GetWithContext(ctx, id)
+		if api.ErrorKind(err) == api.ErrNotFound {
+			return false, nil
+		}
+		return err == nil, err
+	})
+	return sg, err
 }
 
-//Create creates an security group
-func (mgr *SecurityGroupManager) Create(options api.SecurityGroupOptions) (*api.SecurityGroup, api.CreateSecurityGroupError) {
-	out, err := mgr.Provider.AWSServices.EC2Client.CreateSecurityGroup(&ec2.CreateSecurityGroupInput{
+//CreateWithContext creates an security group
+func (mgr *SecurityGroupManager) CreateWithContext(ctx context.Context, options api.SecurityGroupOptions) (*api.SecurityGroup, api.CreateSecurityGroupError) {
+	out, err := mgr.Provider.AWSServices.EC2Client.CreateSecurityGroupWithContext(ctx, &ec2.CreateSecurityGroupInput{
 		Description: aws.String(options.Description),
 		GroupName:   aws.String(options.Name),
 		VpcId:       aws.String(options.NetworkID),
@@ -93,21 +98,41 @@ func (mgr *SecurityGroupManager) Create(options api.SecurityGroupOptions) (*api.
 	if err != nil {
 		return nil, api.NewCreateSecurityGroupError(err, options)
 	}
-	res := retry.With(mgr.getGroup(*out.GroupId)).Every(10 * time.Second).For(1 * time.Minute).Until(noError()).Go()
-	if res.LastError != nil {
-		return nil, api.NewCreateSecurityGroupError(err, options)
+	err = mgr.Provider.AddTags(ctx, *out.GroupId, resourceTags(options.Name, options.Tags))
+	if err != nil {
+		err2 := providers.Cleanup(func(ctx context.Context) error {
+			return mgr.DeleteWithContext(ctx, *out.GroupId)
+		})
+		return nil, api.NewCreateSecurityGroupError(api.NewErrorStackFromError(err, err2), options)
+	}
+	sg, err := mgr.waitVisible(ctx, *out.GroupId)
+	if err != nil {
+		err2 := providers.Cleanup(func(ctx context.Context) error {
+			return mgr.DeleteWithContext(ctx, *out.GroupId)
+		})
+		return nil, api.NewCreateSecurityGroupError(api.NewErrorStackFromError(err, err2), options)
 	}
-	return res.LastValue.(*api.SecurityGroup), nil
+	return sg, nil
 }
 
-//Delete deletes the security group identified by id
-func (mgr *SecurityGroupManager) Delete(id string) api.DeleteSecurityGroupError {
-	_, err := mgr.Provider.AWSServices.EC2Client.DeleteSecurityGroup(&ec2.DeleteSecurityGroupInput{
+//Create creates an security group
+func (mgr *SecurityGroupManager) Create(options api.SecurityGroupOptions) (*api.SecurityGroup, api.CreateSecurityGroupError) {
+	return mgr.CreateWithContext(context.Background(), options)
+}
+
+//DeleteWithContext deletes the security group identified by id
+func (mgr *SecurityGroupManager) DeleteWithContext(ctx context.Context, id string) api.DeleteSecurityGroupError {
+	_, err := mgr.Provider.AWSServices.EC2Client.DeleteSecurityGroupWithContext(ctx, &ec2.DeleteSecurityGroupInput{
 		GroupId: aws.String(id),
 	})
 	return api.NewDeleteSecurityGroupError(err, id)
 }
 
+//Delete deletes the security group identified by id
+func (mgr *SecurityGroupManager) Delete(id string) api.DeleteSecurityGroupError {
+	return mgr.DeleteWithContext(context.Background(), id)
+}
+
 func groups(in []*ec2.SecurityGroup) []api.SecurityGroup {
 	var result []api.SecurityGroup
 	for _, g := range in {
@@ -116,18 +141,23 @@ func groups(in []*ec2.SecurityGroup) []api.SecurityGroup {
 	return result
 }
 
-//List list all security groups defined in the tenant
-func (mgr *SecurityGroupManager) List() ([]api.SecurityGroup, api.ListSecurityGroupsError) {
-	out, err := mgr.Provider.AWSServices.EC2Client.DescribeSecurityGroups(nil)
+//ListWithContext list all security groups defined in the tenant
+func (mgr *SecurityGroupManager) ListWithContext(ctx context.Context) ([]api.SecurityGroup, api.ListSecurityGroupsError) {
+	out, err := mgr.Provider.AWSServices.EC2Client.DescribeSecurityGroupsWithContext(ctx, nil)
 	if err != nil {
 		return nil, api.NewListSecurityGroupsError(err)
 	}
 	return groups(out.SecurityGroups), nil
 }
 
-//Get returns the security group identified by id
-func (mgr *SecurityGroupManager) Get(id string) (*api.SecurityGroup, api.GetSecurityGroupError) {
-	out, err := mgr.Provider.AWSServices.EC2Client.DescribeSecurityGroups(&ec2.DescribeSecurityGroupsInput{
+//List list all security groups defined in the tenant
+func (mgr *SecurityGroupManager) List() ([]api.SecurityGroup, api.ListSecurityGroupsError) {
+	return mgr.ListWithContext(context.Background())
+}
+
+//GetWithContext returns the security group identified by id
+func (mgr *SecurityGroupManager) GetWithContext(ctx context.Context, id string) (*api.SecurityGroup, api.GetSecurityGroupError) {
+	out, err := mgr.Provider.AWSServices.EC2Client.DescribeSecurityGroupsWithContext(ctx, &ec2.DescribeSecurityGroupsInput{
 		GroupIds: []*string{
 			aws.String(id),
 		},
@@ -136,7 +166,7 @@ func (mgr *SecurityGroupManager) Get(id string) (*api.SecurityGroup, api.GetSecu
 		return nil, api.NewGetSecurityGroupError(err, id)
 	}
 	if len(out.SecurityGroups) == 0 {
-		err = fmt.Errorf("security group %s not found", id)
+		err = notFoundError("security group %s not found", id)
 		return nil, api.NewGetSecurityGroupError(err, id)
 	}
 	if len(out.SecurityGroups) > 1 {
@@ -146,9 +176,14 @@ func (mgr *SecurityGroupManager) Get(id string) (*api.SecurityGroup, api.GetSecu
 	return group(out.SecurityGroups[0]), nil
 }
 
-//Attach a server to a security group
-func (mgr *SecurityGroupManager) Attach(options api.AttachSecurityGroupOptions) api.AttachSecurityGroupError {
-	out, err := mgr.Provider.AWSServices.EC2Client.DescribeNetworkInterfaces(&ec2.DescribeNetworkInterfacesInput{
+//Get returns the security group identified by id
+func (mgr *SecurityGroupManager) Get(id string) (*api.SecurityGroup, api.GetSecurityGroupError) {
+	return mgr.GetWithContext(context.Background(), id)
+}
+
+//AttachWithContext a server to a security group
+func (mgr *SecurityGroupManager) AttachWithContext(ctx context.Context, options api.AttachSecurityGroupOptions) api.AttachSecurityGroupError {
+	out, err := mgr.Provider.AWSServices.EC2Client.DescribeNetworkInterfacesWithContext(ctx, &ec2.DescribeNetworkInterfacesInput{
 		Filters: []*ec2.Filter{
 			{
 				Name:   aws.String("attachment.instance-id"),
@@ -175,7 +210,7 @@ func (mgr *SecurityGroupManager) Attach(options api.AttachSecurityGroupOptions)
 		if options.IPAddress != nil && *ni.PrivateIpAddress != *options.IPAddress {
 			continue
 		}
-		_, err = mgr.Provider.AWSServices.EC2Client.ModifyNetworkInterfaceAttribute(&ec2.ModifyNetworkInterfaceAttributeInput{
+		_, err = mgr.Provider.AWSServices.EC2Client.ModifyNetworkInterfaceAttributeWithContext(ctx, &ec2.ModifyNetworkInterfaceAttributeInput{
 			Groups:             []*string{aws.String(options.SecurityGroupID)},
 			NetworkInterfaceId: ni.NetworkInterfaceId,
 		})
@@ -186,6 +221,11 @@ func (mgr *SecurityGroupManager) Attach(options api.AttachSecurityGroupOptions)
 	return nil
 }
 
+//Attach a server to a security group
+func (mgr *SecurityGroupManager) Attach(options api.AttachSecurityGroupOptions) api.AttachSecurityGroupError {
+	return mgr.AttachWithContext(context.Background(), options)
+}
+
 func isV6(CIDR string) (bool, error) {
 	if len(CIDR) == 0 {
 		return false, nil
@@ -285,15 +325,15 @@ func ipPermissionFromRule(r *api.SecurityRule) (*ec2.IpPermission, error) {
 	return p, nil
 }
 
-//AddSecurityRule adds a security rule to an security group
-func (mgr *SecurityGroupManager) AddSecurityRule(options api.AddSecurityRuleOptions) (*api.SecurityRule, api.AddSecurityRuleError) {
+//AddSecurityRuleWithContext adds a security rule to an security group
+func (mgr *SecurityGroupManager) AddSecurityRuleWithContext(ctx context.Context, options api.AddSecurityRuleOptions) (*api.SecurityRule, api.AddSecurityRuleError) {
 	p, err := ipPermission(&options)
 	if err != nil {
 		return nil, api.NewAddSecurityRuleError(err, options)
 	}
 
 	if options.Direction == api.RuleDirectionIngress {
-		_, err = mgr.Provider.AWSServices.EC2Client.AuthorizeSecurityGroupIngress(&ec2.AuthorizeSecurityGroupIngressInput{
+		_, err = mgr.Provider.AWSServices.EC2Client.AuthorizeSecurityGroupIngressWithContext(ctx, &ec2.AuthorizeSecurityGroupIngressInput{
 			GroupId: &options.SecurityGroupID,
 			IpPermissions: []*ec2.IpPermission{
 				p,
@@ -304,7 +344,7 @@ func (mgr *SecurityGroupManager) AddSecurityRule(options api.AddSecurityRuleOpti
 		}
 
 	} else if options.Direction == api.RuleDirectionEgress {
-		_, err = mgr.Provider.AWSServices.EC2Client.AuthorizeSecurityGroupEgress(&ec2.AuthorizeSecurityGroupEgressInput{
+		_, err = mgr.Provider.AWSServices.EC2Client.AuthorizeSecurityGroupEgressWithContext(ctx, &ec2.AuthorizeSecurityGroupEgressInput{
 			GroupId: &options.SecurityGroupID,
 			IpPermissions: []*ec2.IpPermission{
 				p,
@@ -325,15 +365,20 @@ func (mgr *SecurityGroupManager) AddSecurityRule(options api.AddSecurityRuleOpti
 	return &rule, nil
 }
 
-//RemoveSecurityRule deletes a security rule from an security group
-func (mgr *SecurityGroupManager) RemoveSecurityRule(groupID, ruleID string) api.RemoveSecurityRuleError {
+//AddSecurityRule adds a security rule to an security group
+func (mgr *SecurityGroupManager) AddSecurityRule(options api.AddSecurityRuleOptions) (*api.SecurityRule, api.AddSecurityRuleError) {
+	return mgr.AddSecurityRuleWithContext(context.Background(), options)
+}
+
+//RemoveSecurityRuleWithContext deletes a security rule from an security group
+func (mgr *SecurityGroupManager) RemoveSecurityRuleWithContext(ctx context.Context, groupID, ruleID string) api.RemoveSecurityRuleError {
 	rule := idr(ruleID)
 	ipPerm, err := ipPermissionFromRule(rule)
 	if err != nil {
 		return api.NewRemoveSecurityRuleError(err, groupID, ruleID)
 	}
 	if rule.Direction == api.RuleDirectionIngress {
-		_, err := mgr.Provider.AWSServices.EC2Client.RevokeSecurityGroupIngress(&ec2.RevokeSecurityGroupIngressInput{
+		_, err := mgr.Provider.AWSServices.EC2Client.RevokeSecurityGroupIngressWithContext(ctx, &ec2.RevokeSecurityGroupIngressInput{
 			GroupId: &rule.SecurityGroupID,
 			IpPermissions: []*ec2.IpPermission{
 				ipPerm,
@@ -344,7 +389,7 @@ func (mgr *SecurityGroupManager) RemoveSecurityRule(groupID, ruleID string) api.
 		}
 	}
 	if rule.Direction == api.RuleDirectionEgress {
-		_, err := mgr.Provider.AWSServices.EC2Client.RevokeSecurityGroupEgress(&ec2.RevokeSecurityGroupEgressInput{
+		_, err := mgr.Provider.AWSServices.EC2Client.RevokeSecurityGroupEgressWithContext(ctx, &ec2.RevokeSecurityGroupEgressInput{
 			GroupId: &rule.SecurityGroupID,
 			IpPermissions: []*ec2.IpPermission{
 				ipPerm,
@@ -357,3 +402,8 @@ func (mgr *SecurityGroupManager) RemoveSecurityRule(groupID, ruleID string) api.
 	return nil
 
 }
+
+//RemoveSecurityRule deletes a security rule from an security group
+func (mgr *SecurityGroupManager) RemoveSecurityRule(groupID, ruleID string) api.RemoveSecurityRuleError {
+	return mgr.RemoveSecurityRuleWithContext(context.Background(), groupID, ruleID)
+}
@@ -1,9 +1,13 @@
 package aws_test
 
 import (
+	"errors"
 	"testing"
 
+	"github.com/SebastienDorgan/anyclouds/api"
 	"github.com/SebastienDorgan/anyclouds/tests"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -16,6 +20,44 @@ func (suite *AWSSecurityGroupManagerTestSuite) SetupSuite() {
 	suite.Prov = GetProvider()
 }
 
+//TestCreateUnauthorized checks that an error reading the security group after its creation is returned without waiting for
+//the group to be visible and that the group is deleted
+func (suite *AWSSecurityGroupManagerTestSuite) TestCreateUnauthorized() {
+	if !IsFake() {
+		suite.T().Skip("the error is injected by a handler of the fake EC2 client")
+	}
+	prov := GetProvider()
+	net, err := prov.GetNetworkManager().CreateNetwork(api.CreateNetworkOptions{
+		CIDR: "10.0.0.0/16",
+		Name: "unauthorized_network",
+	})
+	suite.NoError(err)
+
+	failed := false
+	prov.AWSServices.EC2Client.Handlers.Validate.PushBack(func(r *request.Request) {
+		if r.Operation.Name == "DescribeSecurityGroups" && !failed {
+			failed = true
+			r.Error = api.WithKind(awserr.New("UnauthorizedOperation", "not authorized", nil), api.ErrUnauthorized)
+		}
+	})
+	mgr := prov.GetSecurityGroupManager()
+	_, err = mgr.Create(api.SecurityGroupOptions{
+		Name:        "unauthorized_group",
+		Description: "unauthorized group",
+		NetworkID:   net.ID,
+	})
+	suite.Error(err)
+	suite.True(errors.Is(err, api.ErrUnauthorized))
+	groups, err := mgr.List()
+	suite.NoError(err)
+	for _, g := range groups {
+		suite.NotEqual("unauthorized_group", g.Name)
+	}
+
+	err = prov.GetNetworkManager().DeleteNetwork(net.ID)
+	suite.NoError(err)
+}
+
 func TestAWSSecurityGroupManagerTestSuite(t *testing.T) {
 	suite.Run(t, new(AWSSecurityGroupManagerTestSuite))
 }
@@ -0,0 +1,386 @@
+package aws
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"sort"
+	"time"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/SebastienDorgan/anyclouds/providers"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	pkgerrors "github.com/pkg/errors"
+)
+
+//ServerGroupManager aws implementation of api.ServerGroupManager based on Auto Scaling groups
+//Each group is an Auto Scaling group launching the instances of a launch template of the same name, the group ID is its name
+type ServerGroupManager struct {
+	Provider *Provider
+}
+
+const (
+	//groupPollInterval interval between two checks of the instances of a group
+	groupPollInterval = 15 * time.Second
+	//groupTimeout maximum time waited for the instances of a group
+	groupTimeout = 10 * time.Minute
+)
+
+//groupKeyName returns the name of the key pair imported for a group created with an inline key
+func groupKeyName(name string) string {
+	return name + "-group-key"
+}
+
+func serverGroup(g *autoscaling.Group) *api.ServerGroup {
+	var tags []*ec2.Tag
+	for _, t := range g.Tags {
+		tags = append(tags, &ec2.Tag{Key: t.Key, Value: t.Value})
+	}
+	res := &api.ServerGroup{
+		ID:          aws.StringValue(g.AutoScalingGroupName),
+		Name:        aws.StringValue(g.AutoScalingGroupName),
+		MinSize:     int(aws.Int64Value(g.MinSize)),
+		MaxSize:     int(aws.Int64Value(g.MaxSize)),
+		DesiredSize: int(aws.Int64Value(g.DesiredCapacity)),
+		Tags:        userTags(tags),
+	}
+	for _, inst := range g.Instances {
+		if aws.StringValue(inst.LifecycleState) == autoscaling.LifecycleStateInService {
+			res.ServerIDs = append(res.ServerIDs, aws.StringValue(inst.InstanceId))
+		}
+	}
+	sort.Strings(res.ServerIDs)
+	return res
+}
+
+//ready returns true if all the instances of the group are in service and the group has DesiredCapacity instances
+func ready(g *autoscaling.Group, excluded string) bool {
+	if int64(len(g.Instances)) != aws.Int64Value(g.DesiredCapacity) {
+		return false
+	}
+	for _, inst := range g.Instances {
+		if aws.StringValue(inst.LifecycleState) != autoscaling.LifecycleStateInService || aws.StringValue(inst.InstanceId) == excluded {
+			return false
+		}
+	}
+	return true
+}
+
+func (mgr *ServerGroupManager) describe(ctx context.Context, name string) (*autoscaling.Group, error) {
+	out, err := mgr.Provider.AWSServices.AutoScalingClient.DescribeAutoScalingGroupsWithContext(ctx, &autoscaling.DescribeAutoScalingGroupsInput{
+		AutoScalingGroupNames: []*string{aws.String(name)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(out.AutoScalingGroups) == 0 {
+		return nil, notFoundError("server group %s not found", name)
+	}
+	return out.AutoScalingGroups[0], nil
+}
+
+//wait waits for the group to have DesiredCapacity instances in service, none of them being the excluded instance
+func (mgr *ServerGroupManager) wait(ctx context.Context, name string, excluded string) (*api.ServerGroup, error) {
+	ctx, cancel := context.WithTimeout(ctx, groupTimeout)
+	defer cancel()
+	for {
+		g, err := mgr.describe(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if ready(g, excluded) {
+			return serverGroup(g), nil
+		}
+		select {
+		case <-time.After(groupPollInterval):
+		case <-ctx.Done():
+			return nil, pkgerrors.Wrapf(ctx.Err(), "servers of group %s do not reach ready state", name)
+		}
+	}
+}
+
+func autoScalingTags(name string, tags map[string]string) []*autoscaling.Tag {
+	//only the name tag is propagated to the instances so that they are named after the group
+	res := []*autoscaling.Tag{{
+		Key:               aws.String("name"),
+		Value:             aws.String(name),
+		PropagateAtLaunch: aws.Bool(true),
+	}}
+	for k, v := range tags {
+		res = append(res, &autoscaling.Tag{
+			Key:               aws.String(k),
+			Value:             aws.String(v),
+			PropagateAtLaunch: aws.Bool(false),
+		})
+	}
+	return res
+}
+
+func (mgr *ServerGroupManager) launchTemplateData(options *api.CreateServerGroupOptions, keyName string) (*ec2.RequestLaunchTemplateData, error) {
+	spec := &options.Spec
+	data := &ec2.RequestLaunchTemplateData{
+		ImageId:      aws.String(spec.ImageID),
+		InstanceType: aws.String(spec.TemplateID),
+		KeyName:      aws.String(keyName),
+	}
+	if spec.BootstrapScript != nil {
+		b, err := ioutil.ReadAll(spec.BootstrapScript)
+		if err != nil {
+			return nil, err
+		}
+		data.UserData = aws.String(base64.StdEncoding.EncodeToString(b))
+	}
+	if spec.DefaultSecurityGroup != "" {
+		data.SecurityGroupIds = []*string{aws.String(spec.DefaultSecurityGroup)}
+	}
+	if len(spec.Tags) > 0 {
+		data.TagSpecifications = []*ec2.LaunchTemplateTagSpecificationRequest{{
+			ResourceType: aws.String(ec2.ResourceTypeInstance),
+			Tags:         createAWSTags(spec.Tags),
+		}}
+	}
+	return data, nil
+}
+
+//release deletes the launch template and the key pair imported for the group if any
+func (mgr *ServerGroupManager) release(ctx context.Context, name string) error {
+	_, err := mgr.Provider.AWSServices.EC2Client.DeleteLaunchTemplateWithContext(ctx, &ec2.DeleteLaunchTemplateInput{
+		LaunchTemplateName: aws.String(name),
+	})
+	if err != nil && !errors.Is(err, api.ErrNotFound) {
+		return err
+	}
+	err = mgr.Provider.KeyPairManager.DeleteWithContext(ctx, groupKeyName(name))
+	if err != nil && !errors.Is(err, api.ErrNotFound) {
+		return err
+	}
+	return nil
+}
+
+func (mgr *ServerGroupManager) create(ctx context.Context, options api.CreateServerGroupOptions) (*api.ServerGroup, error) {
+	err := api.CheckServerGroupOptions(&options)
+	if err != nil {
+		return nil, err
+	}
+	name := options.Name
+	_, err = mgr.describe(ctx, name)
+	if err == nil {
+		return nil, alreadyExistsError("server group %s already exists", name)
+	}
+	if !errors.Is(err, api.ErrNotFound) {
+		return nil, err
+	}
+	keyName := options.Spec.KeyPairName
+	if keyName == "" {
+		//the inline key is imported for the lifetime of the group since instances are launched on demand
+		keyName = groupKeyName(name)
+		_, err = mgr.Provider.KeyPairManager.ImportWithContext(ctx, keyName, options.Spec.KeyPair.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+	g, err := mgr.createGroup(ctx, &options, keyName)
+	if err != nil {
+		err2 := providers.Cleanup(func(ctx context.Context) error {
+			err := mgr.delete(ctx, name)
+			if errors.Is(err, api.ErrNotFound) {
+				return mgr.release(ctx, name)
+			}
+			return err
+		})
+		return nil, api.NewErrorStackFromError(err, err2)
+	}
+	return g, nil
+}
+
+func (mgr *ServerGroupManager) createGroup(ctx context.Context, options *api.CreateServerGroupOptions, keyName string) (*api.ServerGroup, error) {
+	data, err := mgr.launchTemplateData(options, keyName)
+	if err != nil {
+		return nil, err
+	}
+	_, err = mgr.Provider.AWSServices.EC2Client.CreateLaunchTemplateWithContext(ctx, &ec2.CreateLaunchTemplateInput{
+		LaunchTemplateName: aws.String(options.Name),
+		LaunchTemplateData: data,
+	})
+	if err != nil {
+		return nil, err
+	}
+	_, err = mgr.Provider.AWSServices.AutoScalingClient.CreateAutoScalingGroupWithContext(ctx, &autoscaling.CreateAutoScalingGroupInput{
+		AutoScalingGroupName: aws.String(options.Name),
+		LaunchTemplate: &autoscaling.LaunchTemplateSpecification{
+			LaunchTemplateName: aws.String(options.Name),
+			Version:            aws.String("$Latest"),
+		},
+		MinSize:           aws.Int64(int64(options.Size.MinSize)),
+		MaxSize:           aws.Int64(int64(options.Size.MaxSize)),
+		DesiredCapacity:   aws.Int64(int64(options.Size.DesiredSize)),
+		VPCZoneIdentifier: aws.String(options.Spec.Subnets[0].ID),
+		Tags:              autoScalingTags(options.Name, options.Tags),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return mgr.wait(ctx, options.Name, "")
+}
+
+//CreateWithContext creates a server group
+func (mgr *ServerGroupManager) CreateWithContext(ctx context.Context, options api.CreateServerGroupOptions) (*api.ServerGroup, api.CreateServerGroupError) {
+	g, err := mgr.create(ctx, options)
+	if err != nil {
+		return nil, api.NewCreateServerGroupError(err, options)
+	}
+	return g, nil
+}
+
+//Create creates a server group
+func (mgr *ServerGroupManager) Create(options api.CreateServerGroupOptions) (*api.ServerGroup, api.CreateServerGroupError) {
+	return mgr.CreateWithContext(context.Background(), options)
+}
+
+func (mgr *ServerGroupManager) delete(ctx context.Context, id string) error {
+	_, err := mgr.describe(ctx, id)
+	if err != nil {
+		return err
+	}
+	_, err = mgr.Provider.AWSServices.AutoScalingClient.DeleteAutoScalingGroupWithContext(ctx, &autoscaling.DeleteAutoScalingGroupInput{
+		AutoScalingGroupName: aws.String(id),
+		ForceDelete:          aws.Bool(true),
+	})
+	if err != nil {
+		return err
+	}
+	err = mgr.Provider.AWSServices.AutoScalingClient.WaitUntilGroupNotExistsWithContext(ctx, &autoscaling.DescribeAutoScalingGroupsInput{
+		AutoScalingGroupNames: []*string{aws.String(id)},
+	})
+	if err != nil {
+		return err
+	}
+	return mgr.release(ctx, id)
+}
+
+//DeleteWithContext deletes the server group identified by id, its servers, its launch template and its key pair
+func (mgr *ServerGroupManager) DeleteWithContext(ctx context.Context, id string) api.DeleteServerGroupError {
+	return api.NewDeleteServerGroupError(mgr.delete(ctx, id), id)
+}
+
+//Delete deletes the server group identified by id, its servers, its launch template and its key pair
+func (mgr *ServerGroupManager) Delete(id string) api.DeleteServerGroupError {
+	return mgr.DeleteWithContext(context.Background(), id)
+}
+
+func (mgr *ServerGroupManager) list(ctx context.Context) ([]api.ServerGroup, error) {
+	groups := []api.ServerGroup{}
+	err := mgr.Provider.AWSServices.AutoScalingClient.DescribeAutoScalingGroupsPagesWithContext(ctx, &autoscaling.DescribeAutoScalingGroupsInput{},
+		func(out *autoscaling.DescribeAutoScalingGroupsOutput, last bool) bool {
+			for _, g := range out.AutoScalingGroups {
+				groups = append(groups, *serverGroup(g))
+			}
+			return true
+		})
+	if err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+//ListWithContext lists the server groups
+func (mgr *ServerGroupManager) ListWithContext(ctx context.Context) ([]api.ServerGroup, api.ListServerGroupsError) {
+	groups, err := mgr.list(ctx)
+	if err != nil {
+		return nil, api.NewListServerGroupsError(err)
+	}
+	return groups, nil
+}
+
+//List lists the server groups
+func (mgr *ServerGroupManager) List() ([]api.ServerGroup, api.ListServerGroupsError) {
+	return mgr.ListWithContext(context.Background())
+}
+
+//GetWithContext returns the server group identified by id
+func (mgr *ServerGroupManager) GetWithContext(ctx context.Context, id string) (*api.ServerGroup, api.GetServerGroupError) {
+	g, err := mgr.describe(ctx, id)
+	if err != nil {
+		return nil, api.NewGetServerGroupError(err, id)
+	}
+	return serverGroup(g), nil
+}
+
+//Get returns the server group identified by id
+func (mgr *ServerGroupManager) Get(id string) (*api.ServerGroup, api.GetServerGroupError) {
+	return mgr.GetWithContext(context.Background(), id)
+}
+
+func (mgr *ServerGroupManager) scale(ctx context.Context, id string, size api.ServerGroupSize) (*api.ServerGroup, error) {
+	err := api.CheckServerGroupSize(size)
+	if err != nil {
+		return nil, err
+	}
+	_, err = mgr.describe(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	_, err = mgr.Provider.AWSServices.AutoScalingClient.UpdateAutoScalingGroupWithContext(ctx, &autoscaling.UpdateAutoScalingGroupInput{
+		AutoScalingGroupName: aws.String(id),
+		MinSize:              aws.Int64(int64(size.MinSize)),
+		MaxSize:              aws.Int64(int64(size.MaxSize)),
+		DesiredCapacity:      aws.Int64(int64(size.DesiredSize)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return mgr.wait(ctx, id, "")
+}
+
+//ScaleWithContext changes the size of the server group identified by id
+func (mgr *ServerGroupManager) ScaleWithContext(ctx context.Context, id string, size api.ServerGroupSize) (*api.ServerGroup, api.ScaleServerGroupError) {
+	g, err := mgr.scale(ctx, id, size)
+	if err != nil {
+		return nil, api.NewScaleServerGroupError(err, id, size)
+	}
+	return g, nil
+}
+
+//Scale changes the size of the server group identified by id
+func (mgr *ServerGroupManager) Scale(id string, size api.ServerGroupSize) (*api.ServerGroup, api.ScaleServerGroupError) {
+	return mgr.ScaleWithContext(context.Background(), id, size)
+}
+
+func (mgr *ServerGroupManager) replaceServer(ctx context.Context, id string, serverID string) (*api.ServerGroup, error) {
+	g, err := mgr.describe(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	found := false
+	for _, inst := range g.Instances {
+		found = found || aws.StringValue(inst.InstanceId) == serverID
+	}
+	if !found {
+		return nil, notFoundError("server %s is not a server of group %s", serverID, id)
+	}
+	_, err = mgr.Provider.AWSServices.AutoScalingClient.TerminateInstanceInAutoScalingGroupWithContext(ctx, &autoscaling.TerminateInstanceInAutoScalingGroupInput{
+		InstanceId:                     aws.String(serverID),
+		ShouldDecrementDesiredCapacity: aws.Bool(false),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return mgr.wait(ctx, id, serverID)
+}
+
+//ReplaceServerWithContext terminates the server identified by serverID, the Auto Scaling group launches its replacement
+func (mgr *ServerGroupManager) ReplaceServerWithContext(ctx context.Context, id string, serverID string) (*api.ServerGroup, api.ReplaceServerGroupServerError) {
+	g, err := mgr.replaceServer(ctx, id, serverID)
+	if err != nil {
+		return nil, api.NewReplaceServerGroupServerError(err, id, serverID)
+	}
+	return g, nil
+}
+
+//ReplaceServer terminates the server identified by serverID, the Auto Scaling group launches its replacement
+func (mgr *ServerGroupManager) ReplaceServer(id string, serverID string) (*api.ServerGroup, api.ReplaceServerGroupServerError) {
+	return mgr.ReplaceServerWithContext(context.Background(), id, serverID)
+}
@@ -0,0 +1,21 @@
+package aws_test
+
+import (
+	"testing"
+
+	"github.com/SebastienDorgan/anyclouds/tests"
+	"github.com/stretchr/testify/suite"
+)
+
+type AWSServerGroupManagerTestSuite struct {
+	tests.ServerGroupManagerTestSuite
+}
+
+//SetupSuite set up server group manager
+func (suite *AWSServerGroupManagerTestSuite) SetupSuite() {
+	suite.Prov = GetProvider()
+}
+
+func TestAWSServerGroupManagerTestSuite(t *testing.T) {
+	suite.Run(t, new(AWSServerGroupManagerTestSuite))
+}
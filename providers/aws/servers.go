@@ -1,18 +1,20 @@
 package aws
 
 import (
+	"context"
 	"encoding/binary"
 	"fmt"
 	"github.com/google/uuid"
 	"io"
 	"io/ioutil"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/SebastienDorgan/anyclouds/providers"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
-	"github.com/aws/aws-sdk-go/service/opsworks"
 	"github.com/pkg/errors"
 )
 
@@ -43,7 +45,7 @@ func networkInterfaces(options *api.CreateServerOptions) []*ec2.InstanceNetworkI
 	return out
 }
 
-func (mgr *ServerManager) createSpotInstance(options *api.CreateServerOptions, keyName string) (*string, error) {
+func (mgr *ServerManager) createSpotInstance(ctx context.Context, options *api.CreateServerOptions, keyName string) (*string, error) {
 	var blockDurationInMinutes *int64
 	if options.LowPriorityServerOptions.Duration > 0 {
 		blockDuration := int64(options.LowPriorityServerOptions.Duration / time.Minute)
@@ -67,27 +69,27 @@ func (mgr *ServerManager) createSpotInstance(options *api.CreateServerOptions, k
 		Type:                 aws.String("one-time"),
 	}
 
-	out, err := mgr.Provider.AWSServices.EC2Client.RequestSpotInstances(input)
+	out, err := mgr.Provider.AWSServices.EC2Client.RequestSpotInstancesWithContext(ctx, input)
 	if err != nil || out.SpotInstanceRequests == nil {
 		return nil, errors.Wrap(err, "Error creating spot instance")
 	}
 
 	req := out.SpotInstanceRequests[0]
-	err = mgr.Provider.AWSServices.EC2Client.WaitUntilSpotInstanceRequestFulfilled(&ec2.DescribeSpotInstanceRequestsInput{
+	err = mgr.Provider.AWSServices.EC2Client.WaitUntilSpotInstanceRequestFulfilledWithContext(ctx, &ec2.DescribeSpotInstanceRequestsInput{
 		SpotInstanceRequestIds: []*string{req.SpotInstanceRequestId},
 	})
 	if err != nil {
-		_, _ = mgr.Provider.AWSServices.EC2Client.CancelSpotInstanceRequests(&ec2.CancelSpotInstanceRequestsInput{
+		_, _ = mgr.Provider.AWSServices.EC2Client.CancelSpotInstanceRequestsWithContext(ctx, &ec2.CancelSpotInstanceRequestsInput{
 			SpotInstanceRequestIds: []*string{req.SpotInstanceRequestId},
 		})
 		return nil, errors.Wrap(err, "error creating spot instance")
 	}
-	return mgr.getSpotInstanceID(req)
+	return mgr.getSpotInstanceID(ctx, req)
 
 }
 
-func (mgr *ServerManager) getSpotInstanceID(req *ec2.SpotInstanceRequest) (*string, error) {
-	out2, err := mgr.Provider.AWSServices.EC2Client.DescribeSpotInstanceRequests(&ec2.DescribeSpotInstanceRequestsInput{
+func (mgr *ServerManager) getSpotInstanceID(ctx context.Context, req *ec2.SpotInstanceRequest) (*string, error) {
+	out2, err := mgr.Provider.AWSServices.EC2Client.DescribeSpotInstanceRequestsWithContext(ctx, &ec2.DescribeSpotInstanceRequestsInput{
 		SpotInstanceRequestIds: []*string{req.SpotInstanceRequestId},
 	})
 	if err != nil {
@@ -107,9 +109,9 @@ func toString(reader io.Reader) *string {
 	return aws.String(string(b))
 }
 
-func (mgr *ServerManager) createOnDemandInstance(options *api.CreateServerOptions, keyName string) (*string, error) {
+func (mgr *ServerManager) createOnDemandInstance(ctx context.Context, options *api.CreateServerOptions, keyName string) (*string, error) {
 
-	out, err := mgr.Provider.AWSServices.EC2Client.RunInstances(&ec2.RunInstancesInput{
+	out, err := mgr.Provider.AWSServices.EC2Client.RunInstancesWithContext(ctx, &ec2.RunInstancesInput{
 		ImageId:           aws.String(options.ImageID),
 		InstanceType:      aws.String(options.TemplateID),
 		KeyName:           aws.String(keyName),
@@ -130,16 +132,16 @@ func (mgr *ServerManager) createOnDemandInstance(options *api.CreateServerOption
 	return out.Instances[0].InstanceId, nil
 }
 
-func (mgr *ServerManager) searchReservedInstanceOffering(options *api.CreateServerOptions) (*ec2.ReservedInstancesOffering, error) {
+func (mgr *ServerManager) searchReservedInstanceOffering(ctx context.Context, options *api.CreateServerOptions) (*ec2.ReservedInstancesOffering, error) {
 	var err error
-	tpl, err := mgr.Provider.TemplateManager.Get(options.TemplateID)
+	tpl, err := mgr.Provider.TemplateManager.GetWithContext(ctx, options.TemplateID)
 	if err != nil {
 		return nil, errors.Wrapf(err, "Invalid template ID %s", options.TemplateID)
 	}
 	if tpl == nil {
 		return nil, errors.Errorf("unknown error getting server template %s", options.TemplateID)
 	}
-	out, err := mgr.Provider.AWSServices.EC2Client.DescribeReservedInstancesOfferings(&ec2.DescribeReservedInstancesOfferingsInput{
+	out, err := mgr.Provider.AWSServices.EC2Client.DescribeReservedInstancesOfferingsWithContext(ctx, &ec2.DescribeReservedInstancesOfferingsInput{
 		AvailabilityZone:   aws.String(mgr.Provider.Configuration.Region),
 		DryRun:             aws.Bool(false),
 		Filters:            nil,
@@ -164,8 +166,8 @@ func (mgr *ServerManager) searchReservedInstanceOffering(options *api.CreateServ
 	return out.ReservedInstancesOfferings[0], nil
 }
 
-func (mgr *ServerManager) createReservedInstance(options *api.CreateServerOptions) (*string, error) {
-	offering, err := mgr.searchReservedInstanceOffering(options)
+func (mgr *ServerManager) createReservedInstance(ctx context.Context, options *api.CreateServerOptions) (*string, error) {
+	offering, err := mgr.searchReservedInstanceOffering(ctx, options)
 	if err != nil {
 		return nil, errors.Wrapf(err, "Error creating reserved instance")
 	}
@@ -175,7 +177,7 @@ func (mgr *ServerManager) createReservedInstance(options *api.CreateServerOption
 			"Error creating reserved instance")
 	}
 
-	out, err := mgr.Provider.AWSServices.EC2Client.PurchaseReservedInstancesOffering(&ec2.PurchaseReservedInstancesOfferingInput{
+	out, err := mgr.Provider.AWSServices.EC2Client.PurchaseReservedInstancesOfferingWithContext(ctx, &ec2.PurchaseReservedInstancesOfferingInput{
 		DryRun:                      aws.Bool(false),
 		InstanceCount:               aws.Int64(1),
 		LimitPrice:                  nil,
@@ -190,73 +192,94 @@ func (mgr *ServerManager) createReservedInstance(options *api.CreateServerOption
 	return out.ReservedInstancesId, nil
 }
 
-//Create creates an Server with options
-func (mgr *ServerManager) Create(options api.CreateServerOptions) (*api.Server, api.CreateServerError) {
+//CreateWithContext creates an Server with options
+func (mgr *ServerManager) CreateWithContext(ctx context.Context, options api.CreateServerOptions) (*api.Server, api.CreateServerError) {
 	var id *string
 	var err error
 	if options.LowPriorityServerOptions != nil && options.ReservedServerOptions != nil {
 		return nil, api.NewCreateServerError(err, options)
 	}
-	keyName := uuid.New().String()
-	err = mgr.Provider.KeyPairManager.Import(keyName, options.KeyPair.PublicKey)
-	defer func() { _ = mgr.Provider.KeyPairManager.Delete(keyName) }()
-	if err != nil {
-		return nil, api.NewCreateServerError(err, options)
+	keyName := options.KeyPairName
+	if keyName == "" {
+		//the inline key is imported under a random name only for the time of the instance creation
+		keyName = uuid.New().String()
+		_, err = mgr.Provider.KeyPairManager.ImportWithContext(ctx, keyName, options.KeyPair.PublicKey)
+		defer func() {
+			_ = providers.Cleanup(func(ctx context.Context) error {
+				return mgr.Provider.KeyPairManager.DeleteWithContext(ctx, keyName)
+			})
+		}()
+		if err != nil {
+			return nil, api.NewCreateServerError(err, options)
+		}
 	}
 	if options.LowPriorityServerOptions != nil {
-		id, err = mgr.createSpotInstance(&options, keyName)
+		id, err = mgr.createSpotInstance(ctx, &options, keyName)
 	} else if options.ReservedServerOptions != nil {
-		id, err = mgr.createReservedInstance(&options)
+		id, err = mgr.createReservedInstance(ctx, &options)
 	} else {
-		id, err = mgr.createOnDemandInstance(&options, keyName)
+		id, err = mgr.createOnDemandInstance(ctx, &options, keyName)
 	}
 	if err != nil {
 		return nil, api.NewCreateServerError(err, options)
 	}
-	err = mgr.Provider.AWSServices.EC2Client.WaitUntilInstanceStatusOk(&ec2.DescribeInstanceStatusInput{
+	err = mgr.Provider.AWSServices.EC2Client.WaitUntilInstanceStatusOkWithContext(ctx, &ec2.DescribeInstanceStatusInput{
 		InstanceIds: []*string{id},
 	})
 
 	if err != nil {
-		err2 := mgr.Delete(*id)
+		err2 := providers.Cleanup(func(ctx context.Context) error {
+			return mgr.DeleteWithContext(ctx, *id)
+		})
 		err := api.NewErrorStackFromError(err, err2)
 		return nil, api.NewCreateServerError(err, options)
 	}
-	err = mgr.Provider.AddTags(*id, map[string]string{"name": options.Name})
+	err = mgr.Provider.AddTags(ctx, *id, resourceTags(options.Name, options.Tags))
 	if err != nil {
-		err2 := mgr.Delete(*id)
+		err2 := providers.Cleanup(func(ctx context.Context) error {
+			return mgr.DeleteWithContext(ctx, *id)
+		})
 		err := api.NewErrorStackFromError(err, err2)
 		return nil, api.NewCreateServerError(err, options)
 	}
-	err = mgr.addSecurityGroups(&options, *id)
+	err = mgr.addSecurityGroups(ctx, &options, *id)
 	if err != nil {
-		err2 := mgr.Delete(*id)
+		err2 := providers.Cleanup(func(ctx context.Context) error {
+			return mgr.DeleteWithContext(ctx, *id)
+		})
 		err := api.NewErrorStackFromError(err, err2)
 		return nil, api.NewCreateServerError(err, options)
 	}
-	err = mgr.Provider.AWSServices.EC2Client.WaitUntilInstanceStatusOk(&ec2.DescribeInstanceStatusInput{
+	err = mgr.Provider.AWSServices.EC2Client.WaitUntilInstanceStatusOkWithContext(ctx, &ec2.DescribeInstanceStatusInput{
 		InstanceIds: []*string{id},
 	})
 	if err != nil {
-		err2 := mgr.Delete(*id)
+		err2 := providers.Cleanup(func(ctx context.Context) error {
+			return mgr.DeleteWithContext(ctx, *id)
+		})
 		err := api.NewErrorStackFromError(err, err2)
 		return nil, api.NewCreateServerError(err, options)
 	}
-	srv, err := mgr.Get(*id)
+	srv, err := mgr.GetWithContext(ctx, *id)
 	return srv, api.NewCreateServerError(err, options)
 
 }
 
-func (mgr *ServerManager) addSecurityGroups(options *api.CreateServerOptions, instanceID string) error {
+//Create creates an Server with options
+func (mgr *ServerManager) Create(options api.CreateServerOptions) (*api.Server, api.CreateServerError) {
+	return mgr.CreateWithContext(context.Background(), options)
+}
+
+func (mgr *ServerManager) addSecurityGroups(ctx context.Context, options *api.CreateServerOptions, instanceID string) error {
 	if len(options.DefaultSecurityGroup) == 0 {
 		return nil
 	}
-	_, err := mgr.Provider.AWSServices.EC2Client.ModifyInstanceAttribute(&ec2.ModifyInstanceAttributeInput{
+	_, err := mgr.Provider.AWSServices.EC2Client.ModifyInstanceAttributeWithContext(ctx, &ec2.ModifyInstanceAttributeInput{
 		Groups:     []*string{aws.String(options.DefaultSecurityGroup)},
 		InstanceId: aws.String(instanceID),
 	})
 
-	err = mgr.Provider.AWSServices.EC2Client.WaitUntilInstanceStatusOk(&ec2.DescribeInstanceStatusInput{
+	err = mgr.Provider.AWSServices.EC2Client.WaitUntilInstanceStatusOkWithContext(ctx, &ec2.DescribeInstanceStatusInput{
 		InstanceIds: []*string{&instanceID},
 	})
 	if err != nil {
@@ -265,29 +288,34 @@ func (mgr *ServerManager) addSecurityGroups(options *api.CreateServerOptions, in
 	return nil
 }
 
-//Delete delete Server identified by id
-func (mgr *ServerManager) Delete(id string) api.DeleteServerError {
+//DeleteWithContext delete Server identified by id
+func (mgr *ServerManager) DeleteWithContext(ctx context.Context, id string) api.DeleteServerError {
 	var err error
-	publicIps, err := mgr.Provider.PublicIPAddressManager.List(&api.ListPublicIPsOptions{ServerID: &id})
+	publicIps, err := mgr.Provider.PublicIPAddressManager.ListWithContext(ctx, &api.ListPublicIPsOptions{ServerID: &id})
 	if err != nil {
 		return api.NewDeleteServerError(err, id)
 	}
 	for _, ip := range publicIps {
-		_ = mgr.Provider.PublicIPAddressManager.Dissociate(ip.ID)
+		_ = mgr.Provider.PublicIPAddressManager.DissociateWithContext(ctx, ip.ID)
 	}
 
-	_, err = mgr.Provider.AWSServices.EC2Client.TerminateInstances(&ec2.TerminateInstancesInput{
+	_, err = mgr.Provider.AWSServices.EC2Client.TerminateInstancesWithContext(ctx, &ec2.TerminateInstancesInput{
 		InstanceIds: []*string{aws.String(id)},
 	})
 
-	err = mgr.Provider.AWSServices.EC2Client.WaitUntilInstanceTerminated(&ec2.DescribeInstancesInput{
+	err = mgr.Provider.AWSServices.EC2Client.WaitUntilInstanceTerminatedWithContext(ctx, &ec2.DescribeInstancesInput{
 		InstanceIds: []*string{aws.String(id)},
 	})
 	return api.NewDeleteServerError(err, id)
 }
 
-func (mgr *ServerManager) getReservedInstances() ([]*ec2.ReservedInstances, error) {
-	out, err := mgr.Provider.AWSServices.EC2Client.DescribeReservedInstances(&ec2.DescribeReservedInstancesInput{})
+//Delete delete Server identified by id
+func (mgr *ServerManager) Delete(id string) api.DeleteServerError {
+	return mgr.DeleteWithContext(context.Background(), id)
+}
+
+func (mgr *ServerManager) getReservedInstances(ctx context.Context) ([]*ec2.ReservedInstances, error) {
+	out, err := mgr.Provider.AWSServices.EC2Client.DescribeReservedInstancesWithContext(ctx, &ec2.DescribeReservedInstancesInput{})
 	if err != nil {
 		return nil, err
 	}
@@ -298,16 +326,16 @@ func (mgr *ServerManager) getReservedInstances() ([]*ec2.ReservedInstances, erro
 }
 
 func mapToSlice(in map[string]*api.Server) []api.Server {
-	out := make([]api.Server, len(in))
+	out := make([]api.Server, 0, len(in))
 	for _, v := range in {
 		out = append(out, *v)
 	}
 	return out
 }
 
-//List list Servers
-func (mgr *ServerManager) List() ([]api.Server, api.ListServersError) {
-	out, err := mgr.Provider.AWSServices.EC2Client.DescribeInstances(&ec2.DescribeInstancesInput{})
+//ListWithContext list Servers
+func (mgr *ServerManager) ListWithContext(ctx context.Context) ([]api.Server, api.ListServersError) {
+	out, err := mgr.Provider.AWSServices.EC2Client.DescribeInstancesWithContext(ctx, &ec2.DescribeInstancesInput{})
 	if err != nil {
 		return nil, api.NewListServersError(err)
 	}
@@ -318,7 +346,7 @@ func (mgr *ServerManager) List() ([]api.Server, api.ListServersError) {
 			serverMap[srv.ID] = srv
 		}
 	}
-	ris, err := mgr.getReservedInstances()
+	ris, err := mgr.getReservedInstances(ctx)
 	if err != nil { //No reserved instance
 		return mapToSlice(serverMap), nil
 	}
@@ -336,6 +364,11 @@ func (mgr *ServerManager) List() ([]api.Server, api.ListServersError) {
 	return mapToSlice(serverMap), nil
 }
 
+//List list Servers
+func (mgr *ServerManager) List() ([]api.Server, api.ListServersError) {
+	return mgr.ListWithContext(context.Background())
+}
+
 func codeValue(code *int64) uint8 {
 	if code == nil {
 		return 255
@@ -365,31 +398,25 @@ func state(in *ec2.InstanceState) api.ServerState {
 }
 
 func server(instance *ec2.Instance) *api.Server {
-	name := ""
-	n := sort.Search(len(instance.Tags), func(i int) bool {
-		return *instance.Tags[i].Key == "name"
-	})
-	if n < len(instance.Tags) {
-		name = *instance.Tags[n].Value
-	}
 	leasingType := api.LeasingTypeOnDemand
 	if instance.SpotInstanceRequestId != nil {
-		leasingType = api.LeasingTypeOnDemand
+		leasingType = api.LeasingTypeSpot
 	}
 
 	return &api.Server{
 		ID:          *instance.InstanceId,
-		Name:        name,
+		Name:        tagValue(instance.Tags, "name"),
 		ImageID:     *instance.ImageId,
 		TemplateID:  *instance.InstanceType,
 		State:       state(instance.State),
 		CreatedAt:   *instance.LaunchTime,
 		LeasingType: leasingType,
+		Tags:        userTags(instance.Tags),
 	}
 }
 
-func (mgr *ServerManager) getReservedInstance(id string) (*ec2.ReservedInstances, error) {
-	out, err := mgr.Provider.AWSServices.EC2Client.DescribeReservedInstances(&ec2.DescribeReservedInstancesInput{
+func (mgr *ServerManager) getReservedInstance(ctx context.Context, id string) (*ec2.ReservedInstances, error) {
+	out, err := mgr.Provider.AWSServices.EC2Client.DescribeReservedInstancesWithContext(ctx, &ec2.DescribeReservedInstancesInput{
 		ReservedInstancesIds: []*string{aws.String(id)},
 	})
 	if err != nil {
@@ -401,22 +428,25 @@ func (mgr *ServerManager) getReservedInstance(id string) (*ec2.ReservedInstances
 	return out.ReservedInstances[0], nil
 }
 
-//Get get Servers
-func (mgr *ServerManager) Get(id string) (*api.Server, api.GetServerError) {
+//GetWithContext get Servers
+func (mgr *ServerManager) GetWithContext(ctx context.Context, id string) (*api.Server, api.GetServerError) {
 
-	out, err := mgr.Provider.AWSServices.EC2Client.DescribeInstances(&ec2.DescribeInstancesInput{
+	out, err := mgr.Provider.AWSServices.EC2Client.DescribeInstancesWithContext(ctx, &ec2.DescribeInstancesInput{
 		InstanceIds: []*string{
 			aws.String(id),
 		},
 	})
-	if err != nil || out.Reservations == nil || out.Reservations[0].Instances == nil {
+	if err != nil {
 		return nil, api.NewGetServerError(err, id)
 	}
+	if len(out.Reservations) == 0 || len(out.Reservations[0].Instances) == 0 {
+		return nil, api.NewGetServerError(notFoundError("server %s not found", id), id)
+	}
 	srv := server(out.Reservations[0].Instances[0])
 	if srv.LeasingType == api.LeasingTypeSpot {
 		return srv, nil
 	}
-	ri, err := mgr.getReservedInstance(id)
+	ri, err := mgr.getReservedInstance(ctx, id)
 	if err != nil { //On demand instance
 		return srv, nil
 	}
@@ -427,33 +457,182 @@ func (mgr *ServerManager) Get(id string) (*api.Server, api.GetServerError) {
 	return srv, nil
 }
 
+//Get get Servers
+func (mgr *ServerManager) Get(id string) (*api.Server, api.GetServerError) {
+	return mgr.GetWithContext(context.Background(), id)
+}
+
+//StartWithContext starts an Server
+func (mgr *ServerManager) StartWithContext(ctx context.Context, id string) api.StartServerError {
+	_, err := mgr.Provider.AWSServices.EC2Client.StartInstancesWithContext(ctx, &ec2.StartInstancesInput{
+		InstanceIds: []*string{aws.String(id)},
+	})
+	if err != nil {
+		return api.NewStartServerError(err, id)
+	}
+	return nil
+}
+
 //Start starts an Server
 func (mgr *ServerManager) Start(id string) api.StartServerError {
-	_, err := mgr.Provider.AWSServices.EC2Client.StartInstances(&ec2.StartInstancesInput{
+	return mgr.StartWithContext(context.Background(), id)
+}
+
+//StopWithContext stops an Server
+func (mgr *ServerManager) StopWithContext(ctx context.Context, id string) api.StopServerError {
+	_, err := mgr.Provider.AWSServices.EC2Client.StopInstancesWithContext(ctx, &ec2.StopInstancesInput{
 		InstanceIds: []*string{aws.String(id)},
 	})
 	if err != nil {
-		return api.NewStartServerError(err, id)
+		return api.NewStopServerError(err, id)
 	}
 	return nil
 }
 
 //Stop stops an Server
 func (mgr *ServerManager) Stop(id string) api.StopServerError {
-	_, err := mgr.Provider.AWSServices.EC2Client.StopInstances(&ec2.StopInstancesInput{
+	return mgr.StopWithContext(context.Background(), id)
+}
+
+//describeInstance returns the description of the instance identified by id
+func (mgr *ServerManager) describeInstance(ctx context.Context, id string) (*ec2.Instance, error) {
+	out, err := mgr.Provider.AWSServices.EC2Client.DescribeInstancesWithContext(ctx, &ec2.DescribeInstancesInput{
 		InstanceIds: []*string{aws.String(id)},
 	})
 	if err != nil {
-		return api.NewStopServerError(err, id)
+		return nil, err
+	}
+	if len(out.Reservations) == 0 || len(out.Reservations[0].Instances) == 0 {
+		return nil, notFoundError("server %s not found", id)
+	}
+	return out.Reservations[0].Instances[0], nil
+}
+
+//architecture returns the CPU architecture of an EC2 architecture
+func architecture(arch *string) api.CPUArch {
+	switch aws.StringValue(arch) {
+	case ec2.ArchitectureValuesX8664:
+		return api.ArchAmd64
+	case ec2.ArchitectureValuesArm64:
+		return api.ArchARM64
+	case ec2.ArchitectureValuesI386:
+		return api.Arch386
+	}
+	return api.ArchUnknown
+}
+
+//paravirtualFamilies instance type families supporting paravirtual AMIs, the ones mapped to false do not support HVM AMIs
+//https://docs.aws.amazon.com/AWSEC2/latest/UserGuide/virtualization_types.html
+var paravirtualFamilies = map[string]bool{
+	"c1": false, "m1": false, "m2": false, "t1": false,
+	"c3": true, "hs1": true, "m3": true,
+}
+
+//supportsVirtualizationType returns true if instances of type instanceType can run AMIs of virtualization type vt
+func supportsVirtualizationType(instanceType string, vt *string) bool {
+	hvm, pv := paravirtualFamilies[strings.SplitN(instanceType, ".", 2)[0]]
+	if aws.StringValue(vt) == ec2.VirtualizationTypeParavirtual {
+		return pv
+	}
+	return !pv || hvm
+}
+
+//checkResize returns an error if inst cannot be stopped or if its AMI cannot run on the template tpl
+func checkResize(inst *ec2.Instance, tpl *api.ServerTemplate) error {
+	if aws.StringValue(inst.InstanceLifecycle) == ec2.InstanceLifecycleTypeSpot {
+		return invalidArgumentError("spot instance %s cannot be stopped", *inst.InstanceId)
+	}
+	if aws.StringValue(inst.RootDeviceType) != ec2.DeviceTypeEbs {
+		return invalidArgumentError("instance store backed instance %s cannot be stopped", *inst.InstanceId)
+	}
+	if arch := architecture(inst.Architecture); arch != tpl.Arch {
+		return invalidArgumentError("template %s does not support the %s architecture of instance %s", tpl.ID, arch, *inst.InstanceId)
+	}
+	if !supportsVirtualizationType(tpl.ID, inst.VirtualizationType) {
+		return invalidArgumentError("template %s does not support the %s virtualization type of instance %s", tpl.ID, aws.StringValue(inst.VirtualizationType), *inst.InstanceId)
 	}
 	return nil
 }
 
-//Resize resize a server
-func (mgr *ServerManager) Resize(id string, templateID string) api.ResizeServerError {
-	_, err := mgr.Provider.AWSServices.OpsWorksClient.UpdateInstance(&opsworks.UpdateInstanceInput{})
+//setInstanceType changes the type of the stopped instance identified by id
+func (mgr *ServerManager) setInstanceType(ctx context.Context, id string, instanceType string) error {
+	_, err := mgr.Provider.AWSServices.EC2Client.ModifyInstanceAttributeWithContext(ctx, &ec2.ModifyInstanceAttributeInput{
+		InstanceId:   aws.String(id),
+		InstanceType: &ec2.AttributeValue{Value: aws.String(instanceType)},
+	})
+	return err
+}
+
+//start starts the instance identified by id and waits for it to be stable
+func (mgr *ServerManager) start(ctx context.Context, id string) error {
+	_, err := mgr.Provider.AWSServices.EC2Client.StartInstancesWithContext(ctx, &ec2.StartInstancesInput{
+		InstanceIds: []*string{aws.String(id)},
+	})
+	if err != nil {
+		return err
+	}
+	return mgr.Provider.AWSServices.EC2Client.WaitUntilInstanceStatusOkWithContext(ctx, &ec2.DescribeInstanceStatusInput{
+		InstanceIds: []*string{aws.String(id)},
+	})
+}
+
+//resize stops the instance, changes its type and starts it again if it was running
+func (mgr *ServerManager) resize(ctx context.Context, id string, templateID string) error {
+	inst, err := mgr.describeInstance(ctx, id)
 	if err != nil {
-		return api.NewResizeServerError(err, id, templateID)
+		return err
+	}
+	if aws.StringValue(inst.InstanceType) == templateID {
+		return nil
+	}
+	tpl, err := mgr.Provider.TemplateManager.GetWithContext(ctx, templateID)
+	if err != nil {
+		return err
+	}
+	err = checkResize(inst, tpl)
+	if err != nil {
+		return err
+	}
+	state := aws.StringValue(inst.State.Name)
+	if state != ec2.InstanceStateNameRunning && state != ec2.InstanceStateNameStopped {
+		return invalidArgumentError("instance %s is %s, it must be running or stopped to be resized", id, state)
+	}
+	running := state == ec2.InstanceStateNameRunning
+	if running {
+		_, err = mgr.Provider.AWSServices.EC2Client.StopInstancesWithContext(ctx, &ec2.StopInstancesInput{
+			InstanceIds: []*string{aws.String(id)},
+		})
+		if err != nil {
+			return err
+		}
+		err = mgr.Provider.AWSServices.EC2Client.WaitUntilInstanceStoppedWithContext(ctx, &ec2.DescribeInstancesInput{
+			InstanceIds: []*string{aws.String(id)},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	err = mgr.setInstanceType(ctx, id, templateID)
+	if err != nil {
+		if running {
+			//restart the instance with its original type
+			return api.NewErrorStackFromError(err, mgr.start(ctx, id))
+		}
+		return err
+	}
+	if running {
+		return mgr.start(ctx, id)
 	}
 	return nil
 }
+
+//ResizeWithContext resize a server
+//A running server is stopped, its instance type is changed and it is started again
+func (mgr *ServerManager) ResizeWithContext(ctx context.Context, id string, templateID string) api.ResizeServerError {
+	return api.NewResizeServerError(mgr.resize(ctx, id, templateID), id, templateID)
+}
+
+//Resize resize a server
+func (mgr *ServerManager) Resize(id string, templateID string) api.ResizeServerError {
+	return mgr.ResizeWithContext(context.Background(), id, templateID)
+}
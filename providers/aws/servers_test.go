@@ -1,7 +1,13 @@
 package aws_test
 
 import (
+	"context"
+	"errors"
+	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/SebastienDorgan/anyclouds/sshutils"
 	"github.com/SebastienDorgan/anyclouds/tests"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/stretchr/testify/suite"
 	"testing"
 )
@@ -14,6 +20,101 @@ type AWSServerManagerTestSuite struct {
 func (suite *AWSServerManagerTestSuite) SetupSuite() {
 	p := GetProvider()
 	suite.Prov = p
+	suite.SkipSSH = IsFake()
+}
+
+//TestResize checks that a running instance is resized and restarted and that incompatible templates are rejected
+func (suite *AWSServerManagerTestSuite) TestResize() {
+	kp, err := sshutils.CreateKeyPair(4096)
+	suite.NoError(err)
+	mgr := suite.Prov.GetNetworkManager()
+	net, subnet, err := suite.CreateNetwork(mgr)
+	suite.NoError(err)
+	tpl, err := suite.SelectTemplate(suite.Prov.GetTemplateManager())
+	suite.NoError(err)
+	img, err := suite.FindImage(suite.Prov.GetImageManager(), tpl)
+	suite.NoError(err)
+	srvMgr := suite.Prov.GetServerManager()
+	server, err := srvMgr.Create(api.CreateServerOptions{
+		Name:       "resized_server",
+		TemplateID: tpl.ID,
+		ImageID:    img.ID,
+		Subnets:    []api.Subnet{*subnet},
+		KeyPair:    *kp,
+	})
+	suite.NoError(err)
+
+	err = srvMgr.Resize(server.ID, "r5.large")
+	suite.NoError(err)
+	server, err = srvMgr.Get(server.ID)
+	suite.NoError(err)
+	suite.Equal("r5.large", server.TemplateID)
+	suite.Equal(api.ServerReady, server.State)
+
+	err = srvMgr.Resize(server.ID, "a1.xlarge")
+	suite.Error(err)
+	suite.True(errors.Is(err, api.ErrInvalidArgument))
+	server, err = srvMgr.Get(server.ID)
+	suite.NoError(err)
+	suite.Equal("r5.large", server.TemplateID)
+	suite.Equal(api.ServerReady, server.State)
+
+	err = srvMgr.Delete(server.ID)
+	suite.NoError(err)
+	err = mgr.DeleteSubnet(net.ID, subnet.ID)
+	suite.NoError(err)
+	err = mgr.DeleteNetwork(net.ID)
+	suite.NoError(err)
+}
+
+//TestCreateCancelled checks that the instance and the key pair created are deleted when the context is cancelled during the creation
+func (suite *AWSServerManagerTestSuite) TestCreateCancelled() {
+	if !IsFake() {
+		suite.T().Skip("the context is cancelled by a handler of the fake EC2 client")
+	}
+	prov := GetProvider()
+	kp, err := sshutils.CreateKeyPair(2048)
+	suite.NoError(err)
+	mgr := prov.GetNetworkManager()
+	net, subnet, err := suite.CreateNetwork(mgr)
+	suite.NoError(err)
+	tpl, err := suite.SelectTemplate(prov.GetTemplateManager())
+	suite.NoError(err)
+	img, err := suite.FindImage(prov.GetImageManager(), tpl)
+	suite.NoError(err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	prov.AWSServices.EC2Client.Handlers.Send.PushFront(func(r *request.Request) {
+		if r.Operation.Name == "DescribeInstanceStatus" {
+			cancel()
+		}
+	})
+	_, err = prov.ServerManager.CreateWithContext(ctx, api.CreateServerOptions{
+		Name:       "cancelled_server",
+		TemplateID: tpl.ID,
+		ImageID:    img.ID,
+		Subnets:    []api.Subnet{*subnet},
+		KeyPair:    *kp,
+	})
+	suite.Error(err)
+
+	instances, err := prov.AWSServices.EC2Client.DescribeInstances(&ec2.DescribeInstancesInput{})
+	suite.NoError(err)
+	suite.NotEmpty(instances.Reservations)
+	for _, r := range instances.Reservations {
+		for _, i := range r.Instances {
+			suite.Equal(ec2.InstanceStateNameTerminated, *i.State.Name)
+		}
+	}
+	keys, err := prov.AWSServices.EC2Client.DescribeKeyPairs(&ec2.DescribeKeyPairsInput{})
+	suite.NoError(err)
+	suite.Empty(keys.KeyPairs)
+
+	err = mgr.DeleteSubnet(net.ID, subnet.ID)
+	suite.NoError(err)
+	err = mgr.DeleteNetwork(net.ID)
+	suite.NoError(err)
 }
 
 func TestAWSServerManagerTestSuite(t *testing.T) {
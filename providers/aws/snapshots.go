@@ -0,0 +1,160 @@
+package aws
+
+import (
+	"context"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/SebastienDorgan/anyclouds/providers"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+//SnapshotManager defines volume snapshot management functions an anyclouds provider must provide
+type SnapshotManager struct {
+	Provider *Provider
+}
+
+func snapshotState(state *string) api.SnapshotState {
+	switch aws.StringValue(state) {
+	case ec2.SnapshotStatePending:
+		return api.SnapshotPending
+	case ec2.SnapshotStateCompleted:
+		return api.SnapshotReady
+	case ec2.SnapshotStateError:
+		return api.SnapshotInError
+	}
+	return api.SnapshotUnknownState
+}
+
+func snapshot(s *ec2.Snapshot) *api.Snapshot {
+	return &api.Snapshot{
+		ID:        *s.SnapshotId,
+		Name:      name(s.Tags),
+		VolumeID:  aws.StringValue(s.VolumeId),
+		Size:      aws.Int64Value(s.VolumeSize),
+		State:     snapshotState(s.State),
+		CreatedAt: aws.TimeValue(s.StartTime),
+	}
+}
+
+//CreateWithContext creates a snapshot of a volume and waits until it is completed
+func (mgr *SnapshotManager) CreateWithContext(ctx context.Context, options api.CreateSnapshotOptions) (*api.Snapshot, api.CreateSnapshotError) {
+	out, err := mgr.Provider.AWSServices.EC2Client.CreateSnapshotWithContext(ctx, &ec2.CreateSnapshotInput{
+		DryRun: aws.Bool(false),
+		TagSpecifications: []*ec2.TagSpecification{
+			{
+				ResourceType: aws.String("snapshot"),
+				Tags:         createAWSTags(resourceTags(options.Name, nil)),
+			},
+		},
+		VolumeId: aws.String(options.VolumeID),
+	})
+	if err != nil {
+		return nil, api.NewCreateSnapshotError(err, options)
+	}
+	err = mgr.Provider.AWSServices.EC2Client.WaitUntilSnapshotCompletedWithContext(ctx, &ec2.DescribeSnapshotsInput{
+		SnapshotIds: []*string{out.SnapshotId},
+	})
+	if err != nil {
+		err2 := providers.Cleanup(func(ctx context.Context) error {
+			return mgr.DeleteWithContext(ctx, *out.SnapshotId)
+		})
+		err = api.NewErrorStackFromError(err, err2)
+		return nil, api.NewCreateSnapshotError(err, options)
+	}
+	s, err := mgr.GetWithContext(ctx, *out.SnapshotId)
+	if err != nil {
+		return nil, api.NewCreateSnapshotError(err, options)
+	}
+	return s, nil
+}
+
+//Create creates a snapshot of a volume and waits until it is completed
+func (mgr *SnapshotManager) Create(options api.CreateSnapshotOptions) (*api.Snapshot, api.CreateSnapshotError) {
+	return mgr.CreateWithContext(context.Background(), options)
+}
+
+//DeleteWithContext deletes snapshot identified by id
+func (mgr *SnapshotManager) DeleteWithContext(ctx context.Context, id string) api.DeleteSnapshotError {
+	_, err := mgr.Provider.AWSServices.EC2Client.DeleteSnapshotWithContext(ctx, &ec2.DeleteSnapshotInput{
+		DryRun:     aws.Bool(false),
+		SnapshotId: aws.String(id),
+	})
+	return api.NewDeleteSnapshotError(err, id)
+}
+
+//Delete deletes snapshot identified by id
+func (mgr *SnapshotManager) Delete(id string) api.DeleteSnapshotError {
+	return mgr.DeleteWithContext(context.Background(), id)
+}
+
+//ListWithContext lists snapshots owned by the account
+func (mgr *SnapshotManager) ListWithContext(ctx context.Context) ([]api.Snapshot, api.ListSnapshotsError) {
+	var snapshots []api.Snapshot
+	err := mgr.Provider.AWSServices.EC2Client.DescribeSnapshotsPagesWithContext(ctx, &ec2.DescribeSnapshotsInput{
+		DryRun:   aws.Bool(false),
+		OwnerIds: []*string{aws.String("self")},
+	}, func(out *ec2.DescribeSnapshotsOutput, last bool) bool {
+		for _, s := range out.Snapshots {
+			snapshots = append(snapshots, *snapshot(s))
+		}
+		return true
+	})
+	if err != nil {
+		return nil, api.NewListSnapshotsError(err)
+	}
+	return snapshots, nil
+}
+
+//List lists snapshots owned by the account
+func (mgr *SnapshotManager) List() ([]api.Snapshot, api.ListSnapshotsError) {
+	return mgr.ListWithContext(context.Background())
+}
+
+//GetWithContext returns snapshot details
+func (mgr *SnapshotManager) GetWithContext(ctx context.Context, id string) (*api.Snapshot, api.GetSnapshotError) {
+	out, err := mgr.Provider.AWSServices.EC2Client.DescribeSnapshotsWithContext(ctx, &ec2.DescribeSnapshotsInput{
+		DryRun:      aws.Bool(false),
+		SnapshotIds: []*string{aws.String(id)},
+	})
+	if err != nil {
+		return nil, api.NewGetSnapshotError(err, id)
+	}
+	if len(out.Snapshots) == 0 {
+		return nil, api.NewGetSnapshotError(notFoundError("snapshot %s not found", id), id)
+	}
+	return snapshot(out.Snapshots[0]), nil
+}
+
+//Get returns snapshot details
+func (mgr *SnapshotManager) Get(id string) (*api.Snapshot, api.GetSnapshotError) {
+	return mgr.GetWithContext(context.Background(), id)
+}
+
+//CreateVolumeFromSnapshotWithContext creates a volume initialized with the content of a snapshot
+func (mgr *SnapshotManager) CreateVolumeFromSnapshotWithContext(ctx context.Context, options api.CreateVolumeFromSnapshotOptions) (*api.Volume, api.CreateVolumeFromSnapshotError) {
+	size := options.Size
+	if size == 0 {
+		s, err := mgr.GetWithContext(ctx, options.SnapshotID)
+		if err != nil {
+			return nil, api.NewCreateVolumeFromSnapshotError(err, options)
+		}
+		size = s.Size
+	}
+	v, err := mgr.Provider.VolumeManager.create(ctx, api.CreateVolumeOptions{
+		Name:        options.Name,
+		Size:        size,
+		MinIOPS:     options.MinIOPS,
+		MinDataRate: options.MinDataRate,
+		Tags:        options.Tags,
+	}, aws.String(options.SnapshotID))
+	if err != nil {
+		return nil, api.NewCreateVolumeFromSnapshotError(err, options)
+	}
+	return v, nil
+}
+
+//CreateVolumeFromSnapshot creates a volume initialized with the content of a snapshot
+func (mgr *SnapshotManager) CreateVolumeFromSnapshot(options api.CreateVolumeFromSnapshotOptions) (*api.Volume, api.CreateVolumeFromSnapshotError) {
+	return mgr.CreateVolumeFromSnapshotWithContext(context.Background(), options)
+}
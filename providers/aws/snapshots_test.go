@@ -0,0 +1,21 @@
+package aws_test
+
+import (
+	"testing"
+
+	"github.com/SebastienDorgan/anyclouds/tests"
+	"github.com/stretchr/testify/suite"
+)
+
+type AWSSnapshotManagerTestSuite struct {
+	tests.SnapshotManagerTestSuite
+}
+
+//SetupSuite set up snapshot manager
+func (suite *AWSSnapshotManagerTestSuite) SetupSuite() {
+	suite.Prov = GetProvider()
+}
+
+func TestAWSSnapshotManagerTestSuite(t *testing.T) {
+	suite.Run(t, new(AWSSnapshotManagerTestSuite))
+}
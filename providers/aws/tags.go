@@ -1,11 +1,19 @@
 package aws
 
 import (
+	"context"
+
+	"github.com/SebastienDorgan/anyclouds/api"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/pkg/errors"
 )
 
+//reservedTags tag keys used by the provider to store resource attributes
+var reservedTags = map[string]bool{
+	"name": true,
+}
+
 func createAWSTags(tags map[string]string) []*ec2.Tag {
 	var awsTags []*ec2.Tag
 	for k, v := range tags {
@@ -17,8 +25,34 @@ func createAWSTags(tags map[string]string) []*ec2.Tag {
 	return awsTags
 }
 
-func (p *Provider) AddTags(resourceID string, tags map[string]string) error {
-	_, err := p.AWSServices.EC2Client.CreateTags(&ec2.CreateTagsInput{
+//resourceTags returns the tags of a resource named name
+func resourceTags(name string, tags map[string]string) map[string]string {
+	res := map[string]string{"name": name}
+	for k, v := range tags {
+		if !reservedTags[k] {
+			res[k] = v
+		}
+	}
+	return res
+}
+
+//userTags returns the tags that are not reserved or nil if there is none
+func userTags(tags []*ec2.Tag) map[string]string {
+	var res map[string]string
+	for _, t := range tags {
+		if reservedTags[aws.StringValue(t.Key)] {
+			continue
+		}
+		if res == nil {
+			res = map[string]string{}
+		}
+		res[aws.StringValue(t.Key)] = aws.StringValue(t.Value)
+	}
+	return res
+}
+
+func (p *Provider) AddTags(ctx context.Context, resourceID string, tags map[string]string) error {
+	_, err := p.AWSServices.EC2Client.CreateTagsWithContext(ctx, &ec2.CreateTagsInput{
 		DryRun: aws.Bool(false),
 		Resources: []*string{
 			aws.String(resourceID),
@@ -29,3 +63,140 @@ func (p *Provider) AddTags(resourceID string, tags map[string]string) error {
 	return errors.Wrapf(err, "error adding tags to resource %s", resourceID)
 
 }
+
+//tagValue returns the value of the tag key or an empty string if there is no such tag
+func tagValue(tags []*ec2.Tag, key string) string {
+	for _, t := range tags {
+		if aws.StringValue(t.Key) == key {
+			return aws.StringValue(t.Value)
+		}
+	}
+	return ""
+}
+
+//TagManager aws implementation of api.TagManager based on EC2 tags
+type TagManager struct {
+	Provider *Provider
+}
+
+func checkResourceType(resource api.TaggedResource) error {
+	switch resource.Type {
+	case api.ResourceServer, api.ResourceVolume, api.ResourceNetwork, api.ResourceSubnet,
+		api.ResourceSecurityGroup, api.ResourcePublicIP, api.ResourceNetworkInterface:
+		return nil
+	}
+	return invalidArgumentError("resources of type %s cannot be tagged", resource.Type)
+}
+
+func checkTagKeys(keys []string) error {
+	for _, k := range keys {
+		if len(k) == 0 {
+			return invalidArgumentError("tag keys cannot be empty")
+		}
+		if reservedTags[k] {
+			return invalidArgumentError("tag key %s is reserved", k)
+		}
+	}
+	return nil
+}
+
+func (mgr *TagManager) add(ctx context.Context, options api.AddTagsOptions) error {
+	if err := checkResourceType(options.Resource); err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(options.Tags))
+	for k := range options.Tags {
+		keys = append(keys, k)
+	}
+	if err := checkTagKeys(keys); err != nil {
+		return err
+	}
+	if len(options.Tags) == 0 {
+		return nil
+	}
+	_, err := mgr.Provider.AWSServices.EC2Client.CreateTagsWithContext(ctx, &ec2.CreateTagsInput{
+		Resources: []*string{aws.String(options.Resource.ID)},
+		Tags:      createAWSTags(options.Tags),
+	})
+	return err
+}
+
+//AddWithContext adds tags to a resource
+func (mgr *TagManager) AddWithContext(ctx context.Context, options api.AddTagsOptions) api.AddTagsError {
+	return api.NewAddTagsError(mgr.add(ctx, options), options)
+}
+
+//Add adds tags to a resource
+func (mgr *TagManager) Add(options api.AddTagsOptions) api.AddTagsError {
+	return mgr.AddWithContext(context.Background(), options)
+}
+
+func (mgr *TagManager) remove(ctx context.Context, options api.RemoveTagsOptions) error {
+	if err := checkResourceType(options.Resource); err != nil {
+		return err
+	}
+	if err := checkTagKeys(options.Keys); err != nil {
+		return err
+	}
+	if len(options.Keys) == 0 {
+		return nil
+	}
+	var tags []*ec2.Tag
+	for _, k := range options.Keys {
+		tags = append(tags, &ec2.Tag{Key: aws.String(k)})
+	}
+	_, err := mgr.Provider.AWSServices.EC2Client.DeleteTagsWithContext(ctx, &ec2.DeleteTagsInput{
+		Resources: []*string{aws.String(options.Resource.ID)},
+		Tags:      tags,
+	})
+	return err
+}
+
+//RemoveWithContext removes tags from a resource
+func (mgr *TagManager) RemoveWithContext(ctx context.Context, options api.RemoveTagsOptions) api.RemoveTagsError {
+	return api.NewRemoveTagsError(mgr.remove(ctx, options), options)
+}
+
+//Remove removes tags from a resource
+func (mgr *TagManager) Remove(options api.RemoveTagsOptions) api.RemoveTagsError {
+	return mgr.RemoveWithContext(context.Background(), options)
+}
+
+func (mgr *TagManager) list(ctx context.Context, resource api.TaggedResource) (map[string]string, error) {
+	if err := checkResourceType(resource); err != nil {
+		return nil, err
+	}
+	var tags []*ec2.Tag
+	err := mgr.Provider.AWSServices.EC2Client.DescribeTagsPagesWithContext(ctx, &ec2.DescribeTagsInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("resource-id"),
+				Values: []*string{aws.String(resource.ID)},
+			},
+		},
+	}, func(out *ec2.DescribeTagsOutput, last bool) bool {
+		for _, t := range out.Tags {
+			tags = append(tags, &ec2.Tag{Key: t.Key, Value: t.Value})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	res := userTags(tags)
+	if res == nil {
+		res = map[string]string{}
+	}
+	return res, nil
+}
+
+//ListWithContext lists the tags of a resource
+func (mgr *TagManager) ListWithContext(ctx context.Context, resource api.TaggedResource) (map[string]string, api.ListTagsError) {
+	tags, err := mgr.list(ctx, resource)
+	return tags, api.NewListTagsError(err, resource)
+}
+
+//List lists the tags of a resource
+func (mgr *TagManager) List(resource api.TaggedResource) (map[string]string, api.ListTagsError) {
+	return mgr.ListWithContext(context.Background(), resource)
+}
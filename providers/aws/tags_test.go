@@ -0,0 +1,21 @@
+package aws_test
+
+import (
+	"testing"
+
+	"github.com/SebastienDorgan/anyclouds/tests"
+	"github.com/stretchr/testify/suite"
+)
+
+type AWSTagManagerTestSuite struct {
+	tests.TagManagerTestSuite
+}
+
+//SetupSuite set up tag manager
+func (suite *AWSTagManagerTestSuite) SetupSuite() {
+	suite.Prov = GetProvider()
+}
+
+func TestAWSTagManagerTestSuite(t *testing.T) {
+	suite.Run(t, new(AWSTagManagerTestSuite))
+}
@@ -1,6 +1,7 @@
 package aws
 
 import (
+	"context"
 	"encoding/json"
 	"sort"
 	"strconv"
@@ -181,7 +182,7 @@ func toTemplate(price aws.JSONValue) *api.ServerTemplate {
 		strings.Contains(physicalProcessor, "AMD") &&
 			processorArchitecture == "64-bit" {
 		tpl.Arch = api.ArchAmd64
-	} else if physicalProcessor == "Provider Graviton Processor" &&
+	} else if physicalProcessor == "AWS Graviton Processor" &&
 		processorArchitecture == "64-bit" {
 		tpl.Arch = api.ArchARM64
 	} else {
@@ -231,10 +232,10 @@ func (mgr *ServerTemplateManager) createFilters() []*pricing.Filter {
 	}
 }
 
-//List returns available VM templates
-func (mgr *ServerTemplateManager) List() ([]api.ServerTemplate, api.ListServerTemplatesError) {
+//ListWithContext returns available VM templates
+func (mgr *ServerTemplateManager) ListWithContext(ctx context.Context) ([]api.ServerTemplate, api.ListServerTemplatesError) {
 	filters := mgr.createFilters()
-	out, err := mgr.Provider.AWSServices.PricingClient.GetProducts(&pricing.GetProductsInput{
+	out, err := mgr.Provider.AWSServices.PricingClient.GetProductsWithContext(ctx, &pricing.GetProductsInput{
 		Filters:       filters,
 		MaxResults:    aws.Int64(100),
 		FormatVersion: aws.String("aws_v1"),
@@ -246,7 +247,7 @@ func (mgr *ServerTemplateManager) List() ([]api.ServerTemplate, api.ListServerTe
 	var result []api.ServerTemplate
 	result = appendProducts(out, result)
 	for err == nil && out != nil && out.NextToken != nil && out.PriceList != nil && len(out.PriceList) == 100 {
-		out, err = mgr.Provider.AWSServices.PricingClient.GetProducts(&pricing.GetProductsInput{
+		out, err = mgr.Provider.AWSServices.PricingClient.GetProductsWithContext(ctx, &pricing.GetProductsInput{
 			Filters:       filters,
 			NextToken:     out.NextToken,
 			FormatVersion: aws.String("aws_v1"),
@@ -258,6 +259,11 @@ func (mgr *ServerTemplateManager) List() ([]api.ServerTemplate, api.ListServerTe
 	return result, api.NewListServerTemplatesError(err)
 }
 
+//List returns available VM templates
+func (mgr *ServerTemplateManager) List() ([]api.ServerTemplate, api.ListServerTemplatesError) {
+	return mgr.ListWithContext(context.Background())
+}
+
 func appendProducts(out *pricing.GetProductsOutput, result []api.ServerTemplate) []api.ServerTemplate {
 	for _, price := range out.PriceList {
 		tpl := toTemplate(price)
@@ -270,14 +276,14 @@ func appendProducts(out *pricing.GetProductsOutput, result []api.ServerTemplate)
 	return result
 }
 
-//Get returns the template identified by ids
-func (mgr *ServerTemplateManager) Get(id string) (*api.ServerTemplate, api.GetServerTemplateError) {
+//GetWithContext returns the template identified by ids
+func (mgr *ServerTemplateManager) GetWithContext(ctx context.Context, id string) (*api.ServerTemplate, api.GetServerTemplateError) {
 	filters := append(mgr.createFilters(), &pricing.Filter{
 		Field: aws.String("instanceType"),
 		Type:  aws.String("TERM_MATCH"),
 		Value: aws.String(id),
 	})
-	out, err := mgr.Provider.AWSServices.PricingClient.GetProducts(&pricing.GetProductsInput{
+	out, err := mgr.Provider.AWSServices.PricingClient.GetProductsWithContext(ctx, &pricing.GetProductsInput{
 		Filters:       filters,
 		FormatVersion: aws.String("aws_v1"),
 		ServiceCode:   aws.String("AmazonEC2"),
@@ -287,10 +293,15 @@ func (mgr *ServerTemplateManager) Get(id string) (*api.ServerTemplate, api.GetSe
 	}
 	for _, price := range out.PriceList {
 		res := toTemplate(price)
-		if res.ID == id {
+		if res != nil && res.ID == id {
 			return res, nil
 		}
 
 	}
-	return nil, api.NewGetServerTemplateError(err, id)
+	return nil, api.NewGetServerTemplateError(notFoundError("template %s not found", id), id)
+}
+
+//Get returns the template identified by ids
+func (mgr *ServerTemplateManager) Get(id string) (*api.ServerTemplate, api.GetServerTemplateError) {
+	return mgr.GetWithContext(context.Background(), id)
 }
@@ -1,11 +1,12 @@
 package aws
 
 import (
+	"context"
 	"fmt"
 	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/SebastienDorgan/anyclouds/providers"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
-	"sort"
 )
 
 //VolumeManager defines volume management functions an anyclouds provider must provide
@@ -28,61 +29,69 @@ func (mgr *VolumeManager) selectVolumeType(options *api.CreateVolumeOptions) str
 	return "io1"
 }
 
-//Create creates a volume with options
-func (mgr *VolumeManager) Create(options api.CreateVolumeOptions) (*api.Volume, api.CreateVolumeError) {
-	out, err := mgr.Provider.AWSServices.EC2Client.CreateVolume(&ec2.CreateVolumeInput{
+//create creates a volume with options, the volume is initialized with the content of the snapshot identified by snapshotID if not nil
+func (mgr *VolumeManager) create(ctx context.Context, options api.CreateVolumeOptions, snapshotID *string) (*api.Volume, error) {
+	out, err := mgr.Provider.AWSServices.EC2Client.CreateVolumeWithContext(ctx, &ec2.CreateVolumeInput{
 		AvailabilityZone: aws.String(mgr.Provider.Configuration.AvailabilityZone),
 		DryRun:           aws.Bool(false),
 		Encrypted:        aws.Bool(false),
 		Iops:             aws.Int64(options.MinIOPS),
 		KmsKeyId:         nil,
 		Size:             aws.Int64(options.Size),
-		SnapshotId:       nil,
+		SnapshotId:       snapshotID,
 		TagSpecifications: []*ec2.TagSpecification{
 			{
 				ResourceType: aws.String("volume"),
-				Tags: []*ec2.Tag{
-					{
-						Key:   aws.String("name"),
-						Value: aws.String(options.Name),
-					},
-				},
+				Tags:         createAWSTags(resourceTags(options.Name, options.Tags)),
 			},
 		},
 		VolumeType: aws.String(mgr.selectVolumeType(&options)),
 	})
 	if err != nil {
-		return nil, api.NewCreateVolumeError(err, options)
+		return nil, err
 	}
-	err = mgr.Provider.AWSServices.EC2Client.WaitUntilVolumeAvailable(&ec2.DescribeVolumesInput{
+	err = mgr.Provider.AWSServices.EC2Client.WaitUntilVolumeAvailableWithContext(ctx, &ec2.DescribeVolumesInput{
 		VolumeIds: []*string{out.VolumeId},
 	})
 	if err != nil {
-		err2 := mgr.Delete(*out.VolumeId)
-		err = api.NewErrorStackFromError(err, err2)
-		return nil, api.NewCreateVolumeError(err, options)
+		err2 := providers.Cleanup(func(ctx context.Context) error {
+			return mgr.DeleteWithContext(ctx, *out.VolumeId)
+		})
+		return nil, api.NewErrorStackFromError(err, err2)
 	}
 	return volume(out), nil
 }
 
-//Delete deletes volume identified by id
-func (mgr *VolumeManager) Delete(id string) api.DeleteVolumeError {
-	_, err := mgr.Provider.AWSServices.EC2Client.DeleteVolume(&ec2.DeleteVolumeInput{
+//CreateWithContext creates a volume with options
+func (mgr *VolumeManager) CreateWithContext(ctx context.Context, options api.CreateVolumeOptions) (*api.Volume, api.CreateVolumeError) {
+	v, err := mgr.create(ctx, options, nil)
+	if err != nil {
+		return nil, api.NewCreateVolumeError(err, options)
+	}
+	return v, nil
+}
+
+//Create creates a volume with options
+func (mgr *VolumeManager) Create(options api.CreateVolumeOptions) (*api.Volume, api.CreateVolumeError) {
+	return mgr.CreateWithContext(context.Background(), options)
+}
+
+//DeleteWithContext deletes volume identified by id
+func (mgr *VolumeManager) DeleteWithContext(ctx context.Context, id string) api.DeleteVolumeError {
+	_, err := mgr.Provider.AWSServices.EC2Client.DeleteVolumeWithContext(ctx, &ec2.DeleteVolumeInput{
 		DryRun:   aws.Bool(false),
 		VolumeId: aws.String(id),
 	})
 	return api.NewDeleteVolumeError(err, id)
 }
 
-func name(tags []*ec2.Tag) string {
-	i := sort.Search(len(tags), func(i int) bool {
-		return *tags[i].Key == "name"
-	})
-	if i < len(tags) {
-		return *tags[i].Value
-	}
-	return ""
+//Delete deletes volume identified by id
+func (mgr *VolumeManager) Delete(id string) api.DeleteVolumeError {
+	return mgr.DeleteWithContext(context.Background(), id)
+}
 
+func name(tags []*ec2.Tag) string {
+	return tagValue(tags, "name")
 }
 
 func cMBToMiB(v int64) int64 {
@@ -124,18 +133,19 @@ func volume(v *ec2.Volume) *api.Volume {
 		Size:     *v.Size,
 		IOPS:     *v.Iops,
 		DataRate: dataRate,
+		Tags:     userTags(v.Tags),
 	}
 }
 
-//List lists volumes along filter
-func (mgr *VolumeManager) List() ([]api.Volume, api.ListVolumesError) {
-	out, err := mgr.Provider.AWSServices.EC2Client.DescribeVolumes(&ec2.DescribeVolumesInput{
+//ListWithContext lists volumes along filter
+func (mgr *VolumeManager) ListWithContext(ctx context.Context) ([]api.Volume, api.ListVolumesError) {
+	out, err := mgr.Provider.AWSServices.EC2Client.DescribeVolumesWithContext(ctx, &ec2.DescribeVolumesInput{
 		DryRun: aws.Bool(false),
 		Filters: []*ec2.Filter{
 			{
 				Name: aws.String("availability-zone"),
 				Values: []*string{
-					aws.String(mgr.Provider.Configuration.Region),
+					aws.String(mgr.Provider.Configuration.AvailabilityZone),
 				},
 			},
 		},
@@ -150,12 +160,17 @@ func (mgr *VolumeManager) List() ([]api.Volume, api.ListVolumesError) {
 	for _, res := range out.Volumes {
 		volumes = append(volumes, *volume(res))
 	}
-	return nil, nil
+	return volumes, nil
 }
 
-//Get returns volume details
-func (mgr *VolumeManager) Get(id string) (*api.Volume, api.GetVolumeError) {
-	out, err := mgr.Provider.AWSServices.EC2Client.DescribeVolumes(&ec2.DescribeVolumesInput{
+//List lists volumes along filter
+func (mgr *VolumeManager) List() ([]api.Volume, api.ListVolumesError) {
+	return mgr.ListWithContext(context.Background())
+}
+
+//GetWithContext returns volume details
+func (mgr *VolumeManager) GetWithContext(ctx context.Context, id string) (*api.Volume, api.GetVolumeError) {
+	out, err := mgr.Provider.AWSServices.EC2Client.DescribeVolumesWithContext(ctx, &ec2.DescribeVolumesInput{
 		DryRun: aws.Bool(false),
 		Filters: []*ec2.Filter{
 			{
@@ -172,16 +187,21 @@ func (mgr *VolumeManager) Get(id string) (*api.Volume, api.GetVolumeError) {
 	if err != nil {
 		return nil, api.NewGetVolumeError(err, id)
 	}
-	if out.Volumes != nil {
-		return nil, api.NewGetVolumeError(err, id)
+	if len(out.Volumes) == 0 {
+		return nil, api.NewGetVolumeError(notFoundError("volume %s not found", id), id)
 	}
 
 	return volume(out.Volumes[0]), nil
 }
 
-//Attach attaches a volume to an Server
-func (mgr *VolumeManager) Attach(options api.AttachVolumeOptions) (*api.VolumeAttachment, api.AttachVolumeError) {
-	out, err := mgr.Provider.AWSServices.EC2Client.AttachVolume(&ec2.AttachVolumeInput{
+//Get returns volume details
+func (mgr *VolumeManager) Get(id string) (*api.Volume, api.GetVolumeError) {
+	return mgr.GetWithContext(context.Background(), id)
+}
+
+//AttachWithContext attaches a volume to an Server
+func (mgr *VolumeManager) AttachWithContext(ctx context.Context, options api.AttachVolumeOptions) (*api.VolumeAttachment, api.AttachVolumeError) {
+	out, err := mgr.Provider.AWSServices.EC2Client.AttachVolumeWithContext(ctx, &ec2.AttachVolumeInput{
 		Device:     aws.String(options.DevicePath),
 		DryRun:     aws.Bool(false),
 		InstanceId: aws.String(options.ServerID),
@@ -195,6 +215,11 @@ func (mgr *VolumeManager) Attach(options api.AttachVolumeOptions) (*api.VolumeAt
 
 }
 
+//Attach attaches a volume to an Server
+func (mgr *VolumeManager) Attach(options api.AttachVolumeOptions) (*api.VolumeAttachment, api.AttachVolumeError) {
+	return mgr.AttachWithContext(context.Background(), options)
+}
+
 func attachment(out *ec2.VolumeAttachment) *api.VolumeAttachment {
 	return &api.VolumeAttachment{
 		ID:       fmt.Sprintf("%s#%s#%s", *out.VolumeId, *out.InstanceId, *out.Device),
@@ -204,9 +229,9 @@ func attachment(out *ec2.VolumeAttachment) *api.VolumeAttachment {
 	}
 }
 
-//Detach detach a volume from an Server
-func (mgr *VolumeManager) Detach(options api.DetachVolumeOptions) api.DetachVolumeError {
-	_, err := mgr.Provider.AWSServices.EC2Client.DetachVolume(&ec2.DetachVolumeInput{
+//DetachWithContext detach a volume from an Server
+func (mgr *VolumeManager) DetachWithContext(ctx context.Context, options api.DetachVolumeOptions) api.DetachVolumeError {
+	_, err := mgr.Provider.AWSServices.EC2Client.DetachVolumeWithContext(ctx, &ec2.DetachVolumeInput{
 		Device:     nil,
 		DryRun:     aws.Bool(false),
 		Force:      aws.Bool(options.Force),
@@ -216,38 +241,48 @@ func (mgr *VolumeManager) Detach(options api.DetachVolumeOptions) api.DetachVolu
 	if err != nil {
 		return api.NewDetachVolumeError(err, options)
 	}
-	err = mgr.Provider.AWSServices.EC2Client.WaitUntilVolumeAvailable(&ec2.DescribeVolumesInput{
+	err = mgr.Provider.AWSServices.EC2Client.WaitUntilVolumeAvailableWithContext(ctx, &ec2.DescribeVolumesInput{
 		VolumeIds: []*string{&options.VolumeID},
 	})
 	return api.NewDetachVolumeError(err, options)
 }
 
+//Detach detach a volume from an Server
+func (mgr *VolumeManager) Detach(options api.DetachVolumeOptions) api.DetachVolumeError {
+	return mgr.DetachWithContext(context.Background(), options)
+}
+
 func (mgr *VolumeManager) createFilter(options *api.ListAttachmentsOptions) []*ec2.Filter {
-	return []*ec2.Filter{
+	filters := []*ec2.Filter{
 		{
 			Name: aws.String("availability-zone"),
 			Values: []*string{
 				aws.String(mgr.Provider.Configuration.AvailabilityZone),
 			},
 		},
-		{
+	}
+	if options.ServerID != nil {
+		filters = append(filters, &ec2.Filter{
 			Name: aws.String("attachment.instance-id"),
 			Values: []*string{
 				options.ServerID,
 			},
-		},
-		{
+		})
+	}
+	if options.VolumeID != nil {
+		filters = append(filters, &ec2.Filter{
 			Name: aws.String("volume-id"),
 			Values: []*string{
 				options.VolumeID,
 			},
-		},
+		})
 	}
+	return filters
 }
 
 //attachment returns the attachment between a volume and an Server
-func (mgr *VolumeManager) attachments(options *api.ListAttachmentsOptions) ([]api.VolumeAttachment, error) {
-	out, err := mgr.Provider.AWSServices.EC2Client.DescribeVolumes(&ec2.DescribeVolumesInput{
+func (mgr *VolumeManager) attachments(ctx context.Context, options *api.ListAttachmentsOptions) ([]api.VolumeAttachment, error) {
+	out, err := mgr.Provider.AWSServices.EC2Client.DescribeVolumesWithContext(ctx, &ec2.DescribeVolumesInput{
 		DryRun:  aws.Bool(false),
 		Filters: mgr.createFilter(options),
 	})
@@ -268,21 +303,27 @@ func (mgr *VolumeManager) attachments(options *api.ListAttachmentsOptions) ([]ap
 	return attachments, nil
 }
 
-//ListAttachments returns all the attachments of an Server
-func (mgr *VolumeManager) ListAttachments(options *api.ListAttachmentsOptions) ([]api.VolumeAttachment, api.ListVolumeAttachmentsError) {
-	attachments, err := mgr.attachments(options)
+//ListAttachmentsWithContext returns all the attachments of an Server
+func (mgr *VolumeManager) ListAttachmentsWithContext(ctx context.Context, options *api.ListAttachmentsOptions) ([]api.VolumeAttachment, api.ListVolumeAttachmentsError) {
+	attachments, err := mgr.attachments(ctx, options)
 	return attachments, api.NewListVolumeAttachmentsError(err, options)
 
 }
 
-func (mgr *VolumeManager) Resize(options api.ResizeVolumeOptions) (*api.Volume, api.ResizeVolumeError) {
+//ListAttachments returns all the attachments of an Server
+func (mgr *VolumeManager) ListAttachments(options *api.ListAttachmentsOptions) ([]api.VolumeAttachment, api.ListVolumeAttachmentsError) {
+	return mgr.ListAttachmentsWithContext(context.Background(), options)
+}
+
+//ResizeWithContext context aware version of Resize
+func (mgr *VolumeManager) ResizeWithContext(ctx context.Context, options api.ResizeVolumeOptions) (*api.Volume, api.ResizeVolumeError) {
 	vType := mgr.selectVolumeType(&api.CreateVolumeOptions{
 		Name:        "",
 		Size:        options.Size,
 		MinIOPS:     options.MinIOPS,
 		MinDataRate: options.MinDataRate,
 	})
-	out, err := mgr.Provider.AWSServices.EC2Client.ModifyVolume(&ec2.ModifyVolumeInput{
+	out, err := mgr.Provider.AWSServices.EC2Client.ModifyVolumeWithContext(ctx, &ec2.ModifyVolumeInput{
 		DryRun:     aws.Bool(false),
 		Iops:       aws.Int64(options.MinIOPS),
 		Size:       aws.Int64(options.Size),
@@ -292,6 +333,10 @@ func (mgr *VolumeManager) Resize(options api.ResizeVolumeOptions) (*api.Volume,
 	if err != nil {
 		return nil, api.NewResizeVolumeError(err, options)
 	}
-	v, err := mgr.Get(*out.VolumeModification.VolumeId)
+	v, err := mgr.GetWithContext(ctx, *out.VolumeModification.VolumeId)
 	return v, api.NewResizeVolumeError(err, options)
 }
+
+func (mgr *VolumeManager) Resize(options api.ResizeVolumeOptions) (*api.Volume, api.ResizeVolumeError) {
+	return mgr.ResizeWithContext(context.Background(), options)
+}
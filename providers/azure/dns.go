@@ -0,0 +1,299 @@
+package azure
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/dns/mgmt/dns"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/pkg/errors"
+)
+
+//DNSManager azure implementation of api.DNSManager using Azure DNS public zones
+//Zones are identified by their name
+type DNSManager struct {
+	Provider *Provider
+}
+
+func (mgr *DNSManager) resourceGroup() string {
+	return mgr.Provider.Configuration.ResourceGroupName
+}
+
+func convertZone(z *dns.Zone) *api.Zone {
+	res := &api.Zone{
+		ID:   to.String(z.Name),
+		Name: api.ZoneName(to.String(z.Name)),
+	}
+	if z.ZoneProperties != nil && z.NameServers != nil {
+		for _, ns := range *z.NameServers {
+			res.NameServers = append(res.NameServers, api.ZoneName(ns))
+		}
+	}
+	return res
+}
+
+//recordType returns the type of a record set, e.g. A for Microsoft.Network/dnszones/A
+func recordType(rs *dns.RecordSet) string {
+	t := to.String(rs.Type)
+	return t[strings.LastIndex(t, "/")+1:]
+}
+
+//convertRecord converts a record set of the zone zoneID, it returns nil if the type of the record set is not managed
+func convertRecord(zoneID string, rs *dns.RecordSet) *api.Record {
+	if !api.IsRecordType(recordType(rs)) || rs.RecordSetProperties == nil {
+		return nil
+	}
+	r := &api.Record{
+		ZoneID: zoneID,
+		Name:   strings.ToLower(to.String(rs.Name)),
+		Type:   api.RecordType(recordType(rs)),
+		TTL:    int(to.Int64(rs.TTL)),
+	}
+	switch r.Type {
+	case api.RecordA:
+		for _, a := range *rs.ARecords {
+			r.Values = append(r.Values, to.String(a.Ipv4Address))
+		}
+	case api.RecordAAAA:
+		for _, a := range *rs.AaaaRecords {
+			r.Values = append(r.Values, to.String(a.Ipv6Address))
+		}
+	case api.RecordCNAME:
+		r.Values = append(r.Values, api.ZoneName(to.String(rs.CnameRecord.Cname)))
+	case api.RecordTXT:
+		//TXT values longer than 255 characters are split in several strings
+		for _, txt := range *rs.TxtRecords {
+			r.Values = append(r.Values, strings.Join(*txt.Value, ""))
+		}
+	}
+	return r
+}
+
+//recordSetProperties returns the properties of the record set defined by options
+func recordSetProperties(options *api.UpsertRecordOptions) *dns.RecordSetProperties {
+	p := &dns.RecordSetProperties{
+		TTL: to.Int64Ptr(int64(options.TTL)),
+	}
+	switch options.Type {
+	case api.RecordA:
+		var records []dns.ARecord
+		for _, v := range options.Values {
+			records = append(records, dns.ARecord{Ipv4Address: to.StringPtr(v)})
+		}
+		p.ARecords = &records
+	case api.RecordAAAA:
+		var records []dns.AaaaRecord
+		for _, v := range options.Values {
+			records = append(records, dns.AaaaRecord{Ipv6Address: to.StringPtr(v)})
+		}
+		p.AaaaRecords = &records
+	case api.RecordCNAME:
+		p.CnameRecord = &dns.CnameRecord{Cname: to.StringPtr(api.ZoneName(options.Values[0]))}
+	case api.RecordTXT:
+		var records []dns.TxtRecord
+		for _, v := range options.Values {
+			var chunks []string
+			for len(v) > 255 {
+				chunks = append(chunks, v[:255])
+				v = v[255:]
+			}
+			chunks = append(chunks, v)
+			records = append(records, dns.TxtRecord{Value: &chunks})
+		}
+		p.TxtRecords = &records
+	}
+	return p
+}
+
+func (mgr *DNSManager) createZone(ctx context.Context, options api.CreateZoneOptions) (*api.Zone, error) {
+	err := api.CheckZoneOptions(&options)
+	if err != nil {
+		return nil, err
+	}
+	name := api.ZoneName(options.Name)
+	z, err := mgr.Provider.BaseServices.ZonesClient.CreateOrUpdate(ctx, mgr.resourceGroup(), name, dns.Zone{
+		Location: to.StringPtr("global"),
+		ZoneProperties: &dns.ZoneProperties{
+			ZoneType: dns.Public,
+		},
+	}, "", "*")
+	if z.Response.Response != nil && z.StatusCode == http.StatusPreconditionFailed {
+		return nil, api.WithKind(errors.Errorf("zone %s already exists", name), api.ErrAlreadyExists)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return convertZone(&z), nil
+}
+
+//CreateZoneWithContext creates a public DNS zone
+func (mgr *DNSManager) CreateZoneWithContext(ctx context.Context, options api.CreateZoneOptions) (*api.Zone, api.CreateZoneError) {
+	z, err := mgr.createZone(ctx, options)
+	if err != nil {
+		return nil, api.NewCreateZoneError(UnwrapAzureError(err), options)
+	}
+	return z, nil
+}
+
+//CreateZone creates a public DNS zone
+func (mgr *DNSManager) CreateZone(options api.CreateZoneOptions) (*api.Zone, api.CreateZoneError) {
+	return mgr.CreateZoneWithContext(context.Background(), options)
+}
+
+func (mgr *DNSManager) deleteZone(ctx context.Context, id string) error {
+	//deleting a missing zone succeeds
+	_, err := mgr.Provider.BaseServices.ZonesClient.Get(ctx, mgr.resourceGroup(), id)
+	if err != nil {
+		return err
+	}
+	future, err := mgr.Provider.BaseServices.ZonesClient.Delete(ctx, mgr.resourceGroup(), id, "")
+	if err != nil {
+		return err
+	}
+	return future.WaitForCompletionRef(ctx, mgr.Provider.BaseServices.ZonesClient.Client)
+}
+
+//DeleteZoneWithContext deletes the DNS zone identified by id and its records
+func (mgr *DNSManager) DeleteZoneWithContext(ctx context.Context, id string) api.DeleteZoneError {
+	return api.NewDeleteZoneError(UnwrapAzureError(mgr.deleteZone(ctx, id)), id)
+}
+
+//DeleteZone deletes the DNS zone identified by id and its records
+func (mgr *DNSManager) DeleteZone(id string) api.DeleteZoneError {
+	return mgr.DeleteZoneWithContext(context.Background(), id)
+}
+
+func (mgr *DNSManager) listZones(ctx context.Context) ([]api.Zone, error) {
+	res, err := mgr.Provider.BaseServices.ZonesClient.ListByResourceGroup(ctx, mgr.resourceGroup(), nil)
+	if err != nil {
+		return nil, err
+	}
+	zones := []api.Zone{}
+	for res.NotDone() {
+		for _, z := range res.Values() {
+			if z.ZoneProperties == nil || z.ZoneType != dns.Private {
+				zones = append(zones, *convertZone(&z))
+			}
+		}
+		err := res.NextWithContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return zones, nil
+}
+
+//ListZonesWithContext lists the public DNS zones of the resource group
+func (mgr *DNSManager) ListZonesWithContext(ctx context.Context) ([]api.Zone, api.ListZonesError) {
+	zones, err := mgr.listZones(ctx)
+	if err != nil {
+		return nil, api.NewListZonesError(UnwrapAzureError(err))
+	}
+	return zones, nil
+}
+
+//ListZones lists the public DNS zones of the resource group
+func (mgr *DNSManager) ListZones() ([]api.Zone, api.ListZonesError) {
+	return mgr.ListZonesWithContext(context.Background())
+}
+
+//GetZoneWithContext returns the DNS zone identified by id
+func (mgr *DNSManager) GetZoneWithContext(ctx context.Context, id string) (*api.Zone, api.GetZoneError) {
+	z, err := mgr.Provider.BaseServices.ZonesClient.Get(ctx, mgr.resourceGroup(), id)
+	if err != nil {
+		return nil, api.NewGetZoneError(UnwrapAzureError(err), id)
+	}
+	return convertZone(&z), nil
+}
+
+//GetZone returns the DNS zone identified by id
+func (mgr *DNSManager) GetZone(id string) (*api.Zone, api.GetZoneError) {
+	return mgr.GetZoneWithContext(context.Background(), id)
+}
+
+func (mgr *DNSManager) upsertRecord(ctx context.Context, options api.UpsertRecordOptions) (*api.Record, error) {
+	resolved, err := api.ResolveRecordOptions(ctx, &mgr.Provider.PublicIPAddressManager, options)
+	if err != nil {
+		return nil, err
+	}
+	rs, err := mgr.Provider.BaseServices.RecordSetsClient.CreateOrUpdate(ctx, mgr.resourceGroup(), options.ZoneID, resolved.Name, dns.RecordType(resolved.Type), dns.RecordSet{
+		RecordSetProperties: recordSetProperties(resolved),
+	}, "", "")
+	if err != nil {
+		return nil, err
+	}
+	return convertRecord(options.ZoneID, &rs), nil
+}
+
+//UpsertRecordWithContext creates or replaces a record set
+func (mgr *DNSManager) UpsertRecordWithContext(ctx context.Context, options api.UpsertRecordOptions) (*api.Record, api.UpsertRecordError) {
+	r, err := mgr.upsertRecord(ctx, options)
+	if err != nil {
+		return nil, api.NewUpsertRecordError(UnwrapAzureError(err), options)
+	}
+	return r, nil
+}
+
+//UpsertRecord creates or replaces a record set
+func (mgr *DNSManager) UpsertRecord(options api.UpsertRecordOptions) (*api.Record, api.UpsertRecordError) {
+	return mgr.UpsertRecordWithContext(context.Background(), options)
+}
+
+func (mgr *DNSManager) deleteRecord(ctx context.Context, options api.DeleteRecordOptions) error {
+	client := mgr.Provider.BaseServices.RecordSetsClient
+	name := strings.ToLower(options.Name)
+	//deleting a missing record set succeeds
+	_, err := client.Get(ctx, mgr.resourceGroup(), options.ZoneID, name, dns.RecordType(options.Type))
+	if err != nil {
+		return err
+	}
+	_, err = client.Delete(ctx, mgr.resourceGroup(), options.ZoneID, name, dns.RecordType(options.Type), "")
+	return err
+}
+
+//DeleteRecordWithContext deletes a record set
+func (mgr *DNSManager) DeleteRecordWithContext(ctx context.Context, options api.DeleteRecordOptions) api.DeleteRecordError {
+	return api.NewDeleteRecordError(UnwrapAzureError(mgr.deleteRecord(ctx, options)), options)
+}
+
+//DeleteRecord deletes a record set
+func (mgr *DNSManager) DeleteRecord(options api.DeleteRecordOptions) api.DeleteRecordError {
+	return mgr.DeleteRecordWithContext(context.Background(), options)
+}
+
+func (mgr *DNSManager) listRecords(ctx context.Context, zoneID string) ([]api.Record, error) {
+	res, err := mgr.Provider.BaseServices.RecordSetsClient.ListAllByDNSZone(ctx, mgr.resourceGroup(), zoneID, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	records := []api.Record{}
+	for res.NotDone() {
+		for _, rs := range res.Values() {
+			if r := convertRecord(zoneID, &rs); r != nil {
+				records = append(records, *r)
+			}
+		}
+		err := res.NextWithContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	api.SortRecords(records)
+	return records, nil
+}
+
+//ListRecordsWithContext lists the A, AAAA, CNAME and TXT record sets of the DNS zone identified by zoneID
+func (mgr *DNSManager) ListRecordsWithContext(ctx context.Context, zoneID string) ([]api.Record, api.ListRecordsError) {
+	records, err := mgr.listRecords(ctx, zoneID)
+	if err != nil {
+		return nil, api.NewListRecordsError(UnwrapAzureError(err), zoneID)
+	}
+	return records, nil
+}
+
+//ListRecords lists the A, AAAA, CNAME and TXT record sets of the DNS zone identified by zoneID
+func (mgr *DNSManager) ListRecords(zoneID string) ([]api.Record, api.ListRecordsError) {
+	return mgr.ListRecordsWithContext(context.Background(), zoneID)
+}
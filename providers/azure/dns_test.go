@@ -0,0 +1,21 @@
+package azure_test
+
+import (
+	"testing"
+
+	"github.com/SebastienDorgan/anyclouds/tests"
+	"github.com/stretchr/testify/suite"
+)
+
+type AZDNSManagerTestSuite struct {
+	tests.DNSManagerTestSuite
+}
+
+//SetupSuite set up DNS manager
+func (suite *AZDNSManagerTestSuite) SetupSuite() {
+	suite.Prov = GetProvider()
+}
+
+func TestAZDNSManagerTestSuite(t *testing.T) {
+	suite.Run(t, new(AZDNSManagerTestSuite))
+}
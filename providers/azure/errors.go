@@ -0,0 +1,104 @@
+package azure
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/storage"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/SebastienDorgan/anyclouds/api"
+)
+
+func statusCode(code interface{}) int {
+	if c, ok := code.(int); ok {
+		return c
+	}
+	return 0
+}
+
+func kindOf(code string, status int) error {
+	switch {
+	case code == "TooManyRequests" || strings.HasSuffix(code, "Throttled"):
+		return api.ErrThrottled
+	case strings.HasPrefix(code, "Authorization") || strings.HasPrefix(code, "Authentication") ||
+		strings.HasPrefix(code, "InvalidAuthenticationToken") || code == "LinkedAuthorizationFailed":
+		return api.ErrUnauthorized
+	case strings.HasSuffix(code, "NotFound"):
+		return api.ErrNotFound
+	case strings.HasSuffix(code, "AlreadyExists") || code == "ResourceExists":
+		return api.ErrAlreadyExists
+	case strings.Contains(code, "QuotaExceeded") || strings.HasSuffix(code, "LimitExceeded") || code == "OperationNotAllowed":
+		return api.ErrQuotaExceeded
+	case strings.HasPrefix(code, "Invalid") || code == "BadRequest" || code == "MissingParameter":
+		return api.ErrInvalidArgument
+	}
+	switch status {
+	case http.StatusNotFound:
+		return api.ErrNotFound
+	case http.StatusTooManyRequests:
+		return api.ErrThrottled
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return api.ErrUnauthorized
+	case http.StatusBadRequest:
+		return api.ErrInvalidArgument
+	}
+	return nil
+}
+
+func requestErrorKind(e *azure.RequestError) error {
+	code := ""
+	if e.ServiceError != nil {
+		code = e.ServiceError.Code
+	}
+	return kindOf(code, statusCode(e.StatusCode))
+}
+
+//azureErrorKind returns the api error kind of err, following the chain of autorest and github.com/pkg/errors causes
+func azureErrorKind(err error) error {
+	for err != nil {
+		var kind error
+		switch e := err.(type) {
+		case *azure.RequestError:
+			kind = requestErrorKind(e)
+			err = e.Original
+		case azure.RequestError:
+			kind = requestErrorKind(&e)
+			err = e.Original
+		case *autorest.DetailedError:
+			kind = kindOf("", statusCode(e.StatusCode))
+			err = e.Original
+		case autorest.DetailedError:
+			kind = kindOf("", statusCode(e.StatusCode))
+			err = e.Original
+		case *azure.ServiceError:
+			return kindOf(e.Code, 0)
+		case azure.ServiceError:
+			return kindOf(e.Code, 0)
+		case storage.AzureStorageServiceError:
+			return kindOf(e.Code, e.StatusCode)
+		case storage.UnexpectedStatusCodeError:
+			if e.Inner() == nil {
+				return kindOf("", e.Got())
+			}
+			err = e.Inner()
+		case interface{ Cause() error }:
+			err = e.Cause()
+		default:
+			return api.ErrorKind(err)
+		}
+		if kind != nil {
+			return kind
+		}
+	}
+	return nil
+}
+
+//UnwrapAzureError annotates err with its api error kind, errors without kind are returned unchanged
+func UnwrapAzureError(err error) error {
+	kind := azureErrorKind(err)
+	if kind == nil {
+		return err
+	}
+	return api.WithKind(err, kind)
+}
@@ -0,0 +1,47 @@
+package azure_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest"
+	azurerest "github.com/Azure/go-autorest/autorest/azure"
+	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/SebastienDorgan/anyclouds/providers/azure"
+	pkgerrors "github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func requestError(status int, code string) error {
+	return &azurerest.RequestError{
+		DetailedError: autorest.DetailedError{StatusCode: status},
+		ServiceError:  &azurerest.ServiceError{Code: code, Message: "message"},
+	}
+}
+
+func TestUnwrapAzureError(t *testing.T) {
+	cases := []struct {
+		err  error
+		kind error
+	}{
+		{requestError(http.StatusNotFound, "ResourceNotFound"), api.ErrNotFound},
+		{requestError(http.StatusConflict, "ResourceGroupAlreadyExists"), api.ErrAlreadyExists},
+		{requestError(http.StatusConflict, "OperationNotAllowed"), api.ErrQuotaExceeded},
+		{requestError(http.StatusTooManyRequests, "SubscriptionRequestsThrottled"), api.ErrThrottled},
+		{requestError(http.StatusForbidden, "AuthorizationFailed"), api.ErrUnauthorized},
+		{requestError(http.StatusBadRequest, "InvalidParameter"), api.ErrInvalidArgument},
+		{requestError(http.StatusConflict, "InUseSubnetCannotBeDeleted"), nil},
+		{autorest.DetailedError{Original: requestError(http.StatusNotFound, ""), StatusCode: http.StatusNotFound}, api.ErrNotFound},
+		{pkgerrors.Wrap(requestError(http.StatusNotFound, "NotFound"), "error reading network"), api.ErrNotFound},
+	}
+	for _, c := range cases {
+		err := azure.UnwrapAzureError(c.err)
+		assert.Equal(t, c.kind, api.ErrorKind(err), c.err.Error())
+		if c.kind != nil {
+			assert.True(t, errors.Is(err, c.kind))
+		}
+		assert.Equal(t, c.err.Error(), err.Error())
+	}
+	assert.Nil(t, azure.UnwrapAzureError(nil))
+}
@@ -0,0 +1,503 @@
+package fake
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+//blobServicePrefix path of the blob service, storage accounts are addressed in path style (http://endpoint/blob/account/container/blob)
+//as the Azurite emulator does
+const blobServicePrefix = "/blob"
+
+//blobContainerName valid container names
+var blobContainerName = regexp.MustCompile(`^[a-z0-9]([a-z0-9]|-[a-z0-9]){2,62}$`)
+
+type storageBlob struct {
+	content      []byte
+	contentType  string
+	etag         string
+	lastModified time.Time
+}
+
+type storageContainer struct {
+	name         string
+	etag         string
+	lastModified time.Time
+	blobs        map[string]*storageBlob
+	//blocks uncommitted blocks by blob name and block identifier
+	blocks map[string]map[string][]byte
+}
+
+//storageETag returns a new entity tag in the format of the storage service
+func storageETag() string {
+	id := uuid.New()
+	return fmt.Sprintf("\"0x%X\"", id[:8])
+}
+
+func storageTime() time.Time {
+	return time.Now().UTC().Truncate(time.Second)
+}
+
+func (c *cloud) container(name string) (*storageContainer, error) {
+	ct, ok := c.containers[name]
+	if !ok {
+		return nil, errorf(http.StatusNotFound, "ContainerNotFound", "The specified container does not exist.")
+	}
+	return ct, nil
+}
+
+func (c *cloud) blob(container, name string) (*storageBlob, error) {
+	ct, err := c.container(container)
+	if err != nil {
+		return nil, err
+	}
+	b, ok := ct.blobs[name]
+	if !ok {
+		return nil, errorf(http.StatusNotFound, "BlobNotFound", "The specified blob does not exist.")
+	}
+	return b, nil
+}
+
+//checkSharedKey checks that the request is authorized with the key of the storage account
+//The signature itself is not verified
+func checkSharedKey(r *http.Request) error {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "SharedKey "+StorageAccountName+":") {
+		return errorf(http.StatusForbidden, "AuthenticationFailed", "Server failed to authenticate the request. Make sure the value of Authorization header is formed correctly including the signature.")
+	}
+	return nil
+}
+
+//checkSAS checks the signature, the expiry and the permissions of a blob shared access signature
+//The string to sign is the one of the service versions 2015-04-05 to 2018-03-28
+func checkSAS(r *http.Request, container, name string) error {
+	query := r.URL.Query()
+	expiry, err := time.Parse(time.RFC3339, query.Get("se"))
+	if err != nil || query.Get("sr") != "b" || name == "" {
+		return errorf(http.StatusForbidden, "AuthenticationFailed", "Signature fields not well formed.")
+	}
+	toSign := strings.Join([]string{
+		query.Get("sp"), query.Get("st"), query.Get("se"),
+		"/blob/" + StorageAccountName + "/" + container + "/" + name,
+		query.Get("si"), query.Get("sip"), query.Get("spr"), query.Get("sv"),
+		query.Get("rscc"), query.Get("rscd"), query.Get("rsce"), query.Get("rscl"), query.Get("rsct"),
+	}, "\n")
+	key, _ := base64.StdEncoding.DecodeString(StorageAccountKey)
+	h := hmac.New(sha256.New, key)
+	_, _ = h.Write([]byte(toSign))
+	if base64.StdEncoding.EncodeToString(h.Sum(nil)) != query.Get("sig") {
+		return errorf(http.StatusForbidden, "AuthenticationFailed", "Signature did not match. String to sign used was %s", toSign)
+	}
+	if time.Now().After(expiry) {
+		return errorf(http.StatusForbidden, "AuthenticationFailed", "Signed expiry time [%s] has to be after signed start time", query.Get("se"))
+	}
+	permissions := map[string]string{
+		http.MethodGet:    "r",
+		http.MethodHead:   "r",
+		http.MethodPut:    "wc",
+		http.MethodDelete: "d",
+	}
+	if !strings.ContainsAny(query.Get("sp"), permissions[r.Method]) || permissions[r.Method] == "" {
+		return errorf(http.StatusForbidden, "AuthorizationPermissionMismatch", "This request is not authorized to perform this operation using this permission.")
+	}
+	return nil
+}
+
+//serveBlob dispatches blob service requests using their method, their path and their restype and comp parameters
+func (s *Server) serveBlob(w http.ResponseWriter, r *http.Request) {
+	path := strings.SplitN(strings.TrimPrefix(r.URL.Path, blobServicePrefix+"/"), "/", 3)
+	account, container, name := path[0], "", ""
+	if len(path) > 1 {
+		container = path[1]
+	}
+	if len(path) > 2 {
+		name = path[2]
+	}
+	if account != StorageAccountName {
+		writeBlobError(w, r, errorf(http.StatusNotFound, "ResourceNotFound", "The specified resource does not exist."))
+		return
+	}
+	query := r.URL.Query()
+	var err error
+	if query.Get("sig") != "" {
+		err = checkSAS(r, container, name)
+	} else {
+		err = checkSharedKey(r)
+	}
+	if err != nil {
+		writeBlobError(w, r, err)
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeBlobError(w, r, err)
+		return
+	}
+	c := s.cloud
+	comp := query.Get("comp")
+	var output interface{}
+	status := http.StatusOK
+	switch {
+	case container == "" && r.Method == http.MethodGet && comp == "list":
+		output = c.listContainers(query)
+	case name == "" && query.Get("restype") == "container":
+		switch {
+		case r.Method == http.MethodPut:
+			status, err = http.StatusCreated, c.createContainer(w, container)
+		case r.Method == http.MethodDelete:
+			status, err = http.StatusAccepted, c.deleteContainer(container)
+		case r.Method == http.MethodGet && comp == "list":
+			output, err = c.listBlobs(container, query)
+		case r.Method == http.MethodGet || r.Method == http.MethodHead:
+			err = c.getContainer(w, container)
+		default:
+			err = errorf(http.StatusMethodNotAllowed, "UnsupportedHttpVerb", "The resource doesn't support specified Http Verb.")
+		}
+	case name == "":
+		err = errorf(http.StatusBadRequest, "InvalidUri", "The requested URI does not represent any resource on the server.")
+	case r.Method == http.MethodPut && comp == "block":
+		status, err = http.StatusCreated, c.putBlock(container, name, query.Get("blockid"), body)
+	case r.Method == http.MethodPut && comp == "blocklist":
+		status, err = http.StatusCreated, c.putBlockList(w, r, container, name, body)
+	case r.Method == http.MethodPut && comp == "":
+		status, err = http.StatusCreated, c.putBlob(w, r, container, name, body)
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		err = c.getBlob(w, r, container, name)
+		if err == nil {
+			return
+		}
+	case r.Method == http.MethodDelete:
+		status, err = http.StatusAccepted, c.deleteBlob(container, name)
+	default:
+		err = errorf(http.StatusMethodNotAllowed, "UnsupportedHttpVerb", "The resource doesn't support specified Http Verb.")
+	}
+	if err != nil {
+		writeBlobError(w, r, err)
+		return
+	}
+	writeBlobResponse(w, status, output)
+}
+
+func setStorageHeaders(w http.ResponseWriter) {
+	w.Header().Set("x-ms-request-id", uuid.New().String())
+	w.Header().Set("x-ms-version", "2018-03-28")
+}
+
+func writeBlobResponse(w http.ResponseWriter, status int, output interface{}) {
+	setStorageHeaders(w)
+	if output == nil {
+		w.WriteHeader(status)
+		return
+	}
+	b, err := xml.Marshal(output)
+	if err != nil {
+		writeBlobError(w, nil, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_, _ = w.Write(append([]byte(xml.Header), b...))
+}
+
+type blobErrorResponse struct {
+	XMLName xml.Name `xml:"Error"`
+	Code    string   `xml:"Code"`
+	Message string   `xml:"Message"`
+}
+
+//writeBlobError writes the error document of the blob service, responses to HEAD requests have no body
+func writeBlobError(w http.ResponseWriter, r *http.Request, err error) {
+	e := toAPIError(err)
+	setStorageHeaders(w)
+	w.Header().Set("x-ms-error-code", e.code)
+	if r != nil && r.Method == http.MethodHead {
+		w.WriteHeader(e.status)
+		return
+	}
+	b, _ := xml.Marshal(&blobErrorResponse{Code: e.code, Message: e.message})
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(e.status)
+	_, _ = w.Write(append([]byte(xml.Header), b...))
+}
+
+//page returns the sorted names following the marker of the query, limited to its maxresults parameter, and the marker of the next page
+func page(names []string, query url.Values) ([]string, string) {
+	sort.Strings(names)
+	max, err := strconv.Atoi(query.Get("maxresults"))
+	if err != nil || max <= 0 {
+		max = 5000
+	}
+	first := sort.SearchStrings(names, query.Get("marker"))
+	names = names[first:]
+	if len(names) > max {
+		return names[:max], names[max]
+	}
+	return names, ""
+}
+
+type containerList struct {
+	XMLName         xml.Name         `xml:"EnumerationResults"`
+	ServiceEndpoint string           `xml:"ServiceEndpoint,attr"`
+	Prefix          string           `xml:"Prefix,omitempty"`
+	Marker          string           `xml:"Marker,omitempty"`
+	MaxResults      string           `xml:"MaxResults,omitempty"`
+	Containers      []containerEntry `xml:"Containers>Container"`
+	NextMarker      string           `xml:"NextMarker"`
+}
+
+type containerEntry struct {
+	Name         string `xml:"Name"`
+	LastModified string `xml:"Properties>Last-Modified"`
+	Etag         string `xml:"Properties>Etag"`
+}
+
+func (c *cloud) listContainers(query url.Values) interface{} {
+	res := &containerList{
+		ServiceEndpoint: c.url + blobServicePrefix + "/" + StorageAccountName + "/",
+		Prefix:          query.Get("prefix"),
+		Marker:          query.Get("marker"),
+		MaxResults:      query.Get("maxresults"),
+	}
+	var names []string
+	for name := range c.containers {
+		if strings.HasPrefix(name, res.Prefix) {
+			names = append(names, name)
+		}
+	}
+	names, res.NextMarker = page(names, query)
+	for _, name := range names {
+		ct := c.containers[name]
+		res.Containers = append(res.Containers, containerEntry{
+			Name:         name,
+			LastModified: ct.lastModified.Format(http.TimeFormat),
+			Etag:         ct.etag,
+		})
+	}
+	return res
+}
+
+func (c *cloud) createContainer(w http.ResponseWriter, name string) error {
+	if !blobContainerName.MatchString(name) {
+		return errorf(http.StatusBadRequest, "InvalidResourceName", "The specifed resource name contains invalid characters.")
+	}
+	if _, ok := c.containers[name]; ok {
+		return errorf(http.StatusConflict, "ContainerAlreadyExists", "The specified container already exists.")
+	}
+	ct := &storageContainer{
+		name:         name,
+		etag:         storageETag(),
+		lastModified: storageTime(),
+		blobs:        map[string]*storageBlob{},
+		blocks:       map[string]map[string][]byte{},
+	}
+	c.containers[name] = ct
+	w.Header().Set("ETag", ct.etag)
+	w.Header().Set("Last-Modified", ct.lastModified.Format(http.TimeFormat))
+	return nil
+}
+
+//deleteContainer deletes the container and its blobs, the name is immediately available
+func (c *cloud) deleteContainer(name string) error {
+	_, err := c.container(name)
+	if err != nil {
+		return err
+	}
+	delete(c.containers, name)
+	return nil
+}
+
+func (c *cloud) getContainer(w http.ResponseWriter, name string) error {
+	ct, err := c.container(name)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("ETag", ct.etag)
+	w.Header().Set("Last-Modified", ct.lastModified.Format(http.TimeFormat))
+	return nil
+}
+
+type blobList struct {
+	XMLName         xml.Name    `xml:"EnumerationResults"`
+	ServiceEndpoint string      `xml:"ServiceEndpoint,attr"`
+	ContainerName   string      `xml:"ContainerName,attr"`
+	Prefix          string      `xml:"Prefix,omitempty"`
+	Marker          string      `xml:"Marker,omitempty"`
+	MaxResults      string      `xml:"MaxResults,omitempty"`
+	Blobs           []blobEntry `xml:"Blobs>Blob"`
+	NextMarker      string      `xml:"NextMarker"`
+}
+
+type blobEntry struct {
+	Name          string `xml:"Name"`
+	LastModified  string `xml:"Properties>Last-Modified"`
+	Etag          string `xml:"Properties>Etag"`
+	ContentLength int    `xml:"Properties>Content-Length"`
+	ContentType   string `xml:"Properties>Content-Type"`
+	BlobType      string `xml:"Properties>BlobType"`
+}
+
+func (c *cloud) listBlobs(container string, query url.Values) (interface{}, error) {
+	ct, err := c.container(container)
+	if err != nil {
+		return nil, err
+	}
+	res := &blobList{
+		ServiceEndpoint: c.url + blobServicePrefix + "/" + StorageAccountName + "/",
+		ContainerName:   container,
+		Prefix:          query.Get("prefix"),
+		Marker:          query.Get("marker"),
+		MaxResults:      query.Get("maxresults"),
+	}
+	var names []string
+	for name := range ct.blobs {
+		if strings.HasPrefix(name, res.Prefix) {
+			names = append(names, name)
+		}
+	}
+	names, res.NextMarker = page(names, query)
+	for _, name := range names {
+		b := ct.blobs[name]
+		res.Blobs = append(res.Blobs, blobEntry{
+			Name:          name,
+			LastModified:  b.lastModified.Format(http.TimeFormat),
+			Etag:          b.etag,
+			ContentLength: len(b.content),
+			ContentType:   b.contentType,
+			BlobType:      "BlockBlob",
+		})
+	}
+	return res, nil
+}
+
+//contentType returns the content type of the blob created by r
+func contentType(r *http.Request) string {
+	if t := r.Header.Get("x-ms-blob-content-type"); t != "" {
+		return t
+	}
+	if t := r.Header.Get("Content-Type"); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
+
+func (c *cloud) commitBlob(w http.ResponseWriter, ct *storageContainer, name, contentType string, content []byte) {
+	b := &storageBlob{
+		content:      content,
+		contentType:  contentType,
+		etag:         storageETag(),
+		lastModified: storageTime(),
+	}
+	ct.blobs[name] = b
+	delete(ct.blocks, name)
+	w.Header().Set("ETag", b.etag)
+	w.Header().Set("Last-Modified", b.lastModified.Format(http.TimeFormat))
+}
+
+//putBlob creates or replaces a block blob, page and append blobs are not supported
+func (c *cloud) putBlob(w http.ResponseWriter, r *http.Request, container, name string, body []byte) error {
+	ct, err := c.container(container)
+	if err != nil {
+		return err
+	}
+	switch r.Header.Get("x-ms-blob-type") {
+	case "BlockBlob":
+	case "":
+		return errorf(http.StatusBadRequest, "MissingRequiredHeader", "An HTTP header that's mandatory for this request is not specified.")
+	default:
+		return errorf(http.StatusBadRequest, "InvalidHeaderValue", "The value for one of the HTTP headers is not in the correct format.")
+	}
+	c.commitBlob(w, ct, name, contentType(r), body)
+	return nil
+}
+
+func (c *cloud) putBlock(container, name, id string, body []byte) error {
+	ct, err := c.container(container)
+	if err != nil {
+		return err
+	}
+	if id == "" {
+		return errorf(http.StatusBadRequest, "InvalidQueryParameterValue", "Value for one of the query parameters specified in the request URI is invalid.")
+	}
+	if ct.blocks[name] == nil {
+		ct.blocks[name] = map[string][]byte{}
+	}
+	ct.blocks[name][id] = body
+	return nil
+}
+
+type blockList struct {
+	Blocks []struct {
+		XMLName xml.Name
+		ID      string `xml:",chardata"`
+	} `xml:",any"`
+}
+
+//putBlockList commits the uncommitted blocks of the list, committed blocks are not kept once the blob is committed
+func (c *cloud) putBlockList(w http.ResponseWriter, r *http.Request, container, name string, body []byte) error {
+	ct, err := c.container(container)
+	if err != nil {
+		return err
+	}
+	var list blockList
+	err = xml.Unmarshal(body, &list)
+	if err != nil {
+		return errorf(http.StatusBadRequest, "InvalidXmlDocument", "XML specified is not syntactically valid.")
+	}
+	var content []byte
+	for _, b := range list.Blocks {
+		block, ok := ct.blocks[name][b.ID]
+		if !ok || b.XMLName.Local == "Committed" {
+			return errorf(http.StatusBadRequest, "InvalidBlockList", "The specified block list is invalid.")
+		}
+		content = append(content, block...)
+	}
+	t := r.Header.Get("x-ms-blob-content-type")
+	if t == "" {
+		t = "application/octet-stream"
+	}
+	c.commitBlob(w, ct, name, t, content)
+	return nil
+}
+
+//getBlob implements Get Blob and Get Blob Properties
+func (c *cloud) getBlob(w http.ResponseWriter, r *http.Request, container, name string) error {
+	b, err := c.blob(container, name)
+	if err != nil {
+		return err
+	}
+	setStorageHeaders(w)
+	w.Header().Set("Content-Type", b.contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(b.content)))
+	w.Header().Set("ETag", b.etag)
+	w.Header().Set("Last-Modified", b.lastModified.Format(http.TimeFormat))
+	w.Header().Set("x-ms-blob-type", "BlockBlob")
+	w.Header().Set("Accept-Ranges", "bytes")
+	if r.Method == http.MethodGet {
+		_, _ = w.Write(b.content)
+	}
+	return nil
+}
+
+func (c *cloud) deleteBlob(container, name string) error {
+	ct, err := c.container(container)
+	if err != nil {
+		return err
+	}
+	if _, ok := ct.blobs[name]; !ok {
+		return errorf(http.StatusNotFound, "BlobNotFound", "The specified blob does not exist.")
+	}
+	delete(ct.blobs, name)
+	return nil
+}
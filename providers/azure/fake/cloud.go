@@ -0,0 +1,212 @@
+package fake
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	computeNamespace  = "Microsoft.Compute"
+	networkNamespace  = "Microsoft.Network"
+	commerceNamespace = "Microsoft.Commerce"
+
+	stateUpdating  = "Updating"
+	stateDeleting  = "Deleting"
+	stateSucceeded = "Succeeded"
+)
+
+//cloud state of the fake subscription, shared by the resource providers
+//Resources are kept in creation order
+type cloud struct {
+	url               string
+	tokens            map[string]bool
+	operations        map[string]*operation
+	sizes             []vmSize
+	images            []*image
+	meters            []meter
+	virtualNetworks   []*virtualNetwork
+	securityGroups    []*securityGroup
+	networkInterfaces []*networkInterface
+	publicIPAddresses []*publicIPAddress
+	loadBalancers     []*loadBalancer
+	dnsZones          []*dnsZone
+	virtualMachines   []*virtualMachine
+	scaleSets         []*virtualMachineScaleSet
+	disks             []*disk
+	snapshots         []*snapshot
+	managedImages     []*managedImage
+	sshPublicKeys     []*sshPublicKeyResource
+	//containers blob containers of the storage account by name
+	containers map[string]*storageContainer
+}
+
+func newCloud(url string) *cloud {
+	return &cloud{
+		url:        url,
+		tokens:     map[string]bool{},
+		operations: map[string]*operation{},
+		sizes:      newSizes(),
+		images:     newImages(),
+		meters:     newMeters(),
+		containers: map[string]*storageContainer{},
+	}
+}
+
+func newID() string {
+	return uuid.New().String()
+}
+
+//timestamp returns the current time in the format used by the resource manager
+func timestamp() string {
+	return time.Now().UTC().Format(time.RFC3339Nano)
+}
+
+//etag returns a new entity tag
+func etag() string {
+	return fmt.Sprintf("W/\"%s\"", newID())
+}
+
+//resourceID returns the identifier of the resource of type kind (e.g. virtualNetworks) named by names
+//names holds the name of the resource followed by the type and the name of its children
+func resourceID(namespace, kind string, names ...string) string {
+	return fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/%s/%s/%s",
+		SubscriptionID, ResourceGroupName, namespace, kind, strings.Join(names, "/"))
+}
+
+//parseID returns the names of the resource of type kind identified by id, the name of the resource
+//is followed by the type and the name of its children
+func parseID(id, namespace, kind string) ([]string, bool) {
+	prefix := resourceID(namespace, kind)
+	if len(id) <= len(prefix) || !strings.EqualFold(id[:len(prefix)], prefix) {
+		return nil, false
+	}
+	return strings.Split(id[len(prefix):], "/"), true
+}
+
+//reference reference to another resource
+type reference struct {
+	ID string `json:"id"`
+}
+
+//resource properties shared by the top level resources
+type resource struct {
+	ID       string            `json:"id"`
+	Name     string            `json:"name"`
+	Type     string            `json:"type"`
+	Location string            `json:"location"`
+	Tags     map[string]string `json:"tags,omitempty"`
+	Etag     string            `json:"etag,omitempty"`
+}
+
+//checkLocation checks that the location of a new resource is the location simulated by the fake
+func checkLocation(location string) error {
+	if location == "" {
+		return badRequest("LocationRequired", "The location property is required for this definition.")
+	}
+	if !strings.EqualFold(location, Location) {
+		return badRequest("LocationNotAvailableForResourceType", "The provided location '%s' is not available for resource type. List of available regions for the resource type is '%s'.", location, Location)
+	}
+	return nil
+}
+
+//operation long running operation
+type operation struct {
+	ID        string
+	StartTime string
+	Status    string
+	polls     int
+	done      func()
+}
+
+func (c *cloud) startOperation(done func()) *operation {
+	op := &operation{
+		ID:        newID(),
+		StartTime: timestamp(),
+		Status:    "InProgress",
+		done:      done,
+	}
+	c.operations[op.ID] = op
+	return op
+}
+
+//poll returns the status of the operation, the operation completes at its second poll
+func (op *operation) poll() map[string]interface{} {
+	op.polls++
+	if op.Status == "InProgress" && op.polls > 1 {
+		if op.done != nil {
+			op.done()
+		}
+		op.Status = stateSucceeded
+	}
+	status := map[string]interface{}{
+		"name":      op.ID,
+		"status":    op.Status,
+		"startTime": op.StartTime,
+	}
+	if op.Status != "InProgress" {
+		status["endTime"] = timestamp()
+	}
+	return status
+}
+
+func getOperation(c *cloud, r *request) (interface{}, error) {
+	op, ok := c.operations[r.params[2]]
+	if !ok {
+		return nil, errorf(http.StatusNotFound, "OperationNotFound", "The operation '%s' could not be found.", r.params[2])
+	}
+	return op.poll(), nil
+}
+
+//authorized returns true if the request carries a bearer token issued by the fake
+func (c *cloud) authorized(r *http.Request) bool {
+	auth := r.Header.Get("Authorization")
+	return strings.HasPrefix(auth, "Bearer ") && c.tokens[strings.TrimPrefix(auth, "Bearer ")]
+}
+
+//issueToken serves the Azure Active Directory token endpoint of tenant using the client credentials grant
+func issueToken(c *cloud, w http.ResponseWriter, r *http.Request, tenant string) {
+	oauthError := func(status int, code, description string) {
+		writeJSON(w, status, map[string]string{
+			"error":             code,
+			"error_description": description,
+		})
+	}
+	if r.Method != http.MethodPost {
+		oauthError(http.StatusMethodNotAllowed, "invalid_request", "AADSTS900561: The endpoint only accepts POST requests.")
+		return
+	}
+	if tenant != TenantID {
+		oauthError(http.StatusBadRequest, "invalid_request", fmt.Sprintf("AADSTS90002: Tenant '%s' not found.", tenant))
+		return
+	}
+	err := r.ParseForm()
+	if err != nil || r.PostForm.Get("grant_type") != "client_credentials" {
+		oauthError(http.StatusBadRequest, "unsupported_grant_type", "AADSTS70003: The app requested an unsupported grant type.")
+		return
+	}
+	if r.PostForm.Get("client_id") != ClientID {
+		oauthError(http.StatusBadRequest, "unauthorized_client", fmt.Sprintf("AADSTS700016: Application with identifier '%s' was not found in the directory.", r.PostForm.Get("client_id")))
+		return
+	}
+	if r.PostForm.Get("client_secret") != ClientSecret {
+		oauthError(http.StatusUnauthorized, "invalid_client", "AADSTS7000215: Invalid client secret is provided.")
+		return
+	}
+	token := newID()
+	c.tokens[token] = true
+	now := time.Now().Unix()
+	writeJSON(w, http.StatusOK, map[string]string{
+		"access_token":   token,
+		"token_type":     "Bearer",
+		"expires_in":     "3600",
+		"ext_expires_in": "3600",
+		"expires_on":     strconv.FormatInt(now+3600, 10),
+		"not_before":     strconv.FormatInt(now, 10),
+		"resource":       r.PostForm.Get("resource"),
+	})
+}
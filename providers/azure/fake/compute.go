@@ -0,0 +1,240 @@
+package fake
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+func computeRoutes() []route {
+	const locations = "providers/Microsoft.Compute/locations/*/"
+	const offers = locations + "publishers/*/artifacttypes/vmimage/offers"
+	const virtualMachines = "resourceGroups/*/providers/Microsoft.Compute/virtualMachines"
+	const scaleSets = "resourceGroups/*/providers/Microsoft.Compute/virtualMachineScaleSets"
+	const disks = "resourceGroups/*/providers/Microsoft.Compute/disks"
+	const snapshots = "resourceGroups/*/providers/Microsoft.Compute/snapshots"
+	const images = "resourceGroups/*/providers/Microsoft.Compute/images"
+	const sshPublicKeys = "resourceGroups/*/providers/Microsoft.Compute/sshPublicKeys"
+	return []route{
+		{"GET", locations + "vmSizes", http.StatusOK, listVMSizes},
+		{"GET", offers, http.StatusOK, listOffers},
+		{"GET", offers + "/*/skus", http.StatusOK, listSkus},
+		{"GET", offers + "/*/skus/*/versions", http.StatusOK, listVersions},
+		{"GET", offers + "/*/skus/*/versions/*", http.StatusOK, getImage},
+		{"GET", virtualMachines, http.StatusOK, listVirtualMachines},
+		{"GET", virtualMachines + "/*", http.StatusOK, getVirtualMachine},
+		{"PUT", virtualMachines + "/*", http.StatusOK, putVirtualMachine},
+		{"DELETE", virtualMachines + "/*", http.StatusNoContent, deleteVirtualMachine},
+		{"POST", virtualMachines + "/*/start", http.StatusAccepted, startVirtualMachine},
+		{"POST", virtualMachines + "/*/powerOff", http.StatusAccepted, powerOffVirtualMachine},
+		{"POST", virtualMachines + "/*/deallocate", http.StatusAccepted, deallocateVirtualMachine},
+		{"POST", virtualMachines + "/*/restart", http.StatusAccepted, restartVirtualMachine},
+		{"POST", virtualMachines + "/*/generalize", http.StatusOK, generalizeVirtualMachine},
+		{"GET", scaleSets, http.StatusOK, listScaleSets},
+		{"GET", scaleSets + "/*", http.StatusOK, getScaleSet},
+		{"PUT", scaleSets + "/*", http.StatusOK, putScaleSet},
+		{"PATCH", scaleSets + "/*", http.StatusOK, patchScaleSet},
+		{"DELETE", scaleSets + "/*", http.StatusNoContent, deleteScaleSet},
+		{"GET", scaleSets + "/*/virtualMachines", http.StatusOK, listScaleSetVMs},
+		{"DELETE", scaleSets + "/*/virtualMachines/*", http.StatusNoContent, deleteScaleSetVM},
+		{"GET", disks, http.StatusOK, listDisks},
+		{"GET", disks + "/*", http.StatusOK, getDisk},
+		{"PUT", disks + "/*", http.StatusOK, putDisk},
+		{"DELETE", disks + "/*", http.StatusNoContent, deleteDisk},
+		{"GET", snapshots, http.StatusOK, listSnapshots},
+		{"GET", snapshots + "/*", http.StatusOK, getSnapshot},
+		{"PUT", snapshots + "/*", http.StatusOK, putSnapshot},
+		{"DELETE", snapshots + "/*", http.StatusNoContent, deleteSnapshot},
+		{"GET", images, http.StatusOK, listManagedImages},
+		{"GET", images + "/*", http.StatusOK, getManagedImage},
+		{"PUT", images + "/*", http.StatusOK, putManagedImage},
+		{"DELETE", images + "/*", http.StatusNoContent, deleteManagedImage},
+		{"GET", sshPublicKeys, http.StatusOK, listSSHPublicKeys},
+		{"GET", sshPublicKeys + "/*", http.StatusOK, getSSHPublicKey},
+		{"PUT", sshPublicKeys + "/*", http.StatusOK, putSSHPublicKey},
+		{"DELETE", sshPublicKeys + "/*", http.StatusOK, deleteSSHPublicKey},
+	}
+}
+
+type vmSize struct {
+	Name                 string `json:"name"`
+	NumberOfCores        int    `json:"numberOfCores"`
+	OsDiskSizeInMB       int    `json:"osDiskSizeInMB"`
+	ResourceDiskSizeInMB int    `json:"resourceDiskSizeInMB"`
+	MemoryInMB           int    `json:"memoryInMB"`
+	MaxDataDiskCount     int    `json:"maxDataDiskCount"`
+	//price hourly price of the size
+	price float64
+}
+
+func newSizes() []vmSize {
+	return []vmSize{
+		{"Standard_B1s", 1, 1047552, 4096, 1024, 2, 0.0104},
+		{"Standard_B2s", 2, 1047552, 8192, 4096, 4, 0.0416},
+		{"Standard_D2s_v3", 2, 1047552, 16384, 8192, 4, 0.096},
+		{"Standard_D4s_v3", 4, 1047552, 32768, 16384, 8, 0.192},
+		{"Standard_D8s_v3", 8, 1047552, 65536, 32768, 16, 0.384},
+		{"Standard_E4s_v3", 4, 1047552, 65536, 32768, 8, 0.252},
+		{"Standard_F4s_v2", 4, 1047552, 32768, 8192, 8, 0.169},
+	}
+}
+
+func (c *cloud) size(name string) (*vmSize, bool) {
+	for i := range c.sizes {
+		if strings.EqualFold(c.sizes[i].Name, name) {
+			return &c.sizes[i], true
+		}
+	}
+	return nil, false
+}
+
+//listVMSizes returns the sizes of the virtual machines, all the sizes are offered in all the locations
+func listVMSizes(c *cloud, r *request) (interface{}, error) {
+	if !knownLocation(r.params[0]) {
+		return nil, errorf(http.StatusNotFound, "NoRegisteredProviderFound", "No registered resource provider found for location '%s'.", r.params[0])
+	}
+	return map[string]interface{}{"value": c.sizes}, nil
+}
+
+//image virtual machine image of the marketplace
+type image struct {
+	Publisher string
+	Offer     string
+	Sku       string
+	Version   string
+}
+
+func newImages() []*image {
+	return []*image{
+		{"Canonical", "UbuntuServer", "16.04-LTS", "16.04.201910090"},
+		{"Canonical", "UbuntuServer", "16.04-LTS", "16.04.201910230"},
+		{"Canonical", "UbuntuServer", "18.04-LTS", "18.04.201910030"},
+		{"Canonical", "UbuntuServer", "18.04-LTS", "18.04.201910180"},
+		{"OpenLogic", "CentOS", "7.7", "7.7.2019102400"},
+	}
+}
+
+//findImages returns the images matching the non empty criteria, the comparison is case insensitive
+func (c *cloud) findImages(publisher, offer, sku, version string) []*image {
+	match := func(criterion, value string) bool {
+		return criterion == "" || strings.EqualFold(criterion, value)
+	}
+	var l []*image
+	for _, img := range c.images {
+		if match(publisher, img.Publisher) && match(offer, img.Offer) && match(sku, img.Sku) && match(version, img.Version) {
+			l = append(l, img)
+		}
+	}
+	return l
+}
+
+//artifact returns the representation of an image artifact (offer, sku or version) named name and located at path
+func artifact(name, path string) map[string]string {
+	return map[string]string{
+		"location": Location,
+		"name":     name,
+		"id": fmt.Sprintf("/Subscriptions/%s/Providers/Microsoft.Compute/Locations/%s/Publishers/%s",
+			SubscriptionID, Location, path),
+	}
+}
+
+//listArtifacts returns the distinct artifacts named by name among images
+func listArtifacts(images []*image, name func(img *image) string, path func(img *image) string) []map[string]string {
+	l := []map[string]string{}
+	found := map[string]bool{}
+	for _, img := range images {
+		n := name(img)
+		if found[n] {
+			continue
+		}
+		found[n] = true
+		l = append(l, artifact(n, path(img)))
+	}
+	return l
+}
+
+func imageNotFound() error {
+	return errorf(http.StatusNotFound, "NotFound", "Artifact: VMImage was not found.")
+}
+
+func listOffers(c *cloud, r *request) (interface{}, error) {
+	err := checkLocation(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	images := c.findImages(r.params[1], "", "", "")
+	if len(images) == 0 {
+		return nil, imageNotFound()
+	}
+	return listArtifacts(images,
+		func(img *image) string { return img.Offer },
+		func(img *image) string {
+			return fmt.Sprintf("%s/ArtifactTypes/VMImage/Offers/%s", img.Publisher, img.Offer)
+		}), nil
+}
+
+func listSkus(c *cloud, r *request) (interface{}, error) {
+	err := checkLocation(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	images := c.findImages(r.params[1], r.params[2], "", "")
+	if len(images) == 0 {
+		return nil, imageNotFound()
+	}
+	return listArtifacts(images,
+		func(img *image) string { return img.Sku },
+		func(img *image) string {
+			return fmt.Sprintf("%s/ArtifactTypes/VMImage/Offers/%s/Skus/%s", img.Publisher, img.Offer, img.Sku)
+		}), nil
+}
+
+func versionPath(img *image) string {
+	return fmt.Sprintf("%s/ArtifactTypes/VMImage/Offers/%s/Skus/%s/Versions/%s", img.Publisher, img.Offer, img.Sku, img.Version)
+}
+
+func listVersions(c *cloud, r *request) (interface{}, error) {
+	err := checkLocation(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	images := c.findImages(r.params[1], r.params[2], r.params[3], "")
+	if len(images) == 0 {
+		return nil, imageNotFound()
+	}
+	if top := r.URL.Query().Get("$top"); top != "" {
+		n, err := strconv.Atoi(top)
+		if err != nil || n < 0 {
+			return nil, badRequest("InvalidParameter", "The value '%s' of parameter '$top' is invalid.", top)
+		}
+		if n < len(images) {
+			images = images[:n]
+		}
+	}
+	return listArtifacts(images,
+		func(img *image) string { return img.Version },
+		versionPath), nil
+}
+
+func getImage(c *cloud, r *request) (interface{}, error) {
+	err := checkLocation(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	images := c.findImages(r.params[1], r.params[2], r.params[3], r.params[4])
+	if len(images) == 0 {
+		return nil, imageNotFound()
+	}
+	img := images[0]
+	view := map[string]interface{}{}
+	for k, v := range artifact(img.Version, versionPath(img)) {
+		view[k] = v
+	}
+	view["properties"] = map[string]interface{}{
+		"osImage":          map[string]string{"operatingSystem": "Linux"},
+		"dataDiskImages":   []interface{}{},
+		"hyperVGeneration": "V1",
+	}
+	return view, nil
+}
@@ -0,0 +1,284 @@
+package fake
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+const (
+	diskUnattached = "Unattached"
+	diskAttached   = "Attached"
+)
+
+//diskTier performance of the disks of a SKU whose size is at most MaxSizeGB
+type diskTier struct {
+	MaxSizeGB int
+	IOPS      int64
+	MBps      int
+}
+
+//diskTiers performance tiers of the managed disk SKUs, UltraSSD_LRS disks have a provisioned performance
+var diskTiers = map[string][]diskTier{
+	"Standard_LRS": {
+		{4096, 500, 60}, {8192, 1300, 300}, {16384, 2000, 500}, {32767, 2000, 500},
+	},
+	"StandardSSD_LRS": {
+		{4096, 500, 60}, {8192, 2000, 400}, {16384, 4000, 600}, {32767, 6000, 750},
+	},
+	"Premium_LRS": {
+		{32, 120, 25}, {64, 240, 50}, {128, 500, 100}, {256, 1100, 125}, {512, 2300, 150}, {1024, 5000, 200},
+		{4096, 7500, 250}, {8192, 16000, 500}, {16384, 18000, 750}, {32767, 20000, 900},
+	},
+	"UltraSSD_LRS": nil,
+}
+
+var diskName = regexp.MustCompile(`^[A-Za-z0-9_.\-]{1,80}$`)
+
+type diskSku struct {
+	Name string `json:"name"`
+	Tier string `json:"tier,omitempty"`
+}
+
+type diskProperties struct {
+	OsType       string `json:"osType,omitempty"`
+	CreationData struct {
+		CreateOption     string `json:"createOption"`
+		SourceResourceID string `json:"sourceResourceId,omitempty"`
+	} `json:"creationData"`
+	DiskSizeGB        int    `json:"diskSizeGB,omitempty"`
+	DiskIOPSReadWrite int64  `json:"diskIOPSReadWrite,omitempty"`
+	DiskMBpsReadWrite int    `json:"diskMBpsReadWrite,omitempty"`
+	DiskState         string `json:"diskState,omitempty"`
+	UniqueID          string `json:"uniqueId,omitempty"`
+	TimeCreated       string `json:"timeCreated,omitempty"`
+	ProvisioningState string `json:"provisioningState"`
+}
+
+type disk struct {
+	resource
+	ManagedBy  string         `json:"managedBy,omitempty"`
+	Sku        *diskSku       `json:"sku,omitempty"`
+	Properties diskProperties `json:"properties"`
+}
+
+func (c *cloud) disk(name string) (*disk, error) {
+	for _, d := range c.disks {
+		if strings.EqualFold(d.Name, name) {
+			return d, nil
+		}
+	}
+	return nil, notFound("Microsoft.Compute/disks", name)
+}
+
+//diskByID returns the disk identified by id
+func (c *cloud) diskByID(id string) (*disk, error) {
+	names, ok := parseID(id, computeNamespace, "disks")
+	if ok && len(names) == 1 {
+		d, err := c.disk(names[0])
+		if err == nil {
+			return d, nil
+		}
+	}
+	return nil, errorf(http.StatusNotFound, "NotFound", "Disk %s was not found.", id)
+}
+
+//newDisk creates a disk, the disk is added to the cloud by the caller
+func newDisk(name, sku string, sizeGB int) *disk {
+	d := &disk{
+		resource: resource{
+			ID:       resourceID(computeNamespace, "disks", name),
+			Name:     name,
+			Type:     "Microsoft.Compute/disks",
+			Location: Location,
+			Tags:     map[string]string{},
+		},
+		Sku: &diskSku{Name: sku, Tier: strings.Split(sku, "_")[0]},
+	}
+	d.Properties.DiskSizeGB = sizeGB
+	d.Properties.DiskState = diskUnattached
+	d.Properties.UniqueID = newID()
+	d.Properties.TimeCreated = timestamp()
+	d.Properties.ProvisioningState = stateSucceeded
+	d.setPerformance(0, 0)
+	return d
+}
+
+//setPerformance sets the performance of d, iops and mbps are only used by UltraSSD_LRS disks
+func (d *disk) setPerformance(iops int64, mbps int) {
+	p := &d.Properties
+	for _, t := range diskTiers[d.Sku.Name] {
+		if p.DiskSizeGB <= t.MaxSizeGB {
+			p.DiskIOPSReadWrite = t.IOPS
+			p.DiskMBpsReadWrite = t.MBps
+			return
+		}
+	}
+	if iops == 0 {
+		iops = int64(p.DiskSizeGB) * 300
+		if iops > 160000 {
+			iops = 160000
+		}
+	}
+	if mbps == 0 {
+		mbps = int(iops / 4)
+		if mbps > 2000 {
+			mbps = 2000
+		}
+	}
+	p.DiskIOPSReadWrite = iops
+	p.DiskMBpsReadWrite = mbps
+}
+
+//checkDisk checks the properties of the disk in
+func checkDisk(in *disk) error {
+	if in.Sku == nil || in.Sku.Name == "" {
+		in.Sku = &diskSku{Name: "Standard_LRS"}
+	}
+	sku := oneOf(in.Sku.Name, "Standard_LRS", "StandardSSD_LRS", "Premium_LRS", "UltraSSD_LRS")
+	if sku == "" {
+		return badRequest("InvalidParameter", "The value '%s' of parameter 'sku.name' is not valid.", in.Sku.Name)
+	}
+	in.Sku = &diskSku{Name: sku, Tier: strings.Split(sku, "_")[0]}
+	p := &in.Properties
+	max := 32767
+	if sku == "UltraSSD_LRS" {
+		max = 65536
+	}
+	if p.DiskSizeGB < 1 || p.DiskSizeGB > max {
+		return badRequest("InvalidParameter", "The value %d of parameter 'diskSizeGB' is out of range. Value must be between 1 and %d inclusive.", p.DiskSizeGB, max)
+	}
+	if sku != "UltraSSD_LRS" {
+		if p.DiskIOPSReadWrite != 0 || p.DiskMBpsReadWrite != 0 {
+			return badRequest("InvalidParameter", "Setting diskIOPSReadWrite or diskMBpsReadWrite is only supported for UltraSSD_LRS disks.")
+		}
+		return nil
+	}
+	if p.DiskSizeGB < 4 {
+		return badRequest("InvalidParameter", "The size of UltraSSD_LRS disks must be at least 4 GB.")
+	}
+	iops := p.DiskIOPSReadWrite
+	if iops != 0 && (iops < 100 || iops > int64(p.DiskSizeGB)*300 || iops > 160000) {
+		return badRequest("InvalidParameter", "The value %d of parameter 'diskIOPSReadWrite' is out of range for a disk of %d GB.", iops, p.DiskSizeGB)
+	}
+	if iops == 0 {
+		iops = int64(p.DiskSizeGB) * 300
+	}
+	if mbps := p.DiskMBpsReadWrite; mbps != 0 && (mbps < 1 || int64(mbps)*4 > iops || mbps > 2000) {
+		return badRequest("InvalidParameter", "The value %d of parameter 'diskMBpsReadWrite' is out of range for a disk with %d IOPS.", mbps, iops)
+	}
+	return nil
+}
+
+//detachedOrStopped returns an error if d is attached to a virtual machine that is not deallocated
+func (c *cloud) detachedOrStopped(d *disk, operation string) error {
+	if d.ManagedBy == "" {
+		return nil
+	}
+	names, _ := parseID(d.ManagedBy, computeNamespace, "virtualMachines")
+	vm, err := c.virtualMachine(names[0])
+	if err != nil || vm.powerState == powerDeallocated {
+		return nil
+	}
+	return errorf(http.StatusConflict, "OperationNotAllowed", "%s is allowed only when the disk is unattached or when the VM %s is deallocated.", operation, vm.Name)
+}
+
+func listDisks(c *cloud, r *request) (interface{}, error) {
+	return map[string]interface{}{"value": c.disks}, nil
+}
+
+func getDisk(c *cloud, r *request) (interface{}, error) {
+	return c.disk(r.params[0])
+}
+
+func putDisk(c *cloud, r *request) (interface{}, error) {
+	in := &disk{}
+	err := r.decode(in)
+	if err != nil {
+		return nil, err
+	}
+	if !diskName.MatchString(r.params[0]) {
+		return nil, badRequest("InvalidParameter", "The entity name '%s' is invalid according to its validation rule.", r.params[0])
+	}
+	d, err := c.disk(r.params[0])
+	created := err != nil
+	var source *snapshot
+	if created {
+		err = checkLocation(in.Location)
+		if err != nil {
+			return nil, err
+		}
+		option := in.Properties.CreationData.CreateOption
+		switch {
+		case strings.EqualFold(option, "Empty"):
+		case strings.EqualFold(option, "Copy"):
+			source, err = c.snapshotByID(in.Properties.CreationData.SourceResourceID)
+			if err != nil {
+				return nil, err
+			}
+			if in.Properties.DiskSizeGB == 0 {
+				in.Properties.DiskSizeGB = source.Properties.DiskSizeGB
+			}
+			if in.Properties.DiskSizeGB < source.Properties.DiskSizeGB {
+				return nil, badRequest("BadRequest", "The size %d GB of the disk is smaller than the size %d GB of the snapshot %s.", in.Properties.DiskSizeGB, source.Properties.DiskSizeGB, source.Name)
+			}
+		default:
+			return nil, badRequest("InvalidParameter", "The value '%s' of parameter 'creationData.createOption' is not supported.", option)
+		}
+	}
+	err = checkDisk(in)
+	if err != nil {
+		return nil, err
+	}
+	if created {
+		d = newDisk(r.params[0], in.Sku.Name, in.Properties.DiskSizeGB)
+		d.Properties.CreationData.CreateOption = "Empty"
+		if source != nil {
+			d.Properties.CreationData.CreateOption = "Copy"
+			d.Properties.CreationData.SourceResourceID = source.ID
+		}
+		c.disks = append(c.disks, d)
+	} else {
+		if in.Properties.DiskSizeGB < d.Properties.DiskSizeGB {
+			return nil, badRequest("BadRequest", "Disk %s cannot be resized from %d GB to %d GB, disks can only be enlarged.", d.Name, d.Properties.DiskSizeGB, in.Properties.DiskSizeGB)
+		}
+		if (in.Sku.Name == "UltraSSD_LRS") != (d.Sku.Name == "UltraSSD_LRS") {
+			return nil, badRequest("BadRequest", "The SKU of disk %s cannot be changed to or from UltraSSD_LRS.", d.Name)
+		}
+		if in.Properties.DiskSizeGB != d.Properties.DiskSizeGB || in.Sku.Name != d.Sku.Name {
+			err = c.detachedOrStopped(d, "Changing the size or the SKU of a disk")
+			if err != nil {
+				return nil, err
+			}
+		}
+		d.Sku = in.Sku
+		d.Properties.DiskSizeGB = in.Properties.DiskSizeGB
+	}
+	d.setPerformance(in.Properties.DiskIOPSReadWrite, in.Properties.DiskMBpsReadWrite)
+	if in.Tags != nil {
+		d.Tags = in.Tags
+	}
+	d.Properties.ProvisioningState = stateUpdating
+	return putResponse(computeNamespace, created, d, func() {
+		d.Properties.ProvisioningState = stateSucceeded
+	}), nil
+}
+
+func deleteDisk(c *cloud, r *request) (interface{}, error) {
+	d, err := c.disk(r.params[0])
+	if err != nil {
+		return nil, nil
+	}
+	if d.ManagedBy != "" {
+		return nil, errorf(http.StatusConflict, "OperationNotAllowed", "Disk %s is attached to VM %s.", d.Name, d.ManagedBy)
+	}
+	d.Properties.ProvisioningState = stateDeleting
+	return accepted(computeNamespace, func() {
+		for i, o := range c.disks {
+			if o == d {
+				c.disks = append(c.disks[:i], c.disks[i+1:]...)
+				break
+			}
+		}
+	}), nil
+}
@@ -0,0 +1,346 @@
+package fake
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+//dnsLocation location of the DNS zones, which are global resources
+const dnsLocation = "global"
+
+type dnsZoneProperties struct {
+	MaxNumberOfRecordSets int64    `json:"maxNumberOfRecordSets"`
+	NumberOfRecordSets    int64    `json:"numberOfRecordSets"`
+	NameServers           []string `json:"nameServers"`
+	ZoneType              string   `json:"zoneType"`
+}
+
+type dnsZone struct {
+	resource
+	Properties dnsZoneProperties `json:"properties"`
+
+	recordSets []*recordSet
+}
+
+type aRecord struct {
+	IPv4Address string `json:"ipv4Address"`
+}
+
+type aaaaRecord struct {
+	IPv6Address string `json:"ipv6Address"`
+}
+
+type cnameRecord struct {
+	Cname string `json:"cname"`
+}
+
+type txtRecord struct {
+	Value []string `json:"value"`
+}
+
+type nsRecord struct {
+	Nsdname string `json:"nsdname"`
+}
+
+type soaRecord struct {
+	Host         string `json:"host"`
+	Email        string `json:"email"`
+	SerialNumber int64  `json:"serialNumber"`
+	RefreshTime  int64  `json:"refreshTime"`
+	RetryTime    int64  `json:"retryTime"`
+	ExpireTime   int64  `json:"expireTime"`
+	MinimumTTL   int64  `json:"minimumTTL"`
+}
+
+type recordSetProperties struct {
+	Metadata          map[string]string `json:"metadata,omitempty"`
+	TTL               int64             `json:"TTL"`
+	Fqdn              string            `json:"fqdn"`
+	ProvisioningState string            `json:"provisioningState"`
+	ARecords          []aRecord         `json:"ARecords,omitempty"`
+	AAAARecords       []aaaaRecord      `json:"AAAARecords,omitempty"`
+	CNAMERecord       *cnameRecord      `json:"CNAMERecord,omitempty"`
+	TXTRecords        []txtRecord       `json:"TXTRecords,omitempty"`
+	NSRecords         []nsRecord        `json:"NSRecords,omitempty"`
+	SOARecord         *soaRecord        `json:"SOARecord,omitempty"`
+}
+
+type recordSet struct {
+	ID         string              `json:"id"`
+	Name       string              `json:"name"`
+	Type       string              `json:"type"`
+	Etag       string              `json:"etag,omitempty"`
+	Properties recordSetProperties `json:"properties"`
+
+	recordType string
+}
+
+func (c *cloud) dnsZone(name string) (*dnsZone, error) {
+	for _, z := range c.dnsZones {
+		if strings.EqualFold(z.Name, name) {
+			return z, nil
+		}
+	}
+	return nil, notFound("Microsoft.Network/dnszones", name)
+}
+
+//recordSet returns the record set of type t named name
+func (z *dnsZone) recordSet(t, name string) (*recordSet, error) {
+	for _, rs := range z.recordSets {
+		if strings.EqualFold(rs.recordType, t) && strings.EqualFold(rs.Name, name) {
+			return rs, nil
+		}
+	}
+	return nil, notFound("Microsoft.Network/dnszones/"+strings.ToUpper(t), z.Name+"/"+name)
+}
+
+//fqdn returns the fully qualified domain name of the record set named name
+func (z *dnsZone) fqdn(name string) string {
+	if name == "@" {
+		return z.Name + "."
+	}
+	return name + "." + z.Name + "."
+}
+
+func (z *dnsZone) newRecordSet(t, name string, ttl int64) *recordSet {
+	t = strings.ToUpper(t)
+	return &recordSet{
+		ID:   resourceID(networkNamespace, "dnszones", z.Name, t, name),
+		Name: name,
+		Type: "Microsoft.Network/dnszones/" + t,
+		Etag: newID(),
+		Properties: recordSetProperties{
+			TTL:               ttl,
+			Fqdn:              z.fqdn(name),
+			ProvisioningState: stateSucceeded,
+		},
+		recordType: t,
+	}
+}
+
+func (z *dnsZone) view() *dnsZone {
+	v := *z
+	v.Properties.NumberOfRecordSets = int64(len(z.recordSets))
+	return &v
+}
+
+func listDNSZones(c *cloud, r *request) (interface{}, error) {
+	l := []*dnsZone{}
+	for _, z := range c.dnsZones {
+		l = append(l, z.view())
+	}
+	return map[string]interface{}{"value": l}, nil
+}
+
+func getDNSZone(c *cloud, r *request) (interface{}, error) {
+	z, err := c.dnsZone(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	return z.view(), nil
+}
+
+func putDNSZone(c *cloud, r *request) (interface{}, error) {
+	in := &dnsZone{}
+	err := r.decode(in)
+	if err != nil {
+		return nil, err
+	}
+	name := strings.ToLower(r.params[0])
+	if !strings.Contains(name, ".") || strings.ContainsAny(name, " @") || strings.Contains(name, "..") {
+		return nil, badRequest("BadRequest", "The zone name '%s' is invalid.", r.params[0])
+	}
+	z, err := c.dnsZone(name)
+	if err == nil {
+		if r.Header.Get("If-None-Match") == "*" {
+			return nil, errorf(http.StatusPreconditionFailed, "PreconditionFailed", "The Zone %s exists already and hence cannot be created again.", name)
+		}
+		if in.Tags != nil {
+			z.Tags = in.Tags
+		}
+		z.Etag = newID()
+		return z.view(), nil
+	}
+	if !strings.EqualFold(in.Location, dnsLocation) {
+		return nil, badRequest("LocationNotAvailableForResourceType", "The provided location '%s' is not available for resource type 'Microsoft.Network/dnszones'. List of available regions for the resource type is '%s'.", in.Location, dnsLocation)
+	}
+	z = &dnsZone{
+		resource: resource{
+			ID:       resourceID(networkNamespace, "dnszones", name),
+			Name:     name,
+			Type:     "Microsoft.Network/dnszones",
+			Location: dnsLocation,
+			Tags:     in.Tags,
+			Etag:     newID(),
+		},
+	}
+	z.Properties.MaxNumberOfRecordSets = 10000
+	z.Properties.ZoneType = "Public"
+	ns := z.newRecordSet("NS", "@", 172800)
+	for i := 1; i <= 4; i++ {
+		server := fmt.Sprintf("ns%d-0%d.azure-dns.fake.", i, len(c.dnsZones)%10)
+		z.Properties.NameServers = append(z.Properties.NameServers, server)
+		ns.Properties.NSRecords = append(ns.Properties.NSRecords, nsRecord{Nsdname: server})
+	}
+	soa := z.newRecordSet("SOA", "@", 3600)
+	soa.Properties.SOARecord = &soaRecord{
+		Host:         z.Properties.NameServers[0],
+		Email:        "azuredns-hostmaster.microsoft.com",
+		SerialNumber: 1,
+		RefreshTime:  3600,
+		RetryTime:    300,
+		ExpireTime:   2419200,
+		MinimumTTL:   300,
+	}
+	z.recordSets = []*recordSet{soa, ns}
+	c.dnsZones = append(c.dnsZones, z)
+	return z.view(), nil
+}
+
+func deleteDNSZone(c *cloud, r *request) (interface{}, error) {
+	z, err := c.dnsZone(r.params[0])
+	if err != nil {
+		return nil, nil
+	}
+	return accepted(networkNamespace, func() {
+		for i, o := range c.dnsZones {
+			if o == z {
+				c.dnsZones = append(c.dnsZones[:i], c.dnsZones[i+1:]...)
+				break
+			}
+		}
+	}), nil
+}
+
+func listRecordSets(c *cloud, r *request) (interface{}, error) {
+	z, err := c.dnsZone(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"value": z.recordSets}, nil
+}
+
+func listRecordSetsByType(c *cloud, r *request) (interface{}, error) {
+	z, err := c.dnsZone(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	l := []*recordSet{}
+	for _, rs := range z.recordSets {
+		if strings.EqualFold(rs.recordType, r.params[1]) {
+			l = append(l, rs)
+		}
+	}
+	return map[string]interface{}{"value": l}, nil
+}
+
+func getRecordSet(c *cloud, r *request) (interface{}, error) {
+	z, err := c.dnsZone(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	return z.recordSet(r.params[1], r.params[2])
+}
+
+//checkRecordSet checks that the records of the record set in match its type t
+func checkRecordSet(t, name string, in *recordSet) error {
+	p := &in.Properties
+	switch t {
+	case "A":
+		if len(p.ARecords) == 0 {
+			return badRequest("BadRequest", "The record set must contain at least one A record.")
+		}
+		for _, a := range p.ARecords {
+			if ip := net.ParseIP(a.IPv4Address); ip == nil || ip.To4() == nil {
+				return badRequest("BadRequest", "The IPv4 address '%s' is invalid.", a.IPv4Address)
+			}
+		}
+	case "AAAA":
+		if len(p.AAAARecords) == 0 {
+			return badRequest("BadRequest", "The record set must contain at least one AAAA record.")
+		}
+		for _, a := range p.AAAARecords {
+			if ip := net.ParseIP(a.IPv6Address); ip == nil || ip.To4() != nil {
+				return badRequest("BadRequest", "The IPv6 address '%s' is invalid.", a.IPv6Address)
+			}
+		}
+	case "CNAME":
+		if p.CNAMERecord == nil || p.CNAMERecord.Cname == "" {
+			return badRequest("BadRequest", "The CNAME record set must contain a CNAME record.")
+		}
+		if name == "@" {
+			return badRequest("BadRequest", "A CNAME record set cannot be created at the zone apex.")
+		}
+	case "TXT":
+		if len(p.TXTRecords) == 0 {
+			return badRequest("BadRequest", "The record set must contain at least one TXT record.")
+		}
+	default:
+		return badRequest("BadRequest", "Record sets of type %s are not supported.", t)
+	}
+	return nil
+}
+
+func putRecordSet(c *cloud, r *request) (interface{}, error) {
+	z, err := c.dnsZone(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	t, name := strings.ToUpper(r.params[1]), r.params[2]
+	in := &recordSet{}
+	err = r.decode(in)
+	if err != nil {
+		return nil, err
+	}
+	err = checkRecordSet(t, name, in)
+	if err != nil {
+		return nil, err
+	}
+	for _, rs := range z.recordSets {
+		if strings.EqualFold(rs.Name, name) && (rs.recordType == "CNAME") != (t == "CNAME") {
+			return nil, errorf(http.StatusConflict, "Conflict", "The record set %s of type %s conflicts with the record set of type %s with the same name.", name, t, rs.recordType)
+		}
+	}
+	rs, err := z.recordSet(t, name)
+	if err == nil {
+		if r.Header.Get("If-None-Match") == "*" {
+			return nil, errorf(http.StatusPreconditionFailed, "PreconditionFailed", "The record set %s of type %s exists already and hence cannot be created again.", name, t)
+		}
+	} else {
+		rs = z.newRecordSet(t, name, 0)
+		z.recordSets = append(z.recordSets, rs)
+	}
+	p := &rs.Properties
+	p.TTL = in.Properties.TTL
+	if p.TTL == 0 {
+		p.TTL = 3600
+	}
+	p.Metadata = in.Properties.Metadata
+	p.ARecords = in.Properties.ARecords
+	p.AAAARecords = in.Properties.AAAARecords
+	p.CNAMERecord = in.Properties.CNAMERecord
+	p.TXTRecords = in.Properties.TXTRecords
+	rs.Etag = newID()
+	return rs, nil
+}
+
+func deleteRecordSet(c *cloud, r *request) (interface{}, error) {
+	z, err := c.dnsZone(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	t, name := strings.ToUpper(r.params[1]), r.params[2]
+	if name == "@" && (t == "SOA" || t == "NS") {
+		return nil, badRequest("BadRequest", "The %s record set of the zone apex cannot be deleted.", t)
+	}
+	for i, rs := range z.recordSets {
+		if rs.recordType == t && strings.EqualFold(rs.Name, name) {
+			z.recordSets = append(z.recordSets[:i], z.recordSets[i+1:]...)
+			return nil, nil
+		}
+	}
+	//deleting a missing record set succeeds
+	return nil, nil
+}
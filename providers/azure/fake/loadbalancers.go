@@ -0,0 +1,484 @@
+package fake
+
+import (
+	"fmt"
+	"strings"
+)
+
+//loadBalancerChild properties shared by the child resources of a load balancer
+type loadBalancerChild struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Etag string `json:"etag,omitempty"`
+}
+
+type frontendIPConfigurationProperties struct {
+	PrivateIPAddress          string      `json:"privateIPAddress,omitempty"`
+	PrivateIPAllocationMethod string      `json:"privateIPAllocationMethod,omitempty"`
+	Subnet                    *reference  `json:"subnet,omitempty"`
+	PublicIPAddress           *reference  `json:"publicIPAddress,omitempty"`
+	LoadBalancingRules        []reference `json:"loadBalancingRules,omitempty"`
+	ProvisioningState         string      `json:"provisioningState"`
+}
+
+type frontendIPConfiguration struct {
+	loadBalancerChild
+	Properties frontendIPConfigurationProperties `json:"properties"`
+}
+
+type backendAddressPoolProperties struct {
+	BackendIPConfigurations []reference `json:"backendIPConfigurations,omitempty"`
+	LoadBalancingRules      []reference `json:"loadBalancingRules,omitempty"`
+	ProvisioningState       string      `json:"provisioningState"`
+}
+
+type backendAddressPool struct {
+	loadBalancerChild
+	Properties backendAddressPoolProperties `json:"properties"`
+}
+
+type probeProperties struct {
+	Protocol           string      `json:"protocol"`
+	Port               int         `json:"port"`
+	IntervalInSeconds  int         `json:"intervalInSeconds"`
+	NumberOfProbes     int         `json:"numberOfProbes"`
+	RequestPath        string      `json:"requestPath,omitempty"`
+	LoadBalancingRules []reference `json:"loadBalancingRules,omitempty"`
+	ProvisioningState  string      `json:"provisioningState"`
+}
+
+type probe struct {
+	loadBalancerChild
+	Properties probeProperties `json:"properties"`
+}
+
+type loadBalancingRuleProperties struct {
+	FrontendIPConfiguration *reference `json:"frontendIPConfiguration,omitempty"`
+	BackendAddressPool      *reference `json:"backendAddressPool,omitempty"`
+	Probe                   *reference `json:"probe,omitempty"`
+	Protocol                string     `json:"protocol"`
+	LoadDistribution        string     `json:"loadDistribution"`
+	FrontendPort            int        `json:"frontendPort"`
+	BackendPort             int        `json:"backendPort"`
+	IdleTimeoutInMinutes    int        `json:"idleTimeoutInMinutes"`
+	EnableFloatingIP        bool       `json:"enableFloatingIP"`
+	ProvisioningState       string     `json:"provisioningState"`
+}
+
+type loadBalancingRule struct {
+	loadBalancerChild
+	Properties loadBalancingRuleProperties `json:"properties"`
+}
+
+type loadBalancerSku struct {
+	Name string `json:"name"`
+}
+
+type loadBalancerProperties struct {
+	FrontendIPConfigurations []*frontendIPConfiguration `json:"frontendIPConfigurations"`
+	BackendAddressPools      []*backendAddressPool      `json:"backendAddressPools"`
+	Probes                   []*probe                   `json:"probes"`
+	LoadBalancingRules       []*loadBalancingRule       `json:"loadBalancingRules"`
+	ResourceGUID             string                     `json:"resourceGuid"`
+	ProvisioningState        string                     `json:"provisioningState"`
+}
+
+type loadBalancer struct {
+	resource
+	Sku        *loadBalancerSku       `json:"sku,omitempty"`
+	Properties loadBalancerProperties `json:"properties"`
+}
+
+func (c *cloud) loadBalancer(name string) (*loadBalancer, error) {
+	for _, lb := range c.loadBalancers {
+		if strings.EqualFold(lb.Name, name) {
+			return lb, nil
+		}
+	}
+	return nil, notFound("Microsoft.Network/loadBalancers", name)
+}
+
+//backendAddressPoolByID returns the backend address pool identified by id and its load balancer
+func (c *cloud) backendAddressPoolByID(id string) (*loadBalancer, *backendAddressPool, error) {
+	names, ok := parseID(id, networkNamespace, "loadBalancers")
+	if ok && len(names) == 3 && strings.EqualFold(names[1], "backendAddressPools") {
+		if lb, err := c.loadBalancer(names[0]); err == nil {
+			for _, pool := range lb.Properties.BackendAddressPools {
+				if strings.EqualFold(pool.Name, names[2]) {
+					return lb, pool, nil
+				}
+			}
+		}
+	}
+	return nil, nil, badRequest("InvalidResourceReference", "Resource %s referenced by resource was not found.", id)
+}
+
+//poolIPConfigurations returns the IP configurations of the network interfaces belonging to the backend address pool id
+func (c *cloud) poolIPConfigurations(id string) []reference {
+	var l []reference
+	for _, ni := range c.networkInterfaces {
+		for _, ipc := range ni.Properties.IPConfigurations {
+			for _, ref := range ipc.Properties.LoadBalancerBackendAddressPools {
+				if strings.EqualFold(ref.ID, id) {
+					l = append(l, reference{ID: ipc.ID})
+				}
+			}
+		}
+	}
+	return l
+}
+
+//ruleReferences returns the load balancing rules of lb referencing the child resource id
+func ruleReferences(lb *loadBalancer, id string) []reference {
+	var l []reference
+	for _, rule := range lb.Properties.LoadBalancingRules {
+		p := rule.Properties
+		for _, ref := range []*reference{p.FrontendIPConfiguration, p.BackendAddressPool, p.Probe} {
+			if ref != nil && strings.EqualFold(ref.ID, id) {
+				l = append(l, reference{ID: rule.ID})
+			}
+		}
+	}
+	return l
+}
+
+//loadBalancerView returns lb with the references to its load balancing rules and to the IP configurations of its backend pools
+func (c *cloud) loadBalancerView(lb *loadBalancer) *loadBalancer {
+	v := *lb
+	v.Properties.FrontendIPConfigurations = nil
+	for _, fe := range lb.Properties.FrontendIPConfigurations {
+		f := *fe
+		f.Properties.LoadBalancingRules = ruleReferences(lb, fe.ID)
+		v.Properties.FrontendIPConfigurations = append(v.Properties.FrontendIPConfigurations, &f)
+	}
+	v.Properties.BackendAddressPools = nil
+	for _, pool := range lb.Properties.BackendAddressPools {
+		p := *pool
+		p.Properties.BackendIPConfigurations = c.poolIPConfigurations(pool.ID)
+		p.Properties.LoadBalancingRules = ruleReferences(lb, pool.ID)
+		v.Properties.BackendAddressPools = append(v.Properties.BackendAddressPools, &p)
+	}
+	v.Properties.Probes = nil
+	for _, pr := range lb.Properties.Probes {
+		p := *pr
+		p.Properties.LoadBalancingRules = ruleReferences(lb, pr.ID)
+		v.Properties.Probes = append(v.Properties.Probes, &p)
+	}
+	return &v
+}
+
+func listLoadBalancers(c *cloud, r *request) (interface{}, error) {
+	l := []*loadBalancer{}
+	for _, lb := range c.loadBalancers {
+		l = append(l, c.loadBalancerView(lb))
+	}
+	return map[string]interface{}{"value": l}, nil
+}
+
+func getLoadBalancer(c *cloud, r *request) (interface{}, error) {
+	lb, err := c.loadBalancer(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	return c.loadBalancerView(lb), nil
+}
+
+//childNames checks that the names of the child resources of kind are unique, sets their identifiers and returns them indexed by identifier
+func childNames(lbName, kind string, children []*loadBalancerChild) (map[string]bool, error) {
+	ids := map[string]bool{}
+	for _, child := range children {
+		child.ID = resourceID(networkNamespace, "loadBalancers", lbName, kind, child.Name)
+		if child.Name == "" || ids[strings.ToLower(child.ID)] {
+			return nil, badRequest("InvalidRequestFormat", "The %s of load balancer %s must have unique names.", kind, lbName)
+		}
+		ids[strings.ToLower(child.ID)] = true
+		child.Etag = etag()
+	}
+	return ids, nil
+}
+
+//childReference checks that ref references a child resource of the load balancer among ids
+func childReference(ref *reference, ids map[string]bool, ruleID string) error {
+	if ref == nil || !ids[strings.ToLower(ref.ID)] {
+		id := ""
+		if ref != nil {
+			id = ref.ID
+		}
+		return badRequest("InvalidResourceReference", "Resource %s referenced by resource %s was not found.", id, ruleID)
+	}
+	return nil
+}
+
+//frontendIPConfigurations checks and returns the frontend IP configurations of in, the private addresses of the frontends of lb are kept
+func (c *cloud) frontendIPConfigurations(in *loadBalancer, lb *loadBalancer) ([]*frontendIPConfiguration, error) {
+	l := in.Properties.FrontendIPConfigurations
+	public := 0
+	allocated := map[string]bool{}
+	for _, fe := range l {
+		p := &fe.Properties
+		if (p.Subnet == nil) == (p.PublicIPAddress == nil) {
+			return nil, badRequest("InvalidRequestFormat", "Frontend IP configuration %s must reference either a subnet or a public IP address.", fe.ID)
+		}
+		if p.PublicIPAddress != nil {
+			public++
+			ip, err := c.associablePublicIPAddress(p.PublicIPAddress, fe.ID)
+			if err != nil {
+				return nil, err
+			}
+			if !strings.EqualFold(ip.Sku.Name, in.Sku.Name) {
+				return nil, badRequest("LoadBalancerReferencesPublicIPAddressWithDifferentSku", "Load balancer %s with sku %s references public IP address %s with sku %s.", in.Name, in.Sku.Name, ip.ID, ip.Sku.Name)
+			}
+			p.PublicIPAddress = &reference{ID: ip.ID}
+			p.PrivateIPAddress = ""
+			p.PrivateIPAllocationMethod = ""
+			p.ProvisioningState = stateSucceeded
+			continue
+		}
+		_, sn, err := c.subnetByID(p.Subnet.ID)
+		if err != nil {
+			return nil, err
+		}
+		p.Subnet = &reference{ID: sn.ID}
+		previous := currentFrontendAddress(lb, fe.Name, sn)
+		if p.PrivateIPAllocationMethod = oneOf(p.PrivateIPAllocationMethod, "Dynamic", "Static"); p.PrivateIPAllocationMethod == "" {
+			p.PrivateIPAllocationMethod = "Dynamic"
+		}
+		if p.PrivateIPAllocationMethod == "Static" {
+			if p.PrivateIPAddress != previous {
+				err = c.checkStaticAddress(sn, p.PrivateIPAddress, nil, allocated)
+			}
+		} else if previous != "" && !allocated[previous] {
+			p.PrivateIPAddress = previous
+		} else {
+			p.PrivateIPAddress, err = c.allocateAddress(sn, nil, allocated)
+		}
+		if err != nil {
+			return nil, err
+		}
+		allocated[p.PrivateIPAddress] = true
+		p.ProvisioningState = stateSucceeded
+	}
+	if public > 0 && public < len(l) {
+		return nil, badRequest("LoadBalancerWithPublicAndPrivateFrontendIPConfigurations", "Load balancer %s cannot have both public and private frontend IP configurations.", in.Name)
+	}
+	return l, nil
+}
+
+//currentFrontendAddress returns the private address of the frontend IP configuration named name of lb if it uses the subnet sn
+func currentFrontendAddress(lb *loadBalancer, name string, sn *subnet) string {
+	if lb == nil {
+		return ""
+	}
+	for _, fe := range lb.Properties.FrontendIPConfigurations {
+		if strings.EqualFold(fe.Name, name) && fe.Properties.Subnet != nil && strings.EqualFold(fe.Properties.Subnet.ID, sn.ID) {
+			return fe.Properties.PrivateIPAddress
+		}
+	}
+	return ""
+}
+
+//checkProbe checks and normalizes the properties of the probe pr
+func checkProbe(pr *probe) error {
+	p := &pr.Properties
+	if p.Protocol = oneOf(p.Protocol, "Tcp", "Http", "Https"); p.Protocol == "" {
+		return badRequest("InvalidProbeProtocol", "Probe %s has an invalid protocol.", pr.ID)
+	}
+	if p.Port < 1 || p.Port > 65535 {
+		return badRequest("InvalidProbePort", "Port %d of probe %s is not valid.", p.Port, pr.ID)
+	}
+	if p.Protocol == "Tcp" && p.RequestPath != "" {
+		return badRequest("ProbeRequestPathNotAllowedForTcp", "Probe %s of protocol Tcp cannot have a request path.", pr.ID)
+	}
+	if p.Protocol != "Tcp" && !strings.HasPrefix(p.RequestPath, "/") {
+		return badRequest("ProbeRequestPathRequired", "Probe %s of protocol %s must have a request path.", pr.ID, p.Protocol)
+	}
+	if p.IntervalInSeconds == 0 {
+		p.IntervalInSeconds = 15
+	}
+	if p.IntervalInSeconds < 5 {
+		return badRequest("InvalidProbeInterval", "Interval of probe %s must be at least 5 seconds.", pr.ID)
+	}
+	if p.NumberOfProbes == 0 {
+		p.NumberOfProbes = 2
+	}
+	p.ProvisioningState = stateSucceeded
+	return nil
+}
+
+//checkLoadBalancingRule checks and normalizes the properties of the load balancing rule rule
+func checkLoadBalancingRule(rule *loadBalancingRule, frontends, pools, probes map[string]bool) error {
+	p := &rule.Properties
+	if p.Protocol = oneOf(p.Protocol, "Tcp", "Udp", "All"); p.Protocol == "" {
+		return badRequest("InvalidLoadBalancingRuleProtocol", "Load balancing rule %s has an invalid protocol.", rule.ID)
+	}
+	if p.FrontendPort < 1 || p.FrontendPort > 65534 || p.BackendPort < 1 || p.BackendPort > 65535 {
+		return badRequest("InvalidLoadBalancingRulePort", "Ports of load balancing rule %s are not valid.", rule.ID)
+	}
+	if err := childReference(p.FrontendIPConfiguration, frontends, rule.ID); err != nil {
+		return err
+	}
+	if err := childReference(p.BackendAddressPool, pools, rule.ID); err != nil {
+		return err
+	}
+	if p.Probe != nil {
+		if err := childReference(p.Probe, probes, rule.ID); err != nil {
+			return err
+		}
+	}
+	if p.LoadDistribution = oneOf(p.LoadDistribution, "Default", "SourceIP", "SourceIPProtocol"); p.LoadDistribution == "" {
+		p.LoadDistribution = "Default"
+	}
+	if p.IdleTimeoutInMinutes == 0 {
+		p.IdleTimeoutInMinutes = 4
+	}
+	p.ProvisioningState = stateSucceeded
+	return nil
+}
+
+//checkLoadBalancer checks the properties of the load balancer in, lb is the load balancer being updated or nil
+func (c *cloud) checkLoadBalancer(in *loadBalancer, lb *loadBalancer) error {
+	sku := "Basic"
+	if in.Sku != nil {
+		if sku = oneOf(in.Sku.Name, "Basic", "Standard"); sku == "" {
+			return badRequest("InvalidLoadBalancerSku", "Load balancer SKU %s is not supported.", in.Sku.Name)
+		}
+	}
+	if lb != nil && lb.Sku.Name != sku {
+		return badRequest("LoadBalancerSkuCannotBeChanged", "The SKU of load balancer %s cannot be changed.", lb.ID)
+	}
+	in.Sku = &loadBalancerSku{Name: sku}
+	p := &in.Properties
+	var children []*loadBalancerChild
+	for _, fe := range p.FrontendIPConfigurations {
+		children = append(children, &fe.loadBalancerChild)
+	}
+	frontends, err := childNames(in.Name, "frontendIPConfigurations", children)
+	if err != nil {
+		return err
+	}
+	children = nil
+	for _, pool := range p.BackendAddressPools {
+		children = append(children, &pool.loadBalancerChild)
+		pool.Properties.ProvisioningState = stateSucceeded
+	}
+	pools, err := childNames(in.Name, "backendAddressPools", children)
+	if err != nil {
+		return err
+	}
+	if lb != nil {
+		for _, pool := range lb.Properties.BackendAddressPools {
+			if !pools[strings.ToLower(pool.ID)] && len(c.poolIPConfigurations(pool.ID)) > 0 {
+				return badRequest("LoadBalancerBackendAddressPoolInUse", "Backend address pool %s cannot be removed since it is used by %s.", pool.ID, c.poolIPConfigurations(pool.ID)[0].ID)
+			}
+		}
+	}
+	children = nil
+	for _, pr := range p.Probes {
+		children = append(children, &pr.loadBalancerChild)
+	}
+	probes, err := childNames(in.Name, "probes", children)
+	if err != nil {
+		return err
+	}
+	for _, pr := range p.Probes {
+		if err := checkProbe(pr); err != nil {
+			return err
+		}
+	}
+	children = nil
+	for _, rule := range p.LoadBalancingRules {
+		children = append(children, &rule.loadBalancerChild)
+	}
+	if _, err := childNames(in.Name, "loadBalancingRules", children); err != nil {
+		return err
+	}
+	ports := map[string]bool{}
+	for _, rule := range p.LoadBalancingRules {
+		if err := checkLoadBalancingRule(rule, frontends, pools, probes); err != nil {
+			return err
+		}
+		for _, protocol := range []string{"Tcp", "Udp"} {
+			if rule.Properties.Protocol != "All" && rule.Properties.Protocol != protocol {
+				continue
+			}
+			key := fmt.Sprintf("%s/%s/%d", strings.ToLower(rule.Properties.FrontendIPConfiguration.ID), protocol, rule.Properties.FrontendPort)
+			if ports[key] {
+				return badRequest("LoadBalancingRulesWithSameFrontendIPConfigAndPort", "Load balancing rule %s uses a frontend IP configuration, protocol and port already used by another rule.", rule.ID)
+			}
+			ports[key] = true
+		}
+	}
+	return nil
+}
+
+func putLoadBalancer(c *cloud, r *request) (interface{}, error) {
+	in := &loadBalancer{}
+	err := r.decode(in)
+	if err != nil {
+		return nil, err
+	}
+	in.Name = r.params[0]
+	lb, err := c.loadBalancer(r.params[0])
+	created := err != nil
+	if created {
+		err = checkLocation(in.Location)
+		if err != nil {
+			return nil, err
+		}
+		lb = nil
+	}
+	err = c.checkLoadBalancer(in, lb)
+	if err != nil {
+		return nil, err
+	}
+	frontends, err := c.frontendIPConfigurations(in, lb)
+	if err != nil {
+		return nil, err
+	}
+	if created {
+		lb = &loadBalancer{
+			resource: resource{
+				ID:       resourceID(networkNamespace, "loadBalancers", r.params[0]),
+				Name:     r.params[0],
+				Type:     "Microsoft.Network/loadBalancers",
+				Location: strings.ToLower(in.Location),
+			},
+			Sku: in.Sku,
+		}
+		lb.Properties.ResourceGUID = newID()
+		c.loadBalancers = append(c.loadBalancers, lb)
+	}
+	lb.Properties.FrontendIPConfigurations = frontends
+	lb.Properties.BackendAddressPools = in.Properties.BackendAddressPools
+	lb.Properties.Probes = in.Properties.Probes
+	lb.Properties.LoadBalancingRules = in.Properties.LoadBalancingRules
+	if in.Tags != nil || created {
+		lb.Tags = in.Tags
+	}
+	lb.Etag = etag()
+	lb.Properties.ProvisioningState = stateUpdating
+	return putResponse(networkNamespace, created, c.loadBalancerView(lb), func() {
+		lb.Properties.ProvisioningState = stateSucceeded
+	}), nil
+}
+
+func deleteLoadBalancer(c *cloud, r *request) (interface{}, error) {
+	lb, err := c.loadBalancer(r.params[0])
+	if err != nil {
+		return nil, nil
+	}
+	for _, pool := range lb.Properties.BackendAddressPools {
+		if l := c.poolIPConfigurations(pool.ID); len(l) > 0 {
+			return nil, badRequest("LoadBalancerInUseByNetworkInterface", "Load balancer %s cannot be deleted since its backend address pool %s is used by %s.", lb.ID, pool.ID, l[0].ID)
+		}
+	}
+	lb.Properties.ProvisioningState = stateDeleting
+	return accepted(networkNamespace, func() {
+		for i, o := range c.loadBalancers {
+			if o == lb {
+				c.loadBalancers = append(c.loadBalancers[:i], c.loadBalancers[i+1:]...)
+				break
+			}
+		}
+	}), nil
+}
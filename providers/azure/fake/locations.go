@@ -0,0 +1,78 @@
+package fake
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+func locationRoutes() []route {
+	return []route{
+		{"GET", "locations", http.StatusOK, listLocations},
+		{"GET", "providers/Microsoft.Compute/skus", http.StatusOK, listResourceSkus},
+	}
+}
+
+//location location of the subscription
+type location struct {
+	Name        string
+	DisplayName string
+	//Zones availability zones of the location
+	Zones []string
+}
+
+//locations locations listed by the fake, resources can only be created in Location
+var locations = []location{
+	{Location, "East US", []string{"1", "2", "3"}},
+	{"westeurope", "West Europe", []string{"1", "2", "3"}},
+	{"westus", "West US", nil},
+}
+
+//knownLocation returns true if name is one of the locations listed by the fake
+func knownLocation(name string) bool {
+	for _, l := range locations {
+		if strings.EqualFold(l.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func listLocations(c *cloud, r *request) (interface{}, error) {
+	var l []map[string]string
+	for _, loc := range locations {
+		l = append(l, map[string]string{
+			"id":          fmt.Sprintf("/subscriptions/%s/locations/%s", SubscriptionID, loc.Name),
+			"name":        loc.Name,
+			"displayName": loc.DisplayName,
+		})
+	}
+	return map[string]interface{}{"value": l}, nil
+}
+
+//listResourceSkus returns the virtual machine sizes as resource SKUs offered in all the locations
+func listResourceSkus(c *cloud, r *request) (interface{}, error) {
+	var l []interface{}
+	for _, size := range c.sizes {
+		var names []string
+		var infos []interface{}
+		for _, loc := range locations {
+			names = append(names, loc.Name)
+			info := map[string]interface{}{"location": loc.Name}
+			if len(loc.Zones) > 0 {
+				info["zones"] = loc.Zones
+			}
+			infos = append(infos, info)
+		}
+		l = append(l, map[string]interface{}{
+			"resourceType": "virtualMachines",
+			"name":         size.Name,
+			"tier":         "Standard",
+			"size":         strings.TrimPrefix(size.Name, "Standard_"),
+			"locations":    names,
+			"locationInfo": infos,
+			"restrictions": []interface{}{},
+		})
+	}
+	return map[string]interface{}{"value": l}, nil
+}
@@ -0,0 +1,149 @@
+package fake
+
+import (
+	"net/http"
+	"strings"
+)
+
+type managedImageProperties struct {
+	SourceVirtualMachine *reference `json:"sourceVirtualMachine,omitempty"`
+	StorageProfile       struct {
+		OsDisk struct {
+			OsType      string     `json:"osType"`
+			OsState     string     `json:"osState"`
+			ManagedDisk *reference `json:"managedDisk,omitempty"`
+			Caching     string     `json:"caching,omitempty"`
+			DiskSizeGB  int        `json:"diskSizeGB,omitempty"`
+		} `json:"osDisk"`
+		DataDisks []interface{} `json:"dataDisks"`
+	} `json:"storageProfile"`
+	ProvisioningState string `json:"provisioningState"`
+}
+
+//managedImage custom image captured from a generalized virtual machine
+type managedImage struct {
+	resource
+	Properties managedImageProperties `json:"properties"`
+}
+
+func (c *cloud) managedImage(name string) (*managedImage, error) {
+	for _, img := range c.managedImages {
+		if strings.EqualFold(img.Name, name) {
+			return img, nil
+		}
+	}
+	return nil, notFound("Microsoft.Compute/images", name)
+}
+
+//managedImageByID returns the managed image identified by id
+func (c *cloud) managedImageByID(id string) (*managedImage, error) {
+	names, ok := parseID(id, computeNamespace, "images")
+	if ok && len(names) == 1 {
+		img, err := c.managedImage(names[0])
+		if err == nil {
+			return img, nil
+		}
+	}
+	return nil, errorf(http.StatusNotFound, "NotFound", "The Image '%s' cannot be found.", id)
+}
+
+func listManagedImages(c *cloud, r *request) (interface{}, error) {
+	return map[string]interface{}{"value": c.managedImages}, nil
+}
+
+func getManagedImage(c *cloud, r *request) (interface{}, error) {
+	return c.managedImage(r.params[0])
+}
+
+//putManagedImage creates an image from a generalized virtual machine, images cannot be updated except for their tags
+func putManagedImage(c *cloud, r *request) (interface{}, error) {
+	in := &managedImage{}
+	err := r.decode(in)
+	if err != nil {
+		return nil, err
+	}
+	if !diskName.MatchString(r.params[0]) {
+		return nil, badRequest("InvalidParameter", "The entity name '%s' is invalid according to its validation rule.", r.params[0])
+	}
+	img, err := c.managedImage(r.params[0])
+	created := err != nil
+	if created {
+		err = checkLocation(in.Location)
+		if err != nil {
+			return nil, err
+		}
+		src := in.Properties.SourceVirtualMachine
+		if src == nil {
+			return nil, badRequest("InvalidParameter", "Required parameter 'sourceVirtualMachine' is missing (null).")
+		}
+		names, ok := parseID(src.ID, computeNamespace, "virtualMachines")
+		if !ok || len(names) != 1 {
+			return nil, badRequest("InvalidResourceReference", "Resource %s referenced by resource %s was not found.", src.ID, r.params[0])
+		}
+		vm, err := c.virtualMachine(names[0])
+		if err != nil {
+			return nil, err
+		}
+		if !vm.generalized {
+			return nil, errorf(http.StatusConflict, "OperationNotAllowed", "The source virtual machine '%s' must be generalized to create an image.", vm.ID)
+		}
+		img = &managedImage{
+			resource: resource{
+				ID:       resourceID(computeNamespace, "images", r.params[0]),
+				Name:     r.params[0],
+				Type:     "Microsoft.Compute/images",
+				Location: Location,
+				Tags:     map[string]string{},
+			},
+		}
+		img.Properties.SourceVirtualMachine = &reference{ID: vm.ID}
+		osDisk := &img.Properties.StorageProfile.OsDisk
+		osDisk.OsType = "Linux"
+		osDisk.OsState = "Generalized"
+		osDisk.Caching = "ReadWrite"
+		if d := vm.Properties.StorageProfile.OsDisk; d != nil {
+			osDisk.DiskSizeGB = d.DiskSizeGB
+			if d.ManagedDisk != nil {
+				osDisk.ManagedDisk = &reference{ID: d.ManagedDisk.ID}
+			}
+		}
+		img.Properties.StorageProfile.DataDisks = []interface{}{}
+		c.managedImages = append(c.managedImages, img)
+	}
+	if in.Tags != nil {
+		img.Tags = in.Tags
+	}
+	img.Properties.ProvisioningState = stateUpdating
+	return putResponse(computeNamespace, created, img, func() {
+		img.Properties.ProvisioningState = stateSucceeded
+	}), nil
+}
+
+func deleteManagedImage(c *cloud, r *request) (interface{}, error) {
+	img, err := c.managedImage(r.params[0])
+	if err != nil {
+		return nil, nil
+	}
+	img.Properties.ProvisioningState = stateDeleting
+	return accepted(computeNamespace, func() {
+		for i, o := range c.managedImages {
+			if o == img {
+				c.managedImages = append(c.managedImages[:i], c.managedImages[i+1:]...)
+				break
+			}
+		}
+	}), nil
+}
+
+//generalizeVirtualMachine marks a stopped virtual machine as generalized, it cannot be started anymore
+func generalizeVirtualMachine(c *cloud, r *request) (interface{}, error) {
+	vm, err := c.virtualMachine(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	if vm.powerState != powerStopped && vm.powerState != powerDeallocated {
+		return nil, errorf(http.StatusConflict, "OperationNotAllowed", "Operation 'generalize' is not allowed on VM '%s' since the VM is %s.", vm.Name, vm.powerState)
+	}
+	vm.generalized = true
+	return nil, nil
+}
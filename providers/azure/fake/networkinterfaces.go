@@ -0,0 +1,291 @@
+package fake
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/SebastienDorgan/anyclouds/iputils"
+	"github.com/google/uuid"
+)
+
+type ipConfigurationProperties struct {
+	PrivateIPAddress          string     `json:"privateIPAddress,omitempty"`
+	PrivateIPAllocationMethod string     `json:"privateIPAllocationMethod"`
+	PrivateIPAddressVersion   string     `json:"privateIPAddressVersion"`
+	Subnet                    *reference `json:"subnet,omitempty"`
+	PublicIPAddress           *reference `json:"publicIPAddress,omitempty"`
+	//LoadBalancerBackendAddressPools load balancer backend pools the IP configuration belongs to
+	LoadBalancerBackendAddressPools []reference `json:"loadBalancerBackendAddressPools,omitempty"`
+	Primary                         bool        `json:"primary"`
+	ProvisioningState               string      `json:"provisioningState"`
+}
+
+type ipConfiguration struct {
+	ID         string                    `json:"id"`
+	Name       string                    `json:"name"`
+	Etag       string                    `json:"etag,omitempty"`
+	Properties ipConfigurationProperties `json:"properties"`
+}
+
+type networkInterfaceProperties struct {
+	IPConfigurations            []*ipConfiguration `json:"ipConfigurations"`
+	NetworkSecurityGroup        *reference         `json:"networkSecurityGroup,omitempty"`
+	VirtualMachine              *reference         `json:"virtualMachine,omitempty"`
+	MacAddress                  string             `json:"macAddress,omitempty"`
+	EnableAcceleratedNetworking bool               `json:"enableAcceleratedNetworking"`
+	EnableIPForwarding          bool               `json:"enableIPForwarding"`
+	ResourceGUID                string             `json:"resourceGuid"`
+	ProvisioningState           string             `json:"provisioningState"`
+}
+
+type networkInterface struct {
+	resource
+	Properties networkInterfaceProperties `json:"properties"`
+}
+
+func (c *cloud) networkInterface(name string) (*networkInterface, error) {
+	for _, ni := range c.networkInterfaces {
+		if strings.EqualFold(ni.Name, name) {
+			return ni, nil
+		}
+	}
+	return nil, notFound("Microsoft.Network/networkInterfaces", name)
+}
+
+//macAddress returns a new MAC address in the format used by Azure
+func macAddress() string {
+	b := uuid.New()
+	return fmt.Sprintf("00-0D-3A-%02X-%02X-%02X", b[0], b[1], b[2])
+}
+
+//usedAddresses returns the private addresses of the subnet sn used by network interfaces other than self and by load balancers
+func (c *cloud) usedAddresses(sn *subnet, self *networkInterface) map[string]bool {
+	used := map[string]bool{}
+	for _, ni := range c.networkInterfaces {
+		if ni == self {
+			continue
+		}
+		for _, ipc := range ni.Properties.IPConfigurations {
+			if ipc.Properties.Subnet != nil && strings.EqualFold(ipc.Properties.Subnet.ID, sn.ID) {
+				used[ipc.Properties.PrivateIPAddress] = true
+			}
+		}
+	}
+	for _, lb := range c.loadBalancers {
+		for _, fe := range lb.Properties.FrontendIPConfigurations {
+			if fe.Properties.Subnet != nil && strings.EqualFold(fe.Properties.Subnet.ID, sn.ID) {
+				used[fe.Properties.PrivateIPAddress] = true
+			}
+		}
+	}
+	return used
+}
+
+//allocateAddress returns the first free address of sn not in allocated, Azure reserves the first four and the last addresses of a subnet
+func (c *cloud) allocateAddress(sn *subnet, self *networkInterface, allocated map[string]bool) (string, error) {
+	used := c.usedAddresses(sn, self)
+	r, err := iputils.GetRange(sn.Properties.AddressPrefix)
+	if err != nil {
+		return "", err
+	}
+	for u := iputils.Itou(&r.FirstIP) + 4; u < iputils.Itou(&r.LastIP); u++ {
+		addr := iputils.Utoi(u).String()
+		if !used[addr] && !allocated[addr] {
+			return addr, nil
+		}
+	}
+	return "", badRequest("SubnetIsFull", "Subnet %s with address prefix %s does not have enough capacity for 1 IP addresses.", sn.Name, sn.Properties.AddressPrefix)
+}
+
+//checkStaticAddress checks that addr is a free address of sn
+func (c *cloud) checkStaticAddress(sn *subnet, addr string, self *networkInterface, allocated map[string]bool) error {
+	ip := net.ParseIP(addr)
+	if ip == nil || !sn.ipNet.Contains(ip) {
+		return badRequest("PrivateIPAddressNotInSubnet", "Private static IP address %s does not belong to the range of subnet prefix %s.", addr, sn.Properties.AddressPrefix)
+	}
+	if c.usedAddresses(sn, self)[ip.String()] || allocated[ip.String()] {
+		return badRequest("PrivateIPAddressInUse", "IP configuration is using the private IP address %s which is already allocated to another resource.", addr)
+	}
+	return nil
+}
+
+//associablePublicIPAddress returns the public IP address referenced by ref if it can be associated to the IP configuration ipcID
+func (c *cloud) associablePublicIPAddress(ref *reference, ipcID string) (*publicIPAddress, error) {
+	ip, err := c.publicIPAddressByID(ref.ID)
+	if err != nil {
+		return nil, err
+	}
+	if used := c.publicIPConfiguration(ip); used != "" && !strings.EqualFold(used, ipcID) {
+		return nil, badRequest("PublicIPAddressInUse", "Resource %s is referencing public IP address %s that is already allocated to resource %s.", ipcID, ip.ID, used)
+	}
+	return ip, nil
+}
+
+//ipConfigurations checks and returns the IP configurations of in, the addresses of the IP configurations of ni are kept
+func (c *cloud) ipConfigurations(name string, in *networkInterface, ni *networkInterface) ([]*ipConfiguration, error) {
+	l := in.Properties.IPConfigurations
+	if len(l) == 0 {
+		return nil, badRequest("InvalidRequestFormat", "Network interface %s must have at least one IP configuration.", name)
+	}
+	primaries := 0
+	var vnet *virtualNetwork
+	allocated := map[string]bool{}
+	names := map[string]bool{}
+	for _, ipc := range l {
+		if ipc.Name == "" || names[strings.ToLower(ipc.Name)] {
+			return nil, badRequest("InvalidRequestFormat", "IP configurations of network interface %s must have unique names.", name)
+		}
+		names[strings.ToLower(ipc.Name)] = true
+		ipc.ID = resourceID(networkNamespace, "networkInterfaces", name, "ipConfigurations", ipc.Name)
+		p := &ipc.Properties
+		if p.Subnet == nil {
+			return nil, badRequest("InvalidRequestFormat", "IP configuration %s must reference a subnet.", ipc.ID)
+		}
+		n, sn, err := c.subnetByID(p.Subnet.ID)
+		if err != nil {
+			return nil, err
+		}
+		if vnet != nil && vnet != n {
+			return nil, badRequest("IpConfigurationsOnSameNicCannotUseDifferentVnets", "IP configurations on the same network interface %s cannot use different virtual networks.", name)
+		}
+		vnet = n
+		p.Subnet.ID = sn.ID
+		if p.PrivateIPAllocationMethod = oneOf(p.PrivateIPAllocationMethod, "Dynamic", "Static"); p.PrivateIPAllocationMethod == "" {
+			p.PrivateIPAllocationMethod = "Dynamic"
+		}
+		if p.PrivateIPAllocationMethod == "Static" {
+			err = c.checkStaticAddress(sn, p.PrivateIPAddress, ni, allocated)
+		} else {
+			p.PrivateIPAddress = ""
+			if previous := currentAddress(ni, ipc.Name, sn); previous != "" && !allocated[previous] {
+				p.PrivateIPAddress = previous
+			} else {
+				p.PrivateIPAddress, err = c.allocateAddress(sn, ni, allocated)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		allocated[p.PrivateIPAddress] = true
+		if p.PublicIPAddress != nil {
+			ip, err := c.associablePublicIPAddress(p.PublicIPAddress, ipc.ID)
+			if err != nil {
+				return nil, err
+			}
+			p.PublicIPAddress = &reference{ID: ip.ID}
+		}
+		for i, ref := range p.LoadBalancerBackendAddressPools {
+			_, pool, err := c.backendAddressPoolByID(ref.ID)
+			if err != nil {
+				return nil, err
+			}
+			p.LoadBalancerBackendAddressPools[i] = reference{ID: pool.ID}
+		}
+		if p.Primary {
+			primaries++
+		}
+		p.PrivateIPAddressVersion = "IPv4"
+		p.ProvisioningState = stateSucceeded
+		ipc.Etag = etag()
+	}
+	if len(l) == 1 {
+		l[0].Properties.Primary = true
+	} else if primaries != 1 {
+		return nil, badRequest("NicMustHaveOnePrimaryIpConfiguration", "Network interface %s must have one primary IP configuration.", name)
+	}
+	return l, nil
+}
+
+//currentAddress returns the private address of the IP configuration named name of ni if it uses the subnet sn
+func currentAddress(ni *networkInterface, name string, sn *subnet) string {
+	if ni == nil {
+		return ""
+	}
+	for _, ipc := range ni.Properties.IPConfigurations {
+		if strings.EqualFold(ipc.Name, name) && ipc.Properties.Subnet != nil && strings.EqualFold(ipc.Properties.Subnet.ID, sn.ID) {
+			return ipc.Properties.PrivateIPAddress
+		}
+	}
+	return ""
+}
+
+func listNetworkInterfaces(c *cloud, r *request) (interface{}, error) {
+	return map[string]interface{}{"value": c.networkInterfaces}, nil
+}
+
+func getNetworkInterface(c *cloud, r *request) (interface{}, error) {
+	return c.networkInterface(r.params[0])
+}
+
+func putNetworkInterface(c *cloud, r *request) (interface{}, error) {
+	in := &networkInterface{}
+	err := r.decode(in)
+	if err != nil {
+		return nil, err
+	}
+	ni, err := c.networkInterface(r.params[0])
+	created := err != nil
+	if created {
+		err = checkLocation(in.Location)
+		if err != nil {
+			return nil, err
+		}
+		ni = nil
+	}
+	ipcs, err := c.ipConfigurations(r.params[0], in, ni)
+	if err != nil {
+		return nil, err
+	}
+	nsg := in.Properties.NetworkSecurityGroup
+	if nsg != nil {
+		sg, err := c.securityGroupByID(nsg.ID)
+		if err != nil {
+			return nil, err
+		}
+		nsg = &reference{ID: sg.ID}
+	}
+	if created {
+		ni = &networkInterface{
+			resource: resource{
+				ID:       resourceID(networkNamespace, "networkInterfaces", r.params[0]),
+				Name:     r.params[0],
+				Type:     "Microsoft.Network/networkInterfaces",
+				Location: strings.ToLower(in.Location),
+			},
+		}
+		ni.Properties.ResourceGUID = newID()
+		c.networkInterfaces = append(c.networkInterfaces, ni)
+	}
+	ni.Properties.IPConfigurations = ipcs
+	ni.Properties.NetworkSecurityGroup = nsg
+	ni.Properties.EnableAcceleratedNetworking = in.Properties.EnableAcceleratedNetworking
+	ni.Properties.EnableIPForwarding = in.Properties.EnableIPForwarding
+	if in.Tags != nil || created {
+		ni.Tags = in.Tags
+	}
+	ni.Etag = etag()
+	ni.Properties.ProvisioningState = stateUpdating
+	return putResponse(networkNamespace, created, ni, func() {
+		ni.Properties.ProvisioningState = stateSucceeded
+	}), nil
+}
+
+func deleteNetworkInterface(c *cloud, r *request) (interface{}, error) {
+	ni, err := c.networkInterface(r.params[0])
+	if err != nil {
+		return nil, nil
+	}
+	if ni.Properties.VirtualMachine != nil {
+		return nil, badRequest("NicInUse", "Network Interface %s is used by existing resource %s. In order to delete the network interface, it must be dissociated from the resource.", ni.ID, ni.Properties.VirtualMachine.ID)
+	}
+	ni.Properties.ProvisioningState = stateDeleting
+	return accepted(networkNamespace, func() {
+		for i, o := range c.networkInterfaces {
+			if o == ni {
+				c.networkInterfaces = append(c.networkInterfaces[:i], c.networkInterfaces[i+1:]...)
+				break
+			}
+		}
+	}), nil
+}
@@ -0,0 +1,429 @@
+package fake
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+func networkRoutes() []route {
+	const network = "resourceGroups/*/providers/Microsoft.Network/"
+	return []route{
+		{"GET", network + "virtualNetworks", http.StatusOK, listVirtualNetworks},
+		{"GET", network + "virtualNetworks/*", http.StatusOK, getVirtualNetwork},
+		{"PUT", network + "virtualNetworks/*", http.StatusOK, putVirtualNetwork},
+		{"DELETE", network + "virtualNetworks/*", http.StatusNoContent, deleteVirtualNetwork},
+		{"GET", network + "virtualNetworks/*/subnets", http.StatusOK, listSubnets},
+		{"GET", network + "virtualNetworks/*/subnets/*", http.StatusOK, getSubnet},
+		{"PUT", network + "virtualNetworks/*/subnets/*", http.StatusOK, putSubnet},
+		{"DELETE", network + "virtualNetworks/*/subnets/*", http.StatusNoContent, deleteSubnet},
+		{"GET", network + "networkSecurityGroups", http.StatusOK, listSecurityGroups},
+		{"GET", network + "networkSecurityGroups/*", http.StatusOK, getSecurityGroup},
+		{"PUT", network + "networkSecurityGroups/*", http.StatusOK, putSecurityGroup},
+		{"DELETE", network + "networkSecurityGroups/*", http.StatusNoContent, deleteSecurityGroup},
+		{"GET", network + "networkInterfaces", http.StatusOK, listNetworkInterfaces},
+		{"GET", network + "networkInterfaces/*", http.StatusOK, getNetworkInterface},
+		{"PUT", network + "networkInterfaces/*", http.StatusOK, putNetworkInterface},
+		{"DELETE", network + "networkInterfaces/*", http.StatusNoContent, deleteNetworkInterface},
+		{"GET", network + "publicIPAddresses", http.StatusOK, listPublicIPAddresses},
+		{"GET", network + "publicIPAddresses/*", http.StatusOK, getPublicIPAddress},
+		{"PUT", network + "publicIPAddresses/*", http.StatusOK, putPublicIPAddress},
+		{"DELETE", network + "publicIPAddresses/*", http.StatusNoContent, deletePublicIPAddress},
+		{"GET", network + "loadBalancers", http.StatusOK, listLoadBalancers},
+		{"GET", network + "loadBalancers/*", http.StatusOK, getLoadBalancer},
+		{"PUT", network + "loadBalancers/*", http.StatusOK, putLoadBalancer},
+		{"DELETE", network + "loadBalancers/*", http.StatusNoContent, deleteLoadBalancer},
+		{"GET", network + "dnszones", http.StatusOK, listDNSZones},
+		{"GET", network + "dnszones/*", http.StatusOK, getDNSZone},
+		{"PUT", network + "dnszones/*", http.StatusOK, putDNSZone},
+		{"DELETE", network + "dnszones/*", http.StatusNoContent, deleteDNSZone},
+		{"GET", network + "dnszones/*/all", http.StatusOK, listRecordSets},
+		{"GET", network + "dnszones/*/recordsets", http.StatusOK, listRecordSets},
+		{"GET", network + "dnszones/*/*", http.StatusOK, listRecordSetsByType},
+		{"GET", network + "dnszones/*/*/*", http.StatusOK, getRecordSet},
+		{"PUT", network + "dnszones/*/*/*", http.StatusOK, putRecordSet},
+		{"DELETE", network + "dnszones/*/*/*", http.StatusOK, deleteRecordSet},
+	}
+}
+
+type subnetProperties struct {
+	AddressPrefix        string      `json:"addressPrefix"`
+	NetworkSecurityGroup *reference  `json:"networkSecurityGroup,omitempty"`
+	IPConfigurations     []reference `json:"ipConfigurations,omitempty"`
+	ProvisioningState    string      `json:"provisioningState"`
+}
+
+type subnet struct {
+	ID         string           `json:"id"`
+	Name       string           `json:"name"`
+	Etag       string           `json:"etag,omitempty"`
+	Properties subnetProperties `json:"properties"`
+
+	ipNet *net.IPNet
+}
+
+type virtualNetworkProperties struct {
+	AddressSpace struct {
+		AddressPrefixes []string `json:"addressPrefixes"`
+	} `json:"addressSpace"`
+	Subnets           []*subnet `json:"subnets"`
+	ResourceGUID      string    `json:"resourceGuid"`
+	ProvisioningState string    `json:"provisioningState"`
+}
+
+type virtualNetwork struct {
+	resource
+	Properties virtualNetworkProperties `json:"properties"`
+
+	ipNets []*net.IPNet
+}
+
+func (c *cloud) virtualNetwork(name string) (*virtualNetwork, error) {
+	for _, n := range c.virtualNetworks {
+		if strings.EqualFold(n.Name, name) {
+			return n, nil
+		}
+	}
+	return nil, notFound("Microsoft.Network/virtualNetworks", name)
+}
+
+func (n *virtualNetwork) subnet(name string) (*subnet, error) {
+	for _, sn := range n.Properties.Subnets {
+		if strings.EqualFold(sn.Name, name) {
+			return sn, nil
+		}
+	}
+	return nil, errorf(http.StatusNotFound, "NotFound", "Resource %s not found.", resourceID(networkNamespace, "virtualNetworks", n.Name, "subnets", name))
+}
+
+//subnetByID returns the subnet identified by id
+func (c *cloud) subnetByID(id string) (*virtualNetwork, *subnet, error) {
+	names, ok := parseID(id, networkNamespace, "virtualNetworks")
+	if !ok || len(names) != 3 || !strings.EqualFold(names[1], "subnets") {
+		return nil, nil, badRequest("InvalidResourceReference", "Resource %s referenced by resource is not a subnet.", id)
+	}
+	n, err := c.virtualNetwork(names[0])
+	if err != nil {
+		return nil, nil, badRequest("InvalidResourceReference", "Resource %s referenced by resource was not found.", id)
+	}
+	sn, err := n.subnet(names[2])
+	if err != nil {
+		return nil, nil, badRequest("InvalidResourceReference", "Resource %s referenced by resource was not found.", id)
+	}
+	return n, sn, nil
+}
+
+//subnetIPConfigurations returns the IP configurations of the network interfaces, of the load balancers and of the scale set instances using sn
+func (c *cloud) subnetIPConfigurations(sn *subnet) []reference {
+	var l []reference
+	for _, ni := range c.networkInterfaces {
+		for _, ipc := range ni.Properties.IPConfigurations {
+			if ipc.Properties.Subnet != nil && strings.EqualFold(ipc.Properties.Subnet.ID, sn.ID) {
+				l = append(l, reference{ID: ipc.ID})
+			}
+		}
+	}
+	for _, lb := range c.loadBalancers {
+		for _, fe := range lb.Properties.FrontendIPConfigurations {
+			if fe.Properties.Subnet != nil && strings.EqualFold(fe.Properties.Subnet.ID, sn.ID) {
+				l = append(l, reference{ID: fe.ID})
+			}
+		}
+	}
+	for _, ss := range c.scaleSets {
+		l = append(l, ss.ipConfigurations(sn)...)
+	}
+	return l
+}
+
+func (c *cloud) subnetView(sn *subnet) *subnet {
+	v := *sn
+	v.Properties.IPConfigurations = c.subnetIPConfigurations(sn)
+	return &v
+}
+
+func (c *cloud) virtualNetworkView(n *virtualNetwork) *virtualNetwork {
+	v := *n
+	v.Properties.Subnets = []*subnet{}
+	for _, sn := range n.Properties.Subnets {
+		v.Properties.Subnets = append(v.Properties.Subnets, c.subnetView(sn))
+	}
+	return &v
+}
+
+func parseCIDR(cidr string) (*net.IPNet, error) {
+	ip, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil || ip.To4() == nil {
+		return nil, badRequest("InvalidAddressPrefixFormat", "Address prefix %s does not have a valid format.", cidr)
+	}
+	if !ip.Equal(ipNet.IP) {
+		return nil, badRequest("InvalidCIDRNotation", "The address prefix %s has an invalid CIDR notation. For the given prefix length, the address prefix should be %s.", cidr, ipNet.String())
+	}
+	return ipNet, nil
+}
+
+func contains(outer, inner *net.IPNet) bool {
+	ones, _ := outer.Mask.Size()
+	innerOnes, _ := inner.Mask.Size()
+	return innerOnes >= ones && outer.Contains(inner.IP)
+}
+
+func overlaps(a, b *net.IPNet) bool {
+	return a.Contains(b.IP) || b.Contains(a.IP)
+}
+
+//checkSubnet checks the properties of the subnet named name of the network n and returns its address range
+func (c *cloud) checkSubnet(n *virtualNetwork, name string, p *subnetProperties) (*net.IPNet, error) {
+	if p.AddressPrefix == "" {
+		return nil, badRequest("InvalidRequestFormat", "Subnet %s must have an address prefix.", name)
+	}
+	ipNet, err := parseCIDR(p.AddressPrefix)
+	if err != nil {
+		return nil, err
+	}
+	inside := false
+	for _, r := range n.ipNets {
+		inside = inside || contains(r, ipNet)
+	}
+	if !inside {
+		return nil, badRequest("NetcfgSubnetRangeOutsideVnet", "Subnet '%s' is not valid because its IP address range is outside the IP address range of virtual network '%s'.", name, n.Name)
+	}
+	if ones, _ := ipNet.Mask.Size(); ones > 29 {
+		return nil, badRequest("InvalidSubnetPrefixLength", "Subnet %s has a prefix length greater than 29.", name)
+	}
+	for _, sn := range n.Properties.Subnets {
+		if !strings.EqualFold(sn.Name, name) && overlaps(sn.ipNet, ipNet) {
+			return nil, badRequest("NetcfgSubnetRangesOverlap", "Subnet '%s' is not valid because its IP address range overlaps with that of an existing subnet '%s' in virtual network '%s'.", name, sn.Name, n.Name)
+		}
+	}
+	if p.NetworkSecurityGroup != nil {
+		_, err := c.securityGroupByID(p.NetworkSecurityGroup.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ipNet, nil
+}
+
+//setSubnet creates or updates the subnet named name of the network n
+func (c *cloud) setSubnet(n *virtualNetwork, name string, p *subnetProperties) (*subnet, bool, error) {
+	ipNet, err := c.checkSubnet(n, name, p)
+	if err != nil {
+		return nil, false, err
+	}
+	sn, err := n.subnet(name)
+	created := err != nil
+	if created {
+		sn = &subnet{
+			ID:   resourceID(networkNamespace, "virtualNetworks", n.Name, "subnets", name),
+			Name: name,
+		}
+		n.Properties.Subnets = append(n.Properties.Subnets, sn)
+	} else if sn.Properties.AddressPrefix != p.AddressPrefix && len(c.subnetIPConfigurations(sn)) > 0 {
+		return nil, false, badRequest("InUseSubnetCannotBeUpdated", "Subnet %s is in use and cannot be updated.", sn.ID)
+	}
+	sn.Etag = etag()
+	sn.ipNet = ipNet
+	sn.Properties.AddressPrefix = p.AddressPrefix
+	sn.Properties.NetworkSecurityGroup = p.NetworkSecurityGroup
+	sn.Properties.ProvisioningState = stateSucceeded
+	return sn, created, nil
+}
+
+func (c *cloud) checkSubnetRemoval(sn *subnet) error {
+	if len(c.subnetIPConfigurations(sn)) > 0 {
+		return badRequest("InUseSubnetCannotBeDeleted", "Subnet %s is in use by %s and cannot be deleted.", sn.Name, c.subnetIPConfigurations(sn)[0].ID)
+	}
+	return nil
+}
+
+func listVirtualNetworks(c *cloud, r *request) (interface{}, error) {
+	l := []*virtualNetwork{}
+	for _, n := range c.virtualNetworks {
+		l = append(l, c.virtualNetworkView(n))
+	}
+	return map[string]interface{}{"value": l}, nil
+}
+
+func getVirtualNetwork(c *cloud, r *request) (interface{}, error) {
+	n, err := c.virtualNetwork(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	return c.virtualNetworkView(n), nil
+}
+
+func putVirtualNetwork(c *cloud, r *request) (interface{}, error) {
+	in := &virtualNetwork{}
+	err := r.decode(in)
+	if err != nil {
+		return nil, err
+	}
+	prefixes := in.Properties.AddressSpace.AddressPrefixes
+	if len(prefixes) == 0 {
+		return nil, badRequest("InvalidRequestFormat", "Virtual network %s must have at least one address prefix.", r.params[0])
+	}
+	var ipNets []*net.IPNet
+	for _, prefix := range prefixes {
+		ipNet, err := parseCIDR(prefix)
+		if err != nil {
+			return nil, err
+		}
+		ipNets = append(ipNets, ipNet)
+	}
+	n, err := c.virtualNetwork(r.params[0])
+	created := err != nil
+	if created {
+		err = checkLocation(in.Location)
+		if err != nil {
+			return nil, err
+		}
+		n = &virtualNetwork{
+			resource: resource{
+				ID:       resourceID(networkNamespace, "virtualNetworks", r.params[0]),
+				Name:     r.params[0],
+				Type:     "Microsoft.Network/virtualNetworks",
+				Location: strings.ToLower(in.Location),
+			},
+		}
+		n.Properties.ResourceGUID = newID()
+	}
+	//the subnets absent from the request are removed
+	kept := map[string]bool{}
+	for _, sn := range in.Properties.Subnets {
+		kept[strings.ToLower(sn.Name)] = true
+	}
+	var subnets []*subnet
+	for _, sn := range n.Properties.Subnets {
+		if kept[strings.ToLower(sn.Name)] {
+			subnets = append(subnets, sn)
+			continue
+		}
+		err = c.checkSubnetRemoval(sn)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, sn := range subnets {
+		inside := false
+		for _, r := range ipNets {
+			inside = inside || contains(r, sn.ipNet)
+		}
+		if !inside {
+			return nil, badRequest("NetcfgSubnetRangeOutsideVnet", "Subnet '%s' is not valid because its IP address range is outside the IP address range of virtual network '%s'.", sn.Name, n.Name)
+		}
+	}
+	//changes are applied on a copy so that the network is left unchanged if a subnet is invalid
+	updated := *n
+	updated.ipNets = ipNets
+	updated.Properties.AddressSpace.AddressPrefixes = prefixes
+	updated.Properties.Subnets = subnets
+	for _, sn := range in.Properties.Subnets {
+		_, _, err = c.setSubnet(&updated, sn.Name, &sn.Properties)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if in.Tags != nil || created {
+		updated.Tags = in.Tags
+	}
+	updated.Etag = etag()
+	updated.Properties.ProvisioningState = stateUpdating
+	*n = updated
+	if created {
+		c.virtualNetworks = append(c.virtualNetworks, n)
+	}
+	return putResponse(networkNamespace, created, c.virtualNetworkView(n), func() {
+		n.Properties.ProvisioningState = stateSucceeded
+	}), nil
+}
+
+func deleteVirtualNetwork(c *cloud, r *request) (interface{}, error) {
+	n, err := c.virtualNetwork(r.params[0])
+	if err != nil {
+		return nil, nil
+	}
+	for _, sn := range n.Properties.Subnets {
+		err = c.checkSubnetRemoval(sn)
+		if err != nil {
+			return nil, err
+		}
+	}
+	n.Properties.ProvisioningState = stateDeleting
+	return accepted(networkNamespace, func() {
+		for i, o := range c.virtualNetworks {
+			if o == n {
+				c.virtualNetworks = append(c.virtualNetworks[:i], c.virtualNetworks[i+1:]...)
+				break
+			}
+		}
+	}), nil
+}
+
+func listSubnets(c *cloud, r *request) (interface{}, error) {
+	n, err := c.virtualNetwork(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	l := []*subnet{}
+	for _, sn := range n.Properties.Subnets {
+		l = append(l, c.subnetView(sn))
+	}
+	return map[string]interface{}{"value": l}, nil
+}
+
+func getSubnet(c *cloud, r *request) (interface{}, error) {
+	n, err := c.virtualNetwork(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	sn, err := n.subnet(r.params[1])
+	if err != nil {
+		return nil, err
+	}
+	return c.subnetView(sn), nil
+}
+
+func putSubnet(c *cloud, r *request) (interface{}, error) {
+	n, err := c.virtualNetwork(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	in := &subnet{}
+	err = r.decode(in)
+	if err != nil {
+		return nil, err
+	}
+	sn, created, err := c.setSubnet(n, r.params[1], &in.Properties)
+	if err != nil {
+		return nil, err
+	}
+	sn.Properties.ProvisioningState = stateUpdating
+	return putResponse(networkNamespace, created, c.subnetView(sn), func() {
+		sn.Properties.ProvisioningState = stateSucceeded
+	}), nil
+}
+
+func deleteSubnet(c *cloud, r *request) (interface{}, error) {
+	n, err := c.virtualNetwork(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	sn, err := n.subnet(r.params[1])
+	if err != nil {
+		return nil, nil
+	}
+	err = c.checkSubnetRemoval(sn)
+	if err != nil {
+		return nil, err
+	}
+	sn.Properties.ProvisioningState = stateDeleting
+	return accepted(networkNamespace, func() {
+		for i, o := range n.Properties.Subnets {
+			if o == sn {
+				n.Properties.Subnets = append(n.Properties.Subnets[:i], n.Properties.Subnets[i+1:]...)
+				break
+			}
+		}
+	}), nil
+}
@@ -0,0 +1,216 @@
+package fake
+
+import (
+	"strings"
+
+	"github.com/SebastienDorgan/anyclouds/iputils"
+)
+
+//publicAddressPrefix range from which public addresses are allocated
+const publicAddressPrefix = "40.121.0.0/16"
+
+type publicIPAddressSku struct {
+	Name string `json:"name"`
+}
+
+type publicIPAddressProperties struct {
+	PublicIPAllocationMethod string     `json:"publicIPAllocationMethod"`
+	PublicIPAddressVersion   string     `json:"publicIPAddressVersion"`
+	IPAddress                string     `json:"ipAddress,omitempty"`
+	IPConfiguration          *reference `json:"ipConfiguration,omitempty"`
+	PublicIPPrefix           *reference `json:"publicIPPrefix,omitempty"`
+	IdleTimeoutInMinutes     int        `json:"idleTimeoutInMinutes"`
+	ResourceGUID             string     `json:"resourceGuid"`
+	ProvisioningState        string     `json:"provisioningState"`
+}
+
+type publicIPAddress struct {
+	resource
+	Sku        *publicIPAddressSku       `json:"sku,omitempty"`
+	Properties publicIPAddressProperties `json:"properties"`
+}
+
+func (c *cloud) publicIPAddress(name string) (*publicIPAddress, error) {
+	for _, ip := range c.publicIPAddresses {
+		if strings.EqualFold(ip.Name, name) {
+			return ip, nil
+		}
+	}
+	return nil, notFound("Microsoft.Network/publicIPAddresses", name)
+}
+
+//publicIPAddressByID returns the public IP address identified by id
+func (c *cloud) publicIPAddressByID(id string) (*publicIPAddress, error) {
+	names, ok := parseID(id, networkNamespace, "publicIPAddresses")
+	if ok && len(names) == 1 {
+		ip, err := c.publicIPAddress(names[0])
+		if err == nil {
+			return ip, nil
+		}
+	}
+	return nil, badRequest("InvalidResourceReference", "Resource %s referenced by resource was not found.", id)
+}
+
+//publicIPConfiguration returns the identifier of the IP configuration or of the load balancer frontend IP configuration
+//associated to ip, or an empty string if ip is not associated
+func (c *cloud) publicIPConfiguration(ip *publicIPAddress) string {
+	for _, ni := range c.networkInterfaces {
+		for _, ipc := range ni.Properties.IPConfigurations {
+			if ipc.Properties.PublicIPAddress != nil && strings.EqualFold(ipc.Properties.PublicIPAddress.ID, ip.ID) {
+				return ipc.ID
+			}
+		}
+	}
+	for _, lb := range c.loadBalancers {
+		for _, fe := range lb.Properties.FrontendIPConfigurations {
+			if fe.Properties.PublicIPAddress != nil && strings.EqualFold(fe.Properties.PublicIPAddress.ID, ip.ID) {
+				return fe.ID
+			}
+		}
+	}
+	return ""
+}
+
+//allocatePublicAddress returns the first address of the public range not used by another public IP address
+func (c *cloud) allocatePublicAddress() (string, error) {
+	used := map[string]bool{}
+	for _, ip := range c.publicIPAddresses {
+		used[ip.Properties.IPAddress] = true
+	}
+	r, err := iputils.GetRange(publicAddressPrefix)
+	if err != nil {
+		return "", err
+	}
+	for u := iputils.Itou(&r.FirstIP) + 1; u < iputils.Itou(&r.LastIP); u++ {
+		addr := iputils.Utoi(u).String()
+		if !used[addr] {
+			return addr, nil
+		}
+	}
+	return "", badRequest("PublicIPCountLimitReached", "Cannot create more public IP addresses.")
+}
+
+func (c *cloud) publicIPAddressView(ip *publicIPAddress) *publicIPAddress {
+	v := *ip
+	v.Properties.IPConfiguration = nil
+	if id := c.publicIPConfiguration(ip); id != "" {
+		v.Properties.IPConfiguration = &reference{ID: id}
+	}
+	return &v
+}
+
+func listPublicIPAddresses(c *cloud, r *request) (interface{}, error) {
+	l := []*publicIPAddress{}
+	for _, ip := range c.publicIPAddresses {
+		l = append(l, c.publicIPAddressView(ip))
+	}
+	return map[string]interface{}{"value": l}, nil
+}
+
+func getPublicIPAddress(c *cloud, r *request) (interface{}, error) {
+	ip, err := c.publicIPAddress(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	return c.publicIPAddressView(ip), nil
+}
+
+//checkPublicIPAddress checks and normalizes the properties of the public IP address in
+func checkPublicIPAddress(in *publicIPAddress) error {
+	p := &in.Properties
+	sku := "Basic"
+	if in.Sku != nil {
+		if sku = oneOf(in.Sku.Name, "Basic", "Standard"); sku == "" {
+			return badRequest("InvalidPublicIPAddressSku", "Public IP address SKU %s is not supported.", in.Sku.Name)
+		}
+	}
+	in.Sku = &publicIPAddressSku{Name: sku}
+	if p.PublicIPAllocationMethod = oneOf(p.PublicIPAllocationMethod, "Static", "Dynamic"); p.PublicIPAllocationMethod == "" {
+		p.PublicIPAllocationMethod = "Dynamic"
+	}
+	if sku == "Standard" && p.PublicIPAllocationMethod != "Static" {
+		return badRequest("StandardSkuPublicIPAddressesMustBeStatic", "Standard sku publicIp %s must have AllocationMethod set to Static.", in.Name)
+	}
+	if p.PublicIPAddressVersion = oneOf(p.PublicIPAddressVersion, "IPv4", "IPv6"); p.PublicIPAddressVersion == "" {
+		p.PublicIPAddressVersion = "IPv4"
+	}
+	if p.PublicIPAddressVersion == "IPv6" {
+		return badRequest("IPv6PublicIPAddressNotSupported", "IPv6 public IP addresses are not supported.")
+	}
+	if p.PublicIPPrefix != nil && p.PublicIPPrefix.ID != "" {
+		return badRequest("InvalidResourceReference", "Resource %s referenced by resource was not found.", p.PublicIPPrefix.ID)
+	}
+	p.PublicIPPrefix = nil
+	if p.IdleTimeoutInMinutes == 0 {
+		p.IdleTimeoutInMinutes = 4
+	}
+	return nil
+}
+
+func putPublicIPAddress(c *cloud, r *request) (interface{}, error) {
+	in := &publicIPAddress{}
+	err := r.decode(in)
+	if err != nil {
+		return nil, err
+	}
+	in.Name = r.params[0]
+	err = checkPublicIPAddress(in)
+	if err != nil {
+		return nil, err
+	}
+	ip, err := c.publicIPAddress(r.params[0])
+	created := err != nil
+	if created {
+		err = checkLocation(in.Location)
+		if err != nil {
+			return nil, err
+		}
+		ip = &publicIPAddress{
+			resource: resource{
+				ID:       resourceID(networkNamespace, "publicIPAddresses", r.params[0]),
+				Name:     r.params[0],
+				Type:     "Microsoft.Network/publicIPAddresses",
+				Location: strings.ToLower(in.Location),
+			},
+			Sku: in.Sku,
+		}
+		ip.Properties.ResourceGUID = newID()
+		ip.Properties.IPAddress, err = c.allocatePublicAddress()
+		if err != nil {
+			return nil, err
+		}
+		c.publicIPAddresses = append(c.publicIPAddresses, ip)
+	} else if ip.Sku.Name != in.Sku.Name {
+		return nil, badRequest("PublicIPAddressSkuCannotBeChanged", "The SKU of public IP address %s cannot be changed.", ip.ID)
+	}
+	ip.Properties.PublicIPAllocationMethod = in.Properties.PublicIPAllocationMethod
+	ip.Properties.PublicIPAddressVersion = in.Properties.PublicIPAddressVersion
+	ip.Properties.IdleTimeoutInMinutes = in.Properties.IdleTimeoutInMinutes
+	if in.Tags != nil || created {
+		ip.Tags = in.Tags
+	}
+	ip.Etag = etag()
+	ip.Properties.ProvisioningState = stateUpdating
+	return putResponse(networkNamespace, created, c.publicIPAddressView(ip), func() {
+		ip.Properties.ProvisioningState = stateSucceeded
+	}), nil
+}
+
+func deletePublicIPAddress(c *cloud, r *request) (interface{}, error) {
+	ip, err := c.publicIPAddress(r.params[0])
+	if err != nil {
+		return nil, nil
+	}
+	if id := c.publicIPConfiguration(ip); id != "" {
+		return nil, badRequest("PublicIPAddressCannotBeDeleted", "Public IP address %s can not be deleted since it is still allocated to resource %s.", ip.ID, id)
+	}
+	ip.Properties.ProvisioningState = stateDeleting
+	return accepted(networkNamespace, func() {
+		for i, o := range c.publicIPAddresses {
+			if o == ip {
+				c.publicIPAddresses = append(c.publicIPAddresses[:i], c.publicIPAddresses[i+1:]...)
+				break
+			}
+		}
+	}), nil
+}
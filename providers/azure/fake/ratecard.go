@@ -0,0 +1,75 @@
+package fake
+
+import (
+	"net/http"
+	"regexp"
+
+	"github.com/google/uuid"
+)
+
+func commerceRoutes() []route {
+	return []route{
+		{"GET", "providers/Microsoft.Commerce/RateCard", http.StatusOK, getRateCard},
+	}
+}
+
+//meter RateCard entry giving the price of a resource
+type meter struct {
+	MeterID          string             `json:"MeterId"`
+	MeterName        string             `json:"MeterName"`
+	MeterCategory    string             `json:"MeterCategory"`
+	MeterSubCategory string             `json:"MeterSubCategory"`
+	Unit             string             `json:"Unit"`
+	MeterTags        []string           `json:"MeterTags"`
+	MeterRegion      string             `json:"MeterRegion"`
+	MeterRates       map[string]float64 `json:"MeterRates"`
+	EffectiveDate    string             `json:"EffectiveDate"`
+	IncludedQuantity float64            `json:"IncludedQuantity"`
+}
+
+//newMeters returns the compute hour meters of the virtual machine sizes
+func newMeters() []meter {
+	var l []meter
+	for _, size := range newSizes() {
+		l = append(l, meter{
+			MeterID:          uuid.NewSHA1(uuid.NameSpaceOID, []byte(size.Name)).String(),
+			MeterName:        "Compute Hours",
+			MeterCategory:    "Virtual Machines",
+			MeterSubCategory: size.Name,
+			Unit:             "1 Hour",
+			MeterTags:        []string{},
+			MeterRegion:      Location,
+			MeterRates:       map[string]float64{"0": size.price},
+			EffectiveDate:    "2019-10-01T00:00:00Z",
+		})
+	}
+	return l
+}
+
+var filterExpr = regexp.MustCompile(`(\w+) eq '([^']*)'`)
+
+//getRateCard returns the meters of the offer selected by the $filter parameter
+func getRateCard(c *cloud, r *request) (interface{}, error) {
+	criteria := map[string]string{}
+	for _, m := range filterExpr.FindAllStringSubmatch(r.URL.Query().Get("$filter"), -1) {
+		criteria[m[1]] = m[2]
+	}
+	for _, name := range []string{"OfferDurableId", "Currency", "Locale", "RegionInfo"} {
+		if _, ok := criteria[name]; !ok {
+			return nil, badRequest("InvalidFilter", "The $filter parameter must specify %s.", name)
+		}
+	}
+	if criteria["OfferDurableId"] != OfferNumber {
+		return nil, badRequest("InvalidOfferId", "Offer %s is not supported.", criteria["OfferDurableId"])
+	}
+	if criteria["Currency"] != Currency || criteria["RegionInfo"] != RegionInfo {
+		return nil, badRequest("InvalidCurrency", "Currency %s is not supported in region %s.", criteria["Currency"], criteria["RegionInfo"])
+	}
+	return map[string]interface{}{
+		"OfferTerms":    []interface{}{},
+		"Meters":        c.meters,
+		"Currency":      Currency,
+		"Locale":        criteria["Locale"],
+		"IsTaxIncluded": false,
+	}, nil
+}
@@ -0,0 +1,384 @@
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+//maxScaleSetCapacity maximum number of instances of a scale set using a platform image
+const maxScaleSetCapacity = 1000
+
+type scaleSetSku struct {
+	Name     string `json:"name"`
+	Tier     string `json:"tier,omitempty"`
+	Capacity *int   `json:"capacity,omitempty"`
+}
+
+type scaleSetIPConfiguration struct {
+	Name       string `json:"name"`
+	Properties struct {
+		Subnet  *reference `json:"subnet,omitempty"`
+		Primary bool       `json:"primary,omitempty"`
+	} `json:"properties"`
+}
+
+type scaleSetNetworkConfiguration struct {
+	Name       string `json:"name"`
+	Properties struct {
+		Primary              bool                      `json:"primary,omitempty"`
+		NetworkSecurityGroup *reference                `json:"networkSecurityGroup,omitempty"`
+		IPConfigurations     []scaleSetIPConfiguration `json:"ipConfigurations"`
+	} `json:"properties"`
+}
+
+type scaleSetOSProfile struct {
+	ComputerNamePrefix string          `json:"computerNamePrefix"`
+	AdminUsername      string          `json:"adminUsername"`
+	AdminPassword      string          `json:"adminPassword,omitempty"`
+	CustomData         string          `json:"customData,omitempty"`
+	LinuxConfiguration json.RawMessage `json:"linuxConfiguration,omitempty"`
+}
+
+type scaleSetVMProfile struct {
+	OsProfile      *scaleSetOSProfile `json:"osProfile,omitempty"`
+	StorageProfile storageProfile     `json:"storageProfile"`
+	NetworkProfile struct {
+		NetworkInterfaceConfigurations []scaleSetNetworkConfiguration `json:"networkInterfaceConfigurations"`
+	} `json:"networkProfile"`
+	Priority string `json:"priority,omitempty"`
+}
+
+//virtualMachineScaleSet scale set keeping sku.capacity instances of its virtual machine profile
+//The instances are not backed by virtual machines, network interfaces or disks of the resource group
+type virtualMachineScaleSet struct {
+	resource
+	Sku        scaleSetSku `json:"sku"`
+	Properties struct {
+		UpgradePolicy *struct {
+			Mode string `json:"mode"`
+		} `json:"upgradePolicy,omitempty"`
+		VirtualMachineProfile *scaleSetVMProfile `json:"virtualMachineProfile,omitempty"`
+		Overprovision         bool               `json:"overprovision"`
+		UniqueID              string             `json:"uniqueId"`
+		ProvisioningState     string             `json:"provisioningState"`
+	} `json:"properties"`
+
+	instances []*scaleSetVM
+	//nextInstanceID identifier of the next instance, identifiers are not reused
+	nextInstanceID int
+}
+
+type scaleSetVM struct {
+	resource
+	InstanceID string `json:"instanceId"`
+	Sku        struct {
+		Name string `json:"name"`
+		Tier string `json:"tier"`
+	} `json:"sku"`
+	Properties struct {
+		LatestModelApplied bool   `json:"latestModelApplied"`
+		VMID               string `json:"vmId"`
+		HardwareProfile    struct {
+			VMSize string `json:"vmSize"`
+		} `json:"hardwareProfile"`
+		StorageProfile struct {
+			ImageReference *imageReference `json:"imageReference,omitempty"`
+		} `json:"storageProfile"`
+		OsProfile struct {
+			ComputerName  string `json:"computerName"`
+			AdminUsername string `json:"adminUsername"`
+		} `json:"osProfile"`
+		ProvisioningState string `json:"provisioningState"`
+	} `json:"properties"`
+}
+
+func (c *cloud) scaleSet(name string) (*virtualMachineScaleSet, error) {
+	for _, ss := range c.scaleSets {
+		if strings.EqualFold(ss.Name, name) {
+			return ss, nil
+		}
+	}
+	return nil, notFound("Microsoft.Compute/virtualMachineScaleSets", name)
+}
+
+//view returns the representation of the scale set, secrets of the OS profile are not returned
+func (ss *virtualMachineScaleSet) view() *virtualMachineScaleSet {
+	v := *ss
+	if p := ss.Properties.VirtualMachineProfile; p != nil && p.OsProfile != nil {
+		vp := *p
+		os := *p.OsProfile
+		os.AdminPassword = ""
+		os.CustomData = ""
+		vp.OsProfile = &os
+		v.Properties.VirtualMachineProfile = &vp
+	}
+	return &v
+}
+
+//ipConfigurations returns the IP configurations of the instances of the scale set attached to sn
+func (ss *virtualMachineScaleSet) ipConfigurations(sn *subnet) []reference {
+	var l []reference
+	p := ss.Properties.VirtualMachineProfile
+	for _, inst := range ss.instances {
+		for _, nc := range p.NetworkProfile.NetworkInterfaceConfigurations {
+			for _, ipc := range nc.Properties.IPConfigurations {
+				if ipc.Properties.Subnet != nil && strings.EqualFold(ipc.Properties.Subnet.ID, sn.ID) {
+					l = append(l, reference{ID: fmt.Sprintf("%s/networkInterfaces/%s/ipConfigurations/%s", inst.ID, nc.Name, ipc.Name)})
+				}
+			}
+		}
+	}
+	return l
+}
+
+//launch adds an instance to the scale set
+func (ss *virtualMachineScaleSet) launch() {
+	p := ss.Properties.VirtualMachineProfile
+	id := strconv.Itoa(ss.nextInstanceID)
+	inst := &scaleSetVM{
+		resource: resource{
+			ID:       ss.ID + "/virtualMachines/" + id,
+			Name:     ss.Name + "_" + id,
+			Type:     "Microsoft.Compute/virtualMachineScaleSets/virtualMachines",
+			Location: ss.Location,
+		},
+		InstanceID: id,
+	}
+	inst.Sku.Name = ss.Sku.Name
+	inst.Sku.Tier = ss.Sku.Tier
+	inst.Properties.LatestModelApplied = true
+	inst.Properties.VMID = newID()
+	inst.Properties.HardwareProfile.VMSize = ss.Sku.Name
+	inst.Properties.StorageProfile.ImageReference = p.StorageProfile.ImageReference
+	inst.Properties.OsProfile.ComputerName = fmt.Sprintf("%s%06s", p.OsProfile.ComputerNamePrefix, strconv.FormatInt(int64(ss.nextInstanceID), 36))
+	inst.Properties.OsProfile.AdminUsername = p.OsProfile.AdminUsername
+	inst.Properties.ProvisioningState = stateSucceeded
+	ss.nextInstanceID++
+	ss.instances = append(ss.instances, inst)
+}
+
+//reconcile launches or removes instances until the scale set reaches its capacity, the instances with the highest identifiers are removed first
+func (ss *virtualMachineScaleSet) reconcile() {
+	capacity := *ss.Sku.Capacity
+	if len(ss.instances) > capacity {
+		ss.instances = ss.instances[:capacity]
+	}
+	for len(ss.instances) < capacity {
+		ss.launch()
+	}
+}
+
+func checkScaleSet(c *cloud, in *virtualMachineScaleSet) error {
+	if _, ok := c.size(in.Sku.Name); !ok {
+		return badRequest("InvalidParameter", "The value %s provided for the VM size is not valid.", in.Sku.Name)
+	}
+	if in.Sku.Capacity == nil || *in.Sku.Capacity < 0 || *in.Sku.Capacity > maxScaleSetCapacity {
+		return badRequest("InvalidParameter", "The value of parameter 'sku.capacity' must be between 0 and %d.", maxScaleSetCapacity)
+	}
+	p := in.Properties.VirtualMachineProfile
+	if p == nil {
+		return badRequest("InvalidParameter", "Required parameter 'virtualMachineProfile' is missing (null).")
+	}
+	if p.OsProfile == nil || p.OsProfile.ComputerNamePrefix == "" || p.OsProfile.AdminUsername == "" {
+		return badRequest("InvalidParameter", "Required parameter 'osProfile' is missing (null).")
+	}
+	switch p.Priority {
+	case "", "Regular", "Low":
+	default:
+		return badRequest("InvalidParameter", "The value %s of parameter 'priority' is not valid.", p.Priority)
+	}
+	err := c.checkImageReference(p.StorageProfile.ImageReference)
+	if err != nil {
+		return err
+	}
+	if len(p.NetworkProfile.NetworkInterfaceConfigurations) == 0 {
+		return badRequest("VirtualMachineScaleSetMustHaveOneNetworkInterfaceAsPrimary", "Virtual Machine Scale Set %s must have one network interface set as the primary.", in.Name)
+	}
+	for _, nc := range p.NetworkProfile.NetworkInterfaceConfigurations {
+		if nc.Properties.NetworkSecurityGroup != nil {
+			_, err = c.securityGroupByID(nc.Properties.NetworkSecurityGroup.ID)
+			if err != nil {
+				return err
+			}
+		}
+		if len(nc.Properties.IPConfigurations) == 0 {
+			return badRequest("InvalidParameter", "Network interface configuration %s must have at least one IP configuration.", nc.Name)
+		}
+		for _, ipc := range nc.Properties.IPConfigurations {
+			if ipc.Properties.Subnet == nil {
+				return badRequest("InvalidParameter", "Required parameter 'subnet' of IP configuration %s is missing (null).", ipc.Name)
+			}
+			_, _, err = c.subnetByID(ipc.Properties.Subnet.ID)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func listScaleSets(c *cloud, r *request) (interface{}, error) {
+	l := []*virtualMachineScaleSet{}
+	for _, ss := range c.scaleSets {
+		l = append(l, ss.view())
+	}
+	return map[string]interface{}{"value": l}, nil
+}
+
+func getScaleSet(c *cloud, r *request) (interface{}, error) {
+	ss, err := c.scaleSet(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	return ss.view(), nil
+}
+
+//putScaleSet creates or updates a scale set, instances are launched or removed when the operation completes
+func putScaleSet(c *cloud, r *request) (interface{}, error) {
+	in := &virtualMachineScaleSet{}
+	err := r.decode(in)
+	if err != nil {
+		return nil, err
+	}
+	ss, err := c.scaleSet(r.params[0])
+	created := err != nil
+	if created {
+		err = checkLocation(in.Location)
+		if err != nil {
+			return nil, err
+		}
+	} else if in.Properties.VirtualMachineProfile == nil {
+		in.Properties.VirtualMachineProfile = ss.Properties.VirtualMachineProfile
+	} else if in.Properties.VirtualMachineProfile.OsProfile != nil && ss.Properties.VirtualMachineProfile.OsProfile != nil {
+		//secrets are not returned by GET requests, the ones of the current model are kept
+		os := in.Properties.VirtualMachineProfile.OsProfile
+		if os.AdminPassword == "" {
+			os.AdminPassword = ss.Properties.VirtualMachineProfile.OsProfile.AdminPassword
+		}
+		if os.CustomData == "" {
+			os.CustomData = ss.Properties.VirtualMachineProfile.OsProfile.CustomData
+		}
+	}
+	err = checkScaleSet(c, in)
+	if err != nil {
+		return nil, err
+	}
+	if created {
+		ss = &virtualMachineScaleSet{
+			resource: resource{
+				ID:       resourceID(computeNamespace, "virtualMachineScaleSets", r.params[0]),
+				Name:     r.params[0],
+				Type:     "Microsoft.Compute/virtualMachineScaleSets",
+				Location: strings.ToLower(in.Location),
+			},
+		}
+		ss.Properties.UniqueID = newID()
+		ss.Properties.ProvisioningState = "Creating"
+		c.scaleSets = append(c.scaleSets, ss)
+	} else {
+		ss.Properties.ProvisioningState = stateUpdating
+	}
+	ss.Tags = in.Tags
+	ss.Sku = in.Sku
+	if ss.Sku.Tier == "" {
+		ss.Sku.Tier = "Standard"
+	}
+	ss.Properties.UpgradePolicy = in.Properties.UpgradePolicy
+	ss.Properties.VirtualMachineProfile = in.Properties.VirtualMachineProfile
+	ss.Properties.Overprovision = in.Properties.Overprovision
+	return putResponse(computeNamespace, created, ss.view(), func() {
+		ss.reconcile()
+		ss.Properties.ProvisioningState = stateSucceeded
+	}), nil
+}
+
+//patchScaleSet updates the sku and the tags of a scale set, instances are launched or removed when the operation completes
+func patchScaleSet(c *cloud, r *request) (interface{}, error) {
+	in := &struct {
+		Sku  *scaleSetSku      `json:"sku"`
+		Tags map[string]string `json:"tags"`
+	}{}
+	err := r.decode(in)
+	if err != nil {
+		return nil, err
+	}
+	ss, err := c.scaleSet(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	sku := ss.Sku
+	if in.Sku != nil {
+		if in.Sku.Name != "" {
+			sku.Name = in.Sku.Name
+		}
+		if in.Sku.Capacity != nil {
+			sku.Capacity = in.Sku.Capacity
+		}
+	}
+	if _, ok := c.size(sku.Name); !ok {
+		return nil, badRequest("InvalidParameter", "The value %s provided for the VM size is not valid.", sku.Name)
+	}
+	if *sku.Capacity < 0 || *sku.Capacity > maxScaleSetCapacity {
+		return nil, badRequest("InvalidParameter", "The value of parameter 'sku.capacity' must be between 0 and %d.", maxScaleSetCapacity)
+	}
+	ss.Sku = sku
+	if in.Tags != nil {
+		ss.Tags = in.Tags
+	}
+	ss.Properties.ProvisioningState = stateUpdating
+	return putResponse(computeNamespace, false, ss.view(), func() {
+		ss.reconcile()
+		ss.Properties.ProvisioningState = stateSucceeded
+	}), nil
+}
+
+//deleteScaleSet deletes a scale set and its instances
+func deleteScaleSet(c *cloud, r *request) (interface{}, error) {
+	ss, err := c.scaleSet(r.params[0])
+	if err != nil {
+		return nil, nil
+	}
+	ss.Properties.ProvisioningState = stateDeleting
+	return accepted(computeNamespace, func() {
+		for i, o := range c.scaleSets {
+			if o == ss {
+				c.scaleSets = append(c.scaleSets[:i], c.scaleSets[i+1:]...)
+				break
+			}
+		}
+	}), nil
+}
+
+func listScaleSetVMs(c *cloud, r *request) (interface{}, error) {
+	ss, err := c.scaleSet(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"value": ss.instances}, nil
+}
+
+//deleteScaleSetVM deletes an instance of a scale set and decrements its capacity
+func deleteScaleSetVM(c *cloud, r *request) (interface{}, error) {
+	ss, err := c.scaleSet(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	for _, inst := range ss.instances {
+		if inst.InstanceID != r.params[1] {
+			continue
+		}
+		inst.Properties.ProvisioningState = stateDeleting
+		return accepted(computeNamespace, func() {
+			for i, o := range ss.instances {
+				if o == inst {
+					ss.instances = append(ss.instances[:i], ss.instances[i+1:]...)
+					*ss.Sku.Capacity--
+					break
+				}
+			}
+		}), nil
+	}
+	return nil, notFound("Microsoft.Compute/virtualMachineScaleSets/virtualMachines", ss.Name+"/"+r.params[1])
+}
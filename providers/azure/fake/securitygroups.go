@@ -0,0 +1,292 @@
+package fake
+
+import (
+	"net"
+	"strconv"
+	"strings"
+)
+
+type securityRuleProperties struct {
+	Description              string `json:"description,omitempty"`
+	Protocol                 string `json:"protocol"`
+	SourcePortRange          string `json:"sourcePortRange,omitempty"`
+	DestinationPortRange     string `json:"destinationPortRange,omitempty"`
+	SourceAddressPrefix      string `json:"sourceAddressPrefix,omitempty"`
+	DestinationAddressPrefix string `json:"destinationAddressPrefix,omitempty"`
+	Access                   string `json:"access"`
+	Priority                 int    `json:"priority"`
+	Direction                string `json:"direction"`
+	ProvisioningState        string `json:"provisioningState"`
+}
+
+type securityRule struct {
+	ID         string                 `json:"id"`
+	Name       string                 `json:"name"`
+	Etag       string                 `json:"etag,omitempty"`
+	Properties securityRuleProperties `json:"properties"`
+}
+
+type securityGroupProperties struct {
+	SecurityRules        []*securityRule `json:"securityRules"`
+	DefaultSecurityRules []*securityRule `json:"defaultSecurityRules"`
+	NetworkInterfaces    []reference     `json:"networkInterfaces,omitempty"`
+	Subnets              []reference     `json:"subnets,omitempty"`
+	ResourceGUID         string          `json:"resourceGuid"`
+	ProvisioningState    string          `json:"provisioningState"`
+}
+
+type securityGroup struct {
+	resource
+	Properties securityGroupProperties `json:"properties"`
+}
+
+func (c *cloud) securityGroup(name string) (*securityGroup, error) {
+	for _, sg := range c.securityGroups {
+		if strings.EqualFold(sg.Name, name) {
+			return sg, nil
+		}
+	}
+	return nil, notFound("Microsoft.Network/networkSecurityGroups", name)
+}
+
+//securityGroupByID returns the security group identified by id
+func (c *cloud) securityGroupByID(id string) (*securityGroup, error) {
+	names, ok := parseID(id, networkNamespace, "networkSecurityGroups")
+	if ok && len(names) == 1 {
+		sg, err := c.securityGroup(names[0])
+		if err == nil {
+			return sg, nil
+		}
+	}
+	return nil, badRequest("InvalidResourceReference", "Resource %s referenced by resource was not found.", id)
+}
+
+//defaultSecurityRules returns the rules Azure adds to every security group
+func defaultSecurityRules(sgName string) []*securityRule {
+	rule := func(name, description, direction, access string, priority int, source, destination string) *securityRule {
+		return &securityRule{
+			ID:   resourceID(networkNamespace, "networkSecurityGroups", sgName, "defaultSecurityRules", name),
+			Name: name,
+			Etag: etag(),
+			Properties: securityRuleProperties{
+				Description:              description,
+				Protocol:                 "*",
+				SourcePortRange:          "*",
+				DestinationPortRange:     "*",
+				SourceAddressPrefix:      source,
+				DestinationAddressPrefix: destination,
+				Access:                   access,
+				Priority:                 priority,
+				Direction:                direction,
+				ProvisioningState:        stateSucceeded,
+			},
+		}
+	}
+	return []*securityRule{
+		rule("AllowVnetInBound", "Allow inbound traffic from all VMs in VNET", "Inbound", "Allow", 65000, "VirtualNetwork", "VirtualNetwork"),
+		rule("AllowAzureLoadBalancerInBound", "Allow inbound traffic from azure load balancer", "Inbound", "Allow", 65001, "AzureLoadBalancer", "*"),
+		rule("DenyAllInBound", "Deny all inbound traffic", "Inbound", "Deny", 65500, "*", "*"),
+		rule("AllowVnetOutBound", "Allow outbound traffic from all VMs to all VMs in VNET", "Outbound", "Allow", 65000, "VirtualNetwork", "VirtualNetwork"),
+		rule("AllowInternetOutBound", "Allow outbound traffic from all VMs to Internet", "Outbound", "Allow", 65001, "*", "Internet"),
+		rule("DenyAllOutBound", "Deny all outbound traffic", "Outbound", "Deny", 65500, "*", "*"),
+	}
+}
+
+//oneOf returns the value of values equal to v ignoring case, or an empty string
+func oneOf(v string, values ...string) string {
+	for _, value := range values {
+		if strings.EqualFold(v, value) {
+			return value
+		}
+	}
+	return ""
+}
+
+func validPortRange(r string) bool {
+	if r == "*" {
+		return true
+	}
+	tokens := strings.Split(r, "-")
+	if len(tokens) > 2 {
+		return false
+	}
+	previous := 0
+	for _, t := range tokens {
+		p, err := strconv.Atoi(t)
+		if err != nil || p < previous || p > 65535 {
+			return false
+		}
+		previous = p
+	}
+	return true
+}
+
+func validAddressPrefix(prefix string) bool {
+	if oneOf(prefix, "*", "VirtualNetwork", "Internet", "AzureLoadBalancer") != "" {
+		return true
+	}
+	if net.ParseIP(prefix) != nil {
+		return true
+	}
+	_, _, err := net.ParseCIDR(prefix)
+	return err == nil
+}
+
+//checkSecurityRule checks and normalizes the rule r
+func checkSecurityRule(r *securityRule) error {
+	p := &r.Properties
+	if r.Name == "" {
+		return badRequest("InvalidRequestFormat", "Security rule name is missing.")
+	}
+	protocol := oneOf(p.Protocol, "Tcp", "Udp", "Icmp", "*")
+	if protocol == "" {
+		return badRequest("SecurityRuleInvalidProtocol", "Security rule %s has invalid Protocol. Value provided: %s. Allowed values: Tcp, Udp, Icmp, *.", r.Name, p.Protocol)
+	}
+	p.Protocol = protocol
+	if p.Access = oneOf(p.Access, "Allow", "Deny"); p.Access == "" {
+		return badRequest("SecurityRuleInvalidAccess", "Security rule %s has invalid Access. Allowed values: Allow, Deny.", r.Name)
+	}
+	if p.Direction = oneOf(p.Direction, "Inbound", "Outbound"); p.Direction == "" {
+		return badRequest("SecurityRuleInvalidDirection", "Security rule %s has invalid Direction. Allowed values: Inbound, Outbound.", r.Name)
+	}
+	if p.Priority < 100 || p.Priority > 4096 {
+		return badRequest("SecurityRuleInvalidPriority", "Security rule %s has invalid Priority. Value provided: %d. Allowed values are from 100 to 4096.", r.Name, p.Priority)
+	}
+	for _, pr := range []string{p.SourcePortRange, p.DestinationPortRange} {
+		if !validPortRange(pr) {
+			return badRequest("SecurityRuleInvalidPortRange", "Security rule %s has invalid Port range. Value provided: %s. Value should be an integer OR integer range with '-' delimiter. Valid range 0-65535.", r.Name, pr)
+		}
+	}
+	for _, prefix := range []string{p.SourceAddressPrefix, p.DestinationAddressPrefix} {
+		if !validAddressPrefix(prefix) {
+			return badRequest("SecurityRuleInvalidAddressPrefix", "Security rule %s has invalid Address prefix. Value provided: %s.", r.Name, prefix)
+		}
+	}
+	return nil
+}
+
+//checkSecurityRules checks the rules of the security group named sgName and assigns their identifiers
+func checkSecurityRules(sgName string, rules []*securityRule) error {
+	names := map[string]bool{}
+	priorities := map[string]string{}
+	for _, r := range rules {
+		err := checkSecurityRule(r)
+		if err != nil {
+			return err
+		}
+		if names[strings.ToLower(r.Name)] {
+			return badRequest("InvalidRequestFormat", "Security group %s has two rules named %s.", sgName, r.Name)
+		}
+		names[strings.ToLower(r.Name)] = true
+		key := r.Properties.Direction + strconv.Itoa(r.Properties.Priority)
+		if other, ok := priorities[key]; ok {
+			return badRequest("SecurityRuleConflict", "Security rule %s conflicts with rule %s. Rules cannot have the same Priority and Direction.", r.Name, other)
+		}
+		priorities[key] = r.Name
+		r.ID = resourceID(networkNamespace, "networkSecurityGroups", sgName, "securityRules", r.Name)
+		r.Etag = etag()
+		r.Properties.ProvisioningState = stateSucceeded
+	}
+	return nil
+}
+
+func (c *cloud) securityGroupView(sg *securityGroup) *securityGroup {
+	v := *sg
+	v.Properties.NetworkInterfaces = nil
+	v.Properties.Subnets = nil
+	for _, ni := range c.networkInterfaces {
+		if ni.Properties.NetworkSecurityGroup != nil && strings.EqualFold(ni.Properties.NetworkSecurityGroup.ID, sg.ID) {
+			v.Properties.NetworkInterfaces = append(v.Properties.NetworkInterfaces, reference{ID: ni.ID})
+		}
+	}
+	for _, n := range c.virtualNetworks {
+		for _, sn := range n.Properties.Subnets {
+			if sn.Properties.NetworkSecurityGroup != nil && strings.EqualFold(sn.Properties.NetworkSecurityGroup.ID, sg.ID) {
+				v.Properties.Subnets = append(v.Properties.Subnets, reference{ID: sn.ID})
+			}
+		}
+	}
+	return &v
+}
+
+func listSecurityGroups(c *cloud, r *request) (interface{}, error) {
+	l := []*securityGroup{}
+	for _, sg := range c.securityGroups {
+		l = append(l, c.securityGroupView(sg))
+	}
+	return map[string]interface{}{"value": l}, nil
+}
+
+func getSecurityGroup(c *cloud, r *request) (interface{}, error) {
+	sg, err := c.securityGroup(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	return c.securityGroupView(sg), nil
+}
+
+func putSecurityGroup(c *cloud, r *request) (interface{}, error) {
+	in := &securityGroup{}
+	err := r.decode(in)
+	if err != nil {
+		return nil, err
+	}
+	sg, err := c.securityGroup(r.params[0])
+	created := err != nil
+	if created {
+		err = checkLocation(in.Location)
+		if err != nil {
+			return nil, err
+		}
+		sg = &securityGroup{
+			resource: resource{
+				ID:       resourceID(networkNamespace, "networkSecurityGroups", r.params[0]),
+				Name:     r.params[0],
+				Type:     "Microsoft.Network/networkSecurityGroups",
+				Location: strings.ToLower(in.Location),
+			},
+		}
+		sg.Properties.ResourceGUID = newID()
+		sg.Properties.DefaultSecurityRules = defaultSecurityRules(sg.Name)
+	}
+	rules := in.Properties.SecurityRules
+	if rules == nil {
+		rules = []*securityRule{}
+	}
+	err = checkSecurityRules(sg.Name, rules)
+	if err != nil {
+		return nil, err
+	}
+	sg.Properties.SecurityRules = rules
+	if in.Tags != nil || created {
+		sg.Tags = in.Tags
+	}
+	sg.Etag = etag()
+	sg.Properties.ProvisioningState = stateUpdating
+	if created {
+		c.securityGroups = append(c.securityGroups, sg)
+	}
+	return putResponse(networkNamespace, created, c.securityGroupView(sg), func() {
+		sg.Properties.ProvisioningState = stateSucceeded
+	}), nil
+}
+
+func deleteSecurityGroup(c *cloud, r *request) (interface{}, error) {
+	sg, err := c.securityGroup(r.params[0])
+	if err != nil {
+		return nil, nil
+	}
+	v := c.securityGroupView(sg)
+	if len(v.Properties.NetworkInterfaces) > 0 || len(v.Properties.Subnets) > 0 {
+		return nil, badRequest("InUseNetworkSecurityGroupCannotBeDeleted", "Network security group %s cannot be deleted because it is in use.", sg.ID)
+	}
+	sg.Properties.ProvisioningState = stateDeleting
+	return accepted(networkNamespace, func() {
+		for i, o := range c.securityGroups {
+			if o == sg {
+				c.securityGroups = append(c.securityGroups[:i], c.securityGroups[i+1:]...)
+				break
+			}
+		}
+	}), nil
+}
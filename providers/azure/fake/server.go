@@ -0,0 +1,344 @@
+//Package fake implements an in process fake of the Azure Resource Manager APIs used by the azure provider
+//It serves the location, compute, network and commerce operations of the provider along with an Azure Active Directory token endpoint
+//and the blob service of a storage account so that the azure provider can be tested without an Azure subscription
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+const (
+	//TenantID Azure Active Directory tenant of the service principal
+	TenantID = "5f0d2c3e-7a41-4b8e-9c6d-2e1f3a4b5c6d"
+	//ClientID identifier of the service principal allowed to authenticate
+	ClientID = "9b7e4a21-3c5d-4f6e-8a9b-0c1d2e3f4a5b"
+	//ClientSecret secret of the service principal allowed to authenticate
+	ClientSecret = "secret"
+	//SubscriptionID subscription owning the resources
+	SubscriptionID = "1c2d3e4f-5a6b-4c7d-8e9f-0a1b2c3d4e5f"
+	//ResourceGroupName resource group in which resources are created
+	ResourceGroupName = "anyclouds"
+	//Location location simulated by the fake
+	Location = "eastus"
+	//OfferNumber offer for which the RateCard returns prices
+	OfferNumber = "MS-AZR-0003P"
+	//Currency currency of the prices returned by the RateCard
+	Currency = "USD"
+	//RegionInfo region of the offer returned by the RateCard
+	RegionInfo = "US"
+	//UserName default user name of the virtual machines
+	UserName = "ubuntu"
+	//StorageAccountName storage account served by the blob service
+	StorageAccountName = "anyclouds"
+	//StorageAccountKey base64 encoded key of the storage account
+	StorageAccountKey = "YW55Y2xvdWRzIGZha2Ugc3RvcmFnZSBhY2NvdW50IGtleSEh"
+)
+
+//Server fake Azure Resource Manager exposing the compute, network and commerce resource providers
+//The blob service of the storage account is served under the /blob path
+//Long running operations complete at the second poll of their Azure-AsyncOperation URL
+type Server struct {
+	//URL base URL of the server, used both as resource manager and active directory endpoint
+	URL string
+
+	server *httptest.Server
+	lock   sync.Mutex
+	routes []route
+	cloud  *cloud
+}
+
+//NewServer starts a fake Azure Resource Manager
+func NewServer() *Server {
+	s := &Server{}
+	s.server = httptest.NewServer(s)
+	s.URL = s.server.URL
+	s.cloud = newCloud(s.URL)
+	s.routes = append(s.routes, route{"GET", "providers/*/locations/*/operations/*", http.StatusOK, getOperation})
+	s.routes = append(s.routes, commerceRoutes()...)
+	s.routes = append(s.routes, computeRoutes()...)
+	s.routes = append(s.routes, networkRoutes()...)
+	s.routes = append(s.routes, locationRoutes()...)
+	s.routes = append(s.routes, usageRoutes()...)
+	return s
+}
+
+//Close shuts down the server
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+//Config returns a JSON configuration of the azure provider targeting the server
+func (s *Server) Config() string {
+	cfg, _ := json.Marshal(map[string]interface{}{
+		"TenantID":                      TenantID,
+		"ClientID":                      ClientID,
+		"ClientSecret":                  ClientSecret,
+		"ActiveDirectoryEndpoint":       s.URL + "/",
+		"ResourceManagerEndpoint":       s.URL + "/",
+		"SubscriptionID":                SubscriptionID,
+		"UserAgent":                     "anyclouds",
+		"Location":                      Location,
+		"VirtualMachineImagePublishers": []string{"Canonical", "OpenLogic"},
+		"DefaultVMUserName":             UserName,
+		"ResourceGroupName":             ResourceGroupName,
+		"OfferNumber":                   OfferNumber,
+		"Currency":                      Currency,
+		"RegionInfo":                    RegionInfo,
+		"StorageAccountName":            StorageAccountName,
+		"StorageAccountKey":             StorageAccountKey,
+		"StorageEndpoint":               s.URL + blobServicePrefix,
+	})
+	return string(cfg)
+}
+
+//ServeHTTP serves the token endpoint, the blob service and the resource manager operations
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if strings.HasPrefix(r.URL.Path, blobServicePrefix+"/") {
+		s.serveBlob(w, r)
+		return
+	}
+	segments := splitPath(r.URL.EscapedPath())
+	if len(segments) == 3 && segments[1] == "oauth2" && segments[2] == "token" {
+		issueToken(s.cloud, w, r, segments[0])
+		return
+	}
+	if len(segments) < 2 || !strings.EqualFold(segments[0], "subscriptions") {
+		writeError(w, errorf(http.StatusNotFound, "InvalidResourceType", "The resource type could not be found in the namespace."))
+		return
+	}
+	s.serve(w, r, segments[1], segments[2:])
+}
+
+//splitPath returns the unescaped segments of path
+func splitPath(path string) []string {
+	var segments []string
+	for _, s := range strings.Split(strings.Trim(path, "/"), "/") {
+		if s == "" {
+			continue
+		}
+		u, err := url.PathUnescape(s)
+		if err != nil {
+			u = s
+		}
+		segments = append(segments, u)
+	}
+	return segments
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request, subscription string, segments []string) {
+	if !s.cloud.authorized(r) {
+		writeError(w, errorf(http.StatusUnauthorized, "AuthenticationFailed", "Authentication failed. The 'Authorization' header is missing or invalid."))
+		return
+	}
+	if subscription != SubscriptionID {
+		writeError(w, errorf(http.StatusNotFound, "SubscriptionNotFound", "The subscription '%s' could not be found.", subscription))
+		return
+	}
+	if r.URL.Query().Get("api-version") == "" {
+		writeError(w, badRequest("MissingApiVersionParameter", "The api-version query parameter (?api-version=) is required for all requests."))
+		return
+	}
+	var allowed bool
+	for i := range s.routes {
+		rt := &s.routes[i]
+		params, ok := rt.match(segments)
+		if !ok {
+			continue
+		}
+		if rt.method != r.Method {
+			allowed = true
+			continue
+		}
+		if strings.HasPrefix(rt.path, "resourceGroups/*/") {
+			if !strings.EqualFold(params[0], ResourceGroupName) {
+				writeError(w, errorf(http.StatusNotFound, "ResourceGroupNotFound", "Resource group '%s' could not be found.", params[0]))
+				return
+			}
+			params = params[1:]
+		}
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			writeError(w, badRequest("InvalidRequestContent", "%s", err.Error()))
+			return
+		}
+		res, err := rt.handler(s.cloud, &request{Request: r, params: params, body: body})
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		s.writeResponse(w, r, rt.status, res)
+		return
+	}
+	if allowed {
+		writeError(w, errorf(http.StatusMethodNotAllowed, "MethodNotAllowed", "The requested resource does not support http method '%s'.", r.Method))
+		return
+	}
+	writeError(w, errorf(http.StatusNotFound, "InvalidResourceType", "The resource type could not be found in the namespace for api version '%s'.", r.URL.Query().Get("api-version")))
+}
+
+func (s *Server) writeResponse(w http.ResponseWriter, r *http.Request, status int, res interface{}) {
+	w.Header().Set("x-ms-request-id", uuid.New().String())
+	//polling clients use Retry-After of the latest response as delay
+	w.Header().Set("Retry-After", "0")
+	if a, ok := res.(*asyncResponse); ok {
+		op := s.cloud.startOperation(a.done)
+		w.Header().Set("Azure-AsyncOperation", fmt.Sprintf("%s/subscriptions/%s/providers/%s/locations/%s/operations/%s?api-version=%s",
+			s.URL, SubscriptionID, a.namespace, Location, op.ID, r.URL.Query().Get("api-version")))
+		status = a.status
+		res = a.body
+	}
+	if res == nil {
+		w.WriteHeader(status)
+		return
+	}
+	writeJSON(w, status, res)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_, _ = w.Write(b)
+}
+
+//apiError error returned by the fake resource providers
+type apiError struct {
+	status  int
+	code    string
+	message string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("%s: %s", e.code, e.message)
+}
+
+func errorf(status int, code string, format string, args ...interface{}) error {
+	return &apiError{
+		status:  status,
+		code:    code,
+		message: fmt.Sprintf(format, args...),
+	}
+}
+
+func badRequest(code string, format string, args ...interface{}) error {
+	return errorf(http.StatusBadRequest, code, format, args...)
+}
+
+//notFound returns the error of the resource manager for a missing resource of type kind
+func notFound(kind, name string) error {
+	return errorf(http.StatusNotFound, "ResourceNotFound", "The Resource '%s/%s' under resource group '%s' was not found.", kind, name, ResourceGroupName)
+}
+
+func toAPIError(err error) *apiError {
+	if e, ok := err.(*apiError); ok {
+		return e
+	}
+	return &apiError{
+		status:  http.StatusInternalServerError,
+		code:    "InternalServerError",
+		message: err.Error(),
+	}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	e := toAPIError(err)
+	writeJSON(w, e.status, map[string]interface{}{
+		"error": map[string]string{
+			"code":    e.code,
+			"message": e.message,
+		},
+	})
+}
+
+//request request received by a route handler
+type request struct {
+	*http.Request
+	//params values of the wildcard segments of the route path
+	params []string
+	body   []byte
+}
+
+//decode decodes the JSON body of the request into v
+func (r *request) decode(v interface{}) error {
+	err := json.Unmarshal(r.body, v)
+	if err != nil {
+		return badRequest("InvalidRequestContent", "The request content was invalid and could not be deserialized: '%s'.", err.Error())
+	}
+	return nil
+}
+
+//handler handles a request and returns the response body, a nil body produces an empty response
+type handler func(c *cloud, r *request) (interface{}, error)
+
+//route associates a method and a path relative to the subscription to a handler
+//"*" path segments match any value, literal segments are case insensitive
+type route struct {
+	method  string
+	path    string
+	status  int
+	handler handler
+}
+
+//match returns the values of the wildcard segments if the route path matches segments
+func (rt *route) match(segments []string) ([]string, bool) {
+	path := strings.Split(rt.path, "/")
+	if len(path) != len(segments) {
+		return nil, false
+	}
+	var params []string
+	for i, p := range path {
+		if p == "*" {
+			params = append(params, segments[i])
+		} else if !strings.EqualFold(p, segments[i]) {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+//asyncResponse response of a request starting a long running operation
+//done is called when the operation completes
+type asyncResponse struct {
+	namespace string
+	status    int
+	body      interface{}
+	done      func()
+}
+
+//accepted returns the response of a DELETE or POST request starting a long running operation
+func accepted(namespace string, done func()) *asyncResponse {
+	return &asyncResponse{
+		namespace: namespace,
+		status:    http.StatusAccepted,
+		done:      done,
+	}
+}
+
+//putResponse returns the response of a PUT request, created is true if the request created the resource
+func putResponse(namespace string, created bool, body interface{}, done func()) *asyncResponse {
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	return &asyncResponse{
+		namespace: namespace,
+		status:    status,
+		body:      body,
+		done:      done,
+	}
+}
@@ -0,0 +1,125 @@
+package fake
+
+import (
+	"net/http"
+	"strings"
+)
+
+type snapshotProperties struct {
+	CreationData struct {
+		CreateOption     string `json:"createOption"`
+		SourceResourceID string `json:"sourceResourceId,omitempty"`
+	} `json:"creationData"`
+	DiskSizeGB        int    `json:"diskSizeGB,omitempty"`
+	UniqueID          string `json:"uniqueId,omitempty"`
+	TimeCreated       string `json:"timeCreated,omitempty"`
+	ProvisioningState string `json:"provisioningState"`
+}
+
+type snapshot struct {
+	resource
+	Sku        *diskSku           `json:"sku,omitempty"`
+	Properties snapshotProperties `json:"properties"`
+}
+
+func (c *cloud) snapshot(name string) (*snapshot, error) {
+	for _, s := range c.snapshots {
+		if strings.EqualFold(s.Name, name) {
+			return s, nil
+		}
+	}
+	return nil, notFound("Microsoft.Compute/snapshots", name)
+}
+
+//snapshotByID returns the snapshot identified by id
+func (c *cloud) snapshotByID(id string) (*snapshot, error) {
+	names, ok := parseID(id, computeNamespace, "snapshots")
+	if ok && len(names) == 1 {
+		s, err := c.snapshot(names[0])
+		if err == nil {
+			return s, nil
+		}
+	}
+	return nil, errorf(http.StatusNotFound, "NotFound", "Snapshot %s was not found.", id)
+}
+
+func listSnapshots(c *cloud, r *request) (interface{}, error) {
+	return map[string]interface{}{"value": c.snapshots}, nil
+}
+
+func getSnapshot(c *cloud, r *request) (interface{}, error) {
+	return c.snapshot(r.params[0])
+}
+
+//putSnapshot creates a full copy of a disk, snapshots cannot be updated except for their tags
+func putSnapshot(c *cloud, r *request) (interface{}, error) {
+	in := &snapshot{}
+	err := r.decode(in)
+	if err != nil {
+		return nil, err
+	}
+	if !diskName.MatchString(r.params[0]) {
+		return nil, badRequest("InvalidParameter", "The entity name '%s' is invalid according to its validation rule.", r.params[0])
+	}
+	s, err := c.snapshot(r.params[0])
+	created := err != nil
+	if created {
+		err = checkLocation(in.Location)
+		if err != nil {
+			return nil, err
+		}
+		if option := in.Properties.CreationData.CreateOption; !strings.EqualFold(option, "Copy") {
+			return nil, badRequest("InvalidParameter", "The value '%s' of parameter 'creationData.createOption' is not supported.", option)
+		}
+		d, err := c.diskByID(in.Properties.CreationData.SourceResourceID)
+		if err != nil {
+			return nil, err
+		}
+		sku := "Standard_LRS"
+		if in.Sku != nil && in.Sku.Name != "" {
+			sku = oneOf(in.Sku.Name, "Standard_LRS", "Premium_LRS", "Standard_ZRS")
+			if sku == "" {
+				return nil, badRequest("InvalidParameter", "The value '%s' of parameter 'sku.name' is not valid.", in.Sku.Name)
+			}
+		}
+		s = &snapshot{
+			resource: resource{
+				ID:       resourceID(computeNamespace, "snapshots", r.params[0]),
+				Name:     r.params[0],
+				Type:     "Microsoft.Compute/snapshots",
+				Location: Location,
+				Tags:     map[string]string{},
+			},
+			Sku: &diskSku{Name: sku, Tier: strings.Split(sku, "_")[0]},
+		}
+		s.Properties.CreationData.CreateOption = "Copy"
+		s.Properties.CreationData.SourceResourceID = d.ID
+		s.Properties.DiskSizeGB = d.Properties.DiskSizeGB
+		s.Properties.UniqueID = newID()
+		s.Properties.TimeCreated = timestamp()
+		c.snapshots = append(c.snapshots, s)
+	}
+	if in.Tags != nil {
+		s.Tags = in.Tags
+	}
+	s.Properties.ProvisioningState = stateUpdating
+	return putResponse(computeNamespace, created, s, func() {
+		s.Properties.ProvisioningState = stateSucceeded
+	}), nil
+}
+
+func deleteSnapshot(c *cloud, r *request) (interface{}, error) {
+	s, err := c.snapshot(r.params[0])
+	if err != nil {
+		return nil, nil
+	}
+	s.Properties.ProvisioningState = stateDeleting
+	return accepted(computeNamespace, func() {
+		for i, o := range c.snapshots {
+			if o == s {
+				c.snapshots = append(c.snapshots[:i], c.snapshots[i+1:]...)
+				break
+			}
+		}
+	}), nil
+}
@@ -0,0 +1,109 @@
+package fake
+
+import (
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+//sshPublicKeysAPIVersion first api version exposing SSH public key resources
+const sshPublicKeysAPIVersion = "2019-12-01"
+
+type sshPublicKeyResource struct {
+	resource
+	Properties struct {
+		PublicKey string `json:"publicKey,omitempty"`
+	} `json:"properties"`
+}
+
+//checkSSHPublicKeysAPIVersion refuses requests using an api version older than SSH public key resources
+func checkSSHPublicKeysAPIVersion(r *request) error {
+	v := r.URL.Query().Get("api-version")
+	if v < sshPublicKeysAPIVersion {
+		return badRequest("NoRegisteredProviderFound", "No registered resource provider found for location '%s' and API version '%s' for type 'sshPublicKeys'.", Location, v)
+	}
+	return nil
+}
+
+func (c *cloud) sshPublicKeyResource(name string) (*sshPublicKeyResource, error) {
+	for _, k := range c.sshPublicKeys {
+		if strings.EqualFold(k.Name, name) {
+			return k, nil
+		}
+	}
+	return nil, notFound("Microsoft.Compute/sshPublicKeys", name)
+}
+
+func listSSHPublicKeys(c *cloud, r *request) (interface{}, error) {
+	err := checkSSHPublicKeysAPIVersion(r)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"value": c.sshPublicKeys}, nil
+}
+
+func getSSHPublicKey(c *cloud, r *request) (interface{}, error) {
+	err := checkSSHPublicKeysAPIVersion(r)
+	if err != nil {
+		return nil, err
+	}
+	return c.sshPublicKeyResource(r.params[0])
+}
+
+//putSSHPublicKey creates or updates an SSH public key, the operation is synchronous
+func putSSHPublicKey(c *cloud, r *request) (interface{}, error) {
+	err := checkSSHPublicKeysAPIVersion(r)
+	if err != nil {
+		return nil, err
+	}
+	in := &sshPublicKeyResource{}
+	err = r.decode(in)
+	if err != nil {
+		return nil, err
+	}
+	if !diskName.MatchString(r.params[0]) {
+		return nil, badRequest("InvalidParameter", "The entity name '%s' is invalid according to its validation rule.", r.params[0])
+	}
+	if in.Properties.PublicKey != "" {
+		if _, _, _, _, err := ssh.ParseAuthorizedKey([]byte(in.Properties.PublicKey)); err != nil {
+			return nil, badRequest("InvalidParameter", "The value of parameter publicKey is invalid.")
+		}
+	}
+	key, err := c.sshPublicKeyResource(r.params[0])
+	if err != nil {
+		err = checkLocation(in.Location)
+		if err != nil {
+			return nil, err
+		}
+		key = &sshPublicKeyResource{
+			resource: resource{
+				ID:       resourceID(computeNamespace, "sshPublicKeys", r.params[0]),
+				Name:     r.params[0],
+				Type:     "Microsoft.Compute/sshPublicKeys",
+				Location: Location,
+			},
+		}
+		c.sshPublicKeys = append(c.sshPublicKeys, key)
+	}
+	if in.Properties.PublicKey != "" {
+		key.Properties.PublicKey = in.Properties.PublicKey
+	}
+	if in.Tags != nil {
+		key.Tags = in.Tags
+	}
+	return key, nil
+}
+
+func deleteSSHPublicKey(c *cloud, r *request) (interface{}, error) {
+	err := checkSSHPublicKeysAPIVersion(r)
+	if err != nil {
+		return nil, err
+	}
+	for i, k := range c.sshPublicKeys {
+		if strings.EqualFold(k.Name, r.params[0]) {
+			c.sshPublicKeys = append(c.sshPublicKeys[:i], c.sshPublicKeys[i+1:]...)
+			break
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,95 @@
+package fake
+
+import (
+	"fmt"
+	"net/http"
+)
+
+func usageRoutes() []route {
+	return []route{
+		{"GET", "providers/Microsoft.Compute/locations/*/usages", http.StatusOK, listComputeUsages},
+		{"GET", "providers/Microsoft.Network/locations/*/usages", http.StatusOK, listNetworkUsages},
+	}
+}
+
+//usageLimits limits of the subscription in each location indexed by usage name
+var usageLimits = map[string]int{
+	"cores":                   100,
+	"virtualMachines":         25000,
+	"virtualMachineScaleSets": 2500,
+	"StandardDiskCount":       50000,
+	"StandardSSDDiskCount":    50000,
+	"PremiumDiskCount":        50000,
+	"UltraSSDDiskCount":       1000,
+	"PublicIPAddresses":       1000,
+	"NetworkSecurityGroups":   5000,
+	"VirtualNetworks":         1000,
+}
+
+//usage returns the representation of the usage named name
+func usage(name, localizedName string, current int) map[string]interface{} {
+	return map[string]interface{}{
+		"unit":         "Count",
+		"currentValue": current,
+		"limit":        usageLimits[name],
+		"name": map[string]string{
+			"value":          name,
+			"localizedValue": localizedName,
+		},
+	}
+}
+
+//listComputeUsages returns the usage of the virtual machines, of their cores and of the disks, all the resources are in Location
+func listComputeUsages(c *cloud, r *request) (interface{}, error) {
+	if !knownLocation(r.params[0]) {
+		return nil, errorf(http.StatusNotFound, "NoRegisteredProviderFound", "No registered resource provider found for location '%s'.", r.params[0])
+	}
+	used := map[string]int{}
+	if r.params[0] == Location {
+		for _, vm := range c.virtualMachines {
+			used["virtualMachines"]++
+			if size, ok := c.size(vm.Properties.HardwareProfile.VMSize); ok {
+				used["cores"] += size.NumberOfCores
+			}
+		}
+		for _, ss := range c.scaleSets {
+			used["virtualMachineScaleSets"]++
+			if size, ok := c.size(ss.Sku.Name); ok && ss.Sku.Capacity != nil {
+				used["cores"] += size.NumberOfCores * *ss.Sku.Capacity
+			}
+		}
+		for _, d := range c.disks {
+			used[d.Sku.Tier+"DiskCount"]++
+		}
+	}
+	l := []interface{}{
+		usage("cores", "Total Regional vCPUs", used["cores"]),
+		usage("virtualMachines", "Virtual Machines", used["virtualMachines"]),
+		usage("virtualMachineScaleSets", "Virtual Machine Scale Sets", used["virtualMachineScaleSets"]),
+	}
+	for _, tier := range []string{"Standard", "StandardSSD", "Premium", "UltraSSD"} {
+		l = append(l, usage(tier+"DiskCount", fmt.Sprintf("%s Disks", tier), used[tier+"DiskCount"]))
+	}
+	return map[string]interface{}{"value": l}, nil
+}
+
+//listNetworkUsages returns the usage of the public ip addresses, of the network security groups and of the virtual networks, all the resources are in Location
+func listNetworkUsages(c *cloud, r *request) (interface{}, error) {
+	if !knownLocation(r.params[0]) {
+		return nil, errorf(http.StatusNotFound, "NoRegisteredProviderFound", "No registered resource provider found for location '%s'.", r.params[0])
+	}
+	ips, groups, networks := 0, 0, 0
+	if r.params[0] == Location {
+		ips, groups, networks = len(c.publicIPAddresses), len(c.securityGroups), len(c.virtualNetworks)
+	}
+	l := []interface{}{
+		usage("PublicIPAddresses", "Public IP Addresses", ips),
+		usage("NetworkSecurityGroups", "Network Security Groups", groups),
+		usage("VirtualNetworks", "Virtual Networks", networks),
+	}
+	for _, u := range l {
+		u.(map[string]interface{})["id"] = fmt.Sprintf("/subscriptions/%s/providers/Microsoft.Network/locations/%s/usages/%s",
+			SubscriptionID, r.params[0], u.(map[string]interface{})["name"].(map[string]string)["value"])
+	}
+	return map[string]interface{}{"value": l}, nil
+}
@@ -0,0 +1,496 @@
+package fake
+
+import (
+	"net/http"
+	"strings"
+)
+
+const (
+	powerStarting    = "starting"
+	powerRunning     = "running"
+	powerStopped     = "stopped"
+	powerDeallocated = "deallocated"
+)
+
+type imageReference struct {
+	Publisher string `json:"publisher,omitempty"`
+	Offer     string `json:"offer,omitempty"`
+	Sku       string `json:"sku,omitempty"`
+	Version   string `json:"version,omitempty"`
+	ID        string `json:"id,omitempty"`
+}
+
+type managedDiskParameters struct {
+	ID                 string `json:"id,omitempty"`
+	StorageAccountType string `json:"storageAccountType,omitempty"`
+}
+
+type osDisk struct {
+	OsType       string                 `json:"osType,omitempty"`
+	Name         string                 `json:"name,omitempty"`
+	CreateOption string                 `json:"createOption,omitempty"`
+	Caching      string                 `json:"caching,omitempty"`
+	DiskSizeGB   int                    `json:"diskSizeGB,omitempty"`
+	ManagedDisk  *managedDiskParameters `json:"managedDisk,omitempty"`
+}
+
+type dataDisk struct {
+	Lun          int                    `json:"lun"`
+	Name         string                 `json:"name,omitempty"`
+	CreateOption string                 `json:"createOption"`
+	Caching      string                 `json:"caching,omitempty"`
+	DiskSizeGB   int                    `json:"diskSizeGB,omitempty"`
+	ManagedDisk  *managedDiskParameters `json:"managedDisk,omitempty"`
+}
+
+type storageProfile struct {
+	ImageReference *imageReference `json:"imageReference,omitempty"`
+	OsDisk         *osDisk         `json:"osDisk,omitempty"`
+	DataDisks      []*dataDisk     `json:"dataDisks"`
+}
+
+type sshPublicKey struct {
+	Path    string `json:"path"`
+	KeyData string `json:"keyData"`
+}
+
+type osProfile struct {
+	ComputerName       string `json:"computerName"`
+	AdminUsername      string `json:"adminUsername"`
+	AdminPassword      string `json:"adminPassword,omitempty"`
+	LinuxConfiguration *struct {
+		DisablePasswordAuthentication bool `json:"disablePasswordAuthentication"`
+		SSH                           *struct {
+			PublicKeys []sshPublicKey `json:"publicKeys"`
+		} `json:"ssh,omitempty"`
+	} `json:"linuxConfiguration,omitempty"`
+	Secrets []interface{} `json:"secrets"`
+}
+
+type networkInterfaceReference struct {
+	ID         string `json:"id"`
+	Properties *struct {
+		Primary bool `json:"primary"`
+	} `json:"properties,omitempty"`
+}
+
+func (ref *networkInterfaceReference) primary() bool {
+	return ref.Properties != nil && ref.Properties.Primary
+}
+
+type instanceViewStatus struct {
+	Code          string `json:"code"`
+	Level         string `json:"level"`
+	DisplayStatus string `json:"displayStatus"`
+}
+
+type virtualMachineProperties struct {
+	VMID            string `json:"vmId"`
+	HardwareProfile struct {
+		VMSize string `json:"vmSize"`
+	} `json:"hardwareProfile"`
+	StorageProfile storageProfile `json:"storageProfile"`
+	OsProfile      *osProfile     `json:"osProfile,omitempty"`
+	NetworkProfile struct {
+		NetworkInterfaces []networkInterfaceReference `json:"networkInterfaces"`
+	} `json:"networkProfile"`
+	Priority       string `json:"priority,omitempty"`
+	EvictionPolicy string `json:"evictionPolicy,omitempty"`
+	BillingProfile *struct {
+		MaxPrice float64 `json:"maxPrice"`
+	} `json:"billingProfile,omitempty"`
+	ProvisioningState string `json:"provisioningState"`
+	InstanceView      *struct {
+		Statuses []instanceViewStatus `json:"statuses"`
+	} `json:"instanceView,omitempty"`
+}
+
+type virtualMachine struct {
+	resource
+	Properties virtualMachineProperties `json:"properties"`
+
+	powerState string
+	//generalized is true once the virtual machine is prepared to be captured as an image
+	generalized bool
+}
+
+func (c *cloud) virtualMachine(name string) (*virtualMachine, error) {
+	for _, vm := range c.virtualMachines {
+		if strings.EqualFold(vm.Name, name) {
+			return vm, nil
+		}
+	}
+	return nil, notFound("Microsoft.Compute/virtualMachines", name)
+}
+
+//view returns the representation of the virtual machine, with its instance view if expand is true
+func (vm *virtualMachine) view(expand bool) *virtualMachine {
+	v := *vm
+	v.Properties.InstanceView = nil
+	if !expand {
+		return &v
+	}
+	v.Properties.InstanceView = &struct {
+		Statuses []instanceViewStatus `json:"statuses"`
+	}{
+		Statuses: []instanceViewStatus{
+			{
+				Code:          "ProvisioningState/" + strings.ToLower(vm.Properties.ProvisioningState),
+				Level:         "Info",
+				DisplayStatus: "Provisioning " + strings.ToLower(vm.Properties.ProvisioningState),
+			},
+			{
+				Code:          "PowerState/" + vm.powerState,
+				Level:         "Info",
+				DisplayStatus: "VM " + vm.powerState,
+			},
+		},
+	}
+	return &v
+}
+
+func listVirtualMachines(c *cloud, r *request) (interface{}, error) {
+	l := []*virtualMachine{}
+	for _, vm := range c.virtualMachines {
+		l = append(l, vm.view(false))
+	}
+	return map[string]interface{}{"value": l}, nil
+}
+
+func getVirtualMachine(c *cloud, r *request) (interface{}, error) {
+	vm, err := c.virtualMachine(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	return vm.view(strings.EqualFold(r.URL.Query().Get("$expand"), "instanceView")), nil
+}
+
+//vmNetworkInterfaces returns the network interfaces referenced by in, they must not be used by another virtual machine than vm
+func (c *cloud) vmNetworkInterfaces(in *virtualMachine, vm *virtualMachine) ([]*networkInterface, error) {
+	refs := in.Properties.NetworkProfile.NetworkInterfaces
+	if len(refs) == 0 {
+		return nil, badRequest("VirtualMachineMustHaveOneNetworkInterfaceAsPrimary", "Virtual machine %s must have one network interface set as the primary.", in.Name)
+	}
+	var nis []*networkInterface
+	primaries := 0
+	for _, ref := range refs {
+		names, ok := parseID(ref.ID, networkNamespace, "networkInterfaces")
+		if !ok || len(names) != 1 {
+			return nil, badRequest("InvalidResourceReference", "Resource %s referenced by resource %s was not found.", ref.ID, in.Name)
+		}
+		ni, err := c.networkInterface(names[0])
+		if err != nil {
+			return nil, badRequest("InvalidResourceReference", "Resource %s referenced by resource %s was not found.", ref.ID, in.Name)
+		}
+		if used := ni.Properties.VirtualMachine; used != nil && (vm == nil || !strings.EqualFold(used.ID, vm.ID)) {
+			return nil, errorf(http.StatusBadRequest, "NicInUse", "Network Interface %s is used by existing resource %s.", ni.ID, used.ID)
+		}
+		if ref.primary() {
+			primaries++
+		}
+		nis = append(nis, ni)
+	}
+	if len(refs) > 1 && primaries != 1 {
+		return nil, badRequest("VirtualMachineMustHaveOneNetworkInterfaceAsPrimary", "Virtual machine %s must have one network interface set as the primary.", in.Name)
+	}
+	return nis, nil
+}
+
+//attach attaches the network interfaces nis to vm and detaches the ones no longer referenced
+func (c *cloud) attach(vm *virtualMachine, nis []*networkInterface) {
+	for _, ni := range c.networkInterfaces {
+		if ni.Properties.VirtualMachine != nil && strings.EqualFold(ni.Properties.VirtualMachine.ID, vm.ID) {
+			ni.Properties.VirtualMachine = nil
+		}
+	}
+	for _, ni := range nis {
+		ni.Properties.VirtualMachine = &reference{ID: vm.ID}
+		if ni.Properties.MacAddress == "" {
+			ni.Properties.MacAddress = macAddress()
+		}
+	}
+}
+
+//vmDataDisks returns the disks referenced by the data disks of in, they must not be attached to another virtual machine than vm
+func (c *cloud) vmDataDisks(in *virtualMachine, vm *virtualMachine) ([]*disk, error) {
+	size, _ := c.size(in.Properties.HardwareProfile.VMSize)
+	if len(in.Properties.StorageProfile.DataDisks) > size.MaxDataDiskCount {
+		return nil, errorf(http.StatusConflict, "OperationNotAllowed", "The maximum number of data disks allowed to be attached to a VM of this size is %d.", size.MaxDataDiskCount)
+	}
+	var disks []*disk
+	luns := map[int]bool{}
+	for _, dd := range in.Properties.StorageProfile.DataDisks {
+		if !strings.EqualFold(dd.CreateOption, "Attach") {
+			return nil, badRequest("InvalidParameter", "The value '%s' of parameter 'createOption' of data disk %d is not supported.", dd.CreateOption, dd.Lun)
+		}
+		if dd.Lun < 0 || dd.Lun > 63 || luns[dd.Lun] {
+			return nil, badRequest("InvalidParameter", "The value %d of parameter 'lun' is invalid or already used.", dd.Lun)
+		}
+		luns[dd.Lun] = true
+		if dd.ManagedDisk == nil || dd.ManagedDisk.ID == "" {
+			return nil, badRequest("InvalidParameter", "Required parameter 'managedDisk.id' of data disk %d is missing.", dd.Lun)
+		}
+		d, err := c.diskByID(dd.ManagedDisk.ID)
+		if err != nil {
+			return nil, err
+		}
+		if d.ManagedBy != "" && (vm == nil || !strings.EqualFold(d.ManagedBy, vm.ID)) || d.Properties.OsType != "" {
+			return nil, errorf(http.StatusConflict, "ConflictingUserInput", "Disk '%s' cannot be attached as the disk is already owned by VM '%s'.", d.ID, d.ManagedBy)
+		}
+		dd.Name = d.Name
+		dd.CreateOption = "Attach"
+		dd.DiskSizeGB = d.Properties.DiskSizeGB
+		dd.ManagedDisk = &managedDiskParameters{ID: d.ID, StorageAccountType: d.Sku.Name}
+		if dd.Caching == "" {
+			dd.Caching = "None"
+		}
+		disks = append(disks, d)
+	}
+	return disks, nil
+}
+
+//attachDisks attaches the data disks disks to vm and detaches the ones no longer referenced
+func (c *cloud) attachDisks(vm *virtualMachine, disks []*disk) {
+	for _, d := range c.disks {
+		if strings.EqualFold(d.ManagedBy, vm.ID) && d.Properties.OsType == "" {
+			d.ManagedBy = ""
+			d.Properties.DiskState = diskUnattached
+		}
+	}
+	for _, d := range disks {
+		d.ManagedBy = vm.ID
+		d.Properties.DiskState = diskAttached
+	}
+}
+
+//createOsDisk creates the managed disk of the operating system of vm
+func (c *cloud) createOsDisk(vm *virtualMachine, in *osDisk) (*osDisk, error) {
+	sku := "Premium_LRS"
+	if in != nil && in.ManagedDisk != nil && in.ManagedDisk.StorageAccountType != "" {
+		if sku = oneOf(in.ManagedDisk.StorageAccountType, "Standard_LRS", "StandardSSD_LRS", "Premium_LRS"); sku == "" {
+			return nil, badRequest("InvalidParameter", "The value '%s' of parameter 'osDisk.managedDisk.storageAccountType' is not valid.", in.ManagedDisk.StorageAccountType)
+		}
+	}
+	d := newDisk(vm.Name+"_OsDisk_1_"+strings.Replace(newID(), "-", "", -1), sku, 30)
+	d.ManagedBy = vm.ID
+	d.Properties.OsType = "Linux"
+	d.Properties.CreationData.CreateOption = "FromImage"
+	d.Properties.DiskState = diskAttached
+	c.disks = append(c.disks, d)
+	return &osDisk{
+		OsType:       "Linux",
+		Name:         d.Name,
+		CreateOption: "FromImage",
+		Caching:      "ReadWrite",
+		DiskSizeGB:   d.Properties.DiskSizeGB,
+		ManagedDisk:  &managedDiskParameters{ID: d.ID, StorageAccountType: sku},
+	}, nil
+}
+
+func checkVirtualMachine(c *cloud, in *virtualMachine) error {
+	if _, ok := c.size(in.Properties.HardwareProfile.VMSize); !ok {
+		return badRequest("InvalidParameter", "The value %s provided for the VM size is not valid.", in.Properties.HardwareProfile.VMSize)
+	}
+	switch in.Properties.Priority {
+	case "", "Regular", "Low":
+	default:
+		return badRequest("InvalidParameter", "The value %s of parameter 'priority' is not valid.", in.Properties.Priority)
+	}
+	return nil
+}
+
+//checkImageReference checks that ref references a managed image or a platform image
+func (c *cloud) checkImageReference(ref *imageReference) error {
+	if ref == nil {
+		return badRequest("InvalidParameter", "Required parameter 'imageReference' is missing (null).")
+	}
+	if ref.ID != "" {
+		_, err := c.managedImageByID(ref.ID)
+		return err
+	}
+	if len(c.findImages(ref.Publisher, ref.Offer, ref.Sku, ref.Version)) == 0 {
+		return errorf(http.StatusNotFound, "PlatformImageNotFound", "The platform image '%s:%s:%s:%s' is not available.", ref.Publisher, ref.Offer, ref.Sku, ref.Version)
+	}
+	return nil
+}
+
+func createVirtualMachine(c *cloud, r *request, in *virtualMachine) (interface{}, error) {
+	err := checkLocation(in.Location)
+	if err != nil {
+		return nil, err
+	}
+	err = checkVirtualMachine(c, in)
+	if err != nil {
+		return nil, err
+	}
+	err = c.checkImageReference(in.Properties.StorageProfile.ImageReference)
+	if err != nil {
+		return nil, err
+	}
+	p := in.Properties.OsProfile
+	if p == nil || p.ComputerName == "" || p.AdminUsername == "" {
+		return nil, badRequest("InvalidParameter", "Required parameter 'osProfile' is missing (null).")
+	}
+	nis, err := c.vmNetworkInterfaces(in, nil)
+	if err != nil {
+		return nil, err
+	}
+	disks, err := c.vmDataDisks(in, nil)
+	if err != nil {
+		return nil, err
+	}
+	vm := &virtualMachine{
+		resource: resource{
+			ID:       resourceID(computeNamespace, "virtualMachines", r.params[0]),
+			Name:     r.params[0],
+			Type:     "Microsoft.Compute/virtualMachines",
+			Location: strings.ToLower(in.Location),
+			Tags:     in.Tags,
+		},
+		Properties: in.Properties,
+		powerState: powerStarting,
+	}
+	vm.Properties.VMID = newID()
+	vm.Properties.OsProfile.AdminPassword = ""
+	vm.Properties.StorageProfile.OsDisk, err = c.createOsDisk(vm, in.Properties.StorageProfile.OsDisk)
+	if err != nil {
+		return nil, err
+	}
+	if vm.Properties.StorageProfile.DataDisks == nil {
+		vm.Properties.StorageProfile.DataDisks = []*dataDisk{}
+	}
+	if vm.Properties.Priority == "" {
+		vm.Properties.Priority = "Regular"
+	}
+	if vm.Properties.Priority == "Low" && vm.Properties.EvictionPolicy == "" {
+		vm.Properties.EvictionPolicy = "Deallocate"
+	}
+	vm.Properties.ProvisioningState = "Creating"
+	c.attach(vm, nis)
+	c.attachDisks(vm, disks)
+	c.virtualMachines = append(c.virtualMachines, vm)
+	return putResponse(computeNamespace, true, vm.view(false), func() {
+		vm.Properties.ProvisioningState = stateSucceeded
+		vm.powerState = powerRunning
+	}), nil
+}
+
+func sameImage(a, b *imageReference) bool {
+	return strings.EqualFold(a.Publisher, b.Publisher) && strings.EqualFold(a.Offer, b.Offer) &&
+		strings.EqualFold(a.Sku, b.Sku) && strings.EqualFold(a.Version, b.Version) && strings.EqualFold(a.ID, b.ID)
+}
+
+func updateVirtualMachine(c *cloud, in *virtualMachine, vm *virtualMachine) (interface{}, error) {
+	err := checkVirtualMachine(c, in)
+	if err != nil {
+		return nil, err
+	}
+	if ref := in.Properties.StorageProfile.ImageReference; ref != nil && !sameImage(ref, vm.Properties.StorageProfile.ImageReference) {
+		return nil, errorf(http.StatusConflict, "PropertyChangeNotAllowed", "Changing property 'imageReference' is not allowed.")
+	}
+	nis, err := c.vmNetworkInterfaces(in, vm)
+	if err != nil {
+		return nil, err
+	}
+	current := map[string]bool{}
+	for _, ref := range vm.Properties.NetworkProfile.NetworkInterfaces {
+		current[strings.ToLower(ref.ID)] = true
+	}
+	changed := len(in.Properties.NetworkProfile.NetworkInterfaces) != len(current)
+	for _, ref := range in.Properties.NetworkProfile.NetworkInterfaces {
+		changed = changed || !current[strings.ToLower(ref.ID)]
+	}
+	if changed && vm.powerState != powerDeallocated {
+		return nil, errorf(http.StatusConflict, "CannotAddOrRemoveNetworkInterfacesFromARunningVirtualMachine",
+			"Secondary network interface(s) can be added or removed only when the virtual machine %s is deallocated.", vm.Name)
+	}
+	//data disks are left unchanged if the request does not define them
+	dataDisks := in.Properties.StorageProfile.DataDisks != nil
+	var disks []*disk
+	if dataDisks {
+		disks, err = c.vmDataDisks(in, vm)
+		if err != nil {
+			return nil, err
+		}
+	}
+	vm.Properties.HardwareProfile = in.Properties.HardwareProfile
+	vm.Properties.NetworkProfile = in.Properties.NetworkProfile
+	if in.Tags != nil {
+		vm.Tags = in.Tags
+	}
+	c.attach(vm, nis)
+	if dataDisks {
+		vm.Properties.StorageProfile.DataDisks = in.Properties.StorageProfile.DataDisks
+		c.attachDisks(vm, disks)
+	}
+	vm.Properties.ProvisioningState = stateUpdating
+	return putResponse(computeNamespace, false, vm.view(false), func() {
+		vm.Properties.ProvisioningState = stateSucceeded
+	}), nil
+}
+
+func putVirtualMachine(c *cloud, r *request) (interface{}, error) {
+	in := &virtualMachine{}
+	err := r.decode(in)
+	if err != nil {
+		return nil, err
+	}
+	vm, err := c.virtualMachine(r.params[0])
+	if err != nil {
+		return createVirtualMachine(c, r, in)
+	}
+	return updateVirtualMachine(c, in, vm)
+}
+
+func deleteVirtualMachine(c *cloud, r *request) (interface{}, error) {
+	vm, err := c.virtualMachine(r.params[0])
+	if err != nil {
+		return nil, nil
+	}
+	vm.Properties.ProvisioningState = stateDeleting
+	return accepted(computeNamespace, func() {
+		c.attach(vm, nil)
+		//the disks of a deleted virtual machine are kept
+		for _, d := range c.disks {
+			if strings.EqualFold(d.ManagedBy, vm.ID) {
+				d.ManagedBy = ""
+				d.Properties.DiskState = diskUnattached
+			}
+		}
+		for i, o := range c.virtualMachines {
+			if o == vm {
+				c.virtualMachines = append(c.virtualMachines[:i], c.virtualMachines[i+1:]...)
+				break
+			}
+		}
+	}), nil
+}
+
+//powerAction returns a handler changing the power state of a virtual machine to state
+//The action is refused if the current power state of the virtual machine is one of refused
+func powerAction(state string, refused ...string) handler {
+	return func(c *cloud, r *request) (interface{}, error) {
+		vm, err := c.virtualMachine(r.params[0])
+		if err != nil {
+			return nil, err
+		}
+		if state == powerRunning && vm.generalized {
+			return nil, errorf(http.StatusConflict, "OperationNotAllowed", "Operation '%s' is not allowed on VM '%s' since the VM is generalized.", r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:], vm.Name)
+		}
+		for _, s := range refused {
+			if vm.powerState == s {
+				return nil, errorf(http.StatusConflict, "OperationNotAllowed", "Operation '%s' is not allowed on VM '%s' since the VM is %s.", r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:], vm.Name, s)
+			}
+		}
+		return accepted(computeNamespace, func() {
+			vm.powerState = state
+		}), nil
+	}
+}
+
+var (
+	startVirtualMachine      = powerAction(powerRunning)
+	powerOffVirtualMachine   = powerAction(powerStopped, powerDeallocated)
+	deallocateVirtualMachine = powerAction(powerDeallocated)
+	restartVirtualMachine    = powerAction(powerRunning, powerStopped, powerDeallocated)
+)
@@ -3,7 +3,11 @@ package azure
 import (
 	"context"
 	"fmt"
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/compute/mgmt/compute"
+	"github.com/Azure/go-autorest/autorest/to"
 	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
 	"strings"
 	"time"
 )
@@ -25,67 +29,258 @@ func parseImageID(id string) (publisher, offer, sku, version string) {
 	return
 }
 
-func (mgr *ImageManager) list() ([]api.Image, error) {
+//isMarketplaceImage returns true if id identifies a marketplace image, other images are managed images of the resource group
+func isMarketplaceImage(id string) bool {
+	return strings.Contains(id, "##")
+}
+
+func (mgr *ImageManager) resourceGroup() string {
+	return mgr.Provider.Configuration.ResourceGroupName
+}
+
+//imageReference returns the reference used to create a virtual machine from the image identified by id
+func (mgr *ImageManager) imageReference(id string) *compute.ImageReference {
+	if !isMarketplaceImage(id) {
+		return &compute.ImageReference{
+			ID: to.StringPtr(fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Compute/images/%s",
+				mgr.Provider.Configuration.SubscriptionID, mgr.resourceGroup(), id)),
+		}
+	}
+	publisher, offer, sku, version := parseImageID(id)
+	return &compute.ImageReference{
+		Publisher: to.StringPtr(publisher),
+		Offer:     to.StringPtr(offer),
+		Sku:       to.StringPtr(sku),
+		Version:   to.StringPtr(version),
+	}
+}
+
+//marketplaceImage returns the marketplace image identified by publisher, offer, sku and version
+//The operating system is parsed from the offer and the sku, the default user is the user created on the virtual machines
+func (mgr *ImageManager) marketplaceImage(publisher, offer, sku, version string) *api.Image {
+	id := createImageID(publisher, offer, sku, version)
+	family, distribution, osVersion := api.ParseOS(offer + " " + sku)
+	return &api.Image{
+		ID:           id,
+		Name:         id,
+		MinDisk:      0,
+		MinRAM:       0,
+		CreatedAt:    time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC),
+		UpdatedAt:    time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC),
+		OSFamily:     family,
+		Distribution: distribution,
+		Version:      osVersion,
+		Arch:         api.ArchAmd64,
+		Owner:        publisher,
+		DefaultUser:  mgr.Provider.Configuration.DefaultVMUserName,
+	}
+}
+
+//managedImage returns the api.Image of img, the distribution and its version are read from the tags set by createFromServer
+func (mgr *ImageManager) managedImage(img *compute.Image) *api.Image {
+	res := &api.Image{
+		ID:           *img.Name,
+		Name:         *img.Name,
+		OSFamily:     api.OSUnknown,
+		Distribution: to.String(img.Tags["distribution"]),
+		Version:      to.String(img.Tags["version"]),
+		Arch:         api.ArchAmd64,
+		Owner:        mgr.Provider.Configuration.SubscriptionID,
+		DefaultUser:  mgr.Provider.Configuration.DefaultVMUserName,
+	}
+	if name, ok := img.Tags["name"]; ok && name != nil {
+		res.Name = *name
+	}
+	if img.ImageProperties != nil && img.StorageProfile != nil && img.StorageProfile.OsDisk != nil {
+		switch img.StorageProfile.OsDisk.OsType {
+		case compute.Linux:
+			res.OSFamily = api.OSLinux
+		case compute.Windows:
+			res.OSFamily = api.OSWindows
+		}
+		if img.StorageProfile.OsDisk.DiskSizeGB != nil {
+			res.MinDisk = int(*img.StorageProfile.OsDisk.DiskSizeGB)
+		}
+	}
+	return res
+}
+
+func (mgr *ImageManager) listManagedImages(ctx context.Context) ([]api.Image, error) {
+	it, err := mgr.Provider.BaseServices.ImagesClient.ListByResourceGroupComplete(ctx, mgr.resourceGroup())
+	if err != nil {
+		return nil, err
+	}
+	var images []api.Image
+	for it.NotDone() {
+		img := it.Value()
+		images = append(images, *mgr.managedImage(&img))
+		err = it.NextWithContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return images, nil
+}
+
+func (mgr *ImageManager) list(ctx context.Context) ([]api.Image, error) {
 	cfg := mgr.Provider.Configuration
 
 	var images []api.Image
 	for _, publisher := range cfg.VirtualMachineImagePublishers {
-		offers, err := mgr.Provider.BaseServices.VirtualMachineImagesClient.ListOffers(context.Background(), cfg.Location, publisher)
+		offers, err := mgr.Provider.BaseServices.VirtualMachineImagesClient.ListOffers(ctx, cfg.Location, publisher)
 		if err != nil {
 			return nil, err
 		}
 		for _, offer := range *offers.Value {
-			skus, err := mgr.Provider.BaseServices.VirtualMachineImagesClient.ListSkus(context.Background(), cfg.Location, publisher, *offer.Name)
+			skus, err := mgr.Provider.BaseServices.VirtualMachineImagesClient.ListSkus(ctx, cfg.Location, publisher, *offer.Name)
 			if err != nil {
 				return nil, err
 			}
 			for _, sku := range *skus.Value {
 				maxResult := int32(100)
-				versions, err := mgr.Provider.BaseServices.VirtualMachineImagesClient.List(context.Background(), cfg.Location, publisher, *offer.Name, *sku.Name, "", &maxResult, "")
+				versions, err := mgr.Provider.BaseServices.VirtualMachineImagesClient.List(ctx, cfg.Location, publisher, *offer.Name, *sku.Name, "", &maxResult, "")
 				if err != nil {
 					return nil, err
 				}
 				for _, version := range *versions.Value {
-					id := createImageID(publisher, *offer.Name, *sku.Name, *version.Name)
-					images = append(images, api.Image{
-						ID:        id,
-						Name:      id,
-						MinDisk:   0,
-						MinRAM:    0,
-						CreatedAt: time.Date(2016, 1, 1, 0, 0, 0, 0, nil),
-						UpdatedAt: time.Date(2016, 1, 1, 0, 0, 0, 0, nil),
-					})
+					images = append(images, *mgr.marketplaceImage(publisher, *offer.Name, *sku.Name, *version.Name))
 				}
 			}
 		}
 	}
-	return images, nil
+	managed, err := mgr.listManagedImages(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return append(images, managed...), nil
+}
 
+//ListWithContext context aware version of List
+func (mgr *ImageManager) ListWithContext(ctx context.Context) ([]api.Image, api.ListImageError) {
+	l, err := mgr.list(ctx)
+	return l, api.NewListImageError(UnwrapAzureError(err))
 }
 
 func (mgr *ImageManager) List() ([]api.Image, api.ListImageError) {
-	l, err := mgr.list()
-	return l, api.NewListImageError(err)
+	return mgr.ListWithContext(context.Background())
 }
-func (mgr *ImageManager) get(id string) (*api.Image, error) {
+
+func (mgr *ImageManager) get(ctx context.Context, id string) (*api.Image, error) {
+	if !isMarketplaceImage(id) {
+		img, err := mgr.Provider.BaseServices.ImagesClient.Get(ctx, mgr.resourceGroup(), id, "")
+		if err != nil {
+			return nil, err
+		}
+		return mgr.managedImage(&img), nil
+	}
 	cfg := mgr.Provider.Configuration
 	publisher, offer, sku, version := parseImageID(id)
-	_, err := mgr.Provider.BaseServices.VirtualMachineImagesClient.Get(context.Background(), cfg.Location, publisher, offer, sku, version)
+	_, err := mgr.Provider.BaseServices.VirtualMachineImagesClient.Get(ctx, cfg.Location, publisher, offer, sku, version)
 	if err != nil {
 		return nil, err
 	}
+	return mgr.marketplaceImage(publisher, offer, sku, version), nil
+}
 
-	return &api.Image{
-		ID:        id,
-		Name:      id,
-		MinDisk:   0,
-		MinRAM:    0,
-		CreatedAt: time.Date(2016, 1, 1, 0, 0, 0, 0, nil),
-		UpdatedAt: time.Date(2016, 1, 1, 0, 0, 0, 0, nil),
-	}, nil
+//GetWithContext context aware version of Get
+func (mgr *ImageManager) GetWithContext(ctx context.Context, id string) (*api.Image, api.GetImageError) {
+	i, err := mgr.get(ctx, id)
+	return i, api.NewGetImageError(UnwrapAzureError(err), id)
 }
 
 func (mgr *ImageManager) Get(id string) (*api.Image, api.GetImageError) {
-	i, err := mgr.get(id)
-	return i, api.NewGetImageError(err, id)
+	return mgr.GetWithContext(context.Background(), id)
+}
+
+//createFromServer generalizes and deallocates the virtual machine before capturing it, the virtual machine cannot be started anymore
+func (mgr *ImageManager) createFromServer(ctx context.Context, serverID string, name string) (*api.Image, error) {
+	vms := &mgr.Provider.BaseServices.VirtualMachinesClient
+	vm, err := vms.Get(ctx, mgr.resourceGroup(), serverID, "")
+	if err != nil {
+		return nil, err
+	}
+	deallocate, err := vms.Deallocate(ctx, mgr.resourceGroup(), serverID)
+	if err != nil {
+		return nil, err
+	}
+	err = deallocate.WaitForCompletionRef(ctx, vms.Client)
+	if err != nil {
+		return nil, err
+	}
+	_, err = vms.Generalize(ctx, mgr.resourceGroup(), serverID)
+	if err != nil {
+		return nil, err
+	}
+	tags := map[string]*string{"name": to.StringPtr(name)}
+	if vm.StorageProfile != nil && vm.StorageProfile.ImageReference != nil {
+		ref := vm.StorageProfile.ImageReference
+		_, distribution, version := api.ParseOS(to.String(ref.Offer) + " " + to.String(ref.Sku))
+		if distribution != "" {
+			tags["distribution"] = to.StringPtr(distribution)
+			tags["version"] = to.StringPtr(version)
+		}
+	}
+	id := uuid.New().String()
+	future, err := mgr.Provider.BaseServices.ImagesClient.CreateOrUpdate(ctx, mgr.resourceGroup(), id, compute.Image{
+		Location: to.StringPtr(mgr.Provider.Configuration.Location),
+		Tags:     tags,
+		ImageProperties: &compute.ImageProperties{
+			SourceVirtualMachine: &compute.SubResource{ID: vm.ID},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = future.WaitForCompletionRef(ctx, mgr.Provider.BaseServices.ImagesClient.Client)
+	if err != nil {
+		return nil, api.NewErrorStackFromError(err, mgr.delete(ctx, id))
+	}
+	return mgr.get(ctx, id)
+}
+
+//CreateFromServerWithContext creates an image from the server identified by serverID, the server is left generalized and deallocated
+func (mgr *ImageManager) CreateFromServerWithContext(ctx context.Context, serverID string, name string) (*api.Image, api.CreateImageError) {
+	img, err := mgr.createFromServer(ctx, serverID, name)
+	return img, api.NewCreateImageError(UnwrapAzureError(err), serverID, name)
+}
+
+//CreateFromServer creates an image from the server identified by serverID, the server is left generalized and deallocated
+func (mgr *ImageManager) CreateFromServer(serverID string, name string) (*api.Image, api.CreateImageError) {
+	return mgr.CreateFromServerWithContext(context.Background(), serverID, name)
+}
+
+func (mgr *ImageManager) delete(ctx context.Context, id string) error {
+	if isMarketplaceImage(id) {
+		return errors.Errorf("marketplace image %s cannot be deleted", id)
+	}
+	future, err := mgr.Provider.BaseServices.ImagesClient.Delete(ctx, mgr.resourceGroup(), id)
+	if err != nil {
+		return err
+	}
+	return future.WaitForCompletionRef(ctx, mgr.Provider.BaseServices.ImagesClient.Client)
+}
+
+//DeleteWithContext deletes the managed image identified by id
+func (mgr *ImageManager) DeleteWithContext(ctx context.Context, id string) api.DeleteImageError {
+	return api.NewDeleteImageError(UnwrapAzureError(mgr.delete(ctx, id)), id)
+}
+
+//Delete deletes the managed image identified by id
+func (mgr *ImageManager) Delete(id string) api.DeleteImageError {
+	return mgr.DeleteWithContext(context.Background(), id)
+}
+
+//FindWithContext returns the image matching query among the images listed by List
+func (mgr *ImageManager) FindWithContext(ctx context.Context, query api.ImageQuery) (*api.Image, api.FindImageError) {
+	images, err := mgr.list(ctx)
+	if err != nil {
+		return nil, api.NewFindImageError(UnwrapAzureError(err), query)
+	}
+	img, err := api.FindImage(images, query)
+	return img, api.NewFindImageError(err, query)
+}
+
+//Find returns the image matching query among the images listed by List
+func (mgr *ImageManager) Find(query api.ImageQuery) (*api.Image, api.FindImageError) {
+	return mgr.FindWithContext(context.Background(), query)
 }
@@ -0,0 +1,139 @@
+package azure
+
+import (
+	"context"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/SebastienDorgan/anyclouds/sshutils"
+	"github.com/pkg/errors"
+)
+
+//KeyPairManager azure implementation of api.KeyPairManager, key pairs are stored as SSH public key resources
+type KeyPairManager struct {
+	Provider *Provider
+}
+
+func (mgr *KeyPairManager) resourceGroup() string {
+	return mgr.Provider.Configuration.ResourceGroupName
+}
+
+//keyPair converts key, azure does not compute fingerprints so it is computed from the public key
+func keyPair(key *SSHPublicKey) (*api.KeyPair, error) {
+	res := &api.KeyPair{
+		Name: to.String(key.Name),
+	}
+	if key.Properties == nil || key.Properties.PublicKey == nil {
+		return res, nil
+	}
+	fingerprint, err := sshutils.Fingerprint([]byte(*key.Properties.PublicKey))
+	if err != nil {
+		return nil, err
+	}
+	res.Fingerprint = fingerprint
+	return res, nil
+}
+
+//publicKey returns the public key of the key pair identified by name
+func (mgr *KeyPairManager) publicKey(ctx context.Context, name string) (string, error) {
+	key, err := mgr.Provider.BaseServices.SSHPublicKeysClient.Get(ctx, mgr.resourceGroup(), name)
+	if err != nil {
+		return "", err
+	}
+	if key.Properties == nil || key.Properties.PublicKey == nil {
+		return "", errors.Errorf("key pair %s has no public key", name)
+	}
+	return *key.Properties.PublicKey, nil
+}
+
+func (mgr *KeyPairManager) importKeyPair(ctx context.Context, name string, publicKey []byte) (*api.KeyPair, error) {
+	if _, err := sshutils.Fingerprint(publicKey); err != nil {
+		return nil, api.WithKind(errors.Wrap(err, "invalid public key"), api.ErrInvalidArgument)
+	}
+	//PUT updates existing resources, the existence of the key is checked to not replace it silently
+	_, err := mgr.Provider.BaseServices.SSHPublicKeysClient.Get(ctx, mgr.resourceGroup(), name)
+	if err == nil {
+		return nil, api.WithKind(errors.Errorf("key pair %s already exists", name), api.ErrAlreadyExists)
+	}
+	if azureErrorKind(err) != api.ErrNotFound {
+		return nil, err
+	}
+	key, err := mgr.Provider.BaseServices.SSHPublicKeysClient.Create(ctx, mgr.resourceGroup(), name, SSHPublicKey{
+		Location: to.StringPtr(mgr.Provider.Configuration.Location),
+		Properties: &SSHPublicKeyProperties{
+			PublicKey: to.StringPtr(string(publicKey)),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keyPair(&key)
+}
+
+//ImportWithContext registers publicKey under name
+func (mgr *KeyPairManager) ImportWithContext(ctx context.Context, name string, publicKey []byte) (*api.KeyPair, api.ImportKeyPairError) {
+	kp, err := mgr.importKeyPair(ctx, name, publicKey)
+	return kp, api.NewImportKeyPairError(UnwrapAzureError(err), name)
+}
+
+//Import registers publicKey under name
+func (mgr *KeyPairManager) Import(name string, publicKey []byte) (*api.KeyPair, api.ImportKeyPairError) {
+	return mgr.ImportWithContext(context.Background(), name, publicKey)
+}
+
+//DeleteWithContext deletes the key pair identified by name
+func (mgr *KeyPairManager) DeleteWithContext(ctx context.Context, name string) api.DeleteKeyPairError {
+	err := mgr.Provider.BaseServices.SSHPublicKeysClient.Delete(ctx, mgr.resourceGroup(), name)
+	return api.NewDeleteKeyPairError(UnwrapAzureError(err), name)
+}
+
+//Delete deletes the key pair identified by name
+func (mgr *KeyPairManager) Delete(name string) api.DeleteKeyPairError {
+	return mgr.DeleteWithContext(context.Background(), name)
+}
+
+func (mgr *KeyPairManager) list(ctx context.Context) ([]api.KeyPair, error) {
+	keys, err := mgr.Provider.BaseServices.SSHPublicKeysClient.ListByResourceGroup(ctx, mgr.resourceGroup())
+	if err != nil {
+		return nil, err
+	}
+	var res []api.KeyPair
+	for i := range keys {
+		kp, err := keyPair(&keys[i])
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, *kp)
+	}
+	return res, nil
+}
+
+//ListWithContext lists key pairs
+func (mgr *KeyPairManager) ListWithContext(ctx context.Context) ([]api.KeyPair, api.ListKeyPairsError) {
+	l, err := mgr.list(ctx)
+	return l, api.NewListKeyPairsError(UnwrapAzureError(err))
+}
+
+//List lists key pairs
+func (mgr *KeyPairManager) List() ([]api.KeyPair, api.ListKeyPairsError) {
+	return mgr.ListWithContext(context.Background())
+}
+
+func (mgr *KeyPairManager) get(ctx context.Context, name string) (*api.KeyPair, error) {
+	key, err := mgr.Provider.BaseServices.SSHPublicKeysClient.Get(ctx, mgr.resourceGroup(), name)
+	if err != nil {
+		return nil, err
+	}
+	return keyPair(&key)
+}
+
+//GetWithContext returns the key pair identified by name
+func (mgr *KeyPairManager) GetWithContext(ctx context.Context, name string) (*api.KeyPair, api.GetKeyPairError) {
+	kp, err := mgr.get(ctx, name)
+	return kp, api.NewGetKeyPairError(UnwrapAzureError(err), name)
+}
+
+//Get returns the key pair identified by name
+func (mgr *KeyPairManager) Get(name string) (*api.KeyPair, api.GetKeyPairError) {
+	return mgr.GetWithContext(context.Background(), name)
+}
@@ -0,0 +1,21 @@
+package azure_test
+
+import (
+	"testing"
+
+	"github.com/SebastienDorgan/anyclouds/tests"
+	"github.com/stretchr/testify/suite"
+)
+
+type AZKeyPairManagerTestSuite struct {
+	tests.KeyPairManagerTestSuite
+}
+
+//SetupSuite set up key pair manager
+func (suite *AZKeyPairManagerTestSuite) SetupSuite() {
+	suite.Prov = GetProvider()
+}
+
+func TestAZKeyPairManagerTestSuite(t *testing.T) {
+	suite.Run(t, new(AZKeyPairManagerTestSuite))
+}
@@ -0,0 +1,610 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/network/mgmt/network"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/pkg/errors"
+)
+
+//LoadBalancerManager azure implementation of api.LoadBalancerManager based on Azure Load Balancer
+//Each listener is made of a load balancing rule, a backend address pool and a probe named after the listener,
+//servers are added to backend pools through the IP configuration of their network interface attached to the load balancer network
+type LoadBalancerManager struct {
+	Provider *Provider
+}
+
+//frontendName name of the frontend IP configuration of the load balancers
+const frontendName = "frontend"
+
+func (mgr *LoadBalancerManager) resourceGroup() string {
+	return mgr.Provider.Configuration.ResourceGroupName
+}
+
+//loadBalancerID returns the azure resource identifier of the load balancer named name
+func (mgr *LoadBalancerManager) loadBalancerID(name string) string {
+	return fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Network/loadBalancers/%s",
+		mgr.Provider.Configuration.SubscriptionID, mgr.resourceGroup(), name)
+}
+
+//publicIPName returns the name of the public IP address of the public load balancer named name
+func publicIPName(name string) string {
+	return fmt.Sprintf("LB-%s", name)
+}
+
+//listenerName returns the name of the rule, backend pool and probe of a listener
+func listenerName(protocol api.LoadBalancerProtocol, port int) string {
+	return fmt.Sprintf("%s-%d", strings.ToLower(string(protocol)), port)
+}
+
+func (mgr *LoadBalancerManager) checkOptions(options *api.CreateLoadBalancerOptions) error {
+	err := api.CheckLoadBalancerOptions(options)
+	if err != nil {
+		return err
+	}
+	if options.Type == api.LoadBalancerL7 {
+		return api.WithKind(errors.Errorf("azure load balancers cannot forward HTTP requests"), api.ErrInvalidArgument)
+	}
+	if len(options.SubnetIDs) != 1 {
+		return api.WithKind(errors.Errorf("azure load balancers must be attached to a single subnet"), api.ErrInvalidArgument)
+	}
+	return nil
+}
+
+//subnet returns the subnet identified by id, subnets are looked up in every virtual network of the resource group
+func (mgr *LoadBalancerManager) subnet(ctx context.Context, id string) (*network.Subnet, error) {
+	res, err := mgr.Provider.BaseServices.VirtualNetworksClient.List(ctx, mgr.resourceGroup())
+	if err != nil {
+		return nil, err
+	}
+	for res.NotDone() {
+		for _, n := range res.Values() {
+			if n.VirtualNetworkPropertiesFormat == nil || n.Subnets == nil {
+				continue
+			}
+			for _, sn := range *n.Subnets {
+				if sn.Name != nil && *sn.Name == id {
+					return &sn, nil
+				}
+			}
+		}
+		err := res.NextWithContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nil, api.WithKind(errors.Errorf("subnet %s not found", id), api.ErrNotFound)
+}
+
+func probe(name string, pool *api.BackendPool) network.Probe {
+	hc := pool.HealthCheck
+	if hc == nil {
+		hc = api.DefaultHealthCheck(pool.Protocol)
+	}
+	p := &network.ProbePropertiesFormat{
+		Protocol: network.ProbeProtocolTCP,
+		Port:     to.Int32Ptr(int32(pool.Port)),
+	}
+	if hc.Port != 0 {
+		p.Port = to.Int32Ptr(int32(hc.Port))
+	}
+	if hc.Protocol == api.LoadBalancerHTTP {
+		p.Protocol = network.ProbeProtocolHTTP
+		p.RequestPath = to.StringPtr("/")
+		if hc.Path != "" {
+			p.RequestPath = to.StringPtr(hc.Path)
+		}
+	}
+	if hc.Interval > 0 {
+		p.IntervalInSeconds = to.Int32Ptr(int32(hc.Interval / time.Second))
+	}
+	if hc.UnhealthyThreshold > 0 {
+		p.NumberOfProbes = to.Int32Ptr(int32(hc.UnhealthyThreshold))
+	}
+	return network.Probe{
+		Name:                  to.StringPtr(name),
+		ProbePropertiesFormat: p,
+	}
+}
+
+//loadBalancer returns the definition of the load balancer described by options, ip is the public IP address of public load balancers
+func (mgr *LoadBalancerManager) loadBalancer(options *api.CreateLoadBalancerOptions, sn *network.Subnet, ip *network.PublicIPAddress) network.LoadBalancer {
+	id := mgr.loadBalancerID(options.Name)
+	frontend := &network.FrontendIPConfigurationPropertiesFormat{
+		PrivateIPAllocationMethod: network.Dynamic,
+		Subnet:                    &network.Subnet{ID: sn.ID},
+	}
+	if ip != nil {
+		frontend = &network.FrontendIPConfigurationPropertiesFormat{
+			PublicIPAddress: &network.PublicIPAddress{ID: ip.ID},
+		}
+	}
+	var pools []network.BackendAddressPool
+	var probes []network.Probe
+	var rules []network.LoadBalancingRule
+	for _, l := range options.Listeners {
+		name := listenerName(l.Protocol, l.Port)
+		protocol := network.TransportProtocolTCP
+		if l.Protocol == api.LoadBalancerUDP {
+			protocol = network.TransportProtocolUDP
+		}
+		pools = append(pools, network.BackendAddressPool{Name: to.StringPtr(name)})
+		probes = append(probes, probe(name, &l.Pool))
+		rules = append(rules, network.LoadBalancingRule{
+			Name: to.StringPtr(name),
+			LoadBalancingRulePropertiesFormat: &network.LoadBalancingRulePropertiesFormat{
+				FrontendIPConfiguration: &network.SubResource{ID: to.StringPtr(id + "/frontendIPConfigurations/" + frontendName)},
+				BackendAddressPool:      &network.SubResource{ID: to.StringPtr(id + "/backendAddressPools/" + name)},
+				Probe:                   &network.SubResource{ID: to.StringPtr(id + "/probes/" + name)},
+				Protocol:                protocol,
+				FrontendPort:            to.Int32Ptr(int32(l.Port)),
+				BackendPort:             to.Int32Ptr(int32(l.Pool.Port)),
+			},
+		})
+	}
+	return network.LoadBalancer{
+		Sku: &network.LoadBalancerSku{
+			Name: network.LoadBalancerSkuNameStandard,
+		},
+		LoadBalancerPropertiesFormat: &network.LoadBalancerPropertiesFormat{
+			FrontendIPConfigurations: &[]network.FrontendIPConfiguration{
+				{
+					Name:                                    to.StringPtr(frontendName),
+					FrontendIPConfigurationPropertiesFormat: frontend,
+				},
+			},
+			BackendAddressPools: &pools,
+			Probes:              &probes,
+			LoadBalancingRules:  &rules,
+		},
+		Name:     to.StringPtr(options.Name),
+		Location: to.StringPtr(mgr.Provider.Configuration.Location),
+		Tags:     azureTags(options.Tags, map[string]*string{"subnet-id": sn.Name}),
+	}
+}
+
+//createPublicIP creates the public IP address of the public load balancer named name
+func (mgr *LoadBalancerManager) createPublicIP(ctx context.Context, name string) (*network.PublicIPAddress, error) {
+	future, err := mgr.Provider.BaseServices.PublicIPAddressesClient.CreateOrUpdate(ctx, mgr.resourceGroup(), publicIPName(name), network.PublicIPAddress{
+		Sku: &network.PublicIPAddressSku{
+			Name: network.PublicIPAddressSkuNameStandard,
+		},
+		PublicIPAddressPropertiesFormat: &network.PublicIPAddressPropertiesFormat{
+			PublicIPAllocationMethod: network.Static,
+			PublicIPAddressVersion:   network.IPv4,
+		},
+		Name:     to.StringPtr(publicIPName(name)),
+		Location: to.StringPtr(mgr.Provider.Configuration.Location),
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = future.WaitForCompletionRef(ctx, mgr.Provider.BaseServices.PublicIPAddressesClient.Client)
+	if err != nil {
+		return nil, err
+	}
+	ip, err := future.Result(mgr.Provider.BaseServices.PublicIPAddressesClient)
+	if err != nil {
+		return nil, err
+	}
+	return &ip, nil
+}
+
+//deletePublicIP deletes the public IP address of the public load balancer named name
+func (mgr *LoadBalancerManager) deletePublicIP(ctx context.Context, name string) error {
+	future, err := mgr.Provider.BaseServices.PublicIPAddressesClient.Delete(ctx, mgr.resourceGroup(), publicIPName(name))
+	if err != nil {
+		return err
+	}
+	return future.WaitForCompletionRef(ctx, mgr.Provider.BaseServices.PublicIPAddressesClient.Client)
+}
+
+//inNetwork returns true if the IP configuration ipc uses a subnet of the virtual network identified by networkID
+func inNetwork(ipc *network.InterfaceIPConfiguration, networkID string) bool {
+	if ipc.InterfaceIPConfigurationPropertiesFormat == nil || ipc.Subnet == nil || ipc.Subnet.ID == nil {
+		return false
+	}
+	return strings.HasPrefix(strings.ToLower(*ipc.Subnet.ID), strings.ToLower(networkID+"/subnets/"))
+}
+
+//backendNetworkInterface returns the name of the network interface of the server identified by serverID attached to the network identified by networkID
+func (mgr *LoadBalancerManager) backendNetworkInterface(ctx context.Context, serverID string, networkID string) (string, error) {
+	vm, err := mgr.Provider.ServerManager.get(ctx, serverID)
+	if err != nil {
+		return "", err
+	}
+	if vm.NetworkProfile != nil && vm.NetworkProfile.NetworkInterfaces != nil {
+		for _, nir := range *vm.NetworkProfile.NetworkInterfaces {
+			ni, err := mgr.Provider.NetworkInterfacesManager.get(ctx, resourceName(*nir.ID))
+			if err != nil {
+				return "", err
+			}
+			if ni.IPConfigurations == nil {
+				continue
+			}
+			for _, ipc := range *ni.IPConfigurations {
+				if inNetwork(&ipc, networkID) {
+					return *ni.Name, nil
+				}
+			}
+		}
+	}
+	err = errors.Errorf("server %s has no network interface attached to the network of the load balancer", serverID)
+	return "", api.WithKind(err, api.ErrInvalidArgument)
+}
+
+//updateBackendPools updates the backend pools of the IP configuration of the network interface named name attached to the network identified by networkID,
+//the backend pools of every IP configuration are updated if networkID is empty. update returns the new backend pools from the current ones
+func (mgr *LoadBalancerManager) updateBackendPools(ctx context.Context, name string, networkID string, update func(pools []network.BackendAddressPool) []network.BackendAddressPool) error {
+	ni, err := mgr.Provider.NetworkInterfacesManager.get(ctx, name)
+	if err != nil {
+		return err
+	}
+	if ni.IPConfigurations == nil {
+		return nil
+	}
+	for i := range *ni.IPConfigurations {
+		ipc := &(*ni.IPConfigurations)[i]
+		if ipc.InterfaceIPConfigurationPropertiesFormat == nil || (networkID != "" && !inNetwork(ipc, networkID)) {
+			continue
+		}
+		var pools []network.BackendAddressPool
+		if ipc.LoadBalancerBackendAddressPools != nil {
+			pools = *ipc.LoadBalancerBackendAddressPools
+		}
+		pools = update(pools)
+		ipc.LoadBalancerBackendAddressPools = &pools
+		if networkID != "" {
+			break
+		}
+	}
+	future, err := mgr.Provider.BaseServices.InterfacesClient.CreateOrUpdate(ctx, mgr.resourceGroup(), name, *ni)
+	if err != nil {
+		return err
+	}
+	return future.WaitForCompletionRef(ctx, mgr.Provider.BaseServices.InterfacesClient.Client)
+}
+
+//addBackends adds the servers of the backend pools of options to the backend pools of the load balancer
+func (mgr *LoadBalancerManager) addBackends(ctx context.Context, options *api.CreateLoadBalancerOptions, networkID string) error {
+	id := mgr.loadBalancerID(options.Name)
+	var names []string
+	pools := make(map[string][]string)
+	for _, l := range options.Listeners {
+		for _, srv := range l.Pool.ServerIDs {
+			name, err := mgr.backendNetworkInterface(ctx, srv, networkID)
+			if err != nil {
+				return err
+			}
+			if _, ok := pools[name]; !ok {
+				names = append(names, name)
+			}
+			pools[name] = append(pools[name], id+"/backendAddressPools/"+listenerName(l.Protocol, l.Port))
+		}
+	}
+	for _, name := range names {
+		err := mgr.updateBackendPools(ctx, name, networkID, func(current []network.BackendAddressPool) []network.BackendAddressPool {
+			for _, pool := range pools[name] {
+				current = append(current, network.BackendAddressPool{ID: to.StringPtr(pool)})
+			}
+			return current
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//networkID returns the identifier of the virtual network of the subnet sn
+func networkID(sn *network.Subnet) string {
+	return (*sn.ID)[:strings.LastIndex(strings.ToLower(*sn.ID), "/subnets/")]
+}
+
+func (mgr *LoadBalancerManager) create(ctx context.Context, options *api.CreateLoadBalancerOptions) (*api.LoadBalancer, error) {
+	err := mgr.checkOptions(options)
+	if err != nil {
+		return nil, err
+	}
+	sn, err := mgr.subnet(ctx, options.SubnetIDs[0])
+	if err != nil {
+		return nil, err
+	}
+	var ip *network.PublicIPAddress
+	if options.Public {
+		ip, err = mgr.createPublicIP(ctx, options.Name)
+		if err != nil {
+			return nil, err
+		}
+	}
+	future, err := mgr.Provider.BaseServices.LoadBalancersClient.CreateOrUpdate(ctx, mgr.resourceGroup(), options.Name, mgr.loadBalancer(options, sn, ip))
+	if err == nil {
+		err = future.WaitForCompletionRef(ctx, mgr.Provider.BaseServices.LoadBalancersClient.Client)
+	}
+	if err == nil {
+		err = mgr.addBackends(ctx, options, networkID(sn))
+	}
+	if err != nil {
+		_ = mgr.delete(ctx, options.Name)
+		if ip != nil {
+			_ = mgr.deletePublicIP(ctx, options.Name)
+		}
+		return nil, err
+	}
+	return mgr.get(ctx, options.Name)
+}
+
+//CreateWithContext context aware version of Create
+func (mgr *LoadBalancerManager) CreateWithContext(ctx context.Context, options api.CreateLoadBalancerOptions) (*api.LoadBalancer, api.CreateLoadBalancerError) {
+	lb, err := mgr.create(ctx, &options)
+	if err != nil {
+		return nil, api.NewCreateLoadBalancerError(UnwrapAzureError(err), options)
+	}
+	return lb, nil
+}
+
+//Create creates a load balancer
+func (mgr *LoadBalancerManager) Create(options api.CreateLoadBalancerOptions) (*api.LoadBalancer, api.CreateLoadBalancerError) {
+	return mgr.CreateWithContext(context.Background(), options)
+}
+
+//removeBackends removes the network interfaces from the backend pools of lb
+func (mgr *LoadBalancerManager) removeBackends(ctx context.Context, lb *network.LoadBalancer) error {
+	if lb.BackendAddressPools == nil {
+		return nil
+	}
+	var names []string
+	seen := make(map[string]bool)
+	for _, pool := range *lb.BackendAddressPools {
+		if pool.BackendAddressPoolPropertiesFormat == nil || pool.BackendIPConfigurations == nil {
+			continue
+		}
+		for _, ipc := range *pool.BackendIPConfigurations {
+			//ipc.ID is /subscriptions/.../networkInterfaces/{name}/ipConfigurations/{name}
+			tokens := strings.Split(*ipc.ID, "/")
+			name := tokens[len(tokens)-3]
+			if !seen[name] {
+				names = append(names, name)
+			}
+			seen[name] = true
+		}
+	}
+	prefix := strings.ToLower(*lb.ID + "/")
+	for _, name := range names {
+		err := mgr.updateBackendPools(ctx, name, "", func(current []network.BackendAddressPool) []network.BackendAddressPool {
+			var pools []network.BackendAddressPool
+			for _, pool := range current {
+				if !strings.HasPrefix(strings.ToLower(*pool.ID), prefix) {
+					pools = append(pools, pool)
+				}
+			}
+			return pools
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//delete deletes the load balancer named name after having removed the servers from its backend pools, its public IP address is not deleted
+func (mgr *LoadBalancerManager) delete(ctx context.Context, name string) error {
+	lb, err := mgr.Provider.BaseServices.LoadBalancersClient.Get(ctx, mgr.resourceGroup(), name, "")
+	if err != nil {
+		return err
+	}
+	if lb.LoadBalancerPropertiesFormat != nil {
+		err = mgr.removeBackends(ctx, &lb)
+		if err != nil {
+			return err
+		}
+	}
+	future, err := mgr.Provider.BaseServices.LoadBalancersClient.Delete(ctx, mgr.resourceGroup(), name)
+	if err != nil {
+		return err
+	}
+	return future.WaitForCompletionRef(ctx, mgr.Provider.BaseServices.LoadBalancersClient.Client)
+}
+
+//DeleteWithContext context aware version of Delete
+func (mgr *LoadBalancerManager) DeleteWithContext(ctx context.Context, id string) api.DeleteLoadBalancerError {
+	lb, err := mgr.get(ctx, id)
+	if err != nil {
+		return api.NewDeleteLoadBalancerError(UnwrapAzureError(err), id)
+	}
+	err = mgr.delete(ctx, id)
+	if err == nil && lb.Public {
+		err = mgr.deletePublicIP(ctx, id)
+	}
+	return api.NewDeleteLoadBalancerError(UnwrapAzureError(err), id)
+}
+
+//Delete deletes the load balancer identified by id along with its listeners
+func (mgr *LoadBalancerManager) Delete(id string) api.DeleteLoadBalancerError {
+	return mgr.DeleteWithContext(context.Background(), id)
+}
+
+//backendServers returns the virtual machines of the network interfaces of the resource group indexed by network interface name
+func (mgr *LoadBalancerManager) backendServers(ctx context.Context) (map[string]string, error) {
+	nis, err := mgr.Provider.NetworkInterfacesManager.listAzure(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	servers := make(map[string]string)
+	for _, ni := range nis {
+		if ni.InterfacePropertiesFormat != nil && ni.VirtualMachine != nil && ni.VirtualMachine.ID != nil {
+			servers[strings.ToLower(*ni.Name)] = resourceName(*ni.VirtualMachine.ID)
+		}
+	}
+	return servers, nil
+}
+
+func healthCheck(p *network.ProbePropertiesFormat, backendPort int32) *api.HealthCheck {
+	hc := &api.HealthCheck{
+		Protocol: api.LoadBalancerTCP,
+		Path:     to.String(p.RequestPath),
+	}
+	if p.Protocol == network.ProbeProtocolHTTP {
+		hc.Protocol = api.LoadBalancerHTTP
+	}
+	if port := to.Int32(p.Port); port != backendPort {
+		hc.Port = int(port)
+	}
+	hc.Interval = time.Duration(to.Int32(p.IntervalInSeconds)) * time.Second
+	hc.UnhealthyThreshold = int(to.Int32(p.NumberOfProbes))
+	return hc
+}
+
+//listener converts the load balancing rule r of lb, servers is used to resolve the servers of the backend pools
+func listener(lb *network.LoadBalancer, r *network.LoadBalancingRule, servers map[string]string) api.Listener {
+	protocol := api.LoadBalancerTCP
+	if r.Protocol == network.TransportProtocolUDP {
+		protocol = api.LoadBalancerUDP
+	}
+	l := api.Listener{
+		ID:       *r.Name,
+		Protocol: protocol,
+		Port:     int(to.Int32(r.FrontendPort)),
+		Pool: api.BackendPool{
+			Protocol: protocol,
+			Port:     int(to.Int32(r.BackendPort)),
+		},
+	}
+	for _, pool := range *lb.BackendAddressPools {
+		if r.BackendAddressPool == nil || !strings.EqualFold(*pool.ID, *r.BackendAddressPool.ID) {
+			continue
+		}
+		if pool.BackendAddressPoolPropertiesFormat == nil || pool.BackendIPConfigurations == nil {
+			continue
+		}
+		for _, ipc := range *pool.BackendIPConfigurations {
+			//ipc.ID is /subscriptions/.../networkInterfaces/{name}/ipConfigurations/{name}
+			tokens := strings.Split(*ipc.ID, "/")
+			if srv, ok := servers[strings.ToLower(tokens[len(tokens)-3])]; ok {
+				l.Pool.ServerIDs = append(l.Pool.ServerIDs, srv)
+			}
+		}
+	}
+	for _, p := range *lb.Probes {
+		if r.Probe != nil && strings.EqualFold(*p.ID, *r.Probe.ID) && p.ProbePropertiesFormat != nil {
+			l.Pool.HealthCheck = healthCheck(p.ProbePropertiesFormat, to.Int32(r.BackendPort))
+		}
+	}
+	return l
+}
+
+//convertLoadBalancer converts lb, addresses is used to resolve public IP addresses and servers to resolve the servers of the backend pools
+func convertLoadBalancer(lb *network.LoadBalancer, addresses map[string]string, servers map[string]string) *api.LoadBalancer {
+	res := &api.LoadBalancer{
+		ID:   *lb.Name,
+		Name: *lb.Name,
+		Type: api.LoadBalancerL4,
+		Tags: userTags(lb.Tags),
+	}
+	if sn, ok := lb.Tags["subnet-id"]; ok && sn != nil {
+		res.SubnetIDs = []string{*sn}
+	}
+	if lb.LoadBalancerPropertiesFormat == nil {
+		return res
+	}
+	if lb.FrontendIPConfigurations != nil {
+		for _, fe := range *lb.FrontendIPConfigurations {
+			if fe.FrontendIPConfigurationPropertiesFormat == nil {
+				continue
+			}
+			if fe.PublicIPAddress != nil && fe.PublicIPAddress.ID != nil {
+				res.Public = true
+				res.Address = addresses[strings.ToLower(*fe.PublicIPAddress.ID)]
+			} else if fe.PrivateIPAddress != nil {
+				res.Address = *fe.PrivateIPAddress
+			}
+		}
+	}
+	if lb.LoadBalancingRules != nil {
+		for _, r := range *lb.LoadBalancingRules {
+			if r.LoadBalancingRulePropertiesFormat != nil {
+				res.Listeners = append(res.Listeners, listener(lb, &r, servers))
+			}
+		}
+	}
+	sort.Slice(res.Listeners, func(i, j int) bool {
+		return res.Listeners[i].Port < res.Listeners[j].Port
+	})
+	return res
+}
+
+//ListWithContext context aware version of List
+func (mgr *LoadBalancerManager) ListWithContext(ctx context.Context) ([]api.LoadBalancer, api.ListLoadBalancersError) {
+	l, err := mgr.list(ctx)
+	if err != nil {
+		return nil, api.NewListLoadBalancersError(UnwrapAzureError(err))
+	}
+	return l, nil
+}
+
+func (mgr *LoadBalancerManager) list(ctx context.Context) ([]api.LoadBalancer, error) {
+	addresses, err := mgr.Provider.NetworkInterfacesManager.publicAddresses(ctx)
+	if err != nil {
+		return nil, err
+	}
+	servers, err := mgr.backendServers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res, err := mgr.Provider.BaseServices.LoadBalancersClient.List(ctx, mgr.resourceGroup())
+	if err != nil {
+		return nil, err
+	}
+	var list []api.LoadBalancer
+	for res.NotDone() {
+		for _, lb := range res.Values() {
+			list = append(list, *convertLoadBalancer(&lb, addresses, servers))
+		}
+		err := res.NextWithContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return list, nil
+}
+
+//List lists load balancers
+func (mgr *LoadBalancerManager) List() ([]api.LoadBalancer, api.ListLoadBalancersError) {
+	return mgr.ListWithContext(context.Background())
+}
+
+func (mgr *LoadBalancerManager) get(ctx context.Context, id string) (*api.LoadBalancer, error) {
+	lb, err := mgr.Provider.BaseServices.LoadBalancersClient.Get(ctx, mgr.resourceGroup(), id, "")
+	if err != nil {
+		return nil, err
+	}
+	addresses, err := mgr.Provider.NetworkInterfacesManager.publicAddresses(ctx)
+	if err != nil {
+		return nil, err
+	}
+	servers, err := mgr.backendServers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return convertLoadBalancer(&lb, addresses, servers), nil
+}
+
+//GetWithContext context aware version of Get
+func (mgr *LoadBalancerManager) GetWithContext(ctx context.Context, id string) (*api.LoadBalancer, api.GetLoadBalancerError) {
+	lb, err := mgr.get(ctx, id)
+	if err != nil {
+		return nil, api.NewGetLoadBalancerError(UnwrapAzureError(err), id)
+	}
+	return lb, nil
+}
+
+//Get returns the load balancer identified by id
+func (mgr *LoadBalancerManager) Get(id string) (*api.LoadBalancer, api.GetLoadBalancerError) {
+	return mgr.GetWithContext(context.Background(), id)
+}
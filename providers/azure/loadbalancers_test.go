@@ -0,0 +1,23 @@
+package azure_test
+
+import (
+	"testing"
+
+	"github.com/SebastienDorgan/anyclouds/tests"
+	"github.com/stretchr/testify/suite"
+)
+
+type AZLoadBalancerManagerTestSuite struct {
+	tests.LoadBalancerManagerTestSuite
+}
+
+//SetupSuite set up load balancer manager
+func (suite *AZLoadBalancerManagerTestSuite) SetupSuite() {
+	suite.Prov = GetProvider()
+	//azure load balancers only forward TCP and UDP traffic
+	suite.SkipL7 = true
+}
+
+func TestAZLoadBalancerManagerTestSuite(t *testing.T) {
+	suite.Run(t, new(AZLoadBalancerManagerTestSuite))
+}
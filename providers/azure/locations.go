@@ -0,0 +1,150 @@
+package azure
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/compute/mgmt/compute"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/SebastienDorgan/anyclouds/api"
+)
+
+//LocationManager azure implementation of api.LocationManager
+//Regions are the locations of the subscription, availability zones are those offered by the virtual machine sizes of a location
+type LocationManager struct {
+	Provider *Provider
+}
+
+func (mgr *LocationManager) listRegions(ctx context.Context) ([]api.Region, error) {
+	res, err := mgr.Provider.BaseServices.SubscriptionsClient.ListLocations(ctx, mgr.Provider.Configuration.SubscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	regions := []api.Region{}
+	if res.Value != nil {
+		for _, l := range *res.Value {
+			regions = append(regions, api.Region{
+				ID:   to.String(l.Name),
+				Name: to.String(l.DisplayName),
+			})
+		}
+	}
+	sort.Slice(regions, func(i, j int) bool {
+		return regions[i].ID < regions[j].ID
+	})
+	return regions, nil
+}
+
+//ListRegionsWithContext lists the locations of the subscription
+func (mgr *LocationManager) ListRegionsWithContext(ctx context.Context) ([]api.Region, api.ListRegionsError) {
+	regions, err := mgr.listRegions(ctx)
+	if err != nil {
+		return nil, api.NewListRegionsError(UnwrapAzureError(err))
+	}
+	return regions, nil
+}
+
+//ListRegions lists the locations of the subscription
+func (mgr *LocationManager) ListRegions() ([]api.Region, api.ListRegionsError) {
+	return mgr.ListRegionsWithContext(context.Background())
+}
+
+//skuZones adds to zones the zones of region in which sku is offered, a zone is available if the subscription is not restricted from using sku in it
+func skuZones(sku *compute.ResourceSku, region string, zones map[string]bool) {
+	if sku.LocationInfo == nil {
+		return
+	}
+	restricted := map[string]bool{}
+	if sku.Restrictions != nil {
+		for _, r := range *sku.Restrictions {
+			if r.Type != compute.Zone || r.RestrictionInfo == nil || r.RestrictionInfo.Zones == nil {
+				continue
+			}
+			for _, z := range *r.RestrictionInfo.Zones {
+				restricted[z] = true
+			}
+		}
+	}
+	for _, info := range *sku.LocationInfo {
+		if !strings.EqualFold(to.String(info.Location), region) || info.Zones == nil {
+			continue
+		}
+		for _, z := range *info.Zones {
+			zones[z] = zones[z] || !restricted[z]
+		}
+	}
+}
+
+func (mgr *LocationManager) listZones(ctx context.Context, region string) ([]api.AvailabilityZone, error) {
+	regions, err := mgr.listRegions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	_, err = api.FindRegion(regions, region)
+	if err != nil {
+		return nil, err
+	}
+	it, err := mgr.Provider.BaseServices.ResourceSkusClient.ListComplete(ctx)
+	if err != nil {
+		return nil, err
+	}
+	available := map[string]bool{}
+	for it.NotDone() {
+		sku := it.Value()
+		if to.String(sku.ResourceType) == "virtualMachines" {
+			skuZones(&sku, region, available)
+		}
+		err = it.NextWithContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	zones := []api.AvailabilityZone{}
+	for z, ok := range available {
+		zones = append(zones, api.AvailabilityZone{
+			ID:        z,
+			Region:    region,
+			Available: ok,
+		})
+	}
+	sort.Slice(zones, func(i, j int) bool {
+		return zones[i].ID < zones[j].ID
+	})
+	return zones, nil
+}
+
+//ListZonesWithContext lists the availability zones of region
+func (mgr *LocationManager) ListZonesWithContext(ctx context.Context, region string) ([]api.AvailabilityZone, api.ListAvailabilityZonesError) {
+	zones, err := mgr.listZones(ctx, region)
+	if err != nil {
+		return nil, api.NewListAvailabilityZonesError(UnwrapAzureError(err), region)
+	}
+	return zones, nil
+}
+
+//ListZones lists the availability zones of region
+func (mgr *LocationManager) ListZones(region string) ([]api.AvailabilityZone, api.ListAvailabilityZonesError) {
+	return mgr.ListZonesWithContext(context.Background(), region)
+}
+
+//WithRegion returns a new azure provider with the configuration of p bound to the location region
+//Resources are still created in the resource group of the configuration
+func (p *Provider) WithRegion(region string) (api.Provider, error) {
+	regions, err := p.LocationManager.listRegions(context.Background())
+	if err != nil {
+		return nil, UnwrapAzureError(err)
+	}
+	_, err = api.FindRegion(regions, region)
+	if err != nil {
+		return nil, err
+	}
+	cfg := p.Configuration
+	cfg.Location = region
+	res := &Provider{}
+	err = res.init(&cfg)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
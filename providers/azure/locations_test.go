@@ -0,0 +1,21 @@
+package azure_test
+
+import (
+	"testing"
+
+	"github.com/SebastienDorgan/anyclouds/tests"
+	"github.com/stretchr/testify/suite"
+)
+
+type AZLocationManagerTestSuite struct {
+	tests.LocationManagerTestSuite
+}
+
+//SetupSuite set up location manager
+func (suite *AZLocationManagerTestSuite) SetupSuite() {
+	suite.Prov = GetProvider()
+}
+
+func TestAZLocationManagerTestSuite(t *testing.T) {
+	suite.Run(t, new(AZLocationManagerTestSuite))
+}
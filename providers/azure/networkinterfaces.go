@@ -6,6 +6,7 @@ import (
 	"github.com/Azure/go-autorest/autorest/to"
 	"github.com/SebastienDorgan/anyclouds/api"
 	"github.com/pkg/errors"
+	"strings"
 )
 
 type NetworkInterfacesManager struct {
@@ -15,17 +16,39 @@ type NetworkInterfacesManager struct {
 func (mgr *NetworkInterfacesManager) resourceGroup() string {
 	return mgr.Provider.Configuration.ResourceGroupName
 }
-func (mgr *NetworkInterfacesManager) create(options *api.CreateNetworkInterfaceOptions) (*network.Interface, error) {
+
+//securityGroup returns a reference to the security group identified by id or nil if id is empty
+func (mgr *NetworkInterfacesManager) securityGroup(ctx context.Context, id string) (*network.SecurityGroup, error) {
+	if len(id) == 0 {
+		return nil, nil
+	}
+	sg, err := mgr.Provider.BaseServices.SecurityGroupsClient.Get(ctx, mgr.resourceGroup(), id, "")
+	if err != nil {
+		return nil, err
+	}
+	return &network.SecurityGroup{ID: sg.ID}, nil
+}
+
+func (mgr *NetworkInterfacesManager) create(ctx context.Context, options *api.CreateNetworkInterfaceOptions) (*network.Interface, error) {
 	var subResource *network.SubResource
 	if options.ServerID != nil {
 		subResource = &network.SubResource{ID: options.ServerID}
 	}
-	tags := make(map[string]*string)
-	tags["network-id"] = &options.NetworkID
+	sn, err := mgr.Provider.BaseServices.SubnetsClient.Get(ctx, mgr.resourceGroup(), options.NetworkID, options.SubnetID, "")
+	if err != nil {
+		return nil, err
+	}
+	sg, err := mgr.securityGroup(ctx, options.SecurityGroupID)
+	if err != nil {
+		return nil, err
+	}
+	attributes := make(map[string]*string)
+	attributes["network-id"] = &options.NetworkID
 	if options.ServerID != nil {
-		tags["server-id"] = options.ServerID
+		attributes["server-id"] = options.ServerID
 	}
-	future, err := mgr.Provider.BaseServices.InterfacesClient.CreateOrUpdate(context.Background(), mgr.resourceGroup(), options.Name, network.Interface{
+	tags := azureTags(options.Tags, attributes)
+	future, err := mgr.Provider.BaseServices.InterfacesClient.CreateOrUpdate(ctx, mgr.resourceGroup(), options.Name, network.Interface{
 		InterfacePropertiesFormat: &network.InterfacePropertiesFormat{
 			VirtualMachine: subResource,
 			IPConfigurations: &[]network.InterfaceIPConfiguration{
@@ -34,7 +57,7 @@ func (mgr *NetworkInterfacesManager) create(options *api.CreateNetworkInterfaceO
 						PrivateIPAddress:          options.PrivateIPAddress,
 						PrivateIPAllocationMethod: network.Dynamic,
 						Subnet: &network.Subnet{
-							Name: &options.Name,
+							ID: sn.ID,
 						},
 						Primary: to.BoolPtr(options.Primary),
 					},
@@ -44,9 +67,7 @@ func (mgr *NetworkInterfacesManager) create(options *api.CreateNetworkInterfaceO
 			Primary:                     to.BoolPtr(options.Primary),
 			EnableAcceleratedNetworking: to.BoolPtr(true),
 			EnableIPForwarding:          to.BoolPtr(options.Primary),
-			NetworkSecurityGroup: &network.SecurityGroup{
-				Name: &options.SecurityGroupID,
-			},
+			NetworkSecurityGroup:        sg,
 		},
 		Name:     &options.Name,
 		Location: &mgr.Provider.Configuration.Location,
@@ -55,7 +76,7 @@ func (mgr *NetworkInterfacesManager) create(options *api.CreateNetworkInterfaceO
 	if err != nil {
 		return nil, err
 	}
-	err = future.WaitForCompletionRef(context.Background(), mgr.Provider.BaseServices.InterfacesClient.Client)
+	err = future.WaitForCompletionRef(ctx, mgr.Provider.BaseServices.InterfacesClient.Client)
 	if err != nil {
 		return nil, err
 	}
@@ -68,15 +89,48 @@ func (mgr *NetworkInterfacesManager) create(options *api.CreateNetworkInterfaceO
 	}
 	return &ni, nil
 }
+
+//CreateWithContext context aware version of Create
+func (mgr *NetworkInterfacesManager) CreateWithContext(ctx context.Context, options api.CreateNetworkInterfaceOptions) (*api.NetworkInterface, api.CreateNetworkInterfaceError) {
+	ni, err := mgr.create(ctx, &options)
+	if err != nil {
+		return nil, api.NewCreateNetworkInterfaceError(UnwrapAzureError(err), options)
+	}
+	return convertNetworkInterface(ni, nil), nil
+}
+
 func (mgr *NetworkInterfacesManager) Create(options api.CreateNetworkInterfaceOptions) (*api.NetworkInterface, api.CreateNetworkInterfaceError) {
-	ni, err := mgr.create(&options)
-	return convertNetworkInterface(ni), api.NewCreateNetworkInterfaceError(err, options)
+	return mgr.CreateWithContext(context.Background(), options)
+}
+
+//publicAddresses returns the public IP addresses of the resource group indexed by identifier
+func (mgr *NetworkInterfacesManager) publicAddresses(ctx context.Context) (map[string]string, error) {
+	res, err := mgr.Provider.BaseServices.PublicIPAddressesClient.List(ctx, mgr.resourceGroup())
+	if err != nil {
+		return nil, err
+	}
+	addresses := make(map[string]string)
+	for res.NotDone() {
+		for _, ip := range res.Values() {
+			if ip.ID != nil && ip.PublicIPAddressPropertiesFormat != nil && ip.IPAddress != nil {
+				addresses[strings.ToLower(*ip.ID)] = *ip.IPAddress
+			}
+		}
+		err := res.NextWithContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return addresses, nil
 }
 
-func convertNetworkInterface(ni *network.Interface) *api.NetworkInterface {
+//convertNetworkInterface converts ni, addresses is used to resolve public IP addresses that are not expanded
+func convertNetworkInterface(ni *network.Interface, addresses map[string]string) *api.NetworkInterface {
 	ipConf := *ni.IPConfigurations
 	var srvID string
-	if srvName, ok := ni.Tags["server-id"]; ok {
+	if ni.VirtualMachine != nil && ni.VirtualMachine.ID != nil {
+		srvID = resourceName(*ni.VirtualMachine.ID)
+	} else if srvName, ok := ni.Tags["server-id"]; ok {
 		srvID = *srvName
 	}
 	var netID string
@@ -88,37 +142,59 @@ func convertNetworkInterface(ni *network.Interface) *api.NetworkInterface {
 		PrivateIPAddress = *ipConf[0].PrivateIPAddress
 	}
 	var publicIPAddress string
-	if ipConf[0].PublicIPAddress != nil {
-		publicIPAddress = *ipConf[0].PublicIPAddress.IPAddress
+	if ip := ipConf[0].PublicIPAddress; ip != nil {
+		if ip.PublicIPAddressPropertiesFormat != nil && ip.IPAddress != nil {
+			publicIPAddress = *ip.IPAddress
+		} else if ip.ID != nil {
+			publicIPAddress = addresses[strings.ToLower(*ip.ID)]
+		}
+	}
+	var macAddress string
+	if ni.MacAddress != nil {
+		macAddress = *ni.MacAddress
+	}
+	var subnetID string
+	if ipConf[0].Subnet != nil && ipConf[0].Subnet.ID != nil {
+		subnetID = resourceName(*ipConf[0].Subnet.ID)
+	}
+	var sgID string
+	if ni.NetworkSecurityGroup != nil && ni.NetworkSecurityGroup.ID != nil {
+		sgID = resourceName(*ni.NetworkSecurityGroup.ID)
 	}
 
 	return &api.NetworkInterface{
 		ID:               *ni.Name,
 		Name:             *ni.Name,
-		MacAddress:       *ni.MacAddress,
+		MacAddress:       macAddress,
 		NetworkID:        netID,
-		SubnetID:         *ipConf[0].Subnet.Name,
+		SubnetID:         subnetID,
 		ServerID:         srvID,
 		PrivateIPAddress: PrivateIPAddress,
 		PublicIPAddress:  publicIPAddress,
-		SecurityGroupID:  *ni.NetworkSecurityGroup.Name,
+		SecurityGroupID:  sgID,
+		Tags:             userTags(ni.Tags),
 	}
 }
 
-func (mgr *NetworkInterfacesManager) delete(id string) error {
-	future, err := mgr.Provider.BaseServices.InterfacesClient.Delete(context.Background(), mgr.resourceGroup(), id)
+func (mgr *NetworkInterfacesManager) delete(ctx context.Context, id string) error {
+	future, err := mgr.Provider.BaseServices.InterfacesClient.Delete(ctx, mgr.resourceGroup(), id)
 	if err != nil {
 		return err
 	}
-	return future.WaitForCompletionRef(context.Background(), mgr.Provider.BaseServices.InterfacesClient.Client)
+	return future.WaitForCompletionRef(ctx, mgr.Provider.BaseServices.InterfacesClient.Client)
+}
+
+//DeleteWithContext context aware version of Delete
+func (mgr *NetworkInterfacesManager) DeleteWithContext(ctx context.Context, id string) api.DeleteNetworkInterfaceError {
+	return api.NewDeleteNetworkInterfaceError(UnwrapAzureError(mgr.delete(ctx, id)), id)
 }
 
 func (mgr *NetworkInterfacesManager) Delete(id string) api.DeleteNetworkInterfaceError {
-	return api.NewDeleteNetworkInterfaceError(mgr.delete(id), id)
+	return mgr.DeleteWithContext(context.Background(), id)
 }
 
-func (mgr *NetworkInterfacesManager) get(id string) (*network.Interface, error) {
-	res, err := mgr.Provider.BaseServices.InterfacesClient.Get(context.Background(), mgr.resourceGroup(), id, "")
+func (mgr *NetworkInterfacesManager) get(ctx context.Context, id string) (*network.Interface, error) {
+	res, err := mgr.Provider.BaseServices.InterfacesClient.Get(ctx, mgr.resourceGroup(), id, "")
 	if err != nil {
 		return nil, err
 	}
@@ -126,9 +202,21 @@ func (mgr *NetworkInterfacesManager) get(id string) (*network.Interface, error)
 
 }
 
+//GetWithContext context aware version of Get
+func (mgr *NetworkInterfacesManager) GetWithContext(ctx context.Context, id string) (*api.NetworkInterface, api.GetNetworkInterfaceError) {
+	ni, err := mgr.get(ctx, id)
+	if err != nil {
+		return nil, api.NewGetNetworkInterfaceError(UnwrapAzureError(err), id)
+	}
+	addresses, err := mgr.publicAddresses(ctx)
+	if err != nil {
+		return nil, api.NewGetNetworkInterfaceError(UnwrapAzureError(err), id)
+	}
+	return convertNetworkInterface(ni, addresses), nil
+}
+
 func (mgr *NetworkInterfacesManager) Get(id string) (*api.NetworkInterface, api.GetNetworkInterfaceError) {
-	ni, err := mgr.get(id)
-	return convertNetworkInterface(ni), api.NewGetNetworkInterfaceError(err, id)
+	return mgr.GetWithContext(context.Background(), id)
 }
 
 func checkNI(ni *api.NetworkInterface, options *api.ListNetworkInterfacesOptions) bool {
@@ -153,20 +241,24 @@ func checkNI(ni *api.NetworkInterface, options *api.ListNetworkInterfacesOptions
 	return true
 }
 
-func (mgr *NetworkInterfacesManager) listAzure(options *api.ListNetworkInterfacesOptions) ([]network.Interface, error) {
-	res, err := mgr.Provider.BaseServices.InterfacesClient.List(context.Background(), mgr.resourceGroup())
+func (mgr *NetworkInterfacesManager) listAzure(ctx context.Context, options *api.ListNetworkInterfacesOptions) ([]network.Interface, error) {
+	addresses, err := mgr.publicAddresses(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res, err := mgr.Provider.BaseServices.InterfacesClient.List(ctx, mgr.resourceGroup())
 	if err != nil {
 		return nil, err
 	}
 	var list []network.Interface
 	for res.NotDone() {
 		for _, ni := range res.Values() {
-			n := convertNetworkInterface(&ni)
+			n := convertNetworkInterface(&ni, addresses)
 			if checkNI(n, options) {
 				list = append(list, ni)
 			}
 		}
-		err := res.NextWithContext(context.Background())
+		err := res.NextWithContext(ctx)
 		if err != nil {
 			return nil, err
 		}
@@ -174,47 +266,64 @@ func (mgr *NetworkInterfacesManager) listAzure(options *api.ListNetworkInterface
 	return list, nil
 }
 
+//ListWithContext context aware version of List
+func (mgr *NetworkInterfacesManager) ListWithContext(ctx context.Context, options *api.ListNetworkInterfacesOptions) ([]api.NetworkInterface, api.ListNetworkInterfacesError) {
+	l, err := mgr.list(ctx, options)
+	return l, api.NewListNetworkInterfacesError(UnwrapAzureError(err), options)
+}
+
 func (mgr *NetworkInterfacesManager) List(options *api.ListNetworkInterfacesOptions) ([]api.NetworkInterface, api.ListNetworkInterfacesError) {
-	l, err := mgr.list(options)
-	return l, api.NewListNetworkInterfacesError(err, options)
+	return mgr.ListWithContext(context.Background(), options)
 }
 
-func (mgr *NetworkInterfacesManager) list(options *api.ListNetworkInterfacesOptions) ([]api.NetworkInterface, error) {
-	nis, err := mgr.listAzure(options)
+func (mgr *NetworkInterfacesManager) list(ctx context.Context, options *api.ListNetworkInterfacesOptions) ([]api.NetworkInterface, error) {
+	addresses, err := mgr.publicAddresses(ctx)
+	if err != nil {
+		return nil, err
+	}
+	nis, err := mgr.listAzure(ctx, options)
 	if err != nil {
 		return nil, err
 	}
 	var list []api.NetworkInterface
 	for _, ni := range nis {
-		list = append(list, *convertNetworkInterface(&ni))
+		list = append(list, *convertNetworkInterface(&ni, addresses))
 	}
 	return list, nil
 }
 
-func (mgr *NetworkInterfacesManager) update(options api.UpdateNetworkInterfaceOptions) (*api.NetworkInterface, error) {
-	res, err := mgr.Provider.BaseServices.InterfacesClient.Get(context.Background(), mgr.resourceGroup(), options.ID, "")
+func (mgr *NetworkInterfacesManager) update(ctx context.Context, options api.UpdateNetworkInterfaceOptions) (*api.NetworkInterface, error) {
+	res, err := mgr.Provider.BaseServices.InterfacesClient.Get(ctx, mgr.resourceGroup(), options.ID, "")
 	if err != nil {
 		return nil, err
 	}
 	if options.SecurityGroupID != nil {
-		res.NetworkSecurityGroup = &network.SecurityGroup{
-			Name: options.SecurityGroupID,
+		res.NetworkSecurityGroup, err = mgr.securityGroup(ctx, *options.SecurityGroupID)
+		if err != nil {
+			return nil, err
 		}
 	}
 	if options.ServerID != nil {
 		res.VirtualMachine = &network.SubResource{ID: options.ServerID}
 	}
 
-	future, err := mgr.Provider.BaseServices.InterfacesClient.CreateOrUpdate(context.Background(), mgr.resourceGroup(), *res.Name, res)
+	future, err := mgr.Provider.BaseServices.InterfacesClient.CreateOrUpdate(ctx, mgr.resourceGroup(), *res.Name, res)
 	if err != nil {
 		return nil, err
 	}
-	err = future.WaitForCompletionRef(context.Background(), mgr.Provider.BaseServices.InterfacesClient.Client)
-	ni, err := mgr.Get(options.ID)
-	return ni, err
+	err = future.WaitForCompletionRef(ctx, mgr.Provider.BaseServices.InterfacesClient.Client)
+	if err != nil {
+		return nil, err
+	}
+	return mgr.GetWithContext(ctx, options.ID)
+}
+
+//UpdateWithContext context aware version of Update
+func (mgr *NetworkInterfacesManager) UpdateWithContext(ctx context.Context, options api.UpdateNetworkInterfaceOptions) (*api.NetworkInterface, api.UpdateNetworkInterfaceError) {
+	ni, err := mgr.update(ctx, options)
+	return ni, api.NewUpdateNetworkInterfaceError(UnwrapAzureError(err), options)
 }
 
 func (mgr *NetworkInterfacesManager) Update(options api.UpdateNetworkInterfaceOptions) (*api.NetworkInterface, api.UpdateNetworkInterfaceError) {
-	ni, err := mgr.update(options)
-	return ni, api.NewUpdateNetworkInterfaceError(err, options)
+	return mgr.UpdateWithContext(context.Background(), options)
 }
@@ -4,20 +4,32 @@ import (
 	"context"
 	"github.com/Azure/azure-sdk-for-go/profiles/latest/network/mgmt/network"
 	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
 )
 
 type NetworkManager struct {
 	Provider *Provider
 }
 
+//CreateNetworkWithContext context aware version of CreateNetwork
+func (mgr *NetworkManager) CreateNetworkWithContext(ctx context.Context, options api.CreateNetworkOptions) (*api.Network, api.CreateNetworkError) {
+	n, err := mgr.createNetwork(ctx, options)
+	return n, api.NewCreateNetworkError(UnwrapAzureError(err), options)
+}
+
 func (mgr *NetworkManager) CreateNetwork(options api.CreateNetworkOptions) (*api.Network, api.CreateNetworkError) {
-	n, err := mgr.createNetwork(options)
-	return n, api.NewCreateNetworkError(err, options)
+	return mgr.CreateNetworkWithContext(context.Background(), options)
 }
 
-func (mgr *NetworkManager) createNetwork(options api.CreateNetworkOptions) (*api.Network, error) {
-	future, err := mgr.Provider.BaseServices.VirtualNetworksClient.CreateOrUpdate(context.Background(), mgr.resourceGroup(), options.Name, network.VirtualNetwork{
+func (mgr *NetworkManager) createNetwork(ctx context.Context, options api.CreateNetworkOptions) (*api.Network, error) {
+	name := options.Name
+	if name == "" {
+		name = uuid.New().String()
+	}
+	future, err := mgr.Provider.BaseServices.VirtualNetworksClient.CreateOrUpdate(ctx, mgr.resourceGroup(), name, network.VirtualNetwork{
 		Location: &mgr.Provider.Configuration.Location,
+		Tags:     azureTags(options.Tags, nil),
 		VirtualNetworkPropertiesFormat: &network.VirtualNetworkPropertiesFormat{
 			AddressSpace: &network.AddressSpace{
 				AddressPrefixes: &[]string{options.CIDR},
@@ -27,7 +39,7 @@ func (mgr *NetworkManager) createNetwork(options api.CreateNetworkOptions) (*api
 	if err != nil {
 		return nil, err
 	}
-	err = future.WaitForCompletionRef(context.Background(), mgr.Provider.BaseServices.VirtualNetworksClient.Client)
+	err = future.WaitForCompletionRef(ctx, mgr.Provider.BaseServices.VirtualNetworksClient.Client)
 	if err != nil {
 		return nil, err
 	}
@@ -40,26 +52,33 @@ func (mgr *NetworkManager) createNetwork(options api.CreateNetworkOptions) (*api
 		ID:   *n.Name,
 		Name: *n.Name,
 		CIDR: (*n.VirtualNetworkPropertiesFormat.AddressSpace.AddressPrefixes)[0],
+		Tags: userTags(n.Tags),
 	}, nil
 }
 
-func (mgr *NetworkManager) DeleteNetwork(id string) api.DeleteNetworkError {
-	future, err := mgr.Provider.BaseServices.VirtualNetworksClient.Delete(context.Background(), mgr.resourceGroup(), id)
+//DeleteNetworkWithContext context aware version of DeleteNetwork
+func (mgr *NetworkManager) DeleteNetworkWithContext(ctx context.Context, id string) api.DeleteNetworkError {
+	future, err := mgr.Provider.BaseServices.VirtualNetworksClient.Delete(ctx, mgr.resourceGroup(), id)
 	if err != nil {
-		return api.NewDeleteNetworkError(err, id)
+		return api.NewDeleteNetworkError(UnwrapAzureError(err), id)
 	}
-	err = future.WaitForCompletionRef(context.Background(), mgr.Provider.BaseServices.VirtualNetworksClient.Client)
-	return api.NewDeleteNetworkError(err, id)
+	err = future.WaitForCompletionRef(ctx, mgr.Provider.BaseServices.VirtualNetworksClient.Client)
+	return api.NewDeleteNetworkError(UnwrapAzureError(err), id)
+}
+
+func (mgr *NetworkManager) DeleteNetwork(id string) api.DeleteNetworkError {
+	return mgr.DeleteNetworkWithContext(context.Background(), id)
 }
 
 func (mgr *NetworkManager) resourceGroup() string {
 	return mgr.Provider.Configuration.ResourceGroupName
 }
 
-func (mgr *NetworkManager) ListNetworks() ([]api.Network, api.ListNetworksError) {
-	list, err := mgr.Provider.BaseServices.VirtualNetworksClient.List(context.Background(), mgr.resourceGroup())
+//ListNetworksWithContext context aware version of ListNetworks
+func (mgr *NetworkManager) ListNetworksWithContext(ctx context.Context) ([]api.Network, api.ListNetworksError) {
+	list, err := mgr.Provider.BaseServices.VirtualNetworksClient.List(ctx, mgr.resourceGroup())
 	if err != nil {
-		return nil, api.NewListNetworksError(err)
+		return nil, api.NewListNetworksError(UnwrapAzureError(err))
 	}
 	var nets []api.Network
 	for _, n := range list.Values() {
@@ -67,39 +86,55 @@ func (mgr *NetworkManager) ListNetworks() ([]api.Network, api.ListNetworksError)
 			ID:   *n.Name,
 			Name: *n.Name,
 			CIDR: (*n.VirtualNetworkPropertiesFormat.AddressSpace.AddressPrefixes)[0],
+			Tags: userTags(n.Tags),
 		})
 	}
 	return nets, nil
 }
 
-func (mgr *NetworkManager) GetNetwork(id string) (*api.Network, api.GetNetworkError) {
-	n, err := mgr.Provider.BaseServices.VirtualNetworksClient.Get(context.Background(), mgr.resourceGroup(), id, "")
+func (mgr *NetworkManager) ListNetworks() ([]api.Network, api.ListNetworksError) {
+	return mgr.ListNetworksWithContext(context.Background())
+}
+
+//GetNetworkWithContext context aware version of GetNetwork
+func (mgr *NetworkManager) GetNetworkWithContext(ctx context.Context, id string) (*api.Network, api.GetNetworkError) {
+	n, err := mgr.Provider.BaseServices.VirtualNetworksClient.Get(ctx, mgr.resourceGroup(), id, "")
 	if err != nil {
-		return nil, api.NewGetNetworkError(err, id)
+		return nil, api.NewGetNetworkError(UnwrapAzureError(err), id)
 	}
 	return &api.Network{
 		ID:   *n.Name,
 		Name: *n.Name,
-		CIDR: *n.Tags["cidr"],
+		CIDR: (*n.VirtualNetworkPropertiesFormat.AddressSpace.AddressPrefixes)[0],
+		Tags: userTags(n.Tags),
 	}, nil
 }
 
-func (mgr *NetworkManager) CreateSubnet(options api.CreateSubnetOptions) (*api.Subnet, api.CreateSubnetError) {
-	future, err := mgr.Provider.BaseServices.SubnetsClient.CreateOrUpdate(context.Background(), mgr.resourceGroup(), options.NetworkID, options.Name, network.Subnet{
+func (mgr *NetworkManager) GetNetwork(id string) (*api.Network, api.GetNetworkError) {
+	return mgr.GetNetworkWithContext(context.Background(), id)
+}
+
+//CreateSubnetWithContext context aware version of CreateSubnet
+func (mgr *NetworkManager) CreateSubnetWithContext(ctx context.Context, options api.CreateSubnetOptions) (*api.Subnet, api.CreateSubnetError) {
+	if len(options.Tags) > 0 {
+		err := api.WithKind(errors.Errorf("azure subnets cannot be tagged"), api.ErrInvalidArgument)
+		return nil, api.NewCreateSubnetError(err, options)
+	}
+	future, err := mgr.Provider.BaseServices.SubnetsClient.CreateOrUpdate(ctx, mgr.resourceGroup(), options.NetworkID, options.Name, network.Subnet{
 		SubnetPropertiesFormat: &network.SubnetPropertiesFormat{
 			AddressPrefix: &options.CIDR,
 		},
 	})
 	if err != nil {
-		return nil, api.NewCreateSubnetError(err, options)
+		return nil, api.NewCreateSubnetError(UnwrapAzureError(err), options)
 	}
-	err = future.WaitForCompletionRef(context.Background(), mgr.Provider.BaseServices.SubnetsClient.Client)
+	err = future.WaitForCompletionRef(ctx, mgr.Provider.BaseServices.SubnetsClient.Client)
 	if err != nil {
-		return nil, api.NewCreateSubnetError(err, options)
+		return nil, api.NewCreateSubnetError(UnwrapAzureError(err), options)
 	}
 	sn, err := future.Result(mgr.Provider.BaseServices.SubnetsClient)
 	if err != nil {
-		return nil, api.NewCreateSubnetError(err, options)
+		return nil, api.NewCreateSubnetError(UnwrapAzureError(err), options)
 	}
 	return &api.Subnet{
 		ID:        *sn.Name,
@@ -110,24 +145,37 @@ func (mgr *NetworkManager) CreateSubnet(options api.CreateSubnetOptions) (*api.S
 	}, nil
 }
 
-func (mgr *NetworkManager) DeleteSubnet(networkID, subnetID string) api.DeleteSubnetError {
-	future, err := mgr.Provider.BaseServices.SubnetsClient.Delete(context.Background(), mgr.resourceGroup(), networkID, subnetID)
+func (mgr *NetworkManager) CreateSubnet(options api.CreateSubnetOptions) (*api.Subnet, api.CreateSubnetError) {
+	return mgr.CreateSubnetWithContext(context.Background(), options)
+}
+
+//DeleteSubnetWithContext context aware version of DeleteSubnet
+func (mgr *NetworkManager) DeleteSubnetWithContext(ctx context.Context, networkID, subnetID string) api.DeleteSubnetError {
+	future, err := mgr.Provider.BaseServices.SubnetsClient.Delete(ctx, mgr.resourceGroup(), networkID, subnetID)
 	if err != nil {
-		return api.NewDeleteSubnetError(err, networkID, subnetID)
+		return api.NewDeleteSubnetError(UnwrapAzureError(err), networkID, subnetID)
 	}
-	err = future.WaitForCompletionRef(context.Background(), mgr.Provider.BaseServices.SubnetsClient.Client)
-	return api.NewDeleteSubnetError(err, networkID, subnetID)
+	err = future.WaitForCompletionRef(ctx, mgr.Provider.BaseServices.SubnetsClient.Client)
+	return api.NewDeleteSubnetError(UnwrapAzureError(err), networkID, subnetID)
 }
 
-func (mgr *NetworkManager) ListSubnets(networkID string) ([]api.Subnet, api.ListSubnetsError) {
-	n, err := mgr.Provider.BaseServices.VirtualNetworksClient.Get(context.Background(), mgr.resourceGroup(), networkID, "")
+func (mgr *NetworkManager) DeleteSubnet(networkID, subnetID string) api.DeleteSubnetError {
+	return mgr.DeleteSubnetWithContext(context.Background(), networkID, subnetID)
+}
+
+//ListSubnetsWithContext context aware version of ListSubnets
+func (mgr *NetworkManager) ListSubnetsWithContext(ctx context.Context, networkID string) ([]api.Subnet, api.ListSubnetsError) {
+	n, err := mgr.Provider.BaseServices.VirtualNetworksClient.Get(ctx, mgr.resourceGroup(), networkID, "")
 	if err != nil {
-		return nil, api.NewListSubnetsError(err, networkID)
+		return nil, api.NewListSubnetsError(UnwrapAzureError(err), networkID)
 	}
 	var subnets []api.Subnet
+	if n.Subnets == nil {
+		return subnets, nil
+	}
 	for _, sn := range *n.Subnets {
 		subnets = append(subnets, api.Subnet{
-			ID:        *n.Name,
+			ID:        *sn.Name,
 			NetworkID: networkID,
 			Name:      *sn.Name,
 			CIDR:      *sn.SubnetPropertiesFormat.AddressPrefix,
@@ -137,10 +185,15 @@ func (mgr *NetworkManager) ListSubnets(networkID string) ([]api.Subnet, api.List
 	return subnets, nil
 }
 
-func (mgr *NetworkManager) GetSubnet(networkID, subnetID string) (*api.Subnet, api.GetSubnetError) {
-	sn, err := mgr.Provider.BaseServices.SubnetsClient.Get(context.Background(), mgr.resourceGroup(), networkID, subnetID, "")
+func (mgr *NetworkManager) ListSubnets(networkID string) ([]api.Subnet, api.ListSubnetsError) {
+	return mgr.ListSubnetsWithContext(context.Background(), networkID)
+}
+
+//GetSubnetWithContext context aware version of GetSubnet
+func (mgr *NetworkManager) GetSubnetWithContext(ctx context.Context, networkID, subnetID string) (*api.Subnet, api.GetSubnetError) {
+	sn, err := mgr.Provider.BaseServices.SubnetsClient.Get(ctx, mgr.resourceGroup(), networkID, subnetID, "")
 	if err != nil {
-		return nil, api.NewGetSubnetError(err, networkID, subnetID)
+		return nil, api.NewGetSubnetError(UnwrapAzureError(err), networkID, subnetID)
 	}
 	return &api.Subnet{
 		ID:        *sn.Name,
@@ -150,3 +203,7 @@ func (mgr *NetworkManager) GetSubnet(networkID, subnetID string) (*api.Subnet, a
 		IPVersion: api.IPVersion4,
 	}, nil
 }
+
+func (mgr *NetworkManager) GetSubnet(networkID, subnetID string) (*api.Subnet, api.GetSubnetError) {
+	return mgr.GetSubnetWithContext(context.Background(), networkID, subnetID)
+}
@@ -0,0 +1,423 @@
+package azure
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/storage"
+	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/pkg/errors"
+)
+
+//ObjectStorageManager azure implementation of api.ObjectStorageManager using the blob service of the storage account of the configuration
+//Buckets are blob containers identified by their name, objects are block blobs
+type ObjectStorageManager struct {
+	Provider *Provider
+}
+
+//blockSize size of the blocks of the blobs uploaded in several requests
+const blockSize = 4 << 20
+
+//pathStyleSender sends the requests of a storage client to an endpoint addressing the storage account in path style
+//(<endpoint>/<account>/<container>/<blob>) instead of the <account>.blob.core.windows.net host
+type pathStyleSender struct {
+	endpoint *url.URL
+	account  string
+	sender   storage.Sender
+}
+
+func (s *pathStyleSender) rewrite(u *url.URL) {
+	prefix := strings.TrimSuffix(s.endpoint.Path, "/") + "/" + s.account
+	u.Scheme, u.Host = s.endpoint.Scheme, s.endpoint.Host
+	u.Path = prefix + u.Path
+	if u.RawPath != "" {
+		u.RawPath = prefix + u.RawPath
+	}
+}
+
+func (s *pathStyleSender) Send(c *storage.Client, req *http.Request) (*http.Response, error) {
+	s.rewrite(req.URL)
+	req.Host = req.URL.Host
+	return s.sender.Send(c, req)
+}
+
+//contextSender sends the requests of a storage client with a context, the storage client does not support contexts
+type contextSender struct {
+	ctx    context.Context
+	sender storage.Sender
+}
+
+func (s *contextSender) Send(c *storage.Client, req *http.Request) (*http.Response, error) {
+	return s.sender.Send(c, req.WithContext(s.ctx))
+}
+
+//service returns a client of the blob service sending its requests with ctx
+func (mgr *ObjectStorageManager) service(ctx context.Context) (*storage.BlobStorageClient, error) {
+	client := mgr.Provider.BaseServices.StorageClient
+	if client == nil {
+		return nil, errors.New("the storage account is not configured")
+	}
+	c := *client
+	c.Sender = &contextSender{ctx: ctx, sender: client.Sender}
+	service := c.GetBlobService()
+	return &service, nil
+}
+
+func (mgr *ObjectStorageManager) container(ctx context.Context, name string) (*storage.Container, error) {
+	service, err := mgr.service(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return service.GetContainerReference(name), nil
+}
+
+func (mgr *ObjectStorageManager) blob(ctx context.Context, bucketID, key string) (*storage.Blob, error) {
+	c, err := mgr.container(ctx, bucketID)
+	if err != nil {
+		return nil, err
+	}
+	return c.GetBlobReference(key), nil
+}
+
+func convertBlob(bucketID string, b *storage.Blob) *api.Object {
+	return &api.Object{
+		BucketID:     bucketID,
+		Key:          b.Name,
+		Size:         b.Properties.ContentLength,
+		ContentType:  b.Properties.ContentType,
+		ETag:         strings.Trim(b.Properties.Etag, `"`),
+		LastModified: time.Time(b.Properties.LastModified),
+	}
+}
+
+func (mgr *ObjectStorageManager) createBucket(ctx context.Context, options api.CreateBucketOptions) (*api.Bucket, error) {
+	err := api.CheckBucketOptions(&options)
+	if err != nil {
+		return nil, err
+	}
+	c, err := mgr.container(ctx, options.Name)
+	if err != nil {
+		return nil, err
+	}
+	err = c.Create(nil)
+	if err != nil {
+		return nil, UnwrapAzureError(err)
+	}
+	return &api.Bucket{
+		ID:   options.Name,
+		Name: options.Name,
+	}, nil
+}
+
+//CreateBucketWithContext creates a private blob container
+//The name of a deleted container cannot be used again until the storage service has deleted its blobs
+func (mgr *ObjectStorageManager) CreateBucketWithContext(ctx context.Context, options api.CreateBucketOptions) (*api.Bucket, api.CreateBucketError) {
+	b, err := mgr.createBucket(ctx, options)
+	if err != nil {
+		return nil, api.NewCreateBucketError(err, options)
+	}
+	return b, nil
+}
+
+//CreateBucket creates a private blob container
+//The name of a deleted container cannot be used again until the storage service has deleted its blobs
+func (mgr *ObjectStorageManager) CreateBucket(options api.CreateBucketOptions) (*api.Bucket, api.CreateBucketError) {
+	return mgr.CreateBucketWithContext(context.Background(), options)
+}
+
+func (mgr *ObjectStorageManager) deleteBucket(ctx context.Context, id string) error {
+	c, err := mgr.container(ctx, id)
+	if err != nil {
+		return err
+	}
+	//the blobs are deleted with the container
+	return UnwrapAzureError(c.Delete(nil))
+}
+
+//DeleteBucketWithContext deletes the blob container identified by id and its blobs
+func (mgr *ObjectStorageManager) DeleteBucketWithContext(ctx context.Context, id string) api.DeleteBucketError {
+	return api.NewDeleteBucketError(mgr.deleteBucket(ctx, id), id)
+}
+
+//DeleteBucket deletes the blob container identified by id and its blobs
+func (mgr *ObjectStorageManager) DeleteBucket(id string) api.DeleteBucketError {
+	return mgr.DeleteBucketWithContext(context.Background(), id)
+}
+
+func (mgr *ObjectStorageManager) listBuckets(ctx context.Context) ([]api.Bucket, error) {
+	service, err := mgr.service(ctx)
+	if err != nil {
+		return nil, err
+	}
+	buckets := []api.Bucket{}
+	params := storage.ListContainersParameters{}
+	for {
+		res, err := service.ListContainers(params)
+		if err != nil {
+			return nil, UnwrapAzureError(err)
+		}
+		for _, c := range res.Containers {
+			buckets = append(buckets, api.Bucket{
+				ID:   c.Name,
+				Name: c.Name,
+			})
+		}
+		if res.NextMarker == "" {
+			return buckets, nil
+		}
+		params.Marker = res.NextMarker
+	}
+}
+
+//ListBucketsWithContext lists the blob containers of the storage account
+func (mgr *ObjectStorageManager) ListBucketsWithContext(ctx context.Context) ([]api.Bucket, api.ListBucketsError) {
+	buckets, err := mgr.listBuckets(ctx)
+	if err != nil {
+		return nil, api.NewListBucketsError(err)
+	}
+	return buckets, nil
+}
+
+//ListBuckets lists the blob containers of the storage account
+func (mgr *ObjectStorageManager) ListBuckets() ([]api.Bucket, api.ListBucketsError) {
+	return mgr.ListBucketsWithContext(context.Background())
+}
+
+func (mgr *ObjectStorageManager) getBucket(ctx context.Context, id string) (*api.Bucket, error) {
+	c, err := mgr.container(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	err = c.GetProperties()
+	if err != nil {
+		return nil, UnwrapAzureError(err)
+	}
+	return &api.Bucket{
+		ID:   id,
+		Name: id,
+	}, nil
+}
+
+//GetBucketWithContext returns the blob container identified by id
+func (mgr *ObjectStorageManager) GetBucketWithContext(ctx context.Context, id string) (*api.Bucket, api.GetBucketError) {
+	b, err := mgr.getBucket(ctx, id)
+	if err != nil {
+		return nil, api.NewGetBucketError(err, id)
+	}
+	return b, nil
+}
+
+//GetBucket returns the blob container identified by id
+func (mgr *ObjectStorageManager) GetBucket(id string) (*api.Bucket, api.GetBucketError) {
+	return mgr.GetBucketWithContext(context.Background(), id)
+}
+
+//blockID returns the identifier of the i-th block of a blob, the identifiers of the blocks of a blob must have the same length
+func blockID(i int) string {
+	return base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%08d", i)))
+}
+
+//upload uploads content in a single request if it is smaller than a block, in blocks otherwise
+func upload(b *storage.Blob, content io.Reader) error {
+	buf := make([]byte, blockSize)
+	n, err := io.ReadFull(content, buf)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return b.CreateBlockBlobFromReader(bytes.NewReader(buf[:n]), nil)
+	}
+	if err != nil {
+		return err
+	}
+	var blocks []storage.Block
+	for n > 0 {
+		id := blockID(len(blocks))
+		err = b.PutBlock(id, buf[:n], nil)
+		if err != nil {
+			return err
+		}
+		blocks = append(blocks, storage.Block{ID: id, Status: storage.BlockStatusLatest})
+		n, err = io.ReadFull(content, buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+	}
+	return b.PutBlockList(blocks, nil)
+}
+
+func (mgr *ObjectStorageManager) putObject(ctx context.Context, options api.PutObjectOptions) (*api.Object, error) {
+	err := api.CheckObjectKey(options.Key)
+	if err != nil {
+		return nil, err
+	}
+	b, err := mgr.blob(ctx, options.BucketID, options.Key)
+	if err != nil {
+		return nil, err
+	}
+	b.Properties.ContentType = options.ContentType
+	if b.Properties.ContentType == "" {
+		b.Properties.ContentType = api.DefaultContentType
+	}
+	err = upload(b, options.Content)
+	if err != nil {
+		return nil, UnwrapAzureError(err)
+	}
+	err = b.GetProperties(nil)
+	if err != nil {
+		return nil, UnwrapAzureError(err)
+	}
+	return convertBlob(options.BucketID, b), nil
+}
+
+//PutObjectWithContext creates or replaces a block blob
+func (mgr *ObjectStorageManager) PutObjectWithContext(ctx context.Context, options api.PutObjectOptions) (*api.Object, api.PutObjectError) {
+	o, err := mgr.putObject(ctx, options)
+	if err != nil {
+		return nil, api.NewPutObjectError(err, options)
+	}
+	return o, nil
+}
+
+//PutObject creates or replaces a block blob
+func (mgr *ObjectStorageManager) PutObject(options api.PutObjectOptions) (*api.Object, api.PutObjectError) {
+	return mgr.PutObjectWithContext(context.Background(), options)
+}
+
+func (mgr *ObjectStorageManager) getObject(ctx context.Context, bucketID, key string) (*api.ObjectReader, error) {
+	b, err := mgr.blob(ctx, bucketID, key)
+	if err != nil {
+		return nil, err
+	}
+	r, err := b.Get(nil)
+	if err != nil {
+		if r != nil {
+			_ = r.Close()
+		}
+		return nil, UnwrapAzureError(err)
+	}
+	return &api.ObjectReader{
+		Object:     *convertBlob(bucketID, b),
+		ReadCloser: r,
+	}, nil
+}
+
+//GetObjectWithContext returns a reader streaming the content of a blob
+func (mgr *ObjectStorageManager) GetObjectWithContext(ctx context.Context, bucketID string, key string) (*api.ObjectReader, api.GetObjectError) {
+	r, err := mgr.getObject(ctx, bucketID, key)
+	if err != nil {
+		return nil, api.NewGetObjectError(err, bucketID, key)
+	}
+	return r, nil
+}
+
+//GetObject returns a reader streaming the content of a blob
+func (mgr *ObjectStorageManager) GetObject(bucketID string, key string) (*api.ObjectReader, api.GetObjectError) {
+	return mgr.GetObjectWithContext(context.Background(), bucketID, key)
+}
+
+func (mgr *ObjectStorageManager) listObjects(ctx context.Context, options api.ListObjectsOptions) ([]api.Object, error) {
+	c, err := mgr.container(ctx, options.BucketID)
+	if err != nil {
+		return nil, err
+	}
+	objects := []api.Object{}
+	params := storage.ListBlobsParameters{Prefix: options.Prefix}
+	for {
+		res, err := c.ListBlobs(params)
+		if err != nil {
+			return nil, UnwrapAzureError(err)
+		}
+		for i := range res.Blobs {
+			objects = append(objects, *convertBlob(options.BucketID, &res.Blobs[i]))
+		}
+		if res.NextMarker == "" {
+			return objects, nil
+		}
+		params.Marker = res.NextMarker
+	}
+}
+
+//ListObjectsWithContext lists the blobs of a container sorted by name
+func (mgr *ObjectStorageManager) ListObjectsWithContext(ctx context.Context, options api.ListObjectsOptions) ([]api.Object, api.ListObjectsError) {
+	objects, err := mgr.listObjects(ctx, options)
+	if err != nil {
+		return nil, api.NewListObjectsError(err, options)
+	}
+	return objects, nil
+}
+
+//ListObjects lists the blobs of a container sorted by name
+func (mgr *ObjectStorageManager) ListObjects(options api.ListObjectsOptions) ([]api.Object, api.ListObjectsError) {
+	return mgr.ListObjectsWithContext(context.Background(), options)
+}
+
+func (mgr *ObjectStorageManager) deleteObject(ctx context.Context, bucketID, key string) error {
+	b, err := mgr.blob(ctx, bucketID, key)
+	if err != nil {
+		return err
+	}
+	return UnwrapAzureError(b.Delete(nil))
+}
+
+//DeleteObjectWithContext deletes a blob
+func (mgr *ObjectStorageManager) DeleteObjectWithContext(ctx context.Context, bucketID string, key string) api.DeleteObjectError {
+	return api.NewDeleteObjectError(mgr.deleteObject(ctx, bucketID, key), bucketID, key)
+}
+
+//DeleteObject deletes a blob
+func (mgr *ObjectStorageManager) DeleteObject(bucketID string, key string) api.DeleteObjectError {
+	return mgr.DeleteObjectWithContext(context.Background(), bucketID, key)
+}
+
+func (mgr *ObjectStorageManager) presignURL(ctx context.Context, options api.PresignURLOptions) (string, error) {
+	err := api.CheckPresignURLOptions(&options)
+	if err != nil {
+		return "", err
+	}
+	b, err := mgr.blob(ctx, options.BucketID, options.Key)
+	if err != nil {
+		return "", err
+	}
+	sas := storage.BlobSASOptions{
+		SASOptions: storage.SASOptions{Expiry: time.Now().Add(options.Expiry)},
+	}
+	if options.Method == http.MethodPut {
+		sas.Create, sas.Write = true, true
+	} else {
+		sas.Read = true
+	}
+	uri, err := b.GetSASURI(sas)
+	if err != nil {
+		return "", err
+	}
+	s, ok := mgr.Provider.BaseServices.StorageClient.Sender.(*pathStyleSender)
+	if !ok {
+		return uri, nil
+	}
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+	s.rewrite(u)
+	return u.String(), nil
+}
+
+//PresignURLWithContext returns a shared access signature URL granting the operation defined by options until it expires
+//The URL is signed locally with the key of the storage account, ctx is not used
+func (mgr *ObjectStorageManager) PresignURLWithContext(ctx context.Context, options api.PresignURLOptions) (string, api.PresignURLError) {
+	u, err := mgr.presignURL(ctx, options)
+	if err != nil {
+		return "", api.NewPresignURLError(err, options)
+	}
+	return u, nil
+}
+
+//PresignURL returns a shared access signature URL granting the operation defined by options until it expires
+func (mgr *ObjectStorageManager) PresignURL(options api.PresignURLOptions) (string, api.PresignURLError) {
+	return mgr.PresignURLWithContext(context.Background(), options)
+}
@@ -0,0 +1,21 @@
+package azure_test
+
+import (
+	"testing"
+
+	"github.com/SebastienDorgan/anyclouds/tests"
+	"github.com/stretchr/testify/suite"
+)
+
+type AZObjectStorageManagerTestSuite struct {
+	tests.ObjectStorageManagerTestSuite
+}
+
+//SetupSuite set up object storage manager
+func (suite *AZObjectStorageManagerTestSuite) SetupSuite() {
+	suite.Prov = GetProvider()
+}
+
+func TestAZObjectStorageManagerTestSuite(t *testing.T) {
+	suite.Run(t, new(AZObjectStorageManagerTestSuite))
+}
@@ -2,18 +2,31 @@ package azure
 
 import (
 	"github.com/Azure/azure-sdk-for-go/profiles/latest/compute/mgmt/compute"
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/dns/mgmt/dns"
 	"github.com/Azure/azure-sdk-for-go/profiles/latest/network/mgmt/network"
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/resources/mgmt/subscriptions"
 	"github.com/Azure/azure-sdk-for-go/profiles/preview/preview/commerce/mgmt/commerce"
+	"github.com/Azure/azure-sdk-for-go/storage"
 	"github.com/Azure/go-autorest/autorest"
 	"github.com/Azure/go-autorest/autorest/adal"
+	"github.com/Azure/go-autorest/autorest/azure"
 	"github.com/Azure/go-autorest/autorest/azure/auth"
 	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/SebastienDorgan/anyclouds/providers"
 	"github.com/pkg/errors"
 	"github.com/spf13/viper"
 
 	"io"
+	"net/url"
+	"strings"
 )
 
+func init() {
+	providers.Register("azure", func() api.Provider {
+		return &Provider{}
+	})
+}
+
 type BaseServices struct {
 	Authorizer                 autorest.Authorizer
 	VirtualMachineImagesClient compute.VirtualMachineImagesClient
@@ -25,6 +38,24 @@ type BaseServices struct {
 	InterfacesClient           network.InterfacesClient
 	RateCardClient             commerce.RateCardClient
 	PublicIPAddressesClient    network.PublicIPAddressesClient
+	LoadBalancersClient        network.LoadBalancersClient
+	ZonesClient                dns.ZonesClient
+	RecordSetsClient           dns.RecordSetsClient
+	DisksClient                compute.DisksClient
+	SnapshotsClient            compute.SnapshotsClient
+	ImagesClient               compute.ImagesClient
+	SSHPublicKeysClient        SSHPublicKeysClient
+	//VirtualMachineScaleSetsClient and VirtualMachineScaleSetVMsClient clients of the scale sets backing server groups
+	VirtualMachineScaleSetsClient   compute.VirtualMachineScaleSetsClient
+	VirtualMachineScaleSetVMsClient compute.VirtualMachineScaleSetVMsClient
+	//SubscriptionsClient and ResourceSkusClient clients used to list the locations of the subscription and their availability zones
+	SubscriptionsClient subscriptions.Client
+	ResourceSkusClient  compute.ResourceSkusClient
+	//UsageClient and UsagesClient clients used to read the compute and network limits of a location
+	UsageClient  compute.UsageClient
+	UsagesClient network.UsagesClient
+	//StorageClient client of the storage account, nil if the configuration does not define a storage account
+	StorageClient *storage.Client
 }
 
 type Provider struct {
@@ -38,12 +69,22 @@ type Provider struct {
 	ServerManager            ServerManager
 	NetworkInterfacesManager NetworkInterfacesManager
 	PublicIPAddressManager   PublicIPManager
+	VolumeManager            VolumeManager
+	TagManager               TagManager
+	SnapshotManager          SnapshotManager
+	KeyPairManager           KeyPairManager
+	LoadBalancerManager      LoadBalancerManager
+	DNSManager               DNSManager
+	ObjectStorageManager     ObjectStorageManager
+	ServerGroupManager       ServerGroupManager
+	LocationManager          LocationManager
+	QuotaManager             QuotaManager
 }
 
 type Config struct {
 	TenantID                      string
 	ClientID                      string
-	ClientSecret                  string
+	ClientSecret                  string `redact:"true"`
 	ActiveDirectoryEndpoint       string
 	ResourceManagerEndpoint       string
 	UseDeviceFlow                 bool
@@ -57,6 +98,12 @@ type Config struct {
 	Currency                      string
 	RegionInfo                    string
 	PublicAddressesURL            string
+	//StorageAccountName storage account holding the buckets of the ObjectStorageManager
+	StorageAccountName string
+	StorageAccountKey  string `redact:"true"`
+	//StorageEndpoint blob service endpoint addressing the storage account in path style (e.g. an Azurite emulator),
+	//the blob service of the public cloud is used if it is empty
+	StorageEndpoint string
 }
 
 func (p *Provider) Init(config io.Reader, format string) error {
@@ -72,56 +119,28 @@ func (p *Provider) Init(config io.Reader, format string) error {
 	if err != nil {
 		return errors.Wrap(err, "error initializing azure provider")
 	}
-	p.BaseServices.Authorizer, err = getAuthorizerForResource(&cfg)
-	if err != nil {
-		return errors.Wrap(err, "error initializing azure provider")
-	}
-
-	p.BaseServices.VirtualMachineImagesClient = compute.NewVirtualMachineImagesClient(cfg.SubscriptionID)
-	p.BaseServices.VirtualMachineImagesClient.Authorizer = p.BaseServices.Authorizer
-	err = p.BaseServices.VirtualMachineImagesClient.AddToUserAgent(cfg.UserAgent)
-	if err != nil {
-		return errors.Wrap(err, "error initializing azure provider")
+	if cfg.ActiveDirectoryEndpoint == "" {
+		cfg.ActiveDirectoryEndpoint = azure.PublicCloud.ActiveDirectoryEndpoint
 	}
-
-	p.BaseServices.VirtualMachineSizesClient = compute.NewVirtualMachineSizesClient(cfg.SubscriptionID)
-	p.BaseServices.VirtualMachineSizesClient.Authorizer = p.BaseServices.Authorizer
-	err = p.BaseServices.VirtualMachineSizesClient.AddToUserAgent(cfg.UserAgent)
-	if err != nil {
-		return errors.Wrap(err, "error initializing azure provider")
+	if cfg.ResourceManagerEndpoint == "" {
+		cfg.ResourceManagerEndpoint = azure.PublicCloud.ResourceManagerEndpoint
 	}
+	return p.init(&cfg)
+}
 
-	p.BaseServices.VirtualNetworksClient = network.NewVirtualNetworksClient(cfg.SubscriptionID)
-	p.BaseServices.VirtualNetworksClient.Authorizer = p.BaseServices.Authorizer
-	err = p.BaseServices.VirtualNetworksClient.AddToUserAgent(cfg.UserAgent)
-	if err != nil {
-		return errors.Wrap(err, "error initializing azure provider")
-	}
-	p.BaseServices.SubnetsClient = network.NewSubnetsClient(cfg.SubscriptionID)
-	p.BaseServices.SubnetsClient.Authorizer = p.BaseServices.Authorizer
-	err = p.BaseServices.SubnetsClient.AddToUserAgent(cfg.UserAgent)
-
-	p.BaseServices.SecurityGroupsClient = network.NewSecurityGroupsClient(cfg.SubscriptionID)
-	p.BaseServices.SecurityGroupsClient.Authorizer = p.BaseServices.Authorizer
-	err = p.BaseServices.SecurityGroupsClient.AddToUserAgent(cfg.UserAgent)
-
-	p.BaseServices.VirtualMachinesClient = compute.NewVirtualMachinesClient(cfg.SubscriptionID)
-	p.BaseServices.VirtualMachinesClient.Authorizer = p.BaseServices.Authorizer
-	err = p.BaseServices.VirtualMachinesClient.AddToUserAgent(cfg.UserAgent)
+//init creates the clients and the managers of the provider
+func (p *Provider) init(cfg *Config) error {
+	p.Configuration = *cfg
+	var err error
+	p.BaseServices.Authorizer, err = getAuthorizerForResource(cfg)
 	if err != nil {
 		return errors.Wrap(err, "error initializing azure provider")
 	}
-
-	p.BaseServices.RateCardClient = commerce.NewRateCardClient(cfg.SubscriptionID)
-	p.BaseServices.RateCardClient.Authorizer = p.BaseServices.Authorizer
-	err = p.BaseServices.RateCardClient.AddToUserAgent(cfg.UserAgent)
+	err = p.initClients(cfg)
 	if err != nil {
 		return errors.Wrap(err, "error initializing azure provider")
 	}
-
-	p.BaseServices.PublicIPAddressesClient = network.NewPublicIPAddressesClient(cfg.SubscriptionID)
-	p.BaseServices.PublicIPAddressesClient.Authorizer = p.BaseServices.Authorizer
-	err = p.BaseServices.PublicIPAddressesClient.AddToUserAgent(cfg.UserAgent)
+	err = p.initStorageClient(cfg)
 	if err != nil {
 		return errors.Wrap(err, "error initializing azure provider")
 	}
@@ -133,10 +152,108 @@ func (p *Provider) Init(config io.Reader, format string) error {
 	p.ServerManager = ServerManager{Provider: p}
 	p.NetworkInterfacesManager = NetworkInterfacesManager{Provider: p}
 	p.PublicIPAddressManager = PublicIPManager{Provider: p}
+	p.VolumeManager = VolumeManager{Provider: p}
+	p.TagManager = TagManager{Provider: p}
+	p.SnapshotManager = SnapshotManager{Provider: p}
+	p.KeyPairManager = KeyPairManager{Provider: p}
+	p.LoadBalancerManager = LoadBalancerManager{Provider: p}
+	p.DNSManager = DNSManager{Provider: p}
+	p.ObjectStorageManager = ObjectStorageManager{Provider: p}
+	p.ServerGroupManager = ServerGroupManager{Provider: p}
+	p.LocationManager = LocationManager{Provider: p}
+	p.QuotaManager = QuotaManager{Provider: p}
 
 	return nil
 }
 
+//initClients creates the azure clients targeting the resource manager endpoint of the configuration
+func (p *Provider) initClients(cfg *Config) error {
+	baseURI := cfg.ResourceManagerEndpoint
+	p.BaseServices.VirtualMachineImagesClient = compute.NewVirtualMachineImagesClientWithBaseURI(baseURI, cfg.SubscriptionID)
+	p.BaseServices.VirtualMachineSizesClient = compute.NewVirtualMachineSizesClientWithBaseURI(baseURI, cfg.SubscriptionID)
+	p.BaseServices.VirtualMachinesClient = compute.NewVirtualMachinesClientWithBaseURI(baseURI, cfg.SubscriptionID)
+	p.BaseServices.VirtualMachineScaleSetsClient = compute.NewVirtualMachineScaleSetsClientWithBaseURI(baseURI, cfg.SubscriptionID)
+	p.BaseServices.VirtualMachineScaleSetVMsClient = compute.NewVirtualMachineScaleSetVMsClientWithBaseURI(baseURI, cfg.SubscriptionID)
+	p.BaseServices.DisksClient = compute.NewDisksClientWithBaseURI(baseURI, cfg.SubscriptionID)
+	p.BaseServices.SnapshotsClient = compute.NewSnapshotsClientWithBaseURI(baseURI, cfg.SubscriptionID)
+	p.BaseServices.ImagesClient = compute.NewImagesClientWithBaseURI(baseURI, cfg.SubscriptionID)
+	p.BaseServices.SSHPublicKeysClient = NewSSHPublicKeysClientWithBaseURI(baseURI, cfg.SubscriptionID)
+	p.BaseServices.VirtualNetworksClient = network.NewVirtualNetworksClientWithBaseURI(baseURI, cfg.SubscriptionID)
+	p.BaseServices.SubnetsClient = network.NewSubnetsClientWithBaseURI(baseURI, cfg.SubscriptionID)
+	p.BaseServices.SecurityGroupsClient = network.NewSecurityGroupsClientWithBaseURI(baseURI, cfg.SubscriptionID)
+	p.BaseServices.InterfacesClient = network.NewInterfacesClientWithBaseURI(baseURI, cfg.SubscriptionID)
+	p.BaseServices.PublicIPAddressesClient = network.NewPublicIPAddressesClientWithBaseURI(baseURI, cfg.SubscriptionID)
+	p.BaseServices.LoadBalancersClient = network.NewLoadBalancersClientWithBaseURI(baseURI, cfg.SubscriptionID)
+	p.BaseServices.ZonesClient = dns.NewZonesClientWithBaseURI(baseURI, cfg.SubscriptionID)
+	p.BaseServices.RecordSetsClient = dns.NewRecordSetsClientWithBaseURI(baseURI, cfg.SubscriptionID)
+	p.BaseServices.RateCardClient = commerce.NewRateCardClientWithBaseURI(baseURI, cfg.SubscriptionID)
+	p.BaseServices.SubscriptionsClient = subscriptions.NewClientWithBaseURI(baseURI)
+	p.BaseServices.ResourceSkusClient = compute.NewResourceSkusClientWithBaseURI(baseURI, cfg.SubscriptionID)
+	p.BaseServices.UsageClient = compute.NewUsageClientWithBaseURI(baseURI, cfg.SubscriptionID)
+	p.BaseServices.UsagesClient = network.NewUsagesClientWithBaseURI(baseURI, cfg.SubscriptionID)
+	clients := []*autorest.Client{
+		&p.BaseServices.VirtualMachineImagesClient.Client,
+		&p.BaseServices.VirtualMachineSizesClient.Client,
+		&p.BaseServices.VirtualMachinesClient.Client,
+		&p.BaseServices.VirtualMachineScaleSetsClient.Client,
+		&p.BaseServices.VirtualMachineScaleSetVMsClient.Client,
+		&p.BaseServices.DisksClient.Client,
+		&p.BaseServices.SnapshotsClient.Client,
+		&p.BaseServices.ImagesClient.Client,
+		&p.BaseServices.SSHPublicKeysClient.Client,
+		&p.BaseServices.VirtualNetworksClient.Client,
+		&p.BaseServices.SubnetsClient.Client,
+		&p.BaseServices.SecurityGroupsClient.Client,
+		&p.BaseServices.InterfacesClient.Client,
+		&p.BaseServices.PublicIPAddressesClient.Client,
+		&p.BaseServices.LoadBalancersClient.Client,
+		&p.BaseServices.ZonesClient.Client,
+		&p.BaseServices.RecordSetsClient.Client,
+		&p.BaseServices.RateCardClient.Client,
+		&p.BaseServices.SubscriptionsClient.Client,
+		&p.BaseServices.ResourceSkusClient.Client,
+		&p.BaseServices.UsageClient.Client,
+		&p.BaseServices.UsagesClient.Client,
+	}
+	for _, c := range clients {
+		c.Authorizer = p.BaseServices.Authorizer
+		err := c.AddToUserAgent(cfg.UserAgent)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//initStorageClient creates the client of the storage account of the configuration, if any
+func (p *Provider) initStorageClient(cfg *Config) error {
+	if cfg.StorageAccountName == "" {
+		return nil
+	}
+	client, err := storage.NewClient(cfg.StorageAccountName, cfg.StorageAccountKey, storage.DefaultBaseURL, storage.DefaultAPIVersion, true)
+	if err != nil {
+		return err
+	}
+	if cfg.StorageEndpoint != "" {
+		endpoint, err := url.Parse(cfg.StorageEndpoint)
+		if err != nil {
+			return err
+		}
+		client.Sender = &pathStyleSender{endpoint: endpoint, account: cfg.StorageAccountName, sender: client.Sender}
+	}
+	err = client.AddToUserAgent(cfg.UserAgent)
+	if err != nil {
+		return err
+	}
+	p.BaseServices.StorageClient = &client
+	return nil
+}
+
+//resourceName returns the name of the resource identified by the azure resource identifier id
+func resourceName(id string) string {
+	return id[strings.LastIndex(id, "/")+1:]
+}
+
 func getAuthorizerForResource(config *Config) (autorest.Authorizer, error) {
 	if config.UseDeviceFlow {
 		deviceFlowConfig := auth.NewDeviceFlowConfig(config.ClientID, config.TenantID)
@@ -184,9 +301,45 @@ func (p *Provider) GetNetworkInterfaceManager() api.NetworkInterfaceManager {
 }
 
 func (p *Provider) GetVolumeManager() api.VolumeManager {
-	panic("implement me")
+	return &p.VolumeManager
+}
+
+func (p *Provider) GetTagManager() api.TagManager {
+	return &p.TagManager
+}
+
+func (p *Provider) GetSnapshotManager() api.SnapshotManager {
+	return &p.SnapshotManager
+}
+
+func (p *Provider) GetKeyPairManager() api.KeyPairManager {
+	return &p.KeyPairManager
 }
 
 func (p *Provider) GetPublicIPAddressManager() api.PublicIPManager {
 	return &p.PublicIPAddressManager
 }
+
+func (p *Provider) GetLoadBalancerManager() api.LoadBalancerManager {
+	return &p.LoadBalancerManager
+}
+
+func (p *Provider) GetDNSManager() api.DNSManager {
+	return &p.DNSManager
+}
+
+func (p *Provider) GetObjectStorageManager() api.ObjectStorageManager {
+	return &p.ObjectStorageManager
+}
+
+func (p *Provider) GetServerGroupManager() api.ServerGroupManager {
+	return &p.ServerGroupManager
+}
+
+func (p *Provider) GetLocationManager() api.LocationManager {
+	return &p.LocationManager
+}
+
+func (p *Provider) GetQuotaManager() api.QuotaManager {
+	return &p.QuotaManager
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/SebastienDorgan/anyclouds/iputils"
 	"github.com/pkg/errors"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -58,10 +59,11 @@ func (mgr *PublicIPManager) getPublicAddressPools(url string) ([]AddressPool, er
 	return selection, err
 }
 
-func (mgr *PublicIPManager) ListAvailablePools() ([]api.PublicIPPool, api.ListAvailablePublicIPPoolsError) {
+//ListAvailablePoolsWithContext context aware version of ListAvailablePools
+func (mgr *PublicIPManager) ListAvailablePoolsWithContext(ctx context.Context) ([]api.PublicIPPool, api.ListAvailablePublicIPPoolsError) {
 	addressPools, err := mgr.getPublicAddressPools(mgr.Provider.Configuration.PublicAddressesURL)
 	if err != nil {
-		return nil, api.NewListAvailablePublicIPPoolsError(err)
+		return nil, api.NewListAvailablePublicIPPoolsError(UnwrapAzureError(err))
 	}
 	var pools []api.PublicIPPool
 	for _, p := range addressPools {
@@ -71,7 +73,7 @@ func (mgr *PublicIPManager) ListAvailablePools() ([]api.PublicIPPool, api.ListAv
 		for _, prefix := range p.Properties.AddressPrefixes {
 			addressRange, err := iputils.GetRange(prefix)
 			if err != nil {
-				return nil, api.NewListAvailablePublicIPPoolsError(err)
+				return nil, api.NewListAvailablePublicIPPoolsError(UnwrapAzureError(err))
 			}
 			pool.Ranges = append(pool.Ranges, api.AddressRange{
 				FirstAddress: addressRange.FirstIP.String(),
@@ -83,6 +85,10 @@ func (mgr *PublicIPManager) ListAvailablePools() ([]api.PublicIPPool, api.ListAv
 	return pools, nil
 }
 
+func (mgr *PublicIPManager) ListAvailablePools() ([]api.PublicIPPool, api.ListAvailablePublicIPPoolsError) {
+	return mgr.ListAvailablePoolsWithContext(context.Background())
+}
+
 func checkAddress(addresses []string, address *string) bool {
 	if address == nil {
 		return false
@@ -98,14 +104,15 @@ func checkAddress(addresses []string, address *string) bool {
 	return false
 }
 
-func (mgr *PublicIPManager) List(options *api.ListPublicIPsOptions) ([]api.PublicIP, api.ListPublicIPsError) {
+//ListWithContext context aware version of List
+func (mgr *PublicIPManager) ListWithContext(ctx context.Context, options *api.ListPublicIPsOptions) ([]api.PublicIP, api.ListPublicIPsError) {
 	var addresses []string
 	if options != nil && options.ServerID != nil {
-		nis, err := mgr.Provider.NetworkInterfacesManager.List(&api.ListNetworkInterfacesOptions{
+		nis, err := mgr.Provider.NetworkInterfacesManager.ListWithContext(ctx, &api.ListNetworkInterfacesOptions{
 			ServerID: options.ServerID,
 		})
 		if err != nil {
-			return nil, api.NewListPublicIPsError(err, options)
+			return nil, api.NewListPublicIPsError(UnwrapAzureError(err), options)
 		}
 		for _, ni := range nis {
 			if len(ni.PublicIPAddress) > 0 {
@@ -114,9 +121,9 @@ func (mgr *PublicIPManager) List(options *api.ListPublicIPsOptions) ([]api.Publi
 		}
 
 	}
-	ips, err := mgr.Provider.BaseServices.PublicIPAddressesClient.List(context.Background(), mgr.Provider.Configuration.ResourceGroupName)
+	ips, err := mgr.Provider.BaseServices.PublicIPAddressesClient.List(ctx, mgr.Provider.Configuration.ResourceGroupName)
 	if err != nil {
-		return nil, api.NewListPublicIPsError(err, options)
+		return nil, api.NewListPublicIPsError(UnwrapAzureError(err), options)
 	}
 	var list []api.PublicIP
 	for ips.NotDone() {
@@ -125,26 +132,53 @@ func (mgr *PublicIPManager) List(options *api.ListPublicIPsOptions) ([]api.Publi
 				list = append(list, *convertAddress(&ip))
 			}
 		}
-		err := ips.NextWithContext(context.Background())
+		err := ips.NextWithContext(ctx)
 		if err != nil {
-			return nil, api.NewListPublicIPsError(err, options)
+			return nil, api.NewListPublicIPsError(UnwrapAzureError(err), options)
 		}
 	}
 	return list, nil
 }
 
+func (mgr *PublicIPManager) List(options *api.ListPublicIPsOptions) ([]api.PublicIP, api.ListPublicIPsError) {
+	return mgr.ListWithContext(context.Background(), options)
+}
+
 func convertAddress(address *network.PublicIPAddress) *api.PublicIP {
-	return &api.PublicIP{
-		ID:             *address.Name,
-		Name:           *address.Name,
-		Address:        *address.IPAddress,
-		PrivateAddress: *address.IPConfiguration.PrivateIPAddress,
+	ip := &api.PublicIP{
+		ID:   *address.Name,
+		Name: *address.Name,
+		Tags: userTags(address.Tags),
+	}
+	if address.PublicIPAddressPropertiesFormat == nil {
+		return ip
+	}
+	if address.IPAddress != nil {
+		ip.Address = *address.IPAddress
 	}
+	if ipc := address.IPConfiguration; ipc != nil {
+		if ipc.ID != nil {
+			//ipc.ID is /subscriptions/.../networkInterfaces/{name}/ipConfigurations/{name}
+			tokens := strings.Split(*ipc.ID, "/")
+			if len(tokens) > 3 && strings.EqualFold(tokens[len(tokens)-4], "networkInterfaces") {
+				ip.NetworkInterfaceID = tokens[len(tokens)-3]
+			}
+		}
+		if ipc.IPConfigurationPropertiesFormat != nil && ipc.PrivateIPAddress != nil {
+			ip.PrivateAddress = *ipc.PrivateIPAddress
+		}
+	}
+	return ip
 }
 
-func (mgr *PublicIPManager) Create(options api.CreatePublicIPOptions) (*api.PublicIP, api.CreatePublicIPError) {
+//CreateWithContext context aware version of Create
+func (mgr *PublicIPManager) CreateWithContext(ctx context.Context, options api.CreatePublicIPOptions) (*api.PublicIP, api.CreatePublicIPError) {
+	var prefix *network.SubResource
+	if options.IPAddressPoolID != nil {
+		prefix = &network.SubResource{ID: options.IPAddressPoolID}
+	}
 	future, err := mgr.Provider.BaseServices.PublicIPAddressesClient.CreateOrUpdate(
-		context.Background(),
+		ctx,
 		mgr.Provider.Configuration.ResourceGroupName,
 		options.Name,
 		network.PublicIPAddress{
@@ -156,107 +190,149 @@ func (mgr *PublicIPManager) Create(options api.CreatePublicIPOptions) (*api.Publ
 				PublicIPAllocationMethod: network.Static,
 				PublicIPAddressVersion:   network.IPv4,
 				IPAddress:                options.IPAddress,
-				PublicIPPrefix:           &network.SubResource{ID: options.IPAddressPoolID},
+				PublicIPPrefix:           prefix,
 			},
 			Name:     to.StringPtr(options.Name),
 			Location: to.StringPtr(mgr.Provider.Configuration.Location),
+			Tags:     azureTags(options.Tags, nil),
 		},
 	)
 
 	if err != nil {
-		return nil, api.NewCreatePublicIPError(err, options)
+		return nil, api.NewCreatePublicIPError(UnwrapAzureError(err), options)
 	}
-	err = future.WaitForCompletionRef(context.Background(), mgr.Provider.BaseServices.PublicIPAddressesClient.Client)
+	err = future.WaitForCompletionRef(ctx, mgr.Provider.BaseServices.PublicIPAddressesClient.Client)
 	if err != nil {
-		return nil, api.NewCreatePublicIPError(err, options)
+		return nil, api.NewCreatePublicIPError(UnwrapAzureError(err), options)
 	}
 	ip, err := future.Result(mgr.Provider.BaseServices.PublicIPAddressesClient)
 	if err != nil {
-		return nil, api.NewCreatePublicIPError(err, options)
+		return nil, api.NewCreatePublicIPError(UnwrapAzureError(err), options)
 	}
 
 	return convertAddress(&ip), nil
 }
 
-func (mgr *PublicIPManager) Associate(options api.AssociatePublicIPOptions) api.AssociatePublicIPError {
-	nis, err := mgr.Provider.NetworkInterfacesManager.listAzure(&api.ListNetworkInterfacesOptions{
+func (mgr *PublicIPManager) Create(options api.CreatePublicIPOptions) (*api.PublicIP, api.CreatePublicIPError) {
+	return mgr.CreateWithContext(context.Background(), options)
+}
+
+//AssociateWithContext context aware version of Associate
+func (mgr *PublicIPManager) AssociateWithContext(ctx context.Context, options api.AssociatePublicIPOptions) api.AssociatePublicIPError {
+	nis, err := mgr.Provider.NetworkInterfacesManager.listAzure(ctx, &api.ListNetworkInterfacesOptions{
 		SubnetID: &options.SubnetID,
 		ServerID: &options.ServerID,
 	})
 	if err != nil {
-		return api.NewAssociatePublicIPError(err, options)
+		return api.NewAssociatePublicIPError(UnwrapAzureError(err), options)
 	}
 	var ipConf *network.InterfaceIPConfiguration
 	var niToUpdate *network.Interface
-	for _, ni := range nis {
-		for _, ipc := range *ni.IPConfigurations {
-			if *ipc.PrivateIPAddress == options.PrivateIP {
-				ipConf = &ipc
-				niToUpdate = &ni
+	for i := range nis {
+		ni := &nis[i]
+		for j := range *ni.IPConfigurations {
+			ipc := &(*ni.IPConfigurations)[j]
+			if options.PrivateIP == "" || (ipc.PrivateIPAddress != nil && *ipc.PrivateIPAddress == options.PrivateIP) {
+				ipConf = ipc
+				niToUpdate = ni
+				break
 			}
 		}
+		if ipConf != nil {
+			break
+		}
 	}
 	if ipConf == nil || niToUpdate == nil {
 		err = errors.Errorf("unable to find network interface of server %s using private address %s", options.ServerID, options.PrivateIP)
-		return api.NewAssociatePublicIPError(err, options)
+		return api.NewAssociatePublicIPError(UnwrapAzureError(err), options)
 	}
-	addr, err := mgr.get(options.PublicIPId)
+	addr, err := mgr.get(ctx, options.PublicIPId)
 	if err != nil {
-		return api.NewAssociatePublicIPError(err, options)
+		return api.NewAssociatePublicIPError(UnwrapAzureError(err), options)
 	}
-	ipConf.PublicIPAddress = addr
-	future, err := mgr.Provider.BaseServices.InterfacesClient.CreateOrUpdate(context.Background(), mgr.Provider.Configuration.ResourceGroupName, *niToUpdate.Name, *niToUpdate)
+	ipConf.PublicIPAddress = &network.PublicIPAddress{ID: addr.ID}
+	future, err := mgr.Provider.BaseServices.InterfacesClient.CreateOrUpdate(ctx, mgr.Provider.Configuration.ResourceGroupName, *niToUpdate.Name, *niToUpdate)
 	if err != nil {
-		return api.NewAssociatePublicIPError(err, options)
+		return api.NewAssociatePublicIPError(UnwrapAzureError(err), options)
 	}
-	err = future.WaitForCompletionRef(context.Background(), mgr.Provider.BaseServices.InterfacesClient.Client)
+	err = future.WaitForCompletionRef(ctx, mgr.Provider.BaseServices.InterfacesClient.Client)
 
-	return api.NewAssociatePublicIPError(err, options)
+	return api.NewAssociatePublicIPError(UnwrapAzureError(err), options)
 
 }
 
-func (mgr *PublicIPManager) Dissociate(publicIPId string) api.DissociatePublicIPError {
+func (mgr *PublicIPManager) Associate(options api.AssociatePublicIPOptions) api.AssociatePublicIPError {
+	return mgr.AssociateWithContext(context.Background(), options)
+}
+
+//DissociateWithContext context aware version of Dissociate
+func (mgr *PublicIPManager) DissociateWithContext(ctx context.Context, publicIPId string) api.DissociatePublicIPError {
 	var err error
-	ip, err := mgr.Get(publicIPId)
+	ip, err := mgr.GetWithContext(ctx, publicIPId)
 	if err != nil {
-		return api.NewDissociatePublicIPError(err, publicIPId)
+		return api.NewDissociatePublicIPError(UnwrapAzureError(err), publicIPId)
 	}
 	if len(ip.NetworkInterfaceID) == 0 {
 		return nil
 	}
-	ni, err := mgr.Provider.NetworkInterfacesManager.get(ip.NetworkInterfaceID)
+	ni, err := mgr.Provider.NetworkInterfacesManager.get(ctx, ip.NetworkInterfaceID)
 	if err != nil {
-		return api.NewDissociatePublicIPError(err, publicIPId)
+		return api.NewDissociatePublicIPError(UnwrapAzureError(err), publicIPId)
 	}
 	if ni.IPConfigurations == nil {
 		return nil
 	}
+	addr, err := mgr.get(ctx, publicIPId)
+	if err != nil {
+		return api.NewDissociatePublicIPError(UnwrapAzureError(err), publicIPId)
+	}
 	for i := range *ni.IPConfigurations {
-		ipConf := (*ni.IPConfigurations)[i]
-		if ipConf.PublicIPAddress != nil && *ipConf.PublicIPAddress.Name == ip.Name {
+		ipConf := &(*ni.IPConfigurations)[i]
+		if ipConf.PublicIPAddress != nil && ipConf.PublicIPAddress.ID != nil && strings.EqualFold(*ipConf.PublicIPAddress.ID, *addr.ID) {
 			ipConf.PublicIPAddress = nil
 		}
 	}
-	future, err := mgr.Provider.BaseServices.InterfacesClient.CreateOrUpdate(context.Background(), mgr.Provider.Configuration.ResourceGroupName, *ni.Name, *ni)
+	future, err := mgr.Provider.BaseServices.InterfacesClient.CreateOrUpdate(ctx, mgr.Provider.Configuration.ResourceGroupName, *ni.Name, *ni)
 	if err != nil {
-		return api.NewDissociatePublicIPError(err, publicIPId)
+		return api.NewDissociatePublicIPError(UnwrapAzureError(err), publicIPId)
 	}
-	err = future.WaitForCompletionRef(context.Background(), mgr.Provider.BaseServices.InterfacesClient.Client)
+	err = future.WaitForCompletionRef(ctx, mgr.Provider.BaseServices.InterfacesClient.Client)
 
-	return api.NewDissociatePublicIPError(err, publicIPId)
+	return api.NewDissociatePublicIPError(UnwrapAzureError(err), publicIPId)
+}
+
+func (mgr *PublicIPManager) Dissociate(publicIPId string) api.DissociatePublicIPError {
+	return mgr.DissociateWithContext(context.Background(), publicIPId)
+}
+
+//DeleteWithContext context aware version of Delete
+func (mgr *PublicIPManager) DeleteWithContext(ctx context.Context, publicIPId string) api.DeletePublicIPError {
+	future, err := mgr.Provider.BaseServices.PublicIPAddressesClient.Delete(ctx, mgr.Provider.Configuration.ResourceGroupName, publicIPId)
+	if err != nil {
+		return api.NewDeletePublicIPError(UnwrapAzureError(err), publicIPId)
+	}
+	err = future.WaitForCompletionRef(ctx, mgr.Provider.BaseServices.PublicIPAddressesClient.Client)
+	return api.NewDeletePublicIPError(UnwrapAzureError(err), publicIPId)
 }
 
 func (mgr *PublicIPManager) Delete(publicIPId string) api.DeletePublicIPError {
-	_, err := mgr.Provider.BaseServices.PublicIPAddressesClient.Delete(context.Background(), mgr.Provider.Configuration.ResourceGroupName, publicIPId)
-	return api.NewDeletePublicIPError(err, publicIPId)
+	return mgr.DeleteWithContext(context.Background(), publicIPId)
 }
 
-func (mgr *PublicIPManager) get(publicIPId string) (*network.PublicIPAddress, error) {
-	addr, err := mgr.Provider.BaseServices.PublicIPAddressesClient.Get(context.Background(), mgr.Provider.Configuration.ResourceGroupName, publicIPId, "")
+func (mgr *PublicIPManager) get(ctx context.Context, publicIPId string) (*network.PublicIPAddress, error) {
+	addr, err := mgr.Provider.BaseServices.PublicIPAddressesClient.Get(ctx, mgr.Provider.Configuration.ResourceGroupName, publicIPId, "")
 	return &addr, err
 }
 
+//GetWithContext context aware version of Get
+func (mgr *PublicIPManager) GetWithContext(ctx context.Context, publicIPId string) (*api.PublicIP, api.GetPublicIPError) {
+	ip, err := mgr.get(ctx, publicIPId)
+	if err != nil {
+		return nil, api.NewGetPublicIPError(UnwrapAzureError(err), publicIPId)
+	}
+	return convertAddress(ip), nil
+}
+
 func (mgr *PublicIPManager) Get(publicIPId string) (*api.PublicIP, api.GetPublicIPError) {
-	ip, err := mgr.get(publicIPId)
-	return convertAddress(ip), api.NewGetPublicIPError(err, publicIPId)
+	return mgr.GetWithContext(context.Background(), publicIPId)
 }
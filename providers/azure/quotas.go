@@ -0,0 +1,113 @@
+package azure
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/SebastienDorgan/anyclouds/api"
+)
+
+//QuotaManager azure implementation of api.QuotaManager
+//Limits are the compute and network usages of the location of the provider, RAM and storage size are not limited by azure
+type QuotaManager struct {
+	Provider *Provider
+}
+
+//addUsage adds a usage to l, l is set from the first usage added to it
+func addUsage(l *api.Limit, current, limit int64) {
+	if l.Used == api.UnknownLimit {
+		*l = api.Limit{}
+	}
+	l.Used += int(current)
+	l.Max += int(limit)
+}
+
+//computeLimits sets the limits of limits read from the compute usages of the location
+//The number of volumes is the sum of the disk counts of all the disk types
+func (mgr *QuotaManager) computeLimits(ctx context.Context, limits *api.Limits) error {
+	it, err := mgr.Provider.BaseServices.UsageClient.ListComplete(ctx, mgr.Provider.Configuration.Location)
+	if err != nil {
+		return err
+	}
+	for it.NotDone() {
+		u := it.Value()
+		if u.Name != nil {
+			current, limit := int64(to.Int32(u.CurrentValue)), to.Int64(u.Limit)
+			switch name := to.String(u.Name.Value); {
+			case name == "cores":
+				addUsage(&limits.Cores, current, limit)
+			case name == "virtualMachines":
+				addUsage(&limits.Instances, current, limit)
+			case strings.HasSuffix(name, "DiskCount"):
+				addUsage(&limits.Volumes, current, limit)
+			}
+		}
+		err = it.NextWithContext(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//networkLimits sets the limits of limits read from the network usages of the location
+func (mgr *QuotaManager) networkLimits(ctx context.Context, limits *api.Limits) error {
+	it, err := mgr.Provider.BaseServices.UsagesClient.ListComplete(ctx, mgr.Provider.Configuration.Location)
+	if err != nil {
+		return err
+	}
+	for it.NotDone() {
+		u := it.Value()
+		if u.Name != nil {
+			current, limit := to.Int64(u.CurrentValue), to.Int64(u.Limit)
+			switch to.String(u.Name.Value) {
+			case "PublicIPAddresses":
+				addUsage(&limits.PublicIPs, current, limit)
+			case "NetworkSecurityGroups":
+				addUsage(&limits.SecurityGroups, current, limit)
+			}
+		}
+		err = it.NextWithContext(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (mgr *QuotaManager) getLimits(ctx context.Context) (*api.Limits, error) {
+	unknown := api.Limit{Max: api.UnknownLimit, Used: api.UnknownLimit}
+	limits := &api.Limits{
+		Instances:      unknown,
+		Cores:          unknown,
+		RAMSize:        unknown,
+		Volumes:        unknown,
+		StorageSize:    unknown,
+		PublicIPs:      unknown,
+		SecurityGroups: unknown,
+	}
+	err := mgr.computeLimits(ctx, limits)
+	if err != nil {
+		return nil, err
+	}
+	err = mgr.networkLimits(ctx, limits)
+	if err != nil {
+		return nil, err
+	}
+	return limits, nil
+}
+
+//GetLimitsWithContext returns the limits and the current usage of the subscription in the location of the provider
+func (mgr *QuotaManager) GetLimitsWithContext(ctx context.Context) (*api.Limits, api.GetLimitsError) {
+	limits, err := mgr.getLimits(ctx)
+	if err != nil {
+		return nil, api.NewGetLimitsError(UnwrapAzureError(err))
+	}
+	return limits, nil
+}
+
+//GetLimits returns the limits and the current usage of the subscription in the location of the provider
+func (mgr *QuotaManager) GetLimits() (*api.Limits, api.GetLimitsError) {
+	return mgr.GetLimitsWithContext(context.Background())
+}
@@ -0,0 +1,21 @@
+package azure_test
+
+import (
+	"testing"
+
+	"github.com/SebastienDorgan/anyclouds/tests"
+	"github.com/stretchr/testify/suite"
+)
+
+type AZQuotaManagerTestSuite struct {
+	tests.QuotaManagerTestSuite
+}
+
+//SetupSuite set up quota manager
+func (suite *AZQuotaManagerTestSuite) SetupSuite() {
+	suite.Prov = GetProvider()
+}
+
+func TestAZQuotaManagerTestSuite(t *testing.T) {
+	suite.Run(t, new(AZQuotaManagerTestSuite))
+}
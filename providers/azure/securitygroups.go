@@ -21,39 +21,49 @@ func (mgr *SecurityGroupManager) resourceGroup() string {
 	return mgr.Provider.Configuration.ResourceGroupName
 }
 
-func (mgr *SecurityGroupManager) Create(options api.SecurityGroupOptions) (*api.SecurityGroup, api.CreateSecurityGroupError) {
-	tags := make(map[string]*string, 1)
-	tags["networkID"] = &options.NetworkID
-	future, err := mgr.Provider.BaseServices.SecurityGroupsClient.CreateOrUpdate(context.Background(), mgr.resourceGroup(), options.Name, network.SecurityGroup{
+//CreateWithContext context aware version of Create
+func (mgr *SecurityGroupManager) CreateWithContext(ctx context.Context, options api.SecurityGroupOptions) (*api.SecurityGroup, api.CreateSecurityGroupError) {
+	tags := azureTags(options.Tags, map[string]*string{"networkID": &options.NetworkID})
+	future, err := mgr.Provider.BaseServices.SecurityGroupsClient.CreateOrUpdate(ctx, mgr.resourceGroup(), options.Name, network.SecurityGroup{
 		Location: &mgr.Provider.Configuration.Location,
 		Tags:     tags,
 	})
 	if err != nil {
-		return nil, api.NewCreateSecurityGroupError(err, options)
+		return nil, api.NewCreateSecurityGroupError(UnwrapAzureError(err), options)
 	}
-	err = future.WaitForCompletionRef(context.Background(), mgr.Provider.BaseServices.SecurityGroupsClient.Client)
+	err = future.WaitForCompletionRef(ctx, mgr.Provider.BaseServices.SecurityGroupsClient.Client)
 	if err != nil {
-		return nil, api.NewCreateSecurityGroupError(err, options)
+		return nil, api.NewCreateSecurityGroupError(UnwrapAzureError(err), options)
 	}
 	sg, err := future.Result(mgr.Provider.BaseServices.SecurityGroupsClient)
 	if err != nil {
-		return nil, api.NewCreateSecurityGroupError(err, options)
+		return nil, api.NewCreateSecurityGroupError(UnwrapAzureError(err), options)
 	}
 	return &api.SecurityGroup{
 		ID:        *sg.Name,
 		Name:      *sg.Name,
 		NetworkID: *sg.Tags["networkID"],
 		Rules:     nil,
+		Tags:      userTags(sg.Tags),
 	}, nil
 }
 
-func (mgr *SecurityGroupManager) Delete(id string) api.DeleteSecurityGroupError {
-	future, err := mgr.Provider.BaseServices.SecurityGroupsClient.Delete(context.Background(), mgr.resourceGroup(), id)
+func (mgr *SecurityGroupManager) Create(options api.SecurityGroupOptions) (*api.SecurityGroup, api.CreateSecurityGroupError) {
+	return mgr.CreateWithContext(context.Background(), options)
+}
+
+//DeleteWithContext context aware version of Delete
+func (mgr *SecurityGroupManager) DeleteWithContext(ctx context.Context, id string) api.DeleteSecurityGroupError {
+	future, err := mgr.Provider.BaseServices.SecurityGroupsClient.Delete(ctx, mgr.resourceGroup(), id)
 	if err != nil {
-		return api.NewDeleteSecurityGroupError(err, id)
+		return api.NewDeleteSecurityGroupError(UnwrapAzureError(err), id)
 	}
-	err = future.WaitForCompletionRef(context.Background(), mgr.Provider.BaseServices.SecurityGroupsClient.Client)
-	return api.NewDeleteSecurityGroupError(err, id)
+	err = future.WaitForCompletionRef(ctx, mgr.Provider.BaseServices.SecurityGroupsClient.Client)
+	return api.NewDeleteSecurityGroupError(UnwrapAzureError(err), id)
+}
+
+func (mgr *SecurityGroupManager) Delete(id string) api.DeleteSecurityGroupError {
+	return mgr.DeleteWithContext(context.Background(), id)
 }
 
 func convertDirection(direction network.SecurityRuleDirection) api.RuleDirection {
@@ -68,7 +78,7 @@ func convertProtocol(protocol network.SecurityRuleProtocol) api.Protocol {
 		return api.ProtocolTCP
 	}
 	if protocol == network.SecurityRuleProtocolUDP {
-		return api.ProtocolTCP
+		return api.ProtocolUDP
 	}
 	if protocol == network.SecurityRuleProtocolIcmp {
 		return api.ProtocolICMP
@@ -112,19 +122,34 @@ func convertPortRange(sourcePortRange *string) *api.PortRange {
 	}
 }
 
+//convertCIDR converts an azure address prefix, any address is represented by an empty CIDR
+func convertCIDR(prefix *string) string {
+	if prefix == nil || *prefix == "*" {
+		return ""
+	}
+	return *prefix
+}
+
 func convertRule(r *network.SecurityRule, sgIg string) *api.SecurityRule {
 	if r == nil {
 		return nil
 	}
-
+	cidr := convertCIDR(r.SourceAddressPrefix)
+	if r.Direction == network.SecurityRuleDirectionOutbound {
+		cidr = convertCIDR(r.DestinationAddressPrefix)
+	}
+	var description string
+	if r.Description != nil {
+		description = *r.Description
+	}
 	return &api.SecurityRule{
-		ID:              *r.ID,
+		ID:              *r.Name,
 		SecurityGroupID: sgIg,
 		Direction:       convertDirection(r.Direction),
 		PortRange:       *convertPortRange(r.DestinationPortRange),
 		Protocol:        convertProtocol(r.Protocol),
-		CIDR:            *r.DestinationAddressPrefix,
-		Description:     *r.Description,
+		CIDR:            cidr,
+		Description:     description,
 	}
 }
 
@@ -139,10 +164,11 @@ func extractRules(sg *network.SecurityGroup) []api.SecurityRule {
 	return rules
 }
 
-func (mgr *SecurityGroupManager) List() ([]api.SecurityGroup, api.ListSecurityGroupsError) {
-	res, err := mgr.Provider.BaseServices.SecurityGroupsClient.List(context.Background(), mgr.resourceGroup())
+//ListWithContext context aware version of List
+func (mgr *SecurityGroupManager) ListWithContext(ctx context.Context) ([]api.SecurityGroup, api.ListSecurityGroupsError) {
+	res, err := mgr.Provider.BaseServices.SecurityGroupsClient.List(ctx, mgr.resourceGroup())
 	if err != nil {
-		return nil, api.NewListSecurityGroupsError(err)
+		return nil, api.NewListSecurityGroupsError(UnwrapAzureError(err))
 	}
 	var sgs []api.SecurityGroup
 	for _, sg := range res.Values() {
@@ -152,15 +178,21 @@ func (mgr *SecurityGroupManager) List() ([]api.SecurityGroup, api.ListSecurityGr
 			Name:      *sg.Name,
 			NetworkID: *sg.Tags["networkID"],
 			Rules:     extractRules(&sg),
+			Tags:      userTags(sg.Tags),
 		})
 	}
 	return sgs, nil
 }
 
-func (mgr *SecurityGroupManager) Get(id string) (*api.SecurityGroup, api.GetSecurityGroupError) {
-	sg, err := mgr.Provider.BaseServices.SecurityGroupsClient.Get(context.Background(), mgr.resourceGroup(), id, "")
+func (mgr *SecurityGroupManager) List() ([]api.SecurityGroup, api.ListSecurityGroupsError) {
+	return mgr.ListWithContext(context.Background())
+}
+
+//GetWithContext context aware version of Get
+func (mgr *SecurityGroupManager) GetWithContext(ctx context.Context, id string) (*api.SecurityGroup, api.GetSecurityGroupError) {
+	sg, err := mgr.Provider.BaseServices.SecurityGroupsClient.Get(ctx, mgr.resourceGroup(), id, "")
 	if err != nil {
-		return nil, api.NewGetSecurityGroupError(err, id)
+		return nil, api.NewGetSecurityGroupError(UnwrapAzureError(err), id)
 	}
 
 	return &api.SecurityGroup{
@@ -168,32 +200,38 @@ func (mgr *SecurityGroupManager) Get(id string) (*api.SecurityGroup, api.GetSecu
 		Name:      *sg.Name,
 		NetworkID: *sg.Tags["networkID"],
 		Rules:     extractRules(&sg),
+		Tags:      userTags(sg.Tags),
 	}, nil
 }
 
-func (mgr *SecurityGroupManager) Attach(options api.AttachSecurityGroupOptions) api.AttachSecurityGroupError {
-	sg, err := mgr.Provider.BaseServices.SecurityGroupsClient.Get(context.Background(), mgr.resourceGroup(), options.SecurityGroupID, "")
+func (mgr *SecurityGroupManager) Get(id string) (*api.SecurityGroup, api.GetSecurityGroupError) {
+	return mgr.GetWithContext(context.Background(), id)
+}
+
+//AttachWithContext context aware version of Attach
+func (mgr *SecurityGroupManager) AttachWithContext(ctx context.Context, options api.AttachSecurityGroupOptions) api.AttachSecurityGroupError {
+	sg, err := mgr.Provider.BaseServices.SecurityGroupsClient.Get(ctx, mgr.resourceGroup(), options.SecurityGroupID, "")
 	if err != nil {
-		return api.NewAttachSecurityGroupError(err, options)
+		return api.NewAttachSecurityGroupError(UnwrapAzureError(err), options)
 	}
-	srv, err := mgr.Provider.ServerManager.get(options.ServerID)
+	srv, err := mgr.Provider.ServerManager.get(ctx, options.ServerID)
 	if err != nil {
-		return api.NewAttachSecurityGroupError(err, options)
+		return api.NewAttachSecurityGroupError(UnwrapAzureError(err), options)
 	}
 	if srv.NetworkProfile == nil || srv.NetworkProfile.NetworkInterfaces == nil || len(*srv.NetworkProfile.NetworkInterfaces) == 0 {
 		err = errors.Errorf("network interface not found")
-		return api.NewAttachSecurityGroupError(err, options)
+		return api.NewAttachSecurityGroupError(UnwrapAzureError(err), options)
 	}
 	done := false
 	for _, nir := range *srv.NetworkProfile.NetworkInterfaces {
-		ni, err := mgr.Provider.BaseServices.InterfacesClient.Get(context.Background(), mgr.resourceGroup(), *nir.ID, "")
+		ni, err := mgr.Provider.BaseServices.InterfacesClient.Get(ctx, mgr.resourceGroup(), resourceName(*nir.ID), "")
 		if err != nil {
-			return api.NewAttachSecurityGroupError(err, options)
+			return api.NewAttachSecurityGroupError(UnwrapAzureError(err), options)
 		}
 		impacted := false
 		for _, ipc := range *ni.IPConfigurations {
-			if ipc.Subnet != nil && *ipc.Subnet.Name == options.SubnetID &&
-				options.IPAddress == nil || (*ipc.PrivateIPAddress == *options.IPAddress) {
+			if ipc.Subnet != nil && resourceName(*ipc.Subnet.ID) == options.SubnetID &&
+				(options.IPAddress == nil || (ipc.PrivateIPAddress != nil && *ipc.PrivateIPAddress == *options.IPAddress)) {
 				impacted = true
 				break
 			}
@@ -202,23 +240,27 @@ func (mgr *SecurityGroupManager) Attach(options api.AttachSecurityGroupOptions)
 			continue
 		}
 		done = true
-		ni.NetworkSecurityGroup = &sg
-		future, err := mgr.Provider.BaseServices.InterfacesClient.CreateOrUpdate(context.Background(), *ni.Name, mgr.resourceGroup(), ni)
+		ni.NetworkSecurityGroup = &network.SecurityGroup{ID: sg.ID}
+		future, err := mgr.Provider.BaseServices.InterfacesClient.CreateOrUpdate(ctx, mgr.resourceGroup(), *ni.Name, ni)
 		if err != nil {
-			return api.NewAttachSecurityGroupError(err, options)
+			return api.NewAttachSecurityGroupError(UnwrapAzureError(err), options)
 		}
-		err = future.WaitForCompletionRef(context.Background(), mgr.Provider.BaseServices.InterfacesClient.Client)
+		err = future.WaitForCompletionRef(ctx, mgr.Provider.BaseServices.InterfacesClient.Client)
 		if err != nil {
-			return api.NewAttachSecurityGroupError(err, options)
+			return api.NewAttachSecurityGroupError(UnwrapAzureError(err), options)
 		}
 	}
 	if !done {
 		err = errors.Errorf("network interface not found")
-		return api.NewAttachSecurityGroupError(err, options)
+		return api.NewAttachSecurityGroupError(UnwrapAzureError(err), options)
 	}
 	return nil
 }
 
+func (mgr *SecurityGroupManager) Attach(options api.AttachSecurityGroupOptions) api.AttachSecurityGroupError {
+	return mgr.AttachWithContext(context.Background(), options)
+}
+
 func convertAzProtocol(protocol api.Protocol) network.SecurityRuleProtocol {
 	if protocol == api.ProtocolTCP {
 		return network.SecurityRuleProtocolTCP
@@ -233,67 +275,103 @@ func convertAzProtocol(protocol api.Protocol) network.SecurityRuleProtocol {
 	return network.SecurityRuleProtocolAsterisk
 
 }
+
 func convertAzDirection(direction api.RuleDirection) network.SecurityRuleDirection {
 	if direction == api.RuleDirectionEgress {
 		return network.SecurityRuleDirectionOutbound
 	}
 	return network.SecurityRuleDirectionInbound
 }
+
 func convertAzPortRange(portRange api.PortRange) *string {
 	return to.StringPtr(fmt.Sprintf("%d-%d", portRange.From, portRange.To))
 }
 
-func azSecurityRule(rule *api.AddSecurityRuleOptions) *network.SecurityRule {
+//freePriority returns the lowest priority not used by the rules having the given direction
+func freePriority(rules []network.SecurityRule, direction network.SecurityRuleDirection) int32 {
+	used := make(map[int32]bool)
+	for _, r := range rules {
+		if r.SecurityRulePropertiesFormat != nil && r.Direction == direction && r.Priority != nil {
+			used[*r.Priority] = true
+		}
+	}
+	priority := int32(100)
+	for used[priority] {
+		priority++
+	}
+	return priority
+}
+
+func azSecurityRule(rule *api.AddSecurityRuleOptions, priority int32) *network.SecurityRule {
 	id := uuid.New()
+	cidr := rule.CIDR
+	if cidr == "" {
+		cidr = "*"
+	}
 	format := network.SecurityRulePropertiesFormat{
 		Description:              &rule.Description,
 		Protocol:                 convertAzProtocol(rule.Protocol),
+		SourcePortRange:          to.StringPtr("*"),
 		DestinationPortRange:     convertAzPortRange(rule.PortRange),
-		DestinationAddressPrefix: &rule.CIDR,
+		SourceAddressPrefix:      to.StringPtr("*"),
+		DestinationAddressPrefix: to.StringPtr("*"),
+		Access:                   network.SecurityRuleAccessAllow,
+		Priority:                 to.Int32Ptr(priority),
 		Direction:                convertAzDirection(rule.Direction),
 	}
+	if format.Direction == network.SecurityRuleDirectionInbound {
+		format.SourceAddressPrefix = &cidr
+	} else {
+		format.DestinationAddressPrefix = &cidr
+	}
 	return &network.SecurityRule{
 		SecurityRulePropertiesFormat: &format,
 		Name:                         to.StringPtr(id.String()),
 	}
 }
 
-func (mgr *SecurityGroupManager) AddSecurityRule(options api.AddSecurityRuleOptions) (*api.SecurityRule, api.AddSecurityRuleError) {
-	sg, err := mgr.Provider.BaseServices.SecurityGroupsClient.Get(context.Background(), mgr.resourceGroup(), options.SecurityGroupID, "")
+//AddSecurityRuleWithContext context aware version of AddSecurityRule
+func (mgr *SecurityGroupManager) AddSecurityRuleWithContext(ctx context.Context, options api.AddSecurityRuleOptions) (*api.SecurityRule, api.AddSecurityRuleError) {
+	sg, err := mgr.Provider.BaseServices.SecurityGroupsClient.Get(ctx, mgr.resourceGroup(), options.SecurityGroupID, "")
 	if err != nil {
-		return nil, api.NewAddSecurityRuleError(err, options)
+		return nil, api.NewAddSecurityRuleError(UnwrapAzureError(err), options)
 	}
 	var rules []network.SecurityRule
 	if sg.SecurityRules != nil {
 		rules = append(rules, *sg.SecurityRules...)
 	}
-	rule := azSecurityRule(&options)
+	rule := azSecurityRule(&options, freePriority(rules, convertAzDirection(options.Direction)))
 	rules = append(rules, *rule)
 	sg.SecurityRules = &rules
-	future, err := mgr.Provider.BaseServices.SecurityGroupsClient.CreateOrUpdate(context.Background(), mgr.resourceGroup(), options.SecurityGroupID, sg)
+	future, err := mgr.Provider.BaseServices.SecurityGroupsClient.CreateOrUpdate(ctx, mgr.resourceGroup(), options.SecurityGroupID, sg)
 	if err != nil {
-		return nil, api.NewAddSecurityRuleError(err, options)
+		return nil, api.NewAddSecurityRuleError(UnwrapAzureError(err), options)
 	}
-	err = future.WaitForCompletionRef(context.Background(), mgr.Provider.BaseServices.SecurityGroupsClient.Client)
+	err = future.WaitForCompletionRef(ctx, mgr.Provider.BaseServices.SecurityGroupsClient.Client)
 	if err != nil {
-		return nil, api.NewAddSecurityRuleError(err, options)
+		return nil, api.NewAddSecurityRuleError(UnwrapAzureError(err), options)
 	}
 	sg, err = future.Result(mgr.Provider.BaseServices.SecurityGroupsClient)
 	if err != nil {
-		return nil, api.NewAddSecurityRuleError(err, options)
+		return nil, api.NewAddSecurityRuleError(UnwrapAzureError(err), options)
 	}
 	for _, r := range *sg.SecurityRules {
-		if r.Name == rule.Name {
-			return convertRule(rule, *sg.ID), nil
+		if *r.Name == *rule.Name {
+			return convertRule(&r, *sg.Name), nil
 		}
 	}
-	return nil, api.NewAddSecurityRuleError(err, options)
+	return nil, api.NewAddSecurityRuleError(UnwrapAzureError(err), options)
 }
 
-func (mgr *SecurityGroupManager) RemoveSecurityRule(groupID, ruleID string) api.RemoveSecurityRuleError {
-	sg, err := mgr.Provider.BaseServices.SecurityGroupsClient.Get(context.Background(), mgr.resourceGroup(), groupID, "")
+func (mgr *SecurityGroupManager) AddSecurityRule(options api.AddSecurityRuleOptions) (*api.SecurityRule, api.AddSecurityRuleError) {
+	return mgr.AddSecurityRuleWithContext(context.Background(), options)
+}
+
+//RemoveSecurityRuleWithContext context aware version of RemoveSecurityRule
+func (mgr *SecurityGroupManager) RemoveSecurityRuleWithContext(ctx context.Context, groupID, ruleID string) api.RemoveSecurityRuleError {
+	sg, err := mgr.Provider.BaseServices.SecurityGroupsClient.Get(ctx, mgr.resourceGroup(), groupID, "")
 	if err != nil {
-		return api.NewRemoveSecurityRuleError(err, groupID, ruleID)
+		return api.NewRemoveSecurityRuleError(UnwrapAzureError(err), groupID, ruleID)
 	}
 	var rules []network.SecurityRule
 	done := false
@@ -308,14 +386,18 @@ func (mgr *SecurityGroupManager) RemoveSecurityRule(groupID, ruleID string) api.
 	}
 	if !done {
 		err = errors.Errorf("rule does not exist")
-		return api.NewRemoveSecurityRuleError(err, groupID, ruleID)
+		return api.NewRemoveSecurityRuleError(UnwrapAzureError(err), groupID, ruleID)
 	}
 	sg.SecurityRules = &rules
-	future, err := mgr.Provider.BaseServices.SecurityGroupsClient.CreateOrUpdate(context.Background(), mgr.resourceGroup(), groupID, sg)
+	future, err := mgr.Provider.BaseServices.SecurityGroupsClient.CreateOrUpdate(ctx, mgr.resourceGroup(), groupID, sg)
 	if err != nil {
-		return api.NewRemoveSecurityRuleError(err, groupID, ruleID)
+		return api.NewRemoveSecurityRuleError(UnwrapAzureError(err), groupID, ruleID)
 	}
-	err = future.WaitForCompletionRef(context.Background(), mgr.Provider.BaseServices.SecurityGroupsClient.Client)
-	return api.NewRemoveSecurityRuleError(err, groupID, ruleID)
+	err = future.WaitForCompletionRef(ctx, mgr.Provider.BaseServices.SecurityGroupsClient.Client)
+	return api.NewRemoveSecurityRuleError(UnwrapAzureError(err), groupID, ruleID)
 
 }
+
+func (mgr *SecurityGroupManager) RemoveSecurityRule(groupID, ruleID string) api.RemoveSecurityRuleError {
+	return mgr.RemoveSecurityRuleWithContext(context.Background(), groupID, ruleID)
+}
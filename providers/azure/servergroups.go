@@ -0,0 +1,401 @@
+package azure
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/compute/mgmt/compute"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/pkg/errors"
+	"github.com/sethvargo/go-password/password"
+)
+
+//ServerGroupManager azure implementation of api.ServerGroupManager based on virtual machine scale sets
+//The ID of a group is the name of its scale set and the IDs of its servers are the names of the scale set instances
+//Deleting a scale set instance decrements the capacity of the scale set, servers are not replaced unless ReplaceServer is used
+type ServerGroupManager struct {
+	Provider *Provider
+}
+
+const (
+	//minSizeTag and maxSizeTag tags storing the bounds of the group, scale sets do not have any
+	minSizeTag = "min-size"
+	maxSizeTag = "max-size"
+	//maxComputerNamePrefix maximum length of the computer name prefix of a Linux scale set
+	maxComputerNamePrefix = 58
+)
+
+func (mgr *ServerGroupManager) resourceGroup() string {
+	return mgr.Provider.Configuration.ResourceGroupName
+}
+
+func sizeTags(size api.ServerGroupSize) map[string]*string {
+	return map[string]*string{
+		minSizeTag: to.StringPtr(strconv.Itoa(size.MinSize)),
+		maxSizeTag: to.StringPtr(strconv.Itoa(size.MaxSize)),
+	}
+}
+
+func intTag(tags map[string]*string, key string) int {
+	v, ok := tags[key]
+	if !ok || v == nil {
+		return 0
+	}
+	i, _ := strconv.Atoi(*v)
+	return i
+}
+
+//instances returns the instances of the scale set
+func (mgr *ServerGroupManager) instances(ctx context.Context, name string) ([]compute.VirtualMachineScaleSetVM, error) {
+	it, err := mgr.Provider.BaseServices.VirtualMachineScaleSetVMsClient.ListComplete(ctx, mgr.resourceGroup(), name, "", "", "")
+	if err != nil {
+		return nil, err
+	}
+	var vms []compute.VirtualMachineScaleSetVM
+	for it.NotDone() {
+		vms = append(vms, it.Value())
+		err = it.NextWithContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return vms, nil
+}
+
+func (mgr *ServerGroupManager) group(ctx context.Context, ss *compute.VirtualMachineScaleSet) (*api.ServerGroup, error) {
+	vms, err := mgr.instances(ctx, *ss.Name)
+	if err != nil {
+		return nil, err
+	}
+	g := &api.ServerGroup{
+		ID:      *ss.Name,
+		Name:    *ss.Name,
+		MinSize: intTag(ss.Tags, minSizeTag),
+		MaxSize: intTag(ss.Tags, maxSizeTag),
+		Tags:    userTags(ss.Tags),
+	}
+	if ss.Sku != nil && ss.Sku.Capacity != nil {
+		g.DesiredSize = int(*ss.Sku.Capacity)
+	}
+	for _, vm := range vms {
+		g.ServerIDs = append(g.ServerIDs, *vm.Name)
+	}
+	sort.Strings(g.ServerIDs)
+	return g, nil
+}
+
+func (mgr *ServerGroupManager) get(ctx context.Context, id string) (*api.ServerGroup, error) {
+	ss, err := mgr.Provider.BaseServices.VirtualMachineScaleSetsClient.Get(ctx, mgr.resourceGroup(), id)
+	if err != nil {
+		return nil, err
+	}
+	return mgr.group(ctx, &ss)
+}
+
+//networkProfile returns the network profile attaching the instances to the subnet of the launch specification
+func (mgr *ServerGroupManager) networkProfile(ctx context.Context, options *api.CreateServerGroupOptions) (*compute.VirtualMachineScaleSetNetworkProfile, error) {
+	sn := options.Spec.Subnets[0]
+	subnet, err := mgr.Provider.BaseServices.SubnetsClient.Get(ctx, mgr.resourceGroup(), sn.NetworkID, sn.ID, "")
+	if err != nil {
+		return nil, err
+	}
+	nic := compute.VirtualMachineScaleSetNetworkConfiguration{
+		Name: to.StringPtr(options.Name + "-nic"),
+		VirtualMachineScaleSetNetworkConfigurationProperties: &compute.VirtualMachineScaleSetNetworkConfigurationProperties{
+			Primary: to.BoolPtr(true),
+			IPConfigurations: &[]compute.VirtualMachineScaleSetIPConfiguration{{
+				Name: to.StringPtr(options.Name + "-ipconfig"),
+				VirtualMachineScaleSetIPConfigurationProperties: &compute.VirtualMachineScaleSetIPConfigurationProperties{
+					Subnet:  &compute.APIEntityReference{ID: subnet.ID},
+					Primary: to.BoolPtr(true),
+				},
+			}},
+		},
+	}
+	sg, err := mgr.Provider.NetworkInterfacesManager.securityGroup(ctx, options.Spec.DefaultSecurityGroup)
+	if err != nil {
+		return nil, err
+	}
+	if sg != nil {
+		nic.NetworkSecurityGroup = &compute.SubResource{ID: sg.ID}
+	}
+	return &compute.VirtualMachineScaleSetNetworkProfile{
+		NetworkInterfaceConfigurations: &[]compute.VirtualMachineScaleSetNetworkConfiguration{nic},
+	}, nil
+}
+
+//osProfile returns the OS profile of the instances, named after the group
+func (mgr *ServerGroupManager) osProfile(ctx context.Context, options *api.CreateServerGroupOptions) (*compute.VirtualMachineScaleSetOSProfile, error) {
+	publicKey := string(options.Spec.KeyPair.PublicKey)
+	if options.Spec.KeyPairName != "" {
+		key, err := mgr.Provider.KeyPairManager.publicKey(ctx, options.Spec.KeyPairName)
+		if err != nil {
+			return nil, err
+		}
+		publicKey = key
+	}
+	prefix := options.Name
+	if len(prefix) > maxComputerNamePrefix {
+		prefix = prefix[:maxComputerNamePrefix]
+	}
+	user := mgr.Provider.Configuration.DefaultVMUserName
+	profile := &compute.VirtualMachineScaleSetOSProfile{
+		ComputerNamePrefix: to.StringPtr(prefix),
+		AdminUsername:      to.StringPtr(user),
+		AdminPassword:      to.StringPtr(password.MustGenerate(16, 5, 5, false, false)),
+		LinuxConfiguration: &compute.LinuxConfiguration{
+			SSH: &compute.SSHConfiguration{
+				PublicKeys: &[]compute.SSHPublicKey{{
+					Path:    to.StringPtr(fmt.Sprintf("/home/%s/.ssh/authorized_keys", user)),
+					KeyData: to.StringPtr(publicKey),
+				}},
+			},
+		},
+	}
+	if options.Spec.BootstrapScript != nil {
+		b, err := ioutil.ReadAll(options.Spec.BootstrapScript)
+		if err != nil {
+			return nil, err
+		}
+		profile.CustomData = to.StringPtr(base64.StdEncoding.EncodeToString(b))
+	}
+	return profile, nil
+}
+
+func (mgr *ServerGroupManager) create(ctx context.Context, options api.CreateServerGroupOptions) (*api.ServerGroup, error) {
+	err := api.CheckServerGroupOptions(&options)
+	if err != nil {
+		return nil, err
+	}
+	client := mgr.Provider.BaseServices.VirtualMachineScaleSetsClient
+	//PUT updates existing scale sets, the existence of the group is checked to not replace it silently
+	_, err = client.Get(ctx, mgr.resourceGroup(), options.Name)
+	if err == nil {
+		return nil, api.WithKind(errors.Errorf("server group %s already exists", options.Name), api.ErrAlreadyExists)
+	}
+	if azureErrorKind(err) != api.ErrNotFound {
+		return nil, err
+	}
+	osProfile, err := mgr.osProfile(ctx, &options)
+	if err != nil {
+		return nil, err
+	}
+	networkProfile, err := mgr.networkProfile(ctx, &options)
+	if err != nil {
+		return nil, err
+	}
+	images := ImageManager{Provider: mgr.Provider}
+	future, err := client.CreateOrUpdate(ctx, mgr.resourceGroup(), options.Name, compute.VirtualMachineScaleSet{
+		Location: to.StringPtr(mgr.Provider.Configuration.Location),
+		Tags:     azureTags(options.Tags, sizeTags(options.Size)),
+		Sku: &compute.Sku{
+			Name:     to.StringPtr(options.Spec.TemplateID),
+			Tier:     to.StringPtr("Standard"),
+			Capacity: to.Int64Ptr(int64(options.Size.DesiredSize)),
+		},
+		VirtualMachineScaleSetProperties: &compute.VirtualMachineScaleSetProperties{
+			UpgradePolicy: &compute.UpgradePolicy{Mode: compute.Manual},
+			Overprovision: to.BoolPtr(false),
+			VirtualMachineProfile: &compute.VirtualMachineScaleSetVMProfile{
+				OsProfile: osProfile,
+				StorageProfile: &compute.VirtualMachineScaleSetStorageProfile{
+					ImageReference: images.imageReference(options.Spec.ImageID),
+				},
+				NetworkProfile: networkProfile,
+				Priority:       compute.Regular,
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = future.WaitForCompletionRef(ctx, client.Client)
+	if err != nil {
+		return nil, err
+	}
+	return mgr.get(ctx, options.Name)
+}
+
+//CreateWithContext creates a server group
+func (mgr *ServerGroupManager) CreateWithContext(ctx context.Context, options api.CreateServerGroupOptions) (*api.ServerGroup, api.CreateServerGroupError) {
+	g, err := mgr.create(ctx, options)
+	if err != nil {
+		return nil, api.NewCreateServerGroupError(UnwrapAzureError(err), options)
+	}
+	return g, nil
+}
+
+//Create creates a server group
+func (mgr *ServerGroupManager) Create(options api.CreateServerGroupOptions) (*api.ServerGroup, api.CreateServerGroupError) {
+	return mgr.CreateWithContext(context.Background(), options)
+}
+
+func (mgr *ServerGroupManager) delete(ctx context.Context, id string) error {
+	client := mgr.Provider.BaseServices.VirtualMachineScaleSetsClient
+	//deleting a missing scale set succeeds, its existence is checked to report it
+	_, err := client.Get(ctx, mgr.resourceGroup(), id)
+	if err != nil {
+		return err
+	}
+	future, err := client.Delete(ctx, mgr.resourceGroup(), id)
+	if err != nil {
+		return err
+	}
+	return future.WaitForCompletionRef(ctx, client.Client)
+}
+
+//DeleteWithContext deletes the server group identified by id and its servers
+func (mgr *ServerGroupManager) DeleteWithContext(ctx context.Context, id string) api.DeleteServerGroupError {
+	return api.NewDeleteServerGroupError(UnwrapAzureError(mgr.delete(ctx, id)), id)
+}
+
+//Delete deletes the server group identified by id and its servers
+func (mgr *ServerGroupManager) Delete(id string) api.DeleteServerGroupError {
+	return mgr.DeleteWithContext(context.Background(), id)
+}
+
+func (mgr *ServerGroupManager) list(ctx context.Context) ([]api.ServerGroup, error) {
+	it, err := mgr.Provider.BaseServices.VirtualMachineScaleSetsClient.ListComplete(ctx, mgr.resourceGroup())
+	if err != nil {
+		return nil, err
+	}
+	groups := []api.ServerGroup{}
+	for it.NotDone() {
+		ss := it.Value()
+		g, err := mgr.group(ctx, &ss)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, *g)
+		err = it.NextWithContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return groups, nil
+}
+
+//ListWithContext lists the server groups
+func (mgr *ServerGroupManager) ListWithContext(ctx context.Context) ([]api.ServerGroup, api.ListServerGroupsError) {
+	groups, err := mgr.list(ctx)
+	if err != nil {
+		return nil, api.NewListServerGroupsError(UnwrapAzureError(err))
+	}
+	return groups, nil
+}
+
+//List lists the server groups
+func (mgr *ServerGroupManager) List() ([]api.ServerGroup, api.ListServerGroupsError) {
+	return mgr.ListWithContext(context.Background())
+}
+
+//GetWithContext returns the server group identified by id
+func (mgr *ServerGroupManager) GetWithContext(ctx context.Context, id string) (*api.ServerGroup, api.GetServerGroupError) {
+	g, err := mgr.get(ctx, id)
+	if err != nil {
+		return nil, api.NewGetServerGroupError(UnwrapAzureError(err), id)
+	}
+	return g, nil
+}
+
+//Get returns the server group identified by id
+func (mgr *ServerGroupManager) Get(id string) (*api.ServerGroup, api.GetServerGroupError) {
+	return mgr.GetWithContext(context.Background(), id)
+}
+
+//update sets the capacity of the scale set and the bounds of the group
+func (mgr *ServerGroupManager) update(ctx context.Context, ss *compute.VirtualMachineScaleSet, size api.ServerGroupSize) (*api.ServerGroup, error) {
+	client := mgr.Provider.BaseServices.VirtualMachineScaleSetsClient
+	future, err := client.Update(ctx, mgr.resourceGroup(), *ss.Name, compute.VirtualMachineScaleSetUpdate{
+		Sku:  &compute.Sku{Capacity: to.Int64Ptr(int64(size.DesiredSize))},
+		Tags: azureTags(userTags(ss.Tags), sizeTags(size)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = future.WaitForCompletionRef(ctx, client.Client)
+	if err != nil {
+		return nil, err
+	}
+	return mgr.get(ctx, *ss.Name)
+}
+
+func (mgr *ServerGroupManager) scale(ctx context.Context, id string, size api.ServerGroupSize) (*api.ServerGroup, error) {
+	err := api.CheckServerGroupSize(size)
+	if err != nil {
+		return nil, err
+	}
+	ss, err := mgr.Provider.BaseServices.VirtualMachineScaleSetsClient.Get(ctx, mgr.resourceGroup(), id)
+	if err != nil {
+		return nil, err
+	}
+	return mgr.update(ctx, &ss, size)
+}
+
+//ScaleWithContext changes the size of the server group identified by id
+func (mgr *ServerGroupManager) ScaleWithContext(ctx context.Context, id string, size api.ServerGroupSize) (*api.ServerGroup, api.ScaleServerGroupError) {
+	g, err := mgr.scale(ctx, id, size)
+	if err != nil {
+		return nil, api.NewScaleServerGroupError(UnwrapAzureError(err), id, size)
+	}
+	return g, nil
+}
+
+//Scale changes the size of the server group identified by id
+func (mgr *ServerGroupManager) Scale(id string, size api.ServerGroupSize) (*api.ServerGroup, api.ScaleServerGroupError) {
+	return mgr.ScaleWithContext(context.Background(), id, size)
+}
+
+func (mgr *ServerGroupManager) replaceServer(ctx context.Context, id string, serverID string) (*api.ServerGroup, error) {
+	ss, err := mgr.Provider.BaseServices.VirtualMachineScaleSetsClient.Get(ctx, mgr.resourceGroup(), id)
+	if err != nil {
+		return nil, err
+	}
+	vms, err := mgr.instances(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	var instanceID *string
+	for _, vm := range vms {
+		if *vm.Name == serverID {
+			instanceID = vm.InstanceID
+		}
+	}
+	if instanceID == nil {
+		return nil, api.WithKind(errors.Errorf("server %s is not a server of group %s", serverID, id), api.ErrNotFound)
+	}
+	client := mgr.Provider.BaseServices.VirtualMachineScaleSetVMsClient
+	future, err := client.Delete(ctx, mgr.resourceGroup(), id, *instanceID)
+	if err != nil {
+		return nil, err
+	}
+	err = future.WaitForCompletionRef(ctx, client.Client)
+	if err != nil {
+		return nil, err
+	}
+	//deleting an instance decrements the capacity of the scale set, it is restored to launch the replacement
+	return mgr.update(ctx, &ss, api.ServerGroupSize{
+		MinSize:     intTag(ss.Tags, minSizeTag),
+		MaxSize:     intTag(ss.Tags, maxSizeTag),
+		DesiredSize: int(to.Int64(ss.Sku.Capacity)),
+	})
+}
+
+//ReplaceServerWithContext deletes the server identified by serverID and launches its replacement
+func (mgr *ServerGroupManager) ReplaceServerWithContext(ctx context.Context, id string, serverID string) (*api.ServerGroup, api.ReplaceServerGroupServerError) {
+	g, err := mgr.replaceServer(ctx, id, serverID)
+	if err != nil {
+		return nil, api.NewReplaceServerGroupServerError(UnwrapAzureError(err), id, serverID)
+	}
+	return g, nil
+}
+
+//ReplaceServer deletes the server identified by serverID and launches its replacement
+func (mgr *ServerGroupManager) ReplaceServer(id string, serverID string) (*api.ServerGroup, api.ReplaceServerGroupServerError) {
+	return mgr.ReplaceServerWithContext(context.Background(), id, serverID)
+}
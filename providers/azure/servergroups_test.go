@@ -0,0 +1,23 @@
+package azure_test
+
+import (
+	"testing"
+
+	"github.com/SebastienDorgan/anyclouds/tests"
+	"github.com/stretchr/testify/suite"
+)
+
+type AZServerGroupManagerTestSuite struct {
+	tests.ServerGroupManagerTestSuite
+}
+
+//SetupSuite set up server group manager
+func (suite *AZServerGroupManagerTestSuite) SetupSuite() {
+	suite.Prov = GetProvider()
+	//scale sets do not replace deleted instances
+	suite.SkipHealing = true
+}
+
+func TestAZServerGroupManagerTestSuite(t *testing.T) {
+	suite.Run(t, new(AZServerGroupManagerTestSuite))
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/Azure/go-autorest/autorest/to"
 	"github.com/SebastienDorgan/anyclouds/api"
 	"github.com/sethvargo/go-password/password"
+	"strings"
 	"time"
 )
 
@@ -18,10 +19,10 @@ func (mgr *ServerManager) resourceGroup() string {
 	return mgr.Provider.Configuration.ResourceGroupName
 }
 
-func (mgr *ServerManager) createNetworkInterfaces(options *api.CreateServerOptions) ([]compute.NetworkInterfaceReference, error) {
+func (mgr *ServerManager) createNetworkInterfaces(ctx context.Context, options *api.CreateServerOptions) ([]compute.NetworkInterfaceReference, error) {
 	var nis []compute.NetworkInterfaceReference
 	for _, sn := range options.Subnets {
-		ni, err := mgr.Provider.NetworkInterfacesManager.Create(api.CreateNetworkInterfaceOptions{
+		ni, err := mgr.Provider.NetworkInterfacesManager.create(ctx, &api.CreateNetworkInterfaceOptions{
 			Name:             fmt.Sprintf("NI-%s", sn.Name),
 			NetworkID:        sn.NetworkID,
 			SubnetID:         sn.ID,
@@ -35,7 +36,7 @@ func (mgr *ServerManager) createNetworkInterfaces(options *api.CreateServerOptio
 		}
 		nis = append(nis, compute.NetworkInterfaceReference{
 			NetworkInterfaceReferenceProperties: &compute.NetworkInterfaceReferenceProperties{Primary: to.BoolPtr(false)},
-			ID:                                  &ni.ID,
+			ID:                                  ni.ID,
 		})
 	}
 	if nis != nil {
@@ -43,34 +44,40 @@ func (mgr *ServerManager) createNetworkInterfaces(options *api.CreateServerOptio
 	}
 	return nis, nil
 }
-func (mgr *ServerManager) Create(options api.CreateServerOptions) (*api.Server, api.CreateServerError) {
-	publisher, offer, sku, version := parseImageID(options.ImageID)
-	nis, err := mgr.createNetworkInterfaces(&options)
+
+//CreateWithContext context aware version of Create
+func (mgr *ServerManager) CreateWithContext(ctx context.Context, options api.CreateServerOptions) (*api.Server, api.CreateServerError) {
+	images := ImageManager{Provider: mgr.Provider}
+	publicKey := string(options.KeyPair.PublicKey)
+	if options.KeyPairName != "" {
+		key, err := mgr.Provider.KeyPairManager.publicKey(ctx, options.KeyPairName)
+		if err != nil {
+			return nil, api.NewCreateServerError(UnwrapAzureError(err), options)
+		}
+		publicKey = key
+	}
+	nis, err := mgr.createNetworkInterfaces(ctx, &options)
 	if err != nil {
-		return nil, api.NewCreateServerError(err, options)
+		return nil, api.NewCreateServerError(UnwrapAzureError(err), options)
 	}
 	priority := compute.Regular
 	if options.LowPriorityServerOptions != nil {
 		priority = compute.Low
 	}
 	future, err := mgr.Provider.BaseServices.VirtualMachinesClient.CreateOrUpdate(
-		context.Background(),
+		ctx,
 		mgr.resourceGroup(),
 		options.Name,
 		compute.VirtualMachine{
 			Location: to.StringPtr(mgr.Provider.Configuration.Location),
+			Tags:     azureTags(options.Tags, nil),
 			VirtualMachineProperties: &compute.VirtualMachineProperties{
 				HardwareProfile: &compute.HardwareProfile{
 					VMSize: compute.VirtualMachineSizeTypes(options.TemplateID),
 				},
 				Priority: priority,
 				StorageProfile: &compute.StorageProfile{
-					ImageReference: &compute.ImageReference{
-						Publisher: to.StringPtr(publisher),
-						Offer:     to.StringPtr(offer),
-						Sku:       to.StringPtr(sku),
-						Version:   to.StringPtr(version),
-					},
+					ImageReference: images.imageReference(options.ImageID),
 				},
 				OsProfile: &compute.OSProfile{
 					ComputerName:  to.StringPtr(options.Name),
@@ -83,7 +90,7 @@ func (mgr *ServerManager) Create(options api.CreateServerOptions) (*api.Server,
 									Path: to.StringPtr(
 										fmt.Sprintf("/home/%s/.ssh/authorized_keys",
 											mgr.Provider.Configuration.DefaultVMUserName)),
-									KeyData: to.StringPtr(string(options.KeyPair.PublicKey)),
+									KeyData: to.StringPtr(publicKey),
 								},
 							},
 						},
@@ -96,26 +103,64 @@ func (mgr *ServerManager) Create(options api.CreateServerOptions) (*api.Server,
 		},
 	)
 	if err != nil {
-		return nil, api.NewCreateServerError(err, options)
+		return nil, api.NewCreateServerError(UnwrapAzureError(err), options)
 	}
-	err = future.WaitForCompletionRef(context.Background(), mgr.Provider.BaseServices.VirtualMachinesClient.Client)
+	err = future.WaitForCompletionRef(ctx, mgr.Provider.BaseServices.VirtualMachinesClient.Client)
 	if err != nil {
-		return nil, api.NewCreateServerError(err, options)
+		return nil, api.NewCreateServerError(UnwrapAzureError(err), options)
 	}
-	vm, err := future.Result(mgr.Provider.BaseServices.VirtualMachinesClient)
+	vm, err := mgr.get(ctx, options.Name)
 	if err != nil {
-		return nil, api.NewCreateServerError(err, options)
+		return nil, api.NewCreateServerError(UnwrapAzureError(err), options)
 	}
-	return mgr.server(&vm), nil
+	return mgr.server(vm), nil
+}
+
+func (mgr *ServerManager) Create(options api.CreateServerOptions) (*api.Server, api.CreateServerError) {
+	return mgr.CreateWithContext(context.Background(), options)
 }
 
 func imageID(reference *compute.ImageReference) string {
 	if reference == nil {
 		return ""
 	}
+	if reference.ID != nil {
+		return resourceName(*reference.ID)
+	}
 	return createImageID(*reference.Publisher, *reference.Offer, *reference.Sku, *reference.Version)
 }
 
+//serverState returns the state of vm, the power state is only known if the instance view of vm is expanded
+func serverState(vm *compute.VirtualMachine) api.ServerState {
+	if vm.InstanceView != nil && vm.InstanceView.Statuses != nil {
+		for _, status := range *vm.InstanceView.Statuses {
+			if status.Code == nil || !strings.HasPrefix(*status.Code, "PowerState/") {
+				continue
+			}
+			switch strings.TrimPrefix(*status.Code, "PowerState/") {
+			case "running":
+				return api.ServerReady
+			case "stopped", "deallocated":
+				return api.ServerShutoff
+			case "starting", "stopping", "deallocating":
+				return api.ServerPending
+			}
+		}
+	}
+	if vm.ProvisioningState == nil {
+		return api.ServerUnknownState
+	}
+	switch *vm.ProvisioningState {
+	case "Succeeded":
+		return api.ServerReady
+	case "Failed":
+		return api.ServerInError
+	case "Deleting":
+		return api.ServerDeleted
+	}
+	return api.ServerPending
+}
+
 func (mgr *ServerManager) server(vm *compute.VirtualMachine) *api.Server {
 	leasingType := api.LeasingTypeOnDemand
 	if vm.Priority == compute.Low {
@@ -126,27 +171,62 @@ func (mgr *ServerManager) server(vm *compute.VirtualMachine) *api.Server {
 		Name:          *vm.Name,
 		TemplateID:    string(vm.HardwareProfile.VMSize),
 		ImageID:       imageID(vm.StorageProfile.ImageReference),
-		State:         "",
+		State:         serverState(vm),
 		CreatedAt:     time.Time{},
 		LeasingType:   leasingType,
 		LeaseDuration: 0,
+		Tags:          userTags(vm.Tags),
 	}
 	return srv
 }
 
-func (mgr *ServerManager) Delete(id string) api.DeleteServerError {
-	future, err := mgr.Provider.BaseServices.VirtualMachinesClient.Delete(context.Background(), mgr.resourceGroup(), id)
+//DeleteWithContext context aware version of Delete
+func (mgr *ServerManager) DeleteWithContext(ctx context.Context, id string) api.DeleteServerError {
+	return api.NewDeleteServerError(UnwrapAzureError(mgr.delete(ctx, id)), id)
+}
+
+//delete deletes the virtual machine, its OS disk and the network interfaces created with it
+func (mgr *ServerManager) delete(ctx context.Context, id string) error {
+	vm, err := mgr.get(ctx, id)
+	if err != nil {
+		return err
+	}
+	future, err := mgr.Provider.BaseServices.VirtualMachinesClient.Delete(ctx, mgr.resourceGroup(), id)
 	if err != nil {
-		return api.NewDeleteServerError(err, id)
+		return err
+	}
+	err = future.WaitForCompletionRef(ctx, mgr.Provider.BaseServices.VirtualMachinesClient.Client)
+	if err != nil {
+		return err
+	}
+	//the OS disk is not deleted with the virtual machine
+	if vm.StorageProfile != nil && vm.StorageProfile.OsDisk != nil && vm.StorageProfile.OsDisk.ManagedDisk != nil && vm.StorageProfile.OsDisk.ManagedDisk.ID != nil {
+		err = mgr.Provider.VolumeManager.delete(ctx, resourceName(*vm.StorageProfile.OsDisk.ManagedDisk.ID))
+		if err != nil {
+			return err
+		}
+	}
+	if vm.NetworkProfile == nil || vm.NetworkProfile.NetworkInterfaces == nil {
+		return nil
 	}
-	err = future.WaitForCompletionRef(context.Background(), mgr.Provider.BaseServices.VirtualMachinesClient.Client)
-	return api.NewDeleteServerError(err, id)
+	for _, nir := range *vm.NetworkProfile.NetworkInterfaces {
+		err = mgr.Provider.NetworkInterfacesManager.delete(ctx, resourceName(*nir.ID))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func (mgr *ServerManager) List() ([]api.Server, api.ListServersError) {
-	it, err := mgr.Provider.BaseServices.VirtualMachinesClient.List(context.Background(), mgr.resourceGroup())
+func (mgr *ServerManager) Delete(id string) api.DeleteServerError {
+	return mgr.DeleteWithContext(context.Background(), id)
+}
+
+//ListWithContext context aware version of List
+func (mgr *ServerManager) ListWithContext(ctx context.Context) ([]api.Server, api.ListServersError) {
+	it, err := mgr.Provider.BaseServices.VirtualMachinesClient.List(ctx, mgr.resourceGroup())
 	if err != nil {
-		return nil, api.NewListServersError(err)
+		return nil, api.NewListServersError(UnwrapAzureError(err))
 	}
 	var servers []api.Server
 	for it.NotDone() {
@@ -154,16 +234,20 @@ func (mgr *ServerManager) List() ([]api.Server, api.ListServersError) {
 		for _, vm := range vms {
 			servers = append(servers, *mgr.server(&vm))
 		}
-		err = it.NextWithContext(context.Background())
+		err = it.NextWithContext(ctx)
 		if err != nil {
-			return nil, api.NewListServersError(err)
+			return nil, api.NewListServersError(UnwrapAzureError(err))
 		}
 	}
 	return servers, nil
 }
 
-func (mgr *ServerManager) list() ([]compute.VirtualMachine, error) {
-	it, err := mgr.Provider.BaseServices.VirtualMachinesClient.List(context.Background(), mgr.resourceGroup())
+func (mgr *ServerManager) List() ([]api.Server, api.ListServersError) {
+	return mgr.ListWithContext(context.Background())
+}
+
+func (mgr *ServerManager) list(ctx context.Context) ([]compute.VirtualMachine, error) {
+	it, err := mgr.Provider.BaseServices.VirtualMachinesClient.List(ctx, mgr.resourceGroup())
 	if err != nil {
 		return nil, err
 	}
@@ -173,7 +257,7 @@ func (mgr *ServerManager) list() ([]compute.VirtualMachine, error) {
 		for _, vm := range vms {
 			servers = append(servers, vm)
 		}
-		err = it.NextWithContext(context.Background())
+		err = it.NextWithContext(ctx)
 		if err != nil {
 			return nil, err
 		}
@@ -181,44 +265,67 @@ func (mgr *ServerManager) list() ([]compute.VirtualMachine, error) {
 	return servers, nil
 }
 
-func (mgr *ServerManager) get(id string) (*compute.VirtualMachine, error) {
-	res, err := mgr.Provider.BaseServices.VirtualMachinesClient.Get(context.Background(), mgr.resourceGroup(), id, "")
+func (mgr *ServerManager) get(ctx context.Context, id string) (*compute.VirtualMachine, error) {
+	res, err := mgr.Provider.BaseServices.VirtualMachinesClient.Get(ctx, mgr.resourceGroup(), id, compute.InstanceView)
 	return &res, err
 }
 
+//GetWithContext context aware version of Get
+func (mgr *ServerManager) GetWithContext(ctx context.Context, id string) (*api.Server, api.GetServerError) {
+	vm, err := mgr.get(ctx, id)
+	if err != nil {
+		return nil, api.NewGetServerError(UnwrapAzureError(err), id)
+	}
+	return mgr.server(vm), nil
+}
+
 func (mgr *ServerManager) Get(id string) (*api.Server, api.GetServerError) {
-	vm, err := mgr.get(id)
-	return mgr.server(vm), api.NewGetServerError(err, id)
+	return mgr.GetWithContext(context.Background(), id)
 }
 
-func (mgr *ServerManager) Start(id string) api.StartServerError {
-	future, err := mgr.Provider.BaseServices.VirtualMachinesClient.Start(context.Background(), mgr.resourceGroup(), id)
+//StartWithContext context aware version of Start
+func (mgr *ServerManager) StartWithContext(ctx context.Context, id string) api.StartServerError {
+	future, err := mgr.Provider.BaseServices.VirtualMachinesClient.Start(ctx, mgr.resourceGroup(), id)
 	if err != nil {
-		return api.NewStartServerError(err, id)
+		return api.NewStartServerError(UnwrapAzureError(err), id)
 	}
-	err = future.WaitForCompletionRef(context.Background(), mgr.Provider.BaseServices.VirtualMachinesClient.Client)
-	return api.NewStartServerError(err, id)
+	err = future.WaitForCompletionRef(ctx, mgr.Provider.BaseServices.VirtualMachinesClient.Client)
+	return api.NewStartServerError(UnwrapAzureError(err), id)
 }
 
-func (mgr *ServerManager) Stop(id string) api.StopServerError {
-	future, err := mgr.Provider.BaseServices.VirtualMachinesClient.PowerOff(context.Background(), mgr.resourceGroup(), id, to.BoolPtr(false))
+func (mgr *ServerManager) Start(id string) api.StartServerError {
+	return mgr.StartWithContext(context.Background(), id)
+}
+
+//StopWithContext context aware version of Stop
+func (mgr *ServerManager) StopWithContext(ctx context.Context, id string) api.StopServerError {
+	future, err := mgr.Provider.BaseServices.VirtualMachinesClient.PowerOff(ctx, mgr.resourceGroup(), id, to.BoolPtr(false))
 	if err != nil {
-		return api.NewStopServerError(err, id)
+		return api.NewStopServerError(UnwrapAzureError(err), id)
 	}
-	err = future.WaitForCompletionRef(context.Background(), mgr.Provider.BaseServices.VirtualMachinesClient.Client)
-	return api.NewStopServerError(err, id)
+	err = future.WaitForCompletionRef(ctx, mgr.Provider.BaseServices.VirtualMachinesClient.Client)
+	return api.NewStopServerError(UnwrapAzureError(err), id)
 }
 
-func (mgr *ServerManager) Resize(id string, templateID string) api.ResizeServerError {
-	vm, err := mgr.get(id)
+func (mgr *ServerManager) Stop(id string) api.StopServerError {
+	return mgr.StopWithContext(context.Background(), id)
+}
+
+//ResizeWithContext context aware version of Resize
+func (mgr *ServerManager) ResizeWithContext(ctx context.Context, id string, templateID string) api.ResizeServerError {
+	vm, err := mgr.get(ctx, id)
 	if err != nil {
-		return api.NewResizeServerError(err, id, templateID)
+		return api.NewResizeServerError(UnwrapAzureError(err), id, templateID)
 	}
 	vm.HardwareProfile.VMSize = compute.VirtualMachineSizeTypes(templateID)
-	future, err := mgr.Provider.BaseServices.VirtualMachinesClient.CreateOrUpdate(context.Background(), mgr.resourceGroup(), id, *vm)
+	future, err := mgr.Provider.BaseServices.VirtualMachinesClient.CreateOrUpdate(ctx, mgr.resourceGroup(), id, *vm)
 	if err != nil {
-		return api.NewResizeServerError(err, id, templateID)
+		return api.NewResizeServerError(UnwrapAzureError(err), id, templateID)
 	}
-	err = future.WaitForCompletionRef(context.Background(), mgr.Provider.BaseServices.VirtualMachinesClient.Client)
-	return api.NewResizeServerError(err, id, templateID)
+	err = future.WaitForCompletionRef(ctx, mgr.Provider.BaseServices.VirtualMachinesClient.Client)
+	return api.NewResizeServerError(UnwrapAzureError(err), id, templateID)
+}
+
+func (mgr *ServerManager) Resize(id string, templateID string) api.ResizeServerError {
+	return mgr.ResizeWithContext(context.Background(), id, templateID)
 }
@@ -14,6 +14,7 @@ type AZServerManagerTestSuite struct {
 func (suite *AZServerManagerTestSuite) SetupSuite() {
 	p := GetProvider()
 	suite.Prov = p
+	suite.SkipSSH = IsFake()
 }
 
 func TestAZServerManagerTestSuite(t *testing.T) {
@@ -0,0 +1,199 @@
+package azure
+
+import (
+	"context"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/compute/mgmt/compute"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+//SnapshotManager defines volume snapshot management functions an anyclouds provider must provide
+type SnapshotManager struct {
+	Provider *Provider
+}
+
+func (mgr *SnapshotManager) resourceGroup() string {
+	return mgr.Provider.Configuration.ResourceGroupName
+}
+
+func snapshotState(state *string) api.SnapshotState {
+	switch to.String(state) {
+	case "Creating", "Updating":
+		return api.SnapshotPending
+	case "Succeeded":
+		return api.SnapshotReady
+	case "Failed":
+		return api.SnapshotInError
+	}
+	return api.SnapshotUnknownState
+}
+
+func snapshot(s *compute.Snapshot) *api.Snapshot {
+	res := &api.Snapshot{
+		ID:    *s.Name,
+		State: api.SnapshotUnknownState,
+	}
+	if name, ok := s.Tags["name"]; ok && name != nil {
+		res.Name = *name
+	}
+	if s.SnapshotProperties == nil {
+		return res
+	}
+	res.State = snapshotState(s.ProvisioningState)
+	if s.DiskSizeGB != nil {
+		res.Size = int64(*s.DiskSizeGB)
+	}
+	if s.CreationData != nil && s.CreationData.SourceResourceID != nil {
+		res.VolumeID = resourceName(*s.CreationData.SourceResourceID)
+	}
+	if s.TimeCreated != nil {
+		res.CreatedAt = s.TimeCreated.Time
+	}
+	return res
+}
+
+func (mgr *SnapshotManager) create(ctx context.Context, options *api.CreateSnapshotOptions) (*api.Snapshot, error) {
+	d, err := mgr.Provider.BaseServices.DisksClient.Get(ctx, mgr.resourceGroup(), options.VolumeID)
+	if err != nil {
+		return nil, err
+	}
+	name := uuid.New().String()
+	future, err := mgr.Provider.BaseServices.SnapshotsClient.CreateOrUpdate(ctx, mgr.resourceGroup(), name, compute.Snapshot{
+		Location: to.StringPtr(mgr.Provider.Configuration.Location),
+		Tags:     map[string]*string{"name": to.StringPtr(options.Name)},
+		Sku:      &compute.SnapshotSku{Name: compute.SnapshotStorageAccountTypesStandardLRS},
+		SnapshotProperties: &compute.SnapshotProperties{
+			CreationData: &compute.CreationData{
+				CreateOption:     compute.Copy,
+				SourceResourceID: d.ID,
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = future.WaitForCompletionRef(ctx, mgr.Provider.BaseServices.SnapshotsClient.Client)
+	if err != nil {
+		return nil, api.NewErrorStackFromError(err, mgr.delete(ctx, name))
+	}
+	s, err := mgr.Provider.BaseServices.SnapshotsClient.Get(ctx, mgr.resourceGroup(), name)
+	if err != nil {
+		return nil, err
+	}
+	return snapshot(&s), nil
+}
+
+//CreateWithContext creates a snapshot of a managed disk
+func (mgr *SnapshotManager) CreateWithContext(ctx context.Context, options api.CreateSnapshotOptions) (*api.Snapshot, api.CreateSnapshotError) {
+	s, err := mgr.create(ctx, &options)
+	return s, api.NewCreateSnapshotError(UnwrapAzureError(err), options)
+}
+
+//Create creates a snapshot of a managed disk
+func (mgr *SnapshotManager) Create(options api.CreateSnapshotOptions) (*api.Snapshot, api.CreateSnapshotError) {
+	return mgr.CreateWithContext(context.Background(), options)
+}
+
+func (mgr *SnapshotManager) delete(ctx context.Context, id string) error {
+	future, err := mgr.Provider.BaseServices.SnapshotsClient.Delete(ctx, mgr.resourceGroup(), id)
+	if err != nil {
+		return err
+	}
+	return future.WaitForCompletionRef(ctx, mgr.Provider.BaseServices.SnapshotsClient.Client)
+}
+
+//DeleteWithContext deletes snapshot identified by id
+func (mgr *SnapshotManager) DeleteWithContext(ctx context.Context, id string) api.DeleteSnapshotError {
+	return api.NewDeleteSnapshotError(UnwrapAzureError(mgr.delete(ctx, id)), id)
+}
+
+//Delete deletes snapshot identified by id
+func (mgr *SnapshotManager) Delete(id string) api.DeleteSnapshotError {
+	return mgr.DeleteWithContext(context.Background(), id)
+}
+
+//ListWithContext lists snapshots
+func (mgr *SnapshotManager) ListWithContext(ctx context.Context) ([]api.Snapshot, api.ListSnapshotsError) {
+	it, err := mgr.Provider.BaseServices.SnapshotsClient.ListByResourceGroupComplete(ctx, mgr.resourceGroup())
+	if err != nil {
+		return nil, api.NewListSnapshotsError(UnwrapAzureError(err))
+	}
+	var snapshots []api.Snapshot
+	for it.NotDone() {
+		s := it.Value()
+		snapshots = append(snapshots, *snapshot(&s))
+		err = it.NextWithContext(ctx)
+		if err != nil {
+			return nil, api.NewListSnapshotsError(UnwrapAzureError(err))
+		}
+	}
+	return snapshots, nil
+}
+
+//List lists snapshots
+func (mgr *SnapshotManager) List() ([]api.Snapshot, api.ListSnapshotsError) {
+	return mgr.ListWithContext(context.Background())
+}
+
+//GetWithContext returns snapshot details
+func (mgr *SnapshotManager) GetWithContext(ctx context.Context, id string) (*api.Snapshot, api.GetSnapshotError) {
+	s, err := mgr.Provider.BaseServices.SnapshotsClient.Get(ctx, mgr.resourceGroup(), id)
+	if err != nil {
+		return nil, api.NewGetSnapshotError(UnwrapAzureError(err), id)
+	}
+	return snapshot(&s), nil
+}
+
+//Get returns snapshot details
+func (mgr *SnapshotManager) Get(id string) (*api.Snapshot, api.GetSnapshotError) {
+	return mgr.GetWithContext(context.Background(), id)
+}
+
+func (mgr *SnapshotManager) createVolume(ctx context.Context, options *api.CreateVolumeFromSnapshotOptions) (*api.Volume, error) {
+	s, err := mgr.Provider.BaseServices.SnapshotsClient.Get(ctx, mgr.resourceGroup(), options.SnapshotID)
+	if err != nil {
+		return nil, err
+	}
+	size := options.Size
+	if size == 0 && s.SnapshotProperties != nil && s.DiskSizeGB != nil {
+		size = int64(*s.DiskSizeGB)
+	}
+	if s.SnapshotProperties != nil && s.DiskSizeGB != nil && size < int64(*s.DiskSizeGB) {
+		return nil, api.WithKind(errors.Errorf("volume size %d is smaller than snapshot size %d", size, *s.DiskSizeGB), api.ErrInvalidArgument)
+	}
+	volumes := &mgr.Provider.VolumeManager
+	sku, iops, dataRate := volumes.selectDiskSku(&api.CreateVolumeOptions{
+		Size:        size,
+		MinIOPS:     options.MinIOPS,
+		MinDataRate: options.MinDataRate,
+	})
+	return volumes.createOrUpdate(ctx, &compute.Disk{
+		Name:     to.StringPtr(uuid.New().String()),
+		Location: to.StringPtr(mgr.Provider.Configuration.Location),
+		Tags:     azureTags(options.Tags, map[string]*string{"name": to.StringPtr(options.Name)}),
+		Sku:      &compute.DiskSku{Name: sku},
+		DiskProperties: &compute.DiskProperties{
+			CreationData: &compute.CreationData{
+				CreateOption:     compute.Copy,
+				SourceResourceID: s.ID,
+			},
+			DiskSizeGB:        to.Int32Ptr(int32(size)),
+			DiskIOPSReadWrite: iops,
+			DiskMBpsReadWrite: dataRate,
+		},
+	})
+}
+
+//CreateVolumeFromSnapshotWithContext creates a managed disk initialized with the content of a snapshot
+func (mgr *SnapshotManager) CreateVolumeFromSnapshotWithContext(ctx context.Context, options api.CreateVolumeFromSnapshotOptions) (*api.Volume, api.CreateVolumeFromSnapshotError) {
+	v, err := mgr.createVolume(ctx, &options)
+	return v, api.NewCreateVolumeFromSnapshotError(UnwrapAzureError(err), options)
+}
+
+//CreateVolumeFromSnapshot creates a managed disk initialized with the content of a snapshot
+func (mgr *SnapshotManager) CreateVolumeFromSnapshot(options api.CreateVolumeFromSnapshotOptions) (*api.Volume, api.CreateVolumeFromSnapshotError) {
+	return mgr.CreateVolumeFromSnapshotWithContext(context.Background(), options)
+}
@@ -0,0 +1,21 @@
+package azure_test
+
+import (
+	"testing"
+
+	"github.com/SebastienDorgan/anyclouds/tests"
+	"github.com/stretchr/testify/suite"
+)
+
+type AZSnapshotManagerTestSuite struct {
+	tests.SnapshotManagerTestSuite
+}
+
+//SetupSuite set up snapshot manager
+func (suite *AZSnapshotManagerTestSuite) SetupSuite() {
+	suite.Prov = GetProvider()
+}
+
+func TestAZSnapshotManagerTestSuite(t *testing.T) {
+	suite.Run(t, new(AZSnapshotManagerTestSuite))
+}
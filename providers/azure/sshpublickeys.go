@@ -0,0 +1,137 @@
+package azure
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/compute/mgmt/compute"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/azure"
+)
+
+//sshPublicKeysAPIVersion first compute API version exposing SSH public key resources, it is newer than the compute SDK in use
+const sshPublicKeysAPIVersion = "2019-12-01"
+
+//SSHPublicKeyProperties properties of an SSH public key resource
+type SSHPublicKeyProperties struct {
+	//PublicKey public key in authorized_keys format
+	PublicKey *string `json:"publicKey,omitempty"`
+}
+
+//SSHPublicKey Microsoft.Compute/sshPublicKeys resource
+type SSHPublicKey struct {
+	autorest.Response `json:"-"`
+	ID                *string                 `json:"id,omitempty"`
+	Name              *string                 `json:"name,omitempty"`
+	Location          *string                 `json:"location,omitempty"`
+	Tags              map[string]*string      `json:"tags,omitempty"`
+	Properties        *SSHPublicKeyProperties `json:"properties,omitempty"`
+}
+
+type sshPublicKeyList struct {
+	Value    []SSHPublicKey `json:"value"`
+	NextLink *string        `json:"nextLink,omitempty"`
+}
+
+//SSHPublicKeysClient client of SSH public key resources, the compute SDK in use does not provide it
+type SSHPublicKeysClient struct {
+	autorest.Client
+	BaseURI        string
+	SubscriptionID string
+}
+
+//NewSSHPublicKeysClientWithBaseURI creates an SSHPublicKeysClient targeting the resource manager endpoint baseURI
+func NewSSHPublicKeysClientWithBaseURI(baseURI string, subscriptionID string) SSHPublicKeysClient {
+	return SSHPublicKeysClient{
+		Client:         autorest.NewClientWithUserAgent(compute.UserAgent()),
+		BaseURI:        baseURI,
+		SubscriptionID: subscriptionID,
+	}
+}
+
+//do sends a request on the SSH public keys of resourceGroupName, or on the key name if name is not empty,
+//and unmarshals the response into result
+func (client SSHPublicKeysClient) do(ctx context.Context, method string, resourceGroupName string, name string, body interface{}, result interface{}, operation string) error {
+	pathParameters := map[string]interface{}{
+		"resourceGroupName": autorest.Encode("path", resourceGroupName),
+		"subscriptionId":    autorest.Encode("path", client.SubscriptionID),
+	}
+	path := "/subscriptions/{subscriptionId}/resourceGroups/{resourceGroupName}/providers/Microsoft.Compute/sshPublicKeys"
+	if name != "" {
+		pathParameters["sshPublicKeyName"] = autorest.Encode("path", name)
+		path += "/{sshPublicKeyName}"
+	}
+	decorators := []autorest.PrepareDecorator{
+		autorest.WithMethod(method),
+		autorest.WithBaseURL(client.BaseURI),
+		autorest.WithPathParameters(path, pathParameters),
+		autorest.WithQueryParameters(map[string]interface{}{"api-version": sshPublicKeysAPIVersion}),
+	}
+	if body != nil {
+		decorators = append(decorators, autorest.AsContentType("application/json; charset=utf-8"), autorest.WithJSON(body))
+	}
+	req, err := autorest.Prepare((&http.Request{}).WithContext(ctx), decorators...)
+	if err != nil {
+		return autorest.NewErrorWithError(err, "azure.SSHPublicKeysClient", operation, nil, "Failure preparing request")
+	}
+	return client.send(req, result, operation)
+}
+
+func (client SSHPublicKeysClient) send(req *http.Request, result interface{}, operation string) error {
+	resp, err := autorest.SendWithSender(client, req, azure.DoRetryWithRegistration(client.Client))
+	if err != nil {
+		return autorest.NewErrorWithError(err, "azure.SSHPublicKeysClient", operation, resp, "Failure sending request")
+	}
+	responders := []autorest.RespondDecorator{
+		client.ByInspecting(),
+		azure.WithErrorUnlessStatusCode(http.StatusOK, http.StatusCreated, http.StatusNoContent),
+	}
+	if result != nil {
+		responders = append(responders, autorest.ByUnmarshallingJSON(result))
+	}
+	err = autorest.Respond(resp, append(responders, autorest.ByClosing())...)
+	if err != nil {
+		return autorest.NewErrorWithError(err, "azure.SSHPublicKeysClient", operation, resp, "Failure responding to request")
+	}
+	return nil
+}
+
+//Create creates or updates the SSH public key name
+func (client SSHPublicKeysClient) Create(ctx context.Context, resourceGroupName string, name string, key SSHPublicKey) (result SSHPublicKey, err error) {
+	err = client.do(ctx, http.MethodPut, resourceGroupName, name, key, &result, "Create")
+	return
+}
+
+//Get returns the SSH public key name
+func (client SSHPublicKeysClient) Get(ctx context.Context, resourceGroupName string, name string) (result SSHPublicKey, err error) {
+	err = client.do(ctx, http.MethodGet, resourceGroupName, name, nil, &result, "Get")
+	return
+}
+
+//Delete deletes the SSH public key name
+func (client SSHPublicKeysClient) Delete(ctx context.Context, resourceGroupName string, name string) error {
+	return client.do(ctx, http.MethodDelete, resourceGroupName, name, nil, nil, "Delete")
+}
+
+//ListByResourceGroup lists the SSH public keys of resourceGroupName
+func (client SSHPublicKeysClient) ListByResourceGroup(ctx context.Context, resourceGroupName string) ([]SSHPublicKey, error) {
+	var page sshPublicKeyList
+	err := client.do(ctx, http.MethodGet, resourceGroupName, "", nil, &page, "ListByResourceGroup")
+	if err != nil {
+		return nil, err
+	}
+	keys := page.Value
+	for page.NextLink != nil && *page.NextLink != "" {
+		req, err := autorest.Prepare((&http.Request{}).WithContext(ctx), autorest.AsGet(), autorest.WithBaseURL(*page.NextLink))
+		if err != nil {
+			return nil, autorest.NewErrorWithError(err, "azure.SSHPublicKeysClient", "ListByResourceGroup", nil, "Failure preparing next results request")
+		}
+		page = sshPublicKeyList{}
+		err = client.send(req, &page, "ListByResourceGroup")
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, page.Value...)
+	}
+	return keys, nil
+}
@@ -0,0 +1,243 @@
+package azure
+
+import (
+	"context"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/pkg/errors"
+)
+
+//reservedTags tag keys used by the provider to store resource attributes
+var reservedTags = map[string]bool{
+	"name":       true,
+	"min-size":   true,
+	"max-size":   true,
+	"networkID":  true,
+	"network-id": true,
+	"server-id":  true,
+	"subnet-id":  true,
+}
+
+//azureTags returns the Azure tags of a resource made of the user tags and of the attributes stored as tags
+func azureTags(tags map[string]string, attributes map[string]*string) map[string]*string {
+	res := make(map[string]*string, len(tags)+len(attributes))
+	for k, v := range tags {
+		if !reservedTags[k] {
+			res[k] = to.StringPtr(v)
+		}
+	}
+	for k, v := range attributes {
+		res[k] = v
+	}
+	return res
+}
+
+//userTags returns the tags that are not reserved or nil if there is none
+func userTags(tags map[string]*string) map[string]string {
+	var res map[string]string
+	for k, v := range tags {
+		if reservedTags[k] || v == nil {
+			continue
+		}
+		if res == nil {
+			res = map[string]string{}
+		}
+		res[k] = *v
+	}
+	return res
+}
+
+//TagManager azure implementation of api.TagManager based on resource tags
+type TagManager struct {
+	Provider *Provider
+}
+
+func (mgr *TagManager) resourceGroup() string {
+	return mgr.Provider.Configuration.ResourceGroupName
+}
+
+//get returns the tags of resource
+func (mgr *TagManager) get(ctx context.Context, resource api.TaggedResource) (map[string]*string, error) {
+	clients := &mgr.Provider.BaseServices
+	switch resource.Type {
+	case api.ResourceServer:
+		vm, err := clients.VirtualMachinesClient.Get(ctx, mgr.resourceGroup(), resource.ID, "")
+		return vm.Tags, err
+	case api.ResourceVolume:
+		d, err := clients.DisksClient.Get(ctx, mgr.resourceGroup(), resource.ID)
+		return d.Tags, err
+	case api.ResourceNetwork:
+		n, err := clients.VirtualNetworksClient.Get(ctx, mgr.resourceGroup(), resource.ID, "")
+		return n.Tags, err
+	case api.ResourceSecurityGroup:
+		sg, err := clients.SecurityGroupsClient.Get(ctx, mgr.resourceGroup(), resource.ID, "")
+		return sg.Tags, err
+	case api.ResourcePublicIP:
+		ip, err := clients.PublicIPAddressesClient.Get(ctx, mgr.resourceGroup(), resource.ID, "")
+		return ip.Tags, err
+	case api.ResourceNetworkInterface:
+		ni, err := clients.InterfacesClient.Get(ctx, mgr.resourceGroup(), resource.ID, "")
+		return ni.Tags, err
+	}
+	return nil, api.WithKind(errors.Errorf("resources of type %s cannot be tagged", resource.Type), api.ErrInvalidArgument)
+}
+
+//update replaces the tags of resource by the result of fn
+func (mgr *TagManager) update(ctx context.Context, resource api.TaggedResource, fn func(tags map[string]*string) map[string]*string) error {
+	clients := &mgr.Provider.BaseServices
+	switch resource.Type {
+	case api.ResourceServer:
+		vm, err := clients.VirtualMachinesClient.Get(ctx, mgr.resourceGroup(), resource.ID, "")
+		if err != nil {
+			return err
+		}
+		vm.Tags = fn(vm.Tags)
+		vm.InstanceView = nil
+		future, err := clients.VirtualMachinesClient.CreateOrUpdate(ctx, mgr.resourceGroup(), resource.ID, vm)
+		if err != nil {
+			return err
+		}
+		return future.WaitForCompletionRef(ctx, clients.VirtualMachinesClient.Client)
+	case api.ResourceVolume:
+		d, err := clients.DisksClient.Get(ctx, mgr.resourceGroup(), resource.ID)
+		if err != nil {
+			return err
+		}
+		d.Tags = fn(d.Tags)
+		future, err := clients.DisksClient.CreateOrUpdate(ctx, mgr.resourceGroup(), resource.ID, d)
+		if err != nil {
+			return err
+		}
+		return future.WaitForCompletionRef(ctx, clients.DisksClient.Client)
+	case api.ResourceNetwork:
+		n, err := clients.VirtualNetworksClient.Get(ctx, mgr.resourceGroup(), resource.ID, "")
+		if err != nil {
+			return err
+		}
+		n.Tags = fn(n.Tags)
+		future, err := clients.VirtualNetworksClient.CreateOrUpdate(ctx, mgr.resourceGroup(), resource.ID, n)
+		if err != nil {
+			return err
+		}
+		return future.WaitForCompletionRef(ctx, clients.VirtualNetworksClient.Client)
+	case api.ResourceSecurityGroup:
+		sg, err := clients.SecurityGroupsClient.Get(ctx, mgr.resourceGroup(), resource.ID, "")
+		if err != nil {
+			return err
+		}
+		sg.Tags = fn(sg.Tags)
+		future, err := clients.SecurityGroupsClient.CreateOrUpdate(ctx, mgr.resourceGroup(), resource.ID, sg)
+		if err != nil {
+			return err
+		}
+		return future.WaitForCompletionRef(ctx, clients.SecurityGroupsClient.Client)
+	case api.ResourcePublicIP:
+		ip, err := clients.PublicIPAddressesClient.Get(ctx, mgr.resourceGroup(), resource.ID, "")
+		if err != nil {
+			return err
+		}
+		ip.Tags = fn(ip.Tags)
+		future, err := clients.PublicIPAddressesClient.CreateOrUpdate(ctx, mgr.resourceGroup(), resource.ID, ip)
+		if err != nil {
+			return err
+		}
+		return future.WaitForCompletionRef(ctx, clients.PublicIPAddressesClient.Client)
+	case api.ResourceNetworkInterface:
+		ni, err := clients.InterfacesClient.Get(ctx, mgr.resourceGroup(), resource.ID, "")
+		if err != nil {
+			return err
+		}
+		ni.Tags = fn(ni.Tags)
+		future, err := clients.InterfacesClient.CreateOrUpdate(ctx, mgr.resourceGroup(), resource.ID, ni)
+		if err != nil {
+			return err
+		}
+		return future.WaitForCompletionRef(ctx, clients.InterfacesClient.Client)
+	}
+	return api.WithKind(errors.Errorf("resources of type %s cannot be tagged", resource.Type), api.ErrInvalidArgument)
+}
+
+func checkTagKeys(keys []string) error {
+	for _, k := range keys {
+		if len(k) == 0 {
+			return api.WithKind(errors.Errorf("tag keys cannot be empty"), api.ErrInvalidArgument)
+		}
+		if reservedTags[k] {
+			return api.WithKind(errors.Errorf("tag key %s is reserved", k), api.ErrInvalidArgument)
+		}
+	}
+	return nil
+}
+
+func (mgr *TagManager) add(ctx context.Context, options *api.AddTagsOptions) error {
+	keys := make([]string, 0, len(options.Tags))
+	for k := range options.Tags {
+		keys = append(keys, k)
+	}
+	if err := checkTagKeys(keys); err != nil {
+		return err
+	}
+	return mgr.update(ctx, options.Resource, func(tags map[string]*string) map[string]*string {
+		if tags == nil {
+			tags = make(map[string]*string, len(options.Tags))
+		}
+		for k, v := range options.Tags {
+			tags[k] = to.StringPtr(v)
+		}
+		return tags
+	})
+}
+
+//AddWithContext adds tags to a resource
+func (mgr *TagManager) AddWithContext(ctx context.Context, options api.AddTagsOptions) api.AddTagsError {
+	return api.NewAddTagsError(UnwrapAzureError(mgr.add(ctx, &options)), options)
+}
+
+//Add adds tags to a resource
+func (mgr *TagManager) Add(options api.AddTagsOptions) api.AddTagsError {
+	return mgr.AddWithContext(context.Background(), options)
+}
+
+func (mgr *TagManager) remove(ctx context.Context, options *api.RemoveTagsOptions) error {
+	if err := checkTagKeys(options.Keys); err != nil {
+		return err
+	}
+	return mgr.update(ctx, options.Resource, func(tags map[string]*string) map[string]*string {
+		if tags == nil {
+			tags = map[string]*string{}
+		}
+		for _, k := range options.Keys {
+			delete(tags, k)
+		}
+		return tags
+	})
+}
+
+//RemoveWithContext removes tags from a resource
+func (mgr *TagManager) RemoveWithContext(ctx context.Context, options api.RemoveTagsOptions) api.RemoveTagsError {
+	return api.NewRemoveTagsError(UnwrapAzureError(mgr.remove(ctx, &options)), options)
+}
+
+//Remove removes tags from a resource
+func (mgr *TagManager) Remove(options api.RemoveTagsOptions) api.RemoveTagsError {
+	return mgr.RemoveWithContext(context.Background(), options)
+}
+
+//ListWithContext lists the tags of a resource
+func (mgr *TagManager) ListWithContext(ctx context.Context, resource api.TaggedResource) (map[string]string, api.ListTagsError) {
+	tags, err := mgr.get(ctx, resource)
+	if err != nil {
+		return nil, api.NewListTagsError(UnwrapAzureError(err), resource)
+	}
+	res := userTags(tags)
+	if res == nil {
+		res = map[string]string{}
+	}
+	return res, nil
+}
+
+//List lists the tags of a resource
+func (mgr *TagManager) List(resource api.TaggedResource) (map[string]string, api.ListTagsError) {
+	return mgr.ListWithContext(context.Background(), resource)
+}
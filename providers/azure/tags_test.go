@@ -0,0 +1,21 @@
+package azure_test
+
+import (
+	"testing"
+
+	"github.com/SebastienDorgan/anyclouds/tests"
+	"github.com/stretchr/testify/suite"
+)
+
+type AZTagManagerTestSuite struct {
+	tests.TagManagerTestSuite
+}
+
+//SetupSuite set up tag manager
+func (suite *AZTagManagerTestSuite) SetupSuite() {
+	suite.Prov = GetProvider()
+}
+
+func TestAZTagManagerTestSuite(t *testing.T) {
+	suite.Run(t, new(AZTagManagerTestSuite))
+}
@@ -13,13 +13,13 @@ type ServerTemplateManager struct {
 	Provider *Provider
 }
 
-func (mgr *ServerTemplateManager) GetVMMeters() ([]commerce.MeterInfo, error) {
-	filter := fmt.Sprintf("OfferDurableId eq ’%s’ and Currency eq ’%s’ and Locale eq ’en-US’ and RegionInfo eq ’%s’",
+func (mgr *ServerTemplateManager) GetVMMeters(ctx context.Context) ([]commerce.MeterInfo, error) {
+	filter := fmt.Sprintf("OfferDurableId eq '%s' and Currency eq '%s' and Locale eq 'en-US' and RegionInfo eq '%s'",
 		mgr.Provider.Configuration.OfferNumber,
 		mgr.Provider.Configuration.Currency,
 		mgr.Provider.Configuration.RegionInfo)
 
-	result, err := mgr.Provider.BaseServices.RateCardClient.Get(context.Background(), filter)
+	result, err := mgr.Provider.BaseServices.RateCardClient.Get(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -39,22 +39,29 @@ func (mgr *ServerTemplateManager) GetVMMeters() ([]commerce.MeterInfo, error) {
 
 func GetMeter(vmMeters []commerce.MeterInfo, sizeName string) *commerce.MeterInfo {
 	for _, mi := range vmMeters {
-		if *mi.MeterSubCategory == sizeName {
+		if mi.MeterSubCategory != nil && *mi.MeterSubCategory == sizeName {
 			return &mi
 		}
 	}
 	return nil
 }
 
-func (mgr *ServerTemplateManager) List() ([]api.ServerTemplate, api.ListServerTemplatesError) {
-	list, err := mgr.Provider.BaseServices.VirtualMachineSizesClient.List(context.Background(), mgr.Provider.Configuration.Location)
+//ListWithContext context aware version of List
+func (mgr *ServerTemplateManager) ListWithContext(ctx context.Context) ([]api.ServerTemplate, api.ListServerTemplatesError) {
+	list, err := mgr.Provider.BaseServices.VirtualMachineSizesClient.List(ctx, mgr.Provider.Configuration.Location)
 	if err != nil {
-		return nil, api.NewListServerTemplatesError(err)
+		return nil, api.NewListServerTemplatesError(UnwrapAzureError(err))
 	}
 	var templates []api.ServerTemplate
-	vmMeters, err := mgr.GetVMMeters()
+	vmMeters, err := mgr.GetVMMeters(ctx)
+	if err != nil {
+		return nil, api.NewListServerTemplatesError(UnwrapAzureError(err))
+	}
 	for _, size := range *list.Value {
-		meterInfo := GetMeter(vmMeters, *size.Name)
+		var price float32
+		if meterInfo := GetMeter(vmMeters, *size.Name); meterInfo != nil && meterInfo.MeterRates["0"] != nil {
+			price = float32(*meterInfo.MeterRates["0"])
+		}
 		templates = append(templates, api.ServerTemplate{
 			ID:                *size.Name,
 			Name:              *size.Name,
@@ -62,27 +69,36 @@ func (mgr *ServerTemplateManager) List() ([]api.ServerTemplate, api.ListServerTe
 			RAMSize:           int(*size.MemoryInMB),
 			SystemDiskSize:    int(*size.OsDiskSizeInMB / 1000),
 			EphemeralDiskSize: int(*size.ResourceDiskSizeInMB / 1000),
-			CreatedAt:         time.Date(2016, 1, 1, 0, 0, 0, 0, nil),
+			CreatedAt:         time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC),
 			Arch:              api.ArchAmd64,
 			CPUFrequency:      0,
 			NetworkSpeed:      0,
 			GPUInfo:           nil,
-			OneDemandPrice:    float32(*meterInfo.MeterRates["0"]),
+			OneDemandPrice:    price,
 		})
 	}
 
 	return templates, nil
 }
 
-func (mgr *ServerTemplateManager) Get(id string) (*api.ServerTemplate, api.GetServerTemplateError) {
-	list, err := mgr.List()
+func (mgr *ServerTemplateManager) List() ([]api.ServerTemplate, api.ListServerTemplatesError) {
+	return mgr.ListWithContext(context.Background())
+}
+
+//GetWithContext context aware version of Get
+func (mgr *ServerTemplateManager) GetWithContext(ctx context.Context, id string) (*api.ServerTemplate, api.GetServerTemplateError) {
+	list, err := mgr.ListWithContext(ctx)
 	if err != nil {
-		return nil, api.NewGetServerTemplateError(err, id)
+		return nil, api.NewGetServerTemplateError(UnwrapAzureError(err), id)
 	}
 	for _, tpl := range list {
 		if tpl.ID == id {
 			return &tpl, nil
 		}
 	}
-	return nil, api.NewGetServerTemplateError(err, id)
+	return nil, api.NewGetServerTemplateError(UnwrapAzureError(err), id)
+}
+
+func (mgr *ServerTemplateManager) Get(id string) (*api.ServerTemplate, api.GetServerTemplateError) {
+	return mgr.GetWithContext(context.Background(), id)
 }
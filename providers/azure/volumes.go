@@ -0,0 +1,426 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/compute/mgmt/compute"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+//VolumeManager defines volume management functions an anyclouds provider must provide
+type VolumeManager struct {
+	Provider *Provider
+}
+
+func (mgr *VolumeManager) resourceGroup() string {
+	return mgr.Provider.Configuration.ResourceGroupName
+}
+
+//diskPerformance performance of the disks of a SKU whose size is at most MaxSize GB
+type diskPerformance struct {
+	MaxSize  int64
+	IOPS     int64
+	DataRate int64
+}
+
+//diskPerformances performances of the managed disk SKUs ordered by price, UltraSSD_LRS disks have a provisioned performance
+//https://docs.microsoft.com/en-us/azure/virtual-machines/linux/disks-types
+var diskPerformances = []struct {
+	Sku   compute.DiskStorageAccountTypes
+	Tiers []diskPerformance
+}{
+	{compute.StandardLRS, []diskPerformance{{4096, 500, 60}, {8192, 1300, 300}, {16384, 2000, 500}, {32767, 2000, 500}}},
+	{compute.StandardSSDLRS, []diskPerformance{{4096, 500, 60}, {8192, 2000, 400}, {16384, 4000, 600}, {32767, 6000, 750}}},
+	{compute.PremiumLRS, []diskPerformance{
+		{32, 120, 25}, {64, 240, 50}, {128, 500, 100}, {256, 1100, 125}, {512, 2300, 150}, {1024, 5000, 200},
+		{4096, 7500, 250}, {8192, 16000, 500}, {16384, 18000, 750}, {32767, 20000, 900},
+	}},
+}
+
+//performance returns the performance of a disk of the given sku and size, ok is false if the sku has no fixed performance
+func performance(sku compute.DiskStorageAccountTypes, size int64) (p diskPerformance, ok bool) {
+	for _, perf := range diskPerformances {
+		if perf.Sku != sku {
+			continue
+		}
+		for _, t := range perf.Tiers {
+			if size <= t.MaxSize {
+				return t, true
+			}
+		}
+	}
+	return diskPerformance{}, false
+}
+
+//selectDiskSku returns the cheapest SKU providing the performance required by options
+//UltraSSD_LRS disks are selected when no other SKU fits, their IOPS and data rate are then provisioned
+func (mgr *VolumeManager) selectDiskSku(options *api.CreateVolumeOptions) (sku compute.DiskStorageAccountTypes, iops *int64, dataRate *int32) {
+	for _, perf := range diskPerformances {
+		p, ok := performance(perf.Sku, options.Size)
+		if ok && p.IOPS >= options.MinIOPS && p.DataRate >= options.MinDataRate {
+			return perf.Sku, nil, nil
+		}
+	}
+	minIOPS := options.MinIOPS
+	if minIOPS < options.MinDataRate*4 {
+		minIOPS = options.MinDataRate * 4
+	}
+	if minIOPS < 100 {
+		minIOPS = 100
+	}
+	minDataRate := options.MinDataRate
+	if minDataRate < 1 {
+		minDataRate = 1
+	}
+	return compute.UltraSSDLRS, to.Int64Ptr(minIOPS), to.Int32Ptr(int32(minDataRate))
+}
+
+func volume(d *compute.Disk) *api.Volume {
+	v := &api.Volume{
+		ID:   *d.Name,
+		Tags: userTags(d.Tags),
+	}
+	if name, ok := d.Tags["name"]; ok && name != nil {
+		v.Name = *name
+	}
+	if d.DiskProperties == nil {
+		return v
+	}
+	if d.DiskSizeGB != nil {
+		v.Size = int64(*d.DiskSizeGB)
+	}
+	if d.Sku != nil {
+		if p, ok := performance(d.Sku.Name, v.Size); ok {
+			v.IOPS = p.IOPS
+			v.DataRate = p.DataRate
+		}
+	}
+	if d.DiskIOPSReadWrite != nil {
+		v.IOPS = *d.DiskIOPSReadWrite
+	}
+	if d.DiskMBpsReadWrite != nil {
+		v.DataRate = int64(*d.DiskMBpsReadWrite)
+	}
+	return v
+}
+
+//createOrUpdate creates or updates the disk d and returns the resulting volume
+func (mgr *VolumeManager) createOrUpdate(ctx context.Context, d *compute.Disk) (*api.Volume, error) {
+	future, err := mgr.Provider.BaseServices.DisksClient.CreateOrUpdate(ctx, mgr.resourceGroup(), *d.Name, *d)
+	if err != nil {
+		return nil, err
+	}
+	err = future.WaitForCompletionRef(ctx, mgr.Provider.BaseServices.DisksClient.Client)
+	if err != nil {
+		return nil, err
+	}
+	res, err := mgr.Provider.BaseServices.DisksClient.Get(ctx, mgr.resourceGroup(), *d.Name)
+	if err != nil {
+		return nil, err
+	}
+	return volume(&res), nil
+}
+
+//CreateWithContext creates a volume with options
+func (mgr *VolumeManager) CreateWithContext(ctx context.Context, options api.CreateVolumeOptions) (*api.Volume, api.CreateVolumeError) {
+	sku, iops, dataRate := mgr.selectDiskSku(&options)
+	v, err := mgr.createOrUpdate(ctx, &compute.Disk{
+		Name:     to.StringPtr(uuid.New().String()),
+		Location: to.StringPtr(mgr.Provider.Configuration.Location),
+		Tags:     azureTags(options.Tags, map[string]*string{"name": to.StringPtr(options.Name)}),
+		Sku:      &compute.DiskSku{Name: sku},
+		DiskProperties: &compute.DiskProperties{
+			CreationData:      &compute.CreationData{CreateOption: compute.Empty},
+			DiskSizeGB:        to.Int32Ptr(int32(options.Size)),
+			DiskIOPSReadWrite: iops,
+			DiskMBpsReadWrite: dataRate,
+		},
+	})
+	return v, api.NewCreateVolumeError(UnwrapAzureError(err), options)
+}
+
+//Create creates a volume with options
+func (mgr *VolumeManager) Create(options api.CreateVolumeOptions) (*api.Volume, api.CreateVolumeError) {
+	return mgr.CreateWithContext(context.Background(), options)
+}
+
+func (mgr *VolumeManager) delete(ctx context.Context, id string) error {
+	future, err := mgr.Provider.BaseServices.DisksClient.Delete(ctx, mgr.resourceGroup(), id)
+	if err != nil {
+		return err
+	}
+	return future.WaitForCompletionRef(ctx, mgr.Provider.BaseServices.DisksClient.Client)
+}
+
+//DeleteWithContext deletes volume identified by id
+func (mgr *VolumeManager) DeleteWithContext(ctx context.Context, id string) api.DeleteVolumeError {
+	return api.NewDeleteVolumeError(UnwrapAzureError(mgr.delete(ctx, id)), id)
+}
+
+//Delete deletes volume identified by id
+func (mgr *VolumeManager) Delete(id string) api.DeleteVolumeError {
+	return mgr.DeleteWithContext(context.Background(), id)
+}
+
+//ListWithContext lists volumes
+func (mgr *VolumeManager) ListWithContext(ctx context.Context) ([]api.Volume, api.ListVolumesError) {
+	it, err := mgr.Provider.BaseServices.DisksClient.ListByResourceGroupComplete(ctx, mgr.resourceGroup())
+	if err != nil {
+		return nil, api.NewListVolumesError(UnwrapAzureError(err))
+	}
+	var volumes []api.Volume
+	for it.NotDone() {
+		d := it.Value()
+		volumes = append(volumes, *volume(&d))
+		err = it.NextWithContext(ctx)
+		if err != nil {
+			return nil, api.NewListVolumesError(UnwrapAzureError(err))
+		}
+	}
+	return volumes, nil
+}
+
+//List lists volumes
+func (mgr *VolumeManager) List() ([]api.Volume, api.ListVolumesError) {
+	return mgr.ListWithContext(context.Background())
+}
+
+//GetWithContext returns volume details
+func (mgr *VolumeManager) GetWithContext(ctx context.Context, id string) (*api.Volume, api.GetVolumeError) {
+	d, err := mgr.Provider.BaseServices.DisksClient.Get(ctx, mgr.resourceGroup(), id)
+	if err != nil {
+		return nil, api.NewGetVolumeError(UnwrapAzureError(err), id)
+	}
+	return volume(&d), nil
+}
+
+//Get returns volume details
+func (mgr *VolumeManager) Get(id string) (*api.Volume, api.GetVolumeError) {
+	return mgr.GetWithContext(context.Background(), id)
+}
+
+func (mgr *VolumeManager) resize(ctx context.Context, options *api.ResizeVolumeOptions) (*api.Volume, error) {
+	d, err := mgr.Provider.BaseServices.DisksClient.Get(ctx, mgr.resourceGroup(), options.ID)
+	if err != nil {
+		return nil, err
+	}
+	sku, iops, dataRate := mgr.selectDiskSku(&api.CreateVolumeOptions{
+		Size:        options.Size,
+		MinIOPS:     options.MinIOPS,
+		MinDataRate: options.MinDataRate,
+	})
+	//the SKU of an UltraSSD_LRS disk cannot be changed, its performance is provisioned
+	if d.Sku != nil && d.Sku.Name == compute.UltraSSDLRS && sku != compute.UltraSSDLRS {
+		iops, dataRate = d.DiskIOPSReadWrite, d.DiskMBpsReadWrite
+		sku = compute.UltraSSDLRS
+	}
+	d.Sku = &compute.DiskSku{Name: sku}
+	d.DiskSizeGB = to.Int32Ptr(int32(options.Size))
+	d.DiskIOPSReadWrite = iops
+	d.DiskMBpsReadWrite = dataRate
+	return mgr.createOrUpdate(ctx, &d)
+}
+
+//ResizeWithContext context aware version of Resize
+func (mgr *VolumeManager) ResizeWithContext(ctx context.Context, options api.ResizeVolumeOptions) (*api.Volume, api.ResizeVolumeError) {
+	v, err := mgr.resize(ctx, &options)
+	return v, api.NewResizeVolumeError(UnwrapAzureError(err), options)
+}
+
+//Resize resizes a volume, the volume must be detached or its server deallocated
+func (mgr *VolumeManager) Resize(options api.ResizeVolumeOptions) (*api.Volume, api.ResizeVolumeError) {
+	return mgr.ResizeWithContext(context.Background(), options)
+}
+
+//lunDevicePrefix prefix of the paths of the data disks identified by their LUN
+const lunDevicePrefix = "/dev/disk/azure/scsi1/lun"
+
+//lun returns the LUN of the data disk attached to device path, -1 if the device path is empty
+//sda and sdb are respectively the OS disk and the temporary disk, data disks are then named after their LUN from sdc
+func lun(devicePath string) (int32, error) {
+	if devicePath == "" {
+		return -1, nil
+	}
+	if strings.HasPrefix(devicePath, lunDevicePrefix) {
+		n, err := strconv.Atoi(strings.TrimPrefix(devicePath, lunDevicePrefix))
+		if err == nil && n >= 0 && n < 64 {
+			return int32(n), nil
+		}
+	} else if len(devicePath) == len("/dev/sdc") && strings.HasPrefix(devicePath, "/dev/sd") {
+		if c := devicePath[len(devicePath)-1]; c >= 'c' && c <= 'z' {
+			return int32(c - 'c'), nil
+		}
+	}
+	return 0, api.WithKind(errors.Errorf("invalid device path %s", devicePath), api.ErrInvalidArgument)
+}
+
+//devicePath returns the device path of the data disk attached to lun
+func devicePath(lun int32) string {
+	if lun < 'z'-'c'+1 {
+		return fmt.Sprintf("/dev/sd%c", 'c'+lun)
+	}
+	return fmt.Sprintf("%s%d", lunDevicePrefix, lun)
+}
+
+//freeLun returns the lowest LUN not used by disks
+func freeLun(disks []compute.DataDisk) int32 {
+	used := make(map[int32]bool)
+	for _, dd := range disks {
+		if dd.Lun != nil {
+			used[*dd.Lun] = true
+		}
+	}
+	var l int32
+	for used[l] {
+		l++
+	}
+	return l
+}
+
+func attachment(vm *compute.VirtualMachine, dd *compute.DataDisk) *api.VolumeAttachment {
+	volumeID := resourceName(*dd.ManagedDisk.ID)
+	device := devicePath(*dd.Lun)
+	return &api.VolumeAttachment{
+		ID:       fmt.Sprintf("%s#%s#%s", volumeID, *vm.Name, device),
+		VolumeID: volumeID,
+		ServerID: *vm.Name,
+		Device:   device,
+	}
+}
+
+//updateDataDisks replaces the data disks of vm by disks
+func (mgr *VolumeManager) updateDataDisks(ctx context.Context, vm *compute.VirtualMachine, disks []compute.DataDisk) error {
+	vm.StorageProfile.DataDisks = &disks
+	vm.InstanceView = nil
+	future, err := mgr.Provider.BaseServices.VirtualMachinesClient.CreateOrUpdate(ctx, mgr.resourceGroup(), *vm.Name, *vm)
+	if err != nil {
+		return err
+	}
+	return future.WaitForCompletionRef(ctx, mgr.Provider.BaseServices.VirtualMachinesClient.Client)
+}
+
+func (mgr *VolumeManager) attach(ctx context.Context, options *api.AttachVolumeOptions) (*api.VolumeAttachment, error) {
+	l, err := lun(options.DevicePath)
+	if err != nil {
+		return nil, err
+	}
+	d, err := mgr.Provider.BaseServices.DisksClient.Get(ctx, mgr.resourceGroup(), options.VolumeID)
+	if err != nil {
+		return nil, err
+	}
+	vm, err := mgr.Provider.ServerManager.get(ctx, options.ServerID)
+	if err != nil {
+		return nil, err
+	}
+	var disks []compute.DataDisk
+	if vm.StorageProfile.DataDisks != nil {
+		disks = *vm.StorageProfile.DataDisks
+	}
+	if l < 0 {
+		l = freeLun(disks)
+	}
+	dd := compute.DataDisk{
+		Lun:          to.Int32Ptr(l),
+		Name:         d.Name,
+		Caching:      compute.CachingTypesNone,
+		CreateOption: compute.DiskCreateOptionTypesAttach,
+		ManagedDisk:  &compute.ManagedDiskParameters{ID: d.ID},
+	}
+	err = mgr.updateDataDisks(ctx, vm, append(disks, dd))
+	if err != nil {
+		return nil, err
+	}
+	return attachment(vm, &dd), nil
+}
+
+//AttachWithContext attaches a volume to a server as a data disk, the LUN of the data disk is deduced from the device path
+func (mgr *VolumeManager) AttachWithContext(ctx context.Context, options api.AttachVolumeOptions) (*api.VolumeAttachment, api.AttachVolumeError) {
+	att, err := mgr.attach(ctx, &options)
+	return att, api.NewAttachVolumeError(UnwrapAzureError(err), options)
+}
+
+//Attach attaches a volume to a server
+func (mgr *VolumeManager) Attach(options api.AttachVolumeOptions) (*api.VolumeAttachment, api.AttachVolumeError) {
+	return mgr.AttachWithContext(context.Background(), options)
+}
+
+func isDataDisk(dd *compute.DataDisk, volumeID string) bool {
+	return dd.ManagedDisk != nil && dd.ManagedDisk.ID != nil && strings.EqualFold(resourceName(*dd.ManagedDisk.ID), volumeID)
+}
+
+func (mgr *VolumeManager) detach(ctx context.Context, options *api.DetachVolumeOptions) error {
+	vm, err := mgr.Provider.ServerManager.get(ctx, options.ServerID)
+	if err != nil {
+		return err
+	}
+	var disks []compute.DataDisk
+	found := false
+	if vm.StorageProfile.DataDisks != nil {
+		for _, dd := range *vm.StorageProfile.DataDisks {
+			if isDataDisk(&dd, options.VolumeID) {
+				found = true
+			} else {
+				disks = append(disks, dd)
+			}
+		}
+	}
+	if !found {
+		return api.WithKind(errors.Errorf("volume %s is not attached to server %s", options.VolumeID, options.ServerID), api.ErrNotFound)
+	}
+	if disks == nil {
+		disks = []compute.DataDisk{}
+	}
+	return mgr.updateDataDisks(ctx, vm, disks)
+}
+
+//DetachWithContext detaches a volume from a server
+func (mgr *VolumeManager) DetachWithContext(ctx context.Context, options api.DetachVolumeOptions) api.DetachVolumeError {
+	return api.NewDetachVolumeError(UnwrapAzureError(mgr.detach(ctx, &options)), options)
+}
+
+//Detach detaches a volume from a server
+func (mgr *VolumeManager) Detach(options api.DetachVolumeOptions) api.DetachVolumeError {
+	return mgr.DetachWithContext(context.Background(), options)
+}
+
+func (mgr *VolumeManager) attachments(ctx context.Context, options *api.ListAttachmentsOptions) ([]api.VolumeAttachment, error) {
+	vms, err := mgr.Provider.ServerManager.list(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var attachments []api.VolumeAttachment
+	for _, vm := range vms {
+		if options.ServerID != nil && !strings.EqualFold(*vm.Name, *options.ServerID) {
+			continue
+		}
+		if vm.StorageProfile == nil || vm.StorageProfile.DataDisks == nil {
+			continue
+		}
+		for _, dd := range *vm.StorageProfile.DataDisks {
+			if dd.ManagedDisk == nil || dd.ManagedDisk.ID == nil || dd.Lun == nil {
+				continue
+			}
+			if options.VolumeID == nil || isDataDisk(&dd, *options.VolumeID) {
+				attachments = append(attachments, *attachment(&vm, &dd))
+			}
+		}
+	}
+	return attachments, nil
+}
+
+//ListAttachmentsWithContext returns the attachments of the volumes
+func (mgr *VolumeManager) ListAttachmentsWithContext(ctx context.Context, options *api.ListAttachmentsOptions) ([]api.VolumeAttachment, api.ListVolumeAttachmentsError) {
+	attachments, err := mgr.attachments(ctx, options)
+	return attachments, api.NewListVolumeAttachmentsError(UnwrapAzureError(err), options)
+}
+
+//ListAttachments returns the attachments of the volumes
+func (mgr *VolumeManager) ListAttachments(options *api.ListAttachmentsOptions) ([]api.VolumeAttachment, api.ListVolumeAttachmentsError) {
+	return mgr.ListAttachmentsWithContext(context.Background(), options)
+}
@@ -0,0 +1,116 @@
+package azure_test
+
+import (
+	"testing"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/SebastienDorgan/anyclouds/sshutils"
+	"github.com/SebastienDorgan/anyclouds/tests"
+	"github.com/stretchr/testify/suite"
+)
+
+type AZVolumeManagerTestSuite struct {
+	tests.VolumeManagerTestSuite
+}
+
+//SetupSuite set up volume manager
+func (suite *AZVolumeManagerTestSuite) SetupSuite() {
+	suite.Prov = GetProvider()
+}
+
+//TestVolumeManager replaces the test of the suite, Azure has neither a default network nor a default security group
+func (suite *AZVolumeManagerTestSuite) TestVolumeManager() {
+	tpl, err := suite.SelectTemplate()
+	suite.NoError(err)
+	img, err := suite.FindImage(tpl)
+	suite.NoError(err)
+	kp, err := sshutils.CreateKeyPair(4096)
+	suite.NoError(err)
+	n, err := suite.Prov.GetNetworkManager().CreateNetwork(api.CreateNetworkOptions{
+		Name: "volume-network",
+		CIDR: "10.2.0.0/16",
+	})
+	suite.NoError(err)
+	sn, err := suite.Prov.GetNetworkManager().CreateSubnet(api.CreateSubnetOptions{
+		NetworkID: n.ID,
+		Name:      "subnet",
+		CIDR:      "10.2.0.0/24",
+		IPVersion: api.IPVersion4,
+	})
+	suite.NoError(err)
+	sg, err := suite.Prov.GetSecurityGroupManager().Create(api.SecurityGroupOptions{
+		Name:        "volume-sg",
+		Description: "volume test security group",
+		NetworkID:   n.ID,
+	})
+	suite.NoError(err)
+	server, err := suite.Prov.GetServerManager().Create(api.CreateServerOptions{
+		Name:                 "instance_with_volume",
+		TemplateID:           tpl.ID,
+		ImageID:              img.ID,
+		Subnets:              []api.Subnet{*sn},
+		DefaultSecurityGroup: sg.ID,
+		KeyPair:              *kp,
+	})
+	suite.NoError(err)
+
+	v, err := suite.Prov.GetVolumeManager().Create(api.CreateVolumeOptions{
+		Name:        "my volume",
+		Size:        5,
+		MinIOPS:     250,
+		MinDataRate: 250,
+	})
+	suite.NoError(err)
+	suite.Equal("my volume", v.Name)
+	suite.Equal(int64(5), v.Size)
+	suite.True(v.IOPS >= 250)
+	suite.True(v.DataRate >= 250)
+
+	att, err := suite.Prov.GetVolumeManager().Attach(api.AttachVolumeOptions{
+		VolumeID:   v.ID,
+		ServerID:   server.ID,
+		DevicePath: "/dev/sdh",
+	})
+	suite.NoError(err)
+	suite.Equal("/dev/sdh", att.Device)
+	atts, err := suite.Prov.GetVolumeManager().ListAttachments(&api.ListAttachmentsOptions{
+		VolumeID: &v.ID,
+	})
+	suite.NoError(err)
+	suite.Equal([]api.VolumeAttachment{*att}, atts)
+	_, err = suite.Prov.GetVolumeManager().Resize(api.ResizeVolumeOptions{
+		ID:   v.ID,
+		Size: 10,
+	})
+	suite.Error(err)
+
+	err = suite.Prov.GetVolumeManager().Detach(api.DetachVolumeOptions{
+		VolumeID: v.ID,
+		ServerID: server.ID,
+	})
+	suite.NoError(err)
+	atts, err = suite.Prov.GetVolumeManager().ListAttachments(&api.ListAttachmentsOptions{
+		ServerID: &server.ID,
+	})
+	suite.NoError(err)
+	suite.Empty(atts)
+	v, err = suite.Prov.GetVolumeManager().Resize(api.ResizeVolumeOptions{
+		ID:   v.ID,
+		Size: 10,
+	})
+	suite.NoError(err)
+	suite.Equal(int64(10), v.Size)
+
+	err = suite.Prov.GetVolumeManager().Delete(v.ID)
+	suite.NoError(err)
+	err = suite.Prov.GetServerManager().Delete(server.ID)
+	suite.NoError(err)
+	err = suite.Prov.GetSecurityGroupManager().Delete(sg.ID)
+	suite.NoError(err)
+	err = suite.Prov.GetNetworkManager().DeleteNetwork(n.ID)
+	suite.NoError(err)
+}
+
+func TestAZVolumeManagerTestSuite(t *testing.T) {
+	suite.Run(t, new(AZVolumeManagerTestSuite))
+}
@@ -0,0 +1,245 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+)
+
+//DNSManager memory implementation of api.DNSManager
+type DNSManager struct {
+	Provider *Provider
+}
+
+//zone DNS zone and its record sets indexed by name and type
+type zone struct {
+	api.Zone
+	records map[string]*api.Record
+}
+
+func recordKey(name string, t api.RecordType) string {
+	return name + "/" + string(t)
+}
+
+func copyZone(z *zone) *api.Zone {
+	res := z.Zone
+	res.NameServers = append([]string{}, z.NameServers...)
+	return &res
+}
+
+func copyRecord(r *api.Record) *api.Record {
+	res := *r
+	res.Values = append([]string{}, r.Values...)
+	return &res
+}
+
+func (mgr *DNSManager) createZone(options api.CreateZoneOptions) (*api.Zone, error) {
+	err := api.CheckZoneOptions(&options)
+	if err != nil {
+		return nil, err
+	}
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	name := api.ZoneName(options.Name)
+	for _, z := range p.store.zones {
+		if z.Name == name {
+			return nil, alreadyExists("zone %s already exists", name)
+		}
+	}
+	z := &zone{
+		Zone: api.Zone{
+			ID:   p.newID("zone"),
+			Name: name,
+		},
+		records: map[string]*api.Record{},
+	}
+	for i := 1; i <= 2; i++ {
+		z.NameServers = append(z.NameServers, fmt.Sprintf("ns%d.dns.memory", i))
+	}
+	p.store.zones[z.ID] = z
+	return copyZone(z), nil
+}
+
+//CreateZoneWithContext creates a DNS zone
+func (mgr *DNSManager) CreateZoneWithContext(ctx context.Context, options api.CreateZoneOptions) (*api.Zone, api.CreateZoneError) {
+	z, err := mgr.createZone(options)
+	if err != nil {
+		return nil, api.NewCreateZoneError(err, options)
+	}
+	return z, nil
+}
+
+//CreateZone creates a DNS zone
+func (mgr *DNSManager) CreateZone(options api.CreateZoneOptions) (*api.Zone, api.CreateZoneError) {
+	return mgr.CreateZoneWithContext(context.Background(), options)
+}
+
+func (mgr *DNSManager) deleteZone(id string) error {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if _, ok := p.store.zones[id]; !ok {
+		return notFound("zone %s not found", id)
+	}
+	delete(p.store.zones, id)
+	return nil
+}
+
+//DeleteZoneWithContext deletes the DNS zone identified by id and its records
+func (mgr *DNSManager) DeleteZoneWithContext(ctx context.Context, id string) api.DeleteZoneError {
+	err := mgr.deleteZone(id)
+	if err != nil {
+		return api.NewDeleteZoneError(err, id)
+	}
+	return nil
+}
+
+//DeleteZone deletes the DNS zone identified by id and its records
+func (mgr *DNSManager) DeleteZone(id string) api.DeleteZoneError {
+	return mgr.DeleteZoneWithContext(context.Background(), id)
+}
+
+//ListZonesWithContext lists DNS zones
+func (mgr *DNSManager) ListZonesWithContext(ctx context.Context) ([]api.Zone, api.ListZonesError) {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	res := []api.Zone{}
+	for _, z := range p.store.zones {
+		res = append(res, *copyZone(z))
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].ID < res[j].ID
+	})
+	return res, nil
+}
+
+//ListZones lists DNS zones
+func (mgr *DNSManager) ListZones() ([]api.Zone, api.ListZonesError) {
+	return mgr.ListZonesWithContext(context.Background())
+}
+
+//zone returns the zone identified by id, must be called with the lock held
+func (mgr *DNSManager) zone(id string) (*zone, error) {
+	z, ok := mgr.Provider.store.zones[id]
+	if !ok {
+		return nil, notFound("zone %s not found", id)
+	}
+	return z, nil
+}
+
+//GetZoneWithContext returns the DNS zone identified by id
+func (mgr *DNSManager) GetZoneWithContext(ctx context.Context, id string) (*api.Zone, api.GetZoneError) {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	z, err := mgr.zone(id)
+	if err != nil {
+		return nil, api.NewGetZoneError(err, id)
+	}
+	return copyZone(z), nil
+}
+
+//GetZone returns the DNS zone identified by id
+func (mgr *DNSManager) GetZone(id string) (*api.Zone, api.GetZoneError) {
+	return mgr.GetZoneWithContext(context.Background(), id)
+}
+
+func (mgr *DNSManager) upsertRecord(ctx context.Context, options api.UpsertRecordOptions) (*api.Record, error) {
+	resolved, err := api.ResolveRecordOptions(ctx, mgr.Provider.GetPublicIPAddressManager(), options)
+	if err != nil {
+		return nil, err
+	}
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	z, err := mgr.zone(options.ZoneID)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range z.records {
+		if r.Name == resolved.Name && (r.Type == api.RecordCNAME) != (resolved.Type == api.RecordCNAME) {
+			return nil, invalidArgument("a CNAME record cannot share its name %s with another record", r.Name)
+		}
+	}
+	r := &api.Record{
+		ZoneID: z.ID,
+		Name:   resolved.Name,
+		Type:   resolved.Type,
+		TTL:    resolved.TTL,
+		Values: resolved.Values,
+	}
+	r = copyRecord(r)
+	z.records[recordKey(r.Name, r.Type)] = r
+	return copyRecord(r), nil
+}
+
+//UpsertRecordWithContext creates or replaces a DNS record set
+func (mgr *DNSManager) UpsertRecordWithContext(ctx context.Context, options api.UpsertRecordOptions) (*api.Record, api.UpsertRecordError) {
+	r, err := mgr.upsertRecord(ctx, options)
+	if err != nil {
+		return nil, api.NewUpsertRecordError(err, options)
+	}
+	return r, nil
+}
+
+//UpsertRecord creates or replaces a DNS record set
+func (mgr *DNSManager) UpsertRecord(options api.UpsertRecordOptions) (*api.Record, api.UpsertRecordError) {
+	return mgr.UpsertRecordWithContext(context.Background(), options)
+}
+
+func (mgr *DNSManager) deleteRecord(options api.DeleteRecordOptions) error {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	z, err := mgr.zone(options.ZoneID)
+	if err != nil {
+		return err
+	}
+	key := recordKey(strings.ToLower(options.Name), options.Type)
+	if _, ok := z.records[key]; !ok {
+		return notFound("record %s %s not found in zone %s", options.Type, options.Name, z.Name)
+	}
+	delete(z.records, key)
+	return nil
+}
+
+//DeleteRecordWithContext deletes a DNS record set
+func (mgr *DNSManager) DeleteRecordWithContext(ctx context.Context, options api.DeleteRecordOptions) api.DeleteRecordError {
+	err := mgr.deleteRecord(options)
+	if err != nil {
+		return api.NewDeleteRecordError(err, options)
+	}
+	return nil
+}
+
+//DeleteRecord deletes a DNS record set
+func (mgr *DNSManager) DeleteRecord(options api.DeleteRecordOptions) api.DeleteRecordError {
+	return mgr.DeleteRecordWithContext(context.Background(), options)
+}
+
+//ListRecordsWithContext lists the record sets of the DNS zone identified by zoneID
+func (mgr *DNSManager) ListRecordsWithContext(ctx context.Context, zoneID string) ([]api.Record, api.ListRecordsError) {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	z, err := mgr.zone(zoneID)
+	if err != nil {
+		return nil, api.NewListRecordsError(err, zoneID)
+	}
+	res := []api.Record{}
+	for _, r := range z.records {
+		res = append(res, *copyRecord(r))
+	}
+	api.SortRecords(res)
+	return res, nil
+}
+
+//ListRecords lists the record sets of the DNS zone identified by zoneID
+func (mgr *DNSManager) ListRecords(zoneID string) ([]api.Record, api.ListRecordsError) {
+	return mgr.ListRecordsWithContext(context.Background(), zoneID)
+}
@@ -0,0 +1,22 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/SebastienDorgan/anyclouds/tests"
+	"github.com/stretchr/testify/suite"
+)
+
+type MemoryDNSManagerTestSuite struct {
+	tests.DNSManagerTestSuite
+}
+
+//SetupSuite set up DNS manager
+func (suite *MemoryDNSManagerTestSuite) SetupSuite() {
+	p := GetProvider()
+	suite.Prov = p
+}
+
+func TestMemoryDNSManagerTestSuite(t *testing.T) {
+	suite.Run(t, new(MemoryDNSManagerTestSuite))
+}
@@ -0,0 +1,159 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+)
+
+//ImageManager memory implementation of api.ImageManager
+type ImageManager struct {
+	Provider *Provider
+}
+
+//osImage returns an image of distribution in version dated date
+func osImage(id, name, distribution, version string, date time.Time) api.Image {
+	return api.Image{
+		ID:           id,
+		Name:         name,
+		MinDisk:      8,
+		MinRAM:       512,
+		CreatedAt:    date,
+		UpdatedAt:    date,
+		OSFamily:     api.OSLinux,
+		Distribution: distribution,
+		Version:      version,
+		Arch:         api.ArchAmd64,
+		Owner:        "anyclouds",
+		DefaultUser:  api.DefaultUser(distribution),
+	}
+}
+
+func defaultImages() []api.Image {
+	date := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	return []api.Image{
+		osImage("img-ubuntu-1804", "Ubuntu 18.04 LTS", "ubuntu", "18.04", date),
+		osImage("img-ubuntu-1804-20180426", "Ubuntu 18.04 LTS (20180426)", "ubuntu", "18.04", date.AddDate(0, -8, 0)),
+		osImage("img-ubuntu-1604", "Ubuntu 16.04 LTS", "ubuntu", "16.04", date),
+		osImage("img-debian-9", "Debian 9 Stretch", "debian", "9", date),
+		osImage("img-centos-7", "CentOS 7", "centos", "7", date),
+	}
+}
+
+//ListWithContext returns available image list
+func (mgr *ImageManager) ListWithContext(ctx context.Context) ([]api.Image, api.ListImageError) {
+	mgr.Provider.lock.Lock()
+	defer mgr.Provider.lock.Unlock()
+	images := make([]api.Image, len(mgr.Provider.store.images))
+	copy(images, mgr.Provider.store.images)
+	return images, nil
+}
+
+//List returns available image list
+func (mgr *ImageManager) List() ([]api.Image, api.ListImageError) {
+	return mgr.ListWithContext(context.Background())
+}
+
+func (mgr *ImageManager) get(id string) (*api.Image, error) {
+	mgr.Provider.lock.Lock()
+	defer mgr.Provider.lock.Unlock()
+	for _, img := range mgr.Provider.store.images {
+		if img.ID == id {
+			image := img
+			return &image, nil
+		}
+	}
+	return nil, notFound("image %s not found", id)
+}
+
+//GetWithContext returns the image identified by id
+func (mgr *ImageManager) GetWithContext(ctx context.Context, id string) (*api.Image, api.GetImageError) {
+	img, err := mgr.get(id)
+	return img, api.NewGetImageError(err, id)
+}
+
+//Get returns the image identified by id
+func (mgr *ImageManager) Get(id string) (*api.Image, api.GetImageError) {
+	return mgr.GetWithContext(context.Background(), id)
+}
+
+func (mgr *ImageManager) createFromServer(serverID string, name string) (*api.Image, error) {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	srv, ok := p.store.servers[serverID]
+	if !ok {
+		return nil, notFound("server %s not found", serverID)
+	}
+	now := time.Now()
+	img := api.Image{
+		ID:        p.newID("img"),
+		Name:      name,
+		CreatedAt: now,
+		UpdatedAt: now,
+		OSFamily:  api.OSUnknown,
+		Arch:      api.ArchUnknown,
+		Owner:     "self",
+	}
+	for _, src := range p.store.images {
+		if src.ID == srv.ImageID {
+			img.MinDisk = src.MinDisk
+			img.MinRAM = src.MinRAM
+			img.OSFamily = src.OSFamily
+			img.Distribution = src.Distribution
+			img.Version = src.Version
+			img.Arch = src.Arch
+			img.DefaultUser = src.DefaultUser
+		}
+	}
+	p.store.images = append(p.store.images, img)
+	return &img, nil
+}
+
+//CreateFromServerWithContext creates an image from the server identified by serverID
+func (mgr *ImageManager) CreateFromServerWithContext(ctx context.Context, serverID string, name string) (*api.Image, api.CreateImageError) {
+	img, err := mgr.createFromServer(serverID, name)
+	return img, api.NewCreateImageError(err, serverID, name)
+}
+
+//CreateFromServer creates an image from the server identified by serverID
+func (mgr *ImageManager) CreateFromServer(serverID string, name string) (*api.Image, api.CreateImageError) {
+	return mgr.CreateFromServerWithContext(context.Background(), serverID, name)
+}
+
+func (mgr *ImageManager) delete(id string) error {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for i, img := range p.store.images {
+		if img.ID == id {
+			p.store.images = append(p.store.images[:i], p.store.images[i+1:]...)
+			return nil
+		}
+	}
+	return notFound("image %s not found", id)
+}
+
+//DeleteWithContext deletes the image identified by id
+func (mgr *ImageManager) DeleteWithContext(ctx context.Context, id string) api.DeleteImageError {
+	return api.NewDeleteImageError(mgr.delete(id), id)
+}
+
+//Delete deletes the image identified by id
+func (mgr *ImageManager) Delete(id string) api.DeleteImageError {
+	return mgr.DeleteWithContext(context.Background(), id)
+}
+
+//FindWithContext returns the image matching query
+func (mgr *ImageManager) FindWithContext(ctx context.Context, query api.ImageQuery) (*api.Image, api.FindImageError) {
+	mgr.Provider.lock.Lock()
+	defer mgr.Provider.lock.Unlock()
+	img, err := api.FindImage(mgr.Provider.store.images, query)
+	return img, api.NewFindImageError(err, query)
+}
+
+//Find returns the image matching query
+func (mgr *ImageManager) Find(query api.ImageQuery) (*api.Image, api.FindImageError) {
+	return mgr.FindWithContext(context.Background(), query)
+}
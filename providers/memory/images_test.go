@@ -0,0 +1,22 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/SebastienDorgan/anyclouds/tests"
+	"github.com/stretchr/testify/suite"
+)
+
+type MemoryImageManagerTestSuite struct {
+	tests.ImageManagerTestSuite
+}
+
+//SetupSuite set up image manager
+func (suite *MemoryImageManagerTestSuite) SetupSuite() {
+	p := GetProvider()
+	suite.Mgr = p.GetImageManager()
+}
+
+func TestMemoryImageManagerTestSuite(t *testing.T) {
+	suite.Run(t, new(MemoryImageManagerTestSuite))
+}
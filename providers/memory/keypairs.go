@@ -0,0 +1,120 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/SebastienDorgan/anyclouds/sshutils"
+)
+
+//KeyPairManager memory implementation of api.KeyPairManager
+type KeyPairManager struct {
+	Provider *Provider
+}
+
+func (mgr *KeyPairManager) importKeyPair(name string, publicKey []byte) (*api.KeyPair, error) {
+	if name == "" {
+		return nil, invalidArgument("key pair name cannot be empty")
+	}
+	fingerprint, err := sshutils.Fingerprint(publicKey)
+	if err != nil {
+		return nil, invalidArgument("invalid public key: %s", err.Error())
+	}
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if _, ok := p.store.keyPairs[name]; ok {
+		return nil, alreadyExists("key pair %s already exists", name)
+	}
+	kp := &api.KeyPair{
+		Name:        name,
+		Fingerprint: fingerprint,
+	}
+	p.store.keyPairs[name] = kp
+	res := *kp
+	return &res, nil
+}
+
+//ImportWithContext registers publicKey under name
+func (mgr *KeyPairManager) ImportWithContext(ctx context.Context, name string, publicKey []byte) (*api.KeyPair, api.ImportKeyPairError) {
+	kp, err := mgr.importKeyPair(name, publicKey)
+	if err != nil {
+		return nil, api.NewImportKeyPairError(err, name)
+	}
+	return kp, nil
+}
+
+//Import registers publicKey under name
+func (mgr *KeyPairManager) Import(name string, publicKey []byte) (*api.KeyPair, api.ImportKeyPairError) {
+	return mgr.ImportWithContext(context.Background(), name, publicKey)
+}
+
+func (mgr *KeyPairManager) delete(name string) error {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if _, ok := p.store.keyPairs[name]; !ok {
+		return notFound("key pair %s not found", name)
+	}
+	delete(p.store.keyPairs, name)
+	return nil
+}
+
+//DeleteWithContext deletes the key pair identified by name
+func (mgr *KeyPairManager) DeleteWithContext(ctx context.Context, name string) api.DeleteKeyPairError {
+	err := mgr.delete(name)
+	if err != nil {
+		return api.NewDeleteKeyPairError(err, name)
+	}
+	return nil
+}
+
+//Delete deletes the key pair identified by name
+func (mgr *KeyPairManager) Delete(name string) api.DeleteKeyPairError {
+	return mgr.DeleteWithContext(context.Background(), name)
+}
+
+//ListWithContext lists key pairs
+func (mgr *KeyPairManager) ListWithContext(ctx context.Context) ([]api.KeyPair, api.ListKeyPairsError) {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	var res []api.KeyPair
+	for _, kp := range p.store.keyPairs {
+		res = append(res, *kp)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res, nil
+}
+
+//List lists key pairs
+func (mgr *KeyPairManager) List() ([]api.KeyPair, api.ListKeyPairsError) {
+	return mgr.ListWithContext(context.Background())
+}
+
+func (mgr *KeyPairManager) get(name string) (*api.KeyPair, error) {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	kp, ok := p.store.keyPairs[name]
+	if !ok {
+		return nil, notFound("key pair %s not found", name)
+	}
+	res := *kp
+	return &res, nil
+}
+
+//GetWithContext returns the key pair identified by name
+func (mgr *KeyPairManager) GetWithContext(ctx context.Context, name string) (*api.KeyPair, api.GetKeyPairError) {
+	kp, err := mgr.get(name)
+	if err != nil {
+		return nil, api.NewGetKeyPairError(err, name)
+	}
+	return kp, nil
+}
+
+//Get returns the key pair identified by name
+func (mgr *KeyPairManager) Get(name string) (*api.KeyPair, api.GetKeyPairError) {
+	return mgr.GetWithContext(context.Background(), name)
+}
@@ -0,0 +1,22 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/SebastienDorgan/anyclouds/tests"
+	"github.com/stretchr/testify/suite"
+)
+
+type MemoryKeyPairManagerTestSuite struct {
+	tests.KeyPairManagerTestSuite
+}
+
+//SetupSuite set up key pair manager
+func (suite *MemoryKeyPairManagerTestSuite) SetupSuite() {
+	p := GetProvider()
+	suite.Prov = p
+}
+
+func TestMemoryKeyPairManagerTestSuite(t *testing.T) {
+	suite.Run(t, new(MemoryKeyPairManagerTestSuite))
+}
@@ -0,0 +1,194 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+)
+
+//LoadBalancerManager memory implementation of api.LoadBalancerManager
+type LoadBalancerManager struct {
+	Provider *Provider
+}
+
+//copyLoadBalancer returns a deep copy of lb
+func copyLoadBalancer(lb *api.LoadBalancer) *api.LoadBalancer {
+	res := *lb
+	res.SubnetIDs = append([]string{}, lb.SubnetIDs...)
+	res.Listeners = make([]api.Listener, len(lb.Listeners))
+	for i, l := range lb.Listeners {
+		l.Pool.ServerIDs = append([]string{}, l.Pool.ServerIDs...)
+		if l.Pool.HealthCheck != nil {
+			hc := *l.Pool.HealthCheck
+			l.Pool.HealthCheck = &hc
+		}
+		res.Listeners[i] = l
+	}
+	res.Tags = copyTags(lb.Tags)
+	return &res
+}
+
+//checkLoadBalancer checks that the subnets of options belong to the same network and that the servers exist, must be called with the lock held
+func (mgr *LoadBalancerManager) checkLoadBalancer(options *api.CreateLoadBalancerOptions) error {
+	networkID := ""
+	for _, id := range options.SubnetIDs {
+		sn, ok := mgr.Provider.store.subnets[id]
+		if !ok {
+			return notFound("subnet %s not found", id)
+		}
+		if networkID != "" && sn.NetworkID != networkID {
+			return invalidArgument("subnets of load balancer %s belong to different networks", options.Name)
+		}
+		networkID = sn.NetworkID
+	}
+	for _, l := range options.Listeners {
+		for _, id := range l.Pool.ServerIDs {
+			if _, ok := mgr.Provider.store.servers[id]; !ok {
+				return notFound("server %s not found", id)
+			}
+		}
+	}
+	return nil
+}
+
+func (mgr *LoadBalancerManager) create(options api.CreateLoadBalancerOptions) (*api.LoadBalancer, error) {
+	err := api.CheckLoadBalancerOptions(&options)
+	if err != nil {
+		return nil, err
+	}
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	err = mgr.checkLoadBalancer(&options)
+	if err != nil {
+		return nil, err
+	}
+	lb := &api.LoadBalancer{
+		ID:        p.newID("lb"),
+		Name:      options.Name,
+		Type:      options.Type,
+		Public:    options.Public,
+		SubnetIDs: options.SubnetIDs,
+		Tags:      options.Tags,
+	}
+	lb.Address = fmt.Sprintf("%s.lb.memory", lb.ID)
+	for _, l := range options.Listeners {
+		if l.Pool.HealthCheck == nil {
+			l.Pool.HealthCheck = api.DefaultHealthCheck(l.Pool.Protocol)
+		}
+		lb.Listeners = append(lb.Listeners, api.Listener{
+			ID:       p.newID("listener"),
+			Protocol: l.Protocol,
+			Port:     l.Port,
+			Pool:     l.Pool,
+		})
+	}
+	sort.Slice(lb.Listeners, func(i, j int) bool {
+		return lb.Listeners[i].Port < lb.Listeners[j].Port
+	})
+	lb = copyLoadBalancer(lb)
+	p.store.loadBalancers[lb.ID] = lb
+	return copyLoadBalancer(lb), nil
+}
+
+//CreateWithContext creates a load balancer
+func (mgr *LoadBalancerManager) CreateWithContext(ctx context.Context, options api.CreateLoadBalancerOptions) (*api.LoadBalancer, api.CreateLoadBalancerError) {
+	lb, err := mgr.create(options)
+	if err != nil {
+		return nil, api.NewCreateLoadBalancerError(err, options)
+	}
+	return lb, nil
+}
+
+//Create creates a load balancer
+func (mgr *LoadBalancerManager) Create(options api.CreateLoadBalancerOptions) (*api.LoadBalancer, api.CreateLoadBalancerError) {
+	return mgr.CreateWithContext(context.Background(), options)
+}
+
+func (mgr *LoadBalancerManager) delete(id string) error {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if _, ok := p.store.loadBalancers[id]; !ok {
+		return notFound("load balancer %s not found", id)
+	}
+	delete(p.store.loadBalancers, id)
+	return nil
+}
+
+//DeleteWithContext deletes the load balancer identified by id with its listeners
+func (mgr *LoadBalancerManager) DeleteWithContext(ctx context.Context, id string) api.DeleteLoadBalancerError {
+	err := mgr.delete(id)
+	if err != nil {
+		return api.NewDeleteLoadBalancerError(err, id)
+	}
+	return nil
+}
+
+//Delete deletes the load balancer identified by id with its listeners
+func (mgr *LoadBalancerManager) Delete(id string) api.DeleteLoadBalancerError {
+	return mgr.DeleteWithContext(context.Background(), id)
+}
+
+//ListWithContext lists load balancers
+func (mgr *LoadBalancerManager) ListWithContext(ctx context.Context) ([]api.LoadBalancer, api.ListLoadBalancersError) {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	res := []api.LoadBalancer{}
+	for _, lb := range p.store.loadBalancers {
+		res = append(res, *copyLoadBalancer(lb))
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].ID < res[j].ID
+	})
+	return res, nil
+}
+
+//List lists load balancers
+func (mgr *LoadBalancerManager) List() ([]api.LoadBalancer, api.ListLoadBalancersError) {
+	return mgr.ListWithContext(context.Background())
+}
+
+func (mgr *LoadBalancerManager) get(id string) (*api.LoadBalancer, error) {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	lb, ok := p.store.loadBalancers[id]
+	if !ok {
+		return nil, notFound("load balancer %s not found", id)
+	}
+	return copyLoadBalancer(lb), nil
+}
+
+//GetWithContext returns the load balancer identified by id
+func (mgr *LoadBalancerManager) GetWithContext(ctx context.Context, id string) (*api.LoadBalancer, api.GetLoadBalancerError) {
+	lb, err := mgr.get(id)
+	if err != nil {
+		return nil, api.NewGetLoadBalancerError(err, id)
+	}
+	return lb, nil
+}
+
+//Get returns the load balancer identified by id
+func (mgr *LoadBalancerManager) Get(id string) (*api.LoadBalancer, api.GetLoadBalancerError) {
+	return mgr.GetWithContext(context.Background(), id)
+}
+
+//removeServer removes the server identified by id from the backend pools, must be called with the lock held
+func (mgr *LoadBalancerManager) removeServer(id string) {
+	for _, lb := range mgr.Provider.store.loadBalancers {
+		for i := range lb.Listeners {
+			pool := &lb.Listeners[i].Pool
+			var ids []string
+			for _, sid := range pool.ServerIDs {
+				if sid != id {
+					ids = append(ids, sid)
+				}
+			}
+			pool.ServerIDs = ids
+		}
+	}
+}
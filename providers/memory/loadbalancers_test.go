@@ -0,0 +1,22 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/SebastienDorgan/anyclouds/tests"
+	"github.com/stretchr/testify/suite"
+)
+
+type MemoryLoadBalancerManagerTestSuite struct {
+	tests.LoadBalancerManagerTestSuite
+}
+
+//SetupSuite set up load balancer manager
+func (suite *MemoryLoadBalancerManagerTestSuite) SetupSuite() {
+	p := GetProvider()
+	suite.Prov = p
+}
+
+func TestMemoryLoadBalancerManagerTestSuite(t *testing.T) {
+	suite.Run(t, new(MemoryLoadBalancerManagerTestSuite))
+}
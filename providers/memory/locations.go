@@ -0,0 +1,49 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+)
+
+//LocationManager memory implementation of api.LocationManager
+//Regions are defined by the configuration of the provider
+type LocationManager struct {
+	Provider *Provider
+}
+
+//regions returns the regions identified by ids
+func regions(ids []string) []api.Region {
+	res := []api.Region{}
+	for _, id := range ids {
+		res = append(res, api.Region{ID: id, Name: id})
+	}
+	return res
+}
+
+//ListRegionsWithContext lists the regions of the configuration
+func (mgr *LocationManager) ListRegionsWithContext(ctx context.Context) ([]api.Region, api.ListRegionsError) {
+	return regions(mgr.Provider.Configuration.Regions), nil
+}
+
+//ListRegions lists the regions of the configuration
+func (mgr *LocationManager) ListRegions() ([]api.Region, api.ListRegionsError) {
+	return mgr.ListRegionsWithContext(context.Background())
+}
+
+//ListZonesWithContext lists the availability zones of region
+func (mgr *LocationManager) ListZonesWithContext(ctx context.Context, region string) ([]api.AvailabilityZone, api.ListAvailabilityZonesError) {
+	_, err := api.FindRegion(regions(mgr.Provider.Configuration.Regions), region)
+	if err != nil {
+		return nil, api.NewListAvailabilityZonesError(err, region)
+	}
+	return []api.AvailabilityZone{
+		{ID: region + "a", Region: region, Available: true},
+		{ID: region + "b", Region: region, Available: true},
+	}, nil
+}
+
+//ListZones lists the availability zones of region
+func (mgr *LocationManager) ListZones(region string) ([]api.AvailabilityZone, api.ListAvailabilityZonesError) {
+	return mgr.ListZonesWithContext(context.Background(), region)
+}
@@ -0,0 +1,22 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/SebastienDorgan/anyclouds/tests"
+	"github.com/stretchr/testify/suite"
+)
+
+type MemoryLocationManagerTestSuite struct {
+	tests.LocationManagerTestSuite
+}
+
+//SetupSuite set up location manager
+func (suite *MemoryLocationManagerTestSuite) SetupSuite() {
+	p := GetProvider()
+	suite.Prov = p
+}
+
+func TestMemoryLocationManagerTestSuite(t *testing.T) {
+	suite.Run(t, new(MemoryLocationManagerTestSuite))
+}
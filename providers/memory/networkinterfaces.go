@@ -0,0 +1,212 @@
+package memory
+
+import (
+	"context"
+	"net"
+	"sort"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/pkg/errors"
+)
+
+//NetworkInterfaceManager memory implementation of api.NetworkInterfaceManager
+type NetworkInterfaceManager struct {
+	Provider *Provider
+}
+
+//checkPrivateIP checks that ip is a free address of subnet, must be called with the lock held
+func (p *Provider) checkPrivateIP(subnet *api.Subnet, ip string) error {
+	cidr, err := parseCIDR(subnet.CIDR)
+	if err != nil {
+		return err
+	}
+	addr := net.ParseIP(ip)
+	if addr == nil || !cidr.Contains(addr) {
+		return invalidArgument("ip address %s is not in subnet %s", ip, subnet.ID)
+	}
+	for _, ni := range p.store.nics {
+		if ni.SubnetID == subnet.ID && ni.PrivateIPAddress == ip {
+			return alreadyExists("ip address %s is already used by network interface %s", ip, ni.ID)
+		}
+	}
+	return nil
+}
+
+func (mgr *NetworkInterfaceManager) create(options api.CreateNetworkInterfaceOptions) (*api.NetworkInterface, error) {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	sn, ok := p.store.subnets[options.SubnetID]
+	if !ok || sn.NetworkID != options.NetworkID {
+		return nil, notFound("subnet %s not found in network %s", options.SubnetID, options.NetworkID)
+	}
+	sgID, err := p.selectSecurityGroup(sn.NetworkID, options.SecurityGroupID)
+	if err != nil {
+		return nil, err
+	}
+	serverID := ""
+	if options.ServerID != nil {
+		if _, ok := p.store.servers[*options.ServerID]; !ok {
+			return nil, notFound("server %s not found", *options.ServerID)
+		}
+		serverID = *options.ServerID
+	}
+	var ip string
+	if options.PrivateIPAddress != nil {
+		ip = *options.PrivateIPAddress
+		err = p.checkPrivateIP(sn, ip)
+	} else {
+		ip, err = p.allocatePrivateIP(sn)
+	}
+	if err != nil {
+		return nil, err
+	}
+	ni := &api.NetworkInterface{
+		ID:               p.newID("nic"),
+		Name:             options.Name,
+		MacAddress:       macAddress(p.counter),
+		NetworkID:        sn.NetworkID,
+		SubnetID:         sn.ID,
+		ServerID:         serverID,
+		PrivateIPAddress: ip,
+		SecurityGroupID:  sgID,
+		Tags:             copyTags(options.Tags),
+	}
+	p.store.nics[ni.ID] = ni
+	res := *ni
+	return &res, nil
+}
+
+//CreateWithContext creates a network interface card
+func (mgr *NetworkInterfaceManager) CreateWithContext(ctx context.Context, options api.CreateNetworkInterfaceOptions) (*api.NetworkInterface, api.CreateNetworkInterfaceError) {
+	ni, err := mgr.create(options)
+	return ni, api.NewCreateNetworkInterfaceError(err, options)
+}
+
+//Create creates a network interface card
+func (mgr *NetworkInterfaceManager) Create(options api.CreateNetworkInterfaceOptions) (*api.NetworkInterface, api.CreateNetworkInterfaceError) {
+	return mgr.CreateWithContext(context.Background(), options)
+}
+
+func (mgr *NetworkInterfaceManager) delete(id string) error {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if _, ok := p.store.nics[id]; !ok {
+		return notFound("network interface %s not found", id)
+	}
+	for _, ip := range p.store.publicIPs {
+		if ip.NetworkInterfaceID == id {
+			return errors.Errorf("network interface %s is associated with public ip %s", id, ip.ID)
+		}
+	}
+	delete(p.store.nics, id)
+	return nil
+}
+
+//DeleteWithContext deletes the network interface card identified by id
+func (mgr *NetworkInterfaceManager) DeleteWithContext(ctx context.Context, id string) api.DeleteNetworkInterfaceError {
+	return api.NewDeleteNetworkInterfaceError(mgr.delete(id), id)
+}
+
+//Delete deletes the network interface card identified by id
+func (mgr *NetworkInterfaceManager) Delete(id string) api.DeleteNetworkInterfaceError {
+	return mgr.DeleteWithContext(context.Background(), id)
+}
+
+func (mgr *NetworkInterfaceManager) get(id string) (*api.NetworkInterface, error) {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	ni, ok := p.store.nics[id]
+	if !ok {
+		return nil, notFound("network interface %s not found", id)
+	}
+	res := *ni
+	return &res, nil
+}
+
+//GetWithContext retrieves the network interface card identified by id
+func (mgr *NetworkInterfaceManager) GetWithContext(ctx context.Context, id string) (*api.NetworkInterface, api.GetNetworkInterfaceError) {
+	ni, err := mgr.get(id)
+	return ni, api.NewGetNetworkInterfaceError(err, id)
+}
+
+//Get retrieves the network interface card identified by id
+func (mgr *NetworkInterfaceManager) Get(id string) (*api.NetworkInterface, api.GetNetworkInterfaceError) {
+	return mgr.GetWithContext(context.Background(), id)
+}
+
+func match(filter *string, value string) bool {
+	return filter == nil || *filter == value
+}
+
+//ListWithContext list network interface cards matching options
+func (mgr *NetworkInterfaceManager) ListWithContext(ctx context.Context, options *api.ListNetworkInterfacesOptions) ([]api.NetworkInterface, api.ListNetworkInterfacesError) {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if options == nil {
+		options = &api.ListNetworkInterfacesOptions{}
+	}
+	nics := []api.NetworkInterface{}
+	for _, ni := range p.store.nics {
+		if match(options.NetworkID, ni.NetworkID) &&
+			match(options.SubnetID, ni.SubnetID) &&
+			match(options.ServerID, ni.ServerID) &&
+			match(options.SecurityGroupID, ni.SecurityGroupID) &&
+			match(options.PrivateIPAddress, ni.PrivateIPAddress) {
+			nics = append(nics, *ni)
+		}
+	}
+	sort.Slice(nics, func(i, j int) bool {
+		return nics[i].ID < nics[j].ID
+	})
+	return nics, nil
+}
+
+//List list network interface cards matching options
+func (mgr *NetworkInterfaceManager) List(options *api.ListNetworkInterfacesOptions) ([]api.NetworkInterface, api.ListNetworkInterfacesError) {
+	return mgr.ListWithContext(context.Background(), options)
+}
+
+func (mgr *NetworkInterfaceManager) update(options api.UpdateNetworkInterfaceOptions) (*api.NetworkInterface, error) {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	ni, ok := p.store.nics[options.ID]
+	if !ok {
+		return nil, notFound("network interface %s not found", options.ID)
+	}
+	if options.ServerID != nil && len(*options.ServerID) > 0 {
+		if _, ok := p.store.servers[*options.ServerID]; !ok {
+			return nil, notFound("server %s not found", *options.ServerID)
+		}
+	}
+	if options.SecurityGroupID != nil {
+		sg, ok := p.store.securityGroups[*options.SecurityGroupID]
+		if !ok {
+			return nil, notFound("security group %s not found", *options.SecurityGroupID)
+		}
+		if sg.NetworkID != ni.NetworkID {
+			return nil, invalidArgument("security group %s is not in network %s", sg.ID, ni.NetworkID)
+		}
+		ni.SecurityGroupID = sg.ID
+	}
+	if options.ServerID != nil {
+		ni.ServerID = *options.ServerID
+	}
+	res := *ni
+	return &res, nil
+}
+
+//UpdateWithContext updates the network interface card identified by options.ID
+func (mgr *NetworkInterfaceManager) UpdateWithContext(ctx context.Context, options api.UpdateNetworkInterfaceOptions) (*api.NetworkInterface, api.UpdateNetworkInterfaceError) {
+	ni, err := mgr.update(options)
+	return ni, api.NewUpdateNetworkInterfaceError(err, options)
+}
+
+//Update updates the network interface card identified by options.ID
+func (mgr *NetworkInterfaceManager) Update(options api.UpdateNetworkInterfaceOptions) (*api.NetworkInterface, api.UpdateNetworkInterfaceError) {
+	return mgr.UpdateWithContext(context.Background(), options)
+}
@@ -0,0 +1,338 @@
+package memory
+
+import (
+	"context"
+	"net"
+	"sort"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/SebastienDorgan/anyclouds/iputils"
+	"github.com/pkg/errors"
+)
+
+//NetworkManager memory implementation of api.NetworkManager
+type NetworkManager struct {
+	Provider *Provider
+}
+
+func parseCIDR(cidr string) (*net.IPNet, error) {
+	_, n, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, api.WithKind(errors.Wrapf(err, "invalid CIDR %s", cidr), api.ErrInvalidArgument)
+	}
+	if n.IP.To4() == nil {
+		return nil, invalidArgument("invalid CIDR %s: only IP v4 is supported", cidr)
+	}
+	return n, nil
+}
+
+//bounds returns the first and last addresses of n
+func bounds(n *net.IPNet) (uint32, uint32) {
+	ip := n.IP.To4()
+	first := iputils.Itou(&ip)
+	ones, bits := n.Mask.Size()
+	return first, first + uint32(uint64(1)<<uint(bits-ones)-1)
+}
+
+func overlaps(n1, n2 *net.IPNet) bool {
+	f1, l1 := bounds(n1)
+	f2, l2 := bounds(n2)
+	return f1 <= l2 && f2 <= l1
+}
+
+func includes(outer, inner *net.IPNet) bool {
+	fo, lo := bounds(outer)
+	fi, li := bounds(inner)
+	return fo <= fi && li <= lo
+}
+
+func (mgr *NetworkManager) createNetwork(options api.CreateNetworkOptions) (*api.Network, error) {
+	_, err := parseCIDR(options.CIDR)
+	if err != nil {
+		return nil, err
+	}
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	n := &api.Network{
+		ID:   p.newID("net"),
+		Name: options.Name,
+		CIDR: options.CIDR,
+		Tags: copyTags(options.Tags),
+	}
+	p.store.networks[n.ID] = n
+	sg := &securityGroup{
+		SecurityGroup: api.SecurityGroup{
+			ID:        p.newID("sg"),
+			Name:      defaultSecurityGroupName,
+			NetworkID: n.ID,
+		},
+		isDefault: true,
+	}
+	p.store.securityGroups[sg.ID] = sg
+	res := *n
+	return &res, nil
+}
+
+//CreateNetworkWithContext creates a network
+func (mgr *NetworkManager) CreateNetworkWithContext(ctx context.Context, options api.CreateNetworkOptions) (*api.Network, api.CreateNetworkError) {
+	n, err := mgr.createNetwork(options)
+	return n, api.NewCreateNetworkError(err, options)
+}
+
+//CreateNetwork creates a network
+func (mgr *NetworkManager) CreateNetwork(options api.CreateNetworkOptions) (*api.Network, api.CreateNetworkError) {
+	return mgr.CreateNetworkWithContext(context.Background(), options)
+}
+
+func (mgr *NetworkManager) deleteNetwork(id string) error {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if _, ok := p.store.networks[id]; !ok {
+		return notFound("network %s not found", id)
+	}
+	for _, sn := range p.store.subnets {
+		if sn.NetworkID == id {
+			return errors.Errorf("network %s has dependent subnet %s", id, sn.ID)
+		}
+	}
+	for _, sg := range p.store.securityGroups {
+		if sg.NetworkID == id && !sg.isDefault {
+			return errors.Errorf("network %s has dependent security group %s", id, sg.ID)
+		}
+	}
+	for _, ni := range p.store.nics {
+		if ni.NetworkID == id {
+			return errors.Errorf("network %s has dependent network interface %s", id, ni.ID)
+		}
+	}
+	for sgID, sg := range p.store.securityGroups {
+		if sg.NetworkID == id {
+			delete(p.store.securityGroups, sgID)
+		}
+	}
+	delete(p.store.networks, id)
+	return nil
+}
+
+//DeleteNetworkWithContext deletes the network identified by id
+func (mgr *NetworkManager) DeleteNetworkWithContext(ctx context.Context, id string) api.DeleteNetworkError {
+	return api.NewDeleteNetworkError(mgr.deleteNetwork(id), id)
+}
+
+//DeleteNetwork deletes the network identified by id
+func (mgr *NetworkManager) DeleteNetwork(id string) api.DeleteNetworkError {
+	return mgr.DeleteNetworkWithContext(context.Background(), id)
+}
+
+//ListNetworksWithContext lists networks
+func (mgr *NetworkManager) ListNetworksWithContext(ctx context.Context) ([]api.Network, api.ListNetworksError) {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	networks := []api.Network{}
+	for _, n := range p.store.networks {
+		networks = append(networks, *n)
+	}
+	sort.Slice(networks, func(i, j int) bool {
+		return networks[i].ID < networks[j].ID
+	})
+	return networks, nil
+}
+
+//ListNetworks lists networks
+func (mgr *NetworkManager) ListNetworks() ([]api.Network, api.ListNetworksError) {
+	return mgr.ListNetworksWithContext(context.Background())
+}
+
+func (mgr *NetworkManager) getNetwork(id string) (*api.Network, error) {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	n, ok := p.store.networks[id]
+	if !ok {
+		return nil, notFound("network %s not found", id)
+	}
+	res := *n
+	return &res, nil
+}
+
+//GetNetworkWithContext returns the configuration of the network identified by id
+func (mgr *NetworkManager) GetNetworkWithContext(ctx context.Context, id string) (*api.Network, api.GetNetworkError) {
+	n, err := mgr.getNetwork(id)
+	return n, api.NewGetNetworkError(err, id)
+}
+
+//GetNetwork returns the configuration of the network identified by id
+func (mgr *NetworkManager) GetNetwork(id string) (*api.Network, api.GetNetworkError) {
+	return mgr.GetNetworkWithContext(context.Background(), id)
+}
+
+func (mgr *NetworkManager) createSubnet(options api.CreateSubnetOptions) (*api.Subnet, error) {
+	if options.IPVersion != api.IPVersion4 {
+		return nil, invalidArgument("IP version %d is not supported", options.IPVersion)
+	}
+	cidr, err := parseCIDR(options.CIDR)
+	if err != nil {
+		return nil, err
+	}
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	n, ok := p.store.networks[options.NetworkID]
+	if !ok {
+		return nil, notFound("network %s not found", options.NetworkID)
+	}
+	netCIDR, err := parseCIDR(n.CIDR)
+	if err != nil {
+		return nil, err
+	}
+	if !includes(netCIDR, cidr) {
+		return nil, invalidArgument("subnet CIDR %s is not included in network CIDR %s", options.CIDR, n.CIDR)
+	}
+	for _, sn := range p.store.subnets {
+		if sn.NetworkID != n.ID {
+			continue
+		}
+		other, err := parseCIDR(sn.CIDR)
+		if err != nil {
+			return nil, err
+		}
+		if overlaps(cidr, other) {
+			return nil, invalidArgument("subnet CIDR %s overlaps CIDR %s of subnet %s", options.CIDR, sn.CIDR, sn.ID)
+		}
+	}
+	sn := &api.Subnet{
+		ID:        p.newID("subnet"),
+		NetworkID: n.ID,
+		Name:      options.Name,
+		CIDR:      options.CIDR,
+		IPVersion: options.IPVersion,
+		Tags:      copyTags(options.Tags),
+	}
+	p.store.subnets[sn.ID] = sn
+	subnet := *sn
+	return &subnet, nil
+}
+
+//CreateSubnetWithContext creates a subnet
+func (mgr *NetworkManager) CreateSubnetWithContext(ctx context.Context, options api.CreateSubnetOptions) (*api.Subnet, api.CreateSubnetError) {
+	sn, err := mgr.createSubnet(options)
+	return sn, api.NewCreateSubnetError(err, options)
+}
+
+//CreateSubnet creates a subnet
+func (mgr *NetworkManager) CreateSubnet(options api.CreateSubnetOptions) (*api.Subnet, api.CreateSubnetError) {
+	return mgr.CreateSubnetWithContext(context.Background(), options)
+}
+
+func (mgr *NetworkManager) deleteSubnet(networkID string, subnetID string) error {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	sn, ok := p.store.subnets[subnetID]
+	if !ok || sn.NetworkID != networkID {
+		return notFound("subnet %s not found in network %s", subnetID, networkID)
+	}
+	for _, ni := range p.store.nics {
+		if ni.SubnetID == subnetID {
+			return errors.Errorf("subnet %s has dependent network interface %s", subnetID, ni.ID)
+		}
+	}
+	for _, lb := range p.store.loadBalancers {
+		for _, id := range lb.SubnetIDs {
+			if id == subnetID {
+				return errors.Errorf("subnet %s has dependent load balancer %s", subnetID, lb.ID)
+			}
+		}
+	}
+	delete(p.store.subnets, subnetID)
+	return nil
+}
+
+//DeleteSubnetWithContext deletes the subnet identified by subnetID
+func (mgr *NetworkManager) DeleteSubnetWithContext(ctx context.Context, networkID string, subnetID string) api.DeleteSubnetError {
+	return api.NewDeleteSubnetError(mgr.deleteSubnet(networkID, subnetID), networkID, subnetID)
+}
+
+//DeleteSubnet deletes the subnet identified by subnetID
+func (mgr *NetworkManager) DeleteSubnet(networkID string, subnetID string) api.DeleteSubnetError {
+	return mgr.DeleteSubnetWithContext(context.Background(), networkID, subnetID)
+}
+
+func (mgr *NetworkManager) listSubnets(networkID string) ([]api.Subnet, error) {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if _, ok := p.store.networks[networkID]; !ok {
+		return nil, notFound("network %s not found", networkID)
+	}
+	subnets := []api.Subnet{}
+	for _, sn := range p.store.subnets {
+		if sn.NetworkID == networkID {
+			subnets = append(subnets, *sn)
+		}
+	}
+	sort.Slice(subnets, func(i, j int) bool {
+		return subnets[i].ID < subnets[j].ID
+	})
+	return subnets, nil
+}
+
+//ListSubnetsWithContext lists the subnet of the network identified by networkID
+func (mgr *NetworkManager) ListSubnetsWithContext(ctx context.Context, networkID string) ([]api.Subnet, api.ListSubnetsError) {
+	l, err := mgr.listSubnets(networkID)
+	return l, api.NewListSubnetsError(err, networkID)
+}
+
+//ListSubnets lists the subnet of the network identified by networkID
+func (mgr *NetworkManager) ListSubnets(networkID string) ([]api.Subnet, api.ListSubnetsError) {
+	return mgr.ListSubnetsWithContext(context.Background(), networkID)
+}
+
+func (mgr *NetworkManager) getSubnet(networkID, subnetID string) (*api.Subnet, error) {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	sn, ok := p.store.subnets[subnetID]
+	if !ok || sn.NetworkID != networkID {
+		return nil, notFound("subnet %s not found in network %s", subnetID, networkID)
+	}
+	subnet := *sn
+	return &subnet, nil
+}
+
+//GetSubnetWithContext returns the configuration of the subnet identified by subnetID
+func (mgr *NetworkManager) GetSubnetWithContext(ctx context.Context, networkID, subnetID string) (*api.Subnet, api.GetSubnetError) {
+	sn, err := mgr.getSubnet(networkID, subnetID)
+	return sn, api.NewGetSubnetError(err, networkID, subnetID)
+}
+
+//GetSubnet returns the configuration of the subnet identified by subnetID
+func (mgr *NetworkManager) GetSubnet(networkID, subnetID string) (*api.Subnet, api.GetSubnetError) {
+	return mgr.GetSubnetWithContext(context.Background(), networkID, subnetID)
+}
+
+//allocatePrivateIP allocates a free private ip address in subnet, must be called with the lock held
+func (p *Provider) allocatePrivateIP(subnet *api.Subnet) (string, error) {
+	cidr, err := parseCIDR(subnet.CIDR)
+	if err != nil {
+		return "", err
+	}
+	used := map[string]bool{}
+	for _, ni := range p.store.nics {
+		if ni.SubnetID == subnet.ID {
+			used[ni.PrivateIPAddress] = true
+		}
+	}
+	first, last := bounds(cidr)
+	//first 4 addresses and the last one are reserved like on most cloud providers
+	for u := first + 4; u < last; u++ {
+		ip := iputils.Utoi(u).String()
+		if !used[ip] {
+			return ip, nil
+		}
+	}
+	return "", quotaExceeded("no free ip address in subnet %s", subnet.ID)
+}
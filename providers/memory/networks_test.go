@@ -0,0 +1,22 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/SebastienDorgan/anyclouds/tests"
+	"github.com/stretchr/testify/suite"
+)
+
+type MemoryNetworkManagerTestSuite struct {
+	tests.NetworkManagerTestSuite
+}
+
+//SetupSuite set up network manager
+func (suite *MemoryNetworkManagerTestSuite) SetupSuite() {
+	p := GetProvider()
+	suite.Mgr = p.GetNetworkManager()
+}
+
+func TestMemoryNetworkManagerTestSuite(t *testing.T) {
+	suite.Run(t, new(MemoryNetworkManagerTestSuite))
+}
@@ -0,0 +1,383 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+)
+
+//ObjectStorageManager memory implementation of api.ObjectStorageManager
+//Pre-signed URLs are served by a local HTTP server started by the first call to PresignURL
+type ObjectStorageManager struct {
+	Provider *Provider
+
+	once      sync.Once
+	serverURL string
+	secret    []byte
+	serverErr error
+}
+
+//bucket bucket and its objects indexed by key
+type bucket struct {
+	api.Bucket
+	objects map[string]*object
+}
+
+type object struct {
+	api.Object
+	content []byte
+}
+
+//bucket returns the bucket identified by id, must be called with the lock held
+func (mgr *ObjectStorageManager) bucket(id string) (*bucket, error) {
+	b, ok := mgr.Provider.store.buckets[id]
+	if !ok {
+		return nil, notFound("bucket %s not found", id)
+	}
+	return b, nil
+}
+
+//object returns the object of the bucket bucketID identified by key, must be called with the lock held
+func (mgr *ObjectStorageManager) object(bucketID, key string) (*object, error) {
+	b, err := mgr.bucket(bucketID)
+	if err != nil {
+		return nil, err
+	}
+	o, ok := b.objects[key]
+	if !ok {
+		return nil, notFound("object %s not found in bucket %s", key, bucketID)
+	}
+	return o, nil
+}
+
+func (mgr *ObjectStorageManager) createBucket(options api.CreateBucketOptions) (*api.Bucket, error) {
+	err := api.CheckBucketOptions(&options)
+	if err != nil {
+		return nil, err
+	}
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if _, ok := p.store.buckets[options.Name]; ok {
+		return nil, alreadyExists("bucket %s already exists", options.Name)
+	}
+	b := &bucket{
+		Bucket: api.Bucket{
+			ID:   options.Name,
+			Name: options.Name,
+		},
+		objects: map[string]*object{},
+	}
+	p.store.buckets[b.ID] = b
+	res := b.Bucket
+	return &res, nil
+}
+
+//CreateBucketWithContext creates a bucket
+func (mgr *ObjectStorageManager) CreateBucketWithContext(ctx context.Context, options api.CreateBucketOptions) (*api.Bucket, api.CreateBucketError) {
+	b, err := mgr.createBucket(options)
+	if err != nil {
+		return nil, api.NewCreateBucketError(err, options)
+	}
+	return b, nil
+}
+
+//CreateBucket creates a bucket
+func (mgr *ObjectStorageManager) CreateBucket(options api.CreateBucketOptions) (*api.Bucket, api.CreateBucketError) {
+	return mgr.CreateBucketWithContext(context.Background(), options)
+}
+
+func (mgr *ObjectStorageManager) deleteBucket(id string) error {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if _, ok := p.store.buckets[id]; !ok {
+		return notFound("bucket %s not found", id)
+	}
+	delete(p.store.buckets, id)
+	return nil
+}
+
+//DeleteBucketWithContext deletes the bucket identified by id and its objects
+func (mgr *ObjectStorageManager) DeleteBucketWithContext(ctx context.Context, id string) api.DeleteBucketError {
+	return api.NewDeleteBucketError(mgr.deleteBucket(id), id)
+}
+
+//DeleteBucket deletes the bucket identified by id and its objects
+func (mgr *ObjectStorageManager) DeleteBucket(id string) api.DeleteBucketError {
+	return mgr.DeleteBucketWithContext(context.Background(), id)
+}
+
+//ListBucketsWithContext lists buckets
+func (mgr *ObjectStorageManager) ListBucketsWithContext(ctx context.Context) ([]api.Bucket, api.ListBucketsError) {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	res := []api.Bucket{}
+	for _, b := range p.store.buckets {
+		res = append(res, b.Bucket)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
+	return res, nil
+}
+
+//ListBuckets lists buckets
+func (mgr *ObjectStorageManager) ListBuckets() ([]api.Bucket, api.ListBucketsError) {
+	return mgr.ListBucketsWithContext(context.Background())
+}
+
+//GetBucketWithContext returns the bucket identified by id
+func (mgr *ObjectStorageManager) GetBucketWithContext(ctx context.Context, id string) (*api.Bucket, api.GetBucketError) {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	b, err := mgr.bucket(id)
+	if err != nil {
+		return nil, api.NewGetBucketError(err, id)
+	}
+	res := b.Bucket
+	return &res, nil
+}
+
+//GetBucket returns the bucket identified by id
+func (mgr *ObjectStorageManager) GetBucket(id string) (*api.Bucket, api.GetBucketError) {
+	return mgr.GetBucketWithContext(context.Background(), id)
+}
+
+//save stores the object, must be called with the lock held
+func (mgr *ObjectStorageManager) save(bucketID, key string, content []byte, contentType string) (*api.Object, error) {
+	b, err := mgr.bucket(bucketID)
+	if err != nil {
+		return nil, err
+	}
+	if contentType == "" {
+		contentType = api.DefaultContentType
+	}
+	sum := md5.Sum(content)
+	o := &object{
+		Object: api.Object{
+			BucketID:     bucketID,
+			Key:          key,
+			Size:         int64(len(content)),
+			ContentType:  contentType,
+			ETag:         hex.EncodeToString(sum[:]),
+			LastModified: time.Now(),
+		},
+		content: content,
+	}
+	b.objects[key] = o
+	res := o.Object
+	return &res, nil
+}
+
+func (mgr *ObjectStorageManager) putObject(options api.PutObjectOptions) (*api.Object, error) {
+	err := api.CheckObjectKey(options.Key)
+	if err != nil {
+		return nil, err
+	}
+	content, err := ioutil.ReadAll(options.Content)
+	if err != nil {
+		return nil, err
+	}
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return mgr.save(options.BucketID, options.Key, content, options.ContentType)
+}
+
+//PutObjectWithContext creates or replaces an object
+func (mgr *ObjectStorageManager) PutObjectWithContext(ctx context.Context, options api.PutObjectOptions) (*api.Object, api.PutObjectError) {
+	o, err := mgr.putObject(options)
+	if err != nil {
+		return nil, api.NewPutObjectError(err, options)
+	}
+	return o, nil
+}
+
+//PutObject creates or replaces an object
+func (mgr *ObjectStorageManager) PutObject(options api.PutObjectOptions) (*api.Object, api.PutObjectError) {
+	return mgr.PutObjectWithContext(context.Background(), options)
+}
+
+//GetObjectWithContext returns a reader streaming the content of an object
+func (mgr *ObjectStorageManager) GetObjectWithContext(ctx context.Context, bucketID string, key string) (*api.ObjectReader, api.GetObjectError) {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	o, err := mgr.object(bucketID, key)
+	if err != nil {
+		return nil, api.NewGetObjectError(err, bucketID, key)
+	}
+	return &api.ObjectReader{
+		Object:     o.Object,
+		ReadCloser: ioutil.NopCloser(bytes.NewReader(o.content)),
+	}, nil
+}
+
+//GetObject returns a reader streaming the content of an object
+func (mgr *ObjectStorageManager) GetObject(bucketID string, key string) (*api.ObjectReader, api.GetObjectError) {
+	return mgr.GetObjectWithContext(context.Background(), bucketID, key)
+}
+
+//ListObjectsWithContext lists the objects of a bucket sorted by key
+func (mgr *ObjectStorageManager) ListObjectsWithContext(ctx context.Context, options api.ListObjectsOptions) ([]api.Object, api.ListObjectsError) {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	b, err := mgr.bucket(options.BucketID)
+	if err != nil {
+		return nil, api.NewListObjectsError(err, options)
+	}
+	res := []api.Object{}
+	for _, o := range b.objects {
+		if strings.HasPrefix(o.Key, options.Prefix) {
+			res = append(res, o.Object)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Key < res[j].Key
+	})
+	return res, nil
+}
+
+//ListObjects lists the objects of a bucket sorted by key
+func (mgr *ObjectStorageManager) ListObjects(options api.ListObjectsOptions) ([]api.Object, api.ListObjectsError) {
+	return mgr.ListObjectsWithContext(context.Background(), options)
+}
+
+func (mgr *ObjectStorageManager) deleteObject(bucketID, key string) error {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	_, err := mgr.object(bucketID, key)
+	if err != nil {
+		return err
+	}
+	delete(p.store.buckets[bucketID].objects, key)
+	return nil
+}
+
+//DeleteObjectWithContext deletes an object
+func (mgr *ObjectStorageManager) DeleteObjectWithContext(ctx context.Context, bucketID string, key string) api.DeleteObjectError {
+	return api.NewDeleteObjectError(mgr.deleteObject(bucketID, key), bucketID, key)
+}
+
+//DeleteObject deletes an object
+func (mgr *ObjectStorageManager) DeleteObject(bucketID string, key string) api.DeleteObjectError {
+	return mgr.DeleteObjectWithContext(context.Background(), bucketID, key)
+}
+
+//signature returns the signature granting method on the object of the bucket bucketID identified by key until expires
+func (mgr *ObjectStorageManager) signature(method, bucketID, key string, expires int64) string {
+	h := hmac.New(sha256.New, mgr.secret)
+	fmt.Fprintf(h, "%s\n%d\n%s/%s", method, expires, bucketID, key)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+//startServer starts the server of the pre-signed URLs
+func (mgr *ObjectStorageManager) startServer() {
+	mgr.secret = make([]byte, 32)
+	_, mgr.serverErr = rand.Read(mgr.secret)
+	if mgr.serverErr != nil {
+		return
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		mgr.serverErr = err
+		return
+	}
+	mgr.serverURL = "http://" + l.Addr().String()
+	go http.Serve(l, http.HandlerFunc(mgr.serveHTTP))
+}
+
+//serveHTTP serves the requests made with pre-signed URLs
+func (mgr *ObjectStorageManager) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)
+	query := r.URL.Query()
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if len(path) != 2 || err != nil || time.Now().Unix() > expires ||
+		!hmac.Equal([]byte(query.Get("signature")), []byte(mgr.signature(r.Method, path[0], path[1], expires))) {
+		http.Error(w, "invalid or expired signature", http.StatusForbidden)
+		return
+	}
+	p := mgr.Provider
+	switch r.Method {
+	case http.MethodGet:
+		p.lock.Lock()
+		o, err := mgr.object(path[0], path[1])
+		p.lock.Unlock()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", o.ContentType)
+		w.Header().Set("ETag", o.ETag)
+		_, _ = w.Write(o.content)
+	case http.MethodPut:
+		content, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		p.lock.Lock()
+		o, err := mgr.save(path[0], path[1], content, r.Header.Get("Content-Type"))
+		p.lock.Unlock()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("ETag", o.ETag)
+	}
+}
+
+func (mgr *ObjectStorageManager) presignURL(options api.PresignURLOptions) (string, error) {
+	err := api.CheckPresignURLOptions(&options)
+	if err != nil {
+		return "", err
+	}
+	mgr.once.Do(mgr.startServer)
+	if mgr.serverErr != nil {
+		return "", mgr.serverErr
+	}
+	expires := time.Now().Add(options.Expiry).Unix()
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("signature", mgr.signature(options.Method, options.BucketID, options.Key, expires))
+	u := url.URL{
+		Path:     "/" + options.BucketID + "/" + options.Key,
+		RawQuery: query.Encode(),
+	}
+	return mgr.serverURL + u.String(), nil
+}
+
+//PresignURLWithContext returns a URL granting the operation defined by options until it expires
+func (mgr *ObjectStorageManager) PresignURLWithContext(ctx context.Context, options api.PresignURLOptions) (string, api.PresignURLError) {
+	u, err := mgr.presignURL(options)
+	if err != nil {
+		return "", api.NewPresignURLError(err, options)
+	}
+	return u, nil
+}
+
+//PresignURL returns a URL granting the operation defined by options until it expires
+func (mgr *ObjectStorageManager) PresignURL(options api.PresignURLOptions) (string, api.PresignURLError) {
+	return mgr.PresignURLWithContext(context.Background(), options)
+}
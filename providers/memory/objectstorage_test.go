@@ -0,0 +1,22 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/SebastienDorgan/anyclouds/tests"
+	"github.com/stretchr/testify/suite"
+)
+
+type MemoryObjectStorageManagerTestSuite struct {
+	tests.ObjectStorageManagerTestSuite
+}
+
+//SetupSuite set up object storage manager
+func (suite *MemoryObjectStorageManagerTestSuite) SetupSuite() {
+	p := GetProvider()
+	suite.Prov = p
+}
+
+func TestMemoryObjectStorageManagerTestSuite(t *testing.T) {
+	suite.Run(t, new(MemoryObjectStorageManagerTestSuite))
+}
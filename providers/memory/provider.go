@@ -0,0 +1,293 @@
+package memory
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/SebastienDorgan/anyclouds/providers"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	providers.Register("memory", func() api.Provider {
+		return &Provider{}
+	})
+}
+
+//Config memory provider configuration
+type Config struct {
+	//ProvisioningDelay time needed by a server to leave the api.ServerPending state
+	ProvisioningDelay time.Duration
+	//DefaultNetworkCIDR CIDR of the default network, no default network is created if empty
+	DefaultNetworkCIDR string
+	//PublicIPRange CIDR of the range used to allocate public ip addresses
+	PublicIPRange string
+	//Region region of the provider, it must be one of Regions
+	Region string
+	//Regions regions listed by the LocationManager, each region has the availability zones <region>a and <region>b
+	Regions []string
+}
+
+//DefaultConfig returns the configuration used when no configuration is provided
+func DefaultConfig() Config {
+	return Config{
+		ProvisioningDelay:  0,
+		DefaultNetworkCIDR: "172.31.0.0/16",
+		PublicIPRange:      "203.0.113.0/24",
+		Region:             "region-1",
+		Regions:            []string{"region-1", "region-2"},
+	}
+}
+
+//Provider in memory implementation of api.Provider
+//All the resources are stored in memory, it is intended to be used to test code built on top of api.Provider
+type Provider struct {
+	Configuration Config
+
+	ImageManager            ImageManager
+	NetworkManager          NetworkManager
+	NetworkInterfaceManager NetworkInterfaceManager
+	TemplateManager         ServerTemplateManager
+	ServerManager           ServerManager
+	SecurityGroupManager    SecurityGroupManager
+	VolumeManager           VolumeManager
+	PublicIPAddressManager  PublicIPManager
+	TagManager              TagManager
+	SnapshotManager         SnapshotManager
+	KeyPairManager          KeyPairManager
+	LoadBalancerManager     LoadBalancerManager
+	DNSManager              DNSManager
+	ObjectStorageManager    ObjectStorageManager
+	ServerGroupManager      ServerGroupManager
+	LocationManager         LocationManager
+	QuotaManager            QuotaManager
+
+	lock    sync.Mutex
+	counter uint64
+	store   store
+}
+
+type store struct {
+	images         []api.Image
+	templates      []api.ServerTemplate
+	networks       map[string]*api.Network
+	subnets        map[string]*api.Subnet
+	securityGroups map[string]*securityGroup
+	servers        map[string]*server
+	nics           map[string]*api.NetworkInterface
+	publicIPs      map[string]*api.PublicIP
+	volumes        map[string]*api.Volume
+	attachments    map[string]*api.VolumeAttachment
+	snapshots      map[string]*api.Snapshot
+	keyPairs       map[string]*api.KeyPair
+	loadBalancers  map[string]*api.LoadBalancer
+	zones          map[string]*zone
+	buckets        map[string]*bucket
+	serverGroups   map[string]*serverGroup
+}
+
+//Init initialize memory Provider
+//config can be nil, in that case DefaultConfig is used
+func (p *Provider) Init(config io.Reader, format string) error {
+	cfg := DefaultConfig()
+	if config != nil {
+		v := viper.New()
+		v.SetConfigType(format)
+		err := v.ReadConfig(config)
+		if err != nil {
+			return errors.Wrap(err, "error initializing memory provider")
+		}
+		err = v.Unmarshal(&cfg)
+		if err != nil {
+			return errors.Wrap(err, "error initializing memory provider")
+		}
+	}
+	return p.init(cfg)
+}
+
+//init initializes the provider with cfg and an empty store
+func (p *Provider) init(cfg Config) error {
+	if _, err := api.FindRegion(regions(cfg.Regions), cfg.Region); err != nil {
+		return errors.Wrap(err, "error initializing memory provider")
+	}
+	p.Configuration = cfg
+	p.store = store{
+		images:         defaultImages(),
+		templates:      defaultTemplates(),
+		networks:       map[string]*api.Network{},
+		subnets:        map[string]*api.Subnet{},
+		securityGroups: map[string]*securityGroup{},
+		servers:        map[string]*server{},
+		nics:           map[string]*api.NetworkInterface{},
+		publicIPs:      map[string]*api.PublicIP{},
+		volumes:        map[string]*api.Volume{},
+		attachments:    map[string]*api.VolumeAttachment{},
+		snapshots:      map[string]*api.Snapshot{},
+		keyPairs:       map[string]*api.KeyPair{},
+		loadBalancers:  map[string]*api.LoadBalancer{},
+		zones:          map[string]*zone{},
+		buckets:        map[string]*bucket{},
+		serverGroups:   map[string]*serverGroup{},
+	}
+	p.ImageManager.Provider = p
+	p.NetworkManager.Provider = p
+	p.NetworkInterfaceManager.Provider = p
+	p.TemplateManager.Provider = p
+	p.ServerManager.Provider = p
+	p.SecurityGroupManager.Provider = p
+	p.VolumeManager.Provider = p
+	p.PublicIPAddressManager.Provider = p
+	p.TagManager.Provider = p
+	p.SnapshotManager.Provider = p
+	p.KeyPairManager.Provider = p
+	p.LoadBalancerManager.Provider = p
+	p.DNSManager.Provider = p
+	p.ObjectStorageManager.Provider = p
+	p.ServerGroupManager.Provider = p
+	p.LocationManager.Provider = p
+	p.QuotaManager.Provider = p
+
+	if len(cfg.DefaultNetworkCIDR) > 0 {
+		_, err := p.NetworkManager.createNetwork(api.CreateNetworkOptions{
+			CIDR: cfg.DefaultNetworkCIDR,
+			Name: "",
+		})
+		if err != nil {
+			return errors.Wrap(err, "error initializing memory provider")
+		}
+	}
+	return nil
+}
+
+//newID generates a new resource identifier, must be called with the lock held
+func (p *Provider) newID(prefix string) string {
+	p.counter++
+	return fmt.Sprintf("%s-%08x", prefix, p.counter)
+}
+
+func notFound(format string, args ...interface{}) error {
+	return api.WithKind(errors.Errorf(format, args...), api.ErrNotFound)
+}
+
+func alreadyExists(format string, args ...interface{}) error {
+	return api.WithKind(errors.Errorf(format, args...), api.ErrAlreadyExists)
+}
+
+func quotaExceeded(format string, args ...interface{}) error {
+	return api.WithKind(errors.Errorf(format, args...), api.ErrQuotaExceeded)
+}
+
+func invalidArgument(format string, args ...interface{}) error {
+	return api.WithKind(errors.Errorf(format, args...), api.ErrInvalidArgument)
+}
+
+//Name name of the provider
+func (p *Provider) Name() string {
+	return "memory"
+}
+
+//GetNetworkManager returns memory NetworkManager
+func (p *Provider) GetNetworkManager() api.NetworkManager {
+	return &p.NetworkManager
+}
+
+//GetNetworkInterfaceManager returns memory NetworkInterfaceManager
+func (p *Provider) GetNetworkInterfaceManager() api.NetworkInterfaceManager {
+	return &p.NetworkInterfaceManager
+}
+
+//GetImageManager returns memory ImageManager
+func (p *Provider) GetImageManager() api.ImageManager {
+	return &p.ImageManager
+}
+
+//GetTemplateManager returns memory ServerTemplateManager
+func (p *Provider) GetTemplateManager() api.ServerTemplateManager {
+	return &p.TemplateManager
+}
+
+//GetSecurityGroupManager returns memory SecurityGroupManager
+func (p *Provider) GetSecurityGroupManager() api.SecurityGroupManager {
+	return &p.SecurityGroupManager
+}
+
+//GetServerManager returns memory ServerManager
+func (p *Provider) GetServerManager() api.ServerManager {
+	return &p.ServerManager
+}
+
+//GetVolumeManager returns memory VolumeManager
+func (p *Provider) GetVolumeManager() api.VolumeManager {
+	return &p.VolumeManager
+}
+
+//GetPublicIPAddressManager returns memory PublicIPManager
+func (p *Provider) GetPublicIPAddressManager() api.PublicIPManager {
+	return &p.PublicIPAddressManager
+}
+
+//GetTagManager returns memory TagManager
+func (p *Provider) GetTagManager() api.TagManager {
+	return &p.TagManager
+}
+
+//GetSnapshotManager returns memory SnapshotManager
+func (p *Provider) GetSnapshotManager() api.SnapshotManager {
+	return &p.SnapshotManager
+}
+
+//GetKeyPairManager returns memory KeyPairManager
+func (p *Provider) GetKeyPairManager() api.KeyPairManager {
+	return &p.KeyPairManager
+}
+
+//GetLoadBalancerManager returns memory LoadBalancerManager
+func (p *Provider) GetLoadBalancerManager() api.LoadBalancerManager {
+	return &p.LoadBalancerManager
+}
+
+//GetDNSManager returns memory DNSManager
+func (p *Provider) GetDNSManager() api.DNSManager {
+	return &p.DNSManager
+}
+
+//GetObjectStorageManager returns memory ObjectStorageManager
+func (p *Provider) GetObjectStorageManager() api.ObjectStorageManager {
+	return &p.ObjectStorageManager
+}
+
+//GetServerGroupManager returns memory ServerGroupManager
+func (p *Provider) GetServerGroupManager() api.ServerGroupManager {
+	return &p.ServerGroupManager
+}
+
+//GetLocationManager returns memory LocationManager
+func (p *Provider) GetLocationManager() api.LocationManager {
+	return &p.LocationManager
+}
+
+//GetQuotaManager returns memory QuotaManager
+func (p *Provider) GetQuotaManager() api.QuotaManager {
+	return &p.QuotaManager
+}
+
+//WithRegion returns a new memory provider with the configuration of p bound to region
+//The resources of the new provider are not shared with p
+func (p *Provider) WithRegion(region string) (api.Provider, error) {
+	_, err := api.FindRegion(regions(p.Configuration.Regions), region)
+	if err != nil {
+		return nil, err
+	}
+	cfg := p.Configuration
+	cfg.Region = region
+	res := &Provider{}
+	err = res.init(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
@@ -0,0 +1,49 @@
+package memory_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/SebastienDorgan/anyclouds/providers/memory"
+	"github.com/stretchr/testify/assert"
+)
+
+func GetProvider() *memory.Provider {
+	var provider memory.Provider
+	err := provider.Init(nil, "json")
+	if err != nil {
+		return nil
+	}
+	return &provider
+}
+
+//TestCreate create Provider provider
+func TestCreate(t *testing.T) {
+	var provider memory.Provider
+	err := provider.Init(strings.NewReader(`{"ProvisioningDelay": "10ms", "PublicIPRange": "198.51.100.0/24"}`), "json")
+	assert.NoError(t, err)
+	assert.Equal(t, "198.51.100.0/24", provider.Configuration.PublicIPRange)
+	images, err := provider.GetImageManager().List()
+	assert.NoError(t, err)
+	assert.True(t, len(images) > 0)
+	nets, err := provider.GetNetworkManager().ListNetworks()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(nets))
+	assert.Equal(t, memory.DefaultConfig().DefaultNetworkCIDR, nets[0].CIDR)
+}
+
+func TestErrorKinds(t *testing.T) {
+	prov := GetProvider()
+	_, err := prov.GetServerManager().Get("srv-unknown")
+	assert.True(t, errors.Is(err, api.ErrNotFound))
+	_, err = prov.GetNetworkManager().CreateNetwork(api.CreateNetworkOptions{Name: "invalid", CIDR: "10.0.0.0"})
+	assert.True(t, errors.Is(err, api.ErrInvalidArgument))
+	address := "203.0.113.10"
+	_, err = prov.GetPublicIPAddressManager().Create(api.CreatePublicIPOptions{Name: "ip", IPAddress: &address})
+	assert.NoError(t, err)
+	_, err = prov.GetPublicIPAddressManager().Create(api.CreatePublicIPOptions{Name: "ip", IPAddress: &address})
+	assert.True(t, errors.Is(err, api.ErrAlreadyExists))
+	assert.Equal(t, api.ErrAlreadyExists, api.ErrorKind(err))
+}
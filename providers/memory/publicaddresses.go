@@ -0,0 +1,272 @@
+package memory
+
+import (
+	"context"
+	"net"
+	"sort"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/SebastienDorgan/anyclouds/iputils"
+	"github.com/pkg/errors"
+)
+
+const publicIPPoolID = "default"
+
+//PublicIPManager memory implementation of api.PublicIPManager
+type PublicIPManager struct {
+	Provider *Provider
+}
+
+//ListAvailablePoolsWithContext list available public ip address pools
+func (mgr *PublicIPManager) ListAvailablePoolsWithContext(ctx context.Context) ([]api.PublicIPPool, api.ListAvailablePublicIPPoolsError) {
+	cidr, err := parseCIDR(mgr.Provider.Configuration.PublicIPRange)
+	if err != nil {
+		return nil, api.NewListAvailablePublicIPPoolsError(err)
+	}
+	first, last := bounds(cidr)
+	return []api.PublicIPPool{
+		{
+			ID: publicIPPoolID,
+			Ranges: []api.AddressRange{
+				{
+					FirstAddress: iputils.Utoi(first + 1).String(),
+					LastAddress:  iputils.Utoi(last - 1).String(),
+				},
+			},
+		},
+	}, nil
+}
+
+//ListAvailablePools list available public ip address pools
+func (mgr *PublicIPManager) ListAvailablePools() ([]api.PublicIPPool, api.ListAvailablePublicIPPoolsError) {
+	return mgr.ListAvailablePoolsWithContext(context.Background())
+}
+
+//ListWithContext lists public ip addresses, if options.ServerID is set only the addresses associated with the server are listed
+func (mgr *PublicIPManager) ListWithContext(ctx context.Context, options *api.ListPublicIPsOptions) ([]api.PublicIP, api.ListPublicIPsError) {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	ips := []api.PublicIP{}
+	for _, ip := range p.store.publicIPs {
+		if options != nil && options.ServerID != nil {
+			ni, ok := p.store.nics[ip.NetworkInterfaceID]
+			if !ok || ni.ServerID != *options.ServerID {
+				continue
+			}
+		}
+		ips = append(ips, *ip)
+	}
+	sort.Slice(ips, func(i, j int) bool {
+		return ips[i].ID < ips[j].ID
+	})
+	return ips, nil
+}
+
+//List lists public ip addresses, if options.ServerID is set only the addresses associated with the server are listed
+func (mgr *PublicIPManager) List(options *api.ListPublicIPsOptions) ([]api.PublicIP, api.ListPublicIPsError) {
+	return mgr.ListWithContext(context.Background(), options)
+}
+
+func (mgr *PublicIPManager) create(options api.CreatePublicIPOptions) (*api.PublicIP, error) {
+	if options.IPAddressPoolID != nil && *options.IPAddressPoolID != publicIPPoolID {
+		return nil, notFound("public ip address pool %s not found", *options.IPAddressPoolID)
+	}
+	cidr, err := parseCIDR(mgr.Provider.Configuration.PublicIPRange)
+	if err != nil {
+		return nil, err
+	}
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	used := map[string]bool{}
+	for _, ip := range p.store.publicIPs {
+		used[ip.Address] = true
+	}
+	address := ""
+	if options.IPAddress != nil {
+		ip := net.ParseIP(*options.IPAddress)
+		if ip == nil || !cidr.Contains(ip) {
+			return nil, invalidArgument("ip address %s is not in public ip address pool", *options.IPAddress)
+		}
+		if used[ip.String()] {
+			return nil, alreadyExists("ip address %s is already allocated", *options.IPAddress)
+		}
+		address = ip.String()
+	} else {
+		first, last := bounds(cidr)
+		for u := first + 1; u < last; u++ {
+			ip := iputils.Utoi(u).String()
+			if !used[ip] {
+				address = ip
+				break
+			}
+		}
+		if len(address) == 0 {
+			return nil, quotaExceeded("public ip address pool is exhausted")
+		}
+	}
+	ip := &api.PublicIP{
+		ID:      p.newID("ip"),
+		Name:    options.Name,
+		Address: address,
+		Tags:    copyTags(options.Tags),
+	}
+	p.store.publicIPs[ip.ID] = ip
+	res := *ip
+	return &res, nil
+}
+
+//CreateWithContext allocates a public ip address
+func (mgr *PublicIPManager) CreateWithContext(ctx context.Context, options api.CreatePublicIPOptions) (*api.PublicIP, api.CreatePublicIPError) {
+	ip, err := mgr.create(options)
+	return ip, api.NewCreatePublicIPError(err, options)
+}
+
+//Create allocates a public ip address
+func (mgr *PublicIPManager) Create(options api.CreatePublicIPOptions) (*api.PublicIP, api.CreatePublicIPError) {
+	return mgr.CreateWithContext(context.Background(), options)
+}
+
+//findNetworkInterface finds the network interface of the server matching options, must be called with the lock held
+func (mgr *PublicIPManager) findNetworkInterface(options api.AssociatePublicIPOptions) (*api.NetworkInterface, error) {
+	var found []*api.NetworkInterface
+	for _, ni := range mgr.Provider.store.nics {
+		if ni.ServerID != options.ServerID {
+			continue
+		}
+		if len(options.SubnetID) > 0 && ni.SubnetID != options.SubnetID {
+			continue
+		}
+		if len(options.PrivateIP) > 0 && ni.PrivateIPAddress != options.PrivateIP {
+			continue
+		}
+		found = append(found, ni)
+	}
+	if len(found) == 0 {
+		return nil, invalidArgument("no network interface of server %s matches", options.ServerID)
+	}
+	if len(found) > 1 {
+		return nil, invalidArgument("server %s has several network interfaces, subnet must be provided", options.ServerID)
+	}
+	return found[0], nil
+}
+
+func (mgr *PublicIPManager) associate(options api.AssociatePublicIPOptions) error {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	ip, ok := p.store.publicIPs[options.PublicIPId]
+	if !ok {
+		return notFound("public ip %s not found", options.PublicIPId)
+	}
+	if len(ip.NetworkInterfaceID) > 0 {
+		return alreadyExists("public ip %s is already associated with network interface %s", ip.ID, ip.NetworkInterfaceID)
+	}
+	if _, ok := p.store.servers[options.ServerID]; !ok {
+		return notFound("server %s not found", options.ServerID)
+	}
+	ni, err := mgr.findNetworkInterface(options)
+	if err != nil {
+		return err
+	}
+	if len(ni.PublicIPAddress) > 0 {
+		return alreadyExists("network interface %s already has public ip address %s", ni.ID, ni.PublicIPAddress)
+	}
+	ip.NetworkInterfaceID = ni.ID
+	ip.PrivateAddress = ni.PrivateIPAddress
+	ni.PublicIPAddress = ip.Address
+	return nil
+}
+
+//AssociateWithContext associates a public ip address to a server
+func (mgr *PublicIPManager) AssociateWithContext(ctx context.Context, options api.AssociatePublicIPOptions) api.AssociatePublicIPError {
+	return api.NewAssociatePublicIPError(mgr.associate(options), options)
+}
+
+//Associate associates a public ip address to a server
+func (mgr *PublicIPManager) Associate(options api.AssociatePublicIPOptions) api.AssociatePublicIPError {
+	return mgr.AssociateWithContext(context.Background(), options)
+}
+
+//dissociate dissociates the public ip ip, must be called with the lock held
+func (p *Provider) dissociate(ip *api.PublicIP) {
+	if ni, ok := p.store.nics[ip.NetworkInterfaceID]; ok {
+		ni.PublicIPAddress = ""
+	}
+	ip.NetworkInterfaceID = ""
+	ip.PrivateAddress = ""
+}
+
+func (mgr *PublicIPManager) dissociateIP(id string) error {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	ip, ok := p.store.publicIPs[id]
+	if !ok {
+		return notFound("public ip %s not found", id)
+	}
+	if len(ip.NetworkInterfaceID) == 0 {
+		return errors.Errorf("public ip %s is not associated", id)
+	}
+	p.dissociate(ip)
+	return nil
+}
+
+//DissociateWithContext dissociates the public ip address identified by id
+func (mgr *PublicIPManager) DissociateWithContext(ctx context.Context, id string) api.DissociatePublicIPError {
+	return api.NewDissociatePublicIPError(mgr.dissociateIP(id), id)
+}
+
+//Dissociate dissociates the public ip address identified by id
+func (mgr *PublicIPManager) Dissociate(id string) api.DissociatePublicIPError {
+	return mgr.DissociateWithContext(context.Background(), id)
+}
+
+func (mgr *PublicIPManager) delete(id string) error {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	ip, ok := p.store.publicIPs[id]
+	if !ok {
+		return notFound("public ip %s not found", id)
+	}
+	if len(ip.NetworkInterfaceID) > 0 {
+		return errors.Errorf("public ip %s is associated with network interface %s", id, ip.NetworkInterfaceID)
+	}
+	delete(p.store.publicIPs, id)
+	return nil
+}
+
+//DeleteWithContext releases the public ip address identified by id
+func (mgr *PublicIPManager) DeleteWithContext(ctx context.Context, id string) api.DeletePublicIPError {
+	return api.NewDeletePublicIPError(mgr.delete(id), id)
+}
+
+//Delete releases the public ip address identified by id
+func (mgr *PublicIPManager) Delete(id string) api.DeletePublicIPError {
+	return mgr.DeleteWithContext(context.Background(), id)
+}
+
+func (mgr *PublicIPManager) get(id string) (*api.PublicIP, error) {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	ip, ok := p.store.publicIPs[id]
+	if !ok {
+		return nil, notFound("public ip %s not found", id)
+	}
+	res := *ip
+	return &res, nil
+}
+
+//GetWithContext retrieves the public ip address identified by id
+func (mgr *PublicIPManager) GetWithContext(ctx context.Context, id string) (*api.PublicIP, api.GetPublicIPError) {
+	ip, err := mgr.get(id)
+	return ip, api.NewGetPublicIPError(err, id)
+}
+
+//Get retrieves the public ip address identified by id
+func (mgr *PublicIPManager) Get(id string) (*api.PublicIP, api.GetPublicIPError) {
+	return mgr.GetWithContext(context.Background(), id)
+}
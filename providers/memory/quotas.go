@@ -0,0 +1,64 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+)
+
+//QuotaManager memory implementation of api.QuotaManager
+//Only the number of public ip addresses is limited, by the size of the public ip address range
+type QuotaManager struct {
+	Provider *Provider
+}
+
+func unlimited(used int) api.Limit {
+	return api.Limit{Max: api.UnknownLimit, Used: used}
+}
+
+func (mgr *QuotaManager) getLimits() (*api.Limits, error) {
+	cidr, err := parseCIDR(mgr.Provider.Configuration.PublicIPRange)
+	if err != nil {
+		return nil, err
+	}
+	first, last := bounds(cidr)
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	cores, ram := 0, 0
+	for _, srv := range p.store.servers {
+		tpl, err := p.TemplateManager.find(srv.TemplateID)
+		if err != nil {
+			continue
+		}
+		cores += tpl.NumberOfCPUCore
+		ram += tpl.RAMSize
+	}
+	storage := 0
+	for _, v := range p.store.volumes {
+		storage += int(v.Size)
+	}
+	return &api.Limits{
+		Instances:      unlimited(len(p.store.servers)),
+		Cores:          unlimited(cores),
+		RAMSize:        unlimited(ram),
+		Volumes:        unlimited(len(p.store.volumes)),
+		StorageSize:    unlimited(storage),
+		PublicIPs:      api.Limit{Max: int(last - first - 1), Used: len(p.store.publicIPs)},
+		SecurityGroups: unlimited(len(p.store.securityGroups)),
+	}, nil
+}
+
+//GetLimitsWithContext returns the limits and the current usage of the provider
+func (mgr *QuotaManager) GetLimitsWithContext(ctx context.Context) (*api.Limits, api.GetLimitsError) {
+	limits, err := mgr.getLimits()
+	if err != nil {
+		return nil, api.NewGetLimitsError(err)
+	}
+	return limits, nil
+}
+
+//GetLimits returns the limits and the current usage of the provider
+func (mgr *QuotaManager) GetLimits() (*api.Limits, api.GetLimitsError) {
+	return mgr.GetLimitsWithContext(context.Background())
+}
@@ -0,0 +1,22 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/SebastienDorgan/anyclouds/tests"
+	"github.com/stretchr/testify/suite"
+)
+
+type MemoryQuotaManagerTestSuite struct {
+	tests.QuotaManagerTestSuite
+}
+
+//SetupSuite set up quota manager
+func (suite *MemoryQuotaManagerTestSuite) SetupSuite() {
+	p := GetProvider()
+	suite.Prov = p
+}
+
+func TestMemoryQuotaManagerTestSuite(t *testing.T) {
+	suite.Run(t, new(MemoryQuotaManagerTestSuite))
+}
@@ -0,0 +1,276 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/pkg/errors"
+)
+
+const defaultSecurityGroupName = "default"
+
+type securityGroup struct {
+	api.SecurityGroup
+	Description string
+	//isDefault default security group of a network, it is deleted with the network
+	isDefault bool
+}
+
+func (sg *securityGroup) copy() *api.SecurityGroup {
+	res := sg.SecurityGroup
+	res.Rules = make([]api.SecurityRule, len(sg.Rules))
+	copy(res.Rules, sg.Rules)
+	return &res
+}
+
+//SecurityGroupManager memory implementation of api.SecurityGroupManager
+type SecurityGroupManager struct {
+	Provider *Provider
+}
+
+func (mgr *SecurityGroupManager) create(options api.SecurityGroupOptions) (*api.SecurityGroup, error) {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if _, ok := p.store.networks[options.NetworkID]; !ok {
+		return nil, notFound("network %s not found", options.NetworkID)
+	}
+	sg := &securityGroup{
+		SecurityGroup: api.SecurityGroup{
+			ID:        p.newID("sg"),
+			Name:      options.Name,
+			NetworkID: options.NetworkID,
+			Rules:     []api.SecurityRule{},
+			Tags:      copyTags(options.Tags),
+		},
+		Description: options.Description,
+	}
+	p.store.securityGroups[sg.ID] = sg
+	return sg.copy(), nil
+}
+
+//CreateWithContext creates an open Security Group
+func (mgr *SecurityGroupManager) CreateWithContext(ctx context.Context, options api.SecurityGroupOptions) (*api.SecurityGroup, api.CreateSecurityGroupError) {
+	sg, err := mgr.create(options)
+	if err != nil {
+		return nil, api.NewCreateSecurityGroupError(err, options)
+	}
+	return sg, nil
+}
+
+//Create creates an open Security Group
+func (mgr *SecurityGroupManager) Create(options api.SecurityGroupOptions) (*api.SecurityGroup, api.CreateSecurityGroupError) {
+	return mgr.CreateWithContext(context.Background(), options)
+}
+
+func (mgr *SecurityGroupManager) delete(id string) error {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	sg, ok := p.store.securityGroups[id]
+	if !ok {
+		return notFound("security group %s not found", id)
+	}
+	if sg.isDefault {
+		return errors.Errorf("default security group %s of network %s cannot be deleted", id, sg.NetworkID)
+	}
+	for _, ni := range p.store.nics {
+		if ni.SecurityGroupID == id {
+			return errors.Errorf("security group %s is used by network interface %s", id, ni.ID)
+		}
+	}
+	delete(p.store.securityGroups, id)
+	return nil
+}
+
+//DeleteWithContext deletes the Security Group identified by id
+func (mgr *SecurityGroupManager) DeleteWithContext(ctx context.Context, id string) api.DeleteSecurityGroupError {
+	err := mgr.delete(id)
+	if err != nil {
+		return api.NewDeleteSecurityGroupError(err, id)
+	}
+	return nil
+}
+
+//Delete deletes the Security Group identified by id
+func (mgr *SecurityGroupManager) Delete(id string) api.DeleteSecurityGroupError {
+	return mgr.DeleteWithContext(context.Background(), id)
+}
+
+//ListWithContext list all Security Groups
+func (mgr *SecurityGroupManager) ListWithContext(ctx context.Context) ([]api.SecurityGroup, api.ListSecurityGroupsError) {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	sgs := []api.SecurityGroup{}
+	for _, sg := range p.store.securityGroups {
+		sgs = append(sgs, *sg.copy())
+	}
+	sort.Slice(sgs, func(i, j int) bool {
+		return sgs[i].ID < sgs[j].ID
+	})
+	return sgs, nil
+}
+
+//List list all Security Groups
+func (mgr *SecurityGroupManager) List() ([]api.SecurityGroup, api.ListSecurityGroupsError) {
+	return mgr.ListWithContext(context.Background())
+}
+
+func (mgr *SecurityGroupManager) get(id string) (*api.SecurityGroup, error) {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	sg, ok := p.store.securityGroups[id]
+	if !ok {
+		return nil, notFound("security group %s not found", id)
+	}
+	return sg.copy(), nil
+}
+
+//GetWithContext returns the Security Group identified by id
+func (mgr *SecurityGroupManager) GetWithContext(ctx context.Context, id string) (*api.SecurityGroup, api.GetSecurityGroupError) {
+	sg, err := mgr.get(id)
+	if err != nil {
+		return nil, api.NewGetSecurityGroupError(err, id)
+	}
+	return sg, nil
+}
+
+//Get returns the Security Group identified by id
+func (mgr *SecurityGroupManager) Get(id string) (*api.SecurityGroup, api.GetSecurityGroupError) {
+	return mgr.GetWithContext(context.Background(), id)
+}
+
+func (mgr *SecurityGroupManager) attach(options api.AttachSecurityGroupOptions) error {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	sg, ok := p.store.securityGroups[options.SecurityGroupID]
+	if !ok {
+		return notFound("security group %s not found", options.SecurityGroupID)
+	}
+	if _, ok := p.store.servers[options.ServerID]; !ok {
+		return notFound("server %s not found", options.ServerID)
+	}
+	attached := false
+	for _, ni := range p.store.nics {
+		if ni.ServerID != options.ServerID || ni.NetworkID != options.NetworkID {
+			continue
+		}
+		if len(options.SubnetID) > 0 && ni.SubnetID != options.SubnetID {
+			continue
+		}
+		if options.IPAddress != nil && ni.PrivateIPAddress != *options.IPAddress {
+			continue
+		}
+		if ni.NetworkID != sg.NetworkID {
+			return invalidArgument("security group %s and network interface %s are not in the same network", sg.ID, ni.ID)
+		}
+		ni.SecurityGroupID = sg.ID
+		attached = true
+	}
+	if !attached {
+		return invalidArgument("no network interface of server %s matches", options.ServerID)
+	}
+	return nil
+}
+
+//AttachWithContext a server to a security group
+func (mgr *SecurityGroupManager) AttachWithContext(ctx context.Context, options api.AttachSecurityGroupOptions) api.AttachSecurityGroupError {
+	err := mgr.attach(options)
+	if err != nil {
+		return api.NewAttachSecurityGroupError(err, options)
+	}
+	return nil
+}
+
+//Attach a server to a security group
+func (mgr *SecurityGroupManager) Attach(options api.AttachSecurityGroupOptions) api.AttachSecurityGroupError {
+	return mgr.AttachWithContext(context.Background(), options)
+}
+
+func (mgr *SecurityGroupManager) addSecurityRule(options api.AddSecurityRuleOptions) (*api.SecurityRule, error) {
+	switch options.Direction {
+	case api.RuleDirectionIngress, api.RuleDirectionEgress:
+	default:
+		return nil, invalidArgument("invalid rule direction %s", options.Direction)
+	}
+	switch options.Protocol {
+	case api.ProtocolAny, api.ProtocolTCP, api.ProtocolUDP, api.ProtocolICMP:
+	default:
+		return nil, invalidArgument("invalid protocol %s", options.Protocol)
+	}
+	if options.PortRange.From > options.PortRange.To {
+		return nil, invalidArgument("invalid port range %d-%d", options.PortRange.From, options.PortRange.To)
+	}
+	if len(options.CIDR) > 0 {
+		if _, err := parseCIDR(options.CIDR); err != nil {
+			return nil, err
+		}
+	}
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	sg, ok := p.store.securityGroups[options.SecurityGroupID]
+	if !ok {
+		return nil, notFound("security group %s not found", options.SecurityGroupID)
+	}
+	rule := api.SecurityRule{
+		ID:              p.newID("rule"),
+		SecurityGroupID: sg.ID,
+		Direction:       options.Direction,
+		PortRange:       options.PortRange,
+		Protocol:        options.Protocol,
+		CIDR:            options.CIDR,
+		Description:     options.Description,
+	}
+	sg.Rules = append(sg.Rules, rule)
+	return &rule, nil
+}
+
+//AddSecurityRuleWithContext adds a security rule to a security group
+func (mgr *SecurityGroupManager) AddSecurityRuleWithContext(ctx context.Context, options api.AddSecurityRuleOptions) (*api.SecurityRule, api.AddSecurityRuleError) {
+	rule, err := mgr.addSecurityRule(options)
+	if err != nil {
+		return nil, api.NewAddSecurityRuleError(err, options)
+	}
+	return rule, nil
+}
+
+//AddSecurityRule adds a security rule to a security group
+func (mgr *SecurityGroupManager) AddSecurityRule(options api.AddSecurityRuleOptions) (*api.SecurityRule, api.AddSecurityRuleError) {
+	return mgr.AddSecurityRuleWithContext(context.Background(), options)
+}
+
+func (mgr *SecurityGroupManager) removeSecurityRule(groupID, ruleID string) error {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	sg, ok := p.store.securityGroups[groupID]
+	if !ok {
+		return notFound("security group %s not found", groupID)
+	}
+	for i, r := range sg.Rules {
+		if r.ID == ruleID {
+			sg.Rules = append(sg.Rules[:i], sg.Rules[i+1:]...)
+			return nil
+		}
+	}
+	return notFound("rule %s not found in security group %s", ruleID, groupID)
+}
+
+//RemoveSecurityRuleWithContext removes the security rule identified by ruleID from the security group identified by groupID
+func (mgr *SecurityGroupManager) RemoveSecurityRuleWithContext(ctx context.Context, groupID, ruleID string) api.RemoveSecurityRuleError {
+	err := mgr.removeSecurityRule(groupID, ruleID)
+	if err != nil {
+		return api.NewRemoveSecurityRuleError(err, groupID, ruleID)
+	}
+	return nil
+}
+
+//RemoveSecurityRule removes the security rule identified by ruleID from the security group identified by groupID
+func (mgr *SecurityGroupManager) RemoveSecurityRule(groupID, ruleID string) api.RemoveSecurityRuleError {
+	return mgr.RemoveSecurityRuleWithContext(context.Background(), groupID, ruleID)
+}
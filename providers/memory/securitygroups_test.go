@@ -0,0 +1,22 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/SebastienDorgan/anyclouds/tests"
+	"github.com/stretchr/testify/suite"
+)
+
+type MemorySecurityGroupManagerTestSuite struct {
+	tests.SecurityGroupManagerTestSuite
+}
+
+//SetupSuite set up security group manager
+func (suite *MemorySecurityGroupManagerTestSuite) SetupSuite() {
+	p := GetProvider()
+	suite.Prov = p
+}
+
+func TestMemorySecurityGroupManagerTestSuite(t *testing.T) {
+	suite.Run(t, new(MemorySecurityGroupManagerTestSuite))
+}
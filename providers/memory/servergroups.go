@@ -0,0 +1,302 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/pkg/errors"
+)
+
+//serverGroup a group of servers launched from the same specification
+type serverGroup struct {
+	api.ServerGroup
+	spec api.CreateServerOptions
+}
+
+//ServerGroupManager memory implementation of api.ServerGroupManager
+//Groups are reconciled each time they are accessed: deleted servers are replaced and surplus servers are deleted
+type ServerGroupManager struct {
+	Provider *Provider
+}
+
+//reconcile launches or deletes servers until the group has DesiredSize servers, must be called with the lock held
+//It returns true if servers were launched
+func (mgr *ServerGroupManager) reconcile(g *serverGroup) (bool, error) {
+	p := mgr.Provider
+	var ids []string
+	for _, id := range g.ServerIDs {
+		if _, ok := p.store.servers[id]; ok {
+			ids = append(ids, id)
+		}
+	}
+	//the most recent servers are deleted first
+	for len(ids) > g.DesiredSize {
+		err := p.ServerManager.remove(ids[len(ids)-1])
+		if err != nil {
+			return false, err
+		}
+		ids = ids[:len(ids)-1]
+	}
+	launched := false
+	defer func() {
+		sort.Strings(ids)
+		g.ServerIDs = ids
+	}()
+	for len(ids) < g.DesiredSize {
+		spec := g.spec
+		spec.Name = g.Name
+		srv, err := p.ServerManager.launch(&spec)
+		if err != nil {
+			return launched, err
+		}
+		ids = append(ids, srv.ID)
+		launched = true
+	}
+	return launched, nil
+}
+
+//group returns the group identified by id once reconciled, must be called with the lock held
+func (mgr *ServerGroupManager) group(id string) (*serverGroup, bool, error) {
+	g, ok := mgr.Provider.store.serverGroups[id]
+	if !ok {
+		return nil, false, notFound("server group %s not found", id)
+	}
+	launched, err := mgr.reconcile(g)
+	return g, launched, err
+}
+
+func (g *serverGroup) copy() *api.ServerGroup {
+	res := g.ServerGroup
+	res.ServerIDs = append([]string(nil), g.ServerIDs...)
+	res.Tags = copyTags(g.Tags)
+	return &res
+}
+
+//wait waits for the servers launched by a reconciliation to leave the api.ServerPending state
+func (mgr *ServerGroupManager) wait(ctx context.Context, id string, launched bool) (*api.ServerGroup, error) {
+	delay := mgr.Provider.Configuration.ProvisioningDelay
+	if !launched {
+		delay = 0
+	}
+	select {
+	case <-time.After(delay):
+		return mgr.get(id)
+	case <-ctx.Done():
+		return nil, errors.Wrapf(ctx.Err(), "servers of group %s do not reach ready state", id)
+	}
+}
+
+func (mgr *ServerGroupManager) create(ctx context.Context, options api.CreateServerGroupOptions) (*api.ServerGroup, error) {
+	err := api.CheckServerGroupOptions(&options)
+	if err != nil {
+		return nil, err
+	}
+	p := mgr.Provider
+	p.lock.Lock()
+	for _, g := range p.store.serverGroups {
+		if g.Name == options.Name {
+			p.lock.Unlock()
+			return nil, alreadyExists("server group %s already exists", options.Name)
+		}
+	}
+	g := &serverGroup{
+		ServerGroup: api.ServerGroup{
+			ID:          p.newID("grp"),
+			Name:        options.Name,
+			MinSize:     options.Size.MinSize,
+			MaxSize:     options.Size.MaxSize,
+			DesiredSize: options.Size.DesiredSize,
+			Tags:        copyTags(options.Tags),
+		},
+		spec: options.Spec,
+	}
+	launched, err := mgr.reconcile(g)
+	if err != nil {
+		for _, id := range g.ServerIDs {
+			_ = p.ServerManager.remove(id)
+		}
+		p.lock.Unlock()
+		return nil, err
+	}
+	p.store.serverGroups[g.ID] = g
+	p.lock.Unlock()
+	return mgr.wait(ctx, g.ID, launched)
+}
+
+//CreateWithContext creates a server group
+func (mgr *ServerGroupManager) CreateWithContext(ctx context.Context, options api.CreateServerGroupOptions) (*api.ServerGroup, api.CreateServerGroupError) {
+	g, err := mgr.create(ctx, options)
+	if err != nil {
+		return nil, api.NewCreateServerGroupError(err, options)
+	}
+	return g, nil
+}
+
+//Create creates a server group
+func (mgr *ServerGroupManager) Create(options api.CreateServerGroupOptions) (*api.ServerGroup, api.CreateServerGroupError) {
+	return mgr.CreateWithContext(context.Background(), options)
+}
+
+func (mgr *ServerGroupManager) delete(id string) error {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	g, ok := p.store.serverGroups[id]
+	if !ok {
+		return notFound("server group %s not found", id)
+	}
+	for _, srvID := range g.ServerIDs {
+		if _, ok := p.store.servers[srvID]; ok {
+			err := p.ServerManager.remove(srvID)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	delete(p.store.serverGroups, id)
+	return nil
+}
+
+//DeleteWithContext deletes the server group identified by id and its servers
+func (mgr *ServerGroupManager) DeleteWithContext(ctx context.Context, id string) api.DeleteServerGroupError {
+	return api.NewDeleteServerGroupError(mgr.delete(id), id)
+}
+
+//Delete deletes the server group identified by id and its servers
+func (mgr *ServerGroupManager) Delete(id string) api.DeleteServerGroupError {
+	return mgr.DeleteWithContext(context.Background(), id)
+}
+
+func (mgr *ServerGroupManager) list() ([]api.ServerGroup, error) {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	groups := []api.ServerGroup{}
+	for id := range p.store.serverGroups {
+		g, _, err := mgr.group(id)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, *g.copy())
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].ID < groups[j].ID
+	})
+	return groups, nil
+}
+
+//ListWithContext lists the server groups
+func (mgr *ServerGroupManager) ListWithContext(ctx context.Context) ([]api.ServerGroup, api.ListServerGroupsError) {
+	groups, err := mgr.list()
+	if err != nil {
+		return nil, api.NewListServerGroupsError(err)
+	}
+	return groups, nil
+}
+
+//List lists the server groups
+func (mgr *ServerGroupManager) List() ([]api.ServerGroup, api.ListServerGroupsError) {
+	return mgr.ListWithContext(context.Background())
+}
+
+func (mgr *ServerGroupManager) get(id string) (*api.ServerGroup, error) {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	g, _, err := mgr.group(id)
+	if err != nil {
+		return nil, err
+	}
+	return g.copy(), nil
+}
+
+//GetWithContext returns the server group identified by id
+func (mgr *ServerGroupManager) GetWithContext(ctx context.Context, id string) (*api.ServerGroup, api.GetServerGroupError) {
+	g, err := mgr.get(id)
+	if err != nil {
+		return nil, api.NewGetServerGroupError(err, id)
+	}
+	return g, nil
+}
+
+//Get returns the server group identified by id
+func (mgr *ServerGroupManager) Get(id string) (*api.ServerGroup, api.GetServerGroupError) {
+	return mgr.GetWithContext(context.Background(), id)
+}
+
+func (mgr *ServerGroupManager) scale(ctx context.Context, id string, size api.ServerGroupSize) (*api.ServerGroup, error) {
+	err := api.CheckServerGroupSize(size)
+	if err != nil {
+		return nil, err
+	}
+	p := mgr.Provider
+	p.lock.Lock()
+	g, ok := p.store.serverGroups[id]
+	if !ok {
+		p.lock.Unlock()
+		return nil, notFound("server group %s not found", id)
+	}
+	g.MinSize, g.MaxSize, g.DesiredSize = size.MinSize, size.MaxSize, size.DesiredSize
+	launched, err := mgr.reconcile(g)
+	p.lock.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return mgr.wait(ctx, id, launched)
+}
+
+//ScaleWithContext changes the size of the server group identified by id
+func (mgr *ServerGroupManager) ScaleWithContext(ctx context.Context, id string, size api.ServerGroupSize) (*api.ServerGroup, api.ScaleServerGroupError) {
+	g, err := mgr.scale(ctx, id, size)
+	if err != nil {
+		return nil, api.NewScaleServerGroupError(err, id, size)
+	}
+	return g, nil
+}
+
+//Scale changes the size of the server group identified by id
+func (mgr *ServerGroupManager) Scale(id string, size api.ServerGroupSize) (*api.ServerGroup, api.ScaleServerGroupError) {
+	return mgr.ScaleWithContext(context.Background(), id, size)
+}
+
+func (mgr *ServerGroupManager) replaceServer(ctx context.Context, id string, serverID string) (*api.ServerGroup, error) {
+	p := mgr.Provider
+	p.lock.Lock()
+	g, _, err := mgr.group(id)
+	if err != nil {
+		p.lock.Unlock()
+		return nil, err
+	}
+	i := sort.SearchStrings(g.ServerIDs, serverID)
+	if i == len(g.ServerIDs) || g.ServerIDs[i] != serverID {
+		p.lock.Unlock()
+		return nil, notFound("server %s is not a server of group %s", serverID, id)
+	}
+	err = p.ServerManager.remove(serverID)
+	if err != nil {
+		p.lock.Unlock()
+		return nil, err
+	}
+	launched, err := mgr.reconcile(g)
+	p.lock.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return mgr.wait(ctx, id, launched)
+}
+
+//ReplaceServerWithContext deletes the server identified by serverID and launches its replacement
+func (mgr *ServerGroupManager) ReplaceServerWithContext(ctx context.Context, id string, serverID string) (*api.ServerGroup, api.ReplaceServerGroupServerError) {
+	g, err := mgr.replaceServer(ctx, id, serverID)
+	if err != nil {
+		return nil, api.NewReplaceServerGroupServerError(err, id, serverID)
+	}
+	return g, nil
+}
+
+//ReplaceServer deletes the server identified by serverID and launches its replacement
+func (mgr *ServerGroupManager) ReplaceServer(id string, serverID string) (*api.ServerGroup, api.ReplaceServerGroupServerError) {
+	return mgr.ReplaceServerWithContext(context.Background(), id, serverID)
+}
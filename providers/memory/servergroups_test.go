@@ -0,0 +1,22 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/SebastienDorgan/anyclouds/tests"
+	"github.com/stretchr/testify/suite"
+)
+
+type MemoryServerGroupManagerTestSuite struct {
+	tests.ServerGroupManagerTestSuite
+}
+
+//SetupSuite set up server group manager
+func (suite *MemoryServerGroupManagerTestSuite) SetupSuite() {
+	p := GetProvider()
+	suite.Prov = p
+}
+
+func TestMemoryServerGroupManagerTestSuite(t *testing.T) {
+	suite.Run(t, new(MemoryServerGroupManagerTestSuite))
+}
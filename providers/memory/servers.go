@@ -0,0 +1,374 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/pkg/errors"
+)
+
+//spotPriceRatio ratio between the spot price and the on demand price of a template
+const spotPriceRatio = 0.2
+
+type server struct {
+	api.Server
+	//target state reached by the server when leaving the api.ServerPending state
+	target  api.ServerState
+	readyAt time.Time
+}
+
+//refresh updates the server state, must be called with the lock held
+func (s *server) refresh() {
+	if s.State == api.ServerPending && !time.Now().Before(s.readyAt) {
+		s.State = s.target
+	}
+}
+
+//transition moves the server into the api.ServerPending state until delay is elapsed
+func (s *server) transition(target api.ServerState, delay time.Duration) {
+	s.State = api.ServerPending
+	s.target = target
+	s.readyAt = time.Now().Add(delay)
+	s.refresh()
+}
+
+//ServerManager memory implementation of api.ServerManager
+type ServerManager struct {
+	Provider *Provider
+}
+
+func (mgr *ServerManager) create(ctx context.Context, options api.CreateServerOptions) (*api.Server, error) {
+	p := mgr.Provider
+	p.lock.Lock()
+	srv, err := mgr.launch(&options)
+	p.lock.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case <-time.After(p.Configuration.ProvisioningDelay):
+		return mgr.get(srv.ID)
+	case <-ctx.Done():
+		return nil, errors.Wrapf(ctx.Err(), "server %s does not reach ready state", srv.ID)
+	}
+}
+
+//launch creates a server in the api.ServerPending state, must be called with the lock held
+func (mgr *ServerManager) launch(options *api.CreateServerOptions) (*server, error) {
+	p := mgr.Provider
+	tpl, err := p.TemplateManager.find(options.TemplateID)
+	if err != nil {
+		return nil, err
+	}
+	err = mgr.checkImage(options.ImageID)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := p.store.keyPairs[options.KeyPairName]; options.KeyPairName != "" && !ok {
+		return nil, notFound("key pair %s not found", options.KeyPairName)
+	}
+	srv := &server{
+		Server: api.Server{
+			ID:          p.newID("srv"),
+			Name:        options.Name,
+			TemplateID:  tpl.ID,
+			ImageID:     options.ImageID,
+			CreatedAt:   time.Now(),
+			LeasingType: api.LeasingTypeOnDemand,
+			Tags:        copyTags(options.Tags),
+		},
+	}
+	if options.LowPriorityServerOptions != nil {
+		if options.LowPriorityServerOptions.HourlyPrice < tpl.OneDemandPrice*spotPriceRatio {
+			return nil, invalidArgument("hourly price %f is lower than spot price %f",
+				options.LowPriorityServerOptions.HourlyPrice, tpl.OneDemandPrice*spotPriceRatio)
+		}
+		srv.LeasingType = api.LeasingTypeSpot
+		srv.LeaseDuration = options.LowPriorityServerOptions.Duration
+	} else if options.ReservedServerOptions != nil {
+		srv.LeasingType = api.LeasingTypeReserved
+		srv.LeaseDuration = options.ReservedServerOptions.Duration
+	}
+	err = mgr.createNetworkInterfaces(srv.ID, options)
+	if err != nil {
+		return nil, err
+	}
+	srv.transition(api.ServerReady, p.Configuration.ProvisioningDelay)
+	p.store.servers[srv.ID] = srv
+	return srv, nil
+}
+
+//checkImage checks that the image identified by id exists, must be called with the lock held
+func (mgr *ServerManager) checkImage(id string) error {
+	for _, img := range mgr.Provider.store.images {
+		if img.ID == id {
+			return nil
+		}
+	}
+	return notFound("image %s not found", id)
+}
+
+//createNetworkInterfaces creates one network interface by subnet, must be called with the lock held
+func (mgr *ServerManager) createNetworkInterfaces(serverID string, options *api.CreateServerOptions) error {
+	p := mgr.Provider
+	if len(options.Subnets) == 0 {
+		return invalidArgument("at least one subnet must be provided")
+	}
+	var nics []*api.NetworkInterface
+	rollback := func(err error) error {
+		for _, ni := range nics {
+			delete(p.store.nics, ni.ID)
+		}
+		return err
+	}
+	for i, s := range options.Subnets {
+		sn, ok := p.store.subnets[s.ID]
+		if !ok {
+			return rollback(notFound("subnet %s not found", s.ID))
+		}
+		sgID, err := p.selectSecurityGroup(sn.NetworkID, options.DefaultSecurityGroup)
+		if err != nil {
+			return rollback(err)
+		}
+		ip, err := p.allocatePrivateIP(sn)
+		if err != nil {
+			return rollback(err)
+		}
+		id := p.newID("nic")
+		ni := &api.NetworkInterface{
+			ID:               id,
+			Name:             fmt.Sprintf("%s-%d", options.Name, i),
+			MacAddress:       macAddress(p.counter),
+			NetworkID:        sn.NetworkID,
+			SubnetID:         sn.ID,
+			ServerID:         serverID,
+			PrivateIPAddress: ip,
+			SecurityGroupID:  sgID,
+		}
+		p.store.nics[id] = ni
+		nics = append(nics, ni)
+	}
+	return nil
+}
+
+//selectSecurityGroup returns sgID if set or the default security group of the network, must be called with the lock held
+func (p *Provider) selectSecurityGroup(networkID string, sgID string) (string, error) {
+	if len(sgID) > 0 {
+		sg, ok := p.store.securityGroups[sgID]
+		if !ok {
+			return "", notFound("security group %s not found", sgID)
+		}
+		if sg.NetworkID != networkID {
+			return "", invalidArgument("security group %s is not in network %s", sgID, networkID)
+		}
+		return sgID, nil
+	}
+	for _, sg := range p.store.securityGroups {
+		if sg.NetworkID == networkID && sg.isDefault {
+			return sg.ID, nil
+		}
+	}
+	return "", errors.Errorf("network %s has no default security group", networkID)
+}
+
+func macAddress(n uint64) string {
+	return fmt.Sprintf("02:00:00:%02x:%02x:%02x", byte(n>>16), byte(n>>8), byte(n))
+}
+
+//CreateWithContext creates a server
+func (mgr *ServerManager) CreateWithContext(ctx context.Context, options api.CreateServerOptions) (*api.Server, api.CreateServerError) {
+	srv, err := mgr.create(ctx, options)
+	if err != nil {
+		return nil, api.NewCreateServerError(err, options)
+	}
+	return srv, nil
+}
+
+//Create creates a server
+func (mgr *ServerManager) Create(options api.CreateServerOptions) (*api.Server, api.CreateServerError) {
+	return mgr.CreateWithContext(context.Background(), options)
+}
+
+func (mgr *ServerManager) delete(id string) error {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return mgr.remove(id)
+}
+
+//remove deletes the server identified by id and its network interfaces, must be called with the lock held
+func (mgr *ServerManager) remove(id string) error {
+	p := mgr.Provider
+	srv, ok := p.store.servers[id]
+	if !ok {
+		return notFound("server %s not found", id)
+	}
+	for nicID, ni := range p.store.nics {
+		if ni.ServerID != id {
+			continue
+		}
+		for _, ip := range p.store.publicIPs {
+			if ip.NetworkInterfaceID == nicID {
+				p.dissociate(ip)
+			}
+		}
+		delete(p.store.nics, nicID)
+	}
+	for attID, att := range p.store.attachments {
+		if att.ServerID == id {
+			delete(p.store.attachments, attID)
+		}
+	}
+	p.LoadBalancerManager.removeServer(id)
+	srv.State = api.ServerDeleted
+	delete(p.store.servers, id)
+	return nil
+}
+
+//DeleteWithContext deletes the server identified by id
+func (mgr *ServerManager) DeleteWithContext(ctx context.Context, id string) api.DeleteServerError {
+	err := mgr.delete(id)
+	if err != nil {
+		return api.NewDeleteServerError(err, id)
+	}
+	return nil
+}
+
+//Delete deletes the server identified by id
+func (mgr *ServerManager) Delete(id string) api.DeleteServerError {
+	return mgr.DeleteWithContext(context.Background(), id)
+}
+
+//ListWithContext list all servers
+func (mgr *ServerManager) ListWithContext(ctx context.Context) ([]api.Server, api.ListServersError) {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	servers := []api.Server{}
+	for _, srv := range p.store.servers {
+		srv.refresh()
+		servers = append(servers, srv.Server)
+	}
+	sort.Slice(servers, func(i, j int) bool {
+		return servers[i].ID < servers[j].ID
+	})
+	return servers, nil
+}
+
+//List list all servers
+func (mgr *ServerManager) List() ([]api.Server, api.ListServersError) {
+	return mgr.ListWithContext(context.Background())
+}
+
+func (mgr *ServerManager) get(id string) (*api.Server, error) {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	srv, ok := p.store.servers[id]
+	if !ok {
+		return nil, notFound("server %s not found", id)
+	}
+	srv.refresh()
+	res := srv.Server
+	return &res, nil
+}
+
+//GetWithContext get server identified by id
+func (mgr *ServerManager) GetWithContext(ctx context.Context, id string) (*api.Server, api.GetServerError) {
+	srv, err := mgr.get(id)
+	if err != nil {
+		return nil, api.NewGetServerError(err, id)
+	}
+	return srv, nil
+}
+
+//Get get server identified by id
+func (mgr *ServerManager) Get(id string) (*api.Server, api.GetServerError) {
+	return mgr.GetWithContext(context.Background(), id)
+}
+
+//changeState triggers the transition of the server identified by id from one of the states in from to target
+func (mgr *ServerManager) changeState(id string, target api.ServerState, from ...api.ServerState) error {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	srv, ok := p.store.servers[id]
+	if !ok {
+		return notFound("server %s not found", id)
+	}
+	srv.refresh()
+	for _, state := range from {
+		if srv.State == state {
+			srv.transition(target, p.Configuration.ProvisioningDelay)
+			return nil
+		}
+	}
+	return errors.Errorf("server %s cannot reach state %s from state %s", id, target, srv.State)
+}
+
+//StartWithContext starts the server identified by id
+func (mgr *ServerManager) StartWithContext(ctx context.Context, id string) api.StartServerError {
+	err := mgr.changeState(id, api.ServerReady, api.ServerShutoff, api.ServerReady)
+	if err != nil {
+		return api.NewStartServerError(err, id)
+	}
+	return nil
+}
+
+//Start starts the server identified by id
+func (mgr *ServerManager) Start(id string) api.StartServerError {
+	return mgr.StartWithContext(context.Background(), id)
+}
+
+//StopWithContext stops the server identified by id
+func (mgr *ServerManager) StopWithContext(ctx context.Context, id string) api.StopServerError {
+	err := mgr.changeState(id, api.ServerShutoff, api.ServerReady, api.ServerShutoff)
+	if err != nil {
+		return api.NewStopServerError(err, id)
+	}
+	return nil
+}
+
+//Stop stops the server identified by id
+func (mgr *ServerManager) Stop(id string) api.StopServerError {
+	return mgr.StopWithContext(context.Background(), id)
+}
+
+func (mgr *ServerManager) resize(id string, templateID string) error {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	srv, ok := p.store.servers[id]
+	if !ok {
+		return notFound("server %s not found", id)
+	}
+	tpl, err := p.TemplateManager.find(templateID)
+	if err != nil {
+		return err
+	}
+	srv.refresh()
+	if srv.State != api.ServerReady && srv.State != api.ServerShutoff {
+		return errors.Errorf("server %s cannot be resized in state %s", id, srv.State)
+	}
+	srv.TemplateID = tpl.ID
+	srv.transition(srv.State, p.Configuration.ProvisioningDelay)
+	return nil
+}
+
+//ResizeWithContext resize a server
+func (mgr *ServerManager) ResizeWithContext(ctx context.Context, id string, templateID string) api.ResizeServerError {
+	err := mgr.resize(id, templateID)
+	if err != nil {
+		return api.NewResizeServerError(err, id, templateID)
+	}
+	return nil
+}
+
+//Resize resize a server
+func (mgr *ServerManager) Resize(id string, templateID string) api.ResizeServerError {
+	return mgr.ResizeWithContext(context.Background(), id, templateID)
+}
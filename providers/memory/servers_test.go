@@ -0,0 +1,106 @@
+package memory_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/SebastienDorgan/anyclouds/providers/memory"
+	"github.com/SebastienDorgan/anyclouds/tests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type MemoryServerManagerTestSuite struct {
+	tests.ServerManagerTestSuite
+}
+
+//SetupSuite set up server manager
+func (suite *MemoryServerManagerTestSuite) SetupSuite() {
+	suite.Prov = GetProvider()
+	suite.SkipSSH = true
+}
+
+func TestMemoryServerManagerTestSuite(t *testing.T) {
+	suite.Run(t, new(MemoryServerManagerTestSuite))
+}
+
+func TestServerStateTransitions(t *testing.T) {
+	var prov memory.Provider
+	err := prov.Init(strings.NewReader(`{"ProvisioningDelay": "500ms"}`), "json")
+	assert.NoError(t, err)
+	//reached waits until the server leaves the api.ServerPending state and checks that it reaches state
+	reached := func(id string, state api.ServerState) {
+		assert.Eventually(t, func() bool {
+			srv, err := prov.GetServerManager().Get(id)
+			return err == nil && srv.State != api.ServerPending
+		}, 10*time.Second, 10*time.Millisecond)
+		srv, err := prov.GetServerManager().Get(id)
+		assert.NoError(t, err)
+		assert.Equal(t, state, srv.State)
+	}
+	nets, err := prov.GetNetworkManager().ListNetworks()
+	assert.NoError(t, err)
+	sn, err := prov.GetNetworkManager().CreateSubnet(api.CreateSubnetOptions{
+		NetworkID: nets[0].ID,
+		Name:      "subnet",
+		CIDR:      "172.31.0.0/24",
+		IPVersion: api.IPVersion4,
+	})
+	assert.NoError(t, err)
+	srv, err := prov.GetServerManager().Create(api.CreateServerOptions{
+		Name:       "server",
+		TemplateID: "tpl-small",
+		ImageID:    "img-ubuntu-1804",
+		Subnets:    []api.Subnet{*sn},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, api.ServerReady, srv.State)
+
+	err = prov.GetServerManager().Stop(srv.ID)
+	assert.NoError(t, err)
+	srv, err = prov.GetServerManager().Get(srv.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, api.ServerPending, srv.State)
+	err = prov.GetServerManager().Resize(srv.ID, "tpl-medium")
+	assert.Error(t, err)
+	reached(srv.ID, api.ServerShutoff)
+
+	err = prov.GetServerManager().Resize(srv.ID, "tpl-medium")
+	assert.NoError(t, err)
+	err = prov.GetServerManager().Start(srv.ID)
+	assert.Error(t, err)
+	reached(srv.ID, api.ServerShutoff)
+	err = prov.GetServerManager().Start(srv.ID)
+	assert.NoError(t, err)
+	reached(srv.ID, api.ServerReady)
+	srv, err = prov.GetServerManager().Get(srv.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, api.ServerReady, srv.State)
+	assert.Equal(t, "tpl-medium", srv.TemplateID)
+
+	v, err := prov.GetVolumeManager().Create(api.CreateVolumeOptions{Name: "volume", Size: 10})
+	assert.NoError(t, err)
+	_, err = prov.GetVolumeManager().Attach(api.AttachVolumeOptions{VolumeID: v.ID, ServerID: srv.ID, DevicePath: "/dev/sdf"})
+	assert.NoError(t, err)
+	ip, err := prov.GetPublicIPAddressManager().Create(api.CreatePublicIPOptions{Name: "ip"})
+	assert.NoError(t, err)
+	err = prov.GetPublicIPAddressManager().Associate(api.AssociatePublicIPOptions{PublicIPId: ip.ID, ServerID: srv.ID})
+	assert.NoError(t, err)
+	assert.Error(t, prov.GetVolumeManager().Delete(v.ID))
+	assert.Error(t, prov.GetPublicIPAddressManager().Delete(ip.ID))
+	assert.Error(t, prov.GetNetworkManager().DeleteSubnet(sn.NetworkID, sn.ID))
+
+	err = prov.GetServerManager().Delete(srv.ID)
+	assert.NoError(t, err)
+	ip, err = prov.GetPublicIPAddressManager().Get(ip.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, ip.NetworkInterfaceID)
+	atts, err := prov.GetVolumeManager().ListAttachments(&api.ListAttachmentsOptions{VolumeID: &v.ID})
+	assert.NoError(t, err)
+	assert.Empty(t, atts)
+	assert.NoError(t, prov.GetVolumeManager().Delete(v.ID))
+	assert.NoError(t, prov.GetPublicIPAddressManager().Delete(ip.ID))
+	assert.NoError(t, prov.GetNetworkManager().DeleteSubnet(sn.NetworkID, sn.ID))
+}
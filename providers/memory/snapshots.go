@@ -0,0 +1,155 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+)
+
+//SnapshotManager memory implementation of api.SnapshotManager
+type SnapshotManager struct {
+	Provider *Provider
+}
+
+func (mgr *SnapshotManager) create(options api.CreateSnapshotOptions) (*api.Snapshot, error) {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	v, ok := p.store.volumes[options.VolumeID]
+	if !ok {
+		return nil, notFound("volume %s not found", options.VolumeID)
+	}
+	s := &api.Snapshot{
+		ID:        p.newID("snap"),
+		Name:      options.Name,
+		VolumeID:  v.ID,
+		Size:      v.Size,
+		State:     api.SnapshotReady,
+		CreatedAt: time.Now(),
+	}
+	p.store.snapshots[s.ID] = s
+	res := *s
+	return &res, nil
+}
+
+//CreateWithContext creates a snapshot of a volume
+func (mgr *SnapshotManager) CreateWithContext(ctx context.Context, options api.CreateSnapshotOptions) (*api.Snapshot, api.CreateSnapshotError) {
+	s, err := mgr.create(options)
+	if err != nil {
+		return nil, api.NewCreateSnapshotError(err, options)
+	}
+	return s, nil
+}
+
+//Create creates a snapshot of a volume
+func (mgr *SnapshotManager) Create(options api.CreateSnapshotOptions) (*api.Snapshot, api.CreateSnapshotError) {
+	return mgr.CreateWithContext(context.Background(), options)
+}
+
+func (mgr *SnapshotManager) delete(id string) error {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if _, ok := p.store.snapshots[id]; !ok {
+		return notFound("snapshot %s not found", id)
+	}
+	delete(p.store.snapshots, id)
+	return nil
+}
+
+//DeleteWithContext deletes snapshot identified by id
+func (mgr *SnapshotManager) DeleteWithContext(ctx context.Context, id string) api.DeleteSnapshotError {
+	err := mgr.delete(id)
+	if err != nil {
+		return api.NewDeleteSnapshotError(err, id)
+	}
+	return nil
+}
+
+//Delete deletes snapshot identified by id
+func (mgr *SnapshotManager) Delete(id string) api.DeleteSnapshotError {
+	return mgr.DeleteWithContext(context.Background(), id)
+}
+
+//ListWithContext lists snapshots
+func (mgr *SnapshotManager) ListWithContext(ctx context.Context) ([]api.Snapshot, api.ListSnapshotsError) {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	snapshots := []api.Snapshot{}
+	for _, s := range p.store.snapshots {
+		snapshots = append(snapshots, *s)
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].ID < snapshots[j].ID
+	})
+	return snapshots, nil
+}
+
+//List lists snapshots
+func (mgr *SnapshotManager) List() ([]api.Snapshot, api.ListSnapshotsError) {
+	return mgr.ListWithContext(context.Background())
+}
+
+func (mgr *SnapshotManager) get(id string) (*api.Snapshot, error) {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	s, ok := p.store.snapshots[id]
+	if !ok {
+		return nil, notFound("snapshot %s not found", id)
+	}
+	res := *s
+	return &res, nil
+}
+
+//GetWithContext get snapshot identified by id
+func (mgr *SnapshotManager) GetWithContext(ctx context.Context, id string) (*api.Snapshot, api.GetSnapshotError) {
+	s, err := mgr.get(id)
+	if err != nil {
+		return nil, api.NewGetSnapshotError(err, id)
+	}
+	return s, nil
+}
+
+//Get get snapshot identified by id
+func (mgr *SnapshotManager) Get(id string) (*api.Snapshot, api.GetSnapshotError) {
+	return mgr.GetWithContext(context.Background(), id)
+}
+
+func (mgr *SnapshotManager) createVolume(options api.CreateVolumeFromSnapshotOptions) (*api.Volume, error) {
+	s, err := mgr.get(options.SnapshotID)
+	if err != nil {
+		return nil, err
+	}
+	size := options.Size
+	if size == 0 {
+		size = s.Size
+	}
+	if size < s.Size {
+		return nil, invalidArgument("volume size %d is smaller than snapshot size %d", size, s.Size)
+	}
+	return mgr.Provider.VolumeManager.create(api.CreateVolumeOptions{
+		Name:        options.Name,
+		Size:        size,
+		MinIOPS:     options.MinIOPS,
+		MinDataRate: options.MinDataRate,
+		Tags:        options.Tags,
+	})
+}
+
+//CreateVolumeFromSnapshotWithContext creates a volume from a snapshot
+func (mgr *SnapshotManager) CreateVolumeFromSnapshotWithContext(ctx context.Context, options api.CreateVolumeFromSnapshotOptions) (*api.Volume, api.CreateVolumeFromSnapshotError) {
+	v, err := mgr.createVolume(options)
+	if err != nil {
+		return nil, api.NewCreateVolumeFromSnapshotError(err, options)
+	}
+	return v, nil
+}
+
+//CreateVolumeFromSnapshot creates a volume from a snapshot
+func (mgr *SnapshotManager) CreateVolumeFromSnapshot(options api.CreateVolumeFromSnapshotOptions) (*api.Volume, api.CreateVolumeFromSnapshotError) {
+	return mgr.CreateVolumeFromSnapshotWithContext(context.Background(), options)
+}
@@ -0,0 +1,22 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/SebastienDorgan/anyclouds/tests"
+	"github.com/stretchr/testify/suite"
+)
+
+type MemorySnapshotManagerTestSuite struct {
+	tests.SnapshotManagerTestSuite
+}
+
+//SetupSuite set up snapshot manager
+func (suite *MemorySnapshotManagerTestSuite) SetupSuite() {
+	p := GetProvider()
+	suite.Prov = p
+}
+
+func TestMemorySnapshotManagerTestSuite(t *testing.T) {
+	suite.Run(t, new(MemorySnapshotManagerTestSuite))
+}
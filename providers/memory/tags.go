@@ -0,0 +1,148 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+)
+
+//TagManager memory implementation of api.TagManager
+type TagManager struct {
+	Provider *Provider
+}
+
+//copyTags returns a copy of tags or nil if tags is empty
+//The tags of the stored resources are never modified in place, so that the copies of the resources returned by the managers are not altered
+func copyTags(tags map[string]string) map[string]string {
+	if len(tags) == 0 {
+		return nil
+	}
+	res := make(map[string]string, len(tags))
+	for k, v := range tags {
+		res[k] = v
+	}
+	return res
+}
+
+//tags returns the tags of resource, must be called with the lock held
+func (p *Provider) tags(resource api.TaggedResource) (*map[string]string, error) {
+	switch resource.Type {
+	case api.ResourceServer:
+		if srv, ok := p.store.servers[resource.ID]; ok {
+			return &srv.Tags, nil
+		}
+	case api.ResourceVolume:
+		if v, ok := p.store.volumes[resource.ID]; ok {
+			return &v.Tags, nil
+		}
+	case api.ResourceNetwork:
+		if n, ok := p.store.networks[resource.ID]; ok {
+			return &n.Tags, nil
+		}
+	case api.ResourceSubnet:
+		if sn, ok := p.store.subnets[resource.ID]; ok {
+			return &sn.Tags, nil
+		}
+	case api.ResourceSecurityGroup:
+		if sg, ok := p.store.securityGroups[resource.ID]; ok {
+			return &sg.Tags, nil
+		}
+	case api.ResourcePublicIP:
+		if ip, ok := p.store.publicIPs[resource.ID]; ok {
+			return &ip.Tags, nil
+		}
+	case api.ResourceNetworkInterface:
+		if ni, ok := p.store.nics[resource.ID]; ok {
+			return &ni.Tags, nil
+		}
+	default:
+		return nil, invalidArgument("resources of type %s cannot be tagged", resource.Type)
+	}
+	return nil, notFound("%s %s not found", resource.Type, resource.ID)
+}
+
+func (mgr *TagManager) add(options api.AddTagsOptions) error {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for k := range options.Tags {
+		if len(k) == 0 {
+			return invalidArgument("tag keys cannot be empty")
+		}
+	}
+	tags, err := p.tags(options.Resource)
+	if err != nil {
+		return err
+	}
+	res := copyTags(*tags)
+	if res == nil {
+		res = make(map[string]string, len(options.Tags))
+	}
+	for k, v := range options.Tags {
+		res[k] = v
+	}
+	*tags = copyTags(res)
+	return nil
+}
+
+//AddWithContext adds tags to a resource
+func (mgr *TagManager) AddWithContext(ctx context.Context, options api.AddTagsOptions) api.AddTagsError {
+	return api.NewAddTagsError(mgr.add(options), options)
+}
+
+//Add adds tags to a resource
+func (mgr *TagManager) Add(options api.AddTagsOptions) api.AddTagsError {
+	return mgr.AddWithContext(context.Background(), options)
+}
+
+func (mgr *TagManager) remove(options api.RemoveTagsOptions) error {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	tags, err := p.tags(options.Resource)
+	if err != nil {
+		return err
+	}
+	res := copyTags(*tags)
+	for _, k := range options.Keys {
+		delete(res, k)
+	}
+	*tags = copyTags(res)
+	return nil
+}
+
+//RemoveWithContext removes tags from a resource
+func (mgr *TagManager) RemoveWithContext(ctx context.Context, options api.RemoveTagsOptions) api.RemoveTagsError {
+	return api.NewRemoveTagsError(mgr.remove(options), options)
+}
+
+//Remove removes tags from a resource
+func (mgr *TagManager) Remove(options api.RemoveTagsOptions) api.RemoveTagsError {
+	return mgr.RemoveWithContext(context.Background(), options)
+}
+
+func (mgr *TagManager) list(resource api.TaggedResource) (map[string]string, error) {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	tags, err := p.tags(resource)
+	if err != nil {
+		return nil, err
+	}
+	res := copyTags(*tags)
+	if res == nil {
+		res = map[string]string{}
+	}
+	return res, nil
+}
+
+//ListWithContext lists the tags of a resource
+func (mgr *TagManager) ListWithContext(ctx context.Context, resource api.TaggedResource) (map[string]string, api.ListTagsError) {
+	tags, err := mgr.list(resource)
+	return tags, api.NewListTagsError(err, resource)
+}
+
+//List lists the tags of a resource
+func (mgr *TagManager) List(resource api.TaggedResource) (map[string]string, api.ListTagsError) {
+	return mgr.ListWithContext(context.Background(), resource)
+}
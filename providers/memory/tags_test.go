@@ -0,0 +1,21 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/SebastienDorgan/anyclouds/tests"
+	"github.com/stretchr/testify/suite"
+)
+
+type MemoryTagManagerTestSuite struct {
+	tests.TagManagerTestSuite
+}
+
+//SetupSuite set up tag manager
+func (suite *MemoryTagManagerTestSuite) SetupSuite() {
+	suite.Prov = GetProvider()
+}
+
+func TestMemoryTagManagerTestSuite(t *testing.T) {
+	suite.Run(t, new(MemoryTagManagerTestSuite))
+}
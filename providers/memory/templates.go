@@ -0,0 +1,72 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+)
+
+//ServerTemplateManager memory implementation of api.ServerTemplateManager
+type ServerTemplateManager struct {
+	Provider *Provider
+}
+
+func defaultTemplates() []api.ServerTemplate {
+	date := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	return []api.ServerTemplate{
+		{ID: "tpl-small", Name: "small", NumberOfCPUCore: 1, RAMSize: 2048, SystemDiskSize: 20, CreatedAt: date,
+			Arch: api.ArchAmd64, CPUFrequency: 2.5, NetworkSpeed: 1000, OneDemandPrice: 0.02},
+		{ID: "tpl-medium", Name: "medium", NumberOfCPUCore: 2, RAMSize: 8192, SystemDiskSize: 20, CreatedAt: date,
+			Arch: api.ArchAmd64, CPUFrequency: 2.5, NetworkSpeed: 1000, OneDemandPrice: 0.08},
+		{ID: "tpl-large", Name: "large", NumberOfCPUCore: 4, RAMSize: 16384, SystemDiskSize: 20, CreatedAt: date,
+			Arch: api.ArchAmd64, CPUFrequency: 2.5, NetworkSpeed: 10000, OneDemandPrice: 0.16},
+		{ID: "tpl-xlarge", Name: "xlarge", NumberOfCPUCore: 8, RAMSize: 32768, SystemDiskSize: 40, EphemeralDiskSize: 100, CreatedAt: date,
+			Arch: api.ArchAmd64, CPUFrequency: 3.0, NetworkSpeed: 10000, OneDemandPrice: 0.34},
+		{ID: "tpl-arm-large", Name: "arm-large", NumberOfCPUCore: 4, RAMSize: 8192, SystemDiskSize: 20, CreatedAt: date,
+			Arch: api.ArchARM64, CPUFrequency: 2.3, NetworkSpeed: 10000, OneDemandPrice: 0.08},
+		{ID: "tpl-gpu-large", Name: "gpu-large", NumberOfCPUCore: 8, RAMSize: 61440, SystemDiskSize: 40, CreatedAt: date,
+			Arch: api.ArchAmd64, CPUFrequency: 2.7, NetworkSpeed: 10000, OneDemandPrice: 0.90,
+			GPUInfo: &api.GPUInfo{Number: 1, NumberOfCore: 5120, MemorySize: 16384, Type: "Tesla V100"}},
+	}
+}
+
+//ListWithContext returns available server templates
+func (mgr *ServerTemplateManager) ListWithContext(ctx context.Context) ([]api.ServerTemplate, api.ListServerTemplatesError) {
+	mgr.Provider.lock.Lock()
+	defer mgr.Provider.lock.Unlock()
+	templates := make([]api.ServerTemplate, len(mgr.Provider.store.templates))
+	copy(templates, mgr.Provider.store.templates)
+	return templates, nil
+}
+
+//List returns available server templates
+func (mgr *ServerTemplateManager) List() ([]api.ServerTemplate, api.ListServerTemplatesError) {
+	return mgr.ListWithContext(context.Background())
+}
+
+func (mgr *ServerTemplateManager) find(id string) (*api.ServerTemplate, error) {
+	for _, tpl := range mgr.Provider.store.templates {
+		if tpl.ID == id {
+			template := tpl
+			return &template, nil
+		}
+	}
+	return nil, notFound("server template %s not found", id)
+}
+
+//GetWithContext returns the server template identified by id
+func (mgr *ServerTemplateManager) GetWithContext(ctx context.Context, id string) (*api.ServerTemplate, api.GetServerTemplateError) {
+	mgr.Provider.lock.Lock()
+	defer mgr.Provider.lock.Unlock()
+	tpl, err := mgr.find(id)
+	if err != nil {
+		return nil, api.NewGetServerTemplateError(err, id)
+	}
+	return tpl, nil
+}
+
+//Get returns the server template identified by id
+func (mgr *ServerTemplateManager) Get(id string) (*api.ServerTemplate, api.GetServerTemplateError) {
+	return mgr.GetWithContext(context.Background(), id)
+}
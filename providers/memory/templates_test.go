@@ -0,0 +1,22 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/SebastienDorgan/anyclouds/tests"
+	"github.com/stretchr/testify/suite"
+)
+
+type MemoryTemplateManagerTestSuite struct {
+	tests.TemplateManagerTestSuite
+}
+
+//SetupSuite set up template manager
+func (suite *MemoryTemplateManagerTestSuite) SetupSuite() {
+	p := GetProvider()
+	suite.Mgr = p.GetTemplateManager()
+}
+
+func TestMemoryTemplateManagerTestSuite(t *testing.T) {
+	suite.Run(t, new(MemoryTemplateManagerTestSuite))
+}
@@ -0,0 +1,278 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/pkg/errors"
+)
+
+const (
+	//maxVolumeSize maximum size of a volume in GB
+	maxVolumeSize = 16384
+	//maxVolumeIOPS maximum number of IOPS of a volume
+	maxVolumeIOPS = 64000
+	//maxVolumeDataRate maximum data rate of a volume in MB/s
+	maxVolumeDataRate = 1000
+)
+
+//VolumeManager memory implementation of api.VolumeManager
+type VolumeManager struct {
+	Provider *Provider
+}
+
+func checkVolumeOptions(size, iops, dataRate int64) error {
+	if size <= 0 || size > maxVolumeSize {
+		return invalidArgument("invalid volume size %d", size)
+	}
+	if iops > maxVolumeIOPS {
+		return invalidArgument("invalid volume IOPS %d", iops)
+	}
+	if dataRate > maxVolumeDataRate {
+		return invalidArgument("invalid volume data rate %d", dataRate)
+	}
+	return nil
+}
+
+func (mgr *VolumeManager) create(options api.CreateVolumeOptions) (*api.Volume, error) {
+	err := checkVolumeOptions(options.Size, options.MinIOPS, options.MinDataRate)
+	if err != nil {
+		return nil, err
+	}
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	v := &api.Volume{
+		ID:       p.newID("vol"),
+		Name:     options.Name,
+		Size:     options.Size,
+		IOPS:     options.MinIOPS,
+		DataRate: options.MinDataRate,
+		Tags:     copyTags(options.Tags),
+	}
+	p.store.volumes[v.ID] = v
+	res := *v
+	return &res, nil
+}
+
+//CreateWithContext creates a volume with options
+func (mgr *VolumeManager) CreateWithContext(ctx context.Context, options api.CreateVolumeOptions) (*api.Volume, api.CreateVolumeError) {
+	v, err := mgr.create(options)
+	if err != nil {
+		return nil, api.NewCreateVolumeError(err, options)
+	}
+	return v, nil
+}
+
+//Create creates a volume with options
+func (mgr *VolumeManager) Create(options api.CreateVolumeOptions) (*api.Volume, api.CreateVolumeError) {
+	return mgr.CreateWithContext(context.Background(), options)
+}
+
+func (mgr *VolumeManager) delete(id string) error {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if _, ok := p.store.volumes[id]; !ok {
+		return notFound("volume %s not found", id)
+	}
+	for _, att := range p.store.attachments {
+		if att.VolumeID == id {
+			return errors.Errorf("volume %s is attached to server %s", id, att.ServerID)
+		}
+	}
+	delete(p.store.volumes, id)
+	return nil
+}
+
+//DeleteWithContext deletes volume identified by id
+func (mgr *VolumeManager) DeleteWithContext(ctx context.Context, id string) api.DeleteVolumeError {
+	err := mgr.delete(id)
+	if err != nil {
+		return api.NewDeleteVolumeError(err, id)
+	}
+	return nil
+}
+
+//Delete deletes volume identified by id
+func (mgr *VolumeManager) Delete(id string) api.DeleteVolumeError {
+	return mgr.DeleteWithContext(context.Background(), id)
+}
+
+//ListWithContext lists volumes
+func (mgr *VolumeManager) ListWithContext(ctx context.Context) ([]api.Volume, api.ListVolumesError) {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	volumes := []api.Volume{}
+	for _, v := range p.store.volumes {
+		volumes = append(volumes, *v)
+	}
+	sort.Slice(volumes, func(i, j int) bool {
+		return volumes[i].ID < volumes[j].ID
+	})
+	return volumes, nil
+}
+
+//List lists volumes
+func (mgr *VolumeManager) List() ([]api.Volume, api.ListVolumesError) {
+	return mgr.ListWithContext(context.Background())
+}
+
+func (mgr *VolumeManager) get(id string) (*api.Volume, error) {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	v, ok := p.store.volumes[id]
+	if !ok {
+		return nil, notFound("volume %s not found", id)
+	}
+	res := *v
+	return &res, nil
+}
+
+//GetWithContext get volume identified by id
+func (mgr *VolumeManager) GetWithContext(ctx context.Context, id string) (*api.Volume, api.GetVolumeError) {
+	v, err := mgr.get(id)
+	if err != nil {
+		return nil, api.NewGetVolumeError(err, id)
+	}
+	return v, nil
+}
+
+//Get get volume identified by id
+func (mgr *VolumeManager) Get(id string) (*api.Volume, api.GetVolumeError) {
+	return mgr.GetWithContext(context.Background(), id)
+}
+
+func (mgr *VolumeManager) resize(options api.ResizeVolumeOptions) (*api.Volume, error) {
+	err := checkVolumeOptions(options.Size, options.MinIOPS, options.MinDataRate)
+	if err != nil {
+		return nil, err
+	}
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	v, ok := p.store.volumes[options.ID]
+	if !ok {
+		return nil, notFound("volume %s not found", options.ID)
+	}
+	if options.Size < v.Size {
+		return nil, invalidArgument("volume %s cannot be shrunk from %d to %d", v.ID, v.Size, options.Size)
+	}
+	v.Size = options.Size
+	v.IOPS = options.MinIOPS
+	v.DataRate = options.MinDataRate
+	res := *v
+	return &res, nil
+}
+
+//ResizeWithContext changes the size and the performances of a volume
+func (mgr *VolumeManager) ResizeWithContext(ctx context.Context, options api.ResizeVolumeOptions) (*api.Volume, api.ResizeVolumeError) {
+	v, err := mgr.resize(options)
+	if err != nil {
+		return nil, api.NewResizeVolumeError(err, options)
+	}
+	return v, nil
+}
+
+//Resize changes the size and the performances of a volume
+func (mgr *VolumeManager) Resize(options api.ResizeVolumeOptions) (*api.Volume, api.ResizeVolumeError) {
+	return mgr.ResizeWithContext(context.Background(), options)
+}
+
+func (mgr *VolumeManager) attach(options api.AttachVolumeOptions) (*api.VolumeAttachment, error) {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if _, ok := p.store.volumes[options.VolumeID]; !ok {
+		return nil, notFound("volume %s not found", options.VolumeID)
+	}
+	if _, ok := p.store.servers[options.ServerID]; !ok {
+		return nil, notFound("server %s not found", options.ServerID)
+	}
+	for _, att := range p.store.attachments {
+		if att.VolumeID == options.VolumeID {
+			return nil, alreadyExists("volume %s is already attached to server %s", att.VolumeID, att.ServerID)
+		}
+		if att.ServerID == options.ServerID && att.Device == options.DevicePath {
+			return nil, alreadyExists("device %s of server %s is already used", att.Device, att.ServerID)
+		}
+	}
+	att := &api.VolumeAttachment{
+		ID:       p.newID("att"),
+		VolumeID: options.VolumeID,
+		ServerID: options.ServerID,
+		Device:   options.DevicePath,
+	}
+	p.store.attachments[att.ID] = att
+	res := *att
+	return &res, nil
+}
+
+//AttachWithContext attaches a volume to a server
+func (mgr *VolumeManager) AttachWithContext(ctx context.Context, options api.AttachVolumeOptions) (*api.VolumeAttachment, api.AttachVolumeError) {
+	att, err := mgr.attach(options)
+	if err != nil {
+		return nil, api.NewAttachVolumeError(err, options)
+	}
+	return att, nil
+}
+
+//Attach attaches a volume to a server
+func (mgr *VolumeManager) Attach(options api.AttachVolumeOptions) (*api.VolumeAttachment, api.AttachVolumeError) {
+	return mgr.AttachWithContext(context.Background(), options)
+}
+
+func (mgr *VolumeManager) detach(options api.DetachVolumeOptions) error {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for id, att := range p.store.attachments {
+		if att.VolumeID == options.VolumeID && att.ServerID == options.ServerID {
+			delete(p.store.attachments, id)
+			return nil
+		}
+	}
+	return errors.Errorf("volume %s is not attached to server %s", options.VolumeID, options.ServerID)
+}
+
+//DetachWithContext detaches a volume from a server
+func (mgr *VolumeManager) DetachWithContext(ctx context.Context, options api.DetachVolumeOptions) api.DetachVolumeError {
+	err := mgr.detach(options)
+	if err != nil {
+		return api.NewDetachVolumeError(err, options)
+	}
+	return nil
+}
+
+//Detach detaches a volume from a server
+func (mgr *VolumeManager) Detach(options api.DetachVolumeOptions) api.DetachVolumeError {
+	return mgr.DetachWithContext(context.Background(), options)
+}
+
+//ListAttachmentsWithContext lists volume attachments matching options
+func (mgr *VolumeManager) ListAttachmentsWithContext(ctx context.Context, options *api.ListAttachmentsOptions) ([]api.VolumeAttachment, api.ListVolumeAttachmentsError) {
+	p := mgr.Provider
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if options == nil {
+		options = &api.ListAttachmentsOptions{}
+	}
+	attachments := []api.VolumeAttachment{}
+	for _, att := range p.store.attachments {
+		if match(options.VolumeID, att.VolumeID) && match(options.ServerID, att.ServerID) {
+			attachments = append(attachments, *att)
+		}
+	}
+	sort.Slice(attachments, func(i, j int) bool {
+		return attachments[i].ID < attachments[j].ID
+	})
+	return attachments, nil
+}
+
+//ListAttachments lists volume attachments matching options
+func (mgr *VolumeManager) ListAttachments(options *api.ListAttachmentsOptions) ([]api.VolumeAttachment, api.ListVolumeAttachmentsError) {
+	return mgr.ListAttachmentsWithContext(context.Background(), options)
+}
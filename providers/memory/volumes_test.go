@@ -0,0 +1,22 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/SebastienDorgan/anyclouds/tests"
+	"github.com/stretchr/testify/suite"
+)
+
+type MemoryVolumeManagerTestSuite struct {
+	tests.VolumeManagerTestSuite
+}
+
+//SetupSuite set up volume manager
+func (suite *MemoryVolumeManagerTestSuite) SetupSuite() {
+	p := GetProvider()
+	suite.Prov = p
+}
+
+func TestMemoryVolumeManagerTestSuite(t *testing.T) {
+	suite.Run(t, new(MemoryVolumeManagerTestSuite))
+}
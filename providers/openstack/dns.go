@@ -0,0 +1,394 @@
+package openstack
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/SebastienDorgan/anyclouds/providers"
+	gc "github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack/dns/v2/recordsets"
+	"github.com/gophercloud/gophercloud/openstack/dns/v2/zones"
+	"github.com/pkg/errors"
+)
+
+//DNSManager openstack implementation of api.DNSManager using the Designate service
+type DNSManager struct {
+	Provider *Provider
+}
+
+func (mgr *DNSManager) client(ctx context.Context) (*gc.ServiceClient, error) {
+	if mgr.Provider.BaseServices.DNS == nil {
+		return nil, errors.New("the DNS service is not available")
+	}
+	return mgr.Provider.BaseServices.dns(ctx), nil
+}
+
+//nameServers returns the name servers the zone identified by id is delegated to
+func nameServers(client *gc.ServiceClient, id string) ([]string, error) {
+	var body struct {
+		NameServers []struct {
+			Hostname string `json:"hostname"`
+			Priority int    `json:"priority"`
+		} `json:"nameservers"`
+	}
+	_, err := client.Get(client.ServiceURL("zones", id, "nameservers"), &body, nil)
+	if err != nil {
+		return nil, err
+	}
+	var res []string
+	for _, ns := range body.NameServers {
+		res = append(res, api.ZoneName(ns.Hostname))
+	}
+	return res, nil
+}
+
+func (mgr *DNSManager) zone(client *gc.ServiceClient, z *zones.Zone) (*api.Zone, error) {
+	ns, err := nameServers(client, z.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &api.Zone{
+		ID:          z.ID,
+		Name:        api.ZoneName(z.Name),
+		NameServers: ns,
+	}, nil
+}
+
+//record converts a record set, it returns nil if the type of the record set is not managed
+func record(z *zones.Zone, rs *recordsets.RecordSet) *api.Record {
+	if !api.IsRecordType(rs.Type) {
+		return nil
+	}
+	r := &api.Record{
+		ZoneID: z.ID,
+		Name:   api.RecordName(rs.Name, z.Name),
+		Type:   api.RecordType(rs.Type),
+		TTL:    rs.TTL,
+	}
+	for _, v := range rs.Records {
+		switch r.Type {
+		case api.RecordTXT:
+			v = api.UnquoteTXT(v)
+		case api.RecordCNAME:
+			v = api.ZoneName(v)
+		}
+		r.Values = append(r.Values, v)
+	}
+	return r
+}
+
+//waitActive waits until the pending changes of the zone identified by id are applied or ctx is done
+func waitActive(ctx context.Context, client *gc.ServiceClient, id string) (*zones.Zone, error) {
+	var z *zones.Zone
+	err := providers.Poll(ctx, 10*time.Minute, func(ctx context.Context) (bool, error) {
+		var err error
+		z, err = zones.Get(client, id).Extract()
+		if err != nil {
+			return false, err
+		}
+		return z.Status != "PENDING", nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if z.Status != "ACTIVE" {
+		return nil, fmt.Errorf("zone %s is not active", id)
+	}
+	return z, nil
+}
+
+func (mgr *DNSManager) createZone(ctx context.Context, options api.CreateZoneOptions) (*api.Zone, error) {
+	err := api.CheckZoneOptions(&options)
+	if err != nil {
+		return nil, err
+	}
+	client, err := mgr.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	name := api.ZoneName(options.Name)
+	z, err := zones.Create(client, zones.CreateOpts{
+		Name:  name + ".",
+		Email: "hostmaster@" + name,
+		Type:  "PRIMARY",
+	}).Extract()
+	if err != nil {
+		return nil, err
+	}
+	z, err = waitActive(ctx, client, z.ID)
+	if err != nil {
+		return nil, err
+	}
+	return mgr.zone(client, z)
+}
+
+//CreateZoneWithContext creates a DNS zone
+func (mgr *DNSManager) CreateZoneWithContext(ctx context.Context, options api.CreateZoneOptions) (*api.Zone, api.CreateZoneError) {
+	z, err := mgr.createZone(ctx, options)
+	if err != nil {
+		return nil, api.NewCreateZoneError(UnwrapOpenStackError(err), options)
+	}
+	return z, nil
+}
+
+//CreateZone creates a DNS zone
+func (mgr *DNSManager) CreateZone(options api.CreateZoneOptions) (*api.Zone, api.CreateZoneError) {
+	return mgr.CreateZoneWithContext(context.Background(), options)
+}
+
+func (mgr *DNSManager) deleteZone(ctx context.Context, id string) error {
+	client, err := mgr.client(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = zones.Delete(client, id).Extract()
+	if err != nil {
+		return UnwrapOpenStackError(err)
+	}
+	err = providers.Poll(ctx, 10*time.Minute, func(ctx context.Context) (bool, error) {
+		_, err := zones.Get(client, id).Extract()
+		if err == nil {
+			return false, nil
+		}
+		err = UnwrapOpenStackError(err)
+		if api.ErrorKind(err) == api.ErrNotFound {
+			return true, nil
+		}
+		return false, err
+	})
+	if err != nil {
+		return api.NewErrorStack(err, "zone is not deleted", id)
+	}
+	return nil
+}
+
+//DeleteZoneWithContext deletes the DNS zone identified by id and its records
+func (mgr *DNSManager) DeleteZoneWithContext(ctx context.Context, id string) api.DeleteZoneError {
+	return api.NewDeleteZoneError(mgr.deleteZone(ctx, id), id)
+}
+
+//DeleteZone deletes the DNS zone identified by id and its records
+func (mgr *DNSManager) DeleteZone(id string) api.DeleteZoneError {
+	return mgr.DeleteZoneWithContext(context.Background(), id)
+}
+
+func (mgr *DNSManager) listZones(ctx context.Context) ([]api.Zone, error) {
+	client, err := mgr.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	page, err := zones.List(client, zones.ListOpts{}).AllPages()
+	if err != nil {
+		return nil, err
+	}
+	l, err := zones.ExtractZones(page)
+	if err != nil {
+		return nil, err
+	}
+	res := []api.Zone{}
+	for _, z := range l {
+		if z.Type != "PRIMARY" || z.Action == "DELETE" {
+			continue
+		}
+		zone, err := mgr.zone(client, &z)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, *zone)
+	}
+	return res, nil
+}
+
+//ListZonesWithContext lists the primary DNS zones of the project
+func (mgr *DNSManager) ListZonesWithContext(ctx context.Context) ([]api.Zone, api.ListZonesError) {
+	zones, err := mgr.listZones(ctx)
+	if err != nil {
+		return nil, api.NewListZonesError(UnwrapOpenStackError(err))
+	}
+	return zones, nil
+}
+
+//ListZones lists the primary DNS zones of the project
+func (mgr *DNSManager) ListZones() ([]api.Zone, api.ListZonesError) {
+	return mgr.ListZonesWithContext(context.Background())
+}
+
+func (mgr *DNSManager) getZone(ctx context.Context, id string) (*api.Zone, error) {
+	client, err := mgr.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	z, err := zones.Get(client, id).Extract()
+	if err != nil {
+		return nil, err
+	}
+	return mgr.zone(client, z)
+}
+
+//GetZoneWithContext returns the DNS zone identified by id
+func (mgr *DNSManager) GetZoneWithContext(ctx context.Context, id string) (*api.Zone, api.GetZoneError) {
+	z, err := mgr.getZone(ctx, id)
+	if err != nil {
+		return nil, api.NewGetZoneError(UnwrapOpenStackError(err), id)
+	}
+	return z, nil
+}
+
+//GetZone returns the DNS zone identified by id
+func (mgr *DNSManager) GetZone(id string) (*api.Zone, api.GetZoneError) {
+	return mgr.GetZoneWithContext(context.Background(), id)
+}
+
+//findRecordSet returns the record set of the zone z named name of type t, it returns nil if there is no such record set
+func findRecordSet(client *gc.ServiceClient, z *zones.Zone, name string, t api.RecordType) (*recordsets.RecordSet, error) {
+	fqdn := api.RecordFQDN(name, z.Name)
+	page, err := recordsets.ListByZone(client, z.ID, recordsets.ListOpts{
+		Name: fqdn,
+		Type: string(t),
+	}).AllPages()
+	if err != nil {
+		return nil, err
+	}
+	l, err := recordsets.ExtractRecordSets(page)
+	if err != nil {
+		return nil, err
+	}
+	for _, rs := range l {
+		if api.ZoneName(rs.Name) == api.ZoneName(fqdn) && rs.Type == string(t) {
+			return &rs, nil
+		}
+	}
+	return nil, nil
+}
+
+func (mgr *DNSManager) upsertRecord(ctx context.Context, options api.UpsertRecordOptions) (*api.Record, error) {
+	resolved, err := api.ResolveRecordOptions(ctx, &mgr.Provider.PublicIPAddressManager, options)
+	if err != nil {
+		return nil, err
+	}
+	client, err := mgr.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	z, err := zones.Get(client, options.ZoneID).Extract()
+	if err != nil {
+		return nil, err
+	}
+	var values []string
+	for _, v := range resolved.Values {
+		switch resolved.Type {
+		case api.RecordTXT:
+			v = api.QuoteTXT(v)
+		case api.RecordCNAME:
+			v = api.ZoneName(v) + "."
+		}
+		values = append(values, v)
+	}
+	rs, err := findRecordSet(client, z, resolved.Name, resolved.Type)
+	if err != nil {
+		return nil, err
+	}
+	if rs != nil {
+		rs, err = recordsets.Update(client, z.ID, rs.ID, recordsets.UpdateOpts{
+			TTL:     &resolved.TTL,
+			Records: values,
+		}).Extract()
+	} else {
+		rs, err = recordsets.Create(client, z.ID, recordsets.CreateOpts{
+			Name:    api.RecordFQDN(resolved.Name, z.Name),
+			Type:    string(resolved.Type),
+			TTL:     resolved.TTL,
+			Records: values,
+		}).Extract()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return record(z, rs), nil
+}
+
+//UpsertRecordWithContext creates or replaces a record set
+func (mgr *DNSManager) UpsertRecordWithContext(ctx context.Context, options api.UpsertRecordOptions) (*api.Record, api.UpsertRecordError) {
+	r, err := mgr.upsertRecord(ctx, options)
+	if err != nil {
+		return nil, api.NewUpsertRecordError(UnwrapOpenStackError(err), options)
+	}
+	return r, nil
+}
+
+//UpsertRecord creates or replaces a record set
+func (mgr *DNSManager) UpsertRecord(options api.UpsertRecordOptions) (*api.Record, api.UpsertRecordError) {
+	return mgr.UpsertRecordWithContext(context.Background(), options)
+}
+
+func (mgr *DNSManager) deleteRecord(ctx context.Context, options api.DeleteRecordOptions) error {
+	client, err := mgr.client(ctx)
+	if err != nil {
+		return err
+	}
+	z, err := zones.Get(client, options.ZoneID).Extract()
+	if err != nil {
+		return err
+	}
+	rs, err := findRecordSet(client, z, options.Name, options.Type)
+	if err != nil {
+		return err
+	}
+	if rs == nil {
+		return notFoundError("record %s of type %s not found in zone %s", options.Name, options.Type, options.ZoneID)
+	}
+	return recordsets.Delete(client, z.ID, rs.ID).ExtractErr()
+}
+
+//DeleteRecordWithContext deletes a record set
+func (mgr *DNSManager) DeleteRecordWithContext(ctx context.Context, options api.DeleteRecordOptions) api.DeleteRecordError {
+	return api.NewDeleteRecordError(UnwrapOpenStackError(mgr.deleteRecord(ctx, options)), options)
+}
+
+//DeleteRecord deletes a record set
+func (mgr *DNSManager) DeleteRecord(options api.DeleteRecordOptions) api.DeleteRecordError {
+	return mgr.DeleteRecordWithContext(context.Background(), options)
+}
+
+func (mgr *DNSManager) listRecords(ctx context.Context, zoneID string) ([]api.Record, error) {
+	client, err := mgr.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	z, err := zones.Get(client, zoneID).Extract()
+	if err != nil {
+		return nil, err
+	}
+	page, err := recordsets.ListByZone(client, zoneID, recordsets.ListOpts{}).AllPages()
+	if err != nil {
+		return nil, err
+	}
+	l, err := recordsets.ExtractRecordSets(page)
+	if err != nil {
+		return nil, err
+	}
+	records := []api.Record{}
+	for _, rs := range l {
+		if r := record(z, &rs); r != nil {
+			records = append(records, *r)
+		}
+	}
+	api.SortRecords(records)
+	return records, nil
+}
+
+//ListRecordsWithContext lists the A, AAAA, CNAME and TXT record sets of the DNS zone identified by zoneID
+func (mgr *DNSManager) ListRecordsWithContext(ctx context.Context, zoneID string) ([]api.Record, api.ListRecordsError) {
+	records, err := mgr.listRecords(ctx, zoneID)
+	if err != nil {
+		return nil, api.NewListRecordsError(UnwrapOpenStackError(err), zoneID)
+	}
+	return records, nil
+}
+
+//ListRecords lists the A, AAAA, CNAME and TXT record sets of the DNS zone identified by zoneID
+func (mgr *DNSManager) ListRecords(zoneID string) ([]api.Record, api.ListRecordsError) {
+	return mgr.ListRecordsWithContext(context.Background(), zoneID)
+}
@@ -0,0 +1,21 @@
+package openstack_test
+
+import (
+	"testing"
+
+	"github.com/SebastienDorgan/anyclouds/tests"
+	"github.com/stretchr/testify/suite"
+)
+
+type OSDNSManagerTestSuite struct {
+	tests.DNSManagerTestSuite
+}
+
+//SetupSuite set up DNS manager
+func (suite *OSDNSManagerTestSuite) SetupSuite() {
+	suite.Prov = GetProvider()
+}
+
+func TestOSDNSManagerTestSuite(t *testing.T) {
+	suite.Run(t, new(OSDNSManagerTestSuite))
+}
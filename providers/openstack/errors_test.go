@@ -0,0 +1,41 @@
+package openstack_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/SebastienDorgan/anyclouds/providers/openstack"
+	gc "github.com/gophercloud/gophercloud"
+	pkgerrors "github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func responseCode(code int, body string) gc.ErrUnexpectedResponseCode {
+	return gc.ErrUnexpectedResponseCode{Actual: code, Body: []byte(body)}
+}
+
+func TestUnwrapOpenStackError(t *testing.T) {
+	cases := []struct {
+		err  error
+		kind error
+	}{
+		{gc.ErrDefault404{ErrUnexpectedResponseCode: responseCode(404, "Not Found")}, api.ErrNotFound},
+		{&gc.ErrDefault400{ErrUnexpectedResponseCode: responseCode(400, "Bad Request")}, api.ErrInvalidArgument},
+		{gc.ErrDefault401{ErrUnexpectedResponseCode: responseCode(401, "Unauthorized")}, api.ErrUnauthorized},
+		{gc.ErrDefault403{ErrUnexpectedResponseCode: responseCode(403, "Quota exceeded for instances")}, api.ErrQuotaExceeded},
+		{gc.ErrDefault409{ErrUnexpectedResponseCode: responseCode(409, "Security group already exists")}, api.ErrAlreadyExists},
+		{gc.ErrDefault409{ErrUnexpectedResponseCode: responseCode(409, "Port is in use")}, nil},
+		{gc.ErrDefault429{ErrUnexpectedResponseCode: responseCode(429, "Rate limit")}, api.ErrThrottled},
+		{responseCode(413, "Quota exceeded for ram"), api.ErrQuotaExceeded},
+		{pkgerrors.Wrap(gc.ErrDefault404{ErrUnexpectedResponseCode: responseCode(404, "Not Found")}, "error reading server"), api.ErrNotFound},
+	}
+	for _, c := range cases {
+		err := openstack.UnwrapOpenStackError(c.err)
+		assert.Equal(t, c.kind, api.ErrorKind(err), c.err.Error())
+		if c.kind != nil {
+			assert.True(t, errors.Is(err, c.kind))
+		}
+	}
+	assert.Nil(t, openstack.UnwrapOpenStackError(nil))
+}
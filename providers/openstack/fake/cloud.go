@@ -0,0 +1,161 @@
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/SebastienDorgan/anyclouds/iputils"
+	"github.com/google/uuid"
+)
+
+//cloud state of the fake cloud, shared by the services
+//Resources are kept in creation order
+type cloud struct {
+	url            string
+	tokens         map[string]bool
+	flavors        []*flavor
+	images         []*image
+	keypairs       []*keypair
+	servers        []*server
+	networks       []*network
+	subnets        []*subnet
+	ports          []*port
+	routers        []*router
+	floatingIPs    []*floatingIP
+	securityGroups []*securityGroup
+	volumes        []*volume
+	snapshots      []*snapshot
+	loadBalancers  []*loadBalancer
+	lbListeners    []*lbListener
+	lbPools        []*lbPool
+	healthMonitors []*healthMonitor
+	zones          []*zone
+	//swiftContainers Swift containers by name
+	swiftContainers map[string]*swiftContainer
+	//tempURLKey temporary URL key of the Swift account
+	tempURLKey string
+}
+
+func newCloud(url string) *cloud {
+	c := &cloud{
+		url:             url,
+		tokens:          map[string]bool{},
+		flavors:         newFlavors(),
+		images:          newImages(),
+		swiftContainers: map[string]*swiftContainer{},
+	}
+	c.createExternalNetwork()
+	c.createDefaultSecurityGroup()
+	return c
+}
+
+func newID() string {
+	return uuid.New().String()
+}
+
+//timestamp returns the current time in the format used by Nova, Keystone and Neutron
+func timestamp() string {
+	return time.Now().UTC().Format("2006-01-02T15:04:05Z")
+}
+
+//cinderTimestamp returns the current time in the format used by Cinder
+func cinderTimestamp() string {
+	return time.Now().UTC().Format("2006-01-02T15:04:05.000000")
+}
+
+type link struct {
+	Href string `json:"href"`
+	Rel  string `json:"rel"`
+}
+
+//links returns the self and bookmark links of the resource available at path
+func (c *cloud) links(service, path string) []link {
+	return []link{
+		{Href: fmt.Sprintf("%s/%s/%s", c.url, service, path), Rel: "self"},
+		{Href: fmt.Sprintf("%s/%s/%s", c.url, service, path), Rel: "bookmark"},
+	}
+}
+
+//nullString string encoded as JSON null when empty
+type nullString string
+
+func (s nullString) MarshalJSON() ([]byte, error) {
+	if s == "" {
+		return []byte("null"), nil
+	}
+	return json.Marshal(string(s))
+}
+
+//paginationParameters query parameters that are not filters
+var paginationParameters = map[string]bool{
+	"fields":       true,
+	"limit":        true,
+	"marker":       true,
+	"sort_key":     true,
+	"sort_dir":     true,
+	"page_reverse": true,
+	"all_tenants":  true,
+}
+
+//matchQuery returns true if the scalar fields of the JSON representation of v match the query parameters
+//Parameters that do not name a scalar field are ignored
+func matchQuery(v interface{}, query url.Values) bool {
+	b, _ := json.Marshal(v)
+	fields := map[string]interface{}{}
+	_ = json.Unmarshal(b, &fields)
+	for name, values := range query {
+		if paginationParameters[name] {
+			continue
+		}
+		f, ok := fields[name]
+		if !ok {
+			continue
+		}
+		var s string
+		switch t := f.(type) {
+		case nil:
+			s = ""
+		case string:
+			s = t
+		case bool, float64:
+			s = fmt.Sprint(t)
+		default:
+			continue
+		}
+		found := false
+		for _, value := range values {
+			if value == s {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+//ipRange returns the first and last addresses of the IPv4 network n
+func ipRange(n *net.IPNet) (uint32, uint32) {
+	first := iputils.Itou(&n.IP)
+	ones, bits := n.Mask.Size()
+	return first, first + uint32(1<<uint(bits-ones)) - 1
+}
+
+//parseCIDR parses an IPv4 or IPv6 CIDR and returns its canonical form
+func parseCIDR(cidr string) (*net.IPNet, error) {
+	_, n, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, badRequest("Invalid input for cidr. Reason: '%s' is not a valid IP subnet.", cidr)
+	}
+	return n, nil
+}
+
+//overlaps returns true if the networks a and b overlap
+func overlaps(a, b *net.IPNet) bool {
+	return a.Contains(b.IP) || b.Contains(a.IP)
+}
@@ -0,0 +1,938 @@
+package fake
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func computeService(c *cloud) *service {
+	return &service{
+		prefix:        "/compute/v2.1",
+		authenticated: true,
+		routes: []route{
+			{"GET", "flavors", http.StatusOK, listFlavors},
+			{"GET", "flavors/detail", http.StatusOK, listFlavorsDetail},
+			{"GET", "flavors/*", http.StatusOK, getFlavor},
+			{"GET", "flavors/*/os-extra_specs", http.StatusOK, listFlavorExtraSpecs},
+			{"GET", "images", http.StatusOK, listImages},
+			{"GET", "images/detail", http.StatusOK, listImagesDetail},
+			{"GET", "images/*", http.StatusOK, getImage},
+			{"DELETE", "images/*", http.StatusNoContent, deleteImage},
+			{"GET", "limits", http.StatusOK, getLimits},
+			{"GET", "os-availability-zone", http.StatusOK, listAvailabilityZones},
+			{"GET", "os-keypairs", http.StatusOK, listKeyPairs},
+			{"POST", "os-keypairs", http.StatusOK, createKeyPair},
+			{"GET", "os-keypairs/*", http.StatusOK, getKeyPair},
+			{"DELETE", "os-keypairs/*", http.StatusAccepted, deleteKeyPair},
+			{"GET", "servers", http.StatusOK, listServers},
+			{"POST", "servers", http.StatusAccepted, createServer},
+			{"GET", "servers/detail", http.StatusOK, listServersDetail},
+			{"GET", "servers/*", http.StatusOK, getServer},
+			{"DELETE", "servers/*", http.StatusNoContent, deleteServer},
+			{"POST", "servers/*/action", http.StatusAccepted, serverAction},
+			{"GET", "servers/*/metadata", http.StatusOK, getServerMetadata},
+			{"PUT", "servers/*/metadata", http.StatusOK, resetServerMetadata},
+			{"GET", "servers/*/os-volume_attachments", http.StatusOK, listVolumeAttachments},
+			{"POST", "servers/*/os-volume_attachments", http.StatusOK, attachVolume},
+			{"GET", "servers/*/os-volume_attachments/*", http.StatusOK, getVolumeAttachment},
+			{"DELETE", "servers/*/os-volume_attachments/*", http.StatusAccepted, detachVolume},
+		},
+		errorBody: computeError,
+	}
+}
+
+type flavor struct {
+	ID         string
+	Name       string
+	VCPUs      int
+	RAM        int
+	Disk       int
+	Ephemeral  int
+	ExtraSpecs map[string]string
+}
+
+func newFlavors() []*flavor {
+	specs := func(arch string) map[string]string {
+		return map[string]string{"capabilities:cpu_arch": arch}
+	}
+	return []*flavor{
+		{ID: "1", Name: "m1.tiny", VCPUs: 1, RAM: 512, Disk: 1, ExtraSpecs: specs("x86_64")},
+		{ID: "2", Name: "m1.small", VCPUs: 1, RAM: 2048, Disk: 20, ExtraSpecs: specs("x86_64")},
+		{ID: "3", Name: "m1.medium", VCPUs: 2, RAM: 4096, Disk: 40, ExtraSpecs: specs("x86_64")},
+		{ID: "4", Name: "m1.large", VCPUs: 4, RAM: 8192, Disk: 80, ExtraSpecs: specs("x86_64")},
+		{ID: "5", Name: "m1.xlarge", VCPUs: 8, RAM: 16384, Disk: 160, ExtraSpecs: specs("x86_64")},
+		{ID: "6", Name: "r1.xlarge", VCPUs: 4, RAM: 16384, Disk: 80, ExtraSpecs: specs("x86_64")},
+		{ID: "7", Name: "a1.xlarge", VCPUs: 4, RAM: 16384, Disk: 80, ExtraSpecs: specs("aarch64")},
+	}
+}
+
+func (c *cloud) flavor(id string) (*flavor, error) {
+	for _, f := range c.flavors {
+		if f.ID == id {
+			return f, nil
+		}
+	}
+	return nil, notFound("Flavor %s could not be found.", id)
+}
+
+func (c *cloud) flavorView(f *flavor, detail bool) map[string]interface{} {
+	v := map[string]interface{}{
+		"id":    f.ID,
+		"name":  f.Name,
+		"links": c.links("compute/v2.1", "flavors/"+f.ID),
+	}
+	if detail {
+		v["vcpus"] = f.VCPUs
+		v["ram"] = f.RAM
+		v["disk"] = f.Disk
+		v["OS-FLV-EXT-DATA:ephemeral"] = f.Ephemeral
+		v["swap"] = ""
+		v["rxtx_factor"] = 1.0
+		v["os-flavor-access:is_public"] = true
+		v["OS-FLV-DISABLED:disabled"] = false
+	}
+	return v
+}
+
+func (c *cloud) listFlavors(detail bool) interface{} {
+	l := []interface{}{}
+	for _, f := range c.flavors {
+		l = append(l, c.flavorView(f, detail))
+	}
+	return map[string]interface{}{"flavors": l}
+}
+
+func listFlavors(c *cloud, r *request) (interface{}, error) {
+	return c.listFlavors(false), nil
+}
+
+func listFlavorsDetail(c *cloud, r *request) (interface{}, error) {
+	return c.listFlavors(true), nil
+}
+
+func getFlavor(c *cloud, r *request) (interface{}, error) {
+	f, err := c.flavor(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"flavor": c.flavorView(f, true)}, nil
+}
+
+func listFlavorExtraSpecs(c *cloud, r *request) (interface{}, error) {
+	f, err := c.flavor(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"extra_specs": f.ExtraSpecs}, nil
+}
+
+type image struct {
+	ID      string
+	Name    string
+	MinDisk int
+	MinRAM  int
+	Created string
+	//Metadata Glance properties of the image
+	Metadata map[string]string
+}
+
+//osMetadata returns the Glance properties describing the operating system of an image
+func osMetadata(distro, version, arch string) map[string]string {
+	return map[string]string{"os_distro": distro, "os_version": version, "architecture": arch, "os_type": "linux"}
+}
+
+func newImages() []*image {
+	created := timestamp()
+	return []*image{
+		{ID: "6c3a2d4e-8f5b-4a1c-9d7e-2b3c4d5e6f70", Name: "Ubuntu 18.04 LTS", MinDisk: 10, MinRAM: 512, Created: created, Metadata: osMetadata("ubuntu", "18.04", "x86_64")},
+		{ID: "7d4b3e5f-9a6c-4b2d-8e8f-3c4d5e6f7081", Name: "Ubuntu 16.04 LTS", MinDisk: 10, MinRAM: 512, Created: created, Metadata: osMetadata("ubuntu", "16.04", "x86_64")},
+		{ID: "8e5c4f6a-ab7d-4c3e-9f9a-4d5e6f708192", Name: "CentOS 7", MinDisk: 10, MinRAM: 512, Created: created, Metadata: osMetadata("centos", "7", "x86_64")},
+		{ID: "9f6d5a7b-bc8e-4d4f-8a0b-5e6f708192a3", Name: "cirros-0.4.0-x86_64-disk", MinDisk: 0, MinRAM: 0, Created: created, Metadata: map[string]string{}},
+	}
+}
+
+func (c *cloud) image(id string) (*image, error) {
+	for _, img := range c.images {
+		if img.ID == id {
+			return img, nil
+		}
+	}
+	return nil, notFound("Image %s could not be found.", id)
+}
+
+func (c *cloud) imageView(img *image, detail bool) map[string]interface{} {
+	v := map[string]interface{}{
+		"id":    img.ID,
+		"name":  img.Name,
+		"links": c.links("compute/v2.1", "images/"+img.ID),
+	}
+	if detail {
+		v["minDisk"] = img.MinDisk
+		v["minRam"] = img.MinRAM
+		v["status"] = "ACTIVE"
+		v["progress"] = 100
+		v["created"] = img.Created
+		v["updated"] = img.Created
+		v["metadata"] = img.Metadata
+	}
+	return v
+}
+
+func (c *cloud) listImages(detail bool) interface{} {
+	l := []interface{}{}
+	for _, img := range c.images {
+		l = append(l, c.imageView(img, detail))
+	}
+	return map[string]interface{}{"images": l}
+}
+
+func listImages(c *cloud, r *request) (interface{}, error) {
+	return c.listImages(false), nil
+}
+
+func listImagesDetail(c *cloud, r *request) (interface{}, error) {
+	return c.listImages(true), nil
+}
+
+func getImage(c *cloud, r *request) (interface{}, error) {
+	img, err := c.image(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"image": c.imageView(img, true)}, nil
+}
+
+//deleteImage deletes an image, images used by servers can be deleted as their disks are already provisioned
+func deleteImage(c *cloud, r *request) (interface{}, error) {
+	img, err := c.image(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	for i, o := range c.images {
+		if o == img {
+			c.images = append(c.images[:i], c.images[i+1:]...)
+			break
+		}
+	}
+	return nil, nil
+}
+
+//createServerImage captures the disk of s as a new image, the image is active as soon as it is created
+func (c *cloud) createServerImage(s *server, name string) (*image, error) {
+	if name == "" {
+		return nil, badRequest("Invalid input for field/attribute createImage. Value: None. None is not of type 'string'")
+	}
+	img := &image{
+		ID:       newID(),
+		Name:     name,
+		Created:  timestamp(),
+		Metadata: map[string]string{"image_type": "snapshot", "owner_id": ProjectID},
+	}
+	if f, err := c.flavor(s.FlavorID); err == nil {
+		img.MinDisk = f.Disk
+	}
+	if src, err := c.image(s.ImageID); err == nil {
+		for k, v := range src.Metadata {
+			img.Metadata[k] = v
+		}
+		img.Metadata["base_image_ref"] = src.ID
+		img.MinRAM = src.MinRAM
+		if src.MinDisk > img.MinDisk {
+			img.MinDisk = src.MinDisk
+		}
+	}
+	c.images = append(c.images, img)
+	return img, nil
+}
+
+type keypair struct {
+	Name        string `json:"name"`
+	PublicKey   string `json:"public_key"`
+	PrivateKey  string `json:"private_key,omitempty"`
+	Fingerprint string `json:"fingerprint"`
+	UserID      string `json:"user_id"`
+}
+
+func (c *cloud) keypair(name string) (*keypair, error) {
+	for _, kp := range c.keypairs {
+		if kp.Name == name {
+			return kp, nil
+		}
+	}
+	return nil, notFound("Keypair %s not found for user %s", name, UserID)
+}
+
+func listAvailabilityZones(c *cloud, r *request) (interface{}, error) {
+	return map[string]interface{}{
+		"availabilityZoneInfo": []interface{}{
+			map[string]interface{}{
+				"zoneName":  AvailabilityZone,
+				"zoneState": map[string]bool{"available": true},
+				"hosts":     nil,
+			},
+		},
+	}, nil
+}
+
+func listKeyPairs(c *cloud, r *request) (interface{}, error) {
+	l := []interface{}{}
+	for _, kp := range c.keypairs {
+		l = append(l, map[string]interface{}{"keypair": kp})
+	}
+	return map[string]interface{}{"keypairs": l}, nil
+}
+
+//generateKeyPair generates a RSA key pair, the private key is PEM encoded and the public key is in authorized_keys format
+func generateKeyPair() (string, string, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return "", "", err
+	}
+	pub, err := ssh.NewPublicKey(&key.PublicKey)
+	if err != nil {
+		return "", "", err
+	}
+	priv := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	return string(priv), string(ssh.MarshalAuthorizedKey(pub)), nil
+}
+
+func createKeyPair(c *cloud, r *request) (interface{}, error) {
+	in := struct {
+		KeyPair struct {
+			Name      string `json:"name"`
+			PublicKey string `json:"public_key"`
+		} `json:"keypair"`
+	}{}
+	err := r.decode(&in)
+	if err != nil {
+		return nil, err
+	}
+	if in.KeyPair.Name == "" {
+		return nil, badRequest("Invalid input for field/attribute name.")
+	}
+	if _, err := c.keypair(in.KeyPair.Name); err == nil {
+		return nil, conflict("Key pair '%s' already exists.", in.KeyPair.Name)
+	}
+	kp := &keypair{
+		Name:      in.KeyPair.Name,
+		PublicKey: in.KeyPair.PublicKey,
+		UserID:    UserID,
+	}
+	if kp.PublicKey == "" {
+		kp.PrivateKey, kp.PublicKey, err = generateKeyPair()
+		if err != nil {
+			return nil, err
+		}
+	}
+	pub, _, _, _, err := ssh.ParseAuthorizedKey([]byte(kp.PublicKey))
+	if err != nil {
+		return nil, badRequest("Keypair data is invalid: failed to generate fingerprint")
+	}
+	kp.Fingerprint = ssh.FingerprintLegacyMD5(pub)
+	c.keypairs = append(c.keypairs, kp)
+	//the private key is only returned at creation
+	res := *kp
+	kp.PrivateKey = ""
+	return map[string]interface{}{"keypair": &res}, nil
+}
+
+func getKeyPair(c *cloud, r *request) (interface{}, error) {
+	kp, err := c.keypair(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"keypair": kp}, nil
+}
+
+func deleteKeyPair(c *cloud, r *request) (interface{}, error) {
+	kp, err := c.keypair(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	for i, o := range c.keypairs {
+		if o == kp {
+			c.keypairs = append(c.keypairs[:i], c.keypairs[i+1:]...)
+			break
+		}
+	}
+	return nil, nil
+}
+
+type server struct {
+	ID             string
+	Name           string
+	Status         string
+	FlavorID       string
+	ImageID        string
+	KeyName        string
+	SecurityGroups []string
+	Metadata       map[string]string
+	//createdPorts identifiers of the ports created with the server
+	createdPorts []string
+	//oldFlavorID flavor used before a resize not confirmed yet
+	oldFlavorID string
+	Created     string
+	Updated     string
+}
+
+func (c *cloud) server(id string) (*server, error) {
+	for _, s := range c.servers {
+		if s.ID == id {
+			return s, nil
+		}
+	}
+	return nil, notFound("Instance %s could not be found.", id)
+}
+
+//addresses returns the addresses of the server grouped by network name
+func (c *cloud) addresses(s *server) map[string][]interface{} {
+	addresses := map[string][]interface{}{}
+	for _, p := range c.devicePorts(s.ID) {
+		n, err := c.network(p.NetworkID)
+		if err != nil {
+			continue
+		}
+		for _, ip := range p.FixedIPs {
+			version := 4
+			if strings.Contains(ip.IPAddress, ":") {
+				version = 6
+			}
+			addresses[n.Name] = append(addresses[n.Name], map[string]interface{}{
+				"addr":                    ip.IPAddress,
+				"version":                 version,
+				"OS-EXT-IPS:type":         "fixed",
+				"OS-EXT-IPS-MAC:mac_addr": p.MACAddress,
+			})
+			for _, fip := range c.floatingIPs {
+				if string(fip.PortID) == p.ID && string(fip.FixedIPAddress) == ip.IPAddress {
+					addresses[n.Name] = append(addresses[n.Name], map[string]interface{}{
+						"addr":                    fip.FloatingIPAddress,
+						"version":                 4,
+						"OS-EXT-IPS:type":         "floating",
+						"OS-EXT-IPS-MAC:mac_addr": p.MACAddress,
+					})
+				}
+			}
+		}
+	}
+	return addresses
+}
+
+func (c *cloud) serverView(s *server, detail bool) map[string]interface{} {
+	v := map[string]interface{}{
+		"id":    s.ID,
+		"name":  s.Name,
+		"links": c.links("compute/v2.1", "servers/"+s.ID),
+	}
+	if !detail {
+		return v
+	}
+	sgs := []map[string]string{}
+	for _, id := range s.SecurityGroups {
+		if sg, err := c.securityGroup(id); err == nil {
+			sgs = append(sgs, map[string]string{"name": sg.Name})
+		}
+	}
+	v["status"] = s.Status
+	v["tenant_id"] = ProjectID
+	v["user_id"] = UserID
+	v["hostId"] = ""
+	v["progress"] = 0
+	v["accessIPv4"] = ""
+	v["accessIPv6"] = ""
+	v["flavor"] = map[string]interface{}{
+		"id":    s.FlavorID,
+		"links": c.links("compute/v2.1", "flavors/"+s.FlavorID),
+	}
+	v["image"] = map[string]interface{}{
+		"id":    s.ImageID,
+		"links": c.links("compute/v2.1", "images/"+s.ImageID),
+	}
+	v["addresses"] = c.addresses(s)
+	v["metadata"] = s.Metadata
+	v["key_name"] = nullString(s.KeyName)
+	v["security_groups"] = sgs
+	v["created"] = s.Created
+	v["updated"] = s.Updated
+	return v
+}
+
+func (c *cloud) listServers(r *request, detail bool) (interface{}, error) {
+	query := r.URL.Query()
+	var name *regexp.Regexp
+	if n := query.Get("name"); n != "" {
+		re, err := regexp.Compile(n)
+		if err != nil {
+			return nil, badRequest("Invalid name filter %s", n)
+		}
+		name = re
+	}
+	status := query.Get("status")
+	l := []interface{}{}
+	for _, s := range c.servers {
+		if name != nil && !name.MatchString(s.Name) {
+			continue
+		}
+		if status != "" && status != s.Status {
+			continue
+		}
+		l = append(l, c.serverView(s, detail))
+	}
+	return map[string]interface{}{"servers": l}, nil
+}
+
+func listServers(c *cloud, r *request) (interface{}, error) {
+	return c.listServers(r, false)
+}
+
+func listServersDetail(c *cloud, r *request) (interface{}, error) {
+	return c.listServers(r, true)
+}
+
+func getServer(c *cloud, r *request) (interface{}, error) {
+	s, err := c.server(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"server": c.serverView(s, true)}, nil
+}
+
+func getServerMetadata(c *cloud, r *request) (interface{}, error) {
+	s, err := c.server(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"metadata": s.Metadata}, nil
+}
+
+//resetServerMetadata replaces all the metadata of the server
+func resetServerMetadata(c *cloud, r *request) (interface{}, error) {
+	s, err := c.server(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	in := struct {
+		Metadata map[string]string `json:"metadata"`
+	}{}
+	err = r.decode(&in)
+	if err != nil {
+		return nil, err
+	}
+	if in.Metadata == nil {
+		return nil, badRequest("Invalid input for field/attribute metadata.")
+	}
+	s.Metadata = in.Metadata
+	s.Updated = timestamp()
+	return map[string]interface{}{"metadata": s.Metadata}, nil
+}
+
+type serverNetwork struct {
+	UUID    string `json:"uuid"`
+	Port    string `json:"port"`
+	FixedIP string `json:"fixed_ip"`
+}
+
+//serverSecurityGroups returns the identifiers of the security groups referenced by name or by id
+func (c *cloud) serverSecurityGroups(refs []map[string]string) ([]string, error) {
+	if len(refs) == 0 {
+		return []string{c.defaultSecurityGroup().ID}, nil
+	}
+	var ids []string
+	for _, ref := range refs {
+		name := ref["name"]
+		found := false
+		for _, sg := range c.securityGroups {
+			if sg.ID == name || sg.Name == name {
+				ids = append(ids, sg.ID)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, badRequest("Unable to find security_group with name or id '%s'", name)
+		}
+	}
+	return ids, nil
+}
+
+//projectNetworks returns the networks a server may be connected to when no network is requested
+func (c *cloud) projectNetworks() []*network {
+	var l []*network
+	for _, n := range c.networks {
+		if n.TenantID == ProjectID || n.Shared {
+			l = append(l, n)
+		}
+	}
+	return l
+}
+
+//serverPorts returns the ports of a new server, creating them if necessary
+//Created ports are returned along with their identifiers so that they can be released if the creation fails
+func (c *cloud) serverPorts(networks []serverNetwork, sgs []string) ([]*port, []string, error) {
+	if len(networks) == 0 {
+		l := c.projectNetworks()
+		if len(l) > 1 {
+			return nil, nil, conflict("Multiple possible networks found, use a Network ID to be more specific.")
+		}
+		for _, n := range l {
+			networks = append(networks, serverNetwork{UUID: n.ID})
+		}
+	}
+	var ports []*port
+	var created []string
+	release := func() {
+		for _, id := range created {
+			if p, err := c.port(id); err == nil {
+				c.removePort(p)
+			}
+		}
+	}
+	for _, sn := range networks {
+		if sn.Port != "" {
+			p, err := c.port(sn.Port)
+			if err != nil {
+				release()
+				return nil, nil, badRequest("Port %s could not be found.", sn.Port)
+			}
+			if p.DeviceID != "" {
+				release()
+				return nil, nil, conflict("Port %s is still in use.", p.ID)
+			}
+			ports = append(ports, p)
+			continue
+		}
+		n, err := c.network(sn.UUID)
+		if err != nil {
+			release()
+			return nil, nil, badRequest("Network %s could not be found.", sn.UUID)
+		}
+		if len(n.Subnets) == 0 {
+			release()
+			return nil, nil, badRequest("Network %s requires a subnet in order to boot instances on.", n.ID)
+		}
+		in := &portRequest{NetworkID: n.ID, SecurityGroups: &sgs}
+		if sn.FixedIP != "" {
+			in.FixedIPs = &[]fixedIP{{IPAddress: sn.FixedIP}}
+		}
+		p, err := c.newPort(in)
+		if err != nil {
+			release()
+			return nil, nil, err
+		}
+		created = append(created, p.ID)
+		ports = append(ports, p)
+	}
+	return ports, created, nil
+}
+
+func createServer(c *cloud, r *request) (interface{}, error) {
+	in := struct {
+		Server struct {
+			Name           string              `json:"name"`
+			FlavorRef      string              `json:"flavorRef"`
+			ImageRef       string              `json:"imageRef"`
+			KeyName        string              `json:"key_name"`
+			SecurityGroups []map[string]string `json:"security_groups"`
+			Networks       json.RawMessage     `json:"networks"`
+			Metadata       map[string]string   `json:"metadata"`
+		} `json:"server"`
+	}{}
+	err := r.decode(&in)
+	if err != nil {
+		return nil, err
+	}
+	req := &in.Server
+	if req.Name == "" {
+		return nil, badRequest("Invalid input for field/attribute name.")
+	}
+	if _, err := c.flavor(req.FlavorRef); err != nil {
+		return nil, badRequest("Flavor %s could not be found.", req.FlavorRef)
+	}
+	if _, err := c.image(req.ImageRef); err != nil {
+		return nil, badRequest("Image %s could not be found.", req.ImageRef)
+	}
+	if req.KeyName != "" {
+		if _, err := c.keypair(req.KeyName); err != nil {
+			return nil, badRequest("Invalid key_name provided.")
+		}
+	}
+	sgs, err := c.serverSecurityGroups(req.SecurityGroups)
+	if err != nil {
+		return nil, err
+	}
+	var networks []serverNetwork
+	//networks may be "auto", "none" or a list of networks
+	if len(req.Networks) > 0 && req.Networks[0] == '[' {
+		err = json.Unmarshal(req.Networks, &networks)
+		if err != nil {
+			return nil, badRequest("Invalid input for field/attribute networks.")
+		}
+	}
+	s := &server{
+		ID:             newID(),
+		Name:           req.Name,
+		Status:         "ACTIVE",
+		FlavorID:       req.FlavorRef,
+		ImageID:        req.ImageRef,
+		KeyName:        req.KeyName,
+		SecurityGroups: sgs,
+		Metadata:       req.Metadata,
+		Created:        timestamp(),
+		Updated:        timestamp(),
+	}
+	if s.Metadata == nil {
+		s.Metadata = map[string]string{}
+	}
+	var ports []*port
+	if string(req.Networks) != `"none"` {
+		ports, s.createdPorts, err = c.serverPorts(networks, sgs)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, p := range ports {
+		p.DeviceID = s.ID
+		p.DeviceOwner = ownerCompute
+		p.updateStatus()
+	}
+	c.servers = append(c.servers, s)
+	return map[string]interface{}{"server": c.serverView(s, true)}, nil
+}
+
+func deleteServer(c *cloud, r *request) (interface{}, error) {
+	s, err := c.server(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range c.devicePorts(s.ID) {
+		created := false
+		for _, id := range s.createdPorts {
+			created = created || id == p.ID
+		}
+		if created {
+			c.removePort(p)
+			continue
+		}
+		p.DeviceID = ""
+		p.DeviceOwner = ""
+		p.updateStatus()
+	}
+	for _, v := range c.volumes {
+		if v.attachment != nil && v.attachment.ServerID == s.ID {
+			v.detach()
+		}
+	}
+	for i, o := range c.servers {
+		if o == s {
+			c.servers = append(c.servers[:i], c.servers[i+1:]...)
+			break
+		}
+	}
+	return nil, nil
+}
+
+//serverAction executes the action named by the single key of the request body
+func serverAction(c *cloud, r *request) (interface{}, error) {
+	s, err := c.server(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	actions := map[string]json.RawMessage{}
+	err = r.decode(&actions)
+	if err != nil {
+		return nil, err
+	}
+	if len(actions) != 1 {
+		return nil, badRequest("There is not such action: %d actions requested", len(actions))
+	}
+	stateConflict := func(action string) error {
+		return conflict("Cannot '%s' instance %s while it is in vm_state %s", action, s.ID, strings.ToLower(s.Status))
+	}
+	for action, body := range actions {
+		switch action {
+		case "os-start":
+			if s.Status != "SHUTOFF" {
+				return nil, stateConflict("start")
+			}
+			s.Status = "ACTIVE"
+		case "os-stop":
+			if s.Status != "ACTIVE" {
+				return nil, stateConflict("stop")
+			}
+			s.Status = "SHUTOFF"
+		case "resize":
+			if s.Status != "ACTIVE" && s.Status != "SHUTOFF" {
+				return nil, stateConflict("resize")
+			}
+			in := struct {
+				FlavorRef string `json:"flavorRef"`
+			}{}
+			err = json.Unmarshal(body, &in)
+			if err != nil {
+				return nil, badRequest("Invalid input for field/attribute resize.")
+			}
+			f, err := c.flavor(in.FlavorRef)
+			if err != nil {
+				return nil, badRequest("Invalid flavorRef provided.")
+			}
+			if f.ID == s.FlavorID {
+				return nil, badRequest("When resizing, instances must change flavor!")
+			}
+			current, err := c.flavor(s.FlavorID)
+			if err == nil && f.Disk < current.Disk {
+				return nil, badRequest("Resize error: Unable to resize disk down.")
+			}
+			s.oldFlavorID = s.FlavorID
+			s.FlavorID = f.ID
+			s.Status = "VERIFY_RESIZE"
+		case "confirmResize", "revertResize":
+			if s.Status != "VERIFY_RESIZE" {
+				return nil, stateConflict(action)
+			}
+			if action == "revertResize" {
+				s.FlavorID = s.oldFlavorID
+			}
+			s.oldFlavorID = ""
+			s.Status = "ACTIVE"
+		case "createImage":
+			if s.Status != "ACTIVE" && s.Status != "SHUTOFF" {
+				return nil, stateConflict("createImage")
+			}
+			in := struct {
+				Name string `json:"name"`
+			}{}
+			err = json.Unmarshal(body, &in)
+			if err != nil {
+				return nil, badRequest("Invalid input for field/attribute createImage.")
+			}
+			img, err := c.createServerImage(s, in.Name)
+			if err != nil {
+				return nil, err
+			}
+			return &headerResponse{
+				header: map[string]string{"Location": c.url + "/compute/v2.1/images/" + img.ID},
+				body:   map[string]string{"image_id": img.ID},
+			}, nil
+		default:
+			return nil, badRequest("There is not such action: %s", action)
+		}
+	}
+	s.Updated = timestamp()
+	return nil, nil
+}
+
+func volumeAttachmentView(a *attachment) map[string]interface{} {
+	return map[string]interface{}{
+		"id":       a.VolumeID,
+		"volumeId": a.VolumeID,
+		"serverId": a.ServerID,
+		"device":   a.Device,
+	}
+}
+
+func listVolumeAttachments(c *cloud, r *request) (interface{}, error) {
+	s, err := c.server(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	l := []interface{}{}
+	for _, v := range c.volumes {
+		if v.attachment != nil && v.attachment.ServerID == s.ID {
+			l = append(l, volumeAttachmentView(v.attachment))
+		}
+	}
+	return map[string]interface{}{"volumeAttachments": l}, nil
+}
+
+//device returns the requested device if it is free or the first free device of the server
+func (c *cloud) device(s *server, requested string) (string, error) {
+	used := map[string]bool{"/dev/vda": true}
+	for _, v := range c.volumes {
+		if v.attachment != nil && v.attachment.ServerID == s.ID {
+			used[v.attachment.Device] = true
+		}
+	}
+	if requested != "" {
+		if used[requested] {
+			return "", badRequest("The supplied device path (%s) is in use.", requested)
+		}
+		return requested, nil
+	}
+	for l := 'b'; l <= 'z'; l++ {
+		d := fmt.Sprintf("/dev/vd%c", l)
+		if !used[d] {
+			return d, nil
+		}
+	}
+	return "", badRequest("No free disk device names for prefix 'vd'")
+}
+
+func attachVolume(c *cloud, r *request) (interface{}, error) {
+	s, err := c.server(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	in := struct {
+		VolumeAttachment struct {
+			VolumeID string `json:"volumeId"`
+			Device   string `json:"device"`
+		} `json:"volumeAttachment"`
+	}{}
+	err = r.decode(&in)
+	if err != nil {
+		return nil, err
+	}
+	v, err := c.volume(in.VolumeAttachment.VolumeID)
+	if err != nil {
+		return nil, err
+	}
+	if v.Status != "available" {
+		return nil, badRequest("Invalid volume: volume %s status must be available, but current status is: %s.", v.ID, v.Status)
+	}
+	device, err := c.device(s, in.VolumeAttachment.Device)
+	if err != nil {
+		return nil, err
+	}
+	v.attach(s.ID, device)
+	return map[string]interface{}{"volumeAttachment": volumeAttachmentView(v.attachment)}, nil
+}
+
+//serverAttachment returns the volume attached to the server identified by serverID with the attachment identified by id
+func (c *cloud) serverAttachment(serverID, id string) (*volume, error) {
+	s, err := c.server(serverID)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range c.volumes {
+		if v.attachment != nil && v.attachment.ServerID == s.ID && v.ID == id {
+			return v, nil
+		}
+	}
+	return nil, notFound("Volume %s is not attached to %s", id, s.ID)
+}
+
+func getVolumeAttachment(c *cloud, r *request) (interface{}, error) {
+	v, err := c.serverAttachment(r.params[0], r.params[1])
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"volumeAttachment": volumeAttachmentView(v.attachment)}, nil
+}
+
+func detachVolume(c *cloud, r *request) (interface{}, error) {
+	v, err := c.serverAttachment(r.params[0], r.params[1])
+	if err != nil {
+		return nil, err
+	}
+	v.detach()
+	return nil, nil
+}
@@ -0,0 +1,393 @@
+package fake
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+func dnsService(c *cloud) *service {
+	return &service{
+		prefix:        "/dns/v2",
+		authenticated: true,
+		routes: []route{
+			{"GET", "zones", http.StatusOK, listZones},
+			{"POST", "zones", http.StatusAccepted, createZone},
+			{"GET", "zones/*", http.StatusOK, getZone},
+			{"DELETE", "zones/*", http.StatusAccepted, deleteZone},
+			{"GET", "zones/*/nameservers", http.StatusOK, listNameServers},
+			{"GET", "zones/*/recordsets", http.StatusOK, listRecordSets},
+			{"POST", "zones/*/recordsets", http.StatusCreated, createRecordSet},
+			{"GET", "zones/*/recordsets/*", http.StatusOK, getRecordSet},
+			{"PUT", "zones/*/recordsets/*", http.StatusAccepted, updateRecordSet},
+			{"DELETE", "zones/*/recordsets/*", http.StatusAccepted, deleteRecordSet},
+		},
+		errorBody: designateError,
+	}
+}
+
+//designateError formats errors the way Designate does
+func designateError(e *apiError) interface{} {
+	return map[string]interface{}{
+		"code":       e.status,
+		"type":       e.kind,
+		"message":    e.message,
+		"request_id": "req-" + newID(),
+	}
+}
+
+//designateTimestamp returns the current time in the format used by Designate
+func designateTimestamp() string {
+	return time.Now().UTC().Format("2006-01-02T15:04:05.000000")
+}
+
+//dnsNameServers name servers of the zones
+var dnsNameServers = []string{"ns1.designate.fake.", "ns2.designate.fake."}
+
+//zone Designate zone, zones and record sets are ACTIVE as soon as they are created
+type zone struct {
+	ID          string            `json:"id"`
+	PoolID      string            `json:"pool_id"`
+	ProjectID   string            `json:"project_id"`
+	Name        string            `json:"name"`
+	Email       string            `json:"email"`
+	Description string            `json:"description"`
+	TTL         int               `json:"ttl"`
+	Serial      int               `json:"serial"`
+	Status      string            `json:"status"`
+	Action      string            `json:"action"`
+	Version     int               `json:"version"`
+	Attributes  map[string]string `json:"attributes"`
+	Type        string            `json:"type"`
+	Masters     []string          `json:"masters"`
+	CreatedAt   string            `json:"created_at"`
+	UpdatedAt   *string           `json:"updated_at"`
+	Links       map[string]string `json:"links"`
+
+	recordSets []*recordSet
+}
+
+type recordSet struct {
+	ID          string            `json:"id"`
+	ZoneID      string            `json:"zone_id"`
+	ProjectID   string            `json:"project_id"`
+	Name        string            `json:"name"`
+	ZoneName    string            `json:"zone_name"`
+	Type        string            `json:"type"`
+	Records     []string          `json:"records"`
+	TTL         *int              `json:"ttl"`
+	Status      string            `json:"status"`
+	Action      string            `json:"action"`
+	Description *string           `json:"description"`
+	Version     int               `json:"version"`
+	CreatedAt   string            `json:"created_at"`
+	UpdatedAt   *string           `json:"updated_at"`
+	Links       map[string]string `json:"links"`
+}
+
+func (c *cloud) zone(id string) (*zone, error) {
+	for _, z := range c.zones {
+		if z.ID == id {
+			return z, nil
+		}
+	}
+	return nil, errorf(http.StatusNotFound, "zone_not_found", "Could not find Zone")
+}
+
+func (c *cloud) zoneURL(id string) string {
+	return fmt.Sprintf("%s/dns/v2/zones/%s", c.url, id)
+}
+
+//dnsName returns the normalized fully qualified form of a domain name
+func dnsName(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, ".")) + "."
+}
+
+func validDNSName(name string) bool {
+	if !strings.HasSuffix(name, ".") || strings.ContainsAny(name, " @") || strings.Contains(name, "..") {
+		return false
+	}
+	return len(strings.Split(name, ".")) > 2
+}
+
+func listZones(c *cloud, r *request) (interface{}, error) {
+	name := r.URL.Query().Get("name")
+	l := []*zone{}
+	for _, z := range c.zones {
+		if name == "" || z.Name == dnsName(name) {
+			l = append(l, z)
+		}
+	}
+	return map[string]interface{}{
+		"zones":    l,
+		"links":    map[string]string{"self": c.url + "/dns/v2/zones"},
+		"metadata": map[string]int{"total_count": len(l)},
+	}, nil
+}
+
+func createZone(c *cloud, r *request) (interface{}, error) {
+	in := &zone{}
+	err := r.decode(in)
+	if err != nil {
+		return nil, err
+	}
+	if !validDNSName(in.Name) {
+		return nil, errorf(http.StatusBadRequest, "invalid_object", "Provided object does not match schema 'zone': '%s' is not a 'domainname'", in.Name)
+	}
+	if !strings.Contains(in.Email, "@") {
+		return nil, errorf(http.StatusBadRequest, "invalid_object", "Provided object does not match schema 'zone': '%s' is not a 'email'", in.Email)
+	}
+	name := dnsName(in.Name)
+	for _, z := range c.zones {
+		if z.Name == name {
+			return nil, errorf(http.StatusConflict, "duplicate_zone", "Duplicate Zone")
+		}
+	}
+	if in.TTL == 0 {
+		in.TTL = 3600
+	}
+	z := &zone{
+		ID:          newID(),
+		PoolID:      "794ccc2c-d751-44fe-b57f-8894c9f5c842",
+		ProjectID:   ProjectID,
+		Name:        name,
+		Email:       in.Email,
+		Description: in.Description,
+		TTL:         in.TTL,
+		Serial:      int(time.Now().Unix()),
+		Status:      "ACTIVE",
+		Action:      "NONE",
+		Version:     1,
+		Attributes:  map[string]string{},
+		Type:        "PRIMARY",
+		Masters:     []string{},
+		CreatedAt:   designateTimestamp(),
+	}
+	z.Links = map[string]string{"self": c.zoneURL(z.ID)}
+	ns := append([]string{}, dnsNameServers...)
+	z.recordSets = []*recordSet{
+		z.newRecordSet(name, "SOA", []string{fmt.Sprintf("%s %s %d 3559 600 86400 3600", dnsNameServers[0], strings.Replace(in.Email, "@", ".", 1)+".", z.Serial)}, nil),
+		z.newRecordSet(name, "NS", ns, nil),
+	}
+	c.zones = append(c.zones, z)
+	return z, nil
+}
+
+func getZone(c *cloud, r *request) (interface{}, error) {
+	return c.zone(r.params[0])
+}
+
+func deleteZone(c *cloud, r *request) (interface{}, error) {
+	z, err := c.zone(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	for i, o := range c.zones {
+		if o == z {
+			c.zones = append(c.zones[:i], c.zones[i+1:]...)
+			break
+		}
+	}
+	z.Status, z.Action = "PENDING", "DELETE"
+	return z, nil
+}
+
+func listNameServers(c *cloud, r *request) (interface{}, error) {
+	_, err := c.zone(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	var l []map[string]interface{}
+	for i, s := range dnsNameServers {
+		l = append(l, map[string]interface{}{"hostname": s, "priority": i + 1})
+	}
+	return map[string]interface{}{"nameservers": l}, nil
+}
+
+func (z *zone) newRecordSet(name, t string, records []string, ttl *int) *recordSet {
+	return &recordSet{
+		ID:        newID(),
+		ZoneID:    z.ID,
+		ProjectID: ProjectID,
+		Name:      name,
+		ZoneName:  z.Name,
+		Type:      t,
+		Records:   records,
+		TTL:       ttl,
+		Status:    "ACTIVE",
+		Action:    "NONE",
+		Version:   1,
+		CreatedAt: designateTimestamp(),
+	}
+}
+
+func (z *zone) recordSet(id string) (*recordSet, error) {
+	for _, rs := range z.recordSets {
+		if rs.ID == id {
+			return rs, nil
+		}
+	}
+	return nil, errorf(http.StatusNotFound, "recordset_not_found", "Could not find RecordSet")
+}
+
+func listRecordSets(c *cloud, r *request) (interface{}, error) {
+	z, err := c.zone(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	q := r.URL.Query()
+	l := []*recordSet{}
+	for _, rs := range z.recordSets {
+		if q.Get("name") != "" && rs.Name != dnsName(q.Get("name")) {
+			continue
+		}
+		if q.Get("type") != "" && rs.Type != strings.ToUpper(q.Get("type")) {
+			continue
+		}
+		l = append(l, rs)
+	}
+	sort.SliceStable(l, func(i, j int) bool {
+		return l[i].Name < l[j].Name
+	})
+	return map[string]interface{}{
+		"recordsets": l,
+		"links":      map[string]string{"self": c.zoneURL(z.ID) + "/recordsets"},
+		"metadata":   map[string]int{"total_count": len(l)},
+	}, nil
+}
+
+//checkRecords checks that the records of a record set match its type
+func checkRecords(z *zone, name, t string, records []string) error {
+	if len(records) == 0 {
+		return errorf(http.StatusBadRequest, "invalid_object", "Provided object does not match schema 'recordset': records must not be empty")
+	}
+	for _, v := range records {
+		ip := net.ParseIP(v)
+		switch t {
+		case "A":
+			if ip == nil || ip.To4() == nil {
+				return errorf(http.StatusBadRequest, "invalid_object", "Provided object does not match schema 'recordset': '%s' is not a 'ipv4'", v)
+			}
+		case "AAAA":
+			if ip == nil || ip.To4() != nil {
+				return errorf(http.StatusBadRequest, "invalid_object", "Provided object does not match schema 'recordset': '%s' is not a 'ipv6'", v)
+			}
+		case "CNAME":
+			if len(records) > 1 || !strings.HasSuffix(v, ".") {
+				return errorf(http.StatusBadRequest, "invalid_object", "Provided object does not match schema 'recordset': '%s' is not a 'domainname'", v)
+			}
+		case "TXT":
+			if !strings.HasPrefix(v, `"`) && strings.Contains(v, " ") {
+				return errorf(http.StatusBadRequest, "invalid_object", "Provided object does not match schema 'recordset': TXT record with spaces must be quoted")
+			}
+		default:
+			return errorf(http.StatusBadRequest, "invalid_object", "Provided object does not match schema 'recordset': record sets of type %s are not supported", t)
+		}
+	}
+	if t == "CNAME" && name == z.Name {
+		return errorf(http.StatusBadRequest, "invalid_recordset_location", "CNAME recordsets may not be created at the zone apex")
+	}
+	return nil
+}
+
+func createRecordSet(c *cloud, r *request) (interface{}, error) {
+	z, err := c.zone(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	in := &recordSet{}
+	err = r.decode(in)
+	if err != nil {
+		return nil, err
+	}
+	name, t := dnsName(in.Name), strings.ToUpper(in.Type)
+	if !strings.HasSuffix(in.Name, ".") || (name != z.Name && !strings.HasSuffix(name, "."+z.Name)) {
+		return nil, errorf(http.StatusBadRequest, "invalid_recordset_name", "RecordSet name %s is not in zone %s", in.Name, z.Name)
+	}
+	err = checkRecords(z, name, t, in.Records)
+	if err != nil {
+		return nil, err
+	}
+	for _, rs := range z.recordSets {
+		if rs.Name != name {
+			continue
+		}
+		if rs.Type == t {
+			return nil, errorf(http.StatusConflict, "duplicate_recordset", "Duplicate RecordSet")
+		}
+		if rs.Type == "CNAME" || t == "CNAME" {
+			return nil, errorf(http.StatusBadRequest, "invalid_recordset_location", "CNAME recordsets may not share a name with any other records")
+		}
+	}
+	rs := z.newRecordSet(name, t, in.Records, in.TTL)
+	rs.Description = in.Description
+	rs.Links = map[string]string{"self": c.zoneURL(z.ID) + "/recordsets/" + rs.ID}
+	z.recordSets = append(z.recordSets, rs)
+	return rs, nil
+}
+
+func getRecordSet(c *cloud, r *request) (interface{}, error) {
+	z, err := c.zone(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	return z.recordSet(r.params[1])
+}
+
+func updateRecordSet(c *cloud, r *request) (interface{}, error) {
+	z, err := c.zone(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	rs, err := z.recordSet(r.params[1])
+	if err != nil {
+		return nil, err
+	}
+	in := &recordSet{}
+	err = r.decode(in)
+	if err != nil {
+		return nil, err
+	}
+	if in.Records != nil {
+		err = checkRecords(z, rs.Name, rs.Type, in.Records)
+		if err != nil {
+			return nil, err
+		}
+		rs.Records = in.Records
+	}
+	if in.TTL != nil {
+		rs.TTL = in.TTL
+	}
+	if in.Description != nil {
+		rs.Description = in.Description
+	}
+	now := designateTimestamp()
+	rs.UpdatedAt = &now
+	rs.Version++
+	return rs, nil
+}
+
+func deleteRecordSet(c *cloud, r *request) (interface{}, error) {
+	z, err := c.zone(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	rs, err := z.recordSet(r.params[1])
+	if err != nil {
+		return nil, err
+	}
+	if rs.Name == z.Name && (rs.Type == "SOA" || rs.Type == "NS") {
+		return nil, errorf(http.StatusBadRequest, "bad_request", "Managed records may not be deleted")
+	}
+	for i, o := range z.recordSets {
+		if o == rs {
+			z.recordSets = append(z.recordSets[:i], z.recordSets[i+1:]...)
+			break
+		}
+	}
+	rs.Status, rs.Action = "PENDING", "DELETE"
+	return rs, nil
+}
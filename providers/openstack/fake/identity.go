@@ -0,0 +1,219 @@
+package fake
+
+import (
+	"net/http"
+	"time"
+)
+
+func identityService(c *cloud) *service {
+	return &service{
+		prefix: "/identity",
+		routes: []route{
+			{"GET", "", http.StatusMultipleChoices, listVersions},
+			{"GET", "v3", http.StatusOK, getVersion},
+			{"POST", "v3/auth/tokens", http.StatusCreated, createToken},
+			{"GET", "v3/regions", http.StatusOK, listRegions},
+		},
+		errorBody: identityError,
+	}
+}
+
+//identityError formats errors the way Keystone does
+func identityError(e *apiError) interface{} {
+	return map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    e.status,
+			"title":   http.StatusText(e.status),
+			"message": e.message,
+		},
+	}
+}
+
+func (c *cloud) version() map[string]interface{} {
+	return map[string]interface{}{
+		"id":      "v3.13",
+		"status":  "stable",
+		"updated": "2019-07-19T00:00:00Z",
+		"links": []link{
+			{Href: c.url + "/identity/v3/", Rel: "self"},
+		},
+		"media-types": []map[string]string{
+			{"base": "application/json", "type": "application/vnd.openstack.identity-v3+json"},
+		},
+	}
+}
+
+func listVersions(c *cloud, r *request) (interface{}, error) {
+	return map[string]interface{}{
+		"versions": map[string]interface{}{
+			"values": []interface{}{c.version()},
+		},
+	}, nil
+}
+
+func getVersion(c *cloud, r *request) (interface{}, error) {
+	return map[string]interface{}{"version": c.version()}, nil
+}
+
+type domainRef struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+//isDefault returns true if d references the domain of the user and of the project
+func (d *domainRef) isDefault() bool {
+	return d.ID == "default" || d.Name == DomainName
+}
+
+type authRequest struct {
+	Auth struct {
+		Identity struct {
+			Methods  []string `json:"methods"`
+			Password struct {
+				User struct {
+					ID       string    `json:"id"`
+					Name     string    `json:"name"`
+					Domain   domainRef `json:"domain"`
+					Password string    `json:"password"`
+				} `json:"user"`
+			} `json:"password"`
+			Token struct {
+				ID string `json:"id"`
+			} `json:"token"`
+		} `json:"identity"`
+		Scope *struct {
+			Project *struct {
+				ID     string    `json:"id"`
+				Name   string    `json:"name"`
+				Domain domainRef `json:"domain"`
+			} `json:"project"`
+		} `json:"scope"`
+	} `json:"auth"`
+}
+
+func unauthorized() error {
+	return errorf(http.StatusUnauthorized, "Unauthorized", "The request you have made requires authentication.")
+}
+
+//authenticate checks the identity of an authentication request
+func (c *cloud) authenticate(in *authRequest) error {
+	id := &in.Auth.Identity
+	if len(id.Methods) == 0 {
+		return badRequest("Expecting to find methods in identity.")
+	}
+	for _, m := range id.Methods {
+		switch m {
+		case "password":
+			u := &id.Password.User
+			if u.ID != UserID && (u.Name != UserName || !u.Domain.isDefault()) {
+				return unauthorized()
+			}
+			if u.Password != Password {
+				return unauthorized()
+			}
+		case "token":
+			if !c.tokens[id.Token.ID] {
+				return errorf(http.StatusNotFound, "NotFound", "Could not find token: %s.", id.Token.ID)
+			}
+		default:
+			return unauthorized()
+		}
+	}
+	return nil
+}
+
+//catalog returns the service catalog of the region
+func (c *cloud) catalog() []interface{} {
+	entry := func(id, kind, name, url string) interface{} {
+		return map[string]interface{}{
+			"id":   id,
+			"type": kind,
+			"name": name,
+			"endpoints": []interface{}{
+				map[string]string{
+					"id":        id + "-public",
+					"interface": "public",
+					"region":    Region,
+					"region_id": Region,
+					"url":       url,
+				},
+			},
+		}
+	}
+	return []interface{}{
+		entry("b2f45f4e2e5a4a49a3e4f5a6b7c8d9e0", "identity", "keystone", c.url+"/identity"),
+		entry("c3a56a5f3f6b4b5ab4f5a6b7c8d9e0f1", "compute", "nova", c.url+"/compute/v2.1"),
+		entry("d4b67b6a4a7c4c6bc5a6b7c8d9e0f1a2", "network", "neutron", c.url+"/network"),
+		entry("e5c78c7b5b8d4d7cd6b7c8d9e0f1a2b3", "volumev3", "cinderv3", c.url+"/volume/v3/"+ProjectID),
+		entry("f6d89d8c6c9e4e8de7c8d9e0f1a2b3c4", "load-balancer", "octavia", c.url+"/load-balancer"),
+		entry("07e9ae9d7dae4f9ef8d9e0f1a2b3c4d5", "dns", "designate", c.url+"/dns"),
+		entry("18fabfae8ebf4a0f09e0f1a2b3c4d5e6", "object-store", "swift", c.url+objectStoragePrefix+swiftAccount),
+	}
+}
+
+func createToken(c *cloud, r *request) (interface{}, error) {
+	in := &authRequest{}
+	err := r.decode(in)
+	if err != nil {
+		return nil, err
+	}
+	err = c.authenticate(in)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	token := map[string]interface{}{
+		"methods":    in.Auth.Identity.Methods,
+		"issued_at":  now.Format("2006-01-02T15:04:05.000000Z"),
+		"expires_at": now.Add(time.Hour).Format("2006-01-02T15:04:05.000000Z"),
+		"audit_ids":  []string{newID()},
+		"user": map[string]interface{}{
+			"id":     UserID,
+			"name":   UserName,
+			"domain": domainRef{ID: "default", Name: DomainName},
+		},
+	}
+	if scope := in.Auth.Scope; scope != nil && scope.Project != nil {
+		p := scope.Project
+		if p.ID != ProjectID && (p.Name != ProjectName || !p.Domain.isDefault()) {
+			return nil, unauthorized()
+		}
+		token["project"] = map[string]interface{}{
+			"id":     ProjectID,
+			"name":   ProjectName,
+			"domain": domainRef{ID: "default", Name: DomainName},
+		}
+		token["roles"] = []map[string]string{
+			{"id": "9fe2ff9ee4384b1894a90878d3e92bab", "name": "member"},
+		}
+		token["catalog"] = c.catalog()
+	}
+	id := "gAAAAAB" + newID()
+	c.tokens[id] = true
+	return &headerResponse{
+		header: map[string]string{"X-Subject-Token": id},
+		body:   map[string]interface{}{"token": token},
+	}, nil
+}
+
+//listRegions returns the region of the fake, the request must carry a valid token
+func listRegions(c *cloud, r *request) (interface{}, error) {
+	if !c.tokens[r.Header.Get("X-Auth-Token")] {
+		return nil, unauthorized()
+	}
+	return map[string]interface{}{
+		"regions": []interface{}{
+			map[string]interface{}{
+				"id":               Region,
+				"description":      "",
+				"parent_region_id": nil,
+				"links":            map[string]string{"self": c.url + "/identity/v3/regions/" + Region},
+			},
+		},
+		"links": map[string]interface{}{
+			"self":     c.url + "/identity/v3/regions",
+			"next":     nil,
+			"previous": nil,
+		},
+	}, nil
+}
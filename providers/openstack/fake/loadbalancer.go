@@ -0,0 +1,582 @@
+package fake
+
+import (
+	"net"
+	"net/http"
+	"time"
+)
+
+func loadBalancerService(c *cloud) *service {
+	return &service{
+		prefix:        "/load-balancer/v2.0/lbaas",
+		authenticated: true,
+		routes: []route{
+			{"GET", "loadbalancers", http.StatusOK, listLoadBalancers},
+			{"POST", "loadbalancers", http.StatusCreated, createLoadBalancer},
+			{"GET", "loadbalancers/*", http.StatusOK, getLoadBalancer},
+			{"DELETE", "loadbalancers/*", http.StatusNoContent, deleteLoadBalancer},
+			{"POST", "listeners", http.StatusCreated, createListener},
+			{"GET", "listeners/*", http.StatusOK, getListener},
+			{"POST", "pools", http.StatusCreated, createPool},
+			{"GET", "pools/*", http.StatusOK, getPool},
+			{"GET", "pools/*/members", http.StatusOK, listMembers},
+			{"POST", "pools/*/members", http.StatusCreated, createMember},
+			{"POST", "healthmonitors", http.StatusCreated, createHealthMonitor},
+			{"GET", "healthmonitors/*", http.StatusOK, getHealthMonitor},
+		},
+		errorBody: octaviaError,
+	}
+}
+
+//octaviaError formats errors the way Octavia does
+func octaviaError(e *apiError) interface{} {
+	faultCode := "Client"
+	if e.status >= http.StatusInternalServerError {
+		faultCode = "Server"
+	}
+	return map[string]interface{}{
+		"faultcode":   faultCode,
+		"faultstring": e.message,
+		"debuginfo":   nil,
+	}
+}
+
+//octaviaTimestamp returns the current time in the format used by Octavia
+func octaviaTimestamp() string {
+	return time.Now().UTC().Format("2006-01-02T15:04:05")
+}
+
+type idRef struct {
+	ID string `json:"id"`
+}
+
+//loadBalancer Octavia load balancer, load balancers and their children are ACTIVE as soon as they are created
+type loadBalancer struct {
+	ID                 string   `json:"id"`
+	Name               string   `json:"name"`
+	Description        string   `json:"description"`
+	ProjectID          string   `json:"project_id"`
+	ProvisioningStatus string   `json:"provisioning_status"`
+	OperatingStatus    string   `json:"operating_status"`
+	AdminStateUp       bool     `json:"admin_state_up"`
+	VipAddress         string   `json:"vip_address"`
+	VipPortID          string   `json:"vip_port_id"`
+	VipSubnetID        string   `json:"vip_subnet_id"`
+	VipNetworkID       string   `json:"vip_network_id"`
+	Provider           string   `json:"provider"`
+	Listeners          []idRef  `json:"listeners"`
+	Pools              []idRef  `json:"pools"`
+	Tags               []string `json:"tags"`
+	CreatedAt          string   `json:"created_at"`
+	UpdatedAt          string   `json:"updated_at"`
+}
+
+type lbListener struct {
+	ID                 string  `json:"id"`
+	Name               string  `json:"name"`
+	Description        string  `json:"description"`
+	ProjectID          string  `json:"project_id"`
+	Protocol           string  `json:"protocol"`
+	ProtocolPort       int     `json:"protocol_port"`
+	DefaultPoolID      string  `json:"default_pool_id"`
+	Loadbalancers      []idRef `json:"loadbalancers"`
+	ConnectionLimit    int     `json:"connection_limit"`
+	AdminStateUp       bool    `json:"admin_state_up"`
+	ProvisioningStatus string  `json:"provisioning_status"`
+	OperatingStatus    string  `json:"operating_status"`
+}
+
+type lbPool struct {
+	ID                 string  `json:"id"`
+	Name               string  `json:"name"`
+	Description        string  `json:"description"`
+	ProjectID          string  `json:"project_id"`
+	Protocol           string  `json:"protocol"`
+	LBAlgorithm        string  `json:"lb_algorithm"`
+	Listeners          []idRef `json:"listeners"`
+	Loadbalancers      []idRef `json:"loadbalancers"`
+	Members            []idRef `json:"members"`
+	HealthMonitorID    string  `json:"healthmonitor_id"`
+	AdminStateUp       bool    `json:"admin_state_up"`
+	ProvisioningStatus string  `json:"provisioning_status"`
+	OperatingStatus    string  `json:"operating_status"`
+
+	members []*lbMember
+}
+
+type lbMember struct {
+	ID                 string `json:"id"`
+	Name               string `json:"name"`
+	ProjectID          string `json:"project_id"`
+	Address            string `json:"address"`
+	ProtocolPort       int    `json:"protocol_port"`
+	SubnetID           string `json:"subnet_id"`
+	Weight             int    `json:"weight"`
+	Backup             bool   `json:"backup"`
+	AdminStateUp       bool   `json:"admin_state_up"`
+	ProvisioningStatus string `json:"provisioning_status"`
+	OperatingStatus    string `json:"operating_status"`
+	CreatedAt          string `json:"created_at"`
+	UpdatedAt          string `json:"updated_at"`
+}
+
+type healthMonitor struct {
+	ID                 string  `json:"id"`
+	Name               string  `json:"name"`
+	ProjectID          string  `json:"project_id"`
+	Type               string  `json:"type"`
+	Delay              int     `json:"delay"`
+	Timeout            int     `json:"timeout"`
+	MaxRetries         int     `json:"max_retries"`
+	MaxRetriesDown     int     `json:"max_retries_down"`
+	HTTPMethod         string  `json:"http_method,omitempty"`
+	URLPath            string  `json:"url_path,omitempty"`
+	ExpectedCodes      string  `json:"expected_codes,omitempty"`
+	Pools              []idRef `json:"pools"`
+	AdminStateUp       bool    `json:"admin_state_up"`
+	ProvisioningStatus string  `json:"provisioning_status"`
+	OperatingStatus    string  `json:"operating_status"`
+}
+
+//listenerPoolProtocols protocols of the pools a listener of each protocol can forward traffic to
+var listenerPoolProtocols = map[string][]string{
+	"TCP":  {"TCP", "HTTP", "PROXY"},
+	"UDP":  {"UDP"},
+	"HTTP": {"HTTP", "PROXY"},
+}
+
+//poolMonitorTypes types of the health monitors of the pools of each protocol
+var poolMonitorTypes = map[string][]string{
+	"TCP":   {"HTTP", "HTTPS", "PING", "TCP", "TLS-HELLO"},
+	"HTTP":  {"HTTP", "HTTPS", "PING", "TCP", "TLS-HELLO"},
+	"PROXY": {"HTTP", "HTTPS", "PING", "TCP", "TLS-HELLO"},
+	"UDP":   {"UDP-CONNECT", "HTTP", "TCP"},
+}
+
+func hasString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *cloud) loadBalancer(id string) (*loadBalancer, error) {
+	for _, lb := range c.loadBalancers {
+		if lb.ID == id {
+			return lb, nil
+		}
+	}
+	return nil, notFound("Load Balancer %s not found.", id)
+}
+
+func (c *cloud) lbListener(id string) (*lbListener, error) {
+	for _, l := range c.lbListeners {
+		if l.ID == id {
+			return l, nil
+		}
+	}
+	return nil, notFound("Listener %s not found.", id)
+}
+
+func (c *cloud) lbPool(id string) (*lbPool, error) {
+	for _, p := range c.lbPools {
+		if p.ID == id {
+			return p, nil
+		}
+	}
+	return nil, notFound("Pool %s not found.", id)
+}
+
+func (c *cloud) healthMonitor(id string) (*healthMonitor, error) {
+	for _, hm := range c.healthMonitors {
+		if hm.ID == id {
+			return hm, nil
+		}
+	}
+	return nil, notFound("Health Monitor %s not found.", id)
+}
+
+func listLoadBalancers(c *cloud, r *request) (interface{}, error) {
+	l := []*loadBalancer{}
+	for _, lb := range c.loadBalancers {
+		if matchQuery(lb, r.URL.Query()) {
+			l = append(l, lb)
+		}
+	}
+	return map[string]interface{}{"loadbalancers": l}, nil
+}
+
+//createLoadBalancer creates a load balancer and its VIP port in the VIP subnet
+func createLoadBalancer(c *cloud, r *request) (interface{}, error) {
+	in := struct {
+		LoadBalancer struct {
+			Name        string   `json:"name"`
+			Description string   `json:"description"`
+			VipSubnetID string   `json:"vip_subnet_id"`
+			Tags        []string `json:"tags"`
+		} `json:"loadbalancer"`
+	}{}
+	err := r.decode(&in)
+	if err != nil {
+		return nil, err
+	}
+	sn, err := c.subnet(in.LoadBalancer.VipSubnetID)
+	if err != nil {
+		return nil, badRequest("Validation failure: Subnet %s not found.", in.LoadBalancer.VipSubnetID)
+	}
+	lb := &loadBalancer{
+		ID:                 newID(),
+		Name:               in.LoadBalancer.Name,
+		Description:        in.LoadBalancer.Description,
+		ProjectID:          ProjectID,
+		ProvisioningStatus: "ACTIVE",
+		OperatingStatus:    "ONLINE",
+		AdminStateUp:       true,
+		VipSubnetID:        sn.ID,
+		VipNetworkID:       sn.NetworkID,
+		Provider:           "amphora",
+		Listeners:          []idRef{},
+		Pools:              []idRef{},
+		Tags:               in.LoadBalancer.Tags,
+		CreatedAt:          octaviaTimestamp(),
+	}
+	if lb.Tags == nil {
+		lb.Tags = []string{}
+	}
+	name := "octavia-lb-" + lb.ID
+	owner := "Octavia"
+	p, err := c.newPort(&portRequest{
+		NetworkID:   sn.NetworkID,
+		Name:        &name,
+		FixedIPs:    &[]fixedIP{{SubnetID: sn.ID}},
+		DeviceID:    &lb.ID,
+		DeviceOwner: &owner,
+	})
+	if err != nil {
+		return nil, err
+	}
+	lb.VipPortID = p.ID
+	lb.VipAddress = p.FixedIPs[0].IPAddress
+	c.loadBalancers = append(c.loadBalancers, lb)
+	return map[string]interface{}{"loadbalancer": lb}, nil
+}
+
+func getLoadBalancer(c *cloud, r *request) (interface{}, error) {
+	lb, err := c.loadBalancer(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"loadbalancer": lb}, nil
+}
+
+//deleteLoadBalancer deletes a load balancer, its children are deleted only if the cascade parameter is true
+func deleteLoadBalancer(c *cloud, r *request) (interface{}, error) {
+	lb, err := c.loadBalancer(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	if r.URL.Query().Get("cascade") != "true" && (len(lb.Listeners) > 0 || len(lb.Pools) > 0) {
+		return nil, badRequest("Cannot delete Load Balancer %s - it has children", lb.ID)
+	}
+	var listeners []*lbListener
+	for _, l := range c.lbListeners {
+		if l.Loadbalancers[0].ID != lb.ID {
+			listeners = append(listeners, l)
+		}
+	}
+	c.lbListeners = listeners
+	var pools []*lbPool
+	var monitors []*healthMonitor
+	for _, p := range c.lbPools {
+		if p.Loadbalancers[0].ID != lb.ID {
+			pools = append(pools, p)
+		} else if p.HealthMonitorID != "" {
+			hm, _ := c.healthMonitor(p.HealthMonitorID)
+			monitors = append(monitors, hm)
+		}
+	}
+	c.lbPools = pools
+	for _, hm := range monitors {
+		for i, o := range c.healthMonitors {
+			if o == hm {
+				c.healthMonitors = append(c.healthMonitors[:i], c.healthMonitors[i+1:]...)
+				break
+			}
+		}
+	}
+	if p, err := c.port(lb.VipPortID); err == nil {
+		c.removePort(p)
+	}
+	for i, o := range c.loadBalancers {
+		if o == lb {
+			c.loadBalancers = append(c.loadBalancers[:i], c.loadBalancers[i+1:]...)
+			break
+		}
+	}
+	return nil, nil
+}
+
+func createListener(c *cloud, r *request) (interface{}, error) {
+	in := struct {
+		Listener struct {
+			Name           string `json:"name"`
+			Description    string `json:"description"`
+			LoadbalancerID string `json:"loadbalancer_id"`
+			Protocol       string `json:"protocol"`
+			ProtocolPort   int    `json:"protocol_port"`
+		} `json:"listener"`
+	}{}
+	err := r.decode(&in)
+	if err != nil {
+		return nil, err
+	}
+	lb, err := c.loadBalancer(in.Listener.LoadbalancerID)
+	if err != nil {
+		return nil, badRequest("Validation failure: Load Balancer %s not found.", in.Listener.LoadbalancerID)
+	}
+	if _, ok := listenerPoolProtocols[in.Listener.Protocol]; !ok {
+		return nil, badRequest("Invalid input for field/attribute protocol. Value: '%s'.", in.Listener.Protocol)
+	}
+	if in.Listener.ProtocolPort < 1 || in.Listener.ProtocolPort > 65535 {
+		return nil, badRequest("Invalid input for field/attribute protocol_port. Value: '%d'.", in.Listener.ProtocolPort)
+	}
+	for _, l := range c.lbListeners {
+		if l.Loadbalancers[0].ID == lb.ID && l.ProtocolPort == in.Listener.ProtocolPort && (l.Protocol == "UDP") == (in.Listener.Protocol == "UDP") {
+			return nil, conflict("Load Balancer %s already has a listener with protocol_port of %d", lb.ID, l.ProtocolPort)
+		}
+	}
+	l := &lbListener{
+		ID:                 newID(),
+		Name:               in.Listener.Name,
+		Description:        in.Listener.Description,
+		ProjectID:          ProjectID,
+		Protocol:           in.Listener.Protocol,
+		ProtocolPort:       in.Listener.ProtocolPort,
+		Loadbalancers:      []idRef{{ID: lb.ID}},
+		ConnectionLimit:    -1,
+		AdminStateUp:       true,
+		ProvisioningStatus: "ACTIVE",
+		OperatingStatus:    "ONLINE",
+	}
+	c.lbListeners = append(c.lbListeners, l)
+	lb.Listeners = append(lb.Listeners, idRef{ID: l.ID})
+	lb.UpdatedAt = octaviaTimestamp()
+	return map[string]interface{}{"listener": l}, nil
+}
+
+func getListener(c *cloud, r *request) (interface{}, error) {
+	l, err := c.lbListener(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"listener": l}, nil
+}
+
+//createPool creates the default pool of a listener
+func createPool(c *cloud, r *request) (interface{}, error) {
+	in := struct {
+		Pool struct {
+			Name        string `json:"name"`
+			Description string `json:"description"`
+			ListenerID  string `json:"listener_id"`
+			Protocol    string `json:"protocol"`
+			LBAlgorithm string `json:"lb_algorithm"`
+		} `json:"pool"`
+	}{}
+	err := r.decode(&in)
+	if err != nil {
+		return nil, err
+	}
+	l, err := c.lbListener(in.Pool.ListenerID)
+	if err != nil {
+		return nil, badRequest("Validation failure: Listener %s not found.", in.Pool.ListenerID)
+	}
+	if l.DefaultPoolID != "" {
+		return nil, conflict("Listener %s already has a default pool", l.ID)
+	}
+	if !hasString(listenerPoolProtocols[l.Protocol], in.Pool.Protocol) {
+		return nil, badRequest("Validation failure: The pool protocol '%s' is invalid while the listener protocol is '%s'.", in.Pool.Protocol, l.Protocol)
+	}
+	if !hasString([]string{"ROUND_ROBIN", "LEAST_CONNECTIONS", "SOURCE_IP"}, in.Pool.LBAlgorithm) {
+		return nil, badRequest("Invalid input for field/attribute lb_algorithm. Value: '%s'.", in.Pool.LBAlgorithm)
+	}
+	p := &lbPool{
+		ID:                 newID(),
+		Name:               in.Pool.Name,
+		Description:        in.Pool.Description,
+		ProjectID:          ProjectID,
+		Protocol:           in.Pool.Protocol,
+		LBAlgorithm:        in.Pool.LBAlgorithm,
+		Listeners:          []idRef{{ID: l.ID}},
+		Loadbalancers:      l.Loadbalancers,
+		Members:            []idRef{},
+		AdminStateUp:       true,
+		ProvisioningStatus: "ACTIVE",
+		OperatingStatus:    "ONLINE",
+	}
+	c.lbPools = append(c.lbPools, p)
+	l.DefaultPoolID = p.ID
+	lb, _ := c.loadBalancer(l.Loadbalancers[0].ID)
+	lb.Pools = append(lb.Pools, idRef{ID: p.ID})
+	return map[string]interface{}{"pool": p}, nil
+}
+
+func getPool(c *cloud, r *request) (interface{}, error) {
+	p, err := c.lbPool(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"pool": p}, nil
+}
+
+func listMembers(c *cloud, r *request) (interface{}, error) {
+	p, err := c.lbPool(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	l := []*lbMember{}
+	for _, m := range p.members {
+		if matchQuery(m, r.URL.Query()) {
+			l = append(l, m)
+		}
+	}
+	return map[string]interface{}{"members": l}, nil
+}
+
+func createMember(c *cloud, r *request) (interface{}, error) {
+	p, err := c.lbPool(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	in := struct {
+		Member struct {
+			Name         string `json:"name"`
+			Address      string `json:"address"`
+			ProtocolPort int    `json:"protocol_port"`
+			SubnetID     string `json:"subnet_id"`
+		} `json:"member"`
+	}{}
+	err = r.decode(&in)
+	if err != nil {
+		return nil, err
+	}
+	if net.ParseIP(in.Member.Address) == nil {
+		return nil, badRequest("Invalid input for field/attribute address. Value: '%s'.", in.Member.Address)
+	}
+	if in.Member.ProtocolPort < 1 || in.Member.ProtocolPort > 65535 {
+		return nil, badRequest("Invalid input for field/attribute protocol_port. Value: '%d'.", in.Member.ProtocolPort)
+	}
+	if in.Member.SubnetID != "" {
+		if _, err := c.subnet(in.Member.SubnetID); err != nil {
+			return nil, badRequest("Validation failure: Subnet %s not found.", in.Member.SubnetID)
+		}
+	}
+	for _, m := range p.members {
+		if m.Address == in.Member.Address && m.ProtocolPort == in.Member.ProtocolPort {
+			return nil, conflict("Another member on this pool is already using ip %s on protocol_port %d", m.Address, m.ProtocolPort)
+		}
+	}
+	m := &lbMember{
+		ID:                 newID(),
+		Name:               in.Member.Name,
+		ProjectID:          ProjectID,
+		Address:            in.Member.Address,
+		ProtocolPort:       in.Member.ProtocolPort,
+		SubnetID:           in.Member.SubnetID,
+		Weight:             1,
+		AdminStateUp:       true,
+		ProvisioningStatus: "ACTIVE",
+		OperatingStatus:    "NO_MONITOR",
+		CreatedAt:          octaviaTimestamp(),
+		UpdatedAt:          octaviaTimestamp(),
+	}
+	if p.HealthMonitorID != "" {
+		m.OperatingStatus = "ONLINE"
+	}
+	p.members = append(p.members, m)
+	p.Members = append(p.Members, idRef{ID: m.ID})
+	return map[string]interface{}{"member": m}, nil
+}
+
+func createHealthMonitor(c *cloud, r *request) (interface{}, error) {
+	in := struct {
+		HealthMonitor struct {
+			Name          string `json:"name"`
+			PoolID        string `json:"pool_id"`
+			Type          string `json:"type"`
+			Delay         int    `json:"delay"`
+			Timeout       int    `json:"timeout"`
+			MaxRetries    int    `json:"max_retries"`
+			HTTPMethod    string `json:"http_method"`
+			URLPath       string `json:"url_path"`
+			ExpectedCodes string `json:"expected_codes"`
+		} `json:"healthmonitor"`
+	}{}
+	err := r.decode(&in)
+	if err != nil {
+		return nil, err
+	}
+	hmIn := &in.HealthMonitor
+	p, err := c.lbPool(hmIn.PoolID)
+	if err != nil {
+		return nil, badRequest("Validation failure: Pool %s not found.", hmIn.PoolID)
+	}
+	if p.HealthMonitorID != "" {
+		return nil, conflict("This pool already has a health monitor")
+	}
+	if !hasString(poolMonitorTypes[p.Protocol], hmIn.Type) {
+		return nil, badRequest("Validation failure: The health monitor type %s is not supported by %s pools.", hmIn.Type, p.Protocol)
+	}
+	if hmIn.Delay < 1 || hmIn.Timeout < 1 || hmIn.Timeout > hmIn.Delay {
+		return nil, badRequest("Validation failure: The timeout of the health monitor must be lower than its delay.")
+	}
+	if hmIn.MaxRetries < 1 || hmIn.MaxRetries > 10 {
+		return nil, badRequest("Invalid input for field/attribute max_retries. Value: '%d'.", hmIn.MaxRetries)
+	}
+	hm := &healthMonitor{
+		ID:                 newID(),
+		Name:               hmIn.Name,
+		ProjectID:          ProjectID,
+		Type:               hmIn.Type,
+		Delay:              hmIn.Delay,
+		Timeout:            hmIn.Timeout,
+		MaxRetries:         hmIn.MaxRetries,
+		MaxRetriesDown:     3,
+		Pools:              []idRef{{ID: p.ID}},
+		AdminStateUp:       true,
+		ProvisioningStatus: "ACTIVE",
+		OperatingStatus:    "ONLINE",
+	}
+	if hm.Type == "HTTP" || hm.Type == "HTTPS" {
+		hm.HTTPMethod = "GET"
+		hm.URLPath = "/"
+		hm.ExpectedCodes = "200"
+		if hmIn.HTTPMethod != "" {
+			hm.HTTPMethod = hmIn.HTTPMethod
+		}
+		if hmIn.URLPath != "" {
+			hm.URLPath = hmIn.URLPath
+		}
+		if hmIn.ExpectedCodes != "" {
+			hm.ExpectedCodes = hmIn.ExpectedCodes
+		}
+	} else if hmIn.URLPath != "" {
+		return nil, badRequest("Validation failure: url_path is not a valid option for health monitors of type %s", hm.Type)
+	}
+	c.healthMonitors = append(c.healthMonitors, hm)
+	p.HealthMonitorID = hm.ID
+	for _, m := range p.members {
+		m.OperatingStatus = "ONLINE"
+	}
+	return map[string]interface{}{"healthmonitor": hm}, nil
+}
+
+func getHealthMonitor(c *cloud, r *request) (interface{}, error) {
+	hm, err := c.healthMonitor(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"healthmonitor": hm}, nil
+}
@@ -0,0 +1,517 @@
+package fake
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/SebastienDorgan/anyclouds/iputils"
+)
+
+//externalCIDR range of the external network (TEST-NET-3)
+const externalCIDR = "203.0.113.0/24"
+
+func networkService(c *cloud) *service {
+	routes := []route{
+		{"GET", "networks", http.StatusOK, listNetworks},
+		{"POST", "networks", http.StatusCreated, createNetwork},
+		{"GET", "networks/*", http.StatusOK, getNetwork},
+		{"PUT", "networks/*", http.StatusOK, updateNetwork},
+		{"DELETE", "networks/*", http.StatusNoContent, deleteNetwork},
+		{"GET", "subnets", http.StatusOK, listSubnets},
+		{"POST", "subnets", http.StatusCreated, createSubnet},
+		{"GET", "subnets/*", http.StatusOK, getSubnet},
+		{"PUT", "subnets/*", http.StatusOK, updateSubnet},
+		{"DELETE", "subnets/*", http.StatusNoContent, deleteSubnet},
+		{"GET", "ports", http.StatusOK, listPorts},
+		{"POST", "ports", http.StatusCreated, createPort},
+		{"GET", "ports/*", http.StatusOK, getPort},
+		{"PUT", "ports/*", http.StatusOK, updatePort},
+		{"DELETE", "ports/*", http.StatusNoContent, deletePort},
+		{"GET", "quotas/*/details.json", http.StatusOK, getNetworkQuotaDetails},
+		{"GET", "routers", http.StatusOK, listRouters},
+		{"POST", "routers", http.StatusCreated, createRouter},
+		{"GET", "routers/*", http.StatusOK, getRouter},
+		{"PUT", "routers/*", http.StatusOK, updateRouter},
+		{"DELETE", "routers/*", http.StatusNoContent, deleteRouter},
+		{"PUT", "routers/*/add_router_interface", http.StatusOK, addRouterInterface},
+		{"PUT", "routers/*/remove_router_interface", http.StatusOK, removeRouterInterface},
+		{"GET", "floatingips", http.StatusOK, listFloatingIPs},
+		{"POST", "floatingips", http.StatusCreated, createFloatingIP},
+		{"GET", "floatingips/*", http.StatusOK, getFloatingIP},
+		{"PUT", "floatingips/*", http.StatusOK, updateFloatingIP},
+		{"DELETE", "floatingips/*", http.StatusNoContent, deleteFloatingIP},
+		{"GET", "security-groups", http.StatusOK, listSecurityGroups},
+		{"POST", "security-groups", http.StatusCreated, createSecurityGroup},
+		{"GET", "security-groups/*", http.StatusOK, getSecurityGroup},
+		{"PUT", "security-groups/*", http.StatusOK, updateSecurityGroup},
+		{"DELETE", "security-groups/*", http.StatusNoContent, deleteSecurityGroup},
+		{"GET", "security-group-rules", http.StatusOK, listSecurityGroupRules},
+		{"POST", "security-group-rules", http.StatusCreated, createSecurityGroupRule},
+		{"GET", "security-group-rules/*", http.StatusOK, getSecurityGroupRule},
+		{"DELETE", "security-group-rules/*", http.StatusNoContent, deleteSecurityGroupRule},
+	}
+	for _, resourceType := range []string{"networks", "subnets", "ports", "routers", "floatingips", "security-groups"} {
+		routes = append(routes, tagRoutes(resourceType)...)
+	}
+	return &service{
+		prefix:        "/network/v2.0",
+		authenticated: true,
+		routes:        routes,
+		errorBody:     neutronError,
+	}
+}
+
+//neutronError formats errors the way Neutron does
+func neutronError(e *apiError) interface{} {
+	return map[string]interface{}{
+		"NeutronError": map[string]interface{}{
+			"type":    e.kind,
+			"message": e.message,
+			"detail":  "",
+		},
+	}
+}
+
+type network struct {
+	ID           string   `json:"id"`
+	Name         string   `json:"name"`
+	Description  string   `json:"description"`
+	AdminStateUp bool     `json:"admin_state_up"`
+	Status       string   `json:"status"`
+	Subnets      []string `json:"subnets"`
+	Shared       bool     `json:"shared"`
+	External     bool     `json:"router:external"`
+	MTU          int      `json:"mtu"`
+	TenantID     string   `json:"tenant_id"`
+	ProjectID    string   `json:"project_id"`
+	Tags         []string `json:"tags"`
+	CreatedAt    string   `json:"created_at"`
+	UpdatedAt    string   `json:"updated_at"`
+}
+
+type allocationPool struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+type subnet struct {
+	ID              string           `json:"id"`
+	Name            string           `json:"name"`
+	Description     string           `json:"description"`
+	NetworkID       string           `json:"network_id"`
+	CIDR            string           `json:"cidr"`
+	IPVersion       int              `json:"ip_version"`
+	GatewayIP       nullString       `json:"gateway_ip"`
+	EnableDHCP      bool             `json:"enable_dhcp"`
+	AllocationPools []allocationPool `json:"allocation_pools"`
+	DNSNameservers  []string         `json:"dns_nameservers"`
+	HostRoutes      []interface{}    `json:"host_routes"`
+	TenantID        string           `json:"tenant_id"`
+	ProjectID       string           `json:"project_id"`
+	Tags            []string         `json:"tags"`
+	CreatedAt       string           `json:"created_at"`
+	UpdatedAt       string           `json:"updated_at"`
+
+	ipNet *net.IPNet
+}
+
+func networkNotFound(id string) error {
+	return errorf(http.StatusNotFound, "NetworkNotFound", "Network %s could not be found.", id)
+}
+
+func (c *cloud) network(id string) (*network, error) {
+	for _, n := range c.networks {
+		if n.ID == id {
+			return n, nil
+		}
+	}
+	return nil, networkNotFound(id)
+}
+
+func (c *cloud) subnet(id string) (*subnet, error) {
+	for _, sn := range c.subnets {
+		if sn.ID == id {
+			return sn, nil
+		}
+	}
+	return nil, errorf(http.StatusNotFound, "SubnetNotFound", "Subnet %s could not be found.", id)
+}
+
+//networkSubnets returns the subnets of the network n
+func (c *cloud) networkSubnets(n *network) []*subnet {
+	var l []*subnet
+	for _, sn := range c.subnets {
+		if sn.NetworkID == n.ID {
+			l = append(l, sn)
+		}
+	}
+	return l
+}
+
+func (c *cloud) createExternalNetwork() {
+	n := &network{
+		ID:           newID(),
+		Name:         ExternalNetworkName,
+		AdminStateUp: true,
+		Status:       "ACTIVE",
+		Subnets:      []string{},
+		Shared:       false,
+		External:     true,
+		MTU:          1500,
+		TenantID:     "admin",
+		ProjectID:    "admin",
+		Tags:         []string{},
+		CreatedAt:    timestamp(),
+		UpdatedAt:    timestamp(),
+	}
+	c.networks = append(c.networks, n)
+	sn, _ := c.newSubnet(n, &subnetRequest{
+		Name:      ExternalNetworkName + "-subnet",
+		CIDR:      externalCIDR,
+		IPVersion: 4,
+	})
+	sn.TenantID = "admin"
+	sn.ProjectID = "admin"
+}
+
+func listNetworks(c *cloud, r *request) (interface{}, error) {
+	l := []*network{}
+	for _, n := range c.networks {
+		if matchQuery(n, r.URL.Query()) {
+			l = append(l, n)
+		}
+	}
+	return map[string]interface{}{"networks": l}, nil
+}
+
+type networkRequest struct {
+	Name         *string `json:"name"`
+	Description  *string `json:"description"`
+	AdminStateUp *bool   `json:"admin_state_up"`
+	Shared       *bool   `json:"shared"`
+}
+
+func createNetwork(c *cloud, r *request) (interface{}, error) {
+	in := struct {
+		Network networkRequest `json:"network"`
+	}{}
+	err := r.decode(&in)
+	if err != nil {
+		return nil, err
+	}
+	n := &network{
+		ID:           newID(),
+		AdminStateUp: true,
+		Status:       "ACTIVE",
+		Subnets:      []string{},
+		MTU:          1450,
+		TenantID:     ProjectID,
+		ProjectID:    ProjectID,
+		Tags:         []string{},
+		CreatedAt:    timestamp(),
+	}
+	n.update(&in.Network)
+	c.networks = append(c.networks, n)
+	return map[string]interface{}{"network": n}, nil
+}
+
+func (n *network) update(in *networkRequest) {
+	if in.Name != nil {
+		n.Name = *in.Name
+	}
+	if in.Description != nil {
+		n.Description = *in.Description
+	}
+	if in.AdminStateUp != nil {
+		n.AdminStateUp = *in.AdminStateUp
+	}
+	if in.Shared != nil {
+		n.Shared = *in.Shared
+	}
+	n.UpdatedAt = timestamp()
+}
+
+func getNetwork(c *cloud, r *request) (interface{}, error) {
+	n, err := c.network(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"network": n}, nil
+}
+
+func updateNetwork(c *cloud, r *request) (interface{}, error) {
+	n, err := c.network(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	in := struct {
+		Network networkRequest `json:"network"`
+	}{}
+	err = r.decode(&in)
+	if err != nil {
+		return nil, err
+	}
+	n.update(&in.Network)
+	return map[string]interface{}{"network": n}, nil
+}
+
+func deleteNetwork(c *cloud, r *request) (interface{}, error) {
+	n, err := c.network(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range c.ports {
+		if p.NetworkID == n.ID {
+			return nil, errorf(http.StatusConflict, "NetworkInUse", "Unable to complete operation on network %s. There are one or more ports still in use on the network.", n.ID)
+		}
+	}
+	for _, sn := range c.networkSubnets(n) {
+		c.removeSubnet(sn)
+	}
+	for i, o := range c.networks {
+		if o == n {
+			c.networks = append(c.networks[:i], c.networks[i+1:]...)
+			break
+		}
+	}
+	return nil, nil
+}
+
+func listSubnets(c *cloud, r *request) (interface{}, error) {
+	l := []*subnet{}
+	for _, sn := range c.subnets {
+		if matchQuery(sn, r.URL.Query()) {
+			l = append(l, sn)
+		}
+	}
+	return map[string]interface{}{"subnets": l}, nil
+}
+
+type subnetRequest struct {
+	NetworkID      string   `json:"network_id"`
+	Name           string   `json:"name"`
+	Description    string   `json:"description"`
+	CIDR           string   `json:"cidr"`
+	IPVersion      int      `json:"ip_version"`
+	GatewayIP      *string  `json:"gateway_ip"`
+	EnableDHCP     *bool    `json:"enable_dhcp"`
+	DNSNameservers []string `json:"dns_nameservers"`
+}
+
+//newSubnet creates a subnet in the network n, the first address of the subnet is used as gateway unless specified otherwise
+func (c *cloud) newSubnet(n *network, in *subnetRequest) (*subnet, error) {
+	if in.CIDR == "" {
+		return nil, badRequest("Bad subnets request: a cidr must be specified.")
+	}
+	ipNet, err := parseCIDR(in.CIDR)
+	if err != nil {
+		return nil, err
+	}
+	version := 4
+	if ipNet.IP.To4() == nil {
+		version = 6
+	}
+	if in.IPVersion != version {
+		return nil, badRequest("Invalid input for operation: Cidr %s does not match ip_version %d.", in.CIDR, in.IPVersion)
+	}
+	for _, other := range c.networkSubnets(n) {
+		if overlaps(ipNet, other.ipNet) {
+			return nil, badRequest("Invalid input for operation: Requested subnet with cidr: %s for network: %s overlaps with another subnet.", in.CIDR, n.ID)
+		}
+	}
+	sn := &subnet{
+		ID:             newID(),
+		Name:           in.Name,
+		Description:    in.Description,
+		NetworkID:      n.ID,
+		CIDR:           ipNet.String(),
+		IPVersion:      version,
+		EnableDHCP:     true,
+		DNSNameservers: []string{},
+		HostRoutes:     []interface{}{},
+		TenantID:       ProjectID,
+		ProjectID:      ProjectID,
+		Tags:           []string{},
+		CreatedAt:      timestamp(),
+		UpdatedAt:      timestamp(),
+		ipNet:          ipNet,
+	}
+	if in.EnableDHCP != nil {
+		sn.EnableDHCP = *in.EnableDHCP
+	}
+	if in.DNSNameservers != nil {
+		sn.DNSNameservers = in.DNSNameservers
+	}
+	if version == 4 {
+		ones, _ := ipNet.Mask.Size()
+		if ones > 30 {
+			return nil, badRequest("Invalid input for operation: Prefix length of %s is too long, subnets must have at least 2 allocatable addresses.", in.CIDR)
+		}
+		first, last := ipRange(ipNet)
+		sn.GatewayIP = nullString(iputils.Utoi(first + 1).String())
+		sn.AllocationPools = []allocationPool{
+			{Start: iputils.Utoi(first + 1).String(), End: iputils.Utoi(last - 1).String()},
+		}
+	} else {
+		sn.AllocationPools = []allocationPool{}
+	}
+	if in.GatewayIP != nil {
+		ip := net.ParseIP(*in.GatewayIP)
+		if *in.GatewayIP != "" && (ip == nil || !ipNet.Contains(ip)) {
+			return nil, badRequest("Invalid input for operation: Gateway IP %s conflicts with the subnet %s.", *in.GatewayIP, in.CIDR)
+		}
+		sn.GatewayIP = nullString(*in.GatewayIP)
+	}
+	c.subnets = append(c.subnets, sn)
+	n.Subnets = append(n.Subnets, sn.ID)
+	return sn, nil
+}
+
+func createSubnet(c *cloud, r *request) (interface{}, error) {
+	in := struct {
+		Subnet subnetRequest `json:"subnet"`
+	}{}
+	err := r.decode(&in)
+	if err != nil {
+		return nil, err
+	}
+	n, err := c.network(in.Subnet.NetworkID)
+	if err != nil {
+		return nil, err
+	}
+	sn, err := c.newSubnet(n, &in.Subnet)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"subnet": sn}, nil
+}
+
+func getSubnet(c *cloud, r *request) (interface{}, error) {
+	sn, err := c.subnet(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"subnet": sn}, nil
+}
+
+func updateSubnet(c *cloud, r *request) (interface{}, error) {
+	sn, err := c.subnet(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	in := struct {
+		Subnet struct {
+			Name           *string   `json:"name"`
+			Description    *string   `json:"description"`
+			EnableDHCP     *bool     `json:"enable_dhcp"`
+			DNSNameservers *[]string `json:"dns_nameservers"`
+		} `json:"subnet"`
+	}{}
+	err = r.decode(&in)
+	if err != nil {
+		return nil, err
+	}
+	if in.Subnet.Name != nil {
+		sn.Name = *in.Subnet.Name
+	}
+	if in.Subnet.Description != nil {
+		sn.Description = *in.Subnet.Description
+	}
+	if in.Subnet.EnableDHCP != nil {
+		sn.EnableDHCP = *in.Subnet.EnableDHCP
+	}
+	if in.Subnet.DNSNameservers != nil {
+		sn.DNSNameservers = *in.Subnet.DNSNameservers
+	}
+	sn.UpdatedAt = timestamp()
+	return map[string]interface{}{"subnet": sn}, nil
+}
+
+func deleteSubnet(c *cloud, r *request) (interface{}, error) {
+	sn, err := c.subnet(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range c.ports {
+		if p.hasAddressIn(sn.ID) {
+			return nil, errorf(http.StatusConflict, "SubnetInUse", "Unable to complete operation on subnet %s: One or more ports have an IP allocation from this subnet.", sn.ID)
+		}
+	}
+	c.removeSubnet(sn)
+	return nil, nil
+}
+
+func (c *cloud) removeSubnet(sn *subnet) {
+	for i, o := range c.subnets {
+		if o == sn {
+			c.subnets = append(c.subnets[:i], c.subnets[i+1:]...)
+			break
+		}
+	}
+	n, err := c.network(sn.NetworkID)
+	if err != nil {
+		return
+	}
+	for i, id := range n.Subnets {
+		if id == sn.ID {
+			n.Subnets = append(n.Subnets[:i], n.Subnets[i+1:]...)
+			break
+		}
+	}
+}
+
+//usedAddresses returns the addresses of sn allocated to ports and floating IPs, the gateway address included
+//The addresses of the port self are not reported
+func (c *cloud) usedAddresses(sn *subnet, self *port) map[string]bool {
+	used := map[string]bool{}
+	if sn.GatewayIP != "" {
+		used[string(sn.GatewayIP)] = true
+	}
+	for _, p := range c.ports {
+		if p == self {
+			continue
+		}
+		for _, ip := range p.FixedIPs {
+			if ip.SubnetID == sn.ID {
+				used[ip.IPAddress] = true
+			}
+		}
+	}
+	for _, fip := range c.floatingIPs {
+		if sn.ipNet.Contains(net.ParseIP(fip.FloatingIPAddress)) {
+			used[fip.FloatingIPAddress] = true
+		}
+	}
+	return used
+}
+
+//allocateAddress returns the first free address of the allocation pools of sn
+func (c *cloud) allocateAddress(sn *subnet, self *port) (string, error) {
+	if sn.IPVersion != 4 {
+		return "", badRequest("Invalid input for operation: automatic allocation of IPv6 addresses is not supported.")
+	}
+	used := c.usedAddresses(sn, self)
+	for _, pool := range sn.AllocationPools {
+		start := net.ParseIP(pool.Start)
+		end := net.ParseIP(pool.End)
+		for u := iputils.Itou(&start); u <= iputils.Itou(&end); u++ {
+			addr := iputils.Utoi(u).String()
+			if !used[addr] {
+				return addr, nil
+			}
+		}
+	}
+	return "", errorf(http.StatusConflict, "IpAddressGenerationFailure", "No more IP addresses available on network %s.", sn.NetworkID)
+}
+
+//checkAddress checks that addr is a free address of sn
+func (c *cloud) checkAddress(sn *subnet, addr string, self *port) error {
+	ip := net.ParseIP(addr)
+	if ip == nil || !sn.ipNet.Contains(ip) {
+		return badRequest("IP address %s is not a valid IP for the specified subnet.", addr)
+	}
+	if c.usedAddresses(sn, self)[ip.String()] {
+		return errorf(http.StatusConflict, "IpAddressAlreadyAllocated", "IP address %s already allocated in subnet %s", addr, sn.ID)
+	}
+	return nil
+}
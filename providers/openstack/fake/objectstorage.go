@@ -0,0 +1,358 @@
+package fake
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	//objectStoragePrefix path of the Swift service
+	objectStoragePrefix = "/object-store"
+	//swiftAccount path of the Swift account of the project, the Swift API addresses objects as account/container/object
+	swiftAccount = "/v1/AUTH_" + ProjectID
+)
+
+type swiftObject struct {
+	content      []byte
+	contentType  string
+	etag         string
+	lastModified time.Time
+}
+
+type swiftContainer struct {
+	name    string
+	objects map[string]*swiftObject
+}
+
+//swiftError formats errors the way Swift does, as HTML documents
+func swiftError(status int, title, message string) error {
+	return errorf(status, title, "<html><h1>%s</h1><p>%s</p></html>", title, message)
+}
+
+func swiftNotFound() error {
+	return swiftError(http.StatusNotFound, "Not Found", "The resource could not be found.")
+}
+
+func swiftUnauthorized() error {
+	return swiftError(http.StatusUnauthorized, "Unauthorized", "This server could not verify that you are authorized to access the document you requested.")
+}
+
+func (c *cloud) swiftContainer(name string) (*swiftContainer, error) {
+	ct, ok := c.swiftContainers[name]
+	if !ok {
+		return nil, swiftNotFound()
+	}
+	return ct, nil
+}
+
+func (c *cloud) swiftObject(container, name string) (*swiftObject, error) {
+	ct, err := c.swiftContainer(container)
+	if err != nil {
+		return nil, err
+	}
+	o, ok := ct.objects[name]
+	if !ok {
+		return nil, swiftNotFound()
+	}
+	return o, nil
+}
+
+//checkTempURL checks the signature and the expiry of a temporary URL, the signature is a HMAC-SHA1 of the method,
+//the expiry and the path of the object keyed by the temporary URL key of the account
+func (c *cloud) checkTempURL(r *http.Request, path string) error {
+	query := r.URL.Query()
+	expires, err := strconv.ParseInt(query.Get("temp_url_expires"), 10, 64)
+	if err != nil || c.tempURLKey == "" {
+		return swiftUnauthorized()
+	}
+	h := hmac.New(sha1.New, []byte(c.tempURLKey))
+	_, _ = fmt.Fprintf(h, "%s\n%d\n%s", r.Method, expires, path)
+	if !hmac.Equal([]byte(hex.EncodeToString(h.Sum(nil))), []byte(query.Get("temp_url_sig"))) {
+		return swiftUnauthorized()
+	}
+	if time.Now().Unix() > expires {
+		return swiftUnauthorized()
+	}
+	return nil
+}
+
+//serveObjectStorage dispatches Swift requests using their method and their path
+//Requests carry a token or the signature of a temporary URL
+func (s *Server) serveObjectStorage(w http.ResponseWriter, r *http.Request) {
+	c := s.cloud
+	path := strings.TrimPrefix(r.URL.Path, objectStoragePrefix)
+	if path != swiftAccount && !strings.HasPrefix(path, swiftAccount+"/") {
+		writeSwiftError(w, r, swiftNotFound())
+		return
+	}
+	names := strings.SplitN(strings.TrimPrefix(strings.TrimPrefix(path, swiftAccount), "/"), "/", 2)
+	container, name := names[0], ""
+	if len(names) == 2 {
+		name = names[1]
+	}
+	var err error
+	if r.URL.Query().Get("temp_url_sig") != "" && name != "" {
+		err = c.checkTempURL(r, path)
+	} else if !c.tokens[r.Header.Get("X-Auth-Token")] {
+		err = swiftUnauthorized()
+	}
+	if err != nil {
+		writeSwiftError(w, r, err)
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeSwiftError(w, r, err)
+		return
+	}
+	w.Header().Set("X-Trans-Id", "tx"+strings.Replace(newID(), "-", "", -1))
+	switch {
+	case container == "" && r.Method == http.MethodHead:
+		c.getAccount(w)
+	case container == "" && r.Method == http.MethodGet:
+		c.listSwiftContainers(w, r)
+	case container == "" && r.Method == http.MethodPost:
+		c.updateAccount(w, r)
+	case name == "" && r.Method == http.MethodPut:
+		c.createSwiftContainer(w, container)
+	case name == "" && r.Method == http.MethodHead:
+		err = c.getSwiftContainer(w, container)
+	case name == "" && r.Method == http.MethodGet:
+		err = c.listSwiftObjects(w, r, container)
+	case name == "" && r.Method == http.MethodDelete:
+		err = c.deleteSwiftContainer(w, container)
+	case name == "":
+		err = swiftError(http.StatusMethodNotAllowed, "Method Not Allowed", "The method is not allowed for this resource.")
+	case r.Method == http.MethodPut:
+		err = c.putSwiftObject(w, r, container, name, body)
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		err = c.getSwiftObject(w, r, container, name)
+	case r.Method == http.MethodDelete:
+		err = c.deleteSwiftObject(w, container, name)
+	default:
+		err = swiftError(http.StatusMethodNotAllowed, "Method Not Allowed", "The method is not allowed for this resource.")
+	}
+	if err != nil {
+		writeSwiftError(w, r, err)
+	}
+}
+
+//writeSwiftError writes the HTML error document of Swift, responses to HEAD requests have no body
+func writeSwiftError(w http.ResponseWriter, r *http.Request, err error) {
+	e := toAPIError(err)
+	if r.Method == http.MethodHead {
+		w.WriteHeader(e.status)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+	w.WriteHeader(e.status)
+	_, _ = w.Write([]byte(e.message))
+}
+
+func (c *cloud) getAccount(w http.ResponseWriter) {
+	var objects, bytes int
+	for _, ct := range c.swiftContainers {
+		for _, o := range ct.objects {
+			objects++
+			bytes += len(o.content)
+		}
+	}
+	w.Header().Set("X-Account-Container-Count", strconv.Itoa(len(c.swiftContainers)))
+	w.Header().Set("X-Account-Object-Count", strconv.Itoa(objects))
+	w.Header().Set("X-Account-Bytes-Used", strconv.Itoa(bytes))
+	if c.tempURLKey != "" {
+		w.Header().Set("X-Account-Meta-Temp-Url-Key", c.tempURLKey)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+//updateAccount updates the temporary URL key of the account, the other metadata are ignored
+func (c *cloud) updateAccount(w http.ResponseWriter, r *http.Request) {
+	if key, ok := r.Header["X-Account-Meta-Temp-Url-Key"]; ok {
+		c.tempURLKey = key[0]
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+//writeListing writes the entries following the marker of the request, limited to its limit parameter
+//Entries are listed as JSON if the request accepts it and as plain text names otherwise
+func writeListing(w http.ResponseWriter, r *http.Request, names []string, entry func(name string) interface{}) {
+	query := r.URL.Query()
+	sort.Strings(names)
+	first := sort.Search(len(names), func(i int) bool {
+		return names[i] > query.Get("marker")
+	})
+	names = names[first:]
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil || limit <= 0 || limit > 10000 {
+		limit = 10000
+	}
+	if len(names) > limit {
+		names = names[:limit]
+	}
+	if strings.Contains(r.Header.Get("Accept"), "application/json") || query.Get("format") == "json" {
+		entries := []interface{}{}
+		for _, name := range names {
+			entries = append(entries, entry(name))
+		}
+		b, _ := json.Marshal(entries)
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		_, _ = w.Write(b)
+		return
+	}
+	if len(names) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = w.Write([]byte(strings.Join(names, "\n") + "\n"))
+}
+
+func (c *cloud) listSwiftContainers(w http.ResponseWriter, r *http.Request) {
+	prefix := r.URL.Query().Get("prefix")
+	var names []string
+	for name := range c.swiftContainers {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	writeListing(w, r, names, func(name string) interface{} {
+		ct := c.swiftContainers[name]
+		var bytes int
+		for _, o := range ct.objects {
+			bytes += len(o.content)
+		}
+		return map[string]interface{}{
+			"name":  name,
+			"count": len(ct.objects),
+			"bytes": bytes,
+		}
+	})
+}
+
+//createSwiftContainer creates a container, creating an existing container succeeds with the 202 status
+func (c *cloud) createSwiftContainer(w http.ResponseWriter, name string) {
+	if _, ok := c.swiftContainers[name]; ok {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	c.swiftContainers[name] = &swiftContainer{
+		name:    name,
+		objects: map[string]*swiftObject{},
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (c *cloud) getSwiftContainer(w http.ResponseWriter, name string) error {
+	ct, err := c.swiftContainer(name)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("X-Container-Object-Count", strconv.Itoa(len(ct.objects)))
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+//deleteSwiftContainer deletes an empty container
+func (c *cloud) deleteSwiftContainer(w http.ResponseWriter, name string) error {
+	ct, err := c.swiftContainer(name)
+	if err != nil {
+		return err
+	}
+	if len(ct.objects) > 0 {
+		return swiftError(http.StatusConflict, "Conflict", "There was a conflict when trying to complete your request.")
+	}
+	delete(c.swiftContainers, name)
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (c *cloud) listSwiftObjects(w http.ResponseWriter, r *http.Request, container string) error {
+	ct, err := c.swiftContainer(container)
+	if err != nil {
+		return err
+	}
+	prefix := r.URL.Query().Get("prefix")
+	var names []string
+	for name := range ct.objects {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	writeListing(w, r, names, func(name string) interface{} {
+		o := ct.objects[name]
+		return map[string]interface{}{
+			"name":          name,
+			"bytes":         len(o.content),
+			"hash":          o.etag,
+			"content_type":  o.contentType,
+			"last_modified": o.lastModified.Format("2006-01-02T15:04:05.000000"),
+		}
+	})
+	return nil
+}
+
+func (c *cloud) putSwiftObject(w http.ResponseWriter, r *http.Request, container, name string, body []byte) error {
+	ct, err := c.swiftContainer(container)
+	if err != nil {
+		return err
+	}
+	sum := md5.Sum(body)
+	o := &swiftObject{
+		content:      body,
+		contentType:  r.Header.Get("Content-Type"),
+		etag:         hex.EncodeToString(sum[:]),
+		lastModified: time.Now().UTC().Truncate(time.Microsecond),
+	}
+	if etag := r.Header.Get("ETag"); etag != "" && etag != o.etag {
+		return swiftError(http.StatusUnprocessableEntity, "Unprocessable Entity", "Unable to process the contained instructions")
+	}
+	if o.contentType == "" {
+		o.contentType = "application/octet-stream"
+	}
+	ct.objects[name] = o
+	w.Header().Set("Etag", o.etag)
+	w.Header().Set("Last-Modified", o.lastModified.Format(http.TimeFormat))
+	w.WriteHeader(http.StatusCreated)
+	return nil
+}
+
+//getSwiftObject implements the GET and HEAD requests of an object
+func (c *cloud) getSwiftObject(w http.ResponseWriter, r *http.Request, container, name string) error {
+	o, err := c.swiftObject(container, name)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", o.contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(o.content)))
+	w.Header().Set("Etag", o.etag)
+	w.Header().Set("Last-Modified", o.lastModified.Format(http.TimeFormat))
+	w.Header().Set("Accept-Ranges", "bytes")
+	if r.Method == http.MethodGet {
+		_, _ = w.Write(o.content)
+	}
+	return nil
+}
+
+func (c *cloud) deleteSwiftObject(w http.ResponseWriter, container, name string) error {
+	ct, err := c.swiftContainer(container)
+	if err != nil {
+		return err
+	}
+	if _, ok := ct.objects[name]; !ok {
+		return swiftNotFound()
+	}
+	delete(ct.objects, name)
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
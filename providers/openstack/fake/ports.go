@@ -0,0 +1,343 @@
+package fake
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ownerRouterInterface = "network:router_interface"
+	ownerRouterGateway   = "network:router_gateway"
+	ownerCompute         = "compute:nova"
+)
+
+type fixedIP struct {
+	SubnetID  string `json:"subnet_id"`
+	IPAddress string `json:"ip_address"`
+}
+
+type addressPair struct {
+	IPAddress  string `json:"ip_address"`
+	MACAddress string `json:"mac_address,omitempty"`
+}
+
+type port struct {
+	ID                  string        `json:"id"`
+	Name                string        `json:"name"`
+	Description         string        `json:"description"`
+	NetworkID           string        `json:"network_id"`
+	AdminStateUp        bool          `json:"admin_state_up"`
+	Status              string        `json:"status"`
+	MACAddress          string        `json:"mac_address"`
+	FixedIPs            []fixedIP     `json:"fixed_ips"`
+	DeviceID            string        `json:"device_id"`
+	DeviceOwner         string        `json:"device_owner"`
+	SecurityGroups      []string      `json:"security_groups"`
+	AllowedAddressPairs []addressPair `json:"allowed_address_pairs"`
+	TenantID            string        `json:"tenant_id"`
+	ProjectID           string        `json:"project_id"`
+	Tags                []string      `json:"tags"`
+	CreatedAt           string        `json:"created_at"`
+	UpdatedAt           string        `json:"updated_at"`
+}
+
+//hasAddressIn returns true if the port has an address in the subnet identified by subnetID
+func (p *port) hasAddressIn(subnetID string) bool {
+	for _, ip := range p.FixedIPs {
+		if ip.SubnetID == subnetID {
+			return true
+		}
+	}
+	return false
+}
+
+//isNetworkOwned returns true if the port is owned by a network service
+func (p *port) isNetworkOwned() bool {
+	return strings.HasPrefix(p.DeviceOwner, "network:")
+}
+
+func (p *port) updateStatus() {
+	if p.DeviceID != "" && p.AdminStateUp {
+		p.Status = "ACTIVE"
+	} else {
+		p.Status = "DOWN"
+	}
+}
+
+func (c *cloud) port(id string) (*port, error) {
+	for _, p := range c.ports {
+		if p.ID == id {
+			return p, nil
+		}
+	}
+	return nil, errorf(http.StatusNotFound, "PortNotFound", "Port %s could not be found.", id)
+}
+
+//devicePorts returns the ports bound to the device identified by deviceID
+func (c *cloud) devicePorts(deviceID string) []*port {
+	var l []*port
+	for _, p := range c.ports {
+		if p.DeviceID == deviceID {
+			l = append(l, p)
+		}
+	}
+	return l
+}
+
+type portRequest struct {
+	NetworkID           string         `json:"network_id"`
+	Name                *string        `json:"name"`
+	Description         *string        `json:"description"`
+	AdminStateUp        *bool          `json:"admin_state_up"`
+	MACAddress          *string        `json:"mac_address"`
+	FixedIPs            *[]fixedIP     `json:"fixed_ips"`
+	DeviceID            *string        `json:"device_id"`
+	DeviceOwner         *string        `json:"device_owner"`
+	SecurityGroups      *[]string      `json:"security_groups"`
+	AllowedAddressPairs *[]addressPair `json:"allowed_address_pairs"`
+}
+
+func macAddress() string {
+	b := uuid.New()
+	return fmt.Sprintf("fa:16:3e:%02x:%02x:%02x", b[0], b[1], b[2])
+}
+
+//fixedIPs allocates the addresses requested for a port of the network n
+//The first subnet of the network having a free address is used if no address is requested
+func (c *cloud) fixedIPs(n *network, requested *[]fixedIP, self *port) ([]fixedIP, error) {
+	ips := []fixedIP{}
+	if requested == nil {
+		for _, sn := range c.networkSubnets(n) {
+			if sn.IPVersion != 4 {
+				continue
+			}
+			addr, err := c.allocateAddress(sn, self)
+			if err != nil {
+				continue
+			}
+			return []fixedIP{{SubnetID: sn.ID, IPAddress: addr}}, nil
+		}
+		if len(n.Subnets) > 0 {
+			return nil, errorf(http.StatusConflict, "IpAddressGenerationFailure", "No more IP addresses available on network %s.", n.ID)
+		}
+		return ips, nil
+	}
+	allocated := map[string]bool{}
+	for _, req := range *requested {
+		var sn *subnet
+		if req.SubnetID != "" {
+			s, err := c.subnet(req.SubnetID)
+			if err != nil || s.NetworkID != n.ID {
+				return nil, badRequest("Invalid input for operation: Failed to create port on network %s, because fixed_ips included invalid subnet %s.", n.ID, req.SubnetID)
+			}
+			sn = s
+		} else if req.IPAddress != "" {
+			ip := net.ParseIP(req.IPAddress)
+			for _, s := range c.networkSubnets(n) {
+				if ip != nil && s.ipNet.Contains(ip) {
+					sn = s
+				}
+			}
+			if sn == nil {
+				return nil, badRequest("Invalid input for operation: IP address %s is not a valid IP for any of the subnets on the specified network.", req.IPAddress)
+			}
+		} else {
+			return nil, badRequest("Invalid input for fixed_ips. Reason: a subnet_id or an ip_address must be specified.")
+		}
+		addr := req.IPAddress
+		if addr == "" {
+			a, err := c.allocateAddress(sn, self)
+			if err != nil {
+				return nil, err
+			}
+			addr = a
+		} else if err := c.checkAddress(sn, addr, self); err != nil {
+			return nil, err
+		}
+		if allocated[addr] {
+			return nil, badRequest("Invalid input for operation: Duplicate IP address %s.", addr)
+		}
+		allocated[addr] = true
+		ips = append(ips, fixedIP{SubnetID: sn.ID, IPAddress: addr})
+	}
+	return ips, nil
+}
+
+//securityGroupIDs checks that the security groups identified by ids exist
+func (c *cloud) securityGroupIDs(ids []string) ([]string, error) {
+	l := []string{}
+	for _, id := range ids {
+		sg, err := c.securityGroup(id)
+		if err != nil {
+			return nil, err
+		}
+		l = append(l, sg.ID)
+	}
+	return l, nil
+}
+
+//newPort creates a port, the default security group is applied to the ports that are not owned by a network service
+func (c *cloud) newPort(in *portRequest) (*port, error) {
+	n, err := c.network(in.NetworkID)
+	if err != nil {
+		return nil, err
+	}
+	p := &port{
+		ID:                  newID(),
+		NetworkID:           n.ID,
+		AdminStateUp:        true,
+		MACAddress:          macAddress(),
+		SecurityGroups:      []string{},
+		AllowedAddressPairs: []addressPair{},
+		TenantID:            ProjectID,
+		ProjectID:           ProjectID,
+		Tags:                []string{},
+		CreatedAt:           timestamp(),
+	}
+	err = c.updatePort(p, in)
+	if err != nil {
+		return nil, err
+	}
+	if in.SecurityGroups == nil && !p.isNetworkOwned() {
+		p.SecurityGroups = []string{c.defaultSecurityGroup().ID}
+	}
+	c.ports = append(c.ports, p)
+	return p, nil
+}
+
+func (c *cloud) updatePort(p *port, in *portRequest) error {
+	if in.NetworkID != "" && in.NetworkID != p.NetworkID {
+		return badRequest("Cannot update read-only attribute network_id")
+	}
+	n, err := c.network(p.NetworkID)
+	if err != nil {
+		return err
+	}
+	if in.FixedIPs != nil || p.FixedIPs == nil {
+		ips, err := c.fixedIPs(n, in.FixedIPs, p)
+		if err != nil {
+			return err
+		}
+		p.FixedIPs = ips
+	}
+	if in.SecurityGroups != nil {
+		ids, err := c.securityGroupIDs(*in.SecurityGroups)
+		if err != nil {
+			return err
+		}
+		p.SecurityGroups = ids
+	}
+	if in.Name != nil {
+		p.Name = *in.Name
+	}
+	if in.Description != nil {
+		p.Description = *in.Description
+	}
+	if in.AdminStateUp != nil {
+		p.AdminStateUp = *in.AdminStateUp
+	}
+	if in.MACAddress != nil {
+		if _, err := net.ParseMAC(*in.MACAddress); err != nil {
+			return badRequest("Invalid input for mac_address. Reason: '%s' is not a valid MAC address.", *in.MACAddress)
+		}
+		p.MACAddress = *in.MACAddress
+	}
+	if in.DeviceID != nil {
+		p.DeviceID = *in.DeviceID
+	}
+	if in.DeviceOwner != nil {
+		p.DeviceOwner = *in.DeviceOwner
+	}
+	if in.AllowedAddressPairs != nil {
+		p.AllowedAddressPairs = *in.AllowedAddressPairs
+	}
+	p.updateStatus()
+	p.UpdatedAt = timestamp()
+	return nil
+}
+
+//removePort deletes the port p and disassociates its floating IPs
+func (c *cloud) removePort(p *port) {
+	for _, fip := range c.floatingIPs {
+		if string(fip.PortID) == p.ID {
+			fip.disassociate()
+		}
+	}
+	for i, o := range c.ports {
+		if o == p {
+			c.ports = append(c.ports[:i], c.ports[i+1:]...)
+			return
+		}
+	}
+}
+
+func listPorts(c *cloud, r *request) (interface{}, error) {
+	l := []*port{}
+	for _, p := range c.ports {
+		if matchQuery(p, r.URL.Query()) {
+			l = append(l, p)
+		}
+	}
+	return map[string]interface{}{"ports": l}, nil
+}
+
+func createPort(c *cloud, r *request) (interface{}, error) {
+	in := struct {
+		Port portRequest `json:"port"`
+	}{}
+	err := r.decode(&in)
+	if err != nil {
+		return nil, err
+	}
+	if in.Port.NetworkID == "" {
+		return nil, badRequest("Failed to parse request. Required attribute 'network_id' not specified")
+	}
+	p, err := c.newPort(&in.Port)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"port": p}, nil
+}
+
+func getPort(c *cloud, r *request) (interface{}, error) {
+	p, err := c.port(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"port": p}, nil
+}
+
+func updatePort(c *cloud, r *request) (interface{}, error) {
+	p, err := c.port(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	in := struct {
+		Port portRequest `json:"port"`
+	}{}
+	err = r.decode(&in)
+	if err != nil {
+		return nil, err
+	}
+	err = c.updatePort(p, &in.Port)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"port": p}, nil
+}
+
+func deletePort(c *cloud, r *request) (interface{}, error) {
+	p, err := c.port(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	if p.DeviceOwner == ownerRouterInterface || p.DeviceOwner == ownerRouterGateway {
+		return nil, errorf(http.StatusConflict, "ServicePortInUse", "Port %s cannot be deleted directly via the port API: has device owner %s.", p.ID, p.DeviceOwner)
+	}
+	c.removePort(p)
+	return nil, nil
+}
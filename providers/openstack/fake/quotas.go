@@ -0,0 +1,101 @@
+package fake
+
+//quotas quotas of the project indexed by resource name
+var quotas = map[string]int{
+	"instances":      10,
+	"cores":          20,
+	"ram":            51200,
+	"volumes":        10,
+	"gigabytes":      1000,
+	"floatingip":     50,
+	"security_group": 10,
+}
+
+//checkProject returns an error if id is not the identifier of the project
+func checkProject(id string) error {
+	if id != ProjectID {
+		return notFound("project %s could not be found", id)
+	}
+	return nil
+}
+
+//getLimits returns the absolute limits of the project, Nova does not report floating ips and security groups used through Neutron
+func getLimits(c *cloud, r *request) (interface{}, error) {
+	cores, ram := 0, 0
+	for _, s := range c.servers {
+		if f, err := c.flavor(s.FlavorID); err == nil {
+			cores += f.VCPUs
+			ram += f.RAM
+		}
+	}
+	return map[string]interface{}{
+		"limits": map[string]interface{}{
+			"rate": []interface{}{},
+			"absolute": map[string]int{
+				"maxTotalInstances":       quotas["instances"],
+				"maxTotalCores":           quotas["cores"],
+				"maxTotalRAMSize":         quotas["ram"],
+				"maxTotalKeypairs":        100,
+				"maxServerMeta":           128,
+				"maxServerGroups":         10,
+				"maxServerGroupMembers":   10,
+				"totalInstancesUsed":      len(c.servers),
+				"totalCoresUsed":          cores,
+				"totalRAMUsed":            ram,
+				"maxSecurityGroups":       -1,
+				"maxTotalFloatingIps":     -1,
+				"totalSecurityGroupsUsed": 0,
+				"totalFloatingIpsUsed":    0,
+			},
+		},
+	}, nil
+}
+
+//quotaDetail returns the Neutron quota detail of resource
+func quotaDetail(resource string, used int) map[string]int {
+	return map[string]int{"limit": quotas[resource], "used": used, "reserved": 0}
+}
+
+//getNetworkQuotaDetails returns the quotas and the usage of the Neutron resources of the project
+func getNetworkQuotaDetails(c *cloud, r *request) (interface{}, error) {
+	err := checkProject(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"quota": map[string]interface{}{
+			"floatingip":     quotaDetail("floatingip", len(c.floatingIPs)),
+			"security_group": quotaDetail("security_group", len(c.securityGroups)),
+			"network":        map[string]int{"limit": 100, "used": len(c.networks), "reserved": 0},
+			"subnet":         map[string]int{"limit": 100, "used": len(c.subnets), "reserved": 0},
+			"port":           map[string]int{"limit": 500, "used": len(c.ports), "reserved": 0},
+			"router":         map[string]int{"limit": 10, "used": len(c.routers), "reserved": 0},
+		},
+	}, nil
+}
+
+//getVolumeQuotaSet returns the quota set of the project, with the usage of the resources if the usage parameter is true
+func getVolumeQuotaSet(c *cloud, r *request) (interface{}, error) {
+	err := checkProject(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	gigabytes := 0
+	for _, v := range c.volumes {
+		gigabytes += v.Size
+	}
+	set := map[string]interface{}{"id": ProjectID}
+	if r.URL.Query().Get("usage") != "true" {
+		set["volumes"] = quotas["volumes"]
+		set["gigabytes"] = quotas["gigabytes"]
+		set["snapshots"] = 10
+		return map[string]interface{}{"quota_set": set}, nil
+	}
+	usage := func(resource string, inUse int) map[string]int {
+		return map[string]int{"limit": quotas[resource], "in_use": inUse, "reserved": 0, "allocated": 0}
+	}
+	set["volumes"] = usage("volumes", len(c.volumes))
+	set["gigabytes"] = usage("gigabytes", gigabytes)
+	set["snapshots"] = map[string]int{"limit": 10, "in_use": len(c.snapshots), "reserved": 0, "allocated": 0}
+	return map[string]interface{}{"quota_set": set}, nil
+}
@@ -0,0 +1,618 @@
+package fake
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+)
+
+type gatewayInfo struct {
+	NetworkID        string    `json:"network_id"`
+	EnableSNAT       bool      `json:"enable_snat"`
+	ExternalFixedIPs []fixedIP `json:"external_fixed_ips"`
+}
+
+type router struct {
+	ID           string        `json:"id"`
+	Name         string        `json:"name"`
+	Description  string        `json:"description"`
+	AdminStateUp bool          `json:"admin_state_up"`
+	Status       string        `json:"status"`
+	GatewayInfo  *gatewayInfo  `json:"external_gateway_info"`
+	Routes       []interface{} `json:"routes"`
+	TenantID     string        `json:"tenant_id"`
+	ProjectID    string        `json:"project_id"`
+	Tags         []string      `json:"tags"`
+	CreatedAt    string        `json:"created_at"`
+	UpdatedAt    string        `json:"updated_at"`
+}
+
+type floatingIP struct {
+	ID                string     `json:"id"`
+	FloatingIPAddress string     `json:"floating_ip_address"`
+	FloatingNetworkID string     `json:"floating_network_id"`
+	RouterID          nullString `json:"router_id"`
+	PortID            nullString `json:"port_id"`
+	FixedIPAddress    nullString `json:"fixed_ip_address"`
+	Status            string     `json:"status"`
+	Description       string     `json:"description"`
+	TenantID          string     `json:"tenant_id"`
+	ProjectID         string     `json:"project_id"`
+	Tags              []string   `json:"tags"`
+	CreatedAt         string     `json:"created_at"`
+	UpdatedAt         string     `json:"updated_at"`
+}
+
+func (fip *floatingIP) disassociate() {
+	fip.PortID = ""
+	fip.FixedIPAddress = ""
+	fip.RouterID = ""
+	fip.Status = "DOWN"
+	fip.UpdatedAt = timestamp()
+}
+
+func (c *cloud) router(id string) (*router, error) {
+	for _, rt := range c.routers {
+		if rt.ID == id {
+			return rt, nil
+		}
+	}
+	return nil, errorf(http.StatusNotFound, "RouterNotFound", "Router %s could not be found", id)
+}
+
+func (c *cloud) floatingIP(id string) (*floatingIP, error) {
+	for _, fip := range c.floatingIPs {
+		if fip.ID == id {
+			return fip, nil
+		}
+	}
+	return nil, errorf(http.StatusNotFound, "FloatingIPNotFound", "Floating IP %s could not be found", id)
+}
+
+//routerInterfaces returns the interface ports of the router rt
+func (c *cloud) routerInterfaces(rt *router) []*port {
+	var l []*port
+	for _, p := range c.devicePorts(rt.ID) {
+		if p.DeviceOwner == ownerRouterInterface {
+			l = append(l, p)
+		}
+	}
+	return l
+}
+
+//gatewayRouter returns the router connecting the subnet identified by subnetID to the external network identified by networkID
+func (c *cloud) gatewayRouter(networkID, subnetID string) *router {
+	for _, rt := range c.routers {
+		if rt.GatewayInfo == nil || rt.GatewayInfo.NetworkID != networkID {
+			continue
+		}
+		for _, p := range c.routerInterfaces(rt) {
+			if p.hasAddressIn(subnetID) {
+				return rt
+			}
+		}
+	}
+	return nil
+}
+
+//externalNetwork returns the external network identified by id
+func (c *cloud) externalNetwork(id string, kind string) (*network, error) {
+	n, err := c.network(id)
+	if err != nil {
+		return nil, err
+	}
+	if !n.External {
+		return nil, badRequest("Bad %s request: Network %s is not a valid external network.", kind, id)
+	}
+	return n, nil
+}
+
+//setGateway sets the gateway of the router rt, a nil gateway clears it
+func (c *cloud) setGateway(rt *router, in *gatewayInfo) error {
+	if rt.GatewayInfo != nil {
+		if in != nil && in.NetworkID == rt.GatewayInfo.NetworkID {
+			rt.GatewayInfo.EnableSNAT = in.EnableSNAT
+			return nil
+		}
+		for _, fip := range c.floatingIPs {
+			if string(fip.RouterID) == rt.ID {
+				return errorf(http.StatusConflict, "RouterExternalGatewayInUseByFloatingIp", "Gateway cannot be updated for router %s, since a gateway to external network %s is required by one or more floating IPs.", rt.ID, rt.GatewayInfo.NetworkID)
+			}
+		}
+	}
+	var gw *gatewayInfo
+	var gwPort *port
+	if in != nil && in.NetworkID != "" {
+		n, err := c.externalNetwork(in.NetworkID, "router")
+		if err != nil {
+			return err
+		}
+		owner := ownerRouterGateway
+		empty := ""
+		gwPort, err = c.newPort(&portRequest{
+			NetworkID:      n.ID,
+			Name:           &empty,
+			DeviceID:       &rt.ID,
+			DeviceOwner:    &owner,
+			SecurityGroups: &[]string{},
+		})
+		if err != nil {
+			return err
+		}
+		gwPort.TenantID = ""
+		gwPort.ProjectID = ""
+		gw = &gatewayInfo{
+			NetworkID:        n.ID,
+			EnableSNAT:       in.EnableSNAT,
+			ExternalFixedIPs: gwPort.FixedIPs,
+		}
+	}
+	for _, p := range c.devicePorts(rt.ID) {
+		if p.DeviceOwner == ownerRouterGateway && p != gwPort {
+			c.removePort(p)
+		}
+	}
+	rt.GatewayInfo = gw
+	return nil
+}
+
+type routerRequest struct {
+	Name         *string         `json:"name"`
+	Description  *string         `json:"description"`
+	AdminStateUp *bool           `json:"admin_state_up"`
+	GatewayInfo  json.RawMessage `json:"external_gateway_info"`
+}
+
+func (c *cloud) updateRouter(rt *router, in *routerRequest) error {
+	if len(in.GatewayInfo) > 0 {
+		var gw *struct {
+			NetworkID  string `json:"network_id"`
+			EnableSNAT *bool  `json:"enable_snat"`
+		}
+		err := json.Unmarshal(in.GatewayInfo, &gw)
+		if err != nil {
+			return badRequest("Invalid input for external_gateway_info: %s", err.Error())
+		}
+		var info *gatewayInfo
+		if gw != nil && gw.NetworkID != "" {
+			//SNAT is enabled unless explicitly disabled
+			info = &gatewayInfo{
+				NetworkID:  gw.NetworkID,
+				EnableSNAT: gw.EnableSNAT == nil || *gw.EnableSNAT,
+			}
+		}
+		err = c.setGateway(rt, info)
+		if err != nil {
+			return err
+		}
+	}
+	if in.Name != nil {
+		rt.Name = *in.Name
+	}
+	if in.Description != nil {
+		rt.Description = *in.Description
+	}
+	if in.AdminStateUp != nil {
+		rt.AdminStateUp = *in.AdminStateUp
+	}
+	rt.UpdatedAt = timestamp()
+	return nil
+}
+
+func listRouters(c *cloud, r *request) (interface{}, error) {
+	l := []*router{}
+	for _, rt := range c.routers {
+		if matchQuery(rt, r.URL.Query()) {
+			l = append(l, rt)
+		}
+	}
+	return map[string]interface{}{"routers": l}, nil
+}
+
+func createRouter(c *cloud, r *request) (interface{}, error) {
+	in := struct {
+		Router routerRequest `json:"router"`
+	}{}
+	err := r.decode(&in)
+	if err != nil {
+		return nil, err
+	}
+	rt := &router{
+		ID:           newID(),
+		AdminStateUp: true,
+		Status:       "ACTIVE",
+		Routes:       []interface{}{},
+		TenantID:     ProjectID,
+		ProjectID:    ProjectID,
+		Tags:         []string{},
+		CreatedAt:    timestamp(),
+	}
+	err = c.updateRouter(rt, &in.Router)
+	if err != nil {
+		return nil, err
+	}
+	c.routers = append(c.routers, rt)
+	return map[string]interface{}{"router": rt}, nil
+}
+
+func getRouter(c *cloud, r *request) (interface{}, error) {
+	rt, err := c.router(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"router": rt}, nil
+}
+
+func updateRouter(c *cloud, r *request) (interface{}, error) {
+	rt, err := c.router(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	in := struct {
+		Router routerRequest `json:"router"`
+	}{}
+	err = r.decode(&in)
+	if err != nil {
+		return nil, err
+	}
+	err = c.updateRouter(rt, &in.Router)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"router": rt}, nil
+}
+
+func deleteRouter(c *cloud, r *request) (interface{}, error) {
+	rt, err := c.router(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	if len(c.routerInterfaces(rt)) > 0 {
+		return nil, errorf(http.StatusConflict, "RouterInUse", "Router %s still has ports", rt.ID)
+	}
+	err = c.setGateway(rt, nil)
+	if err != nil {
+		return nil, err
+	}
+	for i, o := range c.routers {
+		if o == rt {
+			c.routers = append(c.routers[:i], c.routers[i+1:]...)
+			break
+		}
+	}
+	return nil, nil
+}
+
+type routerInterfaceRequest struct {
+	SubnetID string `json:"subnet_id"`
+	PortID   string `json:"port_id"`
+}
+
+func routerInterfaceInfo(rt *router, p *port) interface{} {
+	ids := []string{}
+	for _, ip := range p.FixedIPs {
+		ids = append(ids, ip.SubnetID)
+	}
+	var subnetID string
+	if len(ids) > 0 {
+		subnetID = ids[0]
+	}
+	return map[string]interface{}{
+		"id":         rt.ID,
+		"tenant_id":  rt.TenantID,
+		"project_id": rt.ProjectID,
+		"port_id":    p.ID,
+		"network_id": p.NetworkID,
+		"subnet_id":  subnetID,
+		"subnet_ids": ids,
+	}
+}
+
+func addRouterInterface(c *cloud, r *request) (interface{}, error) {
+	rt, err := c.router(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	in := &routerInterfaceRequest{}
+	err = r.decode(in)
+	if err != nil {
+		return nil, err
+	}
+	if (in.SubnetID == "") == (in.PortID == "") {
+		return nil, badRequest("Bad router request: Either subnet_id or port_id must be specified.")
+	}
+	var p *port
+	if in.PortID != "" {
+		p, err = c.port(in.PortID)
+		if err != nil {
+			return nil, err
+		}
+		if p.DeviceID != "" {
+			return nil, errorf(http.StatusConflict, "PortInUse", "Unable to complete operation on port %s for network %s. Port already has an attached device %s.", p.ID, p.NetworkID, p.DeviceID)
+		}
+		if len(p.FixedIPs) == 0 {
+			return nil, badRequest("Bad router request: Router port must have at least one fixed IP.")
+		}
+	}
+	var subnets []*subnet
+	if p != nil {
+		for _, ip := range p.FixedIPs {
+			sn, err := c.subnet(ip.SubnetID)
+			if err != nil {
+				return nil, err
+			}
+			subnets = append(subnets, sn)
+		}
+	} else {
+		sn, err := c.subnet(in.SubnetID)
+		if err != nil {
+			return nil, err
+		}
+		if sn.GatewayIP == "" {
+			return nil, badRequest("Bad router request: Subnet for router interface must have a gateway IP.")
+		}
+		subnets = append(subnets, sn)
+	}
+	for _, sn := range subnets {
+		for _, ip := range c.routerInterfaces(rt) {
+			if ip.hasAddressIn(sn.ID) {
+				return nil, badRequest("Bad router request: Router already has a port on subnet %s.", sn.ID)
+			}
+			for _, addr := range ip.FixedIPs {
+				other, err := c.subnet(addr.SubnetID)
+				if err == nil && overlaps(sn.ipNet, other.ipNet) {
+					return nil, badRequest("Bad router request: Cidr %s of subnet %s overlaps with cidr %s of subnet %s.", sn.CIDR, sn.ID, other.CIDR, other.ID)
+				}
+			}
+		}
+	}
+	if p == nil {
+		sn := subnets[0]
+		for _, other := range c.ports {
+			for _, ip := range other.FixedIPs {
+				if ip.SubnetID == sn.ID && ip.IPAddress == string(sn.GatewayIP) {
+					return nil, errorf(http.StatusConflict, "IpAddressInUse", "Unable to complete operation for network %s. The IP address %s is in use.", sn.NetworkID, sn.GatewayIP)
+				}
+			}
+		}
+		p = &port{
+			ID:                  newID(),
+			NetworkID:           sn.NetworkID,
+			AdminStateUp:        true,
+			MACAddress:          macAddress(),
+			FixedIPs:            []fixedIP{{SubnetID: sn.ID, IPAddress: string(sn.GatewayIP)}},
+			SecurityGroups:      []string{},
+			AllowedAddressPairs: []addressPair{},
+			TenantID:            rt.TenantID,
+			ProjectID:           rt.ProjectID,
+			Tags:                []string{},
+			CreatedAt:           timestamp(),
+		}
+		c.ports = append(c.ports, p)
+	}
+	p.DeviceID = rt.ID
+	p.DeviceOwner = ownerRouterInterface
+	p.updateStatus()
+	p.UpdatedAt = timestamp()
+	return routerInterfaceInfo(rt, p), nil
+}
+
+func removeRouterInterface(c *cloud, r *request) (interface{}, error) {
+	rt, err := c.router(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	in := &routerInterfaceRequest{}
+	err = r.decode(in)
+	if err != nil {
+		return nil, err
+	}
+	var p *port
+	for _, ip := range c.routerInterfaces(rt) {
+		if (in.PortID != "" && ip.ID == in.PortID) || (in.PortID == "" && ip.hasAddressIn(in.SubnetID)) {
+			p = ip
+		}
+	}
+	if p == nil && in.PortID != "" {
+		return nil, errorf(http.StatusNotFound, "RouterInterfaceNotFound", "Router %s does not have an interface with id %s", rt.ID, in.PortID)
+	}
+	if p == nil {
+		return nil, errorf(http.StatusNotFound, "RouterInterfaceNotFoundForSubnet", "Router %s has no interface on subnet %s", rt.ID, in.SubnetID)
+	}
+	for _, fip := range c.floatingIPs {
+		if string(fip.RouterID) != rt.ID {
+			continue
+		}
+		fp, err := c.port(string(fip.PortID))
+		if err != nil {
+			continue
+		}
+		for _, ip := range p.FixedIPs {
+			if fp.hasAddressIn(ip.SubnetID) {
+				return nil, errorf(http.StatusConflict, "RouterInterfaceInUseByFloatingIP", "Router interface for subnet %s on router %s cannot be deleted, as it is required by one or more floating IPs.", ip.SubnetID, rt.ID)
+			}
+		}
+	}
+	info := routerInterfaceInfo(rt, p)
+	c.removePort(p)
+	return info, nil
+}
+
+//associate associates the floating IP fip with the port identified by portID
+func (c *cloud) associate(fip *floatingIP, portID string, fixedIPAddress string) error {
+	p, err := c.port(portID)
+	if err != nil {
+		return err
+	}
+	if len(p.FixedIPs) == 0 {
+		return badRequest("Bad floatingip request: Port %s does not have any IP addresses on it.", p.ID)
+	}
+	var fixed *fixedIP
+	for i, ip := range p.FixedIPs {
+		if (fixedIPAddress == "" && net.ParseIP(ip.IPAddress).To4() != nil) || ip.IPAddress == fixedIPAddress {
+			fixed = &p.FixedIPs[i]
+			break
+		}
+	}
+	if fixed == nil {
+		return badRequest("Bad floatingip request: Port %s does not have fixed ip %s.", p.ID, fixedIPAddress)
+	}
+	rt := c.gatewayRouter(fip.FloatingNetworkID, fixed.SubnetID)
+	if rt == nil {
+		return errorf(http.StatusNotFound, "ExternalGatewayForFloatingIPNotFound", "External network %s is not reachable from subnet %s.  Therefore, cannot associate Port %s with a Floating IP.", fip.FloatingNetworkID, fixed.SubnetID, p.ID)
+	}
+	for _, other := range c.floatingIPs {
+		if other != fip && string(other.PortID) == p.ID && string(other.FixedIPAddress) == fixed.IPAddress {
+			return errorf(http.StatusConflict, "FloatingIPPortAlreadyAssociated", "Cannot associate floating IP %s (%s) with port %s using fixed IP %s, as that fixed IP already has a floating IP on external network %s.", fip.FloatingIPAddress, fip.ID, p.ID, fixed.IPAddress, fip.FloatingNetworkID)
+		}
+	}
+	fip.PortID = nullString(p.ID)
+	fip.FixedIPAddress = nullString(fixed.IPAddress)
+	fip.RouterID = nullString(rt.ID)
+	fip.Status = "ACTIVE"
+	fip.UpdatedAt = timestamp()
+	return nil
+}
+
+func listFloatingIPs(c *cloud, r *request) (interface{}, error) {
+	l := []*floatingIP{}
+	for _, fip := range c.floatingIPs {
+		if matchQuery(fip, r.URL.Query()) {
+			l = append(l, fip)
+		}
+	}
+	return map[string]interface{}{"floatingips": l}, nil
+}
+
+func createFloatingIP(c *cloud, r *request) (interface{}, error) {
+	in := struct {
+		FloatingIP struct {
+			FloatingNetworkID string `json:"floating_network_id"`
+			FloatingIPAddress string `json:"floating_ip_address"`
+			SubnetID          string `json:"subnet_id"`
+			PortID            string `json:"port_id"`
+			FixedIPAddress    string `json:"fixed_ip_address"`
+			Description       string `json:"description"`
+		} `json:"floatingip"`
+	}{}
+	err := r.decode(&in)
+	if err != nil {
+		return nil, err
+	}
+	req := &in.FloatingIP
+	n, err := c.externalNetwork(req.FloatingNetworkID, "floatingip")
+	if err != nil {
+		return nil, err
+	}
+	var address string
+	for _, sn := range c.networkSubnets(n) {
+		if req.SubnetID != "" && sn.ID != req.SubnetID {
+			continue
+		}
+		if req.FloatingIPAddress != "" {
+			if !sn.ipNet.Contains(net.ParseIP(req.FloatingIPAddress)) {
+				continue
+			}
+			err = c.checkAddress(sn, req.FloatingIPAddress, nil)
+			if err != nil {
+				return nil, err
+			}
+			address = req.FloatingIPAddress
+			break
+		}
+		if sn.IPVersion != 4 {
+			continue
+		}
+		address, err = c.allocateAddress(sn, nil)
+		if err == nil {
+			break
+		}
+	}
+	if address == "" && req.FloatingIPAddress != "" {
+		return nil, badRequest("Invalid input for operation: IP address %s is not a valid IP for any of the subnets on the specified network.", req.FloatingIPAddress)
+	}
+	if address == "" {
+		return nil, errorf(http.StatusConflict, "IpAddressGenerationFailure", "No more IP addresses available on network %s.", n.ID)
+	}
+	fip := &floatingIP{
+		ID:                newID(),
+		FloatingIPAddress: address,
+		FloatingNetworkID: n.ID,
+		Status:            "DOWN",
+		Description:       req.Description,
+		TenantID:          ProjectID,
+		ProjectID:         ProjectID,
+		Tags:              []string{},
+		CreatedAt:         timestamp(),
+		UpdatedAt:         timestamp(),
+	}
+	if req.PortID != "" {
+		err = c.associate(fip, req.PortID, req.FixedIPAddress)
+		if err != nil {
+			return nil, err
+		}
+	}
+	c.floatingIPs = append(c.floatingIPs, fip)
+	return map[string]interface{}{"floatingip": fip}, nil
+}
+
+func getFloatingIP(c *cloud, r *request) (interface{}, error) {
+	fip, err := c.floatingIP(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"floatingip": fip}, nil
+}
+
+func updateFloatingIP(c *cloud, r *request) (interface{}, error) {
+	fip, err := c.floatingIP(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	//port_id is read as a raw message to distinguish a null port, which disassociates the floating IP, from a missing one
+	in := struct {
+		FloatingIP struct {
+			PortID         json.RawMessage `json:"port_id"`
+			FixedIPAddress string          `json:"fixed_ip_address"`
+			Description    *string         `json:"description"`
+		} `json:"floatingip"`
+	}{}
+	err = r.decode(&in)
+	if err != nil {
+		return nil, err
+	}
+	req := &in.FloatingIP
+	if len(req.PortID) > 0 {
+		var portID *string
+		err = json.Unmarshal(req.PortID, &portID)
+		if err != nil {
+			return nil, badRequest("Invalid input for port_id: %s", err.Error())
+		}
+		if portID == nil || *portID == "" {
+			fip.disassociate()
+		} else {
+			err = c.associate(fip, *portID, req.FixedIPAddress)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	if req.Description != nil {
+		fip.Description = *req.Description
+	}
+	return map[string]interface{}{"floatingip": fip}, nil
+}
+
+func deleteFloatingIP(c *cloud, r *request) (interface{}, error) {
+	fip, err := c.floatingIP(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	for i, o := range c.floatingIPs {
+		if o == fip {
+			c.floatingIPs = append(c.floatingIPs[:i], c.floatingIPs[i+1:]...)
+			break
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,392 @@
+package fake
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type securityGroupRule struct {
+	ID              string     `json:"id"`
+	SecurityGroupID string     `json:"security_group_id"`
+	Direction       string     `json:"direction"`
+	EtherType       string     `json:"ethertype"`
+	Protocol        nullString `json:"protocol"`
+	PortRangeMin    *int       `json:"port_range_min"`
+	PortRangeMax    *int       `json:"port_range_max"`
+	RemoteIPPrefix  nullString `json:"remote_ip_prefix"`
+	RemoteGroupID   nullString `json:"remote_group_id"`
+	Description     string     `json:"description"`
+	TenantID        string     `json:"tenant_id"`
+	ProjectID       string     `json:"project_id"`
+	CreatedAt       string     `json:"created_at"`
+	UpdatedAt       string     `json:"updated_at"`
+}
+
+//sameAs returns true if r and o match the same traffic
+func (r *securityGroupRule) sameAs(o *securityGroupRule) bool {
+	intValue := func(i *int) string {
+		if i == nil {
+			return ""
+		}
+		return strconv.Itoa(*i)
+	}
+	return r.Direction == o.Direction && r.EtherType == o.EtherType && r.Protocol == o.Protocol &&
+		intValue(r.PortRangeMin) == intValue(o.PortRangeMin) && intValue(r.PortRangeMax) == intValue(o.PortRangeMax) &&
+		r.RemoteIPPrefix == o.RemoteIPPrefix && r.RemoteGroupID == o.RemoteGroupID
+}
+
+type securityGroup struct {
+	ID          string               `json:"id"`
+	Name        string               `json:"name"`
+	Description string               `json:"description"`
+	Rules       []*securityGroupRule `json:"security_group_rules"`
+	TenantID    string               `json:"tenant_id"`
+	ProjectID   string               `json:"project_id"`
+	Tags        []string             `json:"tags"`
+	CreatedAt   string               `json:"created_at"`
+	UpdatedAt   string               `json:"updated_at"`
+}
+
+func (sg *securityGroup) addRule(direction, etherType string, remoteGroupID string) {
+	sg.Rules = append(sg.Rules, &securityGroupRule{
+		ID:              newID(),
+		SecurityGroupID: sg.ID,
+		Direction:       direction,
+		EtherType:       etherType,
+		RemoteGroupID:   nullString(remoteGroupID),
+		TenantID:        sg.TenantID,
+		ProjectID:       sg.ProjectID,
+		CreatedAt:       sg.CreatedAt,
+		UpdatedAt:       sg.CreatedAt,
+	})
+}
+
+func (c *cloud) newSecurityGroup(name, description string) *securityGroup {
+	sg := &securityGroup{
+		ID:          newID(),
+		Name:        name,
+		Description: description,
+		Rules:       []*securityGroupRule{},
+		TenantID:    ProjectID,
+		ProjectID:   ProjectID,
+		Tags:        []string{},
+		CreatedAt:   timestamp(),
+		UpdatedAt:   timestamp(),
+	}
+	//as Neutron does, egress traffic is allowed by default
+	sg.addRule("egress", "IPv4", "")
+	sg.addRule("egress", "IPv6", "")
+	c.securityGroups = append(c.securityGroups, sg)
+	return sg
+}
+
+//createDefaultSecurityGroup creates the default security group of the project, it allows ingress traffic between its members
+func (c *cloud) createDefaultSecurityGroup() {
+	sg := c.newSecurityGroup("default", "Default security group")
+	sg.addRule("ingress", "IPv4", sg.ID)
+	sg.addRule("ingress", "IPv6", sg.ID)
+}
+
+func (c *cloud) defaultSecurityGroup() *securityGroup {
+	for _, sg := range c.securityGroups {
+		if sg.Name == "default" {
+			return sg
+		}
+	}
+	return nil
+}
+
+func (c *cloud) securityGroup(id string) (*securityGroup, error) {
+	for _, sg := range c.securityGroups {
+		if sg.ID == id {
+			return sg, nil
+		}
+	}
+	return nil, errorf(http.StatusNotFound, "SecurityGroupNotFound", "Security group %s does not exist", id)
+}
+
+func (c *cloud) securityGroupRule(id string) (*securityGroup, int, error) {
+	for _, sg := range c.securityGroups {
+		for i, r := range sg.Rules {
+			if r.ID == id {
+				return sg, i, nil
+			}
+		}
+	}
+	return nil, 0, errorf(http.StatusNotFound, "SecurityGroupRuleNotFound", "Security group rule %s does not exist", id)
+}
+
+func listSecurityGroups(c *cloud, r *request) (interface{}, error) {
+	l := []*securityGroup{}
+	for _, sg := range c.securityGroups {
+		if matchQuery(sg, r.URL.Query()) {
+			l = append(l, sg)
+		}
+	}
+	return map[string]interface{}{"security_groups": l}, nil
+}
+
+type securityGroupRequest struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+}
+
+func createSecurityGroup(c *cloud, r *request) (interface{}, error) {
+	in := struct {
+		SecurityGroup securityGroupRequest `json:"security_group"`
+	}{}
+	err := r.decode(&in)
+	if err != nil {
+		return nil, err
+	}
+	var name, description string
+	if in.SecurityGroup.Name != nil {
+		name = *in.SecurityGroup.Name
+	}
+	if in.SecurityGroup.Description != nil {
+		description = *in.SecurityGroup.Description
+	}
+	if name == "default" {
+		return nil, errorf(http.StatusConflict, "SecurityGroupDefaultAlreadyExists", "Default security group already exists.")
+	}
+	sg := c.newSecurityGroup(name, description)
+	return map[string]interface{}{"security_group": sg}, nil
+}
+
+func getSecurityGroup(c *cloud, r *request) (interface{}, error) {
+	sg, err := c.securityGroup(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"security_group": sg}, nil
+}
+
+func updateSecurityGroup(c *cloud, r *request) (interface{}, error) {
+	sg, err := c.securityGroup(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	in := struct {
+		SecurityGroup securityGroupRequest `json:"security_group"`
+	}{}
+	err = r.decode(&in)
+	if err != nil {
+		return nil, err
+	}
+	if name := in.SecurityGroup.Name; name != nil && *name != sg.Name {
+		if sg.Name == "default" || *name == "default" {
+			return nil, errorf(http.StatusConflict, "SecurityGroupCannotUpdateDefault", "Updating default security group not allowed.")
+		}
+		sg.Name = *name
+	}
+	if in.SecurityGroup.Description != nil {
+		sg.Description = *in.SecurityGroup.Description
+	}
+	sg.UpdatedAt = timestamp()
+	return map[string]interface{}{"security_group": sg}, nil
+}
+
+func deleteSecurityGroup(c *cloud, r *request) (interface{}, error) {
+	sg, err := c.securityGroup(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	if sg.Name == "default" {
+		return nil, errorf(http.StatusConflict, "SecurityGroupCannotRemoveDefault", "Insufficient rights for removing default security group.")
+	}
+	for _, p := range c.ports {
+		for _, id := range p.SecurityGroups {
+			if id == sg.ID {
+				return nil, errorf(http.StatusConflict, "SecurityGroupInUse", "Security Group %s in use.", sg.ID)
+			}
+		}
+	}
+	for i, o := range c.securityGroups {
+		if o == sg {
+			c.securityGroups = append(c.securityGroups[:i], c.securityGroups[i+1:]...)
+			break
+		}
+	}
+	//rules of the other groups referencing the deleted group are deleted as well
+	for _, o := range c.securityGroups {
+		rules := []*securityGroupRule{}
+		for _, rule := range o.Rules {
+			if string(rule.RemoteGroupID) != sg.ID {
+				rules = append(rules, rule)
+			}
+		}
+		o.Rules = rules
+	}
+	return nil, nil
+}
+
+func listSecurityGroupRules(c *cloud, r *request) (interface{}, error) {
+	l := []*securityGroupRule{}
+	for _, sg := range c.securityGroups {
+		for _, rule := range sg.Rules {
+			if matchQuery(rule, r.URL.Query()) {
+				l = append(l, rule)
+			}
+		}
+	}
+	return map[string]interface{}{"security_group_rules": l}, nil
+}
+
+//protocolName returns the normalized name of the protocol p, given by name or by number
+func protocolName(p interface{}) (string, error) {
+	switch t := p.(type) {
+	case nil:
+		return "", nil
+	case float64:
+		if t < 0 || t > 255 || t != float64(int(t)) {
+			return "", badRequest("Security group rule protocol %v not supported. Only protocol values [None, tcp, udp, icmp, icmpv6] and integer representations [0 to 255] are supported.", t)
+		}
+		return strconv.Itoa(int(t)), nil
+	case string:
+		name := strings.ToLower(t)
+		switch name {
+		case "", "tcp", "udp", "icmp", "icmpv6", "ipv6-icmp":
+			return name, nil
+		}
+		if n, err := strconv.Atoi(name); err == nil && n >= 0 && n <= 255 {
+			return name, nil
+		}
+	}
+	return "", badRequest("Security group rule protocol %v not supported. Only protocol values [None, tcp, udp, icmp, icmpv6] and integer representations [0 to 255] are supported.", p)
+}
+
+//checkPortRange checks the port range of a rule the way Neutron does, for ICMP rules the range holds the ICMP type and code
+func checkPortRange(protocol string, min, max *int) error {
+	if min == nil && max == nil {
+		return nil
+	}
+	switch protocol {
+	case "":
+		return badRequest("Must also specify protocol if port range is given.")
+	case "tcp", "udp", "6", "17":
+		if min != nil && max != nil && *min > *max {
+			return badRequest("For TCP/UDP protocols, port_range_min must be <= port_range_max")
+		}
+		for _, p := range []*int{min, max} {
+			if p != nil && (*p < 0 || *p > 65535) {
+				return badRequest("Invalid value for port %d.", *p)
+			}
+		}
+	case "icmp", "icmpv6", "ipv6-icmp", "1", "58":
+		for _, f := range []struct {
+			value *int
+			field string
+			attr  string
+		}{{min, "type", "port_range_min"}, {max, "code", "port_range_max"}} {
+			if f.value != nil && (*f.value < 0 || *f.value > 255) {
+				return badRequest("Invalid value for ICMP %s (%s) %d. It must be 0 to 255.", f.field, f.attr, *f.value)
+			}
+		}
+		if min == nil {
+			return badRequest("ICMP code (port-range-max) %d is provided but ICMP type (port-range-min) is missing.", *max)
+		}
+	}
+	return nil
+}
+
+func createSecurityGroupRule(c *cloud, r *request) (interface{}, error) {
+	in := struct {
+		Rule struct {
+			SecurityGroupID string      `json:"security_group_id"`
+			Direction       string      `json:"direction"`
+			EtherType       string      `json:"ethertype"`
+			Protocol        interface{} `json:"protocol"`
+			PortRangeMin    *int        `json:"port_range_min"`
+			PortRangeMax    *int        `json:"port_range_max"`
+			RemoteIPPrefix  string      `json:"remote_ip_prefix"`
+			RemoteGroupID   string      `json:"remote_group_id"`
+			Description     string      `json:"description"`
+		} `json:"security_group_rule"`
+	}{}
+	err := r.decode(&in)
+	if err != nil {
+		return nil, err
+	}
+	req := &in.Rule
+	sg, err := c.securityGroup(req.SecurityGroupID)
+	if err != nil {
+		return nil, err
+	}
+	if req.Direction != "ingress" && req.Direction != "egress" {
+		return nil, badRequest("Invalid input for direction. Reason: '%s' is not in ['ingress', 'egress'].", req.Direction)
+	}
+	if req.EtherType == "" {
+		req.EtherType = "IPv4"
+	}
+	if req.EtherType != "IPv4" && req.EtherType != "IPv6" {
+		return nil, badRequest("Invalid input for ethertype. Reason: '%s' is not in ['IPv4', 'IPv6'].", req.EtherType)
+	}
+	protocol, err := protocolName(req.Protocol)
+	if err != nil {
+		return nil, err
+	}
+	err = checkPortRange(protocol, req.PortRangeMin, req.PortRangeMax)
+	if err != nil {
+		return nil, err
+	}
+	if req.RemoteIPPrefix != "" && req.RemoteGroupID != "" {
+		return nil, badRequest("Only remote_ip_prefix or remote_group_id may be provided.")
+	}
+	if req.RemoteIPPrefix != "" {
+		n, err := parseCIDR(req.RemoteIPPrefix)
+		if err != nil {
+			return nil, err
+		}
+		if (n.IP.To4() != nil) != (req.EtherType == "IPv4") {
+			return nil, badRequest("Conflicting value ethertype %s for CIDR %s", req.EtherType, req.RemoteIPPrefix)
+		}
+		req.RemoteIPPrefix = n.String()
+	}
+	if req.RemoteGroupID != "" {
+		if _, err := c.securityGroup(req.RemoteGroupID); err != nil {
+			return nil, err
+		}
+	}
+	rule := &securityGroupRule{
+		ID:              newID(),
+		SecurityGroupID: sg.ID,
+		Direction:       req.Direction,
+		EtherType:       req.EtherType,
+		Protocol:        nullString(protocol),
+		PortRangeMin:    req.PortRangeMin,
+		PortRangeMax:    req.PortRangeMax,
+		RemoteIPPrefix:  nullString(req.RemoteIPPrefix),
+		RemoteGroupID:   nullString(req.RemoteGroupID),
+		Description:     req.Description,
+		TenantID:        sg.TenantID,
+		ProjectID:       sg.ProjectID,
+		CreatedAt:       timestamp(),
+		UpdatedAt:       timestamp(),
+	}
+	for _, o := range sg.Rules {
+		if o.sameAs(rule) {
+			return nil, errorf(http.StatusConflict, "SecurityGroupRuleExists", "Security group rule already exists. Rule id is %s.", o.ID)
+		}
+	}
+	sg.Rules = append(sg.Rules, rule)
+	return map[string]interface{}{"security_group_rule": rule}, nil
+}
+
+func getSecurityGroupRule(c *cloud, r *request) (interface{}, error) {
+	sg, i, err := c.securityGroupRule(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"security_group_rule": sg.Rules[i]}, nil
+}
+
+func deleteSecurityGroupRule(c *cloud, r *request) (interface{}, error) {
+	sg, i, err := c.securityGroupRule(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	sg.Rules = append(sg.Rules[:i], sg.Rules[i+1:]...)
+	sg.UpdatedAt = timestamp()
+	return nil, nil
+}
@@ -0,0 +1,305 @@
+//Package fake implements an in process fake of the Keystone, Nova, Neutron, Cinder, Octavia, Designate and Swift APIs used by the openstack provider
+//It allows the openstack provider to be tested without an OpenStack cloud
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+const (
+	//Region region simulated by the fake
+	Region = "RegionOne"
+	//AvailabilityZone single availability zone of the region
+	AvailabilityZone = "nova"
+	//DomainName domain of the user and of the project
+	DomainName = "Default"
+	//ProjectName name of the project owning the resources
+	ProjectName = "demo"
+	//ProjectID identifier of the project owning the resources
+	ProjectID = "8f8e6b2fd3534dd6a2d2b3bd7d2b5c1e"
+	//UserName name of the user allowed to authenticate
+	UserName = "demo"
+	//Password password of the user allowed to authenticate
+	Password = "secret"
+	//UserID identifier of the user allowed to authenticate
+	UserID = "2a6b1ec1d3a04ab8a4a4bf3a0f7c8d21"
+	//ExternalNetworkName name of the external network providing floating IPs
+	ExternalNetworkName = "public"
+)
+
+//Server fake OpenStack cloud exposing the identity, compute, network, volume, load balancer, DNS and object storage services
+//All the resources are created in their final state (active servers, available volumes, ...) so that the provider waits succeed at their first attempt
+type Server struct {
+	//URL base URL of the server, the identity endpoint is URL/identity/v3
+	URL string
+
+	server   *httptest.Server
+	lock     sync.Mutex
+	services []*service
+	cloud    *cloud
+}
+
+//NewServer starts a fake OpenStack cloud
+func NewServer() *Server {
+	s := &Server{}
+	s.server = httptest.NewServer(s)
+	s.URL = s.server.URL
+	s.cloud = newCloud(s.URL)
+	s.services = []*service{
+		identityService(s.cloud),
+		computeService(s.cloud),
+		networkService(s.cloud),
+		volumeService(s.cloud),
+		loadBalancerService(s.cloud),
+		dnsService(s.cloud),
+	}
+	return s
+}
+
+//Close shuts down the server
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+//Config returns a JSON configuration of the openstack provider targeting the server
+func (s *Server) Config() string {
+	cfg, _ := json.Marshal(map[string]string{
+		"IdentityEndpoint":             s.URL + "/identity/v3",
+		"Username":                     UserName,
+		"Password":                     Password,
+		"DomainName":                   DomainName,
+		"TenantName":                   ProjectName,
+		"Region":                       Region,
+		"ExternalNetworkName":          ExternalNetworkName,
+		"ServerGroupReconcileInterval": "1s",
+	})
+	return string(cfg)
+}
+
+//ServeHTTP dispatches the requests to the service whose prefix matches the request path
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	//Swift addresses objects by paths of any depth and returns raw content, it is not served by routes
+	if strings.HasPrefix(r.URL.Path, objectStoragePrefix+"/") {
+		s.serveObjectStorage(w, r)
+		return
+	}
+	for _, svc := range s.services {
+		if r.URL.Path == svc.prefix || strings.HasPrefix(r.URL.Path, svc.prefix+"/") {
+			svc.serve(s.cloud, w, r)
+			return
+		}
+	}
+	http.NotFound(w, r)
+}
+
+//apiError error returned by the fake services
+type apiError struct {
+	status  int
+	kind    string
+	message string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("%s: %s", e.kind, e.message)
+}
+
+func errorf(status int, kind string, format string, args ...interface{}) error {
+	return &apiError{
+		status:  status,
+		kind:    kind,
+		message: fmt.Sprintf(format, args...),
+	}
+}
+
+func badRequest(format string, args ...interface{}) error {
+	return errorf(http.StatusBadRequest, "BadRequest", format, args...)
+}
+
+func notFound(format string, args ...interface{}) error {
+	return errorf(http.StatusNotFound, "NotFound", format, args...)
+}
+
+func conflict(format string, args ...interface{}) error {
+	return errorf(http.StatusConflict, "Conflict", format, args...)
+}
+
+func toAPIError(err error) *apiError {
+	if e, ok := err.(*apiError); ok {
+		return e
+	}
+	return &apiError{
+		status:  http.StatusInternalServerError,
+		kind:    "InternalServerError",
+		message: err.Error(),
+	}
+}
+
+//request request received by a route handler
+type request struct {
+	*http.Request
+	//params values of the wildcard segments of the route path
+	params []string
+	body   []byte
+}
+
+//decode decodes the JSON body of the request into v
+func (r *request) decode(v interface{}) error {
+	err := json.Unmarshal(r.body, v)
+	if err != nil {
+		return badRequest("malformed request body: %s", err.Error())
+	}
+	return nil
+}
+
+//handler handles a request and returns the response body, a nil body produces an empty response
+type handler func(c *cloud, r *request) (interface{}, error)
+
+//route associates a method and a path to a handler, "*" path segments match any value
+type route struct {
+	method  string
+	path    string
+	status  int
+	handler handler
+}
+
+//match returns the values of the wildcard segments if the route path matches segments
+func (rt *route) match(segments []string) ([]string, bool) {
+	path := strings.Split(rt.path, "/")
+	if rt.path == "" {
+		path = nil
+	}
+	if len(path) != len(segments) {
+		return nil, false
+	}
+	var params []string
+	for i, p := range path {
+		if p == "*" {
+			params = append(params, segments[i])
+		} else if p != segments[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+//service OpenStack service served under prefix
+type service struct {
+	prefix string
+	//authenticated true if requests must carry a valid token
+	authenticated bool
+	routes        []route
+	//errorBody formats the error e the way the service does
+	errorBody func(e *apiError) interface{}
+}
+
+func (svc *service) serve(c *cloud, w http.ResponseWriter, r *http.Request) {
+	if svc.authenticated && !c.tokens[r.Header.Get("X-Auth-Token")] {
+		svc.writeError(w, errorf(http.StatusUnauthorized, "Unauthorized", "The request you have made requires authentication."))
+		return
+	}
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, svc.prefix), "/")
+	var segments []string
+	if path != "" {
+		segments = strings.Split(path, "/")
+	}
+	var allowed bool
+	for i := range svc.routes {
+		rt := &svc.routes[i]
+		params, ok := rt.match(segments)
+		if !ok {
+			continue
+		}
+		if rt.method != r.Method {
+			allowed = true
+			continue
+		}
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			svc.writeError(w, badRequest("%s", err.Error()))
+			return
+		}
+		res, err := rt.handler(c, &request{Request: r, params: params, body: body})
+		if err != nil {
+			svc.writeError(w, err)
+			return
+		}
+		svc.writeResponse(w, rt.status, res)
+		return
+	}
+	if allowed {
+		svc.writeError(w, errorf(http.StatusMethodNotAllowed, "HTTPMethodNotAllowed", "method %s is not allowed on %s", r.Method, r.URL.Path))
+		return
+	}
+	svc.writeError(w, notFound("The resource could not be found."))
+}
+
+func (svc *service) writeResponse(w http.ResponseWriter, status int, res interface{}) {
+	w.Header().Set("X-Openstack-Request-Id", "req-"+uuid.New().String())
+	if res == nil {
+		w.WriteHeader(status)
+		return
+	}
+	if h, ok := res.(*headerResponse); ok {
+		for k, v := range h.header {
+			w.Header().Set(k, v)
+		}
+		res = h.body
+	}
+	b, err := json.Marshal(res)
+	if err != nil {
+		svc.writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(b)
+}
+
+func (svc *service) writeError(w http.ResponseWriter, err error) {
+	e := toAPIError(err)
+	b, _ := json.Marshal(svc.errorBody(e))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(e.status)
+	_, _ = w.Write(b)
+}
+
+//headerResponse response body sent along with additional headers
+type headerResponse struct {
+	header map[string]string
+	body   interface{}
+}
+
+//computeError formats errors the way Nova and Cinder do
+func computeError(e *apiError) interface{} {
+	var kind string
+	switch e.status {
+	case http.StatusBadRequest:
+		kind = "badRequest"
+	case http.StatusUnauthorized, http.StatusForbidden:
+		kind = "forbidden"
+	case http.StatusNotFound:
+		kind = "itemNotFound"
+	case http.StatusConflict:
+		kind = "conflictingRequest"
+	case http.StatusRequestEntityTooLarge:
+		kind = "overLimit"
+	default:
+		kind = "computeFault"
+	}
+	return map[string]interface{}{
+		kind: map[string]interface{}{
+			"code":    e.status,
+			"message": e.message,
+		},
+	}
+}
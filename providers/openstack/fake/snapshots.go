@@ -0,0 +1,117 @@
+package fake
+
+type snapshot struct {
+	ID          string
+	Name        string
+	Description string
+	VolumeID    string
+	Size        int
+	Status      string
+	Metadata    map[string]string
+	Created     string
+	Updated     string
+}
+
+func (c *cloud) snapshot(id string) (*snapshot, error) {
+	for _, s := range c.snapshots {
+		if s.ID == id {
+			return s, nil
+		}
+	}
+	return nil, notFound("Snapshot %s could not be found.", id)
+}
+
+func snapshotView(s *snapshot) map[string]interface{} {
+	return map[string]interface{}{
+		"id":          s.ID,
+		"name":        s.Name,
+		"description": s.Description,
+		"volume_id":   s.VolumeID,
+		"size":        s.Size,
+		"status":      s.Status,
+		"metadata":    s.Metadata,
+		"created_at":  s.Created,
+		"updated_at":  s.Updated,
+	}
+}
+
+func listSnapshots(c *cloud, r *request) (interface{}, error) {
+	query := r.URL.Query()
+	l := []interface{}{}
+	for _, s := range c.snapshots {
+		if name := query.Get("name"); name != "" && name != s.Name {
+			continue
+		}
+		if volumeID := query.Get("volume_id"); volumeID != "" && volumeID != s.VolumeID {
+			continue
+		}
+		if status := query.Get("status"); status != "" && status != s.Status {
+			continue
+		}
+		l = append(l, snapshotView(s))
+	}
+	return map[string]interface{}{"snapshots": l}, nil
+}
+
+//createSnapshot creates a snapshot of a volume, the snapshot is available immediately
+func createSnapshot(c *cloud, r *request) (interface{}, error) {
+	in := struct {
+		Snapshot struct {
+			VolumeID    string            `json:"volume_id"`
+			Force       bool              `json:"force"`
+			Name        string            `json:"name"`
+			Description string            `json:"description"`
+			Metadata    map[string]string `json:"metadata"`
+		} `json:"snapshot"`
+	}{}
+	err := r.decode(&in)
+	if err != nil {
+		return nil, err
+	}
+	v, err := c.volume(in.Snapshot.VolumeID)
+	if err != nil {
+		return nil, err
+	}
+	if v.Status != "available" && !(v.Status == "in-use" && in.Snapshot.Force) {
+		return nil, badRequest("Invalid volume: Volume %s status must be available, but current status is: %s.", v.ID, v.Status)
+	}
+	metadata := in.Snapshot.Metadata
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+	s := &snapshot{
+		ID:          newID(),
+		Name:        in.Snapshot.Name,
+		Description: in.Snapshot.Description,
+		VolumeID:    v.ID,
+		Size:        v.Size,
+		Status:      "available",
+		Metadata:    metadata,
+		Created:     cinderTimestamp(),
+		Updated:     cinderTimestamp(),
+	}
+	c.snapshots = append(c.snapshots, s)
+	return map[string]interface{}{"snapshot": snapshotView(s)}, nil
+}
+
+func getSnapshot(c *cloud, r *request) (interface{}, error) {
+	s, err := c.snapshot(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"snapshot": snapshotView(s)}, nil
+}
+
+func deleteSnapshot(c *cloud, r *request) (interface{}, error) {
+	s, err := c.snapshot(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	for i, o := range c.snapshots {
+		if o == s {
+			c.snapshots = append(c.snapshots[:i], c.snapshots[i+1:]...)
+			break
+		}
+	}
+	return nil, nil
+}
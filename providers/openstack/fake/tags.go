@@ -0,0 +1,149 @@
+package fake
+
+import (
+	"net/http"
+	"sort"
+)
+
+//tagRoutes returns the routes of the Neutron tag extension for the resources of type resourceType
+func tagRoutes(resourceType string) []route {
+	tagsOf := func(c *cloud, r *request) (*[]string, error) {
+		return c.tags(resourceType, r.params[0])
+	}
+	return []route{
+		{"GET", resourceType + "/*/tags", http.StatusOK, func(c *cloud, r *request) (interface{}, error) {
+			tags, err := tagsOf(c, r)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{"tags": *tags}, nil
+		}},
+		{"PUT", resourceType + "/*/tags", http.StatusOK, func(c *cloud, r *request) (interface{}, error) {
+			tags, err := tagsOf(c, r)
+			if err != nil {
+				return nil, err
+			}
+			in := struct {
+				Tags []string `json:"tags"`
+			}{}
+			err = r.decode(&in)
+			if err != nil {
+				return nil, err
+			}
+			if in.Tags == nil {
+				return nil, badRequest("Invalid input for tags. Reason: 'None' is not a list.")
+			}
+			*tags = uniqueTags(in.Tags)
+			return map[string]interface{}{"tags": *tags}, nil
+		}},
+		{"DELETE", resourceType + "/*/tags", http.StatusNoContent, func(c *cloud, r *request) (interface{}, error) {
+			tags, err := tagsOf(c, r)
+			if err != nil {
+				return nil, err
+			}
+			*tags = []string{}
+			return nil, nil
+		}},
+		{"GET", resourceType + "/*/tags/*", http.StatusNoContent, func(c *cloud, r *request) (interface{}, error) {
+			tags, err := tagsOf(c, r)
+			if err != nil {
+				return nil, err
+			}
+			for _, t := range *tags {
+				if t == r.params[1] {
+					return nil, nil
+				}
+			}
+			return nil, errorf(http.StatusNotFound, "TagNotFound", "Tag %s could not be found.", r.params[1])
+		}},
+		{"PUT", resourceType + "/*/tags/*", http.StatusCreated, func(c *cloud, r *request) (interface{}, error) {
+			tags, err := tagsOf(c, r)
+			if err != nil {
+				return nil, err
+			}
+			*tags = uniqueTags(append(*tags, r.params[1]))
+			return nil, nil
+		}},
+		{"DELETE", resourceType + "/*/tags/*", http.StatusNoContent, func(c *cloud, r *request) (interface{}, error) {
+			tags, err := tagsOf(c, r)
+			if err != nil {
+				return nil, err
+			}
+			res := []string{}
+			found := false
+			for _, t := range *tags {
+				if t == r.params[1] {
+					found = true
+				} else {
+					res = append(res, t)
+				}
+			}
+			if !found {
+				return nil, errorf(http.StatusNotFound, "TagNotFound", "Tag %s could not be found.", r.params[1])
+			}
+			*tags = res
+			return nil, nil
+		}},
+	}
+}
+
+//uniqueTags returns the sorted tags without duplicates
+func uniqueTags(tags []string) []string {
+	set := map[string]bool{}
+	res := []string{}
+	for _, t := range tags {
+		if !set[t] {
+			set[t] = true
+			res = append(res, t)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+//tags returns the tags of the Neutron resource of type resourceType identified by id
+func (c *cloud) tags(resourceType, id string) (*[]string, error) {
+	var tags *[]string
+	var err error
+	switch resourceType {
+	case "networks":
+		var n *network
+		if n, err = c.network(id); err == nil {
+			tags = &n.Tags
+		}
+	case "subnets":
+		var sn *subnet
+		if sn, err = c.subnet(id); err == nil {
+			tags = &sn.Tags
+		}
+	case "ports":
+		var p *port
+		if p, err = c.port(id); err == nil {
+			tags = &p.Tags
+		}
+	case "routers":
+		var rt *router
+		if rt, err = c.router(id); err == nil {
+			tags = &rt.Tags
+		}
+	case "floatingips":
+		var fip *floatingIP
+		if fip, err = c.floatingIP(id); err == nil {
+			tags = &fip.Tags
+		}
+	case "security-groups":
+		var sg *securityGroup
+		if sg, err = c.securityGroup(id); err == nil {
+			tags = &sg.Tags
+		}
+	default:
+		return nil, notFound("The resource could not be found.")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if *tags == nil {
+		*tags = []string{}
+	}
+	return tags, nil
+}
@@ -0,0 +1,318 @@
+package fake
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+func volumeService(c *cloud) *service {
+	return &service{
+		prefix:        "/volume/v3/" + ProjectID,
+		authenticated: true,
+		routes: []route{
+			{"GET", "volumes", http.StatusOK, listVolumes},
+			{"POST", "volumes", http.StatusAccepted, createVolume},
+			{"GET", "volumes/detail", http.StatusOK, listVolumesDetail},
+			{"GET", "volumes/*", http.StatusOK, getVolume},
+			{"PUT", "volumes/*", http.StatusOK, updateVolume},
+			{"DELETE", "volumes/*", http.StatusAccepted, deleteVolume},
+			{"POST", "volumes/*/action", http.StatusAccepted, volumeAction},
+			{"GET", "volumes/*/metadata", http.StatusOK, getVolumeMetadata},
+			{"PUT", "volumes/*/metadata", http.StatusOK, resetVolumeMetadata},
+			{"GET", "snapshots", http.StatusOK, listSnapshots},
+			{"POST", "snapshots", http.StatusAccepted, createSnapshot},
+			{"GET", "snapshots/*", http.StatusOK, getSnapshot},
+			{"DELETE", "snapshots/*", http.StatusAccepted, deleteSnapshot},
+			{"GET", "os-quota-sets/*", http.StatusOK, getVolumeQuotaSet},
+		},
+		errorBody: computeError,
+	}
+}
+
+//attachment attachment of a volume to a server
+type attachment struct {
+	ID         string
+	VolumeID   string
+	ServerID   string
+	Device     string
+	AttachedAt string
+}
+
+type volume struct {
+	ID          string
+	Name        string
+	Description string
+	Size        int
+	Status      string
+	Metadata    map[string]string
+	SnapshotID  string
+	Created     string
+	Updated     string
+
+	attachment *attachment
+}
+
+func (v *volume) attach(serverID, device string) {
+	v.attachment = &attachment{
+		ID:         newID(),
+		VolumeID:   v.ID,
+		ServerID:   serverID,
+		Device:     device,
+		AttachedAt: cinderTimestamp(),
+	}
+	v.Status = "in-use"
+	v.Updated = cinderTimestamp()
+}
+
+func (v *volume) detach() {
+	v.attachment = nil
+	v.Status = "available"
+	v.Updated = cinderTimestamp()
+}
+
+func (c *cloud) volume(id string) (*volume, error) {
+	for _, v := range c.volumes {
+		if v.ID == id {
+			return v, nil
+		}
+	}
+	return nil, notFound("Volume %s could not be found.", id)
+}
+
+func (c *cloud) volumeView(v *volume, detail bool) map[string]interface{} {
+	view := map[string]interface{}{
+		"id":    v.ID,
+		"name":  v.Name,
+		"links": c.links("volume/v3/"+ProjectID, "volumes/"+v.ID),
+	}
+	if !detail {
+		return view
+	}
+	attachments := []interface{}{}
+	if a := v.attachment; a != nil {
+		attachments = append(attachments, map[string]interface{}{
+			"id":            a.VolumeID,
+			"attachment_id": a.ID,
+			"volume_id":     a.VolumeID,
+			"server_id":     a.ServerID,
+			"device":        a.Device,
+			"host_name":     nil,
+			"attached_at":   a.AttachedAt,
+		})
+	}
+	view["description"] = v.Description
+	view["size"] = v.Size
+	view["status"] = v.Status
+	view["availability_zone"] = "nova"
+	view["attachments"] = attachments
+	view["metadata"] = v.Metadata
+	view["volume_type"] = "lvmdriver-1"
+	view["bootable"] = "false"
+	view["encrypted"] = false
+	view["multiattach"] = false
+	view["replication_status"] = nil
+	view["snapshot_id"] = nil
+	if v.SnapshotID != "" {
+		view["snapshot_id"] = v.SnapshotID
+	}
+	view["source_volid"] = nil
+	view["user_id"] = UserID
+	view["os-vol-tenant-attr:tenant_id"] = ProjectID
+	view["created_at"] = v.Created
+	view["updated_at"] = v.Updated
+	return view
+}
+
+func (c *cloud) listVolumes(r *request, detail bool) interface{} {
+	query := r.URL.Query()
+	l := []interface{}{}
+	for _, v := range c.volumes {
+		if name := query.Get("name"); name != "" && name != v.Name {
+			continue
+		}
+		if status := query.Get("status"); status != "" && status != v.Status {
+			continue
+		}
+		l = append(l, c.volumeView(v, detail))
+	}
+	return map[string]interface{}{"volumes": l}
+}
+
+func listVolumes(c *cloud, r *request) (interface{}, error) {
+	return c.listVolumes(r, false), nil
+}
+
+func listVolumesDetail(c *cloud, r *request) (interface{}, error) {
+	return c.listVolumes(r, true), nil
+}
+
+type volumeRequest struct {
+	Name        *string           `json:"name"`
+	Description *string           `json:"description"`
+	Size        int               `json:"size"`
+	Metadata    map[string]string `json:"metadata"`
+	SnapshotID  string            `json:"snapshot_id"`
+}
+
+func createVolume(c *cloud, r *request) (interface{}, error) {
+	in := struct {
+		Volume volumeRequest `json:"volume"`
+	}{}
+	err := r.decode(&in)
+	if err != nil {
+		return nil, err
+	}
+	if in.Volume.SnapshotID != "" {
+		s, err := c.snapshot(in.Volume.SnapshotID)
+		if err != nil {
+			return nil, err
+		}
+		if in.Volume.Size == 0 {
+			in.Volume.Size = s.Size
+		}
+		if in.Volume.Size < s.Size {
+			return nil, badRequest("Invalid input received: Volume size '%d'GB cannot be smaller than the snapshot size %dGB. They must be >= original snapshot size.", in.Volume.Size, s.Size)
+		}
+	}
+	if in.Volume.Size < 1 {
+		return nil, badRequest("Invalid input received: Volume size '%d' must be an integer and greater than 0.", in.Volume.Size)
+	}
+	v := &volume{
+		ID:         newID(),
+		Size:       in.Volume.Size,
+		Status:     "available",
+		Metadata:   map[string]string{},
+		SnapshotID: in.Volume.SnapshotID,
+		Created:    cinderTimestamp(),
+		Updated:    cinderTimestamp(),
+	}
+	v.update(&in.Volume)
+	c.volumes = append(c.volumes, v)
+	return map[string]interface{}{"volume": c.volumeView(v, true)}, nil
+}
+
+func (v *volume) update(in *volumeRequest) {
+	if in.Name != nil {
+		v.Name = *in.Name
+	}
+	if in.Description != nil {
+		v.Description = *in.Description
+	}
+	if in.Metadata != nil {
+		v.Metadata = in.Metadata
+	}
+	v.Updated = cinderTimestamp()
+}
+
+func getVolume(c *cloud, r *request) (interface{}, error) {
+	v, err := c.volume(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"volume": c.volumeView(v, true)}, nil
+}
+
+func updateVolume(c *cloud, r *request) (interface{}, error) {
+	v, err := c.volume(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	in := struct {
+		Volume volumeRequest `json:"volume"`
+	}{}
+	err = r.decode(&in)
+	if err != nil {
+		return nil, err
+	}
+	v.update(&in.Volume)
+	return map[string]interface{}{"volume": c.volumeView(v, true)}, nil
+}
+
+func getVolumeMetadata(c *cloud, r *request) (interface{}, error) {
+	v, err := c.volume(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"metadata": v.Metadata}, nil
+}
+
+//resetVolumeMetadata replaces all the metadata of the volume
+func resetVolumeMetadata(c *cloud, r *request) (interface{}, error) {
+	v, err := c.volume(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	in := struct {
+		Metadata map[string]string `json:"metadata"`
+	}{}
+	err = r.decode(&in)
+	if err != nil {
+		return nil, err
+	}
+	if in.Metadata == nil {
+		return nil, badRequest("Malformed request body")
+	}
+	v.update(&volumeRequest{Metadata: in.Metadata})
+	return map[string]interface{}{"metadata": v.Metadata}, nil
+}
+
+func deleteVolume(c *cloud, r *request) (interface{}, error) {
+	v, err := c.volume(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	if v.Status != "available" && v.Status != "error" {
+		return nil, badRequest("Invalid volume: Volume status must be available or error or error_restoring or error_extending or error_managing and must not be migrating, attached, belong to a group, have snapshots or be disassociated from snapshots after volume transfer.")
+	}
+	for _, s := range c.snapshots {
+		if s.VolumeID == v.ID {
+			return nil, badRequest("Invalid volume: Volume %s still has dependent snapshots.", v.ID)
+		}
+	}
+	for i, o := range c.volumes {
+		if o == v {
+			c.volumes = append(c.volumes[:i], c.volumes[i+1:]...)
+			break
+		}
+	}
+	return nil, nil
+}
+
+//volumeAction executes the action named by the single key of the request body
+func volumeAction(c *cloud, r *request) (interface{}, error) {
+	v, err := c.volume(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	actions := map[string]json.RawMessage{}
+	err = r.decode(&actions)
+	if err != nil {
+		return nil, err
+	}
+	if len(actions) != 1 {
+		return nil, badRequest("Invalid request body: %d actions requested", len(actions))
+	}
+	for action, body := range actions {
+		switch action {
+		case "os-extend":
+			in := struct {
+				NewSize int `json:"new_size"`
+			}{}
+			err = json.Unmarshal(body, &in)
+			if err != nil {
+				return nil, badRequest("Invalid input for field/attribute new_size.")
+			}
+			if v.Status != "available" {
+				return nil, badRequest("Invalid volume: Volume %s status must be 'available' to extend, currently %s.", v.ID, v.Status)
+			}
+			if in.NewSize <= v.Size {
+				return nil, badRequest("Invalid input received: New size for extend must be greater than current size. (current: %d, extended: %d).", v.Size, in.NewSize)
+			}
+			v.Size = in.NewSize
+			v.Updated = cinderTimestamp()
+		default:
+			return nil, badRequest("There is no such action: %s", action)
+		}
+	}
+	return nil, nil
+}
@@ -1,8 +1,14 @@
 package openstack
 
 import (
+	"context"
+	"fmt"
 	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/SebastienDorgan/anyclouds/providers"
+	computeimages "github.com/gophercloud/gophercloud/openstack/compute/v2/images"
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/servers"
 	"github.com/gophercloud/gophercloud/openstack/imageservice/v2/images"
+	"strings"
 	"time"
 )
 
@@ -11,11 +17,11 @@ type ImageManager struct {
 	Provider *Provider
 }
 
-func (mgr *ImageManager) list() ([]api.Image, error) {
+func (mgr *ImageManager) list(ctx context.Context) ([]api.Image, error) {
 	opts := images.ListOpts{}
 
 	// Retrieve a pager (i.e. a paginated collection)
-	page, err := images.List(mgr.Provider.BaseServices.Compute, opts).AllPages()
+	page, err := images.List(mgr.Provider.BaseServices.compute(ctx), opts).AllPages()
 	if err != nil {
 		return nil, UnwrapOpenStackError(err)
 	}
@@ -23,7 +29,7 @@ func (mgr *ImageManager) list() ([]api.Image, error) {
 
 	var imgList []api.Image
 	for _, img := range imageList {
-		im, err := mgr.Get(img.ID)
+		im, err := mgr.GetWithContext(ctx, img.ID)
 		if err != nil {
 			return nil, UnwrapOpenStackError(err)
 		}
@@ -32,29 +38,77 @@ func (mgr *ImageManager) list() ([]api.Image, error) {
 	return imgList, nil
 }
 
+//ListWithContext returns available image list
+func (mgr *ImageManager) ListWithContext(ctx context.Context) ([]api.Image, api.ListImageError) {
+	l, err := mgr.list(ctx)
+	return l, api.NewListImageError(UnwrapOpenStackError(err))
+}
+
 //List returns available image list
 func (mgr *ImageManager) List() ([]api.Image, api.ListImageError) {
-	l, err := mgr.list()
-	return l, api.NewListImageError(err)
+	return mgr.ListWithContext(context.Background())
+}
+
+//setOS sets the operating system fields of img from the Glance properties props of the image, they are parsed from the name of the image if the properties are not set
+func setOS(img *api.Image, props map[string]string, owner string) {
+	img.OSFamily, img.Distribution, img.Version = api.ParseOS(img.Name)
+	if distro := strings.ToLower(props["os_distro"]); distro != "" {
+		img.Distribution = distro
+		img.OSFamily = api.OSLinux
+		if distro == "windows" {
+			img.OSFamily = api.OSWindows
+		}
+	}
+	if version := props["os_version"]; version != "" {
+		img.Version = version
+	}
+	switch strings.ToLower(props["os_type"]) {
+	case "linux":
+		img.OSFamily = api.OSLinux
+	case "windows":
+		img.OSFamily = api.OSWindows
+	}
+	img.Arch = api.ParseArch(props["architecture"])
+	img.Owner = owner
+	if o := props["owner_id"]; o != "" {
+		img.Owner = o
+	}
+	img.DefaultUser = props["os_admin_user"]
+	if img.DefaultUser == "" {
+		img.DefaultUser = api.DefaultUser(img.Distribution)
+	}
 }
 
-func (mgr *ImageManager) get(id string) (*api.Image, error) {
-	res := images.Get(mgr.Provider.BaseServices.Compute, id)
+//stringProperties returns the properties of props having a string value
+func stringProperties(props map[string]interface{}) map[string]string {
+	res := map[string]string{}
+	for k, v := range props {
+		if s, ok := v.(string); ok {
+			res[k] = s
+		}
+	}
+	return res
+}
+
+func (mgr *ImageManager) get(ctx context.Context, id string) (*api.Image, error) {
+	res := images.Get(mgr.Provider.BaseServices.compute(ctx), id)
 	img, err := res.Extract()
 	if err != nil {
 		return nil, UnwrapOpenStackError(err)
 	}
 	if len(img.ID) > 0 {
-		return &api.Image{
+		result := &api.Image{
 			ID:        img.ID,
 			Name:      img.Name,
 			MinDisk:   img.MinDiskGigabytes,
 			MinRAM:    img.MinRAMMegabytes,
 			CreatedAt: img.CreatedAt,
 			UpdatedAt: img.UpdatedAt,
-		}, nil
+		}
+		setOS(result, stringProperties(img.Properties), img.Owner)
+		return result, nil
 	}
-	//img, err := images.Get(mgr.Provider.BaseServices.Compute, id).Extract()
+	//img, err := images.Get(mgr.Provider.BaseServices.compute(ctx), id).Extract()
 	type image map[string]interface{}
 	type newImage struct {
 		Image image `json:"image"`
@@ -66,19 +120,98 @@ func (mgr *ImageManager) get(id string) (*api.Image, error) {
 	}
 	createdAt, _ := time.Parse(time.RFC3339, ni.Image["created"].(string))
 	updatedAt, _ := time.Parse(time.RFC3339, ni.Image["updated"].(string))
-	return &api.Image{
+	result := &api.Image{
 		ID:        ni.Image["id"].(string),
 		Name:      ni.Image["name"].(string),
 		MinDisk:   int(ni.Image["minDisk"].(float64)),
 		MinRAM:    int(ni.Image["minRam"].(float64)),
 		CreatedAt: createdAt,
 		UpdatedAt: updatedAt,
-	}, nil
+	}
+	metadata, _ := ni.Image["metadata"].(map[string]interface{})
+	setOS(result, stringProperties(metadata), "")
+	return result, nil
+
+}
 
+//GetWithContext returns the image identified by id
+func (mgr *ImageManager) GetWithContext(ctx context.Context, id string) (*api.Image, api.GetImageError) {
+	i, err := mgr.get(ctx, id)
+	return i, api.NewGetImageError(UnwrapOpenStackError(err), id)
 }
 
 //Get returns the image identified by id
 func (mgr *ImageManager) Get(id string) (*api.Image, api.GetImageError) {
-	i, err := mgr.get(id)
-	return i, api.NewGetImageError(err, id)
+	return mgr.GetWithContext(context.Background(), id)
+}
+
+//waitActive waits until the image identified by id is no longer being saved or ctx is done
+func (mgr *ImageManager) waitActive(ctx context.Context, id string) (*computeimages.Image, error) {
+	var img *computeimages.Image
+	err := providers.Poll(ctx, 30*time.Minute, func(ctx context.Context) (bool, error) {
+		var err error
+		img, err = computeimages.Get(mgr.Provider.BaseServices.compute(ctx), id).Extract()
+		if err != nil {
+			return false, err
+		}
+		return img.Status != "SAVING" && img.Status != "QUEUED", nil
+	})
+	return img, err
+}
+
+func (mgr *ImageManager) createFromServer(ctx context.Context, serverID string, name string) (*api.Image, error) {
+	id, err := servers.CreateImage(mgr.Provider.BaseServices.compute(ctx), serverID, servers.CreateImageOpts{
+		Name: name,
+	}).ExtractImageID()
+	if err != nil {
+		return nil, err
+	}
+	img, err := mgr.waitActive(ctx, id)
+	if err == nil && img.Status != "ACTIVE" {
+		err = fmt.Errorf("image %s is not active", id)
+	}
+	if err != nil {
+		return nil, api.NewErrorStackFromError(err, mgr.delete(ctx, id))
+	}
+	return mgr.get(ctx, id)
+}
+
+//CreateFromServerWithContext creates an image from the server identified by serverID and waits until it is active
+func (mgr *ImageManager) CreateFromServerWithContext(ctx context.Context, serverID string, name string) (*api.Image, api.CreateImageError) {
+	img, err := mgr.createFromServer(ctx, serverID, name)
+	return img, api.NewCreateImageError(UnwrapOpenStackError(err), serverID, name)
+}
+
+//CreateFromServer creates an image from the server identified by serverID and waits until it is active
+func (mgr *ImageManager) CreateFromServer(serverID string, name string) (*api.Image, api.CreateImageError) {
+	return mgr.CreateFromServerWithContext(context.Background(), serverID, name)
+}
+
+func (mgr *ImageManager) delete(ctx context.Context, id string) error {
+	return computeimages.Delete(mgr.Provider.BaseServices.compute(ctx), id).ExtractErr()
+}
+
+//DeleteWithContext deletes the image identified by id
+func (mgr *ImageManager) DeleteWithContext(ctx context.Context, id string) api.DeleteImageError {
+	return api.NewDeleteImageError(UnwrapOpenStackError(mgr.delete(ctx, id)), id)
+}
+
+//Delete deletes the image identified by id
+func (mgr *ImageManager) Delete(id string) api.DeleteImageError {
+	return mgr.DeleteWithContext(context.Background(), id)
+}
+
+//FindWithContext returns the image matching query among the images listed by List
+func (mgr *ImageManager) FindWithContext(ctx context.Context, query api.ImageQuery) (*api.Image, api.FindImageError) {
+	images, err := mgr.list(ctx)
+	if err != nil {
+		return nil, api.NewFindImageError(UnwrapOpenStackError(err), query)
+	}
+	img, err := api.FindImage(images, query)
+	return img, api.NewFindImageError(err, query)
+}
+
+//Find returns the image matching query among the images listed by List
+func (mgr *ImageManager) Find(query api.ImageQuery) (*api.Image, api.FindImageError) {
+	return mgr.FindWithContext(context.Background(), query)
 }
@@ -1,8 +1,10 @@
 package openstack
 
 import (
+	"context"
+
+	"github.com/SebastienDorgan/anyclouds/api"
 	"github.com/gophercloud/gophercloud/openstack/compute/v2/extensions/keypairs"
-	"github.com/pkg/errors"
 )
 
 //KeyPairManager openstack implementation of api.KeyPairManager
@@ -10,23 +12,88 @@ type KeyPairManager struct {
 	Provider *Provider
 }
 
-//Import load a public key
-func (mgr *KeyPairManager) Import(name string, publicKey []byte) error {
-	_, err := keypairs.Create(mgr.Provider.BaseServices.Compute, keypairs.CreateOpts{
+func keyPair(kp *keypairs.KeyPair) *api.KeyPair {
+	return &api.KeyPair{
+		Name:        kp.Name,
+		Fingerprint: kp.Fingerprint,
+	}
+}
+
+func (mgr *KeyPairManager) importKeyPair(ctx context.Context, name string, publicKey []byte) (*api.KeyPair, error) {
+	kp, err := keypairs.Create(mgr.Provider.BaseServices.compute(ctx), keypairs.CreateOpts{
 		Name:      name,
 		PublicKey: string(publicKey),
 	}).Extract()
 	if err != nil {
-		return errors.Wrap(UnwrapOpenStackError(err), "Error listing images")
+		return nil, err
 	}
-	return nil
+	return keyPair(kp), nil
+}
+
+//ImportWithContext registers publicKey under name
+func (mgr *KeyPairManager) ImportWithContext(ctx context.Context, name string, publicKey []byte) (*api.KeyPair, api.ImportKeyPairError) {
+	kp, err := mgr.importKeyPair(ctx, name, publicKey)
+	return kp, api.NewImportKeyPairError(UnwrapOpenStackError(err), name)
+}
+
+//Import registers publicKey under name
+func (mgr *KeyPairManager) Import(name string, publicKey []byte) (*api.KeyPair, api.ImportKeyPairError) {
+	return mgr.ImportWithContext(context.Background(), name, publicKey)
+}
+
+//DeleteWithContext deletes the key pair identified by name
+func (mgr *KeyPairManager) DeleteWithContext(ctx context.Context, name string) api.DeleteKeyPairError {
+	err := keypairs.Delete(mgr.Provider.BaseServices.compute(ctx), name).ExtractErr()
+	return api.NewDeleteKeyPairError(UnwrapOpenStackError(err), name)
 }
 
-//Delete a key pair
-func (mgr *KeyPairManager) Delete(name string) error {
-	err := keypairs.Delete(mgr.Provider.BaseServices.Compute, name).ExtractErr()
+//Delete deletes the key pair identified by name
+func (mgr *KeyPairManager) Delete(name string) api.DeleteKeyPairError {
+	return mgr.DeleteWithContext(context.Background(), name)
+}
+
+func (mgr *KeyPairManager) list(ctx context.Context) ([]api.KeyPair, error) {
+	page, err := keypairs.List(mgr.Provider.BaseServices.compute(ctx)).AllPages()
+	if err != nil {
+		return nil, err
+	}
+	l, err := keypairs.ExtractKeyPairs(page)
 	if err != nil {
-		return errors.Wrap(UnwrapOpenStackError(err), "Error listing images")
+		return nil, err
 	}
-	return nil
+	var res []api.KeyPair
+	for i := range l {
+		res = append(res, *keyPair(&l[i]))
+	}
+	return res, nil
+}
+
+//ListWithContext lists key pairs
+func (mgr *KeyPairManager) ListWithContext(ctx context.Context) ([]api.KeyPair, api.ListKeyPairsError) {
+	l, err := mgr.list(ctx)
+	return l, api.NewListKeyPairsError(UnwrapOpenStackError(err))
+}
+
+//List lists key pairs
+func (mgr *KeyPairManager) List() ([]api.KeyPair, api.ListKeyPairsError) {
+	return mgr.ListWithContext(context.Background())
+}
+
+func (mgr *KeyPairManager) get(ctx context.Context, name string) (*api.KeyPair, error) {
+	kp, err := keypairs.Get(mgr.Provider.BaseServices.compute(ctx), name).Extract()
+	if err != nil {
+		return nil, err
+	}
+	return keyPair(kp), nil
+}
+
+//GetWithContext returns the key pair identified by name
+func (mgr *KeyPairManager) GetWithContext(ctx context.Context, name string) (*api.KeyPair, api.GetKeyPairError) {
+	kp, err := mgr.get(ctx, name)
+	return kp, api.NewGetKeyPairError(UnwrapOpenStackError(err), name)
+}
+
+//Get returns the key pair identified by name
+func (mgr *KeyPairManager) Get(name string) (*api.KeyPair, api.GetKeyPairError) {
+	return mgr.GetWithContext(context.Background(), name)
 }
@@ -0,0 +1,21 @@
+package openstack_test
+
+import (
+	"testing"
+
+	"github.com/SebastienDorgan/anyclouds/tests"
+	"github.com/stretchr/testify/suite"
+)
+
+type OSKeyPairManagerTestSuite struct {
+	tests.KeyPairManagerTestSuite
+}
+
+//SetupSuite set up key pair manager
+func (suite *OSKeyPairManagerTestSuite) SetupSuite() {
+	suite.Prov = GetProvider()
+}
+
+func TestOSKeyPairManagerTestSuite(t *testing.T) {
+	suite.Run(t, new(OSKeyPairManagerTestSuite))
+}
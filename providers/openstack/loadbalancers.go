@@ -0,0 +1,455 @@
+package openstack
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/SebastienDorgan/anyclouds/providers"
+	"github.com/gophercloud/gophercloud/openstack/loadbalancer/v2/listeners"
+	"github.com/gophercloud/gophercloud/openstack/loadbalancer/v2/loadbalancers"
+	"github.com/gophercloud/gophercloud/openstack/loadbalancer/v2/monitors"
+	"github.com/gophercloud/gophercloud/openstack/loadbalancer/v2/pools"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/layer3/floatingips"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/ports"
+	"github.com/pkg/errors"
+)
+
+//LoadBalancerManager openstack implementation of api.LoadBalancerManager based on Octavia
+//Each listener forwards traffic to its own pool, pool members are named after the server they forward traffic to
+//Octavia pools have no port, the port of a backend pool is stored in the pool description
+//Public load balancers get a floating IP associated with their VIP port
+type LoadBalancerManager struct {
+	Provider *Provider
+}
+
+//udpConnect Octavia health monitor type checking UDP pools
+const udpConnect = "UDP-CONNECT"
+
+func (mgr *LoadBalancerManager) checkOptions(options *api.CreateLoadBalancerOptions) error {
+	err := api.CheckLoadBalancerOptions(options)
+	if err != nil {
+		return err
+	}
+	if mgr.Provider.BaseServices.LoadBalancer == nil {
+		return errors.New("the load balancer service is not available")
+	}
+	if len(options.SubnetIDs) != 1 {
+		return api.WithKind(errors.New("openstack load balancers must be attached to a single subnet"), api.ErrInvalidArgument)
+	}
+	for _, l := range options.Listeners {
+		if hc := l.Pool.HealthCheck; hc != nil && hc.Port != 0 {
+			return api.WithKind(errors.New("openstack health checks must check the port of the backend pool"), api.ErrInvalidArgument)
+		}
+	}
+	return nil
+}
+
+//memberAddress returns the address of the server identified by serverID in the subnet identified by subnetID
+func (mgr *LoadBalancerManager) memberAddress(ctx context.Context, serverID string, subnetID string) (string, error) {
+	page, err := ports.List(mgr.Provider.BaseServices.network(ctx), ports.ListOpts{DeviceID: serverID}).AllPages()
+	if err != nil {
+		return "", UnwrapOpenStackError(err)
+	}
+	l, err := ports.ExtractPorts(page)
+	if err != nil {
+		return "", UnwrapOpenStackError(err)
+	}
+	for _, p := range l {
+		for _, ip := range p.FixedIPs {
+			if ip.SubnetID == subnetID {
+				return ip.IPAddress, nil
+			}
+		}
+	}
+	return "", notFoundError("server %s has no address in subnet %s", serverID, subnetID)
+}
+
+//waitActive waits until the load balancer identified by id is no longer being updated
+func (mgr *LoadBalancerManager) waitActive(ctx context.Context, id string) error {
+	var lb *loadbalancers.LoadBalancer
+	err := providers.Poll(ctx, 10*time.Minute, func(ctx context.Context) (bool, error) {
+		var err error
+		lb, err = loadbalancers.Get(mgr.Provider.BaseServices.loadBalancer(ctx), id).Extract()
+		if err != nil {
+			return false, UnwrapOpenStackError(err)
+		}
+		return !strings.HasPrefix(lb.ProvisioningStatus, "PENDING_"), nil
+	})
+	if err != nil {
+		return api.NewErrorStack(err, "load balancer is not active", id)
+	}
+	if lb.ProvisioningStatus != "ACTIVE" {
+		return fmt.Errorf("load balancer %s is not active", id)
+	}
+	return nil
+}
+
+func monitorType(pool api.BackendPool, hc *api.HealthCheck) string {
+	if hc.Protocol == api.LoadBalancerTCP && pool.Protocol == api.LoadBalancerUDP {
+		return udpConnect
+	}
+	return string(hc.Protocol)
+}
+
+//createListener creates a listener with its pool, its health monitor and its members
+func (mgr *LoadBalancerManager) createListener(ctx context.Context, lb *loadbalancers.LoadBalancer, options api.ListenerOptions) error {
+	client := mgr.Provider.BaseServices.loadBalancer(ctx)
+	l, err := listeners.Create(client, listeners.CreateOpts{
+		LoadbalancerID: lb.ID,
+		Protocol:       listeners.Protocol(options.Protocol),
+		ProtocolPort:   options.Port,
+	}).Extract()
+	if err != nil {
+		return UnwrapOpenStackError(err)
+	}
+	err = mgr.waitActive(ctx, lb.ID)
+	if err != nil {
+		return err
+	}
+	pool, err := pools.Create(client, pools.CreateOpts{
+		LBMethod:    pools.LBMethodRoundRobin,
+		Protocol:    pools.Protocol(options.Pool.Protocol),
+		ListenerID:  l.ID,
+		Description: strconv.Itoa(options.Pool.Port),
+	}).Extract()
+	if err != nil {
+		return UnwrapOpenStackError(err)
+	}
+	err = mgr.waitActive(ctx, lb.ID)
+	if err != nil {
+		return err
+	}
+	defaultHC := api.DefaultHealthCheck(options.Pool.Protocol)
+	hc := options.Pool.HealthCheck
+	if hc == nil {
+		hc = defaultHC
+	}
+	delay := int(hc.Interval / time.Second)
+	if delay < 1 {
+		delay = int(defaultHC.Interval / time.Second)
+	}
+	retries := hc.UnhealthyThreshold
+	if retries < 1 {
+		retries = defaultHC.UnhealthyThreshold
+	}
+	monitorOpts := monitors.CreateOpts{
+		PoolID:     pool.ID,
+		Type:       monitorType(options.Pool, hc),
+		Delay:      delay,
+		Timeout:    delay,
+		MaxRetries: retries,
+	}
+	if hc.Protocol == api.LoadBalancerHTTP {
+		monitorOpts.URLPath = hc.Path
+	}
+	_, err = monitors.Create(client, monitorOpts).Extract()
+	if err != nil {
+		return UnwrapOpenStackError(err)
+	}
+	err = mgr.waitActive(ctx, lb.ID)
+	if err != nil {
+		return err
+	}
+	for _, id := range options.Pool.ServerIDs {
+		addr, err := mgr.memberAddress(ctx, id, lb.VipSubnetID)
+		if err != nil {
+			return err
+		}
+		_, err = pools.CreateMember(client, pool.ID, pools.CreateMemberOpts{
+			Address:      addr,
+			ProtocolPort: options.Pool.Port,
+			Name:         id,
+			SubnetID:     lb.VipSubnetID,
+		}).Extract()
+		if err != nil {
+			return UnwrapOpenStackError(err)
+		}
+		err = mgr.waitActive(ctx, lb.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//configure creates the listeners of the load balancer lb and associates a floating IP with it if it is public
+func (mgr *LoadBalancerManager) configure(ctx context.Context, lb *loadbalancers.LoadBalancer, options *api.CreateLoadBalancerOptions) error {
+	err := mgr.waitActive(ctx, lb.ID)
+	if err != nil {
+		return err
+	}
+	for _, l := range options.Listeners {
+		err = mgr.createListener(ctx, lb, l)
+		if err != nil {
+			return err
+		}
+	}
+	if !options.Public {
+		return nil
+	}
+	_, err = floatingips.Create(mgr.Provider.BaseServices.network(ctx), &floatingips.CreateOpts{
+		Description:       options.Name,
+		FloatingNetworkID: mgr.Provider.Config.ExternalNetworkID,
+		PortID:            lb.VipPortID,
+	}).Extract()
+	return UnwrapOpenStackError(err)
+}
+
+func (mgr *LoadBalancerManager) create(ctx context.Context, options *api.CreateLoadBalancerOptions) (*api.LoadBalancer, error) {
+	err := mgr.checkOptions(options)
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range options.Listeners {
+		for _, id := range l.Pool.ServerIDs {
+			_, err = mgr.memberAddress(ctx, id, options.SubnetIDs[0])
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	lb, err := loadbalancers.Create(mgr.Provider.BaseServices.loadBalancer(ctx), loadbalancers.CreateOpts{
+		Name:        options.Name,
+		VipSubnetID: options.SubnetIDs[0],
+		Tags:        neutronTags(options.Tags),
+	}).Extract()
+	if err != nil {
+		return nil, UnwrapOpenStackError(err)
+	}
+	err = mgr.configure(ctx, lb, options)
+	if err != nil {
+		err2 := providers.Cleanup(func(ctx context.Context) error {
+			return mgr.delete(ctx, lb.ID)
+		})
+		return nil, api.NewErrorStackFromError(err, err2)
+	}
+	return mgr.get(ctx, lb.ID)
+}
+
+//CreateWithContext creates a load balancer and waits until it is active
+func (mgr *LoadBalancerManager) CreateWithContext(ctx context.Context, options api.CreateLoadBalancerOptions) (*api.LoadBalancer, api.CreateLoadBalancerError) {
+	lb, err := mgr.create(ctx, &options)
+	if err != nil {
+		return nil, api.NewCreateLoadBalancerError(err, options)
+	}
+	return lb, nil
+}
+
+//Create creates a load balancer and waits until it is active
+func (mgr *LoadBalancerManager) Create(options api.CreateLoadBalancerOptions) (*api.LoadBalancer, api.CreateLoadBalancerError) {
+	return mgr.CreateWithContext(context.Background(), options)
+}
+
+//vipFloatingIPs returns the floating IPs associated with the VIP port of a load balancer
+func (mgr *LoadBalancerManager) vipFloatingIPs(ctx context.Context, lb *loadbalancers.LoadBalancer) ([]floatingips.FloatingIP, error) {
+	page, err := floatingips.List(mgr.Provider.BaseServices.network(ctx), floatingips.ListOpts{PortID: lb.VipPortID}).AllPages()
+	if err != nil {
+		return nil, UnwrapOpenStackError(err)
+	}
+	fips, err := floatingips.ExtractFloatingIPs(page)
+	return fips, UnwrapOpenStackError(err)
+}
+
+func (mgr *LoadBalancerManager) delete(ctx context.Context, id string) error {
+	if mgr.Provider.BaseServices.LoadBalancer == nil {
+		return errors.New("the load balancer service is not available")
+	}
+	client := mgr.Provider.BaseServices.loadBalancer(ctx)
+	lb, err := loadbalancers.Get(client, id).Extract()
+	if err != nil {
+		return UnwrapOpenStackError(err)
+	}
+	fips, err := mgr.vipFloatingIPs(ctx, lb)
+	if err != nil {
+		return err
+	}
+	for _, fip := range fips {
+		err = floatingips.Delete(mgr.Provider.BaseServices.network(ctx), fip.ID).ExtractErr()
+		if err != nil {
+			return UnwrapOpenStackError(err)
+		}
+	}
+	err = loadbalancers.Delete(client, id, loadbalancers.DeleteOpts{Cascade: true}).ExtractErr()
+	if err != nil {
+		return UnwrapOpenStackError(err)
+	}
+	err = providers.Poll(ctx, 10*time.Minute, func(ctx context.Context) (bool, error) {
+		_, err := loadbalancers.Get(mgr.Provider.BaseServices.loadBalancer(ctx), id).Extract()
+		if err == nil {
+			return false, nil
+		}
+		err = UnwrapOpenStackError(err)
+		if api.ErrorKind(err) == api.ErrNotFound {
+			return true, nil
+		}
+		return false, err
+	})
+	if err != nil {
+		return api.NewErrorStack(err, "load balancer is not deleted", id)
+	}
+	return nil
+}
+
+//DeleteWithContext deletes the load balancer identified by id with its listeners, pools and floating IP
+func (mgr *LoadBalancerManager) DeleteWithContext(ctx context.Context, id string) api.DeleteLoadBalancerError {
+	return api.NewDeleteLoadBalancerError(mgr.delete(ctx, id), id)
+}
+
+//Delete deletes the load balancer identified by id with its listeners, pools and floating IP
+func (mgr *LoadBalancerManager) Delete(id string) api.DeleteLoadBalancerError {
+	return mgr.DeleteWithContext(context.Background(), id)
+}
+
+//ListWithContext lists load balancers
+func (mgr *LoadBalancerManager) ListWithContext(ctx context.Context) ([]api.LoadBalancer, api.ListLoadBalancersError) {
+	if mgr.Provider.BaseServices.LoadBalancer == nil {
+		return nil, api.NewListLoadBalancersError(errors.New("the load balancer service is not available"))
+	}
+	page, err := loadbalancers.List(mgr.Provider.BaseServices.loadBalancer(ctx), loadbalancers.ListOpts{}).AllPages()
+	if err != nil {
+		return nil, api.NewListLoadBalancersError(UnwrapOpenStackError(err))
+	}
+	l, err := loadbalancers.ExtractLoadBalancers(page)
+	if err != nil {
+		return nil, api.NewListLoadBalancersError(UnwrapOpenStackError(err))
+	}
+	res := []api.LoadBalancer{}
+	for i := range l {
+		lb, err := mgr.loadBalancer(ctx, &l[i])
+		if err != nil {
+			return nil, api.NewListLoadBalancersError(err)
+		}
+		res = append(res, *lb)
+	}
+	return res, nil
+}
+
+//List lists load balancers
+func (mgr *LoadBalancerManager) List() ([]api.LoadBalancer, api.ListLoadBalancersError) {
+	return mgr.ListWithContext(context.Background())
+}
+
+func healthCheck(m *monitors.Monitor) *api.HealthCheck {
+	hc := &api.HealthCheck{
+		Protocol:           api.LoadBalancerProtocol(m.Type),
+		Interval:           time.Duration(m.Delay) * time.Second,
+		UnhealthyThreshold: m.MaxRetries,
+	}
+	if m.Type == udpConnect {
+		hc.Protocol = api.LoadBalancerTCP
+	}
+	if hc.Protocol == api.LoadBalancerHTTP {
+		hc.Path = m.URLPath
+	}
+	return hc
+}
+
+//pool returns the backend pool identified by id
+func (mgr *LoadBalancerManager) pool(ctx context.Context, id string) (*api.BackendPool, error) {
+	client := mgr.Provider.BaseServices.loadBalancer(ctx)
+	p, err := pools.Get(client, id).Extract()
+	if err != nil {
+		return nil, UnwrapOpenStackError(err)
+	}
+	port, _ := strconv.Atoi(p.Description)
+	res := &api.BackendPool{
+		Protocol: api.LoadBalancerProtocol(p.Protocol),
+		Port:     port,
+	}
+	if p.MonitorID != "" {
+		m, err := monitors.Get(client, p.MonitorID).Extract()
+		if err != nil {
+			return nil, UnwrapOpenStackError(err)
+		}
+		res.HealthCheck = healthCheck(m)
+	}
+	page, err := pools.ListMembers(client, id, pools.ListMembersOpts{}).AllPages()
+	if err != nil {
+		return nil, UnwrapOpenStackError(err)
+	}
+	members, err := pools.ExtractMembers(page)
+	if err != nil {
+		return nil, UnwrapOpenStackError(err)
+	}
+	for _, m := range members {
+		res.ServerIDs = append(res.ServerIDs, m.Name)
+	}
+	return res, nil
+}
+
+//loadBalancer converts lb, the type of the load balancer is L7 if it has HTTP listeners
+func (mgr *LoadBalancerManager) loadBalancer(ctx context.Context, lb *loadbalancers.LoadBalancer) (*api.LoadBalancer, error) {
+	res := &api.LoadBalancer{
+		ID:        lb.ID,
+		Name:      lb.Name,
+		Type:      api.LoadBalancerL4,
+		SubnetIDs: []string{lb.VipSubnetID},
+		Address:   lb.VipAddress,
+		Tags:      tagMap(lb.Tags),
+	}
+	fips, err := mgr.vipFloatingIPs(ctx, lb)
+	if err != nil {
+		return nil, err
+	}
+	if len(fips) > 0 {
+		res.Public = true
+		res.Address = fips[0].FloatingIP
+	}
+	client := mgr.Provider.BaseServices.loadBalancer(ctx)
+	for _, ref := range lb.Listeners {
+		l, err := listeners.Get(client, ref.ID).Extract()
+		if err != nil {
+			return nil, UnwrapOpenStackError(err)
+		}
+		listener := api.Listener{
+			ID:       l.ID,
+			Protocol: api.LoadBalancerProtocol(l.Protocol),
+			Port:     l.ProtocolPort,
+		}
+		if listener.Protocol == api.LoadBalancerHTTP {
+			res.Type = api.LoadBalancerL7
+		}
+		if l.DefaultPoolID != "" {
+			pool, err := mgr.pool(ctx, l.DefaultPoolID)
+			if err != nil {
+				return nil, err
+			}
+			listener.Pool = *pool
+		}
+		res.Listeners = append(res.Listeners, listener)
+	}
+	sort.Slice(res.Listeners, func(i, j int) bool {
+		return res.Listeners[i].Port < res.Listeners[j].Port
+	})
+	return res, nil
+}
+
+func (mgr *LoadBalancerManager) get(ctx context.Context, id string) (*api.LoadBalancer, error) {
+	if mgr.Provider.BaseServices.LoadBalancer == nil {
+		return nil, errors.New("the load balancer service is not available")
+	}
+	lb, err := loadbalancers.Get(mgr.Provider.BaseServices.loadBalancer(ctx), id).Extract()
+	if err != nil {
+		return nil, UnwrapOpenStackError(err)
+	}
+	return mgr.loadBalancer(ctx, lb)
+}
+
+//GetWithContext returns the load balancer identified by id
+func (mgr *LoadBalancerManager) GetWithContext(ctx context.Context, id string) (*api.LoadBalancer, api.GetLoadBalancerError) {
+	lb, err := mgr.get(ctx, id)
+	if err != nil {
+		return nil, api.NewGetLoadBalancerError(err, id)
+	}
+	return lb, nil
+}
+
+//Get returns the load balancer identified by id
+func (mgr *LoadBalancerManager) Get(id string) (*api.LoadBalancer, api.GetLoadBalancerError) {
+	return mgr.GetWithContext(context.Background(), id)
+}
@@ -0,0 +1,21 @@
+package openstack_test
+
+import (
+	"testing"
+
+	"github.com/SebastienDorgan/anyclouds/tests"
+	"github.com/stretchr/testify/suite"
+)
+
+type OSLoadBalancerManagerTestSuite struct {
+	tests.LoadBalancerManagerTestSuite
+}
+
+//SetupSuite set up load balancer manager
+func (suite *OSLoadBalancerManagerTestSuite) SetupSuite() {
+	suite.Prov = GetProvider()
+}
+
+func TestOSLoadBalancerManagerTestSuite(t *testing.T) {
+	suite.Run(t, new(OSLoadBalancerManagerTestSuite))
+}
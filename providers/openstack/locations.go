@@ -0,0 +1,138 @@
+package openstack
+
+import (
+	"context"
+	"sort"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+	gc "github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack"
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/extensions/availabilityzones"
+	"github.com/gophercloud/gophercloud/openstack/identity/v3/regions"
+)
+
+//LocationManager OpenStack implementation of api.LocationManager
+//Regions are the Keystone regions, availability zones are the Nova availability zones of a region
+type LocationManager struct {
+	Provider *Provider
+}
+
+func (mgr *LocationManager) listRegions(ctx context.Context) ([]api.Region, error) {
+	page, err := regions.List(mgr.Provider.BaseServices.identity(ctx), nil).AllPages()
+	if err != nil {
+		return nil, err
+	}
+	l, err := regions.ExtractRegions(page)
+	if err != nil {
+		return nil, err
+	}
+	res := []api.Region{}
+	for _, r := range l {
+		name := r.Description
+		if name == "" {
+			name = r.ID
+		}
+		res = append(res, api.Region{ID: r.ID, Name: name})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].ID < res[j].ID
+	})
+	return res, nil
+}
+
+//ListRegionsWithContext lists the regions of the cloud
+func (mgr *LocationManager) ListRegionsWithContext(ctx context.Context) ([]api.Region, api.ListRegionsError) {
+	res, err := mgr.listRegions(ctx)
+	if err != nil {
+		return nil, api.NewListRegionsError(UnwrapOpenStackError(err))
+	}
+	return res, nil
+}
+
+//ListRegions lists the regions of the cloud
+func (mgr *LocationManager) ListRegions() ([]api.Region, api.ListRegionsError) {
+	return mgr.ListRegionsWithContext(context.Background())
+}
+
+//compute returns a compute client of region
+func (mgr *LocationManager) compute(ctx context.Context, region string) (*gc.ServiceClient, error) {
+	if region == mgr.Provider.config.Region {
+		return mgr.Provider.BaseServices.compute(ctx), nil
+	}
+	client, err := openstack.NewComputeV2(mgr.Provider.BaseServices.client, gc.EndpointOpts{
+		Region: region,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return withContext(ctx, client), nil
+}
+
+func (mgr *LocationManager) listZones(ctx context.Context, region string) ([]api.AvailabilityZone, error) {
+	l, err := mgr.listRegions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	_, err = api.FindRegion(l, region)
+	if err != nil {
+		return nil, err
+	}
+	client, err := mgr.compute(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+	page, err := availabilityzones.List(client).AllPages()
+	if err != nil {
+		return nil, err
+	}
+	zones, err := availabilityzones.ExtractAvailabilityZones(page)
+	if err != nil {
+		return nil, err
+	}
+	res := []api.AvailabilityZone{}
+	for _, z := range zones {
+		res = append(res, api.AvailabilityZone{
+			ID:        z.ZoneName,
+			Region:    region,
+			Available: z.ZoneState.Available,
+		})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].ID < res[j].ID
+	})
+	return res, nil
+}
+
+//ListZonesWithContext lists the availability zones of region
+func (mgr *LocationManager) ListZonesWithContext(ctx context.Context, region string) ([]api.AvailabilityZone, api.ListAvailabilityZonesError) {
+	res, err := mgr.listZones(ctx, region)
+	if err != nil {
+		return nil, api.NewListAvailabilityZonesError(UnwrapOpenStackError(err), region)
+	}
+	return res, nil
+}
+
+//ListZones lists the availability zones of region
+func (mgr *LocationManager) ListZones(region string) ([]api.AvailabilityZone, api.ListAvailabilityZonesError) {
+	return mgr.ListZonesWithContext(context.Background(), region)
+}
+
+//WithRegion returns a new OpenStack provider with the configuration of p bound to region
+func (p *Provider) WithRegion(region string) (api.Provider, error) {
+	l, err := p.LocationManager.listRegions(context.Background())
+	if err != nil {
+		return nil, UnwrapOpenStackError(err)
+	}
+	_, err = api.FindRegion(l, region)
+	if err != nil {
+		return nil, err
+	}
+	cfg := p.config
+	cfg.Region = region
+	res := &Provider{}
+	err = res.init(&cfg)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
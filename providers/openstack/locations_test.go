@@ -0,0 +1,21 @@
+package openstack_test
+
+import (
+	"testing"
+
+	"github.com/SebastienDorgan/anyclouds/tests"
+	"github.com/stretchr/testify/suite"
+)
+
+type OSLocationManagerTestSuite struct {
+	tests.LocationManagerTestSuite
+}
+
+//SetupSuite set up location manager
+func (suite *OSLocationManagerTestSuite) SetupSuite() {
+	suite.Prov = GetProvider()
+}
+
+func TestOSLocationManagerTestSuite(t *testing.T) {
+	suite.Run(t, new(OSLocationManagerTestSuite))
+}
@@ -1,7 +1,9 @@
 package openstack
 
 import (
+	"context"
 	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/SebastienDorgan/anyclouds/providers"
 	"github.com/gophercloud/gophercloud/openstack/networking/v2/ports"
 )
 
@@ -16,20 +18,27 @@ func convert(port *ports.Port, publicIPs []api.PublicIP) *api.NetworkInterface {
 			publicIPAddress = ip.Address
 		}
 	}
-	return &api.NetworkInterface{
-		ID:               port.ID,
-		Name:             port.Name,
-		MacAddress:       port.MACAddress,
-		NetworkID:        port.NetworkID,
-		SubnetID:         port.FixedIPs[0].SubnetID,
-		ServerID:         port.DeviceID,
-		PrivateIPAddress: port.FixedIPs[0].IPAddress,
-		PublicIPAddress:  publicIPAddress,
-		SecurityGroupID:  port.SecurityGroups[0],
+	ni := &api.NetworkInterface{
+		ID:              port.ID,
+		Name:            port.Name,
+		MacAddress:      port.MACAddress,
+		NetworkID:       port.NetworkID,
+		ServerID:        port.DeviceID,
+		PublicIPAddress: publicIPAddress,
+		Tags:            tagMap(port.Tags),
 	}
+	if len(port.FixedIPs) > 0 {
+		ni.SubnetID = port.FixedIPs[0].SubnetID
+		ni.PrivateIPAddress = port.FixedIPs[0].IPAddress
+	}
+	if len(port.SecurityGroups) > 0 {
+		ni.SecurityGroupID = port.SecurityGroups[0]
+	}
+	return ni
 }
 
-func (mgr *NetworkInterfacesManager) Create(options api.CreateNetworkInterfaceOptions) (*api.NetworkInterface, api.CreateNetworkInterfaceError) {
+//CreateWithContext context aware version of Create
+func (mgr *NetworkInterfacesManager) CreateWithContext(ctx context.Context, options api.CreateNetworkInterfaceOptions) (*api.NetworkInterface, api.CreateNetworkInterfaceError) {
 	up := true
 	ip := ports.IP{
 		IPAddress: "",
@@ -42,7 +51,7 @@ func (mgr *NetworkInterfacesManager) Create(options api.CreateNetworkInterfaceOp
 	if options.ServerID != nil {
 		serverID = *options.ServerID
 	}
-	p, err := ports.Create(mgr.Provider.BaseServices.Network, &ports.CreateOpts{
+	p, err := ports.Create(mgr.Provider.BaseServices.network(ctx), &ports.CreateOpts{
 		NetworkID:      options.NetworkID,
 		Name:           options.Name,
 		Description:    options.Name,
@@ -52,25 +61,49 @@ func (mgr *NetworkInterfacesManager) Create(options api.CreateNetworkInterfaceOp
 		SecurityGroups: &[]string{options.SecurityGroupID},
 	}).Extract()
 	if err != nil {
-		return nil, api.NewCreateNetworkInterfaceError(err, options)
+		return nil, api.NewCreateNetworkInterfaceError(UnwrapOpenStackError(err), options)
+	}
+	if len(options.Tags) > 0 {
+		err = mgr.Provider.BaseServices.setNeutronTags(ctx, api.ResourceNetworkInterface, p.ID, options.Tags)
+		if err != nil {
+			err2 := providers.Cleanup(func(ctx context.Context) error {
+				return mgr.DeleteWithContext(ctx, p.ID)
+			})
+			return nil, api.NewCreateNetworkInterfaceError(api.NewErrorStackFromError(err, err2), options)
+		}
+		p.Tags = neutronTags(options.Tags)
 	}
 	return convert(p, nil), nil
 }
 
+func (mgr *NetworkInterfacesManager) Create(options api.CreateNetworkInterfaceOptions) (*api.NetworkInterface, api.CreateNetworkInterfaceError) {
+	return mgr.CreateWithContext(context.Background(), options)
+}
+
+//DeleteWithContext context aware version of Delete
+func (mgr *NetworkInterfacesManager) DeleteWithContext(ctx context.Context, id string) api.DeleteNetworkInterfaceError {
+	err := ports.Delete(mgr.Provider.BaseServices.network(ctx), id).ExtractErr()
+	return api.NewDeleteNetworkInterfaceError(UnwrapOpenStackError(err), id)
+}
+
 func (mgr *NetworkInterfacesManager) Delete(id string) api.DeleteNetworkInterfaceError {
-	err := ports.Delete(mgr.Provider.BaseServices.Network, id).ExtractErr()
-	return api.NewDeleteNetworkInterfaceError(err, id)
+	return mgr.DeleteWithContext(context.Background(), id)
 }
 
-func (mgr *NetworkInterfacesManager) Get(id string) (*api.NetworkInterface, api.GetNetworkInterfaceError) {
-	publicIPs, _ := mgr.Provider.PublicIPAddressManager.List(&api.ListPublicIPsOptions{})
-	p, err := ports.Get(mgr.Provider.BaseServices.Network, id).Extract()
+//GetWithContext context aware version of Get
+func (mgr *NetworkInterfacesManager) GetWithContext(ctx context.Context, id string) (*api.NetworkInterface, api.GetNetworkInterfaceError) {
+	publicIPs, _ := mgr.Provider.PublicIPAddressManager.ListWithContext(ctx, nil)
+	p, err := ports.Get(mgr.Provider.BaseServices.network(ctx), id).Extract()
 	if err != nil {
-		return nil, api.NewGetNetworkInterfaceError(err, id)
+		return nil, api.NewGetNetworkInterfaceError(UnwrapOpenStackError(err), id)
 	}
 	return convert(p, publicIPs), nil
 }
 
+func (mgr *NetworkInterfacesManager) Get(id string) (*api.NetworkInterface, api.GetNetworkInterfaceError) {
+	return mgr.GetWithContext(context.Background(), id)
+}
+
 func checkNI(ni *api.NetworkInterface, options *api.ListNetworkInterfacesOptions) bool {
 	if options == nil {
 		return true
@@ -87,7 +120,7 @@ func checkNI(ni *api.NetworkInterface, options *api.ListNetworkInterfacesOptions
 	return true
 }
 
-func (mgr *NetworkInterfacesManager) list(options *api.ListNetworkInterfacesOptions) ([]api.NetworkInterface, error) {
+func (mgr *NetworkInterfacesManager) list(ctx context.Context, options *api.ListNetworkInterfacesOptions) ([]api.NetworkInterface, error) {
 	var netID string
 	if options != nil && options.NetworkID != nil {
 		netID = *options.NetworkID
@@ -96,8 +129,8 @@ func (mgr *NetworkInterfacesManager) list(options *api.ListNetworkInterfacesOpti
 	if options != nil && options.ServerID != nil {
 		srvID = *options.ServerID
 	}
-	publicIPs, _ := mgr.Provider.PublicIPAddressManager.List(&api.ListPublicIPsOptions{})
-	pages, err := ports.List(mgr.Provider.BaseServices.Network, ports.ListOpts{
+	publicIPs, _ := mgr.Provider.PublicIPAddressManager.ListWithContext(ctx, nil)
+	pages, err := ports.List(mgr.Provider.BaseServices.network(ctx), ports.ListOpts{
 		NetworkID: netID,
 		DeviceID:  srvID,
 	}).AllPages()
@@ -120,27 +153,37 @@ func (mgr *NetworkInterfacesManager) list(options *api.ListNetworkInterfacesOpti
 	return list, nil
 }
 
+//ListWithContext context aware version of List
+func (mgr *NetworkInterfacesManager) ListWithContext(ctx context.Context, options *api.ListNetworkInterfacesOptions) ([]api.NetworkInterface, api.ListNetworkInterfacesError) {
+	l, err := mgr.list(ctx, options)
+	return l, api.NewListNetworkInterfacesError(UnwrapOpenStackError(err), options)
+}
+
 func (mgr *NetworkInterfacesManager) List(options *api.ListNetworkInterfacesOptions) ([]api.NetworkInterface, api.ListNetworkInterfacesError) {
-	l, err := mgr.list(options)
-	return l, api.NewListNetworkInterfacesError(err, options)
+	return mgr.ListWithContext(context.Background(), options)
 }
 
-func (mgr *NetworkInterfacesManager) update(options api.UpdateNetworkInterfaceOptions) (*api.NetworkInterface, error) {
+func (mgr *NetworkInterfacesManager) update(ctx context.Context, options api.UpdateNetworkInterfaceOptions) (*api.NetworkInterface, error) {
 	opts := ports.UpdateOpts{
 		DeviceID: options.ServerID,
 	}
 	if options.SecurityGroupID != nil {
 		opts.SecurityGroups = &[]string{*options.SecurityGroupID}
 	}
-	port, err := ports.Update(mgr.Provider.BaseServices.Network, options.ID, opts).Extract()
+	port, err := ports.Update(mgr.Provider.BaseServices.network(ctx), options.ID, opts).Extract()
 	if err != nil {
 		return nil, err
 	}
-	publicIPs, _ := mgr.Provider.PublicIPAddressManager.List(&api.ListPublicIPsOptions{})
+	publicIPs, _ := mgr.Provider.PublicIPAddressManager.ListWithContext(ctx, nil)
 	return convert(port, publicIPs), nil
 }
 
+//UpdateWithContext context aware version of Update
+func (mgr *NetworkInterfacesManager) UpdateWithContext(ctx context.Context, options api.UpdateNetworkInterfaceOptions) (*api.NetworkInterface, api.UpdateNetworkInterfaceError) {
+	ni, err := mgr.update(ctx, options)
+	return ni, api.NewUpdateNetworkInterfaceError(UnwrapOpenStackError(err), options)
+}
+
 func (mgr *NetworkInterfacesManager) Update(options api.UpdateNetworkInterfaceOptions) (*api.NetworkInterface, api.UpdateNetworkInterfaceError) {
-	ni, err := mgr.Update(options)
-	return ni, api.NewUpdateNetworkInterfaceError(err, options)
+	return mgr.UpdateWithContext(context.Background(), options)
 }
@@ -1,8 +1,10 @@
 package openstack
 
 import (
+	"context"
 	"fmt"
 	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/SebastienDorgan/anyclouds/providers"
 	gc "github.com/gophercloud/gophercloud"
 	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/layer3/routers"
 	"github.com/gophercloud/gophercloud/openstack/networking/v2/networks"
@@ -14,69 +16,102 @@ type NetworkManager struct {
 	Refactor *Provider
 }
 
-//CreateNetwork creates a network
-func (mgr *NetworkManager) CreateNetwork(options api.CreateNetworkOptions) (*api.Network, api.CreateNetworkError) {
+//CreateNetworkWithContext creates a network
+func (mgr *NetworkManager) CreateNetworkWithContext(ctx context.Context, options api.CreateNetworkOptions) (*api.Network, api.CreateNetworkError) {
 	up := true
 	opts := networks.CreateOpts{
 		AdminStateUp: &up,
 		Name:         options.Name,
 		Description:  options.CIDR,
 	}
-	network, err := networks.Create(mgr.Refactor.BaseServices.Network, opts).Extract()
+	network, err := networks.Create(mgr.Refactor.BaseServices.network(ctx), opts).Extract()
 	if err != nil {
 		return nil, api.NewCreateNetworkError(UnwrapOpenStackError(err), options)
 	}
-	_, err = mgr.createRouter(network.ID)
+	if len(options.Tags) > 0 {
+		err = mgr.Refactor.BaseServices.setNeutronTags(ctx, api.ResourceNetwork, network.ID, options.Tags)
+		if err != nil {
+			err2 := providers.Cleanup(func(ctx context.Context) error {
+				return mgr.DeleteNetworkWithContext(ctx, network.ID)
+			})
+			return nil, api.NewCreateNetworkError(api.NewErrorStackFromError(err, err2), options)
+		}
+	}
+	_, err = mgr.createRouter(ctx, network.ID)
 	if err != nil {
-		err2 := mgr.DeleteNetwork(network.ID)
-		err = api.NewErrorStackFromError(err, err2)
-		return nil, api.NewCreateNetworkError(err, options)
+		err2 := providers.Cleanup(func(ctx context.Context) error {
+			return mgr.DeleteNetworkWithContext(ctx, network.ID)
+		})
+		err = api.NewErrorStackFromError(UnwrapOpenStackError(err), err2)
+		return nil, api.NewCreateNetworkError(UnwrapOpenStackError(err), options)
 	}
 	return &api.Network{
 		ID:   network.ID,
 		Name: network.Name,
 		CIDR: network.Description,
+		Tags: metadataTags(options.Tags),
 	}, nil
 }
 
-//DeleteNetwork deletes the network identified by id
-func (mgr *NetworkManager) DeleteNetwork(id string) api.DeleteNetworkError {
-	r, err := mgr.findRouter(id)
+//CreateNetwork creates a network
+func (mgr *NetworkManager) CreateNetwork(options api.CreateNetworkOptions) (*api.Network, api.CreateNetworkError) {
+	return mgr.CreateNetworkWithContext(context.Background(), options)
+}
+
+//DeleteNetworkWithContext deletes the network identified by id
+func (mgr *NetworkManager) DeleteNetworkWithContext(ctx context.Context, id string) api.DeleteNetworkError {
+	r, err := mgr.findRouter(ctx, id)
 	if err != nil {
-		return api.NewDeleteNetworkError(err, id)
+		return api.NewDeleteNetworkError(UnwrapOpenStackError(err), id)
 	}
 	if r != nil && len(r.ID) > 0 {
-		err = mgr.deleteRouter(r.ID)
+		sns, err := mgr.listSubnets(ctx, id)
 		if err != nil {
-			return api.NewDeleteNetworkError(err, id)
+			return api.NewDeleteNetworkError(UnwrapOpenStackError(err), id)
+		}
+		for _, sn := range sns {
+			err = mgr.detachSubnetFromRouter(ctx, id, sn.ID)
+			if err != nil {
+				return api.NewDeleteNetworkError(UnwrapOpenStackError(err), id)
+			}
+		}
+		err = mgr.deleteRouter(ctx, r.ID)
+		if err != nil {
+			return api.NewDeleteNetworkError(UnwrapOpenStackError(err), id)
 		}
 	}
 
-	err = networks.Delete(mgr.Refactor.BaseServices.Network, id).ExtractErr()
+	err = networks.Delete(mgr.Refactor.BaseServices.network(ctx), id).ExtractErr()
 	if err != nil {
-		return api.NewDeleteNetworkError(err, id)
+		return api.NewDeleteNetworkError(UnwrapOpenStackError(err), id)
 	}
 	return nil
 }
 
+//DeleteNetwork deletes the network identified by id
+func (mgr *NetworkManager) DeleteNetwork(id string) api.DeleteNetworkError {
+	return mgr.DeleteNetworkWithContext(context.Background(), id)
+}
+
 func network(net *networks.Network) *api.Network {
 	return &api.Network{
 		ID:   net.ID,
 		Name: net.Name,
 		CIDR: net.Description,
+		Tags: tagMap(net.Tags),
 	}
 }
 
-//ListNetworks lists networks
-func (mgr *NetworkManager) ListNetworks() ([]api.Network, api.ListNetworksError) {
+//ListNetworksWithContext lists networks
+func (mgr *NetworkManager) ListNetworksWithContext(ctx context.Context) ([]api.Network, api.ListNetworksError) {
 	opts := networks.ListOpts{}
-	page, err := networks.List(mgr.Refactor.BaseServices.Network, opts).AllPages()
+	page, err := networks.List(mgr.Refactor.BaseServices.network(ctx), opts).AllPages()
 	if err != nil {
-		return nil, api.NewListNetworksError(err)
+		return nil, api.NewListNetworksError(UnwrapOpenStackError(err))
 	}
 	l, err := networks.ExtractNetworks(page)
 	if err != nil {
-		return nil, api.NewListNetworksError(err)
+		return nil, api.NewListNetworksError(UnwrapOpenStackError(err))
 	}
 	var nets []api.Network
 	for _, n := range l {
@@ -85,18 +120,28 @@ func (mgr *NetworkManager) ListNetworks() ([]api.Network, api.ListNetworksError)
 	return nets, nil
 }
 
-//GetNetwork returns the configuration of the network identified by id
-func (mgr *NetworkManager) GetNetwork(id string) (*api.Network, api.GetNetworkError) {
-	n, err := networks.Get(mgr.Refactor.BaseServices.Network, id).Extract()
+//ListNetworks lists networks
+func (mgr *NetworkManager) ListNetworks() ([]api.Network, api.ListNetworksError) {
+	return mgr.ListNetworksWithContext(context.Background())
+}
+
+//GetNetworkWithContext returns the configuration of the network identified by id
+func (mgr *NetworkManager) GetNetworkWithContext(ctx context.Context, id string) (*api.Network, api.GetNetworkError) {
+	n, err := networks.Get(mgr.Refactor.BaseServices.network(ctx), id).Extract()
 	if err != nil {
-		return nil, api.NewGetNetworkError(err, id)
+		return nil, api.NewGetNetworkError(UnwrapOpenStackError(err), id)
 	}
 	return network(n), nil
 }
 
-func (mgr *NetworkManager) createRouter(networkID string) (*routers.Router, error) {
+//GetNetwork returns the configuration of the network identified by id
+func (mgr *NetworkManager) GetNetwork(id string) (*api.Network, api.GetNetworkError) {
+	return mgr.GetNetworkWithContext(context.Background(), id)
+}
+
+func (mgr *NetworkManager) createRouter(ctx context.Context, networkID string) (*routers.Router, error) {
 	up := true
-	netID, err := networks.IDFromName(mgr.Refactor.BaseServices.Network, mgr.Refactor.Config.ExternalNetworkName)
+	netID, err := networks.IDFromName(mgr.Refactor.BaseServices.network(ctx), mgr.Refactor.Config.ExternalNetworkName)
 	if err != nil {
 		return nil, UnwrapOpenStackError(err)
 	}
@@ -110,14 +155,15 @@ func (mgr *NetworkManager) createRouter(networkID string) (*routers.Router, erro
 		GatewayInfo:  &gwi,
 	}
 
-	router, err := routers.Create(mgr.Refactor.BaseServices.Network, createOpts).Extract()
+	router, err := routers.Create(mgr.Refactor.BaseServices.network(ctx), createOpts).Extract()
 	if err != nil {
 		return nil, UnwrapOpenStackError(err)
 	}
 	return router, nil
 }
-func (mgr *NetworkManager) attachSubnetToRouter(routerID, subnetID string) error {
-	_, err := routers.AddInterface(mgr.Refactor.BaseServices.Network, routerID, routers.AddInterfaceOpts{
+
+func (mgr *NetworkManager) attachSubnetToRouter(ctx context.Context, routerID, subnetID string) error {
+	_, err := routers.AddInterface(mgr.Refactor.BaseServices.network(ctx), routerID, routers.AddInterfaceOpts{
 		SubnetID: subnetID,
 	}).Extract()
 	if err != nil {
@@ -125,13 +171,14 @@ func (mgr *NetworkManager) attachSubnetToRouter(routerID, subnetID string) error
 	}
 	return nil
 }
-func (mgr *NetworkManager) deleteRouter(id string) error {
-	err := routers.Delete(mgr.Refactor.BaseServices.Network, id).ExtractErr()
+
+func (mgr *NetworkManager) deleteRouter(ctx context.Context, id string) error {
+	err := routers.Delete(mgr.Refactor.BaseServices.network(ctx), id).ExtractErr()
 	return UnwrapOpenStackError(err)
 }
 
-func (mgr *NetworkManager) findRouter(name string) (*routers.Router, error) {
-	page, err := routers.List(mgr.Refactor.BaseServices.Network, routers.ListOpts{Name: name}).AllPages()
+func (mgr *NetworkManager) findRouter(ctx context.Context, name string) (*routers.Router, error) {
+	page, err := routers.List(mgr.Refactor.BaseServices.network(ctx), routers.ListOpts{Name: name}).AllPages()
 	if err != nil {
 		return nil, UnwrapOpenStackError(err)
 	}
@@ -148,8 +195,8 @@ func (mgr *NetworkManager) findRouter(name string) (*routers.Router, error) {
 	return &l[0], nil
 }
 
-//CreateSubnet creates a subnet
-func (mgr *NetworkManager) CreateSubnet(options api.CreateSubnetOptions) (*api.Subnet, api.CreateSubnetError) {
+//CreateSubnetWithContext creates a subnet
+func (mgr *NetworkManager) CreateSubnetWithContext(ctx context.Context, options api.CreateSubnetOptions) (*api.Subnet, api.CreateSubnetError) {
 	dhcp := true
 	opts := subnets.CreateOpts{
 		NetworkID:  options.NetworkID,
@@ -158,66 +205,117 @@ func (mgr *NetworkManager) CreateSubnet(options api.CreateSubnetOptions) (*api.S
 		Name:       options.Name,
 		EnableDHCP: &dhcp,
 	}
-	router, err := mgr.findRouter(options.Name)
+	router, err := mgr.findRouter(ctx, options.NetworkID)
 	if err != nil {
-		return nil, api.NewCreateSubnetError(err, options)
+		return nil, api.NewCreateSubnetError(UnwrapOpenStackError(err), options)
 	}
 	// Execute the operation and get back a subnets.Subnet struct
-	subnet, err := subnets.Create(mgr.Refactor.BaseServices.Network, opts).Extract()
+	subnet, err := subnets.Create(mgr.Refactor.BaseServices.network(ctx), opts).Extract()
 	if err != nil {
-		return nil, api.NewCreateSubnetError(err, options)
+		return nil, api.NewCreateSubnetError(UnwrapOpenStackError(err), options)
 	}
-
-	err = mgr.attachSubnetToRouter(router.ID, subnet.ID)
+	if len(options.Tags) > 0 {
+		err = mgr.Refactor.BaseServices.setNeutronTags(ctx, api.ResourceSubnet, subnet.ID, options.Tags)
+		if err != nil {
+			err2 := providers.Cleanup(func(ctx context.Context) error {
+				return subnets.Delete(mgr.Refactor.BaseServices.network(ctx), subnet.ID).ExtractErr()
+			})
+			return nil, api.NewCreateSubnetError(api.NewErrorStackFromError(err, UnwrapOpenStackError(err2)), options)
+		}
+		subnet.Tags = neutronTags(options.Tags)
+	}
+	if router == nil {
+		return mgr.subnet(subnet), nil
+	}
+	err = mgr.attachSubnetToRouter(ctx, router.ID, subnet.ID)
 	if err != nil {
-		err2 := mgr.DeleteSubnet(options.NetworkID, subnet.ID)
-		err = api.NewErrorStackFromError(err, err2)
-		return nil, api.NewCreateSubnetError(err, options)
+		err2 := providers.Cleanup(func(ctx context.Context) error {
+			return mgr.DeleteSubnetWithContext(ctx, options.NetworkID, subnet.ID)
+		})
+		err = api.NewErrorStackFromError(UnwrapOpenStackError(err), err2)
+		return nil, api.NewCreateSubnetError(UnwrapOpenStackError(err), options)
 	}
 
+	return mgr.subnet(subnet), nil
+}
+
+//CreateSubnet creates a subnet
+func (mgr *NetworkManager) CreateSubnet(options api.CreateSubnetOptions) (*api.Subnet, api.CreateSubnetError) {
+	return mgr.CreateSubnetWithContext(context.Background(), options)
+}
+
+func (mgr *NetworkManager) subnet(sn *subnets.Subnet) *api.Subnet {
 	return &api.Subnet{
-		ID:        subnet.ID,
-		Name:      subnet.Name,
-		IPVersion: api.IPVersion(subnet.IPVersion),
-		CIDR:      subnet.CIDR,
-		NetworkID: subnet.NetworkID,
-	}, nil
+		ID:        sn.ID,
+		Name:      sn.Name,
+		IPVersion: api.IPVersion(sn.IPVersion),
+		CIDR:      sn.CIDR,
+		NetworkID: sn.NetworkID,
+		Tags:      tagMap(sn.Tags),
+	}
+}
+
+//detachSubnetFromRouter removes the interface between the subnet and the router of its network if any
+func (mgr *NetworkManager) detachSubnetFromRouter(ctx context.Context, networkID, subnetID string) error {
+	router, err := mgr.findRouter(ctx, networkID)
+	if err != nil || router == nil {
+		return err
+	}
+	_, err = routers.RemoveInterface(mgr.Refactor.BaseServices.network(ctx), router.ID, routers.RemoveInterfaceOpts{
+		SubnetID: subnetID,
+	}).Extract()
+	if err = UnwrapOpenStackError(err); err != nil && api.ErrorKind(err) != api.ErrNotFound {
+		return err
+	}
+	return nil
+}
+
+//DeleteSubnetWithContext deletes the subnet identified by id
+func (mgr *NetworkManager) DeleteSubnetWithContext(ctx context.Context, networkID, subnetID string) api.DeleteSubnetError {
+	err := mgr.detachSubnetFromRouter(ctx, networkID, subnetID)
+	if err != nil {
+		return api.NewDeleteSubnetError(UnwrapOpenStackError(err), networkID, subnetID)
+	}
+	err = subnets.Delete(mgr.Refactor.BaseServices.network(ctx), subnetID).ExtractErr()
+	return api.NewDeleteSubnetError(UnwrapOpenStackError(err), networkID, subnetID)
 }
 
 //DeleteSubnet deletes the subnet identified by id
 func (mgr *NetworkManager) DeleteSubnet(networkID, subnetID string) api.DeleteSubnetError {
-	err := subnets.Delete(mgr.Refactor.BaseServices.Network, subnetID).ExtractErr()
-	return api.NewDeleteSubnetError(UnwrapOpenStackError(err), networkID, subnetID)
+	return mgr.DeleteSubnetWithContext(context.Background(), networkID, subnetID)
 }
 
-//ListSubnets lists the subnet
-func (mgr *NetworkManager) ListSubnets(networkID string) ([]api.Subnet, api.ListSubnetsError) {
-	page, err := subnets.List(mgr.Refactor.BaseServices.Network, subnets.ListOpts{
+func (mgr *NetworkManager) listSubnets(ctx context.Context, networkID string) ([]api.Subnet, error) {
+	page, err := subnets.List(mgr.Refactor.BaseServices.network(ctx), subnets.ListOpts{
 		NetworkID: networkID,
 	}).AllPages()
 	if err != nil {
-		return nil, api.NewListSubnetsError(UnwrapOpenStackError(err), networkID)
+		return nil, UnwrapOpenStackError(err)
 	}
 	l, err := subnets.ExtractSubnets(page)
 	if err != nil {
-		return nil, api.NewListSubnetsError(UnwrapOpenStackError(err), networkID)
+		return nil, UnwrapOpenStackError(err)
 	}
 	var res []api.Subnet
 	for _, sn := range l {
-		item := api.Subnet{
-			CIDR:      sn.CIDR,
-			ID:        sn.ID,
-			IPVersion: api.IPVersion(sn.IPVersion),
-			Name:      sn.Name,
-			NetworkID: sn.NetworkID,
-		}
-		res = append(res, item)
+		res = append(res, *mgr.subnet(&sn))
 	}
 	return res, nil
 }
 
-func (mgr *NetworkManager) listAllSubnets() ([]api.Subnet, error) {
-	page, err := subnets.List(mgr.Refactor.BaseServices.Network, subnets.ListOpts{}).AllPages()
+//ListSubnetsWithContext lists the subnet
+func (mgr *NetworkManager) ListSubnetsWithContext(ctx context.Context, networkID string) ([]api.Subnet, api.ListSubnetsError) {
+	l, err := mgr.listSubnets(ctx, networkID)
+	return l, api.NewListSubnetsError(err, networkID)
+}
+
+//ListSubnets lists the subnet
+func (mgr *NetworkManager) ListSubnets(networkID string) ([]api.Subnet, api.ListSubnetsError) {
+	return mgr.ListSubnetsWithContext(context.Background(), networkID)
+}
+
+func (mgr *NetworkManager) listAllSubnets(ctx context.Context) ([]api.Subnet, error) {
+	page, err := subnets.List(mgr.Refactor.BaseServices.network(ctx), subnets.ListOpts{}).AllPages()
 	if err != nil {
 		return nil, UnwrapOpenStackError(err)
 	}
@@ -233,15 +331,16 @@ func (mgr *NetworkManager) listAllSubnets() ([]api.Subnet, error) {
 			IPVersion: api.IPVersion(sn.IPVersion),
 			Name:      sn.Name,
 			NetworkID: sn.NetworkID,
+			Tags:      tagMap(sn.Tags),
 		}
 		res = append(res, item)
 	}
 	return res, nil
 }
 
-//GetSubnet returns the configuration of the subnet identified by id
-func (mgr *NetworkManager) GetSubnet(networkID, subnetID string) (*api.Subnet, api.GetSubnetError) {
-	sn, err := subnets.Get(mgr.Refactor.BaseServices.Network, subnetID).Extract()
+//GetSubnetWithContext returns the configuration of the subnet identified by id
+func (mgr *NetworkManager) GetSubnetWithContext(ctx context.Context, networkID, subnetID string) (*api.Subnet, api.GetSubnetError) {
+	sn, err := subnets.Get(mgr.Refactor.BaseServices.network(ctx), subnetID).Extract()
 	if err != nil {
 		return nil, api.NewGetSubnetError(UnwrapOpenStackError(err), networkID, subnetID)
 	}
@@ -251,5 +350,11 @@ func (mgr *NetworkManager) GetSubnet(networkID, subnetID string) (*api.Subnet, a
 		IPVersion: api.IPVersion(sn.IPVersion),
 		Name:      sn.Name,
 		NetworkID: sn.NetworkID,
+		Tags:      tagMap(sn.Tags),
 	}, nil
 }
+
+//GetSubnet returns the configuration of the subnet identified by id
+func (mgr *NetworkManager) GetSubnet(networkID, subnetID string) (*api.Subnet, api.GetSubnetError) {
+	return mgr.GetSubnetWithContext(context.Background(), networkID, subnetID)
+}
@@ -0,0 +1,376 @@
+package openstack
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"math"
+	"strings"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+	gc "github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack/objectstorage/v1/accounts"
+	"github.com/gophercloud/gophercloud/openstack/objectstorage/v1/containers"
+	"github.com/gophercloud/gophercloud/openstack/objectstorage/v1/objects"
+	"github.com/pkg/errors"
+)
+
+//ObjectStorageManager openstack implementation of api.ObjectStorageManager using the Swift service
+//Buckets are Swift containers identified by their name
+type ObjectStorageManager struct {
+	Provider *Provider
+}
+
+func (mgr *ObjectStorageManager) client(ctx context.Context) (*gc.ServiceClient, error) {
+	if mgr.Provider.BaseServices.ObjectStorage == nil {
+		return nil, errors.New("the object storage service is not available")
+	}
+	return mgr.Provider.BaseServices.objectStorage(ctx), nil
+}
+
+func (mgr *ObjectStorageManager) createBucket(ctx context.Context, options api.CreateBucketOptions) (*api.Bucket, error) {
+	err := api.CheckBucketOptions(&options)
+	if err != nil {
+		return nil, err
+	}
+	client, err := mgr.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	//Swift silently accepts the creation of an existing container
+	_, err = containers.Get(client, options.Name, nil).Extract()
+	if err == nil {
+		return nil, api.WithKind(errors.Errorf("container %s already exists", options.Name), api.ErrAlreadyExists)
+	}
+	if err = UnwrapOpenStackError(err); api.ErrorKind(err) != api.ErrNotFound {
+		return nil, err
+	}
+	_, err = containers.Create(client, options.Name, nil).Extract()
+	if err != nil {
+		return nil, UnwrapOpenStackError(err)
+	}
+	return &api.Bucket{
+		ID:   options.Name,
+		Name: options.Name,
+	}, nil
+}
+
+//CreateBucketWithContext creates a private Swift container
+func (mgr *ObjectStorageManager) CreateBucketWithContext(ctx context.Context, options api.CreateBucketOptions) (*api.Bucket, api.CreateBucketError) {
+	b, err := mgr.createBucket(ctx, options)
+	if err != nil {
+		return nil, api.NewCreateBucketError(err, options)
+	}
+	return b, nil
+}
+
+//CreateBucket creates a private Swift container
+func (mgr *ObjectStorageManager) CreateBucket(options api.CreateBucketOptions) (*api.Bucket, api.CreateBucketError) {
+	return mgr.CreateBucketWithContext(context.Background(), options)
+}
+
+func (mgr *ObjectStorageManager) deleteBucket(ctx context.Context, id string) error {
+	client, err := mgr.client(ctx)
+	if err != nil {
+		return err
+	}
+	//Swift refuses to delete a container that is not empty
+	objs, err := mgr.listObjects(ctx, api.ListObjectsOptions{BucketID: id})
+	if err != nil {
+		return err
+	}
+	for _, o := range objs {
+		_, err = objects.Delete(client, id, o.Key, nil).Extract()
+		if err != nil && api.ErrorKind(UnwrapOpenStackError(err)) != api.ErrNotFound {
+			return UnwrapOpenStackError(err)
+		}
+	}
+	_, err = containers.Delete(client, id).Extract()
+	return UnwrapOpenStackError(err)
+}
+
+//DeleteBucketWithContext deletes the objects of the Swift container identified by id then the container
+func (mgr *ObjectStorageManager) DeleteBucketWithContext(ctx context.Context, id string) api.DeleteBucketError {
+	return api.NewDeleteBucketError(mgr.deleteBucket(ctx, id), id)
+}
+
+//DeleteBucket deletes the objects of the Swift container identified by id then the container
+func (mgr *ObjectStorageManager) DeleteBucket(id string) api.DeleteBucketError {
+	return mgr.DeleteBucketWithContext(context.Background(), id)
+}
+
+func (mgr *ObjectStorageManager) listBuckets(ctx context.Context) ([]api.Bucket, error) {
+	client, err := mgr.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	page, err := containers.List(client, containers.ListOpts{Full: true}).AllPages()
+	if err != nil {
+		return nil, UnwrapOpenStackError(err)
+	}
+	l, err := containers.ExtractInfo(page)
+	if err != nil {
+		return nil, err
+	}
+	buckets := []api.Bucket{}
+	for _, c := range l {
+		buckets = append(buckets, api.Bucket{
+			ID:   c.Name,
+			Name: c.Name,
+		})
+	}
+	return buckets, nil
+}
+
+//ListBucketsWithContext lists the Swift containers of the project
+func (mgr *ObjectStorageManager) ListBucketsWithContext(ctx context.Context) ([]api.Bucket, api.ListBucketsError) {
+	buckets, err := mgr.listBuckets(ctx)
+	if err != nil {
+		return nil, api.NewListBucketsError(err)
+	}
+	return buckets, nil
+}
+
+//ListBuckets lists the Swift containers of the project
+func (mgr *ObjectStorageManager) ListBuckets() ([]api.Bucket, api.ListBucketsError) {
+	return mgr.ListBucketsWithContext(context.Background())
+}
+
+func (mgr *ObjectStorageManager) getBucket(ctx context.Context, id string) (*api.Bucket, error) {
+	client, err := mgr.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	_, err = containers.Get(client, id, nil).Extract()
+	if err != nil {
+		return nil, UnwrapOpenStackError(err)
+	}
+	return &api.Bucket{
+		ID:   id,
+		Name: id,
+	}, nil
+}
+
+//GetBucketWithContext returns the Swift container identified by id
+func (mgr *ObjectStorageManager) GetBucketWithContext(ctx context.Context, id string) (*api.Bucket, api.GetBucketError) {
+	b, err := mgr.getBucket(ctx, id)
+	if err != nil {
+		return nil, api.NewGetBucketError(err, id)
+	}
+	return b, nil
+}
+
+//GetBucket returns the Swift container identified by id
+func (mgr *ObjectStorageManager) GetBucket(id string) (*api.Bucket, api.GetBucketError) {
+	return mgr.GetBucketWithContext(context.Background(), id)
+}
+
+func (mgr *ObjectStorageManager) putObject(ctx context.Context, options api.PutObjectOptions) (*api.Object, error) {
+	err := api.CheckObjectKey(options.Key)
+	if err != nil {
+		return nil, err
+	}
+	client, err := mgr.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	contentType := options.ContentType
+	if contentType == "" {
+		contentType = api.DefaultContentType
+	}
+	//NoETag streams the content instead of buffering it to compute its MD5 sum
+	_, err = objects.Create(client, options.BucketID, options.Key, objects.CreateOpts{
+		Content:     options.Content,
+		ContentType: contentType,
+		NoETag:      true,
+	}).Extract()
+	if err != nil {
+		return nil, UnwrapOpenStackError(err)
+	}
+	h, err := objects.Get(client, options.BucketID, options.Key, nil).Extract()
+	if err != nil {
+		return nil, UnwrapOpenStackError(err)
+	}
+	return &api.Object{
+		BucketID:     options.BucketID,
+		Key:          options.Key,
+		Size:         h.ContentLength,
+		ContentType:  h.ContentType,
+		ETag:         strings.Trim(h.ETag, `"`),
+		LastModified: h.LastModified,
+	}, nil
+}
+
+//PutObjectWithContext creates or replaces a Swift object
+func (mgr *ObjectStorageManager) PutObjectWithContext(ctx context.Context, options api.PutObjectOptions) (*api.Object, api.PutObjectError) {
+	o, err := mgr.putObject(ctx, options)
+	if err != nil {
+		return nil, api.NewPutObjectError(err, options)
+	}
+	return o, nil
+}
+
+//PutObject creates or replaces a Swift object
+func (mgr *ObjectStorageManager) PutObject(options api.PutObjectOptions) (*api.Object, api.PutObjectError) {
+	return mgr.PutObjectWithContext(context.Background(), options)
+}
+
+func (mgr *ObjectStorageManager) getObject(ctx context.Context, bucketID, key string) (*api.ObjectReader, error) {
+	client, err := mgr.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := objects.Download(client, bucketID, key, nil)
+	h, err := res.Extract()
+	if err != nil {
+		if res.Body != nil {
+			_ = res.Body.Close()
+		}
+		return nil, UnwrapOpenStackError(err)
+	}
+	return &api.ObjectReader{
+		Object: api.Object{
+			BucketID:     bucketID,
+			Key:          key,
+			Size:         h.ContentLength,
+			ContentType:  h.ContentType,
+			ETag:         strings.Trim(h.ETag, `"`),
+			LastModified: h.LastModified,
+		},
+		ReadCloser: res.Body,
+	}, nil
+}
+
+//GetObjectWithContext returns a reader streaming the content of a Swift object
+func (mgr *ObjectStorageManager) GetObjectWithContext(ctx context.Context, bucketID string, key string) (*api.ObjectReader, api.GetObjectError) {
+	r, err := mgr.getObject(ctx, bucketID, key)
+	if err != nil {
+		return nil, api.NewGetObjectError(err, bucketID, key)
+	}
+	return r, nil
+}
+
+//GetObject returns a reader streaming the content of a Swift object
+func (mgr *ObjectStorageManager) GetObject(bucketID string, key string) (*api.ObjectReader, api.GetObjectError) {
+	return mgr.GetObjectWithContext(context.Background(), bucketID, key)
+}
+
+func (mgr *ObjectStorageManager) listObjects(ctx context.Context, options api.ListObjectsOptions) ([]api.Object, error) {
+	client, err := mgr.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	page, err := objects.List(client, options.BucketID, objects.ListOpts{
+		Full:   true,
+		Prefix: options.Prefix,
+	}).AllPages()
+	if err != nil {
+		return nil, UnwrapOpenStackError(err)
+	}
+	l, err := objects.ExtractInfo(page)
+	if err != nil {
+		return nil, err
+	}
+	objs := []api.Object{}
+	for _, o := range l {
+		objs = append(objs, api.Object{
+			BucketID:     options.BucketID,
+			Key:          o.Name,
+			Size:         o.Bytes,
+			ContentType:  o.ContentType,
+			ETag:         o.Hash,
+			LastModified: o.LastModified,
+		})
+	}
+	return objs, nil
+}
+
+//ListObjectsWithContext lists the objects of a Swift container sorted by name
+func (mgr *ObjectStorageManager) ListObjectsWithContext(ctx context.Context, options api.ListObjectsOptions) ([]api.Object, api.ListObjectsError) {
+	objs, err := mgr.listObjects(ctx, options)
+	if err != nil {
+		return nil, api.NewListObjectsError(err, options)
+	}
+	return objs, nil
+}
+
+//ListObjects lists the objects of a Swift container sorted by name
+func (mgr *ObjectStorageManager) ListObjects(options api.ListObjectsOptions) ([]api.Object, api.ListObjectsError) {
+	return mgr.ListObjectsWithContext(context.Background(), options)
+}
+
+func (mgr *ObjectStorageManager) deleteObject(ctx context.Context, bucketID, key string) error {
+	client, err := mgr.client(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = objects.Delete(client, bucketID, key, nil).Extract()
+	return UnwrapOpenStackError(err)
+}
+
+//DeleteObjectWithContext deletes a Swift object
+func (mgr *ObjectStorageManager) DeleteObjectWithContext(ctx context.Context, bucketID string, key string) api.DeleteObjectError {
+	return api.NewDeleteObjectError(mgr.deleteObject(ctx, bucketID, key), bucketID, key)
+}
+
+//DeleteObject deletes a Swift object
+func (mgr *ObjectStorageManager) DeleteObject(bucketID string, key string) api.DeleteObjectError {
+	return mgr.DeleteObjectWithContext(context.Background(), bucketID, key)
+}
+
+//tempURLKey sets a random temporary URL key on the account if it does not have one yet
+func tempURLKey(client *gc.ServiceClient) error {
+	h, err := accounts.Get(client, nil).Extract()
+	if err != nil {
+		return err
+	}
+	if h.TempURLKey != "" {
+		return nil
+	}
+	b := make([]byte, 32)
+	_, err = rand.Read(b)
+	if err != nil {
+		return err
+	}
+	_, err = accounts.Update(client, accounts.UpdateOpts{TempURLKey: hex.EncodeToString(b)}).Extract()
+	return err
+}
+
+func (mgr *ObjectStorageManager) presignURL(ctx context.Context, options api.PresignURLOptions) (string, error) {
+	err := api.CheckPresignURLOptions(&options)
+	if err != nil {
+		return "", err
+	}
+	client, err := mgr.client(ctx)
+	if err != nil {
+		return "", err
+	}
+	err = tempURLKey(client)
+	if err != nil {
+		return "", UnwrapOpenStackError(err)
+	}
+	u, err := objects.CreateTempURL(client, options.BucketID, options.Key, objects.CreateTempURLOpts{
+		Method: objects.HTTPMethod(options.Method),
+		TTL:    int(math.Ceil(options.Expiry.Seconds())),
+	})
+	if err != nil {
+		return "", UnwrapOpenStackError(err)
+	}
+	return u, nil
+}
+
+//PresignURLWithContext returns a temporary URL granting the operation defined by options until it expires
+//A random temporary URL key is set on the account if it does not have one yet
+func (mgr *ObjectStorageManager) PresignURLWithContext(ctx context.Context, options api.PresignURLOptions) (string, api.PresignURLError) {
+	u, err := mgr.presignURL(ctx, options)
+	if err != nil {
+		return "", api.NewPresignURLError(err, options)
+	}
+	return u, nil
+}
+
+//PresignURL returns a temporary URL granting the operation defined by options until it expires
+//A random temporary URL key is set on the account if it does not have one yet
+func (mgr *ObjectStorageManager) PresignURL(options api.PresignURLOptions) (string, api.PresignURLError) {
+	return mgr.PresignURLWithContext(context.Background(), options)
+}
@@ -0,0 +1,21 @@
+package openstack_test
+
+import (
+	"testing"
+
+	"github.com/SebastienDorgan/anyclouds/tests"
+	"github.com/stretchr/testify/suite"
+)
+
+type OSObjectStorageManagerTestSuite struct {
+	tests.ObjectStorageManagerTestSuite
+}
+
+//SetupSuite set up object storage manager
+func (suite *OSObjectStorageManagerTestSuite) SetupSuite() {
+	suite.Prov = GetProvider()
+}
+
+func TestOSObjectStorageManagerTestSuite(t *testing.T) {
+	suite.Run(t, new(OSObjectStorageManagerTestSuite))
+}
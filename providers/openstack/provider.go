@@ -1,10 +1,15 @@
 package openstack
 
 import (
+	"context"
 	"github.com/gophercloud/gophercloud/openstack/networking/v2/networks"
 	"io"
+	"net/http"
+	"strings"
+	"time"
 
 	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/SebastienDorgan/anyclouds/providers"
 	"github.com/spf13/viper"
 
 	gc "github.com/gophercloud/gophercloud"
@@ -12,6 +17,12 @@ import (
 	"github.com/pkg/errors"
 )
 
+func init() {
+	providers.Register("openstack", func() api.Provider {
+		return &Provider{}
+	})
+}
+
 /*Config fields are the union of those recognized by each Provider identity implementation and
 provider.
 */
@@ -31,8 +42,8 @@ type Config struct {
 	// Exactly one of Password or APIKey is required for the Identity V2 and V3
 	// APIs. Consult with your provider's control panel to discover your account's
 	// preferred method of authentication.
-	Password string
-	APIKey   string
+	Password string `redact:"true"`
+	APIKey   string `redact:"true"`
 
 	// At most one of DomainID and DomainName must be provided if using Username
 	// with Identity V3. Otherwise, either are optional.
@@ -60,7 +71,7 @@ type Config struct {
 
 	// TokenID allows users to authenticate (possibly as another user) with an
 	// authentication token ID.
-	TokenID string
+	TokenID string `redact:"true"`
 
 	//Openstack region (data center) where the infrastructure will be created
 	Region string
@@ -71,45 +82,169 @@ type Config struct {
 
 	//Name of the external network used to get public ip addresses
 	ExternalNetworkName string
+
+	//ServerGroupReconcileInterval interval between two reconciliations of a server group, defaults to 1 minute
+	ServerGroupReconcileInterval time.Duration
 }
 
-//UnwrapOpenStackError creates an error string from openstack api error
+//UnwrapOpenStackError creates an error string from openstack api error annotated with its api error kind
 func UnwrapOpenStackError(err error) error {
 	if err == nil {
 		return nil
 	}
+	if resp := unexpectedResponseCode(err); resp != nil {
+		body := string(resp.Body[:])
+		return api.WithKind(errors.Errorf("code: %d, reason: %s", resp.Actual, body), openStackErrorKind(resp.Actual, body))
+	}
+	//err wraps an OpenStack error, the messages of the wrappers are kept
+	if resp := unexpectedResponseCode(errors.Cause(err)); resp != nil {
+		return api.WithKind(err, openStackErrorKind(resp.Actual, string(resp.Body[:])))
+	}
+	return err
+}
+
+//unexpectedResponseCode returns the unexpected response code error carried by err or nil
+func unexpectedResponseCode(err error) *gc.ErrUnexpectedResponseCode {
 	switch e := err.(type) {
+	case gc.ErrDefault400:
+		return &e.ErrUnexpectedResponseCode
+	case *gc.ErrDefault400:
+		return &e.ErrUnexpectedResponseCode
 	case gc.ErrDefault401:
-		return errors.Errorf("code: 401, reason: %s", string(e.Body[:]))
+		return &e.ErrUnexpectedResponseCode
 	case *gc.ErrDefault401:
-		return errors.Errorf("code: 401, reason: %s", string(e.Body[:]))
+		return &e.ErrUnexpectedResponseCode
+	case gc.ErrDefault403:
+		return &e.ErrUnexpectedResponseCode
+	case *gc.ErrDefault403:
+		return &e.ErrUnexpectedResponseCode
 	case gc.ErrDefault404:
-		return errors.Errorf("code: 404, reason: %s", string(e.Body[:]))
+		return &e.ErrUnexpectedResponseCode
 	case *gc.ErrDefault404:
-		return errors.Errorf("code: 404, reason: %s", string(e.Body[:]))
+		return &e.ErrUnexpectedResponseCode
+	case gc.ErrDefault405:
+		return &e.ErrUnexpectedResponseCode
+	case *gc.ErrDefault405:
+		return &e.ErrUnexpectedResponseCode
+	case gc.ErrDefault408:
+		return &e.ErrUnexpectedResponseCode
+	case *gc.ErrDefault408:
+		return &e.ErrUnexpectedResponseCode
+	case gc.ErrDefault409:
+		return &e.ErrUnexpectedResponseCode
+	case *gc.ErrDefault409:
+		return &e.ErrUnexpectedResponseCode
+	case gc.ErrDefault429:
+		return &e.ErrUnexpectedResponseCode
+	case *gc.ErrDefault429:
+		return &e.ErrUnexpectedResponseCode
 	case gc.ErrDefault500:
-		return errors.Errorf("code: 500, reason: %s", string(e.Body[:]))
+		return &e.ErrUnexpectedResponseCode
 	case *gc.ErrDefault500:
-		return errors.Errorf("code: 500, reason: %s", string(e.Body[:]))
+		return &e.ErrUnexpectedResponseCode
+	case gc.ErrDefault503:
+		return &e.ErrUnexpectedResponseCode
+	case *gc.ErrDefault503:
+		return &e.ErrUnexpectedResponseCode
 	case gc.ErrUnexpectedResponseCode:
-		return errors.Errorf("code: %d, reason: %s", e.Actual, string(e.Body[:]))
+		return &e
 	case *gc.ErrUnexpectedResponseCode:
-		return errors.Errorf("code: %d, reason: %s", e.Actual, string(e.Body[:]))
-	default:
 		return e
 	}
+	return nil
+}
+
+//openStackErrorKind returns the api error kind matching an OpenStack response code
+func openStackErrorKind(code int, body string) error {
+	lbody := strings.ToLower(body)
+	quota := strings.Contains(lbody, "quota") || strings.Contains(lbody, "limit exceeded")
+	switch code {
+	case http.StatusBadRequest:
+		return api.ErrInvalidArgument
+	case http.StatusUnauthorized:
+		return api.ErrUnauthorized
+	case http.StatusForbidden, http.StatusRequestEntityTooLarge:
+		if quota {
+			return api.ErrQuotaExceeded
+		}
+		if code == http.StatusForbidden {
+			return api.ErrUnauthorized
+		}
+	case http.StatusNotFound:
+		return api.ErrNotFound
+	case http.StatusConflict:
+		if quota {
+			return api.ErrQuotaExceeded
+		}
+		if strings.Contains(lbody, "already") || strings.Contains(lbody, "exist") || strings.Contains(lbody, "duplicate") {
+			return api.ErrAlreadyExists
+		}
+	case http.StatusTooManyRequests:
+		return api.ErrThrottled
+	}
+	return nil
+}
+
+//notFoundError returns an error of kind api.ErrNotFound, used when OpenStack returns an empty result instead of an error
+func notFoundError(format string, args ...interface{}) error {
+	return api.WithKind(errors.Errorf(format, args...), api.ErrNotFound)
 }
 
 type BaseServices struct {
-	client  *gc.ProviderClient
-	Compute *gc.ServiceClient
-	Network *gc.ServiceClient
-	Volume  *gc.ServiceClient
+	client   *gc.ProviderClient
+	Identity *gc.ServiceClient
+	Compute  *gc.ServiceClient
+	Network  *gc.ServiceClient
+	Volume   *gc.ServiceClient
+	//LoadBalancer nil if the cloud does not provide the Octavia service
+	LoadBalancer *gc.ServiceClient
+	//DNS nil if the cloud does not provide the Designate service
+	DNS *gc.ServiceClient
+	//ObjectStorage nil if the cloud does not provide the Swift service
+	ObjectStorage *gc.ServiceClient
+}
+
+//withContext returns a copy of client sending its requests with ctx
+func withContext(ctx context.Context, client *gc.ServiceClient) *gc.ServiceClient {
+	pc := *client.ProviderClient
+	pc.Context = ctx
+	sc := *client
+	sc.ProviderClient = &pc
+	return &sc
+}
+
+func (s *BaseServices) identity(ctx context.Context) *gc.ServiceClient {
+	return withContext(ctx, s.Identity)
+}
+
+func (s *BaseServices) compute(ctx context.Context) *gc.ServiceClient {
+	return withContext(ctx, s.Compute)
+}
+
+func (s *BaseServices) network(ctx context.Context) *gc.ServiceClient {
+	return withContext(ctx, s.Network)
+}
+
+func (s *BaseServices) volume(ctx context.Context) *gc.ServiceClient {
+	return withContext(ctx, s.Volume)
+}
+
+func (s *BaseServices) loadBalancer(ctx context.Context) *gc.ServiceClient {
+	return withContext(ctx, s.LoadBalancer)
+}
+
+func (s *BaseServices) dns(ctx context.Context) *gc.ServiceClient {
+	return withContext(ctx, s.DNS)
+}
+
+func (s *BaseServices) objectStorage(ctx context.Context) *gc.ServiceClient {
+	return withContext(ctx, s.ObjectStorage)
 }
 
 type Configuration struct {
-	ExternalNetworkName string
-	ExternalNetworkID   string
+	ExternalNetworkName          string
+	ExternalNetworkID            string
+	ServerGroupReconcileInterval time.Duration
 }
 
 //Provider Provider provider
@@ -125,6 +260,16 @@ type Provider struct {
 	SecurityGroupManager     SecurityGroupManager
 	VolumeManager            VolumeManager
 	PublicIPAddressManager   PublicIPManager
+	TagManager               TagManager
+	SnapshotManager          SnapshotManager
+	LoadBalancerManager      LoadBalancerManager
+	DNSManager               DNSManager
+	ObjectStorageManager     ObjectStorageManager
+	ServerGroupManager       ServerGroupManager
+	LocationManager          LocationManager
+	QuotaManager             QuotaManager
+
+	config Config
 }
 
 //Init initialize Provider Provider
@@ -140,6 +285,11 @@ func (p *Provider) Init(config io.Reader, format string) error {
 	if err != nil {
 		return errors.Wrap(err, "error reading provider configuration")
 	}
+	return p.init(&cfg)
+}
+
+//init authenticates the provider and creates the clients and the managers of the provider
+func (p *Provider) init(cfg *Config) error {
 	opts := gc.AuthOptions{
 		IdentityEndpoint: cfg.IdentityEndpoint,
 		Username:         cfg.Username,
@@ -154,10 +304,16 @@ func (p *Provider) Init(config io.Reader, format string) error {
 	}
 
 	// Openstack client
+	var err error
 	p.BaseServices.client, err = openstack.AuthenticatedClient(opts)
 	if err != nil {
 		return errors.Wrap(UnwrapOpenStackError(err), "Error initializing openstack driver")
 	}
+	//Identity API
+	p.BaseServices.Identity, err = openstack.NewIdentityV3(p.BaseServices.client, gc.EndpointOpts{})
+	if err != nil {
+		return errors.Wrap(UnwrapOpenStackError(err), "Error initializing openstack driver")
+	}
 	// Compute API
 	p.BaseServices.Compute, err = openstack.NewComputeV2(p.BaseServices.client, gc.EndpointOpts{
 		Region: cfg.Region,
@@ -180,6 +336,27 @@ func (p *Provider) Init(config io.Reader, format string) error {
 	if err != nil {
 		return errors.Wrap(UnwrapOpenStackError(err), "Error initializing openstack driver")
 	}
+	//Load balancer API, optional
+	p.BaseServices.LoadBalancer, err = openstack.NewLoadBalancerV2(p.BaseServices.client, gc.EndpointOpts{
+		Region: cfg.Region,
+	})
+	if err != nil {
+		p.BaseServices.LoadBalancer = nil
+	}
+	//DNS API, optional
+	p.BaseServices.DNS, err = openstack.NewDNSV2(p.BaseServices.client, gc.EndpointOpts{
+		Region: cfg.Region,
+	})
+	if err != nil {
+		p.BaseServices.DNS = nil
+	}
+	//Object storage API, optional
+	p.BaseServices.ObjectStorage, err = openstack.NewObjectStorageV1(p.BaseServices.client, gc.EndpointOpts{
+		Region: cfg.Region,
+	})
+	if err != nil {
+		p.BaseServices.ObjectStorage = nil
+	}
 
 	p.ImagesManager.Provider = p
 	p.NetworkManager.Refactor = p
@@ -190,8 +367,18 @@ func (p *Provider) Init(config io.Reader, format string) error {
 	p.SecurityGroupManager.Provider = p
 	p.KeyPairManager.Provider = p
 	p.PublicIPAddressManager.OpenStack = p
+	p.TagManager.Provider = p
+	p.SnapshotManager.Provider = p
+	p.LoadBalancerManager.Provider = p
+	p.DNSManager.Provider = p
+	p.ObjectStorageManager.Provider = p
+	p.ServerGroupManager.Provider = p
+	p.LocationManager.Provider = p
+	p.QuotaManager.Provider = p
+	p.config = *cfg
 
 	p.Config.ExternalNetworkName = cfg.ExternalNetworkName
+	p.Config.ServerGroupReconcileInterval = cfg.ServerGroupReconcileInterval
 	extNetID, err := networks.IDFromName(p.BaseServices.Network, p.Config.ExternalNetworkName)
 	p.Config.ExternalNetworkID = extNetID
 	return errors.Wrap(UnwrapOpenStackError(err), "Error initializing openstack driver")
@@ -230,6 +417,52 @@ func (p *Provider) GetServerManager() api.ServerManager {
 func (p *Provider) GetVolumeManager() api.VolumeManager {
 	return &p.VolumeManager
 }
+
 func (p *Provider) GetPublicIPAddressManager() api.PublicIPManager {
 	return &p.PublicIPAddressManager
 }
+
+//GetTagManager returns an Provider TagManager
+func (p *Provider) GetTagManager() api.TagManager {
+	return &p.TagManager
+}
+
+//GetSnapshotManager returns an Provider SnapshotManager
+func (p *Provider) GetSnapshotManager() api.SnapshotManager {
+	return &p.SnapshotManager
+}
+
+//GetKeyPairManager returns an Provider KeyPairManager
+func (p *Provider) GetKeyPairManager() api.KeyPairManager {
+	return &p.KeyPairManager
+}
+
+//GetLoadBalancerManager returns an Provider LoadBalancerManager
+func (p *Provider) GetLoadBalancerManager() api.LoadBalancerManager {
+	return &p.LoadBalancerManager
+}
+
+//GetDNSManager returns an Provider DNSManager
+func (p *Provider) GetDNSManager() api.DNSManager {
+	return &p.DNSManager
+}
+
+//GetObjectStorageManager returns an Provider ObjectStorageManager
+func (p *Provider) GetObjectStorageManager() api.ObjectStorageManager {
+	return &p.ObjectStorageManager
+}
+
+//GetServerGroupManager returns an Provider ServerGroupManager
+func (p *Provider) GetServerGroupManager() api.ServerGroupManager {
+	return &p.ServerGroupManager
+}
+
+//GetLocationManager returns an Provider LocationManager
+func (p *Provider) GetLocationManager() api.LocationManager {
+	return &p.LocationManager
+}
+
+//GetQuotaManager returns an Provider QuotaManager
+func (p *Provider) GetQuotaManager() api.QuotaManager {
+	return &p.QuotaManager
+}
@@ -1,21 +1,43 @@
 package openstack_test
 
 import (
-	"github.com/SebastienDorgan/anyclouds/providers/openstack"
+	"io"
 	"os"
 	"os/user"
 	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/SebastienDorgan/anyclouds/providers/openstack"
+	"github.com/SebastienDorgan/anyclouds/providers/openstack/fake"
 	"github.com/stretchr/testify/assert"
 )
 
+//IsFake returns true if the tests run against the OpenStack fake, i.e. if ~/.anyclouds/openstack.json does not exist
+func IsFake() bool {
+	usr, _ := user.Current()
+	_, err := os.Stat(filepath.Join(usr.HomeDir, ".anyclouds/openstack.json"))
+	return err != nil
+}
+
+//GetConfig returns the content of ~/.anyclouds/openstack.json if it exists, otherwise a configuration targeting a new OpenStack fake
+//The fake is left running until the end of the tests
+func GetConfig() (io.Reader, error) {
+	if IsFake() {
+		srv := fake.NewServer()
+		return strings.NewReader(srv.Config()), nil
+	}
+	usr, _ := user.Current()
+	return os.Open(filepath.Join(usr.HomeDir, ".anyclouds/openstack.json"))
+}
+
 func GetProvider() *openstack.Provider {
 	var provider openstack.Provider
-	usr, _ := user.Current()
-	path := filepath.Join(usr.HomeDir, ".anyclouds/openstack.json")
-	file, err := os.Open(path)
-	err = provider.Init(file, "json")
+	cfg, err := GetConfig()
+	if err != nil {
+		return nil
+	}
+	err = provider.Init(cfg, "json")
 	if err != nil {
 		return nil
 	}
@@ -25,11 +47,9 @@ func GetProvider() *openstack.Provider {
 //TestCreate create Provider provider
 func TestCreate(t *testing.T) {
 	var provider openstack.Provider
-	usr, _ := user.Current()
-	path := filepath.Join(usr.HomeDir, ".anyclouds/openstack.json")
-	file, err := os.Open(path)
+	cfg, err := GetConfig()
 	assert.NoError(t, err)
-	err = provider.Init(file, "json")
+	err = provider.Init(cfg, "json")
 	assert.NoError(t, err)
 	images, err := provider.GetImageManager().List()
 	assert.NoError(t, err)
@@ -1,8 +1,10 @@
 package openstack
 
 import (
+	"context"
 	"github.com/SebastienDorgan/anyclouds/api"
 	"github.com/SebastienDorgan/anyclouds/iputils"
+	"github.com/SebastienDorgan/anyclouds/providers"
 	"github.com/SebastienDorgan/talgo"
 	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/layer3/floatingips"
 	"github.com/gophercloud/gophercloud/openstack/networking/v2/ports"
@@ -13,7 +15,8 @@ type PublicIPManager struct {
 	OpenStack *Provider
 }
 
-func (mgr *PublicIPManager) ListAvailablePools() ([]api.PublicIPPool, api.ListAvailablePublicIPPoolsError) {
+//ListAvailablePoolsWithContext context aware version of ListAvailablePools
+func (mgr *PublicIPManager) ListAvailablePoolsWithContext(ctx context.Context) ([]api.PublicIPPool, api.ListAvailablePublicIPPoolsError) {
 	subnets, err := mgr.OpenStack.GetNetworkManager().ListSubnets(mgr.OpenStack.Config.ExternalNetworkID)
 	if err != nil {
 		return nil, api.NewListAvailablePublicIPPoolsError(UnwrapOpenStackError(err))
@@ -40,20 +43,25 @@ func (mgr *PublicIPManager) ListAvailablePools() ([]api.PublicIPPool, api.ListAv
 	return pools, nil
 }
 
-func checkPublicIP(nis []api.NetworkInterface, fip *floatingips.FloatingIP) bool {
-	if nis == nil {
+func (mgr *PublicIPManager) ListAvailablePools() ([]api.PublicIPPool, api.ListAvailablePublicIPPoolsError) {
+	return mgr.ListAvailablePoolsWithContext(context.Background())
+}
+
+func checkPublicIP(options *api.ListPublicIPsOptions, nis []api.NetworkInterface, fip *floatingips.FloatingIP) bool {
+	if options == nil || options.ServerID == nil {
 		return true
 	}
 	for _, ni := range nis {
-		if ni.PublicIPAddress == fip.FloatingIP {
+		if ni.ID == fip.PortID {
 			return true
 		}
 	}
 	return false
 }
 
-func (mgr *PublicIPManager) List(options *api.ListPublicIPsOptions) ([]api.PublicIP, api.ListPublicIPsError) {
-	pages, err := floatingips.List(mgr.OpenStack.BaseServices.Network, floatingips.ListOpts{}).AllPages()
+//ListWithContext context aware version of List
+func (mgr *PublicIPManager) ListWithContext(ctx context.Context, options *api.ListPublicIPsOptions) ([]api.PublicIP, api.ListPublicIPsError) {
+	pages, err := floatingips.List(mgr.OpenStack.BaseServices.network(ctx), floatingips.ListOpts{}).AllPages()
 	if err != nil {
 		return nil, api.NewListPublicIPsError(UnwrapOpenStackError(err), options)
 	}
@@ -62,8 +70,8 @@ func (mgr *PublicIPManager) List(options *api.ListPublicIPsOptions) ([]api.Publi
 		return nil, api.NewListPublicIPsError(UnwrapOpenStackError(err), options)
 	}
 	var nis []api.NetworkInterface
-	if options != nil {
-		nis, err = mgr.OpenStack.NetworkInterfacesManager.List(&api.ListNetworkInterfacesOptions{
+	if options != nil && options.ServerID != nil {
+		nis, err = mgr.OpenStack.NetworkInterfacesManager.ListWithContext(ctx, &api.ListNetworkInterfacesOptions{
 			ServerID: options.ServerID,
 		})
 		if err != nil {
@@ -72,13 +80,19 @@ func (mgr *PublicIPManager) List(options *api.ListPublicIPsOptions) ([]api.Publi
 	}
 	var publicIPs []api.PublicIP
 	for _, fip := range fips {
-		if checkPublicIP(nis, &fip) {
+		if checkPublicIP(options, nis, &fip) {
 			publicIPs = append(publicIPs, *toPublicIP(&fip))
 		}
 	}
 	return publicIPs, nil
 }
-func (mgr *PublicIPManager) Create(options api.CreatePublicIPOptions) (*api.PublicIP, api.CreatePublicIPError) {
+
+func (mgr *PublicIPManager) List(options *api.ListPublicIPsOptions) ([]api.PublicIP, api.ListPublicIPsError) {
+	return mgr.ListWithContext(context.Background(), options)
+}
+
+//CreateWithContext context aware version of Create
+func (mgr *PublicIPManager) CreateWithContext(ctx context.Context, options api.CreatePublicIPOptions) (*api.PublicIP, api.CreatePublicIPError) {
 	var ipAddress string
 	if options.IPAddress != nil {
 		ipAddress = *options.IPAddress
@@ -87,7 +101,7 @@ func (mgr *PublicIPManager) Create(options api.CreatePublicIPOptions) (*api.Publ
 	if options.IPAddressPoolID != nil {
 		ipAddressPool = *options.IPAddressPoolID
 	}
-	fip, err := floatingips.Create(mgr.OpenStack.BaseServices.Network, &floatingips.CreateOpts{
+	fip, err := floatingips.Create(mgr.OpenStack.BaseServices.network(ctx), &floatingips.CreateOpts{
 		Description:       options.Name,
 		FloatingNetworkID: mgr.OpenStack.Config.ExternalNetworkID,
 		FloatingIP:        ipAddress,
@@ -96,19 +110,34 @@ func (mgr *PublicIPManager) Create(options api.CreatePublicIPOptions) (*api.Publ
 	if err != nil {
 		return nil, api.NewCreatePublicIPError(UnwrapOpenStackError(err), options)
 	}
+	if len(options.Tags) > 0 {
+		err = mgr.OpenStack.BaseServices.setNeutronTags(ctx, api.ResourcePublicIP, fip.ID, options.Tags)
+		if err != nil {
+			err2 := providers.Cleanup(func(ctx context.Context) error {
+				return mgr.DeleteWithContext(ctx, fip.ID)
+			})
+			return nil, api.NewCreatePublicIPError(api.NewErrorStackFromError(err, err2), options)
+		}
+	}
 	return &api.PublicIP{
 		ID:      fip.ID,
 		Name:    fip.Description,
 		Address: fip.FloatingIP,
+		Tags:    metadataTags(options.Tags),
 	}, nil
 }
 
-func (mgr *PublicIPManager) Associate(options api.AssociatePublicIPOptions) api.AssociatePublicIPError {
-	fip, err := floatingips.Get(mgr.OpenStack.BaseServices.Network, options.PublicIPId).Extract()
+func (mgr *PublicIPManager) Create(options api.CreatePublicIPOptions) (*api.PublicIP, api.CreatePublicIPError) {
+	return mgr.CreateWithContext(context.Background(), options)
+}
+
+//AssociateWithContext context aware version of Associate
+func (mgr *PublicIPManager) AssociateWithContext(ctx context.Context, options api.AssociatePublicIPOptions) api.AssociatePublicIPError {
+	fip, err := floatingips.Get(mgr.OpenStack.BaseServices.network(ctx), options.PublicIPId).Extract()
 	if err != nil {
 		return api.NewAssociatePublicIPError(UnwrapOpenStackError(err), options)
 	}
-	portList, err := mgr.listPorts(options.ServerID)
+	portList, err := mgr.listPorts(ctx, options.ServerID)
 	if err != nil {
 		return api.NewAssociatePublicIPError(UnwrapOpenStackError(err), options)
 	}
@@ -137,7 +166,11 @@ func (mgr *PublicIPManager) Associate(options api.AssociatePublicIPOptions) api.
 		}
 		selectedPorts = selection
 	}
-	_, err = floatingips.Update(mgr.OpenStack.BaseServices.Network, options.PublicIPId, floatingips.UpdateOpts{
+	if len(selectedPorts) == 0 {
+		err := notFoundError("no network interface of server %s matches subnet '%s' and address '%s'", options.ServerID, options.SubnetID, options.PrivateIP)
+		return api.NewAssociatePublicIPError(err, options)
+	}
+	_, err = floatingips.Update(mgr.OpenStack.BaseServices.network(ctx), options.PublicIPId, floatingips.UpdateOpts{
 		Description: &fip.Description,
 		PortID:      &portList[selectedPorts[0]].ID,
 		FixedIP:     options.PrivateIP,
@@ -146,9 +179,13 @@ func (mgr *PublicIPManager) Associate(options api.AssociatePublicIPOptions) api.
 
 }
 
-func (mgr *PublicIPManager) listPorts(serverID string) ([]ports.Port, error) {
+func (mgr *PublicIPManager) Associate(options api.AssociatePublicIPOptions) api.AssociatePublicIPError {
+	return mgr.AssociateWithContext(context.Background(), options)
+}
+
+func (mgr *PublicIPManager) listPorts(ctx context.Context, serverID string) ([]ports.Port, error) {
 	up := true
-	pages, err := ports.List(mgr.OpenStack.BaseServices.Network, ports.ListOpts{
+	pages, err := ports.List(mgr.OpenStack.BaseServices.network(ctx), ports.ListOpts{
 		AdminStateUp: &up,
 		DeviceID:     serverID,
 	}).AllPages()
@@ -159,33 +196,49 @@ func (mgr *PublicIPManager) listPorts(serverID string) ([]ports.Port, error) {
 	return portList, err
 }
 
-func (mgr *PublicIPManager) Dissociate(publicIPID string) api.DissociatePublicIPError {
+//DissociateWithContext context aware version of Dissociate
+func (mgr *PublicIPManager) DissociateWithContext(ctx context.Context, publicIPID string) api.DissociatePublicIPError {
 	var err error
-	pip, err := mgr.Get(publicIPID)
+	pip, err := mgr.GetWithContext(ctx, publicIPID)
 	if err != nil {
-		return api.NewDissociatePublicIPError(err, publicIPID)
+		return api.NewDissociatePublicIPError(UnwrapOpenStackError(err), publicIPID)
 	}
-	_, err = floatingips.Update(mgr.OpenStack.BaseServices.Network, publicIPID, floatingips.UpdateOpts{
+	_, err = floatingips.Update(mgr.OpenStack.BaseServices.network(ctx), publicIPID, floatingips.UpdateOpts{
 		Description: &pip.Name,
-		PortID:      nil,
+		PortID:      new(string),
 		FixedIP:     "",
 	}).Extract()
 	return api.NewDissociatePublicIPError(UnwrapOpenStackError(err), publicIPID)
 
 }
-func (mgr *PublicIPManager) Delete(publicIPId string) api.DeletePublicIPError {
-	err := floatingips.Delete(mgr.OpenStack.BaseServices.Network, publicIPId).ExtractErr()
+
+func (mgr *PublicIPManager) Dissociate(publicIPID string) api.DissociatePublicIPError {
+	return mgr.DissociateWithContext(context.Background(), publicIPID)
+}
+
+//DeleteWithContext context aware version of Delete
+func (mgr *PublicIPManager) DeleteWithContext(ctx context.Context, publicIPId string) api.DeletePublicIPError {
+	err := floatingips.Delete(mgr.OpenStack.BaseServices.network(ctx), publicIPId).ExtractErr()
 	return api.NewDeletePublicIPError(UnwrapOpenStackError(err), publicIPId)
 }
 
-func (mgr *PublicIPManager) Get(publicIPID string) (*api.PublicIP, api.GetPublicIPError) {
-	fip, err := floatingips.Get(mgr.OpenStack.BaseServices.Network, publicIPID).Extract()
+func (mgr *PublicIPManager) Delete(publicIPId string) api.DeletePublicIPError {
+	return mgr.DeleteWithContext(context.Background(), publicIPId)
+}
+
+//GetWithContext context aware version of Get
+func (mgr *PublicIPManager) GetWithContext(ctx context.Context, publicIPID string) (*api.PublicIP, api.GetPublicIPError) {
+	fip, err := floatingips.Get(mgr.OpenStack.BaseServices.network(ctx), publicIPID).Extract()
 	if err != nil {
 		return nil, api.NewGetPublicIPError(UnwrapOpenStackError(err), publicIPID)
 	}
 	return toPublicIP(fip), nil
 }
 
+func (mgr *PublicIPManager) Get(publicIPID string) (*api.PublicIP, api.GetPublicIPError) {
+	return mgr.GetWithContext(context.Background(), publicIPID)
+}
+
 func toPublicIP(fip *floatingips.FloatingIP) *api.PublicIP {
 	return &api.PublicIP{
 		ID:                 fip.ID,
@@ -193,5 +246,6 @@ func toPublicIP(fip *floatingips.FloatingIP) *api.PublicIP {
 		Address:            fip.FloatingIP,
 		NetworkInterfaceID: fip.PortID,
 		PrivateAddress:     fip.FixedIP,
+		Tags:               tagMap(fip.Tags),
 	}
 }
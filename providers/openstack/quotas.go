@@ -0,0 +1,113 @@
+package openstack
+
+import (
+	"context"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/gophercloud/gophercloud/openstack/blockstorage/extensions/quotasets"
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/extensions/limits"
+	tokens2 "github.com/gophercloud/gophercloud/openstack/identity/v2/tokens"
+	tokens3 "github.com/gophercloud/gophercloud/openstack/identity/v3/tokens"
+	"github.com/pkg/errors"
+)
+
+//QuotaManager OpenStack implementation of api.QuotaManager
+//Limits are read from the Nova absolute limits, the Neutron quota details and the Cinder quota set of the project
+type QuotaManager struct {
+	Provider *Provider
+}
+
+//projectID returns the identifier of the project the provider is authenticated in
+func (mgr *QuotaManager) projectID() (string, error) {
+	switch r := mgr.Provider.BaseServices.client.GetAuthResult().(type) {
+	case tokens3.CreateResult:
+		p, err := r.ExtractProject()
+		if err != nil {
+			return "", err
+		}
+		if p != nil {
+			return p.ID, nil
+		}
+	case tokens2.CreateResult:
+		t, err := r.ExtractToken()
+		if err != nil {
+			return "", err
+		}
+		return t.Tenant.ID, nil
+	}
+	if mgr.Provider.config.TenantID != "" {
+		return mgr.Provider.config.TenantID, nil
+	}
+	return "", errors.New("the provider is not authenticated in a project")
+}
+
+//neutronQuota quota detail of a Neutron resource
+type neutronQuota struct {
+	Limit int `json:"limit"`
+	Used  int `json:"used"`
+}
+
+//limit returns the api.Limit of q, Neutron uses -1 for unlimited resources
+func (q *neutronQuota) limit() api.Limit {
+	return api.Limit{Max: q.Limit, Used: q.Used}
+}
+
+//networkLimits sets the limits of the floating ips and of the security groups from the Neutron quota details of project
+func (mgr *QuotaManager) networkLimits(ctx context.Context, project string, res *api.Limits) error {
+	client := mgr.Provider.BaseServices.network(ctx)
+	var body struct {
+		Quota struct {
+			FloatingIP    neutronQuota `json:"floatingip"`
+			SecurityGroup neutronQuota `json:"security_group"`
+		} `json:"quota"`
+	}
+	_, err := client.Get(client.ServiceURL("quotas", project, "details.json"), &body, nil)
+	if err != nil {
+		return err
+	}
+	res.PublicIPs = body.Quota.FloatingIP.limit()
+	res.SecurityGroups = body.Quota.SecurityGroup.limit()
+	return nil
+}
+
+func (mgr *QuotaManager) getLimits(ctx context.Context) (*api.Limits, error) {
+	project, err := mgr.projectID()
+	if err != nil {
+		return nil, err
+	}
+	l, err := limits.Get(mgr.Provider.BaseServices.compute(ctx), nil).Extract()
+	if err != nil {
+		return nil, err
+	}
+	abs := l.Absolute
+	res := &api.Limits{
+		Instances: api.Limit{Max: abs.MaxTotalInstances, Used: abs.TotalInstancesUsed},
+		Cores:     api.Limit{Max: abs.MaxTotalCores, Used: abs.TotalCoresUsed},
+		RAMSize:   api.Limit{Max: abs.MaxTotalRAMSize, Used: abs.TotalRAMUsed},
+	}
+	usage, err := quotasets.GetUsage(mgr.Provider.BaseServices.volume(ctx), project).Extract()
+	if err != nil {
+		return nil, err
+	}
+	res.Volumes = api.Limit{Max: usage.Volumes.Limit, Used: usage.Volumes.InUse}
+	res.StorageSize = api.Limit{Max: usage.Gigabytes.Limit, Used: usage.Gigabytes.InUse}
+	err = mgr.networkLimits(ctx, project, res)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+//GetLimitsWithContext returns the limits and the current usage of the project
+func (mgr *QuotaManager) GetLimitsWithContext(ctx context.Context) (*api.Limits, api.GetLimitsError) {
+	limits, err := mgr.getLimits(ctx)
+	if err != nil {
+		return nil, api.NewGetLimitsError(UnwrapOpenStackError(err))
+	}
+	return limits, nil
+}
+
+//GetLimits returns the limits and the current usage of the project
+func (mgr *QuotaManager) GetLimits() (*api.Limits, api.GetLimitsError) {
+	return mgr.GetLimitsWithContext(context.Background())
+}
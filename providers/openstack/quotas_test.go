@@ -0,0 +1,21 @@
+package openstack_test
+
+import (
+	"testing"
+
+	"github.com/SebastienDorgan/anyclouds/tests"
+	"github.com/stretchr/testify/suite"
+)
+
+type OSQuotaManagerTestSuite struct {
+	tests.QuotaManagerTestSuite
+}
+
+//SetupSuite set up quota manager
+func (suite *OSQuotaManagerTestSuite) SetupSuite() {
+	suite.Prov = GetProvider()
+}
+
+func TestOSQuotaManagerTestSuite(t *testing.T) {
+	suite.Run(t, new(OSQuotaManagerTestSuite))
+}
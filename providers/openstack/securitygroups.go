@@ -1,11 +1,13 @@
 package openstack
 
 import (
+	"context"
 	"fmt"
 	"github.com/gophercloud/gophercloud/openstack/networking/v2/ports"
 	"strings"
 
 	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/SebastienDorgan/anyclouds/providers"
 	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/security/groups"
 	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/security/rules"
 )
@@ -15,17 +17,30 @@ type SecurityGroupManager struct {
 	Provider *Provider
 }
 
+//isDefaultEgressRule returns true if r is one of the egress rules Neutron adds to every new security group
+func isDefaultEgressRule(r *rules.SecGroupRule) bool {
+	return r.Direction == string(rules.DirEgress) && r.Protocol == "" && r.RemoteIPPrefix == "" && r.RemoteGroupID == ""
+}
+
 func group(g *groups.SecGroup) *api.SecurityGroup {
-	tokens := strings.Split("/", g.Name)
-	var rls []api.SecurityRule
-	for _, r := range g.Rules {
-		rls = append(rls, *rule(&r))
+	sg := &api.SecurityGroup{
+		Name: g.Name,
+		ID:   g.ID,
+		Tags: tagMap(g.Tags),
 	}
-	return &api.SecurityGroup{
-		Name:      tokens[0],
-		NetworkID: tokens[1],
-		ID:        g.ID,
+	//group names are prefixed by the network ID, see CreateWithContext
+	tokens := strings.SplitN(g.Name, "/", 2)
+	if len(tokens) == 2 {
+		sg.NetworkID = tokens[0]
+		sg.Name = tokens[1]
+	}
+	for _, r := range g.Rules {
+		if isDefaultEgressRule(&r) {
+			continue
+		}
+		sg.Rules = append(sg.Rules, *rule(&r))
 	}
+	return sg
 }
 
 func checkGroupName(name string) error {
@@ -35,8 +50,8 @@ func checkGroupName(name string) error {
 	return nil
 }
 
-//Create creates an openstack security group
-func (mgr *SecurityGroupManager) Create(options api.SecurityGroupOptions) (*api.SecurityGroup, api.CreateSecurityGroupError) {
+//CreateWithContext creates an openstack security group
+func (mgr *SecurityGroupManager) CreateWithContext(ctx context.Context, options api.SecurityGroupOptions) (*api.SecurityGroup, api.CreateSecurityGroupError) {
 	err := checkGroupName(options.Name)
 	if err != nil {
 		return nil, api.NewCreateSecurityGroupError(UnwrapOpenStackError(err), options)
@@ -46,25 +61,45 @@ func (mgr *SecurityGroupManager) Create(options api.SecurityGroupOptions) (*api.
 		Description: options.Description,
 	}
 
-	g, err := groups.Create(mgr.Provider.BaseServices.Network, createOpts).Extract()
+	g, err := groups.Create(mgr.Provider.BaseServices.network(ctx), createOpts).Extract()
 	if err != nil {
 		return nil, api.NewCreateSecurityGroupError(UnwrapOpenStackError(err), options)
 	}
+	if len(options.Tags) > 0 {
+		err = mgr.Provider.BaseServices.setNeutronTags(ctx, api.ResourceSecurityGroup, g.ID, options.Tags)
+		if err != nil {
+			err2 := providers.Cleanup(func(ctx context.Context) error {
+				return mgr.DeleteWithContext(ctx, g.ID)
+			})
+			return nil, api.NewCreateSecurityGroupError(api.NewErrorStackFromError(err, err2), options)
+		}
+		g.Tags = neutronTags(options.Tags)
+	}
 	return group(g), nil
 
 }
 
-//Delete deletes the Openstack security group identified by id
-func (mgr *SecurityGroupManager) Delete(id string) api.DeleteSecurityGroupError {
-	err := groups.Delete(mgr.Provider.BaseServices.Network, id).ExtractErr()
+//Create creates an openstack security group
+func (mgr *SecurityGroupManager) Create(options api.SecurityGroupOptions) (*api.SecurityGroup, api.CreateSecurityGroupError) {
+	return mgr.CreateWithContext(context.Background(), options)
+}
+
+//DeleteWithContext deletes the Openstack security group identified by id
+func (mgr *SecurityGroupManager) DeleteWithContext(ctx context.Context, id string) api.DeleteSecurityGroupError {
+	err := groups.Delete(mgr.Provider.BaseServices.network(ctx), id).ExtractErr()
 	if err != nil {
 		return api.NewDeleteSecurityGroupError(UnwrapOpenStackError(err), id)
 	}
 	return nil
 }
 
-func (mgr *SecurityGroupManager) list(opts groups.ListOpts) ([]api.SecurityGroup, error) {
-	securityGroups, err := mgr.groups(opts)
+//Delete deletes the Openstack security group identified by id
+func (mgr *SecurityGroupManager) Delete(id string) api.DeleteSecurityGroupError {
+	return mgr.DeleteWithContext(context.Background(), id)
+}
+
+func (mgr *SecurityGroupManager) list(ctx context.Context, opts groups.ListOpts) ([]api.SecurityGroup, error) {
+	securityGroups, err := mgr.groups(ctx, opts)
 	if err != nil {
 		return nil, UnwrapOpenStackError(err)
 	}
@@ -75,8 +110,8 @@ func (mgr *SecurityGroupManager) list(opts groups.ListOpts) ([]api.SecurityGroup
 	return result, nil
 }
 
-func (mgr *SecurityGroupManager) groups(opts groups.ListOpts) ([]groups.SecGroup, error) {
-	allPages, err := groups.List(mgr.Provider.BaseServices.Network, opts).AllPages()
+func (mgr *SecurityGroupManager) groups(ctx context.Context, opts groups.ListOpts) ([]groups.SecGroup, error) {
+	allPages, err := groups.List(mgr.Provider.BaseServices.network(ctx), opts).AllPages()
 	if err != nil {
 		return nil, err
 	}
@@ -84,12 +119,17 @@ func (mgr *SecurityGroupManager) groups(opts groups.ListOpts) ([]groups.SecGroup
 
 }
 
-//List list all Openstack security groups defined in the tenant
-func (mgr *SecurityGroupManager) List() ([]api.SecurityGroup, api.ListSecurityGroupsError) {
+//ListWithContext list all Openstack security groups defined in the tenant
+func (mgr *SecurityGroupManager) ListWithContext(ctx context.Context) ([]api.SecurityGroup, api.ListSecurityGroupsError) {
 	listOpts := groups.ListOpts{}
 
-	l, err := mgr.list(listOpts)
-	return l, api.NewListSecurityGroupsError(err)
+	l, err := mgr.list(ctx, listOpts)
+	return l, api.NewListSecurityGroupsError(UnwrapOpenStackError(err))
+}
+
+//List list all Openstack security groups defined in the tenant
+func (mgr *SecurityGroupManager) List() ([]api.SecurityGroup, api.ListSecurityGroupsError) {
+	return mgr.ListWithContext(context.Background())
 }
 
 func rule(r *rules.SecGroupRule) *api.SecurityRule {
@@ -102,32 +142,39 @@ func rule(r *rules.SecGroupRule) *api.SecurityRule {
 			To:   r.PortRangeMax,
 		},
 		Protocol:    api.Protocol(r.Protocol),
+		CIDR:        r.RemoteIPPrefix,
 		Description: r.Description,
 	}
 }
 
 func ruleOptions(rule *api.AddSecurityRuleOptions) *rules.CreateOpts {
+	etherType := rules.EtherType4
+	if strings.Contains(rule.CIDR, ":") {
+		etherType = rules.EtherType6
+	}
 	return &rules.CreateOpts{
-		Description:  rule.Description,
-		Direction:    rules.RuleDirection(rule.Direction),
-		PortRangeMax: rule.PortRange.To,
-		PortRangeMin: rule.PortRange.From,
-		Protocol:     rules.RuleProtocol(rule.Protocol),
+		Description:    rule.Description,
+		Direction:      rules.RuleDirection(rule.Direction),
+		EtherType:      etherType,
+		PortRangeMax:   rule.PortRange.To,
+		PortRangeMin:   rule.PortRange.From,
+		Protocol:       rules.RuleProtocol(rule.Protocol),
+		RemoteIPPrefix: rule.CIDR,
 	}
 }
 
 //Get returns the Openstack security group identified by id
-func (mgr *SecurityGroupManager) get(id string, withRules bool) (*api.SecurityGroup, error) {
+func (mgr *SecurityGroupManager) get(ctx context.Context, id string, withRules bool) (*api.SecurityGroup, error) {
 	listOpts := groups.ListOpts{
 		ID: id,
 	}
 
-	securityGroups, err := mgr.list(listOpts)
+	securityGroups, err := mgr.list(ctx, listOpts)
 	if err != nil {
 		return nil, err
 	}
 	if len(securityGroups) == 0 {
-		return nil, fmt.Errorf("security group does not exists")
+		return nil, notFoundError("security group %s does not exist", id)
 	} else if len(securityGroups) > 1 {
 		return nil, fmt.Errorf("multiple security groups exists with the same identifier")
 	}
@@ -135,10 +182,15 @@ func (mgr *SecurityGroupManager) get(id string, withRules bool) (*api.SecurityGr
 	return group, nil
 }
 
+//GetWithContext returns the  security group identified by id fetching rules
+func (mgr *SecurityGroupManager) GetWithContext(ctx context.Context, id string) (*api.SecurityGroup, api.GetSecurityGroupError) {
+	sg, err := mgr.get(ctx, id, true)
+	return sg, api.NewGetSecurityGroupError(UnwrapOpenStackError(err), id)
+}
+
 //Get returns the  security group identified by id fetching rules
 func (mgr *SecurityGroupManager) Get(id string) (*api.SecurityGroup, api.GetSecurityGroupError) {
-	sg, err := mgr.get(id, true)
-	return sg, api.NewGetSecurityGroupError(UnwrapOpenStackError(err), id)
+	return mgr.GetWithContext(context.Background(), id)
 }
 
 func checkIPs(subnetID string, ip string, ips []ports.IP) bool {
@@ -150,18 +202,18 @@ func checkIPs(subnetID string, ip string, ips []ports.IP) bool {
 	return false
 }
 
-//Attach a server to a security group
-func (mgr *SecurityGroupManager) Attach(options api.AttachSecurityGroupOptions) api.AttachSecurityGroupError {
+//AttachWithContext a server to a security group
+func (mgr *SecurityGroupManager) AttachWithContext(ctx context.Context, options api.AttachSecurityGroupOptions) api.AttachSecurityGroupError {
 	//srv, err := servers.Get(mgr.Provider.Compute, id).Extract()
 	var err error
-	sn, err := mgr.Provider.NetworkManager.GetSubnet(options.NetworkID, options.SubnetID)
+	sn, err := mgr.Provider.NetworkManager.GetSubnetWithContext(ctx, options.NetworkID, options.SubnetID)
 	if err != nil {
 		return api.NewAttachSecurityGroupError(UnwrapOpenStackError(err), options)
 	}
-	pts, err := mgr.Provider.PublicIPAddressManager.listPorts(options.ServerID)
+	pts, err := mgr.Provider.PublicIPAddressManager.listPorts(ctx, options.ServerID)
 	for _, p := range pts {
 		if p.NetworkID == sn.NetworkID && (options.IPAddress == nil || checkIPs(options.SubnetID, *options.IPAddress, p.FixedIPs)) {
-			ports.Update(mgr.Provider.BaseServices.Network, p.ID, ports.UpdateOpts{
+			ports.Update(mgr.Provider.BaseServices.network(ctx), p.ID, ports.UpdateOpts{
 				Name:                &p.Name,
 				Description:         &p.Description,
 				AdminStateUp:        &p.AdminStateUp,
@@ -176,19 +228,34 @@ func (mgr *SecurityGroupManager) Attach(options api.AttachSecurityGroupOptions)
 	return api.NewAttachSecurityGroupError(UnwrapOpenStackError(err), options)
 }
 
-//AddSecurityRule adds a security rule to an Provider security group
-func (mgr *SecurityGroupManager) AddSecurityRule(options api.AddSecurityRuleOptions) (*api.SecurityRule, api.AddSecurityRuleError) {
+//Attach a server to a security group
+func (mgr *SecurityGroupManager) Attach(options api.AttachSecurityGroupOptions) api.AttachSecurityGroupError {
+	return mgr.AttachWithContext(context.Background(), options)
+}
+
+//AddSecurityRuleWithContext adds a security rule to an Provider security group
+func (mgr *SecurityGroupManager) AddSecurityRuleWithContext(ctx context.Context, options api.AddSecurityRuleOptions) (*api.SecurityRule, api.AddSecurityRuleError) {
 	opts := ruleOptions(&options)
 	opts.SecGroupID = options.SecurityGroupID
-	r, err := rules.Create(mgr.Provider.BaseServices.Network, opts).Extract()
+	r, err := rules.Create(mgr.Provider.BaseServices.network(ctx), opts).Extract()
 	if err != nil {
 		return nil, api.NewAddSecurityRuleError(UnwrapOpenStackError(err), options)
 	}
 	return rule(r), nil
 }
 
+//AddSecurityRule adds a security rule to an Provider security group
+func (mgr *SecurityGroupManager) AddSecurityRule(options api.AddSecurityRuleOptions) (*api.SecurityRule, api.AddSecurityRuleError) {
+	return mgr.AddSecurityRuleWithContext(context.Background(), options)
+}
+
+//RemoveSecurityRuleWithContext deletes a security rule from an Provider security group
+func (mgr *SecurityGroupManager) RemoveSecurityRuleWithContext(ctx context.Context, groupID, ruleID string) api.RemoveSecurityRuleError {
+	err := rules.Delete(mgr.Provider.BaseServices.network(ctx), ruleID).ExtractErr()
+	return api.NewRemoveSecurityRuleError(UnwrapOpenStackError(err), groupID, ruleID)
+}
+
 //RemoveSecurityRule deletes a security rule from an Provider security group
 func (mgr *SecurityGroupManager) RemoveSecurityRule(groupID, ruleID string) api.RemoveSecurityRuleError {
-	err := rules.Delete(mgr.Provider.BaseServices.Network, ruleID).ExtractErr()
-	return api.NewRemoveSecurityRuleError(UnwrapOpenStackError(err), groupID, ruleID)
+	return mgr.RemoveSecurityRuleWithContext(context.Background(), groupID, ruleID)
 }
@@ -0,0 +1,361 @@
+package openstack
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/SebastienDorgan/anyclouds/providers"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+//defaultServerGroupReconcileInterval interval between two reconciliations of a server group when the configuration does not set it
+const defaultServerGroupReconcileInterval = time.Minute
+
+//serverGroup a group of servers launched from the same specification
+type serverGroup struct {
+	api.ServerGroup
+	spec      api.CreateServerOptions
+	bootstrap []byte
+	//servers identifiers of the servers of the group in creation order
+	servers []string
+	lock    sync.Mutex
+	cancel  context.CancelFunc
+}
+
+//ServerGroupManager OpenStack implementation of api.ServerGroupManager
+//OpenStack has no server group service: groups are kept in memory and reconciled by a background loop of the process that created them
+type ServerGroupManager struct {
+	Provider *Provider
+	lock     sync.Mutex
+	groups   map[string]*serverGroup
+}
+
+//reconcile deletes the servers in error, deletes the surplus servers and creates the missing ones, must be called with the group lock held
+func (mgr *ServerGroupManager) reconcile(ctx context.Context, g *serverGroup) error {
+	srvMgr := &mgr.Provider.ServerManager
+	var ids []string
+	for _, id := range g.servers {
+		srv, err := srvMgr.GetWithContext(ctx, id)
+		if api.ErrorKind(err) == api.ErrNotFound || err == nil && srv.State == api.ServerDeleted {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if srv.State == api.ServerInError {
+			err = srvMgr.DeleteWithContext(ctx, id)
+			if err != nil && api.ErrorKind(err) != api.ErrNotFound {
+				return err
+			}
+			continue
+		}
+		ids = append(ids, id)
+	}
+	defer func() {
+		g.servers = ids
+		g.ServerIDs = append([]string(nil), ids...)
+		sort.Strings(g.ServerIDs)
+	}()
+	//the most recent servers are deleted first
+	for len(ids) > g.DesiredSize {
+		err := srvMgr.DeleteWithContext(ctx, ids[len(ids)-1])
+		if err != nil && api.ErrorKind(err) != api.ErrNotFound {
+			return err
+		}
+		ids = ids[:len(ids)-1]
+	}
+	for len(ids) < g.DesiredSize {
+		spec := g.spec
+		spec.Name = g.Name
+		spec.BootstrapScript = bytes.NewReader(g.bootstrap)
+		srv, err := srvMgr.CreateWithContext(ctx, spec)
+		if err != nil {
+			return err
+		}
+		ids = append(ids, srv.ID)
+	}
+	return nil
+}
+
+//watch reconciles the group every ServerGroupReconcileInterval until ctx is cancelled
+func (mgr *ServerGroupManager) watch(ctx context.Context, g *serverGroup) {
+	interval := mgr.Provider.Config.ServerGroupReconcileInterval
+	if interval <= 0 {
+		interval = defaultServerGroupReconcileInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			g.lock.Lock()
+			if ctx.Err() == nil {
+				//errors are retried at the next tick
+				_ = mgr.reconcile(ctx, g)
+			}
+			g.lock.Unlock()
+		}
+	}
+}
+
+//group returns the group identified by id
+func (mgr *ServerGroupManager) group(id string) (*serverGroup, error) {
+	mgr.lock.Lock()
+	defer mgr.lock.Unlock()
+	g, ok := mgr.groups[id]
+	if !ok {
+		return nil, notFoundError("server group %s not found", id)
+	}
+	return g, nil
+}
+
+//copy returns a copy of the group, must be called with the group lock held
+func (g *serverGroup) copy() *api.ServerGroup {
+	res := g.ServerGroup
+	res.ServerIDs = append([]string(nil), g.ServerIDs...)
+	res.Tags = map[string]string{}
+	for k, v := range g.Tags {
+		res.Tags[k] = v
+	}
+	return &res
+}
+
+//deleteServers deletes the servers of the group, must be called with the group lock held
+func (mgr *ServerGroupManager) deleteServers(ctx context.Context, g *serverGroup) error {
+	for _, id := range g.servers {
+		err := mgr.Provider.ServerManager.DeleteWithContext(ctx, id)
+		if err != nil && api.ErrorKind(err) != api.ErrNotFound {
+			return err
+		}
+	}
+	return nil
+}
+
+func (mgr *ServerGroupManager) create(ctx context.Context, options api.CreateServerGroupOptions) (*api.ServerGroup, error) {
+	err := api.CheckServerGroupOptions(&options)
+	if err != nil {
+		return nil, err
+	}
+	g := &serverGroup{
+		ServerGroup: api.ServerGroup{
+			ID:          uuid.New().String(),
+			Name:        options.Name,
+			MinSize:     options.Size.MinSize,
+			MaxSize:     options.Size.MaxSize,
+			DesiredSize: options.Size.DesiredSize,
+			Tags:        map[string]string{},
+		},
+		spec: options.Spec,
+	}
+	for k, v := range options.Tags {
+		g.Tags[k] = v
+	}
+	if options.Spec.BootstrapScript != nil {
+		g.bootstrap, err = ioutil.ReadAll(options.Spec.BootstrapScript)
+		if err != nil {
+			return nil, errors.Wrap(err, "error reading bootstrap script")
+		}
+	}
+	mgr.lock.Lock()
+	for _, e := range mgr.groups {
+		if e.Name == options.Name {
+			mgr.lock.Unlock()
+			return nil, api.WithKind(errors.Errorf("server group %s already exists", options.Name), api.ErrAlreadyExists)
+		}
+	}
+	if mgr.groups == nil {
+		mgr.groups = map[string]*serverGroup{}
+	}
+	watchCtx, cancel := context.WithCancel(context.Background())
+	g.cancel = cancel
+	//the group is registered before its servers are created so that its name is reserved
+	mgr.groups[g.ID] = g
+	g.lock.Lock()
+	mgr.lock.Unlock()
+	defer g.lock.Unlock()
+	err = mgr.reconcile(ctx, g)
+	if err != nil {
+		cancel()
+		err2 := providers.Cleanup(func(ctx context.Context) error {
+			return mgr.deleteServers(ctx, g)
+		})
+		mgr.lock.Lock()
+		delete(mgr.groups, g.ID)
+		mgr.lock.Unlock()
+		return nil, api.NewErrorStackFromError(err, err2)
+	}
+	go mgr.watch(watchCtx, g)
+	return g.copy(), nil
+}
+
+//CreateWithContext creates a server group
+func (mgr *ServerGroupManager) CreateWithContext(ctx context.Context, options api.CreateServerGroupOptions) (*api.ServerGroup, api.CreateServerGroupError) {
+	g, err := mgr.create(ctx, options)
+	if err != nil {
+		return nil, api.NewCreateServerGroupError(UnwrapOpenStackError(err), options)
+	}
+	return g, nil
+}
+
+//Create creates a server group
+func (mgr *ServerGroupManager) Create(options api.CreateServerGroupOptions) (*api.ServerGroup, api.CreateServerGroupError) {
+	return mgr.CreateWithContext(context.Background(), options)
+}
+
+func (mgr *ServerGroupManager) delete(ctx context.Context, id string) error {
+	g, err := mgr.group(id)
+	if err != nil {
+		return err
+	}
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	err = mgr.deleteServers(ctx, g)
+	if err != nil {
+		//the group still exists and keeps being reconciled
+		return err
+	}
+	//the watcher checks its context once it holds the group lock, it cannot reconcile the group anymore
+	g.cancel()
+	mgr.lock.Lock()
+	delete(mgr.groups, id)
+	mgr.lock.Unlock()
+	return nil
+}
+
+//DeleteWithContext deletes the server group identified by id and its servers
+func (mgr *ServerGroupManager) DeleteWithContext(ctx context.Context, id string) api.DeleteServerGroupError {
+	return api.NewDeleteServerGroupError(UnwrapOpenStackError(mgr.delete(ctx, id)), id)
+}
+
+//Delete deletes the server group identified by id and its servers
+func (mgr *ServerGroupManager) Delete(id string) api.DeleteServerGroupError {
+	return mgr.DeleteWithContext(context.Background(), id)
+}
+
+//ListWithContext lists the server groups created by this process
+func (mgr *ServerGroupManager) ListWithContext(ctx context.Context) ([]api.ServerGroup, api.ListServerGroupsError) {
+	mgr.lock.Lock()
+	var groups []*serverGroup
+	for _, g := range mgr.groups {
+		groups = append(groups, g)
+	}
+	mgr.lock.Unlock()
+	res := []api.ServerGroup{}
+	for _, g := range groups {
+		g.lock.Lock()
+		res = append(res, *g.copy())
+		g.lock.Unlock()
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].ID < res[j].ID
+	})
+	return res, nil
+}
+
+//List lists the server groups created by this process
+func (mgr *ServerGroupManager) List() ([]api.ServerGroup, api.ListServerGroupsError) {
+	return mgr.ListWithContext(context.Background())
+}
+
+//GetWithContext returns the server group identified by id
+func (mgr *ServerGroupManager) GetWithContext(ctx context.Context, id string) (*api.ServerGroup, api.GetServerGroupError) {
+	g, err := mgr.group(id)
+	if err != nil {
+		return nil, api.NewGetServerGroupError(err, id)
+	}
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	return g.copy(), nil
+}
+
+//Get returns the server group identified by id
+func (mgr *ServerGroupManager) Get(id string) (*api.ServerGroup, api.GetServerGroupError) {
+	return mgr.GetWithContext(context.Background(), id)
+}
+
+func (mgr *ServerGroupManager) scale(ctx context.Context, id string, size api.ServerGroupSize) (*api.ServerGroup, error) {
+	err := api.CheckServerGroupSize(size)
+	if err != nil {
+		return nil, err
+	}
+	g, err := mgr.group(id)
+	if err != nil {
+		return nil, err
+	}
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.MinSize, g.MaxSize, g.DesiredSize = size.MinSize, size.MaxSize, size.DesiredSize
+	err = mgr.reconcile(ctx, g)
+	if err != nil {
+		return nil, err
+	}
+	return g.copy(), nil
+}
+
+//ScaleWithContext changes the size of the server group identified by id
+func (mgr *ServerGroupManager) ScaleWithContext(ctx context.Context, id string, size api.ServerGroupSize) (*api.ServerGroup, api.ScaleServerGroupError) {
+	g, err := mgr.scale(ctx, id, size)
+	if err != nil {
+		return nil, api.NewScaleServerGroupError(UnwrapOpenStackError(err), id, size)
+	}
+	return g, nil
+}
+
+//Scale changes the size of the server group identified by id
+func (mgr *ServerGroupManager) Scale(id string, size api.ServerGroupSize) (*api.ServerGroup, api.ScaleServerGroupError) {
+	return mgr.ScaleWithContext(context.Background(), id, size)
+}
+
+func (mgr *ServerGroupManager) replaceServer(ctx context.Context, id string, serverID string) (*api.ServerGroup, error) {
+	g, err := mgr.group(id)
+	if err != nil {
+		return nil, err
+	}
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	member := false
+	for _, srvID := range g.servers {
+		member = member || srvID == serverID
+	}
+	if !member {
+		return nil, notFoundError("server %s is not a server of group %s", serverID, id)
+	}
+	err = mgr.Provider.ServerManager.DeleteWithContext(ctx, serverID)
+	if err != nil && api.ErrorKind(err) != api.ErrNotFound {
+		return nil, err
+	}
+	var ids []string
+	for _, srvID := range g.servers {
+		if srvID != serverID {
+			ids = append(ids, srvID)
+		}
+	}
+	g.servers = ids
+	err = mgr.reconcile(ctx, g)
+	if err != nil {
+		return nil, err
+	}
+	return g.copy(), nil
+}
+
+//ReplaceServerWithContext deletes the server identified by serverID and creates its replacement
+func (mgr *ServerGroupManager) ReplaceServerWithContext(ctx context.Context, id string, serverID string) (*api.ServerGroup, api.ReplaceServerGroupServerError) {
+	g, err := mgr.replaceServer(ctx, id, serverID)
+	if err != nil {
+		return nil, api.NewReplaceServerGroupServerError(UnwrapOpenStackError(err), id, serverID)
+	}
+	return g, nil
+}
+
+//ReplaceServer deletes the server identified by serverID and creates its replacement
+func (mgr *ServerGroupManager) ReplaceServer(id string, serverID string) (*api.ServerGroup, api.ReplaceServerGroupServerError) {
+	return mgr.ReplaceServerWithContext(context.Background(), id, serverID)
+}
@@ -0,0 +1,21 @@
+package openstack_test
+
+import (
+	"testing"
+
+	"github.com/SebastienDorgan/anyclouds/tests"
+	"github.com/stretchr/testify/suite"
+)
+
+type OSServerGroupManagerTestSuite struct {
+	tests.ServerGroupManagerTestSuite
+}
+
+//SetupSuite set up server group manager
+func (suite *OSServerGroupManagerTestSuite) SetupSuite() {
+	suite.Prov = GetProvider()
+}
+
+func TestOSServerGroupManagerTestSuite(t *testing.T) {
+	suite.Run(t, new(OSServerGroupManagerTestSuite))
+}
@@ -1,17 +1,15 @@
 package openstack
 
 import (
+	"context"
 	"fmt"
 	"github.com/SebastienDorgan/anyclouds/api"
 	"github.com/SebastienDorgan/anyclouds/providers"
-	"github.com/SebastienDorgan/retry"
 	"github.com/google/uuid"
-	"github.com/gophercloud/gophercloud/openstack/compute/v2/extensions/floatingips"
 	"github.com/gophercloud/gophercloud/openstack/compute/v2/extensions/startstop"
 	"github.com/gophercloud/gophercloud/openstack/compute/v2/flavors"
 	"github.com/gophercloud/gophercloud/openstack/compute/v2/servers"
 	"github.com/pkg/errors"
-	"time"
 )
 
 //ServerManager defines Server management functions an anyclouds provider must provide
@@ -31,7 +29,7 @@ func (o createServerOpts) ToServerCreateMap() (map[string]interface{}, error) {
 	if err != nil {
 		return nil, err
 	}
-	m["key_name"] = o.KeyName
+	m["server"].(map[string]interface{})["key_name"] = o.KeyName
 	return m, nil
 
 }
@@ -45,30 +43,38 @@ func (mgr *ServerManager) networks(subnets []api.Subnet) []servers.Network {
 	}
 	return nets
 }
-func (mgr *ServerManager) createServer(options *api.CreateServerOptions) (*api.Server, error) {
+
+func (mgr *ServerManager) createServer(ctx context.Context, options *api.CreateServerOptions) (*api.Server, error) {
 	opts := servers.CreateOpts{
-		FlavorRef:      options.TemplateID,
-		ImageRef:       options.ImageID,
-		Name:           options.Name,
-		SecurityGroups: []string{options.DefaultSecurityGroup},
-		Networks:       mgr.networks(options.Subnets),
-	}
-	keyID := uuid.New().String()
-	err := mgr.Provider.KeyPairManager.Import(keyID, options.KeyPair.PublicKey)
-	defer func() { _ = mgr.Provider.KeyPairManager.Delete(keyID) }()
-	if err != nil {
-		return nil, UnwrapOpenStackError(err)
+		FlavorRef: options.TemplateID,
+		ImageRef:  options.ImageID,
+		Name:      options.Name,
+		Networks:  mgr.networks(options.Subnets),
+		Metadata:  options.Tags,
+	}
+	if len(options.DefaultSecurityGroup) > 0 {
+		opts.SecurityGroups = []string{options.DefaultSecurityGroup}
 	}
-	srv, err := servers.Create(mgr.Provider.BaseServices.Compute, createServerOpts{
+	keyID := options.KeyPairName
+	if keyID == "" {
+		//the inline key is imported under a random name only for the time of the server creation
+		keyID = uuid.New().String()
+		_, err := mgr.Provider.KeyPairManager.ImportWithContext(ctx, keyID, options.KeyPair.PublicKey)
+		defer func() { _ = mgr.Provider.KeyPairManager.DeleteWithContext(context.Background(), keyID) }()
+		if err != nil {
+			return nil, err
+		}
+	}
+	srv, err := servers.Create(mgr.Provider.BaseServices.compute(ctx), createServerOpts{
 		CreateOpts: opts,
-		KeyName:    "key",
+		KeyName:    keyID,
 	}).Extract()
 	if err != nil {
 		return nil, UnwrapOpenStackError(err)
 	}
-	s, err := providers.WaitUntilServerReachStableState(mgr, srv.ID)
+	s, err := providers.WaitUntilServerReachStableStateWithContext(ctx, mgr, srv.ID)
 	if s != nil && s.State != api.ServerReady {
-		_ = mgr.Delete(s.ID)
+		_ = mgr.DeleteWithContext(ctx, s.ID)
 		return nil, errors.Errorf("server in unexpected state: %s", s.State)
 	}
 	if s == nil {
@@ -78,44 +84,29 @@ func (mgr *ServerManager) createServer(options *api.CreateServerOptions) (*api.S
 	return s, nil
 }
 
-//Create creates an Server with options
-func (mgr *ServerManager) Create(options api.CreateServerOptions) (*api.Server, api.CreateServerError) {
-	srv, err := mgr.createServer(&options)
+//CreateWithContext creates an Server with options
+func (mgr *ServerManager) CreateWithContext(ctx context.Context, options api.CreateServerOptions) (*api.Server, api.CreateServerError) {
+	srv, err := mgr.createServer(ctx, &options)
 	if err != nil {
-		return nil, api.NewCreateServerError(err, options)
+		return nil, api.NewCreateServerError(UnwrapOpenStackError(err), options)
 	}
-	srv, err = providers.WaitUntilServerReachStableState(mgr, srv.ID)
-	return nil, api.NewCreateServerError(err, options)
+	return srv, nil
 }
 
-func (mgr *ServerManager) findIP(srvID string) *floatingips.FloatingIP {
-	page, err := floatingips.List(mgr.Provider.BaseServices.Compute).AllPages()
-	if err != nil {
-		return nil
-	}
-	l, err := floatingips.ExtractFloatingIPs(page)
-	if err != nil {
-		return nil
-	}
-	for _, ip := range l {
-		if ip.InstanceID == srvID {
-			return &ip
-		}
-	}
-	return nil
+//Create creates an Server with options
+func (mgr *ServerManager) Create(options api.CreateServerOptions) (*api.Server, api.CreateServerError) {
+	return mgr.CreateWithContext(context.Background(), options)
+}
+
+//DeleteWithContext delete Server identified by id
+func (mgr *ServerManager) DeleteWithContext(ctx context.Context, id string) api.DeleteServerError {
+	err := servers.Delete(mgr.Provider.BaseServices.compute(ctx), id).ExtractErr()
+	return api.NewDeleteServerError(UnwrapOpenStackError(err), id)
 }
 
 //Delete delete Server identified by id
 func (mgr *ServerManager) Delete(id string) api.DeleteServerError {
-	ip := mgr.findIP(id)
-	err := servers.Delete(mgr.Provider.BaseServices.Compute, id).ExtractErr()
-	if err != nil {
-		return api.NewDeleteServerError(UnwrapOpenStackError(err), id)
-	}
-	if ip != nil {
-		err = floatingips.Delete(mgr.Provider.BaseServices.Compute, ip.ID).ExtractErr()
-	}
-	return api.NewDeleteServerError(UnwrapOpenStackError(err), id)
+	return mgr.DeleteWithContext(context.Background(), id)
 }
 
 func state(status string) api.ServerState {
@@ -144,24 +135,30 @@ func state(status string) api.ServerState {
 
 }
 
-func (mgr *ServerManager) server(srv *servers.Server) *api.Server {
+func (mgr *ServerManager) server(ctx context.Context, srv *servers.Server) *api.Server {
 	if srv == nil {
 		return nil
 	}
-	flavorID, _ := flavors.IDFromName(mgr.Provider.BaseServices.Compute, srv.Flavor["original_name"].(string))
+	//the flavor is described by its id or, since compute API 2.47, by its name
+	flavorID, _ := srv.Flavor["id"].(string)
+	if name, ok := srv.Flavor["original_name"].(string); ok && len(flavorID) == 0 {
+		flavorID, _ = flavors.IDFromName(mgr.Provider.BaseServices.compute(ctx), name)
+	}
+	imageID, _ := srv.Image["id"].(string)
 	return &api.Server{
 		ID:         srv.ID,
-		ImageID:    srv.Image["id"].(string),
+		ImageID:    imageID,
 		TemplateID: flavorID,
 		State:      state(srv.Status),
 		Name:       srv.Name,
 		CreatedAt:  srv.Created,
+		Tags:       metadataTags(srv.Metadata),
 	}
 }
 
-//List list Servers
-func (mgr *ServerManager) List() ([]api.Server, api.ListServersError) {
-	page, err := servers.List(mgr.Provider.BaseServices.Compute, servers.ListOpts{}).AllPages()
+//ListWithContext list Servers
+func (mgr *ServerManager) ListWithContext(ctx context.Context) ([]api.Server, api.ListServersError) {
+	page, err := servers.List(mgr.Provider.BaseServices.compute(ctx), servers.ListOpts{}).AllPages()
 	if err != nil {
 		return nil, api.NewListServersError(UnwrapOpenStackError(err))
 	}
@@ -173,68 +170,91 @@ func (mgr *ServerManager) List() ([]api.Server, api.ListServersError) {
 	var res []api.Server
 	for _, srv := range l {
 
-		res = append(res, *mgr.server(&srv))
+		res = append(res, *mgr.server(ctx, &srv))
 	}
 	return res, nil
 }
 
+//List list Servers
+func (mgr *ServerManager) List() ([]api.Server, api.ListServersError) {
+	return mgr.ListWithContext(context.Background())
+}
+
+//GetWithContext get Servers
+func (mgr *ServerManager) GetWithContext(ctx context.Context, id string) (*api.Server, api.GetServerError) {
+	srv, err := servers.Get(mgr.Provider.BaseServices.compute(ctx), id).Extract()
+	return mgr.server(ctx, srv), api.NewGetServerError(UnwrapOpenStackError(err), id)
+}
+
 //Get get Servers
 func (mgr *ServerManager) Get(id string) (*api.Server, api.GetServerError) {
-	srv, err := servers.Get(mgr.Provider.BaseServices.Compute, id).Extract()
-	return mgr.server(srv), api.NewGetServerError(UnwrapOpenStackError(err), id)
+	return mgr.GetWithContext(context.Background(), id)
+}
+
+//StartWithContext starts an Server
+func (mgr *ServerManager) StartWithContext(ctx context.Context, id string) api.StartServerError {
+	err := startstop.Start(mgr.Provider.BaseServices.compute(ctx), id).ExtractErr()
+	return api.NewStartServerError(UnwrapOpenStackError(err), id)
 }
 
 //Start starts an Server
 func (mgr *ServerManager) Start(id string) api.StartServerError {
-	err := startstop.Start(mgr.Provider.BaseServices.Compute, id).ExtractErr()
-	return api.NewStartServerError(UnwrapOpenStackError(err), id)
+	return mgr.StartWithContext(context.Background(), id)
+}
+
+//StopWithContext stops an Server
+func (mgr *ServerManager) StopWithContext(ctx context.Context, id string) api.StopServerError {
+	err := startstop.Stop(mgr.Provider.BaseServices.compute(ctx), id).ExtractErr()
+	return api.NewStopServerError(UnwrapOpenStackError(err), id)
 }
 
 //Stop stops an Server
 func (mgr *ServerManager) Stop(id string) api.StopServerError {
-	err := startstop.Stop(mgr.Provider.BaseServices.Compute, id).ExtractErr()
-	return api.NewStopServerError(UnwrapOpenStackError(err), id)
+	return mgr.StopWithContext(context.Background(), id)
 }
 
-func (mgr *ServerManager) waitResize(id string) *retry.Result {
-	get := func() (interface{}, error) {
-		return servers.Get(mgr.Provider.BaseServices.Compute, id).Extract()
-	}
-	finished := func(v interface{}, e error) bool {
-		state := v.(*servers.Server).Status
-		return state == "RESIZE"
-	}
-	return retry.With(get).For(3 * time.Minute).Every(time.Second).Until(finished).Go()
+//waitResize waits until the resize of the server identified by id needs to be confirmed or fails, or until ctx is done
+//If ctx has no deadline providers.DefaultStableStateTimeout is applied
+func (mgr *ServerManager) waitResize(ctx context.Context, id string) (*servers.Server, error) {
+	var srv *servers.Server
+	err := providers.Poll(ctx, providers.DefaultStableStateTimeout, func(ctx context.Context) (bool, error) {
+		var err error
+		srv, err = servers.Get(mgr.Provider.BaseServices.compute(ctx), id).Extract()
+		if err != nil {
+			return false, err
+		}
+		return srv.Status == "VERIFY_RESIZE" || srv.Status == "ERROR", nil
+	})
+	return srv, err
 }
 
-//Resize resize a server
-func (mgr *ServerManager) Resize(id string, templateID string) api.ResizeServerError {
-	err := servers.Resize(mgr.Provider.BaseServices.Compute, id, servers.ResizeOpts{
+//ResizeWithContext resize a server
+func (mgr *ServerManager) ResizeWithContext(ctx context.Context, id string, templateID string) api.ResizeServerError {
+	err := servers.Resize(mgr.Provider.BaseServices.compute(ctx), id, servers.ResizeOpts{
 		FlavorRef: templateID,
 	}).ExtractErr()
 	if err != nil {
-		servers.RevertResize(mgr.Provider.BaseServices.Compute, id)
-		return api.NewResizeServerError(err, id, templateID)
-	}
-	res := mgr.waitResize(id)
-	if res.LastError != nil {
-		return api.NewResizeServerError(err, id, templateID)
+		servers.RevertResize(mgr.Provider.BaseServices.compute(ctx), id)
+		return api.NewResizeServerError(UnwrapOpenStackError(err), id, templateID)
 	}
-	srv := res.LastValue.(*servers.Server)
-	if srv == nil {
-		servers.RevertResize(mgr.Provider.BaseServices.Compute, id)
-		err := fmt.Errorf("unable to retrive server state")
-		return api.NewResizeServerError(err, id, templateID)
+	srv, err := mgr.waitResize(ctx, id)
+	if err != nil {
+		return api.NewResizeServerError(UnwrapOpenStackError(err), id, templateID)
 	}
-	if srv.Status != "RESIZE" {
-		servers.RevertResize(mgr.Provider.BaseServices.Compute, id)
+	if srv.Status != "VERIFY_RESIZE" {
+		servers.RevertResize(mgr.Provider.BaseServices.compute(ctx), id)
 		err := fmt.Errorf("unexpected server state: %s", srv.Status)
-		return api.NewResizeServerError(err, id, templateID)
+		return api.NewResizeServerError(UnwrapOpenStackError(err), id, templateID)
 	}
-	err = servers.ConfirmResize(mgr.Provider.BaseServices.Compute, id).ExtractErr()
+	err = servers.ConfirmResize(mgr.Provider.BaseServices.compute(ctx), id).ExtractErr()
 	if err != nil {
-		servers.RevertResize(mgr.Provider.BaseServices.Compute, id)
-		return api.NewResizeServerError(err, id, templateID)
+		servers.RevertResize(mgr.Provider.BaseServices.compute(ctx), id)
+		return api.NewResizeServerError(UnwrapOpenStackError(err), id, templateID)
 	}
 	return nil
 }
+
+//Resize resize a server
+func (mgr *ServerManager) Resize(id string, templateID string) api.ResizeServerError {
+	return mgr.ResizeWithContext(context.Background(), id, templateID)
+}
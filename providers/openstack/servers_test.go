@@ -14,6 +14,7 @@ type OSServerManagerTestSuite struct {
 func (suite *OSServerManagerTestSuite) SetupSuite() {
 	p := GetProvider()
 	suite.Prov = p
+	suite.SkipSSH = IsFake()
 }
 
 func TestOSServerManagerTestSuite(t *testing.T) {
@@ -0,0 +1,173 @@
+package openstack
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/SebastienDorgan/anyclouds/providers"
+	"github.com/gophercloud/gophercloud/openstack/blockstorage/v3/snapshots"
+	"github.com/gophercloud/gophercloud/openstack/blockstorage/v3/volumes"
+)
+
+//SnapshotManager defines volume snapshot management functions an anyclouds provider must provide
+type SnapshotManager struct {
+	Provider *Provider
+}
+
+func snapshotState(status string) api.SnapshotState {
+	switch status {
+	case "creating":
+		return api.SnapshotPending
+	case "available":
+		return api.SnapshotReady
+	case "error":
+		return api.SnapshotInError
+	}
+	return api.SnapshotUnknownState
+}
+
+func snapshot(s *snapshots.Snapshot) *api.Snapshot {
+	return &api.Snapshot{
+		ID:        s.ID,
+		Name:      s.Name,
+		VolumeID:  s.VolumeID,
+		Size:      int64(s.Size),
+		State:     snapshotState(s.Status),
+		CreatedAt: s.CreatedAt,
+	}
+}
+
+//waitAvailable waits until the snapshot identified by id is no longer being created or ctx is done
+func (mgr *SnapshotManager) waitAvailable(ctx context.Context, id string) (*snapshots.Snapshot, error) {
+	var s *snapshots.Snapshot
+	err := providers.Poll(ctx, 10*time.Minute, func(ctx context.Context) (bool, error) {
+		var err error
+		s, err = snapshots.Get(mgr.Provider.BaseServices.volume(ctx), id).Extract()
+		if err != nil {
+			return false, err
+		}
+		return s.Status != "creating", nil
+	})
+	return s, err
+}
+
+func (mgr *SnapshotManager) create(ctx context.Context, options *api.CreateSnapshotOptions) (*api.Snapshot, error) {
+	s, err := snapshots.Create(mgr.Provider.BaseServices.volume(ctx), snapshots.CreateOpts{
+		VolumeID: options.VolumeID,
+		Name:     options.Name,
+	}).Extract()
+	if err != nil {
+		return nil, err
+	}
+	id := s.ID
+	s, err = mgr.waitAvailable(ctx, id)
+	if err == nil && s.Status != "available" {
+		err = fmt.Errorf("snapshot %s is not available", id)
+	}
+	if err != nil {
+		err2 := providers.Cleanup(func(ctx context.Context) error {
+			return snapshots.Delete(mgr.Provider.BaseServices.volume(ctx), id).ExtractErr()
+		})
+		return nil, api.NewErrorStackFromError(err, err2)
+	}
+	return snapshot(s), nil
+}
+
+//CreateWithContext creates a snapshot of a volume and waits until it is available
+func (mgr *SnapshotManager) CreateWithContext(ctx context.Context, options api.CreateSnapshotOptions) (*api.Snapshot, api.CreateSnapshotError) {
+	s, err := mgr.create(ctx, &options)
+	if err != nil {
+		return nil, api.NewCreateSnapshotError(UnwrapOpenStackError(err), options)
+	}
+	return s, nil
+}
+
+//Create creates a snapshot of a volume and waits until it is available
+func (mgr *SnapshotManager) Create(options api.CreateSnapshotOptions) (*api.Snapshot, api.CreateSnapshotError) {
+	return mgr.CreateWithContext(context.Background(), options)
+}
+
+//DeleteWithContext deletes snapshot identified by id
+func (mgr *SnapshotManager) DeleteWithContext(ctx context.Context, id string) api.DeleteSnapshotError {
+	err := snapshots.Delete(mgr.Provider.BaseServices.volume(ctx), id).ExtractErr()
+	return api.NewDeleteSnapshotError(UnwrapOpenStackError(err), id)
+}
+
+//Delete deletes snapshot identified by id
+func (mgr *SnapshotManager) Delete(id string) api.DeleteSnapshotError {
+	return mgr.DeleteWithContext(context.Background(), id)
+}
+
+//ListWithContext lists snapshots
+func (mgr *SnapshotManager) ListWithContext(ctx context.Context) ([]api.Snapshot, api.ListSnapshotsError) {
+	page, err := snapshots.List(mgr.Provider.BaseServices.volume(ctx), snapshots.ListOpts{}).AllPages()
+	if err != nil {
+		return nil, api.NewListSnapshotsError(UnwrapOpenStackError(err))
+	}
+	l, err := snapshots.ExtractSnapshots(page)
+	if err != nil {
+		return nil, api.NewListSnapshotsError(UnwrapOpenStackError(err))
+	}
+	var res []api.Snapshot
+	for _, s := range l {
+		res = append(res, *snapshot(&s))
+	}
+	return res, nil
+}
+
+//List lists snapshots
+func (mgr *SnapshotManager) List() ([]api.Snapshot, api.ListSnapshotsError) {
+	return mgr.ListWithContext(context.Background())
+}
+
+//GetWithContext returns snapshot details
+func (mgr *SnapshotManager) GetWithContext(ctx context.Context, id string) (*api.Snapshot, api.GetSnapshotError) {
+	s, err := snapshots.Get(mgr.Provider.BaseServices.volume(ctx), id).Extract()
+	if err != nil {
+		return nil, api.NewGetSnapshotError(UnwrapOpenStackError(err), id)
+	}
+	return snapshot(s), nil
+}
+
+//Get returns snapshot details
+func (mgr *SnapshotManager) Get(id string) (*api.Snapshot, api.GetSnapshotError) {
+	return mgr.GetWithContext(context.Background(), id)
+}
+
+func (mgr *SnapshotManager) createVolume(ctx context.Context, options *api.CreateVolumeFromSnapshotOptions) (*volumes.Volume, error) {
+	size := int(options.Size)
+	if size == 0 {
+		s, err := snapshots.Get(mgr.Provider.BaseServices.volume(ctx), options.SnapshotID).Extract()
+		if err != nil {
+			return nil, err
+		}
+		size = s.Size
+	}
+	return volumes.Create(mgr.Provider.BaseServices.volume(ctx), volumes.CreateOpts{
+		Size:       size,
+		Metadata:   options.Tags,
+		Name:       options.Name,
+		SnapshotID: options.SnapshotID,
+	}).Extract()
+}
+
+//CreateVolumeFromSnapshotWithContext creates a volume initialized with the content of a snapshot
+func (mgr *SnapshotManager) CreateVolumeFromSnapshotWithContext(ctx context.Context, options api.CreateVolumeFromSnapshotOptions) (*api.Volume, api.CreateVolumeFromSnapshotError) {
+	v, err := mgr.createVolume(ctx, &options)
+	if err != nil {
+		return nil, api.NewCreateVolumeFromSnapshotError(UnwrapOpenStackError(err), options)
+	}
+	return &api.Volume{
+		Name: v.Name,
+		ID:   v.ID,
+		Size: int64(v.Size),
+		Tags: metadataTags(v.Metadata),
+	}, nil
+}
+
+//CreateVolumeFromSnapshot creates a volume initialized with the content of a snapshot
+func (mgr *SnapshotManager) CreateVolumeFromSnapshot(options api.CreateVolumeFromSnapshotOptions) (*api.Volume, api.CreateVolumeFromSnapshotError) {
+	return mgr.CreateVolumeFromSnapshotWithContext(context.Background(), options)
+}
@@ -0,0 +1,21 @@
+package openstack_test
+
+import (
+	"testing"
+
+	"github.com/SebastienDorgan/anyclouds/tests"
+	"github.com/stretchr/testify/suite"
+)
+
+type OSSnapshotManagerTestSuite struct {
+	tests.SnapshotManagerTestSuite
+}
+
+//SetupSuite set up snapshot manager
+func (suite *OSSnapshotManagerTestSuite) SetupSuite() {
+	suite.Prov = GetProvider()
+}
+
+func TestOSSnapshotManagerTestSuite(t *testing.T) {
+	suite.Run(t, new(OSSnapshotManagerTestSuite))
+}
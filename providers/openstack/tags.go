@@ -0,0 +1,197 @@
+package openstack
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+	gc "github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack/blockstorage/v3/volumes"
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/servers"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/attributestags"
+	"github.com/pkg/errors"
+)
+
+//neutronResourceTypes Neutron types of the resources tagged with the tag extension
+//Neutron tags are strings, tags are stored as key=value strings
+var neutronResourceTypes = map[api.ResourceType]string{
+	api.ResourceNetwork:          "networks",
+	api.ResourceSubnet:           "subnets",
+	api.ResourceSecurityGroup:    "security-groups",
+	api.ResourcePublicIP:         "floatingips",
+	api.ResourceNetworkInterface: "ports",
+}
+
+//neutronTags converts tags into Neutron tags
+func neutronTags(tags map[string]string) []string {
+	res := make([]string, 0, len(tags))
+	for k, v := range tags {
+		res = append(res, k+"="+v)
+	}
+	sort.Strings(res)
+	return res
+}
+
+//tagMap converts Neutron tags into tags, a Neutron tag without value is converted into a key with an empty value
+func tagMap(tags []string) map[string]string {
+	var res map[string]string
+	for _, t := range tags {
+		if res == nil {
+			res = map[string]string{}
+		}
+		kv := strings.SplitN(t, "=", 2)
+		if len(kv) == 2 {
+			res[kv[0]] = kv[1]
+		} else {
+			res[kv[0]] = ""
+		}
+	}
+	return res
+}
+
+//metadataTags returns a copy of the metadata of a server or a volume or nil if it is empty
+func metadataTags(metadata map[string]string) map[string]string {
+	if len(metadata) == 0 {
+		return nil
+	}
+	res := make(map[string]string, len(metadata))
+	for k, v := range metadata {
+		res[k] = v
+	}
+	return res
+}
+
+//setNeutronTags replaces the tags of the Neutron resource identified by id
+func (s *BaseServices) setNeutronTags(ctx context.Context, resourceType api.ResourceType, id string, tags map[string]string) error {
+	_, err := attributestags.ReplaceAll(s.network(ctx), neutronResourceTypes[resourceType], id, attributestags.ReplaceAllOpts{
+		Tags: neutronTags(tags),
+	}).Extract()
+	return UnwrapOpenStackError(err)
+}
+
+//TagManager openstack implementation of api.TagManager
+//Server and volume tags are stored in their metadata, the other resources are tagged using the Neutron tag extension
+type TagManager struct {
+	Provider *Provider
+}
+
+func (mgr *TagManager) get(ctx context.Context, resource api.TaggedResource) (map[string]string, error) {
+	switch resource.Type {
+	case api.ResourceServer:
+		md, err := servers.Metadata(mgr.Provider.BaseServices.compute(ctx), resource.ID).Extract()
+		return metadataTags(md), UnwrapOpenStackError(err)
+	case api.ResourceVolume:
+		v, err := volumes.Get(mgr.Provider.BaseServices.volume(ctx), resource.ID).Extract()
+		if err != nil {
+			return nil, UnwrapOpenStackError(err)
+		}
+		return metadataTags(v.Metadata), nil
+	}
+	resourceType, ok := neutronResourceTypes[resource.Type]
+	if !ok {
+		return nil, api.WithKind(errors.Errorf("resources of type %s cannot be tagged", resource.Type), api.ErrInvalidArgument)
+	}
+	tags, err := attributestags.List(mgr.Provider.BaseServices.network(ctx), resourceType, resource.ID).Extract()
+	return tagMap(tags), UnwrapOpenStackError(err)
+}
+
+func (mgr *TagManager) set(ctx context.Context, resource api.TaggedResource, tags map[string]string) error {
+	if tags == nil {
+		tags = map[string]string{}
+	}
+	switch resource.Type {
+	case api.ResourceServer:
+		_, err := servers.ResetMetadata(mgr.Provider.BaseServices.compute(ctx), resource.ID, servers.MetadataOpts(tags)).Extract()
+		return UnwrapOpenStackError(err)
+	case api.ResourceVolume:
+		//the volume metadata API is not provided by gophercloud
+		client := mgr.Provider.BaseServices.volume(ctx)
+		_, err := client.Put(client.ServiceURL("volumes", resource.ID, "metadata"), map[string]interface{}{"metadata": tags}, nil, &gc.RequestOpts{
+			OkCodes: []int{200},
+		})
+		return UnwrapOpenStackError(err)
+	}
+	return mgr.Provider.BaseServices.setNeutronTags(ctx, resource.Type, resource.ID, tags)
+}
+
+func checkTagKeys(keys []string) error {
+	for _, k := range keys {
+		if len(k) == 0 {
+			return api.WithKind(errors.Errorf("tag keys cannot be empty"), api.ErrInvalidArgument)
+		}
+		if strings.Contains(k, "=") {
+			return api.WithKind(errors.Errorf("tag key %s contains '='", k), api.ErrInvalidArgument)
+		}
+	}
+	return nil
+}
+
+func (mgr *TagManager) add(ctx context.Context, options *api.AddTagsOptions) error {
+	keys := make([]string, 0, len(options.Tags))
+	for k := range options.Tags {
+		keys = append(keys, k)
+	}
+	if err := checkTagKeys(keys); err != nil {
+		return err
+	}
+	tags, err := mgr.get(ctx, options.Resource)
+	if err != nil {
+		return err
+	}
+	if tags == nil {
+		tags = make(map[string]string, len(options.Tags))
+	}
+	for k, v := range options.Tags {
+		tags[k] = v
+	}
+	return mgr.set(ctx, options.Resource, tags)
+}
+
+//AddWithContext adds tags to a resource
+func (mgr *TagManager) AddWithContext(ctx context.Context, options api.AddTagsOptions) api.AddTagsError {
+	return api.NewAddTagsError(mgr.add(ctx, &options), options)
+}
+
+//Add adds tags to a resource
+func (mgr *TagManager) Add(options api.AddTagsOptions) api.AddTagsError {
+	return mgr.AddWithContext(context.Background(), options)
+}
+
+func (mgr *TagManager) remove(ctx context.Context, options *api.RemoveTagsOptions) error {
+	tags, err := mgr.get(ctx, options.Resource)
+	if err != nil {
+		return err
+	}
+	for _, k := range options.Keys {
+		delete(tags, k)
+	}
+	return mgr.set(ctx, options.Resource, tags)
+}
+
+//RemoveWithContext removes tags from a resource
+func (mgr *TagManager) RemoveWithContext(ctx context.Context, options api.RemoveTagsOptions) api.RemoveTagsError {
+	return api.NewRemoveTagsError(mgr.remove(ctx, &options), options)
+}
+
+//Remove removes tags from a resource
+func (mgr *TagManager) Remove(options api.RemoveTagsOptions) api.RemoveTagsError {
+	return mgr.RemoveWithContext(context.Background(), options)
+}
+
+//ListWithContext lists the tags of a resource
+func (mgr *TagManager) ListWithContext(ctx context.Context, resource api.TaggedResource) (map[string]string, api.ListTagsError) {
+	tags, err := mgr.get(ctx, resource)
+	if err != nil {
+		return nil, api.NewListTagsError(err, resource)
+	}
+	if tags == nil {
+		tags = map[string]string{}
+	}
+	return tags, nil
+}
+
+//List lists the tags of a resource
+func (mgr *TagManager) List(resource api.TaggedResource) (map[string]string, api.ListTagsError) {
+	return mgr.ListWithContext(context.Background(), resource)
+}
@@ -0,0 +1,21 @@
+package openstack_test
+
+import (
+	"testing"
+
+	"github.com/SebastienDorgan/anyclouds/tests"
+	"github.com/stretchr/testify/suite"
+)
+
+type OSTagManagerTestSuite struct {
+	tests.TagManagerTestSuite
+}
+
+//SetupSuite set up tag manager
+func (suite *OSTagManagerTestSuite) SetupSuite() {
+	suite.Prov = GetProvider()
+}
+
+func TestOSTagManagerTestSuite(t *testing.T) {
+	suite.Run(t, new(OSTagManagerTestSuite))
+}
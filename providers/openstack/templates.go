@@ -1,6 +1,7 @@
 package openstack
 
 import (
+	"context"
 	"github.com/SebastienDorgan/anyclouds/api"
 	"github.com/gophercloud/gophercloud/openstack/compute/v2/flavors"
 )
@@ -10,35 +11,24 @@ type ServerTemplateManager struct {
 	Provider *Provider
 }
 
-//List returns available VM templates
-func (mgr *ServerTemplateManager) List() ([]api.ServerTemplate, api.ListServerTemplatesError) {
-	page, err := flavors.ListDetail(mgr.Provider.BaseServices.Compute, flavors.ListOpts{}).AllPages()
-	if err != nil {
-		return nil, api.NewListServerTemplatesError(err)
-	}
-	l, err := flavors.ExtractFlavors(page)
-	var templates []api.ServerTemplate
-
-	for _, f := range l {
-		t := api.ServerTemplate{
-			ID:                f.ID,
-			Name:              f.Name,
-			NumberOfCPUCore:   f.VCPUs,
-			RAMSize:           f.RAM,
-			SystemDiskSize:    f.Disk,
-			EphemeralDiskSize: f.Ephemeral,
-			Arch:              api.ArchUnknown,
-		}
-		templates = append(templates, t)
+//arch converts the capabilities:cpu_arch flavor extra spec into a CPU architecture
+func arch(cpuArch string) api.CPUArch {
+	switch cpuArch {
+	case "x86_64", "amd64":
+		return api.ArchAmd64
+	case "aarch64", "arm64":
+		return api.ArchARM64
+	case "i686", "i386":
+		return api.Arch386
+	default:
+		return api.ArchUnknown
 	}
-	return templates, nil
 }
 
-//Get returns the template identified by ids
-func (mgr *ServerTemplateManager) Get(id string) (*api.ServerTemplate, api.GetServerTemplateError) {
-	f, err := flavors.Get(mgr.Provider.BaseServices.Compute, id).Extract()
+func (mgr *ServerTemplateManager) template(ctx context.Context, f *flavors.Flavor) (*api.ServerTemplate, error) {
+	specs, err := flavors.ListExtraSpecs(mgr.Provider.BaseServices.compute(ctx), f.ID).Extract()
 	if err != nil {
-		return nil, api.NewGetServerTemplateError(err, id)
+		return nil, UnwrapOpenStackError(err)
 	}
 	return &api.ServerTemplate{
 		ID:                f.ID,
@@ -47,6 +37,47 @@ func (mgr *ServerTemplateManager) Get(id string) (*api.ServerTemplate, api.GetSe
 		RAMSize:           f.RAM,
 		SystemDiskSize:    f.Disk,
 		EphemeralDiskSize: f.Ephemeral,
-		Arch:              api.ArchUnknown,
+		Arch:              arch(specs["capabilities:cpu_arch"]),
 	}, nil
 }
+
+//ListWithContext returns available VM templates
+func (mgr *ServerTemplateManager) ListWithContext(ctx context.Context) ([]api.ServerTemplate, api.ListServerTemplatesError) {
+	page, err := flavors.ListDetail(mgr.Provider.BaseServices.compute(ctx), flavors.ListOpts{}).AllPages()
+	if err != nil {
+		return nil, api.NewListServerTemplatesError(UnwrapOpenStackError(err))
+	}
+	l, err := flavors.ExtractFlavors(page)
+	if err != nil {
+		return nil, api.NewListServerTemplatesError(UnwrapOpenStackError(err))
+	}
+	var templates []api.ServerTemplate
+	for _, f := range l {
+		t, err := mgr.template(ctx, &f)
+		if err != nil {
+			return nil, api.NewListServerTemplatesError(err)
+		}
+		templates = append(templates, *t)
+	}
+	return templates, nil
+}
+
+//List returns available VM templates
+func (mgr *ServerTemplateManager) List() ([]api.ServerTemplate, api.ListServerTemplatesError) {
+	return mgr.ListWithContext(context.Background())
+}
+
+//GetWithContext returns the template identified by ids
+func (mgr *ServerTemplateManager) GetWithContext(ctx context.Context, id string) (*api.ServerTemplate, api.GetServerTemplateError) {
+	f, err := flavors.Get(mgr.Provider.BaseServices.compute(ctx), id).Extract()
+	if err != nil {
+		return nil, api.NewGetServerTemplateError(UnwrapOpenStackError(err), id)
+	}
+	t, err := mgr.template(ctx, f)
+	return t, api.NewGetServerTemplateError(err, id)
+}
+
+//Get returns the template identified by ids
+func (mgr *ServerTemplateManager) Get(id string) (*api.ServerTemplate, api.GetServerTemplateError) {
+	return mgr.GetWithContext(context.Background(), id)
+}
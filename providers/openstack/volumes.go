@@ -1,6 +1,7 @@
 package openstack
 
 import (
+	"context"
 	"github.com/SebastienDorgan/anyclouds/api"
 	"github.com/gophercloud/gophercloud/openstack/blockstorage/extensions/volumeactions"
 	"github.com/gophercloud/gophercloud/openstack/blockstorage/v3/volumes"
@@ -12,33 +13,44 @@ type VolumeManager struct {
 	Provider *Provider
 }
 
-//Create creates a volume with options
-func (mgr *VolumeManager) Create(options api.CreateVolumeOptions) (*api.Volume, api.CreateVolumeError) {
-	v, err := volumes.Create(mgr.Provider.BaseServices.Volume, volumes.CreateOpts{
+//CreateWithContext creates a volume with options
+func (mgr *VolumeManager) CreateWithContext(ctx context.Context, options api.CreateVolumeOptions) (*api.Volume, api.CreateVolumeError) {
+	v, err := volumes.Create(mgr.Provider.BaseServices.volume(ctx), volumes.CreateOpts{
 		Size:        int(options.Size),
-		Metadata:    nil,
+		Metadata:    options.Tags,
 		Name:        options.Name,
 		Multiattach: false,
 	}).Extract()
 	if err != nil {
-		return nil, api.NewCreateVolumeError(err, options)
+		return nil, api.NewCreateVolumeError(UnwrapOpenStackError(err), options)
 	}
 	return &api.Volume{
 		Name: v.Name,
 		ID:   v.ID,
 		Size: int64(v.Size),
+		Tags: metadataTags(v.Metadata),
 	}, nil
 }
 
+//Create creates a volume with options
+func (mgr *VolumeManager) Create(options api.CreateVolumeOptions) (*api.Volume, api.CreateVolumeError) {
+	return mgr.CreateWithContext(context.Background(), options)
+}
+
+//DeleteWithContext deletes volume identified by id
+func (mgr *VolumeManager) DeleteWithContext(ctx context.Context, id string) api.DeleteVolumeError {
+	err := volumes.Delete(mgr.Provider.BaseServices.volume(ctx), id, volumes.DeleteOpts{Cascade: true}).ExtractErr()
+	return api.NewDeleteVolumeError(UnwrapOpenStackError(err), id)
+}
+
 //Delete deletes volume identified by id
 func (mgr *VolumeManager) Delete(id string) api.DeleteVolumeError {
-	err := volumes.Delete(mgr.Provider.BaseServices.Volume, id, volumes.DeleteOpts{Cascade: true}).ExtractErr()
-	return api.NewDeleteVolumeError(UnwrapOpenStackError(err), id)
+	return mgr.DeleteWithContext(context.Background(), id)
 }
 
-//List lists volumes along filter
-func (mgr *VolumeManager) List() ([]api.Volume, api.ListVolumesError) {
-	page, err := volumes.List(mgr.Provider.BaseServices.Volume, volumes.ListOpts{}).AllPages()
+//ListWithContext lists volumes along filter
+func (mgr *VolumeManager) ListWithContext(ctx context.Context) ([]api.Volume, api.ListVolumesError) {
+	page, err := volumes.List(mgr.Provider.BaseServices.volume(ctx), volumes.ListOpts{}).AllPages()
 	if err != nil {
 		return nil, api.NewListVolumesError(UnwrapOpenStackError(err))
 	}
@@ -49,14 +61,20 @@ func (mgr *VolumeManager) List() ([]api.Volume, api.ListVolumesError) {
 			Name: v.Name,
 			ID:   v.ID,
 			Size: int64(v.Size),
+			Tags: metadataTags(v.Metadata),
 		})
 	}
 	return res, nil
 }
 
-//Get returns volume details
-func (mgr *VolumeManager) Get(id string) (*api.Volume, api.GetVolumeError) {
-	v, err := volumes.Get(mgr.Provider.BaseServices.Volume, id).Extract()
+//List lists volumes along filter
+func (mgr *VolumeManager) List() ([]api.Volume, api.ListVolumesError) {
+	return mgr.ListWithContext(context.Background())
+}
+
+//GetWithContext returns volume details
+func (mgr *VolumeManager) GetWithContext(ctx context.Context, id string) (*api.Volume, api.GetVolumeError) {
+	v, err := volumes.Get(mgr.Provider.BaseServices.volume(ctx), id).Extract()
 	if err != nil {
 		return nil, api.NewGetVolumeError(UnwrapOpenStackError(err), id)
 	}
@@ -64,12 +82,18 @@ func (mgr *VolumeManager) Get(id string) (*api.Volume, api.GetVolumeError) {
 		Name: v.Name,
 		ID:   v.ID,
 		Size: int64(v.Size),
+		Tags: metadataTags(v.Metadata),
 	}, nil
 }
 
-//Attach attaches a volume to an Server
-func (mgr *VolumeManager) Attach(options api.AttachVolumeOptions) (*api.VolumeAttachment, api.AttachVolumeError) {
-	va, err := volumeattach.Create(mgr.Provider.BaseServices.Compute, options.ServerID, volumeattach.CreateOpts{
+//Get returns volume details
+func (mgr *VolumeManager) Get(id string) (*api.Volume, api.GetVolumeError) {
+	return mgr.GetWithContext(context.Background(), id)
+}
+
+//AttachWithContext attaches a volume to an Server
+func (mgr *VolumeManager) AttachWithContext(ctx context.Context, options api.AttachVolumeOptions) (*api.VolumeAttachment, api.AttachVolumeError) {
+	va, err := volumeattach.Create(mgr.Provider.BaseServices.compute(ctx), options.ServerID, volumeattach.CreateOpts{
 		Device:   options.DevicePath,
 		VolumeID: options.VolumeID,
 	}).Extract()
@@ -84,19 +108,29 @@ func (mgr *VolumeManager) Attach(options api.AttachVolumeOptions) (*api.VolumeAt
 	}, nil
 }
 
-//Detach detach a volume from an Server
-func (mgr *VolumeManager) Detach(options api.DetachVolumeOptions) api.DetachVolumeError {
-	att, err := mgr.attachment(options.VolumeID, options.ServerID)
+//Attach attaches a volume to an Server
+func (mgr *VolumeManager) Attach(options api.AttachVolumeOptions) (*api.VolumeAttachment, api.AttachVolumeError) {
+	return mgr.AttachWithContext(context.Background(), options)
+}
+
+//DetachWithContext detach a volume from an Server
+func (mgr *VolumeManager) DetachWithContext(ctx context.Context, options api.DetachVolumeOptions) api.DetachVolumeError {
+	att, err := mgr.attachment(ctx, options.VolumeID, options.ServerID)
 	if err != nil {
 		return api.NewDetachVolumeError(UnwrapOpenStackError(err), options)
 	}
-	err = volumeattach.Delete(mgr.Provider.BaseServices.Compute, options.ServerID, att.ID).Err
+	err = volumeattach.Delete(mgr.Provider.BaseServices.compute(ctx), options.ServerID, att.ID).Err
 	return api.NewDetachVolumeError(UnwrapOpenStackError(err), options)
 }
 
+//Detach detach a volume from an Server
+func (mgr *VolumeManager) Detach(options api.DetachVolumeOptions) api.DetachVolumeError {
+	return mgr.DetachWithContext(context.Background(), options)
+}
+
 //attachment returns the attachment between a volume and an Server
-func (mgr *VolumeManager) attachment(volumeID string, serverID string) (*api.VolumeAttachment, error) {
-	attachments, err := mgr.ListAttachments(&api.ListAttachmentsOptions{
+func (mgr *VolumeManager) attachment(ctx context.Context, volumeID string, serverID string) (*api.VolumeAttachment, error) {
+	attachments, err := mgr.ListAttachmentsWithContext(ctx, &api.ListAttachmentsOptions{
 		VolumeID: &volumeID,
 		ServerID: &serverID,
 	})
@@ -111,10 +145,10 @@ func (mgr *VolumeManager) attachment(volumeID string, serverID string) (*api.Vol
 	return nil, nil
 }
 
-//ListAttachments returns all the attachments of an Server
-func (mgr *VolumeManager) ListAttachments(options *api.ListAttachmentsOptions) ([]api.VolumeAttachment, api.ListVolumeAttachmentsError) {
+//ListAttachmentsWithContext returns all the attachments of an Server
+func (mgr *VolumeManager) ListAttachmentsWithContext(ctx context.Context, options *api.ListAttachmentsOptions) ([]api.VolumeAttachment, api.ListVolumeAttachmentsError) {
 	if options.ServerID != nil {
-		page, err := volumeattach.List(mgr.Provider.BaseServices.Compute, *options.ServerID).AllPages()
+		page, err := volumeattach.List(mgr.Provider.BaseServices.compute(ctx), *options.ServerID).AllPages()
 		if err != nil {
 			return nil, api.NewListVolumeAttachmentsError(UnwrapOpenStackError(err), options)
 		}
@@ -133,7 +167,7 @@ func (mgr *VolumeManager) ListAttachments(options *api.ListAttachmentsOptions) (
 		}
 		return res, nil
 	}
-	servers, err := mgr.Provider.ServerManager.List()
+	servers, err := mgr.Provider.ServerManager.ListWithContext(ctx)
 	if err != nil {
 		return nil, api.NewListVolumeAttachmentsError(UnwrapOpenStackError(err), options)
 	}
@@ -143,7 +177,7 @@ func (mgr *VolumeManager) ListAttachments(options *api.ListAttachmentsOptions) (
 			VolumeID: options.VolumeID,
 			ServerID: &server.ID,
 		}
-		serverAttachments, err := mgr.ListAttachments(&opt)
+		serverAttachments, err := mgr.ListAttachmentsWithContext(ctx, &opt)
 		if err != nil {
 			return nil, api.NewListVolumeAttachmentsError(UnwrapOpenStackError(err), options)
 		}
@@ -153,13 +187,23 @@ func (mgr *VolumeManager) ListAttachments(options *api.ListAttachmentsOptions) (
 
 }
 
-func (mgr *VolumeManager) Resize(options api.ResizeVolumeOptions) (*api.Volume, api.ResizeVolumeError) {
-	err := volumeactions.ExtendSize(mgr.Provider.BaseServices.Volume, options.ID, volumeactions.ExtendSizeOpts{
+//ListAttachments returns all the attachments of an Server
+func (mgr *VolumeManager) ListAttachments(options *api.ListAttachmentsOptions) ([]api.VolumeAttachment, api.ListVolumeAttachmentsError) {
+	return mgr.ListAttachmentsWithContext(context.Background(), options)
+}
+
+//ResizeWithContext context aware version of Resize
+func (mgr *VolumeManager) ResizeWithContext(ctx context.Context, options api.ResizeVolumeOptions) (*api.Volume, api.ResizeVolumeError) {
+	err := volumeactions.ExtendSize(mgr.Provider.BaseServices.volume(ctx), options.ID, volumeactions.ExtendSizeOpts{
 		NewSize: int(options.Size)}).Err
 	if err != nil {
 		return nil, api.NewResizeVolumeError(UnwrapOpenStackError(err), options)
 	}
 
-	v, err := mgr.Get(options.ID)
+	v, err := mgr.GetWithContext(ctx, options.ID)
 	return v, api.NewResizeVolumeError(UnwrapOpenStackError(err), options)
 }
+
+func (mgr *VolumeManager) Resize(options api.ResizeVolumeOptions) (*api.Volume, api.ResizeVolumeError) {
+	return mgr.ResizeWithContext(context.Background(), options)
+}
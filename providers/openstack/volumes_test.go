@@ -1,6 +1,7 @@
 package openstack_test
 
 import (
+	"github.com/SebastienDorgan/anyclouds/api"
 	"github.com/SebastienDorgan/anyclouds/tests"
 	"github.com/stretchr/testify/suite"
 	"testing"
@@ -8,12 +9,27 @@ import (
 
 type OSVolumeManagerTestSuite struct {
 	tests.VolumeManagerTestSuite
+	network *api.Network
 }
 
 //SetupSuite set up image manager
+//OpenStack projects have no default network, the unnamed network used by the test suite is created here
 func (suite *OSVolumeManagerTestSuite) SetupSuite() {
 	p := GetProvider()
 	suite.Prov = p
+	n, err := p.GetNetworkManager().CreateNetwork(api.CreateNetworkOptions{
+		CIDR: "10.1.0.0/16",
+	})
+	suite.NoError(err)
+	suite.network = n
+}
+
+//TearDownSuite deletes the network created by SetupSuite
+func (suite *OSVolumeManagerTestSuite) TearDownSuite() {
+	if suite.network != nil {
+		err := suite.Prov.GetNetworkManager().DeleteNetwork(suite.network.ID)
+		suite.NoError(err)
+	}
 }
 
 func TestOSVolumeManagerTestSuite(t *testing.T) {
@@ -0,0 +1,64 @@
+package providers
+
+import (
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/pkg/errors"
+)
+
+//Factory function creating an uninitialized provider
+type Factory func() api.Provider
+
+var (
+	registryLock sync.RWMutex
+	registry     = map[string]Factory{}
+)
+
+//Register makes a provider available by name
+//Providers call it from their init function, it panics if factory is nil or if name is already registered
+func Register(name string, factory Factory) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	if factory == nil {
+		panic("providers: Register factory is nil for provider " + name)
+	}
+	if _, dup := registry[name]; dup {
+		panic("providers: Register called twice for provider " + name)
+	}
+	registry[name] = factory
+}
+
+//Registered returns the sorted list of registered provider names
+func Registered() []string {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	var names []string
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+//New creates the provider registered under name and initializes it with config
+//The package of the provider must be imported, for instance:
+//	import _ "github.com/SebastienDorgan/anyclouds/providers/aws"
+//or all the built-in providers can be imported with:
+//	import _ "github.com/SebastienDorgan/anyclouds/providers/all"
+func New(name string, config io.Reader, format string) (api.Provider, error) {
+	registryLock.RLock()
+	factory, ok := registry[name]
+	registryLock.RUnlock()
+	if !ok {
+		return nil, errors.Errorf("unknown provider %q (forgotten import?)", name)
+	}
+	p := factory()
+	err := p.Init(config, format)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error initializing provider %s", name)
+	}
+	return p, nil
+}
@@ -0,0 +1,45 @@
+package providers_test
+
+import (
+	"io"
+	"testing"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/SebastienDorgan/anyclouds/providers"
+	"github.com/SebastienDorgan/anyclouds/providers/memory"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type failingProvider struct {
+	memory.Provider
+}
+
+func (p *failingProvider) Init(config io.Reader, format string) error {
+	return errors.New("init failure")
+}
+
+func TestRegistry(t *testing.T) {
+	p, err := providers.New("memory", nil, "json")
+	assert.NoError(t, err)
+	assert.IsType(t, &memory.Provider{}, p)
+	nets, err := p.GetNetworkManager().ListNetworks()
+	assert.NoError(t, err)
+	assert.NotEmpty(t, nets)
+
+	_, err = providers.New("unknown", nil, "json")
+	assert.Error(t, err)
+
+	providers.Register("failing", func() api.Provider {
+		return &failingProvider{}
+	})
+	assert.Contains(t, providers.Registered(), "failing")
+	assert.Contains(t, providers.Registered(), "memory")
+	_, err = providers.New("failing", nil, "json")
+	assert.Error(t, err)
+	assert.Panics(t, func() {
+		providers.Register("memory", func() api.Provider {
+			return &memory.Provider{}
+		})
+	})
+}
@@ -1,29 +1,86 @@
 package providers
 
 import (
+	"context"
+	"time"
+
 	"github.com/SebastienDorgan/anyclouds/api"
-	"github.com/SebastienDorgan/retry"
 	"github.com/pkg/errors"
-	"time"
 )
 
-const resourceReachStableStateTimeout = 3 * time.Minute
+//DefaultStableStateTimeout timeout used to wait for a server stable state when the context has no deadline
+var DefaultStableStateTimeout = 3 * time.Minute
 
-//WaitUntilServerReachStableState wait until server reach stable state
-func WaitUntilServerReachStableState(mgr api.ServerManager, serverID string) (*api.Server, error) {
-	get := func() (interface{}, error) {
-		return mgr.Get(serverID)
+//StableStatePollingInterval interval between two server state checks
+var StableStatePollingInterval = time.Second
+
+//PollingInterval interval between two checks of Poll
+var PollingInterval = time.Second
+
+//CleanupTimeout timeout of the deletion of the resources left by a failed creation
+var CleanupTimeout = 5 * time.Minute
+
+//Cleanup calls cleanup with a context detached from the context of a failed creation and bounded by CleanupTimeout
+//A creation usually fails because its context is done, the deletion of the resources already created must not be cancelled with it
+func Cleanup(cleanup func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), CleanupTimeout)
+	defer cancel()
+	return cleanup(ctx)
+}
+
+//Poll calls check every PollingInterval until it returns true or an error, or until ctx is done
+//If ctx has no deadline timeout is applied
+func Poll(ctx context.Context, timeout time.Duration, check func(ctx context.Context) (bool, error)) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	ticker := time.NewTicker(PollingInterval)
+	defer ticker.Stop()
+	for {
+		done, err := check(ctx)
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
 	}
-	finished := func(v interface{}, e error) bool {
-		state := v.(*api.Server).State
-		return state != api.ServerPending
+}
+
+//WaitUntilServerReachStableState wait until server reach stable state or DefaultStableStateTimeout is elapsed
+func WaitUntilServerReachStableState(mgr api.ServerManager, serverID string) (*api.Server, error) {
+	return WaitUntilServerReachStableStateWithContext(context.Background(), mgr, serverID)
+}
+
+//WaitUntilServerReachStableStateWithContext wait until server reach stable state or ctx is done
+//If ctx has no deadline DefaultStableStateTimeout is applied
+func WaitUntilServerReachStableStateWithContext(ctx context.Context, mgr api.ServerManagerWithContext, serverID string) (*api.Server, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultStableStateTimeout)
+		defer cancel()
 	}
-	res := retry.With(get).For(resourceReachStableStateTimeout).Every(time.Second).Until(finished).Go()
-	if res.Timeout || res.LastError != nil {
-		if res.LastError != nil {
-			return nil, errors.Wrap(res.LastError, "Stop: server in error")
+	ticker := time.NewTicker(StableStatePollingInterval)
+	defer ticker.Stop()
+	for {
+		srv, err := mgr.GetWithContext(ctx, serverID)
+		if err != nil {
+			return nil, errors.Wrap(err, "Stop: server in error")
+		}
+		if srv.State != api.ServerPending {
+			return srv, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, errors.Wrap(ctx.Err(), "Timeout: server does not reach stable state")
+		case <-ticker.C:
 		}
-		return nil, errors.Errorf("Timeout: server does not reach stable state")
 	}
-	return res.LastValue.(*api.Server), nil
 }
@@ -0,0 +1,83 @@
+package providers_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/SebastienDorgan/anyclouds/providers"
+	"github.com/SebastienDorgan/anyclouds/providers/memory"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaitUntilServerReachStableStateWithContext(t *testing.T) {
+	interval := providers.StableStatePollingInterval
+	defer func() { providers.StableStatePollingInterval = interval }()
+	providers.StableStatePollingInterval = 10 * time.Millisecond
+	var prov memory.Provider
+	err := prov.Init(strings.NewReader(`{"ProvisioningDelay": "200ms"}`), "json")
+	assert.NoError(t, err)
+	nets, err := prov.GetNetworkManager().ListNetworks()
+	assert.NoError(t, err)
+	sn, err := prov.GetNetworkManager().CreateSubnet(api.CreateSubnetOptions{
+		NetworkID: nets[0].ID,
+		Name:      "subnet",
+		CIDR:      "172.31.0.0/24",
+		IPVersion: api.IPVersion4,
+	})
+	assert.NoError(t, err)
+	options := api.CreateServerOptions{
+		Name:       "server",
+		TemplateID: "tpl-small",
+		ImageID:    "img-ubuntu-1804",
+		Subnets:    []api.Subnet{*sn},
+	}
+	mgr := prov.GetServerManager()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = mgr.CreateWithContext(ctx, options)
+	assert.Error(t, err)
+
+	srvs, err := mgr.List()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(srvs))
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = providers.WaitUntilServerReachStableStateWithContext(ctx, mgr, srvs[0].ID)
+	assert.Error(t, err)
+
+	srv, err := providers.WaitUntilServerReachStableStateWithContext(context.Background(), mgr, srvs[0].ID)
+	assert.NoError(t, err)
+	assert.Equal(t, api.ServerReady, srv.State)
+}
+
+func TestPoll(t *testing.T) {
+	interval := providers.PollingInterval
+	defer func() { providers.PollingInterval = interval }()
+	providers.PollingInterval = 10 * time.Millisecond
+
+	calls := 0
+	err := providers.Poll(context.Background(), time.Minute, func(ctx context.Context) (bool, error) {
+		calls++
+		return calls == 3, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	err = providers.Poll(ctx, time.Minute, func(ctx context.Context) (bool, error) {
+		return false, nil
+	})
+	assert.Equal(t, context.Canceled, err)
+	assert.True(t, time.Since(start) < time.Second)
+
+	err = providers.Poll(context.Background(), 30*time.Millisecond, func(ctx context.Context) (bool, error) {
+		return false, nil
+	})
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
@@ -0,0 +1,168 @@
+//Package selector selects server templates from hardware requirements
+//It allows a server size to be chosen portably instead of using provider specific template names
+package selector
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/SebastienDorgan/talgo"
+)
+
+//GPURequirement defines whether the selected templates must, may or must not have GPUs
+type GPURequirement int
+
+const (
+	//NoGPU the templates must not have GPUs
+	NoGPU GPURequirement = iota
+	//AnyGPU the templates may have GPUs
+	AnyGPU
+	//WithGPU the templates must have at least one GPU
+	WithGPU
+)
+
+//Criteria requirements the selected templates must satisfy, zero values are not checked
+type Criteria struct {
+	//MinCPU minimum number of CPU cores
+	MinCPU int
+	//MinRAM minimum RAM size in MB
+	MinRAM int
+	//Arch CPU architecture
+	Arch api.CPUArch
+	//GPU GPU requirement, templates with GPUs are excluded by default
+	GPU GPURequirement
+	//MaxPrice maximum on demand hourly price, templates whose price is unknown do not satisfy it
+	MaxPrice float32
+	//NetworkSpeed minimum network speed in Mbps, templates whose network speed is unknown do not satisfy it
+	NetworkSpeed int
+}
+
+//Match returns true if tpl satisfies c
+func (c *Criteria) Match(tpl *api.ServerTemplate) bool {
+	if tpl.NumberOfCPUCore < c.MinCPU || tpl.RAMSize < c.MinRAM {
+		return false
+	}
+	if c.Arch != "" && tpl.Arch != c.Arch {
+		return false
+	}
+	hasGPU := tpl.GPUInfo != nil && tpl.GPUInfo.Number > 0
+	if c.GPU == NoGPU && hasGPU || c.GPU == WithGPU && !hasGPU {
+		return false
+	}
+	if c.MaxPrice > 0 && (tpl.OneDemandPrice <= 0 || tpl.OneDemandPrice > c.MaxPrice) {
+		return false
+	}
+	return c.NetworkSpeed <= 0 || tpl.NetworkSpeed >= c.NetworkSpeed
+}
+
+//Strategy ranking strategy of the templates matching criteria c
+//It returns a negative number if a ranks before b, a positive number if b ranks before a and 0 if they rank equally
+type Strategy func(c *Criteria, a, b *api.ServerTemplate) int
+
+func compareInts(a, b int) int {
+	if a < b {
+		return -1
+	}
+	if a > b {
+		return 1
+	}
+	return 0
+}
+
+func compareFloats(a, b float64) int {
+	if a < b {
+		return -1
+	}
+	if a > b {
+		return 1
+	}
+	return 0
+}
+
+//compareKnown compares a and b, unknown values (<= 0) rank after known ones
+func compareKnown(a, b float64) int {
+	if a <= 0 || b <= 0 {
+		return compareFloats(b, a)
+	}
+	return compareFloats(a, b)
+}
+
+//Cheapest ranks templates by increasing on demand price, templates whose price is unknown rank last
+func Cheapest(c *Criteria, a, b *api.ServerTemplate) int {
+	return compareKnown(float64(a.OneDemandPrice), float64(b.OneDemandPrice))
+}
+
+//ClosestFit ranks templates by increasing number of CPU cores then by increasing RAM size, i.e. the templates exceeding the least the criteria rank first
+func ClosestFit(c *Criteria, a, b *api.ServerTemplate) int {
+	if r := compareInts(a.NumberOfCPUCore, b.NumberOfCPUCore); r != 0 {
+		return r
+	}
+	return compareInts(a.RAMSize, b.RAMSize)
+}
+
+//BestPricePerCore ranks templates by increasing price per CPU core, templates whose price is unknown rank last
+func BestPricePerCore(c *Criteria, a, b *api.ServerTemplate) int {
+	perCore := func(tpl *api.ServerTemplate) float64 {
+		if tpl.NumberOfCPUCore <= 0 {
+			return 0
+		}
+		return float64(tpl.OneDemandPrice) / float64(tpl.NumberOfCPUCore)
+	}
+	return compareKnown(perCore(a), perCore(b))
+}
+
+//Filter returns the templates satisfying c ranked by the strategies of sortBy
+//Templates ranking equally with a strategy are ranked by the next one, then by ID
+func Filter(templates []api.ServerTemplate, c Criteria, sortBy ...Strategy) []api.ServerTemplate {
+	indexes := talgo.FindAll(len(templates), func(i int) bool {
+		return c.Match(&templates[i])
+	})
+	res := make([]api.ServerTemplate, 0, len(indexes))
+	for _, i := range indexes {
+		res = append(res, templates[i])
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		for _, s := range sortBy {
+			if r := s(&c, &res[i], &res[j]); r != 0 {
+				return r < 0
+			}
+		}
+		return res[i].ID < res[j].ID
+	})
+	return res
+}
+
+//TemplatesWithContext returns the templates of mgr satisfying c ranked by the strategies of sortBy
+func TemplatesWithContext(ctx context.Context, mgr api.ServerTemplateManager, c Criteria, sortBy ...Strategy) ([]api.ServerTemplate, error) {
+	templates, err := mgr.ListWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return Filter(templates, c, sortBy...), nil
+}
+
+//Templates returns the templates of mgr satisfying c ranked by the strategies of sortBy
+func Templates(mgr api.ServerTemplateManager, c Criteria, sortBy ...Strategy) ([]api.ServerTemplate, error) {
+	return TemplatesWithContext(context.Background(), mgr, c, sortBy...)
+}
+
+//BestWithContext returns the template of mgr satisfying c ranked first by the strategies of sortBy
+//The error is of kind api.ErrNotFound if no template satisfies c
+func BestWithContext(ctx context.Context, mgr api.ServerTemplateManager, c Criteria, sortBy ...Strategy) (*api.ServerTemplate, error) {
+	templates, err := TemplatesWithContext(ctx, mgr, c, sortBy...)
+	if err != nil {
+		return nil, err
+	}
+	if len(templates) == 0 {
+		return nil, api.WithKind(fmt.Errorf("no server template matches %+v", c), api.ErrNotFound)
+	}
+	return &templates[0], nil
+}
+
+//Best returns the template of mgr satisfying c ranked first by the strategies of sortBy
+//The error is of kind api.ErrNotFound if no template satisfies c
+func Best(mgr api.ServerTemplateManager, c Criteria, sortBy ...Strategy) (*api.ServerTemplate, error) {
+	return BestWithContext(context.Background(), mgr, c, sortBy...)
+}
@@ -0,0 +1,63 @@
+package selector_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/SebastienDorgan/anyclouds/providers/memory"
+	"github.com/SebastienDorgan/anyclouds/selector"
+	"github.com/stretchr/testify/assert"
+)
+
+var templates = []api.ServerTemplate{
+	{ID: "a", NumberOfCPUCore: 2, RAMSize: 4096, Arch: api.ArchAmd64, NetworkSpeed: 1000, OneDemandPrice: 0.05},
+	{ID: "b", NumberOfCPUCore: 4, RAMSize: 16384, Arch: api.ArchAmd64, NetworkSpeed: 10000, OneDemandPrice: 0.20},
+	{ID: "c", NumberOfCPUCore: 4, RAMSize: 8192, Arch: api.ArchAmd64, NetworkSpeed: 5000, OneDemandPrice: 0.17},
+	{ID: "d", NumberOfCPUCore: 8, RAMSize: 16384, Arch: api.ArchAmd64, OneDemandPrice: 0.30},
+	{ID: "e", NumberOfCPUCore: 4, RAMSize: 8192, Arch: "arm64", NetworkSpeed: 10000, OneDemandPrice: 0.08},
+	{ID: "f", NumberOfCPUCore: 8, RAMSize: 61440, Arch: api.ArchAmd64, OneDemandPrice: 0.90, GPUInfo: &api.GPUInfo{Number: 1}},
+	{ID: "g", NumberOfCPUCore: 16, RAMSize: 65536, Arch: api.ArchAmd64},
+}
+
+func ids(l []api.ServerTemplate) []string {
+	res := []string{}
+	for _, tpl := range l {
+		res = append(res, tpl.ID)
+	}
+	return res
+}
+
+func TestCriteria(t *testing.T) {
+	assert.Equal(t, []string{"a", "b", "c", "d", "e", "g"}, ids(selector.Filter(templates, selector.Criteria{})))
+	assert.Equal(t, []string{"b", "c", "d", "g"}, ids(selector.Filter(templates, selector.Criteria{MinCPU: 4, Arch: api.ArchAmd64})))
+	assert.Equal(t, []string{"b", "d", "g"}, ids(selector.Filter(templates, selector.Criteria{MinRAM: 16000})))
+	assert.Equal(t, []string{"f"}, ids(selector.Filter(templates, selector.Criteria{GPU: selector.WithGPU})))
+	assert.Equal(t, 7, len(selector.Filter(templates, selector.Criteria{GPU: selector.AnyGPU})))
+	assert.Equal(t, []string{"a", "c", "e"}, ids(selector.Filter(templates, selector.Criteria{MaxPrice: 0.18})))
+	assert.Equal(t, []string{"b", "c", "e"}, ids(selector.Filter(templates, selector.Criteria{NetworkSpeed: 5000})))
+}
+
+func TestStrategies(t *testing.T) {
+	c := selector.Criteria{MinCPU: 4}
+	assert.Equal(t, []string{"e", "c", "b", "d", "g"}, ids(selector.Filter(templates, c, selector.Cheapest)))
+	assert.Equal(t, []string{"c", "e", "b", "d", "g"}, ids(selector.Filter(templates, c, selector.ClosestFit)))
+	assert.Equal(t, []string{"e", "c", "b", "d", "g"}, ids(selector.Filter(templates, c, selector.ClosestFit, selector.Cheapest)))
+	assert.Equal(t, []string{"e", "d", "c", "b", "g"}, ids(selector.Filter(templates, c, selector.BestPricePerCore)))
+	assert.Equal(t, []string{"g", "b", "d", "c", "e"}, ids(selector.Filter(templates, c, func(c *selector.Criteria, a, b *api.ServerTemplate) int {
+		return b.RAMSize - a.RAMSize
+	}, selector.ClosestFit)))
+}
+
+func TestBest(t *testing.T) {
+	p := &memory.Provider{}
+	assert.NoError(t, p.Init(nil, ""))
+	tpl, err := selector.Best(p.GetTemplateManager(), selector.Criteria{MinCPU: 4, MinRAM: 15000, Arch: api.ArchAmd64}, selector.ClosestFit)
+	assert.NoError(t, err)
+	assert.Equal(t, "tpl-large", tpl.ID)
+	tpl, err = selector.Best(p.GetTemplateManager(), selector.Criteria{MinCPU: 4}, selector.BestPricePerCore)
+	assert.NoError(t, err)
+	assert.Equal(t, "tpl-arm-large", tpl.ID)
+	_, err = selector.Best(p.GetTemplateManager(), selector.Criteria{MinCPU: 1000})
+	assert.True(t, errors.Is(err, api.ErrNotFound))
+}
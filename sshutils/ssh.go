@@ -40,7 +40,7 @@ func CreateClient(cfg *SSHConfig) (*ssh.Client, error) {
 //KeyPair a key pair
 type KeyPair struct {
 	PublicKey  []byte
-	PrivateKey []byte
+	PrivateKey []byte `redact:"true"`
 }
 
 // CreateKeyPair creates a key pair using size bits
@@ -73,3 +73,12 @@ func (kp *KeyPair) AuthMethod() (ssh.AuthMethod, error) {
 	}
 	return ssh.PublicKeys(signer), nil
 }
+
+//Fingerprint returns the MD5 fingerprint of a public key in authorized_keys format
+func Fingerprint(publicKey []byte) (string, error) {
+	pub, _, _, _, err := ssh.ParseAuthorizedKey(publicKey)
+	if err != nil {
+		return "", err
+	}
+	return ssh.FingerprintLegacyMD5(pub), nil
+}
@@ -0,0 +1,472 @@
+package stack
+
+import (
+	"context"
+	"strings"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/SebastienDorgan/anyclouds/sshutils"
+	"github.com/pkg/errors"
+)
+
+//applier executes the changes of a plan
+type applier struct {
+	ctx  context.Context
+	prov api.Provider
+	plan *Plan
+	//resources resources of the stack indexed by kind and name, updated as changes are executed
+	resources map[Kind]map[string]Resource
+}
+
+func (a *applier) tags(name string) map[string]string {
+	return map[string]string{StackTag: a.plan.Spec.Name, NameTag: name}
+}
+
+func (a *applier) set(r Resource) {
+	if a.resources[r.Kind] == nil {
+		a.resources[r.Kind] = map[string]Resource{}
+	}
+	a.resources[r.Kind][r.Name] = r
+}
+
+//id returns the identifier of the resource of kind named name
+func (a *applier) id(kind Kind, name string) (string, error) {
+	r, ok := a.resources[kind][name]
+	if !ok {
+		return "", errors.Errorf("%s %s does not exist", kind, name)
+	}
+	return r.ID, nil
+}
+
+func (a *applier) list() []Resource {
+	var res []Resource
+	for _, resources := range a.resources {
+		for _, r := range resources {
+			res = append(res, r)
+		}
+	}
+	sortResources(res)
+	return res
+}
+
+func (a *applier) createNetwork(name string) error {
+	for _, n := range a.plan.Spec.Networks {
+		if n.Name != name {
+			continue
+		}
+		net, err := a.prov.GetNetworkManager().CreateNetworkWithContext(a.ctx, api.CreateNetworkOptions{
+			Name: n.Name,
+			CIDR: n.CIDR,
+			Tags: a.tags(n.Name),
+		})
+		if err != nil {
+			return err
+		}
+		a.set(Resource{Kind: KindNetwork, Name: name, ID: net.ID})
+	}
+	return nil
+}
+
+func (a *applier) createSubnet(name string) error {
+	for _, sn := range a.plan.Spec.Subnets {
+		if sn.Name != name {
+			continue
+		}
+		network, err := a.id(KindNetwork, sn.Network)
+		if err != nil {
+			return err
+		}
+		version := sn.IPVersion
+		if version == 0 {
+			version = api.IPVersion4
+		}
+		subnet, err := a.prov.GetNetworkManager().CreateSubnetWithContext(a.ctx, api.CreateSubnetOptions{
+			NetworkID: network,
+			Name:      sn.Name,
+			CIDR:      sn.CIDR,
+			IPVersion: version,
+			Tags:      a.tags(sn.Name),
+		})
+		if err != nil {
+			return err
+		}
+		a.set(Resource{Kind: KindSubnet, Name: name, ID: subnet.ID, Parent: network})
+	}
+	return nil
+}
+
+func (a *applier) addRule(groupID string, rule RuleSpec) error {
+	_, err := a.prov.GetSecurityGroupManager().AddSecurityRuleWithContext(a.ctx, api.AddSecurityRuleOptions{
+		SecurityGroupID: groupID,
+		Direction:       rule.direction(),
+		PortRange:       api.PortRange{From: rule.From, To: rule.To},
+		Protocol:        rule.Protocol,
+		CIDR:            rule.CIDR,
+		Description:     rule.Description,
+	})
+	return err
+}
+
+func (a *applier) createSecurityGroup(name string) error {
+	for _, sg := range a.plan.Spec.SecurityGroups {
+		if sg.Name != name {
+			continue
+		}
+		network, err := a.id(KindNetwork, sg.Network)
+		if err != nil {
+			return err
+		}
+		group, err := a.prov.GetSecurityGroupManager().CreateWithContext(a.ctx, api.SecurityGroupOptions{
+			Name:        sg.Name,
+			Description: sg.Description,
+			NetworkID:   network,
+			Tags:        a.tags(sg.Name),
+		})
+		if err != nil {
+			return err
+		}
+		a.set(Resource{Kind: KindSecurityGroup, Name: name, ID: group.ID})
+		for _, rule := range sg.Rules {
+			err = a.addRule(group.ID, rule)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (a *applier) updateSecurityGroup(c *Change) error {
+	changes := a.plan.rules[c.Name]
+	if changes == nil {
+		return nil
+	}
+	mgr := a.prov.GetSecurityGroupManager()
+	for _, id := range changes.remove {
+		err := mgr.RemoveSecurityRuleWithContext(a.ctx, c.ID, id)
+		if err != nil {
+			return err
+		}
+	}
+	for _, rule := range changes.add {
+		err := a.addRule(c.ID, rule)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *applier) createVolume(name string) error {
+	for _, v := range a.plan.Spec.Volumes {
+		if v.Name != name {
+			continue
+		}
+		vol, err := a.prov.GetVolumeManager().CreateWithContext(a.ctx, api.CreateVolumeOptions{
+			Name:        v.Name,
+			Size:        v.Size,
+			MinIOPS:     v.MinIOPS,
+			MinDataRate: v.MinDataRate,
+			Tags:        a.tags(v.Name),
+		})
+		if err != nil {
+			return err
+		}
+		a.set(Resource{Kind: KindVolume, Name: name, ID: vol.ID})
+	}
+	return nil
+}
+
+func (a *applier) updateVolume(c *Change) error {
+	for _, v := range a.plan.Spec.Volumes {
+		if v.Name != c.Name {
+			continue
+		}
+		_, err := a.prov.GetVolumeManager().ResizeWithContext(a.ctx, api.ResizeVolumeOptions{
+			ID:          c.ID,
+			Size:        v.Size,
+			MinIOPS:     v.MinIOPS,
+			MinDataRate: v.MinDataRate,
+		})
+		return err
+	}
+	return nil
+}
+
+func (a *applier) createServer(name string) error {
+	for _, srv := range a.plan.Spec.Servers {
+		if srv.Name != name {
+			continue
+		}
+		options := api.CreateServerOptions{
+			Name:        srv.Name,
+			TemplateID:  a.plan.templates[srv.Name],
+			ImageID:     a.plan.images[srv.Name],
+			KeyPairName: srv.KeyPair,
+			Tags:        a.tags(srv.Name),
+		}
+		if srv.KeyPair == "" {
+			kp, err := sshutils.CreateKeyPair(2048)
+			if err != nil {
+				return err
+			}
+			options.KeyPair = *kp
+		}
+		if srv.SecurityGroup != "" {
+			id, err := a.id(KindSecurityGroup, srv.SecurityGroup)
+			if err != nil {
+				return err
+			}
+			options.DefaultSecurityGroup = id
+		}
+		for _, name := range srv.Subnets {
+			r, ok := a.resources[KindSubnet][name]
+			if !ok {
+				return errors.Errorf("%s %s does not exist", KindSubnet, name)
+			}
+			sn, err := a.prov.GetNetworkManager().GetSubnetWithContext(a.ctx, r.Parent, r.ID)
+			if err != nil {
+				return err
+			}
+			options.Subnets = append(options.Subnets, *sn)
+		}
+		if srv.BootstrapScript != "" {
+			options.BootstrapScript = strings.NewReader(srv.BootstrapScript)
+		}
+		server, err := a.prov.GetServerManager().CreateWithContext(a.ctx, options)
+		if err != nil {
+			return err
+		}
+		a.set(Resource{Kind: KindServer, Name: name, ID: server.ID})
+	}
+	return nil
+}
+
+//deleteServer dissociates the public ips of the server identified by id then deletes it
+func (a *applier) deleteServer(id string) error {
+	mgr := a.prov.GetPublicIPAddressManager()
+	ips, err := mgr.ListWithContext(a.ctx, &api.ListPublicIPsOptions{ServerID: &id})
+	if err != nil {
+		return err
+	}
+	for _, ip := range ips {
+		err = mgr.DissociateWithContext(a.ctx, ip.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return a.prov.GetServerManager().DeleteWithContext(a.ctx, id)
+}
+
+func (a *applier) createAttachment(name string) error {
+	for _, att := range a.plan.Spec.Attachments {
+		if attachmentName(att.Volume, att.Server) != name {
+			continue
+		}
+		volume, err := a.id(KindVolume, att.Volume)
+		if err != nil {
+			return err
+		}
+		server, err := a.id(KindServer, att.Server)
+		if err != nil {
+			return err
+		}
+		_, err = a.prov.GetVolumeManager().AttachWithContext(a.ctx, api.AttachVolumeOptions{
+			VolumeID:   volume,
+			ServerID:   server,
+			DevicePath: att.Device,
+		})
+		if err != nil {
+			return err
+		}
+		a.set(Resource{Kind: KindAttachment, Name: name, ID: volume, Parent: server})
+	}
+	return nil
+}
+
+//associate associates the public ip identified by id with the server declared in ip, if any
+func (a *applier) associate(id string, ip *PublicIPSpec) (string, error) {
+	if ip.Server == "" {
+		return "", nil
+	}
+	server, err := a.id(KindServer, ip.Server)
+	if err != nil {
+		return "", err
+	}
+	options := api.AssociatePublicIPOptions{PublicIPId: id, ServerID: server}
+	if ip.Subnet != "" {
+		options.SubnetID, err = a.id(KindSubnet, ip.Subnet)
+		if err != nil {
+			return "", err
+		}
+	}
+	return server, a.prov.GetPublicIPAddressManager().AssociateWithContext(a.ctx, options)
+}
+
+//dissociate dissociates the public ip identified by id if it is associated
+func (a *applier) dissociate(id string) error {
+	mgr := a.prov.GetPublicIPAddressManager()
+	ip, err := mgr.GetWithContext(a.ctx, id)
+	if err != nil {
+		return err
+	}
+	if ip.NetworkInterfaceID == "" {
+		return nil
+	}
+	return mgr.DissociateWithContext(a.ctx, id)
+}
+
+func (a *applier) createPublicIP(name string) error {
+	for i := range a.plan.Spec.PublicIPs {
+		ip := &a.plan.Spec.PublicIPs[i]
+		if ip.Name != name {
+			continue
+		}
+		pip, err := a.prov.GetPublicIPAddressManager().CreateWithContext(a.ctx, api.CreatePublicIPOptions{
+			Name: ip.Name,
+			Tags: a.tags(ip.Name),
+		})
+		if err != nil {
+			return err
+		}
+		a.set(Resource{Kind: KindPublicIP, Name: name, ID: pip.ID})
+		server, err := a.associate(pip.ID, ip)
+		if err != nil {
+			return err
+		}
+		a.set(Resource{Kind: KindPublicIP, Name: name, ID: pip.ID, Parent: server})
+	}
+	return nil
+}
+
+func (a *applier) updatePublicIP(c *Change) error {
+	for i := range a.plan.Spec.PublicIPs {
+		ip := &a.plan.Spec.PublicIPs[i]
+		if ip.Name != c.Name {
+			continue
+		}
+		err := a.dissociate(c.ID)
+		if err != nil {
+			return err
+		}
+		a.set(Resource{Kind: KindPublicIP, Name: c.Name, ID: c.ID})
+		server, err := a.associate(c.ID, ip)
+		if err != nil {
+			return err
+		}
+		a.set(Resource{Kind: KindPublicIP, Name: c.Name, ID: c.ID, Parent: server})
+	}
+	return nil
+}
+
+func (a *applier) create(c *Change) error {
+	switch c.Kind {
+	case KindNetwork:
+		return a.createNetwork(c.Name)
+	case KindSubnet:
+		return a.createSubnet(c.Name)
+	case KindSecurityGroup:
+		return a.createSecurityGroup(c.Name)
+	case KindVolume:
+		return a.createVolume(c.Name)
+	case KindServer:
+		return a.createServer(c.Name)
+	case KindAttachment:
+		return a.createAttachment(c.Name)
+	case KindPublicIP:
+		return a.createPublicIP(c.Name)
+	}
+	return errors.Errorf("unknown resource kind %s", c.Kind)
+}
+
+func (a *applier) update(c *Change) error {
+	switch c.Kind {
+	case KindSecurityGroup:
+		return a.updateSecurityGroup(c)
+	case KindVolume:
+		return a.updateVolume(c)
+	case KindServer:
+		return a.prov.GetServerManager().ResizeWithContext(a.ctx, c.ID, a.plan.templates[c.Name])
+	case KindPublicIP:
+		return a.updatePublicIP(c)
+	}
+	return errors.Errorf("%s %s cannot be updated", c.Kind, c.Name)
+}
+
+func (a *applier) delete(c *Change) error {
+	r := a.resources[c.Kind][c.Name]
+	if r.ID != c.ID {
+		//duplicates are not indexed
+		r = Resource{Kind: c.Kind, Name: c.Name, ID: c.ID}
+		for _, o := range a.plan.duplicates {
+			if o.Kind == c.Kind && o.ID == c.ID {
+				r = o
+			}
+		}
+	}
+	var err error
+	switch c.Kind {
+	case KindNetwork:
+		err = a.prov.GetNetworkManager().DeleteNetworkWithContext(a.ctx, r.ID)
+	case KindSubnet:
+		err = a.prov.GetNetworkManager().DeleteSubnetWithContext(a.ctx, r.Parent, r.ID)
+	case KindSecurityGroup:
+		err = a.prov.GetSecurityGroupManager().DeleteWithContext(a.ctx, r.ID)
+	case KindVolume:
+		err = a.prov.GetVolumeManager().DeleteWithContext(a.ctx, r.ID)
+	case KindServer:
+		err = a.deleteServer(r.ID)
+	case KindAttachment:
+		err = a.prov.GetVolumeManager().DetachWithContext(a.ctx, api.DetachVolumeOptions{VolumeID: r.ID, ServerID: r.Parent})
+	case KindPublicIP:
+		err = a.dissociate(r.ID)
+		if err == nil {
+			err = a.prov.GetPublicIPAddressManager().DeleteWithContext(a.ctx, r.ID)
+		}
+	default:
+		err = errors.Errorf("unknown resource kind %s", c.Kind)
+	}
+	if err != nil {
+		return err
+	}
+	if a.resources[c.Kind][c.Name].ID == c.ID {
+		delete(a.resources[c.Kind], c.Name)
+	}
+	return nil
+}
+
+//ApplyWithContext executes the changes of plan in order using the managers of prov and returns the resources of the stack
+//Execution stops at the first change failing, the resources returned are then the resources of the stack at that point
+func ApplyWithContext(ctx context.Context, prov api.Provider, plan *Plan) ([]Resource, error) {
+	a := &applier{
+		ctx:       ctx,
+		prov:      prov,
+		plan:      plan,
+		resources: map[Kind]map[string]Resource{},
+	}
+	for _, r := range plan.Resources {
+		a.set(r)
+	}
+	for i := range plan.Changes {
+		c := &plan.Changes[i]
+		var err error
+		switch c.Action {
+		case Create:
+			err = a.create(c)
+		case Update:
+			err = a.update(c)
+		case Delete:
+			err = a.delete(c)
+		}
+		if err != nil {
+			return a.list(), api.NewErrorStack(err, "error applying change", c.String())
+		}
+	}
+	return a.list(), nil
+}
+
+//Apply executes the changes of plan in order using the managers of prov and returns the resources of the stack
+//Execution stops at the first change failing, the resources returned are then the resources of the stack at that point
+func Apply(prov api.Provider, plan *Plan) ([]Resource, error) {
+	return ApplyWithContext(context.Background(), prov, plan)
+}
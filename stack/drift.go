@@ -0,0 +1,105 @@
+package stack
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+)
+
+//Drift difference between a resource recorded in a state and the resource reported by the provider
+type Drift struct {
+	Resource Resource
+	//Deleted true if the resource does not exist anymore
+	Deleted bool
+	//Differences descriptions of the attributes changed out of band
+	Differences []string
+}
+
+//String returns a one line description of d
+func (d Drift) String() string {
+	s := fmt.Sprintf("%s %s (%s)", d.Resource.Kind, d.Resource.Name, d.Resource.ID)
+	if d.Deleted {
+		return s + ": deleted"
+	}
+	return s + ": " + strings.Join(d.Differences, ", ")
+}
+
+//ruleDifferences describes the rules added to and removed from a security group
+func ruleDifferences(recorded, current []api.SecurityRule) []string {
+	count := func(rules []api.SecurityRule) map[string]int {
+		res := map[string]int{}
+		for i := range rules {
+			res[ruleKey(&rules[i])]++
+		}
+		return res
+	}
+	before, after := count(recorded), count(current)
+	var diffs []string
+	for i := range current {
+		key := ruleKey(&current[i])
+		if before[key] > 0 {
+			before[key]--
+			continue
+		}
+		diffs = append(diffs, "rule "+key+" added")
+	}
+	for i := range recorded {
+		key := ruleKey(&recorded[i])
+		if after[key] > 0 {
+			after[key]--
+			continue
+		}
+		diffs = append(diffs, "rule "+key+" removed")
+	}
+	return diffs
+}
+
+//differences describes the attributes of recorded changed in current
+func differences(recorded, current *ResourceState) []string {
+	var diffs []string
+	changed := func(attribute, before, after string) {
+		if before != after {
+			diffs = append(diffs, fmt.Sprintf("%s changed from %q to %q", attribute, before, after))
+		}
+	}
+	changed("cidr", recorded.CIDR, current.CIDR)
+	diffs = append(diffs, ruleDifferences(recorded.Rules, current.Rules)...)
+	if recorded.Size != current.Size {
+		diffs = append(diffs, fmt.Sprintf("size changed from %d to %d", recorded.Size, current.Size))
+	}
+	changed("template", recorded.TemplateID, current.TemplateID)
+	changed("image", recorded.ImageID, current.ImageID)
+	changed("state", string(recorded.ServerState), string(current.ServerState))
+	changed("address", recorded.Address, current.Address)
+	if recorded.Kind == KindPublicIP {
+		changed("server", recorded.Parent, current.Parent)
+	}
+	return diffs
+}
+
+//DetectDriftWithContext re-reads the resources recorded in st with the managers of prov and returns the resources changed out of band
+func DetectDriftWithContext(ctx context.Context, prov api.Provider, st *State) ([]Drift, error) {
+	var drifts []Drift
+	for i := range st.Resources {
+		recorded := &st.Resources[i]
+		current, err := read(ctx, prov, recorded.Resource)
+		if err != nil {
+			return nil, api.NewErrorStack(err, "error reading resource", recorded.Resource)
+		}
+		if current == nil {
+			drifts = append(drifts, Drift{Resource: recorded.Resource, Deleted: true})
+			continue
+		}
+		if diffs := differences(recorded, current); len(diffs) > 0 {
+			drifts = append(drifts, Drift{Resource: recorded.Resource, Differences: diffs})
+		}
+	}
+	return drifts, nil
+}
+
+//DetectDrift re-reads the resources recorded in st with the managers of prov and returns the resources changed out of band
+func DetectDrift(prov api.Provider, st *State) ([]Drift, error) {
+	return DetectDriftWithContext(context.Background(), prov, st)
+}
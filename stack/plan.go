@@ -0,0 +1,740 @@
+package stack
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/SebastienDorgan/anyclouds/selector"
+)
+
+const (
+	//StackTag tag set to the name of the stack on the resources of the stack
+	StackTag = "anyclouds-stack"
+	//NameTag tag set to the name of the resource in the specification
+	NameTag = "anyclouds-name"
+)
+
+//Kind kind of a resource of a stack
+type Kind string
+
+const (
+	//KindNetwork a network
+	KindNetwork Kind = "network"
+	//KindSubnet a sub network
+	KindSubnet Kind = "subnet"
+	//KindSecurityGroup a security group
+	KindSecurityGroup Kind = "security-group"
+	//KindVolume a volume
+	KindVolume Kind = "volume"
+	//KindServer a server
+	KindServer Kind = "server"
+	//KindAttachment the attachment of a volume to a server
+	KindAttachment Kind = "attachment"
+	//KindPublicIP a public ip address
+	KindPublicIP Kind = "public-ip"
+)
+
+//kinds kinds of resources in dependency order, a resource only depends on resources of the preceding kinds
+var kinds = []Kind{KindNetwork, KindSubnet, KindSecurityGroup, KindVolume, KindServer, KindAttachment, KindPublicIP}
+
+func rank(k Kind) int {
+	for i, o := range kinds {
+		if o == k {
+			return i
+		}
+	}
+	return len(kinds)
+}
+
+//attachmentName returns the name of the attachment of volume to server
+func attachmentName(volume, server string) string {
+	return volume + "@" + server
+}
+
+//Resource a resource of a stack
+type Resource struct {
+	Kind Kind
+	//Name name of the resource in the specification
+	Name string
+	//ID identifier of the resource in the provider, the identifier of the volume for an attachment
+	ID string
+	//Parent identifier of the network of a subnet, of the server of an attachment or of the server a public ip is associated with
+	Parent string
+}
+
+//Action action of a change
+type Action string
+
+const (
+	//Create the resource is created
+	Create Action = "create"
+	//Update the resource is updated in place
+	Update Action = "update"
+	//Delete the resource is deleted
+	Delete Action = "delete"
+)
+
+//Change a change of a plan
+//A resource that cannot be updated in place is replaced by a Delete change followed by a Create change
+type Change struct {
+	Action Action
+	Kind   Kind
+	Name   string
+	//ID identifier of the resource changed, empty for a creation
+	ID string
+	//Reason why the change is needed
+	Reason string
+}
+
+//String returns a one line description of c
+func (c Change) String() string {
+	symbols := map[Action]string{Create: "+", Update: "~", Delete: "-"}
+	s := fmt.Sprintf("%s %s %s", symbols[c.Action], c.Kind, c.Name)
+	if c.ID != "" {
+		s += " (" + c.ID + ")"
+	}
+	if c.Reason != "" {
+		s += ": " + c.Reason
+	}
+	return s
+}
+
+//Plan changes converging the resources of a stack to its specification
+//Changes are in execution order: deletions in reverse dependency order, then creations and updates in dependency order
+type Plan struct {
+	Spec    *Spec
+	Changes []Change
+	//Resources resources of the stack reported by the provider when the plan was computed
+	Resources []Resource
+	//templates and images identifiers of the templates and of the images of the servers
+	templates map[string]string
+	images    map[string]string
+	//rules rules to remove from and to add to the security groups updated by the plan
+	rules map[string]*ruleChanges
+	//duplicates resources of the stack having the same kind and name as another resource
+	duplicates []Resource
+}
+
+//Empty returns true if the plan has no change
+func (p *Plan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+//String returns a description of the changes of the plan, one line per change
+func (p *Plan) String() string {
+	if p.Empty() {
+		return "no change"
+	}
+	lines := make([]string, len(p.Changes))
+	for i, c := range p.Changes {
+		lines[i] = c.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+//ruleChanges rules to remove from and to add to a security group
+type ruleChanges struct {
+	remove []string
+	add    []RuleSpec
+}
+
+//direction returns the direction of r, ingress by default
+func (r *RuleSpec) direction() api.RuleDirection {
+	if r.Direction == "" {
+		return api.RuleDirectionIngress
+	}
+	return r.Direction
+}
+
+func (r *RuleSpec) key() string {
+	return fmt.Sprintf("%s/%s/%d-%d/%s", r.direction(), r.Protocol, r.From, r.To, r.CIDR)
+}
+
+func ruleKey(r *api.SecurityRule) string {
+	spec := RuleSpec{Direction: r.Direction, Protocol: r.Protocol, From: r.PortRange.From, To: r.PortRange.To, CIDR: r.CIDR}
+	return spec.key()
+}
+
+//state resources of a stack reported by a provider, indexed by kind and name
+type state struct {
+	resources  map[Kind]map[string]Resource
+	duplicates []Resource
+	networks   map[string]api.Network
+	subnets    map[string]api.Subnet
+	groups     map[string]api.SecurityGroup
+	volumes    map[string]api.Volume
+	servers    map[string]api.Server
+	//interfaces network interfaces of the servers
+	interfaces  map[string][]api.NetworkInterface
+	attachments map[string]api.VolumeAttachment
+	publicIPs   map[string]api.PublicIP
+}
+
+//add adds r to s, it returns false if a resource of the same kind and name is already in s
+func (s *state) add(r Resource) bool {
+	if _, ok := s.resources[r.Kind][r.Name]; ok {
+		s.duplicates = append(s.duplicates, r)
+		return false
+	}
+	if s.resources[r.Kind] == nil {
+		s.resources[r.Kind] = map[string]Resource{}
+	}
+	s.resources[r.Kind][r.Name] = r
+	return true
+}
+
+//name returns the name of the resource of kind identified by id, empty if it is not a resource of the stack
+func (s *state) name(kind Kind, id string) string {
+	for name, r := range s.resources[kind] {
+		if r.ID == id {
+			return name
+		}
+	}
+	return ""
+}
+
+//owned returns the name of the resource tagged with tags and true if the resource belongs to the stack
+func owned(stack string, tags map[string]string) (string, bool) {
+	name := tags[NameTag]
+	return name, tags[StackTag] == stack && name != ""
+}
+
+func (s *state) readNetworks(ctx context.Context, prov api.Provider, stack string) error {
+	mgr := prov.GetNetworkManager()
+	networks, err := mgr.ListNetworksWithContext(ctx)
+	if err != nil {
+		return err
+	}
+	for _, n := range networks {
+		if name, ok := owned(stack, n.Tags); ok && s.add(Resource{Kind: KindNetwork, Name: name, ID: n.ID}) {
+			s.networks[name] = n
+		}
+	}
+	for _, n := range s.networks {
+		subnets, err := mgr.ListSubnetsWithContext(ctx, n.ID)
+		if err != nil {
+			return err
+		}
+		for _, sn := range subnets {
+			if name, ok := owned(stack, sn.Tags); ok && s.add(Resource{Kind: KindSubnet, Name: name, ID: sn.ID, Parent: n.ID}) {
+				s.subnets[name] = sn
+			}
+		}
+	}
+	return nil
+}
+
+func (s *state) readSecurityGroups(ctx context.Context, prov api.Provider, stack string) error {
+	groups, err := prov.GetSecurityGroupManager().ListWithContext(ctx)
+	if err != nil {
+		return err
+	}
+	for _, sg := range groups {
+		if name, ok := owned(stack, sg.Tags); ok && s.add(Resource{Kind: KindSecurityGroup, Name: name, ID: sg.ID}) {
+			s.groups[name] = sg
+		}
+	}
+	return nil
+}
+
+func (s *state) readServers(ctx context.Context, prov api.Provider, stack string) error {
+	servers, err := prov.GetServerManager().ListWithContext(ctx)
+	if err != nil {
+		return err
+	}
+	for _, srv := range servers {
+		if srv.State == api.ServerDeleted {
+			continue
+		}
+		if name, ok := owned(stack, srv.Tags); ok && s.add(Resource{Kind: KindServer, Name: name, ID: srv.ID}) {
+			s.servers[name] = srv
+		}
+	}
+	mgr := prov.GetNetworkInterfaceManager()
+	for name, srv := range s.servers {
+		id := srv.ID
+		nics, err := mgr.ListWithContext(ctx, &api.ListNetworkInterfacesOptions{ServerID: &id})
+		if err != nil {
+			return err
+		}
+		s.interfaces[name] = nics
+	}
+	return nil
+}
+
+//connections returns the sorted names of the subnets and of the security groups of the stack the server named name is connected to
+//The identifier of a subnet that does not belong to the stack is returned instead of its name, the security groups that do not belong
+//to the stack are ignored
+func (s *state) connections(name string) (subnets []string, groups []string) {
+	seen := map[string]bool{}
+	for _, ni := range s.interfaces[name] {
+		sn := s.name(KindSubnet, ni.SubnetID)
+		if sn == "" {
+			sn = ni.SubnetID
+		}
+		subnets = append(subnets, sn)
+		if sg := s.name(KindSecurityGroup, ni.SecurityGroupID); sg != "" && !seen[sg] {
+			seen[sg] = true
+			groups = append(groups, sg)
+		}
+	}
+	sort.Strings(subnets)
+	sort.Strings(groups)
+	return subnets, groups
+}
+
+//readVolumes reads the volumes of the stack and their attachments to the servers of the stack
+func (s *state) readVolumes(ctx context.Context, prov api.Provider, stack string) error {
+	mgr := prov.GetVolumeManager()
+	volumes, err := mgr.ListWithContext(ctx)
+	if err != nil {
+		return err
+	}
+	for _, v := range volumes {
+		if name, ok := owned(stack, v.Tags); ok && s.add(Resource{Kind: KindVolume, Name: name, ID: v.ID}) {
+			s.volumes[name] = v
+		}
+	}
+	for name, v := range s.volumes {
+		id := v.ID
+		attachments, err := mgr.ListAttachmentsWithContext(ctx, &api.ListAttachmentsOptions{VolumeID: &id})
+		if err != nil {
+			return err
+		}
+		for _, a := range attachments {
+			server := s.name(KindServer, a.ServerID)
+			if server == "" {
+				continue
+			}
+			att := attachmentName(name, server)
+			if s.add(Resource{Kind: KindAttachment, Name: att, ID: a.VolumeID, Parent: a.ServerID}) {
+				s.attachments[att] = a
+			}
+		}
+	}
+	return nil
+}
+
+//readPublicIPs reads the public ips of the stack, the server a public ip is associated with is its parent
+func (s *state) readPublicIPs(ctx context.Context, prov api.Provider, stack string) error {
+	mgr := prov.GetPublicIPAddressManager()
+	ips, err := mgr.ListWithContext(ctx, nil)
+	if err != nil {
+		return err
+	}
+	servers := map[string]string{}
+	for _, srv := range s.resources[KindServer] {
+		id := srv.ID
+		associated, err := mgr.ListWithContext(ctx, &api.ListPublicIPsOptions{ServerID: &id})
+		if err != nil {
+			return err
+		}
+		for _, ip := range associated {
+			servers[ip.ID] = id
+		}
+	}
+	for _, ip := range ips {
+		if name, ok := owned(stack, ip.Tags); ok && s.add(Resource{Kind: KindPublicIP, Name: name, ID: ip.ID, Parent: servers[ip.ID]}) {
+			s.publicIPs[name] = ip
+		}
+	}
+	return nil
+}
+
+//readState reads the resources of stack reported by prov
+func readState(ctx context.Context, prov api.Provider, stack string) (*state, error) {
+	s := &state{
+		resources:   map[Kind]map[string]Resource{},
+		networks:    map[string]api.Network{},
+		subnets:     map[string]api.Subnet{},
+		groups:      map[string]api.SecurityGroup{},
+		volumes:     map[string]api.Volume{},
+		servers:     map[string]api.Server{},
+		interfaces:  map[string][]api.NetworkInterface{},
+		attachments: map[string]api.VolumeAttachment{},
+		publicIPs:   map[string]api.PublicIP{},
+	}
+	readers := []func(context.Context, api.Provider, string) error{
+		s.readNetworks,
+		s.readSecurityGroups,
+		s.readServers,
+		s.readVolumes,
+		s.readPublicIPs,
+	}
+	for _, read := range readers {
+		err := read(ctx, prov, stack)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+//planner computes the changes of a plan
+type planner struct {
+	ctx   context.Context
+	prov  api.Provider
+	spec  *Spec
+	cur   *state
+	plan  *Plan
+	spare map[Kind]map[string]bool
+	//created resources created by the plan, the resources depending on them must be created too
+	created map[Kind]map[string]bool
+}
+
+func (p *planner) add(c Change) {
+	p.plan.Changes = append(p.plan.Changes, c)
+	if c.Action == Create {
+		if p.created[c.Kind] == nil {
+			p.created[c.Kind] = map[string]bool{}
+		}
+		p.created[c.Kind][c.Name] = true
+	}
+}
+
+//current returns the resource of kind named name and marks it as declared, it returns false if it does not exist
+func (p *planner) current(kind Kind, name string) (Resource, bool) {
+	r, ok := p.cur.resources[kind][name]
+	if ok {
+		delete(p.spare[kind], name)
+	}
+	return r, ok
+}
+
+func (p *planner) create(kind Kind, name string) {
+	p.add(Change{Action: Create, Kind: kind, Name: name})
+}
+
+func (p *planner) replace(r Resource, reason string) {
+	p.add(Change{Action: Delete, Kind: r.Kind, Name: r.Name, ID: r.ID, Reason: reason})
+	p.add(Change{Action: Create, Kind: r.Kind, Name: r.Name, Reason: reason})
+}
+
+func (p *planner) update(r Resource, reason string) {
+	p.add(Change{Action: Update, Kind: r.Kind, Name: r.Name, ID: r.ID, Reason: reason})
+}
+
+//recreated returns a reason to replace a resource if one of the resources of kind named names is created by the plan
+func (p *planner) recreated(kind Kind, names ...string) (string, bool) {
+	for _, name := range names {
+		if p.created[kind][name] {
+			return fmt.Sprintf("%s %s is created", kind, name), true
+		}
+	}
+	return "", false
+}
+
+func (p *planner) planNetworks() {
+	for _, n := range p.spec.Networks {
+		r, ok := p.current(KindNetwork, n.Name)
+		if !ok {
+			p.create(KindNetwork, n.Name)
+		} else if cidr := p.cur.networks[n.Name].CIDR; cidr != n.CIDR {
+			p.replace(r, fmt.Sprintf("cidr changes from %s to %s", cidr, n.CIDR))
+		}
+	}
+}
+
+func (p *planner) planSubnets() {
+	for _, sn := range p.spec.Subnets {
+		r, ok := p.current(KindSubnet, sn.Name)
+		if !ok {
+			p.create(KindSubnet, sn.Name)
+		} else if reason, ok := p.recreated(KindNetwork, sn.Network); ok {
+			p.replace(r, reason)
+		} else if network := p.cur.name(KindNetwork, r.Parent); network != sn.Network {
+			p.replace(r, fmt.Sprintf("network changes from %s to %s", network, sn.Network))
+		} else if cidr := p.cur.subnets[sn.Name].CIDR; cidr != sn.CIDR {
+			p.replace(r, fmt.Sprintf("cidr changes from %s to %s", cidr, sn.CIDR))
+		}
+	}
+}
+
+func (p *planner) planSecurityGroups() {
+	for _, sg := range p.spec.SecurityGroups {
+		r, ok := p.current(KindSecurityGroup, sg.Name)
+		if !ok {
+			p.create(KindSecurityGroup, sg.Name)
+			continue
+		}
+		cur := p.cur.groups[sg.Name]
+		if reason, ok := p.recreated(KindNetwork, sg.Network); ok {
+			p.replace(r, reason)
+			continue
+		}
+		if network := p.cur.name(KindNetwork, cur.NetworkID); network != sg.Network {
+			p.replace(r, fmt.Sprintf("network changes from %s to %s", network, sg.Network))
+			continue
+		}
+		changes := &ruleChanges{}
+		desired := map[string]bool{}
+		for _, rule := range sg.Rules {
+			desired[rule.key()] = true
+		}
+		existing := map[string]bool{}
+		for _, rule := range cur.Rules {
+			key := ruleKey(&rule)
+			if !desired[key] || existing[key] {
+				changes.remove = append(changes.remove, rule.ID)
+			}
+			existing[key] = true
+		}
+		for _, rule := range sg.Rules {
+			if !existing[rule.key()] {
+				changes.add = append(changes.add, rule)
+				existing[rule.key()] = true
+			}
+		}
+		if len(changes.remove) > 0 || len(changes.add) > 0 {
+			p.plan.rules[sg.Name] = changes
+			p.update(r, fmt.Sprintf("%d rules removed, %d rules added", len(changes.remove), len(changes.add)))
+		}
+	}
+}
+
+func (p *planner) planVolumes() {
+	for _, v := range p.spec.Volumes {
+		r, ok := p.current(KindVolume, v.Name)
+		if !ok {
+			p.create(KindVolume, v.Name)
+			continue
+		}
+		size := p.cur.volumes[v.Name].Size
+		if v.Size < size {
+			p.replace(r, fmt.Sprintf("size shrinks from %d to %d", size, v.Size))
+		} else if v.Size > size {
+			p.update(r, fmt.Sprintf("size grows from %d to %d", size, v.Size))
+		}
+	}
+}
+
+//resolveTemplate returns the identifier of the template of srv, the template of the current server is kept if it satisfies the criteria
+func (p *planner) resolveTemplate(srv *ServerSpec, templates []api.ServerTemplate) (string, error) {
+	if srv.TemplateCriteria == nil {
+		return srv.Template, nil
+	}
+	if cur, ok := p.cur.servers[srv.Name]; ok {
+		for _, tpl := range templates {
+			if tpl.ID == cur.TemplateID && srv.TemplateCriteria.Match(&tpl) {
+				return tpl.ID, nil
+			}
+		}
+	}
+	tpl, err := selector.BestWithContext(p.ctx, p.prov.GetTemplateManager(), *srv.TemplateCriteria, selector.ClosestFit, selector.Cheapest)
+	if err != nil {
+		return "", err
+	}
+	return tpl.ID, nil
+}
+
+//resolveImage returns the identifier of the image of srv, the image of the current server is kept if it matches the query
+func (p *planner) resolveImage(srv *ServerSpec) (string, error) {
+	if srv.ImageQuery == nil {
+		return srv.Image, nil
+	}
+	mgr := p.prov.GetImageManager()
+	if cur, ok := p.cur.servers[srv.Name]; ok {
+		img, err := mgr.GetWithContext(p.ctx, cur.ImageID)
+		if err == nil && srv.ImageQuery.Match(img) {
+			return img.ID, nil
+		}
+	}
+	img, err := mgr.FindWithContext(p.ctx, *srv.ImageQuery)
+	if err != nil {
+		return "", err
+	}
+	return img.ID, nil
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *planner) planServers() error {
+	var templates []api.ServerTemplate
+	for i := range p.spec.Servers {
+		srv := &p.spec.Servers[i]
+		if srv.TemplateCriteria != nil && templates == nil {
+			l, err := p.prov.GetTemplateManager().ListWithContext(p.ctx)
+			if err != nil {
+				return err
+			}
+			templates = l
+		}
+		tpl, err := p.resolveTemplate(srv, templates)
+		if err != nil {
+			return err
+		}
+		img, err := p.resolveImage(srv)
+		if err != nil {
+			return err
+		}
+		p.plan.templates[srv.Name] = tpl
+		p.plan.images[srv.Name] = img
+		r, ok := p.current(KindServer, srv.Name)
+		if !ok {
+			p.create(KindServer, srv.Name)
+			continue
+		}
+		cur := p.cur.servers[srv.Name]
+		subnets, groups := p.cur.connections(srv.Name)
+		desiredSubnets := append([]string{}, srv.Subnets...)
+		sort.Strings(desiredSubnets)
+		var desiredGroups []string
+		if srv.SecurityGroup != "" {
+			desiredGroups = []string{srv.SecurityGroup}
+		}
+		if reason, ok := p.recreated(KindSubnet, srv.Subnets...); ok {
+			p.replace(r, reason)
+		} else if reason, ok := p.recreated(KindSecurityGroup, srv.SecurityGroup); ok {
+			p.replace(r, reason)
+		} else if !equal(subnets, desiredSubnets) {
+			p.replace(r, fmt.Sprintf("subnets change from %v to %v", subnets, desiredSubnets))
+		} else if !equal(groups, desiredGroups) {
+			p.replace(r, fmt.Sprintf("security groups change from %v to %v", groups, desiredGroups))
+		} else if cur.ImageID != img {
+			p.replace(r, fmt.Sprintf("image changes from %s to %s", cur.ImageID, img))
+		} else if cur.TemplateID != tpl {
+			p.update(r, fmt.Sprintf("template changes from %s to %s", cur.TemplateID, tpl))
+		}
+	}
+	return nil
+}
+
+func (p *planner) planAttachments() {
+	for _, a := range p.spec.Attachments {
+		name := attachmentName(a.Volume, a.Server)
+		r, ok := p.current(KindAttachment, name)
+		if !ok {
+			p.create(KindAttachment, name)
+		} else if reason, ok := p.recreated(KindVolume, a.Volume); ok {
+			p.replace(r, reason)
+		} else if reason, ok := p.recreated(KindServer, a.Server); ok {
+			p.replace(r, reason)
+		}
+	}
+}
+
+func (p *planner) planPublicIPs() {
+	for _, ip := range p.spec.PublicIPs {
+		r, ok := p.current(KindPublicIP, ip.Name)
+		if !ok {
+			p.create(KindPublicIP, ip.Name)
+			continue
+		}
+		server := p.cur.name(KindServer, r.Parent)
+		if reason, ok := p.recreated(KindServer, ip.Server); ok {
+			p.update(r, reason)
+		} else if server != ip.Server {
+			p.update(r, fmt.Sprintf("server changes from %q to %q", server, ip.Server))
+		}
+	}
+}
+
+//planDeletions adds the deletion of the resources of the stack that are not declared in the specification
+func (p *planner) planDeletions() {
+	for _, kind := range kinds {
+		for name := range p.spare[kind] {
+			r := p.cur.resources[kind][name]
+			p.add(Change{Action: Delete, Kind: kind, Name: name, ID: r.ID, Reason: "not declared"})
+		}
+	}
+	for _, r := range p.cur.duplicates {
+		p.add(Change{Action: Delete, Kind: r.Kind, Name: r.Name, ID: r.ID, Reason: "duplicate"})
+	}
+}
+
+//sort sorts the changes in execution order
+func (p *planner) sort() {
+	phase := func(c *Change) int {
+		if c.Action == Delete {
+			return -rank(c.Kind) - 1
+		}
+		return rank(c.Kind)
+	}
+	changes := p.plan.Changes
+	sort.SliceStable(changes, func(i, j int) bool {
+		pi, pj := phase(&changes[i]), phase(&changes[j])
+		if pi != pj {
+			return pi < pj
+		}
+		return changes[i].Name < changes[j].Name
+	})
+}
+
+//NewPlanWithContext computes the plan converging the resources of the stack reported by prov to spec
+//The resources of the stack are the resources tagged with StackTag set to the name of the stack
+func NewPlanWithContext(ctx context.Context, prov api.Provider, spec *Spec) (*Plan, error) {
+	err := spec.Validate()
+	if err != nil {
+		return nil, err
+	}
+	cur, err := readState(ctx, prov, spec.Name)
+	if err != nil {
+		return nil, api.NewErrorStack(err, "error reading stack", spec.Name)
+	}
+	p := &planner{
+		ctx:     ctx,
+		prov:    prov,
+		spec:    spec,
+		cur:     cur,
+		spare:   map[Kind]map[string]bool{},
+		created: map[Kind]map[string]bool{},
+		plan: &Plan{
+			Spec:       spec,
+			templates:  map[string]string{},
+			images:     map[string]string{},
+			rules:      map[string]*ruleChanges{},
+			duplicates: cur.duplicates,
+		},
+	}
+	for kind, resources := range cur.resources {
+		p.spare[kind] = map[string]bool{}
+		for name, r := range resources {
+			p.spare[kind][name] = true
+			p.plan.Resources = append(p.plan.Resources, r)
+		}
+	}
+	sortResources(p.plan.Resources)
+	p.planNetworks()
+	p.planSubnets()
+	p.planSecurityGroups()
+	p.planVolumes()
+	err = p.planServers()
+	if err != nil {
+		return nil, api.NewErrorStack(err, "error planning stack", spec.Name)
+	}
+	p.planAttachments()
+	p.planPublicIPs()
+	p.planDeletions()
+	p.sort()
+	return p.plan, nil
+}
+
+//NewPlan computes the plan converging the resources of the stack reported by prov to spec
+//The resources of the stack are the resources tagged with StackTag set to the name of the stack
+func NewPlan(prov api.Provider, spec *Spec) (*Plan, error) {
+	return NewPlanWithContext(context.Background(), prov, spec)
+}
+
+//sortResources sorts resources in dependency order then by name
+func sortResources(resources []Resource) {
+	sort.SliceStable(resources, func(i, j int) bool {
+		ri, rj := rank(resources[i].Kind), rank(resources[j].Kind)
+		if ri != rj {
+			return ri < rj
+		}
+		return resources[i].Name < resources[j].Name
+	})
+}
@@ -0,0 +1,275 @@
+//Package stack manages infrastructures declared in YAML or JSON specifications
+//A plan of the changes needed to converge the resources reported by an api.Provider to the specification is computed, then applied
+//in dependency order using the managers of the provider
+package stack
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/SebastienDorgan/anyclouds/selector"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+//NetworkSpec declares a network
+type NetworkSpec struct {
+	Name string
+	CIDR string
+}
+
+//SubnetSpec declares a sub network
+type SubnetSpec struct {
+	Name string
+	//Network name of the network of the sub network
+	Network   string
+	CIDR      string
+	IPVersion api.IPVersion
+}
+
+//RuleSpec declares a rule of a security group
+type RuleSpec struct {
+	Direction   api.RuleDirection
+	Protocol    api.Protocol
+	From        int
+	To          int
+	CIDR        string
+	Description string
+}
+
+//SecurityGroupSpec declares a security group
+type SecurityGroupSpec struct {
+	Name string
+	//Network name of the network of the security group
+	Network     string
+	Description string
+	Rules       []RuleSpec
+}
+
+//ServerSpec declares a server
+//The template is either identified by Template or selected from TemplateCriteria, the image is either identified by Image or
+//found with ImageQuery
+type ServerSpec struct {
+	Name             string
+	Template         string
+	TemplateCriteria *selector.Criteria
+	Image            string
+	ImageQuery       *api.ImageQuery
+	//SecurityGroup name of the default security group of the server
+	SecurityGroup string
+	//Subnets names of the sub networks the server is connected to
+	Subnets []string
+	//KeyPair name of a key pair imported with the KeyPairManager
+	//If it is empty the server is created with a generated key pair whose private key is discarded, the server can then only be
+	//accessed through credentials set up by its bootstrap script
+	KeyPair         string
+	BootstrapScript string
+}
+
+//VolumeSpec declares a volume
+type VolumeSpec struct {
+	Name        string
+	Size        int64
+	MinIOPS     int64
+	MinDataRate int64
+}
+
+//AttachmentSpec declares the attachment of a volume to a server
+type AttachmentSpec struct {
+	//Volume name of the volume
+	Volume string
+	//Server name of the server
+	Server string
+	Device string
+}
+
+//PublicIPSpec declares a public IP address
+type PublicIPSpec struct {
+	Name string
+	//Server name of the server the address is associated with, the address is not associated if it is empty
+	Server string
+	//Subnet name of the sub network of the server the address is associated with, required if the server has several sub networks
+	Subnet string
+}
+
+//Spec declares the resources of a stack
+//Resources reference each other by name, all the resources of a kind must have distinct names
+type Spec struct {
+	//Name name of the stack, the resources of the stack are tagged with it
+	Name           string
+	Networks       []NetworkSpec
+	Subnets        []SubnetSpec
+	SecurityGroups []SecurityGroupSpec
+	Servers        []ServerSpec
+	Volumes        []VolumeSpec
+	Attachments    []AttachmentSpec
+	PublicIPs      []PublicIPSpec
+}
+
+//Load reads a specification in format (yaml or json) from r and validates it
+func Load(r io.Reader, format string) (*Spec, error) {
+	v := viper.New()
+	v.SetConfigType(format)
+	err := v.ReadConfig(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading stack specification")
+	}
+	spec := &Spec{}
+	err = v.Unmarshal(spec)
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading stack specification")
+	}
+	err = spec.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+func invalid(format string, args ...interface{}) error {
+	return api.WithKind(fmt.Errorf(format, args...), api.ErrInvalidArgument)
+}
+
+//names returns the set of the names of n resources, an error is returned if a name is empty or duplicated
+func names(kind Kind, n int, name func(i int) string) (map[string]bool, error) {
+	set := map[string]bool{}
+	for i := 0; i < n; i++ {
+		s := name(i)
+		if s == "" {
+			return nil, invalid("%s %d has no name", kind, i)
+		}
+		if set[s] {
+			return nil, invalid("%s %s is declared twice", kind, s)
+		}
+		set[s] = true
+	}
+	return set, nil
+}
+
+//checkRef returns an error if name is not the name of a resource of kind declared in set
+func checkRef(set map[string]bool, kind Kind, name string, from Kind, fromName string) error {
+	if !set[name] {
+		return invalid("%s %s references undeclared %s %q", from, fromName, kind, name)
+	}
+	return nil
+}
+
+func (s *Spec) validateServer(srv *ServerSpec, subnets, groups map[string]bool) error {
+	if srv.Template == "" && srv.TemplateCriteria == nil {
+		return invalid("server %s has neither template nor template criteria", srv.Name)
+	}
+	if srv.Image == "" && srv.ImageQuery == nil {
+		return invalid("server %s has neither image nor image query", srv.Name)
+	}
+	if len(srv.Subnets) == 0 {
+		return invalid("server %s has no subnet", srv.Name)
+	}
+	for _, sn := range srv.Subnets {
+		if err := checkRef(subnets, KindSubnet, sn, KindServer, srv.Name); err != nil {
+			return err
+		}
+	}
+	if srv.SecurityGroup == "" {
+		return nil
+	}
+	return checkRef(groups, KindSecurityGroup, srv.SecurityGroup, KindServer, srv.Name)
+}
+
+func (s *Spec) validatePublicIP(ip *PublicIPSpec, servers map[string]bool) error {
+	if ip.Server == "" {
+		if ip.Subnet != "" {
+			return invalid("public-ip %s has a subnet but no server", ip.Name)
+		}
+		return nil
+	}
+	if err := checkRef(servers, KindServer, ip.Server, KindPublicIP, ip.Name); err != nil {
+		return err
+	}
+	if ip.Subnet == "" {
+		return nil
+	}
+	for _, srv := range s.Servers {
+		if srv.Name != ip.Server {
+			continue
+		}
+		for _, sn := range srv.Subnets {
+			if sn == ip.Subnet {
+				return nil
+			}
+		}
+	}
+	return invalid("public-ip %s references subnet %q the server %s is not connected to", ip.Name, ip.Subnet, ip.Server)
+}
+
+//Validate checks that the resources of s have distinct names and that the references between them are valid
+//The error is of kind api.ErrInvalidArgument
+func (s *Spec) Validate() error {
+	if s.Name == "" {
+		return invalid("stack has no name")
+	}
+	networks, err := names(KindNetwork, len(s.Networks), func(i int) string { return s.Networks[i].Name })
+	if err != nil {
+		return err
+	}
+	subnets, err := names(KindSubnet, len(s.Subnets), func(i int) string { return s.Subnets[i].Name })
+	if err != nil {
+		return err
+	}
+	groups, err := names(KindSecurityGroup, len(s.SecurityGroups), func(i int) string { return s.SecurityGroups[i].Name })
+	if err != nil {
+		return err
+	}
+	servers, err := names(KindServer, len(s.Servers), func(i int) string { return s.Servers[i].Name })
+	if err != nil {
+		return err
+	}
+	volumes, err := names(KindVolume, len(s.Volumes), func(i int) string { return s.Volumes[i].Name })
+	if err != nil {
+		return err
+	}
+	_, err = names(KindPublicIP, len(s.PublicIPs), func(i int) string { return s.PublicIPs[i].Name })
+	if err != nil {
+		return err
+	}
+	for _, sn := range s.Subnets {
+		if err := checkRef(networks, KindNetwork, sn.Network, KindSubnet, sn.Name); err != nil {
+			return err
+		}
+	}
+	for _, sg := range s.SecurityGroups {
+		if err := checkRef(networks, KindNetwork, sg.Network, KindSecurityGroup, sg.Name); err != nil {
+			return err
+		}
+	}
+	for i := range s.Servers {
+		if err := s.validateServer(&s.Servers[i], subnets, groups); err != nil {
+			return err
+		}
+	}
+	for _, v := range s.Volumes {
+		if v.Size <= 0 {
+			return invalid("volume %s has no size", v.Name)
+		}
+	}
+	attachments := map[string]bool{}
+	for _, a := range s.Attachments {
+		name := attachmentName(a.Volume, a.Server)
+		if err := checkRef(volumes, KindVolume, a.Volume, KindAttachment, name); err != nil {
+			return err
+		}
+		if err := checkRef(servers, KindServer, a.Server, KindAttachment, name); err != nil {
+			return err
+		}
+		if attachments[a.Volume] {
+			return invalid("volume %s is attached twice", a.Volume)
+		}
+		attachments[a.Volume] = true
+	}
+	for i := range s.PublicIPs {
+		if err := s.validatePublicIP(&s.PublicIPs[i], servers); err != nil {
+			return err
+		}
+	}
+	return nil
+}
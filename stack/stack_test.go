@@ -0,0 +1,267 @@
+package stack_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/SebastienDorgan/anyclouds/providers/memory"
+	"github.com/SebastienDorgan/anyclouds/stack"
+	"github.com/stretchr/testify/assert"
+)
+
+const spec = `
+name: web
+networks:
+  - name: net
+    cidr: 10.0.0.0/16
+subnets:
+  - name: front
+    network: net
+    cidr: 10.0.1.0/24
+securityGroups:
+  - name: web
+    network: net
+    rules:
+      - protocol: tcp
+        from: 80
+        to: 80
+        cidr: 0.0.0.0/0
+      - protocol: tcp
+        from: 22
+        to: 22
+        cidr: 10.0.0.0/8
+servers:
+  - name: www
+    templateCriteria:
+      minCPU: 2
+      minRAM: 8000
+      arch: amd64
+    imageQuery:
+      distribution: ubuntu
+      version: "18.04"
+      latest: true
+    securityGroup: web
+    subnets: [front]
+volumes:
+  - name: data
+    size: 10
+attachments:
+  - volume: data
+    server: www
+publicIPs:
+  - name: www-ip
+    server: www
+`
+
+func load(t *testing.T) *stack.Spec {
+	s, err := stack.Load(strings.NewReader(spec), "yaml")
+	assert.NoError(t, err)
+	return s
+}
+
+func changes(p *stack.Plan) []string {
+	res := []string{}
+	for _, c := range p.Changes {
+		res = append(res, string(c.Action)+" "+string(c.Kind)+" "+c.Name)
+	}
+	return res
+}
+
+func TestLoad(t *testing.T) {
+	s := load(t)
+	assert.Equal(t, "web", s.Name)
+	assert.Equal(t, "10.0.1.0/24", s.Subnets[0].CIDR)
+	assert.Equal(t, 2, len(s.SecurityGroups[0].Rules))
+	assert.Equal(t, api.ProtocolTCP, s.SecurityGroups[0].Rules[0].Protocol)
+	assert.Equal(t, 8000, s.Servers[0].TemplateCriteria.MinRAM)
+	assert.Equal(t, "18.04", s.Servers[0].ImageQuery.Version)
+	assert.True(t, s.Servers[0].ImageQuery.Latest)
+	assert.Equal(t, []string{"front"}, s.Servers[0].Subnets)
+	assert.Equal(t, int64(10), s.Volumes[0].Size)
+
+	_, err := stack.Load(strings.NewReader(`{"name": "web", "subnets": [{"name": "sn", "network": "missing"}]}`), "json")
+	assert.True(t, errors.Is(err, api.ErrInvalidArgument))
+	s.Servers = append(s.Servers, s.Servers[0])
+	assert.True(t, errors.Is(s.Validate(), api.ErrInvalidArgument))
+	s = load(t)
+	s.PublicIPs[0].Subnet = "back"
+	assert.True(t, errors.Is(s.Validate(), api.ErrInvalidArgument))
+}
+
+func apply(t *testing.T, prov api.Provider, s *stack.Spec) []stack.Resource {
+	p, err := stack.NewPlan(prov, s)
+	assert.NoError(t, err)
+	resources, err := stack.Apply(prov, p)
+	assert.NoError(t, err)
+	p, err = stack.NewPlan(prov, s)
+	assert.NoError(t, err)
+	assert.True(t, p.Empty(), p.String())
+	return resources
+}
+
+func TestPlanApply(t *testing.T) {
+	prov := &memory.Provider{}
+	assert.NoError(t, prov.Init(nil, ""))
+	s := load(t)
+
+	p, err := stack.NewPlan(prov, s)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"create network net",
+		"create subnet front",
+		"create security-group web",
+		"create volume data",
+		"create server www",
+		"create attachment data@www",
+		"create public-ip www-ip",
+	}, changes(p))
+	resources := apply(t, prov, s)
+	assert.Equal(t, 7, len(resources))
+	srv, err := prov.GetServerManager().Get(resources[4].ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "tpl-medium", srv.TemplateID)
+	assert.Equal(t, "img-ubuntu-1804", srv.ImageID)
+	assert.Equal(t, "web", srv.Tags[stack.StackTag])
+	ips, err := prov.GetPublicIPAddressManager().List(&api.ListPublicIPsOptions{ServerID: &srv.ID})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(ips))
+
+	s.SecurityGroups[0].Rules = s.SecurityGroups[0].Rules[:1]
+	s.Volumes[0].Size = 20
+	s.Servers[0].TemplateCriteria.MinCPU = 4
+	s.PublicIPs = nil
+	p, err = stack.NewPlan(prov, s)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"delete public-ip www-ip",
+		"update security-group web",
+		"update volume data",
+		"update server www",
+	}, changes(p))
+	apply(t, prov, s)
+	srv, err = prov.GetServerManager().Get(srv.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "tpl-large", srv.TemplateID)
+	sg, err := prov.GetSecurityGroupManager().Get(resources[2].ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(sg.Rules))
+
+	s.Subnets[0].CIDR = "10.0.2.0/24"
+	p, err = stack.NewPlan(prov, s)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"delete attachment data@www",
+		"delete server www",
+		"delete subnet front",
+		"create subnet front",
+		"create server www",
+		"create attachment data@www",
+	}, changes(p))
+	apply(t, prov, s)
+
+	s.Subnets = append(s.Subnets, stack.SubnetSpec{Name: "back", Network: "net", CIDR: "10.0.3.0/24"})
+	s.Servers[0].Subnets = []string{"back"}
+	p, err = stack.NewPlan(prov, s)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"delete attachment data@www",
+		"delete server www",
+		"create subnet back",
+		"create server www",
+		"create attachment data@www",
+	}, changes(p))
+	apply(t, prov, s)
+	srvs, err := prov.GetServerManager().List()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(srvs))
+	nics, err := prov.GetNetworkInterfaceManager().List(&api.ListNetworkInterfacesOptions{ServerID: &srvs[0].ID})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(nics))
+	sn, err := prov.GetNetworkManager().GetSubnet(nics[0].NetworkID, nics[0].SubnetID)
+	assert.NoError(t, err)
+	assert.Equal(t, "10.0.3.0/24", sn.CIDR)
+
+	resources = apply(t, prov, &stack.Spec{Name: "web"})
+	assert.Empty(t, resources)
+	servers, err := prov.GetServerManager().List()
+	assert.NoError(t, err)
+	assert.Empty(t, servers)
+	volumes, err := prov.GetVolumeManager().List()
+	assert.NoError(t, err)
+	assert.Empty(t, volumes)
+}
+
+func TestStateDrift(t *testing.T) {
+	prov := &memory.Provider{}
+	assert.NoError(t, prov.Init(nil, ""))
+	s := load(t)
+	resources := apply(t, prov, s)
+	st, err := stack.NewState(prov, s.Name, resources)
+	assert.NoError(t, err)
+	assert.Equal(t, len(resources), len(st.Resources))
+
+	dir, err := ioutil.TempDir("", "stack")
+	assert.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	path := filepath.Join(dir, "web.json")
+	store := stack.NewFileStore(path)
+	assert.Error(t, store.Write(st))
+	_, err = store.Read()
+	assert.True(t, errors.Is(err, api.ErrNotFound))
+	assert.NoError(t, store.Lock())
+	assert.True(t, errors.Is(stack.NewFileStore(path).Lock(), stack.ErrLocked))
+	assert.NoError(t, store.Write(st))
+	assert.NoError(t, store.Unlock())
+	assert.NoError(t, stack.NewFileStore(path).Lock())
+	assert.True(t, errors.Is(store.Lock(), stack.ErrLocked))
+	assert.NoError(t, store.ForceUnlock())
+	assert.NoError(t, store.Lock())
+	assert.NoError(t, store.Unlock())
+
+	recorded, err := store.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, st.Resources, recorded.Resources)
+	drifts, err := stack.DetectDrift(prov, recorded)
+	assert.NoError(t, err)
+	assert.Empty(t, drifts)
+
+	id := func(kind stack.Kind) string {
+		for _, r := range resources {
+			if r.Kind == kind {
+				return r.ID
+			}
+		}
+		return ""
+	}
+	_, err = prov.GetSecurityGroupManager().AddSecurityRule(api.AddSecurityRuleOptions{
+		SecurityGroupID: id(stack.KindSecurityGroup),
+		Direction:       api.RuleDirectionIngress,
+		PortRange:       api.PortRange{From: 3306, To: 3306},
+		Protocol:        api.ProtocolTCP,
+		CIDR:            "0.0.0.0/0",
+	})
+	assert.NoError(t, err)
+	_, err = prov.GetVolumeManager().Resize(api.ResizeVolumeOptions{ID: id(stack.KindVolume), Size: 50})
+	assert.NoError(t, err)
+	assert.NoError(t, prov.GetServerManager().Stop(id(stack.KindServer)))
+	assert.NoError(t, prov.GetPublicIPAddressManager().Dissociate(id(stack.KindPublicIP)))
+	assert.NoError(t, prov.GetPublicIPAddressManager().Delete(id(stack.KindPublicIP)))
+
+	drifts, err = stack.DetectDrift(prov, recorded)
+	assert.NoError(t, err)
+	descriptions := []string{}
+	for _, d := range drifts {
+		descriptions = append(descriptions, d.String())
+	}
+	assert.Equal(t, []string{
+		"security-group web (" + id(stack.KindSecurityGroup) + "): rule ingress/tcp/3306-3306/0.0.0.0/0 added",
+		"volume data (" + id(stack.KindVolume) + "): size changed from 10 to 50",
+		"server www (" + id(stack.KindServer) + "): state changed from \"READY\" to \"SHUTOFF\"",
+		"public-ip www-ip (" + id(stack.KindPublicIP) + "): deleted",
+	}, descriptions)
+}
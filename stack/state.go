@@ -0,0 +1,259 @@
+package stack
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+)
+
+//ErrLocked error kind of the errors returned when a state file is locked by another process
+var ErrLocked = errors.New("state is locked")
+
+//ResourceState a resource of a stack and its attributes when the state was recorded
+type ResourceState struct {
+	Resource
+	//CIDR CIDR of a network or of a sub network
+	CIDR string `json:",omitempty"`
+	//Rules rules of a security group
+	Rules []api.SecurityRule `json:",omitempty"`
+	//Size size of a volume
+	Size int64 `json:",omitempty"`
+	//TemplateID template of a server
+	TemplateID string `json:",omitempty"`
+	//ImageID image of a server
+	ImageID string `json:",omitempty"`
+	//ServerState state of a server
+	ServerState api.ServerState `json:",omitempty"`
+	//Address address of a public ip
+	Address string `json:",omitempty"`
+}
+
+//State resources of a stack recorded after they have been applied
+type State struct {
+	Stack     string
+	UpdatedAt time.Time
+	Resources []ResourceState
+}
+
+//isNotFound returns true if err means that the resource read does not exist anymore
+func isNotFound(err error) bool {
+	return api.ErrorKind(err) == api.ErrNotFound
+}
+
+//read returns the state of r reported by prov, nil if r does not exist anymore
+func read(ctx context.Context, prov api.Provider, r Resource) (*ResourceState, error) {
+	res := &ResourceState{Resource: r}
+	switch r.Kind {
+	case KindNetwork:
+		n, err := prov.GetNetworkManager().GetNetworkWithContext(ctx, r.ID)
+		if isNotFound(err) {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+		res.CIDR = n.CIDR
+	case KindSubnet:
+		sn, err := prov.GetNetworkManager().GetSubnetWithContext(ctx, r.Parent, r.ID)
+		if isNotFound(err) {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+		res.CIDR = sn.CIDR
+	case KindSecurityGroup:
+		sg, err := prov.GetSecurityGroupManager().GetWithContext(ctx, r.ID)
+		if isNotFound(err) {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+		res.Rules = sg.Rules
+	case KindVolume:
+		v, err := prov.GetVolumeManager().GetWithContext(ctx, r.ID)
+		if isNotFound(err) {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+		res.Size = v.Size
+	case KindServer:
+		srv, err := prov.GetServerManager().GetWithContext(ctx, r.ID)
+		if isNotFound(err) || err == nil && srv.State == api.ServerDeleted {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+		res.TemplateID = srv.TemplateID
+		res.ImageID = srv.ImageID
+		res.ServerState = srv.State
+	case KindAttachment:
+		volume := r.ID
+		attachments, err := prov.GetVolumeManager().ListAttachmentsWithContext(ctx, &api.ListAttachmentsOptions{VolumeID: &volume})
+		if isNotFound(err) {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+		attached := false
+		for _, a := range attachments {
+			attached = attached || a.ServerID == r.Parent
+		}
+		if !attached {
+			return nil, nil
+		}
+	case KindPublicIP:
+		mgr := prov.GetPublicIPAddressManager()
+		ip, err := mgr.GetWithContext(ctx, r.ID)
+		if isNotFound(err) {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+		res.Address = ip.Address
+		res.Parent = ""
+		if ip.NetworkInterfaceID != "" {
+			ni, err := prov.GetNetworkInterfaceManager().GetWithContext(ctx, ip.NetworkInterfaceID)
+			if err != nil && !isNotFound(err) {
+				return nil, err
+			}
+			if err == nil {
+				res.Parent = ni.ServerID
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown resource kind %s", r.Kind)
+	}
+	return res, nil
+}
+
+//NewStateWithContext records the state of resources, the resources of stack returned by Apply, reading them with the managers of prov
+//Resources that do not exist anymore are not recorded
+func NewStateWithContext(ctx context.Context, prov api.Provider, stack string, resources []Resource) (*State, error) {
+	st := &State{Stack: stack, UpdatedAt: time.Now().UTC()}
+	for _, r := range resources {
+		rs, err := read(ctx, prov, r)
+		if err != nil {
+			return nil, api.NewErrorStack(err, "error reading resource", r)
+		}
+		if rs != nil {
+			st.Resources = append(st.Resources, *rs)
+		}
+	}
+	return st, nil
+}
+
+//NewState records the state of resources, the resources of stack returned by Apply, reading them with the managers of prov
+//Resources that do not exist anymore are not recorded
+func NewState(prov api.Provider, stack string, resources []Resource) (*State, error) {
+	return NewStateWithContext(context.Background(), prov, stack, resources)
+}
+
+//FileStore stores the state of a stack in a local JSON file
+//The file is locked by creating a lock file next to it, a single FileStore can hold the lock at a time
+type FileStore struct {
+	Path   string
+	locked bool
+}
+
+//NewFileStore creates a FileStore storing the state in the file path
+func NewFileStore(path string) *FileStore {
+	return &FileStore{Path: path}
+}
+
+func (s *FileStore) lockPath() string {
+	return s.Path + ".lock"
+}
+
+//Lock locks the state file, the error is of kind ErrLocked if the file is already locked
+//The lock file contains the pid of the process holding the lock, a lock left by a crashed process is cleared with ForceUnlock
+func (s *FileStore) Lock() error {
+	f, err := os.OpenFile(s.lockPath(), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if os.IsExist(err) {
+		owner, _ := ioutil.ReadFile(s.lockPath())
+		return api.WithKind(fmt.Errorf("state %s is locked by process %s", s.Path, owner), ErrLocked)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(f, "%d", os.Getpid())
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(s.lockPath())
+		return err
+	}
+	s.locked = true
+	return nil
+}
+
+//Unlock unlocks the state file locked by Lock
+func (s *FileStore) Unlock() error {
+	if !s.locked {
+		return fmt.Errorf("state %s is not locked", s.Path)
+	}
+	s.locked = false
+	return os.Remove(s.lockPath())
+}
+
+//ForceUnlock removes the lock of the state file whoever holds it, it is meant to clear the lock left by a process that crashed
+//while holding it and must not be used while the process holding the lock is alive
+func (s *FileStore) ForceUnlock() error {
+	err := os.Remove(s.lockPath())
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	s.locked = false
+	return nil
+}
+
+//Read reads the state from the file, the error is of kind api.ErrNotFound if the file does not exist
+func (s *FileStore) Read() (*State, error) {
+	data, err := ioutil.ReadFile(s.Path)
+	if os.IsNotExist(err) {
+		return nil, api.WithKind(fmt.Errorf("state %s does not exist", s.Path), api.ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	st := &State{}
+	err = json.Unmarshal(data, st)
+	if err != nil {
+		return nil, api.NewErrorStack(err, "error reading state", s.Path)
+	}
+	return st, nil
+}
+
+//Write replaces the state in the file, the store must be locked
+//The state is written in a temporary file renamed afterwards so that the file is never partially written
+func (s *FileStore) Write(st *State) error {
+	if !s.locked {
+		return fmt.Errorf("state %s must be locked to be written", s.Path)
+	}
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(s.Path), filepath.Base(s.Path)+".*")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), s.Path)
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+	}
+	return err
+}
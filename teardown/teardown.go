@@ -0,0 +1,458 @@
+//Package teardown deletes graphs of resources in dependency order
+//The resources depending on the roots to delete are discovered with the List functions of the managers of an api.Provider, then
+//the graph is deleted from its leaves to its roots, the deletions failing because a dependency is not released yet are retried
+package teardown
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+)
+
+//Kind kind of a resource of a graph
+type Kind string
+
+//Kinds of resources, in deletion order
+const (
+	KindPublicIP         Kind = "public-ip"
+	KindAttachment       Kind = "attachment"
+	KindServer           Kind = "server"
+	KindNetworkInterface Kind = "network-interface"
+	KindVolume           Kind = "volume"
+	KindSecurityGroup    Kind = "security-group"
+	KindSubnet           Kind = "subnet"
+	KindNetwork          Kind = "network"
+)
+
+var kinds = []Kind{
+	KindPublicIP, KindAttachment, KindServer, KindNetworkInterface, KindVolume, KindSecurityGroup, KindSubnet, KindNetwork,
+}
+
+func (k Kind) rank() int {
+	for i, kind := range kinds {
+		if kind == k {
+			return i
+		}
+	}
+	return len(kinds)
+}
+
+//defaultSecurityGroupName name of the security groups created with networks, they are deleted with their network
+const defaultSecurityGroupName = "default"
+
+//Resource a resource of a graph
+type Resource struct {
+	Kind Kind
+	ID   string
+	//Parent ID of the network of a sub network, ID of the volume of an attachment
+	Parent string
+	//Server ID of the server of an attachment
+	Server string
+	//Associated true if the public IP is associated with a network interface
+	Associated bool
+}
+
+func (r Resource) key() string {
+	return string(r.Kind) + "/" + r.ID
+}
+
+//String returns a one line description of r
+func (r Resource) String() string {
+	if r.Kind == "" {
+		return "resource " + r.ID
+	}
+	return fmt.Sprintf("%s %s", r.Kind, r.ID)
+}
+
+//Failure a resource that could not be deleted
+type Failure struct {
+	Resource Resource
+	Err      error
+}
+
+//Report result of a teardown
+type Report struct {
+	//Deleted resources deleted, in deletion order
+	Deleted []Resource
+	//Failed resources that could not be deleted or discovered, the resources depending on a failed resource are not deleted
+	Failed []Failure
+}
+
+//Err returns an error describing the failures of r, nil if all the resources have been deleted
+func (r *Report) Err() error {
+	if len(r.Failed) == 0 {
+		return nil
+	}
+	msgs := make([]string, len(r.Failed))
+	for i, f := range r.Failed {
+		msgs[i] = fmt.Sprintf("%s: %v", f.Resource, f.Err)
+	}
+	return fmt.Errorf("error deleting %d resources: %s", len(r.Failed), strings.Join(msgs, "; "))
+}
+
+//Options options of a teardown
+type Options struct {
+	//Attempts number of passes over the graph without any deletion before giving up
+	Attempts int
+	//Delay delay between two passes
+	Delay time.Duration
+}
+
+//DefaultOptions options used by Delete
+var DefaultOptions = Options{Attempts: 6, Delay: 10 * time.Second}
+
+//graph resources to delete and the resources depending on them
+type graph struct {
+	prov       api.Provider
+	nodes      map[string]Resource
+	dependents map[string][]string
+	report     *Report
+}
+
+func (g *graph) add(r Resource, dependency *Resource) {
+	k := r.key()
+	if _, ok := g.nodes[k]; !ok {
+		g.nodes[k] = r
+	}
+	if dependency != nil {
+		dk := dependency.key()
+		g.dependents[dk] = append(g.dependents[dk], k)
+	}
+}
+
+func (g *graph) fail(r Resource, err error) {
+	g.report.Failed = append(g.report.Failed, Failure{Resource: r, Err: err})
+}
+
+func isNotFound(err error) bool {
+	return api.ErrorKind(err) == api.ErrNotFound
+}
+
+//isOtherKind returns true if err is returned by a Get function because the identifier is not the one of a resource of its kind
+//Providers checking the format of the identifiers, like aws, return api.ErrInvalidArgument instead of api.ErrNotFound
+func isOtherKind(err error) bool {
+	kind := api.ErrorKind(err)
+	return kind == api.ErrNotFound || kind == api.ErrInvalidArgument
+}
+
+//resolve returns the resource identified by id, it is looked up in all the managers
+func (g *graph) resolve(ctx context.Context, id string) (*Resource, error) {
+	if _, err := g.prov.GetNetworkManager().GetNetworkWithContext(ctx, id); err == nil {
+		return &Resource{Kind: KindNetwork, ID: id}, nil
+	} else if !isOtherKind(err) {
+		return nil, err
+	}
+	if srv, err := g.prov.GetServerManager().GetWithContext(ctx, id); err == nil && srv.State != api.ServerDeleted {
+		return &Resource{Kind: KindServer, ID: id}, nil
+	} else if err != nil && !isOtherKind(err) {
+		return nil, err
+	}
+	if _, err := g.prov.GetVolumeManager().GetWithContext(ctx, id); err == nil {
+		return &Resource{Kind: KindVolume, ID: id}, nil
+	} else if !isOtherKind(err) {
+		return nil, err
+	}
+	if _, err := g.prov.GetSecurityGroupManager().GetWithContext(ctx, id); err == nil {
+		return &Resource{Kind: KindSecurityGroup, ID: id}, nil
+	} else if !isOtherKind(err) {
+		return nil, err
+	}
+	if ip, err := g.prov.GetPublicIPAddressManager().GetWithContext(ctx, id); err == nil {
+		return &Resource{Kind: KindPublicIP, ID: id, Associated: ip.NetworkInterfaceID != ""}, nil
+	} else if !isOtherKind(err) {
+		return nil, err
+	}
+	if _, err := g.prov.GetNetworkInterfaceManager().GetWithContext(ctx, id); err == nil {
+		return &Resource{Kind: KindNetworkInterface, ID: id}, nil
+	} else if !isOtherKind(err) {
+		return nil, err
+	}
+	networks, err := g.prov.GetNetworkManager().ListNetworksWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range networks {
+		subnets, err := g.prov.GetNetworkManager().ListSubnetsWithContext(ctx, n.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, sn := range subnets {
+			if sn.ID == id {
+				return &Resource{Kind: KindSubnet, ID: id, Parent: n.ID}, nil
+			}
+		}
+	}
+	return nil, api.WithKind(fmt.Errorf("resource %s not found", id), api.ErrNotFound)
+}
+
+//addNetworkInterfaces adds the network interfaces of nics to the graph as dependents of r
+//The network interfaces of a server are deleted with the server, the server is added instead
+func (g *graph) addNetworkInterfaces(nics []api.NetworkInterface, r *Resource) {
+	for _, ni := range nics {
+		if ni.ServerID != "" {
+			g.add(Resource{Kind: KindServer, ID: ni.ServerID}, r)
+		} else {
+			g.add(Resource{Kind: KindNetworkInterface, ID: ni.ID}, r)
+		}
+	}
+}
+
+func (g *graph) addPublicIPs(ips []api.PublicIP, r *Resource) {
+	for _, ip := range ips {
+		g.add(Resource{Kind: KindPublicIP, ID: ip.ID, Associated: ip.NetworkInterfaceID != ""}, r)
+	}
+}
+
+func (g *graph) addAttachments(attachments []api.VolumeAttachment, r *Resource) {
+	for _, a := range attachments {
+		g.add(Resource{Kind: KindAttachment, ID: a.ID, Parent: a.VolumeID, Server: a.ServerID}, r)
+	}
+}
+
+//expand adds the resources depending on r to the graph
+func (g *graph) expand(ctx context.Context, r *Resource) error {
+	switch r.Kind {
+	case KindNetwork:
+		subnets, err := g.prov.GetNetworkManager().ListSubnetsWithContext(ctx, r.ID)
+		if err != nil {
+			return err
+		}
+		for _, sn := range subnets {
+			g.add(Resource{Kind: KindSubnet, ID: sn.ID, Parent: r.ID}, r)
+		}
+		groups, err := g.prov.GetSecurityGroupManager().ListWithContext(ctx)
+		if err != nil {
+			return err
+		}
+		for _, sg := range groups {
+			if sg.NetworkID == r.ID && sg.Name != defaultSecurityGroupName {
+				g.add(Resource{Kind: KindSecurityGroup, ID: sg.ID}, r)
+			}
+		}
+		nics, err := g.prov.GetNetworkInterfaceManager().ListWithContext(ctx, &api.ListNetworkInterfacesOptions{NetworkID: &r.ID})
+		if err != nil {
+			return err
+		}
+		g.addNetworkInterfaces(nics, r)
+	case KindSubnet:
+		nics, err := g.prov.GetNetworkInterfaceManager().ListWithContext(ctx, &api.ListNetworkInterfacesOptions{SubnetID: &r.ID})
+		if err != nil {
+			return err
+		}
+		g.addNetworkInterfaces(nics, r)
+	case KindSecurityGroup:
+		nics, err := g.prov.GetNetworkInterfaceManager().ListWithContext(ctx, &api.ListNetworkInterfacesOptions{SecurityGroupID: &r.ID})
+		if err != nil {
+			return err
+		}
+		g.addNetworkInterfaces(nics, r)
+	case KindServer:
+		attachments, err := g.prov.GetVolumeManager().ListAttachmentsWithContext(ctx, &api.ListAttachmentsOptions{ServerID: &r.ID})
+		if err != nil {
+			return err
+		}
+		g.addAttachments(attachments, r)
+		ips, err := g.prov.GetPublicIPAddressManager().ListWithContext(ctx, &api.ListPublicIPsOptions{ServerID: &r.ID})
+		if err != nil {
+			return err
+		}
+		g.addPublicIPs(ips, r)
+	case KindVolume:
+		attachments, err := g.prov.GetVolumeManager().ListAttachmentsWithContext(ctx, &api.ListAttachmentsOptions{VolumeID: &r.ID})
+		if err != nil {
+			return err
+		}
+		g.addAttachments(attachments, r)
+	case KindNetworkInterface:
+		ips, err := g.prov.GetPublicIPAddressManager().ListWithContext(ctx, nil)
+		if err != nil {
+			return err
+		}
+		for _, ip := range ips {
+			if ip.NetworkInterfaceID == r.ID {
+				g.add(Resource{Kind: KindPublicIP, ID: ip.ID, Associated: true}, r)
+			}
+		}
+	}
+	return nil
+}
+
+//discover builds the graph of the resources identified by ids and of the resources depending on them
+func (g *graph) discover(ctx context.Context, ids []string) {
+	var queue []string
+	for _, id := range ids {
+		r, err := g.resolve(ctx, id)
+		if err != nil {
+			g.fail(Resource{ID: id}, err)
+			continue
+		}
+		g.add(*r, nil)
+		queue = append(queue, r.key())
+	}
+	expanded := map[string]bool{}
+	for len(queue) > 0 {
+		k := queue[0]
+		queue = queue[1:]
+		if expanded[k] {
+			continue
+		}
+		expanded[k] = true
+		r := g.nodes[k]
+		err := g.expand(ctx, &r)
+		if err != nil {
+			g.fail(r, api.NewErrorStack(err, "error discovering dependents of", r))
+		}
+		queue = append(queue, g.dependents[k]...)
+	}
+}
+
+//remove deletes r, a resource already deleted is not an error
+func (g *graph) remove(ctx context.Context, r Resource) error {
+	var err error
+	switch r.Kind {
+	case KindPublicIP:
+		mgr := g.prov.GetPublicIPAddressManager()
+		if r.Associated {
+			err = mgr.DissociateWithContext(ctx, r.ID)
+			if err != nil && !isNotFound(err) {
+				return err
+			}
+		}
+		err = mgr.DeleteWithContext(ctx, r.ID)
+	case KindAttachment:
+		err = g.prov.GetVolumeManager().DetachWithContext(ctx, api.DetachVolumeOptions{VolumeID: r.Parent, ServerID: r.Server})
+	case KindServer:
+		err = g.prov.GetServerManager().DeleteWithContext(ctx, r.ID)
+	case KindNetworkInterface:
+		err = g.prov.GetNetworkInterfaceManager().DeleteWithContext(ctx, r.ID)
+	case KindVolume:
+		err = g.prov.GetVolumeManager().DeleteWithContext(ctx, r.ID)
+	case KindSecurityGroup:
+		err = g.prov.GetSecurityGroupManager().DeleteWithContext(ctx, r.ID)
+	case KindSubnet:
+		err = g.prov.GetNetworkManager().DeleteSubnetWithContext(ctx, r.Parent, r.ID)
+	case KindNetwork:
+		err = g.prov.GetNetworkManager().DeleteNetworkWithContext(ctx, r.ID)
+	default:
+		return fmt.Errorf("unknown resource kind %s", r.Kind)
+	}
+	if err != nil && !isNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+//sorted returns the keys of the nodes of the graph in deletion order
+func (g *graph) sorted() []string {
+	keys := make([]string, 0, len(g.nodes))
+	for k := range g.nodes {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := g.nodes[keys[i]], g.nodes[keys[j]]
+		if a.Kind != b.Kind {
+			return a.Kind.rank() < b.Kind.rank()
+		}
+		return a.ID < b.ID
+	})
+	return keys
+}
+
+func wait(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+//delete deletes the nodes of the graph once the resources depending on them are deleted
+//A pass deleting nothing counts as a failed attempt, the resources left after options.Attempts failed attempts are reported as failed
+func (g *graph) delete(ctx context.Context, options Options) {
+	deleted := map[string]bool{}
+	errs := map[string]error{}
+	blocked := func(k string) string {
+		for _, d := range g.dependents[k] {
+			if !deleted[d] {
+				return d
+			}
+		}
+		return ""
+	}
+	pending := g.sorted()
+	for attempt := 0; len(pending) > 0 && attempt < options.Attempts; {
+		var left []string
+		for _, k := range pending {
+			if blocked(k) != "" {
+				left = append(left, k)
+				continue
+			}
+			err := g.remove(ctx, g.nodes[k])
+			if err != nil {
+				errs[k] = err
+				left = append(left, k)
+				continue
+			}
+			deleted[k] = true
+			g.report.Deleted = append(g.report.Deleted, g.nodes[k])
+		}
+		if len(left) == len(pending) {
+			attempt++
+		}
+		pending = left
+		if len(pending) == 0 || attempt == options.Attempts {
+			break
+		}
+		if err := wait(ctx, options.Delay); err != nil {
+			for _, k := range pending {
+				errs[k] = err
+			}
+			break
+		}
+	}
+	for _, k := range pending {
+		err, ok := errs[k]
+		if !ok {
+			err = fmt.Errorf("dependent %s not deleted", g.nodes[blocked(k)])
+		}
+		g.fail(g.nodes[k], err)
+	}
+}
+
+//DeleteWithOptions deletes the resources identified by ids and the resources depending on them:
+//the sub networks, security groups, network interfaces and servers of a network, the network interfaces and servers of a sub
+//network or of a security group, the volume attachments and public IPs of a server and the attachments of a volume
+//Nothing is deleted if the graph cannot be discovered completely, the resources a failed resource depends on are not deleted
+//The routers created by the openstack provider with the networks are deleted with the networks
+func DeleteWithOptions(ctx context.Context, prov api.Provider, options Options, ids ...string) *Report {
+	g := &graph{
+		prov:       prov,
+		nodes:      map[string]Resource{},
+		dependents: map[string][]string{},
+		report:     &Report{},
+	}
+	g.discover(ctx, ids)
+	if len(g.report.Failed) > 0 {
+		return g.report
+	}
+	g.delete(ctx, options)
+	return g.report
+}
+
+//DeleteWithContext deletes the resources identified by ids and the resources depending on them using DefaultOptions
+func DeleteWithContext(ctx context.Context, prov api.Provider, ids ...string) (*Report, error) {
+	r := DeleteWithOptions(ctx, prov, DefaultOptions, ids...)
+	return r, r.Err()
+}
+
+//Delete deletes the resources identified by ids and the resources depending on them using DefaultOptions
+func Delete(prov api.Provider, ids ...string) (*Report, error) {
+	return DeleteWithContext(context.Background(), prov, ids...)
+}
@@ -0,0 +1,165 @@
+package teardown_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/SebastienDorgan/anyclouds/providers/aws"
+	"github.com/SebastienDorgan/anyclouds/providers/aws/fake"
+	"github.com/SebastienDorgan/anyclouds/providers/memory"
+	"github.com/SebastienDorgan/anyclouds/sshutils"
+	"github.com/SebastienDorgan/anyclouds/stack"
+	"github.com/SebastienDorgan/anyclouds/teardown"
+	"github.com/SebastienDorgan/anyclouds/tests"
+	"github.com/stretchr/testify/assert"
+)
+
+const spec = `
+name: web
+networks:
+  - name: net
+    cidr: 10.0.0.0/16
+subnets:
+  - name: front
+    network: net
+    cidr: 10.0.1.0/24
+securityGroups:
+  - name: web
+    network: net
+servers:
+  - name: www
+    template: tpl-small
+    image: img-ubuntu-1804
+    securityGroup: web
+    subnets: [front]
+volumes:
+  - name: data
+    size: 10
+  - name: backup
+    size: 10
+attachments:
+  - volume: data
+    server: www
+publicIPs:
+  - name: www-ip
+    server: www
+`
+
+func create(t *testing.T) (api.Provider, map[stack.Kind]string) {
+	prov := &memory.Provider{}
+	assert.NoError(t, prov.Init(nil, ""))
+	s, err := stack.Load(strings.NewReader(spec), "yaml")
+	assert.NoError(t, err)
+	p, err := stack.NewPlan(prov, s)
+	assert.NoError(t, err)
+	resources, err := stack.Apply(prov, p)
+	assert.NoError(t, err)
+	ids := map[stack.Kind]string{}
+	for _, r := range resources {
+		if r.Name != "backup" {
+			ids[r.Kind] = r.ID
+		}
+	}
+	return prov, ids
+}
+
+func kinds(r *teardown.Report) []string {
+	res := []string{}
+	for _, d := range r.Deleted {
+		res = append(res, string(d.Kind))
+	}
+	return res
+}
+
+func TestDelete(t *testing.T) {
+	prov, ids := create(t)
+	options := teardown.Options{Attempts: 2}
+	ctx := context.Background()
+
+	report := teardown.DeleteWithOptions(ctx, prov, options, ids[stack.KindNetwork])
+	assert.NoError(t, report.Err())
+	assert.Equal(t, []string{"public-ip", "attachment", "server", "security-group", "subnet", "network"}, kinds(report))
+	_, err := prov.GetNetworkManager().GetNetwork(ids[stack.KindNetwork])
+	assert.True(t, errors.Is(err, api.ErrNotFound))
+	volumes, err := prov.GetVolumeManager().List()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(volumes))
+
+	report = teardown.DeleteWithOptions(ctx, prov, options, ids[stack.KindVolume], "unknown")
+	assert.Error(t, report.Err())
+	assert.Empty(t, report.Deleted)
+	assert.Equal(t, 1, len(report.Failed))
+	assert.True(t, errors.Is(report.Failed[0].Err, api.ErrNotFound))
+
+	report, err = teardown.Delete(prov, ids[stack.KindVolume])
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"volume"}, kinds(report))
+	volumes, err = prov.GetVolumeManager().List()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(volumes))
+}
+
+func TestDeleteServer(t *testing.T) {
+	prov, ids := create(t)
+	report, err := teardown.Delete(prov, ids[stack.KindServer])
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"public-ip", "attachment", "server"}, kinds(report))
+	ips, err := prov.GetPublicIPAddressManager().List(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, ips)
+	_, err = prov.GetVolumeManager().Get(ids[stack.KindVolume])
+	assert.NoError(t, err)
+	_, err = prov.GetNetworkManager().GetNetwork(ids[stack.KindNetwork])
+	assert.NoError(t, err)
+}
+
+//TestDeleteAWS checks that the identifiers rejected as malformed by the managers of another kind of resource are resolved
+func TestDeleteAWS(t *testing.T) {
+	srv := fake.NewServer()
+	defer srv.Close()
+	prov := &aws.Provider{}
+	assert.NoError(t, prov.Init(strings.NewReader(srv.Config()), "json"))
+
+	net, err := prov.GetNetworkManager().CreateNetwork(api.CreateNetworkOptions{CIDR: "10.0.0.0/16", Name: "net"})
+	assert.NoError(t, err)
+	subnet, err := prov.GetNetworkManager().CreateSubnet(api.CreateSubnetOptions{
+		NetworkID: net.ID,
+		CIDR:      "10.0.1.0/24",
+		Name:      "front",
+		IPVersion: api.IPVersion4,
+	})
+	assert.NoError(t, err)
+	tpl, err := prov.GetTemplateManager().Get("t3.micro")
+	assert.NoError(t, err)
+	img, err := tests.FindImage(prov.GetImageManager(), tpl)
+	assert.NoError(t, err)
+	kp, err := sshutils.CreateKeyPair(2048)
+	assert.NoError(t, err)
+	server, err := prov.GetServerManager().Create(api.CreateServerOptions{
+		Name:       "www",
+		TemplateID: tpl.ID,
+		ImageID:    img.ID,
+		Subnets:    []api.Subnet{*subnet},
+		KeyPair:    *kp,
+	})
+	assert.NoError(t, err)
+	volume, err := prov.GetVolumeManager().Create(api.CreateVolumeOptions{Name: "data", Size: 10})
+	assert.NoError(t, err)
+	_, err = prov.GetVolumeManager().Attach(api.AttachVolumeOptions{VolumeID: volume.ID, ServerID: server.ID, DevicePath: "/dev/sdf"})
+	assert.NoError(t, err)
+
+	report, err := teardown.DeleteWithContext(context.Background(), prov, volume.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"attachment", "volume"}, kinds(report))
+
+	report = teardown.DeleteWithOptions(context.Background(), prov, teardown.Options{Attempts: 2}, net.ID)
+	assert.NoError(t, report.Err())
+	deleted := kinds(report)
+	assert.Contains(t, deleted, "server")
+	assert.Equal(t, []string{"subnet", "network"}, deleted[len(deleted)-2:])
+	_, err = prov.GetNetworkManager().GetNetwork(net.ID)
+	assert.True(t, errors.Is(err, api.ErrNotFound))
+}
@@ -0,0 +1,113 @@
+package tests
+
+import (
+	"errors"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/stretchr/testify/suite"
+)
+
+//DNSManagerTestSuite test suite of api.DNSManager
+type DNSManagerTestSuite struct {
+	suite.Suite
+	Prov api.Provider
+}
+
+//TestZones canonical test of DNS zones
+func (s *DNSManagerTestSuite) TestZones() {
+	mgr := s.Prov.GetDNSManager()
+	_, err := mgr.CreateZone(api.CreateZoneOptions{Name: "invalid zone"})
+	s.True(errors.Is(err, api.ErrInvalidArgument))
+
+	z, err := mgr.CreateZone(api.CreateZoneOptions{Name: "zones.anyclouds.test."})
+	s.Require().NoError(err)
+	s.NotEmpty(z.ID)
+	s.Equal("zones.anyclouds.test", z.Name)
+	s.NotEmpty(z.NameServers)
+
+	got, err := mgr.GetZone(z.ID)
+	s.NoError(err)
+	s.Equal(z, got)
+	zones, err := mgr.ListZones()
+	s.NoError(err)
+	s.Contains(zones, *z)
+
+	records, err := mgr.ListRecords(z.ID)
+	s.NoError(err)
+	s.Empty(records)
+
+	s.NoError(mgr.DeleteZone(z.ID))
+	_, err = mgr.GetZone(z.ID)
+	s.True(errors.Is(err, api.ErrNotFound))
+}
+
+//TestRecords canonical test of DNS records
+func (s *DNSManagerTestSuite) TestRecords() {
+	mgr := s.Prov.GetDNSManager()
+	z, err := mgr.CreateZone(api.CreateZoneOptions{Name: "records.anyclouds.test"})
+	s.Require().NoError(err)
+	defer func() {
+		s.NoError(mgr.DeleteZone(z.ID))
+	}()
+
+	www, err := mgr.UpsertRecord(api.UpsertRecordOptions{
+		ZoneID: z.ID,
+		Name:   "www",
+		Type:   api.RecordA,
+		Values: []string{"192.0.2.10"},
+	})
+	s.Require().NoError(err)
+	s.Equal(api.Record{ZoneID: z.ID, Name: "www", Type: api.RecordA, TTL: 300, Values: []string{"192.0.2.10"}}, *www)
+
+	//upsert replaces the record set
+	www, err = mgr.UpsertRecord(api.UpsertRecordOptions{
+		ZoneID: z.ID,
+		Name:   "WWW",
+		Type:   api.RecordA,
+		TTL:    60,
+		Values: []string{"192.0.2.10", "192.0.2.11"},
+	})
+	s.Require().NoError(err)
+	s.Equal(api.Record{ZoneID: z.ID, Name: "www", Type: api.RecordA, TTL: 60, Values: []string{"192.0.2.10", "192.0.2.11"}}, *www)
+
+	v6, err := mgr.UpsertRecord(api.UpsertRecordOptions{ZoneID: z.ID, Name: "www", Type: api.RecordAAAA, Values: []string{"2001:db8::10"}})
+	s.Require().NoError(err)
+	txt, err := mgr.UpsertRecord(api.UpsertRecordOptions{ZoneID: z.ID, Name: api.ZoneApex, Type: api.RecordTXT, Values: []string{"v=spf1 -all"}})
+	s.Require().NoError(err)
+	s.Equal(api.ZoneApex, txt.Name)
+	alias, err := mgr.UpsertRecord(api.UpsertRecordOptions{ZoneID: z.ID, Name: "alias", Type: api.RecordCNAME, Values: []string{"www.records.anyclouds.test"}})
+	s.Require().NoError(err)
+
+	_, err = mgr.UpsertRecord(api.UpsertRecordOptions{ZoneID: z.ID, Name: "www", Type: api.RecordA, Values: []string{"2001:db8::10"}})
+	s.True(errors.Is(err, api.ErrInvalidArgument))
+	_, err = mgr.UpsertRecord(api.UpsertRecordOptions{ZoneID: z.ID, Name: "www", Type: api.RecordCNAME, Values: []string{"alias.records.anyclouds.test"}})
+	s.Error(err)
+
+	records, err := mgr.ListRecords(z.ID)
+	s.NoError(err)
+	s.Equal([]api.Record{*txt, *alias, *www, *v6}, records)
+
+	s.NoError(mgr.DeleteRecord(api.DeleteRecordOptions{ZoneID: z.ID, Name: "www", Type: api.RecordA}))
+	records, err = mgr.ListRecords(z.ID)
+	s.NoError(err)
+	s.Equal([]api.Record{*txt, *alias, *v6}, records)
+}
+
+//TestPublicIPRecord checks that an A record can point at a public IP
+func (s *DNSManagerTestSuite) TestPublicIPRecord() {
+	mgr := s.Prov.GetDNSManager()
+	z, err := mgr.CreateZone(api.CreateZoneOptions{Name: "ip.anyclouds.test"})
+	s.Require().NoError(err)
+	defer func() {
+		s.NoError(mgr.DeleteZone(z.ID))
+	}()
+	ip, err := s.Prov.GetPublicIPAddressManager().Create(api.CreatePublicIPOptions{Name: "dns_ip"})
+	s.Require().NoError(err)
+	defer func() {
+		s.NoError(s.Prov.GetPublicIPAddressManager().Delete(ip.ID))
+	}()
+
+	r, err := mgr.UpsertRecord(api.UpsertRecordOptions{ZoneID: z.ID, Name: "server", Type: api.RecordA, PublicIPID: ip.ID})
+	s.Require().NoError(err)
+	s.Equal([]string{ip.Address}, r.Values)
+}
@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
 
@@ -29,3 +30,35 @@ func (s *ImageManagerTestSuite) TestImageManager() {
 	}
 	fmt.Println(len(images))
 }
+
+//TestFind Canonical test for ImageManager Find implementation
+func (s *ImageManagerTestSuite) TestFind() {
+	images, err := s.Mgr.List()
+	assert.NoError(s.T(), err)
+	query := api.ImageQuery{Distribution: "Ubuntu", Version: "18.04", Arch: api.ArchAmd64, Latest: true}
+	img, err := s.Mgr.Find(query)
+	assert.NoError(s.T(), err)
+	if img == nil {
+		return
+	}
+	assert.Equal(s.T(), api.OSLinux, img.OSFamily)
+	assert.Equal(s.T(), "ubuntu", img.Distribution)
+	assert.Equal(s.T(), "18.04", img.Version)
+	assert.Equal(s.T(), api.ArchAmd64, img.Arch)
+	assert.NotEmpty(s.T(), img.DefaultUser)
+	for _, o := range images {
+		if query.Match(&o) {
+			assert.False(s.T(), o.CreatedAt.After(img.CreatedAt))
+		}
+	}
+
+	query.Version = "18"
+	prefixed, err := s.Mgr.Find(query)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), img, prefixed)
+
+	_, err = s.Mgr.Find(api.ImageQuery{Distribution: "ubuntu"})
+	assert.True(s.T(), errors.Is(err, api.ErrInvalidArgument))
+	_, err = s.Mgr.Find(api.ImageQuery{Distribution: "ubuntu", Version: "1.0"})
+	assert.True(s.T(), errors.Is(err, api.ErrNotFound))
+}
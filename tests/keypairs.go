@@ -0,0 +1,45 @@
+package tests
+
+import (
+	"errors"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/SebastienDorgan/anyclouds/sshutils"
+	"github.com/stretchr/testify/suite"
+)
+
+//KeyPairManagerTestSuite test suite of api.KeyPairManager
+type KeyPairManagerTestSuite struct {
+	suite.Suite
+	Prov api.Provider
+}
+
+//TestKeyPairs canonical test of KeyPairManager implementations
+func (s *KeyPairManagerTestSuite) TestKeyPairs() {
+	mgr := s.Prov.GetKeyPairManager()
+	kp, err := sshutils.CreateKeyPair(2048)
+	s.NoError(err)
+	fingerprint, err := sshutils.Fingerprint(kp.PublicKey)
+	s.NoError(err)
+
+	imported, err := mgr.Import("test_key", kp.PublicKey)
+	s.NoError(err)
+	s.Equal("test_key", imported.Name)
+	s.Equal(fingerprint, imported.Fingerprint)
+
+	_, err = mgr.Import("test_key", kp.PublicKey)
+	s.True(errors.Is(err, api.ErrAlreadyExists))
+
+	got, err := mgr.Get("test_key")
+	s.NoError(err)
+	s.Equal(*imported, *got)
+
+	keys, err := mgr.List()
+	s.NoError(err)
+	s.Contains(keys, *imported)
+
+	err = mgr.Delete("test_key")
+	s.NoError(err)
+	_, err = mgr.Get("test_key")
+	s.True(errors.Is(err, api.ErrNotFound))
+}
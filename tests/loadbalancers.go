@@ -0,0 +1,219 @@
+package tests
+
+import (
+	"errors"
+	"time"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/SebastienDorgan/anyclouds/sshutils"
+	"github.com/stretchr/testify/suite"
+)
+
+//LoadBalancerManagerTestSuite test suite of api.LoadBalancerManager
+type LoadBalancerManagerTestSuite struct {
+	suite.Suite
+	Prov api.Provider
+	//SkipL7 disables L7 load balancer tests, to be used by providers that cannot create L7 load balancers in a single subnet
+	SkipL7 bool
+	//SecondZone availability zone of a second subnet the L7 load balancers are attached to, to be used by providers whose L7 load
+	//balancers must span two availability zones
+	SecondZone string
+
+	network *api.Network
+	subnet  *api.Subnet
+	server  *api.Server
+}
+
+//SetupTest creates the server used as backend of the load balancers
+func (s *LoadBalancerManagerTestSuite) SetupTest() {
+	helper := &ServerManagerTestSuite{Prov: s.Prov}
+	var err error
+	s.network, s.subnet, err = helper.CreateNetwork(s.Prov.GetNetworkManager())
+	s.Require().NoError(err)
+	tpl, err := helper.SelectTemplate(s.Prov.GetTemplateManager())
+	s.Require().NoError(err)
+	img, err := helper.FindImage(s.Prov.GetImageManager(), tpl)
+	s.Require().NoError(err)
+	kp, err := sshutils.CreateKeyPair(2048)
+	s.Require().NoError(err)
+	s.server, err = s.Prov.GetServerManager().Create(api.CreateServerOptions{
+		Name:       "backend",
+		TemplateID: tpl.ID,
+		ImageID:    img.ID,
+		Subnets:    []api.Subnet{*s.subnet},
+		KeyPair:    *kp,
+	})
+	s.Require().NoError(err)
+}
+
+//TearDownTest deletes the backend server and its network
+func (s *LoadBalancerManagerTestSuite) TearDownTest() {
+	s.NoError(s.Prov.GetServerManager().Delete(s.server.ID))
+	s.NoError(s.Prov.GetNetworkManager().DeleteSubnet(s.network.ID, s.subnet.ID))
+	s.NoError(s.Prov.GetNetworkManager().DeleteNetwork(s.network.ID))
+}
+
+func (s *LoadBalancerManagerTestSuite) checkLoadBalancer(options api.CreateLoadBalancerOptions) {
+	mgr := s.Prov.GetLoadBalancerManager()
+	lb, err := mgr.Create(options)
+	s.Require().NoError(err)
+	s.Equal(options.Name, lb.Name)
+	s.Equal(options.Type, lb.Type)
+	s.Equal(options.Public, lb.Public)
+	s.Equal(options.SubnetIDs, lb.SubnetIDs)
+	s.NotEmpty(lb.Address)
+	s.Require().Len(lb.Listeners, len(options.Listeners))
+	for i, l := range options.Listeners {
+		s.NotEmpty(lb.Listeners[i].ID)
+		s.Equal(l.Protocol, lb.Listeners[i].Protocol)
+		s.Equal(l.Port, lb.Listeners[i].Port)
+		s.Equal(l.Pool.Protocol, lb.Listeners[i].Pool.Protocol)
+		s.Equal(l.Pool.Port, lb.Listeners[i].Pool.Port)
+		s.Equal(l.Pool.ServerIDs, lb.Listeners[i].Pool.ServerIDs)
+		s.Require().NotNil(lb.Listeners[i].Pool.HealthCheck)
+		if l.Pool.HealthCheck != nil {
+			s.Equal(l.Pool.HealthCheck.Protocol, lb.Listeners[i].Pool.HealthCheck.Protocol)
+			s.Equal(l.Pool.HealthCheck.Path, lb.Listeners[i].Pool.HealthCheck.Path)
+		} else if l.Pool.Protocol == api.LoadBalancerHTTP {
+			s.Equal(api.LoadBalancerHTTP, lb.Listeners[i].Pool.HealthCheck.Protocol)
+		}
+	}
+
+	got, err := mgr.Get(lb.ID)
+	s.NoError(err)
+	s.Equal(lb, got)
+	lbs, err := mgr.List()
+	s.NoError(err)
+	s.Contains(lbs, *lb)
+
+	s.NoError(mgr.Delete(lb.ID))
+	_, err = mgr.Get(lb.ID)
+	s.True(errors.Is(err, api.ErrNotFound))
+}
+
+//TestL4LoadBalancer canonical test of L4 load balancers
+func (s *LoadBalancerManagerTestSuite) TestL4LoadBalancer() {
+	s.checkLoadBalancer(api.CreateLoadBalancerOptions{
+		Name:      "test_l4",
+		Type:      api.LoadBalancerL4,
+		SubnetIDs: []string{s.subnet.ID},
+		Listeners: []api.ListenerOptions{
+			{
+				Protocol: api.LoadBalancerTCP,
+				Port:     80,
+				Pool: api.BackendPool{
+					Protocol:  api.LoadBalancerTCP,
+					Port:      8080,
+					ServerIDs: []string{s.server.ID},
+					HealthCheck: &api.HealthCheck{
+						Protocol:           api.LoadBalancerHTTP,
+						Path:               "/health",
+						Interval:           10 * time.Second,
+						UnhealthyThreshold: 3,
+					},
+				},
+			},
+			{
+				Protocol: api.LoadBalancerTCP,
+				Port:     443,
+				Pool: api.BackendPool{
+					Protocol:  api.LoadBalancerTCP,
+					Port:      8443,
+					ServerIDs: []string{s.server.ID},
+				},
+			},
+		},
+	})
+}
+
+//TestL7LoadBalancer canonical test of L7 load balancers
+func (s *LoadBalancerManagerTestSuite) TestL7LoadBalancer() {
+	if s.SkipL7 {
+		s.T().Skip("L7 load balancers are not supported")
+	}
+	subnetIDs := []string{s.subnet.ID}
+	if s.SecondZone != "" {
+		mgr := s.Prov.GetNetworkManager()
+		sn, err := mgr.CreateSubnet(api.CreateSubnetOptions{
+			NetworkID:        s.network.ID,
+			Name:             "Test subnet 2",
+			CIDR:             "10.0.1.0/24",
+			IPVersion:        api.IPVersion4,
+			AvailabilityZone: s.SecondZone,
+		})
+		s.Require().NoError(err)
+		defer func() { s.NoError(mgr.DeleteSubnet(s.network.ID, sn.ID)) }()
+		subnetIDs = append(subnetIDs, sn.ID)
+	}
+	s.checkLoadBalancer(api.CreateLoadBalancerOptions{
+		Name:      "test_l7",
+		Type:      api.LoadBalancerL7,
+		Public:    true,
+		SubnetIDs: subnetIDs,
+		Listeners: []api.ListenerOptions{
+			{
+				Protocol: api.LoadBalancerHTTP,
+				Port:     80,
+				Pool: api.BackendPool{
+					Protocol:  api.LoadBalancerHTTP,
+					Port:      8080,
+					ServerIDs: []string{s.server.ID},
+					HealthCheck: &api.HealthCheck{
+						Protocol:           api.LoadBalancerHTTP,
+						Path:               "/health",
+						Interval:           10 * time.Second,
+						UnhealthyThreshold: 3,
+					},
+				},
+			},
+			{
+				Protocol: api.LoadBalancerHTTP,
+				Port:     8000,
+				Pool: api.BackendPool{
+					Protocol:  api.LoadBalancerHTTP,
+					Port:      8081,
+					ServerIDs: []string{s.server.ID},
+				},
+			},
+		},
+	})
+}
+
+//TestInvalidLoadBalancer checks that listener protocols must match the load balancer type and health checks their backend pools
+func (s *LoadBalancerManagerTestSuite) TestInvalidLoadBalancer() {
+	_, err := s.Prov.GetLoadBalancerManager().Create(api.CreateLoadBalancerOptions{
+		Name:      "invalid",
+		Type:      api.LoadBalancerL4,
+		SubnetIDs: []string{s.subnet.ID},
+		Listeners: []api.ListenerOptions{
+			{
+				Protocol: api.LoadBalancerHTTP,
+				Port:     80,
+				Pool: api.BackendPool{
+					Protocol:  api.LoadBalancerHTTP,
+					Port:      8080,
+					ServerIDs: []string{s.server.ID},
+				},
+			},
+		},
+	})
+	s.True(errors.Is(err, api.ErrInvalidArgument))
+	_, err = s.Prov.GetLoadBalancerManager().Create(api.CreateLoadBalancerOptions{
+		Name:      "invalid",
+		Type:      api.LoadBalancerL7,
+		SubnetIDs: []string{s.subnet.ID},
+		Listeners: []api.ListenerOptions{
+			{
+				Protocol: api.LoadBalancerHTTP,
+				Port:     80,
+				Pool: api.BackendPool{
+					Protocol:    api.LoadBalancerHTTP,
+					Port:        8080,
+					ServerIDs:   []string{s.server.ID},
+					HealthCheck: &api.HealthCheck{Protocol: api.LoadBalancerTCP},
+				},
+			},
+		},
+	})
+	s.True(errors.Is(err, api.ErrInvalidArgument))
+}
@@ -0,0 +1,55 @@
+package tests
+
+import (
+	"errors"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/stretchr/testify/suite"
+)
+
+//LocationManagerTestSuite test suite of api.LocationManager and api.Provider.WithRegion
+type LocationManagerTestSuite struct {
+	suite.Suite
+	Prov api.Provider
+}
+
+//TestLocations canonical test of LocationManager implementations
+func (s *LocationManagerTestSuite) TestLocations() {
+	mgr := s.Prov.GetLocationManager()
+	regions, err := mgr.ListRegions()
+	s.Require().NoError(err)
+	s.Require().NotEmpty(regions)
+	for _, r := range regions {
+		s.NotEmpty(r.ID)
+		s.NotEmpty(r.Name)
+		zones, err := mgr.ListZones(r.ID)
+		s.NoError(err)
+		for _, z := range zones {
+			s.NotEmpty(z.ID)
+			s.Equal(r.ID, z.Region)
+		}
+	}
+	_, err = mgr.ListZones("no-such-region")
+	s.True(errors.Is(err, api.ErrNotFound))
+}
+
+//TestWithRegion checks that providers bound to the first and the last listed regions can be obtained
+func (s *LocationManagerTestSuite) TestWithRegion() {
+	regions, err := s.Prov.GetLocationManager().ListRegions()
+	s.Require().NoError(err)
+	s.Require().NotEmpty(regions)
+	for _, r := range []api.Region{regions[0], regions[len(regions)-1]} {
+		prov, err := s.Prov.WithRegion(r.ID)
+		s.Require().NoError(err)
+		zones, err := prov.GetLocationManager().ListZones(r.ID)
+		s.NoError(err)
+		for _, z := range zones {
+			s.Equal(r.ID, z.Region)
+		}
+		_, err = prov.GetTemplateManager().List()
+		s.NoError(err)
+	}
+
+	_, err = s.Prov.WithRegion("no-such-region")
+	s.True(errors.Is(err, api.ErrNotFound))
+}
@@ -0,0 +1,181 @@
+package tests
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/stretchr/testify/suite"
+)
+
+//ObjectStorageManagerTestSuite test suite of api.ObjectStorageManager
+type ObjectStorageManagerTestSuite struct {
+	suite.Suite
+	Prov api.Provider
+}
+
+//streamReader hides the io.Seeker implementation of its reader so that objects are uploaded from a stream
+type streamReader struct {
+	io.Reader
+}
+
+func (s *ObjectStorageManagerTestSuite) read(bucketID, key string) (*api.Object, string) {
+	r, err := s.Prov.GetObjectStorageManager().GetObject(bucketID, key)
+	s.Require().NoError(err)
+	defer func() {
+		s.NoError(r.Close())
+	}()
+	content, err := ioutil.ReadAll(r)
+	s.Require().NoError(err)
+	return &r.Object, string(content)
+}
+
+//TestBuckets canonical test of buckets
+func (s *ObjectStorageManagerTestSuite) TestBuckets() {
+	mgr := s.Prov.GetObjectStorageManager()
+	_, err := mgr.CreateBucket(api.CreateBucketOptions{Name: "Invalid_Bucket"})
+	s.True(errors.Is(err, api.ErrInvalidArgument))
+
+	b, err := mgr.CreateBucket(api.CreateBucketOptions{Name: "anyclouds-buckets-test"})
+	s.Require().NoError(err)
+	s.NotEmpty(b.ID)
+	s.Equal("anyclouds-buckets-test", b.Name)
+	_, err = mgr.CreateBucket(api.CreateBucketOptions{Name: "anyclouds-buckets-test"})
+	s.True(errors.Is(err, api.ErrAlreadyExists))
+
+	got, err := mgr.GetBucket(b.ID)
+	s.NoError(err)
+	s.Equal(b, got)
+	buckets, err := mgr.ListBuckets()
+	s.NoError(err)
+	s.Contains(buckets, *b)
+
+	//buckets are deleted with their objects
+	_, err = mgr.PutObject(api.PutObjectOptions{BucketID: b.ID, Key: "remaining", Content: strings.NewReader("data")})
+	s.Require().NoError(err)
+	s.NoError(mgr.DeleteBucket(b.ID))
+	_, err = mgr.GetBucket(b.ID)
+	s.True(errors.Is(err, api.ErrNotFound))
+	s.True(errors.Is(mgr.DeleteBucket(b.ID), api.ErrNotFound))
+}
+
+//TestObjects canonical test of objects
+func (s *ObjectStorageManagerTestSuite) TestObjects() {
+	mgr := s.Prov.GetObjectStorageManager()
+	b, err := mgr.CreateBucket(api.CreateBucketOptions{Name: "anyclouds-objects-test"})
+	s.Require().NoError(err)
+	defer func() {
+		s.NoError(mgr.DeleteBucket(b.ID))
+	}()
+
+	_, err = mgr.PutObject(api.PutObjectOptions{BucketID: b.ID, Key: "", Content: strings.NewReader("data")})
+	s.True(errors.Is(err, api.ErrInvalidArgument))
+
+	hello, err := mgr.PutObject(api.PutObjectOptions{
+		BucketID:    b.ID,
+		Key:         "data/hello.txt",
+		Content:     streamReader{strings.NewReader("hello")},
+		ContentType: "text/plain",
+	})
+	s.Require().NoError(err)
+	s.Equal(b.ID, hello.BucketID)
+	s.Equal("data/hello.txt", hello.Key)
+	s.Equal(int64(5), hello.Size)
+	s.Equal("text/plain", hello.ContentType)
+	s.NotEmpty(hello.ETag)
+
+	big := bytes.Repeat([]byte("0123456789abcdef"), 1<<16)
+	blob, err := mgr.PutObject(api.PutObjectOptions{BucketID: b.ID, Key: "data/blob.bin", Content: streamReader{bytes.NewReader(big)}})
+	s.Require().NoError(err)
+	s.Equal(int64(len(big)), blob.Size)
+	s.Equal(api.DefaultContentType, blob.ContentType)
+	_, err = mgr.PutObject(api.PutObjectOptions{BucketID: b.ID, Key: "other", Content: strings.NewReader("")})
+	s.Require().NoError(err)
+
+	obj, content := s.read(b.ID, "data/hello.txt")
+	s.Equal("hello", content)
+	s.Equal(hello.Size, obj.Size)
+	s.Equal(hello.ContentType, obj.ContentType)
+	s.Equal(hello.ETag, obj.ETag)
+	_, content = s.read(b.ID, "data/blob.bin")
+	s.Equal(string(big), content)
+
+	//put replaces the object
+	replaced, err := mgr.PutObject(api.PutObjectOptions{BucketID: b.ID, Key: "data/hello.txt", Content: strings.NewReader("hello world"), ContentType: "text/plain"})
+	s.Require().NoError(err)
+	s.NotEqual(hello.ETag, replaced.ETag)
+	_, content = s.read(b.ID, "data/hello.txt")
+	s.Equal("hello world", content)
+
+	objects, err := mgr.ListObjects(api.ListObjectsOptions{BucketID: b.ID, Prefix: "data/"})
+	s.Require().NoError(err)
+	s.Require().Len(objects, 2)
+	s.Equal("data/blob.bin", objects[0].Key)
+	s.Equal(int64(len(big)), objects[0].Size)
+	s.Equal("data/hello.txt", objects[1].Key)
+	s.Equal(int64(11), objects[1].Size)
+	s.Equal(replaced.ETag, objects[1].ETag)
+	objects, err = mgr.ListObjects(api.ListObjectsOptions{BucketID: b.ID})
+	s.NoError(err)
+	s.Len(objects, 3)
+
+	s.NoError(mgr.DeleteObject(b.ID, "other"))
+	s.True(errors.Is(mgr.DeleteObject(b.ID, "other"), api.ErrNotFound))
+	_, err = mgr.GetObject(b.ID, "other")
+	s.True(errors.Is(err, api.ErrNotFound))
+	_, err = mgr.ListObjects(api.ListObjectsOptions{BucketID: "anyclouds-missing-bucket"})
+	s.True(errors.Is(err, api.ErrNotFound))
+}
+
+//TestPresignURL checks that pre-signed URLs grant downloads and uploads without credentials
+func (s *ObjectStorageManagerTestSuite) TestPresignURL() {
+	mgr := s.Prov.GetObjectStorageManager()
+	b, err := mgr.CreateBucket(api.CreateBucketOptions{Name: "anyclouds-presign-test"})
+	s.Require().NoError(err)
+	defer func() {
+		s.NoError(mgr.DeleteBucket(b.ID))
+	}()
+	_, err = mgr.PutObject(api.PutObjectOptions{BucketID: b.ID, Key: "bootstrap.sh", Content: strings.NewReader("#!/bin/sh\n")})
+	s.Require().NoError(err)
+
+	_, err = mgr.PresignURL(api.PresignURLOptions{BucketID: b.ID, Key: "bootstrap.sh", Method: http.MethodDelete, Expiry: time.Hour})
+	s.True(errors.Is(err, api.ErrInvalidArgument))
+
+	url, err := mgr.PresignURL(api.PresignURLOptions{BucketID: b.ID, Key: "bootstrap.sh", Method: http.MethodGet, Expiry: time.Hour})
+	s.Require().NoError(err)
+	resp, err := http.Get(url)
+	s.Require().NoError(err)
+	content, err := ioutil.ReadAll(resp.Body)
+	s.NoError(resp.Body.Close())
+	s.NoError(err)
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.Equal("#!/bin/sh\n", string(content))
+
+	url, err = mgr.PresignURL(api.PresignURLOptions{BucketID: b.ID, Key: "artifact.txt", Method: http.MethodPut, Expiry: time.Hour})
+	s.Require().NoError(err)
+	req, err := http.NewRequest(http.MethodPut, url, strings.NewReader("artifact"))
+	s.Require().NoError(err)
+	req.Header.Set("x-ms-blob-type", "BlockBlob")
+	resp, err = http.DefaultClient.Do(req)
+	s.Require().NoError(err)
+	s.NoError(resp.Body.Close())
+	s.True(resp.StatusCode >= 200 && resp.StatusCode < 300, resp.Status)
+	_, uploaded := s.read(b.ID, "artifact.txt")
+	s.Equal("artifact", uploaded)
+
+	//a GET URL does not grant uploads
+	url, err = mgr.PresignURL(api.PresignURLOptions{BucketID: b.ID, Key: "bootstrap.sh", Method: http.MethodGet, Expiry: time.Hour})
+	s.Require().NoError(err)
+	req, err = http.NewRequest(http.MethodPut, url, strings.NewReader("overwritten"))
+	s.Require().NoError(err)
+	req.Header.Set("x-ms-blob-type", "BlockBlob")
+	resp, err = http.DefaultClient.Do(req)
+	s.Require().NoError(err)
+	s.NoError(resp.Body.Close())
+	s.True(resp.StatusCode >= 400, resp.Status)
+}
@@ -0,0 +1,80 @@
+package tests
+
+import (
+	"errors"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/stretchr/testify/suite"
+)
+
+//QuotaManagerTestSuite test suite of api.QuotaManager
+type QuotaManagerTestSuite struct {
+	suite.Suite
+	Prov api.Provider
+}
+
+func (s *QuotaManagerTestSuite) checkLimit(name string, l api.Limit) {
+	if l.Used != api.UnknownLimit {
+		s.True(l.Used >= 0, "%s used %d", name, l.Used)
+	}
+	if l.Max != api.UnknownLimit && l.Used != api.UnknownLimit {
+		s.True(l.Used <= l.Max, "%s used %d max %d", name, l.Used, l.Max)
+		s.Equal(l.Max-l.Used, l.Remaining())
+	} else {
+		s.Equal(api.UnknownLimit, l.Remaining())
+	}
+}
+
+//TestQuotaManager canonical test of QuotaManager implementations
+func (s *QuotaManagerTestSuite) TestQuotaManager() {
+	mgr := s.Prov.GetQuotaManager()
+	limits, err := mgr.GetLimits()
+	s.Require().NoError(err)
+	s.checkLimit("instances", limits.Instances)
+	s.checkLimit("cores", limits.Cores)
+	s.checkLimit("RAM", limits.RAMSize)
+	s.checkLimit("volumes", limits.Volumes)
+	s.checkLimit("storage", limits.StorageSize)
+	s.checkLimit("public ips", limits.PublicIPs)
+	s.checkLimit("security groups", limits.SecurityGroups)
+}
+
+//TestSecurityGroupUsage checks that the creation of a security group is accounted for
+func (s *QuotaManagerTestSuite) TestSecurityGroupUsage() {
+	n, err := s.Prov.GetNetworkManager().CreateNetwork(api.CreateNetworkOptions{
+		CIDR: "10.0.0.0/16",
+	})
+	s.Require().NoError(err)
+	defer func() {
+		s.NoError(s.Prov.GetNetworkManager().DeleteNetwork(n.ID))
+	}()
+	mgr := s.Prov.GetQuotaManager()
+	limits, err := mgr.GetLimits()
+	s.Require().NoError(err)
+	sg, err := s.Prov.GetSecurityGroupManager().Create(api.SecurityGroupOptions{
+		Name:        "quota_sg",
+		Description: "quota test security group",
+		NetworkID:   n.ID,
+	})
+	s.Require().NoError(err)
+	after, err := mgr.GetLimits()
+	s.NoError(err)
+	s.NoError(s.Prov.GetSecurityGroupManager().Delete(sg.ID))
+	if after != nil && limits.SecurityGroups.Used != api.UnknownLimit {
+		s.Equal(limits.SecurityGroups.Used+1, after.SecurityGroups.Used)
+	}
+}
+
+//TestCheckServerLimits checks that CheckServerLimits rejects a request exceeding the remaining instances
+func (s *QuotaManagerTestSuite) TestCheckServerLimits() {
+	limits, err := s.Prov.GetQuotaManager().GetLimits()
+	s.Require().NoError(err)
+	tpl := &api.ServerTemplate{NumberOfCPUCore: 1, RAMSize: 1024}
+	s.NoError(api.CheckServerLimits(limits, tpl, 0))
+	r := limits.Instances.Remaining()
+	if r == api.UnknownLimit {
+		return
+	}
+	err = api.CheckServerLimits(limits, tpl, r+1)
+	s.True(errors.Is(err, api.ErrQuotaExceeded))
+}
@@ -3,23 +3,20 @@ package tests
 import (
 	"fmt"
 	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/SebastienDorgan/anyclouds/selector"
 	"github.com/SebastienDorgan/anyclouds/sshutils"
-	"github.com/SebastienDorgan/talgo"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 	"golang.org/x/crypto/ssh"
-	"strings"
 )
 
 //ServerManagerTestSuite test suite of api.ServerManager
 type ServerManagerTestSuite struct {
 	suite.Suite
 	Prov api.Provider
-}
-
-func weight(tpl api.ServerTemplate) float64 {
-	return float64(tpl.RAMSize)
+	//SkipSSH disables the ssh connection check, to be used by providers whose servers are not reachable
+	SkipSSH bool
 }
 
 func (s *ServerManagerTestSuite) CreateNetwork(mgr api.NetworkManager) (network *api.Network, subnet *api.Subnet, err error) {
@@ -74,49 +71,32 @@ func (s *ServerManagerTestSuite) CreateSecurityGroup(sgm api.SecurityGroupManage
 }
 
 func (s *ServerManagerTestSuite) SelectTemplate(tpm api.ServerTemplateManager) (*api.ServerTemplate, error) {
-	cpu := 4
-	ram := 15000
-	tplMgr := s.Prov.GetTemplateManager()
-	templates, err := tplMgr.List()
+	return selector.Best(tpm, selector.Criteria{
+		MinCPU: 4,
+		MinRAM: 15000,
+		Arch:   api.ArchAmd64,
+	}, selector.ClosestFit)
+}
+
+//FindImage returns the latest Ubuntu 18.04 image fitting tpl
+func FindImage(imm api.ImageManager, tpl *api.ServerTemplate) (*api.Image, error) {
+	img, err := imm.Find(api.ImageQuery{
+		Distribution: "ubuntu",
+		Version:      "18.04",
+		Arch:         tpl.Arch,
+		Latest:       true,
+	})
 	if err != nil {
 		return nil, err
 	}
-	indexes := talgo.FindAll(len(templates), func(i int) bool {
-		if templates[i].NumberOfCPUCore == cpu && templates[i].RAMSize >= ram && templates[i].GPUInfo == nil && templates[i].Arch == api.ArchAmd64 {
-			return true
-		}
-		return false
-	})
-	if len(indexes) == 0 {
-		return nil, errors.Errorf("No matching template find")
+	if img.MinDisk >= tpl.SystemDiskSize || img.MinRAM >= tpl.RAMSize {
+		return nil, errors.Errorf("image %s does not fit template %s", img.ID, tpl.Name)
 	}
-	selected := talgo.Select(len(indexes), func(i, j int) int {
-		if weight(templates[indexes[i]]) < weight(templates[indexes[j]]) {
-			return i
-		}
-		return j
-	})
-	return &templates[indexes[selected]], nil
-}
-
-func CheckImageName(img *api.Image, os, version string) bool {
-	name := strings.ToUpper(img.Name)
-	return strings.Contains(name, os) && strings.Contains(name, version)
+	return img, nil
 }
 
 func (s *ServerManagerTestSuite) FindImage(imm api.ImageManager, tpl *api.ServerTemplate) (*api.Image, error) {
-	images, err := imm.List()
-	if err != nil {
-		return nil, err
-	}
-	os := "UBUNTU"
-	version := "18.04"
-	for _, img := range images {
-		if img.MinDisk < tpl.SystemDiskSize && img.MinRAM < tpl.RAMSize && CheckImageName(&img, os, version) {
-			return &img, err
-		}
-	}
-	return nil, errors.Errorf("Enable to fin image fitting template %s", tpl.Name)
+	return FindImage(imm, tpl)
 }
 
 //TestServerManagerOnDemandInstance Canonical test for ServerTemplateManager implementation
@@ -185,6 +165,33 @@ func (s *ServerManagerTestSuite) TestServerManagerOnDemandInstance() {
 	assert.NoError(s.T(), err)
 }
 
+func (s *ServerManagerTestSuite) checkSSH(kp *sshutils.KeyPair, address string) {
+	auth, err := kp.AuthMethod()
+	assert.NoError(s.T(), err)
+	clt, err := sshutils.CreateClient(&sshutils.SSHConfig{
+		Addr: fmt.Sprintf("%s:%d", address, 22),
+		ClientConfig: &ssh.ClientConfig{
+			Config:          ssh.Config{},
+			User:            "ubuntu",
+			Auth:            []ssh.AuthMethod{auth},
+			Timeout:         0,
+			HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		},
+		Proxy: nil,
+	})
+	println(fmt.Sprintf("%s:%d", address, 22))
+	assert.NoError(s.T(), err)
+	if clt != nil {
+		session, err := clt.NewSession()
+		assert.NoError(s.T(), err)
+		resp, err := session.Output("hostname")
+		assert.NoError(s.T(), err)
+		assert.NotEmpty(s.T(), resp)
+		fmt.Println("hostname", string(resp))
+		_ = session.Close()
+	}
+}
+
 //TestServerManagerSpotInstance Canonical test for ServerTemplateManager implementation
 func (s *ServerManagerTestSuite) TestServerManagerSpotInstance() {
 	kp, err := sshutils.CreateKeyPair(4096)
@@ -238,29 +245,8 @@ func (s *ServerManagerTestSuite) TestServerManagerSpotInstance() {
 	})
 	assert.Equal(s.T(), server.Name, "test_server")
 	assert.Equal(s.T(), server.ImageID, img.ID)
-
-	auth, err := kp.AuthMethod()
-	clt, err := sshutils.CreateClient(&sshutils.SSHConfig{
-		Addr: fmt.Sprintf("%s:%d", nis[0].PublicIPAddress, 22),
-		ClientConfig: &ssh.ClientConfig{
-			Config:          ssh.Config{},
-			User:            "ubuntu",
-			Auth:            []ssh.AuthMethod{auth},
-			Timeout:         0,
-			HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-		},
-		Proxy: nil,
-	})
-	println(fmt.Sprintf("%s:%d", nis[0].PublicIPAddress, 22))
-	assert.NoError(s.T(), err)
-	if clt != nil {
-		session, err := clt.NewSession()
-		assert.NoError(s.T(), err)
-		resp, err := session.Output("hostname")
-		assert.NoError(s.T(), err)
-		assert.NotEmpty(s.T(), resp)
-		fmt.Println("hostname", string(resp))
-		_ = session.Close()
+	if !s.SkipSSH {
+		s.checkSSH(kp, nis[0].PublicIPAddress)
 	}
 
 	err = srvMgr.Delete(server.ID)
@@ -274,3 +260,100 @@ func (s *ServerManagerTestSuite) TestServerManagerSpotInstance() {
 	err = mgr.DeleteNetwork(net.ID)
 	assert.NoError(s.T(), err)
 }
+
+//TestCreateImageFromServer checks that an image captured from a server can be used to create servers once the server is deleted
+func (s *ServerManagerTestSuite) TestCreateImageFromServer() {
+	kp, err := sshutils.CreateKeyPair(4096)
+	assert.NoError(s.T(), err)
+	mgr := s.Prov.GetNetworkManager()
+	net, subnet, err := s.CreateNetwork(mgr)
+	assert.NoError(s.T(), err)
+	tpl, err := s.SelectTemplate(s.Prov.GetTemplateManager())
+	assert.NoError(s.T(), err)
+	imm := s.Prov.GetImageManager()
+	img, err := s.FindImage(imm, tpl)
+	assert.NoError(s.T(), err)
+
+	srvMgr := s.Prov.GetServerManager()
+	server, err := srvMgr.Create(api.CreateServerOptions{
+		Name:       "golden_server",
+		TemplateID: tpl.ID,
+		ImageID:    img.ID,
+		Subnets:    []api.Subnet{*subnet},
+		KeyPair:    *kp,
+	})
+	assert.NoError(s.T(), err)
+	golden, err := imm.CreateFromServer(server.ID, "golden_image")
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "golden_image", golden.Name)
+	image, err := imm.Get(golden.ID)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), golden.ID, image.ID)
+	assert.Equal(s.T(), golden.Name, image.Name)
+	err = srvMgr.Delete(server.ID)
+	assert.NoError(s.T(), err)
+
+	clone, err := srvMgr.Create(api.CreateServerOptions{
+		Name:       "golden_clone",
+		TemplateID: tpl.ID,
+		ImageID:    golden.ID,
+		Subnets:    []api.Subnet{*subnet},
+		KeyPair:    *kp,
+	})
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), golden.ID, clone.ImageID)
+
+	err = srvMgr.Delete(clone.ID)
+	assert.NoError(s.T(), err)
+	err = imm.Delete(golden.ID)
+	assert.NoError(s.T(), err)
+	_, err = imm.Get(golden.ID)
+	assert.Error(s.T(), err)
+	err = mgr.DeleteSubnet(net.ID, subnet.ID)
+	assert.NoError(s.T(), err)
+	err = mgr.DeleteNetwork(net.ID)
+	assert.NoError(s.T(), err)
+}
+
+//TestCreateServerWithKeyPairName checks that servers can be created with a key pair imported with the KeyPairManager
+func (s *ServerManagerTestSuite) TestCreateServerWithKeyPairName() {
+	kp, err := sshutils.CreateKeyPair(2048)
+	assert.NoError(s.T(), err)
+	_, err = s.Prov.GetKeyPairManager().Import("server_key", kp.PublicKey)
+	assert.NoError(s.T(), err)
+	mgr := s.Prov.GetNetworkManager()
+	net, subnet, err := s.CreateNetwork(mgr)
+	assert.NoError(s.T(), err)
+	tpl, err := s.SelectTemplate(s.Prov.GetTemplateManager())
+	assert.NoError(s.T(), err)
+	img, err := s.FindImage(s.Prov.GetImageManager(), tpl)
+	assert.NoError(s.T(), err)
+
+	srvMgr := s.Prov.GetServerManager()
+	_, err = srvMgr.Create(api.CreateServerOptions{
+		Name:        "unknown_key_server",
+		TemplateID:  tpl.ID,
+		ImageID:     img.ID,
+		Subnets:     []api.Subnet{*subnet},
+		KeyPairName: "unknown_key",
+	})
+	assert.Error(s.T(), err)
+	server, err := srvMgr.Create(api.CreateServerOptions{
+		Name:        "named_key_server",
+		TemplateID:  tpl.ID,
+		ImageID:     img.ID,
+		Subnets:     []api.Subnet{*subnet},
+		KeyPairName: "server_key",
+	})
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "named_key_server", server.Name)
+
+	err = srvMgr.Delete(server.ID)
+	assert.NoError(s.T(), err)
+	err = s.Prov.GetKeyPairManager().Delete("server_key")
+	assert.NoError(s.T(), err)
+	err = mgr.DeleteSubnet(net.ID, subnet.ID)
+	assert.NoError(s.T(), err)
+	err = mgr.DeleteNetwork(net.ID)
+	assert.NoError(s.T(), err)
+}
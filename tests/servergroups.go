@@ -0,0 +1,137 @@
+package tests
+
+import (
+	"errors"
+	"time"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/SebastienDorgan/anyclouds/sshutils"
+	"github.com/stretchr/testify/suite"
+)
+
+//ServerGroupManagerTestSuite test suite of api.ServerGroupManager
+type ServerGroupManagerTestSuite struct {
+	suite.Suite
+	Prov api.Provider
+	//SkipHealing disables the replacement check of servers deleted with the ServerManager, to be used by providers whose group servers are not managed by the ServerManager
+	SkipHealing bool
+
+	network *api.Network
+	subnet  *api.Subnet
+	spec    api.CreateServerOptions
+}
+
+//SetupTest creates the network of the groups and selects the template and the image of their servers
+func (s *ServerGroupManagerTestSuite) SetupTest() {
+	helper := &ServerManagerTestSuite{Prov: s.Prov}
+	var err error
+	s.network, s.subnet, err = helper.CreateNetwork(s.Prov.GetNetworkManager())
+	s.Require().NoError(err)
+	tpl, err := helper.SelectTemplate(s.Prov.GetTemplateManager())
+	s.Require().NoError(err)
+	img, err := helper.FindImage(s.Prov.GetImageManager(), tpl)
+	s.Require().NoError(err)
+	kp, err := sshutils.CreateKeyPair(2048)
+	s.Require().NoError(err)
+	s.spec = api.CreateServerOptions{
+		TemplateID: tpl.ID,
+		ImageID:    img.ID,
+		Subnets:    []api.Subnet{*s.subnet},
+		KeyPair:    *kp,
+	}
+}
+
+//TearDownTest deletes the network of the groups
+func (s *ServerGroupManagerTestSuite) TearDownTest() {
+	s.NoError(s.Prov.GetNetworkManager().DeleteSubnet(s.network.ID, s.subnet.ID))
+	s.NoError(s.Prov.GetNetworkManager().DeleteNetwork(s.network.ID))
+}
+
+func (s *ServerGroupManagerTestSuite) checkServers(g *api.ServerGroup, size api.ServerGroupSize) {
+	s.Equal(size.MinSize, g.MinSize)
+	s.Equal(size.MaxSize, g.MaxSize)
+	s.Equal(size.DesiredSize, g.DesiredSize)
+	s.Len(g.ServerIDs, size.DesiredSize)
+	for i := 1; i < len(g.ServerIDs); i++ {
+		s.True(g.ServerIDs[i-1] < g.ServerIDs[i])
+	}
+}
+
+//TestServerGroup canonical test of the life cycle of a server group
+func (s *ServerGroupManagerTestSuite) TestServerGroup() {
+	mgr := s.Prov.GetServerGroupManager()
+	_, err := mgr.Create(api.CreateServerGroupOptions{
+		Name: "invalid-group",
+		Spec: s.spec,
+		Size: api.ServerGroupSize{MinSize: 2, MaxSize: 3, DesiredSize: 1},
+	})
+	s.True(errors.Is(err, api.ErrInvalidArgument))
+
+	size := api.ServerGroupSize{MinSize: 1, MaxSize: 3, DesiredSize: 2}
+	g, err := mgr.Create(api.CreateServerGroupOptions{
+		Name: "test-group",
+		Spec: s.spec,
+		Size: size,
+		Tags: map[string]string{"env": "test"},
+	})
+	s.Require().NoError(err)
+	s.Equal("test-group", g.Name)
+	s.Equal(map[string]string{"env": "test"}, g.Tags)
+	s.checkServers(g, size)
+	got, err := mgr.Get(g.ID)
+	s.NoError(err)
+	s.Equal(g, got)
+	groups, err := mgr.List()
+	s.NoError(err)
+	s.Contains(groups, *g)
+
+	size.DesiredSize = 3
+	scaled, err := mgr.Scale(g.ID, size)
+	s.Require().NoError(err)
+	s.checkServers(scaled, size)
+	for _, id := range g.ServerIDs {
+		s.Contains(scaled.ServerIDs, id)
+	}
+	_, err = mgr.Scale(g.ID, api.ServerGroupSize{MinSize: 1, MaxSize: 3, DesiredSize: 4})
+	s.True(errors.Is(err, api.ErrInvalidArgument))
+
+	replaced := scaled.ServerIDs[0]
+	g, err = mgr.ReplaceServer(g.ID, replaced)
+	s.Require().NoError(err)
+	s.checkServers(g, size)
+	s.NotContains(g.ServerIDs, replaced)
+	_, err = mgr.ReplaceServer(g.ID, replaced)
+	s.True(errors.Is(err, api.ErrNotFound))
+
+	size = api.ServerGroupSize{MinSize: 0, MaxSize: 1, DesiredSize: 1}
+	g, err = mgr.Scale(g.ID, size)
+	s.Require().NoError(err)
+	s.checkServers(g, size)
+
+	s.NoError(mgr.Delete(g.ID))
+	_, err = mgr.Get(g.ID)
+	s.True(errors.Is(err, api.ErrNotFound))
+}
+
+//TestServerGroupHealing checks that a server of a group deleted with the ServerManager is replaced
+func (s *ServerGroupManagerTestSuite) TestServerGroupHealing() {
+	if s.SkipHealing {
+		s.T().Skip("group servers are not managed by the ServerManager")
+	}
+	mgr := s.Prov.GetServerGroupManager()
+	g, err := mgr.Create(api.CreateServerGroupOptions{
+		Name: "healing-group",
+		Spec: s.spec,
+		Size: api.ServerGroupSize{MinSize: 1, MaxSize: 1, DesiredSize: 1},
+	})
+	s.Require().NoError(err)
+	s.Require().Len(g.ServerIDs, 1)
+	deleted := g.ServerIDs[0]
+	s.NoError(s.Prov.GetServerManager().Delete(deleted))
+	s.Eventually(func() bool {
+		g, err := mgr.Get(g.ID)
+		return err == nil && len(g.ServerIDs) == 1 && g.ServerIDs[0] != deleted
+	}, 10*time.Minute, time.Second)
+
+	s.NoError(mgr.Delete(g.ID))
+}
@@ -0,0 +1,71 @@
+package tests
+
+import (
+	"errors"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/stretchr/testify/suite"
+)
+
+//SnapshotManagerTestSuite test suite of api.SnapshotManager
+type SnapshotManagerTestSuite struct {
+	suite.Suite
+	Prov api.Provider
+}
+
+//TestSnapshots canonical test of SnapshotManager implementations
+func (s *SnapshotManagerTestSuite) TestSnapshots() {
+	v, err := s.Prov.GetVolumeManager().Create(api.CreateVolumeOptions{
+		Name: "snapshot_source",
+		Size: 5,
+	})
+	s.NoError(err)
+	snap, err := s.Prov.GetSnapshotManager().Create(api.CreateSnapshotOptions{
+		Name:     "my_snapshot",
+		VolumeID: v.ID,
+	})
+	s.NoError(err)
+	s.Equal("my_snapshot", snap.Name)
+	s.Equal(v.ID, snap.VolumeID)
+	s.Equal(int64(5), snap.Size)
+	s.Equal(api.SnapshotReady, snap.State)
+
+	snap2, err := s.Prov.GetSnapshotManager().Get(snap.ID)
+	s.NoError(err)
+	s.Equal(snap.ID, snap2.ID)
+	s.Equal(snap.Name, snap2.Name)
+
+	snapshots, err := s.Prov.GetSnapshotManager().List()
+	s.NoError(err)
+	found := false
+	for _, sn := range snapshots {
+		if sn.ID == snap.ID {
+			found = true
+		}
+	}
+	s.True(found)
+
+	clone, err := s.Prov.GetSnapshotManager().CreateVolumeFromSnapshot(api.CreateVolumeFromSnapshotOptions{
+		SnapshotID: snap.ID,
+		Name:       "snapshot_clone",
+	})
+	s.NoError(err)
+	s.Equal("snapshot_clone", clone.Name)
+	s.Equal(int64(5), clone.Size)
+
+	_, err = s.Prov.GetSnapshotManager().CreateVolumeFromSnapshot(api.CreateVolumeFromSnapshotOptions{
+		SnapshotID: snap.ID,
+		Name:       "too_small",
+		Size:       1,
+	})
+	s.Error(err)
+
+	err = s.Prov.GetVolumeManager().Delete(clone.ID)
+	s.NoError(err)
+	err = s.Prov.GetSnapshotManager().Delete(snap.ID)
+	s.NoError(err)
+	_, err = s.Prov.GetSnapshotManager().Get(snap.ID)
+	s.True(errors.Is(err, api.ErrNotFound))
+	err = s.Prov.GetVolumeManager().Delete(v.ID)
+	s.NoError(err)
+}
@@ -0,0 +1,55 @@
+package tests
+
+import (
+	"errors"
+
+	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/stretchr/testify/suite"
+)
+
+//TagManagerTestSuite test suite of api.TagManager
+type TagManagerTestSuite struct {
+	suite.Suite
+	Prov api.Provider
+}
+
+//TestTags canonical test of TagManager implementations
+func (s *TagManagerTestSuite) TestTags() {
+	netMgr := s.Prov.GetNetworkManager()
+	tagMgr := s.Prov.GetTagManager()
+	n, err := netMgr.CreateNetwork(api.CreateNetworkOptions{
+		Name: "tagged_network",
+		CIDR: "10.3.0.0/16",
+		Tags: map[string]string{"env": "test"},
+	})
+	s.NoError(err)
+	s.Equal("tagged_network", n.Name)
+	s.Equal(map[string]string{"env": "test"}, n.Tags)
+	resource := api.TaggedResource{Type: api.ResourceNetwork, ID: n.ID}
+
+	err = tagMgr.Add(api.AddTagsOptions{
+		Resource: resource,
+		Tags:     map[string]string{"owner": "anyclouds", "env": "dev"},
+	})
+	s.NoError(err)
+	tags, err := tagMgr.List(resource)
+	s.NoError(err)
+	s.Equal(map[string]string{"env": "dev", "owner": "anyclouds"}, tags)
+
+	err = tagMgr.Remove(api.RemoveTagsOptions{
+		Resource: resource,
+		Keys:     []string{"env"},
+	})
+	s.NoError(err)
+	n, err = netMgr.GetNetwork(n.ID)
+	s.NoError(err)
+	s.Equal("tagged_network", n.Name)
+	s.Equal(map[string]string{"owner": "anyclouds"}, n.Tags)
+
+	_, err = tagMgr.List(api.TaggedResource{Type: "unknown", ID: n.ID})
+	s.Error(err)
+	s.True(errors.Is(err, api.ErrInvalidArgument))
+
+	err = netMgr.DeleteNetwork(n.ID)
+	s.NoError(err)
+}
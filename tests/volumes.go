@@ -2,8 +2,8 @@ package tests
 
 import (
 	"github.com/SebastienDorgan/anyclouds/api"
+	"github.com/SebastienDorgan/anyclouds/selector"
 	"github.com/SebastienDorgan/anyclouds/sshutils"
-	"github.com/SebastienDorgan/talgo"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
@@ -15,44 +15,15 @@ type VolumeManagerTestSuite struct {
 }
 
 func (s *VolumeManagerTestSuite) SelectTemplate() (*api.ServerTemplate, error) {
-	cpu := 4
-	ram := 15000
-	tplMgr := s.Prov.GetTemplateManager()
-	templates, err := tplMgr.List()
-	if err != nil {
-		return nil, err
-	}
-	indexes := talgo.FindAll(len(templates), func(i int) bool {
-		if templates[i].NumberOfCPUCore == cpu && templates[i].RAMSize >= ram && templates[i].GPUInfo == nil && templates[i].Arch == api.ArchAmd64 {
-			return true
-		}
-		return false
-	})
-	if len(indexes) == 0 {
-		return nil, errors.Errorf("No matching template find")
-	}
-	selected := talgo.Select(len(indexes), func(i, j int) int {
-		if templates[indexes[i]].OneDemandPrice < templates[indexes[j]].OneDemandPrice {
-			return i
-		}
-		return j
-	})
-	return &templates[indexes[selected]], nil
+	return selector.Best(s.Prov.GetTemplateManager(), selector.Criteria{
+		MinCPU: 4,
+		MinRAM: 15000,
+		Arch:   api.ArchAmd64,
+	}, selector.ClosestFit, selector.Cheapest)
 }
 
 func (s *VolumeManagerTestSuite) FindImage(tpl *api.ServerTemplate) (*api.Image, error) {
-	images, err := s.Prov.GetImageManager().List()
-	if err != nil {
-		return nil, err
-	}
-	os := "UBUNTU"
-	version := "18.04"
-	for _, img := range images {
-		if img.MinDisk < tpl.SystemDiskSize && img.MinRAM < tpl.RAMSize && CheckImageName(&img, os, version) {
-			return &img, err
-		}
-	}
-	return nil, errors.Errorf("Enable to fin image fitting template %s", tpl.Name)
+	return FindImage(s.Prov.GetImageManager(), tpl)
 }
 func (s *VolumeManagerTestSuite) getDefaultNetwork() (*api.Network, error) {
 	nets, err := s.Prov.GetNetworkManager().ListNetworks()